@@ -0,0 +1,118 @@
+package awsmock
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// healthPath is where the mock reports that it is up, for container
+// healthchecks and readiness probes.
+const healthPath = "/healthz"
+
+// adminPrefix is where the mock serves its admin API, which lets test
+// harnesses in other processes and languages control it over HTTP.
+const adminPrefix = "/_awsmock/"
+
+// serveAdmin handles the admin API:
+//
+//	POST   /_awsmock/reset       reset every service, as Reset does
+//	POST   /_awsmock/checkpoint  record the fixture Reset restores
+//	GET    /_awsmock/state       the ExportState inventory
+//	GET    /_awsmock/clock       the mock clock's time
+//	POST   /_awsmock/clock       advance it by {"advance": "90s"}
+//	GET    /_awsmock/faults      the active faults
+//	POST   /_awsmock/faults      inject a Fault, given as JSON
+//	DELETE /_awsmock/faults      clear every fault
+//	POST   /_awsmock/status      set a resource's status with
+//	                             {"arn": "...", "status": "maintenance"}
+func (m *MockServer) serveAdmin(w http.ResponseWriter, r *http.Request) {
+	switch route := r.Method + " " + strings.TrimPrefix(r.URL.Path, adminPrefix); route {
+	case "POST reset":
+		m.Reset()
+		w.WriteHeader(http.StatusNoContent)
+	case "POST checkpoint":
+		m.Checkpoint()
+		w.WriteHeader(http.StatusNoContent)
+	case "GET state":
+		writeAdminJSON(w, http.StatusOK, m.ExportState())
+	case "GET clock":
+		writeAdminJSON(w, http.StatusOK, map[string]string{"now": m.Now().Format(time.RFC3339Nano)})
+	case "POST clock":
+		var req struct {
+			Advance string `json:"advance"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeAdminError(w, http.StatusBadRequest, "invalid request body: "+err.Error())
+			return
+		}
+		d, err := time.ParseDuration(req.Advance)
+		if err != nil || d < 0 {
+			writeAdminError(w, http.StatusBadRequest, "advance must be a non-negative duration such as \"90s\"")
+			return
+		}
+		m.AdvanceClock(d)
+		writeAdminJSON(w, http.StatusOK, map[string]string{"now": m.Now().Format(time.RFC3339Nano)})
+	case "GET faults":
+		writeAdminJSON(w, http.StatusOK, map[string][]Fault{"faults": m.Faults()})
+	case "POST faults":
+		var f Fault
+		if err := json.NewDecoder(r.Body).Decode(&f); err != nil {
+			writeAdminError(w, http.StatusBadRequest, "invalid fault: "+err.Error())
+			return
+		}
+		if f.Count < 0 || f.Status != 0 && (f.Status < 400 || f.Status > 599) {
+			writeAdminError(w, http.StatusBadRequest, "count must not be negative and status must be an error status")
+			return
+		}
+		m.InjectFault(f)
+		w.WriteHeader(http.StatusNoContent)
+	case "DELETE faults":
+		m.ClearFaults()
+		w.WriteHeader(http.StatusNoContent)
+	case "POST status":
+		var req struct {
+			ARN    string `json:"arn"`
+			Status string `json:"status"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeAdminError(w, http.StatusBadRequest, "invalid request body: "+err.Error())
+			return
+		}
+		if err := m.SetResourceStatus(req.ARN, req.Status); err != nil {
+			writeAdminError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		writeAdminError(w, http.StatusNotFound, "no admin endpoint for "+route)
+	}
+}
+
+// serveHealth reports that the server is up, with the services it serves.
+func (m *MockServer) serveHealth(w http.ResponseWriter) {
+	m.mu.RLock()
+	names := make([]string, 0, len(m.services))
+	for name := range m.services {
+		names = append(names, name)
+	}
+	m.mu.RUnlock()
+	sort.Strings(names)
+
+	writeAdminJSON(w, http.StatusOK, map[string]interface{}{
+		"status":   "ok",
+		"services": names,
+	})
+}
+
+func writeAdminJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeAdminError(w http.ResponseWriter, status int, message string) {
+	writeAdminJSON(w, status, map[string]string{"message": message})
+}
@@ -2,15 +2,22 @@ package awsmock
 
 import (
 	"context"
+	"fmt"
+	"net"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/credentials"
+
+	"github.com/riyanimam/goto/internal/clock"
+	"github.com/riyanimam/goto/internal/lifecycle"
+	"github.com/riyanimam/goto/internal/tags"
 )
 
 // Service represents an AWS service mock that can handle HTTP requests.
@@ -29,19 +36,119 @@ type Service interface {
 type MockServer struct {
 	server   *httptest.Server
 	services map[string]Service
+	clock    *clock.Clock
+	tags     *tags.Store
+	limiters map[string]*rateLimiter
+	latency  LatencyProfile
+	metrics  *metrics
 	mu       sync.RWMutex
+
+	// dashboard records recent calls once EnableDashboard is called.
+	dashboard *dashboard
+
+	// publicURL is the URL clients reach the server at, if it is not the
+	// address the server listens on.
+	publicURL string
+
+	// stsGlobal sends STS requests from legacy regions to the global
+	// endpoint.
+	stsGlobal bool
+
+	// cloudFrontContent routes requests for distribution hosts to the
+	// CloudFront mock to be served from their origins.
+	cloudFrontContent bool
+
+	// transitions decides when resources leave their transitional status.
+	transitions *lifecycle.Transitions
+
+	checkpointed   map[string]bool
+	checkpointTags map[string]map[string]string
+
+	faultsMu sync.Mutex
+	faults   []Fault
+
+	scenariosMu sync.Mutex
+	scenarios   []*Scenario
+
+	// middleware wraps every service request, ahead of the middleware of
+	// the services in order, their registration order.
+	middleware []Middleware
+	order      []string
+
+	// started is set once the services given to the server are
+	// initialized; services registered after that are initialized at once.
+	started bool
 }
 
 // Start creates and starts a new mock AWS server with all built-in services.
 // The server is automatically stopped when the test completes.
 func Start(t testing.TB, opts ...Option) *MockServer {
+	m, err := start(httptest.NewServer, opts)
+	if err != nil {
+		t.Fatalf("awsmock: %v", err)
+	}
+	t.Cleanup(m.Stop)
+	return m
+}
+
+// Listen starts a mock AWS server with all built-in services on addr, such
+// as ":4566", for use outside tests: in a standalone process or a container
+// shared by test suites in other languages. The caller must call
+// [MockServer.Stop] when done. Unless [WithPublicURL] says otherwise, the
+// server hands out endpoints on localhost.
+func Listen(addr string, opts ...Option) (*MockServer, error) {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	return start(func(h http.Handler) *httptest.Server {
+		s := &httptest.Server{Listener: l, Config: &http.Server{Handler: h}}
+		s.Start()
+		return s
+	}, opts)
+}
+
+// NewHandler returns the mock as a plain [http.Handler] with all built-in
+// services, for mounting in a server of your own or serving with
+// [httptest.NewServer]. Nothing listens until you serve it. Control it
+// through the admin API under /_awsmock/, and give it [WithPublicURL] if
+// the API Gateway, Cognito, or OpenSearch endpoints it hands out should
+// resolve to it.
+func NewHandler(opts ...Option) (http.Handler, error) {
+	m, err := start(nil, opts)
+	if err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// start creates a mock server, serving it with the server newServer starts,
+// if any.
+func start(newServer func(http.Handler) *httptest.Server, opts []Option) (*MockServer, error) {
 	cfg := defaultConfig()
 	for _, opt := range opts {
 		opt(&cfg)
 	}
 
 	m := &MockServer{
-		services: make(map[string]Service),
+		services:  make(map[string]Service),
+		clock:     clock.New(time.Now()),
+		tags:      tags.New(),
+		latency:   cfg.latency,
+		stsGlobal: cfg.stsGlobal,
+		publicURL: cfg.publicURL,
+
+		cloudFrontContent: cfg.cloudFront,
+		middleware:        cfg.middleware,
+	}
+	m.transitions = lifecycle.New(m.clock, cfg.asyncDelay)
+	if cfg.metrics {
+		m.metrics = newMetrics(cfg.observers)
+	}
+
+	// Start listening first so services can learn the server URL when wired.
+	if newServer != nil {
+		m.server = newServer(m)
 	}
 
 	// Register built-in services.
@@ -54,29 +161,127 @@ func Start(t testing.TB, opts ...Option) *MockServer {
 		m.Register(svc)
 	}
 
-	m.server = httptest.NewServer(m)
-	t.Cleanup(m.Stop)
+	if err := m.initialize(); err != nil {
+		m.Stop()
+		return nil, err
+	}
+	if err := m.configure(cfg); err != nil {
+		m.Stop()
+		return nil, err
+	}
+	return m, nil
+}
 
-	return m
+// configure applies the options that tune registered services.
+func (m *MockServer) configure(cfg serverConfig) error {
+	if cfg.openSearchProxy != "" {
+		if svc, ok := m.services["es"].(interface{ SetProxy(string) error }); ok {
+			if err := svc.SetProxy(cfg.openSearchProxy); err != nil {
+				return err
+			}
+		}
+	}
+
+	if cfg.consistency != nil {
+		m.applyConsistency(*cfg.consistency)
+	}
+
+	for service, rl := range cfg.rateLimits {
+		if m.limiters == nil {
+			m.limiters = make(map[string]*rateLimiter)
+		}
+		m.limiters[service] = newRateLimiter(m.clock, rl.rate, rl.burst)
+	}
+	for _, q := range cfg.quotas {
+		svc, ok := m.services[q.service].(interface{ SetQuota(string, int) error })
+		if !ok {
+			return fmt.Errorf("%s service does not support quotas", q.service)
+		}
+		if err := svc.SetQuota(q.resource, q.limit); err != nil {
+			return err
+		}
+	}
+	if cfg.spill != nil {
+		if svc, ok := m.services["s3"].(interface{ SetSpillThreshold(int64, string) }); ok {
+			svc.SetSpillThreshold(cfg.spill.threshold, cfg.spill.dir)
+		}
+	}
+	for _, d := range cfg.s3Dirs {
+		svc, ok := m.services["s3"].(interface{ Mount(string, string) error })
+		if !ok {
+			return fmt.Errorf("s3 service does not support mounting directories")
+		}
+		if err := svc.Mount(d.bucket, d.dir); err != nil {
+			return err
+		}
+	}
+	if len(cfg.sourceAccounts) > 0 {
+		svc, ok := m.services["monitoring"].(interface{ LinkSourceAccounts(...string) })
+		if !ok {
+			return fmt.Errorf("monitoring service does not support source accounts")
+		}
+		svc.LinkSourceAccounts(cfg.sourceAccounts...)
+	}
+	if cfg.throughput {
+		if svc, ok := m.services["dynamodb"].(interface{ EnforceThroughput(bool) }); ok {
+			svc.EnforceThroughput(true)
+		}
+	}
+	return nil
 }
 
 // Register adds a service to the mock server.
 // If a service with the same name already exists, it is replaced.
+// Once the server is running, a service implementing [Initializer] is
+// initialized at once; Register panics if that fails or if a service it
+// depends on is not registered.
 func (m *MockServer) Register(svc Service) {
+	m.mu.RLock()
+	started := m.started
+	m.mu.RUnlock()
+	if started {
+		if err := m.checkDependencies(svc); err != nil {
+			panic("awsmock: " + err.Error())
+		}
+	}
+
+	m.wire(svc)
+
 	m.mu.Lock()
-	defer m.mu.Unlock()
+	if _, exists := m.services[svc.Name()]; !exists {
+		m.order = append(m.order, svc.Name())
+	}
 	m.services[svc.Name()] = svc
+	m.mu.Unlock()
+
+	if started {
+		if err := m.initService(svc); err != nil {
+			panic("awsmock: " + err.Error())
+		}
+	}
 }
 
-// URL returns the base URL of the mock server.
+// URL returns the base URL of the mock server: the URL given to
+// [WithPublicURL], or else the address it listens on, with localhost for a
+// server listening on every interface.
 func (m *MockServer) URL() string {
-	return m.server.URL
+	if m.publicURL != "" || m.server == nil {
+		return m.publicURL
+	}
+	u := m.server.URL
+	if host, port, err := net.SplitHostPort(strings.TrimPrefix(u, "http://")); err == nil {
+		if ip := net.ParseIP(host); ip != nil && ip.IsUnspecified() {
+			u = "http://localhost:" + port
+		}
+	}
+	return u
 }
 
 // AWSConfig returns an [aws.Config] pre-configured to route all requests
-// to the mock server with static test credentials.
+// to the mock server with static test credentials. S3 clients built from it
+// work with either path-style or virtual-hosted-style addressing.
 func (m *MockServer) AWSConfig(ctx context.Context) (aws.Config, error) {
-	endpoint := m.server.URL
+	endpoint := m.Endpoint()
 
 	cfg, err := config.LoadDefaultConfig(ctx,
 		config.WithRegion("us-east-1"),
@@ -91,51 +296,134 @@ func (m *MockServer) AWSConfig(ctx context.Context) (aws.Config, error) {
 	}
 
 	cfg.BaseEndpoint = aws.String(endpoint)
+	cfg.HTTPClient = m.HTTPClient()
+	// The resolver is deprecated, but it is the only config-wide way to
+	// give STS clients a signing region other than their own, as the global
+	// endpoint requires. It takes precedence over BaseEndpoint for STS only.
+	cfg.EndpointResolverWithOptions = m.stsResolver() //nolint:staticcheck
 
 	return cfg, nil
 }
 
-// Stop shuts down the mock server and resets all services.
+// Stop shuts down the mock server and clears all services, discarding any
+// checkpoint.
 func (m *MockServer) Stop() {
 	if m.server != nil {
 		m.server.Close()
 	}
+	m.clearCheckpoint()
 	m.Reset()
 }
 
-// Reset clears all in-memory state across all registered services.
+// Reset clears all in-memory state across all registered services. After
+// [MockServer.Checkpoint], services that support checkpoints are restored to
+// the checkpointed state instead.
 func (m *MockServer) Reset() {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 	for _, svc := range m.services {
 		svc.Reset()
 	}
+	for name := range m.checkpointed {
+		m.tags.RestoreService(name, m.checkpointTags)
+	}
+}
+
+// Now returns the current time of the mock clock. The clock starts at the
+// wall-clock time when the server is started and only moves when advanced.
+func (m *MockServer) Now() time.Time {
+	return m.clock.Now()
+}
+
+// AdvanceClock moves the mock clock forward by d, firing any time-driven
+// behavior (such as scheduled invocations) that falls due along the way.
+func (m *MockServer) AdvanceClock(d time.Duration) {
+	m.clock.Advance(d)
 }
 
 // ServeHTTP routes incoming requests to the appropriate service handler.
 // It determines the target service by inspecting the Authorization header's
 // credential scope (e.g., ".../s3/aws4_request").
 func (m *MockServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	serviceName := m.identifyService(r)
-
-	m.mu.RLock()
-	svc, ok := m.services[serviceName]
-	m.mu.RUnlock()
-
-	if !ok {
-		http.Error(w, "unknown service: "+serviceName, http.StatusBadRequest)
+	if r.URL.Path == metricsPath {
+		m.serveMetrics(w)
+		return
+	}
+	// Signed requests for /healthz are for an S3 bucket of that name.
+	if r.URL.Path == healthPath && r.Header.Get("Authorization") == "" {
+		m.serveHealth(w)
+		return
+	}
+	if strings.HasPrefix(r.URL.Path, adminPrefix) {
+		m.serveAdmin(w, r)
+		return
+	}
+	if strings.HasPrefix(r.URL.Path, dashboardPrefix) {
+		m.serveDashboard(w, r)
 		return
 	}
 
-	svc.Handler().ServeHTTP(w, r)
+	m.handler(m.identifyService(r)).ServeHTTP(w, r)
 }
 
 // identifyService extracts the AWS service name from the request.
 // It checks (in order):
-//  1. The Authorization header credential scope
-//  2. The X-Amz-Target header prefix
-//  3. Falls back to "s3" for unsigned requests (S3 presigned URLs, etc.)
+//  1. Mock-specific data-plane path prefixes and hosts (OpenSearch domains,
+//     API Gateway endpoints, Cognito hosted UI endpoints, CloudFormation
+//     custom resource responses, CodePipeline webhooks, CloudFront
+//     distributions, SSO device verification and portal requests, unsigned
+//     SSO OIDC requests, and instance metadata)
+//  2. The Authorization header credential scope
+//  3. The X-Amz-Target header prefix
+//  4. Falls back to "s3" for unsigned requests (S3 presigned URLs, etc.)
 func (m *MockServer) identifyService(r *http.Request) string {
+	// OpenSearch domain endpoints are served from the mock under /_opensearch/.
+	if strings.HasPrefix(r.URL.Path, "/_opensearch/") {
+		return "es"
+	}
+	// API Gateway HTTP API endpoints are served under /_apigateway/, or by
+	// host at {apiId}.execute-api.localhost.
+	if strings.HasPrefix(r.URL.Path, "/_apigateway/") || isExecuteAPIHost(r.Host) {
+		return "apigatewayv2"
+	}
+	// Cognito hosted UI endpoints are served per user pool under /_cognito/.
+	if strings.HasPrefix(r.URL.Path, "/_cognito/") {
+		return "cognito-idp"
+	}
+	// CloudFormation custom resource response URLs are served under
+	// /_cloudformation/.
+	if strings.HasPrefix(r.URL.Path, "/_cloudformation/") {
+		return "cloudformation"
+	}
+	// CodePipeline webhook URLs are served under /_codepipeline/.
+	if strings.HasPrefix(r.URL.Path, "/_codepipeline/") {
+		return "codepipeline"
+	}
+	// SSO device verification URIs are served under /_sso/, and the SSO
+	// portal and OIDC operations are unsigned, so they are told apart by the
+	// portal's bearer token header and the OIDC paths.
+	if strings.HasPrefix(r.URL.Path, "/_sso/") {
+		return "sso-oauth"
+	}
+	if r.Header.Get("X-Amz-Sso_bearer_token") != "" {
+		return "portal.sso"
+	}
+	if r.Method == http.MethodPost && r.Header.Get("Authorization") == "" {
+		switch r.URL.Path {
+		case "/client/register", "/device_authorization", "/token":
+			return "sso-oauth"
+		}
+	}
+	// Instance metadata is served at the paths it has on an instance, to
+	// unsigned requests that are not presigned S3 URLs.
+	if isInstanceMetadataPath(r.URL.Path) && r.Header.Get("Authorization") == "" && r.URL.Query().Get("X-Amz-Credential") == "" {
+		return "imds"
+	}
+	// CloudFront distributions are served by host, when enabled.
+	if m.cloudFrontContent && isCloudFrontHost(r.Host) {
+		return "cloudfront"
+	}
+
 	// Try Authorization header: AWS4-HMAC-SHA256 Credential=.../region/SERVICE/aws4_request
 	if auth := r.Header.Get("Authorization"); auth != "" {
 		if idx := strings.Index(auth, "Credential="); idx >= 0 {
@@ -182,12 +470,16 @@ func (m *MockServer) identifyService(r *http.Request) string {
 				return "kms"
 			case strings.Contains(name, "amazonec2containerregistry"):
 				return "ecr"
+			case strings.Contains(name, "spencerfrontendservice"):
+				return "ecr-public"
 			case strings.Contains(name, "amazonecs"):
 				return "ecs"
 			case strings.Contains(name, "awsstepfunctions"):
 				return "states"
 			case strings.Contains(name, "certificatemanager"):
 				return "acm"
+			case strings.Contains(name, "acmprivateca"):
+				return "acm-pca"
 			case strings.Contains(name, "cognitoidentity"):
 				return "cognito-identity"
 			case strings.Contains(name, "cognitoidp") || strings.Contains(name, "cognito-idp"):
@@ -226,6 +518,8 @@ func (m *MockServer) identifyService(r *http.Request) string {
 				return "sso"
 			case strings.Contains(name, "amazondaxv3"):
 				return "dax"
+			case strings.Contains(name, "apprunner"):
+				return "apprunner"
 			}
 		}
 	}
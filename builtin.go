@@ -1,17 +1,24 @@
 package awsmock
 
 import (
+	"github.com/riyanimam/goto/services/accessanalyzer"
 	"github.com/riyanimam/goto/services/acm"
+	"github.com/riyanimam/goto/services/acmpca"
 	"github.com/riyanimam/goto/services/apigateway"
 	"github.com/riyanimam/goto/services/apigatewayv2"
+	"github.com/riyanimam/goto/services/appflow"
 	"github.com/riyanimam/goto/services/applicationautoscaling"
+	"github.com/riyanimam/goto/services/apprunner"
 	"github.com/riyanimam/goto/services/appsync"
 	"github.com/riyanimam/goto/services/athena"
 	"github.com/riyanimam/goto/services/autoscaling"
 	"github.com/riyanimam/goto/services/backup"
 	"github.com/riyanimam/goto/services/batch"
+	"github.com/riyanimam/goto/services/bedrock"
+	"github.com/riyanimam/goto/services/budgets"
 	"github.com/riyanimam/goto/services/cloudformation"
 	"github.com/riyanimam/goto/services/cloudfront"
+	"github.com/riyanimam/goto/services/cloudhsmv2"
 	"github.com/riyanimam/goto/services/cloudtrail"
 	"github.com/riyanimam/goto/services/cloudwatch"
 	"github.com/riyanimam/goto/services/cloudwatchlogs"
@@ -20,11 +27,14 @@ import (
 	"github.com/riyanimam/goto/services/cognitoidentity"
 	"github.com/riyanimam/goto/services/cognitoidp"
 	"github.com/riyanimam/goto/services/configservice"
+	"github.com/riyanimam/goto/services/costexplorer"
+	"github.com/riyanimam/goto/services/datasync"
 	"github.com/riyanimam/goto/services/dax"
 	"github.com/riyanimam/goto/services/dynamodb"
 	"github.com/riyanimam/goto/services/dynamodbstreams"
 	"github.com/riyanimam/goto/services/ec2"
 	"github.com/riyanimam/goto/services/ecr"
+	"github.com/riyanimam/goto/services/ecrpublic"
 	"github.com/riyanimam/goto/services/ecs"
 	"github.com/riyanimam/goto/services/efs"
 	"github.com/riyanimam/goto/services/eks"
@@ -34,32 +44,54 @@ import (
 	"github.com/riyanimam/goto/services/eventbridge"
 	"github.com/riyanimam/goto/services/firehose"
 	"github.com/riyanimam/goto/services/fsx"
+	"github.com/riyanimam/goto/services/globalaccelerator"
 	"github.com/riyanimam/goto/services/glue"
 	"github.com/riyanimam/goto/services/guardduty"
 	"github.com/riyanimam/goto/services/iam"
+	"github.com/riyanimam/goto/services/imds"
+	"github.com/riyanimam/goto/services/inspector2"
+	"github.com/riyanimam/goto/services/iot"
+	"github.com/riyanimam/goto/services/iotdata"
 	"github.com/riyanimam/goto/services/kafka"
 	"github.com/riyanimam/goto/services/kinesis"
+	"github.com/riyanimam/goto/services/kinesisanalyticsv2"
 	"github.com/riyanimam/goto/services/kms"
 	"github.com/riyanimam/goto/services/lambda"
+	"github.com/riyanimam/goto/services/mediaconvert"
 	"github.com/riyanimam/goto/services/mq"
+	"github.com/riyanimam/goto/services/mwaa"
 	"github.com/riyanimam/goto/services/neptune"
 	"github.com/riyanimam/goto/services/opensearch"
 	"github.com/riyanimam/goto/services/organizations"
+	"github.com/riyanimam/goto/services/paymentcryptography"
+	"github.com/riyanimam/goto/services/pipes"
+	"github.com/riyanimam/goto/services/quicksight"
 	"github.com/riyanimam/goto/services/rds"
 	"github.com/riyanimam/goto/services/redshift"
+	"github.com/riyanimam/goto/services/rekognition"
 	"github.com/riyanimam/goto/services/resourcegroupstaggingapi"
 	"github.com/riyanimam/goto/services/route53"
+	"github.com/riyanimam/goto/services/route53resolver"
 	"github.com/riyanimam/goto/services/s3"
+	"github.com/riyanimam/goto/services/sagemaker"
 	"github.com/riyanimam/goto/services/scheduler"
+	"github.com/riyanimam/goto/services/schemas"
 	"github.com/riyanimam/goto/services/secretsmanager"
+	"github.com/riyanimam/goto/services/securityhub"
 	"github.com/riyanimam/goto/services/servicediscovery"
 	"github.com/riyanimam/goto/services/ses"
 	"github.com/riyanimam/goto/services/sns"
 	"github.com/riyanimam/goto/services/sqs"
 	"github.com/riyanimam/goto/services/ssm"
 	"github.com/riyanimam/goto/services/ssoadmin"
+	"github.com/riyanimam/goto/services/ssooidc"
+	"github.com/riyanimam/goto/services/ssoportal"
 	"github.com/riyanimam/goto/services/stepfunctions"
+	"github.com/riyanimam/goto/services/storagegateway"
 	"github.com/riyanimam/goto/services/sts"
+	"github.com/riyanimam/goto/services/swf"
+	"github.com/riyanimam/goto/services/synthetics"
+	"github.com/riyanimam/goto/services/textract"
 	"github.com/riyanimam/goto/services/transfer"
 	"github.com/riyanimam/goto/services/wafv2"
 	"github.com/riyanimam/goto/services/xray"
@@ -130,5 +162,37 @@ func builtinServices() []Service {
 		neptune.New(),
 		dax.New(),
 		ssoadmin.New(),
+		apprunner.New(),
+		synthetics.New(),
+		acmpca.New(),
+		ecrpublic.New(),
+		securityhub.New(),
+		inspector2.New(),
+		costexplorer.New(),
+		budgets.New(),
+		quicksight.New(),
+		sagemaker.New(),
+		bedrock.New(),
+		textract.New(),
+		rekognition.New(),
+		pipes.New(),
+		mwaa.New(),
+		appflow.New(),
+		mediaconvert.New(),
+		swf.New(),
+		iot.New(),
+		iotdata.New(),
+		schemas.New(),
+		accessanalyzer.New(),
+		ssooidc.New(),
+		ssoportal.New(),
+		imds.New(),
+		kinesisanalyticsv2.New(),
+		route53resolver.New(),
+		globalaccelerator.New(),
+		datasync.New(),
+		storagegateway.New(),
+		cloudhsmv2.New(),
+		paymentcryptography.New(),
 	}
 }
@@ -0,0 +1,55 @@
+package awsmock
+
+// checkpointer is implemented by services that can record their state and
+// restore it on Reset.
+type checkpointer interface {
+	// Checkpoint records the current state; Reset restores it from then on.
+	Checkpoint()
+	// ClearCheckpoint makes Reset clear all state again.
+	ClearCheckpoint()
+}
+
+// Checkpoint records the current state of the mock as the fixture that
+// [MockServer.Reset] restores, instead of clearing everything. Build a
+// fixture once, checkpoint it, and reset between test cases:
+//
+//	seed(t, mock)
+//	mock.Checkpoint()
+//	for _, tc := range cases {
+//		t.Run(tc.name, func(t *testing.T) {
+//			defer mock.Reset()
+//			...
+//		})
+//	}
+//
+// S3, DynamoDB, SQS, and SNS support checkpoints, along with the tags of
+// their resources. S3 objects and DynamoDB items are restored copy-on-write,
+// so a reset does not copy them however many there are. Services without
+// checkpoint support are cleared by Reset as before.
+func (m *MockServer) Checkpoint() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.checkpointTags = m.tags.All()
+	m.checkpointed = make(map[string]bool)
+	for name, svc := range m.services {
+		if c, ok := svc.(checkpointer); ok {
+			c.Checkpoint()
+			m.checkpointed[name] = true
+		}
+	}
+}
+
+// clearCheckpoint discards the checkpoint so that Reset clears everything.
+func (m *MockServer) clearCheckpoint() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for name := range m.checkpointed {
+		if c, ok := m.services[name].(checkpointer); ok {
+			c.ClearCheckpoint()
+		}
+	}
+	m.checkpointed = nil
+	m.checkpointTags = nil
+}
@@ -0,0 +1,79 @@
+// Command awsmock runs the mock AWS server as a standalone process, for
+// docker-compose and testcontainers setups shared by test suites written in
+// any language.
+//
+// The server listens on AWSMOCK_ADDR (default ":4566") and is configured by
+// the AWSMOCK_* environment variables described at
+// [awsmock.OptionsFromEnv]. It reports readiness at /healthz and accepts
+// admin requests under /_awsmock/.
+//
+// Run with -healthcheck to probe a running server's /healthz and exit 0 if
+// it is up, as a container HEALTHCHECK does in images without curl.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	awsmock "github.com/riyanimam/goto"
+)
+
+func main() {
+	healthcheck := flag.Bool("healthcheck", false, "probe a running server's /healthz and exit")
+	flag.Parse()
+
+	addr := os.Getenv("AWSMOCK_ADDR")
+	if addr == "" {
+		addr = ":4566"
+	}
+
+	if *healthcheck {
+		if err := probe(addr); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	opts, err := awsmock.OptionsFromEnv()
+	if err != nil {
+		log.Fatalf("awsmock: %v", err)
+	}
+	mock, err := awsmock.Listen(addr, opts...)
+	if err != nil {
+		log.Fatalf("awsmock: %v", err)
+	}
+	log.Printf("awsmock: serving on %s", mock.URL())
+
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+	<-stop
+
+	log.Printf("awsmock: shutting down")
+	mock.Stop()
+}
+
+// probe checks that the server listening on addr reports itself healthy.
+func probe(addr string) error {
+	_, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return err
+	}
+	client := &http.Client{Timeout: 2 * time.Second}
+	resp, err := client.Get("http://localhost:" + port + "/healthz")
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("healthz: %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+// Command stubgen writes the routing table and handler stubs of a mock
+// service, generated from the service's Smithy model, for a contributor to
+// fill in.
+//
+// Models are the JSON AST files AWS publishes for each service, such as
+// models/kms/service/2014-11-01/kms-2014-11-01.json in the
+// aws/api-models-aws repository:
+//
+//	go run ./cmd/stubgen -model kms-2014-11-01.json -o services/kms/stubs.go
+//
+// For a service that already exists, name the missing operations with -ops
+// and merge the generated routing table into the service's Handler.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/riyanimam/goto/internal/stubgen"
+)
+
+func main() {
+	modelPath := flag.String("model", "", "path of the service's Smithy JSON AST model")
+	pkg := flag.String("package", "", "package name of the generated file (default: the service's SDK ID)")
+	ops := flag.String("ops", "", "comma-separated operations to stub (default: all)")
+	out := flag.String("o", "", "file to write (default: standard output)")
+	flag.Parse()
+
+	if *modelPath == "" {
+		fmt.Fprintln(os.Stderr, "stubgen: -model is required")
+		flag.Usage()
+		os.Exit(2)
+	}
+	if err := run(*modelPath, *pkg, *ops, *out); err != nil {
+		fmt.Fprintf(os.Stderr, "stubgen: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(modelPath, pkg, ops, out string) error {
+	data, err := os.ReadFile(modelPath)
+	if err != nil {
+		return err
+	}
+	opts := stubgen.Options{Package: pkg}
+	if ops != "" {
+		opts.Operations = strings.Split(ops, ",")
+	}
+	src, err := stubgen.Generate(data, opts)
+	if err != nil {
+		return err
+	}
+	if out == "" {
+		_, err = os.Stdout.Write(src)
+		return err
+	}
+	return os.WriteFile(out, src, 0o644)
+}
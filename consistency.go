@@ -0,0 +1,62 @@
+package awsmock
+
+import (
+	"fmt"
+	"time"
+)
+
+// Consistency configures how long writes take to become visible to
+// eventually consistent reads. Zero durations keep read-after-write
+// consistency for that path. Delays are measured in wall-clock time, so
+// code that retries with backoff eventually sees its writes.
+type Consistency struct {
+	// S3List delays new, overwritten, and deleted objects in
+	// ListObjectsV2 results. GetObject and HeadObject are unaffected.
+	S3List time.Duration
+
+	// DynamoDBRead makes GetItem without ConsistentRead return an item's
+	// previous version until the delay has passed since it was written.
+	DynamoDBRead time.Duration
+
+	// IAMPropagation makes STS reject AssumeRole on roles created less
+	// than the delay ago. Role ARNs unknown to the IAM mock are still
+	// accepted.
+	IAMPropagation time.Duration
+}
+
+// applyConsistency configures the registered services to simulate c.
+func (m *MockServer) applyConsistency(c Consistency) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if svc, ok := m.services["s3"].(interface{ SetListDelay(time.Duration) }); ok {
+		svc.SetListDelay(c.S3List)
+	}
+	if svc, ok := m.services["dynamodb"].(interface{ SetStaleReadDelay(time.Duration) }); ok {
+		svc.SetStaleReadDelay(c.DynamoDBRead)
+	}
+	if c.IAMPropagation <= 0 {
+		return
+	}
+	sts, ok := m.services["sts"].(interface {
+		SetRoleCheck(func(roleArn string) error)
+	})
+	if !ok {
+		return
+	}
+	sts.SetRoleCheck(func(roleArn string) error {
+		m.mu.RLock()
+		iam, ok := m.services["iam"].(interface {
+			RoleCreated(roleArn string) (time.Time, bool)
+		})
+		m.mu.RUnlock()
+		if !ok {
+			return nil
+		}
+		created, ok := iam.RoleCreated(roleArn)
+		if ok && time.Since(created) < c.IAMPropagation {
+			return fmt.Errorf("not authorized to perform: sts:AssumeRole on resource: %s", roleArn)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,404 @@
+package awsmock
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/riyanimam/goto/services/cloudwatchlogs"
+	"github.com/riyanimam/goto/services/dynamodb"
+	"github.com/riyanimam/goto/services/s3"
+	"github.com/riyanimam/goto/services/sqs"
+)
+
+// dashboardPrefix is where the mock serves its web dashboard once
+// [MockServer.EnableDashboard] is called.
+const dashboardPrefix = "/_dashboard/"
+
+// maxDashboardCalls is how many recent calls the dashboard keeps.
+const maxDashboardCalls = 200
+
+// maxPreview is how much of an object body or message the dashboard shows.
+const maxPreview = 64 << 10
+
+// dashboardCall is a call as the dashboard lists it.
+type dashboardCall struct {
+	Time time.Time
+	Call
+}
+
+// dashboard keeps the recent calls the dashboard lists, oldest first.
+type dashboard struct {
+	mu    sync.Mutex
+	calls []dashboardCall
+}
+
+func (d *dashboard) record(t time.Time, c Call) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if len(d.calls) == maxDashboardCalls {
+		d.calls = append(d.calls[:0], d.calls[1:]...)
+	}
+	d.calls = append(d.calls, dashboardCall{Time: t, Call: c})
+}
+
+// recent returns the recorded calls, newest first.
+func (d *dashboard) recent() []dashboardCall {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	out := make([]dashboardCall, len(d.calls))
+	for i, c := range d.calls {
+		out[len(out)-1-i] = c
+	}
+	return out
+}
+
+// EnableDashboard turns on a web dashboard for inspecting the mock while
+// debugging a test, and returns its URL. It shows the S3 buckets and
+// objects, SQS queues and messages, DynamoDB tables and items, CloudWatch
+// Logs events, and the most recent API calls, which are recorded from the
+// time the dashboard is enabled. The dashboard only reads state: viewing it
+// does not receive messages or change anything else.
+func (m *MockServer) EnableDashboard() string {
+	m.mu.Lock()
+	if m.dashboard == nil {
+		m.dashboard = &dashboard{}
+	}
+	m.mu.Unlock()
+	return m.URL() + dashboardPrefix
+}
+
+// serveDashboard handles the dashboard pages:
+//
+//	/_dashboard/                  buckets, queues, tables, log groups, and
+//	                              recent calls
+//	/_dashboard/s3/{bucket}       the bucket's objects; ?key= shows one
+//	/_dashboard/sqs/{queue}       the queue's messages
+//	/_dashboard/dynamodb/{table}  the table's items
+//	/_dashboard/logs/{group}      the log group's events
+func (m *MockServer) serveDashboard(w http.ResponseWriter, r *http.Request) {
+	m.mu.RLock()
+	d := m.dashboard
+	m.mu.RUnlock()
+	if d == nil {
+		http.Error(w, "the dashboard is not enabled", http.StatusNotFound)
+		return
+	}
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Resource names are taken from the escaped path, so that log group
+	// names, which contain slashes, survive as one segment.
+	rest := strings.TrimPrefix(r.URL.EscapedPath(), dashboardPrefix)
+	if rest == "" {
+		m.renderDashboard(w, "overview", m.dashboardOverview(d))
+		return
+	}
+	section, escaped, _ := strings.Cut(rest, "/")
+	name, err := url.PathUnescape(escaped)
+	if err != nil || name == "" {
+		http.NotFound(w, r)
+		return
+	}
+
+	var page interface{}
+	switch section {
+	case "s3":
+		page, err = m.dashboardBucket(name, r.URL.Query().Get("key"))
+	case "sqs":
+		page, err = m.dashboardQueue(name)
+	case "dynamodb":
+		page, err = m.dashboardTable(name)
+	case "logs":
+		page, err = m.dashboardLogGroup(name)
+	default:
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	m.renderDashboard(w, section, page)
+}
+
+func (m *MockServer) renderDashboard(w http.ResponseWriter, name string, page interface{}) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := dashboardTemplates.ExecuteTemplate(w, name, page); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+type dashboardLink struct {
+	Name   string
+	URL    string
+	Detail string
+}
+
+type overviewPage struct {
+	Now       time.Time
+	Buckets   []dashboardLink
+	Queues    []dashboardLink
+	Tables    []dashboardLink
+	LogGroups []dashboardLink
+	Calls     []dashboardCall
+}
+
+func (m *MockServer) dashboardOverview(d *dashboard) overviewPage {
+	page := overviewPage{Now: m.Now(), Calls: d.recent()}
+	for _, r := range m.listResources("s3") {
+		if r.Type == "aws_s3_bucket" {
+			page.Buckets = append(page.Buckets, dashboardLink{Name: r.ID, URL: dashboardURL("s3", r.ID)})
+		}
+	}
+	if svc, err := lookup[*sqs.Service](m, "sqs"); err == nil {
+		for _, queueURL := range svc.Queues() {
+			name := path.Base(queueURL)
+			link := dashboardLink{Name: name, URL: dashboardURL("sqs", name)}
+			if msgs, err := svc.Messages(queueURL); err == nil {
+				link.Detail = pluralize(len(msgs), "message")
+			}
+			page.Queues = append(page.Queues, link)
+		}
+	}
+	for _, r := range m.listResources("dynamodb") {
+		link := dashboardLink{Name: r.ID, URL: dashboardURL("dynamodb", r.ID)}
+		if n, ok := r.Attributes["item_count"].(int); ok {
+			link.Detail = pluralize(n, "item")
+		}
+		page.Tables = append(page.Tables, link)
+	}
+	for _, r := range m.listResources("logs") {
+		if r.Type == "aws_cloudwatch_log_group" {
+			page.LogGroups = append(page.LogGroups, dashboardLink{Name: r.ID, URL: dashboardURL("logs", r.ID)})
+		}
+	}
+	for _, links := range [][]dashboardLink{page.Buckets, page.Tables, page.LogGroups} {
+		sort.Slice(links, func(i, j int) bool { return links[i].Name < links[j].Name })
+	}
+	return page
+}
+
+type bucketPage struct {
+	Bucket  string
+	Objects []dashboardObject
+	// Object is the object named by the key query parameter, if any.
+	Object  *dashboardObject
+	Preview string
+}
+
+type dashboardObject struct {
+	Key          string
+	URL          string
+	Size         int
+	ContentType  string
+	LastModified time.Time
+	Metadata     map[string]string
+}
+
+func (m *MockServer) dashboardBucket(bucket, key string) (bucketPage, error) {
+	svc, err := lookup[*s3.Service](m, "s3")
+	if err != nil {
+		return bucketPage{}, err
+	}
+	keys, err := svc.ListObjects(bucket, "")
+	if err != nil {
+		return bucketPage{}, err
+	}
+	sort.Strings(keys)
+
+	page := bucketPage{Bucket: bucket}
+	for _, k := range keys {
+		obj, err := svc.Object(bucket, k)
+		if err != nil {
+			continue // deleted since it was listed
+		}
+		o := dashboardObject{
+			Key:          k,
+			URL:          dashboardURL("s3", bucket) + "?key=" + url.QueryEscape(k),
+			Size:         len(obj.Body),
+			ContentType:  obj.ContentType,
+			LastModified: obj.LastModified,
+			Metadata:     obj.Metadata,
+		}
+		page.Objects = append(page.Objects, o)
+		if k == key {
+			page.Object = &o
+			page.Preview = preview(obj.Body)
+		}
+	}
+	if key != "" && page.Object == nil {
+		return bucketPage{}, s3.ErrNoSuchKey
+	}
+	return page, nil
+}
+
+type queuePage struct {
+	Queue    string
+	URL      string
+	Messages []dashboardMessage
+}
+
+type dashboardMessage struct {
+	sqs.Message
+	Preview string
+}
+
+func (m *MockServer) dashboardQueue(name string) (queuePage, error) {
+	svc, err := lookup[*sqs.Service](m, "sqs")
+	if err != nil {
+		return queuePage{}, err
+	}
+	for _, queueURL := range svc.Queues() {
+		if path.Base(queueURL) != name {
+			continue
+		}
+		msgs, err := svc.Messages(queueURL)
+		if err != nil {
+			return queuePage{}, err
+		}
+		page := queuePage{Queue: name, URL: queueURL}
+		for _, msg := range msgs {
+			page.Messages = append(page.Messages, dashboardMessage{Message: msg, Preview: preview([]byte(msg.Body))})
+		}
+		return page, nil
+	}
+	return queuePage{}, fmt.Errorf("queue %s does not exist", name)
+}
+
+type tablePage struct {
+	Table string
+	Items []string
+}
+
+func (m *MockServer) dashboardTable(name string) (tablePage, error) {
+	svc, err := lookup[*dynamodb.Service](m, "dynamodb")
+	if err != nil {
+		return tablePage{}, err
+	}
+	items, err := svc.Items(name)
+	if err != nil {
+		return tablePage{}, err
+	}
+	page := tablePage{Table: name}
+	for _, item := range items {
+		data, err := json.MarshalIndent(item, "", "  ")
+		if err != nil {
+			return tablePage{}, err
+		}
+		page.Items = append(page.Items, string(data))
+	}
+	return page, nil
+}
+
+type logGroupPage struct {
+	Group  string
+	Events []cloudwatchlogs.Event
+}
+
+func (m *MockServer) dashboardLogGroup(name string) (logGroupPage, error) {
+	svc, err := lookup[*cloudwatchlogs.Service](m, "logs")
+	if err != nil {
+		return logGroupPage{}, err
+	}
+	events, err := svc.Events(name)
+	if err != nil {
+		return logGroupPage{}, err
+	}
+	return logGroupPage{Group: name, Events: events}, nil
+}
+
+// dashboardURL returns the path of a resource's dashboard page.
+func dashboardURL(section, name string) string {
+	return dashboardPrefix + section + "/" + url.PathEscape(name)
+}
+
+// preview returns body as text for display, truncated to maxPreview, or a
+// note that it is binary.
+func preview(body []byte) string {
+	if bytes.IndexByte(body, 0) >= 0 {
+		return fmt.Sprintf("(%d bytes of binary content)", len(body))
+	}
+	if len(body) > maxPreview {
+		return strings.ToValidUTF8(string(body[:maxPreview]), "") + "\n… (truncated)"
+	}
+	return strings.ToValidUTF8(string(body), "\uFFFD")
+}
+
+func pluralize(n int, noun string) string {
+	if n == 1 {
+		return "1 " + noun
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
+
+var dashboardTemplates = template.Must(template.New("").Funcs(template.FuncMap{
+	"time": func(t time.Time) string { return t.Format("2006-01-02 15:04:05.000") },
+}).Parse(`
+{{define "head"}}<!DOCTYPE html>
+<html><head><meta charset="utf-8"><title>{{.}} · awsmock</title>
+<style>
+body { font-family: system-ui, sans-serif; margin: 2em; color: #222; }
+h1 a { color: inherit; text-decoration: none; }
+table { border-collapse: collapse; margin-bottom: 2em; }
+th, td { text-align: left; padding: .3em .8em; border-bottom: 1px solid #ddd; vertical-align: top; }
+pre { background: #f6f6f6; padding: .6em; margin: 0; white-space: pre-wrap; word-break: break-all; }
+.error { color: #b00; }
+.empty { color: #888; }
+</style></head><body>
+<h1><a href="/_dashboard/">awsmock</a></h1>{{end}}
+
+{{define "links"}}{{if .}}<ul>{{range .}}<li><a href="{{.URL}}">{{.Name}}</a>{{with .Detail}} ({{.}}){{end}}</li>{{end}}</ul>{{else}}<p class="empty">None.</p>{{end}}{{end}}
+
+{{define "overview"}}{{template "head" "Dashboard"}}
+<p>Mock clock: {{time .Now}}</p>
+<h2>S3 buckets</h2>{{template "links" .Buckets}}
+<h2>SQS queues</h2>{{template "links" .Queues}}
+<h2>DynamoDB tables</h2>{{template "links" .Tables}}
+<h2>CloudWatch Logs groups</h2>{{template "links" .LogGroups}}
+<h2>Recent calls</h2>
+{{if .Calls}}<table><tr><th>Time</th><th>Service</th><th>Action</th><th>Status</th><th>Duration</th></tr>
+{{range .Calls}}<tr><td>{{time .Time}}</td><td>{{.Service}}</td><td>{{.Action}}</td><td{{if ge .Status 400}} class="error"{{end}}>{{.Status}}</td><td>{{.Duration}}</td></tr>
+{{end}}</table>{{else}}<p class="empty">None.</p>{{end}}
+</body></html>{{end}}
+
+{{define "s3"}}{{template "head" .Bucket}}
+<h2>Bucket {{.Bucket}}</h2>
+{{if .Objects}}<table><tr><th>Key</th><th>Size</th><th>Content type</th><th>Last modified</th></tr>
+{{range .Objects}}<tr><td><a href="{{.URL}}">{{.Key}}</a></td><td>{{.Size}}</td><td>{{.ContentType}}</td><td>{{time .LastModified}}</td></tr>
+{{end}}</table>{{else}}<p class="empty">No objects.</p>{{end}}
+{{with .Object}}<h2>Object {{.Key}}</h2>
+{{if .Metadata}}<table>{{range $k, $v := .Metadata}}<tr><th>{{$k}}</th><td>{{$v}}</td></tr>{{end}}</table>{{end}}
+<pre>{{$.Preview}}</pre>{{end}}
+</body></html>{{end}}
+
+{{define "sqs"}}{{template "head" .Queue}}
+<h2>Queue {{.Queue}}</h2>
+<p>{{.URL}}</p>
+{{if .Messages}}<table><tr><th>Message ID</th><th>Sent</th><th>State</th><th>Receives</th><th>Body</th></tr>
+{{range .Messages}}<tr><td>{{.MessageID}}</td><td>{{time .SentTimestamp}}</td><td>{{if .InFlight}}in flight{{else if .Delayed}}delayed{{else}}visible{{end}}</td><td>{{.ReceiveCount}}</td><td><pre>{{.Preview}}</pre></td></tr>
+{{end}}</table>{{else}}<p class="empty">No messages.</p>{{end}}
+</body></html>{{end}}
+
+{{define "dynamodb"}}{{template "head" .Table}}
+<h2>Table {{.Table}}</h2>
+{{if .Items}}{{range .Items}}<pre>{{.}}</pre><br>{{end}}{{else}}<p class="empty">No items.</p>{{end}}
+</body></html>{{end}}
+
+{{define "logs"}}{{template "head" .Group}}
+<h2>Log group {{.Group}}</h2>
+{{if .Events}}<table><tr><th>Timestamp</th><th>Stream</th><th>Message</th></tr>
+{{range .Events}}<tr><td>{{time .Timestamp}}</td><td>{{.Stream}}</td><td><pre>{{.Message}}</pre></td></tr>
+{{end}}</table>{{else}}<p class="empty">No events.</p>{{end}}
+</body></html>{{end}}
+`))
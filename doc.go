@@ -74,7 +74,7 @@
 //   - X-Ray
 //   - OpenSearch
 //   - Service Discovery (Cloud Map)
-//   - Transfer Family (SFTP/FTPS/FTP)
+//   - Transfer Family
 //   - Application Auto Scaling
 //   - Resource Groups Tagging API
 //   - SSO Admin (IAM Identity Center)
@@ -85,6 +85,7 @@
 //   - Amazon MQ (Message Broker)
 //   - DAX (DynamoDB Accelerator)
 //   - FSx (Managed File Systems)
+//   - App Runner
 //
 // Additional services can be added by implementing the [Service] interface.
 package awsmock
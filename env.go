@@ -0,0 +1,189 @@
+package awsmock
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// OptionsFromEnv returns the options set by AWSMOCK_* environment variables,
+// so that a server started with [Listen], as in the awsmock container, can be
+// configured without code:
+//
+//	AWSMOCK_PUBLIC_URL              WithPublicURL
+//	AWSMOCK_ASYNC_DELAY=2s          WithAsyncStates
+//	AWSMOCK_GLOBAL_STS=true         WithGlobalSTSEndpoint
+//	AWSMOCK_CLOUDFRONT_CONTENT=true WithCloudFrontContent
+//	AWSMOCK_METRICS=true            WithMetrics
+//	AWSMOCK_OPENSEARCH_PROXY=url    WithOpenSearchProxy
+//	AWSMOCK_PROVISIONED_THROUGHPUT  WithProvisionedThroughput
+//	AWSMOCK_S3_SPILL_THRESHOLD=n    WithS3Spill, with AWSMOCK_S3_SPILL_DIR
+//	AWSMOCK_S3_DIRS=bucket=/data/bucket
+//	                                WithS3Dir(bucket, dir)
+//	AWSMOCK_RATE_LIMITS=sqs=10:20,dynamodb=5:5
+//	                                WithRateLimit(service, rate, burst)
+//	AWSMOCK_QUOTAS=s3/buckets=100   WithQuota(service, resource, limit)
+//	AWSMOCK_LATENCY=dynamodb:Query=50ms,*=5ms-20ms
+//	                                WithLatencyProfile, fixed or uniform
+//	AWSMOCK_S3_LIST_DELAY, AWSMOCK_DYNAMODB_READ_DELAY,
+//	AWSMOCK_IAM_PROPAGATION_DELAY   WithEventualConsistency
+//
+// Unset variables leave the defaults. A malformed value is an error.
+func OptionsFromEnv() ([]Option, error) {
+	var opts []Option
+	var err error
+	fail := func(name string, e error) {
+		if err == nil {
+			err = fmt.Errorf("%s: %w", name, e)
+		}
+	}
+
+	if v := os.Getenv("AWSMOCK_PUBLIC_URL"); v != "" {
+		opts = append(opts, WithPublicURL(v))
+	}
+	if v := os.Getenv("AWSMOCK_OPENSEARCH_PROXY"); v != "" {
+		opts = append(opts, WithOpenSearchProxy(v))
+	}
+	if d, ok, e := envDuration("AWSMOCK_ASYNC_DELAY"); e != nil {
+		fail("AWSMOCK_ASYNC_DELAY", e)
+	} else if ok {
+		opts = append(opts, WithAsyncStates(d))
+	}
+	for name, opt := range map[string]Option{
+		"AWSMOCK_GLOBAL_STS":             WithGlobalSTSEndpoint(),
+		"AWSMOCK_CLOUDFRONT_CONTENT":     WithCloudFrontContent(),
+		"AWSMOCK_METRICS":                WithMetrics(),
+		"AWSMOCK_PROVISIONED_THROUGHPUT": WithProvisionedThroughput(),
+	} {
+		if on, e := envBool(name); e != nil {
+			fail(name, e)
+		} else if on {
+			opts = append(opts, opt)
+		}
+	}
+	if v := os.Getenv("AWSMOCK_S3_SPILL_THRESHOLD"); v != "" {
+		threshold, e := strconv.ParseInt(v, 10, 64)
+		if e != nil {
+			fail("AWSMOCK_S3_SPILL_THRESHOLD", e)
+		}
+		opts = append(opts, WithS3Spill(threshold, os.Getenv("AWSMOCK_S3_SPILL_DIR")))
+	}
+
+	for _, entry := range envList("AWSMOCK_S3_DIRS") {
+		bucket, dir, _ := strings.Cut(entry, "=")
+		if bucket == "" || dir == "" {
+			fail("AWSMOCK_S3_DIRS", fmt.Errorf("%q is not bucket=dir", entry))
+			continue
+		}
+		opts = append(opts, WithS3Dir(bucket, dir))
+	}
+
+	var c Consistency
+	var consistent bool
+	for name, d := range map[string]*time.Duration{
+		"AWSMOCK_S3_LIST_DELAY":         &c.S3List,
+		"AWSMOCK_DYNAMODB_READ_DELAY":   &c.DynamoDBRead,
+		"AWSMOCK_IAM_PROPAGATION_DELAY": &c.IAMPropagation,
+	} {
+		v, ok, e := envDuration(name)
+		if e != nil {
+			fail(name, e)
+		}
+		*d, consistent = v, consistent || ok
+	}
+	if consistent {
+		opts = append(opts, WithEventualConsistency(c))
+	}
+
+	for _, entry := range envList("AWSMOCK_RATE_LIMITS") {
+		service, limit, _ := strings.Cut(entry, "=")
+		rate, burst, _ := strings.Cut(limit, ":")
+		r, e1 := strconv.ParseFloat(rate, 64)
+		b, e2 := strconv.Atoi(burst)
+		if service == "" || e1 != nil || e2 != nil {
+			fail("AWSMOCK_RATE_LIMITS", fmt.Errorf("%q is not service=rate:burst", entry))
+			continue
+		}
+		opts = append(opts, WithRateLimit(service, r, b))
+	}
+	for _, entry := range envList("AWSMOCK_QUOTAS") {
+		key, limit, _ := strings.Cut(entry, "=")
+		service, resource, _ := strings.Cut(key, "/")
+		n, e := strconv.Atoi(limit)
+		if service == "" || resource == "" || e != nil {
+			fail("AWSMOCK_QUOTAS", fmt.Errorf("%q is not service/resource=limit", entry))
+			continue
+		}
+		opts = append(opts, WithQuota(service, resource, n))
+	}
+	if entries := envList("AWSMOCK_LATENCY"); len(entries) > 0 {
+		profile := make(LatencyProfile, len(entries))
+		for _, entry := range entries {
+			i := strings.LastIndex(entry, "=")
+			if i <= 0 {
+				fail("AWSMOCK_LATENCY", fmt.Errorf("%q is not pattern=latency", entry))
+				continue
+			}
+			lat, e := parseLatency(entry[i+1:])
+			if e != nil {
+				fail("AWSMOCK_LATENCY", e)
+				continue
+			}
+			profile[entry[:i]] = lat
+		}
+		opts = append(opts, WithLatencyProfile(profile))
+	}
+
+	if err != nil {
+		return nil, err
+	}
+	return opts, nil
+}
+
+// parseLatency parses a fixed latency ("50ms") or a uniform range
+// ("5ms-20ms").
+func parseLatency(s string) (Latency, error) {
+	lo, hi, isRange := strings.Cut(s, "-")
+	min, err := time.ParseDuration(lo)
+	if err != nil {
+		return nil, err
+	}
+	if !isRange {
+		return FixedLatency(min), nil
+	}
+	max, err := time.ParseDuration(hi)
+	if err != nil {
+		return nil, err
+	}
+	return UniformLatency(min, max), nil
+}
+
+func envDuration(name string) (time.Duration, bool, error) {
+	v := os.Getenv(name)
+	if v == "" {
+		return 0, false, nil
+	}
+	d, err := time.ParseDuration(v)
+	return d, err == nil, err
+}
+
+func envBool(name string) (bool, error) {
+	v := os.Getenv(name)
+	if v == "" {
+		return false, nil
+	}
+	return strconv.ParseBool(v)
+}
+
+// envList splits a comma-separated variable, dropping empty entries.
+func envList(name string) []string {
+	var out []string
+	for _, s := range strings.Split(os.Getenv(name), ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			out = append(out, s)
+		}
+	}
+	return out
+}
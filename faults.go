@@ -0,0 +1,124 @@
+package awsmock
+
+import (
+	"fmt"
+	"net/http"
+	"path"
+
+	"github.com/riyanimam/goto/internal/arn"
+)
+
+// Fault makes matching requests fail with an error the mock would not
+// otherwise return, so that retries and error handling can be exercised.
+type Fault struct {
+	// Service is the service whose requests fail, such as "dynamodb". It may
+	// use [path.Match] wildcards; "" or "*" matches every service.
+	Service string `json:"service,omitempty"`
+	// Action is the API action that fails, such as "PutItem", and may also
+	// use wildcards; "" matches every action. As with [LatencyProfile],
+	// actions are known for JSON and query protocol requests only.
+	Action string `json:"action,omitempty"`
+	// Code is the error code, "InternalFailure" by default.
+	Code string `json:"code,omitempty"`
+	// Message is the error message.
+	Message string `json:"message,omitempty"`
+	// Status is the HTTP status of the response, 500 by default.
+	Status int `json:"status,omitempty"`
+	// Count is the number of requests that fail before the fault clears
+	// itself. Zero fails requests until the fault is cleared.
+	Count int `json:"count,omitempty"`
+}
+
+// InjectFault makes requests matching f fail until f has failed f.Count
+// requests or [MockServer.ClearFaults] is called. When several faults match a
+// request, the one injected first applies.
+func (m *MockServer) InjectFault(f Fault) {
+	if f.Code == "" {
+		f.Code = "InternalFailure"
+	}
+	if f.Message == "" {
+		f.Message = "Fault injected by the mock"
+	}
+	if f.Status == 0 {
+		f.Status = http.StatusInternalServerError
+	}
+
+	m.faultsMu.Lock()
+	defer m.faultsMu.Unlock()
+	m.faults = append(m.faults, f)
+}
+
+// Faults returns the injected faults that are still active, with the number
+// of requests each has left to fail.
+func (m *MockServer) Faults() []Fault {
+	m.faultsMu.Lock()
+	defer m.faultsMu.Unlock()
+	return append([]Fault{}, m.faults...)
+}
+
+// ClearFaults removes every injected fault. [MockServer.Reset] leaves faults
+// in place.
+func (m *MockServer) ClearFaults() {
+	m.faultsMu.Lock()
+	defer m.faultsMu.Unlock()
+	m.faults = nil
+}
+
+// injectedFault returns the fault a request to service should fail with,
+// counting the request against it.
+func (m *MockServer) injectedFault(r *http.Request, service string) (Fault, bool) {
+	m.faultsMu.Lock()
+	defer m.faultsMu.Unlock()
+
+	action, actionKnown := "", false
+	for i, f := range m.faults {
+		if f.Service != "" {
+			if ok, _ := path.Match(f.Service, service); !ok {
+				continue
+			}
+		}
+		if f.Action != "" {
+			if !actionKnown {
+				action, actionKnown = requestAction(r), true
+			}
+			if ok, _ := path.Match(f.Action, action); !ok || action == "" {
+				continue
+			}
+		}
+		if f.Count > 0 {
+			m.faults[i].Count--
+			if m.faults[i].Count == 0 {
+				m.faults = append(m.faults[:i:i], m.faults[i+1:]...)
+			}
+		}
+		return f, true
+	}
+	return Fault{}, false
+}
+
+// statusSetter is implemented by services that let tests put resources into
+// a status they would otherwise only reach on their own, such as an RDS
+// instance in its maintenance window.
+type statusSetter interface {
+	SetStatus(arn, status string) error
+}
+
+// SetResourceStatus puts the resource arn names into status, such as
+// "backing-up", "maintenance", or "storage-full" for an RDS instance or
+// Redshift cluster, until it is set back to "available". Requests that
+// modify or delete the resource meanwhile fail as AWS fails them. An SSM
+// hybrid managed instance can be set to "ConnectionLost" or "Inactive", and
+// back to "Online".
+func (m *MockServer) SetResourceStatus(resource, status string) error {
+	a, err := arn.Parse(resource)
+	if err != nil {
+		return err
+	}
+	m.mu.RLock()
+	svc, ok := m.services[ownerOf(a)].(statusSetter)
+	m.mu.RUnlock()
+	if !ok {
+		return fmt.Errorf("%s service does not support setting resource statuses", a.Service)
+	}
+	return svc.SetStatus(resource, status)
+}
@@ -0,0 +1,121 @@
+package awsmock
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+)
+
+// HTTPClient returns an HTTP client that connects requests for localhost and
+// any of its subdomains to the mock server without a DNS lookup. It lets
+// virtual-hosted-style S3 URLs (bucket.localhost), S3 website endpoints
+// (bucket.s3-website.localhost), and host-addressed API Gateway endpoints
+// ({apiId}.execute-api.localhost) reach the mock on systems that do not
+// resolve *.localhost. Other hosts are dialed normally.
+//
+// Configs from [MockServer.AWSConfig] already use this client.
+func (m *MockServer) HTTPClient() *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = m.dialContext
+	return &http.Client{Transport: transport}
+}
+
+// Endpoint returns the base URL of the mock server with localhost as the
+// host, so SDK clients can build virtual-hosted-style URLs from it. Use it
+// with [MockServer.HTTPClient].
+func (m *MockServer) Endpoint() string {
+	_, port, _ := net.SplitHostPort(m.server.Listener.Addr().String())
+	return "http://localhost:" + port
+}
+
+// legacySTSRegions are the regions whose STS clients use the global endpoint
+// when configured for legacy endpoint resolution.
+var legacySTSRegions = map[string]bool{
+	"ap-northeast-1": true,
+	"ap-south-1":     true,
+	"ap-southeast-1": true,
+	"ap-southeast-2": true,
+	"ca-central-1":   true,
+	"eu-central-1":   true,
+	"eu-north-1":     true,
+	"eu-west-1":      true,
+	"eu-west-2":      true,
+	"eu-west-3":      true,
+	"sa-east-1":      true,
+	"us-east-1":      true,
+	"us-east-2":      true,
+	"us-west-1":      true,
+	"us-west-2":      true,
+}
+
+// stsResolver resolves STS clients to the global endpoint (sts.localhost),
+// signing for us-east-1, or to their region's endpoint
+// (sts.{region}.localhost), as [WithGlobalSTSEndpoint] selects. The
+// "aws-global" pseudo-region always uses the global endpoint. Other services
+// fall back to the config's BaseEndpoint.
+//
+//nolint:staticcheck // The global resolver types are deprecated but still honored.
+func (m *MockServer) stsResolver() aws.EndpointResolverWithOptionsFunc {
+	return func(service, region string, _ ...interface{}) (aws.Endpoint, error) {
+		if service != "STS" {
+			return aws.Endpoint{}, &aws.EndpointNotFoundError{}
+		}
+		_, port, _ := net.SplitHostPort(m.server.Listener.Addr().String())
+		if region == "aws-global" || m.stsGlobal && legacySTSRegions[region] {
+			return aws.Endpoint{
+				URL:           "http://sts.localhost:" + port,
+				SigningRegion: "us-east-1",
+				Source:        aws.EndpointSourceCustom,
+			}, nil
+		}
+		return aws.Endpoint{
+			URL:           "http://sts." + region + ".localhost:" + port,
+			SigningRegion: region,
+			Source:        aws.EndpointSourceCustom,
+		}, nil
+	}
+}
+
+// dialContext dials the mock server for localhost addresses and falls back
+// to a normal dial for everything else.
+func (m *MockServer) dialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	return dialLocalhost(ctx, network, addr, m.server.Listener.Addr().String())
+}
+
+// dialLocalhost dials target for localhost addresses and addr otherwise.
+func dialLocalhost(ctx context.Context, network, addr, target string) (net.Conn, error) {
+	var d net.Dialer
+	host, _, err := net.SplitHostPort(addr)
+	if err == nil && (host == "localhost" || strings.HasSuffix(host, ".localhost")) {
+		return d.DialContext(ctx, "tcp", target)
+	}
+	return d.DialContext(ctx, network, addr)
+}
+
+// isInstanceMetadataPath reports whether path is served by the instance
+// metadata service.
+func isInstanceMetadataPath(path string) bool {
+	return path == "/latest/api/token" ||
+		strings.HasPrefix(path, "/latest/meta-data/") ||
+		strings.HasPrefix(path, "/latest/dynamic/")
+}
+
+// isCloudFrontHost reports whether host addresses a CloudFront distribution,
+// as in "e1a2b3c4.cloudfront.localhost" or "e1a2b3c4.cloudfront.net".
+func isCloudFrontHost(host string) bool {
+	if hostname, _, err := net.SplitHostPort(host); err == nil {
+		host = hostname
+	}
+	_, rest, ok := strings.Cut(host, ".")
+	return ok && (rest == "cloudfront.localhost" || rest == "cloudfront.net")
+}
+
+// isExecuteAPIHost reports whether host addresses an API Gateway endpoint
+// by API ID, as in "a1b2c3.execute-api.localhost".
+func isExecuteAPIHost(host string) bool {
+	_, rest, ok := strings.Cut(host, ".")
+	return ok && strings.HasPrefix(rest, "execute-api.")
+}
@@ -0,0 +1,611 @@
+package awsmock
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/riyanimam/goto/services/accessanalyzer"
+	"github.com/riyanimam/goto/services/appflow"
+	"github.com/riyanimam/goto/services/athena"
+	"github.com/riyanimam/goto/services/bedrock"
+	"github.com/riyanimam/goto/services/budgets"
+	"github.com/riyanimam/goto/services/cloudwatch"
+	"github.com/riyanimam/goto/services/codepipeline"
+	"github.com/riyanimam/goto/services/costexplorer"
+	"github.com/riyanimam/goto/services/ec2"
+	"github.com/riyanimam/goto/services/efs"
+	"github.com/riyanimam/goto/services/firehose"
+	"github.com/riyanimam/goto/services/glue"
+	"github.com/riyanimam/goto/services/imds"
+	"github.com/riyanimam/goto/services/inspector2"
+	"github.com/riyanimam/goto/services/lambda"
+	"github.com/riyanimam/goto/services/pipes"
+	"github.com/riyanimam/goto/services/rekognition"
+	"github.com/riyanimam/goto/services/s3"
+	"github.com/riyanimam/goto/services/sagemaker"
+	"github.com/riyanimam/goto/services/scheduler"
+	"github.com/riyanimam/goto/services/sns"
+	"github.com/riyanimam/goto/services/sqs"
+	"github.com/riyanimam/goto/services/ssm"
+	"github.com/riyanimam/goto/services/stepfunctions"
+	"github.com/riyanimam/goto/services/synthetics"
+	"github.com/riyanimam/goto/services/textract"
+	"github.com/riyanimam/goto/services/transfer"
+	"github.com/riyanimam/goto/services/wafv2"
+)
+
+// SQSInspector reads SQS mock state directly, without going through the API.
+type SQSInspector struct{ m *MockServer }
+
+// SNSInspector reads SNS mock state directly, without going through the API.
+type SNSInspector struct{ m *MockServer }
+
+// LambdaInspector reads Lambda mock state directly, without going through
+// the API, and sets the Go handlers functions run.
+type LambdaInspector struct{ m *MockServer }
+
+// S3Inspector reads S3 mock state directly, without going through the API.
+type S3Inspector struct{ m *MockServer }
+
+// EFSInspector reads and writes the files in EFS mock file systems, which
+// have no NFS endpoint to mount.
+type EFSInspector struct{ m *MockServer }
+
+// FirehoseInspector reads Firehose mock state directly, without going
+// through the API.
+type FirehoseInspector struct{ m *MockServer }
+
+// WAFv2Inspector runs requests through WAFv2 mock web ACLs, without going
+// through the API.
+type WAFv2Inspector struct{ m *MockServer }
+
+// SchedulerInspector fires EventBridge Scheduler mock schedules directly,
+// without waiting for the mock clock.
+type SchedulerInspector struct{ m *MockServer }
+
+// TransferInspector opens file sessions on Transfer Family mock servers,
+// which have no SFTP endpoint to connect to.
+type TransferInspector struct{ m *MockServer }
+
+// StepFunctionsInspector completes Step Functions mock executions, which do
+// not run their state machines.
+type StepFunctionsInspector struct{ m *MockServer }
+
+// SyntheticsInspector sets the outcome of CloudWatch Synthetics mock canary
+// runs, whose scripts are not executed, and runs canaries on demand.
+type SyntheticsInspector struct{ m *MockServer }
+
+// EC2Inspector reports network traffic to the EC2 mock, which carries none
+// of its own.
+type EC2Inspector struct{ m *MockServer }
+
+// Inspector2Inspector injects the vulnerabilities Amazon Inspector mock
+// scans report, since nothing is actually scanned.
+type Inspector2Inspector struct{ m *MockServer }
+
+// CostExplorerInspector supplies the billing data the Cost Explorer mock
+// reports on.
+type CostExplorerInspector struct{ m *MockServer }
+
+// BudgetsInspector sets the spend AWS Budgets mock budgets track, which no
+// billing data feeds.
+type BudgetsInspector struct{ m *MockServer }
+
+// SageMakerInspector supplies the models behind SageMaker mock endpoints,
+// which run no containers.
+type SageMakerInspector struct{ m *MockServer }
+
+// BedrockInspector supplies the models that answer Bedrock mock
+// invocations, since none run.
+type BedrockInspector struct{ m *MockServer }
+
+// TextractInspector sets what the Textract mock finds in S3 objects, which
+// it does not read.
+type TextractInspector struct{ m *MockServer }
+
+// RekognitionInspector sets what the Rekognition mock finds in S3 objects,
+// which it does not read.
+type RekognitionInspector struct{ m *MockServer }
+
+// PipesInspector drives EventBridge Pipes mock pipes, which otherwise poll
+// only as the mock clock advances.
+type PipesInspector struct{ m *MockServer }
+
+// AppFlowInspector sets what AppFlow mock flows pull, since they connect to
+// no SaaS application.
+type AppFlowInspector struct{ m *MockServer }
+
+// AccessAnalyzerInspector records the activity IAM Access Analyzer mock
+// policy generation reads in place of CloudTrail events.
+type AccessAnalyzerInspector struct{ m *MockServer }
+
+// CodePipelineInspector overrides how CodePipeline mock actions run.
+type CodePipelineInspector struct{ m *MockServer }
+
+// GlueInspector sets how Glue mock interactive sessions run statements.
+type GlueInspector struct{ m *MockServer }
+
+// IMDSInspector sets the instance the instance metadata mock reports on.
+type IMDSInspector struct{ m *MockServer }
+
+// CloudWatchInspector records CloudWatch mock metrics directly, without
+// going through the API.
+type CloudWatchInspector struct{ m *MockServer }
+
+// AthenaInspector sets fixtures for Athena mock queries.
+type AthenaInspector struct{ m *MockServer }
+
+// SSMInspector registers hybrid machines with the SSM mock, as the SSM
+// Agent would.
+type SSMInspector struct{ m *MockServer }
+
+// SQS returns an inspector for the queues held by the SQS mock.
+func (m *MockServer) SQS() SQSInspector { return SQSInspector{m} }
+
+// SNS returns an inspector for the topics held by the SNS mock.
+func (m *MockServer) SNS() SNSInspector { return SNSInspector{m} }
+
+// Lambda returns an inspector for the functions held by the Lambda mock.
+func (m *MockServer) Lambda() LambdaInspector { return LambdaInspector{m} }
+
+// S3 returns an inspector for the buckets held by the S3 mock.
+func (m *MockServer) S3() S3Inspector { return S3Inspector{m} }
+
+// EFS returns an inspector for the file systems held by the EFS mock.
+func (m *MockServer) EFS() EFSInspector { return EFSInspector{m} }
+
+// Firehose returns an inspector for the delivery streams held by the
+// Firehose mock.
+func (m *MockServer) Firehose() FirehoseInspector { return FirehoseInspector{m} }
+
+// WAFv2 returns an inspector for the web ACLs held by the WAFv2 mock.
+func (m *MockServer) WAFv2() WAFv2Inspector { return WAFv2Inspector{m} }
+
+// Scheduler returns an inspector for the schedules held by the EventBridge
+// Scheduler mock.
+func (m *MockServer) Scheduler() SchedulerInspector { return SchedulerInspector{m} }
+
+// Transfer returns an inspector for the servers held by the Transfer Family
+// mock.
+func (m *MockServer) Transfer() TransferInspector { return TransferInspector{m} }
+
+// StepFunctions returns an inspector for the executions held by the Step
+// Functions mock.
+func (m *MockServer) StepFunctions() StepFunctionsInspector { return StepFunctionsInspector{m} }
+
+// Synthetics returns an inspector for the canaries held by the CloudWatch
+// Synthetics mock.
+func (m *MockServer) Synthetics() SyntheticsInspector { return SyntheticsInspector{m} }
+
+// EC2 returns an inspector for the networks held by the EC2 mock.
+func (m *MockServer) EC2() EC2Inspector { return EC2Inspector{m} }
+
+// Inspector2 returns an inspector for the findings held by the Amazon
+// Inspector mock.
+func (m *MockServer) Inspector2() Inspector2Inspector { return Inspector2Inspector{m} }
+
+// CostExplorer returns an inspector for the costs held by the Cost Explorer
+// mock.
+func (m *MockServer) CostExplorer() CostExplorerInspector { return CostExplorerInspector{m} }
+
+// Budgets returns an inspector for the budgets held by the AWS Budgets mock.
+func (m *MockServer) Budgets() BudgetsInspector { return BudgetsInspector{m} }
+
+// SageMaker returns an inspector for the endpoints held by the SageMaker
+// mock.
+func (m *MockServer) SageMaker() SageMakerInspector { return SageMakerInspector{m} }
+
+// Bedrock returns an inspector for the models of the Bedrock mock.
+func (m *MockServer) Bedrock() BedrockInspector { return BedrockInspector{m} }
+
+// Textract returns an inspector for the document fixtures of the Textract
+// mock.
+func (m *MockServer) Textract() TextractInspector { return TextractInspector{m} }
+
+// Rekognition returns an inspector for the image fixtures of the
+// Rekognition mock.
+func (m *MockServer) Rekognition() RekognitionInspector { return RekognitionInspector{m} }
+
+// Pipes returns an inspector for the pipes held by the EventBridge Pipes
+// mock.
+func (m *MockServer) Pipes() PipesInspector { return PipesInspector{m} }
+
+// AppFlow returns an inspector for the flows held by the AppFlow mock.
+func (m *MockServer) AppFlow() AppFlowInspector { return AppFlowInspector{m} }
+
+// AccessAnalyzer returns an inspector for the activity held by the IAM
+// Access Analyzer mock.
+func (m *MockServer) AccessAnalyzer() AccessAnalyzerInspector { return AccessAnalyzerInspector{m} }
+
+// CodePipeline returns an inspector for the actions of the CodePipeline
+// mock.
+func (m *MockServer) CodePipeline() CodePipelineInspector { return CodePipelineInspector{m} }
+
+// Glue returns an inspector for the interactive sessions of the Glue mock.
+func (m *MockServer) Glue() GlueInspector { return GlueInspector{m} }
+
+// IMDS returns an inspector for the instance metadata endpoints.
+func (m *MockServer) IMDS() IMDSInspector { return IMDSInspector{m} }
+
+// CloudWatch returns an inspector for the metrics of the CloudWatch mock.
+func (m *MockServer) CloudWatch() CloudWatchInspector { return CloudWatchInspector{m} }
+
+// Athena returns an inspector for the queries of the Athena mock.
+func (m *MockServer) Athena() AthenaInspector { return AthenaInspector{m} }
+
+// SSM returns an inspector for the managed instances of the SSM mock.
+func (m *MockServer) SSM() SSMInspector { return SSMInspector{m} }
+
+// Messages returns every message in the queue, including messages that have
+// been received but not yet deleted. Inspecting a queue does not affect
+// message visibility.
+func (i SQSInspector) Messages(queueURL string) ([]sqs.Message, error) {
+	svc, err := lookup[*sqs.Service](i.m, "sqs")
+	if err != nil {
+		return nil, err
+	}
+	return svc.Messages(queueURL)
+}
+
+// Published returns the messages published to the topic, whether through
+// Publish or delivered by another service.
+func (i SNSInspector) Published(topicArn string) ([]sns.Publication, error) {
+	svc, err := lookup[*sns.Service](i.m, "sns")
+	if err != nil {
+		return nil, err
+	}
+	return svc.Published(topicArn)
+}
+
+// Invocations returns the calls made to the function, identified by name or
+// ARN, whether through Invoke or delivered by another service.
+func (i LambdaInspector) Invocations(function string) ([]lambda.Invocation, error) {
+	svc, err := lookup[*lambda.Service](i.m, "lambda")
+	if err != nil {
+		return nil, err
+	}
+	return svc.Invocations(function)
+}
+
+// RegisterHandler makes invocations of the named function run fn, whether
+// they come through Invoke, an API Gateway route, or another service.
+// Functions without a handler echo their payload.
+func (i LambdaInspector) RegisterHandler(name string, fn lambda.HandlerFunc) error {
+	svc, err := lookup[*lambda.Service](i.m, "lambda")
+	if err != nil {
+		return err
+	}
+	svc.SetHandler(name, fn)
+	return nil
+}
+
+// Object returns the object stored under key, with its body and metadata.
+func (i S3Inspector) Object(bucket, key string) (s3.Object, error) {
+	svc, err := lookup[*s3.Service](i.m, "s3")
+	if err != nil {
+		return s3.Object{}, err
+	}
+	return svc.Object(bucket, key)
+}
+
+// File returns the contents of the file at the absolute path p.
+func (i EFSInspector) File(fileSystemID, p string) ([]byte, error) {
+	svc, err := lookup[*efs.Service](i.m, "elasticfilesystem")
+	if err != nil {
+		return nil, err
+	}
+	return svc.ReadFile(fileSystemID, p)
+}
+
+// WriteFile stores data as the file at the absolute path p, as a client
+// with the file system mounted would.
+func (i EFSInspector) WriteFile(fileSystemID, p string, data []byte) error {
+	svc, err := lookup[*efs.Service](i.m, "elasticfilesystem")
+	if err != nil {
+		return err
+	}
+	return svc.WriteFile(fileSystemID, p, data)
+}
+
+// Records returns the records the delivery stream has delivered, after any
+// transformation by its processing Lambda. Records the Lambda failed are
+// included and marked ProcessingFailed; records it dropped are not.
+func (i FirehoseInspector) Records(stream string) ([]firehose.Record, error) {
+	svc, err := lookup[*firehose.Service](i.m, "firehose")
+	if err != nil {
+		return nil, err
+	}
+	return svc.Records(stream)
+}
+
+// Evaluate runs req through the web ACL's rules and reports whether it is
+// allowed or blocked, and by which rule. Rate-based rules count the
+// requests evaluated so far.
+func (i WAFv2Inspector) Evaluate(webACLArn string, req *http.Request) (wafv2.Verdict, error) {
+	svc, err := lookup[*wafv2.Service](i.m, "wafv2")
+	if err != nil {
+		return wafv2.Verdict{}, err
+	}
+	return svc.Evaluate(webACLArn, req)
+}
+
+// Trigger immediately invokes the target of the named schedule. Use
+// "group/name" for schedules outside the default group.
+func (i SchedulerInspector) Trigger(name string) error {
+	svc, err := lookup[*scheduler.Service](i.m, "scheduler")
+	if err != nil {
+		return err
+	}
+	return svc.Trigger(name)
+}
+
+// Invocations returns every delivery schedules have made to their targets,
+// oldest first, with the attempts made so far.
+func (i SchedulerInspector) Invocations() ([]scheduler.Invocation, error) {
+	svc, err := lookup[*scheduler.Service](i.m, "scheduler")
+	if err != nil {
+		return nil, err
+	}
+	return svc.Invocations(), nil
+}
+
+// Login opens a file session on a server as userName, authenticating with
+// one of the user's imported SSH public keys. Files put through the session
+// land in the S3 mock under the user's home directory.
+func (i TransferInspector) Login(serverID, userName, publicKey string) (*transfer.Session, error) {
+	svc, err := lookup[*transfer.Service](i.m, "transfer")
+	if err != nil {
+		return nil, err
+	}
+	return svc.Login(serverID, userName, publicKey)
+}
+
+// Succeed completes the running execution identified by executionArn with
+// output, emitting its status change event to the EventBridge default bus.
+func (i StepFunctionsInspector) Succeed(executionArn, output string) error {
+	svc, err := lookup[*stepfunctions.Service](i.m, "states")
+	if err != nil {
+		return err
+	}
+	return svc.Succeed(executionArn, output)
+}
+
+// Fail fails the running execution identified by executionArn with the given
+// error name and cause, emitting its status change event to the EventBridge
+// default bus.
+func (i StepFunctionsInspector) Fail(executionArn, errName, cause string) error {
+	svc, err := lookup[*stepfunctions.Service](i.m, "states")
+	if err != nil {
+		return err
+	}
+	return svc.Fail(executionArn, errName, cause)
+}
+
+// SetResult sets the outcome of the named canary's subsequent runs. Failed
+// runs report reason as their StateReason.
+func (i SyntheticsInspector) SetResult(name string, passed bool, reason string) error {
+	svc, err := lookup[*synthetics.Service](i.m, "synthetics")
+	if err != nil {
+		return err
+	}
+	return svc.SetResult(name, passed, reason)
+}
+
+// Run runs the named canary now, as its schedule would. The canary must be
+// RUNNING.
+func (i SyntheticsInspector) Run(name string) error {
+	svc, err := lookup[*synthetics.Service](i.m, "synthetics")
+	if err != nil {
+		return err
+	}
+	return svc.Run(name)
+}
+
+// EmitFlowLogRecords records network traffic on the VPC, subnet, or network
+// interface with the given ID, writing it to the CloudWatch Logs groups and
+// S3 buckets of the resource's flow logs.
+func (i EC2Inspector) EmitFlowLogRecords(resourceID string, records ...ec2.FlowLogRecord) error {
+	svc, err := lookup[*ec2.Service](i.m, "ec2")
+	if err != nil {
+		return err
+	}
+	return svc.EmitFlowLogRecords(resourceID, records)
+}
+
+// InjectVulnerability reports v as a finding on a resource of the EC2, ECR,
+// or Lambda mock: an instance ID, an image as "repository:tag",
+// "repository@digest", or its ARN, or a function name or ARN. The finding is
+// listed while scanning of the resource type is enabled.
+func (i Inspector2Inspector) InjectVulnerability(resource string, v inspector2.Vulnerability) error {
+	svc, err := lookup[*inspector2.Service](i.m, "inspector2")
+	if err != nil {
+		return err
+	}
+	return svc.InjectFinding(resource, v)
+}
+
+// AddCosts records cost line items to report, as if they had come from
+// billing data.
+func (i CostExplorerInspector) AddCosts(costs ...costexplorer.Cost) error {
+	svc, err := lookup[*costexplorer.Service](i.m, "ce")
+	if err != nil {
+		return err
+	}
+	svc.AddCosts(costs...)
+	return nil
+}
+
+// SetSpend sets the actual and forecasted spend of the named budget,
+// publishing alerts to the SNS subscribers of each notification whose
+// threshold the spend crosses.
+func (i BudgetsInspector) SetSpend(name string, actual, forecasted float64) error {
+	svc, err := lookup[*budgets.Service](i.m, "budgets")
+	if err != nil {
+		return err
+	}
+	return svc.SetSpend(name, actual, forecasted)
+}
+
+// RegisterEndpointHandler makes InvokeEndpoint requests for the named
+// endpoint run handler, which is served a POST to /invocations the way a
+// model container is. Endpoints without a handler echo the request body.
+func (i SageMakerInspector) RegisterEndpointHandler(endpointName string, handler http.Handler) error {
+	svc, err := lookup[*sagemaker.Service](i.m, "sagemaker")
+	if err != nil {
+		return err
+	}
+	svc.SetEndpointHandler(endpointName, handler)
+	return nil
+}
+
+// RegisterModel makes Bedrock Runtime invocations of modelID answer with fn,
+// such as bedrock.Reply("...") for a canned response. Models without a
+// ModelFunc echo the prompt.
+func (i BedrockInspector) RegisterModel(modelID string, fn bedrock.ModelFunc) error {
+	svc, err := lookup[*bedrock.Service](i.m, "bedrock")
+	if err != nil {
+		return err
+	}
+	svc.SetModel(modelID, fn)
+	return nil
+}
+
+// SetDocument sets the text reported for the S3 object at bucket/key.
+// Objects without a document are a single blank page.
+func (i TextractInspector) SetDocument(bucket, key string, doc textract.Document) error {
+	svc, err := lookup[*textract.Service](i.m, "textract")
+	if err != nil {
+		return err
+	}
+	svc.SetDocument(bucket, key, doc)
+	return nil
+}
+
+// SetImage sets the labels and faces detected in the S3 object at
+// bucket/key. Objects without an image contain nothing.
+func (i RekognitionInspector) SetImage(bucket, key string, img rekognition.Image) error {
+	svc, err := lookup[*rekognition.Service](i.m, "rekognition")
+	if err != nil {
+		return err
+	}
+	svc.SetImage(bucket, key, img)
+	return nil
+}
+
+// Poll immediately moves the records waiting at the named pipe's source to
+// its target, as running pipes otherwise do when the mock clock advances.
+func (i PipesInspector) Poll(name string) error {
+	svc, err := lookup[*pipes.Service](i.m, "pipes")
+	if err != nil {
+		return err
+	}
+	return svc.Poll(name)
+}
+
+// SetExecution sets the outcome of the named flow's subsequent runs: the
+// records they pull from the source, or the error they fail with.
+func (i AppFlowInspector) SetExecution(flowName string, exec appflow.Execution) error {
+	svc, err := lookup[*appflow.Service](i.m, "appflow")
+	if err != nil {
+		return err
+	}
+	svc.SetExecution(flowName, exec)
+	return nil
+}
+
+// RecordActivity records principalArn performing actions, given as
+// "service:Action", at the current mock time, as the CloudTrail events
+// policies are generated for the principal from.
+func (i AccessAnalyzerInspector) RecordActivity(principalArn string, actions ...string) error {
+	svc, err := lookup[*accessanalyzer.Service](i.m, "access-analyzer")
+	if err != nil {
+		return err
+	}
+	return svc.RecordActivity(principalArn, actions...)
+}
+
+// RegisterAction makes sim run every action whose provider is provider,
+// replacing the built-in behavior. Use it to fail a deploy, or to assert on
+// the configuration an action receives.
+func (i CodePipelineInspector) RegisterAction(provider string, sim codepipeline.ActionSimulator) error {
+	svc, err := lookup[*codepipeline.Service](i.m, "codepipeline")
+	if err != nil {
+		return err
+	}
+	svc.RegisterActionSimulator(provider, sim)
+	return nil
+}
+
+// RegisterStatementHandler makes statements run in the interactive session
+// with the given ID return fn's output, or fail with its error. Statements
+// in sessions without a handler succeed with no output.
+func (i GlueInspector) RegisterStatementHandler(sessionID string, fn glue.StatementFunc) error {
+	svc, err := lookup[*glue.Service](i.m, "glue")
+	if err != nil {
+		return err
+	}
+	svc.SetStatementHandler(sessionID, fn)
+	return nil
+}
+
+// SetInstance sets the EC2 instance the instance metadata endpoints report
+// on, including the role whose credentials they serve. Point
+// AWS_EC2_METADATA_SERVICE_ENDPOINT at [MockServer.URL] for the default
+// credential chain to use them.
+func (i IMDSInspector) SetInstance(inst imds.Instance) error {
+	svc, err := lookup[*imds.Service](i.m, "imds")
+	if err != nil {
+		return err
+	}
+	svc.SetInstance(inst)
+	return nil
+}
+
+// PutSourceAccountMetrics records data points published in a source account
+// linked with [WithSourceAccounts], for cross-account GetMetricData queries.
+func (i CloudWatchInspector) PutSourceAccountMetrics(accountID string, data ...cloudwatch.Datum) error {
+	svc, err := lookup[*cloudwatch.Service](i.m, "monitoring")
+	if err != nil {
+		return err
+	}
+	return svc.PutAccountMetrics(accountID, data...)
+}
+
+// SetDataScanned sets the number of bytes queries with the given query
+// string scan, which workgroup data usage limits are checked against. Other
+// queries scan nothing.
+func (i AthenaInspector) SetDataScanned(query string, bytes int64) error {
+	svc, err := lookup[*athena.Service](i.m, "athena")
+	if err != nil {
+		return err
+	}
+	svc.SetDataScanned(query, bytes)
+	return nil
+}
+
+// RegisterManagedInstance registers a hybrid machine with an activation, as
+// installing the SSM Agent with the activation's ID and code does, and
+// returns its mi-* instance ID. The instance appears in
+// DescribeInstanceInformation and can be targeted by SendCommand,
+// associations, and maintenance windows. Take it offline with
+// [MockServer.SetResourceStatus].
+func (i SSMInspector) RegisterManagedInstance(activationID, activationCode string, inst ssm.ManagedInstance) (string, error) {
+	svc, err := lookup[*ssm.Service](i.m, "ssm")
+	if err != nil {
+		return "", err
+	}
+	return svc.RegisterManagedInstance(activationID, activationCode, inst)
+}
+
+// lookup returns the registered service called name, provided it is the
+// built-in implementation T rather than a user replacement.
+func lookup[T Service](m *MockServer, name string) (T, error) {
+	m.mu.RLock()
+	svc, ok := m.services[name].(T)
+	m.mu.RUnlock()
+
+	if !ok {
+		return svc, fmt.Errorf("%s service does not support inspection", name)
+	}
+	return svc, nil
+}
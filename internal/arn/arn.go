@@ -0,0 +1,68 @@
+// Package arn parses and validates Amazon Resource Names.
+//
+// Services use it to check ARNs that refer to resources owned by other
+// services (rule targets, subscription endpoints, execution roles) before
+// accepting them. Whether the named resource exists is answered by the mock
+// server, which asks the owning service; see mockhelpers.Resolver.
+package arn
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrInvalid is returned for strings that are not well-formed ARNs.
+var ErrInvalid = errors.New("invalid ARN")
+
+// ErrNotFound is returned when an ARN is well-formed but names a resource
+// that its owning service does not have.
+var ErrNotFound = errors.New("resource not found")
+
+// ARN is a parsed Amazon Resource Name of the form
+// arn:partition:service:region:account-id:resource.
+type ARN struct {
+	Partition string
+	Service   string
+	Region    string
+	AccountID string
+	// Resource is everything after the account ID, e.g. "function:name",
+	// "role/path/name", or a bare bucket or queue name.
+	Resource string
+}
+
+// Parse splits s into its components. It returns an error wrapping
+// [ErrInvalid] if s is not an ARN or has an empty partition, service, or
+// resource.
+func Parse(s string) (ARN, error) {
+	parts := strings.SplitN(s, ":", 6)
+	if len(parts) < 6 || parts[0] != "arn" {
+		return ARN{}, fmt.Errorf("%w: %q", ErrInvalid, s)
+	}
+	a := ARN{
+		Partition: parts[1],
+		Service:   parts[2],
+		Region:    parts[3],
+		AccountID: parts[4],
+		Resource:  parts[5],
+	}
+	if a.Partition == "" || a.Service == "" || a.Resource == "" {
+		return ARN{}, fmt.Errorf("%w: %q", ErrInvalid, s)
+	}
+	return a, nil
+}
+
+// String reassembles the ARN.
+func (a ARN) String() string {
+	return strings.Join([]string{"arn", a.Partition, a.Service, a.Region, a.AccountID, a.Resource}, ":")
+}
+
+// ResourceType returns the part of the resource before the first ':' or '/',
+// e.g. "function" or "role". It is empty for resources without a type prefix,
+// such as S3 buckets and SQS queues.
+func (a ARN) ResourceType() string {
+	if i := strings.IndexAny(a.Resource, ":/"); i >= 0 {
+		return a.Resource[:i]
+	}
+	return ""
+}
@@ -0,0 +1,120 @@
+// Package awserr writes AWS error responses in the shape each wire protocol
+// expects, so that the SDKs decode them into their modeled error types
+// (e.g. *types.ResourceNotFoundException) rather than a generic API error.
+//
+// The SDKs find the error code in different places depending on the
+// protocol:
+//
+//   - awsJson1.0, awsJson1.1 and restJson1 read the X-Amzn-ErrorType header
+//     and fall back to the "__type" or "code" member of the body.
+//   - awsQuery reads ErrorResponse/Error/Code.
+//   - ec2Query reads Response/Errors/Error/Code.
+//   - restXml reads Error/Code, or ErrorResponse/Error/Code for some services.
+//
+// Every writer here sets the fields its protocol's deserializer reads.
+package awserr
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"math/rand"
+	"net/http"
+)
+
+// Content types for the JSON protocols.
+const (
+	JSON10 = "application/x-amz-json-1.0"
+	JSON11 = "application/x-amz-json-1.1"
+)
+
+// Fault returns the fault party of an error with the given HTTP status:
+// "Receiver" for server errors and "Sender" for everything else.
+func Fault(status int) string {
+	if status >= http.StatusInternalServerError {
+		return "Receiver"
+	}
+	return "Sender"
+}
+
+// WriteJSON writes an error for the awsJson1.0, awsJson1.1 and restJson1
+// protocols. contentType is usually JSON10 or JSON11.
+func WriteJSON(w http.ResponseWriter, contentType, code, message string, status int) {
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("X-Amzn-ErrorType", code)
+	w.Header().Set("X-Amzn-RequestId", requestID())
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"__type":  code,
+		"message": message,
+	})
+}
+
+// WriteQuery writes an error for the awsQuery protocol. An empty fault is
+// derived from status with [Fault].
+func WriteQuery(w http.ResponseWriter, fault, code, message string, status int) {
+	type queryError struct {
+		Type    string `xml:"Type"`
+		Code    string `xml:"Code"`
+		Message string `xml:"Message"`
+	}
+	type queryErrorResponse struct {
+		XMLName   xml.Name   `xml:"ErrorResponse"`
+		Error     queryError `xml:"Error"`
+		RequestID string     `xml:"RequestId"`
+	}
+	if fault == "" {
+		fault = Fault(status)
+	}
+	writeXML(w, status, queryErrorResponse{
+		Error:     queryError{Type: fault, Code: code, Message: message},
+		RequestID: requestID(),
+	})
+}
+
+// WriteEC2 writes an error for the ec2Query protocol.
+func WriteEC2(w http.ResponseWriter, code, message string, status int) {
+	type ec2Error struct {
+		Code    string `xml:"Code"`
+		Message string `xml:"Message"`
+	}
+	type ec2ErrorResponse struct {
+		XMLName   xml.Name   `xml:"Response"`
+		Errors    []ec2Error `xml:"Errors>Error"`
+		RequestID string     `xml:"RequestID"`
+	}
+	writeXML(w, status, ec2ErrorResponse{
+		Errors:    []ec2Error{{Code: code, Message: message}},
+		RequestID: requestID(),
+	})
+}
+
+// WriteRestXML writes an error for the restXml protocol in the unwrapped
+// form S3 uses.
+func WriteRestXML(w http.ResponseWriter, code, message string, status int) {
+	type restXMLError struct {
+		XMLName   xml.Name `xml:"Error"`
+		Code      string   `xml:"Code"`
+		Message   string   `xml:"Message"`
+		RequestID string   `xml:"RequestId"`
+	}
+	writeXML(w, status, restXMLError{
+		Code:      code,
+		Message:   message,
+		RequestID: requestID(),
+	})
+}
+
+func writeXML(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "text/xml; charset=utf-8")
+	w.WriteHeader(status)
+	w.Write([]byte(xml.Header))
+	xml.NewEncoder(w).Encode(v)
+}
+
+// requestID returns a random request ID in the UUID format AWS uses.
+func requestID() string {
+	return fmt.Sprintf("%08x-%04x-%04x-%04x-%012x",
+		rand.Uint32(), rand.Uint32()&0xffff, rand.Uint32()&0xffff,
+		rand.Uint32()&0xffff, rand.Uint64()&0xffffffffffff)
+}
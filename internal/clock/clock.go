@@ -0,0 +1,114 @@
+// Package clock provides the controllable clock shared by mock services.
+//
+// Services that model time-driven behavior (schedules, retention, delayed
+// state transitions) read the current time from a [Clock] and subscribe to
+// [Clock.Advance] so tests can move time forward deterministically instead
+// of sleeping.
+package clock
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock is a manually advanced clock. The zero value is not usable; use [New].
+type Clock struct {
+	mu        sync.RWMutex
+	now       time.Time
+	advances  []advance
+	listeners []func(from, to time.Time)
+}
+
+// New creates a clock starting at the given time.
+func New(start time.Time) *Clock {
+	return &Clock{now: start.UTC()}
+}
+
+// Now returns the current mock time.
+func (c *Clock) Now() time.Time {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.now
+}
+
+// advance is a call to Advance and the wall time it was made at.
+type advance struct {
+	wall time.Time
+	d    time.Duration
+}
+
+// AdvancedSince returns how far the clock has been moved forward by calls
+// to Advance made at or after the wall time since. Adding it to the wall
+// time gives the time as seen by a client that stamped something with its
+// own, real-time clock at since, such as a signature.
+func (c *Clock) AdvancedSince(since time.Time) time.Duration {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	var total time.Duration
+	for i := len(c.advances) - 1; i >= 0 && !c.advances[i].wall.Before(since); i-- {
+		total += c.advances[i].d
+	}
+	return total
+}
+
+// Advance moves the clock forward by d and notifies all listeners with the
+// previous and new time. Listeners run synchronously on the caller's
+// goroutine after the clock lock has been released.
+func (c *Clock) Advance(d time.Duration) {
+	if d <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	from := c.now
+	c.now = c.now.Add(d)
+	c.advances = append(c.advances, advance{wall: time.Now(), d: d})
+	to := c.now
+	listeners := make([]func(from, to time.Time), len(c.listeners))
+	copy(listeners, c.listeners)
+	c.mu.Unlock()
+
+	for _, fn := range listeners {
+		fn(from, to)
+	}
+}
+
+// OnAdvance registers fn to be called every time the clock is advanced.
+func (c *Clock) OnAdvance(fn func(from, to time.Time)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.listeners = append(c.listeners, fn)
+}
+
+// Mark is a point in both real and mock time.
+type Mark struct {
+	wall time.Time
+	mock time.Time
+}
+
+// Mark returns the current point in real and mock time. A nil clock marks
+// real time only.
+func (c *Clock) Mark() Mark {
+	m := Mark{wall: time.Now()}
+	if c != nil {
+		m.mock = c.Now()
+	}
+	return m
+}
+
+// Since returns the time elapsed since m in real time or on the mock clock,
+// whichever has moved further, so that time-driven behavior progresses on
+// its own and faster when a test advances the clock. It is zero for the
+// zero Mark.
+func (c *Clock) Since(m Mark) time.Duration {
+	if m.wall.IsZero() {
+		return 0
+	}
+	elapsed := time.Since(m.wall)
+	if c != nil {
+		if mock := c.Now().Sub(m.mock); mock > elapsed {
+			elapsed = mock
+		}
+	}
+	return elapsed
+}
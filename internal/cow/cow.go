@@ -0,0 +1,132 @@
+// Package cow provides a copy-on-write map for mock state that is
+// checkpointed once and restored many times.
+//
+// A [Map] is a writable layer over a frozen base. [Map.Snapshot] folds the
+// layer into a new base and returns it; [FromSnapshot] builds a fresh map
+// over a snapshot in constant time, however many entries it holds. Writes
+// after that only touch the new map's layer, so the snapshot can be
+// restored again and again.
+package cow
+
+// Map is a copy-on-write map. It is not safe for concurrent use.
+type Map[K comparable, V any] struct {
+	base    map[K]V // shared with snapshots; never written
+	layer   map[K]V
+	deleted map[K]struct{} // base keys deleted in the layer
+	n       int
+}
+
+// Snapshot is a frozen set of map entries.
+type Snapshot[K comparable, V any] struct {
+	entries map[K]V
+}
+
+// New returns an empty map.
+func New[K comparable, V any]() *Map[K, V] {
+	return &Map[K, V]{}
+}
+
+// FromSnapshot returns a map holding the entries of snap. Writes to the map
+// do not affect snap.
+func FromSnapshot[K comparable, V any](snap Snapshot[K, V]) *Map[K, V] {
+	return &Map[K, V]{base: snap.entries, n: len(snap.entries)}
+}
+
+// Get returns the value stored under k.
+func (m *Map[K, V]) Get(k K) (V, bool) {
+	if v, ok := m.layer[k]; ok {
+		return v, true
+	}
+	if _, gone := m.deleted[k]; !gone {
+		if v, ok := m.base[k]; ok {
+			return v, true
+		}
+	}
+	var zero V
+	return zero, false
+}
+
+// Set stores v under k.
+func (m *Map[K, V]) Set(k K, v V) {
+	if _, ok := m.Get(k); !ok {
+		m.n++
+	}
+	if m.layer == nil {
+		m.layer = make(map[K]V)
+	}
+	m.layer[k] = v
+	delete(m.deleted, k)
+}
+
+// Delete removes k.
+func (m *Map[K, V]) Delete(k K) {
+	if _, ok := m.Get(k); !ok {
+		return
+	}
+	m.n--
+	delete(m.layer, k)
+	if _, inBase := m.base[k]; inBase {
+		if m.deleted == nil {
+			m.deleted = make(map[K]struct{})
+		}
+		m.deleted[k] = struct{}{}
+	}
+}
+
+// Owned reports whether the value stored under k was set since the map was
+// created or last snapshotted, so that no snapshot shares it and the caller
+// may modify it in place.
+func (m *Map[K, V]) Owned(k K) bool {
+	_, ok := m.layer[k]
+	return ok
+}
+
+// Len returns the number of entries.
+func (m *Map[K, V]) Len() int {
+	return m.n
+}
+
+// Range calls fn for each entry in unspecified order until fn returns false.
+func (m *Map[K, V]) Range(fn func(k K, v V) bool) {
+	for k, v := range m.layer {
+		if !fn(k, v) {
+			return
+		}
+	}
+	for k, v := range m.base {
+		if _, shadowed := m.layer[k]; shadowed {
+			continue
+		}
+		if _, gone := m.deleted[k]; gone {
+			continue
+		}
+		if !fn(k, v) {
+			return
+		}
+	}
+}
+
+// Snapshot freezes the map's current entries. It costs a copy of the map,
+// after which the map keeps working on an empty layer over the snapshot.
+func (m *Map[K, V]) Snapshot() Snapshot[K, V] {
+	if len(m.layer) == 0 && len(m.deleted) == 0 && m.base != nil {
+		return Snapshot[K, V]{entries: m.base}
+	}
+	entries := make(map[K]V, m.n)
+	m.Range(func(k K, v V) bool {
+		entries[k] = v
+		return true
+	})
+	m.base, m.layer, m.deleted = entries, nil, nil
+	return Snapshot[K, V]{entries: entries}
+}
+
+// Range calls fn for each entry of the snapshot in unspecified order until
+// fn returns false.
+func (s Snapshot[K, V]) Range(fn func(k K, v V) bool) {
+	for k, v := range s.entries {
+		if !fn(k, v) {
+			return
+		}
+	}
+}
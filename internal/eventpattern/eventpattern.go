@@ -0,0 +1,132 @@
+// Package eventpattern matches events against EventBridge event patterns,
+// which EventBridge rules and Pipes filters share.
+package eventpattern
+
+import (
+	"strings"
+)
+
+// Match reports whether event matches an EventBridge event pattern.
+// Every field in the pattern must match: nested objects match nested
+// objects, and arrays list the values, or content filters, that a field may
+// take. A field holding an array matches if any of its elements does.
+func Match(pattern, event map[string]interface{}) bool {
+	for key, want := range pattern {
+		val, present := event[key]
+		switch want := want.(type) {
+		case map[string]interface{}:
+			sub, ok := val.(map[string]interface{})
+			if !ok || !Match(want, sub) {
+				return false
+			}
+		case []interface{}:
+			if !matchValues(want, val, present) {
+				return false
+			}
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// matchValues reports whether val matches any of the allowed values.
+func matchValues(allowed []interface{}, val interface{}, present bool) bool {
+	for _, a := range allowed {
+		if filter, ok := a.(map[string]interface{}); ok {
+			if exists, ok := filter["exists"].(bool); ok {
+				if exists == present {
+					return true
+				}
+				continue
+			}
+			if present && anyElement(val, func(v interface{}) bool { return matchFilter(filter, v) }) {
+				return true
+			}
+			continue
+		}
+		if present && anyElement(val, func(v interface{}) bool { return v == a }) {
+			return true
+		}
+	}
+	return false
+}
+
+// anyElement applies match to val, or to each element if val is an array.
+func anyElement(val interface{}, match func(interface{}) bool) bool {
+	if list, ok := val.([]interface{}); ok {
+		for _, v := range list {
+			if match(v) {
+				return true
+			}
+		}
+		return false
+	}
+	return match(val)
+}
+
+// matchFilter reports whether v satisfies a content filter such as
+// {"prefix": "x"} or {"numeric": [">", 0]}.
+func matchFilter(filter map[string]interface{}, v interface{}) bool {
+	for op, arg := range filter {
+		switch op {
+		case "prefix":
+			s, ok := v.(string)
+			p, _ := arg.(string)
+			return ok && strings.HasPrefix(s, p)
+		case "suffix":
+			s, ok := v.(string)
+			p, _ := arg.(string)
+			return ok && strings.HasSuffix(s, p)
+		case "equals-ignore-case":
+			s, ok := v.(string)
+			p, _ := arg.(string)
+			return ok && strings.EqualFold(s, p)
+		case "anything-but":
+			switch arg := arg.(type) {
+			case []interface{}:
+				for _, a := range arg {
+					if a == v {
+						return false
+					}
+				}
+				return true
+			case map[string]interface{}:
+				return !matchFilter(arg, v)
+			default:
+				return arg != v
+			}
+		case "numeric":
+			n, ok := v.(float64)
+			conds, _ := arg.([]interface{})
+			if !ok || len(conds)%2 != 0 {
+				return false
+			}
+			for i := 0; i < len(conds); i += 2 {
+				cmp, _ := conds[i].(string)
+				bound, _ := conds[i+1].(float64)
+				if !compare(n, cmp, bound) {
+					return false
+				}
+			}
+			return true
+		}
+	}
+	return false
+}
+
+func compare(n float64, op string, bound float64) bool {
+	switch op {
+	case "=":
+		return n == bound
+	case "<":
+		return n < bound
+	case "<=":
+		return n <= bound
+	case ">":
+		return n > bound
+	case ">=":
+		return n >= bound
+	}
+	return false
+}
@@ -0,0 +1,88 @@
+// Package lifecycle models resources that pass through a transitional status
+// (CREATING, PENDING, creating, ...) before they are ready.
+//
+// By default every transition completes at once, so resources are ready as
+// soon as they are created. With a delay, a transition completes once the
+// delay has passed in real time or on the mock clock, whichever comes first:
+// SDK waiters that poll see the transitional status for a while, and tests
+// that do not want to wait can advance the clock instead.
+package lifecycle
+
+import (
+	"time"
+
+	"github.com/riyanimam/goto/internal/clock"
+)
+
+// Transitions decides when transitions complete. A nil *Transitions
+// completes every transition immediately.
+type Transitions struct {
+	delay time.Duration
+	clock *clock.Clock
+}
+
+// New creates transitions that take delay to complete, measured in real time
+// and on c. A zero delay completes every transition immediately.
+func New(c *clock.Clock, delay time.Duration) *Transitions {
+	return &Transitions{clock: c, delay: delay}
+}
+
+// Transition is a resource's move to its ready status, begun at a point in
+// real and mock time.
+type Transition struct {
+	wall  time.Time
+	mock  time.Time
+	delay time.Duration
+}
+
+// Begin starts a transition now.
+func (t *Transitions) Begin() Transition {
+	if t == nil {
+		return Transition{}
+	}
+	tr := Transition{wall: time.Now(), delay: t.delay}
+	if t.clock != nil {
+		tr.mock = t.clock.Now()
+	}
+	return tr
+}
+
+// Done reports whether tr has completed.
+func (t *Transitions) Done(tr Transition) bool {
+	if t == nil || tr.delay <= 0 {
+		return true
+	}
+	if time.Since(tr.wall) >= tr.delay {
+		return true
+	}
+	return t.clock != nil && t.clock.Now().Sub(tr.mock) >= tr.delay
+}
+
+// Status returns transitional while tr is in progress and ready once it has
+// completed.
+func (t *Transitions) Status(tr Transition, transitional, ready string) string {
+	if t.Done(tr) {
+		return ready
+	}
+	return transitional
+}
+
+// Stage returns the status tr has reached in a sequence of statuses: the
+// first when it begins, advancing one status each time the delay passes, and
+// staying on the last. Without a delay it returns the last status at once.
+func (t *Transitions) Stage(tr Transition, statuses ...string) string {
+	n := len(statuses) - 1
+	if t == nil || tr.delay <= 0 {
+		return statuses[n]
+	}
+	elapsed := time.Since(tr.wall)
+	if t.clock != nil {
+		if mock := t.clock.Now().Sub(tr.mock); mock > elapsed {
+			elapsed = mock
+		}
+	}
+	if steps := int(elapsed / tr.delay); steps < n {
+		n = steps
+	}
+	return statuses[n]
+}
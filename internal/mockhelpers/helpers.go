@@ -10,6 +10,9 @@ import (
 	"encoding/xml"
 	"math/rand"
 	"net/http"
+	"time"
+
+	"github.com/riyanimam/goto/internal/awserr"
 )
 
 // NewRequestID generates a random UUID-like request ID string.
@@ -93,12 +96,7 @@ func WriteJSON(w http.ResponseWriter, status int, v interface{}) {
 
 // WriteJSONError writes a JSON error response with the given code, message, and HTTP status.
 func WriteJSONError(w http.ResponseWriter, code, message string, status int) {
-	w.Header().Set("Content-Type", "application/x-amz-json-1.1")
-	w.WriteHeader(status)
-	json.NewEncoder(w).Encode(map[string]interface{}{
-		"__type":  code,
-		"message": message,
-	})
+	awserr.WriteJSON(w, awserr.JSON11, code, message, status)
 }
 
 // WriteXML writes an XML response with the given status code.
@@ -111,25 +109,118 @@ func WriteXML(w http.ResponseWriter, status int, v interface{}) {
 
 // WriteXMLError writes a standard AWS XML error response.
 func WriteXMLError(w http.ResponseWriter, errType, code, message string, status int) {
-	type xmlError struct {
-		Type    string `xml:"Type"`
-		Code    string `xml:"Code"`
-		Message string `xml:"Message"`
-	}
-	type xmlErrorResponse struct {
-		XMLName   xml.Name `xml:"ErrorResponse"`
-		Error     xmlError `xml:"Error"`
-		RequestID string   `xml:"RequestId"`
-	}
-	resp := xmlErrorResponse{
-		Error: xmlError{
-			Type:    errType,
-			Code:    code,
-			Message: message,
-		},
-		RequestID: NewRequestID(),
-	}
-	WriteXML(w, status, resp)
+	awserr.WriteQuery(w, errType, code, message, status)
+}
+
+// Dispatcher delivers payload to the resource identified by arn, which may be
+// owned by another mock service, and returns that resource's response.
+// Services that fan out to targets (schedules, rules, subscriptions) receive
+// a Dispatcher from the mock server instead of referencing each other.
+type Dispatcher func(arn string, payload []byte) ([]byte, error)
+
+// Resolver checks an ARN that refers to a resource owned by any mock
+// service. It returns an error wrapping arn.ErrInvalid if the string is not
+// an ARN, or arn.ErrNotFound if the owning service is running and does not
+// have the resource. ARNs of services that are not running, or that cannot
+// report on their resources, resolve successfully.
+type Resolver func(arn string) error
+
+// ObjectStore gives services direct access to the S3 mock's buckets, for
+// features that land files in S3 or read them back (home directories,
+// exports, delivery streams) without going through HTTP.
+type ObjectStore interface {
+	PutObject(bucket, key string, data []byte) error
+	GetObject(bucket, key string) ([]byte, error)
+	DeleteObject(bucket, key string) error
+	ListObjects(bucket, prefix string) ([]string, error)
+}
+
+// FileStore gives services direct access to the files in the EFS mock's
+// file systems, for features that copy files in or out (data migration)
+// without going through NFS. Paths are absolute within the file system.
+type FileStore interface {
+	WriteFile(fileSystemID, path string, data []byte) error
+	ReadFile(fileSystemID, path string) ([]byte, error)
+	DeleteFile(fileSystemID, path string) error
+	// ListFiles returns the sorted paths of the files under dir, at any
+	// depth.
+	ListFiles(fileSystemID, dir string) ([]string, error)
+}
+
+// EventSource gives services direct access to the records of queues and
+// streams held by other mock services, for features that consume them
+// (pipes) without going through HTTP.
+type EventSource interface {
+	// ReadEvents returns up to max records from the queue or stream
+	// identified by arn, shaped as Lambda and Pipes receive them, and the
+	// position to read from next. Positions are opaque: "" and
+	// "TRIM_HORIZON" start at the oldest record and "LATEST" after the
+	// newest. Queues ignore the position and hide the messages read until
+	// they are acknowledged.
+	ReadEvents(arn, position string, max int) (events []map[string]interface{}, next string, err error)
+	// AckEvents reports whether events read from a queue were processed.
+	// Processed messages are deleted and the rest become visible again.
+	// Streams ignore acknowledgements.
+	AckEvents(arn string, events []map[string]interface{}, processed bool) error
+}
+
+// Metric is a data point recorded in the CloudWatch metrics mock.
+type Metric struct {
+	Namespace  string
+	Name       string
+	Unit       string
+	Value      float64
+	Dimensions map[string]string
+	Timestamp  time.Time
+}
+
+// MetricStore gives services direct access to the CloudWatch metrics mock,
+// for features that publish metrics on their own (embedded metric format
+// logs) without going through HTTP.
+type MetricStore interface {
+	PutMetrics(metrics []Metric) error
+}
+
+// TokenVerifier gives services access to the bearer tokens issued by the
+// SSO OIDC mock, for APIs authorized by them (the SSO portal) rather than by
+// signatures.
+type TokenVerifier interface {
+	// VerifyToken reports an error unless accessToken is current.
+	VerifyToken(accessToken string) error
+	// RevokeToken invalidates accessToken.
+	RevokeToken(accessToken string)
+}
+
+// Credentials are temporary security credentials.
+type Credentials struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+	Expiration      time.Time
+}
+
+// CredentialIssuer gives services access to the STS mock, for features that
+// hand out role credentials without a call to AssumeRole (instance
+// metadata).
+type CredentialIssuer interface {
+	// IssueCredentials returns credentials for the role, valid for
+	// duration, as AssumeRole would.
+	IssueCredentials(roleArn string, duration time.Duration) (Credentials, error)
+}
+
+// ResourceLister returns the resources held by the named mock service (e.g.
+// "ec2"), or nothing if it cannot report them. Services that scan or
+// inventory other services' resources receive one from the mock server.
+type ResourceLister func(service string) []Resource
+
+// Resource describes a resource held by a mock service, for exporting the
+// mock's state. Type is the Terraform resource type (e.g. "aws_sqs_queue"),
+// and Attributes are named as in Terraform where the two overlap.
+type Resource struct {
+	Type       string
+	ID         string
+	ARN        string
+	Attributes map[string]interface{}
 }
 
 // DefaultAccountID is the mock AWS account ID used by all services.
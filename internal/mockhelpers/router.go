@@ -0,0 +1,122 @@
+package mockhelpers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/riyanimam/goto/internal/awserr"
+)
+
+// JSONHandler handles one action of a JSON protocol service, given the
+// decoded request body.
+type JSONHandler func(w http.ResponseWriter, params map[string]interface{})
+
+// JSONRouter serves a JSON protocol (awsJson1.0 or awsJson1.1) service. It
+// maps action names, as they appear after the dot in the X-Amz-Target header
+// (e.g. "CreateTable" in "DynamoDB_20120810.CreateTable"), to handlers.
+//
+// The router reads and decodes the request body and answers requests for
+// unknown actions with UnknownOperationException, so that every service
+// reports malformed and unsupported requests the same way. Used directly as a
+// handler, it writes awsJson1.1 errors; see [JSONRouter.JSON10].
+type JSONRouter map[string]JSONHandler
+
+// ServeHTTP decodes the request and calls the handler for its action.
+func (rt JSONRouter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	rt.serve(w, r, awserr.JSON11)
+}
+
+// JSON10 returns a handler that routes like rt but writes its errors with the
+// awsJson1.0 content type, for services such as DynamoDB and SQS.
+func (rt JSONRouter) JSON10() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rt.serve(w, r, awserr.JSON10)
+	})
+}
+
+func (rt JSONRouter) serve(w http.ResponseWriter, r *http.Request, contentType string) {
+	action := ""
+	if _, a, ok := strings.Cut(r.Header.Get("X-Amz-Target"), "."); ok {
+		action = a
+	}
+	handler, ok := rt[action]
+	if !ok {
+		awserr.WriteJSON(w, contentType, "UnknownOperationException", fmt.Sprintf("action %q is not supported", action), http.StatusBadRequest)
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		awserr.WriteJSON(w, contentType, "InternalFailure", "could not read request body", http.StatusInternalServerError)
+		return
+	}
+	var params map[string]interface{}
+	if len(body) > 0 {
+		if err := json.Unmarshal(body, &params); err != nil {
+			awserr.WriteJSON(w, contentType, "SerializationException", "could not parse request body", http.StatusBadRequest)
+			return
+		}
+	}
+	if params == nil {
+		params = make(map[string]interface{})
+	}
+	handler(w, params)
+}
+
+// Operations returns the names of the routed actions, sorted.
+func (rt JSONRouter) Operations() []string {
+	ops := make([]string, 0, len(rt))
+	for op := range rt {
+		ops = append(ops, op)
+	}
+	sort.Strings(ops)
+	return ops
+}
+
+// QueryRouter serves a query protocol (awsQuery) service, routing on the
+// Action parameter of the form-encoded request. Handlers read their own
+// parameters from r.Form, which the router has already parsed.
+type QueryRouter struct {
+	// Actions maps action names to handlers.
+	Actions map[string]http.HandlerFunc
+	// Error writes an error in the service's XML shape. When nil, errors
+	// are written with WriteXMLError as sender faults.
+	Error func(w http.ResponseWriter, code, message string, status int)
+}
+
+// ServeHTTP parses the request form and calls the handler for its action.
+func (rt QueryRouter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		rt.writeError(w, "MalformedQueryString", "could not parse request", http.StatusBadRequest)
+		return
+	}
+	action := r.FormValue("Action")
+	handler, ok := rt.Actions[action]
+	if !ok {
+		rt.writeError(w, "InvalidAction", fmt.Sprintf("action %q is not supported", action), http.StatusBadRequest)
+		return
+	}
+	handler(w, r)
+}
+
+func (rt QueryRouter) writeError(w http.ResponseWriter, code, message string, status int) {
+	if rt.Error != nil {
+		rt.Error(w, code, message, status)
+		return
+	}
+	WriteXMLError(w, "Sender", code, message, status)
+}
+
+// Operations returns the names of the routed actions, sorted.
+func (rt QueryRouter) Operations() []string {
+	ops := make([]string, 0, len(rt.Actions))
+	for op := range rt.Actions {
+		ops = append(ops, op)
+	}
+	sort.Strings(ops)
+	return ops
+}
@@ -0,0 +1,99 @@
+// Package paginate implements the token-based pagination shared by the list
+// operations of the mock services.
+//
+// Services sort a listing by a unique key and pass it to [Page] together
+// with a function returning each item's key, the token from the request
+// (NextToken, Marker, ContinuationToken, ...) and the requested page size
+// (MaxResults, MaxItems, MaxKeys, ...). The token returned for the next page
+// is opaque to clients; it encodes the key of the last item returned, and the
+// next page resumes strictly after that key, so items created or deleted
+// between pages neither repeat earlier items nor skip later ones.
+package paginate
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ErrInvalidToken is returned by [Page] when the token was not issued by
+// this package.
+var ErrInvalidToken = errors.New("the pagination token is invalid")
+
+const tokenPrefix = "awsmock-page:"
+
+// Page returns at most limit items of items, starting after the key encoded
+// in token, and the token for the following page. key returns the key of
+// items[i]; items must be sorted by key, and no two items may have the same
+// key. The returned token is "" on the last page. A limit of zero or less, or
+// above max, is treated as max; a max of zero or less means no limit.
+func Page[T any](items []T, key func(i int) string, token string, limit, max int) ([]T, string, error) {
+	start := 0
+	if token != "" {
+		after, err := Key(token)
+		if err != nil {
+			return nil, "", err
+		}
+		start = sort.Search(len(items), func(i int) bool { return key(i) > after })
+	}
+	if limit <= 0 || (max > 0 && limit > max) {
+		limit = max
+	}
+	if limit <= 0 || start+limit >= len(items) {
+		return items[start:], "", nil
+	}
+	end := start + limit
+	return items[start:end], Token(key(end - 1)), nil
+}
+
+// Token encodes key, the key of the last item of a page, as a pagination
+// token.
+func Token(key string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(tokenPrefix + key))
+}
+
+// Key decodes a token produced by [Token].
+func Key(token string) (string, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return "", ErrInvalidToken
+	}
+	key, ok := strings.CutPrefix(string(raw), tokenPrefix)
+	if !ok {
+		return "", ErrInvalidToken
+	}
+	return key, nil
+}
+
+// The functions below build keys for listings that are not sorted by a
+// single name, such as listings sorted by time or newest first.
+
+// Join returns a key that sorts by each of parts in turn.
+func Join(parts ...string) string {
+	return strings.Join(parts, "\x00")
+}
+
+// Desc returns a key that sorts in the reverse order of key.
+func Desc(key string) string {
+	b := make([]byte, len(key)+1)
+	for i := 0; i < len(key); i++ {
+		b[i] = 0xff - key[i]
+	}
+	b[len(key)] = 0xff
+	return string(b)
+}
+
+// Time returns a key that sorts in the order of t.
+func Time(t time.Time) string {
+	return t.UTC().Format("20060102150405.000000000")
+}
+
+// Seq returns a key that sorts in the order of n, which must not be
+// negative, such as an item's position in an append-only list or in a
+// listing that cannot change between requests.
+func Seq(n int) string {
+	return fmt.Sprintf("%020d", n)
+}
@@ -0,0 +1,463 @@
+// Package stubgen generates the routing table and handler stubs of a mock
+// service from the service's Smithy model, in the JSON AST form AWS
+// publishes its API models in.
+//
+// The generated code routes each operation with [mockhelpers.JSONRouter]
+// (awsJson1.0 and awsJson1.1 services) or [mockhelpers.QueryRouter]
+// (awsQuery services) and gives it a handler that reads the operation's
+// top-level input members and writes an empty response of the operation's
+// output shape. The stubs compile once the package defines its Service
+// type; filling in their behavior is left to the author.
+// REST and EC2 query services are not supported, since the routers do not
+// model them.
+//
+// [mockhelpers.JSONRouter]: github.com/riyanimam/goto/internal/mockhelpers.JSONRouter
+// [mockhelpers.QueryRouter]: github.com/riyanimam/goto/internal/mockhelpers.QueryRouter
+package stubgen
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/format"
+	"go/token"
+	"sort"
+	"strings"
+)
+
+// Options controls what [Generate] emits.
+type Options struct {
+	// Package is the name of the generated file's package. When empty, it
+	// is the service's SDK ID, lowercased without spaces.
+	Package string
+	// Operations limits the stubs to the named operations, for adding the
+	// missing operations of an existing service. When empty, every
+	// operation of the service is stubbed.
+	Operations []string
+}
+
+// protocol is a wire protocol the routers serve.
+type protocol int
+
+const (
+	awsJSON10 protocol = iota
+	awsJSON11
+	awsQuery
+)
+
+// protocolTraits maps the Smithy protocol traits to the protocols they
+// name.
+var protocolTraits = map[string]protocol{
+	"aws.protocols#awsJson1_0": awsJSON10,
+	"aws.protocols#awsJson1_1": awsJSON11,
+	"aws.protocols#awsQuery":   awsQuery,
+}
+
+type model struct {
+	Shapes map[string]*shape `json:"shapes"`
+}
+
+type shape struct {
+	Type       string                     `json:"type"`
+	Operations []ref                      `json:"operations"`
+	Resources  []ref                      `json:"resources"`
+	Input      *ref                       `json:"input"`
+	Output     *ref                       `json:"output"`
+	Members    map[string]member          `json:"members"`
+	Traits     map[string]json.RawMessage `json:"traits"`
+
+	// Lifecycle operations of a resource.
+	Create               *ref  `json:"create"`
+	Put                  *ref  `json:"put"`
+	Read                 *ref  `json:"read"`
+	Update               *ref  `json:"update"`
+	Delete               *ref  `json:"delete"`
+	List                 *ref  `json:"list"`
+	CollectionOperations []ref `json:"collectionOperations"`
+}
+
+type ref struct {
+	Target string `json:"target"`
+}
+
+type member struct {
+	Target string `json:"target"`
+}
+
+// service is what Generate needs of a service shape.
+type service struct {
+	sdkID      string
+	protocol   protocol
+	xmlns      string
+	operations []string // shape IDs, sorted by name
+}
+
+// Generate returns a gofmt-formatted Go file holding the Handler method and
+// handler stubs for the service described by the Smithy JSON AST model.
+func Generate(data []byte, opts Options) ([]byte, error) {
+	var m model
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("parse model: %w", err)
+	}
+	svc, err := m.service()
+	if err != nil {
+		return nil, err
+	}
+	ops := svc.operations
+	if len(opts.Operations) > 0 {
+		if ops, err = selectOperations(svc.operations, opts.Operations); err != nil {
+			return nil, err
+		}
+	}
+	pkg := opts.Package
+	if pkg == "" {
+		pkg = strings.ToLower(strings.ReplaceAll(svc.sdkID, " ", ""))
+	}
+
+	g := &generator{m: &m, svc: svc}
+	g.header(pkg)
+	g.handler(ops)
+	for _, op := range ops {
+		g.stub(op)
+	}
+	src, err := format.Source(g.buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("format generated code: %w", err)
+	}
+	return src, nil
+}
+
+// service finds the model's service shape and its operations.
+func (m *model) service() (*service, error) {
+	var id string
+	for sid, s := range m.Shapes {
+		if s.Type != "service" {
+			continue
+		}
+		if id != "" {
+			return nil, fmt.Errorf("model defines more than one service: %s and %s", id, sid)
+		}
+		id = sid
+	}
+	if id == "" {
+		return nil, fmt.Errorf("model defines no service")
+	}
+	s := m.Shapes[id]
+
+	svc := &service{sdkID: name(id), protocol: -1}
+	var info struct {
+		SDKID string `json:"sdkId"`
+	}
+	if raw, ok := s.Traits["aws.api#service"]; ok && json.Unmarshal(raw, &info) == nil && info.SDKID != "" {
+		svc.sdkID = info.SDKID
+	}
+	for trait, p := range protocolTraits {
+		if _, ok := s.Traits[trait]; ok {
+			svc.protocol = p
+		}
+	}
+	if svc.protocol < 0 {
+		return nil, fmt.Errorf("%s uses a protocol the routers do not serve; only awsJson1_0, awsJson1_1 and awsQuery are supported", id)
+	}
+	var ns struct {
+		URI string `json:"uri"`
+	}
+	if raw, ok := s.Traits["smithy.api#xmlNamespace"]; ok && json.Unmarshal(raw, &ns) == nil {
+		svc.xmlns = ns.URI
+	}
+
+	seen := make(map[string]bool)
+	m.collect(s, seen)
+	for op := range seen {
+		svc.operations = append(svc.operations, op)
+	}
+	sort.Slice(svc.operations, func(i, j int) bool { return name(svc.operations[i]) < name(svc.operations[j]) })
+	return svc, nil
+}
+
+// collect adds the operations bound to s, a service or resource, and to its
+// resources to seen.
+func (m *model) collect(s *shape, seen map[string]bool) {
+	ops := append([]ref(nil), s.Operations...)
+	ops = append(ops, s.CollectionOperations...)
+	for _, r := range []*ref{s.Create, s.Put, s.Read, s.Update, s.Delete, s.List} {
+		if r != nil {
+			ops = append(ops, *r)
+		}
+	}
+	for _, op := range ops {
+		seen[op.Target] = true
+	}
+	for _, r := range s.Resources {
+		if res, ok := m.Shapes[r.Target]; ok {
+			m.collect(res, seen)
+		}
+	}
+}
+
+// selectOperations returns the operations of ops named in names.
+func selectOperations(ops, names []string) ([]string, error) {
+	byName := make(map[string]string, len(ops))
+	for _, op := range ops {
+		byName[name(op)] = op
+	}
+	var selected []string
+	for _, n := range names {
+		op, ok := byName[n]
+		if !ok {
+			return nil, fmt.Errorf("the service has no operation %q", n)
+		}
+		selected = append(selected, op)
+	}
+	sort.Slice(selected, func(i, j int) bool { return name(selected[i]) < name(selected[j]) })
+	return selected, nil
+}
+
+type generator struct {
+	m   *model
+	svc *service
+	buf bytes.Buffer
+}
+
+func (g *generator) printf(format string, args ...interface{}) {
+	fmt.Fprintf(&g.buf, format, args...)
+}
+
+func (g *generator) header(pkg string) {
+	g.printf("// Stubs generated by stubgen for %s. Fill in each handler's behavior,\n", g.svc.sdkID)
+	g.printf("// then move it next to the service's other handlers.\n\n")
+	g.printf("package %s\n\n", pkg)
+	g.printf("import (\n")
+	if g.svc.protocol == awsQuery {
+		g.printf("\t\"encoding/xml\"\n")
+	}
+	g.printf("\t\"net/http\"\n\n")
+	g.printf("\th \"github.com/riyanimam/goto/internal/mockhelpers\"\n")
+	g.printf(")\n\n")
+}
+
+func (g *generator) handler(ops []string) {
+	g.printf("// Handler returns the HTTP handler for %s requests.\n", g.svc.sdkID)
+	g.printf("func (s *Service) Handler() http.Handler {\n")
+	switch g.svc.protocol {
+	case awsQuery:
+		g.printf("\treturn h.QueryRouter{\n\t\tActions: map[string]http.HandlerFunc{\n")
+		for _, op := range ops {
+			g.printf("\t\t\t%q: s.%s,\n", name(op), handlerName(op))
+		}
+		g.printf("\t\t},\n\t}\n")
+	default:
+		g.printf("\treturn h.JSONRouter{\n")
+		for _, op := range ops {
+			g.printf("\t\t%q: s.%s,\n", name(op), handlerName(op))
+		}
+		g.printf("\t}")
+		if g.svc.protocol == awsJSON10 {
+			g.printf(".JSON10()")
+		}
+		g.printf("\n")
+	}
+	g.printf("}\n\n")
+}
+
+func (g *generator) stub(op string) {
+	o := g.m.Shapes[op]
+	if o == nil {
+		o = &shape{}
+	}
+	in := g.members(o.Input)
+	out := g.members(o.Output)
+	fn := handlerName(op)
+
+	g.printf("// %s serves %s.\n", fn, name(op))
+	if g.svc.protocol == awsQuery {
+		g.printf("func (s *Service) %s(w http.ResponseWriter, r *http.Request) {\n", fn)
+	} else {
+		g.printf("func (s *Service) %s(w http.ResponseWriter, params map[string]interface{}) {\n", fn)
+	}
+
+	var vars []string
+	for _, mem := range in {
+		v := varName(mem.name)
+		vars = append(vars, v)
+		switch {
+		case g.svc.protocol == awsQuery && mem.typ.scalar():
+			g.printf("\t%s := r.FormValue(%q)\n", v, mem.name)
+		case g.svc.protocol == awsQuery:
+			vars = vars[:len(vars)-1]
+			g.printf("\t// %s, a %s, is flattened into r.Form.\n", mem.name, mem.typ)
+		case mem.typ == "string" || mem.typ == "enum":
+			g.printf("\t%s := h.GetString(params, %q)\n", v, mem.name)
+		case mem.typ.integer():
+			g.printf("\t%s := h.GetInt(params, %q, 0)\n", v, mem.name)
+		case mem.typ == "boolean":
+			g.printf("\t%s := h.GetBool(params, %q)\n", v, mem.name)
+		default:
+			g.printf("\t%s := params[%q] // %s\n", v, mem.name, mem.typ)
+		}
+	}
+	if len(vars) > 0 {
+		g.printf("\t%s = %s\n\n", strings.TrimSuffix(strings.Repeat("_, ", len(vars)), ", "), strings.Join(vars, ", "))
+	}
+
+	if g.svc.protocol == awsQuery {
+		g.queryResponse(op, out)
+		return
+	}
+	g.printf("\th.WriteJSON(w, http.StatusOK, map[string]interface{}{\n")
+	for _, mem := range out {
+		g.printf("\t\t%q: %s,\n", mem.name, mem.typ.jsonZero())
+	}
+	g.printf("\t})\n")
+	g.printf("}\n\n")
+}
+
+// queryResponse ends the handler for op by sending an empty awsQuery
+// response, and declares the response types after it.
+func (g *generator) queryResponse(op string, out []field) {
+	n := name(op)
+	resp := handlerName(op) + "Response"
+	result := handlerName(op) + "Result"
+	g.printf("\th.WriteXML(w, http.StatusOK, %s{\n", resp)
+	if g.svc.xmlns != "" {
+		g.printf("\t\tXMLNS: %q,\n", g.svc.xmlns)
+	}
+	g.printf("\t\tRequestID: h.NewRequestID(),\n")
+	g.printf("\t})\n")
+	g.printf("}\n\n")
+
+	g.printf("type %s struct {\n", resp)
+	g.printf("\tXMLName xml.Name `xml:\"%sResponse\"`\n", n)
+	if g.svc.xmlns != "" {
+		g.printf("\tXMLNS string `xml:\"xmlns,attr\"`\n")
+	}
+	g.printf("\tResult %s `xml:\"%sResult\"`\n", result, n)
+	g.printf("\tRequestID string `xml:\"ResponseMetadata>RequestId\"`\n")
+	g.printf("}\n\n")
+
+	g.printf("type %s struct {\n", result)
+	for _, mem := range out {
+		g.printf("\t%s %s `xml:\"%s,omitempty\"`", exported(mem.name), mem.typ.goType(), mem.name)
+		if !mem.typ.scalar() {
+			g.printf(" // %s", mem.typ)
+		}
+		g.printf("\n")
+	}
+	g.printf("}\n\n")
+}
+
+// field is a top-level member of an operation's input or output.
+type field struct {
+	name string
+	typ  shapeType
+}
+
+// members returns the members of the structure r refers to, sorted by
+// name.
+func (g *generator) members(r *ref) []field {
+	if r == nil {
+		return nil
+	}
+	s := g.m.Shapes[r.Target]
+	if s == nil {
+		return nil
+	}
+	fields := make([]field, 0, len(s.Members))
+	for n, mem := range s.Members {
+		fields = append(fields, field{name: n, typ: g.typeOf(mem.Target)})
+	}
+	sort.Slice(fields, func(i, j int) bool { return fields[i].name < fields[j].name })
+	return fields
+}
+
+// shapeType is a Smithy shape type, such as "string" or "structure".
+type shapeType string
+
+// typeOf returns the type of the shape with the given ID, resolving the
+// Smithy prelude's shapes.
+func (g *generator) typeOf(id string) shapeType {
+	if s, ok := g.m.Shapes[id]; ok {
+		return shapeType(s.Type)
+	}
+	if n, ok := strings.CutPrefix(id, "smithy.api#"); ok {
+		return shapeType(strings.ToLower(strings.TrimPrefix(n, "Primitive")))
+	}
+	return "structure"
+}
+
+func (t shapeType) integer() bool {
+	switch t {
+	case "byte", "short", "integer", "long", "intenum":
+		return true
+	}
+	return false
+}
+
+func (t shapeType) scalar() bool {
+	switch t {
+	case "string", "enum", "boolean", "timestamp", "blob", "float", "double", "bigdecimal", "biginteger":
+		return true
+	}
+	return t.integer()
+}
+
+// jsonZero returns the Go expression for an empty value of t in a JSON
+// response.
+func (t shapeType) jsonZero() string {
+	switch {
+	case t == "string" || t == "enum" || t == "blob":
+		return `""`
+	case t == "boolean":
+		return "false"
+	case t.integer() || t.scalar():
+		return "0"
+	case t == "list" || t == "set":
+		return "[]interface{}{}"
+	}
+	return "map[string]interface{}{}"
+}
+
+// goType returns the Go type of a query response field of type t.
+func (t shapeType) goType() string {
+	switch {
+	case t == "boolean":
+		return "bool"
+	case t.integer():
+		return "int64"
+	case t == "float" || t == "double":
+		return "float64"
+	case t.scalar():
+		return "string"
+	}
+	return "interface{}"
+}
+
+// name returns the name part of a shape ID.
+func name(id string) string {
+	if _, n, ok := strings.Cut(id, "#"); ok {
+		return n
+	}
+	return id
+}
+
+// handlerName returns the name of the handler method for operation op.
+func handlerName(op string) string {
+	n := name(op)
+	return strings.ToLower(n[:1]) + n[1:]
+}
+
+// reserved holds the names the stubs already use.
+var reserved = map[string]bool{"s": true, "w": true, "r": true, "h": true, "params": true, "http": true, "xml": true}
+
+// varName returns the name of the variable holding input member n.
+func varName(n string) string {
+	v := strings.ToLower(n[:1]) + n[1:]
+	if token.IsKeyword(v) || reserved[v] {
+		v += "Param"
+	}
+	return v
+}
+
+func exported(n string) string {
+	return strings.ToUpper(n[:1]) + n[1:]
+}
@@ -0,0 +1,244 @@
+// Package tags provides the resource tag registry shared by mock services.
+//
+// Each service records the tags applied through its own API (TagQueue,
+// PutBucketTagging, TagResource, ...) in a [Store] keyed by resource ARN.
+// When services run inside the mock server they all share one store, which
+// is what the Resource Groups Tagging API mock reads, so tags applied through
+// either path are visible through both.
+package tags
+
+import (
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Store maps resource ARNs to their tags. It is safe for concurrent use.
+type Store struct {
+	mu   sync.RWMutex
+	tags map[string]map[string]string
+}
+
+// New creates an empty store.
+func New() *Store {
+	return &Store{tags: make(map[string]map[string]string)}
+}
+
+// Tag adds or overwrites tags on the resource.
+func (s *Store) Tag(arn string, tags map[string]string) {
+	if len(tags) == 0 {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.tags[arn] == nil {
+		s.tags[arn] = make(map[string]string, len(tags))
+	}
+	for k, v := range tags {
+		s.tags[arn][k] = v
+	}
+}
+
+// Untag removes the given tag keys from the resource.
+func (s *Store) Untag(arn string, keys []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	m := s.tags[arn]
+	if m == nil {
+		return
+	}
+	for _, k := range keys {
+		delete(m, k)
+	}
+	if len(m) == 0 {
+		delete(s.tags, arn)
+	}
+}
+
+// Replace sets the resource's tags to exactly tags, as S3's PutBucketTagging
+// does.
+func (s *Store) Replace(arn string, tags map[string]string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(tags) == 0 {
+		delete(s.tags, arn)
+		return
+	}
+	m := make(map[string]string, len(tags))
+	for k, v := range tags {
+		m[k] = v
+	}
+	s.tags[arn] = m
+}
+
+// Get returns a copy of the resource's tags. The result is never nil.
+func (s *Store) Get(arn string) map[string]string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	m := make(map[string]string, len(s.tags[arn]))
+	for k, v := range s.tags[arn] {
+		m[k] = v
+	}
+	return m
+}
+
+// Delete removes all tags for a resource, typically when it is deleted.
+func (s *Store) Delete(arn string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.tags, arn)
+}
+
+// DeleteService removes the tags of every resource whose ARN belongs to the
+// named service (the third ARN field, e.g. "sqs"). Services call it from
+// Reset so that resetting one service leaves other services' tags alone.
+func (s *Store) DeleteService(service string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for arn := range s.tags {
+		if ServiceOf(arn) == service {
+			delete(s.tags, arn)
+		}
+	}
+}
+
+// RestoreService replaces the tags of the named service's resources with
+// those recorded in saved, a result of [Store.All]. Tags of other services'
+// resources are left alone.
+func (s *Store) RestoreService(service string, saved map[string]map[string]string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for arn := range s.tags {
+		if ServiceOf(arn) == service {
+			delete(s.tags, arn)
+		}
+	}
+	for arn, m := range saved {
+		if ServiceOf(arn) != service {
+			continue
+		}
+		c := make(map[string]string, len(m))
+		for k, v := range m {
+			c[k] = v
+		}
+		s.tags[arn] = c
+	}
+}
+
+// Clear removes every tag in the store.
+func (s *Store) Clear() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tags = make(map[string]map[string]string)
+}
+
+// All returns a copy of every tagged resource and its tags.
+func (s *Store) All() map[string]map[string]string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	all := make(map[string]map[string]string, len(s.tags))
+	for arn, m := range s.tags {
+		c := make(map[string]string, len(m))
+		for k, v := range m {
+			c[k] = v
+		}
+		all[arn] = c
+	}
+	return all
+}
+
+// ServiceOf returns the service field of an ARN, or "" if arn is malformed.
+func ServiceOf(arn string) string {
+	parts := strings.SplitN(arn, ":", 4)
+	if len(parts) < 4 || parts[0] != "arn" {
+		return ""
+	}
+	return parts[2]
+}
+
+// FromList converts a list-shaped tag parameter such as
+// [{"Key": "env", "Value": "prod"}] into a map. keyField and valueField name
+// the members, since services disagree ("Key"/"Value", "TagKey"/"TagValue").
+func FromList(v interface{}, keyField, valueField string) map[string]string {
+	m := make(map[string]string)
+	list, _ := v.([]interface{})
+	for _, item := range list {
+		entry, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		k, _ := entry[keyField].(string)
+		val, _ := entry[valueField].(string)
+		if k != "" {
+			m[k] = val
+		}
+	}
+	return m
+}
+
+// FromMap converts a map-shaped tag parameter such as {"env": "prod"} into a
+// map of strings.
+func FromMap(v interface{}) map[string]string {
+	m := make(map[string]string)
+	raw, _ := v.(map[string]interface{})
+	for k, val := range raw {
+		if sv, ok := val.(string); ok {
+			m[k] = sv
+		}
+	}
+	return m
+}
+
+// ToList converts tags into the list shape used by most JSON APIs, sorted by
+// key. The result is never nil.
+func ToList(tags map[string]string, keyField, valueField string) []map[string]string {
+	list := make([]map[string]string, 0, len(tags))
+	for k, v := range tags {
+		list = append(list, map[string]string{keyField: k, valueField: v})
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i][keyField] < list[j][keyField]
+	})
+	return list
+}
+
+// Keys converts a list of tag keys from request parameters.
+func Keys(v interface{}) []string {
+	var keys []string
+	list, _ := v.([]interface{})
+	for _, item := range list {
+		if k, ok := item.(string); ok {
+			keys = append(keys, k)
+		}
+	}
+	return keys
+}
+
+// FromForm converts the list-shaped tag fields of a query-protocol request
+// into a map. prefix is the member prefix up to the index, such as
+// "Tags.member." for <prefix>1.Key and <prefix>1.Value.
+func FromForm(form url.Values, prefix string) map[string]string {
+	m := make(map[string]string)
+	for i := 1; ; i++ {
+		k := form.Get(prefix + strconv.Itoa(i) + ".Key")
+		if k == "" {
+			return m
+		}
+		m[k] = form.Get(prefix + strconv.Itoa(i) + ".Value")
+	}
+}
+
+// KeysFromForm converts the tag key fields <prefix>1, <prefix>2, ... of a
+// query-protocol request.
+func KeysFromForm(form url.Values, prefix string) []string {
+	var keys []string
+	for i := 1; ; i++ {
+		k := form.Get(prefix + strconv.Itoa(i))
+		if k == "" {
+			return keys
+		}
+		keys = append(keys, k)
+	}
+}
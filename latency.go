@@ -0,0 +1,129 @@
+package awsmock
+
+import (
+	"math/rand"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+)
+
+// Latency returns the delay to add to one request.
+type Latency func() time.Duration
+
+// FixedLatency delays every request by d.
+func FixedLatency(d time.Duration) Latency {
+	return func() time.Duration { return d }
+}
+
+// UniformLatency delays requests by a duration drawn uniformly from
+// [min, max).
+func UniformLatency(min, max time.Duration) Latency {
+	return func() time.Duration {
+		if max <= min {
+			return min
+		}
+		return min + time.Duration(rand.Int63n(int64(max-min)))
+	}
+}
+
+// NormalLatency delays requests by a normally distributed duration with the
+// given mean and standard deviation, never less than zero.
+func NormalLatency(mean, stddev time.Duration) Latency {
+	return func() time.Duration {
+		d := time.Duration(rand.NormFloat64()*float64(stddev)) + mean
+		if d < 0 {
+			return 0
+		}
+		return d
+	}
+}
+
+// LatencyProfile maps request patterns to latencies. A key is "*" for every
+// request, a service name such as "dynamodb", or "service:Action" such as
+// "dynamodb:Query". Either part may use [path.Match] wildcards, as in
+// "dynamodb:Batch*". When several keys match, the most specific wins:
+// an exact action, then an action pattern, then the service, then "*".
+//
+// Actions are known for JSON and query protocol services. Requests to REST
+// services (S3, Lambda, API Gateway) only match service-level keys.
+type LatencyProfile map[string]Latency
+
+// delay returns the latency for a request to service and action.
+func (p LatencyProfile) delay(service, action string) (time.Duration, bool) {
+	best, bestRank := Latency(nil), -1
+	for key, lat := range p {
+		rank := matchRank(key, service, action)
+		if rank > bestRank {
+			best, bestRank = lat, rank
+		}
+	}
+	if best == nil {
+		return 0, false
+	}
+	return best(), true
+}
+
+// matchRank scores how specifically key matches the request, or returns -1
+// if it does not match.
+func matchRank(key, service, action string) int {
+	if key == "*" {
+		return 0
+	}
+	svcPattern, actionPattern, hasAction := strings.Cut(key, ":")
+	if ok, _ := path.Match(svcPattern, service); !ok {
+		return -1
+	}
+	if !hasAction {
+		return 1
+	}
+	if action == "" {
+		return -1
+	}
+	if ok, _ := path.Match(actionPattern, action); !ok {
+		return -1
+	}
+	if actionPattern == action {
+		return 3
+	}
+	return 2
+}
+
+// requestAction returns the API action named by a JSON or query protocol
+// request, or "" for REST requests.
+func requestAction(r *http.Request) string {
+	if target := r.Header.Get("X-Amz-Target"); target != "" {
+		if i := strings.LastIndex(target, "."); i >= 0 {
+			return target[i+1:]
+		}
+		return target
+	}
+	if action := r.URL.Query().Get("Action"); action != "" {
+		return action
+	}
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "application/x-www-form-urlencoded") {
+		return r.PostFormValue("Action")
+	}
+	return ""
+}
+
+// applyLatency sleeps for the configured latency of the request, returning
+// false if the client gave up first.
+func (m *MockServer) applyLatency(r *http.Request, service string) bool {
+	if len(m.latency) == 0 {
+		return true
+	}
+	d, ok := m.latency.delay(service, requestAction(r))
+	if !ok || d <= 0 {
+		return true
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-r.Context().Done():
+		return false
+	}
+}
@@ -0,0 +1,188 @@
+package awsmock
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// metricsPath is where the mock serves its metrics in the Prometheus text
+// exposition format.
+const metricsPath = "/_metrics"
+
+// Call describes one request handled by the mock server, as passed to the
+// observers given to [WithMetrics].
+type Call struct {
+	Service string
+	// Action is the API action for JSON and query protocol requests, or the
+	// HTTP method for REST requests.
+	Action string
+	Status int
+	// Duration is the time the service spent handling the request, not
+	// counting latency added by [WithLatencyProfile].
+	Duration time.Duration
+}
+
+// ActionMetrics summarizes the calls made to one service action.
+type ActionMetrics struct {
+	Service       string
+	Action        string
+	Count         int
+	Errors        int // responses with status 400 or above
+	TotalDuration time.Duration
+	MaxDuration   time.Duration
+}
+
+// metrics accumulates per-action call statistics.
+type metrics struct {
+	mu        sync.Mutex
+	actions   map[[2]string]*ActionMetrics
+	observers []func(Call)
+}
+
+func newMetrics(observers []func(Call)) *metrics {
+	return &metrics{
+		actions:   make(map[[2]string]*ActionMetrics),
+		observers: observers,
+	}
+}
+
+func (mt *metrics) record(c Call) {
+	mt.mu.Lock()
+	key := [2]string{c.Service, c.Action}
+	a := mt.actions[key]
+	if a == nil {
+		a = &ActionMetrics{Service: c.Service, Action: c.Action}
+		mt.actions[key] = a
+	}
+	a.Count++
+	if c.Status >= 400 {
+		a.Errors++
+	}
+	a.TotalDuration += c.Duration
+	if c.Duration > a.MaxDuration {
+		a.MaxDuration = c.Duration
+	}
+	mt.mu.Unlock()
+
+	for _, observe := range mt.observers {
+		observe(c)
+	}
+}
+
+func (mt *metrics) snapshot() []ActionMetrics {
+	mt.mu.Lock()
+	out := make([]ActionMetrics, 0, len(mt.actions))
+	for _, a := range mt.actions {
+		out = append(out, *a)
+	}
+	mt.mu.Unlock()
+
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Service != out[j].Service {
+			return out[i].Service < out[j].Service
+		}
+		return out[i].Action < out[j].Action
+	})
+	return out
+}
+
+// Metrics returns call statistics for every service action the mock has
+// handled, sorted by service and action. It returns nil unless the server
+// was started with [WithMetrics].
+func (m *MockServer) Metrics() []ActionMetrics {
+	if m.metrics == nil {
+		return nil
+	}
+	return m.metrics.snapshot()
+}
+
+// serveMetrics writes the collected metrics in the Prometheus text format.
+func (m *MockServer) serveMetrics(w http.ResponseWriter) {
+	if m.metrics == nil {
+		http.Error(w, "metrics are not enabled", http.StatusNotFound)
+		return
+	}
+	snap := m.metrics.snapshot()
+
+	var b strings.Builder
+	b.WriteString("# HELP awsmock_requests_total Requests handled by the mock, by service and action.\n")
+	b.WriteString("# TYPE awsmock_requests_total counter\n")
+	for _, a := range snap {
+		fmt.Fprintf(&b, "awsmock_requests_total{service=%q,action=%q} %d\n", a.Service, a.Action, a.Count)
+	}
+	b.WriteString("# HELP awsmock_request_errors_total Requests that returned an error status.\n")
+	b.WriteString("# TYPE awsmock_request_errors_total counter\n")
+	for _, a := range snap {
+		fmt.Fprintf(&b, "awsmock_request_errors_total{service=%q,action=%q} %d\n", a.Service, a.Action, a.Errors)
+	}
+	b.WriteString("# HELP awsmock_request_duration_seconds Time spent handling requests.\n")
+	b.WriteString("# TYPE awsmock_request_duration_seconds summary\n")
+	for _, a := range snap {
+		fmt.Fprintf(&b, "awsmock_request_duration_seconds_sum{service=%q,action=%q} %g\n", a.Service, a.Action, a.TotalDuration.Seconds())
+		fmt.Fprintf(&b, "awsmock_request_duration_seconds_count{service=%q,action=%q} %d\n", a.Service, a.Action, a.Count)
+	}
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	w.Write([]byte(b.String()))
+}
+
+// statusRecorder captures the status code written by a service handler.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *statusRecorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	return r.ResponseWriter.Write(b)
+}
+
+// Flush lets streaming handlers flush through the recorder.
+func (r *statusRecorder) Flush() {
+	if f, ok := r.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// serveMeasured runs handler for the request and records the call in the
+// metrics and on the dashboard, whichever are enabled.
+func (m *MockServer) serveMeasured(handler http.Handler, w http.ResponseWriter, r *http.Request, service string) {
+	action := requestAction(r)
+	if action == "" {
+		action = r.Method
+	}
+	rec := &statusRecorder{ResponseWriter: w}
+	start := time.Now()
+	handler.ServeHTTP(rec, r)
+	if rec.status == 0 {
+		rec.status = http.StatusOK
+	}
+	call := Call{
+		Service:  service,
+		Action:   action,
+		Status:   rec.status,
+		Duration: time.Since(start),
+	}
+	if m.metrics != nil {
+		m.metrics.record(call)
+	}
+	m.mu.RLock()
+	d := m.dashboard
+	m.mu.RUnlock()
+	if d != nil {
+		d.record(start, call)
+	}
+}
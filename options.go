@@ -1,10 +1,52 @@
 package awsmock
 
+import (
+	"strings"
+	"time"
+)
+
 // Option configures a [MockServer].
 type Option func(*serverConfig)
 
 type serverConfig struct {
-	services []Service
+	services        []Service
+	openSearchProxy string
+	consistency     *Consistency
+	rateLimits      map[string]rateLimit
+	quotas          []quota
+	throughput      bool
+	latency         LatencyProfile
+	metrics         bool
+	observers       []func(Call)
+	spill           *spill
+	s3Dirs          []s3Dir
+	asyncDelay      time.Duration
+	stsGlobal       bool
+	cloudFront      bool
+	publicURL       string
+	middleware      []Middleware
+	sourceAccounts  []string
+}
+
+type spill struct {
+	threshold int64
+	dir       string
+}
+
+type s3Dir struct {
+	bucket string
+	dir    string
+}
+
+type rateLimit struct {
+	rate  float64
+	burst int
+}
+
+type quota struct {
+	service  string
+	resource string
+	limit    int
 }
 
 func defaultConfig() serverConfig {
@@ -18,3 +60,188 @@ func WithService(svc Service) Option {
 		c.services = append(c.services, svc)
 	}
 }
+
+// WithOpenSearchProxy forwards requests made to OpenSearch domain endpoints to
+// target (e.g. "http://localhost:9200") instead of the embedded minimal
+// index/search engine.
+func WithOpenSearchProxy(target string) Option {
+	return func(c *serverConfig) {
+		c.openSearchProxy = target
+	}
+}
+
+// WithEventualConsistency makes reads lag behind writes as described by c,
+// so that tests can catch code that assumes read-after-write consistency.
+func WithEventualConsistency(c Consistency) Option {
+	return func(cfg *serverConfig) {
+		cfg.consistency = &c
+	}
+}
+
+// WithRateLimit throttles requests to service (e.g. "sqs", "dynamodb") beyond
+// rate requests per second, allowing bursts of up to burst requests.
+// Throttled requests fail with the service's throttling error. Tokens refill
+// on the mock clock, so a test exhausts the limit by making requests and
+// restores it with [MockServer.AdvanceClock].
+func WithRateLimit(service string, rate float64, burst int) Option {
+	return func(c *serverConfig) {
+		if c.rateLimits == nil {
+			c.rateLimits = make(map[string]rateLimit)
+		}
+		c.rateLimits[service] = rateLimit{rate: rate, burst: burst}
+	}
+}
+
+// WithQuota caps how many resources of a kind service will hold, failing
+// further creates with the service's limit error. Supported quotas are
+// "buckets" for s3, "tables" for dynamodb, and "concurrent-executions" and
+// "code-storage" (in bytes) for lambda, whose GetAccountSettings reports them.
+func WithQuota(service, resource string, limit int) Option {
+	return func(c *serverConfig) {
+		c.quotas = append(c.quotas, quota{service: service, resource: resource, limit: limit})
+	}
+}
+
+// WithProvisionedThroughput makes DynamoDB tables in PROVISIONED billing mode
+// enforce their read and write capacity units, failing requests with
+// ProvisionedThroughputExceededException once a second's worth is used.
+// Capacity refills on the mock clock.
+func WithProvisionedThroughput() Option {
+	return func(c *serverConfig) {
+		c.throughput = true
+	}
+}
+
+// WithLatencyProfile delays responses as described by p, so that client
+// timeouts and context cancellation can be exercised. Latencies are real
+// time; a request whose context is cancelled while it waits gets no response.
+func WithLatencyProfile(p LatencyProfile) Option {
+	return func(c *serverConfig) {
+		c.latency = p
+	}
+}
+
+// WithMetrics records a count, error count, and handling time for every
+// service action the mock serves. Read them with [MockServer.Metrics], or
+// scrape them in the Prometheus text format from the server's /_metrics
+// path. Each observer is also called once per request, which is the place to
+// feed an OpenTelemetry meter or other metrics library.
+func WithMetrics(observers ...func(Call)) Option {
+	return func(c *serverConfig) {
+		c.metrics = true
+		c.observers = append(c.observers, observers...)
+	}
+}
+
+// WithS3Spill makes the S3 mock stream object bodies larger than threshold
+// bytes to temporary files in dir rather than holding them in memory, so
+// tests can upload objects bigger than the test process could buffer. An
+// empty dir uses the system temporary directory. By default bodies over
+// 64 MiB spill to the system temporary directory; a threshold of zero keeps
+// every body in memory. Spilled files are removed on Reset and when the
+// server stops.
+func WithS3Spill(threshold int64, dir string) Option {
+	return func(c *serverConfig) {
+		c.spill = &spill{threshold: threshold, dir: dir}
+	}
+}
+
+// WithS3Dir makes the files under dir the initial contents of an S3 bucket,
+// so fixture corpora need not be uploaded in test setup:
+//
+//	mock := awsmock.Start(t, awsmock.WithS3Dir("my-bucket", "testdata/s3/my-bucket"))
+//
+// Each file is an object keyed by its path relative to dir. Files are read
+// only when their objects are fetched, and are never modified: writes and
+// deletes change the mock's view of the bucket, and Reset mounts the
+// directory again.
+func WithS3Dir(bucket, dir string) Option {
+	return func(c *serverConfig) {
+		c.s3Dirs = append(c.s3Dirs, s3Dir{bucket: bucket, dir: dir})
+	}
+}
+
+// WithAsyncStates makes resources that AWS provisions asynchronously start in
+// their transitional status (CREATING, PENDING, creating, CREATE_IN_PROGRESS,
+// ...) and report their ready status only once delay has passed, either in
+// real time or on the mock clock. SDK waiters and polling loops then see the
+// same progression they would against AWS; tests that do not want to wait can
+// call [MockServer.AdvanceClock]. Covered resources are EKS clusters and node
+// groups, RDS instances and clusters, ECS tasks, CloudFormation stacks, ACM
+// certificates, Kinesis streams and consumers, DynamoDB tables, ElastiCache
+// clusters and replication groups, Redshift clusters, Lambda functions and
+// provisioned concurrency, Amazon MQ brokers, Secrets Manager replicas,
+// Synthetics canaries, Glue interactive sessions, and API Gateway VPC links.
+// Batch jobs spend delay in each of SUBMITTED, RUNNABLE, and RUNNING before
+// they have SUCCEEDED.
+func WithAsyncStates(delay time.Duration) Option {
+	return func(c *serverConfig) {
+		c.asyncDelay = delay
+	}
+}
+
+// WithGlobalSTSEndpoint makes configs from [MockServer.AWSConfig] send STS
+// requests from the regions enabled by default to the global endpoint, as
+// SDKs configured with sts_regional_endpoints = legacy do: the requests are
+// signed for us-east-1 and get version 1 session tokens. By default each
+// region's STS client uses that region's endpoint, as current SDKs do.
+func WithGlobalSTSEndpoint() Option {
+	return func(c *serverConfig) {
+		c.stsGlobal = true
+	}
+}
+
+// WithSourceAccounts links the given accounts to the mock's account for
+// CloudWatch cross-account observability, with the mock's account as the
+// monitoring account. Metrics recorded in a source account with
+// [CloudWatchInspector.PutSourceAccountMetrics] are read by GetMetricData queries
+// that set its AccountId, and listed by ListMetrics with
+// IncludeLinkedAccounts. Queries naming any other account fail.
+func WithSourceAccounts(accountIDs ...string) Option {
+	return func(c *serverConfig) {
+		c.sourceAccounts = append(c.sourceAccounts, accountIDs...)
+	}
+}
+
+// WithCloudFrontContent serves CloudFront distributions: GET requests for
+// {id}.cloudfront.localhost, or with a Host of {id}.cloudfront.net, are
+// answered from the distribution's S3 origins in the S3 mock. Requests are
+// routed by the cache behaviors' path patterns and cached under each
+// behavior's cache key until its TTL passes or an invalidation covers them;
+// the X-Cache response header tells hits from misses. Use it with
+// [MockServer.HTTPClient].
+func WithCloudFrontContent() Option {
+	return func(c *serverConfig) {
+		c.cloudFront = true
+	}
+}
+
+// WithPublicURL sets the URL clients reach the server at, for a server
+// started with [Listen] behind a port mapping or proxy, as in a container.
+// Endpoints the mock hands out, such as API Gateway endpoints and Cognito
+// issuers, use it.
+func WithPublicURL(url string) Option {
+	return func(c *serverConfig) {
+		c.publicURL = strings.TrimSuffix(url, "/")
+	}
+}
+
+// WithMiddleware wraps every request to a service in mw, outermost first,
+// for logging, authentication, or answering requests a test wants to
+// control:
+//
+//	logRequests := func(service string, next http.Handler) http.Handler {
+//		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+//			t.Logf("%s %s %s", service, r.Method, r.URL.Path)
+//			next.ServeHTTP(w, r)
+//		})
+//	}
+//	mock := awsmock.Start(t, awsmock.WithMiddleware(logRequests))
+//
+// Middleware does not see requests to the admin API, metrics, or health
+// endpoints.
+func WithMiddleware(mw ...Middleware) Option {
+	return func(c *serverConfig) {
+		c.middleware = append(c.middleware, mw...)
+	}
+}
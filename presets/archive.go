@@ -0,0 +1,139 @@
+package presets
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/firehose"
+	firehosetypes "github.com/aws/aws-sdk-go-v2/service/firehose/types"
+	"github.com/aws/aws-sdk-go-v2/service/iam"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/sns"
+
+	awsmock "github.com/riyanimam/goto"
+)
+
+// TopicArchiveConfig customizes [TopicArchive]. Zero fields take the
+// defaults noted.
+type TopicArchiveConfig struct {
+	// Topics names the topics whose messages are archived. Topics that do
+	// not exist are created. Default is a single "events" topic.
+	Topics []string
+
+	Bucket string // default "sns-archive"
+	Prefix string // prepended to object keys; default none
+
+	// Name names the delivery stream and its IAM role, which is named
+	// Name+"-role". Default "sns-archive".
+	Name string
+
+	// BufferInterval is how long the delivery stream buffers messages on
+	// the mock clock before writing them to one object; advance the clock
+	// with [awsmock.MockServer.AdvanceClock] to flush them. Default 0, which
+	// writes each message to its own object as it is published.
+	BufferInterval time.Duration
+
+	// RawMessageDelivery archives the published messages themselves instead
+	// of their SNS notifications.
+	RawMessageDelivery bool
+}
+
+// TopicArchiveFixture describes the resources created by [TopicArchive].
+type TopicArchiveFixture struct {
+	Bucket            string
+	Prefix            string
+	DeliveryStream    string
+	DeliveryStreamARN string
+	RoleARN           string
+
+	// TopicARNs and SubscriptionARNs are keyed by topic name.
+	TopicARNs        map[string]string
+	SubscriptionARNs map[string]string
+}
+
+// TopicArchive archives the messages published to SNS topics in an S3
+// bucket, as JSON lines, through a Firehose delivery stream with an
+// extended S3 destination that each topic has a firehose subscription to.
+// Each line is a message's SNS notification, or the message itself with
+// RawMessageDelivery.
+func TopicArchive(t testing.TB, mock *awsmock.MockServer, c TopicArchiveConfig) *TopicArchiveFixture {
+	t.Helper()
+	ctx := context.Background()
+	cfg := config(t, mock)
+
+	name := orDefault(c.Name, "sns-archive")
+	topics := c.Topics
+	if len(topics) == 0 {
+		topics = []string{"events"}
+	}
+	f := &TopicArchiveFixture{
+		Bucket:           orDefault(c.Bucket, "sns-archive"),
+		Prefix:           c.Prefix,
+		DeliveryStream:   name,
+		TopicARNs:        make(map[string]string, len(topics)),
+		SubscriptionARNs: make(map[string]string, len(topics)),
+	}
+
+	s3Client := s3.NewFromConfig(cfg, func(o *s3.Options) { o.UsePathStyle = true })
+	if _, err := s3Client.CreateBucket(ctx, &s3.CreateBucketInput{Bucket: aws.String(f.Bucket)}); err != nil {
+		t.Fatalf("presets: CreateBucket: %v", err)
+	}
+
+	// One role serves Firehose writing to the bucket and SNS writing to the
+	// stream.
+	role, err := iam.NewFromConfig(cfg).CreateRole(ctx, &iam.CreateRoleInput{
+		RoleName:                 aws.String(name + "-role"),
+		AssumeRolePolicyDocument: aws.String(`{"Version":"2012-10-17","Statement":[{"Effect":"Allow","Principal":{"Service":["firehose.amazonaws.com","sns.amazonaws.com"]},"Action":"sts:AssumeRole"}]}`),
+	})
+	if err != nil {
+		t.Fatalf("presets: CreateRole: %v", err)
+	}
+	f.RoleARN = aws.ToString(role.Role.Arn)
+
+	stream, err := firehose.NewFromConfig(cfg).CreateDeliveryStream(ctx, &firehose.CreateDeliveryStreamInput{
+		DeliveryStreamName: aws.String(name),
+		DeliveryStreamType: firehosetypes.DeliveryStreamTypeDirectPut,
+		ExtendedS3DestinationConfiguration: &firehosetypes.ExtendedS3DestinationConfiguration{
+			BucketARN: aws.String("arn:aws:s3:::" + f.Bucket),
+			RoleARN:   role.Role.Arn,
+			Prefix:    aws.String(f.Prefix),
+			BufferingHints: &firehosetypes.BufferingHints{
+				IntervalInSeconds: aws.Int32(int32(c.BufferInterval / time.Second)),
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("presets: CreateDeliveryStream: %v", err)
+	}
+	f.DeliveryStreamARN = aws.ToString(stream.DeliveryStreamARN)
+
+	raw := "false"
+	if c.RawMessageDelivery {
+		raw = "true"
+	}
+	snsClient := sns.NewFromConfig(cfg)
+	for _, topicName := range topics {
+		topic, err := snsClient.CreateTopic(ctx, &sns.CreateTopicInput{Name: aws.String(topicName)})
+		if err != nil {
+			t.Fatalf("presets: CreateTopic %s: %v", topicName, err)
+		}
+		sub, err := snsClient.Subscribe(ctx, &sns.SubscribeInput{
+			TopicArn: topic.TopicArn,
+			Protocol: aws.String("firehose"),
+			Endpoint: aws.String(f.DeliveryStreamARN),
+			Attributes: map[string]string{
+				"SubscriptionRoleArn": f.RoleARN,
+				"RawMessageDelivery":  raw,
+			},
+			ReturnSubscriptionArn: true,
+		})
+		if err != nil {
+			t.Fatalf("presets: Subscribe %s: %v", topicName, err)
+		}
+		f.TopicARNs[topicName] = aws.ToString(topic.TopicArn)
+		f.SubscriptionARNs[topicName] = aws.ToString(sub.SubscriptionArn)
+	}
+	return f
+}
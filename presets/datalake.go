@@ -0,0 +1,157 @@
+package presets
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/athena"
+	athenatypes "github.com/aws/aws-sdk-go-v2/service/athena/types"
+	"github.com/aws/aws-sdk-go-v2/service/glue"
+	gluetypes "github.com/aws/aws-sdk-go-v2/service/glue/types"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+
+	awsmock "github.com/riyanimam/goto"
+)
+
+// DataLakeConfig customizes [DataLake]. Zero fields take the defaults noted.
+type DataLakeConfig struct {
+	Bucket    string // default "datalake"
+	Database  string // default "analytics"
+	Table     string // default "events"
+	WorkGroup string // default "datalake"
+
+	// Partitions are the values of the table's "dt" partition key. Default
+	// is three consecutive days starting 2024-01-01.
+	Partitions []string
+
+	// RowsPerPartition is the number of sample rows written to each
+	// partition. Default is 10.
+	RowsPerPartition int
+}
+
+// DataLakeFixture describes the resources created by [DataLake].
+type DataLakeFixture struct {
+	Bucket     string
+	Database   string
+	Table      string
+	WorkGroup  string
+	Partitions []string
+
+	// Location is the table's S3 location, e.g. "s3://datalake/events/".
+	Location string
+
+	// OutputLocation is where the workgroup writes query results.
+	OutputLocation string
+
+	// Keys are the S3 keys of the sample data files, one per partition.
+	Keys []string
+}
+
+// dataLakeColumns is the schema of the sample data.
+var dataLakeColumns = []gluetypes.Column{
+	{Name: aws.String("id"), Type: aws.String("bigint")},
+	{Name: aws.String("event"), Type: aws.String("string")},
+	{Name: aws.String("value"), Type: aws.String("double")},
+}
+
+// DataLake creates an S3 bucket holding CSV sample data partitioned by day
+// (table/dt=YYYY-MM-DD/part-00000.csv), a Glue database and external table
+// over it, and an Athena workgroup whose results go to the same bucket.
+func DataLake(t testing.TB, mock *awsmock.MockServer, c DataLakeConfig) *DataLakeFixture {
+	t.Helper()
+	ctx := context.Background()
+	cfg := config(t, mock)
+
+	f := &DataLakeFixture{
+		Bucket:     orDefault(c.Bucket, "datalake"),
+		Database:   orDefault(c.Database, "analytics"),
+		Table:      orDefault(c.Table, "events"),
+		WorkGroup:  orDefault(c.WorkGroup, "datalake"),
+		Partitions: c.Partitions,
+	}
+	if len(f.Partitions) == 0 {
+		f.Partitions = []string{"2024-01-01", "2024-01-02", "2024-01-03"}
+	}
+	rows := c.RowsPerPartition
+	if rows <= 0 {
+		rows = 10
+	}
+	f.Location = fmt.Sprintf("s3://%s/%s/", f.Bucket, f.Table)
+	f.OutputLocation = fmt.Sprintf("s3://%s/athena-results/", f.Bucket)
+
+	s3Client := s3.NewFromConfig(cfg, func(o *s3.Options) { o.UsePathStyle = true })
+	if _, err := s3Client.CreateBucket(ctx, &s3.CreateBucketInput{Bucket: aws.String(f.Bucket)}); err != nil {
+		t.Fatalf("presets: CreateBucket: %v", err)
+	}
+	id := 0
+	for _, dt := range f.Partitions {
+		var buf bytes.Buffer
+		fmt.Fprintln(&buf, "id,event,value")
+		for i := 0; i < rows; i++ {
+			id++
+			fmt.Fprintf(&buf, "%d,%s,%.2f\n", id, []string{"view", "click", "purchase"}[id%3], float64(id)*1.5)
+		}
+		key := fmt.Sprintf("%s/dt=%s/part-00000.csv", f.Table, dt)
+		_, err := s3Client.PutObject(ctx, &s3.PutObjectInput{
+			Bucket:      aws.String(f.Bucket),
+			Key:         aws.String(key),
+			Body:        bytes.NewReader(buf.Bytes()),
+			ContentType: aws.String("text/csv"),
+		})
+		if err != nil {
+			t.Fatalf("presets: PutObject %s: %v", key, err)
+		}
+		f.Keys = append(f.Keys, key)
+	}
+
+	glueClient := glue.NewFromConfig(cfg)
+	_, err := glueClient.CreateDatabase(ctx, &glue.CreateDatabaseInput{
+		DatabaseInput: &gluetypes.DatabaseInput{Name: aws.String(f.Database)},
+	})
+	if err != nil {
+		t.Fatalf("presets: CreateDatabase: %v", err)
+	}
+	_, err = glueClient.CreateTable(ctx, &glue.CreateTableInput{
+		DatabaseName: aws.String(f.Database),
+		TableInput: &gluetypes.TableInput{
+			Name:          aws.String(f.Table),
+			TableType:     aws.String("EXTERNAL_TABLE"),
+			PartitionKeys: []gluetypes.Column{{Name: aws.String("dt"), Type: aws.String("string")}},
+			Parameters: map[string]string{
+				"classification":         "csv",
+				"skip.header.line.count": "1",
+			},
+			StorageDescriptor: &gluetypes.StorageDescriptor{
+				Columns:      dataLakeColumns,
+				Location:     aws.String(f.Location),
+				InputFormat:  aws.String("org.apache.hadoop.mapred.TextInputFormat"),
+				OutputFormat: aws.String("org.apache.hadoop.hive.ql.io.HiveIgnoreKeyTextOutputFormat"),
+				SerdeInfo: &gluetypes.SerDeInfo{
+					SerializationLibrary: aws.String("org.apache.hadoop.hive.serde2.lazy.LazySimpleSerDe"),
+					Parameters:           map[string]string{"field.delim": ","},
+				},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("presets: CreateTable: %v", err)
+	}
+
+	athenaClient := athena.NewFromConfig(cfg)
+	_, err = athenaClient.CreateWorkGroup(ctx, &athena.CreateWorkGroupInput{
+		Name: aws.String(f.WorkGroup),
+		Configuration: &athenatypes.WorkGroupConfiguration{
+			ResultConfiguration: &athenatypes.ResultConfiguration{
+				OutputLocation: aws.String(f.OutputLocation),
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("presets: CreateWorkGroup: %v", err)
+	}
+
+	return f
+}
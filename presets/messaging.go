@@ -0,0 +1,101 @@
+package presets
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sns"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	sqstypes "github.com/aws/aws-sdk-go-v2/service/sqs/types"
+
+	awsmock "github.com/riyanimam/goto"
+)
+
+// TopicQueueConfig customizes [TopicQueue]. Zero fields take the defaults
+// noted.
+type TopicQueueConfig struct {
+	// Name names the topic and queue; the dead-letter queue is named
+	// Name+"-dlq". Default "events".
+	Name string
+
+	// MaxReceiveCount is the number of receives after which the queue's
+	// redrive policy moves a message to the dead-letter queue. Default 3.
+	MaxReceiveCount int
+}
+
+// TopicQueueFixture describes the resources created by [TopicQueue].
+type TopicQueueFixture struct {
+	TopicARN        string
+	SubscriptionARN string
+	QueueURL        string
+	QueueARN        string
+	DLQURL          string
+	DLQARN          string
+}
+
+// TopicQueue creates an SNS topic with an SQS queue subscribed to it using
+// raw message delivery, so messages published to the topic arrive in the
+// queue unwrapped. The queue's redrive policy targets a dead-letter queue.
+func TopicQueue(t testing.TB, mock *awsmock.MockServer, c TopicQueueConfig) *TopicQueueFixture {
+	t.Helper()
+	ctx := context.Background()
+	cfg := config(t, mock)
+
+	name := orDefault(c.Name, "events")
+	maxReceives := c.MaxReceiveCount
+	if maxReceives == 0 {
+		maxReceives = 3
+	}
+	f := &TopicQueueFixture{}
+	sqsClient := sqs.NewFromConfig(cfg)
+
+	f.DLQURL, f.DLQARN = createQueue(t, sqsClient, name+"-dlq", nil)
+	f.QueueURL, f.QueueARN = createQueue(t, sqsClient, name, map[string]string{
+		string(sqstypes.QueueAttributeNameRedrivePolicy): fmt.Sprintf(
+			`{"deadLetterTargetArn":%q,"maxReceiveCount":"%d"}`, f.DLQARN, maxReceives),
+	})
+
+	snsClient := sns.NewFromConfig(cfg)
+	topic, err := snsClient.CreateTopic(ctx, &sns.CreateTopicInput{Name: aws.String(name)})
+	if err != nil {
+		t.Fatalf("presets: CreateTopic: %v", err)
+	}
+	f.TopicARN = aws.ToString(topic.TopicArn)
+
+	sub, err := snsClient.Subscribe(ctx, &sns.SubscribeInput{
+		TopicArn:              topic.TopicArn,
+		Protocol:              aws.String("sqs"),
+		Endpoint:              aws.String(f.QueueARN),
+		Attributes:            map[string]string{"RawMessageDelivery": "true"},
+		ReturnSubscriptionArn: true,
+	})
+	if err != nil {
+		t.Fatalf("presets: Subscribe: %v", err)
+	}
+	f.SubscriptionARN = aws.ToString(sub.SubscriptionArn)
+	return f
+}
+
+// createQueue creates a queue and returns its URL and ARN, failing t on error.
+func createQueue(t testing.TB, client *sqs.Client, name string, attrs map[string]string) (url, arn string) {
+	t.Helper()
+	ctx := context.Background()
+
+	q, err := client.CreateQueue(ctx, &sqs.CreateQueueInput{
+		QueueName:  aws.String(name),
+		Attributes: attrs,
+	})
+	if err != nil {
+		t.Fatalf("presets: CreateQueue %s: %v", name, err)
+	}
+	out, err := client.GetQueueAttributes(ctx, &sqs.GetQueueAttributesInput{
+		QueueUrl:       q.QueueUrl,
+		AttributeNames: []sqstypes.QueueAttributeName{sqstypes.QueueAttributeNameQueueArn},
+	})
+	if err != nil {
+		t.Fatalf("presets: GetQueueAttributes %s: %v", name, err)
+	}
+	return aws.ToString(q.QueueUrl), out.Attributes[string(sqstypes.QueueAttributeNameQueueArn)]
+}
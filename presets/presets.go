@@ -0,0 +1,35 @@
+// Package presets builds common multi-service fixtures on an
+// [awsmock.MockServer] in one call.
+//
+// Each preset creates its resources through the AWS SDK against the mock, as
+// the code under test would, and fails the test if any step fails. Presets
+// return the names, ARNs, and URLs of what they created so tests can
+// reference them directly.
+package presets
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+
+	awsmock "github.com/riyanimam/goto"
+)
+
+// config returns the SDK configuration for mock, failing t on error.
+func config(t testing.TB, mock *awsmock.MockServer) aws.Config {
+	t.Helper()
+	cfg, err := mock.AWSConfig(context.Background())
+	if err != nil {
+		t.Fatalf("presets: AWSConfig: %v", err)
+	}
+	return cfg
+}
+
+// orDefault returns v, or def if v is empty.
+func orDefault(v, def string) string {
+	if v == "" {
+		return def
+	}
+	return v
+}
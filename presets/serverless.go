@@ -0,0 +1,162 @@
+package presets
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/apigatewayv2"
+	apigwtypes "github.com/aws/aws-sdk-go-v2/service/apigatewayv2/types"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	dbtypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-sdk-go-v2/service/iam"
+	awslambda "github.com/aws/aws-sdk-go-v2/service/lambda"
+	lambdatypes "github.com/aws/aws-sdk-go-v2/service/lambda/types"
+
+	awsmock "github.com/riyanimam/goto"
+	"github.com/riyanimam/goto/services/lambda"
+)
+
+// ServerlessConfig customizes [ServerlessAPI]. Zero fields take the defaults
+// noted.
+type ServerlessConfig struct {
+	// Name names the API, function, role, and table. Default "app".
+	Name string
+
+	// Handler runs for every request routed to the function. It receives
+	// an API Gateway payload format 2.0 event and returns either a
+	// {statusCode, headers, body} response or a JSON body. Required.
+	Handler lambda.HandlerFunc
+
+	// Routes are the route keys sent to the function. Default is
+	// "ANY /{proxy+}" and "ANY /".
+	Routes []string
+
+	// Stage is the stage name. Default "$default", which serves the API at
+	// the root of its endpoint.
+	Stage string
+
+	// PartitionKey is the table's string hash key. Default "id".
+	PartitionKey string
+}
+
+// ServerlessFixture describes the resources created by [ServerlessAPI].
+type ServerlessFixture struct {
+	// URL is the base URL of the deployed stage. Requests to it run the
+	// handler.
+	URL string
+
+	APIID        string
+	FunctionName string
+	FunctionARN  string
+	RoleARN      string
+	TableName    string
+	TableARN     string
+}
+
+// ServerlessAPI creates a DynamoDB table, an IAM execution role, and a Lambda
+// function backed by cfg.Handler, then exposes the function through an API
+// Gateway HTTP API. The function's environment has TABLE_NAME set to the
+// table, as a deployed function would.
+func ServerlessAPI(t testing.TB, mock *awsmock.MockServer, c ServerlessConfig) *ServerlessFixture {
+	t.Helper()
+	if c.Handler == nil {
+		t.Fatalf("presets: ServerlessAPI requires a Handler")
+	}
+	ctx := context.Background()
+	cfg := config(t, mock)
+
+	name := orDefault(c.Name, "app")
+	stage := orDefault(c.Stage, "$default")
+	routes := c.Routes
+	if len(routes) == 0 {
+		routes = []string{"ANY /{proxy+}", "ANY /"}
+	}
+	f := &ServerlessFixture{FunctionName: name, TableName: name}
+
+	table, err := dynamodb.NewFromConfig(cfg).CreateTable(ctx, &dynamodb.CreateTableInput{
+		TableName: aws.String(name),
+		KeySchema: []dbtypes.KeySchemaElement{
+			{AttributeName: aws.String(orDefault(c.PartitionKey, "id")), KeyType: dbtypes.KeyTypeHash},
+		},
+		AttributeDefinitions: []dbtypes.AttributeDefinition{
+			{AttributeName: aws.String(orDefault(c.PartitionKey, "id")), AttributeType: dbtypes.ScalarAttributeTypeS},
+		},
+		BillingMode: dbtypes.BillingModePayPerRequest,
+	})
+	if err != nil {
+		t.Fatalf("presets: CreateTable: %v", err)
+	}
+	f.TableARN = aws.ToString(table.TableDescription.TableArn)
+
+	role, err := iam.NewFromConfig(cfg).CreateRole(ctx, &iam.CreateRoleInput{
+		RoleName:                 aws.String(name + "-role"),
+		AssumeRolePolicyDocument: aws.String(`{"Version":"2012-10-17","Statement":[{"Effect":"Allow","Principal":{"Service":"lambda.amazonaws.com"},"Action":"sts:AssumeRole"}]}`),
+	})
+	if err != nil {
+		t.Fatalf("presets: CreateRole: %v", err)
+	}
+	f.RoleARN = aws.ToString(role.Role.Arn)
+
+	if err := mock.Lambda().RegisterHandler(name, c.Handler); err != nil {
+		t.Fatalf("presets: %v", err)
+	}
+	fn, err := awslambda.NewFromConfig(cfg).CreateFunction(ctx, &awslambda.CreateFunctionInput{
+		FunctionName: aws.String(name),
+		Runtime:      lambdatypes.RuntimeProvidedal2023,
+		Role:         role.Role.Arn,
+		Handler:      aws.String("bootstrap"),
+		Code:         &lambdatypes.FunctionCode{ZipFile: []byte("preset")},
+		Environment: &lambdatypes.Environment{
+			Variables: map[string]string{"TABLE_NAME": name},
+		},
+	})
+	if err != nil {
+		t.Fatalf("presets: CreateFunction: %v", err)
+	}
+	f.FunctionARN = aws.ToString(fn.FunctionArn)
+
+	apigw := apigatewayv2.NewFromConfig(cfg)
+	api, err := apigw.CreateApi(ctx, &apigatewayv2.CreateApiInput{
+		Name:         aws.String(name),
+		ProtocolType: apigwtypes.ProtocolTypeHttp,
+	})
+	if err != nil {
+		t.Fatalf("presets: CreateApi: %v", err)
+	}
+	f.APIID = aws.ToString(api.ApiId)
+
+	integration, err := apigw.CreateIntegration(ctx, &apigatewayv2.CreateIntegrationInput{
+		ApiId:                api.ApiId,
+		IntegrationType:      apigwtypes.IntegrationTypeAwsProxy,
+		IntegrationUri:       fn.FunctionArn,
+		PayloadFormatVersion: aws.String("2.0"),
+	})
+	if err != nil {
+		t.Fatalf("presets: CreateIntegration: %v", err)
+	}
+	for _, key := range routes {
+		_, err := apigw.CreateRoute(ctx, &apigatewayv2.CreateRouteInput{
+			ApiId:    api.ApiId,
+			RouteKey: aws.String(key),
+			Target:   aws.String("integrations/" + aws.ToString(integration.IntegrationId)),
+		})
+		if err != nil {
+			t.Fatalf("presets: CreateRoute %s: %v", key, err)
+		}
+	}
+	_, err = apigw.CreateStage(ctx, &apigatewayv2.CreateStageInput{
+		ApiId:      api.ApiId,
+		StageName:  aws.String(stage),
+		AutoDeploy: aws.Bool(true),
+	})
+	if err != nil {
+		t.Fatalf("presets: CreateStage: %v", err)
+	}
+
+	f.URL = aws.ToString(api.ApiEndpoint)
+	if stage != "$default" {
+		f.URL += "/" + stage
+	}
+	return f
+}
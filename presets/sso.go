@@ -0,0 +1,170 @@
+package presets
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials/ssocreds"
+	"github.com/aws/aws-sdk-go-v2/service/ssooidc"
+
+	awsmock "github.com/riyanimam/goto"
+)
+
+// SSOProfileConfig customizes [SSOProfile]. Zero fields take the defaults
+// noted.
+type SSOProfileConfig struct {
+	Profile  string // default "sso"
+	Session  string // sso-session name; default "mock-sso"
+	StartURL string // default "https://mock.awsapps.com/start"
+	Region   string // default "us-east-1"
+
+	// AccountID and RoleName are the role the profile signs in to. Default
+	// the mock account and "Developer". Unless the SSO admin mock holds
+	// account assignments, any role in the mock account can be used.
+	AccountID string
+	RoleName  string
+}
+
+// SSOProfileFixture describes the files written by [SSOProfile].
+type SSOProfileFixture struct {
+	Profile string
+	Session string
+
+	// HomeDir is the temporary home directory the token cache is written
+	// under, ConfigFile the shared config file, and TokenCacheFile the
+	// cached token of the sso-session.
+	HomeDir        string
+	ConfigFile     string
+	TokenCacheFile string
+
+	ClientID     string
+	ClientSecret string
+	AccessToken  string
+	RefreshToken string
+}
+
+// SSOProfile signs in to the SSO mock as the AWS CLI's "aws sso login"
+// would, registering a client, authorizing a device, approving it at its
+// verification URI, and caching the token, then writes a shared config
+// file with a profile using the sso-session. Loading the profile with
+// config.LoadDefaultConfig resolves role credentials from the mock.
+//
+// The environment is pointed at the files with t.Setenv, setting HOME,
+// AWS_CONFIG_FILE, and AWS_SHARED_CREDENTIALS_FILE, so tests using
+// SSOProfile cannot run in parallel.
+func SSOProfile(t testing.TB, mock *awsmock.MockServer, c SSOProfileConfig) *SSOProfileFixture {
+	t.Helper()
+	ctx := context.Background()
+
+	f := &SSOProfileFixture{
+		Profile: orDefault(c.Profile, "sso"),
+		Session: orDefault(c.Session, "mock-sso"),
+		HomeDir: t.TempDir(),
+	}
+	startURL := orDefault(c.StartURL, "https://mock.awsapps.com/start")
+	region := orDefault(c.Region, "us-east-1")
+
+	client := ssooidc.NewFromConfig(config(t, mock), func(o *ssooidc.Options) { o.Region = region })
+	reg, err := client.RegisterClient(ctx, &ssooidc.RegisterClientInput{
+		ClientName: aws.String("awsmock-presets"),
+		ClientType: aws.String("public"),
+		Scopes:     []string{"sso:account:access"},
+	})
+	if err != nil {
+		t.Fatalf("presets: RegisterClient: %v", err)
+	}
+	f.ClientID, f.ClientSecret = aws.ToString(reg.ClientId), aws.ToString(reg.ClientSecret)
+
+	device, err := client.StartDeviceAuthorization(ctx, &ssooidc.StartDeviceAuthorizationInput{
+		ClientId:     reg.ClientId,
+		ClientSecret: reg.ClientSecret,
+		StartUrl:     aws.String(startURL),
+	})
+	if err != nil {
+		t.Fatalf("presets: StartDeviceAuthorization: %v", err)
+	}
+	resp, err := http.Get(aws.ToString(device.VerificationUriComplete))
+	if err != nil {
+		t.Fatalf("presets: approving device authorization: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("presets: approving device authorization: %s", resp.Status)
+	}
+
+	tok, err := client.CreateToken(ctx, &ssooidc.CreateTokenInput{
+		ClientId:     reg.ClientId,
+		ClientSecret: reg.ClientSecret,
+		GrantType:    aws.String("urn:ietf:params:oauth:grant-type:device_code"),
+		DeviceCode:   device.DeviceCode,
+	})
+	if err != nil {
+		t.Fatalf("presets: CreateToken: %v", err)
+	}
+	f.AccessToken, f.RefreshToken = aws.ToString(tok.AccessToken), aws.ToString(tok.RefreshToken)
+	expiresAt := mock.Now().Add(time.Duration(tok.ExpiresIn) * time.Second)
+
+	t.Setenv("HOME", f.HomeDir)
+	t.Setenv("USERPROFILE", f.HomeDir)
+	f.TokenCacheFile, err = ssocreds.StandardCachedTokenFilepath(f.Session)
+	if err != nil {
+		t.Fatalf("presets: token cache path: %v", err)
+	}
+	cached, _ := json.Marshal(map[string]interface{}{
+		"startUrl":              startURL,
+		"region":                region,
+		"accessToken":           f.AccessToken,
+		"expiresAt":             expiresAt.UTC().Format(time.RFC3339),
+		"refreshToken":          f.RefreshToken,
+		"clientId":              f.ClientID,
+		"clientSecret":          f.ClientSecret,
+		"registrationExpiresAt": time.Unix(reg.ClientSecretExpiresAt, 0).UTC().Format(time.RFC3339),
+	})
+	writeFile(t, f.TokenCacheFile, cached)
+
+	// The SSO clients the SDK builds to resolve credentials do not use
+	// the config's base endpoint, so the profile points them at the mock
+	// through a services section.
+	endpoint := mock.Endpoint()
+	var b strings.Builder
+	fmt.Fprintf(&b, "[profile %s]\n", f.Profile)
+	fmt.Fprintf(&b, "sso_session = %s\n", f.Session)
+	fmt.Fprintf(&b, "sso_account_id = %s\n", orDefault(c.AccountID, "123456789012"))
+	fmt.Fprintf(&b, "sso_role_name = %s\n", orDefault(c.RoleName, "Developer"))
+	fmt.Fprintf(&b, "region = %s\n", region)
+	fmt.Fprintf(&b, "endpoint_url = %s\n", endpoint)
+	fmt.Fprintf(&b, "services = %s\n\n", f.Session)
+	fmt.Fprintf(&b, "[sso-session %s]\n", f.Session)
+	fmt.Fprintf(&b, "sso_start_url = %s\n", startURL)
+	fmt.Fprintf(&b, "sso_region = %s\n", region)
+	fmt.Fprintf(&b, "sso_registration_scopes = sso:account:access\n\n")
+	fmt.Fprintf(&b, "[services %s]\n", f.Session)
+	fmt.Fprintf(&b, "sso =\n  endpoint_url = %s\n", endpoint)
+	fmt.Fprintf(&b, "sso_oidc =\n  endpoint_url = %s\n", endpoint)
+	f.ConfigFile = filepath.Join(f.HomeDir, ".aws", "config")
+	writeFile(t, f.ConfigFile, []byte(b.String()))
+
+	t.Setenv("AWS_CONFIG_FILE", f.ConfigFile)
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", filepath.Join(f.HomeDir, ".aws", "credentials"))
+	return f
+}
+
+// writeFile writes data to path, creating its directory, failing t on
+// error.
+func writeFile(t testing.TB, path string, data []byte) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		t.Fatalf("presets: %v", err)
+	}
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatalf("presets: %v", err)
+	}
+}
@@ -0,0 +1,249 @@
+package awsmock
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/riyanimam/goto/internal/mockhelpers"
+)
+
+// Dependent is implemented by services that cannot work without other
+// services, such as a service that delivers messages to SQS queues. The
+// server refuses to start, and [MockServer.Register] panics, if a
+// dependency is not registered.
+type Dependent interface {
+	// Dependencies returns the names of the services this one needs, as
+	// returned by their Name methods.
+	Dependencies() []string
+}
+
+// Initializer is implemented by services that need the server's shared
+// context: the account and region, the mock clock, and the other
+// registered services. Init is called once every service given to the
+// server is registered, after the Init of each service it depends on, and
+// again whenever the service is registered with [MockServer.Register] once
+// the server is running.
+type Initializer interface {
+	Init(r Registry) error
+}
+
+// Middleware wraps the handling of requests to service. Middleware given to
+// [WithMiddleware], and middleware contributed by services implementing
+// [MiddlewareProvider], sees every request a service is identified for,
+// before latency, injected faults, and throttling apply, and can log it,
+// answer it itself, or pass it on to next.
+type Middleware func(service string, next http.Handler) http.Handler
+
+// MiddlewareProvider is implemented by services that take part in the
+// handling of requests to every service, such as a service enforcing
+// authentication. Their middleware runs after that given to
+// [WithMiddleware], in the order the services were registered.
+type MiddlewareProvider interface {
+	Middleware() Middleware
+}
+
+// Registry is the shared context passed to [Initializer] services.
+type Registry struct {
+	m *MockServer
+}
+
+// AccountID returns the account the mock's resources belong to.
+func (r Registry) AccountID() string { return mockhelpers.DefaultAccountID }
+
+// Region returns the region of the configs returned by
+// [MockServer.AWSConfig].
+func (r Registry) Region() string { return "us-east-1" }
+
+// URL returns the base URL of the mock server, as [MockServer.URL] does.
+func (r Registry) URL() string { return r.m.URL() }
+
+// Now returns the current time of the mock clock.
+func (r Registry) Now() time.Time { return r.m.clock.Now() }
+
+// OnAdvance registers fn to be called with the previous and new time each
+// time the mock clock is advanced.
+func (r Registry) OnAdvance(fn func(from, to time.Time)) { r.m.clock.OnAdvance(fn) }
+
+// Service returns the service registered under name at the time of the
+// call, so that a replacement registered later is seen.
+func (r Registry) Service(name string) (Service, bool) {
+	r.m.mu.RLock()
+	defer r.m.mu.RUnlock()
+	svc, ok := r.m.services[name]
+	return svc, ok
+}
+
+// Deliver hands payload to the resource identified by arn, such as an SQS
+// queue or Lambda function, through the service that owns it.
+func (r Registry) Deliver(arn string, payload []byte) ([]byte, error) {
+	return r.m.dispatch(arn, payload)
+}
+
+// Resolve reports an error if arn is malformed, or if the service owning
+// it can tell that the resource does not exist.
+func (r Registry) Resolve(arn string) error { return r.m.resolve(arn) }
+
+// Resources lists the resources service holds, as [MockServer.ExportState]
+// does, without tags.
+func (r Registry) Resources(service string) []Resource {
+	listed := r.m.listResources(service)
+	resources := make([]Resource, 0, len(listed))
+	for _, res := range listed {
+		resources = append(resources, Resource{
+			Service:    service,
+			Type:       res.Type,
+			ID:         res.ID,
+			ARN:        res.ARN,
+			Attributes: res.Attributes,
+		})
+	}
+	return resources
+}
+
+// initialize checks that the dependencies of the registered services are
+// met and initializes them, each after the services it depends on.
+func (m *MockServer) initialize() error {
+	m.mu.RLock()
+	names := make([]string, 0, len(m.services))
+	for name := range m.services {
+		names = append(names, name)
+	}
+	m.mu.RUnlock()
+	sort.Strings(names)
+
+	// Visit services depth first so that each is initialized after its
+	// dependencies, reporting cycles and missing services.
+	const (
+		visiting = 1
+		done     = 2
+	)
+	state := make(map[string]int, len(names))
+	var visit func(name string, from []string) error
+	visit = func(name string, from []string) error {
+		switch state[name] {
+		case done:
+			return nil
+		case visiting:
+			return fmt.Errorf("services depend on each other: %v", append(from, name))
+		}
+		state[name] = visiting
+		svc, _ := Registry{m}.Service(name)
+		if d, ok := svc.(Dependent); ok {
+			for _, dep := range d.Dependencies() {
+				if _, ok := (Registry{m}).Service(dep); !ok {
+					return fmt.Errorf("%s depends on %s, which is not registered", name, dep)
+				}
+				if err := visit(dep, append(from, name)); err != nil {
+					return err
+				}
+			}
+		}
+		state[name] = done
+		return m.initService(svc)
+	}
+	for _, name := range names {
+		if err := visit(name, nil); err != nil {
+			return err
+		}
+	}
+
+	m.mu.Lock()
+	m.started = true
+	m.mu.Unlock()
+	return nil
+}
+
+// initService calls the service's Init, if it has one.
+func (m *MockServer) initService(svc Service) error {
+	i, ok := svc.(Initializer)
+	if !ok {
+		return nil
+	}
+	if err := i.Init(Registry{m}); err != nil {
+		return fmt.Errorf("initializing %s: %w", svc.Name(), err)
+	}
+	return nil
+}
+
+// checkDependencies reports the first dependency of svc that is not
+// registered.
+func (m *MockServer) checkDependencies(svc Service) error {
+	d, ok := svc.(Dependent)
+	if !ok {
+		return nil
+	}
+	for _, dep := range d.Dependencies() {
+		if _, ok := (Registry{m}).Service(dep); !ok {
+			return fmt.Errorf("%s depends on %s, which is not registered", svc.Name(), dep)
+		}
+	}
+	return nil
+}
+
+// handler returns the handler for a request to service: the middleware
+// chain, ending with the service itself.
+func (m *MockServer) handler(service string) http.Handler {
+	m.mu.RLock()
+	svc, ok := m.services[service]
+	measured := m.metrics != nil || m.dashboard != nil
+	chain := append([]Middleware(nil), m.middleware...)
+	for _, name := range m.order {
+		if p, ok := m.services[name].(MiddlewareProvider); ok {
+			if mw := p.Middleware(); mw != nil {
+				chain = append(chain, mw)
+			}
+		}
+	}
+	m.mu.RUnlock()
+	chain = append(chain, m.latencyMiddleware, m.faultMiddleware, m.scenarioMiddleware, m.throttleMiddleware)
+
+	var next http.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !ok {
+			http.Error(w, "unknown service: "+service, http.StatusBadRequest)
+			return
+		}
+		if measured {
+			m.serveMeasured(svc.Handler(), w, r, service)
+			return
+		}
+		svc.Handler().ServeHTTP(w, r)
+	})
+	for i := len(chain) - 1; i >= 0; i-- {
+		next = chain[i](service, next)
+	}
+	return next
+}
+
+// latencyMiddleware delays requests as the latency profile says, dropping
+// those whose context is cancelled while they wait.
+func (m *MockServer) latencyMiddleware(service string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if m.applyLatency(r, service) {
+			next.ServeHTTP(w, r)
+		}
+	})
+}
+
+// faultMiddleware fails requests matching an injected fault.
+func (m *MockServer) faultMiddleware(service string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if f, ok := m.injectedFault(r, service); ok {
+			writeServiceError(w, r, service, f.Status, f.Code, f.Message)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// throttleMiddleware rejects requests beyond the service's rate limit.
+func (m *MockServer) throttleMiddleware(service string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if m.throttled(service) {
+			writeThrottled(w, r, service)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
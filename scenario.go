@@ -0,0 +1,265 @@
+package awsmock
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/riyanimam/goto/internal/arn"
+)
+
+// Scenario scripts how the mock answers a sequence of matching requests,
+// for testing waiters and sagas that depend on what happens from one call
+// to the next. Each step answers the number of requests given to
+// [Scenario.Times], one by default, and the next request goes to the next
+// step. Once every step has answered its requests, matching requests are
+// handled as usual again:
+//
+//	mock.Scenario("rds", "DescribeDBInstances").ForResource(dbARN).
+//		Status("creating").
+//		Status("backing-up").Times(2).
+//		Status("available")
+//
+// Steps can be added while requests are being served.
+type Scenario struct {
+	m        *MockServer
+	service  string
+	action   string
+	resource string
+	steps    []*scenarioStep
+}
+
+// scenarioStep is one step of a scenario. Exactly one of status, fault,
+// response, and fn is set, unless the step passes requests through as is.
+type scenarioStep struct {
+	// times is the number of requests the step has left to answer. Zero
+	// answers every request from then on.
+	times    int
+	status   string
+	fault    *Fault
+	response *scriptedResponse
+	fn       func()
+}
+
+type scriptedResponse struct {
+	status int
+	body   string
+}
+
+// Scenario starts a scenario for requests to service and action, which may
+// use [path.Match] wildcards as in a [Fault]. The scenario applies as soon
+// as it has steps. When several scenarios match a request, the one started
+// first applies. [MockServer.Reset] leaves scenarios in place.
+func (m *MockServer) Scenario(service, action string) *Scenario {
+	s := &Scenario{m: m, service: service, action: action}
+	m.scenariosMu.Lock()
+	defer m.scenariosMu.Unlock()
+	m.scenarios = append(m.scenarios, s)
+	return s
+}
+
+// ClearScenarios removes every scenario.
+func (m *MockServer) ClearScenarios() {
+	m.scenariosMu.Lock()
+	defer m.scenariosMu.Unlock()
+	m.scenarios = nil
+}
+
+// ForResource limits the scenario to requests for resource: those with a
+// parameter or path segment equal to it, or, if it is an ARN, to the name
+// at its end, such as the DBInstanceIdentifier of an RDS instance ARN.
+func (s *Scenario) ForResource(resource string) *Scenario {
+	s.m.scenariosMu.Lock()
+	defer s.m.scenariosMu.Unlock()
+	s.resource = resource
+	return s
+}
+
+// Pass adds a step that lets requests through unchanged.
+func (s *Scenario) Pass() *Scenario {
+	return s.add(&scenarioStep{})
+}
+
+// Status adds a step that puts the resource into status, as
+// [MockServer.SetResourceStatus] does, before the request is handled. The
+// resource given to [Scenario.ForResource] must be its ARN.
+func (s *Scenario) Status(status string) *Scenario {
+	s.m.scenariosMu.Lock()
+	resource := s.resource
+	s.m.scenariosMu.Unlock()
+	if _, err := arn.Parse(resource); err != nil {
+		panic("awsmock: a Status step needs the ARN of the resource given to ForResource")
+	}
+	return s.add(&scenarioStep{status: status})
+}
+
+// Fail adds a step that fails requests with the given HTTP status and
+// error code, as an injected [Fault] does.
+func (s *Scenario) Fail(status int, code, message string) *Scenario {
+	return s.add(&scenarioStep{fault: &Fault{Status: status, Code: code, Message: message}})
+}
+
+// Respond adds a step that answers requests with the given HTTP status and
+// body, a JSON document or an XML one, instead of the service.
+func (s *Scenario) Respond(status int, body string) *Scenario {
+	return s.add(&scenarioStep{response: &scriptedResponse{status: status, body: body}})
+}
+
+// Do adds a step that calls fn before the request is handled, for changes
+// the other steps cannot make, such as completing a Step Functions
+// execution.
+func (s *Scenario) Do(fn func()) *Scenario {
+	return s.add(&scenarioStep{fn: fn})
+}
+
+// Times sets the number of requests the last step answers. Zero makes it
+// answer every request from then on.
+func (s *Scenario) Times(n int) *Scenario {
+	s.m.scenariosMu.Lock()
+	defer s.m.scenariosMu.Unlock()
+	if len(s.steps) == 0 {
+		panic("awsmock: Times called before any step was added to the scenario")
+	}
+	if n < 0 {
+		panic("awsmock: a scenario step cannot answer a negative number of requests")
+	}
+	s.steps[len(s.steps)-1].times = n
+	return s
+}
+
+// Done reports whether every step has answered its requests. A step
+// answering every request is never done.
+func (s *Scenario) Done() bool {
+	s.m.scenariosMu.Lock()
+	defer s.m.scenariosMu.Unlock()
+	return len(s.steps) == 0
+}
+
+func (s *Scenario) add(step *scenarioStep) *Scenario {
+	s.m.scenariosMu.Lock()
+	defer s.m.scenariosMu.Unlock()
+	step.times = 1
+	s.steps = append(s.steps, step)
+	return s
+}
+
+// scriptedStep returns the step that answers a request to service, counting
+// the request against it, and the resource of its scenario.
+func (m *MockServer) scriptedStep(r *http.Request, service string) (scenarioStep, string, bool) {
+	m.scenariosMu.Lock()
+	defer m.scenariosMu.Unlock()
+
+	action, actionKnown := "", false
+	for _, s := range m.scenarios {
+		if len(s.steps) == 0 {
+			continue
+		}
+		if s.service != "" {
+			if ok, _ := path.Match(s.service, service); !ok {
+				continue
+			}
+		}
+		if s.action != "" {
+			if !actionKnown {
+				action, actionKnown = requestAction(r), true
+			}
+			if ok, _ := path.Match(s.action, action); !ok || action == "" {
+				continue
+			}
+		}
+		if s.resource != "" && !namesResource(r, s.resource) {
+			continue
+		}
+		step := s.steps[0]
+		if step.times > 0 {
+			step.times--
+			if step.times == 0 {
+				s.steps = s.steps[1:]
+			}
+		}
+		return *step, s.resource, true
+	}
+	return scenarioStep{}, "", false
+}
+
+// scenarioMiddleware answers requests matching a scenario as its current
+// step says.
+func (m *MockServer) scenarioMiddleware(service string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		step, resource, ok := m.scriptedStep(r, service)
+		switch {
+		case !ok:
+		case step.fault != nil:
+			writeServiceError(w, r, service, step.fault.Status, step.fault.Code, step.fault.Message)
+			return
+		case step.response != nil:
+			if strings.HasPrefix(strings.TrimSpace(step.response.body), "<") {
+				w.Header().Set("Content-Type", "text/xml")
+			} else {
+				w.Header().Set("Content-Type", "application/x-amz-json-1.1")
+			}
+			w.WriteHeader(step.response.status)
+			io.WriteString(w, step.response.body)
+			return
+		case step.status != "":
+			if err := m.SetResourceStatus(resource, step.status); err != nil {
+				writeServiceError(w, r, service, http.StatusInternalServerError, "InternalFailure", fmt.Sprintf("scenario step failed: %v", err))
+				return
+			}
+		case step.fn != nil:
+			step.fn()
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// namesResource reports whether the request names resource, or the name at
+// the end of it if it is an ARN, in a path segment, a query or form
+// parameter, or a top-level JSON parameter.
+func namesResource(r *http.Request, resource string) bool {
+	names := map[string]bool{resource: true}
+	if a, err := arn.Parse(resource); err == nil {
+		name := a.Resource
+		if i := strings.LastIndexAny(name, ":/"); i >= 0 {
+			name = name[i+1:]
+		}
+		names[name] = true
+	}
+
+	for _, segment := range strings.Split(r.URL.Path, "/") {
+		if names[segment] {
+			return true
+		}
+	}
+	contentType := r.Header.Get("Content-Type")
+	values := r.URL.Query()
+	switch {
+	case strings.HasPrefix(contentType, "application/x-www-form-urlencoded"):
+		if r.ParseForm() == nil {
+			values = r.Form
+		}
+	case strings.Contains(contentType, "json") && r.Body != nil:
+		body, err := io.ReadAll(r.Body)
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		var params map[string]interface{}
+		if err == nil && json.Unmarshal(body, &params) == nil {
+			for _, v := range params {
+				if s, ok := v.(string); ok && names[s] {
+					return true
+				}
+			}
+		}
+	}
+	for _, vs := range values {
+		for _, v := range vs {
+			if names[v] {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,371 @@
+// Package accessanalyzer provides a mock implementation of IAM Access
+// Analyzer.
+//
+// Supported actions:
+//   - CreateAnalyzer, GetAnalyzer, ListAnalyzers, DeleteAnalyzer
+//   - ListFindings, GetFinding, UpdateFindings
+//   - StartPolicyGeneration, GetGeneratedPolicy, ListPolicyGenerations,
+//     CancelPolicyGeneration
+//   - ValidatePolicy
+//   - TagResource, UntagResource, ListTagsForResource
+//
+// External access analyzers (ACCOUNT and ORGANIZATION) report the IAM role
+// trust policies and SQS queue policies held by those mocks that grant
+// access to principals outside the account, or to anyone. Findings are
+// refreshed whenever they are read: they become RESOLVED once the policy
+// stops granting the access, and new findings that match one of the
+// analyzer's archive rules are ARCHIVED. Unused access analyzers report
+// nothing.
+//
+// Policy generation builds a policy from the activity recorded with
+// [Service.RecordActivity], as Access Analyzer builds one from the CloudTrail
+// events of the principal. ValidatePolicy parses the policy document and
+// runs the grammar and security checks of the policy check reference,
+// locating each finding by its path in the document and the line and column
+// it spans.
+package accessanalyzer
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/riyanimam/goto/internal/clock"
+	"github.com/riyanimam/goto/internal/lifecycle"
+	h "github.com/riyanimam/goto/internal/mockhelpers"
+	"github.com/riyanimam/goto/internal/paginate"
+	"github.com/riyanimam/goto/internal/tags"
+)
+
+var analyzerName = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_.-]{0,254}$`)
+
+var analyzerTypes = map[string]bool{
+	"ACCOUNT":                    true,
+	"ORGANIZATION":               true,
+	"ACCOUNT_UNUSED_ACCESS":      true,
+	"ORGANIZATION_UNUSED_ACCESS": true,
+}
+
+// Service implements the Access Analyzer mock.
+type Service struct {
+	mu          sync.RWMutex
+	analyzers   map[string]*analyzer // keyed by name
+	generations map[string]*generation
+	activity    []activity
+
+	list        h.ResourceLister
+	resolve     h.Resolver
+	tags        *tags.Store
+	clock       *clock.Clock
+	transitions *lifecycle.Transitions
+}
+
+type analyzer struct {
+	name          string
+	arn           string
+	analyzerType  string
+	configuration map[string]interface{}
+	archiveRules  []archiveRule
+	created       time.Time
+	findings      map[string]*finding // keyed by findingKey
+	lastResource  string
+	lastAnalyzed  time.Time
+}
+
+type archiveRule struct {
+	name    string
+	filter  map[string]interface{}
+	created time.Time
+}
+
+// New creates a new Access Analyzer mock service.
+func New() *Service {
+	return &Service{
+		analyzers:   make(map[string]*analyzer),
+		generations: make(map[string]*generation),
+		tags:        tags.New(),
+	}
+}
+
+// Name returns the service identifier.
+func (s *Service) Name() string { return "access-analyzer" }
+
+// Handler returns the HTTP handler for Access Analyzer requests.
+func (s *Service) Handler() http.Handler {
+	return http.HandlerFunc(s.handle)
+}
+
+// Reset clears all state.
+func (s *Service) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.analyzers = make(map[string]*analyzer)
+	s.generations = make(map[string]*generation)
+	s.activity = nil
+	s.tags.DeleteService("access-analyzer")
+}
+
+// SetResourceLister sets how the IAM roles and SQS queues analyzers report
+// on are found.
+func (s *Service) SetResourceLister(l h.ResourceLister) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.list = l
+}
+
+// SetResolver sets the function used to check that the principals policies
+// are generated for exist.
+func (s *Service) SetResolver(r h.Resolver) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.resolve = r
+}
+
+// SetTagStore sets the registry analyzer tags are recorded in.
+func (s *Service) SetTagStore(store *tags.Store) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tags = store
+}
+
+// SetClock attaches the mock clock used for finding and activity times.
+func (s *Service) SetClock(c *clock.Clock) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.clock = c
+}
+
+// SetTransitions sets how long policy generation jobs stay IN_PROGRESS.
+func (s *Service) SetTransitions(t *lifecycle.Transitions) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.transitions = t
+}
+
+func (s *Service) now() time.Time {
+	if s.clock != nil {
+		return s.clock.Now()
+	}
+	return time.Now().UTC()
+}
+
+// HasResource reports whether the analyzer identified by arn exists.
+func (s *Service) HasResource(arn string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, a := range s.analyzers {
+		if a.arn == arn {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Service) handle(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(r.URL.EscapedPath(), "/"), "/")
+	for i, p := range parts {
+		parts[i], _ = url.PathUnescape(p)
+	}
+	params := map[string]interface{}{}
+	if body, _ := io.ReadAll(r.Body); len(body) > 0 {
+		if err := json.Unmarshal(body, &params); err != nil {
+			writeValidation(w, "Request body is not valid JSON.")
+			return
+		}
+	}
+	q := r.URL.Query()
+
+	switch {
+	case parts[0] == "tags" && len(parts) > 1:
+		s.handleTags(w, r.Method, strings.Join(parts[1:], "/"), params, q)
+	case parts[0] == "analyzer" && len(parts) == 1 && r.Method == http.MethodPut:
+		s.createAnalyzer(w, params)
+	case parts[0] == "analyzer" && len(parts) == 1 && r.Method == http.MethodGet:
+		s.listAnalyzers(w, q)
+	case parts[0] == "analyzer" && len(parts) == 2 && r.Method == http.MethodGet:
+		s.getAnalyzer(w, parts[1])
+	case parts[0] == "analyzer" && len(parts) == 2 && r.Method == http.MethodDelete:
+		s.deleteAnalyzer(w, parts[1])
+	case parts[0] == "finding" && len(parts) == 1 && r.Method == http.MethodPost:
+		s.listFindings(w, params)
+	case parts[0] == "finding" && len(parts) == 1 && r.Method == http.MethodPut:
+		s.updateFindings(w, params)
+	case parts[0] == "finding" && len(parts) == 2 && r.Method == http.MethodGet:
+		s.getFinding(w, parts[1], q.Get("analyzerArn"))
+	case parts[0] == "policy" && len(parts) == 2 && parts[1] == "validation" && r.Method == http.MethodPost:
+		s.validatePolicy(w, params, q)
+	case parts[0] == "policy" && len(parts) >= 2 && parts[1] == "generation":
+		s.handleGenerations(w, r.Method, parts[2:], params, q)
+	default:
+		writeUnsupported(w)
+	}
+}
+
+func writeUnsupported(w http.ResponseWriter) {
+	h.WriteJSONError(w, "ValidationException", "unsupported operation", http.StatusBadRequest)
+}
+
+func writeValidation(w http.ResponseWriter, message string) {
+	h.WriteJSONError(w, "ValidationException", message, http.StatusBadRequest)
+}
+
+func writeNotFound(w http.ResponseWriter, message string) {
+	h.WriteJSONError(w, "ResourceNotFoundException", message, http.StatusNotFound)
+}
+
+func writeConflict(w http.ResponseWriter, message string) {
+	h.WriteJSONError(w, "ConflictException", message, http.StatusConflict)
+}
+
+func resourceArn(resource string) string {
+	return fmt.Sprintf("arn:aws:access-analyzer:us-east-1:%s:%s", h.DefaultAccountID, resource)
+}
+
+func timestamp(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
+
+// writePage writes a page of at most limit items under key. itemKey returns
+// the key items are sorted by, as in [paginate.Page].
+func writePage(w http.ResponseWriter, key string, items []map[string]interface{}, itemKey func(i int) string, token string, limit int) {
+	page, next, err := paginate.Page(items, itemKey, token, limit, 1000)
+	if err != nil {
+		writeValidation(w, "Invalid nextToken.")
+		return
+	}
+	resp := map[string]interface{}{key: page}
+	if next != "" {
+		resp["nextToken"] = next
+	}
+	h.WriteJSON(w, http.StatusOK, resp)
+}
+
+func (s *Service) createAnalyzer(w http.ResponseWriter, params map[string]interface{}) {
+	name, analyzerType := h.GetString(params, "analyzerName"), h.GetString(params, "type")
+	if !analyzerName.MatchString(name) {
+		writeValidation(w, "Invalid analyzerName: "+name+".")
+		return
+	}
+	if !analyzerTypes[analyzerType] {
+		writeValidation(w, "Invalid type: "+analyzerType+".")
+		return
+	}
+	config, _ := params["configuration"].(map[string]interface{})
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, exists := s.analyzers[name]; exists {
+		writeConflict(w, "Analyzer "+name+" already exists.")
+		return
+	}
+	now := s.now()
+	a := &analyzer{
+		name:          name,
+		arn:           resourceArn("analyzer/" + name),
+		analyzerType:  analyzerType,
+		configuration: config,
+		created:       now,
+		findings:      make(map[string]*finding),
+	}
+	rules, _ := params["archiveRules"].([]interface{})
+	for _, raw := range rules {
+		rule, _ := raw.(map[string]interface{})
+		filter, _ := rule["filter"].(map[string]interface{})
+		if err := checkFilter(filter); err != nil {
+			writeValidation(w, err.Error())
+			return
+		}
+		a.archiveRules = append(a.archiveRules, archiveRule{name: h.GetString(rule, "ruleName"), filter: filter, created: now})
+	}
+	s.analyzers[name] = a
+	s.tags.Tag(a.arn, tags.FromMap(params["tags"]))
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{"arn": a.arn})
+}
+
+// summary renders an analyzer. The caller must hold s.mu.
+func (s *Service) summary(a *analyzer) map[string]interface{} {
+	sum := map[string]interface{}{
+		"arn":       a.arn,
+		"name":      a.name,
+		"type":      a.analyzerType,
+		"createdAt": timestamp(a.created),
+		"status":    "ACTIVE",
+		"tags":      s.tags.Get(a.arn),
+	}
+	if a.configuration != nil {
+		sum["configuration"] = a.configuration
+	}
+	if a.lastResource != "" {
+		sum["lastResourceAnalyzed"] = a.lastResource
+		sum["lastResourceAnalyzedAt"] = timestamp(a.lastAnalyzed)
+	}
+	return sum
+}
+
+func (s *Service) getAnalyzer(w http.ResponseWriter, name string) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	a, exists := s.analyzers[name]
+	if !exists {
+		writeNotFound(w, "Analyzer "+name+" not found.")
+		return
+	}
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{"analyzer": s.summary(a)})
+}
+
+func (s *Service) listAnalyzers(w http.ResponseWriter, q url.Values) {
+	analyzerType := q.Get("type")
+	s.mu.RLock()
+	var analyzers []map[string]interface{}
+	for _, a := range s.analyzers {
+		if analyzerType == "" || a.analyzerType == analyzerType {
+			analyzers = append(analyzers, s.summary(a))
+		}
+	}
+	s.mu.RUnlock()
+	sort.Slice(analyzers, func(i, j int) bool {
+		return analyzers[i]["name"].(string) < analyzers[j]["name"].(string)
+	})
+	limit, _ := strconv.Atoi(q.Get("maxResults"))
+	writePage(w, "analyzers", analyzers, func(i int) string { return analyzers[i]["name"].(string) }, q.Get("nextToken"), limit)
+}
+
+func (s *Service) deleteAnalyzer(w http.ResponseWriter, name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	a, exists := s.analyzers[name]
+	if !exists {
+		writeNotFound(w, "Analyzer "+name+" not found.")
+		return
+	}
+	s.tags.Delete(a.arn)
+	delete(s.analyzers, name)
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *Service) handleTags(w http.ResponseWriter, method, arn string, params map[string]interface{}, q url.Values) {
+	if !s.HasResource(arn) {
+		writeNotFound(w, "Resource "+arn+" not found.")
+		return
+	}
+	switch method {
+	case http.MethodPost:
+		s.tags.Tag(arn, tags.FromMap(params["tags"]))
+		w.WriteHeader(http.StatusOK)
+	case http.MethodDelete:
+		s.tags.Untag(arn, q["tagKeys"])
+		w.WriteHeader(http.StatusOK)
+	case http.MethodGet:
+		h.WriteJSON(w, http.StatusOK, map[string]interface{}{"tags": s.tags.Get(arn)})
+	default:
+		writeUnsupported(w)
+	}
+}
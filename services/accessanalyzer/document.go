@@ -0,0 +1,260 @@
+package accessanalyzer
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// node is a JSON value of a policy document, with the byte offsets it
+// spans so that validation findings can point at it.
+type node struct {
+	kind       byte // 'o'bject, 'a'rray, 's'tring, 'n'umber, 'b'oolean, or null 'z'
+	start, end int
+	members    []member // objects, in document order
+	items      []*node  // arrays
+	str        string   // strings, numbers, and booleans, as written
+}
+
+// member is an object member.
+type member struct {
+	key        string
+	start, end int // of the key, quotes included
+	value      *node
+}
+
+// field returns the object member named key, or nil.
+func (n *node) field(key string) *member {
+	for i := range n.members {
+		if n.members[i].key == key {
+			return &n.members[i]
+		}
+	}
+	return nil
+}
+
+// values returns the elements of an array, or n itself for other values,
+// as policy elements accept a single value or a list.
+func (n *node) values() []*node {
+	if n.kind == 'a' {
+		return n.items
+	}
+	return []*node{n}
+}
+
+// syntaxError is a JSON syntax error at a byte offset.
+type syntaxError struct {
+	offset int
+	msg    string
+}
+
+func (e *syntaxError) Error() string { return e.msg }
+
+// parseDocument parses a JSON document, keeping the offsets of its values.
+func parseDocument(doc string) (*node, error) {
+	p := &parser{doc: doc}
+	p.space()
+	n, err := p.value()
+	if err != nil {
+		return nil, err
+	}
+	p.space()
+	if p.pos < len(p.doc) {
+		return nil, p.errorf("Unexpected content after the end of the policy.")
+	}
+	return n, nil
+}
+
+// escapes maps the characters after a backslash in a JSON string to the
+// characters they stand for, other than \u escapes.
+var escapes = map[byte]rune{'"': '"', '\\': '\\', '/': '/', 'b': '\b', 'f': '\f', 'n': '\n', 'r': '\r', 't': '\t'}
+
+type parser struct {
+	doc string
+	pos int
+}
+
+func (p *parser) errorf(format string, args ...interface{}) error {
+	return &syntaxError{offset: p.pos, msg: fmt.Sprintf(format, args...)}
+}
+
+func (p *parser) space() {
+	for p.pos < len(p.doc) && strings.IndexByte(" \t\r\n", p.doc[p.pos]) >= 0 {
+		p.pos++
+	}
+}
+
+func (p *parser) value() (*node, error) {
+	if p.pos >= len(p.doc) {
+		return nil, p.errorf("Unexpected end of the policy.")
+	}
+	switch c := p.doc[p.pos]; {
+	case c == '{':
+		return p.object()
+	case c == '[':
+		return p.array()
+	case c == '"':
+		start := p.pos
+		s, err := p.string()
+		if err != nil {
+			return nil, err
+		}
+		return &node{kind: 's', start: start, end: p.pos, str: s}, nil
+	case c == '-' || (c >= '0' && c <= '9'):
+		return p.number()
+	}
+	for _, word := range []string{"true", "false", "null"} {
+		if strings.HasPrefix(p.doc[p.pos:], word) {
+			n := &node{kind: 'b', start: p.pos, end: p.pos + len(word), str: word}
+			if word == "null" {
+				n.kind = 'z'
+			}
+			p.pos += len(word)
+			return n, nil
+		}
+	}
+	return nil, p.errorf("Unexpected character %q.", p.doc[p.pos])
+}
+
+func (p *parser) object() (*node, error) {
+	n := &node{kind: 'o', start: p.pos}
+	p.pos++
+	p.space()
+	if p.pos < len(p.doc) && p.doc[p.pos] == '}' {
+		p.pos++
+		n.end = p.pos
+		return n, nil
+	}
+	for {
+		p.space()
+		if p.pos >= len(p.doc) || p.doc[p.pos] != '"' {
+			return nil, p.errorf("Expected a quoted member name.")
+		}
+		m := member{start: p.pos}
+		key, err := p.string()
+		if err != nil {
+			return nil, err
+		}
+		m.key, m.end = key, p.pos
+		p.space()
+		if p.pos >= len(p.doc) || p.doc[p.pos] != ':' {
+			return nil, p.errorf("Expected ':' after member name %q.", key)
+		}
+		p.pos++
+		p.space()
+		if m.value, err = p.value(); err != nil {
+			return nil, err
+		}
+		n.members = append(n.members, m)
+		p.space()
+		if p.pos < len(p.doc) && p.doc[p.pos] == ',' {
+			p.pos++
+			continue
+		}
+		if p.pos < len(p.doc) && p.doc[p.pos] == '}' {
+			p.pos++
+			n.end = p.pos
+			return n, nil
+		}
+		return nil, p.errorf("Expected ',' or '}'.")
+	}
+}
+
+func (p *parser) array() (*node, error) {
+	n := &node{kind: 'a', start: p.pos}
+	p.pos++
+	p.space()
+	if p.pos < len(p.doc) && p.doc[p.pos] == ']' {
+		p.pos++
+		n.end = p.pos
+		return n, nil
+	}
+	for {
+		p.space()
+		item, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		n.items = append(n.items, item)
+		p.space()
+		if p.pos < len(p.doc) && p.doc[p.pos] == ',' {
+			p.pos++
+			continue
+		}
+		if p.pos < len(p.doc) && p.doc[p.pos] == ']' {
+			p.pos++
+			n.end = p.pos
+			return n, nil
+		}
+		return nil, p.errorf("Expected ',' or ']'.")
+	}
+}
+
+// string reads a quoted string, returning its decoded value.
+func (p *parser) string() (string, error) {
+	p.pos++
+	var b strings.Builder
+	for p.pos < len(p.doc) {
+		c := p.doc[p.pos]
+		switch {
+		case c == '"':
+			p.pos++
+			return b.String(), nil
+		case c < 0x20:
+			return "", p.errorf("Control characters must be escaped in strings.")
+		case c == '\\':
+			if p.pos+1 >= len(p.doc) {
+				return "", p.errorf("Unterminated string.")
+			}
+			esc := p.doc[p.pos+1]
+			if r, ok := escapes[esc]; ok {
+				b.WriteRune(r)
+				p.pos += 2
+				continue
+			}
+			var r rune
+			if esc != 'u' || p.pos+6 > len(p.doc) {
+				return "", p.errorf("Invalid escape sequence.")
+			}
+			if _, err := fmt.Sscanf(p.doc[p.pos+2:p.pos+6], "%04x", &r); err != nil {
+				return "", p.errorf("Invalid escape sequence.")
+			}
+			b.WriteRune(r)
+			p.pos += 6
+		default:
+			r, size := utf8.DecodeRuneInString(p.doc[p.pos:])
+			b.WriteRune(r)
+			p.pos += size
+		}
+	}
+	return "", p.errorf("Unterminated string.")
+}
+
+func (p *parser) number() (*node, error) {
+	start := p.pos
+	if p.doc[p.pos] == '-' {
+		p.pos++
+	}
+	digits := 0
+	for p.pos < len(p.doc) && strings.IndexByte("0123456789.eE+-", p.doc[p.pos]) >= 0 {
+		p.pos++
+		digits++
+	}
+	if digits == 0 {
+		return nil, p.errorf("Invalid number.")
+	}
+	return &node{kind: 'n', start: start, end: p.pos, str: p.doc[start:p.pos]}, nil
+}
+
+// position converts a byte offset into the line (from 1), column (from 0),
+// and character offset (from 0) Access Analyzer reports.
+func position(doc string, offset int) map[string]interface{} {
+	before := doc[:offset]
+	line := strings.Count(before, "\n") + 1
+	column := utf8.RuneCountInString(before[strings.LastIndexByte(before, '\n')+1:])
+	return map[string]interface{}{
+		"line":   line,
+		"column": column,
+		"offset": utf8.RuneCountInString(before),
+	}
+}
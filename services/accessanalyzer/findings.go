@@ -0,0 +1,508 @@
+package accessanalyzer
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	h "github.com/riyanimam/goto/internal/mockhelpers"
+	"github.com/riyanimam/goto/internal/paginate"
+)
+
+// finding is external access granted by a resource policy: one principal,
+// under one set of conditions, allowed some actions on the resource.
+type finding struct {
+	id           string
+	resource     string
+	resourceType string
+	principal    map[string]string
+	actions      []string
+	condition    map[string]string
+	isPublic     bool
+	status       string
+	created      time.Time
+	analyzed     time.Time
+	updated      time.Time
+}
+
+// grant is external access found in a policy, before it is matched with
+// the analyzer's existing findings.
+type grant struct {
+	key          string
+	resource     string
+	resourceType string
+	principal    map[string]string
+	actions      []string
+	condition    map[string]string
+	isPublic     bool
+}
+
+// policyStatement is the part of a policy statement findings are made from.
+type policyStatement struct {
+	Effect    string                            `json:"Effect"`
+	Principal interface{}                       `json:"Principal"`
+	Action    interface{}                       `json:"Action"`
+	Condition map[string]map[string]interface{} `json:"Condition"`
+}
+
+// accountKeys are the condition keys that limit access to the principals
+// or requests of named accounts.
+var accountKeys = map[string]bool{
+	"aws:principalaccount": true,
+	"aws:sourceaccount":    true,
+	"aws:sourceowner":      true,
+}
+
+// scan lists the external access granted by the IAM role trust policies and
+// SQS queue policies of the account. It must be called without s.mu held,
+// as the lister reads the other services.
+func (s *Service) scan() []grant {
+	s.mu.RLock()
+	list := s.list
+	s.mu.RUnlock()
+	if list == nil {
+		return nil
+	}
+	var grants []grant
+	for _, r := range list("iam") {
+		if doc, ok := r.Attributes["assume_role_policy"].(string); ok && r.Type == "aws_iam_role" {
+			grants = append(grants, policyGrants(r.ARN, "AWS::IAM::Role", doc)...)
+		}
+	}
+	for _, r := range list("sqs") {
+		if attrs, ok := r.Attributes["attributes"].(map[string]string); ok && attrs["Policy"] != "" {
+			grants = append(grants, policyGrants(r.ARN, "AWS::SQS::Queue", attrs["Policy"])...)
+		}
+	}
+	return grants
+}
+
+// policyGrants returns the external access a policy on resource grants,
+// merging the actions allowed to the same principal under the same
+// conditions. Policies that do not parse grant nothing.
+func policyGrants(resource, resourceType, doc string) []grant {
+	var policy struct {
+		Statement json.RawMessage `json:"Statement"`
+	}
+	if err := json.Unmarshal([]byte(doc), &policy); err != nil {
+		return nil
+	}
+	var statements []policyStatement
+	if err := json.Unmarshal(policy.Statement, &statements); err != nil {
+		var st policyStatement
+		if json.Unmarshal(policy.Statement, &st) != nil {
+			return nil
+		}
+		statements = []policyStatement{st}
+	}
+
+	byKey := map[string]*grant{}
+	var order []string
+	for _, st := range statements {
+		if st.Effect != "Allow" {
+			continue
+		}
+		condition := flattenCondition(st.Condition)
+		if limitedToAccount(condition) {
+			continue
+		}
+		for _, p := range externalPrincipals(st.Principal) {
+			principal := map[string]string{p[0]: p[1]}
+			conditionKey, _ := json.Marshal(condition)
+			key := resource + "|" + p[0] + "=" + p[1] + "|" + string(conditionKey)
+			g, exists := byKey[key]
+			if !exists {
+				g = &grant{
+					key:          key,
+					resource:     resource,
+					resourceType: resourceType,
+					principal:    principal,
+					condition:    condition,
+					isPublic:     p[1] == "*" && len(condition) == 0,
+				}
+				byKey[key] = g
+				order = append(order, key)
+			}
+			g.actions = appendUnique(g.actions, stringValues(st.Action)...)
+		}
+	}
+	grants := make([]grant, 0, len(order))
+	for _, key := range order {
+		sort.Strings(byKey[key].actions)
+		grants = append(grants, *byKey[key])
+	}
+	return grants
+}
+
+// externalPrincipals returns the principals outside the account a
+// statement's Principal names, as principal type and value pairs. Service
+// principals and the account's own principals are left out.
+func externalPrincipals(principal interface{}) [][2]string {
+	if principal == "*" {
+		return [][2]string{{"AWS", "*"}}
+	}
+	m, _ := principal.(map[string]interface{})
+	var external [][2]string
+	for _, kind := range []string{"AWS", "Federated", "CanonicalUser"} {
+		for _, v := range stringValues(m[kind]) {
+			if kind == "AWS" && principalAccount(v) == h.DefaultAccountID {
+				continue
+			}
+			external = append(external, [2]string{kind, v})
+		}
+	}
+	return external
+}
+
+// principalAccount returns the account of an AWS principal given as an
+// account ID or ARN, or "" for "*".
+func principalAccount(principal string) string {
+	if len(principal) == 12 && strings.Trim(principal, "0123456789") == "" {
+		return principal
+	}
+	if fields := strings.SplitN(principal, ":", 6); len(fields) == 6 {
+		return fields[4]
+	}
+	return ""
+}
+
+// flattenCondition maps each condition key of a statement's Condition to
+// its values, joined with commas, as findings report conditions.
+func flattenCondition(condition map[string]map[string]interface{}) map[string]string {
+	flat := map[string]string{}
+	for _, keys := range condition {
+		for key, values := range keys {
+			flat[key] = strings.Join(stringValues(values), ",")
+		}
+	}
+	return flat
+}
+
+// limitedToAccount reports whether conditions allow only the account's own
+// principals or requests.
+func limitedToAccount(condition map[string]string) bool {
+	for key, value := range condition {
+		if accountKeys[strings.ToLower(key)] && value == h.DefaultAccountID {
+			return true
+		}
+	}
+	return false
+}
+
+// stringValues returns a policy element given as a string or a list of
+// strings as a list.
+func stringValues(v interface{}) []string {
+	switch v := v.(type) {
+	case string:
+		return []string{v}
+	case []interface{}:
+		values := make([]string, 0, len(v))
+		for _, item := range v {
+			switch item := item.(type) {
+			case string:
+				values = append(values, item)
+			case float64, bool:
+				values = append(values, fmt.Sprint(item))
+			}
+		}
+		return values
+	case float64, bool:
+		return []string{fmt.Sprint(v)}
+	}
+	return nil
+}
+
+func appendUnique(list []string, values ...string) []string {
+	for _, v := range values {
+		if !contains(list, v) {
+			list = append(list, v)
+		}
+	}
+	return list
+}
+
+// refresh brings the findings of every external access analyzer up to date
+// with grants. New findings that match an archive rule are archived, and
+// findings whose access is no longer granted are resolved. The caller must
+// hold s.mu.
+func (s *Service) refresh(grants []grant) {
+	now := s.now()
+	for _, a := range s.analyzers {
+		if a.analyzerType != "ACCOUNT" && a.analyzerType != "ORGANIZATION" {
+			continue
+		}
+		current := make(map[string]bool, len(grants))
+		for _, g := range grants {
+			current[g.key] = true
+			f, exists := a.findings[g.key]
+			if !exists {
+				f = &finding{
+					id:           h.NewRequestID(),
+					resource:     g.resource,
+					resourceType: g.resourceType,
+					principal:    g.principal,
+					condition:    g.condition,
+					isPublic:     g.isPublic,
+					created:      now,
+					updated:      now,
+				}
+				a.findings[g.key] = f
+			}
+			f.actions, f.analyzed = g.actions, now
+			if !exists || f.status == "RESOLVED" {
+				f.status, f.updated = "ACTIVE", now
+				for _, rule := range a.archiveRules {
+					if f.matches(rule.filter) {
+						f.status = "ARCHIVED"
+						break
+					}
+				}
+			}
+			a.lastResource, a.lastAnalyzed = g.resource, now
+		}
+		for key, f := range a.findings {
+			if !current[key] && f.status != "RESOLVED" {
+				f.status, f.updated = "RESOLVED", now
+			}
+		}
+	}
+}
+
+func (f *finding) render() map[string]interface{} {
+	out := map[string]interface{}{
+		"id":                   f.id,
+		"resource":             f.resource,
+		"resourceType":         f.resourceType,
+		"resourceOwnerAccount": h.DefaultAccountID,
+		"principal":            f.principal,
+		"action":               f.actions,
+		"condition":            f.condition,
+		"isPublic":             f.isPublic,
+		"status":               f.status,
+		"createdAt":            timestamp(f.created),
+		"analyzedAt":           timestamp(f.analyzed),
+		"updatedAt":            timestamp(f.updated),
+	}
+	return out
+}
+
+// attribute returns the values of a finding attribute named as in filters
+// and sorts.
+func (f *finding) attribute(name string) []string {
+	switch name {
+	case "id":
+		return []string{f.id}
+	case "resource":
+		return []string{f.resource}
+	case "resourceType":
+		return []string{f.resourceType}
+	case "resourceOwnerAccount":
+		return []string{h.DefaultAccountID}
+	case "status":
+		return []string{f.status}
+	case "isPublic":
+		return []string{fmt.Sprint(f.isPublic)}
+	case "action":
+		return f.actions
+	case "createdAt":
+		return []string{timestamp(f.created)}
+	case "analyzedAt":
+		return []string{timestamp(f.analyzed)}
+	case "updatedAt":
+		return []string{timestamp(f.updated)}
+	}
+	if kind, ok := strings.CutPrefix(name, "principal."); ok {
+		if v, ok := f.principal[kind]; ok {
+			return []string{v}
+		}
+	}
+	if key, ok := strings.CutPrefix(name, "condition."); ok {
+		if v, ok := f.condition[key]; ok {
+			return []string{v}
+		}
+	}
+	return nil
+}
+
+// checkFilter reports a filter whose criteria are not objects with at
+// least one of eq, neq, contains, and exists.
+func checkFilter(filter map[string]interface{}) error {
+	for key, raw := range filter {
+		criterion, _ := raw.(map[string]interface{})
+		if len(criterion) == 0 {
+			return fmt.Errorf("Invalid filter criteria for %s.", key)
+		}
+		for op := range criterion {
+			if op != "eq" && op != "neq" && op != "contains" && op != "exists" {
+				return fmt.Errorf("Invalid filter criteria for %s: unknown operator %s.", key, op)
+			}
+		}
+	}
+	return nil
+}
+
+// matches reports whether the finding meets every criterion of filter.
+func (f *finding) matches(filter map[string]interface{}) bool {
+	for key, raw := range filter {
+		criterion, _ := raw.(map[string]interface{})
+		values := f.attribute(key)
+		if exists, ok := criterion["exists"].(bool); ok && exists != (len(values) > 0) {
+			return false
+		}
+		if eq := stringValues(criterion["eq"]); len(eq) > 0 && !anyValue(values, eq, equal) {
+			return false
+		}
+		if neq := stringValues(criterion["neq"]); len(neq) > 0 && anyValue(values, neq, equal) {
+			return false
+		}
+		if contains := stringValues(criterion["contains"]); len(contains) > 0 && !anyValue(values, contains, strings.Contains) {
+			return false
+		}
+	}
+	return true
+}
+
+func equal(a, b string) bool { return a == b }
+
+func anyValue(values, wanted []string, match func(value, want string) bool) bool {
+	for _, v := range values {
+		for _, want := range wanted {
+			if match(v, want) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// analyzerByArn returns the analyzer identified by arn. The caller must
+// hold s.mu.
+func (s *Service) analyzerByArn(arn string) *analyzer {
+	for _, a := range s.analyzers {
+		if a.arn == arn {
+			return a
+		}
+	}
+	return nil
+}
+
+func (s *Service) listFindings(w http.ResponseWriter, params map[string]interface{}) {
+	filter, _ := params["filter"].(map[string]interface{})
+	if err := checkFilter(filter); err != nil {
+		writeValidation(w, err.Error())
+		return
+	}
+	grants := s.scan()
+
+	s.mu.Lock()
+	s.refresh(grants)
+	a := s.analyzerByArn(h.GetString(params, "analyzerArn"))
+	if a == nil {
+		s.mu.Unlock()
+		writeNotFound(w, "Analyzer "+h.GetString(params, "analyzerArn")+" not found.")
+		return
+	}
+	findings := make([]*finding, 0, len(a.findings))
+	for _, f := range a.findings {
+		if f.matches(filter) {
+			findings = append(findings, f)
+		}
+	}
+	order, _ := params["sort"].(map[string]interface{})
+	attribute, descending := h.GetString(order, "attributeName"), h.GetString(order, "orderBy") == "DESC"
+	key := func(i int) string {
+		f := findings[i]
+		value := ""
+		if attribute != "" {
+			value = strings.Join(f.attribute(attribute), ",")
+			if descending {
+				value = paginate.Desc(value)
+			}
+		}
+		return paginate.Join(value, paginate.Time(f.created), f.id)
+	}
+	sort.Slice(findings, func(i, j int) bool { return key(i) < key(j) })
+	page, next, err := paginate.Page(findings, key, h.GetString(params, "nextToken"), h.GetInt(params, "maxResults", 0), 1000)
+	if err != nil {
+		s.mu.Unlock()
+		writeValidation(w, "Invalid nextToken.")
+		return
+	}
+	items := make([]map[string]interface{}, 0, len(page))
+	for _, f := range page {
+		items = append(items, f.render())
+	}
+	s.mu.Unlock()
+
+	resp := map[string]interface{}{"findings": items}
+	if next != "" {
+		resp["nextToken"] = next
+	}
+	h.WriteJSON(w, http.StatusOK, resp)
+}
+
+func (s *Service) getFinding(w http.ResponseWriter, id, analyzerArn string) {
+	grants := s.scan()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.refresh(grants)
+	a := s.analyzerByArn(analyzerArn)
+	if a == nil {
+		writeNotFound(w, "Analyzer "+analyzerArn+" not found.")
+		return
+	}
+	for _, f := range a.findings {
+		if f.id == id {
+			h.WriteJSON(w, http.StatusOK, map[string]interface{}{"finding": f.render()})
+			return
+		}
+	}
+	writeNotFound(w, "Finding "+id+" not found.")
+}
+
+func (s *Service) updateFindings(w http.ResponseWriter, params map[string]interface{}) {
+	status := h.GetString(params, "status")
+	if status != "ACTIVE" && status != "ARCHIVED" {
+		writeValidation(w, "Invalid status: "+status+".")
+		return
+	}
+	ids := stringValues(params["ids"])
+	resource := h.GetString(params, "resourceArn")
+	if len(ids) == 0 && resource == "" {
+		writeValidation(w, "Either ids or resourceArn is required.")
+		return
+	}
+	grants := s.scan()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.refresh(grants)
+	a := s.analyzerByArn(h.GetString(params, "analyzerArn"))
+	if a == nil {
+		writeNotFound(w, "Analyzer "+h.GetString(params, "analyzerArn")+" not found.")
+		return
+	}
+	now := s.now()
+	for _, f := range a.findings {
+		if f.status == "RESOLVED" || (f.resource != resource && !contains(ids, f.id)) {
+			continue
+		}
+		if f.status != status {
+			f.status, f.updated = status, now
+		}
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+func contains(list []string, v string) bool {
+	for _, item := range list {
+		if item == v {
+			return true
+		}
+	}
+	return false
+}
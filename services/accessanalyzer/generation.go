@@ -0,0 +1,272 @@
+package accessanalyzer
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/riyanimam/goto/internal/lifecycle"
+	h "github.com/riyanimam/goto/internal/mockhelpers"
+	"github.com/riyanimam/goto/internal/paginate"
+)
+
+// activity is an action a principal was recorded performing, standing in
+// for the CloudTrail events policies are generated from.
+type activity struct {
+	principal string
+	action    string
+	at        time.Time
+}
+
+// generation is a policy generation job.
+type generation struct {
+	id         string
+	principal  string
+	trail      map[string]interface{}
+	start, end time.Time
+	started    time.Time
+	completed  time.Time
+	canceled   bool
+	transition lifecycle.Transition
+}
+
+// RecordActivity records principalArn performing actions, given as
+// "service:Action", at the current mock time. Policy generation jobs for
+// the principal whose CloudTrail window covers that time grant the actions.
+func (s *Service) RecordActivity(principalArn string, actions ...string) error {
+	for _, action := range actions {
+		if !actionPattern.MatchString(action) || strings.ContainsAny(action, "*?") {
+			return fmt.Errorf("invalid action %q: expected service:Action", action)
+		}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	for _, action := range actions {
+		s.activity = append(s.activity, activity{principal: principalArn, action: action, at: now})
+	}
+	return nil
+}
+
+func (s *Service) handleGenerations(w http.ResponseWriter, method string, rest []string, params map[string]interface{}, q url.Values) {
+	switch {
+	case len(rest) == 0 && method == http.MethodPut:
+		s.startPolicyGeneration(w, params)
+	case len(rest) == 0 && method == http.MethodGet:
+		s.listPolicyGenerations(w, q)
+	case len(rest) == 1 && method == http.MethodGet:
+		s.getGeneratedPolicy(w, rest[0])
+	case len(rest) == 1 && method == http.MethodPut:
+		s.cancelPolicyGeneration(w, rest[0])
+	default:
+		writeUnsupported(w)
+	}
+}
+
+func parseTime(v string) (time.Time, bool) {
+	t, err := time.Parse(time.RFC3339, v)
+	return t, err == nil
+}
+
+func (s *Service) startPolicyGeneration(w http.ResponseWriter, params map[string]interface{}) {
+	details, _ := params["policyGenerationDetails"].(map[string]interface{})
+	principal := h.GetString(details, "principalArn")
+	if !strings.HasPrefix(principal, "arn:aws:iam::") || (!strings.Contains(principal, ":role/") && !strings.Contains(principal, ":user/")) {
+		writeValidation(w, "principalArn must be the ARN of an IAM role or user.")
+		return
+	}
+	trail, _ := params["cloudTrailDetails"].(map[string]interface{})
+	trails, _ := trail["trails"].([]interface{})
+	if len(trails) == 0 || h.GetString(trail, "accessRole") == "" {
+		writeValidation(w, "cloudTrailDetails requires trails and an accessRole.")
+		return
+	}
+	start, ok := parseTime(h.GetString(trail, "startTime"))
+	if !ok {
+		writeValidation(w, "cloudTrailDetails.startTime is required.")
+		return
+	}
+
+	s.mu.RLock()
+	resolve := s.resolve
+	s.mu.RUnlock()
+	if resolve != nil {
+		if err := resolve(principal); err != nil {
+			writeValidation(w, "Principal "+principal+" does not exist.")
+			return
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	end, ok := parseTime(h.GetString(trail, "endTime"))
+	if !ok {
+		end = now
+	}
+	if !end.After(start) {
+		writeValidation(w, "cloudTrailDetails.endTime must be after startTime.")
+		return
+	}
+	for _, g := range s.generations {
+		if g.principal == principal && s.generationStatus(g) == "IN_PROGRESS" {
+			writeConflict(w, "A policy generation job is already in progress for "+principal+".")
+			return
+		}
+	}
+	g := &generation{
+		id:         h.NewRequestID(),
+		principal:  principal,
+		trail:      trail,
+		start:      start,
+		end:        end,
+		started:    now,
+		transition: s.transitions.Begin(),
+	}
+	s.generations[g.id] = g
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{"jobId": g.id})
+}
+
+// generationStatus returns a job's status, noting when it completed. The
+// caller must hold s.mu for writing.
+func (s *Service) generationStatus(g *generation) string {
+	if g.canceled {
+		return "CANCELED"
+	}
+	status := s.transitions.Status(g.transition, "IN_PROGRESS", "SUCCEEDED")
+	if status == "SUCCEEDED" && g.completed.IsZero() {
+		g.completed = s.now()
+	}
+	return status
+}
+
+// jobDetails renders a job's summary. The caller must hold s.mu for
+// writing.
+func (s *Service) jobDetails(g *generation) map[string]interface{} {
+	details := map[string]interface{}{
+		"jobId":     g.id,
+		"status":    s.generationStatus(g),
+		"startedOn": timestamp(g.started),
+	}
+	if !g.completed.IsZero() {
+		details["completedOn"] = timestamp(g.completed)
+	}
+	return details
+}
+
+// generatedPolicy builds a policy granting the actions the job's principal
+// was recorded performing in its CloudTrail window, with one statement per
+// service. The caller must hold s.mu.
+func (s *Service) generatedPolicy(g *generation) string {
+	byService := map[string][]string{}
+	for _, a := range s.activity {
+		if a.principal != g.principal || a.at.Before(g.start) || a.at.After(g.end) {
+			continue
+		}
+		service := strings.ToLower(a.action[:strings.IndexByte(a.action, ':')])
+		byService[service] = appendUnique(byService[service], service+a.action[len(service):])
+	}
+	services := make([]string, 0, len(byService))
+	for service := range byService {
+		services = append(services, service)
+	}
+	sort.Strings(services)
+
+	statements := make([]map[string]interface{}, 0, len(services))
+	for _, service := range services {
+		actions := byService[service]
+		sort.Strings(actions)
+		statements = append(statements, map[string]interface{}{
+			"Effect":   "Allow",
+			"Action":   actions,
+			"Resource": "*",
+		})
+	}
+	policy, _ := json.Marshal(map[string]interface{}{
+		"Version":   "2012-10-17",
+		"Statement": statements,
+	})
+	return string(policy)
+}
+
+func (s *Service) getGeneratedPolicy(w http.ResponseWriter, id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	g, exists := s.generations[id]
+	if !exists {
+		writeNotFound(w, "Policy generation job "+id+" not found.")
+		return
+	}
+	details := s.jobDetails(g)
+	trails, _ := g.trail["trails"].([]interface{})
+	trailProperties := make([]map[string]interface{}, 0, len(trails))
+	for _, raw := range trails {
+		t, _ := raw.(map[string]interface{})
+		trailProperties = append(trailProperties, map[string]interface{}{
+			"cloudTrailArn": h.GetString(t, "cloudTrailArn"),
+			"regions":       t["regions"],
+			"allRegions":    h.GetBool(t, "allRegions"),
+		})
+	}
+	result := map[string]interface{}{
+		"properties": map[string]interface{}{
+			"isComplete":   details["status"] == "SUCCEEDED",
+			"principalArn": g.principal,
+			"cloudTrailProperties": map[string]interface{}{
+				"trailProperties": trailProperties,
+				"startTime":       timestamp(g.start),
+				"endTime":         timestamp(g.end),
+			},
+		},
+		"generatedPolicies": []map[string]interface{}{},
+	}
+	if details["status"] == "SUCCEEDED" {
+		result["generatedPolicies"] = []map[string]interface{}{{"policy": s.generatedPolicy(g)}}
+	}
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"jobDetails":            details,
+		"generatedPolicyResult": result,
+	})
+}
+
+func (s *Service) listPolicyGenerations(w http.ResponseWriter, q url.Values) {
+	principal := q.Get("principalArn")
+	s.mu.Lock()
+	generations := make([]*generation, 0, len(s.generations))
+	for _, g := range s.generations {
+		if principal == "" || g.principal == principal {
+			generations = append(generations, g)
+		}
+	}
+	key := func(i int) string { return paginate.Join(paginate.Time(generations[i].started), generations[i].id) }
+	sort.Slice(generations, func(i, j int) bool { return key(i) < key(j) })
+	items := make([]map[string]interface{}, 0, len(generations))
+	for _, g := range generations {
+		item := s.jobDetails(g)
+		item["principalArn"] = g.principal
+		items = append(items, item)
+	}
+	s.mu.Unlock()
+
+	limit, _ := strconv.Atoi(q.Get("maxResults"))
+	writePage(w, "policyGenerations", items, key, q.Get("nextToken"), limit)
+}
+
+func (s *Service) cancelPolicyGeneration(w http.ResponseWriter, id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	g, exists := s.generations[id]
+	if !exists {
+		writeNotFound(w, "Policy generation job "+id+" not found.")
+		return
+	}
+	if s.generationStatus(g) == "IN_PROGRESS" {
+		g.canceled = true
+	}
+	w.WriteHeader(http.StatusOK)
+}
@@ -0,0 +1,553 @@
+package accessanalyzer
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	h "github.com/riyanimam/goto/internal/mockhelpers"
+	"github.com/riyanimam/goto/internal/paginate"
+)
+
+// policyTypes are the policy types ValidatePolicy checks.
+var policyTypes = map[string]bool{
+	"IDENTITY_POLICY":         true,
+	"RESOURCE_POLICY":         true,
+	"SERVICE_CONTROL_POLICY":  true,
+	"RESOURCE_CONTROL_POLICY": true,
+}
+
+// learnMoreAnchors maps finding types to the sections of the policy check
+// reference their issues are documented in.
+var learnMoreAnchors = map[string]string{
+	"ERROR":            "error",
+	"SECURITY_WARNING": "security-warning",
+	"WARNING":          "general-warning",
+	"SUGGESTION":       "suggestion",
+}
+
+var (
+	statementKeys = map[string]bool{
+		"Sid": true, "Effect": true, "Principal": true, "NotPrincipal": true, "Action": true,
+		"NotAction": true, "Resource": true, "NotResource": true, "Condition": true,
+	}
+	principalKeys = map[string]bool{"AWS": true, "Service": true, "Federated": true, "CanonicalUser": true}
+
+	actionPattern       = regexp.MustCompile(`^([A-Za-z0-9-]+):([A-Za-z0-9*?]+)$`)
+	awsPrincipalPattern = regexp.MustCompile(`^(\d{12}|arn:aws[a-z-]*:(iam|sts)::(\d{12}|aws):\S+)$`)
+	servicePrincipal    = regexp.MustCompile(`^[a-z0-9.-]+\.(amazonaws\.com|amazonaws\.com\.cn|amazon\.com)$`)
+	federatedPrincipal  = regexp.MustCompile(`^(arn:aws[a-z-]*:iam::\d{12}:(saml-provider|oidc-provider)/\S+|[a-z0-9.-]+\.[a-z]{2,})$`)
+	policyVariable      = regexp.MustCompile(`\$\{[^}]*\}`)
+)
+
+// conditionTypes maps condition operators, without a set prefix or the
+// IfExists suffix, to the type of value they compare.
+var conditionTypes = map[string]string{
+	"StringEquals": "string", "StringNotEquals": "string", "StringEqualsIgnoreCase": "string",
+	"StringNotEqualsIgnoreCase": "string", "StringLike": "string", "StringNotLike": "string",
+	"NumericEquals": "numeric", "NumericNotEquals": "numeric", "NumericLessThan": "numeric",
+	"NumericLessThanEquals": "numeric", "NumericGreaterThan": "numeric", "NumericGreaterThanEquals": "numeric",
+	"DateEquals": "date", "DateNotEquals": "date", "DateLessThan": "date",
+	"DateLessThanEquals": "date", "DateGreaterThan": "date", "DateGreaterThanEquals": "date",
+	"Bool": "bool", "BinaryEquals": "binary", "IpAddress": "ip", "NotIpAddress": "ip",
+	"ArnEquals": "arn", "ArnLike": "arn", "ArnNotEquals": "arn", "ArnNotLike": "arn",
+	"Null": "bool",
+}
+
+// globalConditionKeys are the aws: condition keys, in lower case. Keys
+// ending in a slash take a tag key or similar suffix.
+var globalConditionKeys = stringSet(`
+	aws:calledvia aws:calledviafirst aws:calledvialast aws:currenttime aws:ec2instancesourceprivateipv4
+	aws:ec2instancesourcevpc aws:epochtime aws:federatedprovider aws:multifactorauthage
+	aws:multifactorauthpresent aws:principalaccount aws:principalarn aws:principalisawsservice
+	aws:principalorgid aws:principalorgpaths aws:principalservicename aws:principalservicenameslist
+	aws:principaltag/ aws:principaltype aws:referer aws:requestedregion aws:requesttag/
+	aws:resourceaccount aws:resourceorgid aws:resourceorgpaths aws:resourcetag/ aws:securetransport
+	aws:sourceaccount aws:sourcearn aws:sourceidentity aws:sourceip aws:sourceorgid aws:sourceorgpaths
+	aws:sourcevpc aws:sourcevpcarn aws:sourcevpce aws:tagkeys aws:tokenissuetime aws:useragent
+	aws:userid aws:username aws:viaawsservice aws:vpceaccount aws:vpceorgid aws:vpceorgpaths
+	aws:vpcsourceip`)
+
+// servicePrefixes are the service namespaces IAM actions can name.
+var servicePrefixes = stringSet(`
+	a4b access-analyzer account acm acm-pca airflow amplify amplifybackend aoss apigateway app-integrations
+	appconfig appflow application-autoscaling application-cost-profiler applicationinsights appmesh
+	apprunner appstream appsync aps arc-zonal-shift artifact athena auditmanager autoscaling
+	autoscaling-plans aws-marketplace aws-portal backup backup-gateway batch bedrock billing
+	billingconductor budgets ce cassandra chatbot chime cleanrooms cloud9 cloudcontrolapi clouddirectory
+	cloudformation cloudfront cloudhsm cloudsearch cloudshell cloudtrail cloudwatch codeartifact
+	codebuild codecatalyst codecommit codeconnections codedeploy codeguru codeguru-profiler
+	codeguru-reviewer codepipeline codestar codestar-connections codestar-notifications cognito-identity
+	cognito-idp cognito-sync comprehend comprehendmedical compute-optimizer config connect
+	controltower cur databrew dataexchange datapipeline datasync datazone dax detective devicefarm
+	devops-guru directconnect discovery dlm dms docdb-elastic ds dynamodb ebs ec2 ec2-instance-connect
+	ec2messages ecr ecr-public ecs eks elasticache elasticbeanstalk elasticfilesystem
+	elasticloadbalancing elasticmapreduce elastictranscoder emr-containers emr-serverless es events
+	evidently execute-api firehose fis fms forecast frauddetector freertos fsx gamelift geo glacier
+	globalaccelerator glue grafana greengrass groundstation guardduty health healthlake iam
+	identitystore imagebuilder inspector inspector2 internetmonitor iot iot-device-tester
+	iotanalytics iotevents iotfleetwise iotsitewise iottwinmaker iq ivs kafka kafka-cluster kafkaconnect
+	kendra kinesis kinesisanalytics kinesisvideo kms lakeformation lambda launchwizard lex
+	license-manager lightsail logs lookoutequipment lookoutmetrics lookoutvision m2 machinelearning
+	macie2 managedblockchain mediaconnect mediaconvert medialive mediapackage mediapackagev2
+	mediastore mediatailor memorydb mgh mgn mobiletargeting monitron mq neptune-db neptune-graph
+	network-firewall networkmanager nimble oam omics opsworks organizations osis outposts
+	payment-cryptography personalize pi pipes polly pricing private-networks profile proton q qldb
+	quicksight ram rbin rds rds-data rds-db redshift redshift-data redshift-serverless rekognition
+	resiliencehub resource-explorer-2 resource-groups robomaker route53 route53-recovery-cluster
+	route53-recovery-control-config route53-recovery-readiness route53domains route53resolver rum
+	s3 s3-object-lambda s3-outposts s3express sagemaker savingsplans scheduler schemas sdb
+	secretsmanager securityhub securitylake serverlessrepo servicecatalog servicediscovery servicequotas
+	ses shield signer simspaceweaver sms sms-voice snow-device-management snowball sns sqs ssm
+	ssm-contacts ssm-incidents ssmmessages sso sso-directory sso-oauth states storagegateway sts
+	support supportplans sustainability swf synthetics tag tax textract timestream transcribe transfer
+	translate trustedadvisor verifiedpermissions voiceid vpc-lattice waf waf-regional wafv2
+	wellarchitected workdocs worklink workmail workspaces workspaces-web xray`)
+
+func stringSet(list string) map[string]bool {
+	set := make(map[string]bool)
+	for _, s := range strings.Fields(list) {
+		set[s] = true
+	}
+	return set
+}
+
+// validator collects the findings of a policy check.
+type validator struct {
+	doc        string
+	policyType string
+	findings   []validationFinding
+}
+
+type validationFinding struct {
+	start int
+	body  map[string]interface{}
+}
+
+// pathElement is one step of the path to a policy element: {"value": ...}
+// for a named element or {"index": ...} for an array item.
+type pathElement map[string]interface{}
+
+// at returns path extended by elements, without modifying path.
+func at(path []pathElement, elements ...pathElement) []pathElement {
+	return append(append([]pathElement(nil), path...), elements...)
+}
+
+func key(k string) pathElement { return pathElement{"value": k} }
+func index(i int) pathElement  { return pathElement{"index": i} }
+
+// add records a finding located at the text between start and end.
+func (v *validator) add(findingType, issueCode, details string, path []pathElement, start, end int) {
+	if path == nil {
+		path = []pathElement{}
+	}
+	v.findings = append(v.findings, validationFinding{start: start, body: map[string]interface{}{
+		"findingType":    findingType,
+		"issueCode":      issueCode,
+		"findingDetails": details,
+		"learnMoreLink": "https://docs.aws.amazon.com/IAM/latest/UserGuide/access-analyzer-reference-policy-checks.html#access-analyzer-reference-policy-checks-" +
+			learnMoreAnchors[findingType] + "-" + strings.ToLower(strings.ReplaceAll(issueCode, "_", "-")),
+		"locations": []map[string]interface{}{{
+			"path": path,
+			"span": map[string]interface{}{"start": position(v.doc, start), "end": position(v.doc, end)},
+		}},
+	}})
+}
+
+// addAt records a finding located at a node.
+func (v *validator) addAt(findingType, issueCode, details string, path []pathElement, n *node) {
+	v.add(findingType, issueCode, details, path, n.start, n.end)
+}
+
+// validatePolicy runs the policy checks on doc, returning its findings in
+// document order.
+func validatePolicy(doc, policyType string) []map[string]interface{} {
+	v := &validator{doc: doc, policyType: policyType}
+	root, err := parseDocument(doc)
+	var syntax *syntaxError
+	switch {
+	case errors.As(err, &syntax):
+		end := syntax.offset
+		if end < len(doc) {
+			end++
+		}
+		v.add("ERROR", "JSON_SYNTAX_ERROR", "Fix the JSON syntax error: "+syntax.msg, nil, syntax.offset, end)
+	case root.kind != 'o':
+		v.addAt("ERROR", "JSON_SYNTAX_ERROR", "Fix the JSON syntax error: the policy must be a JSON object.", nil, root)
+	default:
+		v.policy(root)
+	}
+
+	sort.SliceStable(v.findings, func(i, j int) bool { return v.findings[i].start < v.findings[j].start })
+	out := make([]map[string]interface{}, len(v.findings))
+	for i, f := range v.findings {
+		out[i] = f.body
+	}
+	return out
+}
+
+func (v *validator) policy(root *node) {
+	for _, m := range root.members {
+		if m.key != "Version" && m.key != "Id" && m.key != "Statement" {
+			v.add("ERROR", "INVALID_POLICY_ELEMENT", "Remove the element "+m.key+": it is not a valid policy element.", []pathElement{key(m.key)}, m.start, m.end)
+		}
+	}
+
+	if version := root.field("Version"); version == nil {
+		v.add("WARNING", "MISSING_VERSION", "We recommend that you specify the Version element to help you with debugging permission issues.", nil, root.start, root.start+1)
+	} else if version.value.kind != 's' || (version.value.str != "2012-10-17" && version.value.str != "2008-10-17") {
+		v.addAt("ERROR", "INVALID_VERSION", "Specify a valid version for the policy: 2012-10-17 or 2008-10-17.", []pathElement{key("Version")}, version.value)
+	}
+
+	statement := root.field("Statement")
+	switch {
+	case statement == nil:
+		v.add("ERROR", "MISSING_STATEMENT", "Add a Statement element to the policy.", nil, root.start, root.start+1)
+	case statement.value.kind == 'o':
+		v.statement(statement.value, []pathElement{key("Statement")})
+	case statement.value.kind == 'a':
+		for i, item := range statement.value.items {
+			path := []pathElement{key("Statement"), index(i)}
+			if item.kind != 'o' {
+				v.addAt("ERROR", "INVALID_POLICY_ELEMENT", "Each statement must be a JSON object.", path, item)
+				continue
+			}
+			v.statement(item, path)
+		}
+	default:
+		v.addAt("ERROR", "INVALID_POLICY_ELEMENT", "The Statement element must be an object or an array of objects.", []pathElement{key("Statement")}, statement.value)
+	}
+}
+
+func (v *validator) statement(st *node, path []pathElement) {
+	for _, m := range st.members {
+		if !statementKeys[m.key] {
+			v.add("ERROR", "INVALID_POLICY_ELEMENT", "Remove the element "+m.key+": it is not a valid statement element.", at(path, key(m.key)), m.start, m.end)
+		}
+	}
+
+	allow := false
+	if effect := st.field("Effect"); effect == nil {
+		v.add("ERROR", "MISSING_EFFECT", "Add an Effect element to the statement with a value of Allow or Deny.", path, st.start, st.start+1)
+	} else if effect.value.kind != 's' || (effect.value.str != "Allow" && effect.value.str != "Deny") {
+		v.addAt("ERROR", "INVALID_EFFECT", "Specify a valid value for the Effect element: Allow or Deny.", at(path, key("Effect")), effect.value)
+	} else {
+		allow = effect.value.str == "Allow"
+	}
+
+	action := v.pair(st, path, "Action", "NotAction")
+	if action == nil {
+		v.add("ERROR", "MISSING_ACTION", "Add an Action or NotAction element to the statement.", path, st.start, st.start+1)
+	} else {
+		v.actions(action, at(path, key(action.key)))
+	}
+
+	resource := v.pair(st, path, "Resource", "NotResource")
+	if resource == nil && v.policyType != "RESOURCE_POLICY" {
+		v.add("ERROR", "MISSING_RESOURCE", "Add a Resource or NotResource element to the statement.", path, st.start, st.start+1)
+	} else if resource != nil {
+		v.resources(resource, at(path, key(resource.key)))
+	}
+
+	principal := v.pair(st, path, "Principal", "NotPrincipal")
+	switch {
+	case principal != nil && (v.policyType == "IDENTITY_POLICY" || v.policyType == "SERVICE_CONTROL_POLICY"):
+		v.add("ERROR", "UNSUPPORTED_PRINCIPAL", "Remove the "+principal.key+" element: principals are not supported in this type of policy.", at(path, key(principal.key)), principal.start, principal.end)
+	case principal == nil && v.policyType == "RESOURCE_POLICY":
+		v.add("ERROR", "MISSING_PRINCIPAL", "Add a Principal element to the statement of the resource policy.", path, st.start, st.start+1)
+	case principal != nil:
+		if allow && principal.key == "NotPrincipal" {
+			v.add("WARNING", "ALLOW_WITH_NOT_PRINCIPAL", "Using Allow with NotPrincipal can be overly permissive. We recommend that you use Principal instead.", at(path, key("NotPrincipal")), principal.start, principal.end)
+		}
+		v.principals(principal.value, at(path, key(principal.key)))
+	}
+
+	if condition := st.field("Condition"); condition != nil {
+		v.conditions(condition.value, at(path, key("Condition")))
+	}
+
+	if allow && action != nil && action.key == "Action" && resource != nil && resource.key == "Resource" && v.policyType != "RESOURCE_POLICY" {
+		v.passRole(action.value, resource.value, at(path, key("Resource")))
+	}
+}
+
+// pair returns whichever of two mutually exclusive elements the statement
+// has, reporting it if it has both.
+func (v *validator) pair(st *node, path []pathElement, name, notName string) *member {
+	m, not := st.field(name), st.field(notName)
+	if m != nil && not != nil {
+		v.add("ERROR", "UNSUPPORTED_ELEMENT_COMBINATION", "Remove "+name+" or "+notName+": a statement cannot include both.", at(path, key(notName)), not.start, not.end)
+	}
+	if m != nil {
+		return m
+	}
+	return not
+}
+
+// each calls fn with each value of an element that accepts a single value
+// or a list, and the path to it, reporting an empty list with issueCode.
+func (v *validator) each(n *node, path []pathElement, emptyCode, element string, fn func(*node, []pathElement)) {
+	if n.kind != 'a' {
+		fn(n, path)
+		return
+	}
+	if len(n.items) == 0 {
+		v.addAt("SUGGESTION", emptyCode, "Remove the empty "+element+" array or add values to it.", path, n)
+	}
+	for i, item := range n.items {
+		fn(item, at(path, index(i)))
+	}
+}
+
+func (v *validator) actions(m *member, path []pathElement) {
+	type entry struct {
+		n    *node
+		path []pathElement
+	}
+	var valid []entry
+	v.each(m.value, path, "EMPTY_ARRAY_ACTION", m.key, func(n *node, p []pathElement) {
+		if n.kind != 's' {
+			v.addAt("ERROR", "INVALID_ACTION", "Actions must be strings of the form service:action.", p, n)
+			return
+		}
+		if n.str == "*" {
+			valid = append(valid, entry{n, p})
+			return
+		}
+		parts := actionPattern.FindStringSubmatch(n.str)
+		switch {
+		case parts == nil:
+			v.addAt("ERROR", "INVALID_ACTION", "The action "+n.str+" is not valid: actions take the form service:action.", p, n)
+		case !servicePrefixes[strings.ToLower(parts[1])]:
+			v.addAt("ERROR", "INVALID_SERVICE_IN_ACTION", "The service "+parts[1]+" specified in the action does not exist.", p, n)
+		default:
+			valid = append(valid, entry{n, p})
+		}
+	})
+
+	for i, a := range valid {
+		for j, b := range valid {
+			if i == j || (a.n.str == b.n.str && j > i) {
+				continue
+			}
+			if wildcardMatch(b.n.str, a.n.str) {
+				v.addAt("SUGGESTION", "REDUNDANT_ACTION", "The action "+a.n.str+" is already covered by "+b.n.str+". Remove it to simplify the policy.", a.path, a.n)
+				break
+			}
+		}
+	}
+}
+
+// wildcardMatch reports whether an IAM pattern, which may use * and ?,
+// matches s, ignoring case.
+func wildcardMatch(pattern, s string) bool {
+	ok, _ := path.Match(strings.ToLower(pattern), strings.ToLower(s))
+	return ok
+}
+
+func (v *validator) resources(m *member, path []pathElement) {
+	v.each(m.value, path, "EMPTY_ARRAY_RESOURCE", m.key, func(n *node, p []pathElement) {
+		if n.kind != 's' {
+			v.addAt("ERROR", "INVALID_ARN_PREFIX", "Resources must be ARN strings or *.", p, n)
+			return
+		}
+		if n.str == "*" {
+			return
+		}
+		if !strings.HasPrefix(n.str, "arn:") {
+			v.addAt("ERROR", "INVALID_ARN_PREFIX", "The resource "+n.str+" must begin with arn:.", p, n)
+			return
+		}
+		parts := strings.SplitN(n.str, ":", 6)
+		if len(parts) < 6 || parts[2] == "" {
+			v.addAt("ERROR", "MISSING_ARN_FIELD", "The resource ARN "+n.str+" must include the partition, service, region, account, and resource fields.", p, n)
+			return
+		}
+		switch partition := parts[1]; {
+		case strings.Contains(partition, "*"), policyVariable.MatchString(partition):
+		case partition != "aws" && partition != "aws-cn" && partition != "aws-us-gov" && partition != "aws-iso" && partition != "aws-iso-b":
+			v.addAt("ERROR", "INVALID_PARTITION", "The partition "+partition+" in the resource ARN is not valid.", p, n)
+		}
+		if parts[2] == "iam" && parts[3] != "" && parts[3] != "*" {
+			v.addAt("ERROR", "ARN_REGION_NOT_ALLOWED", "IAM resource ARNs must not include a region.", p, n)
+		}
+	})
+}
+
+func (v *validator) principals(n *node, path []pathElement) {
+	switch n.kind {
+	case 's':
+		if n.str != "*" {
+			v.addAt("ERROR", "INVALID_PRINCIPAL_FORMAT", `A principal given as a string must be "*". Use an object such as {"AWS": "..."} to name principals.`, path, n)
+		}
+	case 'o':
+		if len(n.members) == 0 {
+			v.addAt("SUGGESTION", "EMPTY_OBJECT_PRINCIPAL", "Remove the empty principal object or add principals to it.", path, n)
+		}
+		for _, m := range n.members {
+			p := at(path, key(m.key))
+			if !principalKeys[m.key] {
+				v.add("ERROR", "INVALID_PRINCIPAL_KEY", "The principal key "+m.key+" is not valid: use AWS, Service, Federated, or CanonicalUser.", p, m.start, m.end)
+				continue
+			}
+			v.each(m.value, p, "EMPTY_ARRAY_PRINCIPAL", "principal", func(item *node, ip []pathElement) {
+				if item.kind != 's' || !validPrincipal(m.key, item.str) {
+					v.addAt("ERROR", "INVALID_PRINCIPAL_FORMAT", "The "+m.key+" principal "+item.str+" is not valid.", ip, item)
+				}
+			})
+		}
+	default:
+		v.addAt("ERROR", "INVALID_PRINCIPAL_FORMAT", "The principal must be \"*\" or an object.", path, n)
+	}
+}
+
+func validPrincipal(kind, value string) bool {
+	if value == "*" && kind != "CanonicalUser" {
+		return true
+	}
+	switch kind {
+	case "AWS":
+		return awsPrincipalPattern.MatchString(value)
+	case "Service":
+		return servicePrincipal.MatchString(value)
+	case "Federated":
+		return federatedPrincipal.MatchString(value)
+	}
+	return len(value) == 64
+}
+
+func (v *validator) conditions(n *node, path []pathElement) {
+	if n.kind != 'o' {
+		v.addAt("ERROR", "INVALID_POLICY_ELEMENT", "The Condition element must be an object.", path, n)
+		return
+	}
+	if len(n.members) == 0 {
+		v.addAt("SUGGESTION", "EMPTY_OBJECT_CONDITION", "Remove the empty Condition object or add conditions to it.", path, n)
+	}
+	for _, op := range n.members {
+		opPath := at(path, key(op.key))
+		base := strings.TrimPrefix(strings.TrimPrefix(op.key, "ForAllValues:"), "ForAnyValue:")
+		ifExists := strings.HasSuffix(base, "IfExists")
+		base = strings.TrimSuffix(base, "IfExists")
+		valueType, known := conditionTypes[base]
+		switch {
+		case !known:
+			v.add("ERROR", "INVALID_OPERATOR", "The condition operator "+op.key+" is not valid.", opPath, op.start, op.end)
+			continue
+		case base == "Null" && ifExists:
+			v.add("ERROR", "NULL_WITH_IF_EXISTS", "You cannot use the IfExists suffix with the Null condition operator.", opPath, op.start, op.end)
+			continue
+		case op.value.kind != 'o':
+			v.addAt("ERROR", "INVALID_POLICY_ELEMENT", "The condition operator "+op.key+" must map condition keys to values.", opPath, op.value)
+			continue
+		}
+		for _, k := range op.value.members {
+			keyPath := at(opPath, key(k.key))
+			name := strings.ToLower(k.key)
+			service, _, ok := strings.Cut(name, ":")
+			switch {
+			case !ok || service == "":
+				v.add("ERROR", "INVALID_CONDITION_KEY_FORMAT", "The condition key "+k.key+" must take the form service:key.", keyPath, k.start, k.end)
+			case service == "aws" && !globalConditionKey(name):
+				v.add("ERROR", "INVALID_GLOBAL_CONDITION_KEY", "The condition key "+k.key+" is not a valid global condition key.", keyPath, k.start, k.end)
+			}
+			v.each(k.value, keyPath, "EMPTY_ARRAY_CONDITION", "condition value", func(item *node, ip []pathElement) {
+				if !conditionValueMatches(valueType, item) {
+					v.addAt("ERROR", "DATA_TYPE_MISMATCH", "The value "+item.str+" does not match the type of the "+op.key+" operator.", ip, item)
+				}
+			})
+		}
+	}
+}
+
+func globalConditionKey(name string) bool {
+	if globalConditionKeys[name] {
+		return true
+	}
+	prefix, _, ok := strings.Cut(name, "/")
+	return ok && globalConditionKeys[prefix+"/"]
+}
+
+// conditionValueMatches reports whether a condition value is of the type
+// its operator compares. Values using policy variables are not checked.
+func conditionValueMatches(valueType string, n *node) bool {
+	if n.kind == 'o' || n.kind == 'a' || n.kind == 'z' {
+		return false
+	}
+	if policyVariable.MatchString(n.str) {
+		return true
+	}
+	switch valueType {
+	case "numeric":
+		_, err := strconv.ParseFloat(n.str, 64)
+		return err == nil
+	case "date":
+		if _, err := strconv.ParseInt(n.str, 10, 64); err == nil {
+			return true
+		}
+		for _, layout := range []string{time.RFC3339, "2006-01-02T15:04Z07:00", "2006-01-02"} {
+			if _, err := time.Parse(layout, n.str); err == nil {
+				return true
+			}
+		}
+		return false
+	case "bool":
+		return strings.EqualFold(n.str, "true") || strings.EqualFold(n.str, "false")
+	case "ip":
+		if _, _, err := net.ParseCIDR(n.str); err == nil {
+			return true
+		}
+		return net.ParseIP(n.str) != nil
+	}
+	return true
+}
+
+// passRole reports an allowed iam:PassRole, which lets the principal pass
+// any role to a service, when the statement's resources include "*".
+func (v *validator) passRole(actions, resources *node, path []pathElement) {
+	code := ""
+	for _, a := range actions.values() {
+		if a.kind == 's' && wildcardMatch(a.str, "iam:PassRole") {
+			code = "PASS_ROLE_WITH_STAR_IN_RESOURCE"
+			if strings.ContainsAny(a.str, "*?") {
+				code = "PASS_ROLE_WITH_STAR_IN_ACTION_AND_RESOURCE"
+			}
+			break
+		}
+	}
+	if code == "" {
+		return
+	}
+	for i, r := range resources.values() {
+		if r.kind == 's' && r.str == "*" {
+			p := path
+			if resources.kind == 'a' {
+				p = at(path, index(i))
+			}
+			v.addAt("SECURITY_WARNING", code, "Using the iam:PassRole action with a wildcard (*) in the resource can be overly permissive because it allows iam:PassRole permissions on multiple resources. We recommend that you specify resource ARNs or add the iam:PassedToService condition key to your statement.", p, r)
+			return
+		}
+	}
+}
+
+func (s *Service) validatePolicy(w http.ResponseWriter, params map[string]interface{}, q url.Values) {
+	doc, policyType := h.GetString(params, "policyDocument"), h.GetString(params, "policyType")
+	if doc == "" {
+		writeValidation(w, "policyDocument is required.")
+		return
+	}
+	if !policyTypes[policyType] {
+		writeValidation(w, "Invalid policyType: "+policyType+".")
+		return
+	}
+	// The findings depend only on the request, so they are keyed by position.
+	limit, _ := strconv.Atoi(q.Get("maxResults"))
+	writePage(w, "findings", validatePolicy(doc, policyType), paginate.Seq, q.Get("nextToken"), limit)
+}
@@ -5,25 +5,30 @@
 //   - DescribeCertificate
 //   - ListCertificates
 //   - DeleteCertificate
+//   - AddTagsToCertificate
+//   - RemoveTagsFromCertificate
+//   - ListTagsForCertificate
 package acm
 
 import (
-	"encoding/json"
 	"fmt"
-	"io"
 	"net/http"
 	"sort"
-	"strings"
 	"sync"
 	"time"
 
+	"github.com/riyanimam/goto/internal/lifecycle"
 	h "github.com/riyanimam/goto/internal/mockhelpers"
+	"github.com/riyanimam/goto/internal/tags"
 )
 
 // Service implements the ACM mock.
 type Service struct {
 	mu    sync.RWMutex
 	certs map[string]*certificate
+	tags  *tags.Store
+
+	transitions *lifecycle.Transitions
 }
 
 type certificate struct {
@@ -34,12 +39,14 @@ type certificate struct {
 	certType         string
 	validationMethod string
 	created          time.Time
+	ready            lifecycle.Transition
 }
 
 // New creates a new ACM mock service.
 func New() *Service {
 	return &Service{
 		certs: make(map[string]*certificate),
+		tags:  tags.New(),
 	}
 }
 
@@ -48,7 +55,15 @@ func (s *Service) Name() string { return "acm" }
 
 // Handler returns the HTTP handler for ACM requests.
 func (s *Service) Handler() http.Handler {
-	return http.HandlerFunc(s.handle)
+	return h.JSONRouter{
+		"RequestCertificate":        s.requestCertificate,
+		"DescribeCertificate":       s.describeCertificate,
+		"ListCertificates":          s.listCertificates,
+		"DeleteCertificate":         s.deleteCertificate,
+		"AddTagsToCertificate":      s.addTagsToCertificate,
+		"RemoveTagsFromCertificate": s.removeTagsFromCertificate,
+		"ListTagsForCertificate":    s.listTagsForCertificate,
+	}
 }
 
 // Reset clears all state.
@@ -56,45 +71,21 @@ func (s *Service) Reset() {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	s.certs = make(map[string]*certificate)
+	s.tags.DeleteService("acm")
 }
 
-func (s *Service) handle(w http.ResponseWriter, r *http.Request) {
-	target := r.Header.Get("X-Amz-Target")
-
-	bodyBytes, err := io.ReadAll(r.Body)
-	if err != nil {
-		h.WriteJSONError(w, "InternalFailure", "could not read request body", http.StatusInternalServerError)
-		return
-	}
-
-	var params map[string]interface{}
-	if len(bodyBytes) > 0 {
-		json.Unmarshal(bodyBytes, &params)
-	}
-	if params == nil {
-		params = make(map[string]interface{})
-	}
-
-	action := ""
-	if target != "" {
-		parts := strings.SplitN(target, ".", 2)
-		if len(parts) == 2 {
-			action = parts[1]
-		}
-	}
+// SetTagStore sets the registry resource tags are recorded in.
+func (s *Service) SetTagStore(store *tags.Store) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tags = store
+}
 
-	switch action {
-	case "RequestCertificate":
-		s.requestCertificate(w, params)
-	case "DescribeCertificate":
-		s.describeCertificate(w, params)
-	case "ListCertificates":
-		s.listCertificates(w, params)
-	case "DeleteCertificate":
-		s.deleteCertificate(w, params)
-	default:
-		h.WriteJSONError(w, "UnknownOperationException", fmt.Sprintf("action %q is not supported", action), http.StatusBadRequest)
-	}
+// SetTransitions sets how long new certificates stay PENDING_VALIDATION.
+func (s *Service) SetTransitions(t *lifecycle.Transitions) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.transitions = t
 }
 
 func (s *Service) requestCertificate(w http.ResponseWriter, params map[string]interface{}) {
@@ -128,8 +119,10 @@ func (s *Service) requestCertificate(w http.ResponseWriter, params map[string]in
 		certType:         "AMAZON_ISSUED",
 		validationMethod: validationMethod,
 		created:          time.Now().UTC(),
+		ready:            s.transitions.Begin(),
 	}
 	s.certs[arn] = cert
+	s.tags.Tag(arn, tags.FromList(params["Tags"], "Key", "Value"))
 	s.mu.Unlock()
 
 	h.WriteJSON(w, http.StatusOK, map[string]interface{}{
@@ -150,7 +143,7 @@ func (s *Service) describeCertificate(w http.ResponseWriter, params map[string]i
 	}
 
 	h.WriteJSON(w, http.StatusOK, map[string]interface{}{
-		"Certificate": certResp(cert),
+		"Certificate": s.certResp(cert),
 	})
 }
 
@@ -161,7 +154,7 @@ func (s *Service) listCertificates(w http.ResponseWriter, _ map[string]interface
 		summaries = append(summaries, map[string]interface{}{
 			"CertificateArn": cert.arn,
 			"DomainName":     cert.domainName,
-			"Status":         cert.status,
+			"Status":         s.certStatus(cert),
 			"Type":           cert.certType,
 		})
 	}
@@ -186,16 +179,70 @@ func (s *Service) deleteCertificate(w http.ResponseWriter, params map[string]int
 		return
 	}
 	delete(s.certs, arn)
+	s.tags.Delete(arn)
 	s.mu.Unlock()
 
 	h.WriteJSON(w, http.StatusOK, map[string]interface{}{})
 }
 
-func certResp(cert *certificate) map[string]interface{} {
+func (s *Service) addTagsToCertificate(w http.ResponseWriter, params map[string]interface{}) {
+	arn := h.GetString(params, "CertificateArn")
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.certs[arn] == nil {
+		h.WriteJSONError(w, "ResourceNotFoundException", "Certificate not found: "+arn, http.StatusBadRequest)
+		return
+	}
+	s.tags.Tag(arn, tags.FromList(params["Tags"], "Key", "Value"))
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{})
+}
+
+func (s *Service) removeTagsFromCertificate(w http.ResponseWriter, params map[string]interface{}) {
+	arn := h.GetString(params, "CertificateArn")
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.certs[arn] == nil {
+		h.WriteJSONError(w, "ResourceNotFoundException", "Certificate not found: "+arn, http.StatusBadRequest)
+		return
+	}
+	var keys []string
+	for k := range tags.FromList(params["Tags"], "Key", "Value") {
+		keys = append(keys, k)
+	}
+	s.tags.Untag(arn, keys)
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{})
+}
+
+func (s *Service) listTagsForCertificate(w http.ResponseWriter, params map[string]interface{}) {
+	arn := h.GetString(params, "CertificateArn")
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.certs[arn] == nil {
+		h.WriteJSONError(w, "ResourceNotFoundException", "Certificate not found: "+arn, http.StatusBadRequest)
+		return
+	}
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"Tags": tags.ToList(s.tags.Get(arn), "Key", "Value"),
+	})
+}
+
+// certStatus returns the status of cert, which is PENDING_VALIDATION until
+// validation completes.
+func (s *Service) certStatus(cert *certificate) string {
+	return s.transitions.Status(cert.ready, "PENDING_VALIDATION", cert.status)
+}
+
+func (s *Service) certResp(cert *certificate) map[string]interface{} {
 	resp := map[string]interface{}{
 		"CertificateArn":          cert.arn,
 		"DomainName":              cert.domainName,
-		"Status":                  cert.status,
+		"Status":                  s.certStatus(cert),
 		"Type":                    cert.certType,
 		"DomainValidationOptions": []interface{}{},
 		"CreatedAt":               float64(cert.created.Unix()),
@@ -0,0 +1,471 @@
+// Package acmpca provides a mock implementation of AWS Private Certificate
+// Authority.
+//
+// Supported actions:
+//   - CreateCertificateAuthority
+//   - DescribeCertificateAuthority
+//   - ListCertificateAuthorities
+//   - DeleteCertificateAuthority
+//   - GetCertificateAuthorityCsr
+//   - ImportCertificateAuthorityCertificate
+//   - GetCertificateAuthorityCertificate
+//   - IssueCertificate
+//   - GetCertificate
+//   - RevokeCertificate
+//   - TagCertificateAuthority
+//   - UntagCertificateAuthority
+//   - ListTags
+//
+// Certificate authorities are real: each has its own private key, and
+// IssueCertificate signs the submitted CSR with it, so issued certificates
+// verify against the CA certificate with crypto/x509 or openssl.
+package acmpca
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	h "github.com/riyanimam/goto/internal/mockhelpers"
+	"github.com/riyanimam/goto/internal/tags"
+)
+
+// Service implements the ACM Private CA mock.
+type Service struct {
+	mu    sync.RWMutex
+	cas   map[string]*authority
+	tags  *tags.Store
+	store h.ObjectStore
+}
+
+// authority is a private certificate authority.
+type authority struct {
+	arn              string
+	caType           string // ROOT or SUBORDINATE
+	status           string
+	config           map[string]interface{}
+	revocationConfig map[string]interface{}
+	keyAlgorithm     string
+	signingAlgorithm string
+	key              crypto.Signer
+	csr              []byte // PEM
+	cert             *x509.Certificate
+	certPEM          []byte
+	chainPEM         []byte
+	created          time.Time
+	lastStateChange  time.Time
+	issued           map[string]*issuedCert // keyed by certificate ARN
+	revoked          []x509.RevocationListEntry
+	crlNumber        int64
+}
+
+// New creates a new ACM Private CA mock service.
+func New() *Service {
+	return &Service{
+		cas:  make(map[string]*authority),
+		tags: tags.New(),
+	}
+}
+
+// Name returns the service identifier.
+func (s *Service) Name() string { return "acm-pca" }
+
+// Handler returns the HTTP handler for ACM Private CA requests.
+func (s *Service) Handler() http.Handler {
+	return h.JSONRouter{
+		"CreateCertificateAuthority":            s.createCertificateAuthority,
+		"DescribeCertificateAuthority":          s.describeCertificateAuthority,
+		"ListCertificateAuthorities":            s.listCertificateAuthorities,
+		"DeleteCertificateAuthority":            s.deleteCertificateAuthority,
+		"GetCertificateAuthorityCsr":            s.getCertificateAuthorityCsr,
+		"ImportCertificateAuthorityCertificate": s.importCertificateAuthorityCertificate,
+		"GetCertificateAuthorityCertificate":    s.getCertificateAuthorityCertificate,
+		"IssueCertificate":                      s.issueCertificate,
+		"GetCertificate":                        s.getCertificate,
+		"RevokeCertificate":                     s.revokeCertificate,
+		"TagCertificateAuthority":               s.tagCertificateAuthority,
+		"UntagCertificateAuthority":             s.untagCertificateAuthority,
+		"ListTags":                              s.listTags,
+	}
+}
+
+// Reset clears all state.
+func (s *Service) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cas = make(map[string]*authority)
+	s.tags.DeleteService("acm-pca")
+}
+
+// SetTagStore sets the registry resource tags are recorded in.
+func (s *Service) SetTagStore(store *tags.Store) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tags = store
+}
+
+// SetObjectStore sets the store certificate revocation lists are written
+// to.
+func (s *Service) SetObjectStore(store h.ObjectStore) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.store = store
+}
+
+// HasResource reports whether arn names an existing certificate authority
+// or a certificate it issued.
+func (s *Service) HasResource(arn string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if _, ok := s.cas[arn]; ok {
+		return true
+	}
+	for _, ca := range s.cas {
+		if _, ok := ca.issued[arn]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// signatureAlgorithms maps ACM Private CA signing algorithms to their x509
+// equivalents, by key family.
+var signatureAlgorithms = map[string]struct {
+	alg x509.SignatureAlgorithm
+	ec  bool
+}{
+	"SHA256WITHRSA":   {x509.SHA256WithRSA, false},
+	"SHA384WITHRSA":   {x509.SHA384WithRSA, false},
+	"SHA512WITHRSA":   {x509.SHA512WithRSA, false},
+	"SHA256WITHECDSA": {x509.ECDSAWithSHA256, true},
+	"SHA384WITHECDSA": {x509.ECDSAWithSHA384, true},
+	"SHA512WITHECDSA": {x509.ECDSAWithSHA512, true},
+}
+
+// generateKey creates a private key for a KeyAlgorithm.
+func generateKey(algorithm string) (crypto.Signer, error) {
+	switch algorithm {
+	case "RSA_2048":
+		return rsa.GenerateKey(rand.Reader, 2048)
+	case "RSA_3072":
+		return rsa.GenerateKey(rand.Reader, 3072)
+	case "RSA_4096":
+		return rsa.GenerateKey(rand.Reader, 4096)
+	case "EC_prime256v1":
+		return ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case "EC_secp384r1":
+		return ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	}
+	return nil, fmt.Errorf("unsupported KeyAlgorithm %s", algorithm)
+}
+
+// subjectName converts an ASN1Subject to a pkix.Name.
+func subjectName(subject map[string]interface{}) pkix.Name {
+	list := func(key string) []string {
+		if v := h.GetString(subject, key); v != "" {
+			return []string{v}
+		}
+		return nil
+	}
+	return pkix.Name{
+		CommonName:         h.GetString(subject, "CommonName"),
+		SerialNumber:       h.GetString(subject, "SerialNumber"),
+		Country:            list("Country"),
+		Organization:       list("Organization"),
+		OrganizationalUnit: list("OrganizationalUnit"),
+		Province:           list("State"),
+		Locality:           list("Locality"),
+	}
+}
+
+func (s *Service) createCertificateAuthority(w http.ResponseWriter, params map[string]interface{}) {
+	caType := h.GetString(params, "CertificateAuthorityType")
+	config, _ := params["CertificateAuthorityConfiguration"].(map[string]interface{})
+	if caType != "ROOT" && caType != "SUBORDINATE" {
+		h.WriteJSONError(w, "InvalidArgsException", "CertificateAuthorityType must be ROOT or SUBORDINATE", http.StatusBadRequest)
+		return
+	}
+	if config == nil {
+		h.WriteJSONError(w, "InvalidArgsException", "CertificateAuthorityConfiguration is required", http.StatusBadRequest)
+		return
+	}
+	keyAlgorithm := h.GetString(config, "KeyAlgorithm")
+	signingAlgorithm := h.GetString(config, "SigningAlgorithm")
+	sig, ok := signatureAlgorithms[signingAlgorithm]
+	if !ok {
+		h.WriteJSONError(w, "InvalidArgsException", "Unsupported SigningAlgorithm "+signingAlgorithm, http.StatusBadRequest)
+		return
+	}
+	if strings.HasPrefix(keyAlgorithm, "EC_") != sig.ec {
+		h.WriteJSONError(w, "InvalidArgsException",
+			fmt.Sprintf("SigningAlgorithm %s cannot be used with KeyAlgorithm %s", signingAlgorithm, keyAlgorithm), http.StatusBadRequest)
+		return
+	}
+	key, err := generateKey(keyAlgorithm)
+	if err != nil {
+		h.WriteJSONError(w, "InvalidArgsException", err.Error(), http.StatusBadRequest)
+		return
+	}
+	subject, _ := config["Subject"].(map[string]interface{})
+	der, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		Subject:            subjectName(subject),
+		SignatureAlgorithm: sig.alg,
+	}, key)
+	if err != nil {
+		h.WriteJSONError(w, "InvalidArgsException", err.Error(), http.StatusBadRequest)
+		return
+	}
+	revocation, _ := params["RevocationConfiguration"].(map[string]interface{})
+
+	now := time.Now().UTC()
+	ca := &authority{
+		arn:              fmt.Sprintf("arn:aws:acm-pca:us-east-1:%s:certificate-authority/%s", h.DefaultAccountID, h.NewRequestID()),
+		caType:           caType,
+		status:           "PENDING_CERTIFICATE",
+		config:           config,
+		revocationConfig: revocation,
+		keyAlgorithm:     keyAlgorithm,
+		signingAlgorithm: signingAlgorithm,
+		key:              key,
+		csr:              pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: der}),
+		created:          now,
+		lastStateChange:  now,
+		issued:           make(map[string]*issuedCert),
+	}
+
+	s.mu.Lock()
+	s.cas[ca.arn] = ca
+	s.tags.Tag(ca.arn, tags.FromList(params["Tags"], "Key", "Value"))
+	s.mu.Unlock()
+
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"CertificateAuthorityArn": ca.arn,
+	})
+}
+
+// lookup returns the certificate authority the request names, writing an
+// error response if there is none. The caller must hold s.mu.
+func (s *Service) lookup(w http.ResponseWriter, params map[string]interface{}) *authority {
+	arn := h.GetString(params, "CertificateAuthorityArn")
+	ca, exists := s.cas[arn]
+	if !exists {
+		h.WriteJSONError(w, "ResourceNotFoundException", "Could not find certificate authority "+arn, http.StatusBadRequest)
+		return nil
+	}
+	return ca
+}
+
+func (s *Service) describeCertificateAuthority(w http.ResponseWriter, params map[string]interface{}) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if ca := s.lookup(w, params); ca != nil {
+		h.WriteJSON(w, http.StatusOK, map[string]interface{}{
+			"CertificateAuthority": ca.toMap(),
+		})
+	}
+}
+
+func (s *Service) listCertificateAuthorities(w http.ResponseWriter, _ map[string]interface{}) {
+	s.mu.RLock()
+	list := make([]map[string]interface{}, 0, len(s.cas))
+	for _, ca := range s.cas {
+		list = append(list, ca.toMap())
+	}
+	s.mu.RUnlock()
+
+	sort.Slice(list, func(i, j int) bool {
+		return list[i]["Arn"].(string) < list[j]["Arn"].(string)
+	})
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"CertificateAuthorities": list,
+	})
+}
+
+// deleteCertificateAuthority moves a CA to DELETED. Deleted CAs stay listed,
+// as they do during AWS's restoration period, but can no longer issue
+// certificates.
+func (s *Service) deleteCertificateAuthority(w http.ResponseWriter, params map[string]interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ca := s.lookup(w, params)
+	if ca == nil {
+		return
+	}
+	if ca.status == "DELETED" {
+		h.WriteJSONError(w, "InvalidStateException", "The certificate authority is already deleted", http.StatusBadRequest)
+		return
+	}
+	ca.status = "DELETED"
+	ca.lastStateChange = time.Now().UTC()
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{})
+}
+
+func (s *Service) getCertificateAuthorityCsr(w http.ResponseWriter, params map[string]interface{}) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	ca := s.lookup(w, params)
+	if ca == nil {
+		return
+	}
+	if ca.status == "DELETED" {
+		h.WriteJSONError(w, "InvalidStateException", "The certificate authority is deleted", http.StatusBadRequest)
+		return
+	}
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"Csr": string(ca.csr),
+	})
+}
+
+func (s *Service) importCertificateAuthorityCertificate(w http.ResponseWriter, params map[string]interface{}) {
+	certPEM, err := decodeBlob(params, "Certificate")
+	if err != nil {
+		h.WriteJSONError(w, "MalformedCertificateException", "Certificate must be a base64-encoded PEM certificate", http.StatusBadRequest)
+		return
+	}
+	cert, err := parseCertificate(certPEM)
+	if err != nil {
+		h.WriteJSONError(w, "MalformedCertificateException", err.Error(), http.StatusBadRequest)
+		return
+	}
+	chainPEM, err := decodeBlob(params, "CertificateChain")
+	if err != nil {
+		h.WriteJSONError(w, "MalformedCertificateException", "CertificateChain must be base64-encoded PEM certificates", http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	publish := s.activate(w, params, cert, chainPEM)
+	s.mu.Unlock()
+
+	if publish != nil {
+		publish()
+		h.WriteJSON(w, http.StatusOK, map[string]interface{}{})
+	}
+}
+
+// activate installs cert as the certificate of the CA the request names and
+// makes the CA ACTIVE. It returns a function that publishes the CA's first
+// CRL, or nil after writing an error response. The caller must hold s.mu.
+func (s *Service) activate(w http.ResponseWriter, params map[string]interface{}, cert *x509.Certificate, chainPEM []byte) func() {
+	ca := s.lookup(w, params)
+	if ca == nil {
+		return nil
+	}
+	switch {
+	case ca.status == "DELETED":
+		h.WriteJSONError(w, "InvalidStateException", "The certificate authority is deleted", http.StatusBadRequest)
+		return nil
+	case !publicKeysEqual(cert.PublicKey, ca.key.Public()):
+		h.WriteJSONError(w, "CertificateMismatchException",
+			"The certificate does not match the certificate authority's CSR", http.StatusBadRequest)
+		return nil
+	case ca.caType == "SUBORDINATE" && len(chainPEM) == 0:
+		h.WriteJSONError(w, "MalformedCertificateException",
+			"A CertificateChain is required for a subordinate certificate authority", http.StatusBadRequest)
+		return nil
+	}
+	if len(chainPEM) > 0 {
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(chainPEM) {
+			h.WriteJSONError(w, "MalformedCertificateException", "CertificateChain contains no certificates", http.StatusBadRequest)
+			return nil
+		}
+		if _, err := cert.Verify(x509.VerifyOptions{Roots: pool, KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageAny}}); err != nil {
+			h.WriteJSONError(w, "InvalidRequestException", "The certificate does not chain to the CertificateChain: "+err.Error(), http.StatusBadRequest)
+			return nil
+		}
+	}
+	ca.cert = cert
+	ca.certPEM = pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})
+	ca.chainPEM = chainPEM
+	ca.status = "ACTIVE"
+	ca.lastStateChange = time.Now().UTC()
+	return s.crl(ca)
+}
+
+func (s *Service) getCertificateAuthorityCertificate(w http.ResponseWriter, params map[string]interface{}) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	ca := s.lookup(w, params)
+	if ca == nil {
+		return
+	}
+	if ca.cert == nil {
+		h.WriteJSONError(w, "InvalidStateException", "The certificate authority has no certificate", http.StatusBadRequest)
+		return
+	}
+	resp := map[string]interface{}{
+		"Certificate": string(ca.certPEM),
+	}
+	if len(ca.chainPEM) > 0 {
+		resp["CertificateChain"] = string(ca.chainPEM)
+	}
+	h.WriteJSON(w, http.StatusOK, resp)
+}
+
+func (s *Service) tagCertificateAuthority(w http.ResponseWriter, params map[string]interface{}) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if ca := s.lookup(w, params); ca != nil {
+		s.tags.Tag(ca.arn, tags.FromList(params["Tags"], "Key", "Value"))
+		h.WriteJSON(w, http.StatusOK, map[string]interface{}{})
+	}
+}
+
+func (s *Service) untagCertificateAuthority(w http.ResponseWriter, params map[string]interface{}) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if ca := s.lookup(w, params); ca != nil {
+		var keys []string
+		for k := range tags.FromList(params["Tags"], "Key", "Value") {
+			keys = append(keys, k)
+		}
+		s.tags.Untag(ca.arn, keys)
+		h.WriteJSON(w, http.StatusOK, map[string]interface{}{})
+	}
+}
+
+func (s *Service) listTags(w http.ResponseWriter, params map[string]interface{}) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if ca := s.lookup(w, params); ca != nil {
+		h.WriteJSON(w, http.StatusOK, map[string]interface{}{
+			"Tags": tags.ToList(s.tags.Get(ca.arn), "Key", "Value"),
+		})
+	}
+}
+
+// toMap returns the CertificateAuthority structure of ca.
+func (ca *authority) toMap() map[string]interface{} {
+	m := map[string]interface{}{
+		"Arn":                               ca.arn,
+		"OwnerAccount":                      h.DefaultAccountID,
+		"Type":                              ca.caType,
+		"Status":                            ca.status,
+		"CreatedAt":                         float64(ca.created.Unix()),
+		"LastStateChangeAt":                 float64(ca.lastStateChange.Unix()),
+		"CertificateAuthorityConfiguration": ca.config,
+		"KeyStorageSecurityStandard":        "FIPS_140_2_LEVEL_3_OR_HIGHER",
+		"UsageMode":                         "GENERAL_PURPOSE",
+	}
+	if ca.revocationConfig != nil {
+		m["RevocationConfiguration"] = ca.revocationConfig
+	}
+	if ca.cert != nil {
+		m["Serial"] = formatSerial(ca.cert.SerialNumber)
+		m["NotBefore"] = float64(ca.cert.NotBefore.Unix())
+		m["NotAfter"] = float64(ca.cert.NotAfter.Unix())
+	}
+	return m
+}
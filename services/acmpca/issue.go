@@ -0,0 +1,405 @@
+package acmpca
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	h "github.com/riyanimam/goto/internal/mockhelpers"
+)
+
+// issuedCert is a certificate signed by a CA.
+type issuedCert struct {
+	arn      string
+	serial   *big.Int
+	certPEM  []byte
+	chainPEM []byte
+	revoked  bool
+}
+
+// certTemplate is the part of an ACM Private CA certificate template the
+// mock applies: whether it issues CA certificates, and the key usages it
+// grants.
+type certTemplate struct {
+	isCA     bool
+	pathLen  int // -1 for no limit
+	usage    x509.KeyUsage
+	extUsage []x509.ExtKeyUsage
+}
+
+// templateFor returns the template a TemplateArn such as
+// "arn:aws:acm-pca:::template/EndEntityCertificate/V1" names, and whether
+// it is the template a root CA signs its own certificate with.
+func templateFor(arn string) (certTemplate, bool, error) {
+	name := "EndEntityCertificate"
+	if arn != "" {
+		_, rest, ok := strings.Cut(arn, ":template/")
+		if !ok {
+			return certTemplate{}, false, fmt.Errorf("invalid TemplateArn %s", arn)
+		}
+		name, _, _ = strings.Cut(rest, "/")
+	}
+	caUsage := x509.KeyUsageCertSign | x509.KeyUsageCRLSign | x509.KeyUsageDigitalSignature
+	switch name {
+	case "EndEntityCertificate":
+		return certTemplate{
+			usage:    x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+			extUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		}, false, nil
+	case "EndEntityServerAuthCertificate":
+		return certTemplate{
+			usage:    x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+			extUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		}, false, nil
+	case "EndEntityClientAuthCertificate":
+		return certTemplate{
+			usage:    x509.KeyUsageDigitalSignature,
+			extUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		}, false, nil
+	case "CodeSigningCertificate":
+		return certTemplate{
+			usage:    x509.KeyUsageDigitalSignature,
+			extUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning},
+		}, false, nil
+	case "RootCACertificate":
+		return certTemplate{isCA: true, pathLen: -1, usage: caUsage}, true, nil
+	case "SubordinateCACertificate_PathLen0", "SubordinateCACertificate_PathLen1",
+		"SubordinateCACertificate_PathLen2", "SubordinateCACertificate_PathLen3":
+		n, _ := strconv.Atoi(strings.TrimPrefix(name, "SubordinateCACertificate_PathLen"))
+		return certTemplate{isCA: true, pathLen: n, usage: caUsage}, false, nil
+	}
+	return certTemplate{}, false, fmt.Errorf("unsupported certificate template %s", name)
+}
+
+// notAfter returns the end of a Validity starting at now.
+func notAfter(validity map[string]interface{}, now time.Time) (time.Time, error) {
+	value, ok := validity["Value"].(float64)
+	if !ok || value <= 0 {
+		return time.Time{}, errors.New("Validity.Value must be a positive number")
+	}
+	v := int(value)
+	switch validity["Type"] {
+	case "DAYS":
+		return now.AddDate(0, 0, v), nil
+	case "MONTHS":
+		return now.AddDate(0, v, 0), nil
+	case "YEARS":
+		return now.AddDate(v, 0, 0), nil
+	case "ABSOLUTE":
+		return time.Unix(int64(value), 0).UTC(), nil
+	case "END_DATE":
+		return time.Parse("20060102150405", strconv.FormatInt(int64(value), 10))
+	}
+	return time.Time{}, fmt.Errorf("unsupported Validity.Type %v", validity["Type"])
+}
+
+// decodeBlob returns the base64-decoded blob parameter key, or nil if it is
+// absent.
+func decodeBlob(params map[string]interface{}, key string) ([]byte, error) {
+	v := h.GetString(params, key)
+	if v == "" {
+		return nil, nil
+	}
+	return base64.StdEncoding.DecodeString(v)
+}
+
+// parseCertificate parses a PEM-encoded certificate.
+func parseCertificate(data []byte) (*x509.Certificate, error) {
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "CERTIFICATE" {
+		return nil, errors.New("the certificate is not PEM-encoded")
+	}
+	return x509.ParseCertificate(block.Bytes)
+}
+
+// publicKeysEqual reports whether a and b are the same public key.
+func publicKeysEqual(a, b crypto.PublicKey) bool {
+	k, ok := a.(interface{ Equal(crypto.PublicKey) bool })
+	return ok && k.Equal(b)
+}
+
+// formatSerial formats a serial number as colon-separated hex, as AWS
+// reports it.
+func formatSerial(n *big.Int) string {
+	b := n.Bytes()
+	parts := make([]string, len(b))
+	for i, c := range b {
+		parts[i] = fmt.Sprintf("%02x", c)
+	}
+	return strings.Join(parts, ":")
+}
+
+// parseSerial parses a serial number in hex, with or without colons.
+func parseSerial(s string) (*big.Int, bool) {
+	return new(big.Int).SetString(strings.ReplaceAll(s, ":", ""), 16)
+}
+
+func (s *Service) issueCertificate(w http.ResponseWriter, params map[string]interface{}) {
+	tmpl, selfSigned, err := templateFor(h.GetString(params, "TemplateArn"))
+	if err != nil {
+		h.WriteJSONError(w, "InvalidArgsException", err.Error(), http.StatusBadRequest)
+		return
+	}
+	signingAlgorithm := h.GetString(params, "SigningAlgorithm")
+	sig, ok := signatureAlgorithms[signingAlgorithm]
+	if !ok {
+		h.WriteJSONError(w, "InvalidArgsException", "Unsupported SigningAlgorithm "+signingAlgorithm, http.StatusBadRequest)
+		return
+	}
+	validity, _ := params["Validity"].(map[string]interface{})
+	if validity == nil {
+		h.WriteJSONError(w, "InvalidArgsException", "Validity is required", http.StatusBadRequest)
+		return
+	}
+	now := time.Now().UTC()
+	end, err := notAfter(validity, now)
+	if err != nil {
+		h.WriteJSONError(w, "InvalidArgsException", err.Error(), http.StatusBadRequest)
+		return
+	}
+	csrPEM, err := decodeBlob(params, "Csr")
+	if err != nil {
+		h.WriteJSONError(w, "MalformedCSRException", "Csr must be a base64-encoded PEM CSR", http.StatusBadRequest)
+		return
+	}
+	block, _ := pem.Decode(csrPEM)
+	if block == nil || block.Type != "CERTIFICATE REQUEST" {
+		h.WriteJSONError(w, "MalformedCSRException", "The CSR is not PEM-encoded", http.StatusBadRequest)
+		return
+	}
+	csr, err := x509.ParseCertificateRequest(block.Bytes)
+	if err == nil {
+		err = csr.CheckSignature()
+	}
+	if err != nil {
+		h.WriteJSONError(w, "MalformedCSRException", err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ca := s.lookup(w, params)
+	if ca == nil {
+		return
+	}
+	switch {
+	case ca.status == "DELETED":
+		h.WriteJSONError(w, "InvalidStateException", "The certificate authority is deleted", http.StatusBadRequest)
+		return
+	case selfSigned && ca.caType != "ROOT":
+		h.WriteJSONError(w, "InvalidArgsException", "Only a root certificate authority can use the RootCACertificate template", http.StatusBadRequest)
+		return
+	case selfSigned && !publicKeysEqual(csr.PublicKey, ca.key.Public()):
+		h.WriteJSONError(w, "InvalidArgsException", "A root CA certificate must be issued for the certificate authority's own CSR", http.StatusBadRequest)
+		return
+	case !selfSigned && ca.status != "ACTIVE":
+		h.WriteJSONError(w, "InvalidStateException",
+			fmt.Sprintf("The certificate authority is %s, not ACTIVE", ca.status), http.StatusBadRequest)
+		return
+	case sig.ec != strings.HasPrefix(ca.keyAlgorithm, "EC_"):
+		h.WriteJSONError(w, "InvalidArgsException",
+			fmt.Sprintf("SigningAlgorithm %s cannot be used with the certificate authority's %s key", signingAlgorithm, ca.keyAlgorithm), http.StatusBadRequest)
+		return
+	case !selfSigned && end.After(ca.cert.NotAfter):
+		h.WriteJSONError(w, "ValidationException", "The certificate's validity extends past the certificate authority's", http.StatusBadRequest)
+		return
+	}
+
+	serialBytes := make([]byte, 16)
+	rand.Read(serialBytes)
+	serialBytes[0] &= 0x7f
+	cert := &x509.Certificate{
+		SerialNumber:          new(big.Int).SetBytes(serialBytes),
+		Subject:               csr.Subject,
+		NotBefore:             now,
+		NotAfter:              end,
+		KeyUsage:              tmpl.usage,
+		ExtKeyUsage:           tmpl.extUsage,
+		BasicConstraintsValid: true,
+		IsCA:                  tmpl.isCA,
+		MaxPathLen:            tmpl.pathLen,
+		MaxPathLenZero:        tmpl.isCA && tmpl.pathLen == 0,
+		DNSNames:              csr.DNSNames,
+		IPAddresses:           csr.IPAddresses,
+		EmailAddresses:        csr.EmailAddresses,
+		URIs:                  csr.URIs,
+		SignatureAlgorithm:    sig.alg,
+	}
+	if url := ca.crlURL(); url != "" && !selfSigned {
+		cert.CRLDistributionPoints = []string{url}
+	}
+	parent := ca.cert
+	if selfSigned {
+		parent = cert
+	}
+	der, err := x509.CreateCertificate(rand.Reader, cert, parent, csr.PublicKey, ca.key)
+	if err != nil {
+		h.WriteJSONError(w, "InvalidArgsException", err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	issued := &issuedCert{
+		arn:     fmt.Sprintf("%s/certificate/%s", ca.arn, hex.EncodeToString(serialBytes)),
+		serial:  cert.SerialNumber,
+		certPEM: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+	}
+	if !selfSigned {
+		issued.chainPEM = append(append([]byte{}, ca.certPEM...), ca.chainPEM...)
+	}
+	ca.issued[issued.arn] = issued
+
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"CertificateArn": issued.arn,
+	})
+}
+
+func (s *Service) getCertificate(w http.ResponseWriter, params map[string]interface{}) {
+	arn := h.GetString(params, "CertificateArn")
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	ca := s.lookup(w, params)
+	if ca == nil {
+		return
+	}
+	issued, exists := ca.issued[arn]
+	if !exists {
+		h.WriteJSONError(w, "ResourceNotFoundException", "Could not find certificate "+arn, http.StatusBadRequest)
+		return
+	}
+	resp := map[string]interface{}{
+		"Certificate": string(issued.certPEM),
+	}
+	if len(issued.chainPEM) > 0 {
+		resp["CertificateChain"] = string(issued.chainPEM)
+	}
+	h.WriteJSON(w, http.StatusOK, resp)
+}
+
+// revocationReasons maps RevocationReason values to CRL reason codes.
+var revocationReasons = map[string]int{
+	"UNSPECIFIED":                      0,
+	"KEY_COMPROMISE":                   1,
+	"CERTIFICATE_AUTHORITY_COMPROMISE": 2,
+	"AFFILIATION_CHANGED":              3,
+	"SUPERSEDED":                       4,
+	"CESSATION_OF_OPERATION":           5,
+	"PRIVILEGE_WITHDRAWN":              9,
+	"A_A_COMPROMISE":                   10,
+}
+
+func (s *Service) revokeCertificate(w http.ResponseWriter, params map[string]interface{}) {
+	reason, ok := revocationReasons[h.GetString(params, "RevocationReason")]
+	if !ok {
+		h.WriteJSONError(w, "InvalidArgsException", "Unsupported RevocationReason "+h.GetString(params, "RevocationReason"), http.StatusBadRequest)
+		return
+	}
+	serial, ok := parseSerial(h.GetString(params, "CertificateSerial"))
+	if !ok {
+		h.WriteJSONError(w, "InvalidArgsException", "CertificateSerial must be hexadecimal", http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	ca := s.lookup(w, params)
+	if ca == nil {
+		s.mu.Unlock()
+		return
+	}
+	if ca.status != "ACTIVE" {
+		s.mu.Unlock()
+		h.WriteJSONError(w, "InvalidStateException",
+			fmt.Sprintf("The certificate authority is %s, not ACTIVE", ca.status), http.StatusBadRequest)
+		return
+	}
+	var cert *issuedCert
+	for _, c := range ca.issued {
+		if c.serial.Cmp(serial) == 0 {
+			cert = c
+			break
+		}
+	}
+	switch {
+	case cert == nil:
+		s.mu.Unlock()
+		h.WriteJSONError(w, "ResourceNotFoundException", "Could not find certificate with serial "+formatSerial(serial), http.StatusBadRequest)
+		return
+	case cert.revoked:
+		s.mu.Unlock()
+		h.WriteJSONError(w, "RequestAlreadyProcessedException", "The certificate is already revoked", http.StatusBadRequest)
+		return
+	}
+	cert.revoked = true
+	ca.revoked = append(ca.revoked, x509.RevocationListEntry{
+		SerialNumber:   cert.serial,
+		RevocationTime: time.Now().UTC(),
+		ReasonCode:     reason,
+	})
+	publish := s.crl(ca)
+	s.mu.Unlock()
+
+	publish()
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{})
+}
+
+// crlConfiguration returns the CA's CrlConfiguration if it publishes a CRL.
+func (ca *authority) crlConfiguration() map[string]interface{} {
+	crl, _ := ca.revocationConfig["CrlConfiguration"].(map[string]interface{})
+	if enabled, _ := crl["Enabled"].(bool); !enabled || h.GetString(crl, "S3BucketName") == "" {
+		return nil
+	}
+	return crl
+}
+
+// crlKey returns the S3 key of the CA's CRL.
+func (ca *authority) crlKey() string {
+	return "crl/" + ca.arn[strings.LastIndex(ca.arn, "/")+1:] + ".crl"
+}
+
+// crlURL returns the CRL distribution point of certificates the CA issues,
+// or "" if it publishes no CRL.
+func (ca *authority) crlURL() string {
+	crl := ca.crlConfiguration()
+	if crl == nil {
+		return ""
+	}
+	host := h.GetString(crl, "CustomCname")
+	if host == "" {
+		host = h.GetString(crl, "S3BucketName") + ".s3.amazonaws.com"
+	}
+	return "http://" + host + "/" + ca.crlKey()
+}
+
+// crl signs a new CRL for ca if it publishes one, and returns a function
+// that writes it to the object store. The caller must hold s.mu, and must
+// call the function after releasing it.
+func (s *Service) crl(ca *authority) func() {
+	crl := ca.crlConfiguration()
+	if crl == nil || ca.cert == nil || s.store == nil {
+		return func() {}
+	}
+	days := h.GetInt(crl, "ExpirationInDays", 7)
+	now := time.Now().UTC()
+	ca.crlNumber++
+	der, err := x509.CreateRevocationList(rand.Reader, &x509.RevocationList{
+		RevokedCertificateEntries: ca.revoked,
+		Number:                    big.NewInt(ca.crlNumber),
+		ThisUpdate:                now,
+		NextUpdate:                now.AddDate(0, 0, days),
+	}, ca.cert, ca.key)
+	if err != nil {
+		return func() {}
+	}
+	store, bucket, key := s.store, h.GetString(crl, "S3BucketName"), ca.crlKey()
+	return func() { store.PutObject(bucket, key, der) }
+}
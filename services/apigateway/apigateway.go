@@ -9,6 +9,9 @@
 //   - GetResources
 //   - PutMethod
 //   - PutIntegration
+//   - TagResource
+//   - UntagResource
+//   - GetTags
 package apigateway
 
 import (
@@ -21,12 +24,14 @@ import (
 	"time"
 
 	h "github.com/riyanimam/goto/internal/mockhelpers"
+	"github.com/riyanimam/goto/internal/tags"
 )
 
 // Service implements the API Gateway v1 (REST APIs) mock.
 type Service struct {
 	mu   sync.RWMutex
 	apis map[string]*restApi
+	tags *tags.Store
 }
 
 type restApi struct {
@@ -61,6 +66,7 @@ type integration struct {
 func New() *Service {
 	return &Service{
 		apis: make(map[string]*restApi),
+		tags: tags.New(),
 	}
 }
 
@@ -76,14 +82,30 @@ func (s *Service) Handler() http.Handler {
 func (s *Service) Reset() {
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	// API Gateway V2 shares the apigateway ARN namespace, so drop only the
+	// tags of this service's REST APIs.
+	for id := range s.apis {
+		s.tags.Delete(restApiArn(id))
+	}
 	s.apis = make(map[string]*restApi)
 }
 
+// SetTagStore sets the registry resource tags are recorded in.
+func (s *Service) SetTagStore(store *tags.Store) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tags = store
+}
+
 func (s *Service) handle(w http.ResponseWriter, r *http.Request) {
 	path := r.URL.Path
 	method := r.Method
 
 	switch {
+	// TagResource, UntagResource, GetTags: /tags/{arn}
+	case strings.HasPrefix(path, "/tags/"):
+		s.handleTags(w, r, strings.TrimPrefix(path, "/tags/"))
+
 	// PutIntegration: PUT /restapis/{id}/resources/{rid}/methods/{httpMethod}/integration
 	case strings.HasSuffix(path, "/integration") && method == http.MethodPut:
 		s.putIntegration(w, r, path)
@@ -159,24 +181,25 @@ func (s *Service) createRestApi(w http.ResponseWriter, r *http.Request) {
 		},
 	}
 	s.apis[apiID] = api
+	s.tags.Tag(restApiArn(apiID), tags.FromMap(params["tags"]))
+	resp := s.restApiResp(api)
 	s.mu.Unlock()
 
-	h.WriteJSON(w, http.StatusCreated, restApiResp(api))
+	h.WriteJSON(w, http.StatusCreated, resp)
 }
 
 func (s *Service) getRestApi(w http.ResponseWriter, path string) {
 	apiID := extractRestApiID(path)
 
 	s.mu.RLock()
+	defer s.mu.RUnlock()
 	api, exists := s.apis[apiID]
-	s.mu.RUnlock()
-
 	if !exists {
 		h.WriteJSONError(w, "NotFoundException", "REST API "+apiID+" not found", http.StatusNotFound)
 		return
 	}
 
-	h.WriteJSON(w, http.StatusOK, restApiResp(api))
+	h.WriteJSON(w, http.StatusOK, s.restApiResp(api))
 }
 
 func (s *Service) deleteRestApi(w http.ResponseWriter, path string) {
@@ -189,6 +212,7 @@ func (s *Service) deleteRestApi(w http.ResponseWriter, path string) {
 		return
 	}
 	delete(s.apis, apiID)
+	s.tags.Delete(restApiArn(apiID))
 	s.mu.Unlock()
 
 	w.WriteHeader(http.StatusAccepted)
@@ -198,7 +222,7 @@ func (s *Service) getRestApis(w http.ResponseWriter) {
 	s.mu.RLock()
 	var items []map[string]interface{}
 	for _, api := range s.apis {
-		items = append(items, restApiResp(api))
+		items = append(items, s.restApiResp(api))
 	}
 	s.mu.RUnlock()
 
@@ -389,12 +413,20 @@ func (s *Service) putIntegration(w http.ResponseWriter, r *http.Request, path st
 	h.WriteJSON(w, http.StatusCreated, integrationResp(intg))
 }
 
-func restApiResp(api *restApi) map[string]interface{} {
+// restApiArn returns the ARN of the REST API with the given ID, which is
+// what the tagging operations name it by.
+func restApiArn(id string) string {
+	return "arn:aws:apigateway:us-east-1::/restapis/" + id
+}
+
+// restApiResp describes api. The caller must hold s.mu.
+func (s *Service) restApiResp(api *restApi) map[string]interface{} {
 	return map[string]interface{}{
 		"id":          api.id,
 		"name":        api.name,
 		"description": api.description,
 		"createdDate": api.createdDate.Unix(),
+		"tags":        s.tags.Get(restApiArn(api.id)),
 	}
 }
 
@@ -433,3 +465,31 @@ func integrationResp(intg *integration) map[string]interface{} {
 		"httpMethod": intg.httpMethod,
 	}
 }
+
+// handleTags serves TagResource (PUT), UntagResource (DELETE), and GetTags
+// (GET) on /tags/{arn}. REST APIs are the taggable resources.
+func (s *Service) handleTags(w http.ResponseWriter, r *http.Request, arn string) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	id, ok := strings.CutPrefix(arn, restApiArn(""))
+	if _, exists := s.apis[id]; !ok || !exists {
+		h.WriteJSONError(w, "NotFoundException", "Invalid resource: "+arn, http.StatusNotFound)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPut, http.MethodPost:
+		var params map[string]interface{}
+		bodyBytes, _ := io.ReadAll(r.Body)
+		json.Unmarshal(bodyBytes, &params)
+		s.tags.Tag(arn, tags.FromMap(params["tags"]))
+		w.WriteHeader(http.StatusNoContent)
+	case http.MethodDelete:
+		s.tags.Untag(arn, r.URL.Query()["tagKeys"])
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		h.WriteJSON(w, http.StatusOK, map[string]interface{}{
+			"tags": s.tags.Get(arn),
+		})
+	}
+}
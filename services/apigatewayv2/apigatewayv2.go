@@ -11,6 +11,31 @@
 //   - CreateRoute
 //   - GetRoutes
 //   - DeleteRoute
+//   - CreateIntegration
+//   - GetIntegrations
+//   - CreateVpcLink
+//   - GetVpcLink
+//   - GetVpcLinks
+//   - DeleteVpcLink
+//   - CreateDomainName
+//   - GetDomainName
+//   - GetDomainNames
+//   - DeleteDomainName
+//   - CreateApiMapping
+//   - GetApiMapping
+//   - GetApiMappings
+//   - DeleteApiMapping
+//   - TagResource
+//   - UntagResource
+//   - GetTags
+//
+// API endpoints are served by the mock: requests to an API's apiEndpoint are
+// matched against its routes and passed to AWS_PROXY Lambda integrations
+// using payload format 2.0.
+//
+// Custom domain names report the regional apiGatewayDomainName and
+// hostedZoneId that Route 53 alias records point at, and their certificate
+// ARNs must name existing certificates.
 package apigatewayv2
 
 import (
@@ -22,13 +47,23 @@ import (
 	"sync"
 	"time"
 
+	"github.com/riyanimam/goto/internal/lifecycle"
 	h "github.com/riyanimam/goto/internal/mockhelpers"
+	"github.com/riyanimam/goto/internal/tags"
 )
 
 // Service implements the API Gateway V2 mock.
 type Service struct {
-	mu   sync.RWMutex
-	apis map[string]*apiGw
+	mu       sync.RWMutex
+	apis     map[string]*apiGw
+	vpcLinks map[string]*vpcLink
+	domains  map[string]*domainName
+	baseURL  string
+	dispatch h.Dispatcher
+	resolve  h.Resolver
+	tags     *tags.Store
+
+	transitions *lifecycle.Transitions
 }
 
 type apiGw struct {
@@ -40,6 +75,7 @@ type apiGw struct {
 	created      time.Time
 	stages       map[string]*stage
 	routes       map[string]*route
+	integrations map[string]*integration
 }
 
 type stage struct {
@@ -58,7 +94,10 @@ type route struct {
 // New creates a new API Gateway V2 mock service.
 func New() *Service {
 	return &Service{
-		apis: make(map[string]*apiGw),
+		apis:     make(map[string]*apiGw),
+		vpcLinks: make(map[string]*vpcLink),
+		domains:  make(map[string]*domainName),
+		tags:     tags.New(),
 	}
 }
 
@@ -76,7 +115,27 @@ func (s *Service) Handler() http.Handler {
 func (s *Service) Reset() {
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	// API Gateway V1 shares the apigateway ARN namespace, so drop only the
+	// tags of this service's resources.
+	for id := range s.apis {
+		s.tags.Delete(resourceArn("/apis/" + id))
+	}
+	for id := range s.vpcLinks {
+		s.tags.Delete(resourceArn("/vpclinks/" + id))
+	}
+	for name := range s.domains {
+		s.tags.Delete(resourceArn("/domainnames/" + name))
+	}
 	s.apis = make(map[string]*apiGw)
+	s.vpcLinks = make(map[string]*vpcLink)
+	s.domains = make(map[string]*domainName)
+}
+
+// SetTagStore sets the registry resource tags are recorded in.
+func (s *Service) SetTagStore(store *tags.Store) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tags = store
 }
 
 func (s *Service) handle(w http.ResponseWriter, r *http.Request) {
@@ -84,6 +143,50 @@ func (s *Service) handle(w http.ResponseWriter, r *http.Request) {
 	method := r.Method
 
 	switch {
+	// API endpoints: /_apigateway/{apiId}/... or {apiId}.execute-api.localhost
+	case strings.HasPrefix(path, executePrefix) || executeHostAPI(r.Host) != "":
+		s.serveExecute(w, r)
+
+	// Tags: /v2/tags/{arn}
+	case strings.HasPrefix(path, "/v2/tags/"):
+		s.handleTags(w, r, strings.TrimPrefix(path, "/v2/tags/"))
+
+	// API mappings: /v2/domainnames/{domainName}/apimappings[/{apiMappingId}]
+	case strings.HasPrefix(path, "/v2/domainnames/") && strings.Contains(path, "/apimappings/") && method == http.MethodDelete:
+		s.deleteApiMapping(w, r, path)
+	case strings.HasPrefix(path, "/v2/domainnames/") && strings.Contains(path, "/apimappings/") && method == http.MethodGet:
+		s.getApiMapping(w, r, path)
+	case strings.HasPrefix(path, "/v2/domainnames/") && strings.HasSuffix(path, "/apimappings") && method == http.MethodPost:
+		s.createApiMapping(w, r, path)
+	case strings.HasPrefix(path, "/v2/domainnames/") && strings.HasSuffix(path, "/apimappings") && method == http.MethodGet:
+		s.getApiMappings(w, r, path)
+
+	// Domain names: /v2/domainnames[/{domainName}]
+	case strings.HasPrefix(path, "/v2/domainnames/") && method == http.MethodGet:
+		s.getDomainName(w, r, path)
+	case strings.HasPrefix(path, "/v2/domainnames/") && method == http.MethodDelete:
+		s.deleteDomainName(w, r, path)
+	case path == "/v2/domainnames" && method == http.MethodPost:
+		s.createDomainName(w, r)
+	case path == "/v2/domainnames" && method == http.MethodGet:
+		s.getDomainNames(w, r)
+
+	// VPC links: /v2/vpclinks[/{vpcLinkId}]
+	case strings.HasPrefix(path, "/v2/vpclinks/") && method == http.MethodGet:
+		s.getVpcLink(w, r, path)
+	case strings.HasPrefix(path, "/v2/vpclinks/") && method == http.MethodDelete:
+		s.deleteVpcLink(w, r, path)
+	case path == "/v2/vpclinks" && method == http.MethodPost:
+		s.createVpcLink(w, r)
+	case path == "/v2/vpclinks" && method == http.MethodGet:
+		s.getVpcLinks(w, r)
+
+	// Integrations: /v2/apis/{apiId}/integrations
+	case strings.HasSuffix(path, "/integrations") && method == http.MethodPost:
+		s.createIntegration(w, r, path)
+	case strings.HasSuffix(path, "/integrations") && method == http.MethodGet:
+		s.getIntegrations(w, r, path)
+
 	// Routes: /v2/apis/{apiId}/routes/{routeId}
 	case strings.Contains(path, "/routes/") && method == http.MethodDelete:
 		s.deleteRoute(w, r, path)
@@ -148,6 +251,9 @@ func (s *Service) createApi(w http.ResponseWriter, r *http.Request) {
 	s.mu.Lock()
 	apiID := h.RandomHex(10)
 	endpoint := "https://" + apiID + ".execute-api.us-east-1.amazonaws.com"
+	if s.baseURL != "" {
+		endpoint = s.baseURL + executePrefix + apiID
+	}
 	api := &apiGw{
 		apiID:        apiID,
 		name:         name,
@@ -157,26 +263,28 @@ func (s *Service) createApi(w http.ResponseWriter, r *http.Request) {
 		created:      time.Now().UTC(),
 		stages:       make(map[string]*stage),
 		routes:       make(map[string]*route),
+		integrations: make(map[string]*integration),
 	}
 	s.apis[apiID] = api
+	s.tags.Tag(resourceArn("/apis/"+apiID), tags.FromMap(params["tags"]))
+	resp := s.apiResp(api)
 	s.mu.Unlock()
 
-	h.WriteJSON(w, http.StatusCreated, apiResp(api))
+	h.WriteJSON(w, http.StatusCreated, resp)
 }
 
 func (s *Service) getApi(w http.ResponseWriter, _ *http.Request, path string) {
 	apiID := extractAPIID(path)
 
 	s.mu.RLock()
+	defer s.mu.RUnlock()
 	api, exists := s.apis[apiID]
-	s.mu.RUnlock()
-
 	if !exists {
 		h.WriteJSONError(w, "NotFoundException", "API "+apiID+" not found", http.StatusNotFound)
 		return
 	}
 
-	h.WriteJSON(w, http.StatusOK, apiResp(api))
+	h.WriteJSON(w, http.StatusOK, s.apiResp(api))
 }
 
 func (s *Service) deleteApi(w http.ResponseWriter, _ *http.Request, path string) {
@@ -189,6 +297,8 @@ func (s *Service) deleteApi(w http.ResponseWriter, _ *http.Request, path string)
 		return
 	}
 	delete(s.apis, apiID)
+	s.tags.Delete(resourceArn("/apis/" + apiID))
+	s.deleteApiMappings(apiID)
 	s.mu.Unlock()
 
 	w.WriteHeader(http.StatusNoContent)
@@ -198,7 +308,7 @@ func (s *Service) getApis(w http.ResponseWriter, _ *http.Request) {
 	s.mu.RLock()
 	var items []map[string]interface{}
 	for _, api := range s.apis {
-		items = append(items, apiResp(api))
+		items = append(items, s.apiResp(api))
 	}
 	s.mu.RUnlock()
 
@@ -367,7 +477,8 @@ func (s *Service) deleteRoute(w http.ResponseWriter, _ *http.Request, path strin
 	w.WriteHeader(http.StatusNoContent)
 }
 
-func apiResp(api *apiGw) map[string]interface{} {
+// apiResp describes api. The caller must hold s.mu.
+func (s *Service) apiResp(api *apiGw) map[string]interface{} {
 	return map[string]interface{}{
 		"apiId":        api.apiID,
 		"name":         api.name,
@@ -375,6 +486,7 @@ func apiResp(api *apiGw) map[string]interface{} {
 		"description":  api.description,
 		"apiEndpoint":  api.endpoint,
 		"createdDate":  api.created.Format(time.RFC3339),
+		"tags":         s.tags.Get(resourceArn("/apis/" + api.apiID)),
 	}
 }
 
@@ -394,3 +506,57 @@ func routeResp(rt *route) map[string]interface{} {
 		"target":   rt.target,
 	}
 }
+
+// resourceArn returns the ARN the tagging operations name a resource by,
+// from its path below the service root, such as "/apis/{apiId}".
+func resourceArn(path string) string {
+	return "arn:aws:apigateway:us-east-1::" + path
+}
+
+// hasResource reports whether arn names an existing API, VPC link, or
+// domain name. The caller must hold s.mu.
+func (s *Service) hasResource(arn string) bool {
+	path, ok := strings.CutPrefix(arn, resourceArn(""))
+	if !ok {
+		return false
+	}
+	kind, id, _ := strings.Cut(strings.TrimPrefix(path, "/"), "/")
+	switch kind {
+	case "apis":
+		_, ok = s.apis[id]
+	case "vpclinks":
+		_, ok = s.vpcLinks[id]
+	case "domainnames":
+		_, ok = s.domains[id]
+	default:
+		ok = false
+	}
+	return ok
+}
+
+// handleTags serves TagResource (POST), UntagResource (DELETE), and GetTags
+// (GET) on /v2/tags/{arn}.
+func (s *Service) handleTags(w http.ResponseWriter, r *http.Request, arn string) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if !s.hasResource(arn) {
+		h.WriteJSONError(w, "NotFoundException", "Invalid resource: "+arn, http.StatusNotFound)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPost:
+		var params map[string]interface{}
+		bodyBytes, _ := io.ReadAll(r.Body)
+		json.Unmarshal(bodyBytes, &params)
+		s.tags.Tag(arn, tags.FromMap(params["tags"]))
+		h.WriteJSON(w, http.StatusCreated, map[string]interface{}{})
+	case http.MethodDelete:
+		s.tags.Untag(arn, r.URL.Query()["tagKeys"])
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		h.WriteJSON(w, http.StatusOK, map[string]interface{}{
+			"tags": s.tags.Get(arn),
+		})
+	}
+}
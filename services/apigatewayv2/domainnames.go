@@ -0,0 +1,307 @@
+package apigatewayv2
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+
+	h "github.com/riyanimam/goto/internal/mockhelpers"
+	"github.com/riyanimam/goto/internal/tags"
+)
+
+// regionalHostedZoneID is the Route 53 hosted zone of us-east-1 regional
+// API Gateway endpoints, which alias records for custom domains target.
+const regionalHostedZoneID = "Z1UJRXOUMOOFQ8"
+
+type domainName struct {
+	domainName     string
+	certificateArn string
+	endpointType   string
+	securityPolicy string
+	gatewayDomain  string
+	apiMappings    map[string]*apiMapping
+}
+
+type apiMapping struct {
+	apiMappingID  string
+	apiID         string
+	apiMappingKey string
+	stage         string
+}
+
+// SetResolver sets the function used to check that domain name certificates
+// exist.
+func (s *Service) SetResolver(r h.Resolver) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.resolve = r
+}
+
+// domainPathParts splits /v2/domainnames/{domainName}[/apimappings[/{apiMappingId}]]
+// into the domain name and the rest of the path.
+func domainPathParts(path string) (string, []string) {
+	parts := strings.Split(strings.TrimPrefix(path, "/v2/domainnames/"), "/")
+	return parts[0], parts[1:]
+}
+
+func (s *Service) createDomainName(w http.ResponseWriter, r *http.Request) {
+	bodyBytes, _ := io.ReadAll(r.Body)
+	var params map[string]interface{}
+	json.Unmarshal(bodyBytes, &params)
+
+	name := h.GetString(params, "domainName")
+	if name == "" {
+		h.WriteJSONError(w, "BadRequestException", "DomainName is required", http.StatusBadRequest)
+		return
+	}
+	configs, _ := params["domainNameConfigurations"].([]interface{})
+	var config map[string]interface{}
+	if len(configs) > 0 {
+		config, _ = configs[0].(map[string]interface{})
+	}
+	certificateArn := h.GetString(config, "certificateArn")
+	if certificateArn == "" {
+		h.WriteJSONError(w, "BadRequestException", "A certificate is required for domain name "+name, http.StatusBadRequest)
+		return
+	}
+	endpointType := h.GetString(config, "endpointType")
+	if endpointType == "" {
+		endpointType = "REGIONAL"
+	}
+	if endpointType != "REGIONAL" {
+		h.WriteJSONError(w, "BadRequestException", "Only REGIONAL endpoints are supported for domain names", http.StatusBadRequest)
+		return
+	}
+	securityPolicy := h.GetString(config, "securityPolicy")
+	if securityPolicy == "" {
+		securityPolicy = "TLS_1_2"
+	}
+
+	s.mu.RLock()
+	resolve := s.resolve
+	s.mu.RUnlock()
+	if resolve != nil && resolve(certificateArn) != nil {
+		h.WriteJSONError(w, "BadRequestException", "Certificate "+certificateArn+" not found", http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, exists := s.domains[name]; exists {
+		h.WriteJSONError(w, "ConflictException", "The domain name you provided already exists.", http.StatusConflict)
+		return
+	}
+	d := &domainName{
+		domainName:     name,
+		certificateArn: certificateArn,
+		endpointType:   endpointType,
+		securityPolicy: securityPolicy,
+		gatewayDomain:  "d-" + strings.ToLower(h.RandomID(10)) + ".execute-api.us-east-1.amazonaws.com",
+		apiMappings:    make(map[string]*apiMapping),
+	}
+	s.domains[name] = d
+	s.tags.Tag(resourceArn("/domainnames/"+name), tags.FromMap(params["tags"]))
+
+	h.WriteJSON(w, http.StatusCreated, s.domainNameResp(d))
+}
+
+func (s *Service) getDomainName(w http.ResponseWriter, _ *http.Request, path string) {
+	name, _ := domainPathParts(path)
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	d, exists := s.domains[name]
+	if !exists {
+		h.WriteJSONError(w, "NotFoundException", "Domain name "+name+" not found", http.StatusNotFound)
+		return
+	}
+
+	h.WriteJSON(w, http.StatusOK, s.domainNameResp(d))
+}
+
+func (s *Service) getDomainNames(w http.ResponseWriter, _ *http.Request) {
+	s.mu.RLock()
+	items := make([]map[string]interface{}, 0, len(s.domains))
+	for _, d := range s.domains {
+		items = append(items, s.domainNameResp(d))
+	}
+	s.mu.RUnlock()
+
+	sort.Slice(items, func(i, j int) bool {
+		return items[i]["domainName"].(string) < items[j]["domainName"].(string)
+	})
+
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"items": items,
+	})
+}
+
+// deleteDomainName removes a domain name together with its API mappings.
+func (s *Service) deleteDomainName(w http.ResponseWriter, _ *http.Request, path string) {
+	name, _ := domainPathParts(path)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, exists := s.domains[name]; !exists {
+		h.WriteJSONError(w, "NotFoundException", "Domain name "+name+" not found", http.StatusNotFound)
+		return
+	}
+	delete(s.domains, name)
+	s.tags.Delete(resourceArn("/domainnames/" + name))
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// createApiMapping maps a path of a domain name to a deployed stage. Both
+// the API and the stage must exist, and each key can be mapped only once.
+func (s *Service) createApiMapping(w http.ResponseWriter, r *http.Request, path string) {
+	name, _ := domainPathParts(path)
+	bodyBytes, _ := io.ReadAll(r.Body)
+	var params map[string]interface{}
+	json.Unmarshal(bodyBytes, &params)
+
+	apiID := h.GetString(params, "apiId")
+	stageName := h.GetString(params, "stage")
+	if apiID == "" || stageName == "" {
+		h.WriteJSONError(w, "BadRequestException", "ApiId and Stage are required", http.StatusBadRequest)
+		return
+	}
+	key := h.GetString(params, "apiMappingKey")
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	d, exists := s.domains[name]
+	if !exists {
+		h.WriteJSONError(w, "NotFoundException", "Domain name "+name+" not found", http.StatusNotFound)
+		return
+	}
+	api, exists := s.apis[apiID]
+	if !exists {
+		h.WriteJSONError(w, "NotFoundException", "API "+apiID+" not found", http.StatusNotFound)
+		return
+	}
+	if _, exists := api.stages[stageName]; !exists {
+		h.WriteJSONError(w, "NotFoundException", "Stage "+stageName+" not found for API "+apiID, http.StatusNotFound)
+		return
+	}
+	for _, m := range d.apiMappings {
+		if m.apiMappingKey == key {
+			h.WriteJSONError(w, "ConflictException", "API mapping key '"+key+"' already exists for domain name "+name, http.StatusConflict)
+			return
+		}
+	}
+	m := &apiMapping{
+		apiMappingID:  h.RandomHex(6),
+		apiID:         apiID,
+		apiMappingKey: key,
+		stage:         stageName,
+	}
+	d.apiMappings[m.apiMappingID] = m
+
+	h.WriteJSON(w, http.StatusCreated, apiMappingResp(m))
+}
+
+func (s *Service) getApiMapping(w http.ResponseWriter, _ *http.Request, path string) {
+	name, rest := domainPathParts(path)
+	mappingID := rest[len(rest)-1]
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	d, exists := s.domains[name]
+	if !exists {
+		h.WriteJSONError(w, "NotFoundException", "Domain name "+name+" not found", http.StatusNotFound)
+		return
+	}
+	m, exists := d.apiMappings[mappingID]
+	if !exists {
+		h.WriteJSONError(w, "NotFoundException", "API mapping "+mappingID+" not found", http.StatusNotFound)
+		return
+	}
+
+	h.WriteJSON(w, http.StatusOK, apiMappingResp(m))
+}
+
+func (s *Service) getApiMappings(w http.ResponseWriter, _ *http.Request, path string) {
+	name, _ := domainPathParts(path)
+
+	s.mu.RLock()
+	d, exists := s.domains[name]
+	if !exists {
+		s.mu.RUnlock()
+		h.WriteJSONError(w, "NotFoundException", "Domain name "+name+" not found", http.StatusNotFound)
+		return
+	}
+	items := make([]map[string]interface{}, 0, len(d.apiMappings))
+	for _, m := range d.apiMappings {
+		items = append(items, apiMappingResp(m))
+	}
+	s.mu.RUnlock()
+
+	sort.Slice(items, func(i, j int) bool {
+		return items[i]["apiMappingKey"].(string) < items[j]["apiMappingKey"].(string)
+	})
+
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"items": items,
+	})
+}
+
+func (s *Service) deleteApiMapping(w http.ResponseWriter, _ *http.Request, path string) {
+	name, rest := domainPathParts(path)
+	mappingID := rest[len(rest)-1]
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	d, exists := s.domains[name]
+	if !exists {
+		h.WriteJSONError(w, "NotFoundException", "Domain name "+name+" not found", http.StatusNotFound)
+		return
+	}
+	if _, exists := d.apiMappings[mappingID]; !exists {
+		h.WriteJSONError(w, "NotFoundException", "API mapping "+mappingID+" not found", http.StatusNotFound)
+		return
+	}
+	delete(d.apiMappings, mappingID)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// deleteApiMappings removes every mapping to apiID, as deleting an API does.
+// The caller must hold s.mu.
+func (s *Service) deleteApiMappings(apiID string) {
+	for _, d := range s.domains {
+		for id, m := range d.apiMappings {
+			if m.apiID == apiID {
+				delete(d.apiMappings, id)
+			}
+		}
+	}
+}
+
+// domainNameResp describes d. The caller must hold s.mu.
+func (s *Service) domainNameResp(d *domainName) map[string]interface{} {
+	return map[string]interface{}{
+		"domainName":                    d.domainName,
+		"apiMappingSelectionExpression": "$request.basepath",
+		"domainNameConfigurations": []map[string]interface{}{{
+			"apiGatewayDomainName": d.gatewayDomain,
+			"certificateArn":       d.certificateArn,
+			"domainNameStatus":     "AVAILABLE",
+			"endpointType":         d.endpointType,
+			"hostedZoneId":         regionalHostedZoneID,
+			"securityPolicy":       d.securityPolicy,
+		}},
+		"tags": s.tags.Get(resourceArn("/domainnames/" + d.domainName)),
+	}
+}
+
+func apiMappingResp(m *apiMapping) map[string]interface{} {
+	return map[string]interface{}{
+		"apiMappingId":  m.apiMappingID,
+		"apiId":         m.apiID,
+		"apiMappingKey": m.apiMappingKey,
+		"stage":         m.stage,
+	}
+}
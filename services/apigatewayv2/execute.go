@@ -0,0 +1,369 @@
+package apigatewayv2
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	h "github.com/riyanimam/goto/internal/mockhelpers"
+)
+
+// executePrefix is the path under which API endpoints are served.
+const executePrefix = "/_apigateway/"
+
+type integration struct {
+	integrationID        string
+	integrationType      string
+	integrationURI       string
+	integrationMethod    string
+	payloadFormatVersion string
+	connectionType       string
+	connectionID         string
+}
+
+// SetBaseURL records the mock server URL so API endpoints resolve to it.
+func (s *Service) SetBaseURL(u string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.baseURL = u
+}
+
+// SetDispatcher sets the function used to invoke Lambda integrations.
+func (s *Service) SetDispatcher(d h.Dispatcher) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.dispatch = d
+}
+
+func (s *Service) createIntegration(w http.ResponseWriter, r *http.Request, path string) {
+	apiID := extractAPIID(path)
+	bodyBytes, _ := io.ReadAll(r.Body)
+	var params map[string]interface{}
+	json.Unmarshal(bodyBytes, &params)
+
+	integrationType := h.GetString(params, "integrationType")
+	if integrationType == "" {
+		h.WriteJSONError(w, "BadRequestException", "IntegrationType is required", http.StatusBadRequest)
+		return
+	}
+
+	connectionType := h.GetString(params, "connectionType")
+	if connectionType == "" {
+		connectionType = "INTERNET"
+	}
+	connectionID := h.GetString(params, "connectionId")
+
+	s.mu.Lock()
+	api, exists := s.apis[apiID]
+	if !exists {
+		s.mu.Unlock()
+		h.WriteJSONError(w, "NotFoundException", "API "+apiID+" not found", http.StatusNotFound)
+		return
+	}
+	if connectionType == "VPC_LINK" && s.vpcLinks[connectionID] == nil {
+		s.mu.Unlock()
+		h.WriteJSONError(w, "BadRequestException", "Invalid VPC link identifier specified: "+connectionID, http.StatusBadRequest)
+		return
+	}
+
+	in := &integration{
+		integrationID:        h.RandomHex(7),
+		integrationType:      integrationType,
+		integrationURI:       h.GetString(params, "integrationUri"),
+		integrationMethod:    h.GetString(params, "integrationMethod"),
+		payloadFormatVersion: h.GetString(params, "payloadFormatVersion"),
+		connectionType:       connectionType,
+		connectionID:         connectionID,
+	}
+	if in.payloadFormatVersion == "" {
+		in.payloadFormatVersion = "2.0"
+	}
+	api.integrations[in.integrationID] = in
+	s.mu.Unlock()
+
+	h.WriteJSON(w, http.StatusCreated, integrationResp(in))
+}
+
+func (s *Service) getIntegrations(w http.ResponseWriter, _ *http.Request, path string) {
+	apiID := extractAPIID(path)
+
+	s.mu.RLock()
+	api, exists := s.apis[apiID]
+	if !exists {
+		s.mu.RUnlock()
+		h.WriteJSONError(w, "NotFoundException", "API "+apiID+" not found", http.StatusNotFound)
+		return
+	}
+
+	items := make([]map[string]interface{}, 0, len(api.integrations))
+	for _, in := range api.integrations {
+		items = append(items, integrationResp(in))
+	}
+	s.mu.RUnlock()
+
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"items": items,
+	})
+}
+
+func integrationResp(in *integration) map[string]interface{} {
+	resp := map[string]interface{}{
+		"integrationId":        in.integrationID,
+		"integrationType":      in.integrationType,
+		"payloadFormatVersion": in.payloadFormatVersion,
+		"connectionType":       in.connectionType,
+	}
+	if in.connectionID != "" {
+		resp["connectionId"] = in.connectionID
+	}
+	if in.integrationURI != "" {
+		resp["integrationUri"] = in.integrationURI
+	}
+	if in.integrationMethod != "" {
+		resp["integrationMethod"] = in.integrationMethod
+	}
+	return resp
+}
+
+// executeHostAPI returns the API ID named by an execute-api Host header such
+// as "a1b2c3.execute-api.localhost:8080", or "" for any other host.
+func executeHostAPI(host string) string {
+	apiID, rest, ok := strings.Cut(host, ".")
+	if !ok || !strings.HasPrefix(rest, "execute-api.") {
+		return ""
+	}
+	return apiID
+}
+
+// serveExecute handles a request made to an API endpoint, addressed either
+// by path, /_apigateway/{apiId}/{stage}/{path}, or by host,
+// {apiId}.execute-api.localhost/{stage}/{path}. The stage segment is omitted
+// for the $default stage.
+func (s *Service) serveExecute(w http.ResponseWriter, r *http.Request) {
+	apiID, rest := executeHostAPI(r.Host), strings.TrimPrefix(r.URL.Path, "/")
+	if apiID == "" {
+		apiID, rest, _ = strings.Cut(strings.TrimPrefix(r.URL.Path, executePrefix), "/")
+	}
+	reqPath := "/" + rest
+
+	s.mu.RLock()
+	api, exists := s.apis[apiID]
+	if !exists {
+		s.mu.RUnlock()
+		writeExecuteError(w, http.StatusNotFound, "Not Found")
+		return
+	}
+
+	stageName := "$default"
+	if _, ok := api.stages["$default"]; !ok {
+		first, remainder, _ := strings.Cut(rest, "/")
+		if _, ok := api.stages[first]; !ok {
+			s.mu.RUnlock()
+			writeExecuteError(w, http.StatusNotFound, "Not Found")
+			return
+		}
+		stageName, reqPath = first, "/"+remainder
+	}
+
+	rt, pathParams := api.matchRoute(r.Method, reqPath)
+	var in *integration
+	if rt != nil {
+		in = api.integrations[strings.TrimPrefix(rt.target, "integrations/")]
+	}
+	dispatch := s.dispatch
+	s.mu.RUnlock()
+
+	if rt == nil {
+		writeExecuteError(w, http.StatusNotFound, "Not Found")
+		return
+	}
+	if in == nil || in.integrationType != "AWS_PROXY" || dispatch == nil {
+		writeExecuteError(w, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+
+	event := proxyEvent(r, apiID, stageName, rt.routeKey, reqPath, pathParams)
+	payload, _ := json.Marshal(event)
+	out, err := dispatch(lambdaARN(in.integrationURI), payload)
+	if err != nil {
+		writeExecuteError(w, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+	writeProxyResponse(w, out)
+}
+
+// matchRoute finds the route for a request, preferring exact paths over
+// parameterized ones and falling back to $default. Caller must hold s.mu.
+func (api *apiGw) matchRoute(method, reqPath string) (*route, map[string]string) {
+	var best *route
+	var bestParams map[string]string
+	bestScore := -1
+	for _, rt := range api.routes {
+		if rt.routeKey == "$default" {
+			if bestScore < 0 {
+				best, bestScore = rt, 0
+			}
+			continue
+		}
+		routeMethod, routePath, ok := strings.Cut(rt.routeKey, " ")
+		if !ok || (routeMethod != "ANY" && routeMethod != method) {
+			continue
+		}
+		params, score, ok := matchPath(routePath, reqPath)
+		if !ok {
+			continue
+		}
+		if routeMethod != "ANY" {
+			score++
+		}
+		if score > bestScore {
+			best, bestParams, bestScore = rt, params, score
+		}
+	}
+	return best, bestParams
+}
+
+// matchPath matches a request path against a route path with {param} and
+// trailing {proxy+} segments. Literal segments score higher than parameters.
+func matchPath(routePath, reqPath string) (map[string]string, int, bool) {
+	routeSegs := strings.Split(strings.Trim(routePath, "/"), "/")
+	reqSegs := strings.Split(strings.Trim(reqPath, "/"), "/")
+	params := make(map[string]string)
+	score := 1
+
+	for i, seg := range routeSegs {
+		if strings.HasPrefix(seg, "{") && strings.HasSuffix(seg, "+}") {
+			if i >= len(reqSegs) || reqSegs[i] == "" {
+				return nil, 0, false
+			}
+			params[strings.TrimSuffix(strings.TrimPrefix(seg, "{"), "+}")] = strings.Join(reqSegs[i:], "/")
+			return params, score, true
+		}
+		if i >= len(reqSegs) {
+			return nil, 0, false
+		}
+		if strings.HasPrefix(seg, "{") && strings.HasSuffix(seg, "}") {
+			params[strings.Trim(seg, "{}")] = reqSegs[i]
+			score++
+			continue
+		}
+		if seg != reqSegs[i] {
+			return nil, 0, false
+		}
+		score += 2
+	}
+	if len(reqSegs) != len(routeSegs) {
+		return nil, 0, false
+	}
+	return params, score, true
+}
+
+// lambdaARN extracts the function ARN from an integration URI, which may be
+// the function ARN itself or an API Gateway invocation URI wrapping it.
+func lambdaARN(uri string) string {
+	if i := strings.Index(uri, "/functions/"); i >= 0 {
+		return strings.TrimSuffix(uri[i+len("/functions/"):], "/invocations")
+	}
+	return uri
+}
+
+// proxyEvent builds the payload format 2.0 event for a request.
+func proxyEvent(r *http.Request, apiID, stage, routeKey, reqPath string, pathParams map[string]string) map[string]interface{} {
+	body, _ := io.ReadAll(r.Body)
+
+	headers := make(map[string]string, len(r.Header))
+	for k, v := range r.Header {
+		headers[strings.ToLower(k)] = strings.Join(v, ",")
+	}
+	query := make(map[string]string)
+	for k, v := range r.URL.Query() {
+		query[k] = strings.Join(v, ",")
+	}
+
+	now := time.Now().UTC()
+	event := map[string]interface{}{
+		"version":        "2.0",
+		"routeKey":       routeKey,
+		"rawPath":        reqPath,
+		"rawQueryString": r.URL.RawQuery,
+		"headers":        headers,
+		"requestContext": map[string]interface{}{
+			"accountId":  h.DefaultAccountID,
+			"apiId":      apiID,
+			"domainName": r.Host,
+			"requestId":  h.NewRequestID(),
+			"routeKey":   routeKey,
+			"stage":      stage,
+			"time":       now.Format("02/Jan/2006:15:04:05 -0700"),
+			"timeEpoch":  now.UnixMilli(),
+			"http": map[string]interface{}{
+				"method":    r.Method,
+				"path":      reqPath,
+				"protocol":  r.Proto,
+				"sourceIp":  strings.Split(r.RemoteAddr, ":")[0],
+				"userAgent": r.UserAgent(),
+			},
+		},
+		"isBase64Encoded": false,
+	}
+	if len(query) > 0 {
+		event["queryStringParameters"] = query
+	}
+	if len(pathParams) > 0 {
+		event["pathParameters"] = pathParams
+	}
+	if len(body) > 0 {
+		if utf8.Valid(body) {
+			event["body"] = string(body)
+		} else {
+			event["body"] = base64.StdEncoding.EncodeToString(body)
+			event["isBase64Encoded"] = true
+		}
+	}
+	return event
+}
+
+// writeProxyResponse converts a Lambda proxy response into an HTTP response.
+// Output that is not a {statusCode, headers, body} object is returned as a
+// JSON body with status 200, as payload format 2.0 does.
+func writeProxyResponse(w http.ResponseWriter, out []byte) {
+	var resp struct {
+		StatusCode      int               `json:"statusCode"`
+		Headers         map[string]string `json:"headers"`
+		Cookies         []string          `json:"cookies"`
+		Body            string            `json:"body"`
+		IsBase64Encoded bool              `json:"isBase64Encoded"`
+	}
+	if err := json.Unmarshal(out, &resp); err != nil || resp.StatusCode == 0 {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write(out)
+		return
+	}
+
+	for k, v := range resp.Headers {
+		w.Header().Set(k, v)
+	}
+	for _, c := range resp.Cookies {
+		w.Header().Add("Set-Cookie", c)
+	}
+	body := []byte(resp.Body)
+	if resp.IsBase64Encoded {
+		if decoded, err := base64.StdEncoding.DecodeString(resp.Body); err == nil {
+			body = decoded
+		}
+	}
+	w.WriteHeader(resp.StatusCode)
+	w.Write(body)
+}
+
+func writeExecuteError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	fmt.Fprintf(w, "{\"message\":%q}", message)
+}
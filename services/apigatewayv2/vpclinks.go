@@ -0,0 +1,155 @@
+package apigatewayv2
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/riyanimam/goto/internal/lifecycle"
+	h "github.com/riyanimam/goto/internal/mockhelpers"
+	"github.com/riyanimam/goto/internal/tags"
+)
+
+type vpcLink struct {
+	vpcLinkID        string
+	name             string
+	subnetIDs        []string
+	securityGroupIDs []string
+	created          time.Time
+	ready            lifecycle.Transition
+}
+
+// SetTransitions sets how long new VPC links stay PENDING.
+func (s *Service) SetTransitions(t *lifecycle.Transitions) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.transitions = t
+}
+
+// extractVpcLinkID returns the {vpcLinkId} of /v2/vpclinks/{vpcLinkId}.
+func extractVpcLinkID(path string) string {
+	return strings.TrimPrefix(path, "/v2/vpclinks/")
+}
+
+func (s *Service) createVpcLink(w http.ResponseWriter, r *http.Request) {
+	bodyBytes, _ := io.ReadAll(r.Body)
+	var params map[string]interface{}
+	json.Unmarshal(bodyBytes, &params)
+
+	name := h.GetString(params, "name")
+	if name == "" {
+		h.WriteJSONError(w, "BadRequestException", "Name is required", http.StatusBadRequest)
+		return
+	}
+	subnetIDs := getStringSlice(params, "subnetIds")
+	if len(subnetIDs) == 0 {
+		h.WriteJSONError(w, "BadRequestException", "SubnetIds is required", http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	link := &vpcLink{
+		vpcLinkID:        h.RandomHex(6),
+		name:             name,
+		subnetIDs:        subnetIDs,
+		securityGroupIDs: getStringSlice(params, "securityGroupIds"),
+		created:          time.Now().UTC(),
+		ready:            s.transitions.Begin(),
+	}
+	s.vpcLinks[link.vpcLinkID] = link
+	s.tags.Tag(resourceArn("/vpclinks/"+link.vpcLinkID), tags.FromMap(params["tags"]))
+	resp := s.vpcLinkResp(link)
+	s.mu.Unlock()
+
+	h.WriteJSON(w, http.StatusCreated, resp)
+}
+
+func (s *Service) getVpcLink(w http.ResponseWriter, _ *http.Request, path string) {
+	vpcLinkID := extractVpcLinkID(path)
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	link, exists := s.vpcLinks[vpcLinkID]
+	if !exists {
+		h.WriteJSONError(w, "NotFoundException", "VPC link "+vpcLinkID+" not found", http.StatusNotFound)
+		return
+	}
+
+	h.WriteJSON(w, http.StatusOK, s.vpcLinkResp(link))
+}
+
+func (s *Service) getVpcLinks(w http.ResponseWriter, _ *http.Request) {
+	s.mu.RLock()
+	items := make([]map[string]interface{}, 0, len(s.vpcLinks))
+	for _, link := range s.vpcLinks {
+		items = append(items, s.vpcLinkResp(link))
+	}
+	s.mu.RUnlock()
+
+	sort.Slice(items, func(i, j int) bool {
+		return items[i]["name"].(string) < items[j]["name"].(string)
+	})
+
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"items": items,
+	})
+}
+
+// deleteVpcLink removes a VPC link that no integration connects through.
+func (s *Service) deleteVpcLink(w http.ResponseWriter, _ *http.Request, path string) {
+	vpcLinkID := extractVpcLinkID(path)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, exists := s.vpcLinks[vpcLinkID]; !exists {
+		h.WriteJSONError(w, "NotFoundException", "VPC link "+vpcLinkID+" not found", http.StatusNotFound)
+		return
+	}
+	for _, api := range s.apis {
+		for _, in := range api.integrations {
+			if in.connectionType == "VPC_LINK" && in.connectionID == vpcLinkID {
+				h.WriteJSONError(w, "BadRequestException", "VPC link "+vpcLinkID+" is in use by API "+api.apiID, http.StatusBadRequest)
+				return
+			}
+		}
+	}
+	delete(s.vpcLinks, vpcLinkID)
+	s.tags.Delete(resourceArn("/vpclinks/" + vpcLinkID))
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// vpcLinkResp describes link, which is PENDING until its network interfaces
+// are ready. The caller must hold s.mu.
+func (s *Service) vpcLinkResp(link *vpcLink) map[string]interface{} {
+	status := s.transitions.Status(link.ready, "PENDING", "AVAILABLE")
+	message := "VPC link is ready to route traffic"
+	if status == "PENDING" {
+		message = "VPC link is provisioning ENIs"
+	}
+	return map[string]interface{}{
+		"vpcLinkId":            link.vpcLinkID,
+		"name":                 link.name,
+		"subnetIds":            link.subnetIDs,
+		"securityGroupIds":     link.securityGroupIDs,
+		"tags":                 s.tags.Get(resourceArn("/vpclinks/" + link.vpcLinkID)),
+		"vpcLinkStatus":        status,
+		"vpcLinkStatusMessage": message,
+		"vpcLinkVersion":       "V2",
+		"createdDate":          link.created.Format(time.RFC3339),
+	}
+}
+
+func getStringSlice(params map[string]interface{}, key string) []string {
+	arr, _ := params[key].([]interface{})
+	out := make([]string, 0, len(arr))
+	for _, item := range arr {
+		if s, ok := item.(string); ok {
+			out = append(out, s)
+		}
+	}
+	return out
+}
@@ -0,0 +1,628 @@
+// Package appflow provides a mock implementation of Amazon AppFlow.
+//
+// Supported actions:
+//   - CreateFlow
+//   - DescribeFlow
+//   - ListFlows
+//   - DeleteFlow
+//   - StartFlow
+//   - ListFlowExecutionRecords
+//
+// No connector is contacted. A flow run pulls the records set for the flow
+// with [Service.SetExecution], or none, applies the flow's projection and
+// Map tasks to them, and writes them to each S3 destination under
+// prefix/flow-name/execution-id/ as JSON lines, or CSV for the CSV file
+// type. Runs are InProgress until their lifecycle transition completes, and
+// then Successful, or Error with the injected message, in which case nothing
+// is written. StartFlow runs on-demand flows and activates scheduled and
+// event-triggered ones, which do not run on their own.
+package appflow
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/riyanimam/goto/internal/clock"
+	"github.com/riyanimam/goto/internal/lifecycle"
+	h "github.com/riyanimam/goto/internal/mockhelpers"
+	"github.com/riyanimam/goto/internal/paginate"
+	"github.com/riyanimam/goto/internal/tags"
+)
+
+// Execution is the outcome of a flow's runs.
+type Execution struct {
+	// Records are the source records each run pulls.
+	Records []map[string]interface{}
+	// Error, if set, fails each run with this message.
+	Error string
+}
+
+// Service implements the AppFlow mock.
+type Service struct {
+	mu         sync.RWMutex
+	flows      map[string]*flow
+	executions map[string]Execution // by flow name
+
+	store       h.ObjectStore
+	tags        *tags.Store
+	clock       *clock.Clock
+	transitions *lifecycle.Transitions
+}
+
+type flow struct {
+	name         string
+	arn          string
+	status       string // Active, Draft, or Suspended
+	params       map[string]interface{}
+	triggerType  string
+	created      time.Time
+	updated      time.Time
+	runs         []*run // newest last
+	lastRun      *run
+	destinations []destination
+}
+
+type destination struct {
+	bucket   string
+	prefix   string
+	fileType string
+}
+
+type run struct {
+	id       string
+	exec     Execution
+	started  time.Time
+	finished time.Time
+	ready    lifecycle.Transition
+	done     bool
+	result   map[string]interface{}
+	status   string // Successful or Error once done
+	message  string
+}
+
+// New creates a new AppFlow mock service.
+func New() *Service {
+	return &Service{
+		flows:      make(map[string]*flow),
+		executions: make(map[string]Execution),
+		tags:       tags.New(),
+	}
+}
+
+// Name returns the service identifier.
+func (s *Service) Name() string { return "appflow" }
+
+// Handler returns the HTTP handler for AppFlow requests.
+func (s *Service) Handler() http.Handler {
+	return http.HandlerFunc(s.handle)
+}
+
+// Reset clears all state, including injected executions.
+func (s *Service) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.flows = make(map[string]*flow)
+	s.executions = make(map[string]Execution)
+	s.tags.DeleteService("appflow")
+}
+
+// SetObjectStore sets the store S3 destinations are written to.
+func (s *Service) SetObjectStore(store h.ObjectStore) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.store = store
+}
+
+// SetTagStore sets the registry flow tags are recorded in.
+func (s *Service) SetTagStore(store *tags.Store) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tags = store
+}
+
+// SetTransitions sets how long flow runs stay InProgress.
+func (s *Service) SetTransitions(t *lifecycle.Transitions) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.transitions = t
+}
+
+// SetClock attaches the mock clock. Runs that complete as it advances write
+// their records.
+func (s *Service) SetClock(c *clock.Clock) {
+	s.mu.Lock()
+	s.clock = c
+	s.mu.Unlock()
+	c.OnAdvance(func(_, _ time.Time) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.settle()
+	})
+}
+
+func (s *Service) now() time.Time {
+	if s.clock != nil {
+		return s.clock.Now()
+	}
+	return time.Now().UTC()
+}
+
+// SetExecution sets the outcome of the named flow's subsequent runs.
+func (s *Service) SetExecution(flowName string, exec Execution) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.executions[flowName] = exec
+}
+
+func (s *Service) handle(w http.ResponseWriter, r *http.Request) {
+	params := map[string]interface{}{}
+	if body, _ := io.ReadAll(r.Body); len(body) > 0 {
+		if err := json.Unmarshal(body, &params); err != nil {
+			writeValidation(w, "Request body is not valid JSON.")
+			return
+		}
+	}
+
+	switch strings.Trim(r.URL.Path, "/") {
+	case "create-flow":
+		s.createFlow(w, params)
+	case "describe-flow":
+		s.describeFlow(w, params)
+	case "list-flows":
+		s.listFlows(w, params)
+	case "delete-flow":
+		s.deleteFlow(w, params)
+	case "start-flow":
+		s.startFlow(w, params)
+	case "list-flow-execution-records":
+		s.listFlowExecutionRecords(w, params)
+	default:
+		h.WriteJSONError(w, "ResourceNotFoundException", "unsupported operation", http.StatusNotFound)
+	}
+}
+
+func writeValidation(w http.ResponseWriter, message string) {
+	h.WriteJSONError(w, "ValidationException", message, http.StatusBadRequest)
+}
+
+func writeNotFound(w http.ResponseWriter, name string) {
+	h.WriteJSONError(w, "ResourceNotFoundException", "Flow "+name+" does not exist.", http.StatusNotFound)
+}
+
+func (s *Service) createFlow(w http.ResponseWriter, params map[string]interface{}) {
+	name := h.GetString(params, "flowName")
+	if name == "" {
+		writeValidation(w, "flowName is required.")
+		return
+	}
+	trigger, _ := params["triggerConfig"].(map[string]interface{})
+	triggerType := h.GetString(trigger, "triggerType")
+	switch triggerType {
+	case "OnDemand", "Scheduled", "Event":
+	default:
+		writeValidation(w, "triggerConfig.triggerType must be OnDemand, Scheduled, or Event.")
+		return
+	}
+	source, _ := params["sourceFlowConfig"].(map[string]interface{})
+	if h.GetString(source, "connectorType") == "" {
+		writeValidation(w, "sourceFlowConfig.connectorType is required.")
+		return
+	}
+	dests, _ := params["destinationFlowConfigList"].([]interface{})
+	if len(dests) == 0 {
+		writeValidation(w, "destinationFlowConfigList must contain at least one destination.")
+		return
+	}
+
+	f := &flow{
+		name:        name,
+		arn:         fmt.Sprintf("arn:aws:appflow:us-east-1:%s:flow/%s", h.DefaultAccountID, name),
+		status:      "Active",
+		params:      params,
+		triggerType: triggerType,
+	}
+	if triggerType != "OnDemand" {
+		f.status = "Draft"
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, d := range dests {
+		dest, _ := d.(map[string]interface{})
+		if h.GetString(dest, "connectorType") != "S3" {
+			continue
+		}
+		props, _ := dest["destinationConnectorProperties"].(map[string]interface{})
+		s3, _ := props["S3"].(map[string]interface{})
+		output, _ := s3["s3OutputFormatConfig"].(map[string]interface{})
+		dst := destination{
+			bucket:   h.GetString(s3, "bucketName"),
+			prefix:   strings.Trim(h.GetString(s3, "bucketPrefix"), "/"),
+			fileType: h.GetString(output, "fileType"),
+		}
+		if s.store != nil {
+			if _, err := s.store.ListObjects(dst.bucket, ""); err != nil {
+				writeValidation(w, "Destination bucket "+dst.bucket+" does not exist.")
+				return
+			}
+		}
+		f.destinations = append(f.destinations, dst)
+	}
+	if _, exists := s.flows[name]; exists {
+		h.WriteJSONError(w, "ConflictException", "Flow "+name+" already exists.", http.StatusConflict)
+		return
+	}
+	f.created = s.now()
+	f.updated = f.created
+	s.flows[name] = f
+	s.tags.Tag(f.arn, tags.FromMap(params["tags"]))
+
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"flowArn":    f.arn,
+		"flowStatus": f.status,
+	})
+}
+
+func (s *Service) describeFlow(w http.ResponseWriter, params map[string]interface{}) {
+	name := h.GetString(params, "flowName")
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.settle()
+	f, exists := s.flows[name]
+	if !exists {
+		writeNotFound(w, name)
+		return
+	}
+
+	resp := map[string]interface{}{
+		"flowArn":                   f.arn,
+		"flowName":                  f.name,
+		"description":               h.GetString(f.params, "description"),
+		"flowStatus":                f.status,
+		"triggerConfig":             f.params["triggerConfig"],
+		"sourceFlowConfig":          f.params["sourceFlowConfig"],
+		"destinationFlowConfigList": f.params["destinationFlowConfigList"],
+		"tasks":                     f.params["tasks"],
+		"createdAt":                 float64(f.created.Unix()),
+		"lastUpdatedAt":             float64(f.updated.Unix()),
+		"createdBy":                 fmt.Sprintf("arn:aws:iam::%s:user/test", h.DefaultAccountID),
+		"tags":                      s.tags.Get(f.arn),
+	}
+	if r := f.lastRun; r != nil {
+		details := map[string]interface{}{
+			"mostRecentExecutionTime":   float64(r.started.Unix()),
+			"mostRecentExecutionStatus": "InProgress",
+		}
+		if r.done {
+			details["mostRecentExecutionStatus"] = r.status
+			details["mostRecentExecutionMessage"] = r.message
+		}
+		resp["lastRunExecutionDetails"] = details
+	}
+	h.WriteJSON(w, http.StatusOK, resp)
+}
+
+func (s *Service) listFlows(w http.ResponseWriter, params map[string]interface{}) {
+	s.mu.RLock()
+	var flows []map[string]interface{}
+	for _, f := range s.flows {
+		source, _ := f.params["sourceFlowConfig"].(map[string]interface{})
+		var destType string
+		if dests, _ := f.params["destinationFlowConfigList"].([]interface{}); len(dests) > 0 {
+			dest, _ := dests[0].(map[string]interface{})
+			destType = h.GetString(dest, "connectorType")
+		}
+		flows = append(flows, map[string]interface{}{
+			"flowArn":                  f.arn,
+			"flowName":                 f.name,
+			"flowStatus":               f.status,
+			"description":              h.GetString(f.params, "description"),
+			"sourceConnectorType":      h.GetString(source, "connectorType"),
+			"destinationConnectorType": destType,
+			"triggerType":              f.triggerType,
+			"createdAt":                float64(f.created.Unix()),
+			"lastUpdatedAt":            float64(f.updated.Unix()),
+			"tags":                     s.tags.Get(f.arn),
+		})
+	}
+	s.mu.RUnlock()
+	key := func(i int) string { return flows[i]["flowName"].(string) }
+	sort.Slice(flows, func(i, j int) bool { return key(i) < key(j) })
+
+	page, next, err := paginate.Page(flows, key, h.GetString(params, "nextToken"), h.GetInt(params, "maxResults", 100), 100)
+	if err != nil {
+		writeValidation(w, "Invalid nextToken.")
+		return
+	}
+	resp := map[string]interface{}{"flows": page}
+	if next != "" {
+		resp["nextToken"] = next
+	}
+	h.WriteJSON(w, http.StatusOK, resp)
+}
+
+func (s *Service) deleteFlow(w http.ResponseWriter, params map[string]interface{}) {
+	name := h.GetString(params, "flowName")
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.settle()
+	f, exists := s.flows[name]
+	if !exists {
+		writeNotFound(w, name)
+		return
+	}
+	if r := f.lastRun; r != nil && !r.done {
+		if force, _ := params["forceDelete"].(bool); !force {
+			h.WriteJSONError(w, "ConflictException", "Flow "+name+" has a run in progress; set forceDelete to delete it.", http.StatusConflict)
+			return
+		}
+	}
+	delete(s.flows, name)
+	s.tags.Delete(f.arn)
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{})
+}
+
+func (s *Service) startFlow(w http.ResponseWriter, params map[string]interface{}) {
+	name := h.GetString(params, "flowName")
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.settle()
+	f, exists := s.flows[name]
+	if !exists {
+		writeNotFound(w, name)
+		return
+	}
+	if f.triggerType != "OnDemand" {
+		f.status = "Active"
+		f.updated = s.now()
+		h.WriteJSON(w, http.StatusOK, map[string]interface{}{
+			"flowArn":    f.arn,
+			"flowStatus": f.status,
+		})
+		return
+	}
+	if r := f.lastRun; r != nil && !r.done {
+		h.WriteJSONError(w, "ConflictException", "Flow "+name+" already has a run in progress.", http.StatusConflict)
+		return
+	}
+
+	r := &run{
+		id:      h.NewRequestID(),
+		exec:    s.executions[name],
+		started: s.now(),
+		ready:   s.transitions.Begin(),
+	}
+	f.runs = append(f.runs, r)
+	f.lastRun = r
+	s.settle()
+
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"flowArn":     f.arn,
+		"flowStatus":  f.status,
+		"executionId": r.id,
+	})
+}
+
+func (s *Service) listFlowExecutionRecords(w http.ResponseWriter, params map[string]interface{}) {
+	name := h.GetString(params, "flowName")
+	s.mu.Lock()
+	s.settle()
+	f, exists := s.flows[name]
+	if !exists {
+		s.mu.Unlock()
+		writeNotFound(w, name)
+		return
+	}
+	records := make([]map[string]interface{}, 0, len(f.runs))
+	for i := len(f.runs) - 1; i >= 0; i-- {
+		records = append(records, f.runs[i].record())
+	}
+	s.mu.Unlock()
+
+	// Newest first; runs are never removed, so each is keyed by its position
+	// among them.
+	n := len(records)
+	key := func(i int) string { return paginate.Desc(paginate.Seq(n - 1 - i)) }
+	page, next, err := paginate.Page(records, key, h.GetString(params, "nextToken"), h.GetInt(params, "maxResults", 100), 100)
+	if err != nil {
+		writeValidation(w, "Invalid nextToken.")
+		return
+	}
+	resp := map[string]interface{}{"flowExecutions": page}
+	if next != "" {
+		resp["nextToken"] = next
+	}
+	h.WriteJSON(w, http.StatusOK, resp)
+}
+
+// record returns the run as ListFlowExecutionRecords reports it.
+func (r *run) record() map[string]interface{} {
+	rec := map[string]interface{}{
+		"executionId":       r.id,
+		"executionStatus":   "InProgress",
+		"startedAt":         float64(r.started.Unix()),
+		"lastUpdatedAt":     float64(r.started.Unix()),
+		"dataPullStartTime": float64(r.started.Unix()),
+	}
+	if r.done {
+		rec["executionStatus"] = r.status
+		rec["executionResult"] = r.result
+		rec["lastUpdatedAt"] = float64(r.finished.Unix())
+		rec["dataPullEndTime"] = float64(r.finished.Unix())
+	}
+	return rec
+}
+
+// settle completes the runs whose transitions have finished, writing their
+// records to the flow's S3 destinations. The caller must hold s.mu.
+func (s *Service) settle() {
+	for _, f := range s.flows {
+		for _, r := range f.runs {
+			if r.done || !s.transitions.Done(r.ready) {
+				continue
+			}
+			r.done = true
+			r.finished = s.now()
+			s.complete(f, r)
+		}
+	}
+}
+
+// complete records the outcome of a finished run, writing its records to
+// the flow's destinations if it succeeded. The caller must hold s.mu.
+func (s *Service) complete(f *flow, r *run) {
+	errorInfo := map[string]interface{}{"putFailuresCount": 0}
+	r.result = map[string]interface{}{
+		"errorInfo":            errorInfo,
+		"bytesProcessed":       0,
+		"bytesWritten":         0,
+		"recordsProcessed":     0,
+		"numParallelProcesses": 1,
+	}
+	fail := func(message string) {
+		r.status, r.message = "Error", message
+		errorInfo["executionMessage"] = message
+	}
+	if r.exec.Error != "" {
+		fail(r.exec.Error)
+		return
+	}
+
+	records := transform(r.exec.Records, f.params["tasks"])
+	var processed, written int
+	for _, rec := range r.exec.Records {
+		b, _ := json.Marshal(rec)
+		processed += len(b)
+	}
+	for _, d := range f.destinations {
+		data := encode(records, d.fileType)
+		key := strings.TrimPrefix(fmt.Sprintf("%s/%s/%s/%s", d.prefix, f.name, r.id, h.NewRequestID()), "/")
+		if s.store != nil && len(records) > 0 {
+			if err := s.store.PutObject(d.bucket, key, data); err != nil {
+				fail("Failed to write to destination bucket " + d.bucket + ": " + err.Error())
+				errorInfo["putFailuresCount"] = len(records)
+				return
+			}
+		}
+		written += len(data)
+	}
+	r.status = "Successful"
+	r.result["bytesProcessed"] = processed
+	r.result["bytesWritten"] = written
+	r.result["recordsProcessed"] = len(records)
+}
+
+// transform applies a flow's tasks to its source records. A Filter task with
+// the PROJECTION operator keeps only its source fields. If there are Map
+// tasks, each record holds only the fields they map, renamed to their
+// destination fields, unless a Map_all task keeps every field.
+func transform(records []map[string]interface{}, tasks interface{}) []map[string]interface{} {
+	list, _ := tasks.([]interface{})
+	var projection []string
+	mapped := map[string]string{}
+	mapAll := false
+	for _, t := range list {
+		task, _ := t.(map[string]interface{})
+		fields := stringList(task["sourceFields"])
+		switch h.GetString(task, "taskType") {
+		case "Filter":
+			ops, _ := task["connectorOperator"].(map[string]interface{})
+			for _, op := range ops {
+				if op == "PROJECTION" {
+					projection = append(projection, fields...)
+				}
+			}
+		case "Map":
+			if len(fields) == 1 {
+				dest := h.GetString(task, "destinationField")
+				if dest == "" {
+					dest = fields[0]
+				}
+				mapped[fields[0]] = dest
+			}
+		case "Map_all":
+			mapAll = true
+		}
+	}
+
+	out := make([]map[string]interface{}, 0, len(records))
+	for _, rec := range records {
+		row := rec
+		if len(projection) > 0 {
+			row = make(map[string]interface{}, len(projection))
+			for _, field := range projection {
+				if v, ok := rec[field]; ok {
+					row[field] = v
+				}
+			}
+		}
+		if len(mapped) > 0 && !mapAll {
+			renamed := make(map[string]interface{}, len(mapped))
+			for src, dest := range mapped {
+				if v, ok := row[src]; ok {
+					renamed[dest] = v
+				}
+			}
+			row = renamed
+		}
+		out = append(out, row)
+	}
+	return out
+}
+
+func stringList(v interface{}) []string {
+	list, _ := v.([]interface{})
+	out := make([]string, 0, len(list))
+	for _, item := range list {
+		if s, ok := item.(string); ok {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// encode renders records as CSV, with a header of their sorted field names,
+// for the CSV file type, and as JSON lines otherwise.
+func encode(records []map[string]interface{}, fileType string) []byte {
+	var buf bytes.Buffer
+	if fileType != "CSV" {
+		enc := json.NewEncoder(&buf)
+		for _, rec := range records {
+			enc.Encode(rec)
+		}
+		return buf.Bytes()
+	}
+
+	fieldSet := map[string]bool{}
+	for _, rec := range records {
+		for k := range rec {
+			fieldSet[k] = true
+		}
+	}
+	fields := make([]string, 0, len(fieldSet))
+	for k := range fieldSet {
+		fields = append(fields, k)
+	}
+	sort.Strings(fields)
+	cw := csv.NewWriter(&buf)
+	cw.Write(fields)
+	for _, rec := range records {
+		row := make([]string, len(fields))
+		for i, k := range fields {
+			if v, ok := rec[k]; ok && v != nil {
+				row[i] = fmt.Sprint(v)
+			}
+		}
+		cw.Write(row)
+	}
+	cw.Flush()
+	return buf.Bytes()
+}
@@ -7,28 +7,42 @@
 //   - PutScalingPolicy
 //   - DescribeScalingPolicies
 //   - DeleteScalingPolicy
+//   - DescribeScalingActivities
+//   - TagResource
+//   - UntagResource
+//   - ListTagsForResource
+//
+// Scaling policies run when a CloudWatch alarm naming them in its actions
+// fires. The desired count of services in the ECS mock is changed, and
+// recorded as a scaling activity; other scalable dimensions are accepted but
+// their scaling activities fail.
 package applicationautoscaling
 
 import (
-	"encoding/json"
 	"fmt"
-	"io"
 	"net/http"
-	"strings"
 	"sync"
 	"time"
 
+	"github.com/riyanimam/goto/internal/clock"
 	h "github.com/riyanimam/goto/internal/mockhelpers"
+	"github.com/riyanimam/goto/internal/tags"
 )
 
 // Service implements the Application Auto Scaling mock.
 type Service struct {
-	mu       sync.RWMutex
-	targets  map[string]*scalableTarget
-	policies map[string]*scalingPolicy
+	mu         sync.RWMutex
+	targets    map[string]*scalableTarget
+	policies   map[string]*scalingPolicy
+	activities []*scalingActivity
+	tags       *tags.Store
+
+	dispatch h.Dispatcher
+	clock    *clock.Clock
 }
 
 type scalableTarget struct {
+	arn               string
 	serviceNamespace  string
 	resourceID        string
 	scalableDimension string
@@ -45,7 +59,10 @@ type scalingPolicy struct {
 	resourceID        string
 	scalableDimension string
 	policyType        string
+	stepConfig        map[string]interface{}
+	targetConfig      map[string]interface{}
 	created           time.Time
+	lastScaled        time.Time
 }
 
 // New creates a new Application Auto Scaling mock service.
@@ -53,6 +70,7 @@ func New() *Service {
 	return &Service{
 		targets:  make(map[string]*scalableTarget),
 		policies: make(map[string]*scalingPolicy),
+		tags:     tags.New(),
 	}
 }
 
@@ -61,7 +79,18 @@ func (s *Service) Name() string { return "application-autoscaling" }
 
 // Handler returns the HTTP handler for Application Auto Scaling requests.
 func (s *Service) Handler() http.Handler {
-	return http.HandlerFunc(s.handle)
+	return h.JSONRouter{
+		"RegisterScalableTarget":    s.registerScalableTarget,
+		"DescribeScalableTargets":   s.describeScalableTargets,
+		"DeregisterScalableTarget":  s.deregisterScalableTarget,
+		"PutScalingPolicy":          s.putScalingPolicy,
+		"DescribeScalingPolicies":   s.describeScalingPolicies,
+		"DeleteScalingPolicy":       s.deleteScalingPolicy,
+		"DescribeScalingActivities": s.describeScalingActivities,
+		"TagResource":               s.tagResource,
+		"UntagResource":             s.untagResource,
+		"ListTagsForResource":       s.listTagsForResource,
+	}
 }
 
 // Reset clears all state.
@@ -70,52 +99,23 @@ func (s *Service) Reset() {
 	defer s.mu.Unlock()
 	s.targets = make(map[string]*scalableTarget)
 	s.policies = make(map[string]*scalingPolicy)
+	s.activities = nil
+	s.tags.DeleteService("application-autoscaling")
 }
 
-func (s *Service) handle(w http.ResponseWriter, r *http.Request) {
-	target := r.Header.Get("X-Amz-Target")
-
-	bodyBytes, err := io.ReadAll(r.Body)
-	if err != nil {
-		h.WriteJSONError(w, "InternalError", "could not read request body", http.StatusInternalServerError)
-		return
-	}
-
-	var params map[string]interface{}
-	if len(bodyBytes) > 0 {
-		if err := json.Unmarshal(bodyBytes, &params); err != nil {
-			h.WriteJSONError(w, "SerializationException", "could not parse request body", http.StatusBadRequest)
-			return
-		}
-	}
-	if params == nil {
-		params = make(map[string]interface{})
-	}
-
-	action := ""
-	if target != "" {
-		parts := strings.SplitN(target, ".", 2)
-		if len(parts) == 2 {
-			action = parts[1]
-		}
-	}
+// SetClock attaches the mock clock that scaling cooldowns and activities
+// use.
+func (s *Service) SetClock(c *clock.Clock) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.clock = c
+}
 
-	switch action {
-	case "RegisterScalableTarget":
-		s.registerScalableTarget(w, params)
-	case "DescribeScalableTargets":
-		s.describeScalableTargets(w, params)
-	case "DeregisterScalableTarget":
-		s.deregisterScalableTarget(w, params)
-	case "PutScalingPolicy":
-		s.putScalingPolicy(w, params)
-	case "DescribeScalingPolicies":
-		s.describeScalingPolicies(w, params)
-	case "DeleteScalingPolicy":
-		s.deleteScalingPolicy(w, params)
-	default:
-		h.WriteJSONError(w, "UnknownOperationException", fmt.Sprintf("action %q is not supported", action), http.StatusBadRequest)
-	}
+// SetTagStore sets the registry resource tags are recorded in.
+func (s *Service) SetTagStore(store *tags.Store) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tags = store
 }
 
 func targetKey(namespace, resourceID, dimension string) string {
@@ -143,21 +143,21 @@ func (s *Service) registerScalableTarget(w http.ResponseWriter, params map[strin
 	key := targetKey(namespace, resourceID, dimension)
 
 	s.mu.Lock()
-	existing, exists := s.targets[key]
+	target, exists := s.targets[key]
 	if exists {
 		// Update existing target
 		if _, ok := params["MinCapacity"]; ok {
-			existing.minCapacity = minCap
+			target.minCapacity = minCap
 		}
 		if _, ok := params["MaxCapacity"]; ok {
-			existing.maxCapacity = maxCap
+			target.maxCapacity = maxCap
 		}
 		if roleARN != "" {
-			existing.roleARN = roleARN
+			target.roleARN = roleARN
 		}
-		s.mu.Unlock()
 	} else {
-		s.targets[key] = &scalableTarget{
+		target = &scalableTarget{
+			arn:               fmt.Sprintf("arn:aws:application-autoscaling:us-east-1:%s:scalable-target/%s", h.DefaultAccountID, h.RandomHex(32)),
 			serviceNamespace:  namespace,
 			resourceID:        resourceID,
 			scalableDimension: dimension,
@@ -166,10 +166,16 @@ func (s *Service) registerScalableTarget(w http.ResponseWriter, params map[strin
 			roleARN:           roleARN,
 			created:           time.Now().UTC(),
 		}
-		s.mu.Unlock()
+		s.targets[key] = target
 	}
+	s.tags.Tag(target.arn, tags.FromMap(params["Tags"]))
+	registered := *target
+	s.mu.Unlock()
+	s.clampCapacity(registered)
 
-	h.WriteJSON(w, http.StatusOK, map[string]interface{}{})
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"ScalableTargetARN": registered.arn,
+	})
 }
 
 func (s *Service) describeScalableTargets(w http.ResponseWriter, params map[string]interface{}) {
@@ -206,12 +212,14 @@ func (s *Service) deregisterScalableTarget(w http.ResponseWriter, params map[str
 	key := targetKey(namespace, resourceID, dimension)
 
 	s.mu.Lock()
-	if _, exists := s.targets[key]; !exists {
+	target, exists := s.targets[key]
+	if !exists {
 		s.mu.Unlock()
 		h.WriteJSONError(w, "ObjectNotFoundException", "No scalable target found for service namespace: "+namespace+", resource ID: "+resourceID+", scalable dimension: "+dimension, http.StatusBadRequest)
 		return
 	}
 	delete(s.targets, key)
+	s.tags.Delete(target.arn)
 	s.mu.Unlock()
 
 	h.WriteJSON(w, http.StatusOK, map[string]interface{}{})
@@ -232,6 +240,23 @@ func (s *Service) putScalingPolicy(w http.ResponseWriter, params map[string]inte
 	if policyType == "" {
 		policyType = "TargetTrackingScaling"
 	}
+	stepConfig, _ := params["StepScalingPolicyConfiguration"].(map[string]interface{})
+	targetConfig, _ := params["TargetTrackingScalingPolicyConfiguration"].(map[string]interface{})
+	switch policyType {
+	case "StepScaling":
+		if _, _, _, _, err := parseStepConfig(stepConfig); err != nil {
+			h.WriteJSONError(w, "ValidationException", "StepScalingPolicyConfiguration: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+	case "TargetTrackingScaling":
+		if v, _ := targetConfig["TargetValue"].(float64); v <= 0 {
+			h.WriteJSONError(w, "ValidationException", "TargetTrackingScalingPolicyConfiguration.TargetValue must be positive", http.StatusBadRequest)
+			return
+		}
+	default:
+		h.WriteJSONError(w, "ValidationException", "PolicyType must be StepScaling or TargetTrackingScaling", http.StatusBadRequest)
+		return
+	}
 
 	key := policyKey(policyName, namespace, resourceID, dimension)
 	policyARN := fmt.Sprintf("arn:aws:autoscaling:us-east-1:%s:scalingPolicy:%s:resource/%s/%s:policyName/%s",
@@ -242,6 +267,8 @@ func (s *Service) putScalingPolicy(w http.ResponseWriter, params map[string]inte
 	if exists {
 		// Update existing policy, keep existing ARN
 		existing.policyType = policyType
+		existing.stepConfig = stepConfig
+		existing.targetConfig = targetConfig
 		policyARN = existing.policyARN
 		s.mu.Unlock()
 	} else {
@@ -252,6 +279,8 @@ func (s *Service) putScalingPolicy(w http.ResponseWriter, params map[string]inte
 			resourceID:        resourceID,
 			scalableDimension: dimension,
 			policyType:        policyType,
+			stepConfig:        stepConfig,
+			targetConfig:      targetConfig,
 			created:           time.Now().UTC(),
 		}
 		s.mu.Unlock()
@@ -310,6 +339,7 @@ func (s *Service) deleteScalingPolicy(w http.ResponseWriter, params map[string]i
 
 func targetResp(t *scalableTarget) map[string]interface{} {
 	return map[string]interface{}{
+		"ScalableTargetARN": t.arn,
 		"ServiceNamespace":  t.serviceNamespace,
 		"ResourceId":        t.resourceID,
 		"ScalableDimension": t.scalableDimension,
@@ -321,7 +351,7 @@ func targetResp(t *scalableTarget) map[string]interface{} {
 }
 
 func policyResp(p *scalingPolicy) map[string]interface{} {
-	return map[string]interface{}{
+	resp := map[string]interface{}{
 		"PolicyName":        p.policyName,
 		"PolicyARN":         p.policyARN,
 		"ServiceNamespace":  p.serviceNamespace,
@@ -330,4 +360,65 @@ func policyResp(p *scalingPolicy) map[string]interface{} {
 		"PolicyType":        p.policyType,
 		"CreationTime":      float64(p.created.Unix()),
 	}
+	if p.stepConfig != nil {
+		resp["StepScalingPolicyConfiguration"] = p.stepConfig
+	}
+	if p.targetConfig != nil {
+		resp["TargetTrackingScalingPolicyConfiguration"] = p.targetConfig
+	}
+	return resp
+}
+
+// hasTarget reports whether arn names an existing scalable target. The
+// caller must hold s.mu.
+func (s *Service) hasTarget(arn string) bool {
+	for _, t := range s.targets {
+		if t.arn == arn {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Service) tagResource(w http.ResponseWriter, params map[string]interface{}) {
+	arn := h.GetString(params, "ResourceARN")
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if !s.hasTarget(arn) {
+		h.WriteJSONError(w, "ResourceNotFoundException", "Resource not found: "+arn, http.StatusBadRequest)
+		return
+	}
+	s.tags.Tag(arn, tags.FromMap(params["Tags"]))
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{})
+}
+
+func (s *Service) untagResource(w http.ResponseWriter, params map[string]interface{}) {
+	arn := h.GetString(params, "ResourceARN")
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if !s.hasTarget(arn) {
+		h.WriteJSONError(w, "ResourceNotFoundException", "Resource not found: "+arn, http.StatusBadRequest)
+		return
+	}
+	s.tags.Untag(arn, tags.Keys(params["TagKeys"]))
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{})
+}
+
+func (s *Service) listTagsForResource(w http.ResponseWriter, params map[string]interface{}) {
+	arn := h.GetString(params, "ResourceARN")
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if !s.hasTarget(arn) {
+		h.WriteJSONError(w, "ResourceNotFoundException", "Resource not found: "+arn, http.StatusBadRequest)
+		return
+	}
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"Tags": s.tags.Get(arn),
+	})
 }
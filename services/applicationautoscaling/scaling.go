@@ -0,0 +1,345 @@
+package applicationautoscaling
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	h "github.com/riyanimam/goto/internal/mockhelpers"
+)
+
+// ecsDesiredCount is the only scalable dimension whose capacity the mock
+// changes: the desired count of a service in the ECS mock.
+const ecsDesiredCount = "ecs:service:DesiredCount"
+
+// stepAdjustment is one step of a step scaling policy. Bounds are relative
+// to the alarm threshold; a nil bound is infinite.
+type stepAdjustment struct {
+	lower, upper *float64
+	adjustment   float64
+}
+
+// scalingActivity records a scaling policy changing, or failing to change,
+// the capacity of a scalable target.
+type scalingActivity struct {
+	id                string
+	serviceNamespace  string
+	resourceID        string
+	scalableDimension string
+	description       string
+	cause             string
+	start, end        time.Time
+	status            string
+	message           string
+}
+
+// alarmNotification is the part of a CloudWatch alarm notification a
+// scaling policy acts on.
+type alarmNotification struct {
+	AlarmName          string
+	NewStateValue      string
+	NewStateReasonData string
+	Trigger            struct {
+		Threshold float64
+	}
+}
+
+// SetDispatcher sets the function used to read and change the capacity of
+// scalable resources held by other mocks.
+func (s *Service) SetDispatcher(d h.Dispatcher) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.dispatch = d
+}
+
+func (s *Service) now() time.Time {
+	if s.clock != nil {
+		return s.clock.Now()
+	}
+	return time.Now().UTC()
+}
+
+// parseStepConfig reads a StepScalingPolicyConfiguration.
+func parseStepConfig(config map[string]interface{}) (adjustmentType string, steps []stepAdjustment, minMagnitude int, cooldown time.Duration, err error) {
+	adjustmentType = h.GetString(config, "AdjustmentType")
+	switch adjustmentType {
+	case "ChangeInCapacity", "PercentChangeInCapacity", "ExactCapacity":
+	default:
+		return "", nil, 0, 0, fmt.Errorf("AdjustmentType must be ChangeInCapacity, PercentChangeInCapacity, or ExactCapacity")
+	}
+	list, _ := config["StepAdjustments"].([]interface{})
+	if len(list) == 0 {
+		return "", nil, 0, 0, fmt.Errorf("StepAdjustments must not be empty")
+	}
+	for _, raw := range list {
+		item, _ := raw.(map[string]interface{})
+		step := stepAdjustment{adjustment: float64(h.GetInt(item, "ScalingAdjustment", 0))}
+		if v, ok := item["MetricIntervalLowerBound"].(float64); ok {
+			step.lower = &v
+		}
+		if v, ok := item["MetricIntervalUpperBound"].(float64); ok {
+			step.upper = &v
+		}
+		if step.lower == nil && step.upper == nil {
+			return "", nil, 0, 0, fmt.Errorf("a step adjustment needs a lower or an upper bound")
+		}
+		steps = append(steps, step)
+	}
+	return adjustmentType, steps, h.GetInt(config, "MinAdjustmentMagnitude", 0), time.Duration(h.GetInt(config, "Cooldown", 0)) * time.Second, nil
+}
+
+// step returns the adjustment whose interval contains delta, the breaching
+// value less the alarm threshold.
+func step(steps []stepAdjustment, delta float64) (stepAdjustment, bool) {
+	for _, st := range steps {
+		if (st.lower == nil || delta >= *st.lower) && (st.upper == nil || delta < *st.upper) {
+			return st, true
+		}
+	}
+	return stepAdjustment{}, false
+}
+
+// stepCapacity applies a step adjustment to current capacity.
+func stepCapacity(adjustmentType string, adjustment float64, minMagnitude, current int) int {
+	switch adjustmentType {
+	case "ExactCapacity":
+		return int(adjustment)
+	case "PercentChangeInCapacity":
+		change := float64(current) * adjustment / 100
+		// Changes smaller than one round away from zero, larger ones
+		// toward it.
+		switch {
+		case change > 0 && change < 1:
+			change = 1
+		case change < 0 && change > -1:
+			change = -1
+		default:
+			change = math.Trunc(change)
+		}
+		if minMagnitude > 0 && math.Abs(change) < float64(minMagnitude) {
+			change = math.Copysign(float64(minMagnitude), change)
+		}
+		return current + int(change)
+	default:
+		return current + int(adjustment)
+	}
+}
+
+// Deliver runs the scaling policy identified by arn for the CloudWatch
+// alarm notification in payload. Policies act while their alarm is in
+// ALARM: step scaling applies the step matching how far the metric is past
+// the threshold, and target tracking scales capacity in proportion to the
+// metric's distance from the target value. Capacity stays within the
+// target's bounds, and nothing happens during the policy's cooldown.
+func (s *Service) Deliver(arn string, payload []byte) ([]byte, error) {
+	var n alarmNotification
+	if err := json.Unmarshal(payload, &n); err != nil {
+		return nil, fmt.Errorf("invalid alarm notification: %w", err)
+	}
+	var reason struct {
+		RecentDatapoints []float64 `json:"recentDatapoints"`
+	}
+	json.Unmarshal([]byte(n.NewStateReasonData), &reason)
+
+	s.mu.RLock()
+	var policy *scalingPolicy
+	for _, p := range s.policies {
+		if p.policyARN == arn {
+			policy = p
+			break
+		}
+	}
+	if policy == nil {
+		s.mu.RUnlock()
+		return nil, fmt.Errorf("scaling policy %s not found", arn)
+	}
+	p := *policy
+	target, ok := s.targets[targetKey(p.serviceNamespace, p.resourceID, p.scalableDimension)]
+	var t scalableTarget
+	if ok {
+		t = *target
+	}
+	dispatch := s.dispatch
+	s.mu.RUnlock()
+
+	if n.NewStateValue != "ALARM" || len(reason.RecentDatapoints) == 0 {
+		return nil, nil
+	}
+	if !ok {
+		return nil, fmt.Errorf("no scalable target registered for %s", p.resourceID)
+	}
+	cause := fmt.Sprintf("monitor alarm %s in state ALARM triggered policy %s", n.AlarmName, p.policyName)
+	if p.scalableDimension != ecsDesiredCount || dispatch == nil {
+		s.record(p, "", cause, "Failed", "Scaling "+p.scalableDimension+" is not simulated.")
+		return nil, nil
+	}
+
+	resource := "arn:aws:ecs:us-east-1:" + h.DefaultAccountID + ":" + p.resourceID
+	current, err := capacity(dispatch, resource, nil)
+	if err != nil {
+		s.record(p, "", cause, "Failed", err.Error())
+		return nil, nil
+	}
+	value := reason.RecentDatapoints[0]
+	desired, cooldown, ok := p.desiredCapacity(current, value, n.Trigger.Threshold)
+	if !ok {
+		return nil, nil
+	}
+	desired = max(t.minCapacity, min(t.maxCapacity, desired))
+	if desired == current {
+		return nil, nil
+	}
+
+	s.mu.Lock()
+	now := s.now()
+	if live, ok := s.policies[policyKey(p.policyName, p.serviceNamespace, p.resourceID, p.scalableDimension)]; ok {
+		if !live.lastScaled.IsZero() && now.Sub(live.lastScaled) < cooldown {
+			s.mu.Unlock()
+			return nil, nil
+		}
+		live.lastScaled = now
+	}
+	s.mu.Unlock()
+
+	if _, err := capacity(dispatch, resource, &desired); err != nil {
+		s.record(p, "", cause, "Failed", err.Error())
+		return nil, nil
+	}
+	s.record(p, fmt.Sprintf("Setting desired count to %d.", desired), cause+fmt.Sprintf(" changing the desired capacity from %d to %d", current, desired), "Successful", fmt.Sprintf("Successfully set desired count to %d. Change successfully fulfilled by ecs.", desired))
+	return nil, nil
+}
+
+// desiredCapacity returns the capacity the policy asks for when its alarm
+// reports value against threshold, and the cooldown that applies.
+func (p scalingPolicy) desiredCapacity(current int, value, threshold float64) (int, time.Duration, bool) {
+	switch p.policyType {
+	case "StepScaling":
+		adjustmentType, steps, minMagnitude, cooldown, err := parseStepConfig(p.stepConfig)
+		if err != nil {
+			return 0, 0, false
+		}
+		st, ok := step(steps, value-threshold)
+		if !ok {
+			return 0, 0, false
+		}
+		return stepCapacity(adjustmentType, st.adjustment, minMagnitude, current), cooldown, true
+	default:
+		targetValue, _ := p.targetConfig["TargetValue"].(float64)
+		if targetValue <= 0 {
+			return 0, 0, false
+		}
+		desired := int(math.Ceil(float64(current) * value / targetValue))
+		if desired > current {
+			return desired, time.Duration(h.GetInt(p.targetConfig, "ScaleOutCooldown", 300)) * time.Second, true
+		}
+		if h.GetBool(p.targetConfig, "DisableScaleIn") {
+			return 0, 0, false
+		}
+		return desired, time.Duration(h.GetInt(p.targetConfig, "ScaleInCooldown", 300)) * time.Second, true
+	}
+}
+
+// capacity reads the desired count of the ECS service identified by
+// resource, after setting it to *desired if desired is not nil.
+func capacity(dispatch h.Dispatcher, resource string, desired *int) (int, error) {
+	payload := []byte("{}")
+	if desired != nil {
+		payload = []byte(`{"desiredCount":` + strconv.Itoa(*desired) + `}`)
+	}
+	resp, err := dispatch(resource, payload)
+	if err != nil {
+		return 0, err
+	}
+	var state struct {
+		DesiredCount int `json:"desiredCount"`
+	}
+	if err := json.Unmarshal(resp, &state); err != nil {
+		return 0, err
+	}
+	return state.DesiredCount, nil
+}
+
+// record adds a scaling activity for the policy's target.
+func (s *Service) record(p scalingPolicy, description, cause, status, message string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	if description == "" {
+		description = "Failed to set desired capacity."
+	}
+	s.activities = append(s.activities, &scalingActivity{
+		id:                h.NewRequestID(),
+		serviceNamespace:  p.serviceNamespace,
+		resourceID:        p.resourceID,
+		scalableDimension: p.scalableDimension,
+		description:       description,
+		cause:             cause,
+		start:             now,
+		end:               now,
+		status:            status,
+		message:           message,
+	})
+}
+
+// clampCapacity brings the capacity of a newly registered or updated ECS
+// target within its bounds, as registering a scalable target does.
+func (s *Service) clampCapacity(t scalableTarget) {
+	s.mu.RLock()
+	dispatch := s.dispatch
+	s.mu.RUnlock()
+	if t.scalableDimension != ecsDesiredCount || dispatch == nil {
+		return
+	}
+	resource := "arn:aws:ecs:us-east-1:" + h.DefaultAccountID + ":" + t.resourceID
+	current, err := capacity(dispatch, resource, nil)
+	if err != nil {
+		return
+	}
+	if desired := max(t.minCapacity, min(t.maxCapacity, current)); desired != current {
+		capacity(dispatch, resource, &desired)
+	}
+}
+
+func (s *Service) describeScalingActivities(w http.ResponseWriter, params map[string]interface{}) {
+	namespace := h.GetString(params, "ServiceNamespace")
+	if namespace == "" {
+		h.WriteJSONError(w, "ValidationException", "ServiceNamespace is required", http.StatusBadRequest)
+		return
+	}
+	resourceID := h.GetString(params, "ResourceId")
+	dimension := h.GetString(params, "ScalableDimension")
+
+	s.mu.RLock()
+	var matched []*scalingActivity
+	for _, a := range s.activities {
+		if a.serviceNamespace != namespace || (resourceID != "" && a.resourceID != resourceID) || (dimension != "" && a.scalableDimension != dimension) {
+			continue
+		}
+		matched = append(matched, a)
+	}
+	s.mu.RUnlock()
+
+	// Newest first, as the API returns them.
+	list := make([]map[string]interface{}, 0, len(matched))
+	for i := len(matched) - 1; i >= 0; i-- {
+		a := matched[i]
+		list = append(list, map[string]interface{}{
+			"ActivityId":        a.id,
+			"ServiceNamespace":  a.serviceNamespace,
+			"ResourceId":        a.resourceID,
+			"ScalableDimension": a.scalableDimension,
+			"Description":       a.description,
+			"Cause":             a.cause,
+			"StartTime":         float64(a.start.Unix()),
+			"EndTime":           float64(a.end.Unix()),
+			"StatusCode":        a.status,
+			"StatusMessage":     a.message,
+		})
+	}
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"ScalingActivities": list,
+	})
+}
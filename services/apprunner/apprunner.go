@@ -0,0 +1,432 @@
+// Package apprunner provides a mock implementation of AWS App Runner.
+//
+// Supported actions:
+//   - CreateService
+//   - DescribeService
+//   - ListServices
+//   - UpdateService
+//   - PauseService
+//   - ResumeService
+//   - DeleteService
+//   - ListOperations
+//   - TagResource
+//   - UntagResource
+//   - ListTagsForResource
+//
+// Every state-changing call is recorded as an operation and completes
+// immediately: services are RUNNING after creation, PAUSED after
+// PauseService, and gone after DeleteService. Pausing a service that is not
+// RUNNING, or resuming one that is not PAUSED, fails with
+// InvalidStateException.
+package apprunner
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	h "github.com/riyanimam/goto/internal/mockhelpers"
+	"github.com/riyanimam/goto/internal/tags"
+)
+
+// Service implements the App Runner mock.
+type Service struct {
+	mu         sync.RWMutex
+	services   map[string]*appService // keyed by ARN
+	operations map[string][]*operation
+	tags       *tags.Store
+}
+
+type appService struct {
+	name                string
+	id                  string
+	arn                 string
+	url                 string
+	status              string
+	created             time.Time
+	updated             time.Time
+	sourceConfiguration interface{}
+	instanceConfig      map[string]interface{}
+	healthCheckConfig   map[string]interface{}
+	networkConfig       map[string]interface{}
+	autoScalingArn      string
+}
+
+type operation struct {
+	id        string
+	opType    string
+	targetArn string
+	started   time.Time
+	ended     time.Time
+}
+
+// New creates a new App Runner mock service.
+func New() *Service {
+	return &Service{
+		services:   make(map[string]*appService),
+		operations: make(map[string][]*operation),
+		tags:       tags.New(),
+	}
+}
+
+// Name returns the service identifier.
+func (s *Service) Name() string { return "apprunner" }
+
+// Handler returns the HTTP handler for App Runner requests.
+func (s *Service) Handler() http.Handler {
+	return h.JSONRouter{
+		"CreateService":   s.createService,
+		"DescribeService": s.describeService,
+		"ListServices": func(w http.ResponseWriter, _ map[string]interface{}) {
+			s.listServices(w)
+		},
+		"UpdateService": s.updateService,
+		"PauseService": func(w http.ResponseWriter, params map[string]interface{}) {
+			s.changeState(w, params, "PAUSE_SERVICE", "RUNNING", "PAUSED")
+		},
+		"ResumeService": func(w http.ResponseWriter, params map[string]interface{}) {
+			s.changeState(w, params, "RESUME_SERVICE", "PAUSED", "RUNNING")
+		},
+		"DeleteService":       s.deleteService,
+		"ListOperations":      s.listOperations,
+		"TagResource":         s.tagResource,
+		"UntagResource":       s.untagResource,
+		"ListTagsForResource": s.listTagsForResource,
+	}
+}
+
+// Reset clears all state.
+func (s *Service) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.services = make(map[string]*appService)
+	s.operations = make(map[string][]*operation)
+	s.tags.DeleteService("apprunner")
+}
+
+// SetTagStore sets the registry service tags are recorded in.
+func (s *Service) SetTagStore(store *tags.Store) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tags = store
+}
+
+func (s *Service) createService(w http.ResponseWriter, params map[string]interface{}) {
+	name := h.GetString(params, "ServiceName")
+	if name == "" {
+		h.WriteJSONError(w, "InvalidRequestException", "ServiceName is required", http.StatusBadRequest)
+		return
+	}
+	source, ok := params["SourceConfiguration"].(map[string]interface{})
+	if !ok {
+		h.WriteJSONError(w, "InvalidRequestException", "SourceConfiguration is required", http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, svc := range s.services {
+		if svc.name == name {
+			h.WriteJSONError(w, "InvalidRequestException", "Service with the provided name already exists: "+name, http.StatusBadRequest)
+			return
+		}
+	}
+
+	id := h.RandomHex(32)
+	now := time.Now().UTC()
+	svc := &appService{
+		name:                name,
+		id:                  id,
+		arn:                 fmt.Sprintf("arn:aws:apprunner:us-east-1:%s:service/%s/%s", h.DefaultAccountID, name, id),
+		url:                 h.RandomID(10) + ".us-east-1.awsapprunner.com",
+		status:              "RUNNING",
+		created:             now,
+		updated:             now,
+		sourceConfiguration: source,
+		instanceConfig: map[string]interface{}{
+			"Cpu":    "1024",
+			"Memory": "2048",
+		},
+		healthCheckConfig: map[string]interface{}{
+			"Protocol":           "TCP",
+			"Path":               "/",
+			"Interval":           5,
+			"Timeout":            2,
+			"HealthyThreshold":   1,
+			"UnhealthyThreshold": 5,
+		},
+		networkConfig: map[string]interface{}{
+			"EgressConfiguration":  map[string]interface{}{"EgressType": "DEFAULT"},
+			"IngressConfiguration": map[string]interface{}{"IsPubliclyAccessible": true},
+		},
+		autoScalingArn: h.GetString(params, "AutoScalingConfigurationArn"),
+	}
+	mergeInto(svc.instanceConfig, params["InstanceConfiguration"])
+	mergeInto(svc.healthCheckConfig, params["HealthCheckConfiguration"])
+	mergeInto(svc.networkConfig, params["NetworkConfiguration"])
+	if svc.autoScalingArn == "" {
+		svc.autoScalingArn = fmt.Sprintf("arn:aws:apprunner:us-east-1:%s:autoscalingconfiguration/DefaultConfiguration/1/%s", h.DefaultAccountID, h.RandomHex(32))
+	}
+
+	s.services[svc.arn] = svc
+	s.tags.Tag(svc.arn, tags.FromList(params["Tags"], "Key", "Value"))
+	op := s.recordOperation(svc.arn, "CREATE_SERVICE")
+
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"Service":     serviceResp(svc),
+		"OperationId": op.id,
+	})
+}
+
+func (s *Service) describeService(w http.ResponseWriter, params map[string]interface{}) {
+	arn := h.GetString(params, "ServiceArn")
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	svc, exists := s.services[arn]
+	if !exists {
+		h.WriteJSONError(w, "ResourceNotFoundException", "Service not found: "+arn, http.StatusBadRequest)
+		return
+	}
+
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"Service": serviceResp(svc),
+	})
+}
+
+func (s *Service) listServices(w http.ResponseWriter) {
+	s.mu.RLock()
+	list := make([]map[string]interface{}, 0, len(s.services))
+	for _, svc := range s.services {
+		list = append(list, map[string]interface{}{
+			"ServiceName": svc.name,
+			"ServiceId":   svc.id,
+			"ServiceArn":  svc.arn,
+			"ServiceUrl":  svc.url,
+			"CreatedAt":   float64(svc.created.Unix()),
+			"UpdatedAt":   float64(svc.updated.Unix()),
+			"Status":      svc.status,
+		})
+	}
+	s.mu.RUnlock()
+
+	sort.Slice(list, func(i, j int) bool {
+		return list[i]["ServiceName"].(string) < list[j]["ServiceName"].(string)
+	})
+
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"ServiceSummaryList": list,
+	})
+}
+
+func (s *Service) updateService(w http.ResponseWriter, params map[string]interface{}) {
+	arn := h.GetString(params, "ServiceArn")
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	svc, exists := s.services[arn]
+	if !exists {
+		h.WriteJSONError(w, "ResourceNotFoundException", "Service not found: "+arn, http.StatusBadRequest)
+		return
+	}
+	if svc.status != "RUNNING" {
+		h.WriteJSONError(w, "InvalidStateException", "Service "+svc.name+" cannot be updated while "+svc.status, http.StatusBadRequest)
+		return
+	}
+
+	if source, ok := params["SourceConfiguration"].(map[string]interface{}); ok {
+		svc.sourceConfiguration = source
+	}
+	mergeInto(svc.instanceConfig, params["InstanceConfiguration"])
+	mergeInto(svc.healthCheckConfig, params["HealthCheckConfiguration"])
+	mergeInto(svc.networkConfig, params["NetworkConfiguration"])
+	if v := h.GetString(params, "AutoScalingConfigurationArn"); v != "" {
+		svc.autoScalingArn = v
+	}
+	svc.updated = time.Now().UTC()
+	op := s.recordOperation(svc.arn, "UPDATE_SERVICE")
+
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"Service":     serviceResp(svc),
+		"OperationId": op.id,
+	})
+}
+
+// changeState moves a service from one status to another, as PauseService
+// and ResumeService do.
+func (s *Service) changeState(w http.ResponseWriter, params map[string]interface{}, opType, from, to string) {
+	arn := h.GetString(params, "ServiceArn")
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	svc, exists := s.services[arn]
+	if !exists {
+		h.WriteJSONError(w, "ResourceNotFoundException", "Service not found: "+arn, http.StatusBadRequest)
+		return
+	}
+	if svc.status != from {
+		h.WriteJSONError(w, "InvalidStateException", fmt.Sprintf("Service %s is %s, expected %s", svc.name, svc.status, from), http.StatusBadRequest)
+		return
+	}
+
+	svc.status = to
+	svc.updated = time.Now().UTC()
+	op := s.recordOperation(svc.arn, opType)
+
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"Service":     serviceResp(svc),
+		"OperationId": op.id,
+	})
+}
+
+func (s *Service) deleteService(w http.ResponseWriter, params map[string]interface{}) {
+	arn := h.GetString(params, "ServiceArn")
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	svc, exists := s.services[arn]
+	if !exists {
+		h.WriteJSONError(w, "ResourceNotFoundException", "Service not found: "+arn, http.StatusBadRequest)
+		return
+	}
+
+	svc.status = "DELETED"
+	svc.updated = time.Now().UTC()
+	delete(s.services, arn)
+	s.tags.Delete(arn)
+	op := s.recordOperation(arn, "DELETE_SERVICE")
+
+	resp := serviceResp(svc)
+	resp["DeletedAt"] = float64(svc.updated.Unix())
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"Service":     resp,
+		"OperationId": op.id,
+	})
+}
+
+func (s *Service) listOperations(w http.ResponseWriter, params map[string]interface{}) {
+	arn := h.GetString(params, "ServiceArn")
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	ops, exists := s.operations[arn]
+	if !exists {
+		h.WriteJSONError(w, "ResourceNotFoundException", "Service not found: "+arn, http.StatusBadRequest)
+		return
+	}
+
+	// Most recent first.
+	list := make([]map[string]interface{}, 0, len(ops))
+	for i := len(ops) - 1; i >= 0; i-- {
+		op := ops[i]
+		list = append(list, map[string]interface{}{
+			"Id":        op.id,
+			"Type":      op.opType,
+			"Status":    "SUCCEEDED",
+			"TargetArn": op.targetArn,
+			"StartedAt": float64(op.started.Unix()),
+			"EndedAt":   float64(op.ended.Unix()),
+			"UpdatedAt": float64(op.ended.Unix()),
+		})
+	}
+
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"OperationSummaryList": list,
+	})
+}
+
+func (s *Service) tagResource(w http.ResponseWriter, params map[string]interface{}) {
+	arn := h.GetString(params, "ResourceArn")
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if _, exists := s.services[arn]; !exists {
+		h.WriteJSONError(w, "ResourceNotFoundException", "Resource not found: "+arn, http.StatusBadRequest)
+		return
+	}
+	s.tags.Tag(arn, tags.FromList(params["Tags"], "Key", "Value"))
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{})
+}
+
+func (s *Service) untagResource(w http.ResponseWriter, params map[string]interface{}) {
+	arn := h.GetString(params, "ResourceArn")
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if _, exists := s.services[arn]; !exists {
+		h.WriteJSONError(w, "ResourceNotFoundException", "Resource not found: "+arn, http.StatusBadRequest)
+		return
+	}
+	s.tags.Untag(arn, tags.Keys(params["TagKeys"]))
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{})
+}
+
+func (s *Service) listTagsForResource(w http.ResponseWriter, params map[string]interface{}) {
+	arn := h.GetString(params, "ResourceArn")
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if _, exists := s.services[arn]; !exists {
+		h.WriteJSONError(w, "ResourceNotFoundException", "Resource not found: "+arn, http.StatusBadRequest)
+		return
+	}
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"Tags": tags.ToList(s.tags.Get(arn), "Key", "Value"),
+	})
+}
+
+// recordOperation logs a completed operation against a service. Caller must
+// hold s.mu.
+func (s *Service) recordOperation(arn, opType string) *operation {
+	now := time.Now().UTC()
+	op := &operation{
+		id:        h.NewRequestID(),
+		opType:    opType,
+		targetArn: arn,
+		started:   now,
+		ended:     now,
+	}
+	s.operations[arn] = append(s.operations[arn], op)
+	return op
+}
+
+// mergeInto overlays the members of a request structure onto dst.
+func mergeInto(dst map[string]interface{}, src interface{}) {
+	if m, ok := src.(map[string]interface{}); ok {
+		for k, v := range m {
+			dst[k] = v
+		}
+	}
+}
+
+func serviceResp(svc *appService) map[string]interface{} {
+	return map[string]interface{}{
+		"ServiceName":              svc.name,
+		"ServiceId":                svc.id,
+		"ServiceArn":               svc.arn,
+		"ServiceUrl":               svc.url,
+		"Status":                   svc.status,
+		"CreatedAt":                float64(svc.created.Unix()),
+		"UpdatedAt":                float64(svc.updated.Unix()),
+		"SourceConfiguration":      svc.sourceConfiguration,
+		"InstanceConfiguration":    svc.instanceConfig,
+		"HealthCheckConfiguration": svc.healthCheckConfig,
+		"NetworkConfiguration":     svc.networkConfig,
+		"AutoScalingConfigurationSummary": map[string]interface{}{
+			"AutoScalingConfigurationArn":      svc.autoScalingArn,
+			"AutoScalingConfigurationName":     "DefaultConfiguration",
+			"AutoScalingConfigurationRevision": 1,
+		},
+	}
+}
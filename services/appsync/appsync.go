@@ -8,6 +8,9 @@
 //   - CreateDataSource
 //   - GetDataSource
 //   - DeleteDataSource
+//   - TagResource
+//   - UntagResource
+//   - ListTagsForResource
 package appsync
 
 import (
@@ -20,12 +23,14 @@ import (
 	"time"
 
 	h "github.com/riyanimam/goto/internal/mockhelpers"
+	"github.com/riyanimam/goto/internal/tags"
 )
 
 // Service implements the AppSync mock.
 type Service struct {
 	mu   sync.RWMutex
 	apis map[string]*graphqlAPI
+	tags *tags.Store
 }
 
 type graphqlAPI struct {
@@ -34,7 +39,6 @@ type graphqlAPI struct {
 	arn                string
 	authenticationType string
 	logConfig          interface{}
-	tags               map[string]interface{}
 	created            time.Time
 	dataSources        map[string]*dataSource
 }
@@ -50,6 +54,7 @@ type dataSource struct {
 func New() *Service {
 	return &Service{
 		apis: make(map[string]*graphqlAPI),
+		tags: tags.New(),
 	}
 }
 
@@ -66,6 +71,14 @@ func (s *Service) Reset() {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	s.apis = make(map[string]*graphqlAPI)
+	s.tags.DeleteService("appsync")
+}
+
+// SetTagStore sets the registry resource tags are recorded in.
+func (s *Service) SetTagStore(store *tags.Store) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tags = store
 }
 
 func (s *Service) handle(w http.ResponseWriter, r *http.Request) {
@@ -73,6 +86,8 @@ func (s *Service) handle(w http.ResponseWriter, r *http.Request) {
 	method := r.Method
 
 	switch {
+	case strings.Contains(path, "/v1/tags/"):
+		s.handleTags(w, r, path[strings.Index(path, "/v1/tags/")+len("/v1/tags/"):])
 	// DataSource by name: /v1/apis/{apiId}/datasources/{name}
 	case strings.Contains(path, "/datasources/") && method == http.MethodGet:
 		s.getDataSource(w, r, path)
@@ -128,11 +143,6 @@ func (s *Service) createGraphqlAPI(w http.ResponseWriter, r *http.Request) {
 	apiID := h.RandomHex(8)
 	arn := fmt.Sprintf("arn:aws:appsync:us-east-1:%s:apis/%s", h.DefaultAccountID, apiID)
 
-	var tags map[string]interface{}
-	if t, ok := params["tags"].(map[string]interface{}); ok {
-		tags = t
-	}
-
 	s.mu.Lock()
 	api := &graphqlAPI{
 		apiID:              apiID,
@@ -140,15 +150,15 @@ func (s *Service) createGraphqlAPI(w http.ResponseWriter, r *http.Request) {
 		arn:                arn,
 		authenticationType: authType,
 		logConfig:          params["logConfig"],
-		tags:               tags,
 		created:            time.Now().UTC(),
 		dataSources:        make(map[string]*dataSource),
 	}
 	s.apis[apiID] = api
+	s.tags.Tag(arn, tags.FromMap(params["tags"]))
 	s.mu.Unlock()
 
 	h.WriteJSON(w, http.StatusOK, map[string]interface{}{
-		"graphqlApi": apiResp(api),
+		"graphqlApi": apiResp(api, s.tags.Get(api.arn)),
 	})
 }
 
@@ -165,7 +175,7 @@ func (s *Service) getGraphqlAPI(w http.ResponseWriter, _ *http.Request, path str
 	}
 
 	h.WriteJSON(w, http.StatusOK, map[string]interface{}{
-		"graphqlApi": apiResp(api),
+		"graphqlApi": apiResp(api, s.tags.Get(api.arn)),
 	})
 }
 
@@ -173,12 +183,13 @@ func (s *Service) deleteGraphqlAPI(w http.ResponseWriter, _ *http.Request, path
 	apiID := extractAPIID(path)
 
 	s.mu.Lock()
-	_, exists := s.apis[apiID]
+	api, exists := s.apis[apiID]
 	if !exists {
 		s.mu.Unlock()
 		h.WriteJSONError(w, "NotFoundException", "GraphQL API "+apiID+" not found", http.StatusNotFound)
 		return
 	}
+	s.tags.Delete(api.arn)
 	delete(s.apis, apiID)
 	s.mu.Unlock()
 
@@ -189,7 +200,7 @@ func (s *Service) listGraphqlApis(w http.ResponseWriter, _ *http.Request) {
 	s.mu.RLock()
 	var apis []map[string]interface{}
 	for _, api := range s.apis {
-		apis = append(apis, apiResp(api))
+		apis = append(apis, apiResp(api, s.tags.Get(api.arn)))
 	}
 	s.mu.RUnlock()
 
@@ -298,7 +309,7 @@ func (s *Service) deleteDataSource(w http.ResponseWriter, _ *http.Request, path
 	h.WriteJSON(w, http.StatusOK, map[string]interface{}{})
 }
 
-func apiResp(api *graphqlAPI) map[string]interface{} {
+func apiResp(api *graphqlAPI, apiTags map[string]string) map[string]interface{} {
 	resp := map[string]interface{}{
 		"apiId":              api.apiID,
 		"name":               api.name,
@@ -311,8 +322,8 @@ func apiResp(api *graphqlAPI) map[string]interface{} {
 	if api.logConfig != nil {
 		resp["logConfig"] = api.logConfig
 	}
-	if api.tags != nil {
-		resp["tags"] = api.tags
+	if len(apiTags) > 0 {
+		resp["tags"] = apiTags
 	}
 	return resp
 }
@@ -325,3 +336,41 @@ func dataSourceResp(ds *dataSource) map[string]interface{} {
 		"serviceRoleArn": ds.serviceRoleArn,
 	}
 }
+
+// hasResource reports whether arn names an existing resource.
+// The caller must hold s.mu.
+func (s *Service) hasResource(arn string) bool {
+	for _, api := range s.apis {
+		if api.arn == arn {
+			return true
+		}
+	}
+	return false
+}
+
+// handleTags serves TagResource (POST), UntagResource (DELETE), and
+// ListTagsForResource (GET) on /v1/tags/{arn}.
+func (s *Service) handleTags(w http.ResponseWriter, r *http.Request, arn string) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if !s.hasResource(arn) {
+		h.WriteJSONError(w, "NotFoundException", "Resource not found: "+arn, http.StatusNotFound)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPost:
+		var params map[string]interface{}
+		bodyBytes, _ := io.ReadAll(r.Body)
+		json.Unmarshal(bodyBytes, &params)
+		s.tags.Tag(arn, tags.FromMap(params["tags"]))
+		h.WriteJSON(w, http.StatusOK, map[string]interface{}{})
+	case http.MethodDelete:
+		s.tags.Untag(arn, r.URL.Query()["tagKeys"])
+		h.WriteJSON(w, http.StatusOK, map[string]interface{}{})
+	default:
+		h.WriteJSON(w, http.StatusOK, map[string]interface{}{
+			"tags": s.tags.Get(arn),
+		})
+	}
+}
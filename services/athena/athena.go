@@ -9,12 +9,21 @@
 //   - GetWorkGroup
 //   - DeleteWorkGroup
 //   - ListWorkGroups
+//   - UpdateWorkGroup
+//   - TagResource
+//   - UntagResource
+//   - ListTagsForResource
+//
+// Queries are not run: they succeed at once with an empty result set,
+// scanning the bytes set for them with SetDataScanned. Workgroup settings
+// are honored: queries in disabled workgroups are rejected, an enforced
+// workgroup's result configuration overrides the client's, a query without
+// an output location is rejected, and a query that would scan more than the
+// workgroup's BytesScannedCutoffPerQuery fails.
 package athena
 
 import (
-	"encoding/json"
 	"fmt"
-	"io"
 	"net/http"
 	"sort"
 	"strings"
@@ -22,6 +31,7 @@ import (
 	"time"
 
 	h "github.com/riyanimam/goto/internal/mockhelpers"
+	"github.com/riyanimam/goto/internal/tags"
 )
 
 // Service implements the Athena mock.
@@ -29,6 +39,8 @@ type Service struct {
 	mu         sync.RWMutex
 	executions map[string]*queryExecution
 	workgroups map[string]*workGroup
+	scanned    map[string]int64 // bytes scanned by query string
+	tags       *tags.Store
 }
 
 type queryExecution struct {
@@ -37,7 +49,11 @@ type queryExecution struct {
 	database  string
 	workgroup string
 	outputLoc string
+	encrypt   map[string]interface{}
+	owner     string
+	scanned   int64
 	status    string
+	reason    string
 	submitted time.Time
 	completed time.Time
 }
@@ -47,6 +63,7 @@ type workGroup struct {
 	state       string
 	description string
 	created     time.Time
+	config      workGroupConfig
 }
 
 // New creates a new Athena mock service.
@@ -54,17 +71,36 @@ func New() *Service {
 	return &Service{
 		executions: make(map[string]*queryExecution),
 		workgroups: map[string]*workGroup{
-			"primary": {name: "primary", state: "ENABLED", created: time.Now().UTC()},
+			"primary": primaryWorkGroup(),
 		},
+		scanned: make(map[string]int64),
+		tags:    tags.New(),
 	}
 }
 
+func primaryWorkGroup() *workGroup {
+	return &workGroup{name: "primary", state: "ENABLED", created: time.Now().UTC(), config: defaultWorkGroupConfig()}
+}
+
 // Name returns the service identifier.
 func (s *Service) Name() string { return "athena" }
 
 // Handler returns the HTTP handler for Athena requests.
 func (s *Service) Handler() http.Handler {
-	return http.HandlerFunc(s.handle)
+	return h.JSONRouter{
+		"StartQueryExecution": s.startQueryExecution,
+		"GetQueryExecution":   s.getQueryExecution,
+		"GetQueryResults":     s.getQueryResults,
+		"ListQueryExecutions": s.listQueryExecutions,
+		"CreateWorkGroup":     s.createWorkGroup,
+		"GetWorkGroup":        s.getWorkGroup,
+		"DeleteWorkGroup":     s.deleteWorkGroup,
+		"ListWorkGroups":      s.listWorkGroups,
+		"UpdateWorkGroup":     s.updateWorkGroup,
+		"TagResource":         s.tagResource,
+		"UntagResource":       s.untagResource,
+		"ListTagsForResource": s.listTagsForResource,
+	}
 }
 
 // Reset clears all state.
@@ -73,55 +109,25 @@ func (s *Service) Reset() {
 	defer s.mu.Unlock()
 	s.executions = make(map[string]*queryExecution)
 	s.workgroups = map[string]*workGroup{
-		"primary": {name: "primary", state: "ENABLED", created: time.Now().UTC()},
+		"primary": primaryWorkGroup(),
 	}
+	s.scanned = make(map[string]int64)
+	s.tags.DeleteService("athena")
 }
 
-func (s *Service) handle(w http.ResponseWriter, r *http.Request) {
-	target := r.Header.Get("X-Amz-Target")
-
-	bodyBytes, err := io.ReadAll(r.Body)
-	if err != nil {
-		h.WriteJSONError(w, "InternalFailure", "could not read request body", http.StatusInternalServerError)
-		return
-	}
-
-	var params map[string]interface{}
-	if len(bodyBytes) > 0 {
-		json.Unmarshal(bodyBytes, &params)
-	}
-	if params == nil {
-		params = make(map[string]interface{})
-	}
-
-	action := ""
-	if target != "" {
-		parts := strings.SplitN(target, ".", 2)
-		if len(parts) == 2 {
-			action = parts[1]
-		}
-	}
+// SetDataScanned sets the number of bytes queries with the given query
+// string scan. Other queries scan nothing.
+func (s *Service) SetDataScanned(query string, bytes int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.scanned[strings.TrimSpace(query)] = bytes
+}
 
-	switch action {
-	case "StartQueryExecution":
-		s.startQueryExecution(w, params)
-	case "GetQueryExecution":
-		s.getQueryExecution(w, params)
-	case "GetQueryResults":
-		s.getQueryResults(w, params)
-	case "ListQueryExecutions":
-		s.listQueryExecutions(w, params)
-	case "CreateWorkGroup":
-		s.createWorkGroup(w, params)
-	case "GetWorkGroup":
-		s.getWorkGroup(w, params)
-	case "DeleteWorkGroup":
-		s.deleteWorkGroup(w, params)
-	case "ListWorkGroups":
-		s.listWorkGroups(w, params)
-	default:
-		h.WriteJSONError(w, "UnknownOperationException", fmt.Sprintf("action %q is not supported", action), http.StatusBadRequest)
-	}
+// SetTagStore sets the registry resource tags are recorded in.
+func (s *Service) SetTagStore(store *tags.Store) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tags = store
 }
 
 func (s *Service) startQueryExecution(w http.ResponseWriter, params map[string]interface{}) {
@@ -136,31 +142,57 @@ func (s *Service) startQueryExecution(w http.ResponseWriter, params map[string]i
 		database = h.GetString(qCtx, "Database")
 	}
 
-	outputLoc := ""
-	if resCfg, ok := params["ResultConfiguration"].(map[string]interface{}); ok {
-		outputLoc = h.GetString(resCfg, "OutputLocation")
+	resCfg, _ := params["ResultConfiguration"].(map[string]interface{})
+	clientEncryption, _ := resCfg["EncryptionConfiguration"].(map[string]interface{})
+	if !validEncryption(w, clientEncryption) {
+		return
 	}
 
-	wg := h.GetString(params, "WorkGroup")
-	if wg == "" {
-		wg = "primary"
+	name := h.GetString(params, "WorkGroup")
+	if name == "" {
+		name = "primary"
 	}
 
 	now := time.Now().UTC()
 	id := h.NewRequestID()
 
 	s.mu.Lock()
-	s.executions[id] = &queryExecution{
+	defer s.mu.Unlock()
+	wg, exists := s.workgroups[name]
+	if !exists {
+		h.WriteJSONError(w, "InvalidRequestException", "WorkGroup "+name+" is not found.", http.StatusBadRequest)
+		return
+	}
+	if wg.state != "ENABLED" {
+		h.WriteJSONError(w, "InvalidRequestException", "WorkGroup "+name+" is disabled.", http.StatusBadRequest)
+		return
+	}
+	outputLoc, encryption, owner := wg.config.resultConfig(resCfg)
+	if outputLoc == "" {
+		h.WriteJSONError(w, "InvalidRequestException", "No output location provided. An output location is required either through the Workgroup result configuration setting or as an API input.", http.StatusBadRequest)
+		return
+	}
+
+	exec := &queryExecution{
 		id:        id,
 		query:     query,
 		database:  database,
-		workgroup: wg,
+		workgroup: name,
 		outputLoc: outputLoc,
+		encrypt:   encryption,
+		owner:     owner,
+		scanned:   s.scanned[strings.TrimSpace(query)],
 		status:    "SUCCEEDED",
 		submitted: now,
 		completed: now,
 	}
-	s.mu.Unlock()
+	// Athena cancels a query once it scans past the workgroup's limit.
+	if cutoff := wg.config.bytesScannedCutoff; cutoff != 0 && exec.scanned > cutoff {
+		exec.scanned = cutoff
+		exec.status = "FAILED"
+		exec.reason = "Query cancelled! : Bytes scanned limit was exceeded"
+	}
+	s.executions[id] = exec
 
 	h.WriteJSON(w, http.StatusOK, map[string]interface{}{
 		"QueryExecutionId": id,
@@ -230,6 +262,10 @@ func (s *Service) createWorkGroup(w http.ResponseWriter, params map[string]inter
 	}
 
 	desc := h.GetString(params, "Description")
+	cfg, ok := parseWorkGroupConfig(w, params["Configuration"])
+	if !ok {
+		return
+	}
 
 	s.mu.Lock()
 	if _, exists := s.workgroups[name]; exists {
@@ -243,7 +279,9 @@ func (s *Service) createWorkGroup(w http.ResponseWriter, params map[string]inter
 		state:       "ENABLED",
 		description: desc,
 		created:     time.Now().UTC(),
+		config:      cfg,
 	}
+	s.tags.Tag(workGroupARN(name), tags.FromList(params["Tags"], "Key", "Value"))
 	s.mu.Unlock()
 
 	h.WriteJSON(w, http.StatusOK, map[string]interface{}{})
@@ -263,10 +301,11 @@ func (s *Service) getWorkGroup(w http.ResponseWriter, params map[string]interfac
 
 	h.WriteJSON(w, http.StatusOK, map[string]interface{}{
 		"WorkGroup": map[string]interface{}{
-			"Name":         wg.name,
-			"State":        wg.state,
-			"Description":  wg.description,
-			"CreationTime": float64(wg.created.Unix()),
+			"Name":          wg.name,
+			"State":         wg.state,
+			"Description":   wg.description,
+			"CreationTime":  float64(wg.created.Unix()),
+			"Configuration": wg.config.resp(),
 		},
 	})
 }
@@ -281,6 +320,7 @@ func (s *Service) deleteWorkGroup(w http.ResponseWriter, params map[string]inter
 		return
 	}
 	delete(s.workgroups, name)
+	s.tags.Delete(workGroupARN(name))
 	s.mu.Unlock()
 
 	h.WriteJSON(w, http.StatusOK, map[string]interface{}{})
@@ -308,21 +348,95 @@ func (s *Service) listWorkGroups(w http.ResponseWriter, _ map[string]interface{}
 	})
 }
 
+// workGroupARN returns the ARN of the named workgroup.
+func workGroupARN(name string) string {
+	return fmt.Sprintf("arn:aws:athena:us-east-1:%s:workgroup/%s", h.DefaultAccountID, name)
+}
+
+// hasWorkGroup reports whether arn names an existing workgroup. The caller
+// must hold s.mu.
+func (s *Service) hasWorkGroup(arn string) bool {
+	_, name, ok := strings.Cut(arn, ":workgroup/")
+	return ok && s.workgroups[name] != nil && workGroupARN(name) == arn
+}
+
+func (s *Service) tagResource(w http.ResponseWriter, params map[string]interface{}) {
+	arn := h.GetString(params, "ResourceARN")
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if !s.hasWorkGroup(arn) {
+		h.WriteJSONError(w, "InvalidRequestException", "WorkGroup not found: "+arn, http.StatusBadRequest)
+		return
+	}
+	s.tags.Tag(arn, tags.FromList(params["Tags"], "Key", "Value"))
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{})
+}
+
+func (s *Service) untagResource(w http.ResponseWriter, params map[string]interface{}) {
+	arn := h.GetString(params, "ResourceARN")
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if !s.hasWorkGroup(arn) {
+		h.WriteJSONError(w, "InvalidRequestException", "WorkGroup not found: "+arn, http.StatusBadRequest)
+		return
+	}
+	s.tags.Untag(arn, tags.Keys(params["TagKeys"]))
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{})
+}
+
+func (s *Service) listTagsForResource(w http.ResponseWriter, params map[string]interface{}) {
+	arn := h.GetString(params, "ResourceARN")
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if !s.hasWorkGroup(arn) {
+		h.WriteJSONError(w, "InvalidRequestException", "WorkGroup not found: "+arn, http.StatusBadRequest)
+		return
+	}
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"Tags": tags.ToList(s.tags.Get(arn), "Key", "Value"),
+	})
+}
+
 func execResp(exec *queryExecution) map[string]interface{} {
+	resultConfig := map[string]interface{}{
+		"OutputLocation": exec.outputLoc,
+	}
+	if exec.encrypt != nil {
+		resultConfig["EncryptionConfiguration"] = exec.encrypt
+	}
+	if exec.owner != "" {
+		resultConfig["ExpectedBucketOwner"] = exec.owner
+	}
+	status := map[string]interface{}{
+		"State":              exec.status,
+		"SubmissionDateTime": float64(exec.submitted.Unix()),
+		"CompletionDateTime": float64(exec.completed.Unix()),
+	}
+	if exec.reason != "" {
+		status["StateChangeReason"] = exec.reason
+		status["AthenaError"] = map[string]interface{}{
+			"ErrorCategory": 2, // USER
+			"Retryable":     false,
+			"ErrorMessage":  exec.reason,
+		}
+	}
 	return map[string]interface{}{
 		"QueryExecutionId": exec.id,
 		"Query":            exec.query,
 		"QueryExecutionContext": map[string]interface{}{
 			"Database": exec.database,
 		},
-		"ResultConfiguration": map[string]interface{}{
-			"OutputLocation": exec.outputLoc,
-		},
-		"WorkGroup": exec.workgroup,
-		"Status": map[string]interface{}{
-			"State":              exec.status,
-			"SubmissionDateTime": float64(exec.submitted.Unix()),
-			"CompletionDateTime": float64(exec.completed.Unix()),
+		"ResultConfiguration": resultConfig,
+		"WorkGroup":           exec.workgroup,
+		"Status":              status,
+		"Statistics": map[string]interface{}{
+			"DataScannedInBytes": exec.scanned,
 		},
 	}
 }
@@ -0,0 +1,208 @@
+package athena
+
+import (
+	"net/http"
+	"strings"
+
+	h "github.com/riyanimam/goto/internal/mockhelpers"
+)
+
+// minBytesScannedCutoff is the smallest per-query data usage limit Athena
+// accepts, 10 MB.
+const minBytesScannedCutoff = 10000000
+
+// workGroupConfig is a workgroup's Configuration.
+type workGroupConfig struct {
+	outputLocation      string
+	encryption          map[string]interface{} // EncryptionConfiguration, or nil
+	expectedBucketOwner string
+	enforce             bool
+	bytesScannedCutoff  int64 // zero if unlimited
+	publishMetrics      bool
+	requesterPays       bool
+}
+
+// defaultWorkGroupConfig is the configuration of the primary workgroup and
+// of workgroups created without one: client-side settings are used.
+func defaultWorkGroupConfig() workGroupConfig {
+	return workGroupConfig{publishMetrics: true}
+}
+
+// parseWorkGroupConfig reads a CreateWorkGroup Configuration, writing
+// InvalidRequestException and returning false if it is invalid.
+func parseWorkGroupConfig(w http.ResponseWriter, raw interface{}) (workGroupConfig, bool) {
+	cfg := defaultWorkGroupConfig()
+	params, _ := raw.(map[string]interface{})
+	if v, ok := params["EnforceWorkGroupConfiguration"].(bool); ok {
+		cfg.enforce = v
+	}
+	if v, ok := params["PublishCloudWatchMetricsEnabled"].(bool); ok {
+		cfg.publishMetrics = v
+	}
+	cfg.requesterPays = h.GetBool(params, "RequesterPaysEnabled")
+	if v, ok := params["BytesScannedCutoffPerQuery"].(float64); ok {
+		cfg.bytesScannedCutoff = int64(v)
+	}
+	if rc, ok := params["ResultConfiguration"].(map[string]interface{}); ok {
+		cfg.outputLocation = h.GetString(rc, "OutputLocation")
+		cfg.encryption, _ = rc["EncryptionConfiguration"].(map[string]interface{})
+		cfg.expectedBucketOwner = h.GetString(rc, "ExpectedBucketOwner")
+	}
+	return cfg, cfg.validate(w)
+}
+
+// update applies UpdateWorkGroup ConfigurationUpdates to a copy of the
+// configuration, writing InvalidRequestException and returning false if the
+// result is invalid.
+func (cfg workGroupConfig) update(w http.ResponseWriter, raw interface{}) (workGroupConfig, bool) {
+	params, _ := raw.(map[string]interface{})
+	if v, ok := params["EnforceWorkGroupConfiguration"].(bool); ok {
+		cfg.enforce = v
+	}
+	if v, ok := params["PublishCloudWatchMetricsEnabled"].(bool); ok {
+		cfg.publishMetrics = v
+	}
+	if v, ok := params["RequesterPaysEnabled"].(bool); ok {
+		cfg.requesterPays = v
+	}
+	if v, ok := params["BytesScannedCutoffPerQuery"].(float64); ok {
+		cfg.bytesScannedCutoff = int64(v)
+	}
+	if h.GetBool(params, "RemoveBytesScannedCutoffPerQuery") {
+		cfg.bytesScannedCutoff = 0
+	}
+	if rc, ok := params["ResultConfigurationUpdates"].(map[string]interface{}); ok {
+		if v := h.GetString(rc, "OutputLocation"); v != "" {
+			cfg.outputLocation = v
+		}
+		if h.GetBool(rc, "RemoveOutputLocation") {
+			cfg.outputLocation = ""
+		}
+		if v, ok := rc["EncryptionConfiguration"].(map[string]interface{}); ok {
+			cfg.encryption = v
+		}
+		if h.GetBool(rc, "RemoveEncryptionConfiguration") {
+			cfg.encryption = nil
+		}
+		if v := h.GetString(rc, "ExpectedBucketOwner"); v != "" {
+			cfg.expectedBucketOwner = v
+		}
+		if h.GetBool(rc, "RemoveExpectedBucketOwner") {
+			cfg.expectedBucketOwner = ""
+		}
+	}
+	return cfg, cfg.validate(w)
+}
+
+// validate checks the configuration, writing InvalidRequestException if it
+// is invalid.
+func (cfg workGroupConfig) validate(w http.ResponseWriter) bool {
+	if cfg.bytesScannedCutoff != 0 && cfg.bytesScannedCutoff < minBytesScannedCutoff {
+		h.WriteJSONError(w, "InvalidRequestException", "BytesScannedCutoffPerQuery must be at least 10000000 bytes (10 MB).", http.StatusBadRequest)
+		return false
+	}
+	if cfg.outputLocation != "" && !strings.HasPrefix(cfg.outputLocation, "s3://") {
+		h.WriteJSONError(w, "InvalidRequestException", "The output location must be an S3 path starting with s3://.", http.StatusBadRequest)
+		return false
+	}
+	return validEncryption(w, cfg.encryption)
+}
+
+// validEncryption checks an EncryptionConfiguration: KMS options need a
+// key and SSE_S3 takes none. It writes InvalidRequestException if the
+// configuration is invalid.
+func validEncryption(w http.ResponseWriter, enc map[string]interface{}) bool {
+	if enc == nil {
+		return true
+	}
+	key := h.GetString(enc, "KmsKey")
+	switch option := h.GetString(enc, "EncryptionOption"); option {
+	case "SSE_KMS", "CSE_KMS":
+		if key == "" {
+			h.WriteJSONError(w, "InvalidRequestException", "A KMS key is required for "+option+" encryption.", http.StatusBadRequest)
+			return false
+		}
+	case "SSE_S3":
+		if key != "" {
+			h.WriteJSONError(w, "InvalidRequestException", "A KMS key cannot be specified for SSE_S3 encryption.", http.StatusBadRequest)
+			return false
+		}
+	default:
+		h.WriteJSONError(w, "InvalidRequestException", "EncryptionOption must be SSE_S3, SSE_KMS, or CSE_KMS.", http.StatusBadRequest)
+		return false
+	}
+	return true
+}
+
+// resp returns the configuration in the shape GetWorkGroup reports it.
+func (cfg workGroupConfig) resp() map[string]interface{} {
+	rc := map[string]interface{}{}
+	if cfg.outputLocation != "" {
+		rc["OutputLocation"] = cfg.outputLocation
+	}
+	if cfg.encryption != nil {
+		rc["EncryptionConfiguration"] = cfg.encryption
+	}
+	if cfg.expectedBucketOwner != "" {
+		rc["ExpectedBucketOwner"] = cfg.expectedBucketOwner
+	}
+	resp := map[string]interface{}{
+		"ResultConfiguration":             rc,
+		"EnforceWorkGroupConfiguration":   cfg.enforce,
+		"PublishCloudWatchMetricsEnabled": cfg.publishMetrics,
+		"RequesterPaysEnabled":            cfg.requesterPays,
+	}
+	if cfg.bytesScannedCutoff != 0 {
+		resp["BytesScannedCutoffPerQuery"] = cfg.bytesScannedCutoff
+	}
+	return resp
+}
+
+// resultConfig returns the output location, encryption, and bucket owner a
+// query in the workgroup uses. An enforced workgroup's settings override
+// the client's; otherwise the client's settings override the workgroup's.
+func (cfg workGroupConfig) resultConfig(client map[string]interface{}) (outputLoc string, encryption map[string]interface{}, owner string) {
+	outputLoc, encryption, owner = cfg.outputLocation, cfg.encryption, cfg.expectedBucketOwner
+	if cfg.enforce {
+		return outputLoc, encryption, owner
+	}
+	if v := h.GetString(client, "OutputLocation"); v != "" {
+		outputLoc = v
+	}
+	if v, ok := client["EncryptionConfiguration"].(map[string]interface{}); ok {
+		encryption = v
+	}
+	if v := h.GetString(client, "ExpectedBucketOwner"); v != "" {
+		owner = v
+	}
+	return outputLoc, encryption, owner
+}
+
+func (s *Service) updateWorkGroup(w http.ResponseWriter, params map[string]interface{}) {
+	name := h.GetString(params, "WorkGroup")
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	wg, exists := s.workgroups[name]
+	if !exists {
+		h.WriteJSONError(w, "InvalidRequestException", "WorkGroup "+name+" not found", http.StatusBadRequest)
+		return
+	}
+	state := h.GetString(params, "State")
+	if state != "" && state != "ENABLED" && state != "DISABLED" {
+		h.WriteJSONError(w, "InvalidRequestException", "State must be ENABLED or DISABLED", http.StatusBadRequest)
+		return
+	}
+	cfg, ok := wg.config.update(w, params["ConfigurationUpdates"])
+	if !ok {
+		return
+	}
+	wg.config = cfg
+	if state != "" {
+		wg.state = state
+	}
+	if _, ok := params["Description"]; ok {
+		wg.description = h.GetString(params, "Description")
+	}
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{})
+}
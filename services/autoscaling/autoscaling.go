@@ -9,6 +9,9 @@
 //   - DescribeLaunchConfigurations
 //   - DeleteLaunchConfiguration
 //   - SetDesiredCapacity
+//   - CreateOrUpdateTags
+//   - DeleteTags
+//   - DescribeTags
 package autoscaling
 
 import (
@@ -20,6 +23,7 @@ import (
 	"sync"
 
 	h "github.com/riyanimam/goto/internal/mockhelpers"
+	"github.com/riyanimam/goto/internal/tags"
 )
 
 // Service implements the Auto Scaling mock.
@@ -27,6 +31,7 @@ type Service struct {
 	mu            sync.RWMutex
 	groups        map[string]*autoScalingGroup
 	launchConfigs map[string]*launchConfiguration
+	tags          *tags.Store
 }
 
 type autoScalingGroup struct {
@@ -36,6 +41,7 @@ type autoScalingGroup struct {
 	maxSize                 int
 	desiredCapacity         int
 	launchConfigurationName string
+	propagate               map[string]bool // tag key -> PropagateAtLaunch
 }
 
 type launchConfiguration struct {
@@ -50,6 +56,7 @@ func New() *Service {
 	return &Service{
 		groups:        make(map[string]*autoScalingGroup),
 		launchConfigs: make(map[string]*launchConfiguration),
+		tags:          tags.New(),
 	}
 }
 
@@ -58,7 +65,22 @@ func (s *Service) Name() string { return "autoscaling" }
 
 // Handler returns the HTTP handler for Auto Scaling requests.
 func (s *Service) Handler() http.Handler {
-	return http.HandlerFunc(s.handle)
+	return h.QueryRouter{
+		Actions: map[string]http.HandlerFunc{
+			"CreateAutoScalingGroup":       s.createAutoScalingGroup,
+			"DescribeAutoScalingGroups":    s.describeAutoScalingGroups,
+			"DeleteAutoScalingGroup":       s.deleteAutoScalingGroup,
+			"UpdateAutoScalingGroup":       s.updateAutoScalingGroup,
+			"CreateLaunchConfiguration":    s.createLaunchConfiguration,
+			"DescribeLaunchConfigurations": s.describeLaunchConfigurations,
+			"DeleteLaunchConfiguration":    s.deleteLaunchConfiguration,
+			"SetDesiredCapacity":           s.setDesiredCapacity,
+			"CreateOrUpdateTags":           s.createOrUpdateTags,
+			"DeleteTags":                   s.deleteTags,
+			"DescribeTags":                 s.describeTags,
+		},
+		Error: writeASError,
+	}
 }
 
 // Reset clears all state.
@@ -67,35 +89,14 @@ func (s *Service) Reset() {
 	defer s.mu.Unlock()
 	s.groups = make(map[string]*autoScalingGroup)
 	s.launchConfigs = make(map[string]*launchConfiguration)
+	s.tags.DeleteService("autoscaling")
 }
 
-func (s *Service) handle(w http.ResponseWriter, r *http.Request) {
-	if err := r.ParseForm(); err != nil {
-		writeASError(w, "ValidationError", "could not parse request", http.StatusBadRequest)
-		return
-	}
-
-	action := r.FormValue("Action")
-	switch action {
-	case "CreateAutoScalingGroup":
-		s.createAutoScalingGroup(w, r)
-	case "DescribeAutoScalingGroups":
-		s.describeAutoScalingGroups(w, r)
-	case "DeleteAutoScalingGroup":
-		s.deleteAutoScalingGroup(w, r)
-	case "UpdateAutoScalingGroup":
-		s.updateAutoScalingGroup(w, r)
-	case "CreateLaunchConfiguration":
-		s.createLaunchConfiguration(w, r)
-	case "DescribeLaunchConfigurations":
-		s.describeLaunchConfigurations(w, r)
-	case "DeleteLaunchConfiguration":
-		s.deleteLaunchConfiguration(w, r)
-	case "SetDesiredCapacity":
-		s.setDesiredCapacity(w, r)
-	default:
-		writeASError(w, "ValidationError", fmt.Sprintf("action %q is not supported", action), http.StatusBadRequest)
-	}
+// SetTagStore sets the registry group tags are recorded in.
+func (s *Service) SetTagStore(store *tags.Store) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tags = store
 }
 
 func (s *Service) createAutoScalingGroup(w http.ResponseWriter, r *http.Request) {
@@ -123,8 +124,12 @@ func (s *Service) createAutoScalingGroup(w http.ResponseWriter, r *http.Request)
 		maxSize:                 maxSize,
 		desiredCapacity:         desiredCapacity,
 		launchConfigurationName: r.FormValue("LaunchConfigurationName"),
+		propagate:               make(map[string]bool),
 	}
 	s.groups[name] = g
+	for _, t := range formTags(r) {
+		s.applyTag(g, t)
+	}
 	s.mu.Unlock()
 
 	resp := createAutoScalingGroupResponse{RequestID: h.NewRequestID()}
@@ -148,7 +153,7 @@ func (s *Service) describeAutoScalingGroups(w http.ResponseWriter, r *http.Reque
 		if len(filterNames) > 0 && !contains(filterNames, g.name) {
 			continue
 		}
-		groups = append(groups, groupToXML(g))
+		groups = append(groups, s.groupToXML(g))
 	}
 	s.mu.RUnlock()
 
@@ -169,12 +174,14 @@ func (s *Service) deleteAutoScalingGroup(w http.ResponseWriter, r *http.Request)
 	}
 
 	s.mu.Lock()
-	if _, exists := s.groups[name]; !exists {
+	g, exists := s.groups[name]
+	if !exists {
 		s.mu.Unlock()
 		writeASError(w, "ValidationError", "AutoScalingGroup ["+name+"] not found", http.StatusBadRequest)
 		return
 	}
 	delete(s.groups, name)
+	s.tags.Delete(g.arn)
 	s.mu.Unlock()
 
 	resp := deleteAutoScalingGroupResponse{RequestID: h.NewRequestID()}
@@ -319,6 +326,131 @@ func (s *Service) setDesiredCapacity(w http.ResponseWriter, r *http.Request) {
 	h.WriteXML(w, http.StatusOK, resp)
 }
 
+// groupTag is one member of the Tags list of a tagging request.
+type groupTag struct {
+	group     string
+	key       string
+	value     string
+	propagate bool
+}
+
+// formTags collects the Tags.member.N fields of a request.
+func formTags(r *http.Request) []groupTag {
+	var list []groupTag
+	for i := 1; ; i++ {
+		prefix := fmt.Sprintf("Tags.member.%d.", i)
+		key := r.FormValue(prefix + "Key")
+		if key == "" {
+			return list
+		}
+		list = append(list, groupTag{
+			group:     r.FormValue(prefix + "ResourceId"),
+			key:       key,
+			value:     r.FormValue(prefix + "Value"),
+			propagate: r.FormValue(prefix+"PropagateAtLaunch") == "true",
+		})
+	}
+}
+
+// applyTag records t on g. The caller must hold s.mu for writing.
+func (s *Service) applyTag(g *autoScalingGroup, t groupTag) {
+	s.tags.Tag(g.arn, map[string]string{t.key: t.value})
+	g.propagate[t.key] = t.propagate
+}
+
+// groupTags renders the tags of g, sorted by key.
+func (s *Service) groupTags(g *autoScalingGroup) []xmlTagDescription {
+	var list []xmlTagDescription
+	for _, t := range tags.ToList(s.tags.Get(g.arn), "Key", "Value") {
+		list = append(list, xmlTagDescription{
+			ResourceID:        g.name,
+			ResourceType:      "auto-scaling-group",
+			Key:               t["Key"],
+			Value:             t["Value"],
+			PropagateAtLaunch: g.propagate[t["Key"]],
+		})
+	}
+	return list
+}
+
+func (s *Service) createOrUpdateTags(w http.ResponseWriter, r *http.Request) {
+	list := formTags(r)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, t := range list {
+		if s.groups[t.group] == nil {
+			writeASError(w, "ValidationError", "AutoScalingGroup ["+t.group+"] not found", http.StatusBadRequest)
+			return
+		}
+	}
+	for _, t := range list {
+		s.applyTag(s.groups[t.group], t)
+	}
+	h.WriteXML(w, http.StatusOK, createOrUpdateTagsResponse{RequestID: h.NewRequestID()})
+}
+
+func (s *Service) deleteTags(w http.ResponseWriter, r *http.Request) {
+	list := formTags(r)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, t := range list {
+		if g := s.groups[t.group]; g != nil {
+			s.tags.Untag(g.arn, []string{t.key})
+			delete(g.propagate, t.key)
+		}
+	}
+	h.WriteXML(w, http.StatusOK, deleteTagsResponse{RequestID: h.NewRequestID()})
+}
+
+// describeTags lists group tags, filtered by the auto-scaling-group, key,
+// value and propagate-at-launch filters.
+func (s *Service) describeTags(w http.ResponseWriter, r *http.Request) {
+	filters := make(map[string][]string)
+	for i := 1; ; i++ {
+		prefix := fmt.Sprintf("Filters.member.%d.", i)
+		name := r.FormValue(prefix + "Name")
+		if name == "" {
+			break
+		}
+		for j := 1; ; j++ {
+			v := r.FormValue(fmt.Sprintf("%sValues.member.%d", prefix, j))
+			if v == "" {
+				break
+			}
+			filters[name] = append(filters[name], v)
+		}
+	}
+	match := func(name, v string) bool {
+		values, ok := filters[name]
+		return !ok || contains(values, v)
+	}
+
+	s.mu.RLock()
+	var list []xmlTagDescription
+	for _, g := range s.groups {
+		for _, t := range s.groupTags(g) {
+			if match("auto-scaling-group", t.ResourceID) && match("key", t.Key) && match("value", t.Value) &&
+				match("propagate-at-launch", strconv.FormatBool(t.PropagateAtLaunch)) {
+				list = append(list, t)
+			}
+		}
+	}
+	s.mu.RUnlock()
+
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].ResourceID != list[j].ResourceID {
+			return list[i].ResourceID < list[j].ResourceID
+		}
+		return list[i].Key < list[j].Key
+	})
+	h.WriteXML(w, http.StatusOK, describeTagsResponse{
+		Result:    describeTagsResult{Tags: list},
+		RequestID: h.NewRequestID(),
+	})
+}
+
 // Helpers.
 
 func parseIntParam(r *http.Request, key string, defaultVal int) int {
@@ -342,7 +474,8 @@ func contains(list []string, s string) bool {
 	return false
 }
 
-func groupToXML(g *autoScalingGroup) xmlAutoScalingGroup {
+// groupToXML renders a group along with its tags.
+func (s *Service) groupToXML(g *autoScalingGroup) xmlAutoScalingGroup {
 	return xmlAutoScalingGroup{
 		Name:                    g.name,
 		Arn:                     g.arn,
@@ -350,6 +483,7 @@ func groupToXML(g *autoScalingGroup) xmlAutoScalingGroup {
 		MaxSize:                 g.maxSize,
 		DesiredCapacity:         g.desiredCapacity,
 		LaunchConfigurationName: g.launchConfigurationName,
+		Tags:                    s.groupTags(g),
 	}
 }
 
@@ -365,12 +499,21 @@ func launchConfigToXML(lc *launchConfiguration) xmlLaunchConfiguration {
 // XML types.
 
 type xmlAutoScalingGroup struct {
-	Name                    string `xml:"AutoScalingGroupName"`
-	Arn                     string `xml:"AutoScalingGroupARN"`
-	MinSize                 int    `xml:"MinSize"`
-	MaxSize                 int    `xml:"MaxSize"`
-	DesiredCapacity         int    `xml:"DesiredCapacity"`
-	LaunchConfigurationName string `xml:"LaunchConfigurationName"`
+	Name                    string              `xml:"AutoScalingGroupName"`
+	Arn                     string              `xml:"AutoScalingGroupARN"`
+	MinSize                 int                 `xml:"MinSize"`
+	MaxSize                 int                 `xml:"MaxSize"`
+	DesiredCapacity         int                 `xml:"DesiredCapacity"`
+	LaunchConfigurationName string              `xml:"LaunchConfigurationName"`
+	Tags                    []xmlTagDescription `xml:"Tags>member"`
+}
+
+type xmlTagDescription struct {
+	ResourceID        string `xml:"ResourceId"`
+	ResourceType      string `xml:"ResourceType"`
+	Key               string `xml:"Key"`
+	Value             string `xml:"Value"`
+	PropagateAtLaunch bool   `xml:"PropagateAtLaunch"`
 }
 
 type xmlLaunchConfiguration struct {
@@ -428,6 +571,25 @@ type setDesiredCapacityResponse struct {
 	RequestID string   `xml:"ResponseMetadata>RequestId"`
 }
 
+type createOrUpdateTagsResponse struct {
+	XMLName   xml.Name `xml:"CreateOrUpdateTagsResponse"`
+	RequestID string   `xml:"ResponseMetadata>RequestId"`
+}
+
+type deleteTagsResponse struct {
+	XMLName   xml.Name `xml:"DeleteTagsResponse"`
+	RequestID string   `xml:"ResponseMetadata>RequestId"`
+}
+
+type describeTagsResponse struct {
+	XMLName   xml.Name           `xml:"DescribeTagsResponse"`
+	Result    describeTagsResult `xml:"DescribeTagsResult"`
+	RequestID string             `xml:"ResponseMetadata>RequestId"`
+}
+type describeTagsResult struct {
+	Tags []xmlTagDescription `xml:"Tags>member"`
+}
+
 func writeASError(w http.ResponseWriter, code, message string, status int) {
 	h.WriteXMLError(w, "Sender", code, message, status)
 }
@@ -9,6 +9,9 @@
 //   - GetBackupPlan
 //   - DeleteBackupPlan
 //   - ListBackupPlans
+//   - TagResource
+//   - UntagResource
+//   - ListTags
 package backup
 
 import (
@@ -22,6 +25,7 @@ import (
 	"time"
 
 	h "github.com/riyanimam/goto/internal/mockhelpers"
+	"github.com/riyanimam/goto/internal/tags"
 )
 
 // Service implements the Backup mock.
@@ -29,6 +33,7 @@ type Service struct {
 	mu     sync.RWMutex
 	vaults map[string]*backupVault
 	plans  map[string]*backupPlan
+	tags   *tags.Store
 }
 
 type backupVault struct {
@@ -36,7 +41,6 @@ type backupVault struct {
 	arn                    string
 	created                time.Time
 	numberOfRecoveryPoints int64
-	tags                   map[string]string
 }
 
 type backupPlan struct {
@@ -53,6 +57,7 @@ func New() *Service {
 	return &Service{
 		vaults: make(map[string]*backupVault),
 		plans:  make(map[string]*backupPlan),
+		tags:   tags.New(),
 	}
 }
 
@@ -70,6 +75,14 @@ func (s *Service) Reset() {
 	defer s.mu.Unlock()
 	s.vaults = make(map[string]*backupVault)
 	s.plans = make(map[string]*backupPlan)
+	s.tags.DeleteService("backup")
+}
+
+// SetTagStore sets the registry resource tags are recorded in.
+func (s *Service) SetTagStore(store *tags.Store) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tags = store
 }
 
 func (s *Service) handle(w http.ResponseWriter, r *http.Request) {
@@ -78,6 +91,14 @@ func (s *Service) handle(w http.ResponseWriter, r *http.Request) {
 	parts := strings.Split(strings.TrimPrefix(path, "/"), "/")
 
 	switch {
+	// Tags: /tags/{arn}, /untag/{arn}
+	case strings.HasPrefix(path, "/tags/") && method == http.MethodPost:
+		s.tagResource(w, r, strings.TrimPrefix(path, "/tags/"))
+	case strings.HasPrefix(path, "/tags/") && method == http.MethodGet:
+		s.listTags(w, strings.TrimSuffix(strings.TrimPrefix(path, "/tags/"), "/"))
+	case strings.HasPrefix(path, "/untag/") && method == http.MethodPost:
+		s.untagResource(w, r, strings.TrimPrefix(path, "/untag/"))
+
 	// Backup vaults: /backup-vaults/{name}
 	case len(parts) == 2 && parts[0] == "backup-vaults" && parts[1] != "" && method == http.MethodPut:
 		s.createBackupVault(w, r, parts[1])
@@ -112,15 +133,6 @@ func (s *Service) createBackupVault(w http.ResponseWriter, r *http.Request, name
 	var params map[string]interface{}
 	json.Unmarshal(bodyBytes, &params)
 
-	tags := make(map[string]string)
-	if t, ok := params["BackupVaultTags"].(map[string]interface{}); ok {
-		for k, v := range t {
-			if str, ok := v.(string); ok {
-				tags[k] = str
-			}
-		}
-	}
-
 	s.mu.Lock()
 	if _, exists := s.vaults[name]; exists {
 		s.mu.Unlock()
@@ -136,9 +148,9 @@ func (s *Service) createBackupVault(w http.ResponseWriter, r *http.Request, name
 		arn:                    arn,
 		created:                now,
 		numberOfRecoveryPoints: 0,
-		tags:                   tags,
 	}
 	s.vaults[name] = v
+	s.tags.Tag(arn, tags.FromMap(params["BackupVaultTags"]))
 	s.mu.Unlock()
 
 	h.WriteJSON(w, http.StatusOK, vaultResp(v))
@@ -146,12 +158,13 @@ func (s *Service) createBackupVault(w http.ResponseWriter, r *http.Request, name
 
 func (s *Service) deleteBackupVault(w http.ResponseWriter, name string) {
 	s.mu.Lock()
-	_, exists := s.vaults[name]
+	v, exists := s.vaults[name]
 	if !exists {
 		s.mu.Unlock()
 		h.WriteJSONError(w, "ResourceNotFoundException", "Backup vault "+name+" not found", http.StatusNotFound)
 		return
 	}
+	s.tags.Delete(v.arn)
 	delete(s.vaults, name)
 	s.mu.Unlock()
 
@@ -222,6 +235,7 @@ func (s *Service) createBackupPlan(w http.ResponseWriter, r *http.Request) {
 
 	s.mu.Lock()
 	s.plans[planID] = p
+	s.tags.Tag(arn, tags.FromMap(params["BackupPlanTags"]))
 	s.mu.Unlock()
 
 	h.WriteJSON(w, http.StatusOK, map[string]interface{}{
@@ -259,6 +273,7 @@ func (s *Service) deleteBackupPlan(w http.ResponseWriter, planID string) {
 		"VersionId":     p.versionId,
 		"DeletionDate":  float64(time.Now().UTC().Unix()),
 	}
+	s.tags.Delete(p.arn)
 	delete(s.plans, planID)
 	s.mu.Unlock()
 
@@ -288,7 +303,6 @@ func vaultResp(v *backupVault) map[string]interface{} {
 		"BackupVaultArn":         v.arn,
 		"CreationDate":           float64(v.created.Unix()),
 		"NumberOfRecoveryPoints": v.numberOfRecoveryPoints,
-		"BackupVaultTags":        v.tags,
 	}
 }
 
@@ -305,3 +319,61 @@ func planResp(p *backupPlan) map[string]interface{} {
 		},
 	}
 }
+
+// hasResource reports whether arn names an existing backup vault or plan.
+// The caller must hold s.mu.
+func (s *Service) hasResource(arn string) bool {
+	for _, v := range s.vaults {
+		if v.arn == arn {
+			return true
+		}
+	}
+	for _, p := range s.plans {
+		if p.arn == arn {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Service) tagResource(w http.ResponseWriter, r *http.Request, arn string) {
+	bodyBytes, _ := io.ReadAll(r.Body)
+	var params map[string]interface{}
+	json.Unmarshal(bodyBytes, &params)
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if !s.hasResource(arn) {
+		h.WriteJSONError(w, "ResourceNotFoundException", "Resource "+arn+" not found", http.StatusNotFound)
+		return
+	}
+	s.tags.Tag(arn, tags.FromMap(params["Tags"]))
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{})
+}
+
+func (s *Service) untagResource(w http.ResponseWriter, r *http.Request, arn string) {
+	bodyBytes, _ := io.ReadAll(r.Body)
+	var params map[string]interface{}
+	json.Unmarshal(bodyBytes, &params)
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if !s.hasResource(arn) {
+		h.WriteJSONError(w, "ResourceNotFoundException", "Resource "+arn+" not found", http.StatusNotFound)
+		return
+	}
+	s.tags.Untag(arn, tags.Keys(params["TagKeyList"]))
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{})
+}
+
+func (s *Service) listTags(w http.ResponseWriter, arn string) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if !s.hasResource(arn) {
+		h.WriteJSONError(w, "ResourceNotFoundException", "Resource "+arn+" not found", http.StatusNotFound)
+		return
+	}
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"Tags": s.tags.Get(arn),
+	})
+}
@@ -7,8 +7,22 @@
 //   - CreateJobQueue
 //   - DescribeJobQueues
 //   - DeleteJobQueue
+//   - RegisterJobDefinition
+//   - DescribeJobDefinitions
+//   - DeregisterJobDefinition
 //   - SubmitJob
 //   - DescribeJobs
+//   - ListJobs
+//   - TerminateJob
+//   - CancelJob
+//   - TagResource
+//   - UntagResource
+//   - ListTagsForResource
+//
+// Submitted jobs move through SUBMITTED, RUNNABLE, and RUNNING to SUCCEEDED.
+// Without [lifecycle.Transitions] they have succeeded at once; with them,
+// each status lasts for the transition delay. Array jobs create one child
+// job per index, which progress alongside their parent.
 package batch
 
 import (
@@ -18,9 +32,10 @@ import (
 	"net/http"
 	"strings"
 	"sync"
-	"time"
 
+	"github.com/riyanimam/goto/internal/lifecycle"
 	h "github.com/riyanimam/goto/internal/mockhelpers"
+	"github.com/riyanimam/goto/internal/tags"
 )
 
 // Service implements the AWS Batch mock.
@@ -28,7 +43,10 @@ type Service struct {
 	mu          sync.RWMutex
 	computeEnvs map[string]*computeEnvironment
 	jobQueues   map[string]*jobQueue
+	jobDefs     map[string][]*jobDefinition // revisions by name
 	jobs        map[string]*job
+	tags        *tags.Store
+	transitions *lifecycle.Transitions
 }
 
 type computeEnvironment struct {
@@ -40,21 +58,12 @@ type computeEnvironment struct {
 }
 
 type jobQueue struct {
-	name     string
-	arn      string
-	state    string
-	priority int
-	status   string
-}
-
-type job struct {
-	id         string
-	name       string
-	arn        string
-	queue      string
-	definition string
-	status     string
-	createdAt  time.Time
+	name         string
+	arn          string
+	state        string
+	priority     int
+	status       string
+	computeOrder interface{}
 }
 
 // New creates a new AWS Batch mock service.
@@ -62,7 +71,9 @@ func New() *Service {
 	return &Service{
 		computeEnvs: make(map[string]*computeEnvironment),
 		jobQueues:   make(map[string]*jobQueue),
+		jobDefs:     make(map[string][]*jobDefinition),
 		jobs:        make(map[string]*job),
+		tags:        tags.New(),
 	}
 }
 
@@ -80,12 +91,26 @@ func (s *Service) Reset() {
 	defer s.mu.Unlock()
 	s.computeEnvs = make(map[string]*computeEnvironment)
 	s.jobQueues = make(map[string]*jobQueue)
+	s.jobDefs = make(map[string][]*jobDefinition)
 	s.jobs = make(map[string]*job)
+	s.tags.DeleteService("batch")
+}
+
+// SetTagStore sets the registry resource tags are recorded in.
+func (s *Service) SetTagStore(store *tags.Store) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tags = store
 }
 
 func (s *Service) handle(w http.ResponseWriter, r *http.Request) {
 	path := r.URL.Path
 
+	if strings.Contains(path, "/v1/tags/") {
+		s.handleTags(w, r, path[strings.Index(path, "/v1/tags/")+len("/v1/tags/"):])
+		return
+	}
+
 	if r.Method != http.MethodPost {
 		h.WriteJSONError(w, "ClientException", "unsupported method", http.StatusBadRequest)
 		return
@@ -104,10 +129,22 @@ func (s *Service) handle(w http.ResponseWriter, r *http.Request) {
 		s.describeJobQueues(w, r)
 	case strings.HasSuffix(path, "/v1/deletejobqueue"):
 		s.deleteJobQueue(w, r)
+	case strings.HasSuffix(path, "/v1/registerjobdefinition"):
+		s.registerJobDefinition(w, r)
+	case strings.HasSuffix(path, "/v1/describejobdefinitions"):
+		s.describeJobDefinitions(w, r)
+	case strings.HasSuffix(path, "/v1/deregisterjobdefinition"):
+		s.deregisterJobDefinition(w, r)
 	case strings.HasSuffix(path, "/v1/submitjob"):
 		s.submitJob(w, r)
 	case strings.HasSuffix(path, "/v1/describejobs"):
 		s.describeJobs(w, r)
+	case strings.HasSuffix(path, "/v1/listjobs"):
+		s.listJobs(w, r)
+	case strings.HasSuffix(path, "/v1/terminatejob"):
+		s.terminateJob(w, r)
+	case strings.HasSuffix(path, "/v1/canceljob"):
+		s.cancelJob(w, r)
 	default:
 		h.WriteJSONError(w, "ClientException", "unsupported operation", http.StatusBadRequest)
 	}
@@ -160,6 +197,7 @@ func (s *Service) createComputeEnvironment(w http.ResponseWriter, r *http.Reques
 		state:  state,
 		status: "VALID",
 	}
+	s.tags.Tag(arn, tags.FromMap(params["tags"]))
 	s.mu.Unlock()
 
 	h.WriteJSON(w, http.StatusOK, map[string]interface{}{
@@ -222,6 +260,9 @@ func (s *Service) deleteComputeEnvironment(w http.ResponseWriter, r *http.Reques
 	}
 
 	s.mu.Lock()
+	if ce, ok := s.computeEnvs[name]; ok {
+		s.tags.Delete(ce.arn)
+	}
 	delete(s.computeEnvs, name)
 	s.mu.Unlock()
 
@@ -251,13 +292,20 @@ func (s *Service) createJobQueue(w http.ResponseWriter, r *http.Request) {
 	arn := fmt.Sprintf("arn:aws:batch:us-east-1:%s:job-queue/%s", h.DefaultAccountID, name)
 
 	s.mu.Lock()
+	if _, exists := s.jobQueues[name]; exists {
+		s.mu.Unlock()
+		h.WriteJSONError(w, "ClientException", "Object already exists", http.StatusBadRequest)
+		return
+	}
 	s.jobQueues[name] = &jobQueue{
-		name:     name,
-		arn:      arn,
-		state:    state,
-		priority: priority,
-		status:   "VALID",
+		name:         name,
+		arn:          arn,
+		state:        state,
+		priority:     priority,
+		status:       "VALID",
+		computeOrder: params["computeEnvironmentOrder"],
 	}
+	s.tags.Tag(arn, tags.FromMap(params["tags"]))
 	s.mu.Unlock()
 
 	h.WriteJSON(w, http.StatusOK, map[string]interface{}{
@@ -278,8 +326,8 @@ func (s *Service) describeJobQueues(w http.ResponseWriter, r *http.Request) {
 
 	if names, ok := params["jobQueues"].([]interface{}); ok && len(names) > 0 {
 		for _, n := range names {
-			name, _ := n.(string)
-			if jq, exists := s.jobQueues[name]; exists {
+			ref, _ := n.(string)
+			if jq := s.findJobQueue(ref); jq != nil {
 				queues = append(queues, jqToMap(jq))
 			}
 		}
@@ -296,13 +344,17 @@ func (s *Service) describeJobQueues(w http.ResponseWriter, r *http.Request) {
 }
 
 func jqToMap(jq *jobQueue) map[string]interface{} {
-	return map[string]interface{}{
+	m := map[string]interface{}{
 		"jobQueueName": jq.name,
 		"jobQueueArn":  jq.arn,
 		"state":        jq.state,
 		"priority":     jq.priority,
 		"status":       jq.status,
 	}
+	if jq.computeOrder != nil {
+		m["computeEnvironmentOrder"] = jq.computeOrder
+	}
+	return m
 }
 
 func (s *Service) deleteJobQueue(w http.ResponseWriter, r *http.Request) {
@@ -319,82 +371,66 @@ func (s *Service) deleteJobQueue(w http.ResponseWriter, r *http.Request) {
 	}
 
 	s.mu.Lock()
+	if q, ok := s.jobQueues[name]; ok {
+		s.tags.Delete(q.arn)
+	}
 	delete(s.jobQueues, name)
 	s.mu.Unlock()
 
 	h.WriteJSON(w, http.StatusOK, map[string]interface{}{})
 }
 
-func (s *Service) submitJob(w http.ResponseWriter, r *http.Request) {
-	params, err := readBody(r)
-	if err != nil {
-		h.WriteJSONError(w, "ClientException", "invalid request body", http.StatusBadRequest)
-		return
+// hasResource reports whether arn names an existing resource.
+// The caller must hold s.mu.
+func (s *Service) hasResource(arn string) bool {
+	for _, ce := range s.computeEnvs {
+		if ce.arn == arn {
+			return true
+		}
 	}
-
-	jobName := h.GetString(params, "jobName")
-	if jobName == "" {
-		h.WriteJSONError(w, "ClientException", "jobName is required", http.StatusBadRequest)
-		return
+	for _, q := range s.jobQueues {
+		if q.arn == arn {
+			return true
+		}
 	}
-
-	jobQueue := h.GetString(params, "jobQueue")
-	jobDefinition := h.GetString(params, "jobDefinition")
-
-	jobID := h.NewRequestID()
-	arn := fmt.Sprintf("arn:aws:batch:us-east-1:%s:job/%s", h.DefaultAccountID, jobID)
-
-	s.mu.Lock()
-	s.jobs[jobID] = &job{
-		id:         jobID,
-		name:       jobName,
-		arn:        arn,
-		queue:      jobQueue,
-		definition: jobDefinition,
-		status:     "SUBMITTED",
-		createdAt:  time.Now().UTC(),
+	for _, revisions := range s.jobDefs {
+		for _, def := range revisions {
+			if def.arn == arn {
+				return true
+			}
+		}
 	}
-	s.mu.Unlock()
-
-	h.WriteJSON(w, http.StatusOK, map[string]interface{}{
-		"jobId":   jobID,
-		"jobName": jobName,
-	})
-}
-
-func (s *Service) describeJobs(w http.ResponseWriter, r *http.Request) {
-	params, err := readBody(r)
-	if err != nil {
-		h.WriteJSONError(w, "ClientException", "invalid request body", http.StatusBadRequest)
-		return
+	for _, j := range s.jobs {
+		if j.arn == arn {
+			return true
+		}
 	}
+	return false
+}
 
+// handleTags serves TagResource (POST), UntagResource (DELETE), and
+// ListTagsForResource (GET) on /v1/tags/{arn}.
+func (s *Service) handleTags(w http.ResponseWriter, r *http.Request, arn string) {
 	s.mu.RLock()
-	var jobs []map[string]interface{}
-
-	if ids, ok := params["jobs"].([]interface{}); ok {
-		for _, id := range ids {
-			jobID, _ := id.(string)
-			if j, exists := s.jobs[jobID]; exists {
-				jobs = append(jobs, jobToMap(j))
-			}
-		}
+	defer s.mu.RUnlock()
+	if !s.hasResource(arn) {
+		h.WriteJSONError(w, "ClientException", "Resource not found: "+arn, http.StatusNotFound)
+		return
 	}
-	s.mu.RUnlock()
 
-	h.WriteJSON(w, http.StatusOK, map[string]interface{}{
-		"jobs": jobs,
-	})
-}
-
-func jobToMap(j *job) map[string]interface{} {
-	return map[string]interface{}{
-		"jobId":         j.id,
-		"jobName":       j.name,
-		"jobArn":        j.arn,
-		"jobQueue":      j.queue,
-		"jobDefinition": j.definition,
-		"status":        j.status,
-		"createdAt":     j.createdAt.Unix(),
+	switch r.Method {
+	case http.MethodPost:
+		var params map[string]interface{}
+		bodyBytes, _ := io.ReadAll(r.Body)
+		json.Unmarshal(bodyBytes, &params)
+		s.tags.Tag(arn, tags.FromMap(params["tags"]))
+		h.WriteJSON(w, http.StatusOK, map[string]interface{}{})
+	case http.MethodDelete:
+		s.tags.Untag(arn, r.URL.Query()["tagKeys"])
+		h.WriteJSON(w, http.StatusOK, map[string]interface{}{})
+	default:
+		h.WriteJSON(w, http.StatusOK, map[string]interface{}{
+			"tags": s.tags.Get(arn),
+		})
 	}
 }
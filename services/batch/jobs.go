@@ -0,0 +1,500 @@
+package batch
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/riyanimam/goto/internal/lifecycle"
+	h "github.com/riyanimam/goto/internal/mockhelpers"
+	"github.com/riyanimam/goto/internal/tags"
+)
+
+// jobStatuses are the statuses a job passes through on its way to
+// completion.
+var jobStatuses = []string{"SUBMITTED", "RUNNABLE", "RUNNING", "SUCCEEDED"}
+
+type jobDefinition struct {
+	name                 string
+	arn                  string
+	revision             int
+	defType              string
+	status               string
+	parameters           interface{}
+	containerProperties  interface{}
+	retryStrategy        interface{}
+	timeout              interface{}
+	platformCapabilities interface{}
+}
+
+type job struct {
+	id           string
+	name         string
+	arn          string
+	queue        string
+	definition   string
+	ready        lifecycle.Transition
+	stopped      string // FAILED once terminated or cancelled, else ""
+	statusReason string
+	createdAt    time.Time
+	arraySize    int      // number of children of an array job
+	children     []string // IDs of the children of an array job
+	arrayIndex   int      // index of an array job child, else -1
+}
+
+// SetTransitions sets how long submitted jobs take to move through each of
+// SUBMITTED, RUNNABLE, and RUNNING before they have SUCCEEDED.
+func (s *Service) SetTransitions(t *lifecycle.Transitions) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.transitions = t
+}
+
+func (s *Service) registerJobDefinition(w http.ResponseWriter, r *http.Request) {
+	params, err := readBody(r)
+	if err != nil {
+		h.WriteJSONError(w, "ClientException", "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	name := h.GetString(params, "jobDefinitionName")
+	if name == "" {
+		h.WriteJSONError(w, "ClientException", "jobDefinitionName is required", http.StatusBadRequest)
+		return
+	}
+	defType := h.GetString(params, "type")
+	if defType != "container" && defType != "multinode" {
+		h.WriteJSONError(w, "ClientException", "type must be container or multinode", http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	revision := len(s.jobDefs[name]) + 1
+	def := &jobDefinition{
+		name:                 name,
+		arn:                  fmt.Sprintf("arn:aws:batch:us-east-1:%s:job-definition/%s:%d", h.DefaultAccountID, name, revision),
+		revision:             revision,
+		defType:              defType,
+		status:               "ACTIVE",
+		parameters:           params["parameters"],
+		containerProperties:  params["containerProperties"],
+		retryStrategy:        params["retryStrategy"],
+		timeout:              params["timeout"],
+		platformCapabilities: params["platformCapabilities"],
+	}
+	s.jobDefs[name] = append(s.jobDefs[name], def)
+	s.tags.Tag(def.arn, tags.FromMap(params["tags"]))
+	s.mu.Unlock()
+
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"jobDefinitionName": name,
+		"jobDefinitionArn":  def.arn,
+		"revision":          revision,
+	})
+}
+
+func (s *Service) describeJobDefinitions(w http.ResponseWriter, r *http.Request) {
+	params, err := readBody(r)
+	if err != nil {
+		h.WriteJSONError(w, "ClientException", "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	var refs []string
+	if list, ok := params["jobDefinitions"].([]interface{}); ok {
+		for _, v := range list {
+			ref, _ := v.(string)
+			refs = append(refs, ref)
+		}
+	}
+	name := h.GetString(params, "jobDefinitionName")
+	status := h.GetString(params, "status")
+
+	s.mu.RLock()
+	var defs []map[string]interface{}
+	for _, revisions := range s.jobDefs {
+		for _, def := range revisions {
+			if name != "" && def.name != name || status != "" && def.status != status {
+				continue
+			}
+			if len(refs) > 0 && !def.matchesAny(refs) {
+				continue
+			}
+			defs = append(defs, jobDefToMap(def, s.tags.Get(def.arn)))
+		}
+	}
+	s.mu.RUnlock()
+
+	sort.Slice(defs, func(i, j int) bool {
+		if defs[i]["jobDefinitionName"] != defs[j]["jobDefinitionName"] {
+			return defs[i]["jobDefinitionName"].(string) < defs[j]["jobDefinitionName"].(string)
+		}
+		return defs[i]["revision"].(int) < defs[j]["revision"].(int)
+	})
+
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"jobDefinitions": defs,
+	})
+}
+
+// matchesAny reports whether def is named by any of refs, each an ARN, a
+// name:revision, or a bare name matching every revision.
+func (def *jobDefinition) matchesAny(refs []string) bool {
+	for _, ref := range refs {
+		if ref == def.arn || ref == def.name || ref == def.name+":"+strconv.Itoa(def.revision) {
+			return true
+		}
+	}
+	return false
+}
+
+func jobDefToMap(def *jobDefinition, tagMap map[string]string) map[string]interface{} {
+	m := map[string]interface{}{
+		"jobDefinitionName": def.name,
+		"jobDefinitionArn":  def.arn,
+		"revision":          def.revision,
+		"type":              def.defType,
+		"status":            def.status,
+		"tags":              tagMap,
+	}
+	for key, v := range map[string]interface{}{
+		"parameters":           def.parameters,
+		"containerProperties":  def.containerProperties,
+		"retryStrategy":        def.retryStrategy,
+		"timeout":              def.timeout,
+		"platformCapabilities": def.platformCapabilities,
+	} {
+		if v != nil {
+			m[key] = v
+		}
+	}
+	return m
+}
+
+func (s *Service) deregisterJobDefinition(w http.ResponseWriter, r *http.Request) {
+	params, err := readBody(r)
+	if err != nil {
+		h.WriteJSONError(w, "ClientException", "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	ref := h.GetString(params, "jobDefinition")
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	def := s.findJobDefinition(ref)
+	if def == nil || !strings.Contains(ref, ":") {
+		h.WriteJSONError(w, "ClientException", "Job definition "+ref+" does not exist", http.StatusBadRequest)
+		return
+	}
+	def.status = "INACTIVE"
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{})
+}
+
+// findJobDefinition looks up a job definition by ARN, by name:revision, or by
+// name alone for its latest active revision. The caller must hold s.mu.
+func (s *Service) findJobDefinition(ref string) *jobDefinition {
+	if strings.HasPrefix(ref, "arn:") {
+		for _, revisions := range s.jobDefs {
+			for _, def := range revisions {
+				if def.arn == ref {
+					return def
+				}
+			}
+		}
+		return nil
+	}
+	name, rev, hasRev := strings.Cut(ref, ":")
+	revisions := s.jobDefs[name]
+	if hasRev {
+		n, err := strconv.Atoi(rev)
+		if err != nil || n < 1 || n > len(revisions) {
+			return nil
+		}
+		return revisions[n-1]
+	}
+	for i := len(revisions) - 1; i >= 0; i-- {
+		if revisions[i].status == "ACTIVE" {
+			return revisions[i]
+		}
+	}
+	return nil
+}
+
+// findJobQueue looks up a job queue by name or ARN. The caller must hold
+// s.mu.
+func (s *Service) findJobQueue(ref string) *jobQueue {
+	if jq, ok := s.jobQueues[ref]; ok {
+		return jq
+	}
+	for _, jq := range s.jobQueues {
+		if jq.arn == ref {
+			return jq
+		}
+	}
+	return nil
+}
+
+func (s *Service) submitJob(w http.ResponseWriter, r *http.Request) {
+	params, err := readBody(r)
+	if err != nil {
+		h.WriteJSONError(w, "ClientException", "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	jobName := h.GetString(params, "jobName")
+	if jobName == "" {
+		h.WriteJSONError(w, "ClientException", "jobName is required", http.StatusBadRequest)
+		return
+	}
+	arraySize := 0
+	if props, ok := params["arrayProperties"].(map[string]interface{}); ok {
+		arraySize = h.GetInt(props, "size", 0)
+		if arraySize < 2 || arraySize > 10000 {
+			h.WriteJSONError(w, "ClientException", "Array job size must be between 2 and 10000", http.StatusBadRequest)
+			return
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	queueRef := h.GetString(params, "jobQueue")
+	jq := s.findJobQueue(queueRef)
+	if jq == nil {
+		h.WriteJSONError(w, "ClientException", "Job queue "+queueRef+" does not exist", http.StatusBadRequest)
+		return
+	}
+	if jq.state != "ENABLED" {
+		h.WriteJSONError(w, "ClientException", "Job queue "+jq.name+" is not enabled", http.StatusBadRequest)
+		return
+	}
+	defRef := h.GetString(params, "jobDefinition")
+	def := s.findJobDefinition(defRef)
+	if def == nil || def.status != "ACTIVE" {
+		h.WriteJSONError(w, "ClientException", "Job definition "+defRef+" does not exist", http.StatusBadRequest)
+		return
+	}
+
+	jobID := h.NewRequestID()
+	arn := fmt.Sprintf("arn:aws:batch:us-east-1:%s:job/%s", h.DefaultAccountID, jobID)
+	parent := &job{
+		id:         jobID,
+		name:       jobName,
+		arn:        arn,
+		queue:      jq.arn,
+		definition: def.arn,
+		ready:      s.transitions.Begin(),
+		createdAt:  time.Now().UTC(),
+		arraySize:  arraySize,
+		arrayIndex: -1,
+	}
+	for i := 0; i < arraySize; i++ {
+		child := *parent
+		child.id = fmt.Sprintf("%s:%d", jobID, i)
+		child.arn = fmt.Sprintf("%s:%d", arn, i)
+		child.arraySize = 0
+		child.children = nil
+		child.arrayIndex = i
+		s.jobs[child.id] = &child
+		parent.children = append(parent.children, child.id)
+	}
+	s.jobs[jobID] = parent
+	s.tags.Tag(arn, tags.FromMap(params["tags"]))
+
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"jobId":   jobID,
+		"jobName": jobName,
+		"jobArn":  arn,
+	})
+}
+
+// jobStatus returns the current status of j. An array job has FAILED once
+// it has finished if any of its children failed. The caller must hold s.mu.
+func (s *Service) jobStatus(j *job) string {
+	if j.stopped != "" {
+		return j.stopped
+	}
+	status := s.transitions.Stage(j.ready, jobStatuses...)
+	if status == "SUCCEEDED" {
+		for _, id := range j.children {
+			if s.jobs[id].stopped != "" {
+				return "FAILED"
+			}
+		}
+	}
+	return status
+}
+
+func (s *Service) describeJobs(w http.ResponseWriter, r *http.Request) {
+	params, err := readBody(r)
+	if err != nil {
+		h.WriteJSONError(w, "ClientException", "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	s.mu.RLock()
+	var jobs []map[string]interface{}
+
+	if ids, ok := params["jobs"].([]interface{}); ok {
+		for _, id := range ids {
+			jobID, _ := id.(string)
+			if j, exists := s.jobs[jobID]; exists {
+				jobs = append(jobs, s.jobToMap(j))
+			}
+		}
+	}
+	s.mu.RUnlock()
+
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"jobs": jobs,
+	})
+}
+
+// jobToMap describes j. The caller must hold s.mu.
+func (s *Service) jobToMap(j *job) map[string]interface{} {
+	m := s.jobSummary(j)
+	m["jobQueue"] = j.queue
+	m["jobDefinition"] = j.definition
+	m["tags"] = s.tags.Get(j.arn)
+	if j.arraySize > 0 {
+		summary := make(map[string]int)
+		for _, id := range j.children {
+			summary[s.jobStatus(s.jobs[id])]++
+		}
+		m["arrayProperties"].(map[string]interface{})["statusSummary"] = summary
+	}
+	return m
+}
+
+// jobSummary returns the fields of j that ListJobs reports. The caller must
+// hold s.mu.
+func (s *Service) jobSummary(j *job) map[string]interface{} {
+	m := map[string]interface{}{
+		"jobId":     j.id,
+		"jobName":   j.name,
+		"jobArn":    j.arn,
+		"status":    s.jobStatus(j),
+		"createdAt": j.createdAt.UnixMilli(),
+	}
+	if j.statusReason != "" {
+		m["statusReason"] = j.statusReason
+	}
+	switch {
+	case j.arraySize > 0:
+		m["arrayProperties"] = map[string]interface{}{"size": j.arraySize}
+	case j.arrayIndex >= 0:
+		m["arrayProperties"] = map[string]interface{}{"index": j.arrayIndex}
+	}
+	return m
+}
+
+func (s *Service) listJobs(w http.ResponseWriter, r *http.Request) {
+	params, err := readBody(r)
+	if err != nil {
+		h.WriteJSONError(w, "ClientException", "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	queueRef := h.GetString(params, "jobQueue")
+	arrayJobID := h.GetString(params, "arrayJobId")
+	if queueRef == "" && arrayJobID == "" {
+		h.WriteJSONError(w, "ClientException", "jobQueue or arrayJobId is required", http.StatusBadRequest)
+		return
+	}
+	// Like AWS, only running jobs are listed unless another status is asked
+	// for.
+	status := h.GetString(params, "jobStatus")
+	if status == "" {
+		status = "RUNNING"
+	}
+
+	s.mu.RLock()
+	var candidates []*job
+	if arrayJobID != "" {
+		if parent, ok := s.jobs[arrayJobID]; ok {
+			for _, id := range parent.children {
+				candidates = append(candidates, s.jobs[id])
+			}
+		}
+	} else if jq := s.findJobQueue(queueRef); jq != nil {
+		for _, j := range s.jobs {
+			if j.queue == jq.arn && j.arrayIndex < 0 {
+				candidates = append(candidates, j)
+			}
+		}
+	}
+	summaries := []map[string]interface{}{}
+	for _, j := range candidates {
+		if s.jobStatus(j) == status {
+			summaries = append(summaries, s.jobSummary(j))
+		}
+	}
+	s.mu.RUnlock()
+
+	sort.Slice(summaries, func(i, j int) bool {
+		return summaries[i]["jobId"].(string) < summaries[j]["jobId"].(string)
+	})
+
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"jobSummaryList": summaries,
+	})
+}
+
+func (s *Service) terminateJob(w http.ResponseWriter, r *http.Request) {
+	s.stopJob(w, r, "SUCCEEDED")
+}
+
+// cancelJob stops jobs that have not started running yet.
+func (s *Service) cancelJob(w http.ResponseWriter, r *http.Request) {
+	s.stopJob(w, r, "RUNNING", "SUCCEEDED")
+}
+
+// stopJob fails the job named in the request, and the children of an array
+// job, with the given reason. Jobs that have reached one of the statuses in
+// past have gone too far to be stopped and are left as they are.
+func (s *Service) stopJob(w http.ResponseWriter, r *http.Request, past ...string) {
+	params, err := readBody(r)
+	if err != nil {
+		h.WriteJSONError(w, "ClientException", "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	jobID := h.GetString(params, "jobId")
+	reason := h.GetString(params, "reason")
+	if reason == "" {
+		h.WriteJSONError(w, "ClientException", "reason is required", http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	j, ok := s.jobs[jobID]
+	if !ok {
+		h.WriteJSONError(w, "ClientException", "Job "+jobID+" does not exist", http.StatusBadRequest)
+		return
+	}
+	for _, id := range append([]string{jobID}, j.children...) {
+		s.stop(s.jobs[id], reason, past)
+	}
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{})
+}
+
+// stop fails j with reason unless it has already failed or reached one of
+// the statuses in past. The caller must hold s.mu.
+func (s *Service) stop(j *job, reason string, past []string) {
+	if j.stopped != "" {
+		return
+	}
+	status := s.transitions.Stage(j.ready, jobStatuses...)
+	for _, p := range past {
+		if status == p {
+			return
+		}
+	}
+	j.stopped = "FAILED"
+	j.statusReason = reason
+}
@@ -0,0 +1,241 @@
+// Package bedrock provides a mock implementation of Amazon Bedrock and the
+// Bedrock Runtime.
+//
+// Supported actions:
+//   - ListFoundationModels
+//   - GetFoundationModel
+//   - InvokeModel (Bedrock Runtime)
+//   - InvokeModelWithResponseStream (Bedrock Runtime)
+//   - Converse (Bedrock Runtime)
+//
+// The runtime signs for the same "bedrock" scope as the control plane and is
+// served by the same service. No model runs: each invocation is answered by
+// the [ModelFunc] registered for its model ID with [Service.SetModel], or by
+// echoing the prompt back when none is. A response's text is rendered in the
+// native body format of the model's provider (Anthropic, Amazon Titan and
+// Nova, Meta Llama, and Mistral) for InvokeModel, streamed as one chunk per
+// word for InvokeModelWithResponseStream, and returned as the assistant
+// message for Converse. Token counts are word counts.
+package bedrock
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	h "github.com/riyanimam/goto/internal/mockhelpers"
+)
+
+// Request is a model invocation as a ModelFunc sees it.
+type Request struct {
+	ModelID   string
+	Operation string // InvokeModel, InvokeModelWithResponseStream, or Converse
+	Body      []byte // the raw request body
+	Prompt    string // the text of the last user message or prompt
+}
+
+// Response is a model's answer to a Request. Text is rendered in the
+// format the operation and model call for; Body, when set, is returned from
+// InvokeModel as is instead, for models whose format the mock does not
+// render. StopReason uses the Converse vocabulary and defaults to
+// "end_turn".
+type Response struct {
+	Text       string
+	Body       []byte
+	StopReason string
+}
+
+// ModelFunc stands in for a model. A non-nil error fails the invocation
+// with a ModelErrorException carrying its text.
+type ModelFunc func(req Request) (Response, error)
+
+// Reply returns a ModelFunc that always answers with text.
+func Reply(text string) ModelFunc {
+	return func(Request) (Response, error) {
+		return Response{Text: text}, nil
+	}
+}
+
+// Service implements the Bedrock mock.
+type Service struct {
+	mu     sync.RWMutex
+	models map[string]ModelFunc // by model ID
+}
+
+// New creates a new Bedrock mock service.
+func New() *Service {
+	return &Service{models: make(map[string]ModelFunc)}
+}
+
+// Name returns the service identifier.
+func (s *Service) Name() string { return "bedrock" }
+
+// Handler returns the HTTP handler for Bedrock and Bedrock Runtime requests.
+func (s *Service) Handler() http.Handler {
+	return http.HandlerFunc(s.handle)
+}
+
+// Reset does nothing: the catalog is fixed, and registered models survive
+// like Lambda handlers.
+func (s *Service) Reset() {}
+
+// SetModel makes invocations of modelID, a foundation model or inference
+// profile ID or ARN, answer with fn. Model IDs with a ModelFunc are
+// accepted even when they are not in the foundation model catalog.
+func (s *Service) SetModel(modelID string, fn ModelFunc) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.models[modelID] = fn
+}
+
+// handle routes requests. Path segments are unescaped individually, since
+// model IDs such as ARNs arrive with escaped slashes.
+func (s *Service) handle(w http.ResponseWriter, r *http.Request) {
+	var parts []string
+	for _, seg := range strings.Split(strings.Trim(r.URL.EscapedPath(), "/"), "/") {
+		v, err := url.PathUnescape(seg)
+		if err != nil {
+			v = seg
+		}
+		parts = append(parts, v)
+	}
+	method := r.Method
+
+	switch {
+	case len(parts) == 1 && parts[0] == "foundation-models" && method == http.MethodGet:
+		s.listFoundationModels(w, r)
+	case len(parts) == 2 && parts[0] == "foundation-models" && method == http.MethodGet:
+		s.getFoundationModel(w, parts[1])
+	case len(parts) == 3 && parts[0] == "model" && parts[2] == "invoke" && method == http.MethodPost:
+		s.invokeModel(w, r, parts[1])
+	case len(parts) == 3 && parts[0] == "model" && parts[2] == "invoke-with-response-stream" && method == http.MethodPost:
+		s.invokeModelWithResponseStream(w, r, parts[1])
+	case len(parts) == 3 && parts[0] == "model" && parts[2] == "converse" && method == http.MethodPost:
+		s.converse(w, r, parts[1])
+	default:
+		h.WriteJSONError(w, "UnknownOperationException", "unsupported operation", http.StatusNotFound)
+	}
+}
+
+func writeValidation(w http.ResponseWriter, message string) {
+	h.WriteJSONError(w, "ValidationException", message, http.StatusBadRequest)
+}
+
+// --- Foundation models ---
+
+// foundationModel is an entry in the foundation model catalog.
+type foundationModel struct {
+	id        string
+	name      string
+	provider  string
+	input     []string
+	output    []string
+	streaming bool
+}
+
+// catalog lists the foundation models the mock knows.
+var catalog = []foundationModel{
+	{"anthropic.claude-3-5-sonnet-20240620-v1:0", "Claude 3.5 Sonnet", "Anthropic", []string{"TEXT", "IMAGE"}, []string{"TEXT"}, true},
+	{"anthropic.claude-3-5-haiku-20241022-v1:0", "Claude 3.5 Haiku", "Anthropic", []string{"TEXT"}, []string{"TEXT"}, true},
+	{"anthropic.claude-3-haiku-20240307-v1:0", "Claude 3 Haiku", "Anthropic", []string{"TEXT", "IMAGE"}, []string{"TEXT"}, true},
+	{"amazon.nova-pro-v1:0", "Nova Pro", "Amazon", []string{"TEXT", "IMAGE", "VIDEO"}, []string{"TEXT"}, true},
+	{"amazon.nova-lite-v1:0", "Nova Lite", "Amazon", []string{"TEXT", "IMAGE", "VIDEO"}, []string{"TEXT"}, true},
+	{"amazon.titan-text-express-v1", "Titan Text G1 - Express", "Amazon", []string{"TEXT"}, []string{"TEXT"}, true},
+	{"amazon.titan-embed-text-v2:0", "Titan Text Embeddings V2", "Amazon", []string{"TEXT"}, []string{"EMBEDDING"}, false},
+	{"meta.llama3-8b-instruct-v1:0", "Llama 3 8B Instruct", "Meta", []string{"TEXT"}, []string{"TEXT"}, true},
+	{"mistral.mistral-7b-instruct-v0:2", "Mistral 7B Instruct", "Mistral AI", []string{"TEXT"}, []string{"TEXT"}, true},
+	{"cohere.command-r-v1:0", "Command R", "Cohere", []string{"TEXT"}, []string{"TEXT"}, true},
+}
+
+func (m foundationModel) arn() string {
+	return "arn:aws:bedrock:us-east-1::foundation-model/" + m.id
+}
+
+func (m foundationModel) toMap() map[string]interface{} {
+	return map[string]interface{}{
+		"modelId":                    m.id,
+		"modelArn":                   m.arn(),
+		"modelName":                  m.name,
+		"providerName":               m.provider,
+		"inputModalities":            m.input,
+		"outputModalities":           m.output,
+		"responseStreamingSupported": m.streaming,
+		"customizationsSupported":    []string{},
+		"inferenceTypesSupported":    []string{"ON_DEMAND"},
+		"modelLifecycle":             map[string]string{"status": "ACTIVE"},
+	}
+}
+
+// foundationModelID returns the foundation model an ID invokes: the ID
+// itself, the model of a foundation model ARN, or the model of a
+// cross-region inference profile such as "us.anthropic.claude-...".
+func foundationModelID(modelID string) string {
+	if _, id, ok := strings.Cut(modelID, ":foundation-model/"); ok {
+		return id
+	}
+	if _, id, ok := strings.Cut(modelID, ":inference-profile/"); ok {
+		modelID = id
+	}
+	for _, prefix := range []string{"us.", "eu.", "apac.", "us-gov.", "global."} {
+		if id, ok := strings.CutPrefix(modelID, prefix); ok {
+			return id
+		}
+	}
+	return modelID
+}
+
+func lookupModel(id string) (foundationModel, bool) {
+	for _, m := range catalog {
+		if m.id == id {
+			return m, true
+		}
+	}
+	return foundationModel{}, false
+}
+
+func (s *Service) listFoundationModels(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	provider := q.Get("byProvider")
+	output := q.Get("byOutputModality")
+	inference := q.Get("byInferenceType")
+	if customization := q.Get("byCustomizationType"); customization != "" {
+		// No catalog model supports customization.
+		h.WriteJSON(w, http.StatusOK, map[string]interface{}{"modelSummaries": []interface{}{}})
+		return
+	}
+
+	summaries := []map[string]interface{}{}
+	for _, m := range catalog {
+		if provider != "" && !strings.EqualFold(provider, m.provider) {
+			continue
+		}
+		if output != "" && !contains(m.output, output) {
+			continue
+		}
+		if inference != "" && inference != "ON_DEMAND" {
+			continue
+		}
+		summaries = append(summaries, m.toMap())
+	}
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{"modelSummaries": summaries})
+}
+
+func (s *Service) getFoundationModel(w http.ResponseWriter, modelID string) {
+	m, ok := lookupModel(foundationModelID(modelID))
+	if !ok {
+		h.WriteJSONError(w, "ResourceNotFoundException", fmt.Sprintf("Could not find model %s.", modelID), http.StatusNotFound)
+		return
+	}
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{"modelDetails": m.toMap()})
+}
+
+func contains(list []string, v string) bool {
+	for _, item := range list {
+		if item == v {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,224 @@
+package bedrock
+
+import (
+	"strings"
+
+	h "github.com/riyanimam/goto/internal/mockhelpers"
+)
+
+// modelFamily returns the body format of a foundation model's provider, or
+// "" for models the mock cannot render text for.
+func modelFamily(modelID string) string {
+	switch {
+	case strings.HasPrefix(modelID, "anthropic."):
+		return "anthropic"
+	case strings.HasPrefix(modelID, "amazon.nova-"):
+		return "nova"
+	case strings.HasPrefix(modelID, "amazon.titan-text-"):
+		return "titan"
+	case strings.HasPrefix(modelID, "meta.llama"):
+		return "llama"
+	case strings.HasPrefix(modelID, "mistral."):
+		return "mistral"
+	}
+	return ""
+}
+
+// nativeStopReason translates a Converse stop reason into the family's own
+// vocabulary.
+func nativeStopReason(family, stop string) string {
+	switch family {
+	case "titan":
+		if stop == "max_tokens" {
+			return "LENGTH"
+		}
+		return "FINISH"
+	case "llama", "mistral":
+		if stop == "max_tokens" {
+			return "length"
+		}
+		return "stop"
+	}
+	return stop
+}
+
+// render returns the InvokeModel response body for the invocation.
+func (inv *invocation) render() map[string]interface{} {
+	text, stop := inv.resp.Text, nativeStopReason(inv.family, inv.resp.StopReason)
+	switch inv.family {
+	case "anthropic":
+		return map[string]interface{}{
+			"id":            "msg_bdrk_" + h.RandomID(24),
+			"type":          "message",
+			"role":          "assistant",
+			"model":         foundationModelID(inv.req.ModelID),
+			"content":       []map[string]string{{"type": "text", "text": text}},
+			"stop_reason":   stop,
+			"stop_sequence": nil,
+			"usage":         map[string]int{"input_tokens": inv.inTokens, "output_tokens": inv.outTokens},
+		}
+	case "nova":
+		return map[string]interface{}{
+			"output": map[string]interface{}{
+				"message": map[string]interface{}{
+					"role":    "assistant",
+					"content": []map[string]string{{"text": text}},
+				},
+			},
+			"stopReason": stop,
+			"usage": map[string]int{
+				"inputTokens":  inv.inTokens,
+				"outputTokens": inv.outTokens,
+				"totalTokens":  inv.inTokens + inv.outTokens,
+			},
+		}
+	case "titan":
+		return map[string]interface{}{
+			"inputTextTokenCount": inv.inTokens,
+			"results": []map[string]interface{}{{
+				"tokenCount":       inv.outTokens,
+				"outputText":       text,
+				"completionReason": stop,
+			}},
+		}
+	case "llama":
+		return map[string]interface{}{
+			"generation":             text,
+			"prompt_token_count":     inv.inTokens,
+			"generation_token_count": inv.outTokens,
+			"stop_reason":            stop,
+		}
+	default: // mistral
+		return map[string]interface{}{
+			"outputs": []map[string]interface{}{{"text": text, "stop_reason": stop}},
+		}
+	}
+}
+
+// words splits text into streaming deltas of one word each, keeping the
+// whitespace after each word so that the deltas join back into text.
+func words(text string) []string {
+	var out []string
+	for _, w := range strings.SplitAfter(text, " ") {
+		if w != "" {
+			out = append(out, w)
+		}
+	}
+	return out
+}
+
+// streamChunks returns the InvokeModelWithResponseStream chunks for the
+// invocation: the family's stream events, one text delta per word, with
+// Bedrock's invocation metrics on the last.
+func (inv *invocation) streamChunks() []map[string]interface{} {
+	deltas := words(inv.resp.Text)
+	stop := nativeStopReason(inv.family, inv.resp.StopReason)
+	var chunks []map[string]interface{}
+
+	switch inv.family {
+	case "anthropic":
+		chunks = append(chunks,
+			map[string]interface{}{
+				"type": "message_start",
+				"message": map[string]interface{}{
+					"id":            "msg_bdrk_" + h.RandomID(24),
+					"type":          "message",
+					"role":          "assistant",
+					"model":         foundationModelID(inv.req.ModelID),
+					"content":       []interface{}{},
+					"stop_reason":   nil,
+					"stop_sequence": nil,
+					"usage":         map[string]int{"input_tokens": inv.inTokens, "output_tokens": 0},
+				},
+			},
+			map[string]interface{}{
+				"type":          "content_block_start",
+				"index":         0,
+				"content_block": map[string]string{"type": "text", "text": ""},
+			},
+		)
+		for _, d := range deltas {
+			chunks = append(chunks, map[string]interface{}{
+				"type":  "content_block_delta",
+				"index": 0,
+				"delta": map[string]string{"type": "text_delta", "text": d},
+			})
+		}
+		chunks = append(chunks,
+			map[string]interface{}{"type": "content_block_stop", "index": 0},
+			map[string]interface{}{
+				"type":  "message_delta",
+				"delta": map[string]interface{}{"stop_reason": stop, "stop_sequence": nil},
+				"usage": map[string]int{"output_tokens": inv.outTokens},
+			},
+			map[string]interface{}{"type": "message_stop"},
+		)
+	case "nova":
+		chunks = append(chunks, map[string]interface{}{"messageStart": map[string]string{"role": "assistant"}})
+		for _, d := range deltas {
+			chunks = append(chunks, map[string]interface{}{
+				"contentBlockDelta": map[string]interface{}{"delta": map[string]string{"text": d}, "contentBlockIndex": 0},
+			})
+		}
+		chunks = append(chunks,
+			map[string]interface{}{"contentBlockStop": map[string]int{"contentBlockIndex": 0}},
+			map[string]interface{}{"messageStop": map[string]string{"stopReason": stop}},
+			map[string]interface{}{"metadata": map[string]interface{}{
+				"usage":   map[string]int{"inputTokens": inv.inTokens, "outputTokens": inv.outTokens},
+				"metrics": map[string]int{},
+			}},
+		)
+	default:
+		// The completion-style families stream their response body shape
+		// with a partial text and no stop reason until the last chunk.
+		if len(deltas) == 0 {
+			deltas = []string{""}
+		}
+		for i, d := range deltas {
+			last := i == len(deltas)-1
+			var chunkStop interface{}
+			if last {
+				chunkStop = stop
+			}
+			switch inv.family {
+			case "titan":
+				chunk := map[string]interface{}{
+					"outputText":                d,
+					"index":                     0,
+					"totalOutputTextTokenCount": nil,
+					"completionReason":          chunkStop,
+				}
+				if i == 0 {
+					chunk["inputTextTokenCount"] = inv.inTokens
+				}
+				if last {
+					chunk["totalOutputTextTokenCount"] = inv.outTokens
+				}
+				chunks = append(chunks, chunk)
+			case "llama":
+				var promptTokens interface{}
+				if i == 0 {
+					promptTokens = inv.inTokens
+				}
+				chunks = append(chunks, map[string]interface{}{
+					"generation":             d,
+					"prompt_token_count":     promptTokens,
+					"generation_token_count": i + 1,
+					"stop_reason":            chunkStop,
+				})
+			default: // mistral
+				chunks = append(chunks, map[string]interface{}{
+					"outputs": []map[string]interface{}{{"text": d, "stop_reason": chunkStop}},
+				})
+			}
+		}
+	}
+
+	chunks[len(chunks)-1]["amazon-bedrock-invocationMetrics"] = map[string]int{
+		"inputTokenCount":   inv.inTokens,
+		"outputTokenCount":  inv.outTokens,
+		"invocationLatency": 0,
+		"firstByteLatency":  0,
+	}
+	return chunks
+}
@@ -0,0 +1,202 @@
+package bedrock
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws/protocol/eventstream"
+	h "github.com/riyanimam/goto/internal/mockhelpers"
+)
+
+// invocation is a request answered by a model, with the details needed to
+// render the answer.
+type invocation struct {
+	req       Request
+	resp      Response
+	family    string // the provider body format, or "" if the mock has none
+	streaming bool   // whether the model supports response streaming
+	inTokens  int
+	outTokens int
+}
+
+// invoke answers a runtime request for modelID with its ModelFunc, writing
+// an error and returning false if the model is unknown or fails.
+func (s *Service) invoke(w http.ResponseWriter, r *http.Request, modelID, operation string) (*invocation, bool) {
+	s.mu.RLock()
+	fn := s.models[modelID]
+	s.mu.RUnlock()
+
+	base := foundationModelID(modelID)
+	m, known := lookupModel(base)
+	if fn == nil && !known {
+		writeValidation(w, "The provided model identifier is invalid.")
+		return nil, false
+	}
+	body, _ := io.ReadAll(r.Body)
+	var params map[string]interface{}
+	if err := json.Unmarshal(body, &params); err != nil {
+		writeValidation(w, "Malformed input request, please reformat your input and try again.")
+		return nil, false
+	}
+	if messages, _ := params["messages"].([]interface{}); operation == "Converse" && len(messages) == 0 {
+		writeValidation(w, "A conversation must start with a user message.")
+		return nil, false
+	}
+
+	inv := &invocation{
+		req: Request{
+			ModelID:   modelID,
+			Operation: operation,
+			Body:      body,
+			Prompt:    promptOf(params),
+		},
+		family:    modelFamily(base),
+		streaming: !known || m.streaming,
+	}
+	if fn == nil {
+		inv.resp = Response{Text: inv.req.Prompt}
+	} else {
+		resp, err := fn(inv.req)
+		if err != nil {
+			h.WriteJSONError(w, "ModelErrorException", err.Error(), http.StatusFailedDependency)
+			return nil, false
+		}
+		inv.resp = resp
+	}
+	if inv.resp.StopReason == "" {
+		inv.resp.StopReason = "end_turn"
+	}
+	inv.inTokens = len(strings.Fields(inv.req.Prompt))
+	inv.outTokens = len(strings.Fields(inv.resp.Text))
+	return inv, true
+}
+
+// promptOf returns the text of the last user message of a messages-style
+// request (Converse, Anthropic, Nova), or the prompt of a completion-style
+// one (Titan's inputText, Llama's and Mistral's prompt, Cohere's message).
+func promptOf(params map[string]interface{}) string {
+	if messages, ok := params["messages"].([]interface{}); ok {
+		for i := len(messages) - 1; i >= 0; i-- {
+			msg, _ := messages[i].(map[string]interface{})
+			if h.GetString(msg, "role") != "user" {
+				continue
+			}
+			if text, ok := msg["content"].(string); ok {
+				return text
+			}
+			blocks, _ := msg["content"].([]interface{})
+			var texts []string
+			for _, b := range blocks {
+				block, _ := b.(map[string]interface{})
+				if text, ok := block["text"].(string); ok {
+					texts = append(texts, text)
+				}
+			}
+			return strings.Join(texts, "\n")
+		}
+		return ""
+	}
+	for _, key := range []string{"inputText", "prompt", "message"} {
+		if text := h.GetString(params, key); text != "" {
+			return text
+		}
+	}
+	return ""
+}
+
+func (inv *invocation) setTokenHeaders(w http.ResponseWriter) {
+	w.Header().Set("X-Amzn-Bedrock-Input-Token-Count", strconv.Itoa(inv.inTokens))
+	w.Header().Set("X-Amzn-Bedrock-Output-Token-Count", strconv.Itoa(inv.outTokens))
+	w.Header().Set("X-Amzn-Bedrock-Invocation-Latency", "0")
+}
+
+func writeUnrenderable(w http.ResponseWriter, modelID string) {
+	writeValidation(w, "The mock has no response format for model "+modelID+"; return a Body from its ModelFunc.")
+}
+
+func (s *Service) invokeModel(w http.ResponseWriter, r *http.Request, modelID string) {
+	inv, ok := s.invoke(w, r, modelID, "InvokeModel")
+	if !ok {
+		return
+	}
+	body := inv.resp.Body
+	if body == nil {
+		if inv.family == "" {
+			writeUnrenderable(w, modelID)
+			return
+		}
+		body, _ = json.Marshal(inv.render())
+	}
+	w.Header().Set("Content-Type", "application/json")
+	inv.setTokenHeaders(w)
+	w.WriteHeader(http.StatusOK)
+	w.Write(body)
+}
+
+func (s *Service) invokeModelWithResponseStream(w http.ResponseWriter, r *http.Request, modelID string) {
+	inv, ok := s.invoke(w, r, modelID, "InvokeModelWithResponseStream")
+	if !ok {
+		return
+	}
+	if !inv.streaming {
+		writeValidation(w, "The model is unsupported for streaming.")
+		return
+	}
+	var chunks [][]byte
+	if inv.resp.Body != nil {
+		chunks = [][]byte{inv.resp.Body}
+	} else {
+		if inv.family == "" {
+			writeUnrenderable(w, modelID)
+			return
+		}
+		for _, chunk := range inv.streamChunks() {
+			b, _ := json.Marshal(chunk)
+			chunks = append(chunks, b)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/vnd.amazon.eventstream")
+	w.Header().Set("X-Amzn-Bedrock-Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	enc := eventstream.NewEncoder()
+	flusher, _ := w.(http.Flusher)
+	for _, chunk := range chunks {
+		payload, _ := json.Marshal(map[string][]byte{"bytes": chunk})
+		msg := eventstream.Message{Payload: payload}
+		msg.Headers.Set(":event-type", eventstream.StringValue("chunk"))
+		msg.Headers.Set(":content-type", eventstream.StringValue("application/json"))
+		msg.Headers.Set(":message-type", eventstream.StringValue("event"))
+		if err := enc.Encode(w, msg); err != nil {
+			return
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+}
+
+func (s *Service) converse(w http.ResponseWriter, r *http.Request, modelID string) {
+	inv, ok := s.invoke(w, r, modelID, "Converse")
+	if !ok {
+		return
+	}
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"output": map[string]interface{}{
+			"message": map[string]interface{}{
+				"role":    "assistant",
+				"content": []map[string]string{{"text": inv.resp.Text}},
+			},
+		},
+		"stopReason": inv.resp.StopReason,
+		"usage": map[string]int{
+			"inputTokens":  inv.inTokens,
+			"outputTokens": inv.outTokens,
+			"totalTokens":  inv.inTokens + inv.outTokens,
+		},
+		"metrics": map[string]int{"latencyMs": 0},
+	})
+}
@@ -0,0 +1,695 @@
+// Package budgets provides a mock implementation of AWS Budgets.
+//
+// Supported actions:
+//   - CreateBudget
+//   - DescribeBudget
+//   - DescribeBudgets
+//   - DeleteBudget
+//   - CreateNotification
+//   - DeleteNotification
+//   - DescribeNotificationsForBudget
+//   - DescribeSubscribersForNotification
+//
+// Spend is not tracked from billing data: tests set a budget's actual and
+// forecasted spend with [Service.SetSpend]. Whenever spend or notifications
+// change, each notification is evaluated against its threshold. One that
+// crosses it goes to ALARM and publishes an alert to its SNS subscribers;
+// one that no longer does goes back to OK. Email subscribers are recorded
+// but sent nothing.
+package budgets
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/riyanimam/goto/internal/clock"
+	h "github.com/riyanimam/goto/internal/mockhelpers"
+	"github.com/riyanimam/goto/internal/paginate"
+)
+
+// Service implements the Budgets mock.
+type Service struct {
+	mu       sync.RWMutex
+	budgets  map[string]*budget
+	clock    *clock.Clock
+	dispatch h.Dispatcher
+}
+
+type budget struct {
+	name          string
+	limitAmount   float64
+	limitUnit     string
+	timeUnit      string
+	budgetType    string
+	costFilters   interface{}
+	start, end    time.Time
+	actual        float64
+	forecasted    float64
+	notifications []*notification
+	updated       time.Time
+}
+
+type notification struct {
+	notificationType   string // ACTUAL or FORECASTED
+	comparisonOperator string // GREATER_THAN, LESS_THAN, or EQUAL_TO
+	threshold          float64
+	thresholdType      string // PERCENTAGE or ABSOLUTE_VALUE
+	state              string // OK or ALARM
+	subscribers        []subscriber
+}
+
+type subscriber struct {
+	subscriptionType string // SNS or EMAIL
+	address          string
+}
+
+// delivery is an alert to publish once the service's lock is released.
+type delivery struct {
+	topicArn string
+	message  string
+}
+
+// New creates a new Budgets mock service.
+func New() *Service {
+	return &Service{
+		budgets: make(map[string]*budget),
+	}
+}
+
+// Name returns the service identifier.
+func (s *Service) Name() string { return "budgets" }
+
+// Handler returns the HTTP handler for Budgets requests.
+func (s *Service) Handler() http.Handler {
+	return h.JSONRouter{
+		"CreateBudget":                       s.createBudget,
+		"DescribeBudget":                     s.describeBudget,
+		"DescribeBudgets":                    s.describeBudgets,
+		"DeleteBudget":                       s.deleteBudget,
+		"CreateNotification":                 s.createNotification,
+		"DeleteNotification":                 s.deleteNotification,
+		"DescribeNotificationsForBudget":     s.describeNotificationsForBudget,
+		"DescribeSubscribersForNotification": s.describeSubscribersForNotification,
+	}
+}
+
+// Reset clears all state.
+func (s *Service) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.budgets = make(map[string]*budget)
+}
+
+// SetClock attaches the mock clock that budget periods start from.
+func (s *Service) SetClock(c *clock.Clock) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.clock = c
+}
+
+// SetDispatcher sets the function used to publish alerts to SNS topics.
+func (s *Service) SetDispatcher(d h.Dispatcher) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.dispatch = d
+}
+
+func (s *Service) now() time.Time {
+	if s.clock != nil {
+		return s.clock.Now()
+	}
+	return time.Now().UTC()
+}
+
+// SetSpend sets the named budget's actual and forecasted spend for the
+// current period, in the budget's unit, and fires the notifications whose
+// thresholds it crosses.
+func (s *Service) SetSpend(name string, actual, forecasted float64) error {
+	s.mu.Lock()
+	b, exists := s.budgets[name]
+	if !exists {
+		s.mu.Unlock()
+		return fmt.Errorf("budget %s not found", name)
+	}
+	b.actual, b.forecasted = actual, forecasted
+	b.updated = s.now()
+	deliveries := s.evaluate(b)
+	s.mu.Unlock()
+
+	s.deliver(deliveries)
+	return nil
+}
+
+// evaluate updates the state of b's notifications, returning the alerts of
+// those that have just crossed their thresholds. The caller must hold s.mu.
+func (s *Service) evaluate(b *budget) []delivery {
+	var out []delivery
+	for _, n := range b.notifications {
+		spend := b.actual
+		if n.notificationType == "FORECASTED" {
+			spend = b.forecasted
+		}
+		threshold := n.threshold
+		if n.thresholdType != "ABSOLUTE_VALUE" {
+			threshold = b.limitAmount * n.threshold / 100
+		}
+		var crossed bool
+		switch n.comparisonOperator {
+		case "LESS_THAN":
+			crossed = spend < threshold
+		case "EQUAL_TO":
+			crossed = spend == threshold
+		default:
+			crossed = spend > threshold
+		}
+		if !crossed {
+			n.state = "OK"
+			continue
+		}
+		if n.state == "ALARM" {
+			continue
+		}
+		n.state = "ALARM"
+		message := s.alertMessage(b, n, spend, threshold)
+		for _, sub := range n.subscribers {
+			if sub.subscriptionType == "SNS" {
+				out = append(out, delivery{topicArn: sub.address, message: message})
+			}
+		}
+	}
+	return out
+}
+
+// deliver publishes alerts. Failures are dropped, as Budgets does when it
+// cannot publish to a topic.
+func (s *Service) deliver(deliveries []delivery) {
+	s.mu.RLock()
+	dispatch := s.dispatch
+	s.mu.RUnlock()
+	if dispatch == nil {
+		return
+	}
+	for _, d := range deliveries {
+		dispatch(d.topicArn, []byte(d.message))
+	}
+}
+
+var comparisonWords = map[string]string{
+	"GREATER_THAN": "greater than",
+	"LESS_THAN":    "less than",
+	"EQUAL_TO":     "equal to",
+}
+
+var comparisonSymbols = map[string]string{
+	"GREATER_THAN": ">",
+	"LESS_THAN":    "<",
+	"EQUAL_TO":     "=",
+}
+
+// alertMessage renders the text Budgets publishes for a notification.
+func (s *Service) alertMessage(b *budget, n *notification, spend, threshold float64) string {
+	limit := fmt.Sprintf("$%.2f", b.limitAmount)
+	condition := fmt.Sprintf("%s $%.2f", comparisonWords[n.comparisonOperator], threshold)
+	if n.thresholdType != "ABSOLUTE_VALUE" {
+		condition = fmt.Sprintf("%s %.2f%% of %s", comparisonWords[n.comparisonOperator], n.threshold, limit)
+	}
+	return fmt.Sprintf("AWS Budget Notification %s\n"+
+		"AWS Account %s\n\n"+
+		"Dear AWS Customer,\n\n"+
+		"You requested that we alert you when the %s %s associated with your %s budget is %s for the current %s. "+
+		"The %s %s associated with this budget is $%.2f.\n\n"+
+		"Budget Name: %s\n"+
+		"Budget Type: %s\n"+
+		"Budgeted Amount: %s\n"+
+		"Alert Type: %s\n"+
+		"Alert Threshold: %s $%.2f\n"+
+		"%s Amount: $%.2f\n",
+		s.now().Format("January 02, 2006"), h.DefaultAccountID,
+		n.notificationType, titleCase(b.budgetType), b.name, condition, periodWord(b.timeUnit),
+		n.notificationType, titleCase(b.budgetType), spend,
+		b.name, titleCase(b.budgetType), limit, n.notificationType,
+		comparisonSymbols[n.comparisonOperator], threshold, n.notificationType, spend)
+}
+
+// titleCase renders a budget type as the alert text does, e.g. "Cost" or
+// "Ri utilization".
+func titleCase(budgetType string) string {
+	if budgetType == "" {
+		return ""
+	}
+	rest := strings.ToLower(strings.ReplaceAll(budgetType[1:], "_", " "))
+	return budgetType[:1] + rest
+}
+
+func periodWord(timeUnit string) string {
+	switch timeUnit {
+	case "DAILY":
+		return "day"
+	case "QUARTERLY":
+		return "quarter"
+	case "ANNUALLY":
+		return "year"
+	default:
+		return "month"
+	}
+}
+
+// periodStart returns the start of the period containing t.
+func periodStart(t time.Time, timeUnit string) time.Time {
+	t = t.UTC()
+	switch timeUnit {
+	case "DAILY":
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	case "QUARTERLY":
+		return time.Date(t.Year(), t.Month()-(t.Month()-1)%3, 1, 0, 0, 0, 0, time.UTC)
+	case "ANNUALLY":
+		return time.Date(t.Year(), 1, 1, 0, 0, 0, 0, time.UTC)
+	default:
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+	}
+}
+
+// defaultEnd is the end of budgets created without one, as in AWS.
+var defaultEnd = time.Date(2087, 6, 15, 0, 0, 0, 0, time.UTC)
+
+func formatAmount(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+func (b *budget) toMap() map[string]interface{} {
+	m := map[string]interface{}{
+		"BudgetName": b.name,
+		"BudgetType": b.budgetType,
+		"TimeUnit":   b.timeUnit,
+		"BudgetLimit": map[string]interface{}{
+			"Amount": formatAmount(b.limitAmount),
+			"Unit":   b.limitUnit,
+		},
+		"TimePeriod": map[string]interface{}{
+			"Start": float64(b.start.Unix()),
+			"End":   float64(b.end.Unix()),
+		},
+		"CalculatedSpend": map[string]interface{}{
+			"ActualSpend":     map[string]interface{}{"Amount": formatAmount(b.actual), "Unit": b.limitUnit},
+			"ForecastedSpend": map[string]interface{}{"Amount": formatAmount(b.forecasted), "Unit": b.limitUnit},
+		},
+		"LastUpdatedTime": float64(b.updated.Unix()),
+	}
+	if b.costFilters != nil {
+		m["CostFilters"] = b.costFilters
+	}
+	return m
+}
+
+func (n *notification) toMap() map[string]interface{} {
+	return map[string]interface{}{
+		"NotificationType":   n.notificationType,
+		"ComparisonOperator": n.comparisonOperator,
+		"Threshold":          n.threshold,
+		"ThresholdType":      n.thresholdType,
+		"NotificationState":  n.state,
+	}
+}
+
+func (sub subscriber) toMap() map[string]interface{} {
+	return map[string]interface{}{
+		"SubscriptionType": sub.subscriptionType,
+		"Address":          sub.address,
+	}
+}
+
+func writeNotFound(w http.ResponseWriter, message string) {
+	h.WriteJSONError(w, "NotFoundException", message, http.StatusBadRequest)
+}
+
+func writeInvalid(w http.ResponseWriter, message string) {
+	h.WriteJSONError(w, "InvalidParameterException", message, http.StatusBadRequest)
+}
+
+// checkAccount rejects requests for accounts other than the mock's.
+func checkAccount(w http.ResponseWriter, params map[string]interface{}) bool {
+	switch id := h.GetString(params, "AccountId"); id {
+	case h.DefaultAccountID:
+		return true
+	case "":
+		writeInvalid(w, "AccountId is required.")
+	default:
+		h.WriteJSONError(w, "AccessDeniedException", "Account "+id+" is not authorized to manage these budgets.", http.StatusBadRequest)
+	}
+	return false
+}
+
+// parseNotification reads a Notification and its Subscribers.
+func parseNotification(w http.ResponseWriter, raw map[string]interface{}, subs []interface{}) (*notification, bool) {
+	n := &notification{
+		notificationType:   h.GetString(raw, "NotificationType"),
+		comparisonOperator: h.GetString(raw, "ComparisonOperator"),
+		thresholdType:      h.GetString(raw, "ThresholdType"),
+		state:              "OK",
+	}
+	n.threshold, _ = raw["Threshold"].(float64)
+	if n.thresholdType == "" {
+		n.thresholdType = "PERCENTAGE"
+	}
+	if n.notificationType != "ACTUAL" && n.notificationType != "FORECASTED" {
+		writeInvalid(w, "NotificationType must be ACTUAL or FORECASTED.")
+		return nil, false
+	}
+	if _, ok := comparisonWords[n.comparisonOperator]; !ok {
+		writeInvalid(w, "ComparisonOperator must be GREATER_THAN, LESS_THAN, or EQUAL_TO.")
+		return nil, false
+	}
+	if n.thresholdType != "PERCENTAGE" && n.thresholdType != "ABSOLUTE_VALUE" {
+		writeInvalid(w, "ThresholdType must be PERCENTAGE or ABSOLUTE_VALUE.")
+		return nil, false
+	}
+	if len(subs) == 0 || len(subs) > 11 {
+		writeInvalid(w, "A notification must have between 1 and 11 subscribers.")
+		return nil, false
+	}
+	for _, raw := range subs {
+		m, _ := raw.(map[string]interface{})
+		sub := subscriber{
+			subscriptionType: h.GetString(m, "SubscriptionType"),
+			address:          h.GetString(m, "Address"),
+		}
+		if (sub.subscriptionType != "SNS" && sub.subscriptionType != "EMAIL") || sub.address == "" {
+			writeInvalid(w, "Subscribers need a SubscriptionType of SNS or EMAIL and an Address.")
+			return nil, false
+		}
+		n.subscribers = append(n.subscribers, sub)
+	}
+	return n, true
+}
+
+// sameNotification reports whether two notifications have the same
+// identifying fields.
+func sameNotification(a, b *notification) bool {
+	return a.notificationType == b.notificationType &&
+		a.comparisonOperator == b.comparisonOperator &&
+		a.threshold == b.threshold &&
+		a.thresholdType == b.thresholdType
+}
+
+// findNotification returns the index of b's notification matching the
+// Notification parameter, or -1.
+func findNotification(b *budget, params map[string]interface{}) int {
+	raw, _ := params["Notification"].(map[string]interface{})
+	want := &notification{
+		notificationType:   h.GetString(raw, "NotificationType"),
+		comparisonOperator: h.GetString(raw, "ComparisonOperator"),
+		thresholdType:      h.GetString(raw, "ThresholdType"),
+	}
+	want.threshold, _ = raw["Threshold"].(float64)
+	if want.thresholdType == "" {
+		want.thresholdType = "PERCENTAGE"
+	}
+	for i, n := range b.notifications {
+		if sameNotification(n, want) {
+			return i
+		}
+	}
+	return -1
+}
+
+func (s *Service) createBudget(w http.ResponseWriter, params map[string]interface{}) {
+	if !checkAccount(w, params) {
+		return
+	}
+	raw, _ := params["Budget"].(map[string]interface{})
+	name := h.GetString(raw, "BudgetName")
+	timeUnit := h.GetString(raw, "TimeUnit")
+	budgetType := h.GetString(raw, "BudgetType")
+	if name == "" || timeUnit == "" || budgetType == "" {
+		writeInvalid(w, "Budget requires BudgetName, TimeUnit, and BudgetType.")
+		return
+	}
+	if timeUnit != "DAILY" && timeUnit != "MONTHLY" && timeUnit != "QUARTERLY" && timeUnit != "ANNUALLY" {
+		writeInvalid(w, "TimeUnit must be DAILY, MONTHLY, QUARTERLY, or ANNUALLY.")
+		return
+	}
+	limit, _ := raw["BudgetLimit"].(map[string]interface{})
+	amount, err := strconv.ParseFloat(h.GetString(limit, "Amount"), 64)
+	if err != nil || amount < 0 {
+		writeInvalid(w, "BudgetLimit requires a non-negative Amount.")
+		return
+	}
+	unit := h.GetString(limit, "Unit")
+	if unit == "" {
+		unit = "USD"
+	}
+
+	var pending []*notification
+	list, _ := params["NotificationsWithSubscribers"].([]interface{})
+	for _, item := range list {
+		m, _ := item.(map[string]interface{})
+		rawNotification, _ := m["Notification"].(map[string]interface{})
+		subs, _ := m["Subscribers"].([]interface{})
+		n, ok := parseNotification(w, rawNotification, subs)
+		if !ok {
+			return
+		}
+		pending = append(pending, n)
+	}
+
+	s.mu.Lock()
+	if _, exists := s.budgets[name]; exists {
+		s.mu.Unlock()
+		h.WriteJSONError(w, "DuplicateRecordException", "Budget "+name+" already exists.", http.StatusBadRequest)
+		return
+	}
+	now := s.now()
+	b := &budget{
+		name:          name,
+		limitAmount:   amount,
+		limitUnit:     unit,
+		timeUnit:      timeUnit,
+		budgetType:    budgetType,
+		costFilters:   raw["CostFilters"],
+		start:         periodStart(now, timeUnit),
+		end:           defaultEnd,
+		notifications: pending,
+		updated:       now,
+	}
+	if tp, ok := raw["TimePeriod"].(map[string]interface{}); ok {
+		if start, ok := tp["Start"].(float64); ok {
+			b.start = time.Unix(int64(start), 0).UTC()
+		}
+		if end, ok := tp["End"].(float64); ok {
+			b.end = time.Unix(int64(end), 0).UTC()
+		}
+	}
+	s.budgets[name] = b
+	deliveries := s.evaluate(b)
+	s.mu.Unlock()
+
+	s.deliver(deliveries)
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{})
+}
+
+func (s *Service) describeBudget(w http.ResponseWriter, params map[string]interface{}) {
+	if !checkAccount(w, params) {
+		return
+	}
+	name := h.GetString(params, "BudgetName")
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	b, exists := s.budgets[name]
+	if !exists {
+		writeNotFound(w, "Unable to get budget: "+name+" - the budget doesn't exist.")
+		return
+	}
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"Budget": b.toMap(),
+	})
+}
+
+func (s *Service) describeBudgets(w http.ResponseWriter, params map[string]interface{}) {
+	if !checkAccount(w, params) {
+		return
+	}
+
+	s.mu.RLock()
+	list := make([]*budget, 0, len(s.budgets))
+	for _, b := range s.budgets {
+		list = append(list, b)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].name < list[j].name })
+	page, next, err := paginate.Page(list, func(i int) string { return list[i].name }, h.GetString(params, "NextToken"), h.GetInt(params, "MaxResults", 0), 100)
+	if err != nil {
+		s.mu.RUnlock()
+		h.WriteJSONError(w, "InvalidNextTokenException", "Invalid NextToken", http.StatusBadRequest)
+		return
+	}
+	budgets := make([]map[string]interface{}, 0, len(page))
+	for _, b := range page {
+		budgets = append(budgets, b.toMap())
+	}
+	s.mu.RUnlock()
+
+	resp := map[string]interface{}{
+		"Budgets": budgets,
+	}
+	if next != "" {
+		resp["NextToken"] = next
+	}
+	h.WriteJSON(w, http.StatusOK, resp)
+}
+
+func (s *Service) deleteBudget(w http.ResponseWriter, params map[string]interface{}) {
+	if !checkAccount(w, params) {
+		return
+	}
+	name := h.GetString(params, "BudgetName")
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, exists := s.budgets[name]; !exists {
+		writeNotFound(w, "Unable to delete budget: "+name+" - the budget doesn't exist.")
+		return
+	}
+	delete(s.budgets, name)
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{})
+}
+
+func (s *Service) createNotification(w http.ResponseWriter, params map[string]interface{}) {
+	if !checkAccount(w, params) {
+		return
+	}
+	name := h.GetString(params, "BudgetName")
+	raw, _ := params["Notification"].(map[string]interface{})
+	subs, _ := params["Subscribers"].([]interface{})
+	n, ok := parseNotification(w, raw, subs)
+	if !ok {
+		return
+	}
+
+	s.mu.Lock()
+	b, exists := s.budgets[name]
+	if !exists {
+		s.mu.Unlock()
+		writeNotFound(w, "Unable to create notification: "+name+" - the budget doesn't exist.")
+		return
+	}
+	for _, existing := range b.notifications {
+		if sameNotification(existing, n) {
+			s.mu.Unlock()
+			h.WriteJSONError(w, "DuplicateRecordException", "The notification already exists on budget "+name+".", http.StatusBadRequest)
+			return
+		}
+	}
+	if len(b.notifications) >= 10 {
+		s.mu.Unlock()
+		h.WriteJSONError(w, "CreationLimitExceededException", "A budget can have at most 10 notifications.", http.StatusBadRequest)
+		return
+	}
+	b.notifications = append(b.notifications, n)
+	deliveries := s.evaluate(b)
+	s.mu.Unlock()
+
+	s.deliver(deliveries)
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{})
+}
+
+func (s *Service) deleteNotification(w http.ResponseWriter, params map[string]interface{}) {
+	if !checkAccount(w, params) {
+		return
+	}
+	name := h.GetString(params, "BudgetName")
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	b, exists := s.budgets[name]
+	if !exists {
+		writeNotFound(w, "Unable to delete notification: "+name+" - the budget doesn't exist.")
+		return
+	}
+	i := findNotification(b, params)
+	if i < 0 {
+		writeNotFound(w, "Unable to delete notification: the notification doesn't exist on budget "+name+".")
+		return
+	}
+	b.notifications = append(b.notifications[:i], b.notifications[i+1:]...)
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{})
+}
+
+func (s *Service) describeNotificationsForBudget(w http.ResponseWriter, params map[string]interface{}) {
+	if !checkAccount(w, params) {
+		return
+	}
+	name := h.GetString(params, "BudgetName")
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	b, exists := s.budgets[name]
+	if !exists {
+		writeNotFound(w, "Unable to get notifications: "+name+" - the budget doesn't exist.")
+		return
+	}
+	// Notifications can be deleted between pages, so they are paged in the
+	// order of their identifying fields.
+	list := append([]*notification(nil), b.notifications...)
+	key := func(i int) string {
+		n := list[i]
+		return paginate.Join(n.notificationType, n.comparisonOperator, n.thresholdType, strconv.FormatFloat(n.threshold, 'f', -1, 64))
+	}
+	sort.Slice(list, func(i, j int) bool { return key(i) < key(j) })
+	page, next, err := paginate.Page(list, key, h.GetString(params, "NextToken"), h.GetInt(params, "MaxResults", 0), 100)
+	if err != nil {
+		h.WriteJSONError(w, "InvalidNextTokenException", "Invalid NextToken", http.StatusBadRequest)
+		return
+	}
+	notifications := make([]map[string]interface{}, 0, len(page))
+	for _, n := range page {
+		notifications = append(notifications, n.toMap())
+	}
+	resp := map[string]interface{}{
+		"Notifications": notifications,
+	}
+	if next != "" {
+		resp["NextToken"] = next
+	}
+	h.WriteJSON(w, http.StatusOK, resp)
+}
+
+func (s *Service) describeSubscribersForNotification(w http.ResponseWriter, params map[string]interface{}) {
+	if !checkAccount(w, params) {
+		return
+	}
+	name := h.GetString(params, "BudgetName")
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	b, exists := s.budgets[name]
+	if !exists {
+		writeNotFound(w, "Unable to get subscribers: "+name+" - the budget doesn't exist.")
+		return
+	}
+	i := findNotification(b, params)
+	if i < 0 {
+		writeNotFound(w, "Unable to get subscribers: the notification doesn't exist on budget "+name+".")
+		return
+	}
+	page, next, err := paginate.Page(b.notifications[i].subscribers, paginate.Seq, h.GetString(params, "NextToken"), h.GetInt(params, "MaxResults", 0), 100)
+	if err != nil {
+		h.WriteJSONError(w, "InvalidNextTokenException", "Invalid NextToken", http.StatusBadRequest)
+		return
+	}
+	subscribers := make([]map[string]interface{}, 0, len(page))
+	for _, sub := range page {
+		subscribers = append(subscribers, sub.toMap())
+	}
+	resp := map[string]interface{}{
+		"Subscribers": subscribers,
+	}
+	if next != "" {
+		resp["NextToken"] = next
+	}
+	h.WriteJSON(w, http.StatusOK, resp)
+}
@@ -6,6 +6,39 @@
 //   - DescribeStacks
 //   - ListStacks
 //   - UpdateStack
+//   - DescribeStackResources
+//   - CreateStackSet
+//   - DescribeStackSet
+//   - ListStackSets
+//   - DeleteStackSet
+//   - CreateStackInstances
+//   - DeleteStackInstances
+//   - ListStackInstances
+//   - DescribeStackSetOperation
+//   - ListStackSetOperations
+//
+// Only the custom resources of a JSON template are modelled. When a stack
+// is created, updated, or deleted, each Custom:: or
+// AWS::CloudFormation::CustomResource resource's Create, Update, or Delete
+// request is sent, one at a time in dependency order, to the Lambda
+// function or SNS topic named by its ServiceToken. The stack waits for the
+// provider to PUT its response to the request's ResponseURL, which the mock
+// serves, and rolls back as CloudFormation does if the provider reports
+// FAILED or does not respond within the resource's ServiceTimeout (an hour
+// by default) on the mock clock. Properties and Outputs can use Ref,
+// Fn::GetAtt, Fn::Join, and Fn::Sub on parameters and custom resources.
+//
+// Stack sets record a stack instance for each account and region they are
+// deployed to, without running the template there. Self-managed stack sets
+// deploy to the accounts listed; service-managed ones to the accounts of
+// the Organizations mock under the organizational units listed. Operations
+// are RUNNING until the server's status transitions complete, and a stack
+// set runs one operation at a time.
+//
+// CloudFormation has no tagging operations of its own: stacks and stack
+// sets take their tags from the Tags of the create call, and UpdateStack
+// replaces a stack's tags when it lists any. The tags are recorded in the
+// shared registry, where the Resource Groups Tagging API finds them.
 package cloudformation
 
 import (
@@ -14,8 +47,15 @@ import (
 	"math/rand"
 	"net/http"
 	"sort"
+	"strings"
 	"sync"
 	"time"
+
+	"github.com/riyanimam/goto/internal/awserr"
+	"github.com/riyanimam/goto/internal/clock"
+	"github.com/riyanimam/goto/internal/lifecycle"
+	h "github.com/riyanimam/goto/internal/mockhelpers"
+	"github.com/riyanimam/goto/internal/tags"
 )
 
 const defaultAccountID = "123456789012"
@@ -24,6 +64,18 @@ const defaultAccountID = "123456789012"
 type Service struct {
 	mu     sync.RWMutex
 	stacks map[string]*stack // keyed by stack name
+	// responses maps the tokens of outstanding custom resource response
+	// URLs to their stacks.
+	responses map[string]*stack
+	stackSets map[string]*stackSet // keyed by stack set name
+
+	transitions   *lifecycle.Transitions
+	dispatch      h.Dispatcher
+	resolve       h.Resolver
+	listResources h.ResourceLister
+	clock         *clock.Clock
+	baseURL       string
+	tags          *tags.Store
 }
 
 type stack struct {
@@ -36,12 +88,32 @@ type stack struct {
 	created      time.Time
 	updated      time.Time
 	parameters   map[string]string
+	ready        lifecycle.Transition // of the last create or update
+	reason       string
+
+	// template is the parsed JSON template, or nil.
+	template  map[string]interface{}
+	resources map[string]*customResource // by logical ID
+	// previousTemplate and previousParameters are what an update rolls
+	// back to.
+	previousTemplate   map[string]interface{}
+	previousParameters map[string]string
+
+	// operation is the custom resource operation in progress, or "".
+	operation string
+	queue     []step
+	done      []step
+	cleanup   []step // run after the update in progress succeeds
+	pending   *pendingRequest
 }
 
 // New creates a new CloudFormation mock service.
 func New() *Service {
 	return &Service{
-		stacks: make(map[string]*stack),
+		stacks:    make(map[string]*stack),
+		responses: make(map[string]*stack),
+		stackSets: make(map[string]*stackSet),
+		tags:      tags.New(),
 	}
 }
 
@@ -50,7 +122,33 @@ func (s *Service) Name() string { return "cloudformation" }
 
 // Handler returns the HTTP handler for CloudFormation requests.
 func (s *Service) Handler() http.Handler {
-	return http.HandlerFunc(s.handle)
+	router := h.QueryRouter{
+		Actions: map[string]http.HandlerFunc{
+			"CreateStack":               s.createStack,
+			"DeleteStack":               s.deleteStack,
+			"DescribeStacks":            s.describeStacks,
+			"ListStacks":                s.listStacks,
+			"UpdateStack":               s.updateStack,
+			"DescribeStackResources":    s.describeStackResources,
+			"CreateStackSet":            s.createStackSet,
+			"DescribeStackSet":          s.describeStackSet,
+			"ListStackSets":             s.listStackSets,
+			"DeleteStackSet":            s.deleteStackSet,
+			"CreateStackInstances":      s.createStackInstances,
+			"DeleteStackInstances":      s.deleteStackInstances,
+			"ListStackInstances":        s.listStackInstances,
+			"DescribeStackSetOperation": s.describeStackSetOperation,
+			"ListStackSetOperations":    s.listStackSetOperations,
+		},
+		Error: writeCFError,
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, responsePath) {
+			s.handleResponse(w, r)
+			return
+		}
+		router.ServeHTTP(w, r)
+	})
 }
 
 // Reset clears all stacks.
@@ -58,29 +156,76 @@ func (s *Service) Reset() {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	s.stacks = make(map[string]*stack)
+	s.responses = make(map[string]*stack)
+	s.stackSets = make(map[string]*stackSet)
+	s.tags.DeleteService("cloudformation")
 }
 
-func (s *Service) handle(w http.ResponseWriter, r *http.Request) {
-	if err := r.ParseForm(); err != nil {
-		writeCFError(w, "ValidationError", "could not parse request", http.StatusBadRequest)
-		return
-	}
+// SetTagStore sets the registry stack and stack set tags are recorded in.
+func (s *Service) SetTagStore(store *tags.Store) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tags = store
+}
+
+// SetTransitions sets how long stacks stay CREATE_IN_PROGRESS or
+// UPDATE_IN_PROGRESS after a create or update.
+func (s *Service) SetTransitions(t *lifecycle.Transitions) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.transitions = t
+}
+
+// SetDispatcher sets the function custom resource requests are sent with.
+func (s *Service) SetDispatcher(d h.Dispatcher) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.dispatch = d
+}
 
-	action := r.FormValue("Action")
-	switch action {
-	case "CreateStack":
-		s.createStack(w, r)
-	case "DeleteStack":
-		s.deleteStack(w, r)
-	case "DescribeStacks":
-		s.describeStacks(w, r)
-	case "ListStacks":
-		s.listStacks(w, r)
-	case "UpdateStack":
-		s.updateStack(w, r)
-	default:
-		writeCFError(w, "ValidationError", fmt.Sprintf("action %q is not supported", action), http.StatusBadRequest)
+// SetResolver sets the function used to check that custom resource service
+// tokens exist.
+func (s *Service) SetResolver(r h.Resolver) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.resolve = r
+}
+
+// SetResourceLister sets the function service-managed stack sets read the
+// organization's accounts with.
+func (s *Service) SetResourceLister(l h.ResourceLister) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.listResources = l
+}
+
+// SetBaseURL records the mock server URL, which custom resource response
+// URLs point at.
+func (s *Service) SetBaseURL(u string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.baseURL = u
+}
+
+// SetClock attaches the mock clock used for stack times and custom resource
+// service timeouts.
+func (s *Service) SetClock(c *clock.Clock) {
+	s.mu.Lock()
+	s.clock = c
+	s.mu.Unlock()
+	c.OnAdvance(func(_, _ time.Time) {
+		s.mu.Lock()
+		next := s.settle()
+		s.mu.Unlock()
+		s.deliver(next)
+	})
+}
+
+func (s *Service) now() time.Time {
+	if s.clock != nil {
+		return s.clock.Now()
 	}
+	return time.Now().UTC()
 }
 
 func (s *Service) createStack(w http.ResponseWriter, r *http.Request) {
@@ -89,6 +234,11 @@ func (s *Service) createStack(w http.ResponseWriter, r *http.Request) {
 		writeCFError(w, "ValidationError", "StackName is required", http.StatusBadRequest)
 		return
 	}
+	tmpl, err := parseTemplate(r.FormValue("TemplateBody"))
+	if err != nil {
+		writeCFError(w, "ValidationError", err.Error(), http.StatusBadRequest)
+		return
+	}
 
 	s.mu.Lock()
 	if _, exists := s.stacks[name]; exists {
@@ -98,7 +248,7 @@ func (s *Service) createStack(w http.ResponseWriter, r *http.Request) {
 	}
 
 	stackID := newRequestID()
-	now := time.Now().UTC()
+	now := s.now()
 	st := &stack{
 		name:         name,
 		id:           stackID,
@@ -108,22 +258,23 @@ func (s *Service) createStack(w http.ResponseWriter, r *http.Request) {
 		description:  r.FormValue("Description"),
 		created:      now,
 		updated:      now,
-		parameters:   make(map[string]string),
+		parameters:   parseParameters(r, nil),
+		ready:        s.transitions.Begin(),
+		template:     tmpl,
+		resources:    make(map[string]*customResource),
 	}
+	s.stacks[name] = st
+	s.tags.Tag(st.arn, tags.FromForm(r.Form, "Tags.member."))
 
-	// Parse parameters.
-	for i := 1; ; i++ {
-		key := r.FormValue(fmt.Sprintf("Parameters.member.%d.ParameterKey", i))
-		if key == "" {
-			break
-		}
-		value := r.FormValue(fmt.Sprintf("Parameters.member.%d.ParameterValue", i))
-		st.parameters[key] = value
+	order, _ := customResourceOrder(tmpl)
+	var steps []step
+	for _, id := range order {
+		steps = append(steps, step{requestType: "Create", logicalID: id})
 	}
-
-	s.stacks[name] = st
+	next := s.begin(st, "CREATE", steps)
 	s.mu.Unlock()
 
+	s.deliver(next)
 	resp := createStackResponse{
 		Result:    createStackResult{StackId: st.arn},
 		RequestID: newRequestID(),
@@ -131,17 +282,41 @@ func (s *Service) createStack(w http.ResponseWriter, r *http.Request) {
 	writeXML(w, http.StatusOK, resp)
 }
 
+// parseParameters reads a request's Parameters. Parameters that use their
+// previous value take it from previous.
+func parseParameters(r *http.Request, previous map[string]string) map[string]string {
+	params := make(map[string]string)
+	for i := 1; ; i++ {
+		key := r.FormValue(fmt.Sprintf("Parameters.member.%d.ParameterKey", i))
+		if key == "" {
+			break
+		}
+		if r.FormValue(fmt.Sprintf("Parameters.member.%d.UsePreviousValue", i)) == "true" {
+			params[key] = previous[key]
+			continue
+		}
+		params[key] = r.FormValue(fmt.Sprintf("Parameters.member.%d.ParameterValue", i))
+	}
+	return params
+}
+
 func (s *Service) deleteStack(w http.ResponseWriter, r *http.Request) {
 	name := r.FormValue("StackName")
 
 	s.mu.Lock()
 	st, exists := s.stacks[name]
-	if exists {
-		st.status = "DELETE_COMPLETE"
-		delete(s.stacks, name)
+	if exists && st.operation != "" && st.operation != "DELETE" {
+		s.mu.Unlock()
+		writeCFError(w, "ValidationError", "Stack ["+name+"] cannot be deleted while in status "+st.status, http.StatusBadRequest)
+		return
+	}
+	var next []delivery
+	if exists && st.operation == "" {
+		next = s.begin(st, "DELETE", s.deleteSteps(st))
 	}
 	s.mu.Unlock()
 
+	s.deliver(next)
 	resp := deleteStackResponse{RequestID: newRequestID()}
 	writeXML(w, http.StatusOK, resp)
 }
@@ -153,11 +328,11 @@ func (s *Service) describeStacks(w http.ResponseWriter, r *http.Request) {
 	var members []cfStack
 	if name != "" {
 		if st, exists := s.stacks[name]; exists {
-			members = append(members, stackToXML(st))
+			members = append(members, s.stackToXML(st))
 		}
 	} else {
 		for _, st := range s.stacks {
-			members = append(members, stackToXML(st))
+			members = append(members, s.stackToXML(st))
 		}
 	}
 	s.mu.RUnlock()
@@ -180,7 +355,7 @@ func (s *Service) listStacks(w http.ResponseWriter, _ *http.Request) {
 		summaries = append(summaries, cfStackSummary{
 			StackName:    st.name,
 			StackId:      st.arn,
-			StackStatus:  st.status,
+			StackStatus:  s.stackStatus(st),
 			CreationTime: st.created.Format(time.RFC3339),
 		})
 	}
@@ -199,6 +374,12 @@ func (s *Service) listStacks(w http.ResponseWriter, _ *http.Request) {
 
 func (s *Service) updateStack(w http.ResponseWriter, r *http.Request) {
 	name := r.FormValue("StackName")
+	body := r.FormValue("TemplateBody")
+	tmpl, err := parseTemplate(body)
+	if err != nil {
+		writeCFError(w, "ValidationError", err.Error(), http.StatusBadRequest)
+		return
+	}
 
 	s.mu.Lock()
 	st, exists := s.stacks[name]
@@ -207,15 +388,49 @@ func (s *Service) updateStack(w http.ResponseWriter, r *http.Request) {
 		writeCFError(w, "ValidationError", "Stack ["+name+"] does not exist", http.StatusBadRequest)
 		return
 	}
+	if st.operation != "" {
+		s.mu.Unlock()
+		writeCFError(w, "ValidationError", "Stack:"+st.arn+" is in "+st.status+" state and can not be updated.", http.StatusBadRequest)
+		return
+	}
 
-	if body := r.FormValue("TemplateBody"); body != "" {
+	st.previousTemplate, st.previousParameters = st.template, st.parameters
+	if body != "" {
 		st.templateBody = body
+		st.template = tmpl
+	}
+	if r.FormValue("Parameters.member.1.ParameterKey") != "" {
+		st.parameters = parseParameters(r, st.parameters)
 	}
-	st.status = "UPDATE_COMPLETE"
-	st.updated = time.Now().UTC()
+	if r.FormValue("Tags.member.1.Key") != "" {
+		s.tags.Replace(st.arn, tags.FromForm(r.Form, "Tags.member."))
+	}
+	st.updated = s.now()
+	st.reason = ""
+
+	// Resources still in the template are updated in dependency order, and
+	// the ones removed from it are deleted once the update succeeds.
+	order, _ := customResourceOrder(st.template)
+	inTemplate := make(map[string]bool, len(order))
+	var steps []step
+	for _, id := range order {
+		inTemplate[id] = true
+		requestType := "Create"
+		if st.resources[id] != nil {
+			requestType = "Update"
+		}
+		steps = append(steps, step{requestType: requestType, logicalID: id})
+	}
+	for _, sp := range s.deleteSteps(st) {
+		if !inTemplate[sp.logicalID] {
+			st.cleanup = append(st.cleanup, sp)
+		}
+	}
+	next := s.begin(st, "UPDATE", steps)
 	arn := st.arn
 	s.mu.Unlock()
 
+	s.deliver(next)
 	resp := updateStackResponse{
 		Result:    updateStackResult{StackId: arn},
 		RequestID: newRequestID(),
@@ -223,7 +438,57 @@ func (s *Service) updateStack(w http.ResponseWriter, r *http.Request) {
 	writeXML(w, http.StatusOK, resp)
 }
 
-func stackToXML(st *stack) cfStack {
+func (s *Service) describeStackResources(w http.ResponseWriter, r *http.Request) {
+	name, logicalID := r.FormValue("StackName"), r.FormValue("LogicalResourceId")
+
+	s.mu.RLock()
+	st, exists := s.stacks[name]
+	if !exists {
+		s.mu.RUnlock()
+		writeCFError(w, "ValidationError", "Stack with id "+name+" does not exist", http.StatusBadRequest)
+		return
+	}
+	var members []cfStackResource
+	for _, res := range st.resources {
+		if logicalID != "" && res.logicalID != logicalID {
+			continue
+		}
+		members = append(members, cfStackResource{
+			StackName:            st.name,
+			StackId:              st.arn,
+			LogicalResourceId:    res.logicalID,
+			PhysicalResourceId:   res.physicalID,
+			ResourceType:         res.resourceType,
+			ResourceStatus:       res.status,
+			ResourceStatusReason: res.reason,
+			Timestamp:            res.updated.Format(time.RFC3339),
+		})
+	}
+	s.mu.RUnlock()
+
+	sort.Slice(members, func(i, j int) bool {
+		return members[i].LogicalResourceId < members[j].LogicalResourceId
+	})
+	resp := describeStackResourcesResponse{
+		Result:    describeStackResourcesResult{StackResources: members},
+		RequestID: newRequestID(),
+	}
+	writeXML(w, http.StatusOK, resp)
+}
+
+// stackStatus returns the status of st, which is still in progress until
+// its last create or update completes.
+func (s *Service) stackStatus(st *stack) string {
+	switch st.status {
+	case "CREATE_COMPLETE":
+		return s.transitions.Status(st.ready, "CREATE_IN_PROGRESS", st.status)
+	case "UPDATE_COMPLETE":
+		return s.transitions.Status(st.ready, "UPDATE_IN_PROGRESS", st.status)
+	}
+	return st.status
+}
+
+func (s *Service) stackToXML(st *stack) cfStack {
 	var params []cfParameter
 	for k, v := range st.parameters {
 		params = append(params, cfParameter{
@@ -231,25 +496,86 @@ func stackToXML(st *stack) cfStack {
 			ParameterValue: v,
 		})
 	}
+	status := s.stackStatus(st)
+	var outputs []cfOutput
+	if status == "CREATE_COMPLETE" || status == "UPDATE_COMPLETE" {
+		outputs = stackOutputs(st)
+	}
 	return cfStack{
-		StackName:    st.name,
-		StackId:      st.arn,
-		StackStatus:  st.status,
-		Description:  st.description,
-		CreationTime: st.created.Format(time.RFC3339),
-		Parameters:   params,
+		StackName:         st.name,
+		StackId:           st.arn,
+		StackStatus:       status,
+		StackStatusReason: st.reason,
+		Description:       st.description,
+		CreationTime:      st.created.Format(time.RFC3339),
+		Parameters:        params,
+		Outputs:           outputs,
+		Tags:              tagsToXML(s.tags.Get(st.arn)),
 	}
 }
 
+// tagsToXML lists tags sorted by key.
+func tagsToXML(m map[string]string) []cfTag {
+	list := make([]cfTag, 0, len(m))
+	for k, v := range m {
+		list = append(list, cfTag{Key: k, Value: v})
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Key < list[j].Key })
+	return list
+}
+
+// stackOutputs resolves the Outputs of the stack's template, leaving out
+// those that do not resolve.
+func stackOutputs(st *stack) []cfOutput {
+	defs, _ := st.template["Outputs"].(map[string]interface{})
+	var outputs []cfOutput
+	for key, raw := range defs {
+		def, _ := raw.(map[string]interface{})
+		v, err := st.resolve(def["Value"])
+		if err != nil {
+			continue
+		}
+		desc, _ := def["Description"].(string)
+		outputs = append(outputs, cfOutput{OutputKey: key, OutputValue: fmt.Sprint(v), Description: desc})
+	}
+	sort.Slice(outputs, func(i, j int) bool { return outputs[i].OutputKey < outputs[j].OutputKey })
+	return outputs
+}
+
 // XML response types.
 
 type cfStack struct {
-	StackName    string        `xml:"StackName"`
-	StackId      string        `xml:"StackId"`
-	StackStatus  string        `xml:"StackStatus"`
-	Description  string        `xml:"Description"`
-	CreationTime string        `xml:"CreationTime"`
-	Parameters   []cfParameter `xml:"Parameters>member"`
+	StackName         string        `xml:"StackName"`
+	StackId           string        `xml:"StackId"`
+	StackStatus       string        `xml:"StackStatus"`
+	StackStatusReason string        `xml:"StackStatusReason,omitempty"`
+	Description       string        `xml:"Description"`
+	CreationTime      string        `xml:"CreationTime"`
+	Parameters        []cfParameter `xml:"Parameters>member"`
+	Outputs           []cfOutput    `xml:"Outputs>member,omitempty"`
+	Tags              []cfTag       `xml:"Tags>member,omitempty"`
+}
+
+type cfTag struct {
+	Key   string `xml:"Key"`
+	Value string `xml:"Value"`
+}
+
+type cfOutput struct {
+	OutputKey   string `xml:"OutputKey"`
+	OutputValue string `xml:"OutputValue"`
+	Description string `xml:"Description,omitempty"`
+}
+
+type cfStackResource struct {
+	StackName            string `xml:"StackName"`
+	StackId              string `xml:"StackId"`
+	LogicalResourceId    string `xml:"LogicalResourceId"`
+	PhysicalResourceId   string `xml:"PhysicalResourceId"`
+	ResourceType         string `xml:"ResourceType"`
+	ResourceStatus       string `xml:"ResourceStatus"`
+	ResourceStatusReason string `xml:"ResourceStatusReason,omitempty"`
+	Timestamp            string `xml:"Timestamp"`
 }
 
 type cfParameter struct {
@@ -300,6 +626,16 @@ type listStacksResult struct {
 	StackSummaries []cfStackSummary `xml:"StackSummaries>member"`
 }
 
+type describeStackResourcesResponse struct {
+	XMLName   xml.Name                     `xml:"DescribeStackResourcesResponse"`
+	XMLNS     string                       `xml:"xmlns,attr"`
+	Result    describeStackResourcesResult `xml:"DescribeStackResourcesResult"`
+	RequestID string                       `xml:"ResponseMetadata>RequestId"`
+}
+type describeStackResourcesResult struct {
+	StackResources []cfStackResource `xml:"StackResources>member"`
+}
+
 type updateStackResponse struct {
 	XMLName   xml.Name          `xml:"UpdateStackResponse"`
 	XMLNS     string            `xml:"xmlns,attr"`
@@ -310,18 +646,6 @@ type updateStackResult struct {
 	StackId string `xml:"StackId"`
 }
 
-type cfErrorResponse struct {
-	XMLName   xml.Name `xml:"ErrorResponse"`
-	Error     cfError  `xml:"Error"`
-	RequestID string   `xml:"RequestId"`
-}
-
-type cfError struct {
-	Type    string `xml:"Type"`
-	Code    string `xml:"Code"`
-	Message string `xml:"Message"`
-}
-
 func writeXML(w http.ResponseWriter, status int, v interface{}) {
 	w.Header().Set("Content-Type", "text/xml; charset=utf-8")
 	w.WriteHeader(status)
@@ -330,15 +654,7 @@ func writeXML(w http.ResponseWriter, status int, v interface{}) {
 }
 
 func writeCFError(w http.ResponseWriter, code, message string, status int) {
-	resp := cfErrorResponse{
-		Error: cfError{
-			Type:    "Sender",
-			Code:    code,
-			Message: message,
-		},
-		RequestID: newRequestID(),
-	}
-	writeXML(w, status, resp)
+	awserr.WriteQuery(w, "", code, message, status)
 }
 
 func newRequestID() string {
@@ -0,0 +1,729 @@
+package cloudformation
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/riyanimam/goto/internal/arn"
+	h "github.com/riyanimam/goto/internal/mockhelpers"
+)
+
+// responsePath is where the mock serves custom resource response URLs.
+const responsePath = "/_cloudformation/responses/"
+
+// maxResponseData is the largest Data object a provider can return, in
+// bytes.
+const maxResponseData = 4096
+
+// defaultServiceTimeout is how long CloudFormation waits for a provider to
+// respond when the resource sets no ServiceTimeout.
+const defaultServiceTimeout = time.Hour
+
+// customResource is a Custom:: or AWS::CloudFormation::CustomResource
+// resource of a stack.
+type customResource struct {
+	logicalID    string
+	resourceType string
+	properties   map[string]interface{} // as last sent to the provider
+	physicalID   string
+	data         map[string]interface{}
+	status       string
+	reason       string
+	updated      time.Time
+}
+
+// step is a request a stack operation still has to send to a provider.
+type step struct {
+	requestType string // Create, Update, or Delete
+	logicalID   string
+	// properties are the resource's properties as sent, for Update steps
+	// that roll a resource back and Delete steps. Create and Update steps
+	// otherwise resolve them from the template when sent.
+	properties map[string]interface{}
+	// physicalID is the resource to delete, for Delete steps that clean up
+	// a replaced resource.
+	physicalID string
+	// previous are the resource's properties before a completed Update
+	// step, for rolling it back.
+	previous map[string]interface{}
+}
+
+// pendingRequest is a request sent to a provider and awaiting its response.
+type pendingRequest struct {
+	token     string
+	requestID string
+	step      step
+	event     map[string]interface{}
+	deadline  time.Time
+}
+
+// delivery is a request to send once s.mu is released.
+type delivery struct {
+	serviceToken string
+	event        map[string]interface{}
+}
+
+// operation describes the statuses a stack operation moves through.
+type operation struct {
+	inProgress, complete, failed string
+}
+
+var operations = map[string]operation{
+	"CREATE":          {"CREATE_IN_PROGRESS", "CREATE_COMPLETE", "CREATE_FAILED"},
+	"ROLLBACK":        {"ROLLBACK_IN_PROGRESS", "ROLLBACK_COMPLETE", "ROLLBACK_FAILED"},
+	"UPDATE":          {"UPDATE_IN_PROGRESS", "UPDATE_COMPLETE", "UPDATE_FAILED"},
+	"CLEANUP":         {"UPDATE_COMPLETE_CLEANUP_IN_PROGRESS", "UPDATE_COMPLETE", "UPDATE_COMPLETE"},
+	"UPDATE_ROLLBACK": {"UPDATE_ROLLBACK_IN_PROGRESS", "UPDATE_ROLLBACK_COMPLETE", "UPDATE_ROLLBACK_FAILED"},
+	"DELETE":          {"DELETE_IN_PROGRESS", "DELETE_COMPLETE", "DELETE_FAILED"},
+}
+
+// isCustomResource reports whether a template resource type is handled by
+// a custom resource provider.
+func isCustomResource(resourceType string) bool {
+	return strings.HasPrefix(resourceType, "Custom::") || resourceType == "AWS::CloudFormation::CustomResource"
+}
+
+// parseTemplate parses a JSON template body. Templates that are not JSON,
+// such as YAML ones, parse to nil: the mock does not read them.
+func parseTemplate(body string) (map[string]interface{}, error) {
+	if !strings.HasPrefix(strings.TrimSpace(body), "{") {
+		return nil, nil
+	}
+	var tmpl map[string]interface{}
+	if err := json.Unmarshal([]byte(body), &tmpl); err != nil {
+		return nil, fmt.Errorf("Template format error: JSON not well-formed. (%v)", err)
+	}
+	if _, err := customResourceOrder(tmpl); err != nil {
+		return nil, err
+	}
+	return tmpl, nil
+}
+
+// templateResources returns a template's custom resource definitions by
+// logical ID.
+func templateResources(tmpl map[string]interface{}) map[string]map[string]interface{} {
+	resources, _ := tmpl["Resources"].(map[string]interface{})
+	out := make(map[string]map[string]interface{})
+	for id, raw := range resources {
+		def, _ := raw.(map[string]interface{})
+		if t, _ := def["Type"].(string); isCustomResource(t) {
+			out[id] = def
+		}
+	}
+	return out
+}
+
+// customResourceOrder returns a template's custom resources in an order
+// that creates each after the custom resources it references or depends
+// on, breaking ties by logical ID.
+func customResourceOrder(tmpl map[string]interface{}) ([]string, error) {
+	defs := templateResources(tmpl)
+	deps := make(map[string]map[string]bool, len(defs))
+	for id, def := range defs {
+		deps[id] = map[string]bool{}
+		for _, ref := range references(def["Properties"]) {
+			if _, ok := defs[ref]; ok && ref != id {
+				deps[id][ref] = true
+			}
+		}
+		dependsOn := def["DependsOn"]
+		if one, ok := dependsOn.(string); ok {
+			dependsOn = []interface{}{one}
+		}
+		list, _ := dependsOn.([]interface{})
+		for _, d := range list {
+			if ref, _ := d.(string); defs[ref] != nil {
+				deps[id][ref] = true
+			}
+		}
+	}
+
+	var order []string
+	placed := make(map[string]bool, len(defs))
+	for len(order) < len(defs) {
+		var ready []string
+		for id := range defs {
+			if placed[id] {
+				continue
+			}
+			waiting := false
+			for dep := range deps[id] {
+				waiting = waiting || !placed[dep]
+			}
+			if !waiting {
+				ready = append(ready, id)
+			}
+		}
+		if len(ready) == 0 {
+			return nil, errors.New("Circular dependency between resources")
+		}
+		sort.Strings(ready)
+		for _, id := range ready {
+			placed[id] = true
+		}
+		order = append(order, ready...)
+	}
+	return order, nil
+}
+
+var subVariable = regexp.MustCompile(`\$\{([^!}][^}]*)\}`)
+
+// references returns the logical IDs and parameter names a template value
+// refers to with Ref, Fn::GetAtt, and Fn::Sub.
+func references(v interface{}) []string {
+	var refs []string
+	switch v := v.(type) {
+	case map[string]interface{}:
+		if len(v) == 1 {
+			if ref, ok := v["Ref"].(string); ok {
+				return []string{ref}
+			}
+			if att, ok := v["Fn::GetAtt"]; ok {
+				if id, _, ok := getAttTarget(att); ok {
+					return []string{id}
+				}
+			}
+			if sub, ok := v["Fn::Sub"]; ok {
+				str, _ := sub.(string)
+				if list, ok := sub.([]interface{}); ok && len(list) > 0 {
+					str, _ = list[0].(string)
+					refs = append(refs, references(list[1:])...)
+				}
+				for _, m := range subVariable.FindAllStringSubmatch(str, -1) {
+					id, _, _ := strings.Cut(m[1], ".")
+					refs = append(refs, id)
+				}
+				return refs
+			}
+		}
+		for _, sub := range v {
+			refs = append(refs, references(sub)...)
+		}
+	case []interface{}:
+		for _, sub := range v {
+			refs = append(refs, references(sub)...)
+		}
+	}
+	return refs
+}
+
+// getAttTarget returns the logical ID and attribute of an Fn::GetAtt
+// argument, given as a list or as "LogicalId.Attribute".
+func getAttTarget(v interface{}) (string, string, bool) {
+	switch v := v.(type) {
+	case []interface{}:
+		if len(v) == 2 {
+			id, ok1 := v[0].(string)
+			attr, ok2 := v[1].(string)
+			return id, attr, ok1 && ok2
+		}
+	case string:
+		id, attr, ok := strings.Cut(v, ".")
+		return id, attr, ok
+	}
+	return "", "", false
+}
+
+// resolve evaluates the Ref, Fn::GetAtt, Fn::Join, and Fn::Sub intrinsic
+// functions in a template value. Refs to parameters and pseudo parameters
+// resolve to their values and refs to custom resources to their physical
+// IDs; other resources are not modelled, so refs to them resolve to their
+// logical IDs. Other intrinsic functions are left as they are.
+func (st *stack) resolve(v interface{}) (interface{}, error) {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		if len(v) == 1 {
+			if ref, ok := v["Ref"].(string); ok {
+				return st.ref(ref)
+			}
+			if att, ok := v["Fn::GetAtt"]; ok {
+				id, attr, ok := getAttTarget(att)
+				if !ok {
+					return nil, errors.New("Template error: invalid Fn::GetAtt")
+				}
+				return st.getAtt(id, attr)
+			}
+			if join, ok := v["Fn::Join"].([]interface{}); ok && len(join) == 2 {
+				return st.join(join)
+			}
+			if sub, ok := v["Fn::Sub"]; ok {
+				return st.sub(sub)
+			}
+		}
+		out := make(map[string]interface{}, len(v))
+		for k, sub := range v {
+			r, err := st.resolve(sub)
+			if err != nil {
+				return nil, err
+			}
+			out[k] = r
+		}
+		return out, nil
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, sub := range v {
+			r, err := st.resolve(sub)
+			if err != nil {
+				return nil, err
+			}
+			out[i] = r
+		}
+		return out, nil
+	}
+	return v, nil
+}
+
+func (st *stack) ref(name string) (interface{}, error) {
+	switch name {
+	case "AWS::StackName":
+		return st.name, nil
+	case "AWS::StackId":
+		return st.arn, nil
+	case "AWS::Region":
+		return "us-east-1", nil
+	case "AWS::AccountId":
+		return defaultAccountID, nil
+	case "AWS::Partition":
+		return "aws", nil
+	case "AWS::URLSuffix":
+		return "amazonaws.com", nil
+	case "AWS::NoValue":
+		return nil, nil
+	}
+	if v, ok := st.parameters[name]; ok {
+		return v, nil
+	}
+	params, _ := st.template["Parameters"].(map[string]interface{})
+	if p, ok := params[name].(map[string]interface{}); ok {
+		if d, ok := p["Default"]; ok {
+			return fmt.Sprint(d), nil
+		}
+		return nil, fmt.Errorf("Parameters: [%s] must have values", name)
+	}
+	if res, ok := st.resources[name]; ok {
+		return res.physicalID, nil
+	}
+	if resources, _ := st.template["Resources"].(map[string]interface{}); resources[name] != nil {
+		return name, nil
+	}
+	return nil, fmt.Errorf("Template format error: Unresolved resource dependencies [%s] in the Resources block of the template", name)
+}
+
+func (st *stack) getAtt(id, attr string) (interface{}, error) {
+	res, ok := st.resources[id]
+	if !ok {
+		return nil, fmt.Errorf("Template error: resource %s does not support attribute type %s in Fn::GetAtt", id, attr)
+	}
+	v, ok := res.data[attr]
+	if !ok {
+		return nil, fmt.Errorf("CustomResource attribute error: Vendor response doesn't contain %s key in object %s", attr, res.physicalID)
+	}
+	return v, nil
+}
+
+func (st *stack) join(args []interface{}) (interface{}, error) {
+	delim, _ := args[0].(string)
+	list, err := st.resolve(args[1])
+	if err != nil {
+		return nil, err
+	}
+	items, _ := list.([]interface{})
+	parts := make([]string, len(items))
+	for i, item := range items {
+		parts[i] = fmt.Sprint(item)
+	}
+	return strings.Join(parts, delim), nil
+}
+
+func (st *stack) sub(arg interface{}) (interface{}, error) {
+	str, _ := arg.(string)
+	vars := map[string]interface{}{}
+	if list, ok := arg.([]interface{}); ok && len(list) == 2 {
+		str, _ = list[0].(string)
+		m, _ := list[1].(map[string]interface{})
+		for k, raw := range m {
+			v, err := st.resolve(raw)
+			if err != nil {
+				return nil, err
+			}
+			vars[k] = v
+		}
+	}
+	var err error
+	out := subVariable.ReplaceAllStringFunc(str, func(m string) string {
+		name := m[2 : len(m)-1]
+		v, ok := vars[name]
+		if !ok {
+			var e error
+			if id, attr, isAtt := strings.Cut(name, "."); isAtt {
+				v, e = st.getAtt(id, attr)
+			} else {
+				v, e = st.ref(name)
+			}
+			if e != nil && err == nil {
+				err = e
+			}
+		}
+		return fmt.Sprint(v)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return strings.ReplaceAll(out, "${!", "${"), nil
+}
+
+// begin starts a stack operation with the given steps, returning the
+// requests to deliver. The caller must hold s.mu.
+func (s *Service) begin(st *stack, op string, steps []step) []delivery {
+	st.operation = op
+	st.status = operations[op].inProgress
+	st.queue = steps
+	st.done = nil
+	return s.advance(st)
+}
+
+// advance sends the stack's next request, if none is awaiting a response,
+// finishing the operation once every step is done. It returns the requests
+// to deliver. The caller must hold s.mu.
+func (s *Service) advance(st *stack) []delivery {
+	for st.pending == nil {
+		if len(st.queue) == 0 {
+			return s.finish(st)
+		}
+		next := st.queue[0]
+		st.queue = st.queue[1:]
+		d, err := s.send(st, next)
+		if err != nil {
+			return s.fail(st, next, err.Error())
+		}
+		if d != nil {
+			return []delivery{*d}
+		}
+	}
+	return nil
+}
+
+// send builds the request for a step and records it as pending. It returns
+// nil if the step needs no request. The caller must hold s.mu.
+func (s *Service) send(st *stack, sp step) (*delivery, error) {
+	def := templateResources(st.template)[sp.logicalID]
+	resourceType, _ := def["Type"].(string)
+	if sp.requestType == "Create" {
+		st.resources[sp.logicalID] = &customResource{logicalID: sp.logicalID, resourceType: resourceType}
+	}
+	res := st.resources[sp.logicalID]
+	event := map[string]interface{}{
+		"RequestType":       sp.requestType,
+		"StackId":           st.arn,
+		"RequestId":         newRequestID(),
+		"LogicalResourceId": sp.logicalID,
+	}
+
+	props := sp.properties
+	if props == nil && sp.requestType != "Delete" {
+		resolved, err := st.resolve(def["Properties"])
+		if err != nil {
+			return nil, err
+		}
+		props, _ = resolved.(map[string]interface{})
+	}
+	if props == nil {
+		props = map[string]interface{}{}
+	}
+	switch sp.requestType {
+	case "Update":
+		if reflect.DeepEqual(props, res.properties) {
+			return nil, nil
+		}
+		event["PhysicalResourceId"] = res.physicalID
+		event["OldResourceProperties"] = res.properties
+		if resourceType != "" {
+			res.resourceType = resourceType
+		}
+	case "Delete":
+		event["PhysicalResourceId"] = res.physicalID
+		if sp.physicalID != "" {
+			event["PhysicalResourceId"] = sp.physicalID
+		}
+	}
+	event["ResourceType"] = res.resourceType
+	event["ResourceProperties"] = props
+	serviceToken, _ := props["ServiceToken"].(string)
+	event["ServiceToken"] = serviceToken
+	if sp.physicalID == "" {
+		res.status = strings.ToUpper(sp.requestType) + "_IN_PROGRESS"
+		res.reason = ""
+		res.updated = s.now()
+	}
+
+	a, err := arn.Parse(serviceToken)
+	if err != nil || (a.Service != "lambda" && a.Service != "sns") {
+		return nil, fmt.Errorf("Invalid service token: %q", serviceToken)
+	}
+	if s.resolve != nil && errors.Is(s.resolve(serviceToken), arn.ErrNotFound) {
+		return nil, fmt.Errorf("Invalid service token: %s does not exist", serviceToken)
+	}
+
+	timeout := defaultServiceTimeout
+	if v, err := strconv.Atoi(fmt.Sprint(props["ServiceTimeout"])); err == nil && v > 0 && v < 3600 {
+		timeout = time.Duration(v) * time.Second
+	}
+	token := h.RandomHex(32)
+	event["ResponseURL"] = s.baseURL + responsePath + token
+	st.pending = &pendingRequest{
+		token:     token,
+		requestID: event["RequestId"].(string),
+		step:      sp,
+		event:     event,
+		deadline:  s.now().Add(timeout),
+	}
+	s.responses[token] = st
+	return &delivery{serviceToken, event}, nil
+}
+
+// succeed records a provider's successful response to the stack's pending
+// request. The caller must hold s.mu.
+func (s *Service) succeed(st *stack, physicalID string, data map[string]interface{}) []delivery {
+	p := st.pending
+	st.pending = nil
+	delete(s.responses, p.token)
+	sp := p.step
+	res := st.resources[sp.logicalID]
+
+	switch {
+	case sp.requestType == "Delete" && sp.physicalID != "":
+		// A replaced resource is cleaned up; the resource itself stays.
+	case sp.requestType == "Delete":
+		delete(st.resources, sp.logicalID)
+	default:
+		if sp.requestType == "Update" {
+			sp.previous = res.properties
+		}
+		if sp.requestType == "Update" && physicalID != res.physicalID && st.operation == "UPDATE" {
+			// A new physical ID replaces the resource, so the old one is
+			// deleted once the update completes.
+			st.cleanup = append(st.cleanup, step{requestType: "Delete", logicalID: sp.logicalID, properties: res.properties, physicalID: res.physicalID})
+		}
+		res.physicalID = physicalID
+		res.data = data
+		res.properties = p.event["ResourceProperties"].(map[string]interface{})
+		res.status = strings.ToUpper(sp.requestType) + "_COMPLETE"
+		res.updated = s.now()
+	}
+	st.done = append(st.done, sp)
+	return s.advance(st)
+}
+
+// fail records the failure of a step and starts the stack's rollback, if
+// its operation has one, returning the requests to deliver. The caller must
+// hold s.mu.
+func (s *Service) fail(st *stack, sp step, reason string) []delivery {
+	var attempted map[string]interface{}
+	if st.pending != nil {
+		attempted, _ = st.pending.event["ResourceProperties"].(map[string]interface{})
+		delete(s.responses, st.pending.token)
+		st.pending = nil
+	}
+	if res := st.resources[sp.logicalID]; res != nil && sp.physicalID == "" {
+		res.status = strings.ToUpper(sp.requestType) + "_FAILED"
+		res.reason = reason
+		res.updated = s.now()
+	}
+
+	st.reason = "The following resource(s) failed to " + strings.ToLower(sp.requestType) + ": [" + sp.logicalID + "]."
+	switch st.operation {
+	case "CREATE":
+		st.reason += " Rollback requested by user."
+		return s.begin(st, "ROLLBACK", s.deleteSteps(st))
+	case "UPDATE":
+		return s.begin(st, "UPDATE_ROLLBACK", s.rollbackSteps(st, sp, attempted))
+	case "CLEANUP":
+		// Cleanup failures leave the resource behind but do not fail the
+		// update.
+		st.reason = ""
+		return s.advance(st)
+	}
+	st.status = operations[st.operation].failed
+	st.queue = nil
+	st.operation = ""
+	return nil
+}
+
+// deleteSteps returns Delete steps for the stack's custom resources that
+// have a physical ID, in the reverse of the order they were created in.
+// The caller must hold s.mu.
+func (s *Service) deleteSteps(st *stack) []step {
+	order, _ := customResourceOrder(st.template)
+	for id := range st.resources {
+		if templateResources(st.template)[id] == nil {
+			order = append(order, id)
+		}
+	}
+	var steps []step
+	for i := len(order) - 1; i >= 0; i-- {
+		res := st.resources[order[i]]
+		if res == nil {
+			continue
+		}
+		if res.physicalID == "" {
+			delete(st.resources, order[i])
+			continue
+		}
+		steps = append(steps, step{requestType: "Delete", logicalID: res.logicalID, properties: res.properties})
+	}
+	return steps
+}
+
+// rollbackSteps returns the steps that undo a failed update, newest
+// first: the resources it created are deleted, and the ones it updated get
+// their previous properties back. That includes the resource that failed,
+// if its provider received the update, with attempted as the properties it
+// is rolled back from. The caller must hold s.mu.
+func (s *Service) rollbackSteps(st *stack, failed step, attempted map[string]interface{}) []step {
+	st.template, st.parameters = st.previousTemplate, st.previousParameters
+	st.cleanup = nil
+	var steps []step
+	if res := st.resources[failed.logicalID]; res != nil {
+		switch {
+		case failed.requestType == "Create":
+			delete(st.resources, failed.logicalID)
+		case failed.requestType == "Update" && attempted != nil:
+			steps = append(steps, step{requestType: "Update", logicalID: failed.logicalID, properties: res.properties})
+			res.properties = attempted
+		}
+	}
+	for i := len(st.done) - 1; i >= 0; i-- {
+		sp := st.done[i]
+		res := st.resources[sp.logicalID]
+		switch {
+		case res == nil:
+		case sp.requestType == "Create":
+			steps = append(steps, step{requestType: "Delete", logicalID: sp.logicalID, properties: res.properties})
+		case sp.requestType == "Update":
+			steps = append(steps, step{requestType: "Update", logicalID: sp.logicalID, properties: sp.previous})
+		}
+	}
+	return steps
+}
+
+// finish completes the stack's operation once all its steps are done. It
+// returns the requests to deliver. The caller must hold s.mu.
+func (s *Service) finish(st *stack) []delivery {
+	op := st.operation
+	if op == "UPDATE" && len(st.cleanup) > 0 {
+		steps := st.cleanup
+		st.cleanup = nil
+		return s.begin(st, "CLEANUP", steps)
+	}
+	st.status = operations[op].complete
+	st.operation = ""
+	st.done = nil
+	switch op {
+	case "CREATE", "UPDATE", "CLEANUP":
+		st.ready = s.transitions.Begin()
+	case "DELETE":
+		delete(s.stacks, st.name)
+		s.tags.Delete(st.arn)
+	}
+	return nil
+}
+
+// settle fails requests whose providers have not responded within their
+// service timeout, returning the requests to deliver next. The caller must
+// hold s.mu.
+func (s *Service) settle() []delivery {
+	now := s.now()
+	var out []delivery
+	for _, st := range s.stacks {
+		for st.pending != nil && !now.Before(st.pending.deadline) {
+			out = append(out, s.fail(st, st.pending.step, "CloudFormation did not receive a response from your Custom Resource. Please check your logs for requestId ["+st.pending.requestID+"].")...)
+		}
+	}
+	return out
+}
+
+// deliver sends requests to their providers. Providers can respond during
+// delivery, which may produce further requests.
+func (s *Service) deliver(deliveries []delivery) {
+	s.mu.RLock()
+	dispatch := s.dispatch
+	s.mu.RUnlock()
+	if dispatch == nil {
+		return
+	}
+	for _, d := range deliveries {
+		payload, _ := json.Marshal(d.event)
+		// Providers that fail without responding time out, as in
+		// CloudFormation, so delivery errors are not acted on.
+		dispatch(d.serviceToken, payload)
+	}
+}
+
+// handleResponse accepts a provider's response at the URL sent in its
+// request, as cfn-response and the custom resource helper libraries PUT it.
+func (s *Service) handleResponse(w http.ResponseWriter, r *http.Request) {
+	token := strings.TrimPrefix(r.URL.Path, responsePath)
+	if r.Method != http.MethodPut {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var resp struct {
+		Status             string
+		Reason             string
+		PhysicalResourceId string
+		StackId            string
+		RequestId          string
+		LogicalResourceId  string
+		Data               map[string]interface{}
+	}
+	body, _ := io.ReadAll(r.Body)
+	if err := json.Unmarshal(body, &resp); err != nil {
+		http.Error(w, "response is not valid JSON", http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	st, ok := s.responses[token]
+	if !ok || st.pending == nil || st.pending.token != token {
+		s.mu.Unlock()
+		http.Error(w, "AccessDenied: the response URL has expired or was already used", http.StatusForbidden)
+		return
+	}
+	p := st.pending
+	if resp.RequestId != p.requestID || resp.StackId != st.arn || resp.LogicalResourceId != p.step.logicalID {
+		s.mu.Unlock()
+		http.Error(w, "StackId, RequestId, and LogicalResourceId must match the request", http.StatusBadRequest)
+		return
+	}
+	data, _ := json.Marshal(resp.Data)
+	var next []delivery
+	switch {
+	case resp.Status == "FAILED":
+		reason := resp.Reason
+		if reason == "" {
+			reason = "Failed to " + strings.ToLower(p.step.requestType) + " resource."
+		}
+		next = s.fail(st, p.step, reason)
+	case resp.Status != "SUCCESS":
+		next = s.fail(st, p.step, "Invalid response status: "+resp.Status)
+	case resp.PhysicalResourceId == "":
+		next = s.fail(st, p.step, "Invalid PhysicalResourceId")
+	case len(data) > maxResponseData:
+		next = s.fail(st, p.step, "Response object is too long.")
+	default:
+		next = s.succeed(st, resp.PhysicalResourceId, resp.Data)
+	}
+	s.mu.Unlock()
+
+	s.deliver(next)
+	w.WriteHeader(http.StatusOK)
+}
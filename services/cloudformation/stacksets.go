@@ -0,0 +1,777 @@
+package cloudformation
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/riyanimam/goto/internal/lifecycle"
+	"github.com/riyanimam/goto/internal/paginate"
+	"github.com/riyanimam/goto/internal/tags"
+)
+
+// stackSetName matches the names CloudFormation accepts for stack sets.
+var stackSetName = regexp.MustCompile(`^[a-zA-Z][-a-zA-Z0-9]{0,127}$`)
+
+// accountID and regionName match the targets stack instances can be
+// deployed to.
+var (
+	accountID  = regexp.MustCompile(`^[0-9]{12}$`)
+	regionName = regexp.MustCompile(`^[a-z]{2}(-gov|-iso[a-z]?)?-[a-z]+-[0-9]$`)
+)
+
+// stackSet is a template deployed as a stack instance to each of a set of
+// accounts and regions. The instances' stacks are not created in the mock;
+// only the stack set's bookkeeping is modelled.
+type stackSet struct {
+	name            string
+	id              string
+	arn             string
+	description     string
+	templateBody    string
+	parameters      map[string]string
+	permissionModel string // SELF_MANAGED or SERVICE_MANAGED
+	adminRoleARN    string
+	execRoleName    string
+	autoDeployment  bool
+	instances       []*stackInstance     // in the order they were deployed
+	operations      []*stackSetOperation // oldest first
+}
+
+type stackInstance struct {
+	account string
+	region  string
+	ouID    string // the deployment target it was found in, if service-managed
+	stackID string
+	// operation is the last operation on the instance; a DELETE removes
+	// the instance once it completes.
+	operation *stackSetOperation
+}
+
+type stackSetOperation struct {
+	id           string
+	action       string // CREATE or DELETE
+	created      time.Time
+	ready        lifecycle.Transition
+	adminRoleARN string
+	execRoleName string
+	retainStacks bool
+}
+
+// memberList reads the list a query request encodes as name.member.N.
+func memberList(r *http.Request, name string) []string {
+	var list []string
+	for i := 1; ; i++ {
+		v, ok := r.Form[fmt.Sprintf("%s.member.%d", name, i)]
+		if !ok {
+			return list
+		}
+		list = append(list, v[0])
+	}
+}
+
+// operationStatus returns the status of op, which runs while its
+// transition is in progress.
+func (s *Service) operationStatus(op *stackSetOperation) string {
+	return s.transitions.Status(op.ready, "RUNNING", "SUCCEEDED")
+}
+
+// liveInstances returns the stack set's instances, less those whose
+// deletion has completed. The caller must hold s.mu.
+func (s *Service) liveInstances(set *stackSet) []*stackInstance {
+	var live []*stackInstance
+	for _, inst := range set.instances {
+		if inst.operation.action == "DELETE" && s.operationStatus(inst.operation) == "SUCCEEDED" {
+			continue
+		}
+		live = append(live, inst)
+	}
+	return live
+}
+
+// running returns the stack set's operation still in progress, if any.
+// The caller must hold s.mu.
+func (s *Service) running(set *stackSet) *stackSetOperation {
+	for _, op := range set.operations {
+		if s.operationStatus(op) == "RUNNING" {
+			return op
+		}
+	}
+	return nil
+}
+
+func (s *Service) createStackSet(w http.ResponseWriter, r *http.Request) {
+	name := r.FormValue("StackSetName")
+	if !stackSetName.MatchString(name) {
+		writeCFError(w, "ValidationError", "StackSetName must start with a letter and contain only letters, numbers, and hyphens", http.StatusBadRequest)
+		return
+	}
+	if _, err := parseTemplate(r.FormValue("TemplateBody")); err != nil || r.FormValue("TemplateBody") == "" {
+		writeCFError(w, "ValidationError", "TemplateBody must be a valid template", http.StatusBadRequest)
+		return
+	}
+	model := r.FormValue("PermissionModel")
+	switch model {
+	case "":
+		model = "SELF_MANAGED"
+	case "SELF_MANAGED", "SERVICE_MANAGED":
+	default:
+		writeCFError(w, "ValidationError", "PermissionModel must be SELF_MANAGED or SERVICE_MANAGED", http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.stackSets[name]; exists {
+		writeCFError(w, "NameAlreadyExistsException", "StackSet "+name+" already exists", http.StatusConflict)
+		return
+	}
+	id := name + ":" + newRequestID()
+	set := &stackSet{
+		name:            name,
+		id:              id,
+		arn:             fmt.Sprintf("arn:aws:cloudformation:us-east-1:%s:stackset/%s", defaultAccountID, id),
+		description:     r.FormValue("Description"),
+		templateBody:    r.FormValue("TemplateBody"),
+		parameters:      parseParameters(r, nil),
+		permissionModel: model,
+		adminRoleARN:    r.FormValue("AdministrationRoleARN"),
+		execRoleName:    r.FormValue("ExecutionRoleName"),
+		autoDeployment:  r.FormValue("AutoDeployment.Enabled") == "true",
+	}
+	if model == "SELF_MANAGED" {
+		if set.adminRoleARN == "" {
+			set.adminRoleARN = fmt.Sprintf("arn:aws:iam::%s:role/AWSCloudFormationStackSetAdministrationRole", defaultAccountID)
+		}
+		if set.execRoleName == "" {
+			set.execRoleName = "AWSCloudFormationStackSetExecutionRole"
+		}
+	}
+	s.stackSets[name] = set
+	s.tags.Tag(set.arn, tags.FromForm(r.Form, "Tags.member."))
+
+	writeXML(w, http.StatusOK, createStackSetResponse{
+		Result:    createStackSetResult{StackSetId: id},
+		RequestID: newRequestID(),
+	})
+}
+
+func (s *Service) describeStackSet(w http.ResponseWriter, r *http.Request) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	set, ok := s.stackSets[r.FormValue("StackSetName")]
+	if !ok {
+		writeCFError(w, "StackSetNotFoundException", "StackSet "+r.FormValue("StackSetName")+" not found", http.StatusNotFound)
+		return
+	}
+
+	x := cfStackSet{
+		StackSetName:          set.name,
+		StackSetId:            set.id,
+		StackSetARN:           set.arn,
+		Description:           set.description,
+		Status:                "ACTIVE",
+		TemplateBody:          set.templateBody,
+		Parameters:            parametersToXML(set.parameters),
+		PermissionModel:       set.permissionModel,
+		AdministrationRoleARN: set.adminRoleARN,
+		ExecutionRoleName:     set.execRoleName,
+		Tags:                  tagsToXML(s.tags.Get(set.arn)),
+	}
+	if set.permissionModel == "SERVICE_MANAGED" {
+		x.AutoDeployment = &cfAutoDeployment{Enabled: set.autoDeployment}
+	}
+	writeXML(w, http.StatusOK, describeStackSetResponse{
+		Result:    describeStackSetResult{StackSet: x},
+		RequestID: newRequestID(),
+	})
+}
+
+func (s *Service) listStackSets(w http.ResponseWriter, r *http.Request) {
+	s.mu.RLock()
+	var summaries []cfStackSetSummary
+	for _, set := range s.stackSets {
+		summaries = append(summaries, cfStackSetSummary{
+			StackSetName:    set.name,
+			StackSetId:      set.id,
+			Description:     set.description,
+			Status:          "ACTIVE",
+			PermissionModel: set.permissionModel,
+		})
+	}
+	s.mu.RUnlock()
+
+	sort.Slice(summaries, func(i, j int) bool {
+		return summaries[i].StackSetName < summaries[j].StackSetName
+	})
+	page, next, ok := pageOf(w, r, summaries, func(i int) string { return summaries[i].StackSetName })
+	if !ok {
+		return
+	}
+	writeXML(w, http.StatusOK, listStackSetsResponse{
+		Result:    listStackSetsResult{Summaries: page, NextToken: next},
+		RequestID: newRequestID(),
+	})
+}
+
+func (s *Service) deleteStackSet(w http.ResponseWriter, r *http.Request) {
+	name := r.FormValue("StackSetName")
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	set, ok := s.stackSets[name]
+	if !ok {
+		writeXML(w, http.StatusOK, deleteStackSetResponse{RequestID: newRequestID()})
+		return
+	}
+	if s.running(set) != nil {
+		writeCFError(w, "OperationInProgressException", "Another Operation on StackSet "+set.id+" is in progress", http.StatusConflict)
+		return
+	}
+	if len(s.liveInstances(set)) > 0 {
+		writeCFError(w, "StackSetNotEmptyException", "StackSet "+name+" is not empty", http.StatusConflict)
+		return
+	}
+	delete(s.stackSets, name)
+	s.tags.Delete(set.arn)
+	writeXML(w, http.StatusOK, deleteStackSetResponse{RequestID: newRequestID()})
+}
+
+// beginOperation starts an operation on the stack set, or writes the error
+// that prevents it. The caller must hold s.mu.
+func (s *Service) beginOperation(w http.ResponseWriter, r *http.Request, set *stackSet, action string) (*stackSetOperation, bool) {
+	if op := s.running(set); op != nil {
+		writeCFError(w, "OperationInProgressException", "Another Operation on StackSet "+set.id+" is in progress: "+op.id, http.StatusConflict)
+		return nil, false
+	}
+	id := r.FormValue("OperationId")
+	if id == "" {
+		id = newRequestID()
+	}
+	for _, op := range set.operations {
+		if op.id == id {
+			writeCFError(w, "OperationIdAlreadyExistsException", "The operation ID "+id+" already exists", http.StatusConflict)
+			return nil, false
+		}
+	}
+	op := &stackSetOperation{
+		id:           id,
+		action:       action,
+		created:      s.now(),
+		ready:        s.transitions.Begin(),
+		adminRoleARN: set.adminRoleARN,
+		execRoleName: set.execRoleName,
+	}
+	set.operations = append(set.operations, op)
+	return op, true
+}
+
+// deploymentTargets resolves the accounts a request deploys to: the
+// accounts it lists for a self-managed stack set, or for a service-managed
+// one the member accounts of the organization under the organizational
+// units it lists, read from the Organizations mock. The returned map gives
+// the organizational unit each account was found under. s.mu must not be
+// held.
+func (s *Service) deploymentTargets(r *http.Request, set *stackSet) ([]string, map[string]string, error) {
+	accounts := memberList(r, "Accounts")
+	accounts = append(accounts, memberList(r, "DeploymentTargets.Accounts")...)
+	ous := memberList(r, "DeploymentTargets.OrganizationalUnitIds")
+
+	if set.permissionModel == "SELF_MANAGED" {
+		if len(ous) > 0 {
+			return nil, nil, fmt.Errorf("OrganizationalUnitIds are only supported by SERVICE_MANAGED stack sets")
+		}
+		if len(accounts) == 0 {
+			return nil, nil, fmt.Errorf("Accounts must be specified for a SELF_MANAGED stack set")
+		}
+		for _, a := range accounts {
+			if !accountID.MatchString(a) {
+				return nil, nil, fmt.Errorf("Account %s is not a valid account ID", a)
+			}
+		}
+		return accounts, nil, nil
+	}
+
+	if len(ous) == 0 {
+		return nil, nil, fmt.Errorf("DeploymentTargets.OrganizationalUnitIds must be specified for a SERVICE_MANAGED stack set")
+	}
+	s.mu.RLock()
+	list := s.listResources
+	s.mu.RUnlock()
+	if list == nil {
+		return nil, nil, fmt.Errorf("no organization is available to deploy to")
+	}
+	parents := make(map[string]string)
+	var members []struct{ id, parent string }
+	for _, res := range list("organizations") {
+		parent, _ := res.Attributes["parent_id"].(string)
+		switch res.Type {
+		case "aws_organizations_organizational_unit":
+			parents[res.ID] = parent
+		case "aws_organizations_account":
+			// The management account is never a stack set target.
+			if res.ID != defaultAccountID {
+				members = append(members, struct{ id, parent string }{res.ID, parent})
+			}
+		}
+	}
+	for _, ou := range ous {
+		if _, ok := parents[ou]; !ok {
+			return nil, nil, fmt.Errorf("Organizational unit %s was not found in the organization", ou)
+		}
+	}
+	sort.Slice(members, func(i, j int) bool { return members[i].id < members[j].id })
+
+	targetOU := make(map[string]string)
+	var found []string
+	for _, m := range members {
+		// An account is a target if one of the units is among its
+		// ancestors.
+		for p := m.parent; p != ""; p = parents[p] {
+			if ou := matchOU(ous, p); ou != "" {
+				found = append(found, m.id)
+				targetOU[m.id] = ou
+				break
+			}
+		}
+	}
+	return found, targetOU, nil
+}
+
+func matchOU(ous []string, id string) string {
+	for _, ou := range ous {
+		if ou == id {
+			return ou
+		}
+	}
+	return ""
+}
+
+// instanceRegions reads and checks the Regions of a stack instances
+// request.
+func instanceRegions(r *http.Request) ([]string, error) {
+	regions := memberList(r, "Regions")
+	if len(regions) == 0 {
+		return nil, fmt.Errorf("Regions must be specified")
+	}
+	for _, region := range regions {
+		if !regionName.MatchString(region) {
+			return nil, fmt.Errorf("Region %s is not a valid region", region)
+		}
+	}
+	return regions, nil
+}
+
+func (s *Service) createStackInstances(w http.ResponseWriter, r *http.Request) {
+	name := r.FormValue("StackSetName")
+	regions, err := instanceRegions(r)
+	if err != nil {
+		writeCFError(w, "ValidationError", err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	s.mu.RLock()
+	set, ok := s.stackSets[name]
+	s.mu.RUnlock()
+	if !ok {
+		writeCFError(w, "StackSetNotFoundException", "StackSet "+name+" not found", http.StatusNotFound)
+		return
+	}
+	// Targets are resolved without s.mu held, as they may come from the
+	// Organizations mock.
+	accounts, ous, err := s.deploymentTargets(r, set)
+	if err != nil {
+		writeCFError(w, "ValidationError", err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.stackSets[name] != set {
+		writeCFError(w, "StackSetNotFoundException", "StackSet "+name+" not found", http.StatusNotFound)
+		return
+	}
+	op, ok := s.beginOperation(w, r, set, "CREATE")
+	if !ok {
+		return
+	}
+	live := s.liveInstances(set)
+	for _, account := range accounts {
+		for _, region := range regions {
+			var inst *stackInstance
+			for _, existing := range live {
+				if existing.account == account && existing.region == region {
+					inst = existing
+				}
+			}
+			if inst == nil {
+				stackID := newRequestID()
+				inst = &stackInstance{
+					account: account,
+					region:  region,
+					ouID:    ous[account],
+					stackID: fmt.Sprintf("arn:aws:cloudformation:%s:%s:stack/StackSet-%s-%s/%s", region, account, set.name, stackID, stackID),
+				}
+				set.instances = append(set.instances, inst)
+				live = append(live, inst)
+			}
+			inst.operation = op
+		}
+	}
+	set.instances = live
+
+	writeXML(w, http.StatusOK, stackInstancesResponse{
+		XMLName:   xml.Name{Local: "CreateStackInstancesResponse"},
+		Result:    stackInstancesResult{XMLName: xml.Name{Local: "CreateStackInstancesResult"}, OperationId: op.id},
+		RequestID: newRequestID(),
+	})
+}
+
+func (s *Service) deleteStackInstances(w http.ResponseWriter, r *http.Request) {
+	name := r.FormValue("StackSetName")
+	regions, err := instanceRegions(r)
+	if err != nil {
+		writeCFError(w, "ValidationError", err.Error(), http.StatusBadRequest)
+		return
+	}
+	retain, err := strconv.ParseBool(r.FormValue("RetainStacks"))
+	if err != nil {
+		writeCFError(w, "ValidationError", "RetainStacks must be true or false", http.StatusBadRequest)
+		return
+	}
+
+	s.mu.RLock()
+	set, ok := s.stackSets[name]
+	s.mu.RUnlock()
+	if !ok {
+		writeCFError(w, "StackSetNotFoundException", "StackSet "+name+" not found", http.StatusNotFound)
+		return
+	}
+	accounts, _, err := s.deploymentTargets(r, set)
+	if err != nil {
+		writeCFError(w, "ValidationError", err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.stackSets[name] != set {
+		writeCFError(w, "StackSetNotFoundException", "StackSet "+name+" not found", http.StatusNotFound)
+		return
+	}
+	op, ok := s.beginOperation(w, r, set, "DELETE")
+	if !ok {
+		return
+	}
+	op.retainStacks = retain
+	live := s.liveInstances(set)
+	for _, inst := range live {
+		for _, account := range accounts {
+			for _, region := range regions {
+				if inst.account == account && inst.region == region {
+					inst.operation = op
+				}
+			}
+		}
+	}
+	set.instances = live
+
+	writeXML(w, http.StatusOK, stackInstancesResponse{
+		XMLName:   xml.Name{Local: "DeleteStackInstancesResponse"},
+		Result:    stackInstancesResult{XMLName: xml.Name{Local: "DeleteStackInstancesResult"}, OperationId: op.id},
+		RequestID: newRequestID(),
+	})
+}
+
+func (s *Service) listStackInstances(w http.ResponseWriter, r *http.Request) {
+	name := r.FormValue("StackSetName")
+	account := r.FormValue("StackInstanceAccount")
+	region := r.FormValue("StackInstanceRegion")
+
+	s.mu.RLock()
+	set, ok := s.stackSets[name]
+	if !ok {
+		s.mu.RUnlock()
+		writeCFError(w, "StackSetNotFoundException", "StackSet "+name+" not found", http.StatusNotFound)
+		return
+	}
+	var summaries []cfStackInstanceSummary
+	for _, inst := range s.liveInstances(set) {
+		if account != "" && inst.account != account || region != "" && inst.region != region {
+			continue
+		}
+		status, detailed := "CURRENT", "SUCCEEDED"
+		if s.operationStatus(inst.operation) == "RUNNING" {
+			status, detailed = "OUTDATED", "RUNNING"
+		}
+		summaries = append(summaries, cfStackInstanceSummary{
+			StackSetId:           set.id,
+			Region:               inst.region,
+			Account:              inst.account,
+			StackId:              inst.stackID,
+			Status:               status,
+			StackInstanceStatus:  cfStackInstanceStatus{DetailedStatus: detailed},
+			OrganizationalUnitId: inst.ouID,
+			DriftStatus:          "NOT_CHECKED",
+			LastOperationId:      inst.operation.id,
+		})
+	}
+	s.mu.RUnlock()
+
+	key := func(i int) string { return paginate.Join(summaries[i].Account, summaries[i].Region) }
+	sort.Slice(summaries, func(i, j int) bool { return key(i) < key(j) })
+	page, next, ok := pageOf(w, r, summaries, key)
+	if !ok {
+		return
+	}
+	writeXML(w, http.StatusOK, listStackInstancesResponse{
+		Result:    listStackInstancesResult{Summaries: page, NextToken: next},
+		RequestID: newRequestID(),
+	})
+}
+
+func (s *Service) describeStackSetOperation(w http.ResponseWriter, r *http.Request) {
+	name := r.FormValue("StackSetName")
+	id := r.FormValue("OperationId")
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	set, ok := s.stackSets[name]
+	if !ok {
+		writeCFError(w, "StackSetNotFoundException", "StackSet "+name+" not found", http.StatusNotFound)
+		return
+	}
+	for _, op := range set.operations {
+		if op.id != id {
+			continue
+		}
+		summary := s.operationSummary(op)
+		writeXML(w, http.StatusOK, describeStackSetOperationResponse{
+			Result: describeStackSetOperationResult{StackSetOperation: cfStackSetOperation{
+				OperationId:           op.id,
+				StackSetId:            set.id,
+				Action:                op.action,
+				Status:                summary.Status,
+				RetainStacks:          op.retainStacks,
+				AdministrationRoleARN: op.adminRoleARN,
+				ExecutionRoleName:     op.execRoleName,
+				CreationTimestamp:     summary.CreationTimestamp,
+				EndTimestamp:          summary.EndTimestamp,
+			}},
+			RequestID: newRequestID(),
+		})
+		return
+	}
+	writeCFError(w, "OperationNotFoundException", "Operation "+id+" not found for StackSet "+name, http.StatusNotFound)
+}
+
+func (s *Service) listStackSetOperations(w http.ResponseWriter, r *http.Request) {
+	name := r.FormValue("StackSetName")
+
+	s.mu.RLock()
+	set, ok := s.stackSets[name]
+	if !ok {
+		s.mu.RUnlock()
+		writeCFError(w, "StackSetNotFoundException", "StackSet "+name+" not found", http.StatusNotFound)
+		return
+	}
+	summaries := make([]cfStackSetOperationSummary, 0, len(set.operations))
+	for i := len(set.operations) - 1; i >= 0; i-- {
+		summaries = append(summaries, s.operationSummary(set.operations[i]))
+	}
+	s.mu.RUnlock()
+
+	// Newest first; operations are never removed, so each is keyed by its
+	// position among them.
+	n := len(summaries)
+	page, next, ok := pageOf(w, r, summaries, func(i int) string { return paginate.Desc(paginate.Seq(n - 1 - i)) })
+	if !ok {
+		return
+	}
+	writeXML(w, http.StatusOK, listStackSetOperationsResponse{
+		Result:    listStackSetOperationsResult{Summaries: page, NextToken: next},
+		RequestID: newRequestID(),
+	})
+}
+
+// operationSummary describes op. The caller must hold s.mu.
+func (s *Service) operationSummary(op *stackSetOperation) cfStackSetOperationSummary {
+	summary := cfStackSetOperationSummary{
+		OperationId:       op.id,
+		Action:            op.action,
+		Status:            s.operationStatus(op),
+		CreationTimestamp: op.created.Format(time.RFC3339),
+	}
+	if summary.Status == "SUCCEEDED" {
+		summary.EndTimestamp = op.created.Format(time.RFC3339)
+	}
+	return summary
+}
+
+// pageOf returns the page of items, sorted by key, that a request's
+// NextToken and MaxResults select, or writes the error for an invalid token.
+func pageOf[T any](w http.ResponseWriter, r *http.Request, items []T, key func(i int) string) ([]T, string, bool) {
+	limit, _ := strconv.Atoi(r.FormValue("MaxResults"))
+	page, next, err := paginate.Page(items, key, r.FormValue("NextToken"), limit, 100)
+	if err != nil {
+		writeCFError(w, "ValidationError", "Invalid NextToken", http.StatusBadRequest)
+		return nil, "", false
+	}
+	return page, next, true
+}
+
+func parametersToXML(params map[string]string) []cfParameter {
+	var list []cfParameter
+	for k, v := range params {
+		list = append(list, cfParameter{ParameterKey: k, ParameterValue: v})
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ParameterKey < list[j].ParameterKey })
+	return list
+}
+
+// XML types.
+
+type cfStackSet struct {
+	StackSetName          string            `xml:"StackSetName"`
+	StackSetId            string            `xml:"StackSetId"`
+	StackSetARN           string            `xml:"StackSetARN"`
+	Description           string            `xml:"Description,omitempty"`
+	Status                string            `xml:"Status"`
+	TemplateBody          string            `xml:"TemplateBody"`
+	Parameters            []cfParameter     `xml:"Parameters>member"`
+	PermissionModel       string            `xml:"PermissionModel"`
+	AdministrationRoleARN string            `xml:"AdministrationRoleARN,omitempty"`
+	ExecutionRoleName     string            `xml:"ExecutionRoleName,omitempty"`
+	AutoDeployment        *cfAutoDeployment `xml:"AutoDeployment,omitempty"`
+	Tags                  []cfTag           `xml:"Tags>member,omitempty"`
+}
+
+type cfAutoDeployment struct {
+	Enabled bool `xml:"Enabled"`
+}
+
+type cfStackSetSummary struct {
+	StackSetName    string `xml:"StackSetName"`
+	StackSetId      string `xml:"StackSetId"`
+	Description     string `xml:"Description,omitempty"`
+	Status          string `xml:"Status"`
+	PermissionModel string `xml:"PermissionModel"`
+}
+
+type cfStackInstanceSummary struct {
+	StackSetId           string                `xml:"StackSetId"`
+	Region               string                `xml:"Region"`
+	Account              string                `xml:"Account"`
+	StackId              string                `xml:"StackId"`
+	Status               string                `xml:"Status"`
+	StackInstanceStatus  cfStackInstanceStatus `xml:"StackInstanceStatus"`
+	OrganizationalUnitId string                `xml:"OrganizationalUnitId,omitempty"`
+	DriftStatus          string                `xml:"DriftStatus"`
+	LastOperationId      string                `xml:"LastOperationId"`
+}
+
+type cfStackInstanceStatus struct {
+	DetailedStatus string `xml:"DetailedStatus"`
+}
+
+type cfStackSetOperation struct {
+	OperationId           string `xml:"OperationId"`
+	StackSetId            string `xml:"StackSetId"`
+	Action                string `xml:"Action"`
+	Status                string `xml:"Status"`
+	RetainStacks          bool   `xml:"RetainStacks"`
+	AdministrationRoleARN string `xml:"AdministrationRoleARN,omitempty"`
+	ExecutionRoleName     string `xml:"ExecutionRoleName,omitempty"`
+	CreationTimestamp     string `xml:"CreationTimestamp"`
+	EndTimestamp          string `xml:"EndTimestamp,omitempty"`
+}
+
+type cfStackSetOperationSummary struct {
+	OperationId       string `xml:"OperationId"`
+	Action            string `xml:"Action"`
+	Status            string `xml:"Status"`
+	CreationTimestamp string `xml:"CreationTimestamp"`
+	EndTimestamp      string `xml:"EndTimestamp,omitempty"`
+}
+
+type createStackSetResponse struct {
+	XMLName   xml.Name             `xml:"CreateStackSetResponse"`
+	Result    createStackSetResult `xml:"CreateStackSetResult"`
+	RequestID string               `xml:"ResponseMetadata>RequestId"`
+}
+type createStackSetResult struct {
+	StackSetId string `xml:"StackSetId"`
+}
+
+type describeStackSetResponse struct {
+	XMLName   xml.Name               `xml:"DescribeStackSetResponse"`
+	Result    describeStackSetResult `xml:"DescribeStackSetResult"`
+	RequestID string                 `xml:"ResponseMetadata>RequestId"`
+}
+type describeStackSetResult struct {
+	StackSet cfStackSet `xml:"StackSet"`
+}
+
+type listStackSetsResponse struct {
+	XMLName   xml.Name            `xml:"ListStackSetsResponse"`
+	Result    listStackSetsResult `xml:"ListStackSetsResult"`
+	RequestID string              `xml:"ResponseMetadata>RequestId"`
+}
+type listStackSetsResult struct {
+	Summaries []cfStackSetSummary `xml:"Summaries>member"`
+	NextToken string              `xml:"NextToken,omitempty"`
+}
+
+type deleteStackSetResponse struct {
+	XMLName   xml.Name `xml:"DeleteStackSetResponse"`
+	RequestID string   `xml:"ResponseMetadata>RequestId"`
+}
+
+type stackInstancesResponse struct {
+	XMLName   xml.Name
+	Result    stackInstancesResult
+	RequestID string `xml:"ResponseMetadata>RequestId"`
+}
+type stackInstancesResult struct {
+	XMLName     xml.Name
+	OperationId string `xml:"OperationId"`
+}
+
+type listStackInstancesResponse struct {
+	XMLName   xml.Name                 `xml:"ListStackInstancesResponse"`
+	Result    listStackInstancesResult `xml:"ListStackInstancesResult"`
+	RequestID string                   `xml:"ResponseMetadata>RequestId"`
+}
+type listStackInstancesResult struct {
+	Summaries []cfStackInstanceSummary `xml:"Summaries>member"`
+	NextToken string                   `xml:"NextToken,omitempty"`
+}
+
+type describeStackSetOperationResponse struct {
+	XMLName   xml.Name                        `xml:"DescribeStackSetOperationResponse"`
+	Result    describeStackSetOperationResult `xml:"DescribeStackSetOperationResult"`
+	RequestID string                          `xml:"ResponseMetadata>RequestId"`
+}
+type describeStackSetOperationResult struct {
+	StackSetOperation cfStackSetOperation `xml:"StackSetOperation"`
+}
+
+type listStackSetOperationsResponse struct {
+	XMLName   xml.Name                     `xml:"ListStackSetOperationsResponse"`
+	Result    listStackSetOperationsResult `xml:"ListStackSetOperationsResult"`
+	RequestID string                       `xml:"ResponseMetadata>RequestId"`
+}
+type listStackSetOperationsResult struct {
+	Summaries []cfStackSetOperationSummary `xml:"Summaries>member"`
+	NextToken string                       `xml:"NextToken,omitempty"`
+}
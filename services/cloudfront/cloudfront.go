@@ -2,10 +2,21 @@
 //
 // Supported actions:
 //   - CreateDistribution
+//   - CreateDistributionWithTags
 //   - GetDistribution
 //   - DeleteDistribution
 //   - ListDistributions
 //   - UpdateDistribution
+//   - CreateInvalidation
+//   - GetInvalidation
+//   - ListInvalidations
+//   - TagResource
+//   - UntagResource
+//   - ListTagsForResource
+//
+// GET and HEAD requests routed to the service for a distribution's host are
+// answered from its S3 origins through an edge cache; see
+// [Service.SetObjectStore].
 package cloudfront
 
 import (
@@ -18,33 +29,43 @@ import (
 	"sync"
 	"time"
 
+	"github.com/riyanimam/goto/internal/clock"
 	h "github.com/riyanimam/goto/internal/mockhelpers"
+	"github.com/riyanimam/goto/internal/tags"
 )
 
 // Service implements the CloudFront mock.
 type Service struct {
 	mu            sync.RWMutex
 	distributions map[string]*distribution
+	store         h.ObjectStore
+	clock         *clock.Clock
+	tags          *tags.Store
 }
 
 type distribution struct {
-	id           string
-	arn          string
-	domainName   string
-	status       string
-	enabled      bool
-	comment      string
-	etag         string
-	originDomain string
-	originID     string
-	created      time.Time
-	modified     time.Time
+	id              string
+	arn             string
+	domainName      string
+	status          string
+	enabled         bool
+	comment         string
+	etag            string
+	rootObject      string
+	origins         []Origin
+	defaultBehavior *CacheBehavior
+	behaviors       []CacheBehavior
+	invalidations   []*invalidation
+	cache           map[string]*cachedObject
+	created         time.Time
+	modified        time.Time
 }
 
 // New creates a new CloudFront mock service.
 func New() *Service {
 	return &Service{
 		distributions: make(map[string]*distribution),
+		tags:          tags.New(),
 	}
 }
 
@@ -61,17 +82,45 @@ func (s *Service) Reset() {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	s.distributions = make(map[string]*distribution)
+	s.tags.DeleteService("cloudfront")
+}
+
+// SetClock attaches the mock clock that cached objects expire by.
+func (s *Service) SetClock(c *clock.Clock) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.clock = c
+}
+
+func (s *Service) now() time.Time {
+	if s.clock != nil {
+		return s.clock.Now()
+	}
+	return time.Now().UTC()
 }
 
 func (s *Service) handle(w http.ResponseWriter, r *http.Request) {
 	path := r.URL.Path
 	method := r.Method
 
+	if id := distributionHost(r.Host); id != "" {
+		s.serveContent(w, r, id)
+		return
+	}
+
 	switch {
+	case path == "/2020-05-31/tagging":
+		s.handleTagging(w, r)
 	case path == "/2020-05-31/distribution" && method == http.MethodPost:
 		s.createDistribution(w, r)
 	case path == "/2020-05-31/distribution" && method == http.MethodGet:
 		s.listDistributions(w, r)
+	case strings.HasSuffix(path, "/invalidation") && method == http.MethodPost:
+		s.createInvalidation(w, r, invalidationDistID(path))
+	case strings.HasSuffix(path, "/invalidation") && method == http.MethodGet:
+		s.listInvalidations(w, r, invalidationDistID(path))
+	case strings.Contains(path, "/invalidation/") && method == http.MethodGet:
+		s.getInvalidation(w, r, invalidationDistID(path), path[strings.LastIndex(path, "/")+1:])
 	case strings.HasPrefix(path, "/2020-05-31/distribution/") && method == http.MethodGet:
 		id := extractDistID(path)
 		s.getDistribution(w, r, id)
@@ -97,11 +146,14 @@ func extractDistID(path string) string {
 
 // DistributionConfig represents the XML input for create/update.
 type DistributionConfig struct {
-	XMLName         xml.Name `xml:"DistributionConfig"`
-	CallerReference string   `xml:"CallerReference"`
-	Comment         string   `xml:"Comment"`
-	Enabled         bool     `xml:"Enabled"`
-	Origins         *Origins `xml:"Origins"`
+	XMLName              xml.Name        `xml:"DistributionConfig"`
+	CallerReference      string          `xml:"CallerReference"`
+	Comment              string          `xml:"Comment"`
+	Enabled              bool            `xml:"Enabled"`
+	DefaultRootObject    string          `xml:"DefaultRootObject"`
+	Origins              *Origins        `xml:"Origins"`
+	DefaultCacheBehavior *CacheBehavior  `xml:"DefaultCacheBehavior"`
+	CacheBehaviors       *CacheBehaviors `xml:"CacheBehaviors"`
 }
 
 // Origins represents the Origins section.
@@ -113,13 +165,63 @@ type Origins struct {
 type Origin struct {
 	DomainName string `xml:"DomainName"`
 	Id         string `xml:"Id"`
+	OriginPath string `xml:"OriginPath"`
+}
+
+// CacheBehaviors represents the CacheBehaviors section.
+type CacheBehaviors struct {
+	Quantity int             `xml:"Quantity"`
+	Items    []CacheBehavior `xml:"Items>CacheBehavior"`
+}
+
+// CacheBehavior represents the default cache behavior or one matched by
+// path pattern.
+type CacheBehavior struct {
+	PathPattern          string           `xml:"PathPattern,omitempty"`
+	TargetOriginId       string           `xml:"TargetOriginId"`
+	ViewerProtocolPolicy string           `xml:"ViewerProtocolPolicy"`
+	CachePolicyId        string           `xml:"CachePolicyId,omitempty"`
+	ForwardedValues      *ForwardedValues `xml:"ForwardedValues"`
+	MinTTL               *int64           `xml:"MinTTL"`
+	DefaultTTL           *int64           `xml:"DefaultTTL"`
+	MaxTTL               *int64           `xml:"MaxTTL"`
+}
+
+// ForwardedValues represents the legacy cache key settings of a behavior.
+type ForwardedValues struct {
+	QueryString          bool     `xml:"QueryString"`
+	QueryStringCacheKeys *Names   `xml:"QueryStringCacheKeys"`
+	Headers              *Names   `xml:"Headers"`
+	Cookies              *Cookies `xml:"Cookies"`
+}
+
+// Cookies represents the cookies forwarded to the origin: none, all, or the
+// whitelisted names.
+type Cookies struct {
+	Forward          string `xml:"Forward"`
+	WhitelistedNames *Names `xml:"WhitelistedNames"`
+}
+
+// Names represents a list of header, cookie, or query string names.
+type Names struct {
+	Quantity int      `xml:"Quantity"`
+	Items    []string `xml:"Items>Name"`
 }
 
 func (s *Service) createDistribution(w http.ResponseWriter, r *http.Request) {
 	bodyBytes, _ := io.ReadAll(r.Body)
 
+	// CreateDistributionWithTags wraps the config together with its tags.
 	var cfg DistributionConfig
-	if err := xml.Unmarshal(bodyBytes, &cfg); err != nil {
+	var withTags distributionConfigWithTags
+	var err error
+	if r.URL.Query().Has("WithTags") {
+		err = xml.Unmarshal(bodyBytes, &withTags)
+		cfg = withTags.DistributionConfig
+	} else {
+		err = xml.Unmarshal(bodyBytes, &cfg)
+	}
+	if err != nil {
 		h.WriteXMLError(w, "Sender", "MalformedXML", "could not parse request body", http.StatusBadRequest)
 		return
 	}
@@ -130,26 +232,20 @@ func (s *Service) createDistribution(w http.ResponseWriter, r *http.Request) {
 	etag := "E" + h.RandomID(14)
 	now := time.Now().UTC()
 
-	var originDomain, originID string
-	if cfg.Origins != nil && len(cfg.Origins.Items) > 0 {
-		originDomain = cfg.Origins.Items[0].DomainName
-		originID = cfg.Origins.Items[0].Id
-	}
-
 	dist := &distribution{
-		id:           id,
-		arn:          arn,
-		domainName:   id + ".cloudfront.net",
-		status:       "Deployed",
-		enabled:      cfg.Enabled,
-		comment:      cfg.Comment,
-		etag:         etag,
-		originDomain: originDomain,
-		originID:     originID,
-		created:      now,
-		modified:     now,
+		id:         id,
+		arn:        arn,
+		domainName: id + ".cloudfront.net",
+		status:     "Deployed",
+		enabled:    cfg.Enabled,
+		comment:    cfg.Comment,
+		etag:       etag,
+		created:    now,
+		modified:   now,
 	}
+	dist.configure(&cfg)
 	s.distributions[id] = dist
+	s.tags.Tag(arn, withTags.Tags.toMap())
 	s.mu.Unlock()
 
 	w.Header().Set("ETag", etag)
@@ -172,11 +268,13 @@ func (s *Service) getDistribution(w http.ResponseWriter, _ *http.Request, id str
 
 func (s *Service) deleteDistribution(w http.ResponseWriter, _ *http.Request, id string) {
 	s.mu.Lock()
-	if _, exists := s.distributions[id]; !exists {
+	dist, exists := s.distributions[id]
+	if !exists {
 		s.mu.Unlock()
 		h.WriteXMLError(w, "Sender", "NoSuchDistribution", "Distribution "+id+" not found", http.StatusNotFound)
 		return
 	}
+	s.tags.Delete(dist.arn)
 	delete(s.distributions, id)
 	s.mu.Unlock()
 
@@ -238,16 +336,30 @@ func (s *Service) updateDistribution(w http.ResponseWriter, r *http.Request, id
 	dist.modified = time.Now().UTC()
 	dist.etag = "E" + h.RandomID(14)
 
-	if cfg.Origins != nil && len(cfg.Origins.Items) > 0 {
-		dist.originDomain = cfg.Origins.Items[0].DomainName
-		dist.originID = cfg.Origins.Items[0].Id
-	}
+	dist.configure(&cfg)
 	s.mu.Unlock()
 
 	w.Header().Set("ETag", dist.etag)
 	h.WriteXML(w, http.StatusOK, distFullResp(dist))
 }
 
+// configure applies the origins, cache behaviors, and default root object
+// of cfg, as a full distribution config replaces them.
+func (dist *distribution) configure(cfg *DistributionConfig) {
+	if cfg.Origins != nil && len(cfg.Origins.Items) > 0 {
+		dist.origins = cfg.Origins.Items
+	}
+	if cfg.DefaultCacheBehavior == nil {
+		return
+	}
+	dist.rootObject = cfg.DefaultRootObject
+	dist.defaultBehavior = cfg.DefaultCacheBehavior
+	dist.behaviors = nil
+	if cfg.CacheBehaviors != nil {
+		dist.behaviors = cfg.CacheBehaviors.Items
+	}
+}
+
 type distSummary struct {
 	XMLName    xml.Name `xml:"DistributionSummary"`
 	Id         string   `xml:"Id"`
@@ -269,15 +381,15 @@ type distFullResponse struct {
 }
 
 type distConfig struct {
-	Enabled bool   `xml:"Enabled"`
-	Comment string `xml:"Comment"`
-	Origins struct {
-		Items []struct {
-			DomainName string `xml:"DomainName"`
-			Id         string `xml:"Id"`
-		} `xml:"Items>Origin"`
-		Quantity int `xml:"Quantity"`
+	Enabled           bool   `xml:"Enabled"`
+	Comment           string `xml:"Comment"`
+	DefaultRootObject string `xml:"DefaultRootObject"`
+	Origins           struct {
+		Items    []Origin `xml:"Items>Origin"`
+		Quantity int      `xml:"Quantity"`
 	} `xml:"Origins"`
+	DefaultCacheBehavior *CacheBehavior `xml:"DefaultCacheBehavior"`
+	CacheBehaviors       CacheBehaviors `xml:"CacheBehaviors"`
 }
 
 func distFullResp(dist *distribution) distFullResponse {
@@ -290,12 +402,13 @@ func distFullResp(dist *distribution) distFullResponse {
 	}
 	resp.DistConfig.Enabled = dist.enabled
 	resp.DistConfig.Comment = dist.comment
-	resp.DistConfig.Origins.Quantity = 1
-	resp.DistConfig.Origins.Items = []struct {
-		DomainName string `xml:"DomainName"`
-		Id         string `xml:"Id"`
-	}{
-		{DomainName: dist.originDomain, Id: dist.originID},
+	resp.DistConfig.DefaultRootObject = dist.rootObject
+	resp.DistConfig.Origins.Quantity = len(dist.origins)
+	resp.DistConfig.Origins.Items = dist.origins
+	resp.DistConfig.DefaultCacheBehavior = dist.defaultBehavior
+	resp.DistConfig.CacheBehaviors = CacheBehaviors{
+		Quantity: len(dist.behaviors),
+		Items:    dist.behaviors,
 	}
 	return resp
 }
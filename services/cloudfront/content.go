@@ -0,0 +1,294 @@
+package cloudfront
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	h "github.com/riyanimam/goto/internal/mockhelpers"
+)
+
+// cachingDisabledPolicy is the ID of the managed CachingDisabled cache
+// policy. Other cache policies, such as CachingOptimized, are treated as
+// caching for a day with a cache key of the path alone.
+const cachingDisabledPolicy = "4135ea2d-6df8-44a3-9df3-4b5a84be39ad"
+
+// defaultTTL is how long objects are cached when a behavior sets no TTL.
+const defaultTTL = 24 * time.Hour
+
+// cachedObject is an origin response held in a distribution's edge cache.
+type cachedObject struct {
+	path        string
+	body        []byte
+	contentType string
+	etag        string
+	stored      time.Time
+	expires     time.Time
+}
+
+// SetObjectStore sets the store that S3 origins are read from when requests
+// for a distribution's host, {id}.cloudfront.localhost or
+// {id}.cloudfront.net, are routed to the service.
+//
+// Each request is matched against the cache behaviors' path patterns in
+// order, falling back to the default behavior, and answered from the edge
+// cache under the behavior's cache key or from its target origin. The cache
+// key is the path plus the query string, headers, and cookies the behavior
+// forwards; cached objects are served until their TTL passes or an
+// invalidation removes them. The default root object is served for "/" only.
+func (s *Service) SetObjectStore(store h.ObjectStore) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.store = store
+}
+
+// distributionHost returns the distribution ID addressed by a Host header
+// such as "e1abc.cloudfront.localhost:8080" or "e1abc.cloudfront.net", or ""
+// for any other host.
+func distributionHost(host string) string {
+	if hostname, _, err := net.SplitHostPort(host); err == nil {
+		host = hostname
+	}
+	id, rest, ok := strings.Cut(host, ".")
+	if !ok || (rest != "cloudfront.localhost" && rest != "cloudfront.net") {
+		return ""
+	}
+	return strings.ToUpper(id)
+}
+
+func (s *Service) serveContent(w http.ResponseWriter, r *http.Request, id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	dist, exists := s.distributions[id]
+	if !exists || s.store == nil {
+		writeContentError(w, http.StatusNotFound, "The request could not be satisfied: no distribution serves this host.")
+		return
+	}
+	if !dist.enabled {
+		writeContentError(w, http.StatusForbidden, "The request could not be satisfied: the distribution is disabled.")
+		return
+	}
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		writeContentError(w, http.StatusForbidden, "This distribution is not configured to allow the HTTP request method that was used for this request.")
+		return
+	}
+
+	requestPath := r.URL.Path
+	if requestPath == "/" && dist.rootObject != "" {
+		requestPath = "/" + strings.TrimPrefix(dist.rootObject, "/")
+	}
+	behavior := dist.behaviorFor(requestPath)
+	if behavior == nil {
+		writeContentError(w, http.StatusBadGateway, "The request could not be satisfied: the distribution has no cache behavior.")
+		return
+	}
+
+	now := s.now()
+	key := behavior.cacheKey(requestPath, r)
+	if obj, hit := dist.cache[key]; hit && now.Before(obj.expires) {
+		w.Header().Set("X-Cache", "Hit from cloudfront")
+		w.Header().Set("Age", strconv.Itoa(int(now.Sub(obj.stored).Seconds())))
+		writeContent(w, r, obj)
+		return
+	}
+
+	origin := dist.origin(behavior.TargetOriginId)
+	bucket := originBucket(origin)
+	if bucket == "" {
+		writeContentError(w, http.StatusBadGateway, "The request could not be satisfied: only S3 origins are served.")
+		return
+	}
+	objectKey := strings.TrimPrefix(strings.TrimSuffix(origin.OriginPath, "/")+requestPath, "/")
+	body, err := s.store.GetObject(bucket, objectKey)
+	if err != nil {
+		w.Header().Set("X-Cache", "Error from cloudfront")
+		h.WriteXMLError(w, "Sender", "NoSuchKey", "The specified key does not exist.", http.StatusNotFound)
+		return
+	}
+	sum := md5.Sum(body)
+	obj := &cachedObject{
+		path:        requestPath,
+		body:        body,
+		contentType: mime.TypeByExtension(path.Ext(objectKey)),
+		etag:        `"` + hex.EncodeToString(sum[:]) + `"`,
+		stored:      now,
+		expires:     now.Add(behavior.ttl()),
+	}
+	if obj.contentType == "" {
+		obj.contentType = "binary/octet-stream"
+	}
+	if obj.expires.After(now) {
+		if dist.cache == nil {
+			dist.cache = make(map[string]*cachedObject)
+		}
+		dist.cache[key] = obj
+	}
+	w.Header().Set("X-Cache", "Miss from cloudfront")
+	writeContent(w, r, obj)
+}
+
+func writeContent(w http.ResponseWriter, r *http.Request, obj *cachedObject) {
+	w.Header().Set("Content-Type", obj.contentType)
+	w.Header().Set("Content-Length", strconv.Itoa(len(obj.body)))
+	w.Header().Set("ETag", obj.etag)
+	w.Header().Set("Via", "1.1 "+h.RandomHex(16)+".cloudfront.net (CloudFront)")
+	w.Header().Set("X-Amz-Cf-Id", h.NewRequestID())
+	w.WriteHeader(http.StatusOK)
+	if r.Method != http.MethodHead {
+		w.Write(obj.body)
+	}
+}
+
+func writeContentError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("X-Cache", "Error from cloudfront")
+	w.WriteHeader(status)
+	w.Write([]byte(message + "\n"))
+}
+
+// behaviorFor returns the first cache behavior whose path pattern matches
+// path, or the default behavior.
+func (dist *distribution) behaviorFor(path string) *CacheBehavior {
+	for i := range dist.behaviors {
+		if matchPathPattern(dist.behaviors[i].PathPattern, path) {
+			return &dist.behaviors[i]
+		}
+	}
+	return dist.defaultBehavior
+}
+
+func (dist *distribution) origin(id string) Origin {
+	for _, o := range dist.origins {
+		if o.Id == id {
+			return o
+		}
+	}
+	return Origin{}
+}
+
+// originBucket returns the bucket of an S3 origin domain, such as
+// "assets.s3.amazonaws.com", "assets.s3.us-east-1.amazonaws.com", or
+// "assets.s3-website-us-east-1.amazonaws.com", or "" for other origins.
+func originBucket(o Origin) string {
+	i := strings.Index(o.DomainName, ".s3")
+	if i <= 0 || len(o.DomainName) == i+3 {
+		return ""
+	}
+	if c := o.DomainName[i+3]; c != '.' && c != '-' {
+		return ""
+	}
+	return o.DomainName[:i]
+}
+
+// matchPathPattern reports whether path matches a cache behavior path
+// pattern, in which * matches any run of characters, slashes included, and ?
+// any one character. Patterns need not start with a slash.
+func matchPathPattern(pattern, path string) bool {
+	pattern = "/" + strings.TrimPrefix(pattern, "/")
+	p, s := 0, 0
+	star, match := -1, 0
+	for s < len(path) {
+		switch {
+		case p < len(pattern) && (pattern[p] == '?' || pattern[p] == path[s]):
+			p++
+			s++
+		case p < len(pattern) && pattern[p] == '*':
+			star, match = p, s
+			p++
+		case star >= 0:
+			match++
+			p, s = star+1, match
+		default:
+			return false
+		}
+	}
+	for p < len(pattern) && pattern[p] == '*' {
+		p++
+	}
+	return p == len(pattern)
+}
+
+// ttl returns how long responses under the behavior are cached.
+func (b *CacheBehavior) ttl() time.Duration {
+	if b.CachePolicyId == cachingDisabledPolicy {
+		return 0
+	}
+	if fv := b.ForwardedValues; fv != nil && fv.Headers != nil {
+		for _, name := range fv.Headers.Items {
+			// Forwarding every header makes every request unique.
+			if name == "*" {
+				return 0
+			}
+		}
+	}
+	if b.DefaultTTL != nil {
+		return time.Duration(*b.DefaultTTL) * time.Second
+	}
+	return defaultTTL
+}
+
+// cacheKey returns the key the behavior caches the response to r under: the
+// path, then the forwarded query string parameters in request order, then
+// the forwarded headers and cookies. Cache policies other than the legacy
+// forwarded values key on the path alone.
+func (b *CacheBehavior) cacheKey(path string, r *http.Request) string {
+	fv := b.ForwardedValues
+	if b.CachePolicyId != "" || fv == nil {
+		return path
+	}
+	key := []string{path}
+
+	if fv.QueryString && r.URL.RawQuery != "" {
+		var allowed map[string]bool
+		if fv.QueryStringCacheKeys != nil && len(fv.QueryStringCacheKeys.Items) > 0 {
+			allowed = make(map[string]bool)
+			for _, name := range fv.QueryStringCacheKeys.Items {
+				allowed[name] = true
+			}
+		}
+		var params []string
+		for _, param := range strings.Split(r.URL.RawQuery, "&") {
+			name, _, _ := strings.Cut(param, "=")
+			if name, err := url.QueryUnescape(name); err == nil && (allowed == nil || allowed[name]) {
+				params = append(params, param)
+			}
+		}
+		key = append(key, "?"+strings.Join(params, "&"))
+	}
+	if fv.Headers != nil {
+		for _, name := range fv.Headers.Items {
+			key = append(key, strings.ToLower(name)+":"+r.Header.Get(name))
+		}
+	}
+	if fv.Cookies != nil {
+		var cookies []string
+		for _, c := range r.Cookies() {
+			if fv.Cookies.Forward == "all" || fv.Cookies.Forward == "whitelist" && fv.Cookies.WhitelistedNames != nil && contains(fv.Cookies.WhitelistedNames.Items, c.Name) {
+				cookies = append(cookies, c.Name+"="+c.Value)
+			}
+		}
+		sort.Strings(cookies)
+		if len(cookies) > 0 {
+			key = append(key, "cookie:"+strings.Join(cookies, ";"))
+		}
+	}
+	return strings.Join(key, "\n")
+}
+
+func contains(list []string, v string) bool {
+	for _, item := range list {
+		if item == v {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,178 @@
+package cloudfront
+
+import (
+	"encoding/xml"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	h "github.com/riyanimam/goto/internal/mockhelpers"
+)
+
+// invalidation removes the cached objects whose paths match any of its
+// paths. Invalidations complete as soon as they are created.
+type invalidation struct {
+	id              string
+	callerReference string
+	paths           []string
+	created         time.Time
+}
+
+// InvalidationBatch represents the XML input for CreateInvalidation.
+type InvalidationBatch struct {
+	XMLName         xml.Name `xml:"InvalidationBatch"`
+	Paths           Paths    `xml:"Paths"`
+	CallerReference string   `xml:"CallerReference"`
+}
+
+// Paths represents the paths of an invalidation batch.
+type Paths struct {
+	Quantity int      `xml:"Quantity"`
+	Items    []string `xml:"Items>Path"`
+}
+
+type invalidationResponse struct {
+	XMLName    xml.Name          `xml:"Invalidation"`
+	Id         string            `xml:"Id"`
+	Status     string            `xml:"Status"`
+	CreateTime string            `xml:"CreateTime"`
+	Batch      InvalidationBatch `xml:"InvalidationBatch"`
+}
+
+type invalidationSummary struct {
+	Id         string `xml:"Id"`
+	CreateTime string `xml:"CreateTime"`
+	Status     string `xml:"Status"`
+}
+
+// invalidationDistID returns the distribution ID of an invalidation path,
+// /2020-05-31/distribution/{id}/invalidation[/{invalidationId}].
+func invalidationDistID(path string) string {
+	rest := strings.TrimPrefix(path, "/2020-05-31/distribution/")
+	id, _, _ := strings.Cut(rest, "/")
+	return id
+}
+
+func (inv *invalidation) toResponse() invalidationResponse {
+	return invalidationResponse{
+		Id:         inv.id,
+		Status:     "Completed",
+		CreateTime: inv.created.Format(time.RFC3339),
+		Batch: InvalidationBatch{
+			Paths:           Paths{Quantity: len(inv.paths), Items: inv.paths},
+			CallerReference: inv.callerReference,
+		},
+	}
+}
+
+func writeNoSuchDistribution(w http.ResponseWriter, id string) {
+	h.WriteXMLError(w, "Sender", "NoSuchDistribution", "Distribution "+id+" not found", http.StatusNotFound)
+}
+
+func (s *Service) createInvalidation(w http.ResponseWriter, r *http.Request, id string) {
+	bodyBytes, _ := io.ReadAll(r.Body)
+
+	var batch InvalidationBatch
+	if err := xml.Unmarshal(bodyBytes, &batch); err != nil {
+		h.WriteXMLError(w, "Sender", "MalformedXML", "could not parse request body", http.StatusBadRequest)
+		return
+	}
+	if len(batch.Paths.Items) == 0 || batch.CallerReference == "" {
+		h.WriteXMLError(w, "Sender", "InvalidArgument", "Paths and CallerReference are required.", http.StatusBadRequest)
+		return
+	}
+	for _, p := range batch.Paths.Items {
+		if !strings.HasPrefix(p, "/") || strings.Contains(strings.TrimSuffix(p, "*"), "*") {
+			h.WriteXMLError(w, "Sender", "InvalidArgument", "Invalid path "+p+": paths start with / and may end with a * wildcard.", http.StatusBadRequest)
+			return
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	dist, exists := s.distributions[id]
+	if !exists {
+		writeNoSuchDistribution(w, id)
+		return
+	}
+	inv := &invalidation{
+		id:              "I" + strings.ToUpper(h.RandomID(13)),
+		callerReference: batch.CallerReference,
+		paths:           batch.Paths.Items,
+		created:         s.now(),
+	}
+	dist.invalidations = append(dist.invalidations, inv)
+	for key, obj := range dist.cache {
+		if inv.covers(obj.path) {
+			delete(dist.cache, key)
+		}
+	}
+
+	w.Header().Set("Location", "https://cloudfront.amazonaws.com/2020-05-31/distribution/"+id+"/invalidation/"+inv.id)
+	h.WriteXML(w, http.StatusCreated, inv.toResponse())
+}
+
+// covers reports whether any of the invalidation's paths, which may end in
+// a * wildcard, matches path.
+func (inv *invalidation) covers(path string) bool {
+	for _, p := range inv.paths {
+		if prefix, wildcard := strings.CutSuffix(p, "*"); wildcard && strings.HasPrefix(path, prefix) || p == path {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Service) getInvalidation(w http.ResponseWriter, _ *http.Request, id, invalidationID string) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	dist, exists := s.distributions[id]
+	if !exists {
+		writeNoSuchDistribution(w, id)
+		return
+	}
+	for _, inv := range dist.invalidations {
+		if inv.id == invalidationID {
+			h.WriteXML(w, http.StatusOK, inv.toResponse())
+			return
+		}
+	}
+	h.WriteXMLError(w, "Sender", "NoSuchInvalidation", "Invalidation "+invalidationID+" not found", http.StatusNotFound)
+}
+
+func (s *Service) listInvalidations(w http.ResponseWriter, _ *http.Request, id string) {
+	s.mu.RLock()
+	dist, exists := s.distributions[id]
+	if !exists {
+		s.mu.RUnlock()
+		writeNoSuchDistribution(w, id)
+		return
+	}
+	// Newest first, as CloudFront lists them.
+	items := make([]invalidationSummary, 0, len(dist.invalidations))
+	for i := len(dist.invalidations) - 1; i >= 0; i-- {
+		inv := dist.invalidations[i]
+		items = append(items, invalidationSummary{
+			Id:         inv.id,
+			CreateTime: inv.created.Format(time.RFC3339),
+			Status:     "Completed",
+		})
+	}
+	s.mu.RUnlock()
+
+	type invalidationList struct {
+		XMLName     xml.Name              `xml:"InvalidationList"`
+		Marker      string                `xml:"Marker"`
+		MaxItems    int                   `xml:"MaxItems"`
+		IsTruncated bool                  `xml:"IsTruncated"`
+		Quantity    int                   `xml:"Quantity"`
+		Items       []invalidationSummary `xml:"Items>InvalidationSummary"`
+	}
+
+	h.WriteXML(w, http.StatusOK, invalidationList{
+		MaxItems: 100,
+		Quantity: len(items),
+		Items:    items,
+	})
+}
@@ -0,0 +1,106 @@
+package cloudfront
+
+import (
+	"encoding/xml"
+	"io"
+	"net/http"
+	"sort"
+
+	h "github.com/riyanimam/goto/internal/mockhelpers"
+	"github.com/riyanimam/goto/internal/tags"
+)
+
+// SetTagStore sets the registry distribution tags are recorded in.
+func (s *Service) SetTagStore(store *tags.Store) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tags = store
+}
+
+// distributionConfigWithTags is the body of CreateDistributionWithTags.
+type distributionConfigWithTags struct {
+	XMLName            xml.Name           `xml:"DistributionConfigWithTags"`
+	DistributionConfig DistributionConfig `xml:"DistributionConfig"`
+	Tags               tagSet             `xml:"Tags"`
+}
+
+// tagSet is the Tags element of tagging requests and responses.
+type tagSet struct {
+	XMLName xml.Name `xml:"Tags"`
+	Items   []tagXML `xml:"Items>Tag"`
+}
+
+type tagXML struct {
+	Key   string `xml:"Key"`
+	Value string `xml:"Value"`
+}
+
+func (t tagSet) toMap() map[string]string {
+	m := make(map[string]string, len(t.Items))
+	for _, tag := range t.Items {
+		m[tag.Key] = tag.Value
+	}
+	return m
+}
+
+// tagSetOf lists tags sorted by key.
+func tagSetOf(m map[string]string) tagSet {
+	t := tagSet{Items: make([]tagXML, 0, len(m))}
+	for k, v := range m {
+		t.Items = append(t.Items, tagXML{Key: k, Value: v})
+	}
+	sort.Slice(t.Items, func(i, j int) bool { return t.Items[i].Key < t.Items[j].Key })
+	return t
+}
+
+// tagKeys is the body of UntagResource.
+type tagKeys struct {
+	Items []string `xml:"Items>Key"`
+}
+
+// handleTagging serves /2020-05-31/tagging: TagResource (POST with
+// Operation=Tag), UntagResource (POST with Operation=Untag), and
+// ListTagsForResource (GET). Distributions are the taggable resources.
+func (s *Service) handleTagging(w http.ResponseWriter, r *http.Request) {
+	arn := r.URL.Query().Get("Resource")
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	found := false
+	for _, dist := range s.distributions {
+		if dist.arn == arn {
+			found = true
+			break
+		}
+	}
+	if !found {
+		h.WriteXMLError(w, "Sender", "NoSuchResource", "The specified resource does not exist.", http.StatusNotFound)
+		return
+	}
+
+	if r.Method == http.MethodGet {
+		h.WriteXML(w, http.StatusOK, tagSetOf(s.tags.Get(arn)))
+		return
+	}
+	bodyBytes, _ := io.ReadAll(r.Body)
+	switch r.URL.Query().Get("Operation") {
+	case "Tag":
+		var t tagSet
+		if err := xml.Unmarshal(bodyBytes, &t); err != nil {
+			h.WriteXMLError(w, "Sender", "MalformedXML", "could not parse request body", http.StatusBadRequest)
+			return
+		}
+		s.tags.Tag(arn, t.toMap())
+	case "Untag":
+		var keys tagKeys
+		if err := xml.Unmarshal(bodyBytes, &keys); err != nil {
+			h.WriteXMLError(w, "Sender", "MalformedXML", "could not parse request body", http.StatusBadRequest)
+			return
+		}
+		s.tags.Untag(arn, keys.Items)
+	default:
+		h.WriteXMLError(w, "Sender", "InvalidArgument", "unsupported tagging operation", http.StatusBadRequest)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,390 @@
+// Package cloudhsmv2 provides a mock implementation of AWS CloudHSM (v2).
+//
+// Supported actions:
+//   - CreateCluster, DescribeClusters, InitializeCluster, DeleteCluster
+//
+// Clusters hold no HSMs and perform no cryptography for clients, but their
+// certificate handshake is real: once a cluster is UNINITIALIZED it offers
+// a certificate signing request for its own key, and InitializeCluster
+// only accepts a certificate for that key which verifies against the trust
+// anchor given with it. Subnets are checked against the EC2 mock when it
+// is running.
+package cloudhsmv2
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/riyanimam/goto/internal/clock"
+	"github.com/riyanimam/goto/internal/lifecycle"
+	h "github.com/riyanimam/goto/internal/mockhelpers"
+	"github.com/riyanimam/goto/internal/paginate"
+	"github.com/riyanimam/goto/internal/tags"
+)
+
+var hsmTypes = map[string]bool{"hsm1.medium": true, "hsm2m.medium": true}
+
+// Service implements the CloudHSM v2 mock.
+type Service struct {
+	mu       sync.RWMutex
+	clusters map[string]*cluster
+
+	list        h.ResourceLister
+	tags        *tags.Store
+	clock       *clock.Clock
+	transitions *lifecycle.Transitions
+}
+
+type cluster struct {
+	id            string
+	arn           string
+	hsmType       string
+	mode          string
+	vpcID         string
+	subnets       map[string]string // subnet ID by availability zone
+	securityGroup string
+	key           crypto.Signer
+	csr           []byte // PEM
+	created       time.Time
+	ready         lifecycle.Transition
+	// cert is the signed cluster certificate given to InitializeCluster,
+	// and nil until then.
+	cert        []byte // PEM
+	initialized lifecycle.Transition
+}
+
+// New creates a new CloudHSM v2 mock service.
+func New() *Service {
+	return &Service{
+		clusters: make(map[string]*cluster),
+		tags:     tags.New(),
+	}
+}
+
+// Name returns the service identifier.
+func (s *Service) Name() string { return "cloudhsm" }
+
+// Handler returns the HTTP handler for CloudHSM v2 requests.
+func (s *Service) Handler() http.Handler {
+	return h.JSONRouter{
+		"CreateCluster":     s.createCluster,
+		"DescribeClusters":  s.describeClusters,
+		"InitializeCluster": s.initializeCluster,
+		"DeleteCluster":     s.deleteCluster,
+	}
+}
+
+// Reset clears all state.
+func (s *Service) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.clusters = make(map[string]*cluster)
+	s.tags.DeleteService("cloudhsm")
+}
+
+// SetResourceLister sets how the EC2 mock's subnets are found.
+func (s *Service) SetResourceLister(l h.ResourceLister) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.list = l
+}
+
+// SetTagStore sets the registry cluster tags are recorded in.
+func (s *Service) SetTagStore(store *tags.Store) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tags = store
+}
+
+// SetClock attaches the mock clock used for creation times.
+func (s *Service) SetClock(c *clock.Clock) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.clock = c
+}
+
+// SetTransitions sets how long clusters stay CREATE_IN_PROGRESS and
+// INITIALIZE_IN_PROGRESS.
+func (s *Service) SetTransitions(t *lifecycle.Transitions) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.transitions = t
+}
+
+func (s *Service) now() time.Time {
+	if s.clock != nil {
+		return s.clock.Now()
+	}
+	return time.Now().UTC()
+}
+
+func writeError(w http.ResponseWriter, code, message string) {
+	h.WriteJSONError(w, code, message, http.StatusBadRequest)
+}
+
+// state returns the cluster's state. The caller must hold s.mu.
+func (s *Service) state(c *cluster) string {
+	if c.cert != nil {
+		return s.transitions.Status(c.initialized, "INITIALIZE_IN_PROGRESS", "INITIALIZED")
+	}
+	return s.transitions.Status(c.ready, "CREATE_IN_PROGRESS", "UNINITIALIZED")
+}
+
+// subnet is an EC2 mock subnet as a cluster sees it.
+type subnet struct {
+	vpcID string
+	zone  string
+}
+
+// subnets reads the EC2 mock's subnets. It returns nil if no EC2 mock is
+// registered, in which case subnets are not checked. s.mu must not be held.
+func (s *Service) subnets() map[string]subnet {
+	s.mu.RLock()
+	list := s.list
+	s.mu.RUnlock()
+	if list == nil {
+		return nil
+	}
+	out := make(map[string]subnet)
+	for _, r := range list("ec2") {
+		if r.Type != "aws_subnet" {
+			continue
+		}
+		vpcID, _ := r.Attributes["vpc_id"].(string)
+		zone, _ := r.Attributes["availability_zone"].(string)
+		out[r.ID] = subnet{vpcID: vpcID, zone: zone}
+	}
+	return out
+}
+
+func (s *Service) createCluster(w http.ResponseWriter, params map[string]interface{}) {
+	hsmType := h.GetString(params, "HsmType")
+	if !hsmTypes[hsmType] {
+		writeError(w, "CloudHsmInvalidRequestException", "HsmType must be hsm1.medium or hsm2m.medium.")
+		return
+	}
+	mode := h.GetString(params, "Mode")
+	if mode == "" {
+		mode = "FIPS"
+	}
+	if mode != "FIPS" && mode != "NON_FIPS" {
+		writeError(w, "CloudHsmInvalidRequestException", "Mode must be FIPS or NON_FIPS.")
+		return
+	}
+	if mode == "NON_FIPS" && hsmType != "hsm2m.medium" {
+		writeError(w, "CloudHsmInvalidRequestException", "NON_FIPS clusters require HsmType hsm2m.medium.")
+		return
+	}
+	if id := h.GetString(params, "SourceBackupId"); id != "" {
+		writeError(w, "CloudHsmResourceNotFoundException", "Backup "+id+" not found.")
+		return
+	}
+	raw, _ := params["SubnetIds"].([]interface{})
+	if len(raw) == 0 || len(raw) > 10 {
+		writeError(w, "CloudHsmInvalidRequestException", "Between 1 and 10 SubnetIds are required.")
+		return
+	}
+
+	c := &cluster{hsmType: hsmType, mode: mode, subnets: make(map[string]string)}
+	known := s.subnets()
+	for i, v := range raw {
+		id, _ := v.(string)
+		// Without the EC2 mock, subnets are spread over zones in order.
+		sn := subnet{zone: fmt.Sprintf("us-east-1%c", 'a'+i%6)}
+		if known != nil {
+			var exists bool
+			if sn, exists = known[id]; !exists {
+				writeError(w, "CloudHsmInvalidRequestException", "Subnet "+id+" does not exist.")
+				return
+			}
+			if c.vpcID != "" && sn.vpcID != c.vpcID {
+				writeError(w, "CloudHsmInvalidRequestException", "All subnets of a cluster must be in the same VPC.")
+				return
+			}
+			c.vpcID = sn.vpcID
+		}
+		if _, taken := c.subnets[sn.zone]; taken {
+			writeError(w, "CloudHsmInvalidRequestException", "A cluster can have only one subnet in availability zone "+sn.zone+".")
+			return
+		}
+		c.subnets[sn.zone] = id
+	}
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		writeError(w, "CloudHsmInternalFailureException", err.Error())
+		return
+	}
+	c.key = key
+	c.id = "cluster-" + h.RandomHex(11)
+	c.arn = fmt.Sprintf("arn:aws:cloudhsm:us-east-1:%s:cluster/%s", h.DefaultAccountID, c.id)
+	c.securityGroup = "sg-" + h.RandomHex(17)
+	csr, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		Subject: pkix.Name{CommonName: c.id, Organization: []string{"AWS CloudHSM"}},
+	}, key)
+	if err != nil {
+		writeError(w, "CloudHsmInternalFailureException", err.Error())
+		return
+	}
+	c.csr = pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: csr})
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c.created = s.now()
+	c.ready = s.transitions.Begin()
+	s.clusters[c.id] = c
+	s.tags.Tag(c.arn, tags.FromList(params["TagList"], "Key", "Value"))
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{"Cluster": s.clusterResp(c)})
+}
+
+// clusterResp returns the cluster as DescribeClusters reports it. The
+// caller must hold s.mu.
+func (s *Service) clusterResp(c *cluster) map[string]interface{} {
+	state := s.state(c)
+	certs := map[string]interface{}{}
+	switch state {
+	case "UNINITIALIZED":
+		certs["ClusterCsr"] = string(c.csr)
+	case "INITIALIZE_IN_PROGRESS", "INITIALIZED":
+		certs["ClusterCertificate"] = string(c.cert)
+	}
+	resp := map[string]interface{}{
+		"ClusterId":             c.id,
+		"HsmType":               c.hsmType,
+		"Mode":                  c.mode,
+		"State":                 state,
+		"SubnetMapping":         c.subnets,
+		"SecurityGroup":         c.securityGroup,
+		"Hsms":                  []interface{}{},
+		"Certificates":          certs,
+		"BackupPolicy":          "DEFAULT",
+		"BackupRetentionPolicy": map[string]interface{}{"Type": "DAYS", "Value": "90"},
+		"CreateTimestamp":       float64(c.created.Unix()),
+		"TagList":               tags.ToList(s.tags.Get(c.arn), "Key", "Value"),
+	}
+	if c.vpcID != "" {
+		resp["VpcId"] = c.vpcID
+	}
+	return resp
+}
+
+func (s *Service) describeClusters(w http.ResponseWriter, params map[string]interface{}) {
+	filters, _ := params["Filters"].(map[string]interface{})
+	matches := func(key, value string) bool {
+		values, ok := filters[key].([]interface{})
+		if !ok {
+			return true
+		}
+		for _, v := range values {
+			if v == value {
+				return true
+			}
+		}
+		return false
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	ids := make([]string, 0, len(s.clusters))
+	for id := range s.clusters {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	out := make([]map[string]interface{}, 0, len(ids))
+	for _, id := range ids {
+		c := s.clusters[id]
+		if matches("clusterIds", c.id) && matches("vpcIds", c.vpcID) && matches("states", s.state(c)) {
+			out = append(out, s.clusterResp(c))
+		}
+	}
+	page, next, err := paginate.Page(out, func(i int) string { return out[i]["ClusterId"].(string) }, h.GetString(params, "NextToken"), h.GetInt(params, "MaxResults", 25), 25)
+	if err != nil {
+		writeError(w, "CloudHsmInvalidRequestException", "Invalid NextToken.")
+		return
+	}
+	resp := map[string]interface{}{"Clusters": page}
+	if next != "" {
+		resp["NextToken"] = next
+	}
+	h.WriteJSON(w, http.StatusOK, resp)
+}
+
+// parseCertificate decodes a single PEM certificate.
+func parseCertificate(data string) (*x509.Certificate, bool) {
+	block, _ := pem.Decode([]byte(data))
+	if block == nil || block.Type != "CERTIFICATE" {
+		return nil, false
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	return cert, err == nil
+}
+
+// initializeCluster accepts the cluster certificate: it must certify the
+// cluster's own key and verify against the trust anchor.
+func (s *Service) initializeCluster(w http.ResponseWriter, params map[string]interface{}) {
+	signedPEM := h.GetString(params, "SignedCert")
+	signed, ok := parseCertificate(signedPEM)
+	if !ok {
+		writeError(w, "CloudHsmInvalidRequestException", "SignedCert must be a PEM-encoded certificate.")
+		return
+	}
+	anchor, ok := parseCertificate(h.GetString(params, "TrustAnchor"))
+	if !ok {
+		writeError(w, "CloudHsmInvalidRequestException", "TrustAnchor must be a PEM-encoded certificate.")
+		return
+	}
+	if err := signed.CheckSignatureFrom(anchor); err != nil {
+		writeError(w, "CloudHsmInvalidRequestException", "SignedCert was not issued by TrustAnchor: "+err.Error())
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	id := h.GetString(params, "ClusterId")
+	c, exists := s.clusters[id]
+	if !exists {
+		writeError(w, "CloudHsmResourceNotFoundException", "Cluster "+id+" not found.")
+		return
+	}
+	if state := s.state(c); state != "UNINITIALIZED" {
+		writeError(w, "CloudHsmInvalidRequestException", "Cluster "+id+" is "+state+", not UNINITIALIZED.")
+		return
+	}
+	pub, ok := signed.PublicKey.(interface{ Equal(crypto.PublicKey) bool })
+	if !ok || !pub.Equal(c.key.Public()) {
+		writeError(w, "CloudHsmInvalidRequestException", "SignedCert does not certify the key in the cluster CSR.")
+		return
+	}
+	c.cert = []byte(signedPEM)
+	c.initialized = s.transitions.Begin()
+	state := s.state(c)
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"State":        state,
+		"StateMessage": "Cluster is " + state + ".",
+	})
+}
+
+func (s *Service) deleteCluster(w http.ResponseWriter, params map[string]interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	id := h.GetString(params, "ClusterId")
+	c, exists := s.clusters[id]
+	if !exists {
+		writeError(w, "CloudHsmResourceNotFoundException", "Cluster "+id+" not found.")
+		return
+	}
+	resp := s.clusterResp(c)
+	resp["State"] = "DELETED"
+	delete(s.clusters, id)
+	s.tags.Delete(c.arn)
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{"Cluster": resp})
+}
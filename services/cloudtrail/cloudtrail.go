@@ -9,24 +9,27 @@
 //   - StopLogging
 //   - GetTrailStatus
 //   - LookupEvents
+//   - AddTags
+//   - RemoveTags
+//   - ListTags
 package cloudtrail
 
 import (
-	"encoding/json"
 	"fmt"
-	"io"
 	"net/http"
 	"strings"
 	"sync"
 	"time"
 
 	h "github.com/riyanimam/goto/internal/mockhelpers"
+	"github.com/riyanimam/goto/internal/tags"
 )
 
 // Service implements the CloudTrail mock.
 type Service struct {
 	mu     sync.RWMutex
 	trails map[string]*trail
+	tags   *tags.Store
 }
 
 type trail struct {
@@ -44,6 +47,7 @@ type trail struct {
 func New() *Service {
 	return &Service{
 		trails: make(map[string]*trail),
+		tags:   tags.New(),
 	}
 }
 
@@ -52,7 +56,19 @@ func (s *Service) Name() string { return "cloudtrail" }
 
 // Handler returns the HTTP handler for CloudTrail requests.
 func (s *Service) Handler() http.Handler {
-	return http.HandlerFunc(s.handle)
+	return h.JSONRouter{
+		"CreateTrail":    s.createTrail,
+		"GetTrail":       s.getTrail,
+		"DeleteTrail":    s.deleteTrail,
+		"DescribeTrails": s.describeTrails,
+		"StartLogging":   s.startLogging,
+		"StopLogging":    s.stopLogging,
+		"GetTrailStatus": s.getTrailStatus,
+		"LookupEvents":   s.lookupEvents,
+		"AddTags":        s.addTags,
+		"RemoveTags":     s.removeTags,
+		"ListTags":       s.listTags,
+	}
 }
 
 // Reset clears all state.
@@ -60,53 +76,14 @@ func (s *Service) Reset() {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	s.trails = make(map[string]*trail)
+	s.tags.DeleteService("cloudtrail")
 }
 
-func (s *Service) handle(w http.ResponseWriter, r *http.Request) {
-	target := r.Header.Get("X-Amz-Target")
-
-	bodyBytes, err := io.ReadAll(r.Body)
-	if err != nil {
-		h.WriteJSONError(w, "InternalFailure", "could not read request body", http.StatusInternalServerError)
-		return
-	}
-
-	var params map[string]interface{}
-	if len(bodyBytes) > 0 {
-		json.Unmarshal(bodyBytes, &params)
-	}
-	if params == nil {
-		params = make(map[string]interface{})
-	}
-
-	action := ""
-	if target != "" {
-		parts := strings.SplitN(target, ".", 2)
-		if len(parts) == 2 {
-			action = parts[1]
-		}
-	}
-
-	switch action {
-	case "CreateTrail":
-		s.createTrail(w, params)
-	case "GetTrail":
-		s.getTrail(w, params)
-	case "DeleteTrail":
-		s.deleteTrail(w, params)
-	case "DescribeTrails":
-		s.describeTrails(w, params)
-	case "StartLogging":
-		s.startLogging(w, params)
-	case "StopLogging":
-		s.stopLogging(w, params)
-	case "GetTrailStatus":
-		s.getTrailStatus(w, params)
-	case "LookupEvents":
-		s.lookupEvents(w, params)
-	default:
-		h.WriteJSONError(w, "UnknownOperationException", fmt.Sprintf("action %q is not supported", action), http.StatusBadRequest)
-	}
+// SetTagStore sets the registry resource tags are recorded in.
+func (s *Service) SetTagStore(store *tags.Store) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tags = store
 }
 
 func (s *Service) createTrail(w http.ResponseWriter, params map[string]interface{}) {
@@ -140,6 +117,7 @@ func (s *Service) createTrail(w http.ResponseWriter, params map[string]interface
 		created:             time.Now().UTC(),
 	}
 	s.trails[name] = t
+	s.tags.Tag(arn, tags.FromList(params["TagsList"], "Key", "Value"))
 	s.mu.Unlock()
 
 	h.WriteJSON(w, http.StatusOK, trailResp(t))
@@ -166,12 +144,14 @@ func (s *Service) deleteTrail(w http.ResponseWriter, params map[string]interface
 	name := h.GetString(params, "Name")
 
 	s.mu.Lock()
-	if _, exists := s.trails[name]; !exists {
+	t, exists := s.trails[name]
+	if !exists {
 		s.mu.Unlock()
 		h.WriteJSONError(w, "TrailNotFoundException", "Trail not found: "+name, http.StatusBadRequest)
 		return
 	}
 	delete(s.trails, name)
+	s.tags.Delete(t.arn)
 	s.mu.Unlock()
 
 	h.WriteJSON(w, http.StatusOK, map[string]interface{}{})
@@ -265,6 +245,66 @@ func (s *Service) lookupEvents(w http.ResponseWriter, _ map[string]interface{})
 	})
 }
 
+// hasTrail reports whether arn names an existing trail. The caller must
+// hold s.mu.
+func (s *Service) hasTrail(arn string) bool {
+	_, name, _ := strings.Cut(arn, ":trail/")
+	t := s.trails[name]
+	return t != nil && t.arn == arn
+}
+
+func (s *Service) addTags(w http.ResponseWriter, params map[string]interface{}) {
+	arn := h.GetString(params, "ResourceId")
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if !s.hasTrail(arn) {
+		h.WriteJSONError(w, "ResourceNotFoundException", "Trail not found: "+arn, http.StatusBadRequest)
+		return
+	}
+	s.tags.Tag(arn, tags.FromList(params["TagsList"], "Key", "Value"))
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{})
+}
+
+func (s *Service) removeTags(w http.ResponseWriter, params map[string]interface{}) {
+	arn := h.GetString(params, "ResourceId")
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if !s.hasTrail(arn) {
+		h.WriteJSONError(w, "ResourceNotFoundException", "Trail not found: "+arn, http.StatusBadRequest)
+		return
+	}
+	var keys []string
+	for k := range tags.FromList(params["TagsList"], "Key", "Value") {
+		keys = append(keys, k)
+	}
+	s.tags.Untag(arn, keys)
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{})
+}
+
+func (s *Service) listTags(w http.ResponseWriter, params map[string]interface{}) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	list := make([]map[string]interface{}, 0)
+	for _, arn := range tags.Keys(params["ResourceIdList"]) {
+		if !s.hasTrail(arn) {
+			h.WriteJSONError(w, "ResourceNotFoundException", "Trail not found: "+arn, http.StatusBadRequest)
+			return
+		}
+		list = append(list, map[string]interface{}{
+			"ResourceId": arn,
+			"TagsList":   tags.ToList(s.tags.Get(arn), "Key", "Value"),
+		})
+	}
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"ResourceTagList": list,
+	})
+}
+
 func trailResp(t *trail) map[string]interface{} {
 	return map[string]interface{}{
 		"Name":                     t.name,
@@ -0,0 +1,393 @@
+package cloudwatch
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	h "github.com/riyanimam/goto/internal/mockhelpers"
+)
+
+// Alarm states.
+const (
+	stateOK               = "OK"
+	stateAlarm            = "ALARM"
+	stateInsufficientData = "INSUFFICIENT_DATA"
+)
+
+// cborNumber reads a number decoded from CBOR, which arrives as an integer
+// or a float of either width depending on its value.
+func cborNumber(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case float32:
+		return float64(n), true
+	case uint64:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	}
+	return 0, false
+}
+
+// cborInt reads an integer parameter decoded from CBOR, or def if absent.
+func cborInt(params map[string]interface{}, key string, def int) int {
+	if n, ok := cborNumber(params[key]); ok {
+		return int(n)
+	}
+	return def
+}
+
+// cborTime reads a timestamp decoded from CBOR: a tagged time, or epoch
+// seconds.
+func cborTime(v interface{}) (time.Time, bool) {
+	switch t := v.(type) {
+	case time.Time:
+		return t.UTC(), true
+	default:
+		if n, ok := cborNumber(v); ok {
+			return time.Unix(0, int64(n*float64(time.Second))).UTC(), true
+		}
+	}
+	return time.Time{}, false
+}
+
+// cborStrings reads a list of strings decoded from CBOR.
+func cborStrings(v interface{}) []string {
+	list, _ := v.([]interface{})
+	out := make([]string, 0, len(list))
+	for _, item := range list {
+		if s, ok := item.(string); ok {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// cborDimensions converts a CBOR-decoded Dimensions list.
+func cborDimensions(v interface{}) map[string]string {
+	list, _ := v.([]interface{})
+	dims := make(map[string]string, len(list))
+	for _, item := range list {
+		entry, ok := item.(map[interface{}]interface{})
+		if !ok {
+			continue
+		}
+		name, _ := entry["Name"].(string)
+		value, _ := entry["Value"].(string)
+		if name != "" {
+			dims[name] = value
+		}
+	}
+	return dims
+}
+
+func sameDimensions(a, b map[string]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for k, v := range a {
+		if bv, ok := b[k]; !ok || bv != v {
+			return false
+		}
+	}
+	return true
+}
+
+// statistic computes stat over values, which must not be empty.
+// Percentiles are given as pNN, such as p99 or p99.9.
+func statistic(stat string, values []float64) float64 {
+	if pct, err := strconv.ParseFloat(strings.TrimPrefix(stat, "p"), 64); err == nil && strings.HasPrefix(stat, "p") {
+		sorted := append([]float64(nil), values...)
+		sort.Float64s(sorted)
+		rank := int(math.Ceil(pct / 100 * float64(len(sorted))))
+		return sorted[min(max(rank, 1), len(sorted))-1]
+	}
+	switch stat {
+	case "Sum":
+		var sum float64
+		for _, v := range values {
+			sum += v
+		}
+		return sum
+	case "Minimum":
+		min := values[0]
+		for _, v := range values[1:] {
+			if v < min {
+				min = v
+			}
+		}
+		return min
+	case "Maximum":
+		max := values[0]
+		for _, v := range values[1:] {
+			if v > max {
+				max = v
+			}
+		}
+		return max
+	case "SampleCount":
+		return float64(len(values))
+	default:
+		return statistic("Sum", values) / float64(len(values))
+	}
+}
+
+// breaches reports whether value crosses threshold by the comparison
+// operator.
+func breaches(operator string, value, threshold float64) bool {
+	switch operator {
+	case "GreaterThanOrEqualToThreshold":
+		return value >= threshold
+	case "GreaterThanThreshold":
+		return value > threshold
+	case "LessThanThreshold":
+		return value < threshold
+	case "LessThanOrEqualToThreshold":
+		return value <= threshold
+	}
+	return false
+}
+
+// operatorPhrases describe comparison operators in state reasons.
+var operatorPhrases = map[string]string{
+	"GreaterThanOrEqualToThreshold": "greater than or equal to",
+	"GreaterThanThreshold":          "greater than",
+	"LessThanThreshold":             "less than",
+	"LessThanOrEqualToThreshold":    "less than or equal to",
+}
+
+// datapoint is a statistic computed over one period of an alarm's metric.
+type datapoint struct {
+	start time.Time
+	value float64
+	count int
+}
+
+// datapoints returns the alarm's statistic for each of its evaluation
+// periods ending at now, most recent first, omitting periods without data.
+// Periods include their end, so data just published counts at once.
+// The caller must hold s.mu.
+func (s *Service) datapoints(a *alarm, now time.Time) []datapoint {
+	period := time.Duration(a.period) * time.Second
+	var points []datapoint
+	for i := 0; i < a.evaluationPeriods; i++ {
+		end := now.Add(-time.Duration(i) * period)
+		start := end.Add(-period)
+		var values []float64
+		for _, m := range s.metrics {
+			if m.account != h.DefaultAccountID || m.namespace != a.namespace || m.metricName != a.metricName || !sameDimensions(m.dimensions, a.dimensions) {
+				continue
+			}
+			if !m.timestamp.After(start) || m.timestamp.After(end) {
+				continue
+			}
+			values = append(values, m.value)
+		}
+		if len(values) > 0 {
+			points = append(points, datapoint{start: start, value: statistic(a.statistic, values), count: len(values)})
+		}
+	}
+	return points
+}
+
+// evaluate works out the alarm's state from its metric's data at now,
+// recording any change, and returns whether its actions should run: on a
+// change of state, and again each period an alarm stays in ALARM, as
+// scaling policies are invoked repeatedly while their alarm is firing. The
+// caller must hold s.mu for writing.
+func (s *Service) evaluate(a *alarm, now time.Time) bool {
+	if a.metricName == "" {
+		return false
+	}
+	points := s.datapoints(a, now)
+	missing := a.evaluationPeriods - len(points)
+
+	breaching := 0
+	for _, p := range points {
+		if breaches(a.comparisonOperator, p.value, a.threshold) {
+			breaching++
+		}
+	}
+	state := stateOK
+	switch a.treatMissingData {
+	case "breaching":
+		breaching += missing
+	case "ignore":
+		if missing > 0 {
+			return false
+		}
+	case "notBreaching":
+	default:
+		if len(points) == 0 {
+			state = stateInsufficientData
+		}
+	}
+	if breaching >= a.datapointsToAlarm {
+		state = stateAlarm
+	}
+
+	if state == a.state {
+		if state == stateAlarm && now.Sub(a.actioned) >= time.Duration(a.period)*time.Second {
+			a.actioned = now
+			return a.actionsEnabled
+		}
+		return false
+	}
+	a.reason, a.reasonData = stateReason(a, state, points, now)
+	s.transition(a, state, now)
+	return a.actionsEnabled
+}
+
+// stateReason explains a state reached by evaluating points.
+func stateReason(a *alarm, state string, points []datapoint, now time.Time) (string, string) {
+	if state == stateInsufficientData {
+		return fmt.Sprintf("Insufficient Data: %d datapoints were unknown.", a.evaluationPeriods), ""
+	}
+	recent := make([]float64, 0, len(points))
+	shown := make([]string, 0, len(points))
+	for _, p := range points {
+		recent = append(recent, p.value)
+		shown = append(shown, fmt.Sprintf("%g (%s)", p.value, p.start.Format("02/01/06 15:04:05")))
+	}
+	verb := "was"
+	if len(points) != 1 {
+		verb = "were"
+	}
+	if state == stateOK {
+		verb += " not"
+	}
+	reason := fmt.Sprintf("Threshold Crossed: %d out of the last %d datapoints [%s] %s %s the threshold (%g) (minimum %d datapoint for %s transition).",
+		len(points), a.evaluationPeriods, strings.Join(shown, ", "), verb, operatorPhrases[a.comparisonOperator], a.threshold, a.datapointsToAlarm, state)
+	data, _ := json.Marshal(map[string]interface{}{
+		"version":          "1.0",
+		"queryDate":        now.Format("2006-01-02T15:04:05.000-0700"),
+		"statistic":        a.statistic,
+		"period":           a.period,
+		"recentDatapoints": recent,
+		"threshold":        a.threshold,
+	})
+	return reason, string(data)
+}
+
+// transition moves the alarm to state. The caller must hold s.mu for
+// writing.
+func (s *Service) transition(a *alarm, state string, now time.Time) {
+	a.previous, a.state = a.state, state
+	a.updated, a.actioned = now, now
+}
+
+// notification is the message an alarm's actions receive, as CloudWatch
+// sends to SNS topics, with the data behind the state reason.
+func (a *alarm) notification() []byte {
+	dims := make([]map[string]string, 0, len(a.dimensions))
+	names := make([]string, 0, len(a.dimensions))
+	for name := range a.dimensions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		dims = append(dims, map[string]string{"name": name, "value": a.dimensions[name]})
+	}
+	msg, _ := json.Marshal(map[string]interface{}{
+		"AlarmName":          a.name,
+		"AlarmDescription":   a.description,
+		"AWSAccountId":       h.DefaultAccountID,
+		"AlarmArn":           a.arn,
+		"NewStateValue":      a.state,
+		"NewStateReason":     a.reason,
+		"NewStateReasonData": a.reasonData,
+		"OldStateValue":      a.previous,
+		"StateChangeTime":    a.updated.Format("2006-01-02T15:04:05.000-0700"),
+		"Region":             "US East (N. Virginia)",
+		"Trigger": map[string]interface{}{
+			"MetricName":         a.metricName,
+			"Namespace":          a.namespace,
+			"StatisticType":      "Statistic",
+			"Statistic":          strings.ToUpper(a.statistic),
+			"Unit":               nil,
+			"Dimensions":         dims,
+			"Period":             a.period,
+			"EvaluationPeriods":  a.evaluationPeriods,
+			"DatapointsToAlarm":  a.datapointsToAlarm,
+			"ComparisonOperator": a.comparisonOperator,
+			"Threshold":          a.threshold,
+			"TreatMissingData":   a.treatMissingData,
+		},
+	})
+	return msg
+}
+
+// actions returns the actions for the alarm's current state.
+func (a *alarm) actions() []string {
+	switch a.state {
+	case stateAlarm:
+		return a.alarmActions
+	case stateOK:
+		return a.okActions
+	default:
+		return a.insufficientDataActions
+	}
+}
+
+// pendingAction is an alarm action to run once s.mu is released.
+type pendingAction struct {
+	arn     string
+	payload []byte
+}
+
+// queueActions returns the actions to run for the alarm's current state.
+// Repeated runs while an alarm stays in ALARM only invoke scaling
+// policies, whose ARNs name the autoscaling service; notifications are
+// sent once per change of state. The caller must hold s.mu.
+func queueActions(a *alarm, changed bool) []pendingAction {
+	var pending []pendingAction
+	payload := a.notification()
+	for _, action := range a.actions() {
+		if !changed && !strings.HasPrefix(action, "arn:aws:autoscaling:") {
+			continue
+		}
+		pending = append(pending, pendingAction{arn: action, payload: payload})
+	}
+	return pending
+}
+
+// evaluateAll evaluates every alarm at the current time and runs the
+// actions due.
+func (s *Service) evaluateAll() {
+	s.mu.Lock()
+	now := s.now()
+	names := make([]string, 0, len(s.alarms))
+	for name := range s.alarms {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var pending []pendingAction
+	for _, name := range names {
+		a := s.alarms[name]
+		before := a.state
+		if s.evaluate(a, now) {
+			pending = append(pending, queueActions(a, a.state != before)...)
+		}
+	}
+	dispatch := s.dispatch
+	s.mu.Unlock()
+	run(dispatch, pending)
+}
+
+// run delivers the pending actions.
+func run(dispatch h.Dispatcher, pending []pendingAction) {
+	if dispatch == nil {
+		return
+	}
+	for _, p := range pending {
+		dispatch(p.arn, p.payload)
+	}
+}
@@ -7,6 +7,20 @@
 //   - PutMetricAlarm
 //   - DescribeAlarms
 //   - DeleteAlarms
+//   - SetAlarmState
+//   - TagResource
+//   - UntagResource
+//   - ListTagsForResource
+//
+// Metric alarms are evaluated against the data points stored, whenever data
+// is put, an alarm is created, or the mock clock advances, and run their
+// actions when their state changes: an SNS topic receives the alarm
+// notification, and an Application Auto Scaling policy scales its target.
+//
+// GetMetricData computes each query's statistic per period and evaluates
+// metric math expressions over the results. Once source accounts are
+// linked with [Service.LinkSourceAccounts], queries with an AccountId read
+// the metrics published in them.
 package cloudwatch
 
 import (
@@ -19,7 +33,9 @@ import (
 	"time"
 
 	"github.com/fxamacker/cbor/v2"
+	"github.com/riyanimam/goto/internal/clock"
 	h "github.com/riyanimam/goto/internal/mockhelpers"
+	"github.com/riyanimam/goto/internal/tags"
 )
 
 // Service implements the CloudWatch metrics mock.
@@ -27,9 +43,15 @@ type Service struct {
 	mu      sync.RWMutex
 	metrics []*metricDatum
 	alarms  map[string]*alarm
+	tags    *tags.Store
+	linked  map[string]bool // source accounts, by ID
+
+	dispatch h.Dispatcher
+	clock    *clock.Clock
 }
 
 type metricDatum struct {
+	account    string
 	namespace  string
 	metricName string
 	value      float64
@@ -41,21 +63,36 @@ type metricDatum struct {
 type alarm struct {
 	name               string
 	arn                string
+	description        string
 	namespace          string
 	metricName         string
+	dimensions         map[string]string
 	comparisonOperator string
 	threshold          float64
 	period             int
 	evaluationPeriods  int
+	datapointsToAlarm  int
 	statistic          string
-	state              string
-	stateReason        string
+	treatMissingData   string
+
+	actionsEnabled          bool
+	alarmActions            []string
+	okActions               []string
+	insufficientDataActions []string
+
+	state      string
+	previous   string
+	reason     string
+	reasonData string
+	updated    time.Time
+	actioned   time.Time // when its actions last ran
 }
 
 // New creates a new CloudWatch mock service.
 func New() *Service {
 	return &Service{
 		alarms: make(map[string]*alarm),
+		tags:   tags.New(),
 	}
 }
 
@@ -73,6 +110,61 @@ func (s *Service) Reset() {
 	defer s.mu.Unlock()
 	s.metrics = nil
 	s.alarms = make(map[string]*alarm)
+	s.tags.DeleteService("cloudwatch")
+}
+
+// SetTagStore sets the registry resource tags are recorded in.
+func (s *Service) SetTagStore(store *tags.Store) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tags = store
+}
+
+// SetDispatcher sets the function used to run alarm actions.
+func (s *Service) SetDispatcher(d h.Dispatcher) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.dispatch = d
+}
+
+// SetClock attaches the mock clock. Data points are stamped with its time
+// by default, and alarms are evaluated as it advances.
+func (s *Service) SetClock(c *clock.Clock) {
+	s.mu.Lock()
+	s.clock = c
+	s.mu.Unlock()
+	c.OnAdvance(func(_, _ time.Time) { s.evaluateAll() })
+}
+
+func (s *Service) now() time.Time {
+	if s.clock != nil {
+		return s.clock.Now()
+	}
+	return time.Now().UTC()
+}
+
+// PutMetrics records data points published by other services, as
+// PutMetricData does.
+func (s *Service) PutMetrics(metrics []h.Metric) error {
+	defer s.evaluateAll()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, m := range metrics {
+		unit := m.Unit
+		if unit == "" {
+			unit = "None"
+		}
+		s.metrics = append(s.metrics, &metricDatum{
+			account:    h.DefaultAccountID,
+			namespace:  m.Namespace,
+			metricName: m.Name,
+			value:      m.Value,
+			unit:       unit,
+			timestamp:  m.Timestamp.UTC(),
+			dimensions: m.Dimensions,
+		})
+	}
+	return nil
 }
 
 func (s *Service) handle(w http.ResponseWriter, r *http.Request) {
@@ -111,6 +203,14 @@ func (s *Service) handle(w http.ResponseWriter, r *http.Request) {
 		s.describeAlarms(w, params)
 	case "DeleteAlarms":
 		s.deleteAlarms(w, params)
+	case "SetAlarmState":
+		s.setAlarmState(w, params)
+	case "TagResource":
+		s.tagResource(w, params)
+	case "UntagResource":
+		s.untagResource(w, params)
+	case "ListTagsForResource":
+		s.listTagsForResource(w, params)
 	default:
 		writeCBORError(w, "UnsupportedOperation", fmt.Sprintf("action %q is not supported", operation), http.StatusBadRequest)
 	}
@@ -127,81 +227,101 @@ func (s *Service) putMetricData(w http.ResponseWriter, params map[string]interfa
 				if v, ok := mdm["MetricName"]; ok {
 					metricName = fmt.Sprintf("%v", v)
 				}
-				var value float64
-				if v, ok := mdm["Value"]; ok {
-					switch n := v.(type) {
-					case float64:
-						value = n
-					case float32:
-						value = float64(n)
-					}
-				}
+				value, _ := cborNumber(mdm["Value"])
 				unit := "None"
 				if v, ok := mdm["Unit"]; ok {
 					unit = fmt.Sprintf("%v", v)
 				}
+				timestamp, ok := cborTime(mdm["Timestamp"])
+				if !ok {
+					timestamp = s.now()
+				}
 				s.metrics = append(s.metrics, &metricDatum{
+					account:    h.DefaultAccountID,
 					namespace:  namespace,
 					metricName: metricName,
 					value:      value,
 					unit:       unit,
-					timestamp:  time.Now().UTC(),
+					timestamp:  timestamp,
+					dimensions: cborDimensions(mdm["Dimensions"]),
 				})
 			}
 		}
 	}
 	s.mu.Unlock()
+	s.evaluateAll()
 
 	writeCBOR(w, http.StatusOK, map[string]interface{}{})
 }
 
-func (s *Service) getMetricData(w http.ResponseWriter, _ map[string]interface{}) {
-	s.mu.RLock()
-	var results []map[string]interface{}
-	for _, m := range s.metrics {
-		results = append(results, map[string]interface{}{
-			"Id":     fmt.Sprintf("m_%s_%s", m.namespace, m.metricName),
-			"Label":  m.metricName,
-			"Values": []float64{m.value},
-		})
-	}
-	s.mu.RUnlock()
-
-	writeCBOR(w, http.StatusOK, map[string]interface{}{
-		"MetricDataResults": results,
-	})
-}
-
 func (s *Service) listMetrics(w http.ResponseWriter, params map[string]interface{}) {
 	namespace := h.GetString(params, "Namespace")
+	includeLinked, _ := params["IncludeLinkedAccounts"].(bool)
+	owningAccount := h.GetString(params, "OwningAccount")
 
 	s.mu.RLock()
 	seen := make(map[string]bool)
 	var metrics []map[string]interface{}
+	var owners []string
 	for _, m := range s.metrics {
 		if namespace != "" && m.namespace != namespace {
 			continue
 		}
-		key := m.namespace + "/" + m.metricName
+		if m.account != h.DefaultAccountID && !includeLinked {
+			continue
+		}
+		if owningAccount != "" && m.account != owningAccount {
+			continue
+		}
+		names := make([]string, 0, len(m.dimensions))
+		for name := range m.dimensions {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		key := m.account + "/" + m.namespace + "/" + m.metricName
+		dims := make([]map[string]interface{}, 0, len(names))
+		for _, name := range names {
+			key += "/" + name + "=" + m.dimensions[name]
+			dims = append(dims, map[string]interface{}{"Name": name, "Value": m.dimensions[name]})
+		}
 		if seen[key] {
 			continue
 		}
 		seen[key] = true
 
-		metrics = append(metrics, map[string]interface{}{
+		metric := map[string]interface{}{
 			"Namespace":  m.namespace,
 			"MetricName": m.metricName,
-		})
+		}
+		if len(dims) > 0 {
+			metric["Dimensions"] = dims
+		}
+		metrics = append(metrics, metric)
+		owners = append(owners, m.account)
 	}
 	s.mu.RUnlock()
 
-	sort.Slice(metrics, func(i, j int) bool {
-		return metrics[i]["MetricName"].(string) < metrics[j]["MetricName"].(string)
+	// OwningAccounts lists each metric's account, in the same order.
+	order := make([]int, len(metrics))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return metrics[order[i]]["MetricName"].(string) < metrics[order[j]]["MetricName"].(string)
 	})
+	sortedMetrics := make([]map[string]interface{}, len(order))
+	sortedOwners := make([]string, len(order))
+	for i, k := range order {
+		sortedMetrics[i], sortedOwners[i] = metrics[k], owners[k]
+	}
 
-	writeCBOR(w, http.StatusOK, map[string]interface{}{
-		"Metrics": metrics,
-	})
+	resp := map[string]interface{}{
+		"Metrics": sortedMetrics,
+	}
+	if includeLinked {
+		resp["OwningAccounts"] = sortedOwners
+	}
+	writeCBOR(w, http.StatusOK, resp)
 }
 
 func (s *Service) putMetricAlarm(w http.ResponseWriter, params map[string]interface{}) {
@@ -211,34 +331,101 @@ func (s *Service) putMetricAlarm(w http.ResponseWriter, params map[string]interf
 		return
 	}
 
-	var threshold float64
-	if v, ok := params["Threshold"]; ok {
-		switch n := v.(type) {
-		case float64:
-			threshold = n
-		case float32:
-			threshold = float64(n)
-		}
+	threshold, _ := cborNumber(params["Threshold"])
+	period := cborInt(params, "Period", 300)
+	evalPeriods := cborInt(params, "EvaluationPeriods", 1)
+	if period <= 0 || evalPeriods <= 0 {
+		writeCBORError(w, "InvalidParameterValue", "Period and EvaluationPeriods must be positive", http.StatusBadRequest)
+		return
+	}
+	datapointsToAlarm := cborInt(params, "DatapointsToAlarm", evalPeriods)
+	if datapointsToAlarm <= 0 || datapointsToAlarm > evalPeriods {
+		writeCBORError(w, "InvalidParameterValue", "DatapointsToAlarm must be between 1 and EvaluationPeriods", http.StatusBadRequest)
+		return
+	}
+	statistic := h.GetString(params, "Statistic")
+	if statistic == "" {
+		statistic = "Average"
+	}
+	treatMissing := h.GetString(params, "TreatMissingData")
+	if treatMissing == "" {
+		treatMissing = "missing"
+	}
+	actionsEnabled := true
+	if v, ok := params["ActionsEnabled"].(bool); ok {
+		actionsEnabled = v
 	}
-	period := h.GetInt(params, "Period", 300)
-	evalPeriods := h.GetInt(params, "EvaluationPeriods", 1)
 
 	s.mu.Lock()
+	now := s.now()
 	a := &alarm{
-		name:               name,
-		arn:                fmt.Sprintf("arn:aws:cloudwatch:us-east-1:%s:alarm:%s", h.DefaultAccountID, name),
-		namespace:          h.GetString(params, "Namespace"),
-		metricName:         h.GetString(params, "MetricName"),
-		comparisonOperator: h.GetString(params, "ComparisonOperator"),
-		threshold:          threshold,
-		period:             period,
-		evaluationPeriods:  evalPeriods,
-		statistic:          h.GetString(params, "Statistic"),
-		state:              "OK",
-		stateReason:        "Threshold Crossing: 0 datapoints were OK",
+		name:                    name,
+		arn:                     fmt.Sprintf("arn:aws:cloudwatch:us-east-1:%s:alarm:%s", h.DefaultAccountID, name),
+		description:             h.GetString(params, "AlarmDescription"),
+		namespace:               h.GetString(params, "Namespace"),
+		metricName:              h.GetString(params, "MetricName"),
+		dimensions:              cborDimensions(params["Dimensions"]),
+		comparisonOperator:      h.GetString(params, "ComparisonOperator"),
+		threshold:               threshold,
+		period:                  period,
+		evaluationPeriods:       evalPeriods,
+		datapointsToAlarm:       datapointsToAlarm,
+		statistic:               statistic,
+		treatMissingData:        treatMissing,
+		actionsEnabled:          actionsEnabled,
+		alarmActions:            cborStrings(params["AlarmActions"]),
+		okActions:               cborStrings(params["OKActions"]),
+		insufficientDataActions: cborStrings(params["InsufficientDataActions"]),
+		state:                   stateInsufficientData,
+		reason:                  "Unchecked: Initial alarm creation",
+		updated:                 now,
+	}
+	// Updating an alarm keeps its state until it is next evaluated.
+	if old, ok := s.alarms[name]; ok {
+		a.state, a.previous, a.reason, a.reasonData = old.state, old.previous, old.reason, old.reasonData
+		a.updated, a.actioned = old.updated, old.actioned
 	}
 	s.alarms[name] = a
+	s.tags.Tag(a.arn, cborTags(params["Tags"]))
 	s.mu.Unlock()
+	s.evaluateAll()
+
+	writeCBOR(w, http.StatusOK, map[string]interface{}{})
+}
+
+// setAlarmState sets an alarm's state until it is next evaluated, running
+// the actions of the new state.
+func (s *Service) setAlarmState(w http.ResponseWriter, params map[string]interface{}) {
+	name := h.GetString(params, "AlarmName")
+	state := h.GetString(params, "StateValue")
+	reason := h.GetString(params, "StateReason")
+	if state != stateOK && state != stateAlarm && state != stateInsufficientData {
+		writeCBORError(w, "InvalidParameterValue", "StateValue must be OK, ALARM, or INSUFFICIENT_DATA", http.StatusBadRequest)
+		return
+	}
+	if reason == "" {
+		writeCBORError(w, "InvalidParameterValue", "StateReason is required", http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	a, ok := s.alarms[name]
+	if !ok {
+		s.mu.Unlock()
+		writeCBORError(w, "ResourceNotFound", "Alarm "+name+" does not exist", http.StatusNotFound)
+		return
+	}
+	var pending []pendingAction
+	if state != a.state {
+		a.reason, a.reasonData = reason, h.GetString(params, "StateReasonData")
+		s.transition(a, state, s.now())
+		if a.actionsEnabled {
+			pending = queueActions(a, true)
+		}
+	}
+	dispatch := s.dispatch
+	s.mu.Unlock()
+	run(dispatch, pending)
 
 	writeCBOR(w, http.StatusOK, map[string]interface{}{})
 }
@@ -265,6 +452,9 @@ func (s *Service) deleteAlarms(w http.ResponseWriter, params map[string]interfac
 	if names, ok := params["AlarmNames"].([]interface{}); ok {
 		for _, n := range names {
 			if name, ok := n.(string); ok {
+				if a, ok := s.alarms[name]; ok {
+					s.tags.Delete(a.arn)
+				}
 				delete(s.alarms, name)
 			}
 		}
@@ -274,20 +464,126 @@ func (s *Service) deleteAlarms(w http.ResponseWriter, params map[string]interfac
 	writeCBOR(w, http.StatusOK, map[string]interface{}{})
 }
 
+// alarmByARN returns the alarm with the given ARN, or nil.
+// The caller must hold s.mu.
+func (s *Service) alarmByARN(arn string) *alarm {
+	for _, a := range s.alarms {
+		if a.arn == arn {
+			return a
+		}
+	}
+	return nil
+}
+
+func (s *Service) tagResource(w http.ResponseWriter, params map[string]interface{}) {
+	arn := h.GetString(params, "ResourceARN")
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.alarmByARN(arn) == nil {
+		writeCBORError(w, "ResourceNotFoundException", "Resource "+arn+" not found", http.StatusNotFound)
+		return
+	}
+	s.tags.Tag(arn, cborTags(params["Tags"]))
+	writeCBOR(w, http.StatusOK, map[string]interface{}{})
+}
+
+func (s *Service) untagResource(w http.ResponseWriter, params map[string]interface{}) {
+	arn := h.GetString(params, "ResourceARN")
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.alarmByARN(arn) == nil {
+		writeCBORError(w, "ResourceNotFoundException", "Resource "+arn+" not found", http.StatusNotFound)
+		return
+	}
+	s.tags.Untag(arn, tags.Keys(params["TagKeys"]))
+	writeCBOR(w, http.StatusOK, map[string]interface{}{})
+}
+
+func (s *Service) listTagsForResource(w http.ResponseWriter, params map[string]interface{}) {
+	arn := h.GetString(params, "ResourceARN")
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.alarmByARN(arn) == nil {
+		writeCBORError(w, "ResourceNotFoundException", "Resource "+arn+" not found", http.StatusNotFound)
+		return
+	}
+	writeCBOR(w, http.StatusOK, map[string]interface{}{
+		"Tags": tags.ToList(s.tags.Get(arn), "Key", "Value"),
+	})
+}
+
+// cborTags converts a CBOR-decoded Tags list. Nested CBOR maps decode with
+// interface{} keys, so tags.FromList cannot read them directly.
+func cborTags(v interface{}) map[string]string {
+	m := make(map[string]string)
+	list, _ := v.([]interface{})
+	for _, item := range list {
+		entry, ok := item.(map[interface{}]interface{})
+		if !ok {
+			continue
+		}
+		k, _ := entry["Key"].(string)
+		val, _ := entry["Value"].(string)
+		if k != "" {
+			m[k] = val
+		}
+	}
+	return m
+}
+
 func alarmToMap(a *alarm) map[string]interface{} {
-	return map[string]interface{}{
-		"AlarmName":          a.name,
-		"AlarmArn":           a.arn,
-		"Namespace":          a.namespace,
-		"MetricName":         a.metricName,
-		"ComparisonOperator": a.comparisonOperator,
-		"Threshold":          a.threshold,
-		"Period":             a.period,
-		"EvaluationPeriods":  a.evaluationPeriods,
-		"Statistic":          a.statistic,
-		"StateValue":         a.state,
-		"StateReason":        a.stateReason,
+	names := make([]string, 0, len(a.dimensions))
+	for name := range a.dimensions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	dims := make([]map[string]interface{}, 0, len(names))
+	for _, name := range names {
+		dims = append(dims, map[string]interface{}{"Name": name, "Value": a.dimensions[name]})
+	}
+	m := map[string]interface{}{
+		"AlarmName":               a.name,
+		"AlarmArn":                a.arn,
+		"Namespace":               a.namespace,
+		"MetricName":              a.metricName,
+		"Dimensions":              dims,
+		"ComparisonOperator":      a.comparisonOperator,
+		"Threshold":               a.threshold,
+		"Period":                  a.period,
+		"EvaluationPeriods":       a.evaluationPeriods,
+		"DatapointsToAlarm":       a.datapointsToAlarm,
+		"Statistic":               a.statistic,
+		"TreatMissingData":        a.treatMissingData,
+		"ActionsEnabled":          a.actionsEnabled,
+		"AlarmActions":            nonNil(a.alarmActions),
+		"OKActions":               nonNil(a.okActions),
+		"InsufficientDataActions": nonNil(a.insufficientDataActions),
+		"StateValue":              a.state,
+		"StateReason":             a.reason,
+		"StateUpdatedTimestamp":   epochTag(a.updated),
+	}
+	if a.description != "" {
+		m["AlarmDescription"] = a.description
+	}
+	if a.reasonData != "" {
+		m["StateReasonData"] = a.reasonData
+	}
+	return m
+}
+
+// epochTag encodes t as an RPC v2 CBOR timestamp: epoch seconds under tag 1.
+func epochTag(t time.Time) cbor.Tag {
+	return cbor.Tag{Number: 1, Content: float64(t.UnixMilli()) / 1000}
+}
+
+func nonNil(list []string) []string {
+	if list == nil {
+		return []string{}
 	}
+	return list
 }
 
 func writeCBOR(w http.ResponseWriter, status int, v interface{}) {
@@ -0,0 +1,329 @@
+package cloudwatch
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	h "github.com/riyanimam/goto/internal/mockhelpers"
+)
+
+// Datum is a data point published in a source account linked to the
+// mock's account, for cross-account GetMetricData queries.
+type Datum struct {
+	Namespace  string
+	MetricName string
+	Dimensions map[string]string
+	Value      float64
+	Unit       string
+	// Timestamp is the time of the data point; the mock clock's current
+	// time if zero.
+	Timestamp time.Time
+}
+
+// LinkSourceAccounts makes the mock's account a cross-account
+// observability monitoring account for the given source accounts: their
+// metrics can be queried with the AccountId of a GetMetricData query, and
+// are listed by ListMetrics with IncludeLinkedAccounts.
+func (s *Service) LinkSourceAccounts(accountIDs ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.linked == nil {
+		s.linked = make(map[string]bool)
+	}
+	for _, id := range accountIDs {
+		s.linked[id] = true
+	}
+}
+
+// PutAccountMetrics records data points published in a linked source
+// account.
+func (s *Service) PutAccountMetrics(accountID string, data ...Datum) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.linked[accountID] {
+		return fmt.Errorf("account %s is not a source account linked to %s", accountID, h.DefaultAccountID)
+	}
+	for _, d := range data {
+		unit := d.Unit
+		if unit == "" {
+			unit = "None"
+		}
+		ts := d.Timestamp
+		if ts.IsZero() {
+			ts = s.now()
+		}
+		s.metrics = append(s.metrics, &metricDatum{
+			account:    accountID,
+			namespace:  d.Namespace,
+			metricName: d.MetricName,
+			value:      d.Value,
+			unit:       unit,
+			timestamp:  ts.UTC(),
+			dimensions: d.Dimensions,
+		})
+	}
+	return nil
+}
+
+// queryID matches the IDs of metric data queries, which expressions refer
+// to them by.
+var queryID = regexp.MustCompile(`^[a-z][a-zA-Z0-9_]*$`)
+
+// metricQuery is one of the MetricDataQueries of a GetMetricData request.
+type metricQuery struct {
+	id         string
+	label      string
+	expression string
+	returnData bool
+
+	account    string
+	namespace  string
+	metricName string
+	dimensions map[string]string
+	period     time.Duration
+	stat       string
+	unit       string
+}
+
+func parseMetricQueries(v interface{}) ([]*metricQuery, error) {
+	list, _ := v.([]interface{})
+	if len(list) == 0 {
+		return nil, fmt.Errorf("MetricDataQueries is required")
+	}
+	seen := make(map[string]bool)
+	queries := make([]*metricQuery, 0, len(list))
+	for i, item := range list {
+		m, _ := item.(map[interface{}]interface{})
+		q := &metricQuery{returnData: true}
+		q.id, _ = m["Id"].(string)
+		q.label, _ = m["Label"].(string)
+		q.expression, _ = m["Expression"].(string)
+		q.account, _ = m["AccountId"].(string)
+		if rd, ok := m["ReturnData"].(bool); ok {
+			q.returnData = rd
+		}
+		if !queryID.MatchString(q.id) {
+			return nil, fmt.Errorf("The value %s for parameter MetricDataQueries.member.%d.Id is not valid. Ids must start with a lowercase letter and contain only letters, numbers, and underscores.", q.id, i+1)
+		}
+		if seen[q.id] {
+			return nil, fmt.Errorf("The values for parameter id in MetricDataQueries are not unique.")
+		}
+		seen[q.id] = true
+		queries = append(queries, q)
+
+		stat, hasStat := m["MetricStat"].(map[interface{}]interface{})
+		if hasStat == (q.expression != "") {
+			return nil, fmt.Errorf("Exactly one element of the metrics list in MetricDataQueries.member.%d should specify either MetricStat or Expression.", i+1)
+		}
+		if !hasStat {
+			if n, ok := cborNumber(m["Period"]); ok {
+				q.period = time.Duration(n) * time.Second
+			}
+			continue
+		}
+		metric, _ := stat["Metric"].(map[interface{}]interface{})
+		q.namespace, _ = metric["Namespace"].(string)
+		q.metricName, _ = metric["MetricName"].(string)
+		q.dimensions = cborDimensions(metric["Dimensions"])
+		q.stat, _ = stat["Stat"].(string)
+		q.unit, _ = stat["Unit"].(string)
+		period, _ := cborNumber(stat["Period"])
+		if period <= 0 || (period >= 60 && int(period)%60 != 0) {
+			return nil, fmt.Errorf("The parameter MetricDataQueries.member.%d.MetricStat.Period must be 1, 5, 10, 30, or a multiple of 60.", i+1)
+		}
+		q.period = time.Duration(period) * time.Second
+		if q.stat == "" {
+			return nil, fmt.Errorf("The parameter MetricDataQueries.member.%d.MetricStat.Stat is required.", i+1)
+		}
+		if q.label == "" {
+			q.label = q.metricName
+		}
+	}
+	return queries, nil
+}
+
+func (s *Service) getMetricData(w http.ResponseWriter, params map[string]interface{}) {
+	start, okStart := cborTime(params["StartTime"])
+	end, okEnd := cborTime(params["EndTime"])
+	if !okStart || !okEnd {
+		writeCBORError(w, "InvalidParameterValue", "StartTime and EndTime are required", http.StatusBadRequest)
+		return
+	}
+	if !start.Before(end) {
+		writeCBORError(w, "InvalidParameterValue", "The parameter StartTime must be less than the parameter EndTime.", http.StatusBadRequest)
+		return
+	}
+	queries, err := parseMetricQueries(params["MetricDataQueries"])
+	if err != nil {
+		writeCBORError(w, "InvalidParameterValue", err.Error(), http.StatusBadRequest)
+		return
+	}
+	byID := make(map[string]*metricQuery, len(queries))
+	for _, q := range queries {
+		byID[q.id] = q
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, q := range queries {
+		if q.expression == "" && q.account != "" && q.account != h.DefaultAccountID && !s.linked[q.account] {
+			writeCBORError(w, "InvalidParameterValue", fmt.Sprintf("Account %s in MetricDataQuery %s is not a source account linked to this monitoring account.", q.account, q.id), http.StatusBadRequest)
+			return
+		}
+	}
+
+	// The smallest metric period is the step of expressions that do not
+	// set their own.
+	var step time.Duration
+	for _, q := range queries {
+		if q.expression == "" && (step == 0 || q.period < step) {
+			step = q.period
+		}
+	}
+	if step == 0 {
+		step = time.Minute
+	}
+
+	values := make(map[string]interface{}, len(queries))
+	visiting := make(map[string]bool)
+	var resolve func(id string) (interface{}, error)
+	resolve = func(id string) (interface{}, error) {
+		if v, ok := values[id]; ok {
+			return v, nil
+		}
+		q, ok := byID[id]
+		if !ok {
+			return nil, fmt.Errorf("Unrecognized identifier %s", id)
+		}
+		if visiting[id] {
+			return nil, fmt.Errorf("Circular reference to %s", id)
+		}
+		visiting[id] = true
+		defer delete(visiting, id)
+
+		var v interface{}
+		if q.expression == "" {
+			v = s.statSeries(q, start, end)
+		} else {
+			period := q.period
+			if period == 0 {
+				period = step
+			}
+			env := &mathEnv{start: start, end: end, period: period, resolve: resolve}
+			env.metrics = func(filter string) ([]*series, error) {
+				var arr []*series
+				for _, mq := range queries {
+					if mq.expression != "" || !strings.Contains(mq.id, filter) {
+						continue
+					}
+					sv, err := resolve(mq.id)
+					if err != nil {
+						return nil, err
+					}
+					arr = append(arr, sv.(*series))
+				}
+				return arr, nil
+			}
+			var err error
+			if v, err = evalExpression(q.expression, env); err != nil {
+				return nil, fmt.Errorf("Error in expression '%s': %v", q.id, err)
+			}
+		}
+		values[id] = v
+		return v, nil
+	}
+
+	descending := h.GetString(params, "ScanBy") != "TimestampAscending"
+	results := make([]map[string]interface{}, 0, len(queries))
+	for _, q := range queries {
+		v, err := resolve(q.id)
+		if err != nil {
+			writeCBORError(w, "InvalidParameterValue", err.Error(), http.StatusBadRequest)
+			return
+		}
+		if !q.returnData {
+			continue
+		}
+		var out *series
+		switch sv := v.(type) {
+		case *series:
+			out = sv
+		case float64:
+			// A scalar is reported in every period of the time range.
+			period := q.period
+			if period == 0 {
+				period = step
+			}
+			out = newSeries(period)
+			if !math.IsNaN(sv) && !math.IsInf(sv, 0) {
+				for t := start; t.Before(end); t = t.Add(period) {
+					out.points[t.Unix()] = sv
+				}
+			}
+		default:
+			writeCBORError(w, "InvalidParameterValue", fmt.Sprintf("Error in expression '%s': the expression must return a single time series; aggregate arrays with a function such as SUM", q.id), http.StatusBadRequest)
+			return
+		}
+		label := q.label
+		if label == "" {
+			label = q.id
+		}
+		ts := out.times()
+		if descending {
+			sort.Slice(ts, func(i, j int) bool { return ts[i] > ts[j] })
+		}
+		timestamps := make([]interface{}, len(ts))
+		vals := make([]float64, len(ts))
+		for i, t := range ts {
+			timestamps[i] = epochTag(time.Unix(t, 0))
+			vals[i] = out.points[t]
+		}
+		results = append(results, map[string]interface{}{
+			"Id":         q.id,
+			"Label":      label,
+			"Timestamps": timestamps,
+			"Values":     vals,
+			"StatusCode": "Complete",
+		})
+	}
+
+	writeCBOR(w, http.StatusOK, map[string]interface{}{
+		"MetricDataResults": results,
+		"Messages":          []interface{}{},
+	})
+}
+
+// statSeries computes a metric query's statistic for each period from
+// start to end that has data, periods being aligned to start. The caller
+// must hold s.mu.
+func (s *Service) statSeries(q *metricQuery, start, end time.Time) *series {
+	account := q.account
+	if account == "" {
+		account = h.DefaultAccountID
+	}
+	byPeriod := make(map[int64][]float64)
+	for _, m := range s.metrics {
+		if m.account != account || m.namespace != q.namespace || m.metricName != q.metricName || !sameDimensions(m.dimensions, q.dimensions) {
+			continue
+		}
+		if q.unit != "" && m.unit != q.unit {
+			continue
+		}
+		if m.timestamp.Before(start) || !m.timestamp.Before(end) {
+			continue
+		}
+		bucket := start.Add(m.timestamp.Sub(start) / q.period * q.period)
+		byPeriod[bucket.Unix()] = append(byPeriod[bucket.Unix()], m.value)
+	}
+	out := newSeries(q.period)
+	for t, values := range byPeriod {
+		out.points[t] = statistic(q.stat, values)
+	}
+	return out
+}
@@ -0,0 +1,497 @@
+package cloudwatch
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// series is a metric time series: one value per period, keyed by the
+// period's start in Unix seconds.
+type series struct {
+	period time.Duration
+	points map[int64]float64
+}
+
+func newSeries(period time.Duration) *series {
+	return &series{period: period, points: make(map[int64]float64)}
+}
+
+// times returns the series' timestamps in ascending order.
+func (s *series) times() []int64 {
+	ts := make([]int64, 0, len(s.points))
+	for t := range s.points {
+		ts = append(ts, t)
+	}
+	sort.Slice(ts, func(i, j int) bool { return ts[i] < ts[j] })
+	return ts
+}
+
+// fillMode is the REPEAT or LINEAR argument of FILL.
+type fillMode string
+
+// mathEnv is what a metric math expression is evaluated against.
+type mathEnv struct {
+	start, end time.Time
+	period     time.Duration
+	// resolve returns the value of the query with the given ID.
+	resolve func(id string) (interface{}, error)
+	// metrics returns the series of the metric queries whose IDs contain
+	// filter, in query order.
+	metrics func(filter string) ([]*series, error)
+}
+
+// evalExpression evaluates a metric math expression. Values are float64
+// scalars, *series, and []*series arrays:
+//
+//	m1 + m2 * 2, (m1 - m2) / m1 ^ 2, -m1
+//	SUM([m1, m2]), AVG(METRICS()), MIN(m1), MAX(METRICS("errors"))
+//	FILL(m1, 0), FILL(m1, REPEAT), FILL(m1, LINEAR)
+//	RATE(m1), DIFF(m1), ABS(m1), CEIL(m1), FLOOR(m1)
+//
+// Arithmetic between two series pairs up their values by timestamp.
+// SUM, AVG, MIN, and MAX reduce a single series to a scalar, and an array
+// to a series of the aggregate at each timestamp.
+func evalExpression(expr string, env *mathEnv) (interface{}, error) {
+	toks, err := tokenizeMath(expr)
+	if err != nil {
+		return nil, err
+	}
+	p := &mathParser{toks: toks, env: env}
+	v, err := p.expr()
+	if err == nil && p.pos < len(p.toks) {
+		err = fmt.Errorf("unexpected %q", p.toks[p.pos])
+	}
+	return v, err
+}
+
+// tokenizeMath splits an expression into numbers, identifiers, quoted
+// strings, and operators.
+func tokenizeMath(expr string) ([]string, error) {
+	var toks []string
+	for i := 0; i < len(expr); {
+		c := expr[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n':
+			i++
+		case strings.IndexByte("+-*/^()[],", c) >= 0:
+			toks = append(toks, string(c))
+			i++
+		case c == '"':
+			end := strings.IndexByte(expr[i+1:], '"')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated string")
+			}
+			toks = append(toks, expr[i:i+end+2])
+			i += end + 2
+		default:
+			j := i
+			for j < len(expr) && (expr[j] == '_' || expr[j] == '.' || isAlnum(expr[j])) {
+				j++
+			}
+			if j == i {
+				return nil, fmt.Errorf("unexpected %q", c)
+			}
+			toks = append(toks, expr[i:j])
+			i = j
+		}
+	}
+	return toks, nil
+}
+
+func isAlnum(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
+
+type mathParser struct {
+	toks []string
+	pos  int
+	env  *mathEnv
+}
+
+func (p *mathParser) peek() string {
+	if p.pos < len(p.toks) {
+		return p.toks[p.pos]
+	}
+	return ""
+}
+
+func (p *mathParser) expect(tok string) error {
+	if p.peek() != tok {
+		if p.peek() == "" {
+			return fmt.Errorf("expected %q at end of expression", tok)
+		}
+		return fmt.Errorf("expected %q, found %q", tok, p.peek())
+	}
+	p.pos++
+	return nil
+}
+
+func (p *mathParser) expr() (interface{}, error) {
+	left, err := p.term()
+	for err == nil && (p.peek() == "+" || p.peek() == "-") {
+		op := p.toks[p.pos]
+		p.pos++
+		var right interface{}
+		if right, err = p.term(); err == nil {
+			left, err = arith(op, left, right)
+		}
+	}
+	return left, err
+}
+
+func (p *mathParser) term() (interface{}, error) {
+	left, err := p.power()
+	for err == nil && (p.peek() == "*" || p.peek() == "/") {
+		op := p.toks[p.pos]
+		p.pos++
+		var right interface{}
+		if right, err = p.power(); err == nil {
+			left, err = arith(op, left, right)
+		}
+	}
+	return left, err
+}
+
+func (p *mathParser) power() (interface{}, error) {
+	base, err := p.unary()
+	if err != nil || p.peek() != "^" {
+		return base, err
+	}
+	p.pos++
+	exp, err := p.power()
+	if err != nil {
+		return nil, err
+	}
+	return arith("^", base, exp)
+}
+
+func (p *mathParser) unary() (interface{}, error) {
+	if p.peek() == "-" {
+		p.pos++
+		x, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return arith("*", x, -1.0)
+	}
+	return p.primary()
+}
+
+func (p *mathParser) primary() (interface{}, error) {
+	t := p.peek()
+	p.pos++
+	switch {
+	case t == "":
+		return nil, fmt.Errorf("unexpected end of expression")
+	case t == "(":
+		v, err := p.expr()
+		if err == nil {
+			err = p.expect(")")
+		}
+		return v, err
+	case t == "[":
+		args, err := p.args("]")
+		if err != nil {
+			return nil, err
+		}
+		var arr []*series
+		for _, a := range args {
+			switch v := a.(type) {
+			case *series:
+				arr = append(arr, v)
+			case []*series:
+				arr = append(arr, v...)
+			default:
+				return nil, fmt.Errorf("arrays can only contain time series")
+			}
+		}
+		return arr, nil
+	case strings.HasPrefix(t, `"`):
+		return t[1 : len(t)-1], nil
+	case t == "REPEAT" || t == "LINEAR":
+		return fillMode(t), nil
+	}
+	if n, err := strconv.ParseFloat(t, 64); err == nil {
+		return n, nil
+	}
+	if p.peek() == "(" {
+		p.pos++
+		args, err := p.args(")")
+		if err != nil {
+			return nil, err
+		}
+		return p.call(t, args)
+	}
+	return p.env.resolve(t)
+}
+
+// args parses a comma-separated list up to and including the close token.
+func (p *mathParser) args(close string) ([]interface{}, error) {
+	var args []interface{}
+	for p.peek() != close {
+		v, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, v)
+		if p.peek() != "," {
+			break
+		}
+		p.pos++
+	}
+	return args, p.expect(close)
+}
+
+func (p *mathParser) call(name string, args []interface{}) (interface{}, error) {
+	switch name {
+	case "METRICS":
+		filter := ""
+		if len(args) > 0 {
+			s, ok := args[0].(string)
+			if !ok || len(args) > 1 {
+				return nil, fmt.Errorf("METRICS takes an optional string argument")
+			}
+			filter = s
+		}
+		return p.env.metrics(filter)
+	case "SUM", "AVG", "MIN", "MAX":
+		if len(args) != 1 {
+			return nil, fmt.Errorf("%s takes one argument", name)
+		}
+		return aggregate(name, args[0])
+	case "ABS", "CEIL", "FLOOR":
+		if len(args) != 1 {
+			return nil, fmt.Errorf("%s takes one argument", name)
+		}
+		fn := map[string]func(float64) float64{"ABS": math.Abs, "CEIL": math.Ceil, "FLOOR": math.Floor}[name]
+		return mapValues(args[0], func(*series) func(int64, float64) (float64, bool) {
+			return func(_ int64, v float64) (float64, bool) { return fn(v), true }
+		})
+	case "RATE", "DIFF":
+		if len(args) != 1 {
+			return nil, fmt.Errorf("%s takes one argument", name)
+		}
+		if _, ok := args[0].(float64); ok {
+			return nil, fmt.Errorf("%s takes a time series", name)
+		}
+		return mapValues(args[0], func(s *series) func(int64, float64) (float64, bool) {
+			ts := s.times()
+			prev := make(map[int64]int64, len(ts))
+			for i := 1; i < len(ts); i++ {
+				prev[ts[i]] = ts[i-1]
+			}
+			return func(t int64, v float64) (float64, bool) {
+				pt, ok := prev[t]
+				if !ok {
+					return 0, false
+				}
+				d := v - s.points[pt]
+				if name == "RATE" {
+					d /= float64(t - pt)
+				}
+				return d, true
+			}
+		})
+	case "FILL":
+		if len(args) != 2 {
+			return nil, fmt.Errorf("FILL takes a time series and a fill value")
+		}
+		return p.fill(args[0], args[1])
+	}
+	return nil, fmt.Errorf("unknown function %s", name)
+}
+
+// fill gives x a value in every period between the query's start and end
+// that it has none for: with is a number, REPEAT for the last value before
+// it, or LINEAR to interpolate between the values around it.
+func (p *mathParser) fill(x, with interface{}) (interface{}, error) {
+	switch with.(type) {
+	case float64, fillMode:
+	default:
+		return nil, fmt.Errorf("FILL value must be a number, REPEAT, or LINEAR")
+	}
+	fillOne := func(s *series) *series {
+		out := newSeries(s.period)
+		ts := s.times()
+		step := int64(s.period / time.Second)
+		if step <= 0 {
+			step = int64(p.env.period / time.Second)
+		}
+		for t := p.env.start.Unix(); t < p.env.end.Unix(); t += step {
+			if v, ok := s.points[t]; ok {
+				out.points[t] = v
+				continue
+			}
+			i := sort.Search(len(ts), func(i int) bool { return ts[i] > t })
+			switch w := with.(type) {
+			case float64:
+				out.points[t] = w
+			case fillMode:
+				switch {
+				case len(ts) == 0:
+				case i == 0:
+					out.points[t] = s.points[ts[0]]
+				case i == len(ts) || w == "REPEAT":
+					out.points[t] = s.points[ts[i-1]]
+				default:
+					t0, t1 := ts[i-1], ts[i]
+					v0, v1 := s.points[t0], s.points[t1]
+					out.points[t] = v0 + (v1-v0)*float64(t-t0)/float64(t1-t0)
+				}
+			}
+		}
+		return out
+	}
+	switch v := x.(type) {
+	case *series:
+		return fillOne(v), nil
+	case []*series:
+		out := make([]*series, len(v))
+		for i, s := range v {
+			out[i] = fillOne(s)
+		}
+		return out, nil
+	}
+	return nil, fmt.Errorf("FILL takes a time series")
+}
+
+// mapValues applies the function made for each series to its values,
+// dropping values it reports false for. Scalars are mapped as a series
+// with one value.
+func mapValues(x interface{}, fn func(*series) func(int64, float64) (float64, bool)) (interface{}, error) {
+	mapOne := func(s *series) *series {
+		f := fn(s)
+		out := newSeries(s.period)
+		for t, v := range s.points {
+			if r, ok := f(t, v); ok {
+				out.points[t] = r
+			}
+		}
+		return out
+	}
+	switch v := x.(type) {
+	case float64:
+		r, _ := fn(nil)(0, v)
+		return r, nil
+	case *series:
+		return mapOne(v), nil
+	case []*series:
+		out := make([]*series, len(v))
+		for i, s := range v {
+			out[i] = mapOne(s)
+		}
+		return out, nil
+	}
+	return nil, fmt.Errorf("unsupported argument")
+}
+
+// aggregate computes SUM, AVG, MIN, or MAX.
+func aggregate(name string, x interface{}) (interface{}, error) {
+	stat := map[string]string{"SUM": "Sum", "AVG": "Average", "MIN": "Minimum", "MAX": "Maximum"}[name]
+	switch v := x.(type) {
+	case float64:
+		return v, nil
+	case *series:
+		if len(v.points) == 0 {
+			return math.NaN(), nil
+		}
+		values := make([]float64, 0, len(v.points))
+		for _, t := range v.times() {
+			values = append(values, v.points[t])
+		}
+		return statistic(stat, values), nil
+	case []*series:
+		var period time.Duration
+		byTime := make(map[int64][]float64)
+		for _, s := range v {
+			if period == 0 {
+				period = s.period
+			}
+			for t, val := range s.points {
+				byTime[t] = append(byTime[t], val)
+			}
+		}
+		out := newSeries(period)
+		for t, values := range byTime {
+			out.points[t] = statistic(stat, values)
+		}
+		return out, nil
+	}
+	return nil, fmt.Errorf("%s takes a time series or an array of time series", name)
+}
+
+// arith applies an arithmetic operator to two values, pairing up series
+// values by timestamp. Results that are not finite, as from dividing by
+// zero, are dropped.
+func arith(op string, a, b interface{}) (interface{}, error) {
+	apply := func(x, y float64) float64 {
+		switch op {
+		case "+":
+			return x + y
+		case "-":
+			return x - y
+		case "*":
+			return x * y
+		case "/":
+			return x / y
+		}
+		return math.Pow(x, y)
+	}
+	switch x := a.(type) {
+	case float64:
+		switch y := b.(type) {
+		case float64:
+			return apply(x, y), nil
+		case *series:
+			return combine(y, func(_ int64, v float64) (float64, bool) { return apply(x, v), true }), nil
+		case []*series:
+			return eachSeries(y, func(s *series) (interface{}, error) { return arith(op, x, s) })
+		}
+	case *series:
+		switch y := b.(type) {
+		case float64:
+			return combine(x, func(_ int64, v float64) (float64, bool) { return apply(v, y), true }), nil
+		case *series:
+			return combine(x, func(t int64, v float64) (float64, bool) {
+				w, ok := y.points[t]
+				return apply(v, w), ok
+			}), nil
+		case []*series:
+			return eachSeries(y, func(s *series) (interface{}, error) { return arith(op, x, s) })
+		}
+	case []*series:
+		switch b.(type) {
+		case float64, *series:
+			return eachSeries(x, func(s *series) (interface{}, error) { return arith(op, s, b) })
+		case []*series:
+			return nil, fmt.Errorf("cannot apply %s to two arrays of time series", op)
+		}
+	}
+	return nil, fmt.Errorf("invalid operands for %s", op)
+}
+
+func combine(s *series, fn func(int64, float64) (float64, bool)) *series {
+	out := newSeries(s.period)
+	for t, v := range s.points {
+		if r, ok := fn(t, v); ok && !math.IsNaN(r) && !math.IsInf(r, 0) {
+			out.points[t] = r
+		}
+	}
+	return out
+}
+
+func eachSeries(arr []*series, fn func(*series) (interface{}, error)) (interface{}, error) {
+	out := make([]*series, len(arr))
+	for i, s := range arr {
+		v, err := fn(s)
+		if err != nil {
+			return nil, err
+		}
+		out[i] = v.(*series)
+	}
+	return out, nil
+}
@@ -10,18 +10,28 @@
 //   - PutLogEvents
 //   - GetLogEvents
 //   - FilterLogEvents
+//   - TagResource
+//   - UntagResource
+//   - ListTagsForResource
+//
+// Log events in the CloudWatch embedded metric format publish their metrics
+// to the CloudWatch metrics mock, where GetMetricData and ListMetrics see
+// them.
 package cloudwatchlogs
 
 import (
 	"encoding/json"
 	"fmt"
-	"io"
 	"math/rand"
 	"net/http"
 	"sort"
 	"strings"
 	"sync"
 	"time"
+
+	"github.com/riyanimam/goto/internal/awserr"
+	"github.com/riyanimam/goto/internal/mockhelpers"
+	"github.com/riyanimam/goto/internal/tags"
 )
 
 const defaultAccountID = "123456789012"
@@ -30,6 +40,9 @@ const defaultAccountID = "123456789012"
 type Service struct {
 	mu        sync.RWMutex
 	logGroups map[string]*logGroup // keyed by log group name
+	tags      *tags.Store
+
+	metricStore mockhelpers.MetricStore
 }
 
 type logGroup struct {
@@ -57,6 +70,7 @@ type logEvent struct {
 func New() *Service {
 	return &Service{
 		logGroups: make(map[string]*logGroup),
+		tags:      tags.New(),
 	}
 }
 
@@ -65,7 +79,20 @@ func (s *Service) Name() string { return "logs" }
 
 // Handler returns the HTTP handler for CloudWatch Logs requests.
 func (s *Service) Handler() http.Handler {
-	return http.HandlerFunc(s.handle)
+	return mockhelpers.JSONRouter{
+		"CreateLogGroup":      s.createLogGroup,
+		"DeleteLogGroup":      s.deleteLogGroup,
+		"DescribeLogGroups":   s.describeLogGroups,
+		"CreateLogStream":     s.createLogStream,
+		"DeleteLogStream":     s.deleteLogStream,
+		"DescribeLogStreams":  s.describeLogStreams,
+		"PutLogEvents":        s.putLogEvents,
+		"GetLogEvents":        s.getLogEvents,
+		"FilterLogEvents":     s.filterLogEvents,
+		"TagResource":         s.tagResource,
+		"UntagResource":       s.untagResource,
+		"ListTagsForResource": s.listTagsForResource,
+	}
 }
 
 // Reset clears all log groups, streams, and events.
@@ -73,58 +100,69 @@ func (s *Service) Reset() {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	s.logGroups = make(map[string]*logGroup)
+	s.tags.DeleteService("logs")
 }
 
-func (s *Service) handle(w http.ResponseWriter, r *http.Request) {
-	target := r.Header.Get("X-Amz-Target")
+// SetTagStore sets the registry log group tags are recorded in.
+func (s *Service) SetTagStore(store *tags.Store) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tags = store
+}
 
-	bodyBytes, err := io.ReadAll(r.Body)
-	if err != nil {
-		writeJSONError(w, "InternalFailure", "could not read request body", http.StatusInternalServerError)
-		return
+// HasResource reports whether the log group identified by arn exists. The
+// ARN matches with or without the trailing ":*".
+func (s *Service) HasResource(arn string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, g := range s.logGroups {
+		if g.arn == arn || g.arn == arn+":*" {
+			return true
+		}
 	}
+	return false
+}
 
-	var params map[string]interface{}
-	if len(bodyBytes) > 0 {
-		if err := json.Unmarshal(bodyBytes, &params); err != nil {
-			writeJSONError(w, "InvalidParameterException", "could not parse request body", http.StatusBadRequest)
-			return
-		}
+// Deliver appends each line of payload as a log event to the log stream
+// identified by arn, creating the stream if the log group does not have it
+// yet. Services that write logs on their own (e.g. VPC flow logs) deliver
+// them this way.
+func (s *Service) Deliver(arn string, payload []byte) ([]byte, error) {
+	_, rest, ok := strings.Cut(arn, ":log-group:")
+	groupName, streamName, ok2 := strings.Cut(rest, ":log-stream:")
+	if !ok || !ok2 || streamName == "" {
+		return nil, fmt.Errorf("%s is not a log stream ARN", arn)
 	}
-	if params == nil {
-		params = make(map[string]interface{})
+
+	s.mu.RLock()
+	lg, exists := s.logGroups[groupName]
+	s.mu.RUnlock()
+
+	if !exists {
+		return nil, fmt.Errorf("log group %s does not exist", groupName)
 	}
 
-	action := ""
-	if target != "" {
-		parts := strings.SplitN(target, ".", 2)
-		if len(parts) == 2 {
-			action = parts[1]
+	lg.streamsMu.Lock()
+	ls, exists := lg.streams[streamName]
+	if !exists {
+		ls = &logStream{
+			name:    streamName,
+			arn:     fmt.Sprintf("arn:aws:logs:us-east-1:%s:log-group:%s:log-stream:%s", defaultAccountID, groupName, streamName),
+			created: time.Now().UnixMilli(),
 		}
+		lg.streams[streamName] = ls
 	}
-
-	switch action {
-	case "CreateLogGroup":
-		s.createLogGroup(w, params)
-	case "DeleteLogGroup":
-		s.deleteLogGroup(w, params)
-	case "DescribeLogGroups":
-		s.describeLogGroups(w, params)
-	case "CreateLogStream":
-		s.createLogStream(w, params)
-	case "DeleteLogStream":
-		s.deleteLogStream(w, params)
-	case "DescribeLogStreams":
-		s.describeLogStreams(w, params)
-	case "PutLogEvents":
-		s.putLogEvents(w, params)
-	case "GetLogEvents":
-		s.getLogEvents(w, params)
-	case "FilterLogEvents":
-		s.filterLogEvents(w, params)
-	default:
-		writeJSONError(w, "UnknownOperationException", fmt.Sprintf("action %q is not supported", action), http.StatusBadRequest)
+	now := time.Now().UnixMilli()
+	lines := strings.Split(string(payload), "\n")
+	timestamps := make([]int64, len(lines))
+	for i, line := range lines {
+		ls.events = append(ls.events, &logEvent{timestamp: now, message: line, ingested: now})
+		timestamps[i] = now
 	}
+	lg.streamsMu.Unlock()
+
+	s.publishEMF(lines, timestamps)
+	return nil, nil
 }
 
 func (s *Service) createLogGroup(w http.ResponseWriter, params map[string]interface{}) {
@@ -141,12 +179,14 @@ func (s *Service) createLogGroup(w http.ResponseWriter, params map[string]interf
 		return
 	}
 
-	s.logGroups[name] = &logGroup{
+	lg := &logGroup{
 		name:    name,
 		arn:     fmt.Sprintf("arn:aws:logs:us-east-1:%s:log-group:%s:*", defaultAccountID, name),
 		created: time.Now().UnixMilli(),
 		streams: make(map[string]*logStream),
 	}
+	s.logGroups[name] = lg
+	s.tags.Tag(lg.taggingARN(), tags.FromMap(params["tags"]))
 	s.mu.Unlock()
 
 	writeJSON(w, http.StatusOK, map[string]interface{}{})
@@ -156,17 +196,78 @@ func (s *Service) deleteLogGroup(w http.ResponseWriter, params map[string]interf
 	name := getString(params, "logGroupName")
 
 	s.mu.Lock()
-	if _, exists := s.logGroups[name]; !exists {
+	lg, exists := s.logGroups[name]
+	if !exists {
 		s.mu.Unlock()
 		writeJSONError(w, "ResourceNotFoundException", "The specified log group does not exist", http.StatusBadRequest)
 		return
 	}
 	delete(s.logGroups, name)
+	s.tags.Delete(lg.taggingARN())
 	s.mu.Unlock()
 
 	writeJSON(w, http.StatusOK, map[string]interface{}{})
 }
 
+func (s *Service) tagResource(w http.ResponseWriter, params map[string]interface{}) {
+	arn := getString(params, "resourceArn")
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if !s.hasLogGroup(arn) {
+		writeJSONError(w, "ResourceNotFoundException", "The specified resource does not exist", http.StatusBadRequest)
+		return
+	}
+	s.tags.Tag(arn, tags.FromMap(params["tags"]))
+	writeJSON(w, http.StatusOK, map[string]interface{}{})
+}
+
+func (s *Service) untagResource(w http.ResponseWriter, params map[string]interface{}) {
+	arn := getString(params, "resourceArn")
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if !s.hasLogGroup(arn) {
+		writeJSONError(w, "ResourceNotFoundException", "The specified resource does not exist", http.StatusBadRequest)
+		return
+	}
+	s.tags.Untag(arn, tags.Keys(params["tagKeys"]))
+	writeJSON(w, http.StatusOK, map[string]interface{}{})
+}
+
+func (s *Service) listTagsForResource(w http.ResponseWriter, params map[string]interface{}) {
+	arn := getString(params, "resourceArn")
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if !s.hasLogGroup(arn) {
+		writeJSONError(w, "ResourceNotFoundException", "The specified resource does not exist", http.StatusBadRequest)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"tags": s.tags.Get(arn),
+	})
+}
+
+// taggingARN returns the log group ARN used by the tagging APIs, which omits
+// the ":*" suffix of the ARN reported by DescribeLogGroups.
+func (lg *logGroup) taggingARN() string {
+	return strings.TrimSuffix(lg.arn, ":*")
+}
+
+// hasLogGroup reports whether arn names a log group. Caller must hold s.mu.
+func (s *Service) hasLogGroup(arn string) bool {
+	for _, lg := range s.logGroups {
+		if lg.taggingARN() == arn {
+			return true
+		}
+	}
+	return false
+}
+
 func (s *Service) describeLogGroups(w http.ResponseWriter, params map[string]interface{}) {
 	prefix := getString(params, "logGroupNamePrefix")
 
@@ -303,6 +404,8 @@ func (s *Service) putLogEvents(w http.ResponseWriter, params map[string]interfac
 	}
 
 	now := time.Now().UnixMilli()
+	var messages []string
+	var timestamps []int64
 	if events, ok := params["logEvents"].([]interface{}); ok {
 		for _, e := range events {
 			if em, ok := e.(map[string]interface{}); ok {
@@ -319,10 +422,13 @@ func (s *Service) putLogEvents(w http.ResponseWriter, params map[string]interfac
 					message:   msg,
 					ingested:  now,
 				})
+				messages = append(messages, msg)
+				timestamps = append(timestamps, ts)
 			}
 		}
 	}
 	lg.streamsMu.Unlock()
+	s.publishEMF(messages, timestamps)
 
 	writeJSON(w, http.StatusOK, map[string]interface{}{
 		"nextSequenceToken": newRequestID(),
@@ -421,12 +527,7 @@ func writeJSON(w http.ResponseWriter, status int, v interface{}) {
 }
 
 func writeJSONError(w http.ResponseWriter, code, message string, status int) {
-	w.Header().Set("Content-Type", "application/x-amz-json-1.1")
-	w.WriteHeader(status)
-	json.NewEncoder(w).Encode(map[string]interface{}{
-		"__type":  code,
-		"message": message,
-	})
+	awserr.WriteJSON(w, awserr.JSON11, code, message, status)
 }
 
 func newRequestID() string {
@@ -0,0 +1,133 @@
+package cloudwatchlogs
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/riyanimam/goto/internal/mockhelpers"
+)
+
+// emfMetadata is the "_aws" member of an embedded metric format log event.
+type emfMetadata struct {
+	Timestamp         *int64 `json:"Timestamp"`
+	CloudWatchMetrics []struct {
+		Namespace  string     `json:"Namespace"`
+		Dimensions [][]string `json:"Dimensions"`
+		Metrics    []struct {
+			Name string `json:"Name"`
+			Unit string `json:"Unit"`
+		} `json:"Metrics"`
+	} `json:"CloudWatchMetrics"`
+}
+
+// SetMetricStore sets where metrics extracted from embedded metric format
+// log events are published.
+func (s *Service) SetMetricStore(store mockhelpers.MetricStore) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.metricStore = store
+}
+
+// publishEMF extracts the metrics of the embedded metric format events among
+// messages and publishes them to the CloudWatch metrics mock. Other messages,
+// and events that are not valid EMF, are only logged, as in CloudWatch.
+func (s *Service) publishEMF(messages []string, timestamps []int64) {
+	s.mu.RLock()
+	store := s.metricStore
+	s.mu.RUnlock()
+	if store == nil {
+		return
+	}
+
+	var metrics []mockhelpers.Metric
+	for i, msg := range messages {
+		if !strings.Contains(msg, `"_aws"`) {
+			continue
+		}
+		metrics = append(metrics, extractEMF(msg, timestamps[i])...)
+	}
+	if len(metrics) > 0 {
+		store.PutMetrics(metrics)
+	}
+}
+
+// extractEMF returns the metrics an embedded metric format event declares:
+// one data point per value, per metric, per dimension set of each
+// directive. Dimension and metric values are read from the event's root.
+func extractEMF(message string, timestamp int64) []mockhelpers.Metric {
+	var root map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(message), &root); err != nil {
+		return nil
+	}
+	var meta emfMetadata
+	if err := json.Unmarshal(root["_aws"], &meta); err != nil || len(meta.CloudWatchMetrics) == 0 {
+		return nil
+	}
+	if meta.Timestamp != nil {
+		timestamp = *meta.Timestamp
+	}
+	ts := time.UnixMilli(timestamp).UTC()
+
+	var out []mockhelpers.Metric
+	for _, directive := range meta.CloudWatchMetrics {
+		if directive.Namespace == "" {
+			continue
+		}
+		dimensionSets := directive.Dimensions
+		if len(dimensionSets) == 0 {
+			dimensionSets = [][]string{nil}
+		}
+		for _, set := range dimensionSets {
+			dims, ok := emfDimensions(root, set)
+			if !ok {
+				continue
+			}
+			for _, m := range directive.Metrics {
+				for _, v := range emfValues(root[m.Name]) {
+					out = append(out, mockhelpers.Metric{
+						Namespace:  directive.Namespace,
+						Name:       m.Name,
+						Unit:       m.Unit,
+						Value:      v,
+						Dimensions: dims,
+						Timestamp:  ts,
+					})
+				}
+			}
+		}
+	}
+	return out
+}
+
+// emfDimensions reads the values of a dimension set from the event's root.
+// A set naming a member that is missing is skipped, as in CloudWatch.
+func emfDimensions(root map[string]json.RawMessage, names []string) (map[string]string, bool) {
+	dims := make(map[string]string, len(names))
+	for _, name := range names {
+		raw, ok := root[name]
+		if !ok {
+			return nil, false
+		}
+		var v interface{}
+		json.Unmarshal(raw, &v)
+		if str, ok := v.(string); ok {
+			dims[name] = str
+		} else {
+			dims[name] = fmt.Sprint(v)
+		}
+	}
+	return dims, true
+}
+
+// emfValues reads a metric's value, a number or an array of numbers.
+func emfValues(raw json.RawMessage) []float64 {
+	var v float64
+	if err := json.Unmarshal(raw, &v); err == nil {
+		return []float64{v}
+	}
+	var vs []float64
+	json.Unmarshal(raw, &vs)
+	return vs
+}
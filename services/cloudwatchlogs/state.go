@@ -0,0 +1,80 @@
+package cloudwatchlogs
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/riyanimam/goto/internal/mockhelpers"
+)
+
+// ExportState lists the log groups and their streams.
+func (s *Service) ExportState() []mockhelpers.Resource {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	resources := make([]mockhelpers.Resource, 0, len(s.logGroups))
+	for _, lg := range s.logGroups {
+		resources = append(resources, mockhelpers.Resource{
+			Type:       "aws_cloudwatch_log_group",
+			ID:         lg.name,
+			ARN:        lg.arn,
+			Attributes: map[string]interface{}{"name": lg.name},
+		})
+
+		lg.streamsMu.Lock()
+		for _, ls := range lg.streams {
+			resources = append(resources, mockhelpers.Resource{
+				Type: "aws_cloudwatch_log_stream",
+				ID:   ls.name,
+				ARN:  ls.arn,
+				Attributes: map[string]interface{}{
+					"name":           ls.name,
+					"log_group_name": lg.name,
+					"event_count":    len(ls.events),
+				},
+			})
+		}
+		lg.streamsMu.Unlock()
+	}
+	return resources
+}
+
+// Event is a log event as seen by [Service.Events].
+type Event struct {
+	Stream    string
+	Timestamp time.Time
+	Message   string
+}
+
+// Events returns the events in every stream of the log group, ordered by
+// timestamp.
+func (s *Service) Events(group string) ([]Event, error) {
+	s.mu.RLock()
+	lg, exists := s.logGroups[group]
+	s.mu.RUnlock()
+
+	if !exists {
+		return nil, fmt.Errorf("log group %s does not exist", group)
+	}
+
+	lg.streamsMu.Lock()
+	defer lg.streamsMu.Unlock()
+	var events []Event
+	for _, ls := range lg.streams {
+		for _, e := range ls.events {
+			events = append(events, Event{
+				Stream:    ls.name,
+				Timestamp: time.UnixMilli(e.timestamp).UTC(),
+				Message:   e.message,
+			})
+		}
+	}
+	sort.SliceStable(events, func(i, j int) bool {
+		if !events[i].Timestamp.Equal(events[j].Timestamp) {
+			return events[i].Timestamp.Before(events[j].Timestamp)
+		}
+		return events[i].Stream < events[j].Stream
+	})
+	return events, nil
+}
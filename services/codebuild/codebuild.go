@@ -7,12 +7,14 @@
 //   - DeleteProject
 //   - StartBuild
 //   - BatchGetBuilds
+//
+// Builds started with StartBuild stay IN_PROGRESS. Builds started by other
+// mock services (CodePipeline build actions) complete immediately.
 package codebuild
 
 import (
 	"encoding/json"
 	"fmt"
-	"io"
 	"net/http"
 	"sort"
 	"strings"
@@ -20,6 +22,7 @@ import (
 	"time"
 
 	h "github.com/riyanimam/goto/internal/mockhelpers"
+	"github.com/riyanimam/goto/internal/tags"
 )
 
 // Service implements the CodeBuild mock.
@@ -28,6 +31,7 @@ type Service struct {
 	projects map[string]*project
 	builds   map[string]*build
 	buildSeq map[string]int
+	tags     *tags.Store
 }
 
 type project struct {
@@ -57,7 +61,9 @@ type build struct {
 	projectName string
 	buildNumber int
 	buildStatus string
+	initiator   string
 	startTime   time.Time
+	endTime     time.Time
 	source      sourceInfo
 	environment environmentInfo
 }
@@ -68,6 +74,7 @@ func New() *Service {
 		projects: make(map[string]*project),
 		builds:   make(map[string]*build),
 		buildSeq: make(map[string]int),
+		tags:     tags.New(),
 	}
 }
 
@@ -76,7 +83,14 @@ func (s *Service) Name() string { return "codebuild" }
 
 // Handler returns the HTTP handler for CodeBuild requests.
 func (s *Service) Handler() http.Handler {
-	return http.HandlerFunc(s.handle)
+	return h.JSONRouter{
+		"CreateProject":    s.createProject,
+		"BatchGetProjects": s.batchGetProjects,
+		"ListProjects":     s.listProjects,
+		"DeleteProject":    s.deleteProject,
+		"StartBuild":       s.startBuild,
+		"BatchGetBuilds":   s.batchGetBuilds,
+	}
 }
 
 // Reset clears all state.
@@ -86,49 +100,14 @@ func (s *Service) Reset() {
 	s.projects = make(map[string]*project)
 	s.builds = make(map[string]*build)
 	s.buildSeq = make(map[string]int)
+	s.tags.DeleteService("codebuild")
 }
 
-func (s *Service) handle(w http.ResponseWriter, r *http.Request) {
-	target := r.Header.Get("X-Amz-Target")
-
-	bodyBytes, err := io.ReadAll(r.Body)
-	if err != nil {
-		h.WriteJSONError(w, "InternalFailure", "could not read request body", http.StatusInternalServerError)
-		return
-	}
-
-	var params map[string]interface{}
-	if len(bodyBytes) > 0 {
-		json.Unmarshal(bodyBytes, &params)
-	}
-	if params == nil {
-		params = make(map[string]interface{})
-	}
-
-	action := ""
-	if target != "" {
-		parts := strings.SplitN(target, ".", 2)
-		if len(parts) == 2 {
-			action = parts[1]
-		}
-	}
-
-	switch action {
-	case "CreateProject":
-		s.createProject(w, params)
-	case "BatchGetProjects":
-		s.batchGetProjects(w, params)
-	case "ListProjects":
-		s.listProjects(w, params)
-	case "DeleteProject":
-		s.deleteProject(w, params)
-	case "StartBuild":
-		s.startBuild(w, params)
-	case "BatchGetBuilds":
-		s.batchGetBuilds(w, params)
-	default:
-		h.WriteJSONError(w, "UnknownOperationException", fmt.Sprintf("action %q is not supported", action), http.StatusBadRequest)
-	}
+// SetTagStore sets the registry project tags are recorded in.
+func (s *Service) SetTagStore(store *tags.Store) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tags = store
 }
 
 func (s *Service) createProject(w http.ResponseWriter, params map[string]interface{}) {
@@ -166,10 +145,11 @@ func (s *Service) createProject(w http.ResponseWriter, params map[string]interfa
 	}
 
 	s.projects[name] = p
+	s.tags.Tag(p.arn, tags.FromList(params["tags"], "key", "value"))
 	s.mu.Unlock()
 
 	h.WriteJSON(w, http.StatusOK, map[string]interface{}{
-		"project": projectResp(p),
+		"project": s.projectResp(p),
 	})
 }
 
@@ -181,7 +161,7 @@ func (s *Service) batchGetProjects(w http.ResponseWriter, params map[string]inte
 	var notFound []string
 	for _, name := range names {
 		if p, exists := s.projects[name]; exists {
-			found = append(found, projectResp(p))
+			found = append(found, s.projectResp(p))
 		} else {
 			notFound = append(notFound, name)
 		}
@@ -214,12 +194,14 @@ func (s *Service) deleteProject(w http.ResponseWriter, params map[string]interfa
 	name := h.GetString(params, "name")
 
 	s.mu.Lock()
-	if _, exists := s.projects[name]; !exists {
+	p, exists := s.projects[name]
+	if !exists {
 		s.mu.Unlock()
 		h.WriteJSONError(w, "ResourceNotFoundException", "Project not found: "+name, http.StatusBadRequest)
 		return
 	}
 	delete(s.projects, name)
+	s.tags.Delete(p.arn)
 	s.mu.Unlock()
 
 	h.WriteJSON(w, http.StatusOK, map[string]interface{}{})
@@ -233,17 +215,30 @@ func (s *Service) startBuild(w http.ResponseWriter, params map[string]interface{
 	}
 
 	s.mu.Lock()
-	p, exists := s.projects[projectName]
-	if !exists {
-		s.mu.Unlock()
+	b := s.newBuild(projectName)
+	s.mu.Unlock()
+
+	if b == nil {
 		h.WriteJSONError(w, "ResourceNotFoundException", "Project not found: "+projectName, http.StatusBadRequest)
 		return
 	}
 
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"build": buildResp(b),
+	})
+}
+
+// newBuild records a new in-progress build of the project, or returns nil if
+// the project does not exist. Caller must hold s.mu.
+func (s *Service) newBuild(projectName string) *build {
+	p, exists := s.projects[projectName]
+	if !exists {
+		return nil
+	}
+
 	s.buildSeq[projectName]++
 	buildNumber := s.buildSeq[projectName]
 	buildID := fmt.Sprintf("%s:%s", projectName, h.NewRequestID())
-	now := time.Now().UTC()
 
 	b := &build{
 		id:          buildID,
@@ -251,16 +246,42 @@ func (s *Service) startBuild(w http.ResponseWriter, params map[string]interface{
 		projectName: projectName,
 		buildNumber: buildNumber,
 		buildStatus: "IN_PROGRESS",
-		startTime:   now,
+		startTime:   time.Now().UTC(),
 		source:      p.source,
 		environment: p.environment,
 	}
 	s.builds[buildID] = b
+	return b
+}
+
+// Deliver runs a build of the project identified by arn on behalf of another
+// service, such as a CodePipeline build action. The payload may name the
+// "initiator". Unlike StartBuild, the build completes immediately with
+// status SUCCEEDED, and the build is returned as JSON.
+func (s *Service) Deliver(arn string, payload []byte) ([]byte, error) {
+	projectName := arn
+	if i := strings.Index(arn, ":project/"); i >= 0 {
+		projectName = arn[i+len(":project/"):]
+	}
+
+	var req struct {
+		Initiator string `json:"initiator"`
+	}
+	json.Unmarshal(payload, &req)
+
+	s.mu.Lock()
+	b := s.newBuild(projectName)
+	if b != nil {
+		b.initiator = req.Initiator
+		b.buildStatus = "SUCCEEDED"
+		b.endTime = b.startTime
+	}
 	s.mu.Unlock()
 
-	h.WriteJSON(w, http.StatusOK, map[string]interface{}{
-		"build": buildResp(b),
-	})
+	if b == nil {
+		return nil, fmt.Errorf("project %s does not exist", projectName)
+	}
+	return json.Marshal(buildResp(b))
 }
 
 func (s *Service) batchGetBuilds(w http.ResponseWriter, params map[string]interface{}) {
@@ -285,7 +306,8 @@ func (s *Service) batchGetBuilds(w http.ResponseWriter, params map[string]interf
 	h.WriteJSON(w, http.StatusOK, resp)
 }
 
-func projectResp(p *project) map[string]interface{} {
+// projectResp renders a project along with its tags.
+func (s *Service) projectResp(p *project) map[string]interface{} {
 	return map[string]interface{}{
 		"name": p.name,
 		"arn":  p.arn,
@@ -301,11 +323,12 @@ func projectResp(p *project) map[string]interface{} {
 		"serviceRole":  p.serviceRole,
 		"created":      float64(p.created.Unix()),
 		"lastModified": float64(p.lastModified.Unix()),
+		"tags":         tags.ToList(s.tags.Get(p.arn), "key", "value"),
 	}
 }
 
 func buildResp(b *build) map[string]interface{} {
-	return map[string]interface{}{
+	resp := map[string]interface{}{
 		"id":          b.id,
 		"arn":         b.arn,
 		"projectName": b.projectName,
@@ -322,6 +345,13 @@ func buildResp(b *build) map[string]interface{} {
 			"computeType": b.environment.computeType,
 		},
 	}
+	if b.initiator != "" {
+		resp["initiator"] = b.initiator
+	}
+	if !b.endTime.IsZero() {
+		resp["endTime"] = float64(b.endTime.Unix())
+	}
+	return resp
 }
 
 func getStringSlice(params map[string]interface{}, key string) []string {
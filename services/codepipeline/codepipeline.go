@@ -6,25 +6,59 @@
 //   - DeletePipeline
 //   - ListPipelines
 //   - UpdatePipeline
+//   - StartPipelineExecution
+//   - GetPipelineExecution
+//   - ListPipelineExecutions
+//   - GetPipelineState
+//   - PutApprovalResult
+//   - RetryStageExecution
+//   - PutWebhook
+//   - DeleteWebhook
+//   - ListWebhooks
+//   - RegisterWebhookWithThirdParty
+//   - DeregisterWebhookWithThirdParty
+//   - TagResource
+//   - UntagResource
+//   - ListTagsForResource
+//
+// Executions run stage by stage as soon as they start. Each action is run by
+// the [ActionSimulator] registered for its provider; by default source
+// actions produce a random revision, CodeBuild actions start a build on the
+// CodeBuild mock, and everything else succeeds. Manual approval actions wait
+// for PutApprovalResult.
+//
+// Executions also start on their own when a source changes: S3 source
+// actions watch their object key in the S3 mock and ECR source actions
+// their image tag in the ECR mock, both checked as the mock clock advances,
+// and a POST to a webhook's URL whose payload passes the webhook's filters
+// starts its target pipeline. There is no CodeCommit mock, so CodeCommit
+// source actions only run when started.
 package codepipeline
 
 import (
-	"encoding/json"
 	"fmt"
-	"io"
 	"net/http"
 	"sort"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/riyanimam/goto/internal/clock"
 	h "github.com/riyanimam/goto/internal/mockhelpers"
+	"github.com/riyanimam/goto/internal/tags"
 )
 
 // Service implements the CodePipeline mock.
 type Service struct {
-	mu        sync.RWMutex
-	pipelines map[string]*pipeline
+	mu         sync.RWMutex
+	pipelines  map[string]*pipeline
+	simulators map[string]ActionSimulator
+	webhooks   map[string]*webhook
+	dispatch   h.Dispatcher
+	objects    h.ObjectStore
+	resources  h.ResourceLister
+	baseURL    string
+	tags       *tags.Store
 }
 
 type pipeline struct {
@@ -35,12 +69,21 @@ type pipeline struct {
 	version int
 	created time.Time
 	updated time.Time
+
+	executions []*execution // oldest first
+
+	// revisions are the last seen revisions of the watched source
+	// actions, by action name.
+	revisions map[string]string
 }
 
 // New creates a new CodePipeline mock service.
 func New() *Service {
 	return &Service{
-		pipelines: make(map[string]*pipeline),
+		pipelines:  make(map[string]*pipeline),
+		simulators: make(map[string]ActionSimulator),
+		webhooks:   make(map[string]*webhook),
+		tags:       tags.New(),
 	}
 }
 
@@ -49,58 +92,85 @@ func (s *Service) Name() string { return "codepipeline" }
 
 // Handler returns the HTTP handler for CodePipeline requests.
 func (s *Service) Handler() http.Handler {
-	return http.HandlerFunc(s.handle)
+	api := h.JSONRouter{
+		"CreatePipeline": s.createPipeline,
+		"GetPipeline":    s.getPipeline,
+		"DeletePipeline": s.deletePipeline,
+		"ListPipelines": func(w http.ResponseWriter, _ map[string]interface{}) {
+			s.listPipelines(w)
+		},
+		"UpdatePipeline":         s.updatePipeline,
+		"StartPipelineExecution": s.startPipelineExecution,
+		"GetPipelineExecution":   s.getPipelineExecution,
+		"ListPipelineExecutions": s.listPipelineExecutions,
+		"GetPipelineState":       s.getPipelineState,
+		"PutApprovalResult":      s.putApprovalResult,
+		"RetryStageExecution":    s.retryStageExecution,
+		"PutWebhook":             s.putWebhook,
+		"DeleteWebhook":          s.deleteWebhook,
+		"ListWebhooks":           s.listWebhooks,
+		"RegisterWebhookWithThirdParty": func(w http.ResponseWriter, params map[string]interface{}) {
+			s.registerWebhook(w, params, true)
+		},
+		"DeregisterWebhookWithThirdParty": func(w http.ResponseWriter, params map[string]interface{}) {
+			s.registerWebhook(w, params, false)
+		},
+		"TagResource":         s.tagResource,
+		"UntagResource":       s.untagResource,
+		"ListTagsForResource": s.listTagsForResource,
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, webhookPath) {
+			s.serveWebhook(w, r)
+			return
+		}
+		api.ServeHTTP(w, r)
+	})
 }
 
-// Reset clears all state.
+// Reset clears all pipelines, executions, and webhooks. Registered action
+// simulators are kept.
 func (s *Service) Reset() {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	s.pipelines = make(map[string]*pipeline)
+	s.webhooks = make(map[string]*webhook)
+	s.tags.DeleteService("codepipeline")
 }
 
-func (s *Service) handle(w http.ResponseWriter, r *http.Request) {
-	target := r.Header.Get("X-Amz-Target")
+// SetTagStore sets the registry resource tags are recorded in.
+func (s *Service) SetTagStore(store *tags.Store) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tags = store
+}
 
-	bodyBytes, err := io.ReadAll(r.Body)
-	if err != nil {
-		h.WriteJSONError(w, "InternalFailure", "could not read request body", http.StatusInternalServerError)
-		return
-	}
+// SetObjectStore sets the store S3 source actions read their objects from.
+func (s *Service) SetObjectStore(store h.ObjectStore) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.objects = store
+}
 
-	var params map[string]interface{}
-	if len(bodyBytes) > 0 {
-		if err := json.Unmarshal(bodyBytes, &params); err != nil {
-			h.WriteJSONError(w, "SerializationException", "could not parse request body", http.StatusBadRequest)
-			return
-		}
-	}
-	if params == nil {
-		params = make(map[string]interface{})
-	}
+// SetResourceLister sets the function ECR source actions look up their
+// images with.
+func (s *Service) SetResourceLister(l h.ResourceLister) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.resources = l
+}
 
-	action := ""
-	if target != "" {
-		parts := strings.SplitN(target, ".", 2)
-		if len(parts) == 2 {
-			action = parts[1]
-		}
-	}
+// SetBaseURL records the mock server URL, which webhook URLs point at.
+func (s *Service) SetBaseURL(u string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.baseURL = u
+}
 
-	switch action {
-	case "CreatePipeline":
-		s.createPipeline(w, params)
-	case "GetPipeline":
-		s.getPipeline(w, params)
-	case "DeletePipeline":
-		s.deletePipeline(w, params)
-	case "ListPipelines":
-		s.listPipelines(w)
-	case "UpdatePipeline":
-		s.updatePipeline(w, params)
-	default:
-		h.WriteJSONError(w, "UnknownOperationException", fmt.Sprintf("action %q is not supported", action), http.StatusBadRequest)
-	}
+// SetClock attaches the mock clock; watched sources are checked for
+// changes whenever it advances.
+func (s *Service) SetClock(c *clock.Clock) {
+	c.OnAdvance(func(_, _ time.Time) { s.pollSources() })
 }
 
 func (s *Service) createPipeline(w http.ResponseWriter, params map[string]interface{}) {
@@ -115,6 +185,7 @@ func (s *Service) createPipeline(w http.ResponseWriter, params map[string]interf
 		h.WriteJSONError(w, "InvalidParameterException", "pipeline name is required", http.StatusBadRequest)
 		return
 	}
+	revisions := s.sourceRevisions(pipelineObj["stages"])
 
 	s.mu.Lock()
 	if _, exists := s.pipelines[name]; exists {
@@ -132,8 +203,11 @@ func (s *Service) createPipeline(w http.ResponseWriter, params map[string]interf
 		version: 1,
 		created: now,
 		updated: now,
+
+		revisions: revisions,
 	}
 	s.pipelines[name] = p
+	s.tags.Tag(p.arn, tags.FromList(params["tags"], "key", "value"))
 	s.mu.Unlock()
 
 	h.WriteJSON(w, http.StatusOK, map[string]interface{}{
@@ -167,17 +241,78 @@ func (s *Service) deletePipeline(w http.ResponseWriter, params map[string]interf
 	name := h.GetString(params, "name")
 
 	s.mu.Lock()
-	if _, exists := s.pipelines[name]; !exists {
+	p, exists := s.pipelines[name]
+	if !exists {
 		s.mu.Unlock()
 		h.WriteJSONError(w, "PipelineNotFoundException", "Pipeline not found: "+name, http.StatusBadRequest)
 		return
 	}
 	delete(s.pipelines, name)
+	s.tags.Delete(p.arn)
 	s.mu.Unlock()
 
 	h.WriteJSON(w, http.StatusOK, map[string]interface{}{})
 }
 
+// hasResource reports whether arn names an existing pipeline or webhook.
+// The caller must hold s.mu.
+func (s *Service) hasResource(arn string) bool {
+	for _, p := range s.pipelines {
+		if p.arn == arn {
+			return true
+		}
+	}
+	for _, wh := range s.webhooks {
+		if wh.arn == arn {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Service) tagResource(w http.ResponseWriter, params map[string]interface{}) {
+	arn := h.GetString(params, "resourceArn")
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if !s.hasResource(arn) {
+		h.WriteJSONError(w, "ResourceNotFoundException", "Resource not found: "+arn, http.StatusBadRequest)
+		return
+	}
+	s.tags.Tag(arn, tags.FromList(params["tags"], "key", "value"))
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{})
+}
+
+func (s *Service) untagResource(w http.ResponseWriter, params map[string]interface{}) {
+	arn := h.GetString(params, "resourceArn")
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if !s.hasResource(arn) {
+		h.WriteJSONError(w, "ResourceNotFoundException", "Resource not found: "+arn, http.StatusBadRequest)
+		return
+	}
+	s.tags.Untag(arn, tags.Keys(params["tagKeys"]))
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{})
+}
+
+func (s *Service) listTagsForResource(w http.ResponseWriter, params map[string]interface{}) {
+	arn := h.GetString(params, "resourceArn")
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if !s.hasResource(arn) {
+		h.WriteJSONError(w, "ResourceNotFoundException", "Resource not found: "+arn, http.StatusBadRequest)
+		return
+	}
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"tags": tags.ToList(s.tags.Get(arn), "key", "value"),
+	})
+}
+
 func (s *Service) listPipelines(w http.ResponseWriter) {
 	s.mu.RLock()
 	var list []map[string]interface{}
@@ -212,6 +347,7 @@ func (s *Service) updatePipeline(w http.ResponseWriter, params map[string]interf
 		h.WriteJSONError(w, "InvalidParameterException", "pipeline name is required", http.StatusBadRequest)
 		return
 	}
+	revisions := s.sourceRevisions(pipelineObj["stages"])
 
 	s.mu.Lock()
 	p, exists := s.pipelines[name]
@@ -225,6 +361,7 @@ func (s *Service) updatePipeline(w http.ResponseWriter, params map[string]interf
 	p.stages = pipelineObj["stages"]
 	p.version++
 	p.updated = time.Now().UTC()
+	p.revisions = revisions
 	s.mu.Unlock()
 
 	h.WriteJSON(w, http.StatusOK, map[string]interface{}{
@@ -232,6 +369,271 @@ func (s *Service) updatePipeline(w http.ResponseWriter, params map[string]interf
 	})
 }
 
+func (s *Service) startPipelineExecution(w http.ResponseWriter, params map[string]interface{}) {
+	name := h.GetString(params, "name")
+
+	s.mu.Lock()
+	p, exists := s.pipelines[name]
+	if !exists {
+		s.mu.Unlock()
+		h.WriteJSONError(w, "PipelineNotFoundException", "Pipeline not found: "+name, http.StatusBadRequest)
+		return
+	}
+
+	ex := s.start(p, "StartPipelineExecution", "")
+	s.mu.Unlock()
+
+	s.advance(ex)
+
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"pipelineExecutionId": ex.id,
+	})
+}
+
+// start begins a new execution of p, superseding any that is still in
+// progress. The caller must hold s.mu, and advance the execution once it
+// is released.
+func (s *Service) start(p *pipeline, triggerType, triggerDetail string) *execution {
+	for _, old := range p.executions {
+		if old.status != "InProgress" {
+			continue
+		}
+		old.status = "Superseded"
+		old.updated = time.Now().UTC()
+		for _, st := range old.stages {
+			for _, a := range st.actions {
+				if a.status == "InProgress" {
+					a.status = "Abandoned"
+				}
+			}
+		}
+	}
+
+	ex := newExecution(p)
+	ex.triggerType = triggerType
+	ex.triggerDetail = triggerDetail
+	p.executions = append(p.executions, ex)
+	return ex
+}
+
+func (s *Service) getPipelineExecution(w http.ResponseWriter, params map[string]interface{}) {
+	name := h.GetString(params, "pipelineName")
+	id := h.GetString(params, "pipelineExecutionId")
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	p, exists := s.pipelines[name]
+	if !exists {
+		h.WriteJSONError(w, "PipelineNotFoundException", "Pipeline not found: "+name, http.StatusBadRequest)
+		return
+	}
+	ex := p.findExecution(id)
+	if ex == nil {
+		h.WriteJSONError(w, "PipelineExecutionNotFoundException", "Pipeline execution not found: "+id, http.StatusBadRequest)
+		return
+	}
+
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"pipelineExecution": map[string]interface{}{
+			"pipelineName":        p.name,
+			"pipelineVersion":     ex.version,
+			"pipelineExecutionId": ex.id,
+			"status":              ex.status,
+			"artifactRevisions":   []interface{}{},
+			"trigger":             triggerResp(ex),
+		},
+	})
+}
+
+func (s *Service) listPipelineExecutions(w http.ResponseWriter, params map[string]interface{}) {
+	name := h.GetString(params, "pipelineName")
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	p, exists := s.pipelines[name]
+	if !exists {
+		h.WriteJSONError(w, "PipelineNotFoundException", "Pipeline not found: "+name, http.StatusBadRequest)
+		return
+	}
+
+	list := make([]map[string]interface{}, 0, len(p.executions))
+	for i := len(p.executions) - 1; i >= 0; i-- {
+		list = append(list, executionSummaryResp(p.executions[i]))
+	}
+
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"pipelineExecutionSummaries": list,
+	})
+}
+
+func (s *Service) getPipelineState(w http.ResponseWriter, params map[string]interface{}) {
+	name := h.GetString(params, "name")
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	p, exists := s.pipelines[name]
+	if !exists {
+		h.WriteJSONError(w, "PipelineNotFoundException", "Pipeline not found: "+name, http.StatusBadRequest)
+		return
+	}
+
+	var stageStates []map[string]interface{}
+	for _, def := range parseStages(p.stages) {
+		state := map[string]interface{}{
+			"stageName":              def.name,
+			"inboundTransitionState": map[string]interface{}{"enabled": true},
+		}
+
+		ex, run := p.latestStageRun(def.name)
+		var actionStates []map[string]interface{}
+		if run != nil {
+			for _, a := range run.actions {
+				actionStates = append(actionStates, actionStateResp(a))
+			}
+			state["latestExecution"] = map[string]interface{}{
+				"pipelineExecutionId": ex.id,
+				"status":              run.status,
+			}
+		} else {
+			for _, a := range def.actions {
+				actionStates = append(actionStates, map[string]interface{}{"actionName": a.name})
+			}
+		}
+		state["actionStates"] = actionStates
+		stageStates = append(stageStates, state)
+	}
+
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"pipelineName":    p.name,
+		"pipelineVersion": p.version,
+		"created":         float64(p.created.Unix()),
+		"updated":         float64(p.updated.Unix()),
+		"stageStates":     stageStates,
+	})
+}
+
+func (s *Service) putApprovalResult(w http.ResponseWriter, params map[string]interface{}) {
+	name := h.GetString(params, "pipelineName")
+	stageName := h.GetString(params, "stageName")
+	actionName := h.GetString(params, "actionName")
+	token := h.GetString(params, "token")
+	result, _ := params["result"].(map[string]interface{})
+	status := h.GetString(result, "status")
+
+	if status != "Approved" && status != "Rejected" {
+		h.WriteJSONError(w, "ValidationException", "result status must be Approved or Rejected", http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	p, exists := s.pipelines[name]
+	if !exists {
+		s.mu.Unlock()
+		h.WriteJSONError(w, "PipelineNotFoundException", "Pipeline not found: "+name, http.StatusBadRequest)
+		return
+	}
+
+	ex, run := p.latestStageRun(stageName)
+	var action *actionRun
+	if run != nil {
+		for _, a := range run.actions {
+			if a.def.name == actionName {
+				action = a
+			}
+		}
+	}
+	if action == nil || action.def.category != "Approval" {
+		s.mu.Unlock()
+		h.WriteJSONError(w, "ActionNotFoundException", "Approval action not found: "+actionName, http.StatusBadRequest)
+		return
+	}
+	if action.status != "InProgress" {
+		s.mu.Unlock()
+		h.WriteJSONError(w, "ApprovalAlreadyCompletedException", "Approval for "+actionName+" has already been completed", http.StatusBadRequest)
+		return
+	}
+	if action.token != token {
+		s.mu.Unlock()
+		h.WriteJSONError(w, "InvalidApprovalTokenException", "The approval token is not valid", http.StatusBadRequest)
+		return
+	}
+
+	now := time.Now().UTC()
+	action.lastChange = now
+	action.summary = h.GetString(result, "summary")
+	if status == "Approved" {
+		action.status = "Succeeded"
+	} else {
+		action.status = "Failed"
+		action.errMsg = "Approval was rejected"
+	}
+	ex.updated = now
+	s.mu.Unlock()
+
+	s.advance(ex)
+
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"approvedAt": float64(now.Unix()),
+	})
+}
+
+func (s *Service) retryStageExecution(w http.ResponseWriter, params map[string]interface{}) {
+	name := h.GetString(params, "pipelineName")
+	stageName := h.GetString(params, "stageName")
+	id := h.GetString(params, "pipelineExecutionId")
+	retryMode := h.GetString(params, "retryMode")
+
+	s.mu.Lock()
+	p, exists := s.pipelines[name]
+	if !exists {
+		s.mu.Unlock()
+		h.WriteJSONError(w, "PipelineNotFoundException", "Pipeline not found: "+name, http.StatusBadRequest)
+		return
+	}
+	ex := p.findExecution(id)
+	if ex == nil {
+		s.mu.Unlock()
+		h.WriteJSONError(w, "PipelineExecutionNotFoundException", "Pipeline execution not found: "+id, http.StatusBadRequest)
+		return
+	}
+	if latest, _ := p.latestStageRun(stageName); latest != ex {
+		s.mu.Unlock()
+		h.WriteJSONError(w, "NotLatestPipelineExecutionException", "Execution "+id+" is not the latest execution of stage "+stageName, http.StatusBadRequest)
+		return
+	}
+
+	var run *stageRun
+	for _, st := range ex.stages {
+		if st.name == stageName {
+			run = st
+		}
+	}
+	if ex.status != "Failed" || run.status != "Failed" {
+		s.mu.Unlock()
+		h.WriteJSONError(w, "StageNotRetryableException", "Stage "+stageName+" has not failed", http.StatusBadRequest)
+		return
+	}
+
+	for _, a := range run.actions {
+		if retryMode == "ALL_ACTIONS" || a.status == "Failed" {
+			*a = actionRun{def: a.def}
+		}
+	}
+	run.status = "InProgress"
+	ex.status = "InProgress"
+	ex.updated = time.Now().UTC()
+	s.mu.Unlock()
+
+	s.advance(ex)
+
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"pipelineExecutionId": ex.id,
+	})
+}
+
 func pipelineResp(p *pipeline) map[string]interface{} {
 	return map[string]interface{}{
 		"name":    p.name,
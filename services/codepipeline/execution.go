@@ -0,0 +1,398 @@
+package codepipeline
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	h "github.com/riyanimam/goto/internal/mockhelpers"
+)
+
+// Action describes a pipeline action that is about to run.
+type Action struct {
+	PipelineName  string
+	ExecutionID   string
+	StageName     string
+	ActionName    string
+	Category      string
+	Owner         string
+	Provider      string
+	Configuration map[string]string
+}
+
+// ActionResult is the outcome of a successfully simulated action.
+type ActionResult struct {
+	// ExternalExecutionID identifies the work in the provider, such as a
+	// commit ID or a CodeBuild build ID.
+	ExternalExecutionID string
+	Summary             string
+}
+
+// ActionSimulator runs a pipeline action. Returning an error fails the
+// action, and with it the stage and the execution.
+type ActionSimulator func(a Action) (ActionResult, error)
+
+type actionDef struct {
+	name          string
+	category      string
+	owner         string
+	provider      string
+	runOrder      int
+	configuration map[string]string
+}
+
+type stageDef struct {
+	name    string
+	actions []actionDef
+}
+
+type execution struct {
+	id       string
+	pipeline string
+	version  int
+	status   string // InProgress, Succeeded, Failed, Superseded
+	started  time.Time
+	updated  time.Time
+	stages   []*stageRun
+
+	// triggerType is what started the execution: StartPipelineExecution,
+	// PollForSourceChanges, CloudWatchEvent, or Webhook.
+	triggerType   string
+	triggerDetail string
+}
+
+type stageRun struct {
+	name    string
+	status  string // "" until reached, then InProgress, Succeeded, Failed
+	actions []*actionRun
+}
+
+type actionRun struct {
+	def        actionDef
+	status     string // "" until run, then InProgress, Succeeded, Failed, Abandoned
+	summary    string
+	externalID string
+	errMsg     string
+	token      string
+	lastChange time.Time
+}
+
+// RegisterActionSimulator makes sim run every action whose provider is
+// provider (e.g. "CodeBuild", "CodeDeploy", "ECS"), replacing the default
+// behavior for that provider. Manual approval actions cannot be simulated;
+// they wait for PutApprovalResult.
+func (s *Service) RegisterActionSimulator(provider string, sim ActionSimulator) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.simulators[provider] = sim
+}
+
+// SetDispatcher sets the function used to start builds on the CodeBuild mock.
+func (s *Service) SetDispatcher(d h.Dispatcher) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.dispatch = d
+}
+
+// parseStages converts the stored stage declarations into definitions.
+func parseStages(raw interface{}) []stageDef {
+	list, _ := raw.([]interface{})
+	stages := make([]stageDef, 0, len(list))
+	for _, item := range list {
+		sm, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		st := stageDef{name: h.GetString(sm, "name")}
+		actions, _ := sm["actions"].([]interface{})
+		for _, a := range actions {
+			am, ok := a.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			def := actionDef{
+				name:          h.GetString(am, "name"),
+				runOrder:      h.GetInt(am, "runOrder", 1),
+				configuration: make(map[string]string),
+			}
+			if t, ok := am["actionTypeId"].(map[string]interface{}); ok {
+				def.category = h.GetString(t, "category")
+				def.owner = h.GetString(t, "owner")
+				def.provider = h.GetString(t, "provider")
+			}
+			if c, ok := am["configuration"].(map[string]interface{}); ok {
+				for k, v := range c {
+					if sv, ok := v.(string); ok {
+						def.configuration[k] = sv
+					}
+				}
+			}
+			st.actions = append(st.actions, def)
+		}
+		sort.SliceStable(st.actions, func(i, j int) bool {
+			return st.actions[i].runOrder < st.actions[j].runOrder
+		})
+		stages = append(stages, st)
+	}
+	return stages
+}
+
+func newExecution(p *pipeline) *execution {
+	now := time.Now().UTC()
+	ex := &execution{
+		id:       h.NewRequestID(),
+		pipeline: p.name,
+		version:  p.version,
+		status:   "InProgress",
+		started:  now,
+		updated:  now,
+	}
+	for _, st := range parseStages(p.stages) {
+		run := &stageRun{name: st.name}
+		for _, a := range st.actions {
+			run.actions = append(run.actions, &actionRun{def: a})
+		}
+		ex.stages = append(ex.stages, run)
+	}
+	return ex
+}
+
+// step is one unit of work found by nextStep: either an action to run, or
+// nothing (the execution is finished or waiting on an approval).
+type step struct {
+	stage  *stageRun
+	action *actionRun
+}
+
+// nextStep advances stage and execution statuses as far as possible without
+// running anything and returns the next action to run. Caller must hold s.mu.
+func (ex *execution) nextStep() (step, bool) {
+	if ex.status != "InProgress" {
+		return step{}, false
+	}
+	now := time.Now().UTC()
+
+	for _, st := range ex.stages {
+		if st.status == "Succeeded" {
+			continue
+		}
+		if st.status == "Failed" {
+			ex.status = "Failed"
+			ex.updated = now
+			return step{}, false
+		}
+		st.status = "InProgress"
+
+		// Actions run in runOrder groups; a group must finish before the
+		// next one starts, and a failure stops the stage after its group.
+		for i := 0; i < len(st.actions); {
+			order := st.actions[i].def.runOrder
+			j := i
+			waiting, failed := false, false
+			for ; j < len(st.actions) && st.actions[j].def.runOrder == order; j++ {
+				a := st.actions[j]
+				switch a.status {
+				case "":
+					return step{stage: st, action: a}, true
+				case "InProgress":
+					waiting = true
+				case "Failed":
+					failed = true
+				}
+			}
+			if waiting {
+				return step{}, false
+			}
+			if failed {
+				st.status = "Failed"
+				ex.status = "Failed"
+				ex.updated = now
+				return step{}, false
+			}
+			i = j
+		}
+		st.status = "Succeeded"
+	}
+
+	ex.status = "Succeeded"
+	ex.updated = now
+	return step{}, false
+}
+
+// advance runs the execution's actions until it finishes, fails, or waits on
+// an approval. Simulators run without s.mu held.
+func (s *Service) advance(ex *execution) {
+	for {
+		s.mu.Lock()
+		next, ok := ex.nextStep()
+		if !ok {
+			s.mu.Unlock()
+			return
+		}
+		a := next.action
+		a.status = "InProgress"
+		a.lastChange = time.Now().UTC()
+		if a.def.category == "Approval" {
+			a.token = h.NewRequestID()
+			s.mu.Unlock()
+			return
+		}
+		sim := s.simulators[a.def.provider]
+		if sim == nil {
+			sim = s.defaultSimulator(a.def)
+		}
+		action := Action{
+			PipelineName:  ex.pipeline,
+			ExecutionID:   ex.id,
+			StageName:     next.stage.name,
+			ActionName:    a.def.name,
+			Category:      a.def.category,
+			Owner:         a.def.owner,
+			Provider:      a.def.provider,
+			Configuration: a.def.configuration,
+		}
+		s.mu.Unlock()
+
+		result, err := sim(action)
+
+		s.mu.Lock()
+		if ex.status == "InProgress" && a.status == "InProgress" {
+			a.lastChange = time.Now().UTC()
+			a.externalID = result.ExternalExecutionID
+			a.summary = result.Summary
+			if err != nil {
+				a.status = "Failed"
+				a.errMsg = err.Error()
+			} else {
+				a.status = "Succeeded"
+			}
+			ex.updated = a.lastChange
+		}
+		s.mu.Unlock()
+	}
+}
+
+// defaultSimulator returns the built-in behavior for an action. Caller must
+// hold s.mu.
+func (s *Service) defaultSimulator(def actionDef) ActionSimulator {
+	if def.provider == "CodeBuild" {
+		dispatch := s.dispatch
+		return func(a Action) (ActionResult, error) {
+			return runCodeBuild(dispatch, a)
+		}
+	}
+	if def.category == "Source" {
+		src := s.sources()
+		return func(Action) (ActionResult, error) {
+			// Watched sources report the revision they were found at.
+			if rev := src.revision(def); rev != "" {
+				return ActionResult{ExternalExecutionID: rev, Summary: "Source revision"}, nil
+			}
+			return ActionResult{ExternalExecutionID: h.RandomHex(40), Summary: "Source revision"}, nil
+		}
+	}
+	return func(Action) (ActionResult, error) {
+		return ActionResult{}, nil
+	}
+}
+
+// runCodeBuild starts a build of the action's ProjectName on the CodeBuild
+// mock and reports the build ID and outcome.
+func runCodeBuild(dispatch h.Dispatcher, a Action) (ActionResult, error) {
+	project := a.Configuration["ProjectName"]
+	if dispatch == nil {
+		return ActionResult{}, fmt.Errorf("CodeBuild project %s is not reachable", project)
+	}
+	arn := fmt.Sprintf("arn:aws:codebuild:us-east-1:%s:project/%s", h.DefaultAccountID, project)
+	payload, _ := json.Marshal(map[string]string{
+		"initiator": "codepipeline/" + a.PipelineName,
+	})
+	out, err := dispatch(arn, payload)
+	if err != nil {
+		return ActionResult{}, err
+	}
+
+	var b struct {
+		ID          string `json:"id"`
+		BuildStatus string `json:"buildStatus"`
+	}
+	json.Unmarshal(out, &b)
+	if b.BuildStatus != "SUCCEEDED" {
+		return ActionResult{ExternalExecutionID: b.ID}, fmt.Errorf("build %s finished with status %s", b.ID, b.BuildStatus)
+	}
+	return ActionResult{ExternalExecutionID: b.ID, Summary: "Build succeeded"}, nil
+}
+
+// findExecution returns the pipeline's execution with the given ID. Caller
+// must hold s.mu.
+func (p *pipeline) findExecution(id string) *execution {
+	for _, ex := range p.executions {
+		if ex.id == id {
+			return ex
+		}
+	}
+	return nil
+}
+
+// latestStageRun returns the most recent execution's run of the named stage
+// that has been reached. Caller must hold s.mu.
+func (p *pipeline) latestStageRun(name string) (*execution, *stageRun) {
+	for i := len(p.executions) - 1; i >= 0; i-- {
+		ex := p.executions[i]
+		for _, st := range ex.stages {
+			if st.name == name && st.status != "" {
+				return ex, st
+			}
+		}
+	}
+	return nil, nil
+}
+
+func actionStateResp(a *actionRun) map[string]interface{} {
+	state := map[string]interface{}{
+		"actionName": a.def.name,
+	}
+	if a.status == "" {
+		return state
+	}
+	latest := map[string]interface{}{
+		"status":           a.status,
+		"lastStatusChange": float64(a.lastChange.Unix()),
+	}
+	if a.summary != "" {
+		latest["summary"] = a.summary
+	}
+	if a.externalID != "" {
+		latest["externalExecutionId"] = a.externalID
+	}
+	if a.token != "" && a.status == "InProgress" {
+		latest["token"] = a.token
+	}
+	if a.errMsg != "" {
+		latest["errorDetails"] = map[string]interface{}{
+			"code":    "JobFailed",
+			"message": a.errMsg,
+		}
+	}
+	state["latestExecution"] = latest
+	return state
+}
+
+func executionSummaryResp(ex *execution) map[string]interface{} {
+	return map[string]interface{}{
+		"pipelineExecutionId": ex.id,
+		"status":              ex.status,
+		"startTime":           float64(ex.started.Unix()),
+		"lastUpdateTime":      float64(ex.updated.Unix()),
+		"trigger":             triggerResp(ex),
+	}
+}
+
+func triggerResp(ex *execution) map[string]interface{} {
+	trigger := map[string]interface{}{"triggerType": ex.triggerType}
+	if ex.triggerDetail != "" {
+		trigger["triggerDetail"] = ex.triggerDetail
+	}
+	return trigger
+}
@@ -0,0 +1,163 @@
+package codepipeline
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"sort"
+
+	h "github.com/riyanimam/goto/internal/mockhelpers"
+)
+
+// sourceReader looks up the current revisions of watched source actions in
+// the S3 and ECR mocks.
+type sourceReader struct {
+	objects   h.ObjectStore
+	resources h.ResourceLister
+}
+
+// sources returns the reader for source revisions. The caller must hold
+// s.mu.
+func (s *Service) sources() sourceReader {
+	return sourceReader{objects: s.objects, resources: s.resources}
+}
+
+// watched reports whether changes to the action's source start executions
+// of its pipeline.
+func watched(def actionDef) bool {
+	return def.category == "Source" && (def.provider == "S3" || def.provider == "ECR")
+}
+
+// triggerType is how a change to the action's source is detected: S3
+// sources are polled unless PollForSourceChanges is false, and the rest
+// are reported by events.
+func triggerType(def actionDef) string {
+	if def.provider == "S3" && def.configuration["PollForSourceChanges"] != "false" {
+		return "PollForSourceChanges"
+	}
+	return "CloudWatchEvent"
+}
+
+// revision returns the current revision of a watched source: the MD5 of an
+// S3 source's object, or the digest of the image an ECR source's tag
+// points at. It is empty if the object or image does not exist.
+func (src sourceReader) revision(def actionDef) string {
+	switch def.provider {
+	case "S3":
+		if src.objects == nil {
+			return ""
+		}
+		data, err := src.objects.GetObject(def.configuration["S3Bucket"], def.configuration["S3ObjectKey"])
+		if err != nil {
+			return ""
+		}
+		sum := md5.Sum(data)
+		return hex.EncodeToString(sum[:])
+	case "ECR":
+		if src.resources == nil {
+			return ""
+		}
+		tag := def.configuration["ImageTag"]
+		if tag == "" {
+			tag = "latest"
+		}
+		// Images are listed oldest first, so the last one carrying the tag
+		// is the one it points at.
+		var digest string
+		for _, r := range src.resources("ecr") {
+			if r.Type != "aws_ecr_image" || r.Attributes["repository_name"] != def.configuration["RepositoryName"] {
+				continue
+			}
+			imageTags, _ := r.Attributes["image_tags"].([]string)
+			for _, t := range imageTags {
+				if t == tag {
+					digest, _ = r.Attributes["image_digest"].(string)
+				}
+			}
+		}
+		return digest
+	}
+	return ""
+}
+
+// sourceRevisions returns the current revisions of the watched source
+// actions among stages, by action name. s.mu must not be held.
+func (s *Service) sourceRevisions(stages interface{}) map[string]string {
+	s.mu.RLock()
+	src := s.sources()
+	s.mu.RUnlock()
+
+	revisions := make(map[string]string)
+	for _, st := range parseStages(stages) {
+		for _, def := range st.actions {
+			if watched(def) {
+				revisions[def.name] = src.revision(def)
+			}
+		}
+	}
+	return revisions
+}
+
+// pollSources starts an execution of every pipeline with a watched source
+// whose revision changed since it was last seen. A source that disappears
+// starts nothing, but its reappearance does.
+func (s *Service) pollSources() {
+	s.mu.RLock()
+	src := s.sources()
+	watches := make(map[string][]actionDef)
+	var names []string
+	for name, p := range s.pipelines {
+		for _, st := range parseStages(p.stages) {
+			for _, def := range st.actions {
+				if watched(def) {
+					watches[name] = append(watches[name], def)
+				}
+			}
+		}
+		if len(watches[name]) > 0 {
+			names = append(names, name)
+		}
+	}
+	s.mu.RUnlock()
+	sort.Strings(names)
+
+	// Revisions are read without s.mu held, as they come from other
+	// services.
+	found := make(map[string]map[string]string, len(names))
+	for _, name := range names {
+		found[name] = make(map[string]string)
+		for _, def := range watches[name] {
+			found[name][def.name] = src.revision(def)
+		}
+	}
+
+	s.mu.Lock()
+	var started []*execution
+	for _, name := range names {
+		p, ok := s.pipelines[name]
+		if !ok {
+			continue
+		}
+		if p.revisions == nil {
+			p.revisions = make(map[string]string)
+		}
+		var trigger, detail string
+		for _, def := range watches[name] {
+			rev := found[name][def.name]
+			if rev == p.revisions[def.name] {
+				continue
+			}
+			p.revisions[def.name] = rev
+			if rev != "" && trigger == "" {
+				trigger, detail = triggerType(def), def.name
+			}
+		}
+		if trigger != "" {
+			started = append(started, s.start(p, trigger, detail))
+		}
+	}
+	s.mu.Unlock()
+
+	for _, ex := range started {
+		s.advance(ex)
+	}
+}
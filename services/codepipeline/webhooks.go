@@ -0,0 +1,408 @@
+package codepipeline
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	h "github.com/riyanimam/goto/internal/mockhelpers"
+	"github.com/riyanimam/goto/internal/paginate"
+	"github.com/riyanimam/goto/internal/tags"
+)
+
+// webhookPath is where the mock serves webhook URLs.
+const webhookPath = "/_codepipeline/webhooks/"
+
+// maxWebhookFilters is the most filters a webhook can have.
+const maxWebhookFilters = 5
+
+type webhook struct {
+	name           string
+	arn            string
+	token          string
+	pipeline       string
+	action         string
+	filters        []webhookFilter
+	authentication string // GITHUB_HMAC, IP, or UNAUTHENTICATED
+	secretToken    string
+	allowedIPRange *net.IPNet
+	definition     map[string]interface{}
+	registered     bool
+	lastTriggered  time.Time
+}
+
+type webhookFilter struct {
+	jsonPath    string
+	matchEquals string
+}
+
+func (s *Service) putWebhook(w http.ResponseWriter, params map[string]interface{}) {
+	def, _ := params["webhook"].(map[string]interface{})
+	if def == nil {
+		h.WriteJSONError(w, "ValidationException", "webhook is required", http.StatusBadRequest)
+		return
+	}
+	wh := &webhook{
+		name:           h.GetString(def, "name"),
+		pipeline:       h.GetString(def, "targetPipeline"),
+		action:         h.GetString(def, "targetAction"),
+		authentication: h.GetString(def, "authentication"),
+		definition:     def,
+	}
+	if wh.name == "" || wh.pipeline == "" || wh.action == "" {
+		h.WriteJSONError(w, "ValidationException", "webhook name, targetPipeline, and targetAction are required", http.StatusBadRequest)
+		return
+	}
+
+	filters, _ := def["filters"].([]interface{})
+	if len(filters) == 0 || len(filters) > maxWebhookFilters {
+		h.WriteJSONError(w, "ValidationException", fmt.Sprintf("A webhook must have between 1 and %d filters", maxWebhookFilters), http.StatusBadRequest)
+		return
+	}
+	for _, item := range filters {
+		fm, _ := item.(map[string]interface{})
+		f := webhookFilter{jsonPath: h.GetString(fm, "jsonPath"), matchEquals: h.GetString(fm, "matchEquals")}
+		if _, err := parseJSONPath(f.jsonPath); err != nil {
+			h.WriteJSONError(w, "InvalidWebhookFilterPatternException", fmt.Sprintf("Invalid JsonPath %q: %v", f.jsonPath, err), http.StatusBadRequest)
+			return
+		}
+		wh.filters = append(wh.filters, f)
+	}
+
+	auth, _ := def["authenticationConfiguration"].(map[string]interface{})
+	switch wh.authentication {
+	case "GITHUB_HMAC":
+		wh.secretToken = h.GetString(auth, "SecretToken")
+		if wh.secretToken == "" {
+			h.WriteJSONError(w, "InvalidWebhookAuthenticationParametersException", "SecretToken is required for GITHUB_HMAC authentication", http.StatusBadRequest)
+			return
+		}
+	case "IP":
+		_, ipRange, err := net.ParseCIDR(h.GetString(auth, "AllowedIPRange"))
+		if err != nil {
+			h.WriteJSONError(w, "InvalidWebhookAuthenticationParametersException", "AllowedIPRange must be a CIDR block for IP authentication", http.StatusBadRequest)
+			return
+		}
+		wh.allowedIPRange = ipRange
+	case "UNAUTHENTICATED":
+	default:
+		h.WriteJSONError(w, "ValidationException", "authentication must be GITHUB_HMAC, IP, or UNAUTHENTICATED", http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.pipelines[wh.pipeline]; !ok {
+		h.WriteJSONError(w, "PipelineNotFoundException", "Pipeline not found: "+wh.pipeline, http.StatusBadRequest)
+		return
+	}
+	// Putting an existing webhook replaces its definition but keeps its URL.
+	if old, ok := s.webhooks[wh.name]; ok {
+		wh.arn, wh.token = old.arn, old.token
+		wh.registered, wh.lastTriggered = old.registered, old.lastTriggered
+	} else {
+		wh.arn = fmt.Sprintf("arn:aws:codepipeline:us-east-1:%s:webhook:%s", h.DefaultAccountID, wh.name)
+		wh.token = h.RandomHex(32)
+	}
+	s.webhooks[wh.name] = wh
+	s.tags.Tag(wh.arn, tags.FromList(params["tags"], "key", "value"))
+
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"webhook": s.webhookResp(wh),
+	})
+}
+
+func (s *Service) deleteWebhook(w http.ResponseWriter, params map[string]interface{}) {
+	name := h.GetString(params, "name")
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if wh, ok := s.webhooks[name]; ok {
+		delete(s.webhooks, name)
+		s.tags.Delete(wh.arn)
+	}
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{})
+}
+
+func (s *Service) listWebhooks(w http.ResponseWriter, params map[string]interface{}) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	names := make([]string, 0, len(s.webhooks))
+	for name := range s.webhooks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	page, next, err := paginate.Page(names, func(i int) string { return names[i] }, h.GetString(params, "NextToken"), h.GetInt(params, "MaxResults", 0), 100)
+	if err != nil {
+		h.WriteJSONError(w, "InvalidNextTokenException", "Invalid NextToken", http.StatusBadRequest)
+		return
+	}
+
+	list := make([]map[string]interface{}, 0, len(page))
+	for _, name := range page {
+		list = append(list, s.webhookResp(s.webhooks[name]))
+	}
+	resp := map[string]interface{}{"webhooks": list}
+	if next != "" {
+		resp["NextToken"] = next
+	}
+	h.WriteJSON(w, http.StatusOK, resp)
+}
+
+// registerWebhook records whether the webhook is registered with its third
+// party. The mock has no third party to call; the webhook's URL accepts
+// requests either way.
+func (s *Service) registerWebhook(w http.ResponseWriter, params map[string]interface{}, registered bool) {
+	name := h.GetString(params, "webhookName")
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	wh, ok := s.webhooks[name]
+	if !ok {
+		h.WriteJSONError(w, "WebhookNotFoundException", "Webhook not found: "+name, http.StatusBadRequest)
+		return
+	}
+	wh.registered = registered
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{})
+}
+
+// webhookResp describes a webhook as ListWebhooks and PutWebhook do. The
+// caller must hold s.mu.
+func (s *Service) webhookResp(wh *webhook) map[string]interface{} {
+	resp := map[string]interface{}{
+		"definition": wh.definition,
+		"url":        s.baseURL + webhookPath + wh.token,
+		"arn":        wh.arn,
+	}
+	if !wh.lastTriggered.IsZero() {
+		resp["lastTriggered"] = float64(wh.lastTriggered.Unix())
+	}
+	if t := s.tags.Get(wh.arn); len(t) > 0 {
+		resp["tags"] = tags.ToList(t, "key", "value")
+	}
+	return resp
+}
+
+// serveWebhook handles a request to a webhook's URL: if it authenticates
+// and its JSON payload passes every filter, the webhook's pipeline starts.
+// Payloads that do not pass are ignored, as they are by AWS.
+func (s *Service) serveWebhook(w http.ResponseWriter, r *http.Request) {
+	token := strings.TrimPrefix(r.URL.Path, webhookPath)
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		h.WriteJSONError(w, "ValidationException", "could not read request body", http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	var wh *webhook
+	for _, candidate := range s.webhooks {
+		if candidate.token == token {
+			wh = candidate
+		}
+	}
+	if wh == nil {
+		s.mu.Unlock()
+		h.WriteJSONError(w, "WebhookNotFoundException", "Webhook not found", http.StatusNotFound)
+		return
+	}
+	if err := wh.authenticate(r, body); err != nil {
+		s.mu.Unlock()
+		h.WriteJSONError(w, "AccessDeniedException", err.Error(), http.StatusUnauthorized)
+		return
+	}
+	var payload interface{}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		s.mu.Unlock()
+		h.WriteJSONError(w, "ValidationException", "webhook payload is not valid JSON", http.StatusBadRequest)
+		return
+	}
+
+	p, ok := s.pipelines[wh.pipeline]
+	if !ok || !wh.matches(p, payload) {
+		s.mu.Unlock()
+		h.WriteJSON(w, http.StatusOK, map[string]interface{}{})
+		return
+	}
+	wh.lastTriggered = time.Now().UTC()
+	ex := s.start(p, "Webhook", wh.arn)
+	s.mu.Unlock()
+
+	s.advance(ex)
+
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"pipelineExecutionId": ex.id,
+	})
+}
+
+// authenticate checks a request against the webhook's authentication:
+// GITHUB_HMAC requests carry the HMAC of their body under the secret
+// token in X-Hub-Signature-256 or X-Hub-Signature, and IP requests must
+// come from the allowed range.
+func (wh *webhook) authenticate(r *http.Request, body []byte) error {
+	switch wh.authentication {
+	case "GITHUB_HMAC":
+		var newHash func() hash.Hash
+		sig := r.Header.Get("X-Hub-Signature-256")
+		if strings.HasPrefix(sig, "sha256=") {
+			newHash, sig = sha256.New, strings.TrimPrefix(sig, "sha256=")
+		} else if sig = r.Header.Get("X-Hub-Signature"); strings.HasPrefix(sig, "sha1=") {
+			newHash, sig = sha1.New, strings.TrimPrefix(sig, "sha1=")
+		} else {
+			return fmt.Errorf("the request is not signed")
+		}
+		mac := hmac.New(newHash, []byte(wh.secretToken))
+		mac.Write(body)
+		want := hex.EncodeToString(mac.Sum(nil))
+		if !hmac.Equal([]byte(sig), []byte(want)) {
+			return fmt.Errorf("the request signature does not match")
+		}
+	case "IP":
+		host, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			host = r.RemoteAddr
+		}
+		if ip := net.ParseIP(host); ip == nil || !wh.allowedIPRange.Contains(ip) {
+			return fmt.Errorf("the request does not come from the allowed IP range")
+		}
+	}
+	return nil
+}
+
+// matches reports whether payload passes every filter of the webhook.
+// A filter passes if its JSONPath selects a value and, when MatchEquals is
+// set, the value equals it once {Key} placeholders are replaced by the
+// target action's configuration. The caller must hold s.mu.
+func (wh *webhook) matches(p *pipeline, payload interface{}) bool {
+	config := map[string]string{}
+	for _, st := range parseStages(p.stages) {
+		for _, def := range st.actions {
+			if def.name == wh.action {
+				config = def.configuration
+			}
+		}
+	}
+
+	for _, f := range wh.filters {
+		path, err := parseJSONPath(f.jsonPath)
+		if err != nil {
+			return false
+		}
+		v, ok := path.lookup(payload)
+		if !ok {
+			return false
+		}
+		if f.matchEquals == "" {
+			continue
+		}
+		want := f.matchEquals
+		for k, cv := range config {
+			want = strings.ReplaceAll(want, "{"+k+"}", cv)
+		}
+		if jsonString(v) != want {
+			return false
+		}
+	}
+	return true
+}
+
+// jsonString renders a decoded JSON value for comparison with MatchEquals.
+func jsonString(v interface{}) string {
+	switch tv := v.(type) {
+	case string:
+		return tv
+	case nil:
+		return "null"
+	case float64:
+		return strconv.FormatFloat(tv, 'f', -1, 64)
+	}
+	b, _ := json.Marshal(v)
+	return string(b)
+}
+
+// jsonPath is a parsed JSONPath made of member names and array indexes,
+// such as $.commits[0].author.name or $['ref'].
+type jsonPath []interface{} // string member names and int indexes
+
+func parseJSONPath(expr string) (jsonPath, error) {
+	if !strings.HasPrefix(expr, "$") {
+		return nil, fmt.Errorf("must start with $")
+	}
+	var path jsonPath
+	rest := expr[1:]
+	for rest != "" {
+		switch {
+		case strings.HasPrefix(rest, "['"):
+			end := strings.Index(rest, "']")
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated member name")
+			}
+			path = append(path, rest[2:end])
+			rest = rest[end+2:]
+		case rest[0] == '[':
+			end := strings.IndexByte(rest, ']')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated index")
+			}
+			n, err := strconv.Atoi(rest[1:end])
+			if err != nil || n < 0 {
+				return nil, fmt.Errorf("invalid index %q", rest[1:end])
+			}
+			path = append(path, n)
+			rest = rest[end+1:]
+		case rest[0] == '.':
+			end := strings.IndexAny(rest[1:], ".[")
+			if end < 0 {
+				end = len(rest) - 1
+			}
+			name := rest[1 : end+1]
+			if name == "" {
+				return nil, fmt.Errorf("empty member name")
+			}
+			path = append(path, name)
+			rest = rest[end+1:]
+		default:
+			return nil, fmt.Errorf("unexpected %q", rest)
+		}
+	}
+	return path, nil
+}
+
+// lookup returns the value the path selects in doc.
+func (path jsonPath) lookup(doc interface{}) (interface{}, bool) {
+	v := doc
+	for _, step := range path {
+		switch s := step.(type) {
+		case string:
+			m, ok := v.(map[string]interface{})
+			if !ok {
+				return nil, false
+			}
+			if v, ok = m[s]; !ok {
+				return nil, false
+			}
+		case int:
+			list, ok := v.([]interface{})
+			if !ok || s >= len(list) {
+				return nil, false
+			}
+			v = list[s]
+		}
+	}
+	return v, true
+}
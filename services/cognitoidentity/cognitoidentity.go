@@ -6,12 +6,13 @@
 //   - DeleteIdentityPool
 //   - ListIdentityPools
 //   - UpdateIdentityPool
+//   - TagResource
+//   - UntagResource
+//   - ListTagsForResource
 package cognitoidentity
 
 import (
-	"encoding/json"
 	"fmt"
-	"io"
 	"net/http"
 	"sort"
 	"strings"
@@ -19,12 +20,14 @@ import (
 	"time"
 
 	h "github.com/riyanimam/goto/internal/mockhelpers"
+	"github.com/riyanimam/goto/internal/tags"
 )
 
 // Service implements the Cognito Identity mock.
 type Service struct {
 	mu    sync.RWMutex
 	pools map[string]*identityPool
+	tags  *tags.Store
 }
 
 type identityPool struct {
@@ -38,6 +41,7 @@ type identityPool struct {
 func New() *Service {
 	return &Service{
 		pools: make(map[string]*identityPool),
+		tags:  tags.New(),
 	}
 }
 
@@ -46,7 +50,16 @@ func (s *Service) Name() string { return "cognito-identity" }
 
 // Handler returns the HTTP handler for Cognito Identity requests.
 func (s *Service) Handler() http.Handler {
-	return http.HandlerFunc(s.handle)
+	return h.JSONRouter{
+		"CreateIdentityPool":   s.createIdentityPool,
+		"DescribeIdentityPool": s.describeIdentityPool,
+		"DeleteIdentityPool":   s.deleteIdentityPool,
+		"ListIdentityPools":    s.listIdentityPools,
+		"UpdateIdentityPool":   s.updateIdentityPool,
+		"TagResource":          s.tagResource,
+		"UntagResource":        s.untagResource,
+		"ListTagsForResource":  s.listTagsForResource,
+	}
 }
 
 // Reset clears all state.
@@ -54,47 +67,14 @@ func (s *Service) Reset() {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	s.pools = make(map[string]*identityPool)
+	s.tags.DeleteService("cognito-identity")
 }
 
-func (s *Service) handle(w http.ResponseWriter, r *http.Request) {
-	target := r.Header.Get("X-Amz-Target")
-
-	bodyBytes, err := io.ReadAll(r.Body)
-	if err != nil {
-		h.WriteJSONError(w, "InternalFailure", "could not read request body", http.StatusInternalServerError)
-		return
-	}
-
-	var params map[string]interface{}
-	if len(bodyBytes) > 0 {
-		json.Unmarshal(bodyBytes, &params)
-	}
-	if params == nil {
-		params = make(map[string]interface{})
-	}
-
-	action := ""
-	if target != "" {
-		parts := strings.SplitN(target, ".", 2)
-		if len(parts) == 2 {
-			action = parts[1]
-		}
-	}
-
-	switch action {
-	case "CreateIdentityPool":
-		s.createIdentityPool(w, params)
-	case "DescribeIdentityPool":
-		s.describeIdentityPool(w, params)
-	case "DeleteIdentityPool":
-		s.deleteIdentityPool(w, params)
-	case "ListIdentityPools":
-		s.listIdentityPools(w, params)
-	case "UpdateIdentityPool":
-		s.updateIdentityPool(w, params)
-	default:
-		h.WriteJSONError(w, "UnknownOperationException", fmt.Sprintf("action %q is not supported", action), http.StatusBadRequest)
-	}
+// SetTagStore sets the registry resource tags are recorded in.
+func (s *Service) SetTagStore(store *tags.Store) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tags = store
 }
 
 func (s *Service) createIdentityPool(w http.ResponseWriter, params map[string]interface{}) {
@@ -115,9 +95,10 @@ func (s *Service) createIdentityPool(w http.ResponseWriter, params map[string]in
 		created:              time.Now().UTC(),
 	}
 	s.pools[id] = pool
+	s.tags.Tag(poolARN(id), tags.FromMap(params["IdentityPoolTags"]))
 	s.mu.Unlock()
 
-	h.WriteJSON(w, http.StatusOK, poolResp(pool))
+	h.WriteJSON(w, http.StatusOK, s.poolResp(pool))
 }
 
 func (s *Service) describeIdentityPool(w http.ResponseWriter, params map[string]interface{}) {
@@ -132,7 +113,7 @@ func (s *Service) describeIdentityPool(w http.ResponseWriter, params map[string]
 		return
 	}
 
-	h.WriteJSON(w, http.StatusOK, poolResp(pool))
+	h.WriteJSON(w, http.StatusOK, s.poolResp(pool))
 }
 
 func (s *Service) deleteIdentityPool(w http.ResponseWriter, params map[string]interface{}) {
@@ -145,6 +126,7 @@ func (s *Service) deleteIdentityPool(w http.ResponseWriter, params map[string]in
 		return
 	}
 	delete(s.pools, id)
+	s.tags.Delete(poolARN(id))
 	s.mu.Unlock()
 
 	h.WriteJSON(w, http.StatusOK, map[string]interface{}{})
@@ -176,6 +158,61 @@ func (s *Service) listIdentityPools(w http.ResponseWriter, params map[string]int
 	})
 }
 
+// poolARN returns the ARN of the identity pool with the given ID.
+func poolARN(id string) string {
+	return fmt.Sprintf("arn:aws:cognito-identity:us-east-1:%s:identitypool/%s", h.DefaultAccountID, id)
+}
+
+// hasPool reports whether arn names an existing identity pool. The caller
+// must hold s.mu.
+func (s *Service) hasPool(arn string) bool {
+	_, id, _ := strings.Cut(arn, ":identitypool/")
+	return s.pools[id] != nil && poolARN(id) == arn
+}
+
+func (s *Service) tagResource(w http.ResponseWriter, params map[string]interface{}) {
+	arn := h.GetString(params, "ResourceArn")
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if !s.hasPool(arn) {
+		h.WriteJSONError(w, "ResourceNotFoundException", "Resource not found: "+arn, http.StatusBadRequest)
+		return
+	}
+	s.tags.Tag(arn, tags.FromMap(params["Tags"]))
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{})
+}
+
+func (s *Service) untagResource(w http.ResponseWriter, params map[string]interface{}) {
+	arn := h.GetString(params, "ResourceArn")
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if !s.hasPool(arn) {
+		h.WriteJSONError(w, "ResourceNotFoundException", "Resource not found: "+arn, http.StatusBadRequest)
+		return
+	}
+	s.tags.Untag(arn, tags.Keys(params["TagKeys"]))
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{})
+}
+
+func (s *Service) listTagsForResource(w http.ResponseWriter, params map[string]interface{}) {
+	arn := h.GetString(params, "ResourceArn")
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if !s.hasPool(arn) {
+		h.WriteJSONError(w, "ResourceNotFoundException", "Resource not found: "+arn, http.StatusBadRequest)
+		return
+	}
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"Tags": s.tags.Get(arn),
+	})
+}
+
 func (s *Service) updateIdentityPool(w http.ResponseWriter, params map[string]interface{}) {
 	id := h.GetString(params, "IdentityPoolId")
 
@@ -195,13 +232,15 @@ func (s *Service) updateIdentityPool(w http.ResponseWriter, params map[string]in
 	}
 	s.mu.Unlock()
 
-	h.WriteJSON(w, http.StatusOK, poolResp(pool))
+	h.WriteJSON(w, http.StatusOK, s.poolResp(pool))
 }
 
-func poolResp(pool *identityPool) map[string]interface{} {
+// poolResp renders an identity pool along with its tags.
+func (s *Service) poolResp(pool *identityPool) map[string]interface{} {
 	return map[string]interface{}{
 		"IdentityPoolId":                 pool.id,
 		"IdentityPoolName":               pool.name,
 		"AllowUnauthenticatedIdentities": pool.allowUnauthenticated,
+		"IdentityPoolTags":               s.tags.Get(poolARN(pool.id)),
 	}
 }
@@ -6,29 +6,42 @@
 //   - DeleteUserPool
 //   - ListUserPools
 //   - CreateUserPoolClient
+//   - DescribeUserPoolClient
 //   - AdminCreateUser
 //   - AdminGetUser
 //   - AdminDeleteUser
 //   - ListUsers
+//   - TagResource
+//   - UntagResource
+//   - ListTagsForResource
+//
+// Each user pool also serves the OAuth 2.0 endpoints of the Cognito hosted
+// UI under /_cognito/{userPoolId}: /oauth2/authorize, which approves every
+// request without a login page, /oauth2/token, /.well-known/jwks.json, and
+// /.well-known/openid-configuration.
 package cognitoidp
 
 import (
-	"encoding/json"
+	"crypto/rsa"
 	"fmt"
-	"io"
 	"net/http"
 	"sort"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/riyanimam/goto/internal/clock"
 	h "github.com/riyanimam/goto/internal/mockhelpers"
+	"github.com/riyanimam/goto/internal/tags"
 )
 
 // Service implements the Cognito Identity Provider mock.
 type Service struct {
-	mu    sync.RWMutex
-	pools map[string]*userPool
+	mu      sync.RWMutex
+	pools   map[string]*userPool
+	tags    *tags.Store
+	clock   *clock.Clock
+	baseURL string
 }
 
 type userPool struct {
@@ -40,16 +53,29 @@ type userPool struct {
 	modified time.Time
 	clients  map[string]*userPoolClient
 	users    map[string]*cognitoUser
+
+	// Hosted UI state. The signing key is generated on first use.
+	key           *rsa.PrivateKey
+	keyID         string
+	codes         map[string]*authCode
+	refreshTokens map[string]*refreshGrant
 }
 
 type userPoolClient struct {
 	clientID   string
 	clientName string
 	poolID     string
+	secret     string
+
+	oauthEnabled bool
+	oauthFlows   []string // code, implicit, client_credentials
+	oauthScopes  []string
+	callbackURLs []string
 }
 
 type cognitoUser struct {
 	username   string
+	sub        string
 	status     string
 	enabled    bool
 	created    time.Time
@@ -61,6 +87,7 @@ type cognitoUser struct {
 func New() *Service {
 	return &Service{
 		pools: make(map[string]*userPool),
+		tags:  tags.New(),
 	}
 }
 
@@ -69,7 +96,28 @@ func (s *Service) Name() string { return "cognito-idp" }
 
 // Handler returns the HTTP handler for Cognito requests.
 func (s *Service) Handler() http.Handler {
-	return http.HandlerFunc(s.handle)
+	api := h.JSONRouter{
+		"CreateUserPool":         s.createUserPool,
+		"DescribeUserPool":       s.describeUserPool,
+		"DeleteUserPool":         s.deleteUserPool,
+		"ListUserPools":          s.listUserPools,
+		"CreateUserPoolClient":   s.createUserPoolClient,
+		"DescribeUserPoolClient": s.describeUserPoolClient,
+		"AdminCreateUser":        s.adminCreateUser,
+		"AdminGetUser":           s.adminGetUser,
+		"AdminDeleteUser":        s.adminDeleteUser,
+		"ListUsers":              s.listUsers,
+		"TagResource":            s.tagResource,
+		"UntagResource":          s.untagResource,
+		"ListTagsForResource":    s.listTagsForResource,
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, oauthPrefix) {
+			s.serveOAuth(w, r)
+			return
+		}
+		api.ServeHTTP(w, r)
+	})
 }
 
 // Reset clears all state.
@@ -77,55 +125,29 @@ func (s *Service) Reset() {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	s.pools = make(map[string]*userPool)
+	s.tags.DeleteService("cognito-idp")
 }
 
-func (s *Service) handle(w http.ResponseWriter, r *http.Request) {
-	target := r.Header.Get("X-Amz-Target")
-
-	bodyBytes, err := io.ReadAll(r.Body)
-	if err != nil {
-		h.WriteJSONError(w, "InternalFailure", "could not read request body", http.StatusInternalServerError)
-		return
-	}
-
-	var params map[string]interface{}
-	if len(bodyBytes) > 0 {
-		json.Unmarshal(bodyBytes, &params)
-	}
-	if params == nil {
-		params = make(map[string]interface{})
-	}
+// SetTagStore sets the registry resource tags are recorded in.
+func (s *Service) SetTagStore(store *tags.Store) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tags = store
+}
 
-	action := ""
-	if target != "" {
-		parts := strings.SplitN(target, ".", 2)
-		if len(parts) == 2 {
-			action = parts[1]
-		}
-	}
+// SetClock sets the clock that token issue and expiry times are read from.
+func (s *Service) SetClock(c *clock.Clock) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.clock = c
+}
 
-	switch action {
-	case "CreateUserPool":
-		s.createUserPool(w, params)
-	case "DescribeUserPool":
-		s.describeUserPool(w, params)
-	case "DeleteUserPool":
-		s.deleteUserPool(w, params)
-	case "ListUserPools":
-		s.listUserPools(w, params)
-	case "CreateUserPoolClient":
-		s.createUserPoolClient(w, params)
-	case "AdminCreateUser":
-		s.adminCreateUser(w, params)
-	case "AdminGetUser":
-		s.adminGetUser(w, params)
-	case "AdminDeleteUser":
-		s.adminDeleteUser(w, params)
-	case "ListUsers":
-		s.listUsers(w, params)
-	default:
-		h.WriteJSONError(w, "UnknownOperationException", fmt.Sprintf("action %q is not supported", action), http.StatusBadRequest)
-	}
+// SetBaseURL records the mock server URL, the base of the hosted UI
+// endpoints and of the issuer in tokens.
+func (s *Service) SetBaseURL(u string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.baseURL = u
 }
 
 func (s *Service) createUserPool(w http.ResponseWriter, params map[string]interface{}) {
@@ -148,8 +170,12 @@ func (s *Service) createUserPool(w http.ResponseWriter, params map[string]interf
 		modified: now,
 		clients:  make(map[string]*userPoolClient),
 		users:    make(map[string]*cognitoUser),
+
+		codes:         make(map[string]*authCode),
+		refreshTokens: make(map[string]*refreshGrant),
 	}
 	s.pools[id] = pool
+	s.tags.Tag(arn, tags.FromMap(params["UserPoolTags"]))
 	s.mu.Unlock()
 
 	h.WriteJSON(w, http.StatusOK, map[string]interface{}{
@@ -178,12 +204,14 @@ func (s *Service) deleteUserPool(w http.ResponseWriter, params map[string]interf
 	poolID := h.GetString(params, "UserPoolId")
 
 	s.mu.Lock()
-	if _, exists := s.pools[poolID]; !exists {
+	pool, exists := s.pools[poolID]
+	if !exists {
 		s.mu.Unlock()
 		h.WriteJSONError(w, "ResourceNotFoundException", "User pool "+poolID+" does not exist.", http.StatusBadRequest)
 		return
 	}
 	delete(s.pools, poolID)
+	s.tags.Delete(pool.arn)
 	s.mu.Unlock()
 
 	h.WriteJSON(w, http.StatusOK, map[string]interface{}{})
@@ -211,6 +239,59 @@ func (s *Service) listUserPools(w http.ResponseWriter, _ map[string]interface{})
 	})
 }
 
+// hasPool reports whether arn names a user pool. Caller must hold s.mu.
+func (s *Service) hasPool(arn string) bool {
+	for _, pool := range s.pools {
+		if pool.arn == arn {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Service) tagResource(w http.ResponseWriter, params map[string]interface{}) {
+	arn := h.GetString(params, "ResourceArn")
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if !s.hasPool(arn) {
+		h.WriteJSONError(w, "ResourceNotFoundException", "Resource not found: "+arn, http.StatusBadRequest)
+		return
+	}
+	s.tags.Tag(arn, tags.FromMap(params["Tags"]))
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{})
+}
+
+func (s *Service) untagResource(w http.ResponseWriter, params map[string]interface{}) {
+	arn := h.GetString(params, "ResourceArn")
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if !s.hasPool(arn) {
+		h.WriteJSONError(w, "ResourceNotFoundException", "Resource not found: "+arn, http.StatusBadRequest)
+		return
+	}
+	s.tags.Untag(arn, tags.Keys(params["TagKeys"]))
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{})
+}
+
+func (s *Service) listTagsForResource(w http.ResponseWriter, params map[string]interface{}) {
+	arn := h.GetString(params, "ResourceArn")
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if !s.hasPool(arn) {
+		h.WriteJSONError(w, "ResourceNotFoundException", "Resource not found: "+arn, http.StatusBadRequest)
+		return
+	}
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"Tags": s.tags.Get(arn),
+	})
+}
+
 func (s *Service) createUserPoolClient(w http.ResponseWriter, params map[string]interface{}) {
 	poolID := h.GetString(params, "UserPoolId")
 	clientName := h.GetString(params, "ClientName")
@@ -228,16 +309,41 @@ func (s *Service) createUserPoolClient(w http.ResponseWriter, params map[string]
 		clientID:   clientID,
 		clientName: clientName,
 		poolID:     poolID,
+
+		oauthEnabled: h.GetBool(params, "AllowedOAuthFlowsUserPoolClient"),
+		oauthFlows:   getStringSlice(params, "AllowedOAuthFlows"),
+		oauthScopes:  getStringSlice(params, "AllowedOAuthScopes"),
+		callbackURLs: getStringSlice(params, "CallbackURLs"),
+	}
+	if h.GetBool(params, "GenerateSecret") {
+		client.secret = strings.ToLower(h.RandomID(51))
 	}
 	pool.clients[clientID] = client
 	s.mu.Unlock()
 
 	h.WriteJSON(w, http.StatusOK, map[string]interface{}{
-		"UserPoolClient": map[string]interface{}{
-			"ClientId":   clientID,
-			"ClientName": clientName,
-			"UserPoolId": poolID,
-		},
+		"UserPoolClient": clientResp(client),
+	})
+}
+
+func (s *Service) describeUserPoolClient(w http.ResponseWriter, params map[string]interface{}) {
+	poolID := h.GetString(params, "UserPoolId")
+	clientID := h.GetString(params, "ClientId")
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	pool, exists := s.pools[poolID]
+	if !exists {
+		h.WriteJSONError(w, "ResourceNotFoundException", "User pool "+poolID+" does not exist.", http.StatusBadRequest)
+		return
+	}
+	client, exists := pool.clients[clientID]
+	if !exists {
+		h.WriteJSONError(w, "ResourceNotFoundException", "User pool client "+clientID+" does not exist.", http.StatusBadRequest)
+		return
+	}
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"UserPoolClient": clientResp(client),
 	})
 }
 
@@ -271,9 +377,17 @@ func (s *Service) adminCreateUser(w http.ResponseWriter, params map[string]inter
 		}
 	}
 
+	sub := h.NewRequestID()
+	if attrs["sub"] == "" {
+		attrs["sub"] = sub
+	} else {
+		sub = attrs["sub"]
+	}
+
 	now := time.Now().UTC()
 	user := &cognitoUser{
 		username:   username,
+		sub:        sub,
 		status:     "FORCE_CHANGE_PASSWORD",
 		enabled:    true,
 		created:    now,
@@ -371,6 +485,28 @@ func poolResp(pool *userPool) map[string]interface{} {
 	}
 }
 
+func clientResp(client *userPoolClient) map[string]interface{} {
+	resp := map[string]interface{}{
+		"ClientId":                        client.clientID,
+		"ClientName":                      client.clientName,
+		"UserPoolId":                      client.poolID,
+		"AllowedOAuthFlowsUserPoolClient": client.oauthEnabled,
+	}
+	if client.secret != "" {
+		resp["ClientSecret"] = client.secret
+	}
+	if len(client.oauthFlows) > 0 {
+		resp["AllowedOAuthFlows"] = client.oauthFlows
+	}
+	if len(client.oauthScopes) > 0 {
+		resp["AllowedOAuthScopes"] = client.oauthScopes
+	}
+	if len(client.callbackURLs) > 0 {
+		resp["CallbackURLs"] = client.callbackURLs
+	}
+	return resp
+}
+
 func userResp(user *cognitoUser) map[string]interface{} {
 	var attrs []map[string]interface{}
 	for k, v := range user.attributes {
@@ -388,3 +524,14 @@ func userResp(user *cognitoUser) map[string]interface{} {
 		"Attributes":           attrs,
 	}
 }
+
+func getStringSlice(params map[string]interface{}, key string) []string {
+	arr, _ := params[key].([]interface{})
+	out := make([]string, 0, len(arr))
+	for _, item := range arr {
+		if s, ok := item.(string); ok {
+			out = append(out, s)
+		}
+	}
+	return out
+}
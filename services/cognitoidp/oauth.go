@@ -0,0 +1,450 @@
+package cognitoidp
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	h "github.com/riyanimam/goto/internal/mockhelpers"
+)
+
+// oauthPrefix is the path under which each user pool's hosted UI endpoints
+// are served: /_cognito/{userPoolId}/oauth2/token and so on.
+const oauthPrefix = "/_cognito/"
+
+// tokenLifetime is how long access and ID tokens are valid, Cognito's
+// default of one hour.
+const tokenLifetime = time.Hour
+
+// authCode is an authorization code issued by /oauth2/authorize and not
+// yet exchanged.
+type authCode struct {
+	clientID    string
+	redirectURI string
+	username    string
+	scopes      []string
+	nonce       string
+	challenge   string // PKCE code_challenge
+	method      string // PKCE code_challenge_method: S256 or plain
+}
+
+// refreshGrant is what a refresh token stands for.
+type refreshGrant struct {
+	clientID string
+	username string
+	scopes   []string
+}
+
+// serveOAuth serves a user pool's hosted UI endpoints.
+func (s *Service) serveOAuth(w http.ResponseWriter, r *http.Request) {
+	poolID, endpoint, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, oauthPrefix), "/")
+	switch endpoint {
+	case "oauth2/authorize":
+		s.authorize(w, r, poolID)
+	case "oauth2/token":
+		s.token(w, r, poolID)
+	case ".well-known/jwks.json":
+		s.jwks(w, poolID)
+	case ".well-known/openid-configuration":
+		s.openIDConfiguration(w, poolID)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// authorize serves /oauth2/authorize for the authorization code grant. It
+// approves the request at once, as the user named by login_hint or, if
+// there is none, the pool's only user, and redirects to redirect_uri with
+// the code.
+func (s *Service) authorize(w http.ResponseWriter, r *http.Request, poolID string) {
+	q := r.URL.Query()
+	redirectURI := q.Get("redirect_uri")
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	pool, client := s.oauthClient(w, poolID, q.Get("client_id"))
+	if client == nil {
+		return
+	}
+	if redirectURI == "" || !containsString(client.callbackURLs, redirectURI) {
+		writeOAuthError(w, "redirect_mismatch", http.StatusBadRequest)
+		return
+	}
+	target, _ := url.Parse(redirectURI)
+	fail := func(code string) {
+		v := target.Query()
+		v.Set("error", code)
+		if state := q.Get("state"); state != "" {
+			v.Set("state", state)
+		}
+		target.RawQuery = v.Encode()
+		http.Redirect(w, r, target.String(), http.StatusFound)
+	}
+
+	if q.Get("response_type") != "code" {
+		fail("unsupported_response_type")
+		return
+	}
+	if !containsString(client.oauthFlows, "code") {
+		fail("unauthorized_client")
+		return
+	}
+	scopes, ok := client.grantScopes(q.Get("scope"))
+	if !ok {
+		fail("invalid_scope")
+		return
+	}
+	method := q.Get("code_challenge_method")
+	if q.Get("code_challenge") != "" && method == "" {
+		method = "plain"
+	}
+	if method != "" && method != "S256" && method != "plain" {
+		fail("invalid_request")
+		return
+	}
+	user := pool.loginUser(q.Get("login_hint"))
+	if user == nil {
+		fail("access_denied")
+		return
+	}
+
+	code := h.NewRequestID()
+	pool.codes[code] = &authCode{
+		clientID:    client.clientID,
+		redirectURI: redirectURI,
+		username:    user.username,
+		scopes:      scopes,
+		nonce:       q.Get("nonce"),
+		challenge:   q.Get("code_challenge"),
+		method:      method,
+	}
+	v := target.Query()
+	v.Set("code", code)
+	if state := q.Get("state"); state != "" {
+		v.Set("state", state)
+	}
+	target.RawQuery = v.Encode()
+	http.Redirect(w, r, target.String(), http.StatusFound)
+}
+
+// token serves /oauth2/token for the authorization_code,
+// client_credentials, and refresh_token grants.
+func (s *Service) token(w http.ResponseWriter, r *http.Request, poolID string) {
+	if r.Method != http.MethodPost {
+		writeOAuthError(w, "invalid_request", http.StatusMethodNotAllowed)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		writeOAuthError(w, "invalid_request", http.StatusBadRequest)
+		return
+	}
+	clientID, secret, basic := r.BasicAuth()
+	if !basic {
+		clientID, secret = r.PostForm.Get("client_id"), r.PostForm.Get("client_secret")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	pool, client := s.oauthClient(w, poolID, clientID)
+	if client == nil {
+		return
+	}
+	if client.secret != secret {
+		writeOAuthError(w, "invalid_client", http.StatusBadRequest)
+		return
+	}
+
+	switch r.PostForm.Get("grant_type") {
+	case "authorization_code":
+		code, exists := pool.codes[r.PostForm.Get("code")]
+		if !exists || code.clientID != client.clientID || code.redirectURI != r.PostForm.Get("redirect_uri") ||
+			!code.verify(r.PostForm.Get("code_verifier")) {
+			writeOAuthError(w, "invalid_grant", http.StatusBadRequest)
+			return
+		}
+		delete(pool.codes, r.PostForm.Get("code"))
+		user, exists := pool.users[code.username]
+		if !exists {
+			writeOAuthError(w, "invalid_grant", http.StatusBadRequest)
+			return
+		}
+		refresh := h.NewRequestID()
+		pool.refreshTokens[refresh] = &refreshGrant{clientID: client.clientID, username: user.username, scopes: code.scopes}
+		resp := s.userTokens(pool, client, user, code.scopes, code.nonce)
+		resp["refresh_token"] = refresh
+		writeOAuthJSON(w, resp)
+
+	case "refresh_token":
+		grant, exists := pool.refreshTokens[r.PostForm.Get("refresh_token")]
+		if !exists || grant.clientID != client.clientID {
+			writeOAuthError(w, "invalid_grant", http.StatusBadRequest)
+			return
+		}
+		user, exists := pool.users[grant.username]
+		if !exists {
+			writeOAuthError(w, "invalid_grant", http.StatusBadRequest)
+			return
+		}
+		writeOAuthJSON(w, s.userTokens(pool, client, user, grant.scopes, ""))
+
+	case "client_credentials":
+		if client.secret == "" || !containsString(client.oauthFlows, "client_credentials") {
+			writeOAuthError(w, "unauthorized_client", http.StatusBadRequest)
+			return
+		}
+		scopes, ok := client.grantScopes(r.PostForm.Get("scope"))
+		if !ok {
+			writeOAuthError(w, "invalid_scope", http.StatusBadRequest)
+			return
+		}
+		now := s.now()
+		writeOAuthJSON(w, map[string]interface{}{
+			"access_token": s.sign(pool, map[string]interface{}{
+				"sub":       client.clientID,
+				"client_id": client.clientID,
+				"token_use": "access",
+				"scope":     strings.Join(scopes, " "),
+				"auth_time": now.Unix(),
+				"iss":       s.issuer(pool.id),
+				"exp":       now.Add(tokenLifetime).Unix(),
+				"iat":       now.Unix(),
+				"jti":       h.NewRequestID(),
+				"version":   2,
+			}),
+			"token_type": "Bearer",
+			"expires_in": int(tokenLifetime.Seconds()),
+		})
+
+	default:
+		writeOAuthError(w, "unsupported_grant_type", http.StatusBadRequest)
+	}
+}
+
+// userTokens returns the access and ID tokens issued to user through
+// client. The caller must hold s.mu.
+func (s *Service) userTokens(pool *userPool, client *userPoolClient, user *cognitoUser, scopes []string, nonce string) map[string]interface{} {
+	now := s.now()
+	access := map[string]interface{}{
+		"sub":       user.sub,
+		"client_id": client.clientID,
+		"username":  user.username,
+		"token_use": "access",
+		"scope":     strings.Join(scopes, " "),
+		"auth_time": now.Unix(),
+		"iss":       s.issuer(pool.id),
+		"exp":       now.Add(tokenLifetime).Unix(),
+		"iat":       now.Unix(),
+		"jti":       h.NewRequestID(),
+		"version":   2,
+	}
+	id := map[string]interface{}{
+		"sub":              user.sub,
+		"aud":              client.clientID,
+		"cognito:username": user.username,
+		"token_use":        "id",
+		"auth_time":        now.Unix(),
+		"iss":              s.issuer(pool.id),
+		"exp":              now.Add(tokenLifetime).Unix(),
+		"iat":              now.Unix(),
+		"jti":              h.NewRequestID(),
+	}
+	for name, value := range user.attributes {
+		if strings.HasSuffix(name, "_verified") {
+			id[name] = value == "true"
+		} else if name != "sub" {
+			id[name] = value
+		}
+	}
+	if nonce != "" {
+		id["nonce"] = nonce
+	}
+	return map[string]interface{}{
+		"access_token": s.sign(pool, access),
+		"id_token":     s.sign(pool, id),
+		"token_type":   "Bearer",
+		"expires_in":   int(tokenLifetime.Seconds()),
+	}
+}
+
+// jwks serves the public half of the pool's signing key.
+func (s *Service) jwks(w http.ResponseWriter, poolID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	pool, exists := s.pools[poolID]
+	if !exists {
+		writeOAuthError(w, "invalid_request", http.StatusNotFound)
+		return
+	}
+	key := pool.signingKey()
+	writeOAuthJSON(w, map[string]interface{}{
+		"keys": []map[string]interface{}{{
+			"kid": pool.keyID,
+			"alg": "RS256",
+			"kty": "RSA",
+			"use": "sig",
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}},
+	})
+}
+
+// openIDConfiguration serves the pool's OpenID Connect discovery document.
+func (s *Service) openIDConfiguration(w http.ResponseWriter, poolID string) {
+	s.mu.RLock()
+	_, exists := s.pools[poolID]
+	issuer := s.issuer(poolID)
+	s.mu.RUnlock()
+	if !exists {
+		writeOAuthError(w, "invalid_request", http.StatusNotFound)
+		return
+	}
+	writeOAuthJSON(w, map[string]interface{}{
+		"issuer":                                issuer,
+		"authorization_endpoint":                issuer + "/oauth2/authorize",
+		"token_endpoint":                        issuer + "/oauth2/token",
+		"jwks_uri":                              issuer + "/.well-known/jwks.json",
+		"response_types_supported":              []string{"code"},
+		"grant_types_supported":                 []string{"authorization_code", "client_credentials", "refresh_token"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+		"scopes_supported":                      []string{"openid", "email", "phone", "profile"},
+		"token_endpoint_auth_methods_supported": []string{"client_secret_basic", "client_secret_post"},
+		"code_challenge_methods_supported":      []string{"S256", "plain"},
+	})
+}
+
+// oauthClient returns the pool and its app client with the given ID,
+// writing an error and returning nil if either does not exist. The caller
+// must hold s.mu.
+func (s *Service) oauthClient(w http.ResponseWriter, poolID, clientID string) (*userPool, *userPoolClient) {
+	pool, exists := s.pools[poolID]
+	if !exists {
+		writeOAuthError(w, "invalid_request", http.StatusNotFound)
+		return nil, nil
+	}
+	client, exists := pool.clients[clientID]
+	if !exists {
+		writeOAuthError(w, "invalid_client", http.StatusBadRequest)
+		return nil, nil
+	}
+	return pool, client
+}
+
+// grantScopes returns the space-separated scopes requested, or all the
+// client's scopes if none are, reporting false if any is not allowed.
+func (client *userPoolClient) grantScopes(requested string) ([]string, bool) {
+	if requested == "" {
+		return client.oauthScopes, true
+	}
+	scopes := strings.Fields(requested)
+	for _, scope := range scopes {
+		if !containsString(client.oauthScopes, scope) {
+			return nil, false
+		}
+	}
+	return scopes, true
+}
+
+// loginUser returns the user an authorization request signs in: the user
+// named by hint, or the pool's only user if hint is empty.
+func (pool *userPool) loginUser(hint string) *cognitoUser {
+	if hint != "" {
+		return pool.users[hint]
+	}
+	if len(pool.users) != 1 {
+		return nil
+	}
+	for _, user := range pool.users {
+		return user
+	}
+	return nil
+}
+
+// verify checks a PKCE code_verifier against the code's challenge.
+func (code *authCode) verify(verifier string) bool {
+	switch code.method {
+	case "":
+		return true
+	case "S256":
+		sum := sha256.Sum256([]byte(verifier))
+		return base64.RawURLEncoding.EncodeToString(sum[:]) == code.challenge
+	default:
+		return verifier == code.challenge
+	}
+}
+
+// signingKey returns the pool's token signing key, generating it on first
+// use. The caller must hold s.mu for writing.
+func (pool *userPool) signingKey() *rsa.PrivateKey {
+	if pool.key == nil {
+		key, err := rsa.GenerateKey(rand.Reader, 2048)
+		if err != nil {
+			panic("cognitoidp: generating signing key: " + err.Error())
+		}
+		pool.key = key
+		pool.keyID = h.NewRequestID()
+	}
+	return pool.key
+}
+
+// sign returns claims as an RS256 JSON Web Token signed with the pool's
+// key. The caller must hold s.mu for writing.
+func (s *Service) sign(pool *userPool, claims map[string]interface{}) string {
+	key := pool.signingKey()
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "kid": pool.keyID, "typ": "JWT"})
+	payload, _ := json.Marshal(claims)
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(signed))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		panic("cognitoidp: signing token: " + err.Error())
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+// issuer returns the iss claim of the pool's tokens. The caller must hold
+// s.mu.
+func (s *Service) issuer(poolID string) string {
+	return s.baseURL + oauthPrefix + poolID
+}
+
+// now returns the current time of the mock clock, or the wall-clock time
+// if there is none. The caller must hold s.mu.
+func (s *Service) now() time.Time {
+	if s.clock != nil {
+		return s.clock.Now()
+	}
+	return time.Now()
+}
+
+func writeOAuthJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json;charset=UTF-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeOAuthError writes an OAuth 2.0 error response, which the hosted UI
+// endpoints use instead of the Cognito API's error shape.
+func writeOAuthError(w http.ResponseWriter, code string, status int) {
+	w.Header().Set("Content-Type", "application/json;charset=UTF-8")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": code})
+}
+
+func containsString(values []string, v string) bool {
+	for _, s := range values {
+		if s == v {
+			return true
+		}
+	}
+	return false
+}
@@ -7,18 +7,19 @@
 //   - PutConfigurationRecorder
 //   - DescribeConfigurationRecorders
 //   - PutDeliveryChannel
+//   - TagResource
+//   - UntagResource
+//   - ListTagsForResource
 package configservice
 
 import (
-	"encoding/json"
 	"fmt"
-	"io"
 	"net/http"
 	"sort"
-	"strings"
 	"sync"
 
 	h "github.com/riyanimam/goto/internal/mockhelpers"
+	"github.com/riyanimam/goto/internal/tags"
 )
 
 // Service implements the AWS Config mock.
@@ -27,6 +28,7 @@ type Service struct {
 	rules     map[string]*configRule
 	recorders map[string]*configurationRecorder
 	channels  map[string]*deliveryChannel
+	tags      *tags.Store
 }
 
 type configRule struct {
@@ -56,6 +58,7 @@ func New() *Service {
 		rules:     make(map[string]*configRule),
 		recorders: make(map[string]*configurationRecorder),
 		channels:  make(map[string]*deliveryChannel),
+		tags:      tags.New(),
 	}
 }
 
@@ -64,7 +67,17 @@ func (s *Service) Name() string { return "config" }
 
 // Handler returns the HTTP handler for Config requests.
 func (s *Service) Handler() http.Handler {
-	return http.HandlerFunc(s.handle)
+	return h.JSONRouter{
+		"PutConfigRule":                  s.putConfigRule,
+		"DescribeConfigRules":            s.describeConfigRules,
+		"DeleteConfigRule":               s.deleteConfigRule,
+		"PutConfigurationRecorder":       s.putConfigurationRecorder,
+		"DescribeConfigurationRecorders": s.describeConfigurationRecorders,
+		"PutDeliveryChannel":             s.putDeliveryChannel,
+		"TagResource":                    s.tagResource,
+		"UntagResource":                  s.untagResource,
+		"ListTagsForResource":            s.listTagsForResource,
+	}
 }
 
 // Reset clears all state.
@@ -74,49 +87,14 @@ func (s *Service) Reset() {
 	s.rules = make(map[string]*configRule)
 	s.recorders = make(map[string]*configurationRecorder)
 	s.channels = make(map[string]*deliveryChannel)
+	s.tags.DeleteService("config")
 }
 
-func (s *Service) handle(w http.ResponseWriter, r *http.Request) {
-	target := r.Header.Get("X-Amz-Target")
-
-	bodyBytes, err := io.ReadAll(r.Body)
-	if err != nil {
-		h.WriteJSONError(w, "InternalFailure", "could not read request body", http.StatusInternalServerError)
-		return
-	}
-
-	var params map[string]interface{}
-	if len(bodyBytes) > 0 {
-		json.Unmarshal(bodyBytes, &params)
-	}
-	if params == nil {
-		params = make(map[string]interface{})
-	}
-
-	action := ""
-	if target != "" {
-		parts := strings.SplitN(target, ".", 2)
-		if len(parts) == 2 {
-			action = parts[1]
-		}
-	}
-
-	switch action {
-	case "PutConfigRule":
-		s.putConfigRule(w, params)
-	case "DescribeConfigRules":
-		s.describeConfigRules(w, params)
-	case "DeleteConfigRule":
-		s.deleteConfigRule(w, params)
-	case "PutConfigurationRecorder":
-		s.putConfigurationRecorder(w, params)
-	case "DescribeConfigurationRecorders":
-		s.describeConfigurationRecorders(w, params)
-	case "PutDeliveryChannel":
-		s.putDeliveryChannel(w, params)
-	default:
-		h.WriteJSONError(w, "UnknownOperationException", fmt.Sprintf("action %q is not supported", action), http.StatusBadRequest)
-	}
+// SetTagStore sets the registry resource tags are recorded in.
+func (s *Service) SetTagStore(store *tags.Store) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tags = store
 }
 
 func (s *Service) putConfigRule(w http.ResponseWriter, params map[string]interface{}) {
@@ -149,6 +127,7 @@ func (s *Service) putConfigRule(w http.ResponseWriter, params map[string]interfa
 			state:  "ACTIVE",
 		}
 		s.rules[name] = rule
+		s.tags.Tag(rule.arn, tags.FromList(params["Tags"], "Key", "Value"))
 	}
 	if source != nil {
 		rule.source = source
@@ -200,17 +179,73 @@ func (s *Service) deleteConfigRule(w http.ResponseWriter, params map[string]inte
 	}
 
 	s.mu.Lock()
-	if _, exists := s.rules[name]; !exists {
+	rule, exists := s.rules[name]
+	if !exists {
 		s.mu.Unlock()
 		h.WriteJSONError(w, "NoSuchConfigRuleException", "Config rule not found: "+name, http.StatusBadRequest)
 		return
 	}
 	delete(s.rules, name)
+	s.tags.Delete(rule.arn)
 	s.mu.Unlock()
 
 	h.WriteJSON(w, http.StatusOK, map[string]interface{}{})
 }
 
+// hasRule reports whether arn names an existing config rule. The caller
+// must hold s.mu.
+func (s *Service) hasRule(arn string) bool {
+	for _, rule := range s.rules {
+		if rule.arn == arn {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Service) tagResource(w http.ResponseWriter, params map[string]interface{}) {
+	arn := h.GetString(params, "ResourceArn")
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if !s.hasRule(arn) {
+		h.WriteJSONError(w, "ResourceNotFoundException", "Resource not found: "+arn, http.StatusBadRequest)
+		return
+	}
+	s.tags.Tag(arn, tags.FromList(params["Tags"], "Key", "Value"))
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{})
+}
+
+func (s *Service) untagResource(w http.ResponseWriter, params map[string]interface{}) {
+	arn := h.GetString(params, "ResourceArn")
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if !s.hasRule(arn) {
+		h.WriteJSONError(w, "ResourceNotFoundException", "Resource not found: "+arn, http.StatusBadRequest)
+		return
+	}
+	s.tags.Untag(arn, tags.Keys(params["TagKeys"]))
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{})
+}
+
+func (s *Service) listTagsForResource(w http.ResponseWriter, params map[string]interface{}) {
+	arn := h.GetString(params, "ResourceArn")
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if !s.hasRule(arn) {
+		h.WriteJSONError(w, "ResourceNotFoundException", "Resource not found: "+arn, http.StatusBadRequest)
+		return
+	}
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"Tags": tags.ToList(s.tags.Get(arn), "Key", "Value"),
+	})
+}
+
 func (s *Service) putConfigurationRecorder(w http.ResponseWriter, params map[string]interface{}) {
 	recObj, ok := params["ConfigurationRecorder"].(map[string]interface{})
 	if !ok {
@@ -0,0 +1,617 @@
+// Package costexplorer provides a mock implementation of AWS Cost Explorer.
+//
+// Supported actions:
+//   - GetCostAndUsage
+//   - GetCostForecast
+//   - GetDimensionValues
+//
+// There is no billing pipeline behind the mock: costs are the line items
+// injected with [Service.AddCosts], which GetCostAndUsage aggregates by
+// period, filter, and grouping. Forecasts project the average daily cost of
+// the 30 days before the mock clock's current date.
+package costexplorer
+
+import (
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/riyanimam/goto/internal/clock"
+	h "github.com/riyanimam/goto/internal/mockhelpers"
+	"github.com/riyanimam/goto/internal/paginate"
+)
+
+// Cost is one day's charge for a usage line, as it would appear in the
+// Cost and Usage Report.
+type Cost struct {
+	// Date is the day the cost was incurred, in UTC.
+	Date time.Time
+	// Service is the service name, such as "Amazon Simple Storage Service".
+	Service string
+	// LinkedAccount defaults to the mock's account ID and Region to
+	// us-east-1.
+	LinkedAccount string
+	Region        string
+	// UsageType, Operation, and InstanceType describe the usage, such as
+	// "USE1-TimedStorage-ByteHrs", "StandardStorage", and "m5.large".
+	UsageType    string
+	Operation    string
+	InstanceType string
+	// RecordType is Usage by default; Credit, Refund, and Tax lines are
+	// other record types.
+	RecordType string
+	// Tags are the cost allocation tags of the resources billed.
+	Tags map[string]string
+	// Amount is the cost in USD, reported for every cost metric.
+	Amount float64
+	// UsageQuantity is the amount used, in Unit (such as "GB-Mo" or "Hrs").
+	UsageQuantity float64
+	Unit          string
+}
+
+// dimensions maps the dimensions the mock supports to the cost fields they
+// select.
+var dimensions = map[string]func(c *Cost) string{
+	"SERVICE":        func(c *Cost) string { return c.Service },
+	"LINKED_ACCOUNT": func(c *Cost) string { return c.LinkedAccount },
+	"REGION":         func(c *Cost) string { return c.Region },
+	"USAGE_TYPE":     func(c *Cost) string { return c.UsageType },
+	"OPERATION":      func(c *Cost) string { return c.Operation },
+	"INSTANCE_TYPE":  func(c *Cost) string { return c.InstanceType },
+	"RECORD_TYPE":    func(c *Cost) string { return c.RecordType },
+}
+
+// costMetrics are the metrics that report Amount; the others report
+// UsageQuantity.
+var costMetrics = map[string]bool{
+	"AmortizedCost":    true,
+	"BlendedCost":      true,
+	"NetAmortizedCost": true,
+	"NetUnblendedCost": true,
+	"UnblendedCost":    true,
+}
+
+var usageMetrics = map[string]bool{
+	"UsageQuantity":         true,
+	"NormalizedUsageAmount": true,
+}
+
+// forecastMetrics maps GetCostForecast's metric names to GetCostAndUsage's.
+var forecastMetrics = map[string]string{
+	"AMORTIZED_COST":     "AmortizedCost",
+	"BLENDED_COST":       "BlendedCost",
+	"NET_AMORTIZED_COST": "NetAmortizedCost",
+	"NET_UNBLENDED_COST": "NetUnblendedCost",
+	"UNBLENDED_COST":     "UnblendedCost",
+}
+
+// Service implements the Cost Explorer mock.
+type Service struct {
+	mu    sync.RWMutex
+	costs []Cost
+	clock *clock.Clock
+}
+
+// New creates a new Cost Explorer mock service.
+func New() *Service {
+	return &Service{}
+}
+
+// Name returns the service identifier.
+func (s *Service) Name() string { return "ce" }
+
+// Handler returns the HTTP handler for Cost Explorer requests.
+func (s *Service) Handler() http.Handler {
+	return h.JSONRouter{
+		"GetCostAndUsage":    s.getCostAndUsage,
+		"GetCostForecast":    s.getCostForecast,
+		"GetDimensionValues": s.getDimensionValues,
+	}
+}
+
+// Reset clears all injected costs.
+func (s *Service) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.costs = nil
+}
+
+// SetClock attaches the mock clock that decides which periods are estimated
+// and what forecasts are based on.
+func (s *Service) SetClock(c *clock.Clock) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.clock = c
+}
+
+func (s *Service) today() time.Time {
+	now := time.Now()
+	if s.clock != nil {
+		now = s.clock.Now()
+	}
+	return now.UTC().Truncate(24 * time.Hour)
+}
+
+// AddCosts records cost line items for the API to report.
+func (s *Service) AddCosts(costs ...Cost) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, c := range costs {
+		c.Date = c.Date.UTC().Truncate(24 * time.Hour)
+		if c.LinkedAccount == "" {
+			c.LinkedAccount = h.DefaultAccountID
+		}
+		if c.Region == "" {
+			c.Region = "us-east-1"
+		}
+		if c.RecordType == "" {
+			c.RecordType = "Usage"
+		}
+		s.costs = append(s.costs, c)
+	}
+}
+
+// period is a half-open range of days.
+type period struct {
+	start, end time.Time
+}
+
+func (p period) toMap() map[string]interface{} {
+	return map[string]interface{}{
+		"Start": p.start.Format(time.DateOnly),
+		"End":   p.end.Format(time.DateOnly),
+	}
+}
+
+func (p period) contains(t time.Time) bool {
+	return !t.Before(p.start) && t.Before(p.end)
+}
+
+// parsePeriod reads a DateInterval parameter.
+func parsePeriod(w http.ResponseWriter, params map[string]interface{}) (period, bool) {
+	tp, _ := params["TimePeriod"].(map[string]interface{})
+	start, errStart := time.Parse(time.DateOnly, h.GetString(tp, "Start"))
+	end, errEnd := time.Parse(time.DateOnly, h.GetString(tp, "End"))
+	if tp == nil || errStart != nil || errEnd != nil {
+		h.WriteJSONError(w, "ValidationException", "TimePeriod requires Start and End dates in the format YYYY-MM-DD.", http.StatusBadRequest)
+		return period{}, false
+	}
+	if !start.Before(end) {
+		h.WriteJSONError(w, "ValidationException", "Start date should be before end date.", http.StatusBadRequest)
+		return period{}, false
+	}
+	return period{start, end}, true
+}
+
+// splitPeriod divides p into days or calendar months; the first and last
+// months may be partial.
+func splitPeriod(p period, granularity string) []period {
+	var out []period
+	for start := p.start; start.Before(p.end); {
+		end := start.AddDate(0, 0, 1)
+		if granularity == "MONTHLY" {
+			end = time.Date(start.Year(), start.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+		}
+		if end.After(p.end) {
+			end = p.end
+		}
+		out = append(out, period{start, end})
+		start = end
+	}
+	return out
+}
+
+// metricValue returns the named metric's contribution from c.
+func metricValue(c *Cost, metric string) float64 {
+	if costMetrics[metric] {
+		return c.Amount
+	}
+	return c.UsageQuantity
+}
+
+// formatAmount renders an amount as Cost Explorer does, as a decimal string.
+func formatAmount(v float64) string {
+	return strconv.FormatFloat(math.Round(v*1e10)/1e10, 'f', -1, 64)
+}
+
+// metricsMap renders the totals of metrics over costs.
+func metricsMap(costs []*Cost, metrics []string) map[string]interface{} {
+	out := make(map[string]interface{}, len(metrics))
+	for _, metric := range metrics {
+		total := 0.0
+		unit := "USD"
+		if !costMetrics[metric] {
+			unit = ""
+			for _, c := range costs {
+				switch {
+				case unit == "":
+					unit = c.Unit
+				case unit != c.Unit:
+					unit = "N/A"
+				}
+			}
+			if unit == "" {
+				unit = "N/A"
+			}
+		}
+		for _, c := range costs {
+			total += metricValue(c, metric)
+		}
+		out[metric] = map[string]interface{}{
+			"Amount": formatAmount(total),
+			"Unit":   unit,
+		}
+	}
+	return out
+}
+
+// matching returns the costs in p that match filter. The caller must hold
+// s.mu.
+func (s *Service) matching(p period, filter map[string]interface{}) []*Cost {
+	var out []*Cost
+	for i := range s.costs {
+		c := &s.costs[i]
+		if p.contains(c.Date) && matchExpression(c, filter) {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+func stringList(v interface{}) []string {
+	list, _ := v.([]interface{})
+	out := make([]string, 0, len(list))
+	for _, item := range list {
+		if str, ok := item.(string); ok {
+			out = append(out, str)
+		}
+	}
+	return out
+}
+
+// validateExpression checks the dimensions a filter names.
+func validateExpression(w http.ResponseWriter, expr map[string]interface{}) bool {
+	if expr == nil {
+		return true
+	}
+	if dim, ok := expr["Dimensions"].(map[string]interface{}); ok {
+		if _, known := dimensions[h.GetString(dim, "Key")]; !known {
+			h.WriteJSONError(w, "ValidationException", "Dimension "+h.GetString(dim, "Key")+" is not supported.", http.StatusBadRequest)
+			return false
+		}
+	}
+	for _, key := range []string{"And", "Or"} {
+		list, _ := expr[key].([]interface{})
+		for _, sub := range list {
+			if m, _ := sub.(map[string]interface{}); !validateExpression(w, m) {
+				return false
+			}
+		}
+	}
+	if not, ok := expr["Not"].(map[string]interface{}); ok {
+		return validateExpression(w, not)
+	}
+	return true
+}
+
+// matchExpression evaluates a Cost Explorer filter expression against c.
+func matchExpression(c *Cost, expr map[string]interface{}) bool {
+	if expr == nil {
+		return true
+	}
+	if and, ok := expr["And"].([]interface{}); ok {
+		for _, sub := range and {
+			if m, _ := sub.(map[string]interface{}); !matchExpression(c, m) {
+				return false
+			}
+		}
+		return true
+	}
+	if or, ok := expr["Or"].([]interface{}); ok {
+		for _, sub := range or {
+			if m, _ := sub.(map[string]interface{}); matchExpression(c, m) {
+				return true
+			}
+		}
+		return false
+	}
+	if not, ok := expr["Not"].(map[string]interface{}); ok {
+		return !matchExpression(c, not)
+	}
+	if dim, ok := expr["Dimensions"].(map[string]interface{}); ok {
+		get := dimensions[h.GetString(dim, "Key")]
+		value := ""
+		if get != nil {
+			value = get(c)
+		}
+		return matchValues(value, value != "", dim)
+	}
+	if tag, ok := expr["Tags"].(map[string]interface{}); ok {
+		value, present := c.Tags[h.GetString(tag, "Key")]
+		return matchValues(value, present, tag)
+	}
+	return true
+}
+
+// matchValues applies a DimensionValues or TagValues condition, whose
+// MatchOptions default to EQUALS, to a value.
+func matchValues(value string, present bool, cond map[string]interface{}) bool {
+	options := stringList(cond["MatchOptions"])
+	has := func(opt string) bool {
+		for _, o := range options {
+			if o == opt {
+				return true
+			}
+		}
+		return false
+	}
+	if has("ABSENT") {
+		return !present
+	}
+	if !present {
+		return false
+	}
+	test := func(want string) bool {
+		got := value
+		if has("CASE_INSENSITIVE") {
+			got, want = strings.ToLower(got), strings.ToLower(want)
+		}
+		switch {
+		case has("STARTS_WITH"):
+			return strings.HasPrefix(got, want)
+		case has("ENDS_WITH"):
+			return strings.HasSuffix(got, want)
+		case has("CONTAINS"):
+			return strings.Contains(got, want)
+		default:
+			return got == want
+		}
+	}
+	values := stringList(cond["Values"])
+	if len(values) == 0 {
+		return true
+	}
+	for _, want := range values {
+		if test(want) {
+			return true
+		}
+	}
+	return false
+}
+
+// groupKey returns a cost's key for a GroupBy definition: the dimension's
+// value, or "key$value" for a tag.
+func groupKey(c *Cost, group map[string]interface{}) string {
+	key := h.GetString(group, "Key")
+	if h.GetString(group, "Type") == "TAG" {
+		return key + "$" + c.Tags[key]
+	}
+	return dimensions[key](c)
+}
+
+func (s *Service) getCostAndUsage(w http.ResponseWriter, params map[string]interface{}) {
+	p, ok := parsePeriod(w, params)
+	if !ok {
+		return
+	}
+	granularity := h.GetString(params, "Granularity")
+	if granularity != "DAILY" && granularity != "MONTHLY" {
+		h.WriteJSONError(w, "ValidationException", "Granularity must be DAILY or MONTHLY.", http.StatusBadRequest)
+		return
+	}
+	metrics := stringList(params["Metrics"])
+	if len(metrics) == 0 {
+		h.WriteJSONError(w, "ValidationException", "Metrics is required.", http.StatusBadRequest)
+		return
+	}
+	for _, metric := range metrics {
+		if !costMetrics[metric] && !usageMetrics[metric] {
+			h.WriteJSONError(w, "ValidationException", "Metric "+metric+" is not supported.", http.StatusBadRequest)
+			return
+		}
+	}
+	filter, _ := params["Filter"].(map[string]interface{})
+	if !validateExpression(w, filter) {
+		return
+	}
+	var groupBy []map[string]interface{}
+	groups, _ := params["GroupBy"].([]interface{})
+	for _, g := range groups {
+		group, _ := g.(map[string]interface{})
+		groupType, key := h.GetString(group, "Type"), h.GetString(group, "Key")
+		if _, known := dimensions[key]; groupType == "DIMENSION" && !known || groupType != "DIMENSION" && groupType != "TAG" || key == "" {
+			h.WriteJSONError(w, "ValidationException", "Cannot group by "+groupType+" "+key+".", http.StatusBadRequest)
+			return
+		}
+		groupBy = append(groupBy, group)
+	}
+	if len(groupBy) > 2 {
+		h.WriteJSONError(w, "ValidationException", "GroupBy accepts at most two groups.", http.StatusBadRequest)
+		return
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	today := s.today()
+	results := []map[string]interface{}{}
+	for _, bucket := range splitPeriod(p, granularity) {
+		costs := s.matching(bucket, filter)
+		result := map[string]interface{}{
+			"TimePeriod": bucket.toMap(),
+			"Total":      map[string]interface{}{},
+			"Groups":     []map[string]interface{}{},
+			"Estimated":  bucket.end.After(today),
+		}
+		if len(groupBy) == 0 {
+			result["Total"] = metricsMap(costs, metrics)
+		} else {
+			grouped := map[string][]*Cost{}
+			keysOf := map[string][]string{}
+			for _, c := range costs {
+				keys := make([]string, len(groupBy))
+				for i, g := range groupBy {
+					keys[i] = groupKey(c, g)
+				}
+				id := strings.Join(keys, "\x00")
+				grouped[id] = append(grouped[id], c)
+				keysOf[id] = keys
+			}
+			ids := make([]string, 0, len(grouped))
+			for id := range grouped {
+				ids = append(ids, id)
+			}
+			sort.Strings(ids)
+			groups := make([]map[string]interface{}, 0, len(ids))
+			for _, id := range ids {
+				groups = append(groups, map[string]interface{}{
+					"Keys":    keysOf[id],
+					"Metrics": metricsMap(grouped[id], metrics),
+				})
+			}
+			result["Groups"] = groups
+		}
+		results = append(results, result)
+	}
+
+	definitions := make([]map[string]interface{}, 0, len(groupBy))
+	for _, g := range groupBy {
+		definitions = append(definitions, map[string]interface{}{
+			"Type": h.GetString(g, "Type"),
+			"Key":  h.GetString(g, "Key"),
+		})
+	}
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"GroupDefinitions":         definitions,
+		"ResultsByTime":            results,
+		"DimensionValueAttributes": []interface{}{},
+	})
+}
+
+// forecastWindow is how much history forecasts are based on.
+const forecastWindow = 30
+
+func (s *Service) getCostForecast(w http.ResponseWriter, params map[string]interface{}) {
+	p, ok := parsePeriod(w, params)
+	if !ok {
+		return
+	}
+	granularity := h.GetString(params, "Granularity")
+	if granularity != "DAILY" && granularity != "MONTHLY" {
+		h.WriteJSONError(w, "ValidationException", "Granularity must be DAILY or MONTHLY.", http.StatusBadRequest)
+		return
+	}
+	metric, ok := forecastMetrics[h.GetString(params, "Metric")]
+	if !ok {
+		h.WriteJSONError(w, "ValidationException", "Metric must be one of AMORTIZED_COST, BLENDED_COST, NET_AMORTIZED_COST, NET_UNBLENDED_COST, or UNBLENDED_COST.", http.StatusBadRequest)
+		return
+	}
+	filter, _ := params["Filter"].(map[string]interface{})
+	if !validateExpression(w, filter) {
+		return
+	}
+	level := h.GetInt(params, "PredictionIntervalLevel", 0)
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	today := s.today()
+	if p.start.Before(today) {
+		h.WriteJSONError(w, "ValidationException", "Start date must be equal to or later than the current date.", http.StatusBadRequest)
+		return
+	}
+	history := s.matching(period{today.AddDate(0, 0, -forecastWindow), today}, filter)
+	if len(history) == 0 {
+		h.WriteJSONError(w, "DataUnavailableException", "Insufficient amount of historical data to generate forecast.", http.StatusBadRequest)
+		return
+	}
+	daily := 0.0
+	for _, c := range history {
+		daily += metricValue(c, metric)
+	}
+	daily /= forecastWindow
+
+	// The prediction interval widens with the confidence level asked for.
+	spread := 0.0
+	if level > 0 {
+		spread = float64(level) / 500
+	}
+	total := 0.0
+	results := []map[string]interface{}{}
+	for _, bucket := range splitPeriod(p, granularity) {
+		mean := daily * bucket.end.Sub(bucket.start).Hours() / 24
+		total += mean
+		result := map[string]interface{}{
+			"TimePeriod": bucket.toMap(),
+			"MeanValue":  formatAmount(mean),
+		}
+		if level > 0 {
+			result["PredictionIntervalLowerBound"] = formatAmount(mean * (1 - spread))
+			result["PredictionIntervalUpperBound"] = formatAmount(mean * (1 + spread))
+		}
+		results = append(results, result)
+	}
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"Total": map[string]interface{}{
+			"Amount": formatAmount(total),
+			"Unit":   "USD",
+		},
+		"ForecastResultsByTime": results,
+	})
+}
+
+func (s *Service) getDimensionValues(w http.ResponseWriter, params map[string]interface{}) {
+	p, ok := parsePeriod(w, params)
+	if !ok {
+		return
+	}
+	dimension := h.GetString(params, "Dimension")
+	get, known := dimensions[dimension]
+	if !known {
+		h.WriteJSONError(w, "ValidationException", "Dimension "+dimension+" is not supported.", http.StatusBadRequest)
+		return
+	}
+	filter, _ := params["Filter"].(map[string]interface{})
+	if !validateExpression(w, filter) {
+		return
+	}
+	search := strings.ToLower(h.GetString(params, "SearchString"))
+
+	s.mu.RLock()
+	seen := map[string]bool{}
+	var values []string
+	for _, c := range s.matching(p, filter) {
+		value := get(c)
+		if value == "" || seen[value] || !strings.Contains(strings.ToLower(value), search) {
+			continue
+		}
+		seen[value] = true
+		values = append(values, value)
+	}
+	s.mu.RUnlock()
+	sort.Strings(values)
+
+	page, next, err := paginate.Page(values, func(i int) string { return values[i] }, h.GetString(params, "NextPageToken"), h.GetInt(params, "MaxResults", 0), 1000)
+	if err != nil {
+		h.WriteJSONError(w, "InvalidNextTokenException", "Invalid NextPageToken", http.StatusBadRequest)
+		return
+	}
+	out := make([]map[string]interface{}, 0, len(page))
+	for _, v := range page {
+		out = append(out, map[string]interface{}{
+			"Value":      v,
+			"Attributes": map[string]string{},
+		})
+	}
+	resp := map[string]interface{}{
+		"DimensionValues": out,
+		"ReturnSize":      len(out),
+		"TotalSize":       len(values),
+	}
+	if next != "" {
+		resp["NextPageToken"] = next
+	}
+	h.WriteJSON(w, http.StatusOK, resp)
+}
@@ -0,0 +1,422 @@
+// Package datasync provides a mock implementation of AWS DataSync.
+//
+// Supported actions:
+//   - CreateLocationS3, DescribeLocationS3, CreateLocationEfs,
+//     DescribeLocationEfs, ListLocations, DeleteLocation
+//   - CreateTask, DescribeTask, ListTasks, UpdateTask, DeleteTask
+//   - StartTaskExecution, DescribeTaskExecution, ListTaskExecutions,
+//     CancelTaskExecution
+//   - TagResource, UntagResource, ListTagsForResource
+//
+// Locations name a bucket in the S3 mock or a file system in the EFS mock,
+// which must exist, and a subdirectory within it. Task executions really
+// copy: an execution is LAUNCHING, PREPARING, TRANSFERRING, and VERIFYING,
+// advancing one status each time the lifecycle transition delay passes, and
+// then copies the files under the source location to the destination,
+// honoring the task's include and exclude filters and its OverwriteMode,
+// TransferMode, and PreserveDeletedFiles options, and ends in SUCCESS, or in
+// ERROR if a location can no longer be read or written. Completion is seen
+// at once without a transition delay, and otherwise when the mock clock
+// advances past it or the execution or its task is described.
+package datasync
+
+import (
+	"fmt"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/riyanimam/goto/internal/arn"
+	"github.com/riyanimam/goto/internal/clock"
+	"github.com/riyanimam/goto/internal/lifecycle"
+	h "github.com/riyanimam/goto/internal/mockhelpers"
+	"github.com/riyanimam/goto/internal/paginate"
+	"github.com/riyanimam/goto/internal/tags"
+)
+
+// Service implements the DataSync mock.
+type Service struct {
+	mu         sync.RWMutex
+	locations  map[string]*location
+	tasks      map[string]*task
+	executions map[string]*execution
+
+	objects     h.ObjectStore
+	files       h.FileStore
+	resolve     h.Resolver
+	tags        *tags.Store
+	clock       *clock.Clock
+	transitions *lifecycle.Transitions
+}
+
+type location struct {
+	arn          string
+	kind         string // S3 or EFS
+	bucket       string
+	fileSystemID string
+	subdirectory string // absolute, with a trailing slash
+	created      time.Time
+	// config is the location's type-specific settings as given, reported
+	// back by DescribeLocationS3 and DescribeLocationEfs.
+	config map[string]interface{}
+}
+
+// New creates a new DataSync mock service.
+func New() *Service {
+	return &Service{
+		locations:  make(map[string]*location),
+		tasks:      make(map[string]*task),
+		executions: make(map[string]*execution),
+		tags:       tags.New(),
+	}
+}
+
+// Name returns the service identifier.
+func (s *Service) Name() string { return "datasync" }
+
+// Handler returns the HTTP handler for DataSync requests.
+func (s *Service) Handler() http.Handler {
+	return h.JSONRouter{
+		"CreateLocationS3":      s.createLocationS3,
+		"DescribeLocationS3":    s.describeLocation("S3"),
+		"CreateLocationEfs":     s.createLocationEfs,
+		"DescribeLocationEfs":   s.describeLocation("EFS"),
+		"ListLocations":         s.listLocations,
+		"DeleteLocation":        s.deleteLocation,
+		"CreateTask":            s.createTask,
+		"DescribeTask":          s.describeTask,
+		"ListTasks":             s.listTasks,
+		"UpdateTask":            s.updateTask,
+		"DeleteTask":            s.deleteTask,
+		"StartTaskExecution":    s.startTaskExecution,
+		"DescribeTaskExecution": s.describeTaskExecution,
+		"ListTaskExecutions":    s.listTaskExecutions,
+		"CancelTaskExecution":   s.cancelTaskExecution,
+		"TagResource":           s.tagResource,
+		"UntagResource":         s.untagResource,
+		"ListTagsForResource":   s.listTagsForResource,
+	}
+}
+
+// Reset clears all state.
+func (s *Service) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.locations = make(map[string]*location)
+	s.tasks = make(map[string]*task)
+	s.executions = make(map[string]*execution)
+	s.tags.DeleteService("datasync")
+}
+
+// SetObjectStore sets the S3 buckets that S3 locations read and write.
+func (s *Service) SetObjectStore(store h.ObjectStore) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.objects = store
+}
+
+// SetFileStore sets the EFS file systems that EFS locations read and write.
+func (s *Service) SetFileStore(store h.FileStore) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.files = store
+}
+
+// SetResolver sets the function used to check bucket access roles.
+func (s *Service) SetResolver(r h.Resolver) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.resolve = r
+}
+
+// SetTagStore sets the registry resource tags are recorded in.
+func (s *Service) SetTagStore(store *tags.Store) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tags = store
+}
+
+// SetClock attaches the mock clock, and completes task executions whose
+// transition the clock advances past.
+func (s *Service) SetClock(c *clock.Clock) {
+	s.mu.Lock()
+	s.clock = c
+	s.mu.Unlock()
+	c.OnAdvance(func(_, _ time.Time) {
+		s.mu.Lock()
+		s.settle()
+		s.mu.Unlock()
+	})
+}
+
+// SetTransitions sets how long task executions take to pass through each
+// status.
+func (s *Service) SetTransitions(t *lifecycle.Transitions) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.transitions = t
+}
+
+func (s *Service) now() time.Time {
+	if s.clock != nil {
+		return s.clock.Now()
+	}
+	return time.Now().UTC()
+}
+
+// writeError writes an InvalidRequestException, the error DataSync reports
+// for every client error.
+func writeError(w http.ResponseWriter, message string) {
+	h.WriteJSONError(w, "InvalidRequestException", message, http.StatusBadRequest)
+}
+
+func newArn(resource string) string {
+	return fmt.Sprintf("arn:aws:datasync:us-east-1:%s:%s", h.DefaultAccountID, resource)
+}
+
+// cleanSubdirectory returns dir as an absolute path with a trailing slash.
+func cleanSubdirectory(dir string) string {
+	return strings.TrimSuffix(path.Clean("/"+dir), "/") + "/"
+}
+
+func (l *location) uri() string {
+	if l.kind == "S3" {
+		return "s3://" + l.bucket + l.subdirectory
+	}
+	return "efs://us-east-1." + l.fileSystemID + l.subdirectory
+}
+
+func (s *Service) createLocationS3(w http.ResponseWriter, params map[string]interface{}) {
+	a, err := arn.Parse(h.GetString(params, "S3BucketArn"))
+	if err != nil || a.Service != "s3" || strings.Contains(a.Resource, "/") {
+		writeError(w, "S3BucketArn must be the ARN of an S3 bucket.")
+		return
+	}
+	s3Config, _ := params["S3Config"].(map[string]interface{})
+	role := h.GetString(s3Config, "BucketAccessRoleArn")
+	if role == "" {
+		writeError(w, "S3Config.BucketAccessRoleArn is required.")
+		return
+	}
+	storageClass := h.GetString(params, "S3StorageClass")
+	if storageClass == "" {
+		storageClass = "STANDARD"
+	}
+
+	s.mu.RLock()
+	objects, resolve := s.objects, s.resolve
+	s.mu.RUnlock()
+	if objects != nil {
+		if _, err := objects.ListObjects(a.Resource, ""); err != nil {
+			writeError(w, "Unable to access bucket "+a.Resource+": the bucket does not exist.")
+			return
+		}
+	}
+	if resolve != nil {
+		if err := resolve(role); err != nil {
+			writeError(w, "Unable to assume role "+role+".")
+			return
+		}
+	}
+
+	l := &location{
+		kind:         "S3",
+		bucket:       a.Resource,
+		subdirectory: cleanSubdirectory(h.GetString(params, "Subdirectory")),
+		config: map[string]interface{}{
+			"S3Config":       map[string]interface{}{"BucketAccessRoleArn": role},
+			"S3StorageClass": storageClass,
+		},
+	}
+	s.addLocation(w, l, params)
+}
+
+func (s *Service) createLocationEfs(w http.ResponseWriter, params map[string]interface{}) {
+	a, err := arn.Parse(h.GetString(params, "EfsFilesystemArn"))
+	fileSystemID, ok := strings.CutPrefix(a.Resource, "file-system/")
+	if err != nil || a.Service != "elasticfilesystem" || !ok {
+		writeError(w, "EfsFilesystemArn must be the ARN of an EFS file system.")
+		return
+	}
+	ec2Config, _ := params["Ec2Config"].(map[string]interface{})
+	if h.GetString(ec2Config, "SubnetArn") == "" {
+		writeError(w, "Ec2Config.SubnetArn is required.")
+		return
+	}
+	if groups, _ := ec2Config["SecurityGroupArns"].([]interface{}); len(groups) == 0 || len(groups) > 5 {
+		writeError(w, "Ec2Config.SecurityGroupArns requires between 1 and 5 security groups.")
+		return
+	}
+	encryption := h.GetString(params, "InTransitEncryption")
+	if encryption == "" {
+		encryption = "NONE"
+	}
+
+	s.mu.RLock()
+	files := s.files
+	s.mu.RUnlock()
+	if files != nil {
+		if _, err := files.ListFiles(fileSystemID, "/"); err != nil {
+			writeError(w, "Unable to mount file system "+fileSystemID+": the file system does not exist.")
+			return
+		}
+	}
+
+	config := map[string]interface{}{
+		"Ec2Config":           ec2Config,
+		"InTransitEncryption": encryption,
+	}
+	if v := h.GetString(params, "AccessPointArn"); v != "" {
+		config["AccessPointArn"] = v
+	}
+	l := &location{
+		kind:         "EFS",
+		fileSystemID: fileSystemID,
+		subdirectory: cleanSubdirectory(h.GetString(params, "Subdirectory")),
+		config:       config,
+	}
+	s.addLocation(w, l, params)
+}
+
+// addLocation records a new location and writes its ARN.
+func (s *Service) addLocation(w http.ResponseWriter, l *location, params map[string]interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	l.arn = newArn("location/loc-" + h.RandomHex(17))
+	l.created = s.now()
+	s.locations[l.arn] = l
+	s.tags.Tag(l.arn, tags.FromList(params["Tags"], "Key", "Value"))
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{"LocationArn": l.arn})
+}
+
+// lookupLocation returns the location, or writes an error. The caller must
+// hold s.mu.
+func (s *Service) lookupLocation(w http.ResponseWriter, arn string) (*location, bool) {
+	l, exists := s.locations[arn]
+	if !exists {
+		writeError(w, "Location "+arn+" not found.")
+	}
+	return l, exists
+}
+
+// describeLocation returns the handler describing locations of the kind.
+func (s *Service) describeLocation(kind string) h.JSONHandler {
+	return func(w http.ResponseWriter, params map[string]interface{}) {
+		s.mu.RLock()
+		defer s.mu.RUnlock()
+		l, ok := s.lookupLocation(w, h.GetString(params, "LocationArn"))
+		if !ok {
+			return
+		}
+		if l.kind != kind {
+			writeError(w, "Location "+l.arn+" is not an "+kind+" location.")
+			return
+		}
+		resp := map[string]interface{}{
+			"LocationArn":  l.arn,
+			"LocationUri":  l.uri(),
+			"CreationTime": float64(l.created.Unix()),
+		}
+		for k, v := range l.config {
+			resp[k] = v
+		}
+		h.WriteJSON(w, http.StatusOK, resp)
+	}
+}
+
+func (s *Service) listLocations(w http.ResponseWriter, params map[string]interface{}) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	out := make([]map[string]interface{}, 0, len(s.locations))
+	for _, arn := range sortedKeys(s.locations) {
+		l := s.locations[arn]
+		out = append(out, map[string]interface{}{"LocationArn": l.arn, "LocationUri": l.uri()})
+	}
+	writePage(w, out, params, "Locations", "LocationArn")
+}
+
+func (s *Service) deleteLocation(w http.ResponseWriter, params map[string]interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	l, ok := s.lookupLocation(w, h.GetString(params, "LocationArn"))
+	if !ok {
+		return
+	}
+	for _, t := range s.tasks {
+		if t.source == l.arn || t.destination == l.arn {
+			writeError(w, "Location "+l.arn+" is used by task "+t.arn+".")
+			return
+		}
+	}
+	delete(s.locations, l.arn)
+	s.tags.Delete(l.arn)
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{})
+}
+
+func sortedKeys[T any](m map[string]T) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// writePage writes a page of items, which are sorted by their field named
+// field, under key, with MaxResults and NextToken from params.
+func writePage(w http.ResponseWriter, items []map[string]interface{}, params map[string]interface{}, key, field string) {
+	page, next, err := paginate.Page(items, func(i int) string { return items[i][field].(string) }, h.GetString(params, "NextToken"), h.GetInt(params, "MaxResults", 100), 100)
+	if err != nil {
+		writeError(w, "Invalid NextToken.")
+		return
+	}
+	resp := map[string]interface{}{key: page}
+	if next != "" {
+		resp["NextToken"] = next
+	}
+	h.WriteJSON(w, http.StatusOK, resp)
+}
+
+// taggable reports whether arn names a location, task, or task execution.
+// The caller must hold s.mu.
+func (s *Service) taggable(arn string) bool {
+	return s.locations[arn] != nil || s.tasks[arn] != nil || s.executions[arn] != nil
+}
+
+func (s *Service) tagResource(w http.ResponseWriter, params map[string]interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	arn := h.GetString(params, "ResourceArn")
+	if !s.taggable(arn) {
+		writeError(w, "Resource "+arn+" not found.")
+		return
+	}
+	s.tags.Tag(arn, tags.FromList(params["Tags"], "Key", "Value"))
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{})
+}
+
+func (s *Service) untagResource(w http.ResponseWriter, params map[string]interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	arn := h.GetString(params, "ResourceArn")
+	if !s.taggable(arn) {
+		writeError(w, "Resource "+arn+" not found.")
+		return
+	}
+	s.tags.Untag(arn, tags.Keys(params["Keys"]))
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{})
+}
+
+func (s *Service) listTagsForResource(w http.ResponseWriter, params map[string]interface{}) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	arn := h.GetString(params, "ResourceArn")
+	if !s.taggable(arn) {
+		writeError(w, "Resource "+arn+" not found.")
+		return
+	}
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{"Tags": tags.ToList(s.tags.Get(arn), "Key", "Value")})
+}
@@ -0,0 +1,628 @@
+package datasync
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/riyanimam/goto/internal/lifecycle"
+	h "github.com/riyanimam/goto/internal/mockhelpers"
+	"github.com/riyanimam/goto/internal/tags"
+)
+
+// defaultOptions are the task options DataSync applies unless told
+// otherwise.
+var defaultOptions = map[string]interface{}{
+	"VerifyMode":           "POINT_IN_TIME_CONSISTENT",
+	"OverwriteMode":        "ALWAYS",
+	"Atime":                "BEST_EFFORT",
+	"Mtime":                "PRESERVE",
+	"Uid":                  "INT_VALUE",
+	"Gid":                  "INT_VALUE",
+	"PreserveDeletedFiles": "PRESERVE",
+	"PreserveDevices":      "NONE",
+	"PosixPermissions":     "PRESERVE",
+	"BytesPerSecond":       -1,
+	"TaskQueueing":         "ENABLED",
+	"LogLevel":             "OFF",
+	"TransferMode":         "CHANGED",
+	"ObjectTags":           "PRESERVE",
+}
+
+// optionValues are the values allowed for the options that change what an
+// execution copies.
+var optionValues = map[string][]string{
+	"OverwriteMode":        {"ALWAYS", "NEVER"},
+	"TransferMode":         {"CHANGED", "ALL"},
+	"PreserveDeletedFiles": {"PRESERVE", "REMOVE"},
+	"VerifyMode":           {"POINT_IN_TIME_CONSISTENT", "ONLY_FILES_TRANSFERRED", "NONE"},
+}
+
+type task struct {
+	arn         string
+	name        string
+	source      string // location ARNs
+	destination string
+	options     map[string]interface{}
+	includes    filter
+	excludes    filter
+	logGroupArn string
+	created     time.Time
+}
+
+// filter is a task's include or exclude filter: SIMPLE_PATTERN values
+// separated by "|", each matching a file or, naming a folder, everything
+// in it, with "*" matching any run of characters.
+type filter struct {
+	raw      []interface{} // as given, for reporting back
+	patterns []*regexp.Regexp
+}
+
+type execution struct {
+	arn      string
+	task     *task
+	options  map[string]interface{}
+	includes filter
+	excludes filter
+	started  time.Time
+	finished time.Time
+	ready    lifecycle.Transition
+	done     bool   // the execution has copied, or been cancelled
+	status   string // SUCCESS or ERROR, once done
+	// phase is the phase an ERROR happened in, PREPARING or TRANSFERRING.
+	phase       string
+	errorCode   string
+	errorDetail string
+	counts      counts
+}
+
+// counts are an execution's progress counters.
+type counts struct {
+	estimatedFiles   int
+	estimatedBytes   int
+	filesTransferred int
+	bytesWritten     int
+	filesDeleted     int
+	filesSkipped     int
+	filesVerified    int
+}
+
+// errNoStore is returned when the service holding a location's files is
+// not running.
+var errNoStore = errors.New("the location's storage service is not available")
+
+// parseFilter reads an Includes or Excludes list, returning a message
+// describing the first problem if it is invalid.
+func parseFilter(v interface{}) (filter, string) {
+	raw, _ := v.([]interface{})
+	if len(raw) > 1 {
+		return filter{}, "Only one filter rule is supported; separate patterns with \"|\"."
+	}
+	f := filter{raw: raw}
+	for _, item := range raw {
+		m, _ := item.(map[string]interface{})
+		if t := h.GetString(m, "FilterType"); t != "SIMPLE_PATTERN" {
+			return filter{}, "FilterType must be SIMPLE_PATTERN."
+		}
+		for _, p := range strings.Split(h.GetString(m, "Value"), "|") {
+			if p = strings.TrimSuffix(p, "/"); p == "" {
+				continue
+			}
+			if !strings.HasPrefix(p, "/") && !strings.HasPrefix(p, "*") {
+				p = "/" + p
+			}
+			expr := strings.ReplaceAll(regexp.QuoteMeta(p), `\*`, ".*")
+			f.patterns = append(f.patterns, regexp.MustCompile("^"+expr+"(/.*)?$"))
+		}
+	}
+	return f, ""
+}
+
+// matches reports whether the filter matches the file at rel, a path
+// relative to the location's subdirectory.
+func (f filter) matches(rel string) bool {
+	for _, p := range f.patterns {
+		if p.MatchString("/" + rel) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseOptions returns base with the options in v applied, or a message
+// describing the first invalid option.
+func parseOptions(base map[string]interface{}, v interface{}) (map[string]interface{}, string) {
+	given, _ := v.(map[string]interface{})
+	options := make(map[string]interface{}, len(base))
+	for k, v := range base {
+		options[k] = v
+	}
+	for k, v := range given {
+		if allowed, ok := optionValues[k]; ok {
+			s, _ := v.(string)
+			valid := false
+			for _, a := range allowed {
+				valid = valid || s == a
+			}
+			if !valid {
+				return nil, k + " must be one of " + strings.Join(allowed, ", ") + "."
+			}
+		}
+		options[k] = v
+	}
+	return options, ""
+}
+
+func (s *Service) createTask(w http.ResponseWriter, params map[string]interface{}) {
+	options, msg := parseOptions(defaultOptions, params["Options"])
+	if msg != "" {
+		writeError(w, msg)
+		return
+	}
+	includes, msg := parseFilter(params["Includes"])
+	if msg != "" {
+		writeError(w, msg)
+		return
+	}
+	excludes, msg := parseFilter(params["Excludes"])
+	if msg != "" {
+		writeError(w, msg)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	source, ok := s.lookupLocation(w, h.GetString(params, "SourceLocationArn"))
+	if !ok {
+		return
+	}
+	destination, ok := s.lookupLocation(w, h.GetString(params, "DestinationLocationArn"))
+	if !ok {
+		return
+	}
+	if source == destination {
+		writeError(w, "The source and destination locations must differ.")
+		return
+	}
+	t := &task{
+		arn:         newArn("task/task-" + h.RandomHex(17)),
+		name:        h.GetString(params, "Name"),
+		source:      source.arn,
+		destination: destination.arn,
+		options:     options,
+		includes:    includes,
+		excludes:    excludes,
+		logGroupArn: h.GetString(params, "CloudWatchLogGroupArn"),
+		created:     s.now(),
+	}
+	s.tasks[t.arn] = t
+	s.tags.Tag(t.arn, tags.FromList(params["Tags"], "Key", "Value"))
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{"TaskArn": t.arn})
+}
+
+// lookupTask returns the task, or writes an error. The caller must hold
+// s.mu.
+func (s *Service) lookupTask(w http.ResponseWriter, arn string) (*task, bool) {
+	t, exists := s.tasks[arn]
+	if !exists {
+		writeError(w, "Task "+arn+" not found.")
+	}
+	return t, exists
+}
+
+// running returns the task's execution that has not finished, or nil. The
+// caller must hold s.mu.
+func (s *Service) running(t *task) *execution {
+	for _, e := range s.executions {
+		if e.task == t && !e.done {
+			return e
+		}
+	}
+	return nil
+}
+
+// taskResp returns the task as DescribeTask reports it. The caller must
+// hold s.mu.
+func (s *Service) taskResp(t *task) map[string]interface{} {
+	resp := map[string]interface{}{
+		"TaskArn":                t.arn,
+		"Name":                   t.name,
+		"Status":                 "AVAILABLE",
+		"SourceLocationArn":      t.source,
+		"DestinationLocationArn": t.destination,
+		"Options":                t.options,
+		"Includes":               filterResp(t.includes),
+		"Excludes":               filterResp(t.excludes),
+		"CreationTime":           float64(t.created.Unix()),
+	}
+	if t.logGroupArn != "" {
+		resp["CloudWatchLogGroupArn"] = t.logGroupArn
+	}
+	if e := s.running(t); e != nil {
+		resp["Status"] = "RUNNING"
+		resp["CurrentTaskExecutionArn"] = e.arn
+	}
+	return resp
+}
+
+func filterResp(f filter) []interface{} {
+	if f.raw == nil {
+		return []interface{}{}
+	}
+	return f.raw
+}
+
+func (s *Service) describeTask(w http.ResponseWriter, params map[string]interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.settle()
+	t, ok := s.lookupTask(w, h.GetString(params, "TaskArn"))
+	if !ok {
+		return
+	}
+	h.WriteJSON(w, http.StatusOK, s.taskResp(t))
+}
+
+func (s *Service) listTasks(w http.ResponseWriter, params map[string]interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.settle()
+	out := make([]map[string]interface{}, 0, len(s.tasks))
+	for _, arn := range sortedKeys(s.tasks) {
+		resp := s.taskResp(s.tasks[arn])
+		out = append(out, map[string]interface{}{"TaskArn": arn, "Name": resp["Name"], "Status": resp["Status"]})
+	}
+	writePage(w, out, params, "Tasks", "TaskArn")
+}
+
+func (s *Service) updateTask(w http.ResponseWriter, params map[string]interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t, ok := s.lookupTask(w, h.GetString(params, "TaskArn"))
+	if !ok {
+		return
+	}
+	options, msg := parseOptions(t.options, params["Options"])
+	if msg != "" {
+		writeError(w, msg)
+		return
+	}
+	includes, excludes := t.includes, t.excludes
+	if _, ok := params["Includes"]; ok {
+		if includes, msg = parseFilter(params["Includes"]); msg != "" {
+			writeError(w, msg)
+			return
+		}
+	}
+	if _, ok := params["Excludes"]; ok {
+		if excludes, msg = parseFilter(params["Excludes"]); msg != "" {
+			writeError(w, msg)
+			return
+		}
+	}
+	t.options, t.includes, t.excludes = options, includes, excludes
+	if v := h.GetString(params, "Name"); v != "" {
+		t.name = v
+	}
+	if v, ok := params["CloudWatchLogGroupArn"].(string); ok {
+		t.logGroupArn = v
+	}
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{})
+}
+
+func (s *Service) deleteTask(w http.ResponseWriter, params map[string]interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.settle()
+	t, ok := s.lookupTask(w, h.GetString(params, "TaskArn"))
+	if !ok {
+		return
+	}
+	if e := s.running(t); e != nil {
+		writeError(w, "Task "+t.arn+" has a running execution, "+e.arn+". Cancel it first.")
+		return
+	}
+	for arn, e := range s.executions {
+		if e.task == t {
+			delete(s.executions, arn)
+			s.tags.Delete(arn)
+		}
+	}
+	delete(s.tasks, t.arn)
+	s.tags.Delete(t.arn)
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{})
+}
+
+func (s *Service) startTaskExecution(w http.ResponseWriter, params map[string]interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.settle()
+	t, ok := s.lookupTask(w, h.GetString(params, "TaskArn"))
+	if !ok {
+		return
+	}
+	if e := s.running(t); e != nil {
+		writeError(w, "Task "+t.arn+" already has a running execution, "+e.arn+".")
+		return
+	}
+	options, msg := parseOptions(t.options, params["OverrideOptions"])
+	if msg != "" {
+		writeError(w, msg)
+		return
+	}
+	e := &execution{
+		arn:      t.arn + "/execution/exec-" + h.RandomHex(17),
+		task:     t,
+		options:  options,
+		includes: t.includes,
+		excludes: t.excludes,
+		started:  s.now(),
+		ready:    s.transitions.Begin(),
+	}
+	if _, ok := params["Includes"]; ok {
+		if e.includes, msg = parseFilter(params["Includes"]); msg != "" {
+			writeError(w, msg)
+			return
+		}
+	}
+	if _, ok := params["Excludes"]; ok {
+		if e.excludes, msg = parseFilter(params["Excludes"]); msg != "" {
+			writeError(w, msg)
+			return
+		}
+	}
+	s.executions[e.arn] = e
+	s.tags.Tag(e.arn, tags.FromList(params["Tags"], "Key", "Value"))
+	s.settle()
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{"TaskExecutionArn": e.arn})
+}
+
+// status returns the execution's current status. The caller must hold s.mu.
+func (s *Service) status(e *execution) string {
+	if e.done {
+		return e.status
+	}
+	return s.transitions.Stage(e.ready, "LAUNCHING", "PREPARING", "TRANSFERRING", "VERIFYING", "SUCCESS")
+}
+
+// settle runs the copies of the executions whose transition has completed
+// since it last ran. The caller must hold s.mu.
+func (s *Service) settle() {
+	for _, arn := range sortedKeys(s.executions) {
+		e := s.executions[arn]
+		if e.done || s.status(e) != "SUCCESS" {
+			continue
+		}
+		e.done = true
+		e.finished = s.now()
+		e.status = "SUCCESS"
+		if phase, code, detail := s.copy(e); phase != "" {
+			e.status, e.phase, e.errorCode, e.errorDetail = "ERROR", phase, code, detail
+		}
+	}
+}
+
+// copy copies the files of the execution's source location to its
+// destination and counts them, returning the phase, error code, and detail
+// of the first failure. The caller must hold s.mu.
+func (s *Service) copy(e *execution) (phase, code, detail string) {
+	source, destination := s.locations[e.task.source], s.locations[e.task.destination]
+	if source == nil || destination == nil {
+		return "PREPARING", "LocationNotFound", "The task's source or destination location was deleted."
+	}
+	src, err := s.list(source)
+	if err != nil {
+		return "PREPARING", "SourceUnavailable", "Unable to list " + source.uri() + ": " + err.Error()
+	}
+	dst, err := s.list(destination)
+	if err != nil {
+		return "PREPARING", "DestinationUnavailable", "Unable to list " + destination.uri() + ": " + err.Error()
+	}
+	included := func(rel string) bool {
+		return (len(e.includes.patterns) == 0 || e.includes.matches(rel)) && !e.excludes.matches(rel)
+	}
+
+	c := &e.counts
+	for _, rel := range src {
+		if !included(rel) {
+			continue
+		}
+		data, err := s.read(source, rel)
+		if err != nil {
+			return "TRANSFERRING", "SourceUnavailable", "Unable to read " + source.uri() + rel + ": " + err.Error()
+		}
+		if contains(dst, rel) {
+			if e.options["OverwriteMode"] == "NEVER" {
+				c.filesSkipped++
+				continue
+			}
+			if e.options["TransferMode"] == "CHANGED" {
+				if existing, err := s.read(destination, rel); err == nil && bytes.Equal(existing, data) {
+					c.filesSkipped++
+					continue
+				}
+			}
+		}
+		c.estimatedFiles++
+		c.estimatedBytes += len(data)
+		if err := s.write(destination, rel, data); err != nil {
+			return "TRANSFERRING", "DestinationUnavailable", "Unable to write " + destination.uri() + rel + ": " + err.Error()
+		}
+		c.filesTransferred++
+		c.bytesWritten += len(data)
+	}
+	if e.options["PreserveDeletedFiles"] == "REMOVE" {
+		for _, rel := range dst {
+			if included(rel) && !contains(src, rel) {
+				if err := s.remove(destination, rel); err != nil {
+					return "TRANSFERRING", "DestinationUnavailable", "Unable to delete " + destination.uri() + rel + ": " + err.Error()
+				}
+				c.filesDeleted++
+			}
+		}
+	}
+	switch e.options["VerifyMode"] {
+	case "POINT_IN_TIME_CONSISTENT":
+		c.filesVerified = c.filesTransferred + c.filesSkipped
+	case "ONLY_FILES_TRANSFERRED":
+		c.filesVerified = c.filesTransferred
+	}
+	return "", "", ""
+}
+
+func contains(sorted []string, s string) bool {
+	i := sort.SearchStrings(sorted, s)
+	return i < len(sorted) && sorted[i] == s
+}
+
+// list returns the sorted paths of the files under the location's
+// subdirectory, relative to it. The caller must hold s.mu.
+func (s *Service) list(l *location) ([]string, error) {
+	var paths []string
+	var err error
+	prefix := l.subdirectory
+	if l.kind == "S3" {
+		if s.objects == nil {
+			return nil, errNoStore
+		}
+		prefix = strings.TrimPrefix(prefix, "/")
+		paths, err = s.objects.ListObjects(l.bucket, prefix)
+	} else {
+		if s.files == nil {
+			return nil, errNoStore
+		}
+		paths, err = s.files.ListFiles(l.fileSystemID, prefix)
+	}
+	if err != nil {
+		return nil, err
+	}
+	rels := make([]string, 0, len(paths))
+	for _, p := range paths {
+		// Zero-byte keys ending in a slash are S3 folder markers.
+		if rel := strings.TrimPrefix(p, prefix); rel != "" && !strings.HasSuffix(rel, "/") {
+			rels = append(rels, rel)
+		}
+	}
+	sort.Strings(rels)
+	return rels, nil
+}
+
+func (s *Service) read(l *location, rel string) ([]byte, error) {
+	if l.kind == "S3" {
+		return s.objects.GetObject(l.bucket, strings.TrimPrefix(l.subdirectory, "/")+rel)
+	}
+	return s.files.ReadFile(l.fileSystemID, l.subdirectory+rel)
+}
+
+func (s *Service) write(l *location, rel string, data []byte) error {
+	if l.kind == "S3" {
+		return s.objects.PutObject(l.bucket, strings.TrimPrefix(l.subdirectory, "/")+rel, data)
+	}
+	return s.files.WriteFile(l.fileSystemID, l.subdirectory+rel, data)
+}
+
+func (s *Service) remove(l *location, rel string) error {
+	if l.kind == "S3" {
+		return s.objects.DeleteObject(l.bucket, strings.TrimPrefix(l.subdirectory, "/")+rel)
+	}
+	return s.files.DeleteFile(l.fileSystemID, l.subdirectory+rel)
+}
+
+// lookupExecution returns the task execution, or writes an error. The
+// caller must hold s.mu.
+func (s *Service) lookupExecution(w http.ResponseWriter, arn string) (*execution, bool) {
+	e, exists := s.executions[arn]
+	if !exists {
+		writeError(w, "Task execution "+arn+" not found.")
+	}
+	return e, exists
+}
+
+func (s *Service) describeTaskExecution(w http.ResponseWriter, params map[string]interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.settle()
+	e, ok := s.lookupExecution(w, h.GetString(params, "TaskExecutionArn"))
+	if !ok {
+		return
+	}
+	status := s.status(e)
+	c := e.counts
+	resp := map[string]interface{}{
+		"TaskExecutionArn":         e.arn,
+		"Status":                   status,
+		"Options":                  e.options,
+		"Includes":                 filterResp(e.includes),
+		"Excludes":                 filterResp(e.excludes),
+		"StartTime":                float64(e.started.Unix()),
+		"EstimatedFilesToTransfer": c.estimatedFiles,
+		"EstimatedBytesToTransfer": c.estimatedBytes,
+		"FilesTransferred":         c.filesTransferred,
+		"BytesWritten":             c.bytesWritten,
+		"BytesTransferred":         c.bytesWritten,
+		"BytesCompressed":          c.bytesWritten,
+		"FilesDeleted":             c.filesDeleted,
+		"FilesSkipped":             c.filesSkipped,
+		"FilesVerified":            c.filesVerified,
+	}
+	result := map[string]interface{}{}
+	switch {
+	case status == "SUCCESS":
+		result["PrepareStatus"], result["TransferStatus"], result["VerifyStatus"] = "SUCCESS", "SUCCESS", "SUCCESS"
+	case status == "ERROR" && e.phase == "PREPARING":
+		result["PrepareStatus"] = "ERROR"
+		result["ErrorCode"], result["ErrorDetail"] = e.errorCode, e.errorDetail
+	case status == "ERROR":
+		result["PrepareStatus"], result["TransferStatus"] = "SUCCESS", "ERROR"
+		result["ErrorCode"], result["ErrorDetail"] = e.errorCode, e.errorDetail
+	case status == "TRANSFERRING" || status == "VERIFYING":
+		result["PrepareStatus"] = "SUCCESS"
+	}
+	if e.done {
+		result["TotalDuration"] = e.finished.Sub(e.started).Milliseconds()
+	}
+	resp["Result"] = result
+	h.WriteJSON(w, http.StatusOK, resp)
+}
+
+func (s *Service) listTaskExecutions(w http.ResponseWriter, params map[string]interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.settle()
+	taskArn := h.GetString(params, "TaskArn")
+	if taskArn != "" {
+		if _, ok := s.lookupTask(w, taskArn); !ok {
+			return
+		}
+	}
+	var out []map[string]interface{}
+	for _, arn := range sortedKeys(s.executions) {
+		if e := s.executions[arn]; taskArn == "" || e.task.arn == taskArn {
+			out = append(out, map[string]interface{}{"TaskExecutionArn": arn, "Status": s.status(e)})
+		}
+	}
+	writePage(w, out, params, "TaskExecutions", "TaskExecutionArn")
+}
+
+func (s *Service) cancelTaskExecution(w http.ResponseWriter, params map[string]interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.settle()
+	e, ok := s.lookupExecution(w, h.GetString(params, "TaskExecutionArn"))
+	if !ok {
+		return
+	}
+	if e.done {
+		writeError(w, "Task execution "+e.arn+" has already finished.")
+		return
+	}
+	e.done = true
+	e.finished = s.now()
+	e.status, e.phase = "ERROR", "TRANSFERRING"
+	e.errorCode, e.errorDetail = "OpCancelled", "The task execution was cancelled."
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{})
+}
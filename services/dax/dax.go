@@ -8,17 +8,17 @@
 //   - CreateSubnetGroup
 //   - DescribeSubnetGroups
 //   - DeleteSubnetGroup
+//   - TagResource
+//   - UntagResource
 package dax
 
 import (
-	"encoding/json"
 	"fmt"
-	"io"
 	"net/http"
-	"strings"
 	"sync"
 
 	h "github.com/riyanimam/goto/internal/mockhelpers"
+	"github.com/riyanimam/goto/internal/tags"
 )
 
 // Service implements the DAX mock.
@@ -26,7 +26,7 @@ type Service struct {
 	mu           sync.RWMutex
 	clusters     map[string]*cluster
 	subnetGroups map[string]*subnetGroup
-	tags         map[string][]map[string]string
+	tags         *tags.Store
 }
 
 type cluster struct {
@@ -50,7 +50,7 @@ func New() *Service {
 	return &Service{
 		clusters:     make(map[string]*cluster),
 		subnetGroups: make(map[string]*subnetGroup),
-		tags:         make(map[string][]map[string]string),
+		tags:         tags.New(),
 	}
 }
 
@@ -59,7 +59,17 @@ func (s *Service) Name() string { return "dax" }
 
 // Handler returns the HTTP handler for DAX requests.
 func (s *Service) Handler() http.Handler {
-	return http.HandlerFunc(s.handle)
+	return h.JSONRouter{
+		"CreateCluster":        s.createCluster,
+		"DescribeClusters":     s.describeClusters,
+		"DeleteCluster":        s.deleteCluster,
+		"CreateSubnetGroup":    s.createSubnetGroup,
+		"DescribeSubnetGroups": s.describeSubnetGroups,
+		"DeleteSubnetGroup":    s.deleteSubnetGroup,
+		"TagResource":          s.tagResource,
+		"UntagResource":        s.untagResource,
+		"ListTags":             s.listTags,
+	}
 }
 
 // Reset clears all state.
@@ -68,55 +78,14 @@ func (s *Service) Reset() {
 	defer s.mu.Unlock()
 	s.clusters = make(map[string]*cluster)
 	s.subnetGroups = make(map[string]*subnetGroup)
-	s.tags = make(map[string][]map[string]string)
+	s.tags.DeleteService("dax")
 }
 
-func (s *Service) handle(w http.ResponseWriter, r *http.Request) {
-	target := r.Header.Get("X-Amz-Target")
-
-	bodyBytes, err := io.ReadAll(r.Body)
-	if err != nil {
-		h.WriteJSONError(w, "InternalServerError", "could not read request body", http.StatusInternalServerError)
-		return
-	}
-
-	var params map[string]interface{}
-	if len(bodyBytes) > 0 {
-		if err := json.Unmarshal(bodyBytes, &params); err != nil {
-			h.WriteJSONError(w, "SerializationException", "could not parse request body", http.StatusBadRequest)
-			return
-		}
-	}
-	if params == nil {
-		params = make(map[string]interface{})
-	}
-
-	action := ""
-	if target != "" {
-		parts := strings.SplitN(target, ".", 2)
-		if len(parts) == 2 {
-			action = parts[1]
-		}
-	}
-
-	switch action {
-	case "CreateCluster":
-		s.createCluster(w, params)
-	case "DescribeClusters":
-		s.describeClusters(w, params)
-	case "DeleteCluster":
-		s.deleteCluster(w, params)
-	case "ListTags":
-		s.listTags(w, params)
-	case "CreateSubnetGroup":
-		s.createSubnetGroup(w, params)
-	case "DescribeSubnetGroups":
-		s.describeSubnetGroups(w, params)
-	case "DeleteSubnetGroup":
-		s.deleteSubnetGroup(w, params)
-	default:
-		h.WriteJSONError(w, "UnknownOperationException", fmt.Sprintf("action %q is not supported", action), http.StatusBadRequest)
-	}
+// SetTagStore sets the registry resource tags are recorded in.
+func (s *Service) SetTagStore(store *tags.Store) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tags = store
 }
 
 func (s *Service) createCluster(w http.ResponseWriter, params map[string]interface{}) {
@@ -146,6 +115,7 @@ func (s *Service) createCluster(w http.ResponseWriter, params map[string]interfa
 		description:       h.GetString(params, "Description"),
 	}
 	s.clusters[name] = c
+	s.tags.Tag(arn, tags.FromList(params["Tags"], "Key", "Value"))
 	s.mu.Unlock()
 
 	h.WriteJSON(w, http.StatusOK, map[string]interface{}{
@@ -193,6 +163,7 @@ func (s *Service) deleteCluster(w http.ResponseWriter, params map[string]interfa
 	c.status = "deleting"
 	resp := clusterResp(c)
 	delete(s.clusters, name)
+	s.tags.Delete(c.arn)
 	s.mu.Unlock()
 
 	h.WriteJSON(w, http.StatusOK, map[string]interface{}{
@@ -200,23 +171,60 @@ func (s *Service) deleteCluster(w http.ResponseWriter, params map[string]interfa
 	})
 }
 
-func (s *Service) listTags(w http.ResponseWriter, params map[string]interface{}) {
-	resourceName := h.GetString(params, "ResourceName")
-	if resourceName == "" {
-		h.WriteJSONError(w, "InvalidParameterValueException", "ResourceName is required", http.StatusBadRequest)
+// hasCluster reports whether arn names a cluster. Caller must hold s.mu.
+func (s *Service) hasCluster(arn string) bool {
+	for _, c := range s.clusters {
+		if c.arn == arn {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Service) tagResource(w http.ResponseWriter, params map[string]interface{}) {
+	arn := h.GetString(params, "ResourceName")
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if !s.hasCluster(arn) {
+		h.WriteJSONError(w, "InvalidARNFault", "Resource not found: "+arn, http.StatusBadRequest)
 		return
 	}
+	s.tags.Tag(arn, tags.FromList(params["Tags"], "Key", "Value"))
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"Tags": tags.ToList(s.tags.Get(arn), "Key", "Value"),
+	})
+}
+
+func (s *Service) untagResource(w http.ResponseWriter, params map[string]interface{}) {
+	arn := h.GetString(params, "ResourceName")
 
 	s.mu.RLock()
-	tags := s.tags[resourceName]
-	s.mu.RUnlock()
+	defer s.mu.RUnlock()
 
-	if tags == nil {
-		tags = []map[string]string{}
+	if !s.hasCluster(arn) {
+		h.WriteJSONError(w, "InvalidARNFault", "Resource not found: "+arn, http.StatusBadRequest)
+		return
 	}
+	s.tags.Untag(arn, tags.Keys(params["TagKeys"]))
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"Tags": tags.ToList(s.tags.Get(arn), "Key", "Value"),
+	})
+}
+
+func (s *Service) listTags(w http.ResponseWriter, params map[string]interface{}) {
+	arn := h.GetString(params, "ResourceName")
 
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if !s.hasCluster(arn) {
+		h.WriteJSONError(w, "InvalidARNFault", "Resource not found: "+arn, http.StatusBadRequest)
+		return
+	}
 	h.WriteJSON(w, http.StatusOK, map[string]interface{}{
-		"Tags": tags,
+		"Tags": tags.ToList(s.tags.Get(arn), "Key", "Value"),
 	})
 }
 
@@ -0,0 +1,329 @@
+package dynamodb
+
+import (
+	"fmt"
+	"math/rand"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/riyanimam/goto/internal/paginate"
+)
+
+// backup is an on-demand backup of a table: its definition and items as
+// they were when the backup was created.
+type backup struct {
+	arn       string
+	name      string
+	tableName string
+	tableArn  string
+	tableID   string
+	created   time.Time
+	itemCount int
+	sizeBytes int
+	saved     *savedTable
+}
+
+// resourceSuffix returns the "{13-digit ms}-{8 hex}" suffix DynamoDB gives
+// backup and export ARNs.
+func resourceSuffix(t time.Time) string {
+	return fmt.Sprintf("%013d-%08x", t.UnixMilli(), rand.Uint32())
+}
+
+func (s *Service) createBackup(w http.ResponseWriter, params map[string]interface{}) {
+	name := getString(params, "TableName")
+	backupName := getString(params, "BackupName")
+	if backupName == "" {
+		writeJSONError(w, "ValidationException", "BackupName is required", http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t, exists := s.tables[name]
+	if !exists {
+		writeJSONError(w, "TableNotFoundException", "Table not found: "+name, http.StatusBadRequest)
+		return
+	}
+
+	now := time.Now().UTC()
+	t.mu.Lock()
+	b := &backup{
+		arn:       fmt.Sprintf("%s/backup/%s", t.arn, resourceSuffix(now)),
+		name:      backupName,
+		tableName: t.name,
+		tableArn:  t.arn,
+		tableID:   t.id,
+		created:   now,
+		itemCount: t.items.Len(),
+		sizeBytes: itemsSize(t.all()),
+		saved: &savedTable{
+			def:        t.definition(),
+			items:      t.items.Snapshot(),
+			partitions: t.partitions.Snapshot(),
+		},
+	}
+	t.mu.Unlock()
+	s.backups[b.arn] = b
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"BackupDetails": b.details("AVAILABLE"),
+	})
+}
+
+// details returns the BackupDetails of b with the given status.
+func (b *backup) details(status string) map[string]interface{} {
+	return map[string]interface{}{
+		"BackupArn":              b.arn,
+		"BackupName":             b.name,
+		"BackupSizeBytes":        b.sizeBytes,
+		"BackupStatus":           status,
+		"BackupType":             "USER",
+		"BackupCreationDateTime": float64(b.created.Unix()),
+	}
+}
+
+// description returns the BackupDescription of b with the given status.
+func (b *backup) description(status string) map[string]interface{} {
+	def := b.saved.def
+	source := map[string]interface{}{
+		"TableName":             b.tableName,
+		"TableArn":              b.tableArn,
+		"TableId":               b.tableID,
+		"KeySchema":             def.keySchema,
+		"TableCreationDateTime": float64(def.created.Unix()),
+		"ItemCount":             b.itemCount,
+		"TableSizeBytes":        b.sizeBytes,
+		"BillingMode":           def.billingMode,
+		"ProvisionedThroughput": map[string]interface{}{
+			"ReadCapacityUnits":  def.provisionedRead,
+			"WriteCapacityUnits": def.provisionedWrite,
+		},
+	}
+	return map[string]interface{}{
+		"BackupDetails":             b.details(status),
+		"SourceTableDetails":        source,
+		"SourceTableFeatureDetails": map[string]interface{}{},
+	}
+}
+
+func (s *Service) describeBackup(w http.ResponseWriter, params map[string]interface{}) {
+	arn := getString(params, "BackupArn")
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	b, exists := s.backups[arn]
+	if !exists {
+		writeJSONError(w, "BackupNotFoundException", "Backup not found: "+arn, http.StatusBadRequest)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"BackupDescription": b.description("AVAILABLE"),
+	})
+}
+
+func (s *Service) deleteBackup(w http.ResponseWriter, params map[string]interface{}) {
+	arn := getString(params, "BackupArn")
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	b, exists := s.backups[arn]
+	if !exists {
+		writeJSONError(w, "BackupNotFoundException", "Backup not found: "+arn, http.StatusBadRequest)
+		return
+	}
+	delete(s.backups, arn)
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"BackupDescription": b.description("DELETED"),
+	})
+}
+
+func (s *Service) listBackups(w http.ResponseWriter, params map[string]interface{}) {
+	name := getString(params, "TableName")
+	backupType := getString(params, "BackupType")
+	lower, hasLower := params["TimeRangeLowerBound"].(float64)
+	upper, hasUpper := params["TimeRangeUpperBound"].(float64)
+
+	s.mu.RLock()
+	var list []*backup
+	for _, b := range s.backups {
+		created := float64(b.created.Unix())
+		switch {
+		case name != "" && b.tableName != name,
+			backupType != "" && backupType != "ALL" && backupType != "USER",
+			hasLower && created < lower,
+			hasUpper && created >= upper:
+			continue
+		}
+		list = append(list, b)
+	}
+	s.mu.RUnlock()
+	sort.Slice(list, func(i, j int) bool { return list[i].arn < list[j].arn })
+
+	// ListBackups pages by ARN: the listing resumes after
+	// ExclusiveStartBackupArn.
+	if start := getString(params, "ExclusiveStartBackupArn"); start != "" {
+		list = list[sort.Search(len(list), func(i int) bool { return list[i].arn > start }):]
+	}
+	page, next, _ := paginate.Page(list, func(i int) string { return list[i].arn }, "", int(getInt64(params, "Limit", 0)), 100)
+
+	summaries := make([]map[string]interface{}, 0, len(page))
+	for _, b := range page {
+		summaries = append(summaries, map[string]interface{}{
+			"TableName":              b.tableName,
+			"TableArn":               b.tableArn,
+			"TableId":                b.tableID,
+			"BackupArn":              b.arn,
+			"BackupName":             b.name,
+			"BackupCreationDateTime": float64(b.created.Unix()),
+			"BackupStatus":           "AVAILABLE",
+			"BackupType":             "USER",
+			"BackupSizeBytes":        b.sizeBytes,
+		})
+	}
+	resp := map[string]interface{}{
+		"BackupSummaries": summaries,
+	}
+	if next != "" {
+		resp["LastEvaluatedBackupArn"] = page[len(page)-1].arn
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (s *Service) restoreTableFromBackup(w http.ResponseWriter, params map[string]interface{}) {
+	target := getString(params, "TargetTableName")
+	arn := getString(params, "BackupArn")
+
+	s.mu.Lock()
+	b, exists := s.backups[arn]
+	if !exists {
+		s.mu.Unlock()
+		writeJSONError(w, "BackupNotFoundException", "Backup not found: "+arn, http.StatusBadRequest)
+		return
+	}
+	if _, exists := s.tables[target]; exists {
+		s.mu.Unlock()
+		writeJSONError(w, "TableAlreadyExistsException", "Table already exists: "+target, http.StatusBadRequest)
+		return
+	}
+
+	now := time.Now().UTC()
+	t := b.saved.restored()
+	t.name = target
+	t.arn = fmt.Sprintf("arn:aws:dynamodb:us-east-1:%s:table/%s", defaultAccountID, target)
+	t.id = newRequestID()
+	t.status = "ACTIVE"
+	t.created = now
+	t.ready = s.transitions.Begin()
+	t.pitr = pointInTimeRecovery{}
+	t.stream = nil
+	t.restore = &restoreSummary{
+		sourceBackupArn: b.arn,
+		sourceTableArn:  b.tableArn,
+		restored:        now,
+	}
+	if mode := getString(params, "BillingModeOverride"); mode != "" {
+		t.billingMode = mode
+	}
+	if pt, ok := params["ProvisionedThroughputOverride"].(map[string]interface{}); ok {
+		t.provisionedRead = getInt64(pt, "ReadCapacityUnits", t.provisionedRead)
+		t.provisionedWrite = getInt64(pt, "WriteCapacityUnits", t.provisionedWrite)
+	}
+	s.tables[target] = t
+	s.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"TableDescription": s.tableDescription(t),
+	})
+}
+
+// restoreSummary records the backup a table was restored from.
+type restoreSummary struct {
+	sourceBackupArn string
+	sourceTableArn  string
+	restored        time.Time
+}
+
+// pointInTimeRecovery is a table's continuous backup setting.
+type pointInTimeRecovery struct {
+	enabled        bool
+	enabledAt      time.Time
+	recoveryPeriod int64
+}
+
+// continuousBackups returns the ContinuousBackupsDescription of t. Caller
+// must hold s.mu.
+func continuousBackups(t *table) map[string]interface{} {
+	pitr := map[string]interface{}{
+		"PointInTimeRecoveryStatus": "DISABLED",
+	}
+	if t.pitr.enabled {
+		pitr["PointInTimeRecoveryStatus"] = "ENABLED"
+		pitr["RecoveryPeriodInDays"] = t.pitr.recoveryPeriod
+		pitr["EarliestRestorableDateTime"] = float64(t.pitr.enabledAt.Unix())
+		pitr["LatestRestorableDateTime"] = float64(time.Now().Add(-5 * time.Minute).Unix())
+	}
+	return map[string]interface{}{
+		"ContinuousBackupsStatus":        "ENABLED",
+		"PointInTimeRecoveryDescription": pitr,
+	}
+}
+
+func (s *Service) describeContinuousBackups(w http.ResponseWriter, params map[string]interface{}) {
+	name := getString(params, "TableName")
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	t, exists := s.tables[name]
+	if !exists {
+		writeJSONError(w, "TableNotFoundException", "Table not found: "+name, http.StatusBadRequest)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"ContinuousBackupsDescription": continuousBackups(t),
+	})
+}
+
+func (s *Service) updateContinuousBackups(w http.ResponseWriter, params map[string]interface{}) {
+	name := getString(params, "TableName")
+	spec, ok := params["PointInTimeRecoverySpecification"].(map[string]interface{})
+	if !ok {
+		writeJSONError(w, "ValidationException", "PointInTimeRecoverySpecification is required", http.StatusBadRequest)
+		return
+	}
+	enabled, _ := spec["PointInTimeRecoveryEnabled"].(bool)
+	period := getInt64(spec, "RecoveryPeriodInDays", 35)
+	if period < 1 || period > 35 {
+		writeJSONError(w, "ValidationException", "RecoveryPeriodInDays must be between 1 and 35", http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t, exists := s.tables[name]
+	if !exists {
+		writeJSONError(w, "TableNotFoundException", "Table not found: "+name, http.StatusBadRequest)
+		return
+	}
+	switch {
+	case !enabled:
+		t.pitr = pointInTimeRecovery{}
+	case !t.pitr.enabled:
+		t.pitr = pointInTimeRecovery{enabled: true, enabledAt: time.Now().UTC(), recoveryPeriod: period}
+	default:
+		t.pitr.recoveryPeriod = period
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"ContinuousBackupsDescription": continuousBackups(t),
+	})
+}
+
+// describeLimits reports the default DynamoDB capacity quotas.
+func (s *Service) describeLimits(w http.ResponseWriter, _ map[string]interface{}) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"AccountMaxReadCapacityUnits":  80000,
+		"AccountMaxWriteCapacityUnits": 80000,
+		"TableMaxReadCapacityUnits":    40000,
+		"TableMaxWriteCapacityUnits":   40000,
+	})
+}
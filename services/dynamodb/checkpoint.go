@@ -0,0 +1,80 @@
+package dynamodb
+
+import "github.com/riyanimam/goto/internal/cow"
+
+// Checkpoint records the current tables and items so that Reset restores
+// them rather than clearing everything. Restored tables keep the
+// checkpoint's items copy-on-write, so a restore costs time proportional to
+// the number of tables, not items.
+func (s *Service) Checkpoint() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	saved := make(map[string]*savedTable, len(s.tables))
+	for name, t := range s.tables {
+		t.mu.Lock()
+		saved[name] = &savedTable{
+			def:        t.definition(),
+			items:      t.items.Snapshot(),
+			partitions: t.partitions.Snapshot(),
+		}
+		t.mu.Unlock()
+	}
+	s.checkpoint = saved
+}
+
+// savedTable is a table as it was at the last checkpoint.
+type savedTable struct {
+	def        *table // definition only; its item storage is unused
+	items      cow.Snapshot[string, map[string]interface{}]
+	partitions cow.Snapshot[string, []indexEntry]
+}
+
+// ClearCheckpoint discards the checkpoint, so Reset clears everything again.
+func (s *Service) ClearCheckpoint() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.checkpoint = nil
+}
+
+// restoreCheckpoint returns fresh tables holding the checkpointed items, or
+// no tables if there is no checkpoint. Caller must hold s.mu.
+func (s *Service) restoreCheckpoint() map[string]*table {
+	tables := make(map[string]*table, len(s.checkpoint))
+	for name, saved := range s.checkpoint {
+		tables[name] = saved.restored()
+	}
+	return tables
+}
+
+// restored returns a fresh table with the saved definition and items, no
+// pending stale reads, and full capacity.
+func (saved *savedTable) restored() *table {
+	t := saved.def.definition()
+	t.items = cow.FromSnapshot(saved.items)
+	t.partitions = cow.FromSnapshot(saved.partitions)
+	return t
+}
+
+// definition returns a new table with t's name, keys, billing, backup and
+// stream settings but no items or stream records.
+func (t *table) definition() *table {
+	def := &table{
+		name:             t.name,
+		arn:              t.arn,
+		id:               t.id,
+		status:           t.status,
+		keySchema:        t.keySchema,
+		attributeDefs:    t.attributeDefs,
+		created:          t.created,
+		billingMode:      t.billingMode,
+		provisionedRead:  t.provisionedRead,
+		provisionedWrite: t.provisionedWrite,
+		pitr:             t.pitr,
+		restore:          t.restore,
+	}
+	if t.stream != nil {
+		def.stream = &changeStream{arn: t.stream.arn, label: t.stream.label, viewType: t.stream.viewType}
+	}
+	return def
+}
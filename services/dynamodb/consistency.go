@@ -0,0 +1,73 @@
+package dynamodb
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// staleItem is the version of an item eventually consistent reads return
+// until a recent write has propagated.
+type staleItem struct {
+	item  map[string]interface{} // nil if the item did not exist
+	until time.Time
+}
+
+// SetStaleReadDelay makes GetItem calls without ConsistentRead keep
+// returning an item's previous version for d after it is put or deleted.
+// Strongly consistent reads always see the latest write. Zero disables
+// stale reads.
+func (s *Service) SetStaleReadDelay(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.staleReadDelay = d
+}
+
+func (s *Service) currentStaleReadDelay() time.Duration {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.staleReadDelay
+}
+
+// noteChange records prev as the stale version of the item at key. Repeated
+// writes within the window extend it but keep the original version. Caller
+// must hold t.mu.
+func (t *table) noteChange(key string, prev map[string]interface{}, delay time.Duration) {
+	if delay <= 0 {
+		return
+	}
+	now := time.Now()
+	if t.stale == nil {
+		t.stale = make(map[string]staleItem)
+	}
+	for k, st := range t.stale {
+		if !now.Before(st.until) {
+			delete(t.stale, k)
+		}
+	}
+	if st, ok := t.stale[key]; ok {
+		st.until = now.Add(delay)
+		t.stale[key] = st
+		return
+	}
+	t.stale[key] = staleItem{item: prev, until: now.Add(delay)}
+}
+
+// staleVersion returns the version an eventually consistent read of key
+// sees, if a write to it has not yet propagated. Caller must hold t.mu.
+func (t *table) staleVersion(key string) (map[string]interface{}, bool) {
+	st, ok := t.stale[key]
+	if !ok || !time.Now().Before(st.until) {
+		return nil, false
+	}
+	return st.item, true
+}
+
+// itemKey identifies an item by its key attributes.
+func itemKey(item map[string]interface{}, keyAttrs []string) string {
+	key := make(map[string]interface{}, len(keyAttrs))
+	for _, attr := range keyAttrs {
+		key[attr] = canonicalKey(item[attr])
+	}
+	b, _ := json.Marshal(key)
+	return string(b)
+}
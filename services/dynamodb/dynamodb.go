@@ -10,40 +10,89 @@
 //   - DeleteItem
 //   - Query
 //   - Scan
+//   - TagResource
+//   - UntagResource
+//   - ListTagsOfResource
+//   - CreateBackup
+//   - DescribeBackup
+//   - DeleteBackup
+//   - ListBackups
+//   - RestoreTableFromBackup
+//   - DescribeContinuousBackups
+//   - UpdateContinuousBackups
+//   - ExportTableToPointInTime
+//   - DescribeExport
+//   - ListExports
+//   - DescribeLimits
+//
+// Query and Scan evaluate FilterExpression and ProjectionExpression, and
+// honor Select, including COUNT. They read at most Limit items before
+// filtering, returning a LastEvaluatedKey to resume from with
+// ExclusiveStartKey when more remain. Scan divides a table into segments by
+// partition key for parallel scans with Segment and TotalSegments.
+//
+// Tables created with a StreamSpecification record a stream of item-level
+// changes made by PutItem and DeleteItem. Consumers inside the mock, such as
+// pipes, read it; the DynamoDB Streams API does not serve its records.
 package dynamodb
 
 import (
 	"encoding/json"
 	"fmt"
-	"io"
+	"hash/fnv"
 	"math/rand"
 	"net/http"
 	"sort"
-	"strings"
 	"sync"
 	"time"
+
+	"github.com/riyanimam/goto/internal/awserr"
+	"github.com/riyanimam/goto/internal/clock"
+	"github.com/riyanimam/goto/internal/cow"
+	"github.com/riyanimam/goto/internal/lifecycle"
+	"github.com/riyanimam/goto/internal/mockhelpers"
+	"github.com/riyanimam/goto/internal/paginate"
+	"github.com/riyanimam/goto/internal/tags"
 )
 
 const defaultAccountID = "123456789012"
 
 // Service implements the DynamoDB mock.
 type Service struct {
-	mu     sync.RWMutex
-	tables map[string]*table
+	mu                sync.RWMutex
+	tables            map[string]*table
+	staleReadDelay    time.Duration
+	clock             *clock.Clock
+	enforceThroughput bool
+	tableQuota        int
+	checkpoint        map[string]*savedTable
+	tags              *tags.Store
+	transitions       *lifecycle.Transitions
+	backups           map[string]*backup
+	exports           map[string]*export
+	store             mockhelpers.ObjectStore
 }
 
 type table struct {
 	name             string
 	arn              string
+	id               string
 	status           string
 	keySchema        []keySchemaElement
 	attributeDefs    []attributeDefinition
 	created          time.Time
-	itemCount        int64
+	ready            lifecycle.Transition
 	billingMode      string
 	provisionedRead  int64
 	provisionedWrite int64
-	items            []map[string]interface{}
+	items            *cow.Map[string, map[string]interface{}] // keyed by itemKey
+	partitions       *cow.Map[string, []indexEntry]           // keyed by partitionKey
+	stale            map[string]staleItem
+	readCapacity     capacity
+	writeCapacity    capacity
+	pitr             pointInTimeRecovery
+	restore          *restoreSummary
+	stream           *changeStream
 	mu               sync.Mutex
 }
 
@@ -60,7 +109,10 @@ type attributeDefinition struct {
 // New creates a new DynamoDB mock service.
 func New() *Service {
 	return &Service{
-		tables: make(map[string]*table),
+		tables:  make(map[string]*table),
+		tags:    tags.New(),
+		backups: make(map[string]*backup),
+		exports: make(map[string]*export),
 	}
 }
 
@@ -69,66 +121,56 @@ func (s *Service) Name() string { return "dynamodb" }
 
 // Handler returns the HTTP handler for DynamoDB requests.
 func (s *Service) Handler() http.Handler {
-	return http.HandlerFunc(s.handle)
+	return mockhelpers.JSONRouter{
+		"CreateTable":               s.createTable,
+		"DeleteTable":               s.deleteTable,
+		"DescribeTable":             s.describeTable,
+		"ListTables":                s.listTables,
+		"PutItem":                   s.putItem,
+		"GetItem":                   s.getItem,
+		"DeleteItem":                s.deleteItem,
+		"Query":                     s.query,
+		"Scan":                      s.scan,
+		"TagResource":               s.tagResource,
+		"UntagResource":             s.untagResource,
+		"ListTagsOfResource":        s.listTagsOfResource,
+		"CreateBackup":              s.createBackup,
+		"DescribeBackup":            s.describeBackup,
+		"DeleteBackup":              s.deleteBackup,
+		"ListBackups":               s.listBackups,
+		"RestoreTableFromBackup":    s.restoreTableFromBackup,
+		"DescribeContinuousBackups": s.describeContinuousBackups,
+		"UpdateContinuousBackups":   s.updateContinuousBackups,
+		"ExportTableToPointInTime":  s.exportTableToPointInTime,
+		"DescribeExport":            s.describeExport,
+		"ListExports":               s.listExports,
+		"DescribeLimits":            s.describeLimits,
+	}.JSON10()
 }
 
-// Reset clears all tables and items.
+// Reset clears all tables and items. After [Service.Checkpoint], it
+// restores the checkpointed tables instead.
 func (s *Service) Reset() {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	s.tables = make(map[string]*table)
+	s.tables = s.restoreCheckpoint()
+	s.backups = make(map[string]*backup)
+	s.exports = make(map[string]*export)
+	s.tags.DeleteService("dynamodb")
 }
 
-func (s *Service) handle(w http.ResponseWriter, r *http.Request) {
-	target := r.Header.Get("X-Amz-Target")
-
-	bodyBytes, err := io.ReadAll(r.Body)
-	if err != nil {
-		writeJSONError(w, "InternalServerError", "could not read request body", http.StatusInternalServerError)
-		return
-	}
-
-	var params map[string]interface{}
-	if len(bodyBytes) > 0 {
-		if err := json.Unmarshal(bodyBytes, &params); err != nil {
-			writeJSONError(w, "SerializationException", "could not parse request body", http.StatusBadRequest)
-			return
-		}
-	}
-	if params == nil {
-		params = make(map[string]interface{})
-	}
-
-	action := ""
-	if target != "" {
-		parts := strings.SplitN(target, ".", 2)
-		if len(parts) == 2 {
-			action = parts[1]
-		}
-	}
+// SetTagStore sets the registry table tags are recorded in.
+func (s *Service) SetTagStore(store *tags.Store) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tags = store
+}
 
-	switch action {
-	case "CreateTable":
-		s.createTable(w, params)
-	case "DeleteTable":
-		s.deleteTable(w, params)
-	case "DescribeTable":
-		s.describeTable(w, params)
-	case "ListTables":
-		s.listTables(w, params)
-	case "PutItem":
-		s.putItem(w, params)
-	case "GetItem":
-		s.getItem(w, params)
-	case "DeleteItem":
-		s.deleteItem(w, params)
-	case "Query":
-		s.query(w, params)
-	case "Scan":
-		s.scan(w, params)
-	default:
-		writeJSONError(w, "UnknownOperationException", fmt.Sprintf("action %q is not supported", action), http.StatusBadRequest)
-	}
+// SetTransitions sets how long new tables stay CREATING.
+func (s *Service) SetTransitions(t *lifecycle.Transitions) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.transitions = t
 }
 
 func (s *Service) createTable(w http.ResponseWriter, params map[string]interface{}) {
@@ -144,13 +186,21 @@ func (s *Service) createTable(w http.ResponseWriter, params map[string]interface
 		writeJSONError(w, "ResourceInUseException", "Table already exists: "+name, http.StatusBadRequest)
 		return
 	}
+	if s.tableQuota > 0 && len(s.tables) >= s.tableQuota {
+		s.mu.Unlock()
+		writeJSONError(w, "LimitExceededException", fmt.Sprintf("Subscriber limit exceeded: Only %d tables can be created", s.tableQuota), http.StatusBadRequest)
+		return
+	}
 
 	t := &table{
 		name:    name,
 		arn:     fmt.Sprintf("arn:aws:dynamodb:us-east-1:%s:table/%s", defaultAccountID, name),
+		id:      newRequestID(),
 		status:  "ACTIVE",
 		created: time.Now().UTC(),
+		ready:   s.transitions.Begin(),
 	}
+	t.newIndex()
 
 	// Parse KeySchema.
 	if ks, ok := params["KeySchema"].([]interface{}); ok {
@@ -191,7 +241,21 @@ func (s *Service) createTable(w http.ResponseWriter, params map[string]interface
 		t.provisionedWrite = 5
 	}
 
+	// Parse StreamSpecification.
+	if spec, ok := params["StreamSpecification"].(map[string]interface{}); ok {
+		if enabled, _ := spec["StreamEnabled"].(bool); enabled {
+			viewType := getString(spec, "StreamViewType")
+			if !streamViewTypes[viewType] {
+				s.mu.Unlock()
+				writeJSONError(w, "ValidationException", "StreamViewType is required when StreamEnabled is true", http.StatusBadRequest)
+				return
+			}
+			t.stream = newChangeStream(t.arn, viewType, t.created)
+		}
+	}
+
 	s.tables[name] = t
+	s.tags.Tag(t.arn, tags.FromList(params["Tags"], "Key", "Value"))
 	s.mu.Unlock()
 
 	writeJSON(w, http.StatusOK, map[string]interface{}{
@@ -210,6 +274,7 @@ func (s *Service) deleteTable(w http.ResponseWriter, params map[string]interface
 		return
 	}
 	delete(s.tables, name)
+	s.tags.Delete(t.arn)
 	s.mu.Unlock()
 
 	desc := s.tableDescription(t)
@@ -236,9 +301,9 @@ func (s *Service) describeTable(w http.ResponseWriter, params map[string]interfa
 	})
 }
 
-func (s *Service) listTables(w http.ResponseWriter, _ map[string]interface{}) {
+func (s *Service) listTables(w http.ResponseWriter, params map[string]interface{}) {
 	s.mu.RLock()
-	var names []string
+	names := make([]string, 0, len(s.tables))
 	for name := range s.tables {
 		names = append(names, name)
 	}
@@ -246,11 +311,76 @@ func (s *Service) listTables(w http.ResponseWriter, _ map[string]interface{}) {
 
 	sort.Strings(names)
 
+	// ListTables pages by table name rather than by token: the listing
+	// resumes after ExclusiveStartTableName.
+	if start := getString(params, "ExclusiveStartTableName"); start != "" {
+		names = names[sort.Search(len(names), func(i int) bool { return names[i] > start }):]
+	}
+	page, next, _ := paginate.Page(names, func(i int) string { return names[i] }, "", int(getInt64(params, "Limit", 0)), 100)
+
+	resp := map[string]interface{}{
+		"TableNames": page,
+	}
+	if next != "" {
+		resp["LastEvaluatedTableName"] = page[len(page)-1]
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (s *Service) tagResource(w http.ResponseWriter, params map[string]interface{}) {
+	arn := getString(params, "ResourceArn")
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.tableByARN(arn) == nil {
+		writeJSONError(w, "ResourceNotFoundException", "Requested resource not found: ResourceArn: "+arn+" not found", http.StatusBadRequest)
+		return
+	}
+	s.tags.Tag(arn, tags.FromList(params["Tags"], "Key", "Value"))
+	writeJSON(w, http.StatusOK, map[string]interface{}{})
+}
+
+func (s *Service) untagResource(w http.ResponseWriter, params map[string]interface{}) {
+	arn := getString(params, "ResourceArn")
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.tableByARN(arn) == nil {
+		writeJSONError(w, "ResourceNotFoundException", "Requested resource not found: ResourceArn: "+arn+" not found", http.StatusBadRequest)
+		return
+	}
+	s.tags.Untag(arn, tags.Keys(params["TagKeys"]))
+	writeJSON(w, http.StatusOK, map[string]interface{}{})
+}
+
+func (s *Service) listTagsOfResource(w http.ResponseWriter, params map[string]interface{}) {
+	arn := getString(params, "ResourceArn")
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.tableByARN(arn) == nil {
+		writeJSONError(w, "ResourceNotFoundException", "Requested resource not found: ResourceArn: "+arn+" not found", http.StatusBadRequest)
+		return
+	}
 	writeJSON(w, http.StatusOK, map[string]interface{}{
-		"TableNames": names,
+		"Tags": tags.ToList(s.tags.Get(arn), "Key", "Value"),
 	})
 }
 
+// tableByARN returns the table with the given ARN, or nil. Caller must hold
+// s.mu.
+func (s *Service) tableByARN(arn string) *table {
+	for _, t := range s.tables {
+		if t.arn == arn {
+			return t
+		}
+	}
+	return nil
+}
+
 func (s *Service) putItem(w http.ResponseWriter, params map[string]interface{}) {
 	name := getString(params, "TableName")
 
@@ -269,20 +399,20 @@ func (s *Service) putItem(w http.ResponseWriter, params map[string]interface{})
 		return
 	}
 
-	t.mu.Lock()
-	// Check if item with same key exists and replace it.
-	keyAttrs := s.getKeyAttributes(t)
-	replaced := false
-	for i, existing := range t.items {
-		if itemKeysMatch(existing, item, keyAttrs) {
-			t.items[i] = item
-			replaced = true
-			break
-		}
+	if !s.consume(t, true, writeUnits(item)) {
+		writeJSONError(w, "ProvisionedThroughputExceededException", throughputExceeded, http.StatusBadRequest)
+		return
 	}
-	if !replaced {
-		t.items = append(t.items, item)
-		t.itemCount++
+
+	delay := s.currentStaleReadDelay()
+
+	t.mu.Lock()
+	prev := t.store(item)
+	t.noteChange(itemKey(item, t.keyAttrs()), prev, delay)
+	if prev == nil {
+		t.capture("INSERT", nil, item)
+	} else {
+		t.capture("MODIFY", prev, item)
 	}
 	t.mu.Unlock()
 
@@ -307,18 +437,22 @@ func (s *Service) getItem(w http.ResponseWriter, params map[string]interface{})
 		return
 	}
 
-	keyAttrs := s.getKeyAttributes(t)
+	consistent, _ := params["ConsistentRead"].(bool)
 
 	t.mu.Lock()
-	var found map[string]interface{}
-	for _, item := range t.items {
-		if itemKeysMatch(item, key, keyAttrs) {
-			found = item
-			break
+	found, _ := t.lookup(key)
+	if !consistent {
+		if prev, stale := t.staleVersion(itemKey(key, t.keyAttrs())); stale {
+			found = prev
 		}
 	}
 	t.mu.Unlock()
 
+	if !s.consume(t, false, readUnits(consistent, found)) {
+		writeJSONError(w, "ProvisionedThroughputExceededException", throughputExceeded, http.StatusBadRequest)
+		return
+	}
+
 	resp := map[string]interface{}{}
 	if found != nil {
 		resp["Item"] = found
@@ -344,15 +478,17 @@ func (s *Service) deleteItem(w http.ResponseWriter, params map[string]interface{
 		return
 	}
 
-	keyAttrs := s.getKeyAttributes(t)
+	if !s.consume(t, true, writeUnits(key)) {
+		writeJSONError(w, "ProvisionedThroughputExceededException", throughputExceeded, http.StatusBadRequest)
+		return
+	}
+
+	delay := s.currentStaleReadDelay()
 
 	t.mu.Lock()
-	for i, item := range t.items {
-		if itemKeysMatch(item, key, keyAttrs) {
-			t.items = append(t.items[:i], t.items[i+1:]...)
-			t.itemCount--
-			break
-		}
+	if prev := t.remove(key); prev != nil {
+		t.noteChange(itemKey(key, t.keyAttrs()), prev, delay)
+		t.capture("REMOVE", prev, nil)
 	}
 	t.mu.Unlock()
 
@@ -371,37 +507,54 @@ func (s *Service) query(w http.ResponseWriter, params map[string]interface{}) {
 		return
 	}
 
-	// Simple implementation: return items matching the KeyConditionExpression values.
-	expressionValues, _ := params["ExpressionAttributeValues"].(map[string]interface{})
-
-	t.mu.Lock()
-	var items []interface{}
-	if expressionValues != nil && len(t.keySchema) > 0 {
-		partitionKeyName := t.keySchema[0].AttributeName
-		// Try to find the partition key value from expression attribute values.
-		for _, val := range expressionValues {
-			for _, item := range t.items {
-				if itemAttrVal, ok := item[partitionKeyName]; ok {
-					if attrValuesEqual(itemAttrVal, val) {
-						items = append(items, item)
-					}
-				}
+	opts, err := parseReadOptions(params)
+	if err != nil {
+		writeJSONError(w, "ValidationException", err.Error(), http.StatusBadRequest)
+		return
+	}
+	for _, attr := range opts.filterAttrs {
+		for _, key := range t.keyAttrs() {
+			if attr == key {
+				writeJSONError(w, "ValidationException", "Filter Expression can only contain non-primary key attributes: Primary key attribute: "+key, http.StatusBadRequest)
+				return
 			}
-			break // Only use the first expression value for partition key matching.
-		}
-	} else {
-		for _, item := range t.items {
-			items = append(items, item)
 		}
 	}
+
+	kc, err := parseKeyCondition(t, params)
+	if err != nil {
+		writeJSONError(w, "ValidationException", err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Return the items the key condition selects, in sort key order.
+	t.mu.Lock()
+	matched := t.query(kc)
 	t.mu.Unlock()
 
-	writeJSON(w, http.StatusOK, map[string]interface{}{
-		"Items":            items,
-		"Count":            len(items),
-		"ScannedCount":     len(items),
-		"ConsumedCapacity": nil,
-	})
+	forward := true
+	if f, ok := params["ScanIndexForward"].(bool); ok && !f {
+		forward = false
+		reversed := make([]map[string]interface{}, len(matched))
+		for i, item := range matched {
+			reversed[len(matched)-1-i] = item
+		}
+		matched = reversed
+	}
+	matched, last, err := opts.page(t, matched, forward)
+	if err != nil {
+		writeJSONError(w, "ValidationException", err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Reads are charged for the items read before filtering.
+	consistent, _ := params["ConsistentRead"].(bool)
+	if !s.consume(t, false, readUnits(consistent, matched...)) {
+		writeJSONError(w, "ProvisionedThroughputExceededException", throughputExceeded, http.StatusBadRequest)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, opts.results(matched, last))
 }
 
 func (s *Service) scan(w http.ResponseWriter, params map[string]interface{}) {
@@ -416,30 +569,198 @@ func (s *Service) scan(w http.ResponseWriter, params map[string]interface{}) {
 		return
 	}
 
+	opts, err := parseReadOptions(params)
+	if err != nil {
+		writeJSONError(w, "ValidationException", err.Error(), http.StatusBadRequest)
+		return
+	}
+	segment, total, err := parseSegment(params)
+	if err != nil {
+		writeJSONError(w, "ValidationException", err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	t.mu.Lock()
-	var items []interface{}
-	for _, item := range t.items {
-		items = append(items, item)
+	var scanned []map[string]interface{}
+	for _, item := range t.all() {
+		if total > 1 && segmentOf(item[t.hashKey()], total) != segment {
+			continue
+		}
+		scanned = append(scanned, item)
 	}
 	t.mu.Unlock()
 
-	writeJSON(w, http.StatusOK, map[string]interface{}{
-		"Items":            items,
+	scanned, last, err := opts.page(t, scanned, true)
+	if err != nil {
+		writeJSONError(w, "ValidationException", err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	consistent, _ := params["ConsistentRead"].(bool)
+	if !s.consume(t, false, readUnits(consistent, scanned...)) {
+		writeJSONError(w, "ProvisionedThroughputExceededException", throughputExceeded, http.StatusBadRequest)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, opts.results(scanned, last))
+}
+
+// readOptions are the parts of a Query or Scan request that shape its
+// results.
+type readOptions struct {
+	filter      condition   // nil without a FilterExpression
+	filterAttrs []string    // top-level attributes the filter refers to
+	projection  *projection // nil without a ProjectionExpression
+	count       bool        // Select is COUNT
+	limit       int         // 0 without a Limit
+	startKey    map[string]interface{}
+}
+
+func parseReadOptions(params map[string]interface{}) (readOptions, error) {
+	var opts readOptions
+	var err error
+	if opts.filter, opts.filterAttrs, err = parseCondition("FilterExpression", params); err != nil {
+		return opts, err
+	}
+	if opts.projection, err = parseProjection(params); err != nil {
+		return opts, err
+	}
+	switch sel := getString(params, "Select"); sel {
+	case "", "ALL_ATTRIBUTES", "COUNT":
+		if sel != "" && opts.projection != nil {
+			return opts, fmt.Errorf("Cannot specify the ProjectionExpression when choosing to get %s", sel)
+		}
+		opts.count = sel == "COUNT"
+	case "SPECIFIC_ATTRIBUTES":
+		if opts.projection == nil {
+			return opts, fmt.Errorf("Must specify the ProjectionExpression when choosing to get SPECIFIC_ATTRIBUTES")
+		}
+	case "ALL_PROJECTED_ATTRIBUTES":
+		return opts, fmt.Errorf("ALL_PROJECTED_ATTRIBUTES can be used only when Querying using an IndexName")
+	default:
+		return opts, fmt.Errorf("1 validation error detected: Value '%s' at 'select' failed to satisfy constraint: Member must satisfy enum value set: [SPECIFIC_ATTRIBUTES, COUNT, ALL_ATTRIBUTES, ALL_PROJECTED_ATTRIBUTES]", sel)
+	}
+	if _, ok := params["Limit"]; ok {
+		opts.limit = int(getInt64(params, "Limit", 0))
+		if opts.limit < 1 {
+			return opts, fmt.Errorf("1 validation error detected: Value '%d' at 'limit' failed to satisfy constraint: Member must have value greater than or equal to 1", opts.limit)
+		}
+	}
+	opts.startKey, _ = params["ExclusiveStartKey"].(map[string]interface{})
+	return opts, nil
+}
+
+// page returns the items of read, in read order, that follow the request's
+// ExclusiveStartKey, up to its Limit. When items remain past the page, it
+// also returns the key of the page's last item to resume from. Items are
+// placed by key order rather than found by key, so a read can resume after
+// its last item has been deleted.
+func (o readOptions) page(t *table, read []map[string]interface{}, forward bool) ([]map[string]interface{}, map[string]interface{}, error) {
+	attrs := t.keyAttrs()
+	if o.startKey != nil {
+		if len(o.startKey) != len(attrs) {
+			return nil, nil, fmt.Errorf("The provided starting key is invalid: The provided key element does not match the schema")
+		}
+		for _, attr := range attrs {
+			if _, ok := o.startKey[attr]; !ok {
+				return nil, nil, fmt.Errorf("The provided starting key is invalid: The provided key element does not match the schema")
+			}
+		}
+		read = read[sort.Search(len(read), func(i int) bool {
+			c := t.compareKeys(read[i], o.startKey)
+			return forward && c > 0 || !forward && c < 0
+		}):]
+	}
+	if o.limit == 0 || len(read) <= o.limit {
+		return read, nil, nil
+	}
+	read = read[:o.limit]
+	last := make(map[string]interface{}, len(attrs))
+	for _, attr := range attrs {
+		last[attr] = read[len(read)-1][attr]
+	}
+	return read, last, nil
+}
+
+// results builds the response to a read that examined scanned, filtering
+// and projecting the items. COUNT reads return only the counts. last is the
+// LastEvaluatedKey, nil when the read is complete.
+func (o readOptions) results(scanned []map[string]interface{}, last map[string]interface{}) map[string]interface{} {
+	items := make([]interface{}, 0, len(scanned))
+	for _, item := range scanned {
+		if o.filter != nil && !o.filter.match(item) {
+			continue
+		}
+		if o.projection != nil {
+			item = o.projection.apply(item)
+		}
+		items = append(items, item)
+	}
+	resp := map[string]interface{}{
 		"Count":            len(items),
-		"ScannedCount":     len(items),
+		"ScannedCount":     len(scanned),
 		"ConsumedCapacity": nil,
-	})
+	}
+	if !o.count {
+		resp["Items"] = items
+	}
+	if last != nil {
+		resp["LastEvaluatedKey"] = last
+	}
+	return resp
+}
+
+// maxTotalSegments is the most segments a parallel scan can divide a table
+// into.
+const maxTotalSegments = 1000000
+
+// parseSegment returns the segment of a parallel scan and the number of
+// segments, which is 1 for an ordinary scan.
+func parseSegment(params map[string]interface{}) (segment, total int, err error) {
+	_, hasSegment := params["Segment"]
+	_, hasTotal := params["TotalSegments"]
+	switch {
+	case !hasSegment && !hasTotal:
+		return 0, 1, nil
+	case !hasTotal:
+		return 0, 0, fmt.Errorf("The TotalSegments parameter is required but was not present in the request when parameter Segment is present")
+	case !hasSegment:
+		return 0, 0, fmt.Errorf("The Segment parameter is required but was not present in the request when parameter TotalSegments is present")
+	}
+	segment = int(getInt64(params, "Segment", 0))
+	total = int(getInt64(params, "TotalSegments", 0))
+	switch {
+	case total < 1:
+		return 0, 0, fmt.Errorf("1 validation error detected: Value '%d' at 'totalSegments' failed to satisfy constraint: Member must have value greater than or equal to 1", total)
+	case total > maxTotalSegments:
+		return 0, 0, fmt.Errorf("1 validation error detected: Value '%d' at 'totalSegments' failed to satisfy constraint: Member must have value less than or equal to %d", total, maxTotalSegments)
+	case segment < 0:
+		return 0, 0, fmt.Errorf("1 validation error detected: Value '%d' at 'segment' failed to satisfy constraint: Member must have value greater than or equal to 0", segment)
+	case segment >= total:
+		return 0, 0, fmt.Errorf("The Segment parameter is zero-based and must be less than parameter TotalSegments: Segment: %d is not less than TotalSegments: %d", segment, total)
+	}
+	return segment, total, nil
+}
+
+// segmentOf assigns a partition to one of total scan segments by hashing
+// its key, so every item lands in exactly one segment and a partition is
+// never split.
+func segmentOf(partition interface{}, total int) int {
+	f := fnv.New32a()
+	f.Write([]byte(partitionKey(partition)))
+	return int(f.Sum32() % uint32(total))
 }
 
 func (s *Service) tableDescription(t *table) map[string]interface{} {
 	t.mu.Lock()
-	itemCount := t.itemCount
+	itemCount := t.items.Len()
 	t.mu.Unlock()
 
 	desc := map[string]interface{}{
 		"TableName":            t.name,
 		"TableArn":             t.arn,
-		"TableStatus":          t.status,
+		"TableId":              t.id,
+		"TableStatus":          s.transitions.Status(t.ready, "CREATING", t.status),
 		"CreationDateTime":     float64(t.created.Unix()),
 		"ItemCount":            itemCount,
 		"TableSizeBytes":       0,
@@ -455,49 +776,24 @@ func (s *Service) tableDescription(t *table) map[string]interface{} {
 			"NumberOfDecreasesToday": 0,
 		}
 	}
-
-	return desc
-}
-
-func (s *Service) getKeyAttributes(t *table) []string {
-	var keys []string
-	for _, ks := range t.keySchema {
-		keys = append(keys, ks.AttributeName)
-	}
-	return keys
-}
-
-// itemKeysMatch checks if two DynamoDB items have the same key attribute values.
-func itemKeysMatch(item, key map[string]interface{}, keyAttrs []string) bool {
-	for _, attr := range keyAttrs {
-		itemVal, ok1 := item[attr]
-		keyVal, ok2 := key[attr]
-		if !ok1 || !ok2 {
-			return false
+	if t.stream != nil {
+		desc["StreamSpecification"] = map[string]interface{}{
+			"StreamEnabled":  true,
+			"StreamViewType": t.stream.viewType,
 		}
-		if !attrValuesEqual(itemVal, keyVal) {
-			return false
+		desc["LatestStreamArn"] = t.stream.arn
+		desc["LatestStreamLabel"] = t.stream.label
+	}
+	if t.restore != nil {
+		desc["RestoreSummary"] = map[string]interface{}{
+			"SourceBackupArn":   t.restore.sourceBackupArn,
+			"SourceTableArn":    t.restore.sourceTableArn,
+			"RestoreDateTime":   float64(t.restore.restored.Unix()),
+			"RestoreInProgress": false,
 		}
 	}
-	return true
-}
 
-// attrValuesEqual compares two DynamoDB attribute values in their map representation.
-func attrValuesEqual(a, b interface{}) bool {
-	aMap, aOk := a.(map[string]interface{})
-	bMap, bOk := b.(map[string]interface{})
-	if !aOk || !bOk {
-		return false
-	}
-	// Compare the typed value (e.g., {"S": "val"} == {"S": "val"}).
-	for k, av := range aMap {
-		if bv, ok := bMap[k]; ok {
-			if fmt.Sprintf("%v", av) == fmt.Sprintf("%v", bv) {
-				return true
-			}
-		}
-	}
-	return false
+	return desc
 }
 
 // Helper functions.
@@ -532,12 +828,7 @@ func writeJSON(w http.ResponseWriter, status int, v interface{}) {
 }
 
 func writeJSONError(w http.ResponseWriter, code, message string, status int) {
-	w.Header().Set("Content-Type", "application/x-amz-json-1.0")
-	w.WriteHeader(status)
-	json.NewEncoder(w).Encode(map[string]interface{}{
-		"__type":  code,
-		"message": message,
-	})
+	awserr.WriteJSON(w, awserr.JSON10, code, message, status)
 }
 
 func newRequestID() string {
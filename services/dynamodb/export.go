@@ -0,0 +1,281 @@
+package dynamodb
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/riyanimam/goto/internal/mockhelpers"
+	"github.com/riyanimam/goto/internal/paginate"
+)
+
+// export is a table export to S3.
+type export struct {
+	arn          string
+	tableArn     string
+	tableID      string
+	bucket       string
+	prefix       string
+	exportTime   time.Time
+	started      time.Time
+	ended        time.Time
+	status       string
+	failureCode  string
+	failureMsg   string
+	itemCount    int
+	billedBytes  int
+	manifestKey  string
+	clientToken  string
+	exportFormat string
+}
+
+// SetObjectStore sets the store table exports are written to.
+func (s *Service) SetObjectStore(store mockhelpers.ObjectStore) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.store = store
+}
+
+func (s *Service) exportTableToPointInTime(w http.ResponseWriter, params map[string]interface{}) {
+	tableArn := getString(params, "TableArn")
+	bucket := getString(params, "S3Bucket")
+	format := getString(params, "ExportFormat")
+	if format == "" {
+		format = "DYNAMODB_JSON"
+	}
+	switch {
+	case bucket == "":
+		writeJSONError(w, "ValidationException", "S3Bucket is required", http.StatusBadRequest)
+		return
+	case format != "DYNAMODB_JSON":
+		writeJSONError(w, "ValidationException", "ExportFormat "+format+" is not supported; use DYNAMODB_JSON", http.StatusBadRequest)
+		return
+	case getString(params, "ExportType") == "INCREMENTAL_EXPORT":
+		writeJSONError(w, "ValidationException", "Incremental exports are not supported", http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	t := s.tableByARN(tableArn)
+	if t == nil {
+		s.mu.Unlock()
+		writeJSONError(w, "TableNotFoundException", "Table not found: "+tableArn, http.StatusBadRequest)
+		return
+	}
+	if !t.pitr.enabled {
+		s.mu.Unlock()
+		writeJSONError(w, "PointInTimeRecoveryUnavailableException",
+			"Point in time recovery is not enabled for table '"+t.name+"'", http.StatusBadRequest)
+		return
+	}
+	token := getString(params, "ClientToken")
+	for _, e := range s.exports {
+		if token != "" && e.clientToken == token {
+			desc := e.description()
+			s.mu.Unlock()
+			writeJSON(w, http.StatusOK, map[string]interface{}{"ExportDescription": desc})
+			return
+		}
+	}
+
+	now := time.Now().UTC()
+	e := &export{
+		arn:          fmt.Sprintf("%s/export/%s", t.arn, resourceSuffix(now)),
+		tableArn:     t.arn,
+		tableID:      t.id,
+		bucket:       bucket,
+		prefix:       getString(params, "S3Prefix"),
+		exportTime:   now,
+		started:      now,
+		status:       "IN_PROGRESS",
+		clientToken:  token,
+		exportFormat: format,
+	}
+	e.manifestKey = path.Join(e.dir(), "manifest-summary.json")
+	if ts, ok := params["ExportTime"].(float64); ok {
+		e.exportTime = time.Unix(int64(ts), 0).UTC()
+	}
+	t.mu.Lock()
+	items := t.all()
+	t.mu.Unlock()
+	s.exports[e.arn] = e
+	store := s.store
+	s.mu.Unlock()
+
+	// Write the files outside s.mu: the object store is another service.
+	code, msg := writeExport(store, e, items)
+
+	s.mu.Lock()
+	e.ended = time.Now().UTC()
+	e.status = "COMPLETED"
+	e.itemCount = len(items)
+	e.billedBytes = itemsSize(items)
+	if code != "" {
+		e.status, e.failureCode, e.failureMsg = "FAILED", code, msg
+	}
+	desc := e.description()
+	s.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"ExportDescription": desc,
+	})
+}
+
+// writeExport writes items to S3 in the DynamoDB JSON export layout: a
+// gzipped data file of one {"Item": ...} object per line, listed in
+// manifest-files.json and summarized in manifest-summary.json, all under
+// {prefix}/AWSDynamoDB/{export ID}/. It returns the failure code and message
+// if the files cannot be written.
+func writeExport(store mockhelpers.ObjectStore, e *export, items []map[string]interface{}) (string, string) {
+	if store == nil {
+		return "S3NoSuchBucket", "The specified bucket does not exist"
+	}
+	dir := e.dir()
+
+	var data bytes.Buffer
+	zw := gzip.NewWriter(&data)
+	for _, item := range items {
+		line, _ := json.Marshal(map[string]interface{}{"Item": item})
+		zw.Write(append(line, '\n'))
+	}
+	zw.Close()
+	sum := md5.Sum(data.Bytes())
+	dataKey := path.Join(dir, "data", fmt.Sprintf("%026x.json.gz", rand.Uint64()))
+
+	manifestFiles, _ := json.Marshal(map[string]interface{}{
+		"itemCount":     len(items),
+		"md5Checksum":   base64.StdEncoding.EncodeToString(sum[:]),
+		"etag":          hex.EncodeToString(sum[:]),
+		"dataFileS3Key": dataKey,
+	})
+	summary, _ := json.Marshal(map[string]interface{}{
+		"version":            "2020-06-30",
+		"exportArn":          e.arn,
+		"startTime":          e.started.Format(time.RFC3339Nano),
+		"endTime":            time.Now().UTC().Format(time.RFC3339Nano),
+		"tableArn":           e.tableArn,
+		"tableId":            e.tableID,
+		"exportTime":         e.exportTime.Format(time.RFC3339Nano),
+		"s3Bucket":           e.bucket,
+		"s3Prefix":           e.prefix,
+		"s3SseAlgorithm":     "AES256",
+		"s3SseKmsKeyId":      nil,
+		"manifestFilesS3Key": path.Join(dir, "manifest-files.json"),
+		"billedSizeBytes":    itemsSize(items),
+		"itemCount":          len(items),
+		"outputFormat":       e.exportFormat,
+	})
+
+	files := []struct {
+		key  string
+		data []byte
+	}{
+		{path.Join(dir, "_started"), nil},
+		{dataKey, data.Bytes()},
+		{path.Join(dir, "manifest-files.json"), append(manifestFiles, '\n')},
+		{e.manifestKey, summary},
+	}
+	for _, f := range files {
+		if err := store.PutObject(e.bucket, f.key, f.data); err != nil {
+			return "S3NoSuchBucket", err.Error()
+		}
+	}
+	return "", ""
+}
+
+// dir returns the S3 key prefix e writes its files under.
+func (e *export) dir() string {
+	return path.Join(e.prefix, "AWSDynamoDB", e.arn[strings.LastIndex(e.arn, "/")+1:])
+}
+
+// description returns the ExportDescription of e. Caller must hold s.mu.
+func (e *export) description() map[string]interface{} {
+	desc := map[string]interface{}{
+		"ExportArn":      e.arn,
+		"ExportStatus":   e.status,
+		"StartTime":      float64(e.started.Unix()),
+		"TableArn":       e.tableArn,
+		"TableId":        e.tableID,
+		"ExportTime":     float64(e.exportTime.Unix()),
+		"ClientToken":    e.clientToken,
+		"S3Bucket":       e.bucket,
+		"S3Prefix":       e.prefix,
+		"S3SseAlgorithm": "AES256",
+		"ExportFormat":   e.exportFormat,
+		"ExportType":     "FULL_EXPORT",
+	}
+	if !e.ended.IsZero() {
+		desc["EndTime"] = float64(e.ended.Unix())
+	}
+	switch e.status {
+	case "COMPLETED":
+		desc["ExportManifest"] = e.manifestKey
+		desc["ItemCount"] = e.itemCount
+		desc["BilledSizeBytes"] = e.billedBytes
+	case "FAILED":
+		desc["FailureCode"] = e.failureCode
+		desc["FailureMessage"] = e.failureMsg
+	}
+	return desc
+}
+
+func (s *Service) describeExport(w http.ResponseWriter, params map[string]interface{}) {
+	arn := getString(params, "ExportArn")
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	e, exists := s.exports[arn]
+	if !exists {
+		writeJSONError(w, "ExportNotFoundException", "Export not found: "+arn, http.StatusBadRequest)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"ExportDescription": e.description(),
+	})
+}
+
+func (s *Service) listExports(w http.ResponseWriter, params map[string]interface{}) {
+	tableArn := getString(params, "TableArn")
+
+	s.mu.RLock()
+	var list []*export
+	for _, e := range s.exports {
+		if tableArn == "" || e.tableArn == tableArn {
+			list = append(list, e)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].arn < list[j].arn })
+	page, next, err := paginate.Page(list, func(i int) string { return list[i].arn }, getString(params, "NextToken"), int(getInt64(params, "MaxResults", 0)), 25)
+	if err != nil {
+		s.mu.RUnlock()
+		writeJSONError(w, "ValidationException", "Invalid NextToken", http.StatusBadRequest)
+		return
+	}
+	summaries := make([]map[string]interface{}, 0, len(page))
+	for _, e := range page {
+		summaries = append(summaries, map[string]interface{}{
+			"ExportArn":    e.arn,
+			"ExportStatus": e.status,
+			"ExportType":   "FULL_EXPORT",
+		})
+	}
+	s.mu.RUnlock()
+
+	resp := map[string]interface{}{
+		"ExportSummaries": summaries,
+	}
+	if next != "" {
+		resp["NextToken"] = next
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
@@ -0,0 +1,816 @@
+package dynamodb
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"math/big"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// condition is a parsed condition expression, such as a FilterExpression:
+//
+//	operand comparator operand
+//	operand BETWEEN operand AND operand
+//	operand IN (operand, ...)
+//	function(path, ...)
+//	condition AND condition | condition OR condition | NOT condition | (condition)
+//
+// Comparators are =, <>, <, <=, >, and >=. Operands are document paths
+// (a.b[1].c, with #name placeholders), :value placeholders, and size(path).
+// The functions are attribute_exists, attribute_not_exists, attribute_type,
+// begins_with, and contains.
+type condition interface {
+	match(item map[string]interface{}) bool
+}
+
+// operand yields an attribute value, or false if it has none, as a path to
+// a missing attribute does.
+type operand interface {
+	value(item map[string]interface{}) (interface{}, bool)
+}
+
+// pathElem is one step of a document path: a map key, or a list index.
+type pathElem struct {
+	name  string
+	index int // -1 for a map key
+}
+
+type docPath []pathElem
+
+func (p docPath) value(item map[string]interface{}) (interface{}, bool) {
+	var v interface{} = map[string]interface{}{"M": item}
+	for _, e := range p {
+		av, _ := v.(map[string]interface{})
+		if e.index < 0 {
+			m, _ := av["M"].(map[string]interface{})
+			if v = m[e.name]; v == nil {
+				return nil, false
+			}
+			continue
+		}
+		l, _ := av["L"].([]interface{})
+		if e.index >= len(l) {
+			return nil, false
+		}
+		v = l[e.index]
+	}
+	return v, true
+}
+
+func (p docPath) String() string {
+	var b strings.Builder
+	for i, e := range p {
+		switch {
+		case e.index >= 0:
+			fmt.Fprintf(&b, "[%d]", e.index)
+		case i > 0:
+			b.WriteString("." + e.name)
+		default:
+			b.WriteString(e.name)
+		}
+	}
+	return b.String()
+}
+
+type literal struct{ v interface{} }
+
+func (l literal) value(map[string]interface{}) (interface{}, bool) { return l.v, true }
+
+// sizeOperand is size(path): the length of a string or binary value, or
+// the number of elements in a set, list, or map.
+type sizeOperand struct{ path docPath }
+
+func (s sizeOperand) value(item map[string]interface{}) (interface{}, bool) {
+	v, ok := s.path.value(item)
+	if !ok {
+		return nil, false
+	}
+	av, _ := v.(map[string]interface{})
+	n := -1
+	switch t := attrType(av); t {
+	case "S":
+		str, _ := av[t].(string)
+		n = len(str)
+	case "B":
+		str, _ := av[t].(string)
+		b, _ := base64.StdEncoding.DecodeString(str)
+		n = len(b)
+	case "SS", "NS", "BS", "L":
+		l, _ := av[t].([]interface{})
+		n = len(l)
+	case "M":
+		m, _ := av[t].(map[string]interface{})
+		n = len(m)
+	}
+	if n < 0 {
+		return nil, false
+	}
+	return map[string]interface{}{"N": strconv.Itoa(n)}, true
+}
+
+type comparison struct {
+	op          string
+	left, right operand
+}
+
+func (c comparison) match(item map[string]interface{}) bool {
+	l, ok := c.left.value(item)
+	if !ok {
+		return false
+	}
+	r, ok := c.right.value(item)
+	if !ok {
+		return false
+	}
+	switch c.op {
+	case "=":
+		return equalValues(l, r)
+	case "<>":
+		return !equalValues(l, r)
+	}
+	cmp, ok := orderValues(l, r)
+	if !ok {
+		return false
+	}
+	switch c.op {
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	case ">":
+		return cmp > 0
+	}
+	return cmp >= 0
+}
+
+type between struct{ x, lo, hi operand }
+
+func (b between) match(item map[string]interface{}) bool {
+	return comparison{">=", b.x, b.lo}.match(item) && comparison{"<=", b.x, b.hi}.match(item)
+}
+
+type in struct {
+	x    operand
+	list []operand
+}
+
+func (n in) match(item map[string]interface{}) bool {
+	for _, o := range n.list {
+		if (comparison{"=", n.x, o}).match(item) {
+			return true
+		}
+	}
+	return false
+}
+
+type call struct {
+	name string
+	path docPath
+	arg  operand // nil for attribute_exists and attribute_not_exists
+}
+
+func (c call) match(item map[string]interface{}) bool {
+	v, exists := c.path.value(item)
+	switch c.name {
+	case "attribute_exists":
+		return exists
+	case "attribute_not_exists":
+		return !exists
+	}
+	arg, ok := c.arg.value(item)
+	if !exists || !ok {
+		return false
+	}
+	av, _ := v.(map[string]interface{})
+	argAV, _ := arg.(map[string]interface{})
+	switch c.name {
+	case "attribute_type":
+		want, _ := argAV["S"].(string)
+		return attrType(av) == want
+	case "begins_with":
+		return hasPrefix(av, argAV)
+	}
+	// contains: a substring of a string, or an element of a set or list.
+	switch t := attrType(av); t {
+	case "S":
+		s, _ := av[t].(string)
+		sub, ok := argAV["S"].(string)
+		return ok && strings.Contains(s, sub)
+	case "SS", "NS", "BS":
+		elemType := t[:1]
+		if attrType(argAV) != elemType {
+			return false
+		}
+		l, _ := av[t].([]interface{})
+		for _, e := range l {
+			if equalValues(map[string]interface{}{elemType: e}, argAV) {
+				return true
+			}
+		}
+	case "L":
+		l, _ := av[t].([]interface{})
+		for _, e := range l {
+			if equalValues(e, argAV) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// hasPrefix reports whether the string or binary value av begins with
+// prefix, a value of the same type.
+func hasPrefix(av, prefix map[string]interface{}) bool {
+	t := attrType(av)
+	if (t != "S" && t != "B") || attrType(prefix) != t {
+		return false
+	}
+	s, _ := av[t].(string)
+	p, _ := prefix[t].(string)
+	if t == "B" {
+		sb, _ := base64.StdEncoding.DecodeString(s)
+		pb, _ := base64.StdEncoding.DecodeString(p)
+		return bytes.HasPrefix(sb, pb)
+	}
+	return strings.HasPrefix(s, p)
+}
+
+type and struct{ left, right condition }
+
+func (a and) match(item map[string]interface{}) bool {
+	return a.left.match(item) && a.right.match(item)
+}
+
+type or struct{ left, right condition }
+
+func (o or) match(item map[string]interface{}) bool {
+	return o.left.match(item) || o.right.match(item)
+}
+
+type not struct{ x condition }
+
+func (n not) match(item map[string]interface{}) bool { return !n.x.match(item) }
+
+// attrType returns the data type of an attribute value: S, N, B, BOOL,
+// NULL, SS, NS, BS, L, or M.
+func attrType(av map[string]interface{}) string {
+	for t := range av {
+		return t
+	}
+	return ""
+}
+
+// equalValues reports whether two attribute values are the same type and
+// value. Numbers compare numerically and sets ignore element order.
+func equalValues(a, b interface{}) bool {
+	am, _ := a.(map[string]interface{})
+	bm, _ := b.(map[string]interface{})
+	t := attrType(am)
+	if t == "" || t != attrType(bm) {
+		return false
+	}
+	switch t {
+	case "N":
+		cmp, ok := orderValues(am, bm)
+		return ok && cmp == 0
+	case "SS", "NS", "BS":
+		x, _ := am[t].([]interface{})
+		y, _ := bm[t].([]interface{})
+		return sameSet(t, x, y)
+	case "L":
+		x, _ := am[t].([]interface{})
+		y, _ := bm[t].([]interface{})
+		if len(x) != len(y) {
+			return false
+		}
+		for i := range x {
+			if !equalValues(x[i], y[i]) {
+				return false
+			}
+		}
+		return true
+	case "M":
+		x, _ := am[t].(map[string]interface{})
+		y, _ := bm[t].(map[string]interface{})
+		if len(x) != len(y) {
+			return false
+		}
+		for k, v := range x {
+			if !equalValues(v, y[k]) {
+				return false
+			}
+		}
+		return true
+	}
+	return am[t] == bm[t]
+}
+
+func sameSet(t string, x, y []interface{}) bool {
+	canon := func(l []interface{}) []string {
+		out := make([]string, 0, len(l))
+		for _, e := range l {
+			s, _ := e.(string)
+			if t == "NS" {
+				if f, ok := new(big.Float).SetString(s); ok {
+					s = f.Text('g', -1)
+				}
+			}
+			out = append(out, s)
+		}
+		sort.Strings(out)
+		return out
+	}
+	cx, cy := canon(x), canon(y)
+	if len(cx) != len(cy) {
+		return false
+	}
+	for i := range cx {
+		if cx[i] != cy[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// orderValues compares two numbers, strings, or binary values of the same
+// type, reporting false for any other pair.
+func orderValues(a, b interface{}) (int, bool) {
+	am, _ := a.(map[string]interface{})
+	bm, _ := b.(map[string]interface{})
+	t := attrType(am)
+	if t != attrType(bm) {
+		return 0, false
+	}
+	switch t {
+	case "N":
+		x, okx := new(big.Float).SetString(fmt.Sprint(am[t]))
+		y, oky := new(big.Float).SetString(fmt.Sprint(bm[t]))
+		if !okx || !oky {
+			return 0, false
+		}
+		return x.Cmp(y), true
+	case "S", "B":
+		return compareValues(am, bm), true
+	}
+	return 0, false
+}
+
+// exprParser parses one expression parameter of a request, resolving its
+// #name and :value placeholders.
+type exprParser struct {
+	param  string // e.g. "FilterExpression", for error messages
+	toks   []string
+	pos    int
+	names  map[string]interface{}
+	values map[string]interface{}
+	attrs  []string // top-level attributes the expression refers to
+}
+
+func newExprParser(param, expr string, params map[string]interface{}) (*exprParser, error) {
+	toks, err := tokenizeExpr(expr)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid %s: %v", param, err)
+	}
+	if len(toks) == 0 {
+		return nil, fmt.Errorf("Invalid %s: The expression can not be empty;", param)
+	}
+	p := &exprParser{param: param, toks: toks}
+	p.names, _ = params["ExpressionAttributeNames"].(map[string]interface{})
+	p.values, _ = params["ExpressionAttributeValues"].(map[string]interface{})
+	return p, nil
+}
+
+// parseCondition parses the condition expression in params[param], or
+// returns nil if there is none.
+func parseCondition(param string, params map[string]interface{}) (condition, []string, error) {
+	expr := getString(params, param)
+	if expr == "" {
+		return nil, nil, nil
+	}
+	p, err := newExprParser(param, expr, params)
+	if err != nil {
+		return nil, nil, err
+	}
+	c, err := p.condition()
+	if err == nil && p.pos < len(p.toks) {
+		err = p.syntaxError()
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return c, p.attrs, nil
+}
+
+// tokenizeExpr splits an expression into names, placeholders, numbers,
+// and operators.
+func tokenizeExpr(expr string) ([]string, error) {
+	var toks []string
+	for i := 0; i < len(expr); {
+		c := expr[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case strings.HasPrefix(expr[i:], "<>") || strings.HasPrefix(expr[i:], "<=") || strings.HasPrefix(expr[i:], ">="):
+			toks = append(toks, expr[i:i+2])
+			i += 2
+		case strings.IndexByte("=<>(),.[]", c) >= 0:
+			toks = append(toks, string(c))
+			i++
+		default:
+			j := i
+			if c == '#' || c == ':' {
+				j++
+			}
+			for j < len(expr) && isNameByte(expr[j]) {
+				j++
+			}
+			if j == i || (j == i+1 && (c == '#' || c == ':')) {
+				r, _ := utf8.DecodeRuneInString(expr[i:])
+				return nil, fmt.Errorf("Syntax error; token: %q, near: %q", string(r), expr[i:])
+			}
+			toks = append(toks, expr[i:j])
+			i = j
+		}
+	}
+	return toks, nil
+}
+
+func isNameByte(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
+
+func (p *exprParser) peek() string {
+	if p.pos < len(p.toks) {
+		return p.toks[p.pos]
+	}
+	return ""
+}
+
+func (p *exprParser) next() string {
+	t := p.peek()
+	p.pos++
+	return t
+}
+
+// keyword consumes the next token if it is kw, in any case.
+func (p *exprParser) keyword(kw string) bool {
+	if strings.EqualFold(p.peek(), kw) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *exprParser) expect(tok string) error {
+	if p.peek() != tok {
+		return p.syntaxError()
+	}
+	p.pos++
+	return nil
+}
+
+func (p *exprParser) syntaxError() error {
+	if p.pos >= len(p.toks) {
+		return fmt.Errorf("Invalid %s: Syntax error; token: <EOF>, near: %q", p.param, strings.Join(p.toks[max(0, len(p.toks)-2):], " "))
+	}
+	return fmt.Errorf("Invalid %s: Syntax error; token: %q, near: %q", p.param, p.toks[p.pos], strings.Join(p.toks[max(0, p.pos-1):min(len(p.toks), p.pos+2)], " "))
+}
+
+func (p *exprParser) condition() (condition, error) {
+	left, err := p.conjunction()
+	for err == nil && p.keyword("OR") {
+		var right condition
+		if right, err = p.conjunction(); err == nil {
+			left = or{left, right}
+		}
+	}
+	return left, err
+}
+
+func (p *exprParser) conjunction() (condition, error) {
+	left, err := p.negation()
+	for err == nil && p.keyword("AND") {
+		var right condition
+		if right, err = p.negation(); err == nil {
+			left = and{left, right}
+		}
+	}
+	return left, err
+}
+
+func (p *exprParser) negation() (condition, error) {
+	if p.keyword("NOT") {
+		x, err := p.negation()
+		return not{x}, err
+	}
+	if p.peek() == "(" {
+		p.pos++
+		c, err := p.condition()
+		if err == nil {
+			err = p.expect(")")
+		}
+		return c, err
+	}
+	if p.pos+1 < len(p.toks) && p.toks[p.pos+1] == "(" {
+		switch name := p.peek(); name {
+		case "attribute_exists", "attribute_not_exists", "attribute_type", "begins_with", "contains":
+			p.pos += 2
+			return p.call(name)
+		}
+	}
+	left, err := p.operand()
+	if err != nil {
+		return nil, err
+	}
+	switch op := p.peek(); {
+	case op == "=" || op == "<>" || op == "<" || op == "<=" || op == ">" || op == ">=":
+		p.pos++
+		right, err := p.operand()
+		return comparison{op, left, right}, err
+	case strings.EqualFold(op, "BETWEEN"):
+		p.pos++
+		lo, err := p.operand()
+		if err != nil {
+			return nil, err
+		}
+		if !p.keyword("AND") {
+			return nil, p.syntaxError()
+		}
+		hi, err := p.operand()
+		return between{left, lo, hi}, err
+	case strings.EqualFold(op, "IN"):
+		p.pos++
+		if err := p.expect("("); err != nil {
+			return nil, err
+		}
+		n := in{x: left}
+		for {
+			o, err := p.operand()
+			if err != nil {
+				return nil, err
+			}
+			n.list = append(n.list, o)
+			if p.peek() != "," {
+				break
+			}
+			p.pos++
+		}
+		return n, p.expect(")")
+	}
+	return nil, p.syntaxError()
+}
+
+// call parses the arguments of a function, after its opening parenthesis.
+func (p *exprParser) call(name string) (condition, error) {
+	path, err := p.path()
+	if err != nil {
+		return nil, err
+	}
+	c := call{name: name, path: path}
+	if name != "attribute_exists" && name != "attribute_not_exists" {
+		if err := p.expect(","); err != nil {
+			return nil, err
+		}
+		if c.arg, err = p.operand(); err != nil {
+			return nil, err
+		}
+		if name == "attribute_type" {
+			v, _ := c.arg.(literal)
+			av, _ := v.v.(map[string]interface{})
+			switch t, _ := av["S"].(string); t {
+			case "S", "N", "B", "BOOL", "NULL", "SS", "NS", "BS", "L", "M":
+			default:
+				return nil, fmt.Errorf("Invalid %s: Invalid attribute type name found; type: %s, valid types: { B, NULL, SS, BOOL, L, BS, N, NS, S, M }", p.param, t)
+			}
+		}
+	}
+	return c, p.expect(")")
+}
+
+func (p *exprParser) operand() (operand, error) {
+	t := p.peek()
+	switch {
+	case strings.HasPrefix(t, ":"):
+		p.pos++
+		v, ok := p.values[t]
+		if !ok {
+			return nil, fmt.Errorf("Invalid %s: An expression attribute value used in expression is not defined; attribute value: %s", p.param, t)
+		}
+		return literal{v}, nil
+	case t == "size" && p.pos+1 < len(p.toks) && p.toks[p.pos+1] == "(":
+		p.pos += 2
+		path, err := p.path()
+		if err != nil {
+			return nil, err
+		}
+		return sizeOperand{path}, p.expect(")")
+	}
+	return p.path()
+}
+
+// path parses a document path such as a.b[2].#c.
+func (p *exprParser) path() (docPath, error) {
+	var path docPath
+	for {
+		t := p.next()
+		name := t
+		if strings.HasPrefix(t, "#") {
+			n, ok := p.names[t].(string)
+			if !ok {
+				return nil, fmt.Errorf("Invalid %s: An expression attribute name used in the document path is not defined; attribute name: %s", p.param, t)
+			}
+			name = n
+		} else if t == "" || !isNameByte(t[0]) || strings.HasPrefix(t, ":") {
+			p.pos--
+			return nil, p.syntaxError()
+		}
+		if len(path) == 0 {
+			p.attrs = append(p.attrs, name)
+		}
+		path = append(path, pathElem{name: name, index: -1})
+		for p.peek() == "[" {
+			p.pos++
+			n, err := strconv.Atoi(p.peek())
+			if err != nil || n < 0 {
+				return nil, p.syntaxError()
+			}
+			p.pos++
+			if err := p.expect("]"); err != nil {
+				return nil, err
+			}
+			path = append(path, pathElem{index: n})
+		}
+		if p.peek() != "." {
+			return path, nil
+		}
+		p.pos++
+	}
+}
+
+// projection selects document paths from an item, for a
+// ProjectionExpression.
+type projection struct {
+	whole    bool
+	fields   map[string]*projection
+	elems    map[int]*projection
+	selected []docPath
+}
+
+// parseProjection parses the ProjectionExpression in params, or returns
+// nil if there is none.
+func parseProjection(params map[string]interface{}) (*projection, error) {
+	expr := getString(params, "ProjectionExpression")
+	if expr == "" {
+		return nil, nil
+	}
+	p, err := newExprParser("ProjectionExpression", expr, params)
+	if err != nil {
+		return nil, err
+	}
+	root := &projection{}
+	for {
+		path, err := p.path()
+		if err != nil {
+			return nil, err
+		}
+		if err := root.add(path); err != nil {
+			return nil, err
+		}
+		if p.peek() != "," {
+			break
+		}
+		p.pos++
+	}
+	if p.pos < len(p.toks) {
+		return nil, p.syntaxError()
+	}
+	return root, nil
+}
+
+// add selects path, rejecting paths that overlap or conflict with those
+// already selected, as DynamoDB does.
+func (pr *projection) add(path docPath) error {
+	n := pr
+	for _, e := range path {
+		if n.whole {
+			return pr.overlap(path)
+		}
+		if e.index < 0 {
+			if n.elems != nil {
+				return pr.conflict(path)
+			}
+			if n.fields == nil {
+				n.fields = make(map[string]*projection)
+			}
+			child := n.fields[e.name]
+			if child == nil {
+				child = &projection{}
+				n.fields[e.name] = child
+			}
+			n = child
+			continue
+		}
+		if n.fields != nil {
+			return pr.conflict(path)
+		}
+		if n.elems == nil {
+			n.elems = make(map[int]*projection)
+		}
+		child := n.elems[e.index]
+		if child == nil {
+			child = &projection{}
+			n.elems[e.index] = child
+		}
+		n = child
+	}
+	if n.whole || n.fields != nil || n.elems != nil {
+		return pr.overlap(path)
+	}
+	n.whole = true
+	pr.selected = append(pr.selected, path)
+	return nil
+}
+
+func (pr *projection) overlap(path docPath) error {
+	for _, s := range pr.selected {
+		if s.covers(path) || path.covers(s) {
+			return fmt.Errorf("Invalid ProjectionExpression: Two document paths overlap with each other; must remove or rewrite one of these paths; path one: [%s], path two: [%s]", s, path)
+		}
+	}
+	return fmt.Errorf("Invalid ProjectionExpression: Two document paths overlap with each other; path: [%s]", path)
+}
+
+func (pr *projection) conflict(path docPath) error {
+	return fmt.Errorf("Invalid ProjectionExpression: Two document paths conflict with each other; must remove or rewrite one of these paths; path: [%s]", path)
+}
+
+// covers reports whether q is p or lies within it.
+func (p docPath) covers(q docPath) bool {
+	if len(p) > len(q) {
+		return false
+	}
+	for i := range p {
+		if p[i] != q[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// apply returns the parts of item the projection selects. Selected list
+// elements keep their order but are packed together, as in DynamoDB.
+func (pr *projection) apply(item map[string]interface{}) map[string]interface{} {
+	out := make(map[string]interface{})
+	for name, child := range pr.fields {
+		if v, ok := child.extract(item[name]); ok {
+			out[name] = v
+		}
+	}
+	return out
+}
+
+func (pr *projection) extract(v interface{}) (interface{}, bool) {
+	av, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, false
+	}
+	if pr.whole {
+		return av, true
+	}
+	if pr.fields != nil {
+		m, ok := av["M"].(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		out := make(map[string]interface{})
+		for name, child := range pr.fields {
+			if cv, ok := child.extract(m[name]); ok {
+				out[name] = cv
+			}
+		}
+		return map[string]interface{}{"M": out}, len(out) > 0
+	}
+	l, ok := av["L"].([]interface{})
+	if !ok {
+		return nil, false
+	}
+	indexes := make([]int, 0, len(pr.elems))
+	for i := range pr.elems {
+		indexes = append(indexes, i)
+	}
+	sort.Ints(indexes)
+	var out []interface{}
+	for _, i := range indexes {
+		if i >= len(l) {
+			continue
+		}
+		if cv, ok := pr.elems[i].extract(l[i]); ok {
+			out = append(out, cv)
+		}
+	}
+	return map[string]interface{}{"L": out}, len(out) > 0
+}
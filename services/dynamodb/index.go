@@ -0,0 +1,394 @@
+package dynamodb
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+
+	"github.com/riyanimam/goto/internal/cow"
+)
+
+// indexEntry places an item within its partition.
+type indexEntry struct {
+	key  string      // the item's itemKey
+	sort interface{} // the item's sort key value, nil without a sort key
+}
+
+// newIndex gives the table empty item storage. Items are kept in a map keyed
+// by primary key, and each partition lists its items in sort key order, so
+// point reads and writes take constant time and a query only visits its
+// partition.
+func (t *table) newIndex() {
+	t.items = cow.New[string, map[string]interface{}]()
+	t.partitions = cow.New[string, []indexEntry]()
+}
+
+// keyAttrs returns the names of the table's key attributes, partition key
+// first.
+func (t *table) keyAttrs() []string {
+	attrs := []string{t.hashKey()}
+	if rk := t.rangeKey(); rk != "" {
+		attrs = append(attrs, rk)
+	}
+	return attrs
+}
+
+func (t *table) hashKey() string {
+	for _, ks := range t.keySchema {
+		if ks.KeyType == "HASH" {
+			return ks.AttributeName
+		}
+	}
+	if len(t.keySchema) > 0 {
+		return t.keySchema[0].AttributeName
+	}
+	return ""
+}
+
+func (t *table) rangeKey() string {
+	for _, ks := range t.keySchema {
+		if ks.KeyType == "RANGE" {
+			return ks.AttributeName
+		}
+	}
+	return ""
+}
+
+// lookup returns the item with the given key attributes. Caller must hold
+// t.mu.
+func (t *table) lookup(key map[string]interface{}) (map[string]interface{}, bool) {
+	return t.items.Get(itemKey(key, t.keyAttrs()))
+}
+
+// store puts item into the table, returning the item it replaced, if any.
+// Caller must hold t.mu.
+func (t *table) store(item map[string]interface{}) map[string]interface{} {
+	key := itemKey(item, t.keyAttrs())
+	prev, existed := t.items.Get(key)
+	t.items.Set(key, item)
+	if existed {
+		// Same key attributes, so the partition listing is unchanged.
+		return prev
+	}
+
+	part := partitionKey(item[t.hashKey()])
+	entries, _ := t.partitions.Get(part)
+	entry := indexEntry{key: key, sort: item[t.rangeKey()]}
+	i := sort.Search(len(entries), func(i int) bool {
+		return compareValues(entries[i].sort, entry.sort) > 0
+	})
+	if !t.partitions.Owned(part) {
+		// The listing is shared with a checkpoint, so copy it once; later
+		// writes to the partition insert into the copy.
+		entries = append(make([]indexEntry, 0, len(entries)+1), entries...)
+	}
+	entries = append(entries, indexEntry{})
+	copy(entries[i+1:], entries[i:])
+	entries[i] = entry
+	t.partitions.Set(part, entries)
+	return nil
+}
+
+// remove deletes the item with the given key attributes, returning it if it
+// existed. Caller must hold t.mu.
+func (t *table) remove(key map[string]interface{}) map[string]interface{} {
+	k := itemKey(key, t.keyAttrs())
+	prev, existed := t.items.Get(k)
+	if !existed {
+		return nil
+	}
+	t.items.Delete(k)
+
+	part := partitionKey(prev[t.hashKey()])
+	entries, _ := t.partitions.Get(part)
+	if len(entries) == 1 {
+		t.partitions.Delete(part)
+		return prev
+	}
+	i := sort.Search(len(entries), func(i int) bool {
+		return compareValues(entries[i].sort, prev[t.rangeKey()]) >= 0
+	})
+	if t.partitions.Owned(part) {
+		entries = append(entries[:i], entries[i+1:]...)
+	} else {
+		entries = append(append(make([]indexEntry, 0, len(entries)-1), entries[:i]...), entries[i+1:]...)
+	}
+	t.partitions.Set(part, entries)
+	return prev
+}
+
+// query returns the items a Query's key condition selects, in sort key
+// order. The sort key condition is applied by binary search over the
+// partition. Caller must hold t.mu.
+func (t *table) query(kc keyCondition) []map[string]interface{} {
+	entries, _ := t.partitions.Get(partitionKey(kc.partition))
+	search := func(from int, pred func(sortKey interface{}) bool) int {
+		return from + sort.Search(len(entries)-from, func(i int) bool { return pred(entries[from+i].sort) })
+	}
+	atLeast := func(v interface{}) int {
+		return search(0, func(k interface{}) bool { return compareValues(k, v) >= 0 })
+	}
+	above := func(v interface{}) int {
+		return search(0, func(k interface{}) bool { return compareValues(k, v) > 0 })
+	}
+
+	lo, hi := 0, len(entries)
+	switch kc.op {
+	case "=":
+		lo, hi = atLeast(kc.args[0]), above(kc.args[0])
+	case "<":
+		hi = atLeast(kc.args[0])
+	case "<=":
+		hi = above(kc.args[0])
+	case ">":
+		lo = above(kc.args[0])
+	case ">=":
+		lo = atLeast(kc.args[0])
+	case "BETWEEN":
+		lo, hi = atLeast(kc.args[0]), above(kc.args[1])
+	case "begins_with":
+		// Keys with the prefix sort together, starting at the prefix.
+		lo = atLeast(kc.args[0])
+		prefix, _ := kc.args[0].(map[string]interface{})
+		hi = search(lo, func(k interface{}) bool {
+			av, _ := k.(map[string]interface{})
+			return !hasPrefix(av, prefix)
+		})
+	}
+
+	items := make([]map[string]interface{}, 0, max(hi-lo, 0))
+	for _, e := range entries[lo:max(lo, hi)] {
+		if item, ok := t.items.Get(e.key); ok {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// all returns every item, ordered by partition and then by sort key, so
+// scans are repeatable. Caller must hold t.mu.
+func (t *table) all() []map[string]interface{} {
+	parts := make([]string, 0, t.partitions.Len())
+	t.partitions.Range(func(p string, _ []indexEntry) bool {
+		parts = append(parts, p)
+		return true
+	})
+	sort.Strings(parts)
+
+	items := make([]map[string]interface{}, 0, t.items.Len())
+	for _, p := range parts {
+		entries, _ := t.partitions.Get(p)
+		for _, e := range entries {
+			if item, ok := t.items.Get(e.key); ok {
+				items = append(items, item)
+			}
+		}
+	}
+	return items
+}
+
+// compareKeys orders items a and b as [table.all] does: by encoded partition
+// key, then by sort key.
+func (t *table) compareKeys(a, b map[string]interface{}) int {
+	if c := strings.Compare(partitionKey(a[t.hashKey()]), partitionKey(b[t.hashKey()])); c != 0 {
+		return c
+	}
+	return compareValues(a[t.rangeKey()], b[t.rangeKey()])
+}
+
+// partitionKey encodes a partition key value for the index.
+func partitionKey(v interface{}) string {
+	b, _ := json.Marshal(canonicalKey(v))
+	return string(b)
+}
+
+// canonicalKey returns a key attribute value in a form that encodes the same
+// way for equal values: numbers such as 1, 1.0 and 10E-1 all become 1.
+func canonicalKey(v interface{}) interface{} {
+	av, _ := v.(map[string]interface{})
+	n, ok := av["N"].(string)
+	if !ok {
+		return v
+	}
+	r, ok := new(big.Rat).SetString(n)
+	if !ok {
+		return v
+	}
+	return map[string]interface{}{"N": r.RatString()}
+}
+
+// compareValues orders two key attribute values the way DynamoDB orders sort
+// keys: numbers numerically, strings by UTF-8 bytes, and binary by bytes.
+func compareValues(a, b interface{}) int {
+	am, _ := a.(map[string]interface{})
+	bm, _ := b.(map[string]interface{})
+	if n1, ok := am["N"].(string); ok {
+		if n2, ok := bm["N"].(string); ok {
+			x, okx := new(big.Float).SetString(n1)
+			y, oky := new(big.Float).SetString(n2)
+			if okx && oky {
+				return x.Cmp(y)
+			}
+		}
+	}
+	if b1, ok := am["B"].(string); ok {
+		if b2, ok := bm["B"].(string); ok {
+			x, _ := base64.StdEncoding.DecodeString(b1)
+			y, _ := base64.StdEncoding.DecodeString(b2)
+			return bytes.Compare(x, y)
+		}
+	}
+	s1, _ := am["S"].(string)
+	s2, _ := bm["S"].(string)
+	return strings.Compare(s1, s2)
+}
+
+// keyCondition is the key condition of a Query: the partition it reads and
+// an optional condition on the sort key.
+type keyCondition struct {
+	partition interface{}
+	op        string        // "", =, <, <=, >, >=, BETWEEN or begins_with
+	args      []interface{} // the sort key condition's operands
+}
+
+// keyConditionOps maps the legacy KeyConditions comparison operators to
+// their expression equivalents.
+var keyConditionOps = map[string]string{
+	"EQ": "=", "LT": "<", "LE": "<=", "GT": ">", "GE": ">=",
+	"BETWEEN": "BETWEEN", "BEGINS_WITH": "begins_with",
+}
+
+// parseKeyCondition returns the key condition of a Query, from its
+// KeyConditionExpression or legacy KeyConditions.
+func parseKeyCondition(t *table, params map[string]interface{}) (keyCondition, error) {
+	var kc keyCondition
+	conds := map[string]keyCondition{} // by key attribute
+	add := func(attr, op string, args ...interface{}) error {
+		if _, dup := conds[attr]; dup {
+			return fmt.Errorf("KeyConditionExpressions must only contain one condition per key")
+		}
+		conds[attr] = keyCondition{op: op, args: args}
+		return nil
+	}
+
+	switch legacy, _ := params["KeyConditions"].(map[string]interface{}); {
+	case getString(params, "KeyConditionExpression") != "":
+		c, _, err := parseCondition("KeyConditionExpression", params)
+		if err != nil {
+			return kc, err
+		}
+		if err := addKeyClauses(c, add); err != nil {
+			return kc, err
+		}
+	case legacy != nil:
+		for attr, v := range legacy {
+			cond, _ := v.(map[string]interface{})
+			op, ok := keyConditionOps[getString(cond, "ComparisonOperator")]
+			list, _ := cond["AttributeValueList"].([]interface{})
+			if !ok {
+				return kc, fmt.Errorf("Attempted conditional constraint is not an indexable operation")
+			}
+			want := 1
+			if op == "BETWEEN" {
+				want = 2
+			}
+			if len(list) != want {
+				return kc, fmt.Errorf("One or more parameter values were invalid: Invalid number of argument(s) for the %s ComparisonOperator", getString(cond, "ComparisonOperator"))
+			}
+			if err := add(attr, op, list...); err != nil {
+				return kc, err
+			}
+		}
+	default:
+		return kc, fmt.Errorf("Either the KeyConditions or KeyConditionExpression parameter must be specified in the request.")
+	}
+
+	hash, rng := t.hashKey(), t.rangeKey()
+	pc, ok := conds[hash]
+	if !ok {
+		return kc, fmt.Errorf("Query condition missed key schema element: %s", hash)
+	}
+	if pc.op != "=" {
+		return kc, fmt.Errorf("Query key condition not supported")
+	}
+	kc.partition = pc.args[0]
+	for attr, c := range conds {
+		if attr != hash && attr != rng {
+			return kc, fmt.Errorf("Query key condition not supported")
+		}
+		for _, v := range c.args {
+			av, _ := v.(map[string]interface{})
+			if want := t.keyType(attr); want != "" && attrType(av) != want {
+				return kc, fmt.Errorf("One or more parameter values were invalid: Condition parameter type does not match schema type")
+			}
+		}
+	}
+	if sc, ok := conds[rng]; ok && rng != "" {
+		switch {
+		case sc.op == "begins_with" && t.keyType(rng) == "N":
+			return kc, fmt.Errorf("Invalid KeyConditionExpression: Incorrect operand type for operator or function; operator or function: begins_with, operand type: N")
+		case sc.op == "BETWEEN" && compareValues(sc.args[0], sc.args[1]) > 0:
+			return kc, fmt.Errorf("Invalid KeyConditionExpression: The BETWEEN operator requires upper bound to be greater than or equal to lower bound")
+		}
+		kc.op, kc.args = sc.op, sc.args
+	}
+	return kc, nil
+}
+
+// addKeyClauses passes each clause of a parsed KeyConditionExpression to add,
+// rejecting anything a key condition cannot contain.
+func addKeyClauses(c condition, add func(attr, op string, args ...interface{}) error) error {
+	keyAttr := func(o operand) (string, bool) {
+		if p, ok := o.(docPath); ok && len(p) == 1 {
+			return p[0].name, true
+		}
+		return "", false
+	}
+	value := func(o operand) interface{} {
+		l, _ := o.(literal)
+		return l.v
+	}
+	switch c := c.(type) {
+	case and:
+		if err := addKeyClauses(c.left, add); err != nil {
+			return err
+		}
+		return addKeyClauses(c.right, add)
+	case comparison:
+		attr, ok := keyAttr(c.left)
+		if _, lit := c.right.(literal); ok && lit && c.op != "<>" {
+			return add(attr, c.op, value(c.right))
+		}
+	case between:
+		attr, ok := keyAttr(c.x)
+		_, loLit := c.lo.(literal)
+		_, hiLit := c.hi.(literal)
+		if ok && loLit && hiLit {
+			return add(attr, "BETWEEN", value(c.lo), value(c.hi))
+		}
+	case call:
+		if _, lit := c.arg.(literal); c.name == "begins_with" && len(c.path) == 1 && lit {
+			return add(c.path[0].name, "begins_with", value(c.arg))
+		}
+	case or:
+		return fmt.Errorf("Invalid operator used in KeyConditionExpression: OR")
+	case not:
+		return fmt.Errorf("Invalid operator used in KeyConditionExpression: NOT")
+	}
+	return fmt.Errorf("Query key condition not supported")
+}
+
+// keyType returns the type of the key attribute name from the table's
+// attribute definitions, or "" if it is not defined.
+func (t *table) keyType(name string) string {
+	for _, d := range t.attributeDefs {
+		if d.AttributeName == name {
+			return d.AttributeType
+		}
+	}
+	return ""
+}
@@ -0,0 +1,87 @@
+package dynamodb
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/riyanimam/goto/internal/mockhelpers"
+)
+
+// ExportState lists the tables, with their key schema, billing mode, and
+// item count.
+func (s *Service) ExportState() []mockhelpers.Resource {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	resources := make([]mockhelpers.Resource, 0, len(s.tables))
+	for _, t := range s.tables {
+		t.mu.Lock()
+		itemCount := t.items.Len()
+		t.mu.Unlock()
+
+		attributes := make(map[string]string, len(t.attributeDefs))
+		for _, def := range t.attributeDefs {
+			attributes[def.AttributeName] = def.AttributeType
+		}
+		attrs := map[string]interface{}{
+			"name":         t.name,
+			"billing_mode": t.billingMode,
+			"attribute":    attributes,
+			"item_count":   itemCount,
+		}
+		for _, k := range t.keySchema {
+			if k.KeyType == "HASH" {
+				attrs["hash_key"] = k.AttributeName
+			} else {
+				attrs["range_key"] = k.AttributeName
+			}
+		}
+		if t.billingMode == "PROVISIONED" {
+			attrs["read_capacity"] = t.provisionedRead
+			attrs["write_capacity"] = t.provisionedWrite
+		}
+		resources = append(resources, mockhelpers.Resource{
+			Type:       "aws_dynamodb_table",
+			ID:         t.name,
+			ARN:        t.arn,
+			Attributes: attrs,
+		})
+	}
+	return resources
+}
+
+// Items returns the table's items in DynamoDB JSON, ordered by primary key.
+// They are copies, so changing them does not change the table.
+func (s *Service) Items(tableName string) ([]map[string]interface{}, error) {
+	s.mu.RLock()
+	t, exists := s.tables[tableName]
+	s.mu.RUnlock()
+
+	if !exists {
+		return nil, fmt.Errorf("table %s does not exist", tableName)
+	}
+
+	t.mu.Lock()
+	keys := make([]string, 0, t.items.Len())
+	t.items.Range(func(k string, _ map[string]interface{}) bool {
+		keys = append(keys, k)
+		return true
+	})
+	sort.Strings(keys)
+	ordered := make([]map[string]interface{}, len(keys))
+	for i, k := range keys {
+		ordered[i], _ = t.items.Get(k)
+	}
+	data, err := json.Marshal(ordered)
+	t.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	var items []map[string]interface{}
+	if err := json.Unmarshal(data, &items); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
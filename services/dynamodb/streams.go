@@ -0,0 +1,124 @@
+package dynamodb
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// changeStream is a table's stream of item-level changes, enabled with
+// CreateTable's StreamSpecification. Its records are read by consumers of
+// the mock's event sources, such as pipes.
+type changeStream struct {
+	arn      string
+	label    string
+	viewType string // KEYS_ONLY, NEW_IMAGE, OLD_IMAGE or NEW_AND_OLD_IMAGES
+	records  []map[string]interface{}
+}
+
+var streamViewTypes = map[string]bool{
+	"KEYS_ONLY":          true,
+	"NEW_IMAGE":          true,
+	"OLD_IMAGE":          true,
+	"NEW_AND_OLD_IMAGES": true,
+}
+
+// newChangeStream returns an empty stream for the table with ARN tableArn,
+// labelled with the time it was enabled.
+func newChangeStream(tableArn, viewType string, enabled time.Time) *changeStream {
+	label := enabled.Format("2006-01-02T15:04:05.000")
+	return &changeStream{
+		arn:      tableArn + "/stream/" + label,
+		label:    label,
+		viewType: viewType,
+	}
+}
+
+// capture appends a stream record for a change to the item with the given
+// keys, if the table has a stream. old and new are nil for inserts and
+// removals respectively. The caller must hold t.mu.
+func (t *table) capture(eventName string, old, new map[string]interface{}) {
+	cs := t.stream
+	if cs == nil {
+		return
+	}
+	item := new
+	if item == nil {
+		item = old
+	}
+	keys := make(map[string]interface{})
+	for _, attr := range t.keyAttrs() {
+		keys[attr] = item[attr]
+	}
+	change := map[string]interface{}{
+		"ApproximateCreationDateTime": float64(time.Now().Unix()),
+		"Keys":                        keys,
+		"SequenceNumber":              fmt.Sprintf("%021d", (len(cs.records)+1)*100),
+		"StreamViewType":              cs.viewType,
+	}
+	if new != nil && (cs.viewType == "NEW_IMAGE" || cs.viewType == "NEW_AND_OLD_IMAGES") {
+		change["NewImage"] = new
+	}
+	if old != nil && (cs.viewType == "OLD_IMAGE" || cs.viewType == "NEW_AND_OLD_IMAGES") {
+		change["OldImage"] = old
+	}
+	raw, _ := json.Marshal(change)
+	change["SizeBytes"] = len(raw)
+	cs.records = append(cs.records, map[string]interface{}{
+		"eventID":        newRequestID(),
+		"eventName":      eventName,
+		"eventVersion":   "1.1",
+		"eventSource":    "aws:dynamodb",
+		"awsRegion":      "us-east-1",
+		"dynamodb":       change,
+		"eventSourceARN": cs.arn,
+	})
+}
+
+// ReadEvents returns up to max records of the table stream identified by
+// arn, shaped as DynamoDB Streams event records, starting at position: ""
+// or "TRIM_HORIZON", "LATEST", or a position previously returned.
+func (s *Service) ReadEvents(arn, position string, max int) ([]map[string]interface{}, string, error) {
+	s.mu.RLock()
+	var t *table
+	for _, candidate := range s.tables {
+		if candidate.stream != nil && candidate.stream.arn == arn {
+			t = candidate
+			break
+		}
+	}
+	s.mu.RUnlock()
+	if t == nil {
+		return nil, "", fmt.Errorf("stream %s does not exist", arn)
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	records := t.stream.records
+	var start int
+	switch position {
+	case "", "TRIM_HORIZON":
+	case "LATEST":
+		start = len(records)
+	default:
+		n, err := strconv.Atoi(position)
+		if err != nil {
+			return nil, "", fmt.Errorf("invalid stream position %q", position)
+		}
+		start = n
+	}
+	if start > len(records) {
+		start = len(records)
+	}
+	end := len(records)
+	if max > 0 && start+max < end {
+		end = start + max
+	}
+	return append([]map[string]interface{}(nil), records[start:end]...), strconv.Itoa(end), nil
+}
+
+// AckEvents does nothing: stream consumers track their own position.
+func (s *Service) AckEvents(string, []map[string]interface{}, bool) error {
+	return nil
+}
@@ -0,0 +1,106 @@
+package dynamodb
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+
+	"github.com/riyanimam/goto/internal/clock"
+)
+
+// capacity is a table's unused read or write capacity. It refills at the
+// provisioned rate, up to one second's worth, as time passes in real time or
+// on the mock clock, whichever has moved further.
+type capacity struct {
+	units float64
+	last  clock.Mark
+}
+
+// SetClock attaches the mock clock used to refill provisioned capacity.
+func (s *Service) SetClock(c *clock.Clock) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.clock = c
+}
+
+// EnforceThroughput makes requests against PROVISIONED tables consume read
+// and write capacity units, failing with ProvisionedThroughputExceededException
+// once a table's capacity for the current second is used up. Capacity refills
+// over time, and at once when the mock clock is advanced.
+func (s *Service) EnforceThroughput(enforce bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.enforceThroughput = enforce
+}
+
+// SetQuota limits how many resources of a kind can exist. The only
+// supported resource is "tables".
+func (s *Service) SetQuota(resource string, limit int) error {
+	if resource != "tables" {
+		return fmt.Errorf("dynamodb has no %q quota", resource)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tableQuota = limit
+	return nil
+}
+
+// throughputExceeded is the message DynamoDB returns when a table runs out
+// of provisioned capacity.
+const throughputExceeded = "The level of configured provisioned throughput for the table was exceeded. Consider increasing your provisioning level with the UpdateTable API."
+
+// consume charges units of capacity to the table, reporting false if the
+// table does not have enough left.
+func (s *Service) consume(t *table, write bool, units float64) bool {
+	s.mu.RLock()
+	enforce, c := s.enforceThroughput, s.clock
+	s.mu.RUnlock()
+
+	if !enforce || t.billingMode != "PROVISIONED" {
+		return true
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	pool, provisioned := &t.readCapacity, float64(t.provisionedRead)
+	if write {
+		pool, provisioned = &t.writeCapacity, float64(t.provisionedWrite)
+	}
+	if pool.last == (clock.Mark{}) {
+		pool.units = provisioned
+	} else {
+		pool.units = math.Min(provisioned, pool.units+provisioned*c.Since(pool.last).Seconds())
+	}
+	pool.last = c.Mark()
+	if pool.units < units {
+		return false
+	}
+	pool.units -= units
+	return true
+}
+
+// readUnits is the read capacity a read of items costs: one unit per 4 KB,
+// halved for eventually consistent reads.
+func readUnits(consistent bool, items ...map[string]interface{}) float64 {
+	units := math.Max(1, math.Ceil(float64(itemsSize(items))/4096))
+	if !consistent {
+		units /= 2
+	}
+	return units
+}
+
+// writeUnits is the write capacity writing item costs: one unit per 1 KB.
+func writeUnits(item map[string]interface{}) float64 {
+	return math.Max(1, math.Ceil(float64(itemsSize([]map[string]interface{}{item}))/1024))
+}
+
+func itemsSize(items []map[string]interface{}) int {
+	size := 0
+	for _, item := range items {
+		if item == nil {
+			continue
+		}
+		b, _ := json.Marshal(item)
+		size += len(b)
+	}
+	return size
+}
@@ -8,11 +8,7 @@
 package dynamodbstreams
 
 import (
-	"encoding/json"
-	"fmt"
-	"io"
 	"net/http"
-	"strings"
 	"sync"
 
 	h "github.com/riyanimam/goto/internal/mockhelpers"
@@ -56,7 +52,12 @@ func (s *Service) Name() string { return "streams.dynamodb" }
 
 // Handler returns the HTTP handler for DynamoDB Streams requests.
 func (s *Service) Handler() http.Handler {
-	return http.HandlerFunc(s.handle)
+	return h.JSONRouter{
+		"ListStreams":      s.listStreams,
+		"DescribeStream":   s.describeStream,
+		"GetShardIterator": s.getShardIterator,
+		"GetRecords":       s.getRecords,
+	}
 }
 
 // Reset clears all state.
@@ -82,48 +83,6 @@ func (s *Service) AddStream(arn, label, tableName string) {
 	}
 }
 
-func (s *Service) handle(w http.ResponseWriter, r *http.Request) {
-	target := r.Header.Get("X-Amz-Target")
-
-	bodyBytes, err := io.ReadAll(r.Body)
-	if err != nil {
-		h.WriteJSONError(w, "InternalFailure", "could not read request body", http.StatusInternalServerError)
-		return
-	}
-
-	var params map[string]interface{}
-	if len(bodyBytes) > 0 {
-		if err := json.Unmarshal(bodyBytes, &params); err != nil {
-			h.WriteJSONError(w, "SerializationException", "could not parse request body", http.StatusBadRequest)
-			return
-		}
-	}
-	if params == nil {
-		params = make(map[string]interface{})
-	}
-
-	action := ""
-	if target != "" {
-		parts := strings.SplitN(target, ".", 2)
-		if len(parts) == 2 {
-			action = parts[1]
-		}
-	}
-
-	switch action {
-	case "ListStreams":
-		s.listStreams(w, params)
-	case "DescribeStream":
-		s.describeStream(w, params)
-	case "GetShardIterator":
-		s.getShardIterator(w, params)
-	case "GetRecords":
-		s.getRecords(w, params)
-	default:
-		h.WriteJSONError(w, "UnknownOperationException", fmt.Sprintf("action %q is not supported", action), http.StatusBadRequest)
-	}
-}
-
 func (s *Service) listStreams(w http.ResponseWriter, params map[string]interface{}) {
 	tableFilter := h.GetString(params, "TableName")
 
@@ -13,6 +13,21 @@
 //   - CreateSubnet
 //   - DescribeSubnets
 //   - DeleteSubnet
+//   - CreateTags
+//   - DeleteTags
+//   - DescribeTags
+//   - DescribeRegions
+//   - DescribeAvailabilityZones
+//   - DescribeAccountAttributes
+//   - DescribeInstanceTypes
+//   - CreateFlowLogs
+//   - DescribeFlowLogs
+//   - DeleteFlowLogs
+//
+// Availability zones are those of the region a request is signed for, and
+// instance types come from a built-in catalog of common types. Flow logs
+// write the records passed to EmitFlowLogRecords to their CloudWatch Logs
+// group or S3 bucket.
 package ec2
 
 import (
@@ -20,8 +35,15 @@ import (
 	"fmt"
 	"math/rand"
 	"net/http"
+	"sort"
+	"strconv"
 	"sync"
 	"time"
+
+	"github.com/riyanimam/goto/internal/awserr"
+	"github.com/riyanimam/goto/internal/mockhelpers"
+	"github.com/riyanimam/goto/internal/paginate"
+	"github.com/riyanimam/goto/internal/tags"
 )
 
 const defaultAccountID = "123456789012"
@@ -37,6 +59,11 @@ type Service struct {
 	vpcCounter      int
 	sgCounter       int
 	subnetCounter   int
+	flowLogs        map[string]*flowLog
+	flowLogCounter  int
+	tags            *tags.Store
+	store           mockhelpers.ObjectStore
+	dispatch        mockhelpers.Dispatcher
 }
 
 type instance struct {
@@ -79,6 +106,8 @@ func New() *Service {
 		vpcs:           make(map[string]*vpc),
 		securityGroups: make(map[string]*securityGroup),
 		subnets:        make(map[string]*subnet),
+		flowLogs:       make(map[string]*flowLog),
+		tags:           tags.New(),
 	}
 }
 
@@ -87,7 +116,33 @@ func (s *Service) Name() string { return "ec2" }
 
 // Handler returns the HTTP handler for EC2 requests.
 func (s *Service) Handler() http.Handler {
-	return http.HandlerFunc(s.handle)
+	return mockhelpers.QueryRouter{
+		Actions: map[string]http.HandlerFunc{
+			"RunInstances":              s.runInstances,
+			"DescribeInstances":         s.describeInstances,
+			"TerminateInstances":        s.terminateInstances,
+			"CreateVpc":                 s.createVpc,
+			"DescribeVpcs":              s.describeVpcs,
+			"DeleteVpc":                 s.deleteVpc,
+			"CreateSecurityGroup":       s.createSecurityGroup,
+			"DescribeSecurityGroups":    s.describeSecurityGroups,
+			"DeleteSecurityGroup":       s.deleteSecurityGroup,
+			"CreateSubnet":              s.createSubnet,
+			"DescribeSubnets":           s.describeSubnets,
+			"DeleteSubnet":              s.deleteSubnet,
+			"CreateTags":                s.createTags,
+			"DeleteTags":                s.deleteTags,
+			"DescribeTags":              s.describeTags,
+			"DescribeRegions":           s.describeRegions,
+			"DescribeAvailabilityZones": s.describeAvailabilityZones,
+			"DescribeAccountAttributes": s.describeAccountAttributes,
+			"DescribeInstanceTypes":     s.describeInstanceTypes,
+			"CreateFlowLogs":            s.createFlowLogs,
+			"DescribeFlowLogs":          s.describeFlowLogs,
+			"DeleteFlowLogs":            s.deleteFlowLogs,
+		},
+		Error: writeEC2Error,
+	}
 }
 
 // Reset clears all state.
@@ -98,47 +153,20 @@ func (s *Service) Reset() {
 	s.vpcs = make(map[string]*vpc)
 	s.securityGroups = make(map[string]*securityGroup)
 	s.subnets = make(map[string]*subnet)
+	s.flowLogs = make(map[string]*flowLog)
 	s.instanceCounter = 0
 	s.vpcCounter = 0
 	s.sgCounter = 0
 	s.subnetCounter = 0
+	s.flowLogCounter = 0
+	s.tags.DeleteService("ec2")
 }
 
-func (s *Service) handle(w http.ResponseWriter, r *http.Request) {
-	if err := r.ParseForm(); err != nil {
-		writeEC2Error(w, "InvalidRequest", "could not parse request", http.StatusBadRequest)
-		return
-	}
-
-	action := r.FormValue("Action")
-	switch action {
-	case "RunInstances":
-		s.runInstances(w, r)
-	case "DescribeInstances":
-		s.describeInstances(w, r)
-	case "TerminateInstances":
-		s.terminateInstances(w, r)
-	case "CreateVpc":
-		s.createVpc(w, r)
-	case "DescribeVpcs":
-		s.describeVpcs(w, r)
-	case "DeleteVpc":
-		s.deleteVpc(w, r)
-	case "CreateSecurityGroup":
-		s.createSecurityGroup(w, r)
-	case "DescribeSecurityGroups":
-		s.describeSecurityGroups(w, r)
-	case "DeleteSecurityGroup":
-		s.deleteSecurityGroup(w, r)
-	case "CreateSubnet":
-		s.createSubnet(w, r)
-	case "DescribeSubnets":
-		s.describeSubnets(w, r)
-	case "DeleteSubnet":
-		s.deleteSubnet(w, r)
-	default:
-		writeEC2Error(w, "UnsupportedOperation", fmt.Sprintf("action %q is not supported", action), http.StatusBadRequest)
-	}
+// SetTagStore sets the registry resource tags are recorded in.
+func (s *Service) SetTagStore(store *tags.Store) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tags = store
 }
 
 func (s *Service) runInstances(w http.ResponseWriter, r *http.Request) {
@@ -167,7 +195,8 @@ func (s *Service) runInstances(w http.ResponseWriter, r *http.Request) {
 			privateIP:    fmt.Sprintf("10.0.%d.%d", rand.Intn(255), rand.Intn(255)+1),
 		}
 		s.instances[inst.id] = inst
-		items = append(items, instanceToXML(inst))
+		s.tagOnCreate(r, inst.id)
+		items = append(items, s.instanceToXML(inst))
 	}
 	s.mu.Unlock()
 
@@ -178,21 +207,40 @@ func (s *Service) runInstances(w http.ResponseWriter, r *http.Request) {
 	writeXML(w, http.StatusOK, resp)
 }
 
-func (s *Service) describeInstances(w http.ResponseWriter, _ *http.Request) {
+func (s *Service) describeInstances(w http.ResponseWriter, r *http.Request) {
 	s.mu.RLock()
 	var items []ec2Instance
 	for _, inst := range s.instances {
-		items = append(items, instanceToXML(inst))
+		items = append(items, s.instanceToXML(inst))
 	}
 	s.mu.RUnlock()
 
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].InstanceID < items[j].InstanceID
+	})
+
+	// Without MaxResults, every instance is returned in one page.
+	limit, _ := strconv.Atoi(r.FormValue("MaxResults"))
+	if limit > 0 && limit < 5 {
+		limit = 5
+	}
+	page, next, err := paginate.Page(items, func(i int) string { return items[i].InstanceID }, r.FormValue("NextToken"), limit, 1000)
+	if err != nil {
+		writeEC2Error(w, "InvalidPaginationToken", "The pagination token is not valid.", http.StatusBadRequest)
+		return
+	}
+	if limit == 0 {
+		next = ""
+	}
+
 	resp := describeInstancesResponse{
 		RequestID: newRequestID(),
 		Reservations: []reservation{{
 			ReservationID: "r-" + newRequestID()[:8],
 			OwnerID:       defaultAccountID,
-			Instances:     items,
+			Instances:     page,
 		}},
+		NextToken: next,
 	}
 	writeXML(w, http.StatusOK, resp)
 }
@@ -238,11 +286,12 @@ func (s *Service) createVpc(w http.ResponseWriter, r *http.Request) {
 		state:     "available",
 	}
 	s.vpcs[v.id] = v
+	s.tagOnCreate(r, v.id)
 	s.mu.Unlock()
 
 	resp := createVpcResponse{
 		RequestID: newRequestID(),
-		Vpc:       vpcToXML(v),
+		Vpc:       s.vpcToXML(v),
 	}
 	writeXML(w, http.StatusOK, resp)
 }
@@ -251,7 +300,7 @@ func (s *Service) describeVpcs(w http.ResponseWriter, _ *http.Request) {
 	s.mu.RLock()
 	var items []ec2Vpc
 	for _, v := range s.vpcs {
-		items = append(items, vpcToXML(v))
+		items = append(items, s.vpcToXML(v))
 	}
 	s.mu.RUnlock()
 
@@ -267,6 +316,8 @@ func (s *Service) deleteVpc(w http.ResponseWriter, r *http.Request) {
 
 	s.mu.Lock()
 	delete(s.vpcs, id)
+	s.tags.Delete(resourceARN(id))
+	s.deleteFlowLogsOf(id)
 	s.mu.Unlock()
 
 	resp := simpleResponse{RequestID: newRequestID(), Return: true}
@@ -287,6 +338,7 @@ func (s *Service) createSecurityGroup(w http.ResponseWriter, r *http.Request) {
 		vpcID:       vpcID,
 	}
 	s.securityGroups[sg.id] = sg
+	s.tagOnCreate(r, sg.id)
 	s.mu.Unlock()
 
 	resp := createSecurityGroupResponse{
@@ -306,6 +358,7 @@ func (s *Service) describeSecurityGroups(w http.ResponseWriter, _ *http.Request)
 			Description: sg.description,
 			VpcID:       sg.vpcID,
 			OwnerID:     defaultAccountID,
+			Tags:        s.tagSet(sg.id),
 		})
 	}
 	s.mu.RUnlock()
@@ -322,6 +375,7 @@ func (s *Service) deleteSecurityGroup(w http.ResponseWriter, r *http.Request) {
 
 	s.mu.Lock()
 	delete(s.securityGroups, id)
+	s.tags.Delete(resourceARN(id))
 	s.mu.Unlock()
 
 	resp := simpleResponse{RequestID: newRequestID(), Return: true}
@@ -333,7 +387,7 @@ func (s *Service) createSubnet(w http.ResponseWriter, r *http.Request) {
 	cidr := r.FormValue("CidrBlock")
 	az := r.FormValue("AvailabilityZone")
 	if az == "" {
-		az = "us-east-1a"
+		az = zonesOf(regionOf(r))[0].ZoneName
 	}
 
 	s.mu.Lock()
@@ -346,11 +400,12 @@ func (s *Service) createSubnet(w http.ResponseWriter, r *http.Request) {
 		state:            "available",
 	}
 	s.subnets[sn.id] = sn
+	s.tagOnCreate(r, sn.id)
 	s.mu.Unlock()
 
 	resp := createSubnetResponse{
 		RequestID: newRequestID(),
-		Subnet:    subnetToXML(sn),
+		Subnet:    s.subnetToXML(sn),
 	}
 	writeXML(w, http.StatusOK, resp)
 }
@@ -359,7 +414,7 @@ func (s *Service) describeSubnets(w http.ResponseWriter, _ *http.Request) {
 	s.mu.RLock()
 	var items []ec2Subnet
 	for _, sn := range s.subnets {
-		items = append(items, subnetToXML(sn))
+		items = append(items, s.subnetToXML(sn))
 	}
 	s.mu.RUnlock()
 
@@ -375,15 +430,17 @@ func (s *Service) deleteSubnet(w http.ResponseWriter, r *http.Request) {
 
 	s.mu.Lock()
 	delete(s.subnets, id)
+	s.tags.Delete(resourceARN(id))
+	s.deleteFlowLogsOf(id)
 	s.mu.Unlock()
 
 	resp := simpleResponse{RequestID: newRequestID(), Return: true}
 	writeXML(w, http.StatusOK, resp)
 }
 
-// XML helpers.
+// XML helpers. The caller must hold s.mu.
 
-func instanceToXML(inst *instance) ec2Instance {
+func (s *Service) instanceToXML(inst *instance) ec2Instance {
 	return ec2Instance{
 		InstanceID:   inst.id,
 		ImageID:      inst.imageID,
@@ -391,25 +448,28 @@ func instanceToXML(inst *instance) ec2Instance {
 		State:        instanceState{Code: inst.stateCode, Name: inst.state},
 		LaunchTime:   inst.launchTime.Format(time.RFC3339),
 		PrivateIP:    inst.privateIP,
+		Tags:         s.tagSet(inst.id),
 	}
 }
 
-func vpcToXML(v *vpc) ec2Vpc {
+func (s *Service) vpcToXML(v *vpc) ec2Vpc {
 	return ec2Vpc{
 		VpcID:     v.id,
 		CidrBlock: v.cidrBlock,
 		State:     v.state,
 		OwnerID:   defaultAccountID,
+		Tags:      s.tagSet(v.id),
 	}
 }
 
-func subnetToXML(sn *subnet) ec2Subnet {
+func (s *Service) subnetToXML(sn *subnet) ec2Subnet {
 	return ec2Subnet{
 		SubnetID:         sn.id,
 		VpcID:            sn.vpcID,
 		CidrBlock:        sn.cidrBlock,
 		AvailabilityZone: sn.availabilityZone,
 		State:            sn.state,
+		Tags:             s.tagSet(sn.id),
 	}
 }
 
@@ -422,6 +482,7 @@ type ec2Instance struct {
 	State        instanceState `xml:"instanceState"`
 	LaunchTime   string        `xml:"launchTime"`
 	PrivateIP    string        `xml:"privateIpAddress"`
+	Tags         []ec2Tag      `xml:"tagSet>item"`
 }
 
 type instanceState struct {
@@ -430,26 +491,29 @@ type instanceState struct {
 }
 
 type ec2Vpc struct {
-	VpcID     string `xml:"vpcId"`
-	CidrBlock string `xml:"cidrBlock"`
-	State     string `xml:"state"`
-	OwnerID   string `xml:"ownerId"`
+	VpcID     string   `xml:"vpcId"`
+	CidrBlock string   `xml:"cidrBlock"`
+	State     string   `xml:"state"`
+	OwnerID   string   `xml:"ownerId"`
+	Tags      []ec2Tag `xml:"tagSet>item"`
 }
 
 type ec2SecurityGroup struct {
-	GroupID     string `xml:"groupId"`
-	GroupName   string `xml:"groupName"`
-	Description string `xml:"groupDescription"`
-	VpcID       string `xml:"vpcId"`
-	OwnerID     string `xml:"ownerId"`
+	GroupID     string   `xml:"groupId"`
+	GroupName   string   `xml:"groupName"`
+	Description string   `xml:"groupDescription"`
+	VpcID       string   `xml:"vpcId"`
+	OwnerID     string   `xml:"ownerId"`
+	Tags        []ec2Tag `xml:"tagSet>item"`
 }
 
 type ec2Subnet struct {
-	SubnetID         string `xml:"subnetId"`
-	VpcID            string `xml:"vpcId"`
-	CidrBlock        string `xml:"cidrBlock"`
-	AvailabilityZone string `xml:"availabilityZone"`
-	State            string `xml:"state"`
+	SubnetID         string   `xml:"subnetId"`
+	VpcID            string   `xml:"vpcId"`
+	CidrBlock        string   `xml:"cidrBlock"`
+	AvailabilityZone string   `xml:"availabilityZone"`
+	State            string   `xml:"state"`
+	Tags             []ec2Tag `xml:"tagSet>item"`
 }
 
 type instanceStateChange struct {
@@ -474,6 +538,7 @@ type describeInstancesResponse struct {
 	XMLName      xml.Name      `xml:"DescribeInstancesResponse"`
 	RequestID    string        `xml:"requestId"`
 	Reservations []reservation `xml:"reservationSet>item"`
+	NextToken    string        `xml:"nextToken,omitempty"`
 }
 
 type terminateInstancesResponse struct {
@@ -524,17 +589,6 @@ type simpleResponse struct {
 	Return    bool     `xml:"return"`
 }
 
-type ec2ErrorResponse struct {
-	XMLName   xml.Name `xml:"Response"`
-	Errors    []ec2Err `xml:"Errors>Error"`
-	RequestID string   `xml:"RequestID"`
-}
-
-type ec2Err struct {
-	Code    string `xml:"Code"`
-	Message string `xml:"Message"`
-}
-
 func writeXML(w http.ResponseWriter, status int, v interface{}) {
 	w.Header().Set("Content-Type", "text/xml; charset=utf-8")
 	w.WriteHeader(status)
@@ -543,11 +597,7 @@ func writeXML(w http.ResponseWriter, status int, v interface{}) {
 }
 
 func writeEC2Error(w http.ResponseWriter, code, message string, status int) {
-	resp := ec2ErrorResponse{
-		Errors:    []ec2Err{{Code: code, Message: message}},
-		RequestID: newRequestID(),
-	}
-	writeXML(w, status, resp)
+	awserr.WriteEC2(w, code, message, status)
 }
 
 func newRequestID() string {
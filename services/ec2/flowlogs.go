@@ -0,0 +1,528 @@
+package ec2
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/xml"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/riyanimam/goto/internal/mockhelpers"
+	"github.com/riyanimam/goto/internal/paginate"
+)
+
+// defaultFlowLogFormat is the record format of flow logs created without a
+// LogFormat.
+const defaultFlowLogFormat = "${version} ${account-id} ${interface-id} ${srcaddr} ${dstaddr} ${srcport} ${dstport} ${protocol} ${packets} ${bytes} ${start} ${end} ${action} ${log-status}"
+
+// flowLog is a VPC flow log.
+type flowLog struct {
+	id              string
+	resourceID      string
+	trafficType     string
+	destinationType string // cloud-watch-logs or s3
+	logGroupName    string
+	destination     string // log group or bucket ARN
+	permissionARN   string
+	format          string
+	aggregation     int
+	region          string
+	created         time.Time
+	deliverStatus   string
+	deliverError    string
+}
+
+// FlowLogRecord is network traffic to record in the flow logs of a VPC,
+// subnet, or network interface. Zero fields get defaults: Action ACCEPT,
+// Protocol 6 (TCP), Start and End the current time, and InterfaceID the
+// network interface the records are emitted on, or a random one.
+type FlowLogRecord struct {
+	InterfaceID string
+	InstanceID  string
+	SrcAddr     string
+	DstAddr     string
+	SrcPort     int
+	DstPort     int
+	Protocol    int
+	Packets     int64
+	Bytes       int64
+	Start       time.Time
+	End         time.Time
+	Action      string // ACCEPT or REJECT
+}
+
+// SetObjectStore sets the store flow logs with an S3 destination are
+// written to.
+func (s *Service) SetObjectStore(store mockhelpers.ObjectStore) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.store = store
+}
+
+// SetDispatcher sets the function flow log records are delivered to
+// CloudWatch Logs with.
+func (s *Service) SetDispatcher(d mockhelpers.Dispatcher) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.dispatch = d
+}
+
+func (s *Service) createFlowLogs(w http.ResponseWriter, r *http.Request) {
+	resourceType := r.FormValue("ResourceType")
+	trafficType := r.FormValue("TrafficType")
+	if trafficType == "" {
+		trafficType = "ALL"
+	}
+	destType := r.FormValue("LogDestinationType")
+	if destType == "" {
+		destType = "cloud-watch-logs"
+	}
+	groupName := r.FormValue("LogGroupName")
+	destination := r.FormValue("LogDestination")
+	permission := r.FormValue("DeliverLogsPermissionArn")
+	format := r.FormValue("LogFormat")
+	if format == "" {
+		format = defaultFlowLogFormat
+	}
+	aggregation := 600
+	if v := r.FormValue("MaxAggregationInterval"); v != "" {
+		aggregation, _ = strconv.Atoi(v)
+	}
+
+	switch {
+	case resourceType != "VPC" && resourceType != "Subnet" && resourceType != "NetworkInterface":
+		writeEC2Error(w, "InvalidParameterValue", fmt.Sprintf("Invalid resource type: %s", resourceType), http.StatusBadRequest)
+		return
+	case trafficType != "ACCEPT" && trafficType != "REJECT" && trafficType != "ALL":
+		writeEC2Error(w, "InvalidParameterValue", fmt.Sprintf("Invalid traffic type: %s", trafficType), http.StatusBadRequest)
+		return
+	case aggregation != 60 && aggregation != 600:
+		writeEC2Error(w, "InvalidParameterValue", "MaxAggregationInterval must be 60 or 600", http.StatusBadRequest)
+		return
+	}
+	switch destType {
+	case "cloud-watch-logs":
+		if groupName == "" && destination == "" {
+			writeEC2Error(w, "InvalidParameter", "Either LogGroupName or LogDestination must be specified", http.StatusBadRequest)
+			return
+		}
+		if groupName == "" {
+			_, name, ok := strings.Cut(destination, ":log-group:")
+			if !ok {
+				writeEC2Error(w, "InvalidParameter", "LogDestination must be a log group ARN", http.StatusBadRequest)
+				return
+			}
+			groupName = strings.TrimSuffix(name, ":*")
+		}
+		destination = fmt.Sprintf("arn:aws:logs:%s:%s:log-group:%s", regionOf(r), defaultAccountID, groupName)
+		if permission == "" {
+			writeEC2Error(w, "InvalidParameter", "DeliverLogsPermissionArn is required for cloud-watch-logs destinations", http.StatusBadRequest)
+			return
+		}
+	case "s3":
+		if !strings.HasPrefix(destination, "arn:aws:s3:::") {
+			writeEC2Error(w, "InvalidParameter", "LogDestination must be an S3 bucket ARN", http.StatusBadRequest)
+			return
+		}
+	default:
+		writeEC2Error(w, "InvalidParameterValue", fmt.Sprintf("Invalid log destination type: %s", destType), http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	var ids []string
+	var failures []unsuccessfulItem
+	for _, resourceID := range list(r, "ResourceId") {
+		if code := s.flowLogResourceError(resourceType, resourceID); code != "" {
+			failures = append(failures, unsuccessfulItem{
+				ResourceID: resourceID,
+				Error:      unsuccessfulError{Code: code, Message: fmt.Sprintf("The ID '%s' does not exist", resourceID)},
+			})
+			continue
+		}
+		if s.hasFlowLog(resourceID, trafficType, destination) {
+			failures = append(failures, unsuccessfulItem{
+				ResourceID: resourceID,
+				Error:      unsuccessfulError{Code: "FlowLogAlreadyExists", Message: "Error. There is an existing Flow Log with the same configuration and log destination."},
+			})
+			continue
+		}
+		s.flowLogCounter++
+		fl := &flowLog{
+			id:              fmt.Sprintf("fl-%017x", s.flowLogCounter),
+			resourceID:      resourceID,
+			trafficType:     trafficType,
+			destinationType: destType,
+			logGroupName:    groupName,
+			destination:     destination,
+			permissionARN:   permission,
+			format:          format,
+			aggregation:     aggregation,
+			region:          regionOf(r),
+			created:         time.Now().UTC(),
+			deliverStatus:   "SUCCESS",
+		}
+		s.flowLogs[fl.id] = fl
+		s.tagOnCreate(r, fl.id)
+		ids = append(ids, fl.id)
+	}
+	s.mu.Unlock()
+
+	writeXML(w, http.StatusOK, createFlowLogsResponse{
+		RequestID:    newRequestID(),
+		ClientToken:  r.FormValue("ClientToken"),
+		FlowLogIDs:   ids,
+		Unsuccessful: failures,
+	})
+}
+
+// flowLogResourceError returns the error code for a flow log on a resource
+// that does not exist. Network interfaces are not modeled, so any ID is
+// accepted for them. The caller must hold s.mu.
+func (s *Service) flowLogResourceError(resourceType, id string) string {
+	switch {
+	case resourceType == "VPC" && s.vpcs[id] == nil:
+		return "InvalidVpcID.NotFound"
+	case resourceType == "Subnet" && s.subnets[id] == nil:
+		return "InvalidSubnetID.NotFound"
+	case resourceType == "NetworkInterface" && !strings.HasPrefix(id, "eni-"):
+		return "InvalidNetworkInterfaceID.NotFound"
+	}
+	return ""
+}
+
+// hasFlowLog reports whether a flow log with the same configuration already
+// exists. The caller must hold s.mu.
+func (s *Service) hasFlowLog(resourceID, trafficType, destination string) bool {
+	for _, fl := range s.flowLogs {
+		if fl.resourceID == resourceID && fl.trafficType == trafficType && fl.destination == destination {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Service) describeFlowLogs(w http.ResponseWriter, r *http.Request) {
+	f := filters(r)
+	ids := list(r, "FlowLogId")
+
+	s.mu.RLock()
+	var items []ec2FlowLog
+	for _, fl := range s.flowLogs {
+		switch {
+		case len(ids) > 0 && !contains(ids, fl.id),
+			!matches(f, "flow-log-id", fl.id),
+			!matches(f, "resource-id", fl.resourceID),
+			!matches(f, "traffic-type", fl.trafficType),
+			!matches(f, "log-destination-type", fl.destinationType),
+			!matches(f, "log-group-name", fl.logGroupName),
+			!matches(f, "deliver-log-status", fl.deliverStatus):
+			continue
+		}
+		items = append(items, s.flowLogToXML(fl))
+	}
+	s.mu.RUnlock()
+
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].FlowLogID < items[j].FlowLogID
+	})
+
+	limit, _ := strconv.Atoi(r.FormValue("MaxResults"))
+	page, next, err := paginate.Page(items, func(i int) string { return items[i].FlowLogID }, r.FormValue("NextToken"), limit, 1000)
+	if err != nil {
+		writeEC2Error(w, "InvalidPaginationToken", "The pagination token is not valid.", http.StatusBadRequest)
+		return
+	}
+	writeXML(w, http.StatusOK, describeFlowLogsResponse{
+		RequestID: newRequestID(),
+		FlowLogs:  page,
+		NextToken: next,
+	})
+}
+
+func (s *Service) deleteFlowLogs(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	var failures []unsuccessfulItem
+	for _, id := range list(r, "FlowLogId") {
+		if s.flowLogs[id] == nil {
+			failures = append(failures, unsuccessfulItem{
+				ResourceID: id,
+				Error:      unsuccessfulError{Code: "InvalidFlowLogId.NotFound", Message: fmt.Sprintf("Flow Log '%s' does not exist", id)},
+			})
+			continue
+		}
+		delete(s.flowLogs, id)
+		s.tags.Delete(resourceARN(id))
+	}
+	s.mu.Unlock()
+
+	writeXML(w, http.StatusOK, deleteFlowLogsResponse{
+		RequestID:    newRequestID(),
+		Unsuccessful: failures,
+	})
+}
+
+// deleteFlowLogsOf deletes the flow logs of a resource that is being
+// deleted. The caller must hold s.mu.
+func (s *Service) deleteFlowLogsOf(resourceID string) {
+	for id, fl := range s.flowLogs {
+		if fl.resourceID == resourceID {
+			delete(s.flowLogs, id)
+			s.tags.Delete(resourceARN(id))
+		}
+	}
+}
+
+// EmitFlowLogRecords records traffic on the VPC, subnet, or network
+// interface with the given ID. Each flow log of the resource whose traffic
+// type admits a record writes it, in the flow log's format, to its log
+// group (in a stream named after the network interface) or as a gzipped
+// log file in its bucket. Records on a subnet also reach the flow logs of
+// its VPC. A failed delivery marks the flow log's DeliverLogsStatus FAILED.
+func (s *Service) EmitFlowLogRecords(resourceID string, records []FlowLogRecord) error {
+	now := time.Now().UTC()
+	records = append([]FlowLogRecord(nil), records...)
+	for i := range records {
+		rec := &records[i]
+		if rec.Action == "" {
+			rec.Action = "ACCEPT"
+		}
+		if rec.Protocol == 0 {
+			rec.Protocol = 6
+		}
+		if rec.Start.IsZero() {
+			rec.Start = now
+		}
+		if rec.End.IsZero() {
+			rec.End = now
+		}
+		if rec.InterfaceID == "" && strings.HasPrefix(resourceID, "eni-") {
+			rec.InterfaceID = resourceID
+		} else if rec.InterfaceID == "" {
+			rec.InterfaceID = fmt.Sprintf("eni-%017x", rand.Int63())
+		}
+	}
+
+	type delivery struct {
+		fl    *flowLog
+		lines map[string][]string // by interface ID
+	}
+	s.mu.RLock()
+	vpcID, subnetID := resourceID, ""
+	if sn := s.subnets[resourceID]; sn != nil {
+		vpcID, subnetID = sn.vpcID, sn.id
+	}
+	var deliveries []delivery
+	for _, fl := range s.flowLogs {
+		if fl.resourceID != resourceID && fl.resourceID != vpcID {
+			continue
+		}
+		d := delivery{fl: fl, lines: make(map[string][]string)}
+		for _, rec := range records {
+			if fl.trafficType == "ALL" || fl.trafficType == rec.Action {
+				d.lines[rec.InterfaceID] = append(d.lines[rec.InterfaceID], fl.render(rec, vpcID, subnetID))
+			}
+		}
+		if len(d.lines) > 0 {
+			deliveries = append(deliveries, d)
+		}
+	}
+	store, dispatch := s.store, s.dispatch
+	s.mu.RUnlock()
+
+	sort.Slice(deliveries, func(i, j int) bool {
+		return deliveries[i].fl.id < deliveries[j].fl.id
+	})
+
+	// Deliver outside s.mu: the destinations belong to other services.
+	var errs []error
+	for _, d := range deliveries {
+		var err error
+		if d.fl.destinationType == "s3" {
+			err = d.fl.writeS3(store, d.lines, now)
+		} else {
+			err = d.fl.writeLogs(dispatch, d.lines)
+		}
+
+		s.mu.Lock()
+		if err != nil {
+			d.fl.deliverStatus, d.fl.deliverError = "FAILED", "Access error"
+			errs = append(errs, fmt.Errorf("flow log %s: %w", d.fl.id, err))
+		} else {
+			d.fl.deliverStatus, d.fl.deliverError = "SUCCESS", ""
+		}
+		s.mu.Unlock()
+	}
+	if len(errs) > 0 {
+		return errs[0]
+	}
+	return nil
+}
+
+// render formats rec in the flow log's format. Fields the mock does not
+// track are written as "-", as AWS does for fields that do not apply.
+func (fl *flowLog) render(rec FlowLogRecord, vpcID, subnetID string) string {
+	values := map[string]string{
+		"version":      "2",
+		"account-id":   defaultAccountID,
+		"interface-id": rec.InterfaceID,
+		"srcaddr":      rec.SrcAddr,
+		"dstaddr":      rec.DstAddr,
+		"srcport":      strconv.Itoa(rec.SrcPort),
+		"dstport":      strconv.Itoa(rec.DstPort),
+		"protocol":     strconv.Itoa(rec.Protocol),
+		"packets":      strconv.FormatInt(rec.Packets, 10),
+		"bytes":        strconv.FormatInt(rec.Bytes, 10),
+		"start":        strconv.FormatInt(rec.Start.Unix(), 10),
+		"end":          strconv.FormatInt(rec.End.Unix(), 10),
+		"action":       rec.Action,
+		"log-status":   "OK",
+		"region":       fl.region,
+		"instance-id":  rec.InstanceID,
+	}
+	if strings.HasPrefix(vpcID, "vpc-") {
+		values["vpc-id"] = vpcID
+	}
+	if subnetID != "" {
+		values["subnet-id"] = subnetID
+	}
+
+	fields := strings.Fields(fl.format)
+	out := make([]string, len(fields))
+	for i, field := range fields {
+		name := strings.TrimSuffix(strings.TrimPrefix(field, "${"), "}")
+		out[i] = values[name]
+		if out[i] == "" {
+			out[i] = "-"
+		}
+	}
+	return strings.Join(out, " ")
+}
+
+// writeLogs appends the records to the flow log's log group, one stream per
+// network interface.
+func (fl *flowLog) writeLogs(dispatch mockhelpers.Dispatcher, lines map[string][]string) error {
+	if dispatch == nil {
+		return fmt.Errorf("CloudWatch Logs is not available")
+	}
+	enis := make([]string, 0, len(lines))
+	for eni := range lines {
+		enis = append(enis, eni)
+	}
+	sort.Strings(enis)
+	for _, eni := range enis {
+		stream := fl.destination + ":log-stream:" + eni
+		if _, err := dispatch(stream, []byte(strings.Join(lines[eni], "\n"))); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeS3 writes the records as one gzipped log file, with a header line of
+// field names, under the AWSLogs/ prefix layout AWS uses.
+func (fl *flowLog) writeS3(store mockhelpers.ObjectStore, lines map[string][]string, now time.Time) error {
+	if store == nil {
+		return fmt.Errorf("S3 is not available")
+	}
+	bucket, prefix, _ := strings.Cut(strings.TrimPrefix(fl.destination, "arn:aws:s3:::"), "/")
+	if prefix != "" {
+		prefix = strings.TrimSuffix(prefix, "/") + "/"
+	}
+	key := fmt.Sprintf("%sAWSLogs/%s/vpcflowlogs/%s/%s/%s_vpcflowlogs_%s_%s_%s_%s.log.gz",
+		prefix, defaultAccountID, fl.region, now.Format("2006/01/02"),
+		defaultAccountID, fl.region, fl.id, now.Format("20060102T1504Z"), newRequestID()[:8])
+
+	header := strings.NewReplacer("${", "", "}", "").Replace(fl.format)
+	var body bytes.Buffer
+	zw := gzip.NewWriter(&body)
+	fmt.Fprintln(zw, header)
+	enis := make([]string, 0, len(lines))
+	for eni := range lines {
+		enis = append(enis, eni)
+	}
+	sort.Strings(enis)
+	for _, eni := range enis {
+		for _, line := range lines[eni] {
+			fmt.Fprintln(zw, line)
+		}
+	}
+	zw.Close()
+	return store.PutObject(bucket, key, body.Bytes())
+}
+
+// flowLogToXML renders fl. The caller must hold s.mu.
+func (s *Service) flowLogToXML(fl *flowLog) ec2FlowLog {
+	x := ec2FlowLog{
+		FlowLogID:              fl.id,
+		FlowLogStatus:          "ACTIVE",
+		ResourceID:             fl.resourceID,
+		TrafficType:            fl.trafficType,
+		LogDestinationType:     fl.destinationType,
+		LogDestination:         fl.destination,
+		DeliverLogsPermission:  fl.permissionARN,
+		DeliverLogsStatus:      fl.deliverStatus,
+		DeliverLogsError:       fl.deliverError,
+		LogFormat:              fl.format,
+		MaxAggregationInterval: fl.aggregation,
+		CreationTime:           fl.created.Format(time.RFC3339),
+		Tags:                   s.tagSet(fl.id),
+	}
+	if fl.destinationType == "cloud-watch-logs" {
+		x.LogGroupName = fl.logGroupName
+	}
+	return x
+}
+
+type ec2FlowLog struct {
+	FlowLogID              string   `xml:"flowLogId"`
+	FlowLogStatus          string   `xml:"flowLogStatus"`
+	ResourceID             string   `xml:"resourceId"`
+	TrafficType            string   `xml:"trafficType"`
+	LogGroupName           string   `xml:"logGroupName,omitempty"`
+	LogDestinationType     string   `xml:"logDestinationType"`
+	LogDestination         string   `xml:"logDestination"`
+	DeliverLogsPermission  string   `xml:"deliverLogsPermissionArn,omitempty"`
+	DeliverLogsStatus      string   `xml:"deliverLogsStatus"`
+	DeliverLogsError       string   `xml:"deliverLogsErrorMessage,omitempty"`
+	LogFormat              string   `xml:"logFormat"`
+	MaxAggregationInterval int      `xml:"maxAggregationInterval"`
+	CreationTime           string   `xml:"creationTime"`
+	Tags                   []ec2Tag `xml:"tagSet>item"`
+}
+
+type unsuccessfulItem struct {
+	ResourceID string            `xml:"resourceId"`
+	Error      unsuccessfulError `xml:"error"`
+}
+
+type unsuccessfulError struct {
+	Code    string `xml:"code"`
+	Message string `xml:"message"`
+}
+
+type createFlowLogsResponse struct {
+	XMLName      xml.Name           `xml:"CreateFlowLogsResponse"`
+	RequestID    string             `xml:"requestId"`
+	ClientToken  string             `xml:"clientToken,omitempty"`
+	FlowLogIDs   []string           `xml:"flowLogIdSet>item"`
+	Unsuccessful []unsuccessfulItem `xml:"unsuccessful>item"`
+}
+
+type describeFlowLogsResponse struct {
+	XMLName   xml.Name     `xml:"DescribeFlowLogsResponse"`
+	RequestID string       `xml:"requestId"`
+	FlowLogs  []ec2FlowLog `xml:"flowLogSet>item"`
+	NextToken string       `xml:"nextToken,omitempty"`
+}
+
+type deleteFlowLogsResponse struct {
+	XMLName      xml.Name           `xml:"DeleteFlowLogsResponse"`
+	RequestID    string             `xml:"requestId"`
+	Unsuccessful []unsuccessfulItem `xml:"unsuccessful>item"`
+}
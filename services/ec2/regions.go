@@ -0,0 +1,497 @@
+package ec2
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/riyanimam/goto/internal/paginate"
+)
+
+// region is a region in the built-in catalog.
+type region struct {
+	name  string
+	zones string // zone letters
+	optIn bool   // whether the region must be enabled before use
+}
+
+// regions are the commercial regions DescribeRegions reports. Opt-in regions
+// are reported as not-opted-in.
+var regions = []region{
+	{"af-south-1", "abc", true},
+	{"ap-east-1", "abc", true},
+	{"ap-northeast-1", "acd", false},
+	{"ap-northeast-2", "abcd", false},
+	{"ap-northeast-3", "abc", false},
+	{"ap-south-1", "abc", false},
+	{"ap-southeast-1", "abc", false},
+	{"ap-southeast-2", "abc", false},
+	{"ca-central-1", "abd", false},
+	{"eu-central-1", "abc", false},
+	{"eu-north-1", "abc", false},
+	{"eu-south-1", "abc", true},
+	{"eu-west-1", "abc", false},
+	{"eu-west-2", "abc", false},
+	{"eu-west-3", "abc", false},
+	{"me-south-1", "abc", true},
+	{"sa-east-1", "abc", false},
+	{"us-east-1", "abcdef", false},
+	{"us-east-2", "abc", false},
+	{"us-west-1", "ac", false},
+	{"us-west-2", "abcd", false},
+}
+
+// regionOf returns the region a request is signed for, taken from the
+// credential scope of its Authorization header.
+func regionOf(r *http.Request) string {
+	auth := r.Header.Get("Authorization")
+	if idx := strings.Index(auth, "Credential="); idx >= 0 {
+		if parts := strings.Split(auth[idx:], "/"); len(parts) >= 4 && parts[2] != "" {
+			return parts[2]
+		}
+	}
+	return "us-east-1"
+}
+
+// zonesOf returns the availability zones of name. Regions missing from the
+// catalog get three zones, so custom regions still work.
+func zonesOf(name string) []ec2AvailabilityZone {
+	letters := "abc"
+	for _, rg := range regions {
+		if rg.name == name {
+			letters = rg.zones
+		}
+	}
+	// Zone IDs abbreviate the region, as in use1-az1 for us-east-1.
+	parts := strings.Split(name, "-")
+	prefix := name
+	if len(parts) == 3 {
+		dir := parts[1]
+		for _, d := range []string{"north", "south", "east", "west", "central"} {
+			dir = strings.Replace(dir, d, d[:1], 1)
+		}
+		prefix = parts[0] + dir + parts[2]
+	}
+
+	zones := make([]ec2AvailabilityZone, len(letters))
+	for i, l := range letters {
+		zones[i] = ec2AvailabilityZone{
+			ZoneName:           name + string(l),
+			ZoneID:             fmt.Sprintf("%s-az%d", prefix, i+1),
+			ZoneType:           "availability-zone",
+			State:              "available",
+			OptInStatus:        "opt-in-not-required",
+			RegionName:         name,
+			GroupName:          name,
+			NetworkBorderGroup: name,
+		}
+	}
+	return zones
+}
+
+// filters returns the Filter.N parameters of a request as a map from filter
+// name to the set of values it accepts.
+func filters(r *http.Request) map[string][]string {
+	m := make(map[string][]string)
+	for i := 1; ; i++ {
+		prefix := fmt.Sprintf("Filter.%d.", i)
+		name := r.FormValue(prefix + "Name")
+		if name == "" {
+			break
+		}
+		m[name] = list(r, prefix+"Value")
+	}
+	return m
+}
+
+// list returns the values of the name.N parameters of a request.
+func list(r *http.Request, name string) []string {
+	var values []string
+	for i := 1; ; i++ {
+		v := r.FormValue(fmt.Sprintf("%s.%d", name, i))
+		if v == "" {
+			return values
+		}
+		values = append(values, v)
+	}
+}
+
+// matches reports whether v passes the named filter: it does when the
+// filter is absent or one of its values, which may use * and ? wildcards,
+// matches v.
+func matches(f map[string][]string, name string, v ...string) bool {
+	patterns, ok := f[name]
+	if !ok {
+		return true
+	}
+	for _, p := range patterns {
+		for _, s := range v {
+			if ok, _ := path.Match(p, s); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func contains(values []string, v string) bool {
+	for _, s := range values {
+		if s == v {
+			return true
+		}
+	}
+	return false
+}
+
+// describeRegions lists the enabled regions, or every region when
+// AllRegions is set.
+func (s *Service) describeRegions(w http.ResponseWriter, r *http.Request) {
+	f := filters(r)
+	names := list(r, "RegionName")
+	all := r.FormValue("AllRegions") == "true"
+	for _, name := range names {
+		if !knownRegion(name) {
+			writeEC2Error(w, "InvalidParameterValue", fmt.Sprintf("Invalid region: %s", name), http.StatusBadRequest)
+			return
+		}
+	}
+
+	var items []ec2Region
+	for _, rg := range regions {
+		status := "opt-in-not-required"
+		if rg.optIn {
+			status = "not-opted-in"
+		}
+		endpoint := "ec2." + rg.name + ".amazonaws.com"
+		switch {
+		case rg.optIn && !all && len(names) == 0,
+			len(names) > 0 && !contains(names, rg.name),
+			!matches(f, "region-name", rg.name),
+			!matches(f, "endpoint", endpoint),
+			!matches(f, "opt-in-status", status):
+			continue
+		}
+		items = append(items, ec2Region{Name: rg.name, Endpoint: endpoint, OptInStatus: status})
+	}
+	writeXML(w, http.StatusOK, describeRegionsResponse{RequestID: newRequestID(), Regions: items})
+}
+
+func knownRegion(name string) bool {
+	for _, rg := range regions {
+		if rg.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// describeAvailabilityZones lists the zones of the region the request is
+// signed for.
+func (s *Service) describeAvailabilityZones(w http.ResponseWriter, r *http.Request) {
+	f := filters(r)
+	names := list(r, "ZoneName")
+	ids := list(r, "ZoneId")
+	zones := zonesOf(regionOf(r))
+
+	for _, n := range names {
+		if !containsZone(zones, n, "") {
+			writeEC2Error(w, "InvalidParameterValue", fmt.Sprintf("The zone '%s' does not exist.", n), http.StatusBadRequest)
+			return
+		}
+	}
+	for _, id := range ids {
+		if !containsZone(zones, "", id) {
+			writeEC2Error(w, "InvalidParameterValue", fmt.Sprintf("The zone ID '%s' does not exist.", id), http.StatusBadRequest)
+			return
+		}
+	}
+
+	var items []ec2AvailabilityZone
+	for _, z := range zones {
+		switch {
+		case len(names) > 0 && !contains(names, z.ZoneName),
+			len(ids) > 0 && !contains(ids, z.ZoneID),
+			!matches(f, "zone-name", z.ZoneName),
+			!matches(f, "zone-id", z.ZoneID),
+			!matches(f, "zone-type", z.ZoneType),
+			!matches(f, "state", z.State),
+			!matches(f, "region-name", z.RegionName),
+			!matches(f, "group-name", z.GroupName),
+			!matches(f, "opt-in-status", z.OptInStatus):
+			continue
+		}
+		items = append(items, z)
+	}
+	writeXML(w, http.StatusOK, describeAvailabilityZonesResponse{RequestID: newRequestID(), Zones: items})
+}
+
+func containsZone(zones []ec2AvailabilityZone, name, id string) bool {
+	for _, z := range zones {
+		if z.ZoneName == name || z.ZoneID == id {
+			return true
+		}
+	}
+	return false
+}
+
+// accountAttributes are the attributes DescribeAccountAttributes reports,
+// with the defaults of a new account.
+var accountAttributes = []struct {
+	name   string
+	values []string
+}{
+	{"supported-platforms", []string{"VPC"}},
+	{"default-vpc", []string{"none"}},
+	{"max-instances", []string{"20"}},
+	{"vpc-max-security-groups-per-interface", []string{"5"}},
+	{"max-elastic-ips", []string{"5"}},
+	{"vpc-max-elastic-ips", []string{"5"}},
+}
+
+func (s *Service) describeAccountAttributes(w http.ResponseWriter, r *http.Request) {
+	names := list(r, "AttributeName")
+
+	var items []ec2AccountAttribute
+	for _, attr := range accountAttributes {
+		if len(names) > 0 && !contains(names, attr.name) {
+			continue
+		}
+		item := ec2AccountAttribute{Name: attr.name}
+		for _, v := range attr.values {
+			item.Values = append(item.Values, ec2AttributeValue{Value: v})
+		}
+		items = append(items, item)
+	}
+	writeXML(w, http.StatusOK, describeAccountAttributesResponse{RequestID: newRequestID(), Attributes: items})
+}
+
+// instanceType describes an instance type in the built-in catalog.
+type instanceType struct {
+	name      string
+	arch      []string
+	clockGHz  float64
+	vcpus     int
+	cores     int
+	memoryMiB int
+	network   string
+	enis      int
+	ipsPerENI int
+}
+
+var (
+	archX86  = []string{"x86_64"}
+	archARM  = []string{"arm64"}
+	archBoth = []string{"i386", "x86_64"}
+)
+
+// instanceTypes is the built-in catalog DescribeInstanceTypes reports: the
+// common general purpose, compute optimized, and memory optimized sizes,
+// with the specifications AWS publishes for them.
+var instanceTypes = []instanceType{
+	{"c5.large", archX86, 3.4, 2, 1, 4096, "Up to 10 Gigabit", 3, 10},
+	{"c5.xlarge", archX86, 3.4, 4, 2, 8192, "Up to 10 Gigabit", 4, 15},
+	{"c6g.large", archARM, 2.5, 2, 2, 4096, "Up to 10 Gigabit", 3, 10},
+	{"c6i.large", archX86, 3.5, 2, 1, 4096, "Up to 12.5 Gigabit", 3, 10},
+	{"m1.small", archBoth, 0, 1, 1, 1740, "Low", 2, 4},
+	{"m5.2xlarge", archX86, 3.1, 8, 4, 32768, "Up to 10 Gigabit", 4, 15},
+	{"m5.large", archX86, 3.1, 2, 1, 8192, "Up to 10 Gigabit", 3, 10},
+	{"m5.xlarge", archX86, 3.1, 4, 2, 16384, "Up to 10 Gigabit", 4, 15},
+	{"m5d.large", archX86, 3.1, 2, 1, 8192, "Up to 10 Gigabit", 3, 10},
+	{"m6g.large", archARM, 2.5, 2, 2, 8192, "Up to 10 Gigabit", 3, 10},
+	{"m6i.large", archX86, 3.5, 2, 1, 8192, "Up to 12.5 Gigabit", 3, 10},
+	{"m7g.large", archARM, 2.6, 2, 2, 8192, "Up to 12.5 Gigabit", 3, 10},
+	{"r5.large", archX86, 3.1, 2, 1, 16384, "Up to 10 Gigabit", 3, 10},
+	{"r5.xlarge", archX86, 3.1, 4, 2, 32768, "Up to 10 Gigabit", 4, 15},
+	{"t2.medium", archBoth, 2.3, 2, 2, 4096, "Low to Moderate", 3, 6},
+	{"t2.micro", archBoth, 2.5, 1, 1, 1024, "Low to Moderate", 2, 2},
+	{"t2.small", archBoth, 2.5, 1, 1, 2048, "Low to Moderate", 3, 4},
+	{"t3.large", archX86, 2.5, 2, 1, 8192, "Up to 5 Gigabit", 3, 12},
+	{"t3.medium", archX86, 2.5, 2, 1, 4096, "Up to 5 Gigabit", 3, 6},
+	{"t3.micro", archX86, 2.5, 2, 1, 1024, "Up to 5 Gigabit", 2, 2},
+	{"t3.small", archX86, 2.5, 2, 1, 2048, "Up to 5 Gigabit", 3, 4},
+	{"t4g.micro", archARM, 2.5, 2, 2, 1024, "Up to 5 Gigabit", 2, 2},
+	{"t4g.small", archARM, 2.5, 2, 2, 2048, "Up to 5 Gigabit", 3, 4},
+}
+
+// toXML returns the InstanceTypeInfo of t. The remaining specifications
+// follow from the family: m1 is the previous generation, t families are
+// burstable, t2 and older run on Xen, and d variants have instance storage.
+func (t instanceType) toXML() ec2InstanceType {
+	family, _, _ := strings.Cut(t.name, ".")
+	previous := family == "m1"
+	nitro := !previous && family != "t2"
+
+	x := ec2InstanceType{
+		InstanceType:             t.name,
+		CurrentGeneration:        !previous,
+		FreeTierEligible:         t.name == "t2.micro" || t.name == "t3.micro",
+		UsageClasses:             []string{"on-demand", "spot"},
+		RootDeviceTypes:          []string{"ebs"},
+		VirtualizationTypes:      []string{"hvm"},
+		Hypervisor:               "xen",
+		Architectures:            t.arch,
+		VCPUs:                    t.vcpus,
+		Cores:                    t.cores,
+		ThreadsPerCore:           t.vcpus / t.cores,
+		MemoryMiB:                t.memoryMiB,
+		InstanceStorageSupported: previous || strings.HasSuffix(family, "d"),
+		EBSOptimizedSupport:      "unsupported",
+		EBSEncryptionSupport:     "supported",
+		NetworkPerformance:       t.network,
+		MaxNetworkInterfaces:     t.enis,
+		IPv4AddressesPerENI:      t.ipsPerENI,
+		Burstable:                strings.HasPrefix(family, "t"),
+	}
+	if previous {
+		x.RootDeviceTypes = append(x.RootDeviceTypes, "instance-store")
+		x.VirtualizationTypes = append(x.VirtualizationTypes, "paravirtual")
+	}
+	if nitro {
+		x.Hypervisor = "nitro"
+		x.EBSOptimizedSupport = "default"
+	}
+	if t.clockGHz > 0 {
+		x.ClockGHz = strconv.FormatFloat(t.clockGHz, 'f', -1, 64)
+	}
+	return x
+}
+
+// describeInstanceTypes lists the catalog's instance types, filtered by the
+// InstanceType.N parameters and the common specification filters.
+func (s *Service) describeInstanceTypes(w http.ResponseWriter, r *http.Request) {
+	f := filters(r)
+	names := list(r, "InstanceType")
+	for _, n := range names {
+		if !knownInstanceType(n) {
+			writeEC2Error(w, "InvalidInstanceType", fmt.Sprintf("The following supplied instance types do not exist: [%s]", n), http.StatusBadRequest)
+			return
+		}
+	}
+
+	var items []ec2InstanceType
+	for _, t := range instanceTypes {
+		x := t.toXML()
+		switch {
+		case len(names) > 0 && !contains(names, t.name),
+			!matches(f, "instance-type", t.name),
+			!matches(f, "current-generation", strconv.FormatBool(x.CurrentGeneration)),
+			!matches(f, "free-tier-eligible", strconv.FormatBool(x.FreeTierEligible)),
+			!matches(f, "burstable-performance-supported", strconv.FormatBool(x.Burstable)),
+			!matches(f, "hypervisor", x.Hypervisor),
+			!matches(f, "instance-storage-supported", strconv.FormatBool(x.InstanceStorageSupported)),
+			!matches(f, "processor-info.supported-architecture", x.Architectures...),
+			!matches(f, "supported-virtualization-type", x.VirtualizationTypes...),
+			!matches(f, "vcpu-info.default-vcpus", strconv.Itoa(t.vcpus)),
+			!matches(f, "memory-info.size-in-mib", strconv.Itoa(t.memoryMiB)),
+			!matches(f, "network-info.network-performance", t.network):
+			continue
+		}
+		items = append(items, x)
+	}
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].InstanceType < items[j].InstanceType
+	})
+
+	limit, _ := strconv.Atoi(r.FormValue("MaxResults"))
+	if limit > 0 && limit < 5 {
+		limit = 5
+	}
+	page, next, err := paginate.Page(items, func(i int) string { return items[i].InstanceType }, r.FormValue("NextToken"), limit, 100)
+	if err != nil {
+		writeEC2Error(w, "InvalidPaginationToken", "The pagination token is not valid.", http.StatusBadRequest)
+		return
+	}
+	writeXML(w, http.StatusOK, describeInstanceTypesResponse{
+		RequestID:     newRequestID(),
+		InstanceTypes: page,
+		NextToken:     next,
+	})
+}
+
+func knownInstanceType(name string) bool {
+	for _, t := range instanceTypes {
+		if t.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+type ec2Region struct {
+	Name        string `xml:"regionName"`
+	Endpoint    string `xml:"regionEndpoint"`
+	OptInStatus string `xml:"optInStatus"`
+}
+
+type ec2AvailabilityZone struct {
+	ZoneName           string `xml:"zoneName"`
+	ZoneID             string `xml:"zoneId"`
+	ZoneType           string `xml:"zoneType"`
+	State              string `xml:"zoneState"`
+	OptInStatus        string `xml:"optInStatus"`
+	RegionName         string `xml:"regionName"`
+	GroupName          string `xml:"groupName"`
+	NetworkBorderGroup string `xml:"networkBorderGroup"`
+}
+
+type ec2AccountAttribute struct {
+	Name   string              `xml:"attributeName"`
+	Values []ec2AttributeValue `xml:"attributeValueSet>item"`
+}
+
+type ec2AttributeValue struct {
+	Value string `xml:"attributeValue"`
+}
+
+type ec2InstanceType struct {
+	InstanceType             string   `xml:"instanceType"`
+	CurrentGeneration        bool     `xml:"currentGeneration"`
+	FreeTierEligible         bool     `xml:"freeTierEligible"`
+	UsageClasses             []string `xml:"supportedUsageClasses>item"`
+	RootDeviceTypes          []string `xml:"supportedRootDeviceTypes>item"`
+	VirtualizationTypes      []string `xml:"supportedVirtualizationTypes>item"`
+	BareMetal                bool     `xml:"bareMetal"`
+	Hypervisor               string   `xml:"hypervisor"`
+	Architectures            []string `xml:"processorInfo>supportedArchitectures>item"`
+	ClockGHz                 string   `xml:"processorInfo>sustainedClockSpeedInGhz,omitempty"`
+	VCPUs                    int      `xml:"vCpuInfo>defaultVCpus"`
+	Cores                    int      `xml:"vCpuInfo>defaultCores"`
+	ThreadsPerCore           int      `xml:"vCpuInfo>defaultThreadsPerCore"`
+	MemoryMiB                int      `xml:"memoryInfo>sizeInMiB"`
+	InstanceStorageSupported bool     `xml:"instanceStorageSupported"`
+	EBSOptimizedSupport      string   `xml:"ebsInfo>ebsOptimizedSupport"`
+	EBSEncryptionSupport     string   `xml:"ebsInfo>encryptionSupport"`
+	NetworkPerformance       string   `xml:"networkInfo>networkPerformance"`
+	MaxNetworkInterfaces     int      `xml:"networkInfo>maximumNetworkInterfaces"`
+	IPv4AddressesPerENI      int      `xml:"networkInfo>ipv4AddressesPerInterface"`
+	Burstable                bool     `xml:"burstablePerformanceSupported"`
+}
+
+type describeRegionsResponse struct {
+	XMLName   xml.Name    `xml:"DescribeRegionsResponse"`
+	RequestID string      `xml:"requestId"`
+	Regions   []ec2Region `xml:"regionInfo>item"`
+}
+
+type describeAvailabilityZonesResponse struct {
+	XMLName   xml.Name              `xml:"DescribeAvailabilityZonesResponse"`
+	RequestID string                `xml:"requestId"`
+	Zones     []ec2AvailabilityZone `xml:"availabilityZoneInfo>item"`
+}
+
+type describeAccountAttributesResponse struct {
+	XMLName    xml.Name              `xml:"DescribeAccountAttributesResponse"`
+	RequestID  string                `xml:"requestId"`
+	Attributes []ec2AccountAttribute `xml:"accountAttributeSet>item"`
+}
+
+type describeInstanceTypesResponse struct {
+	XMLName       xml.Name          `xml:"DescribeInstanceTypesResponse"`
+	RequestID     string            `xml:"requestId"`
+	InstanceTypes []ec2InstanceType `xml:"instanceTypeSet>item"`
+	NextToken     string            `xml:"nextToken,omitempty"`
+}
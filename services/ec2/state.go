@@ -0,0 +1,63 @@
+package ec2
+
+import "github.com/riyanimam/goto/internal/mockhelpers"
+
+// ExportState lists the VPCs, subnets, security groups, and instances that
+// have not been terminated.
+func (s *Service) ExportState() []mockhelpers.Resource {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	resources := make([]mockhelpers.Resource, 0, len(s.instances)+len(s.vpcs)+len(s.subnets)+len(s.securityGroups))
+	for _, v := range s.vpcs {
+		resources = append(resources, mockhelpers.Resource{
+			Type: "aws_vpc",
+			ID:   v.id,
+			ARN:  resourceARN(v.id),
+			Attributes: map[string]interface{}{
+				"cidr_block": v.cidrBlock,
+			},
+		})
+	}
+	for _, sn := range s.subnets {
+		resources = append(resources, mockhelpers.Resource{
+			Type: "aws_subnet",
+			ID:   sn.id,
+			ARN:  resourceARN(sn.id),
+			Attributes: map[string]interface{}{
+				"vpc_id":            sn.vpcID,
+				"cidr_block":        sn.cidrBlock,
+				"availability_zone": sn.availabilityZone,
+			},
+		})
+	}
+	for _, sg := range s.securityGroups {
+		resources = append(resources, mockhelpers.Resource{
+			Type: "aws_security_group",
+			ID:   sg.id,
+			ARN:  resourceARN(sg.id),
+			Attributes: map[string]interface{}{
+				"name":        sg.name,
+				"description": sg.description,
+				"vpc_id":      sg.vpcID,
+			},
+		})
+	}
+	for _, inst := range s.instances {
+		if inst.state == "terminated" {
+			continue
+		}
+		resources = append(resources, mockhelpers.Resource{
+			Type: "aws_instance",
+			ID:   inst.id,
+			ARN:  resourceARN(inst.id),
+			Attributes: map[string]interface{}{
+				"ami":            inst.imageID,
+				"instance_type":  inst.instanceType,
+				"subnet_id":      inst.subnetID,
+				"instance_state": inst.state,
+			},
+		})
+	}
+	return resources
+}
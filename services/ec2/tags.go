@@ -0,0 +1,226 @@
+package ec2
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/riyanimam/goto/internal/tags"
+)
+
+// resourceTypes maps resource ID prefixes to the EC2 resource type they
+// identify.
+var resourceTypes = map[string]string{
+	"i":      "instance",
+	"vpc":    "vpc",
+	"sg":     "security-group",
+	"subnet": "subnet",
+	"fl":     "vpc-flow-log",
+}
+
+// resourceType returns the EC2 resource type of the given resource ID.
+func resourceType(id string) string {
+	prefix, _, _ := strings.Cut(id, "-")
+	return resourceTypes[prefix]
+}
+
+// resourceARN returns the ARN tags of the given resource ID are recorded
+// under.
+func resourceARN(id string) string {
+	return fmt.Sprintf("arn:aws:ec2:us-east-1:%s:%s/%s", defaultAccountID, resourceType(id), id)
+}
+
+// hasResource reports whether id names an existing resource. The caller
+// must hold s.mu.
+func (s *Service) hasResource(id string) bool {
+	switch resourceType(id) {
+	case "instance":
+		return s.instances[id] != nil
+	case "vpc":
+		return s.vpcs[id] != nil
+	case "security-group":
+		return s.securityGroups[id] != nil
+	case "subnet":
+		return s.subnets[id] != nil
+	case "vpc-flow-log":
+		return s.flowLogs[id] != nil
+	}
+	return false
+}
+
+// tagSet renders the tags of the given resource ID.
+func (s *Service) tagSet(id string) []ec2Tag {
+	current := s.tags.Get(resourceARN(id))
+	keys := make([]string, 0, len(current))
+	for k := range current {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	set := make([]ec2Tag, 0, len(keys))
+	for _, k := range keys {
+		set = append(set, ec2Tag{Key: k, Value: current[k]})
+	}
+	return set
+}
+
+// tagOnCreate applies the TagSpecification.N entries of a create request
+// whose ResourceType matches that of id.
+func (s *Service) tagOnCreate(r *http.Request, id string) {
+	for i := 1; ; i++ {
+		prefix := fmt.Sprintf("TagSpecification.%d.", i)
+		typ := r.FormValue(prefix + "ResourceType")
+		if typ == "" {
+			return
+		}
+		if typ == resourceType(id) {
+			s.tags.Tag(resourceARN(id), tags.FromForm(r.Form, prefix+"Tag."))
+		}
+	}
+}
+
+// resourceIDs collects the ResourceId.N form fields, writing an error and
+// returning false if any of them does not exist.
+func (s *Service) resourceIDs(w http.ResponseWriter, r *http.Request) ([]string, bool) {
+	var ids []string
+	for i := 1; ; i++ {
+		id := r.FormValue(fmt.Sprintf("ResourceId.%d", i))
+		if id == "" {
+			break
+		}
+		if !s.hasResource(id) {
+			writeEC2Error(w, "InvalidID", fmt.Sprintf("The ID '%s' is not valid", id), http.StatusBadRequest)
+			return nil, false
+		}
+		ids = append(ids, id)
+	}
+	return ids, true
+}
+
+func (s *Service) createTags(w http.ResponseWriter, r *http.Request) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	ids, ok := s.resourceIDs(w, r)
+	if !ok {
+		return
+	}
+	newTags := tags.FromForm(r.Form, "Tag.")
+	for _, id := range ids {
+		s.tags.Tag(resourceARN(id), newTags)
+	}
+	writeXML(w, http.StatusOK, tagsResponse{XMLName: xml.Name{Local: "CreateTagsResponse"}, RequestID: newRequestID(), Return: true})
+}
+
+// deleteTags removes the Tag.N.Key tags from each resource. A tag given
+// with a Value is only removed if its value matches.
+func (s *Service) deleteTags(w http.ResponseWriter, r *http.Request) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	ids, ok := s.resourceIDs(w, r)
+	if !ok {
+		return
+	}
+	for _, id := range ids {
+		arn := resourceARN(id)
+		current := s.tags.Get(arn)
+		var keys []string
+		for i := 1; ; i++ {
+			prefix := fmt.Sprintf("Tag.%d.", i)
+			key := r.FormValue(prefix + "Key")
+			if key == "" {
+				break
+			}
+			if value, set := r.Form[prefix+"Value"]; set && current[key] != value[0] {
+				continue
+			}
+			keys = append(keys, key)
+		}
+		s.tags.Untag(arn, keys)
+	}
+	writeXML(w, http.StatusOK, tagsResponse{XMLName: xml.Name{Local: "DeleteTagsResponse"}, RequestID: newRequestID(), Return: true})
+}
+
+// describeTags lists the tags of every resource, filtered by the
+// resource-id, resource-type, key and value filters.
+func (s *Service) describeTags(w http.ResponseWriter, r *http.Request) {
+	filters := make(map[string]map[string]bool)
+	for i := 1; ; i++ {
+		prefix := fmt.Sprintf("Filter.%d.", i)
+		name := r.FormValue(prefix + "Name")
+		if name == "" {
+			break
+		}
+		values := make(map[string]bool)
+		for j := 1; ; j++ {
+			v := r.FormValue(fmt.Sprintf("%sValue.%d", prefix, j))
+			if v == "" {
+				break
+			}
+			values[v] = true
+		}
+		filters[name] = values
+	}
+	match := func(name, v string) bool {
+		values, ok := filters[name]
+		return !ok || values[v]
+	}
+
+	s.mu.RLock()
+	var ids []string
+	for id := range s.instances {
+		ids = append(ids, id)
+	}
+	for id := range s.vpcs {
+		ids = append(ids, id)
+	}
+	for id := range s.securityGroups {
+		ids = append(ids, id)
+	}
+	for id := range s.subnets {
+		ids = append(ids, id)
+	}
+	for id := range s.flowLogs {
+		ids = append(ids, id)
+	}
+	s.mu.RUnlock()
+	sort.Strings(ids)
+
+	var items []tagDescription
+	for _, id := range ids {
+		typ := resourceType(id)
+		if !match("resource-id", id) || !match("resource-type", typ) {
+			continue
+		}
+		for _, t := range s.tagSet(id) {
+			if match("key", t.Key) && match("value", t.Value) {
+				items = append(items, tagDescription{ResourceID: id, ResourceType: typ, Key: t.Key, Value: t.Value})
+			}
+		}
+	}
+	writeXML(w, http.StatusOK, describeTagsResponse{RequestID: newRequestID(), Tags: items})
+}
+
+type ec2Tag struct {
+	Key   string `xml:"key"`
+	Value string `xml:"value"`
+}
+
+type tagDescription struct {
+	ResourceID   string `xml:"resourceId"`
+	ResourceType string `xml:"resourceType"`
+	Key          string `xml:"key"`
+	Value        string `xml:"value"`
+}
+
+type tagsResponse struct {
+	XMLName   xml.Name
+	RequestID string `xml:"requestId"`
+	Return    bool   `xml:"return"`
+}
+
+type describeTagsResponse struct {
+	XMLName   xml.Name         `xml:"DescribeTagsResponse"`
+	RequestID string           `xml:"requestId"`
+	Tags      []tagDescription `xml:"tagSet>item"`
+}
@@ -8,19 +8,24 @@
 //   - PutImage
 //   - BatchGetImage
 //   - GetAuthorizationToken
+//   - TagResource
+//   - UntagResource
+//   - ListTagsForResource
 package ecr
 
 import (
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
-	"io"
 	"math/rand"
 	"net/http"
 	"sort"
-	"strings"
 	"sync"
 	"time"
+
+	"github.com/riyanimam/goto/internal/awserr"
+	"github.com/riyanimam/goto/internal/mockhelpers"
+	"github.com/riyanimam/goto/internal/tags"
 )
 
 const defaultAccountID = "123456789012"
@@ -29,6 +34,7 @@ const defaultAccountID = "123456789012"
 type Service struct {
 	mu    sync.RWMutex
 	repos map[string]*repository // keyed by repo name
+	tags  *tags.Store
 }
 
 type repository struct {
@@ -51,6 +57,7 @@ type image struct {
 func New() *Service {
 	return &Service{
 		repos: make(map[string]*repository),
+		tags:  tags.New(),
 	}
 }
 
@@ -59,7 +66,18 @@ func (s *Service) Name() string { return "ecr" }
 
 // Handler returns the HTTP handler for ECR requests.
 func (s *Service) Handler() http.Handler {
-	return http.HandlerFunc(s.handle)
+	return mockhelpers.JSONRouter{
+		"CreateRepository":      s.createRepository,
+		"DeleteRepository":      s.deleteRepository,
+		"DescribeRepositories":  s.describeRepositories,
+		"ListImages":            s.listImages,
+		"PutImage":              s.putImage,
+		"BatchGetImage":         s.batchGetImage,
+		"GetAuthorizationToken": s.getAuthorizationToken,
+		"TagResource":           s.tagResource,
+		"UntagResource":         s.untagResource,
+		"ListTagsForResource":   s.listTagsForResource,
+	}
 }
 
 // Reset clears all repositories and images.
@@ -67,54 +85,14 @@ func (s *Service) Reset() {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	s.repos = make(map[string]*repository)
+	s.tags.DeleteService("ecr")
 }
 
-func (s *Service) handle(w http.ResponseWriter, r *http.Request) {
-	target := r.Header.Get("X-Amz-Target")
-
-	bodyBytes, err := io.ReadAll(r.Body)
-	if err != nil {
-		writeJSONError(w, "ServerException", "could not read request body", http.StatusInternalServerError)
-		return
-	}
-
-	var params map[string]interface{}
-	if len(bodyBytes) > 0 {
-		if err := json.Unmarshal(bodyBytes, &params); err != nil {
-			writeJSONError(w, "InvalidParameterException", "could not parse request body", http.StatusBadRequest)
-			return
-		}
-	}
-	if params == nil {
-		params = make(map[string]interface{})
-	}
-
-	action := ""
-	if target != "" {
-		parts := strings.SplitN(target, ".", 2)
-		if len(parts) == 2 {
-			action = parts[1]
-		}
-	}
-
-	switch action {
-	case "CreateRepository":
-		s.createRepository(w, params)
-	case "DeleteRepository":
-		s.deleteRepository(w, params)
-	case "DescribeRepositories":
-		s.describeRepositories(w, params)
-	case "ListImages":
-		s.listImages(w, params)
-	case "PutImage":
-		s.putImage(w, params)
-	case "BatchGetImage":
-		s.batchGetImage(w, params)
-	case "GetAuthorizationToken":
-		s.getAuthorizationToken(w, params)
-	default:
-		writeJSONError(w, "UnsupportedCommandException", fmt.Sprintf("action %q is not supported", action), http.StatusBadRequest)
-	}
+// SetTagStore sets the registry resource tags are recorded in.
+func (s *Service) SetTagStore(store *tags.Store) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tags = store
 }
 
 func (s *Service) createRepository(w http.ResponseWriter, params map[string]interface{}) {
@@ -139,6 +117,7 @@ func (s *Service) createRepository(w http.ResponseWriter, params map[string]inte
 		created:    time.Now().UTC(),
 	}
 	s.repos[name] = repo
+	s.tags.Tag(repo.arn, tags.FromList(params["tags"], "Key", "Value"))
 	s.mu.Unlock()
 
 	writeJSON(w, http.StatusOK, map[string]interface{}{
@@ -157,6 +136,7 @@ func (s *Service) deleteRepository(w http.ResponseWriter, params map[string]inte
 		return
 	}
 	delete(s.repos, name)
+	s.tags.Delete(repo.arn)
 	s.mu.Unlock()
 
 	writeJSON(w, http.StatusOK, map[string]interface{}{
@@ -315,6 +295,57 @@ func (s *Service) getAuthorizationToken(w http.ResponseWriter, _ map[string]inte
 	})
 }
 
+// repoByARN returns the repository with the given ARN, or nil.
+// The caller must hold s.mu.
+func (s *Service) repoByARN(arn string) *repository {
+	for _, repo := range s.repos {
+		if repo.arn == arn {
+			return repo
+		}
+	}
+	return nil
+}
+
+func (s *Service) tagResource(w http.ResponseWriter, params map[string]interface{}) {
+	arn := getString(params, "resourceArn")
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.repoByARN(arn) == nil {
+		writeJSONError(w, "RepositoryNotFoundException", "The repository with arn '"+arn+"' does not exist", http.StatusBadRequest)
+		return
+	}
+	s.tags.Tag(arn, tags.FromList(params["tags"], "Key", "Value"))
+	writeJSON(w, http.StatusOK, map[string]interface{}{})
+}
+
+func (s *Service) untagResource(w http.ResponseWriter, params map[string]interface{}) {
+	arn := getString(params, "resourceArn")
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.repoByARN(arn) == nil {
+		writeJSONError(w, "RepositoryNotFoundException", "The repository with arn '"+arn+"' does not exist", http.StatusBadRequest)
+		return
+	}
+	s.tags.Untag(arn, tags.Keys(params["tagKeys"]))
+	writeJSON(w, http.StatusOK, map[string]interface{}{})
+}
+
+func (s *Service) listTagsForResource(w http.ResponseWriter, params map[string]interface{}) {
+	arn := getString(params, "resourceArn")
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.repoByARN(arn) == nil {
+		writeJSONError(w, "RepositoryNotFoundException", "The repository with arn '"+arn+"' does not exist", http.StatusBadRequest)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"tags": tags.ToList(s.tags.Get(arn), "Key", "Value"),
+	})
+}
+
 func repoResponse(repo *repository) map[string]interface{} {
 	return map[string]interface{}{
 		"repositoryName": repo.name,
@@ -343,12 +374,7 @@ func writeJSON(w http.ResponseWriter, status int, v interface{}) {
 }
 
 func writeJSONError(w http.ResponseWriter, code, message string, status int) {
-	w.Header().Set("Content-Type", "application/x-amz-json-1.1")
-	w.WriteHeader(status)
-	json.NewEncoder(w).Encode(map[string]interface{}{
-		"__type":  code,
-		"message": message,
-	})
+	awserr.WriteJSON(w, awserr.JSON11, code, message, status)
 }
 
 func newRequestID() string {
@@ -0,0 +1,57 @@
+package ecr
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/riyanimam/goto/internal/mockhelpers"
+)
+
+// ExportState lists the repositories and their images. Images are typed
+// aws_ecr_image, as the Terraform data source is, identified as
+// "repository@digest", and carry all of their tags.
+func (s *Service) ExportState() []mockhelpers.Resource {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var resources []mockhelpers.Resource
+	for _, repo := range s.repos {
+		resources = append(resources, mockhelpers.Resource{
+			Type: "aws_ecr_repository",
+			ID:   repo.name,
+			ARN:  repo.arn,
+			Attributes: map[string]interface{}{
+				"name":           repo.name,
+				"registry_id":    repo.registryID,
+				"repository_url": repo.uri,
+			},
+		})
+
+		tagsByDigest := map[string][]string{}
+		var digests []string
+		for _, img := range repo.images {
+			if _, seen := tagsByDigest[img.digest]; !seen {
+				digests = append(digests, img.digest)
+				tagsByDigest[img.digest] = []string{}
+			}
+			if img.tag != "" {
+				tagsByDigest[img.digest] = append(tagsByDigest[img.digest], img.tag)
+			}
+		}
+		for _, digest := range digests {
+			imageTags := tagsByDigest[digest]
+			sort.Strings(imageTags)
+			resources = append(resources, mockhelpers.Resource{
+				Type: "aws_ecr_image",
+				ID:   repo.name + "@" + digest,
+				ARN:  fmt.Sprintf("%s/%s", repo.arn, digest),
+				Attributes: map[string]interface{}{
+					"repository_name": repo.name,
+					"image_digest":    digest,
+					"image_tags":      imageTags,
+				},
+			})
+		}
+	}
+	return resources
+}
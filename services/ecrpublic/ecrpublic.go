@@ -0,0 +1,482 @@
+// Package ecrpublic provides a mock implementation of Amazon ECR Public.
+//
+// Supported actions:
+//   - CreateRepository
+//   - DeleteRepository
+//   - DescribeRepositories
+//   - DescribeRegistries
+//   - PutImage
+//   - DescribeImages
+//   - GetAuthorizationToken
+//   - TagResource
+//   - UntagResource
+//   - ListTagsForResource
+//
+// The mock has one registry, whose alias is fixed when the service is
+// created. Images are identified by the SHA-256 digest of their manifest,
+// so pushing the same manifest again under a new tag adds the tag to the
+// existing image.
+package ecrpublic
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	h "github.com/riyanimam/goto/internal/mockhelpers"
+	"github.com/riyanimam/goto/internal/paginate"
+	"github.com/riyanimam/goto/internal/tags"
+)
+
+// Service implements the ECR Public mock.
+type Service struct {
+	mu    sync.RWMutex
+	alias string
+	repos map[string]*repository // keyed by repository name
+	tags  *tags.Store
+}
+
+type repository struct {
+	name        string
+	arn         string
+	uri         string
+	created     time.Time
+	catalogData map[string]interface{}
+	images      []*image // oldest first
+}
+
+type image struct {
+	digest    string
+	tags      []string
+	manifest  string
+	mediaType string
+	pushed    time.Time
+}
+
+// New creates a new ECR Public mock service.
+func New() *Service {
+	return &Service{
+		alias: strings.ToLower(h.RandomID(8)),
+		repos: make(map[string]*repository),
+		tags:  tags.New(),
+	}
+}
+
+// Name returns the service identifier.
+func (s *Service) Name() string { return "ecr-public" }
+
+// Handler returns the HTTP handler for ECR Public requests.
+func (s *Service) Handler() http.Handler {
+	return h.JSONRouter{
+		"CreateRepository":      s.createRepository,
+		"DeleteRepository":      s.deleteRepository,
+		"DescribeRepositories":  s.describeRepositories,
+		"DescribeRegistries":    s.describeRegistries,
+		"PutImage":              s.putImage,
+		"DescribeImages":        s.describeImages,
+		"GetAuthorizationToken": s.getAuthorizationToken,
+		"TagResource":           s.tagResource,
+		"UntagResource":         s.untagResource,
+		"ListTagsForResource":   s.listTagsForResource,
+	}
+}
+
+// Reset clears all repositories and images.
+func (s *Service) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.repos = make(map[string]*repository)
+	s.tags.DeleteService("ecr-public")
+}
+
+// SetTagStore sets the registry resource tags are recorded in.
+func (s *Service) SetTagStore(store *tags.Store) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tags = store
+}
+
+// HasResource reports whether arn names an existing repository.
+func (s *Service) HasResource(arn string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.repoByARN(arn) != nil
+}
+
+func (s *Service) createRepository(w http.ResponseWriter, params map[string]interface{}) {
+	name := h.GetString(params, "repositoryName")
+	if name == "" {
+		h.WriteJSONError(w, "InvalidParameterException", "repositoryName is required", http.StatusBadRequest)
+		return
+	}
+	catalogData, _ := params["catalogData"].(map[string]interface{})
+
+	s.mu.Lock()
+	if _, exists := s.repos[name]; exists {
+		s.mu.Unlock()
+		h.WriteJSONError(w, "RepositoryAlreadyExistsException",
+			fmt.Sprintf("The repository with name '%s' already exists in the registry with id '%s'", name, h.DefaultAccountID), http.StatusBadRequest)
+		return
+	}
+	repo := &repository{
+		name:        name,
+		arn:         fmt.Sprintf("arn:aws:ecr-public::%s:repository/%s", h.DefaultAccountID, name),
+		uri:         fmt.Sprintf("public.ecr.aws/%s/%s", s.alias, name),
+		created:     time.Now().UTC(),
+		catalogData: catalogData,
+	}
+	s.repos[name] = repo
+	s.tags.Tag(repo.arn, tags.FromList(params["tags"], "Key", "Value"))
+	s.mu.Unlock()
+
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"repository":  repoResp(repo),
+		"catalogData": catalogResp(repo),
+	})
+}
+
+func (s *Service) deleteRepository(w http.ResponseWriter, params map[string]interface{}) {
+	name := h.GetString(params, "repositoryName")
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	repo := s.namedRepo(w, name)
+	if repo == nil {
+		return
+	}
+	if len(repo.images) > 0 && !h.GetBool(params, "force") {
+		h.WriteJSONError(w, "RepositoryNotEmptyException",
+			fmt.Sprintf("The repository with name '%s' in registry with id '%s' cannot be deleted because it still contains images", name, h.DefaultAccountID), http.StatusBadRequest)
+		return
+	}
+	delete(s.repos, name)
+	s.tags.Delete(repo.arn)
+
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"repository": repoResp(repo),
+	})
+}
+
+func (s *Service) describeRepositories(w http.ResponseWriter, params map[string]interface{}) {
+	names := getStringSlice(params, "repositoryNames")
+
+	s.mu.RLock()
+	// Named repositories are listed in the order requested.
+	var repos []map[string]interface{}
+	key := paginate.Seq
+	if len(names) > 0 {
+		for _, name := range names {
+			repo := s.namedRepo(w, name)
+			if repo == nil {
+				s.mu.RUnlock()
+				return
+			}
+			repos = append(repos, repoResp(repo))
+		}
+	} else {
+		for _, repo := range s.repos {
+			repos = append(repos, repoResp(repo))
+		}
+		key = func(i int) string { return repos[i]["repositoryName"].(string) }
+		sort.Slice(repos, func(i, j int) bool { return key(i) < key(j) })
+	}
+	s.mu.RUnlock()
+
+	page, next, err := paginate.Page(repos, key, h.GetString(params, "nextToken"), h.GetInt(params, "maxResults", 0), 1000)
+	if err != nil {
+		h.WriteJSONError(w, "InvalidParameterException", "Invalid parameter at 'nextToken' failed to satisfy constraint: 'Invalid token'", http.StatusBadRequest)
+		return
+	}
+	resp := map[string]interface{}{"repositories": page}
+	if next != "" {
+		resp["nextToken"] = next
+	}
+	h.WriteJSON(w, http.StatusOK, resp)
+}
+
+func (s *Service) describeRegistries(w http.ResponseWriter, _ map[string]interface{}) {
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"registries": []map[string]interface{}{{
+			"registryId":  h.DefaultAccountID,
+			"registryArn": fmt.Sprintf("arn:aws:ecr-public::%s:registry/%s", h.DefaultAccountID, h.DefaultAccountID),
+			"registryUri": "public.ecr.aws/" + s.alias,
+			"verified":    false,
+			"aliases": []map[string]interface{}{{
+				"name":                 s.alias,
+				"status":               "ACTIVE",
+				"primaryRegistryAlias": true,
+				"defaultRegistryAlias": true,
+			}},
+		}},
+	})
+}
+
+// putImage stores an image manifest. As in ECR Public, pushing a manifest
+// already in the repository under a tag it already has is an error, and
+// moving a tag to a new manifest removes it from the old image.
+func (s *Service) putImage(w http.ResponseWriter, params map[string]interface{}) {
+	name := h.GetString(params, "repositoryName")
+	manifest := h.GetString(params, "imageManifest")
+	tag := h.GetString(params, "imageTag")
+	if manifest == "" {
+		h.WriteJSONError(w, "InvalidParameterException", "imageManifest is required", http.StatusBadRequest)
+		return
+	}
+	sum := sha256.Sum256([]byte(manifest))
+	digest := "sha256:" + hex.EncodeToString(sum[:])
+	if d := h.GetString(params, "imageDigest"); d != "" && d != digest {
+		h.WriteJSONError(w, "ImageDigestDoesNotMatchException",
+			fmt.Sprintf("The provided image digest '%s' does not match the digest calculated for the image manifest", d), http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	repo := s.namedRepo(w, name)
+	if repo == nil {
+		return
+	}
+	var img *image
+	for _, existing := range repo.images {
+		if existing.digest == digest {
+			img = existing
+		}
+	}
+	if img != nil && (tag == "" || containsString(img.tags, tag)) {
+		h.WriteJSONError(w, "ImageAlreadyExistsException",
+			fmt.Sprintf("Image with digest '%s' and tag '%s' already exists in the repository with name '%s' in registry with id '%s'", digest, tag, name, h.DefaultAccountID), http.StatusBadRequest)
+		return
+	}
+	if img == nil {
+		img = &image{
+			digest:    digest,
+			manifest:  manifest,
+			mediaType: h.GetString(params, "imageManifestMediaType"),
+			pushed:    time.Now().UTC(),
+		}
+		repo.images = append(repo.images, img)
+	}
+	if tag != "" {
+		for _, other := range repo.images {
+			other.tags = removeString(other.tags, tag)
+		}
+		img.tags = append(img.tags, tag)
+	}
+
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"image": map[string]interface{}{
+			"registryId":     h.DefaultAccountID,
+			"repositoryName": name,
+			"imageId": map[string]interface{}{
+				"imageDigest": digest,
+				"imageTag":    tag,
+			},
+			"imageManifest":          img.manifest,
+			"imageManifestMediaType": img.mediaType,
+		},
+	})
+}
+
+func (s *Service) describeImages(w http.ResponseWriter, params map[string]interface{}) {
+	name := h.GetString(params, "repositoryName")
+	ids, _ := params["imageIds"].([]interface{})
+
+	s.mu.RLock()
+	repo := s.namedRepo(w, name)
+	if repo == nil {
+		s.mu.RUnlock()
+		return
+	}
+	// Requested images are listed in the order requested, and the others
+	// newest first, keyed by their position in the repository, which only
+	// ever grows.
+	var details []map[string]interface{}
+	key := paginate.Seq
+	if len(ids) > 0 {
+		for _, raw := range ids {
+			id, _ := raw.(map[string]interface{})
+			img := repo.image(h.GetString(id, "imageDigest"), h.GetString(id, "imageTag"))
+			if img == nil {
+				s.mu.RUnlock()
+				h.WriteJSONError(w, "ImageNotFoundException",
+					fmt.Sprintf("The image with imageId %v does not exist within the repository with name '%s' in the registry with id '%s'", id, name, h.DefaultAccountID), http.StatusBadRequest)
+				return
+			}
+			details = append(details, imageDetail(repo, img))
+		}
+	} else {
+		for i := len(repo.images) - 1; i >= 0; i-- {
+			details = append(details, imageDetail(repo, repo.images[i]))
+		}
+		n := len(details)
+		key = func(i int) string { return paginate.Desc(paginate.Seq(n - 1 - i)) }
+	}
+	s.mu.RUnlock()
+
+	page, next, err := paginate.Page(details, key, h.GetString(params, "nextToken"), h.GetInt(params, "maxResults", 0), 1000)
+	if err != nil {
+		h.WriteJSONError(w, "InvalidParameterException", "Invalid parameter at 'nextToken' failed to satisfy constraint: 'Invalid token'", http.StatusBadRequest)
+		return
+	}
+	resp := map[string]interface{}{"imageDetails": page}
+	if next != "" {
+		resp["nextToken"] = next
+	}
+	h.WriteJSON(w, http.StatusOK, resp)
+}
+
+// getAuthorizationToken returns a token for logging in to public.ecr.aws.
+// The registry accepts any client, so the token is not checked anywhere.
+func (s *Service) getAuthorizationToken(w http.ResponseWriter, _ map[string]interface{}) {
+	token := base64.StdEncoding.EncodeToString([]byte("AWS:" + h.NewRequestID()))
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"authorizationData": map[string]interface{}{
+			"authorizationToken": token,
+			"expiresAt":          float64(time.Now().Add(12 * time.Hour).Unix()),
+		},
+	})
+}
+
+func (s *Service) tagResource(w http.ResponseWriter, params map[string]interface{}) {
+	arn := h.GetString(params, "resourceArn")
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.repoByARN(arn) == nil {
+		h.WriteJSONError(w, "RepositoryNotFoundException", "The repository with arn '"+arn+"' does not exist", http.StatusBadRequest)
+		return
+	}
+	s.tags.Tag(arn, tags.FromList(params["tags"], "Key", "Value"))
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{})
+}
+
+func (s *Service) untagResource(w http.ResponseWriter, params map[string]interface{}) {
+	arn := h.GetString(params, "resourceArn")
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.repoByARN(arn) == nil {
+		h.WriteJSONError(w, "RepositoryNotFoundException", "The repository with arn '"+arn+"' does not exist", http.StatusBadRequest)
+		return
+	}
+	s.tags.Untag(arn, tags.Keys(params["tagKeys"]))
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{})
+}
+
+func (s *Service) listTagsForResource(w http.ResponseWriter, params map[string]interface{}) {
+	arn := h.GetString(params, "resourceArn")
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.repoByARN(arn) == nil {
+		h.WriteJSONError(w, "RepositoryNotFoundException", "The repository with arn '"+arn+"' does not exist", http.StatusBadRequest)
+		return
+	}
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"tags": tags.ToList(s.tags.Get(arn), "Key", "Value"),
+	})
+}
+
+// namedRepo returns the named repository, writing an error and returning
+// nil if it does not exist. The caller must hold s.mu.
+func (s *Service) namedRepo(w http.ResponseWriter, name string) *repository {
+	repo, exists := s.repos[name]
+	if !exists {
+		h.WriteJSONError(w, "RepositoryNotFoundException",
+			fmt.Sprintf("The repository with name '%s' does not exist in the registry with id '%s'", name, h.DefaultAccountID), http.StatusBadRequest)
+		return nil
+	}
+	return repo
+}
+
+// repoByARN returns the repository with the given ARN, or nil.
+// The caller must hold s.mu.
+func (s *Service) repoByARN(arn string) *repository {
+	for _, repo := range s.repos {
+		if repo.arn == arn {
+			return repo
+		}
+	}
+	return nil
+}
+
+// image returns the image with the given digest or tag, or nil.
+func (repo *repository) image(digest, tag string) *image {
+	for _, img := range repo.images {
+		if (digest != "" && img.digest == digest) || (tag != "" && containsString(img.tags, tag)) {
+			return img
+		}
+	}
+	return nil
+}
+
+func repoResp(repo *repository) map[string]interface{} {
+	return map[string]interface{}{
+		"repositoryName": repo.name,
+		"repositoryArn":  repo.arn,
+		"repositoryUri":  repo.uri,
+		"registryId":     h.DefaultAccountID,
+		"createdAt":      float64(repo.created.Unix()),
+	}
+}
+
+func catalogResp(repo *repository) map[string]interface{} {
+	out := map[string]interface{}{}
+	for _, key := range []string{"description", "architectures", "operatingSystems", "aboutText", "usageText"} {
+		if v, ok := repo.catalogData[key]; ok {
+			out[key] = v
+		}
+	}
+	return out
+}
+
+func imageDetail(repo *repository, img *image) map[string]interface{} {
+	d := map[string]interface{}{
+		"registryId":             h.DefaultAccountID,
+		"repositoryName":         repo.name,
+		"imageDigest":            img.digest,
+		"imagePushedAt":          float64(img.pushed.Unix()),
+		"imageSizeInBytes":       len(img.manifest),
+		"imageManifestMediaType": img.mediaType,
+	}
+	if len(img.tags) > 0 {
+		d["imageTags"] = img.tags
+	}
+	return d
+}
+
+func getStringSlice(params map[string]interface{}, key string) []string {
+	arr, _ := params[key].([]interface{})
+	out := make([]string, 0, len(arr))
+	for _, item := range arr {
+		if s, ok := item.(string); ok {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+func containsString(values []string, v string) bool {
+	for _, s := range values {
+		if s == v {
+			return true
+		}
+	}
+	return false
+}
+
+func removeString(values []string, v string) []string {
+	out := values[:0]
+	for _, s := range values {
+		if s != v {
+			out = append(out, s)
+		}
+	}
+	return out
+}
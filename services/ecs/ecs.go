@@ -17,19 +17,23 @@
 //   - UpdateService
 //   - ListServices
 //   - DescribeServices
+//   - TagResource
+//   - UntagResource
+//   - ListTagsForResource
 package ecs
 
 import (
 	"encoding/json"
 	"fmt"
-	"io"
 	"net/http"
 	"sort"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/riyanimam/goto/internal/lifecycle"
 	h "github.com/riyanimam/goto/internal/mockhelpers"
+	"github.com/riyanimam/goto/internal/tags"
 )
 
 // Service implements the ECS mock.
@@ -41,6 +45,9 @@ type Service struct {
 	tasks           map[string]*task
 	services        map[string]*ecsService
 	taskCounter     int
+	tags            *tags.Store
+
+	transitions *lifecycle.Transitions
 }
 
 type cluster struct {
@@ -71,6 +78,7 @@ type task struct {
 	lastStatus    string
 	desiredStatus string
 	startedAt     time.Time
+	ready         lifecycle.Transition
 }
 
 type ecsService struct {
@@ -91,6 +99,7 @@ func New() *Service {
 		taskDefFamilies: make(map[string]int),
 		tasks:           make(map[string]*task),
 		services:        make(map[string]*ecsService),
+		tags:            tags.New(),
 	}
 }
 
@@ -99,7 +108,27 @@ func (s *Service) Name() string { return "ecs" }
 
 // Handler returns the HTTP handler for ECS requests.
 func (s *Service) Handler() http.Handler {
-	return http.HandlerFunc(s.handle)
+	return h.JSONRouter{
+		"CreateCluster":            s.createCluster,
+		"DeleteCluster":            s.deleteCluster,
+		"DescribeClusters":         s.describeClusters,
+		"ListClusters":             s.listClusters,
+		"RegisterTaskDefinition":   s.registerTaskDefinition,
+		"DeregisterTaskDefinition": s.deregisterTaskDefinition,
+		"ListTaskDefinitions":      s.listTaskDefinitions,
+		"RunTask":                  s.runTask,
+		"StopTask":                 s.stopTask,
+		"ListTasks":                s.listTasks,
+		"DescribeTasks":            s.describeTasks,
+		"CreateService":            s.createService,
+		"DeleteService":            s.deleteService,
+		"UpdateService":            s.updateService,
+		"ListServices":             s.listServices,
+		"DescribeServices":         s.describeServices,
+		"TagResource":              s.tagResource,
+		"UntagResource":            s.untagResource,
+		"ListTagsForResource":      s.listTagsForResource,
+	}
 }
 
 // Reset clears all state.
@@ -112,69 +141,21 @@ func (s *Service) Reset() {
 	s.tasks = make(map[string]*task)
 	s.services = make(map[string]*ecsService)
 	s.taskCounter = 0
+	s.tags.DeleteService("ecs")
 }
 
-func (s *Service) handle(w http.ResponseWriter, r *http.Request) {
-	target := r.Header.Get("X-Amz-Target")
-
-	bodyBytes, err := io.ReadAll(r.Body)
-	if err != nil {
-		h.WriteJSONError(w, "ServerException", "could not read request body", http.StatusInternalServerError)
-		return
-	}
-
-	var params map[string]interface{}
-	if len(bodyBytes) > 0 {
-		json.Unmarshal(bodyBytes, &params)
-	}
-	if params == nil {
-		params = make(map[string]interface{})
-	}
-
-	action := ""
-	if target != "" {
-		parts := strings.SplitN(target, ".", 2)
-		if len(parts) == 2 {
-			action = parts[1]
-		}
-	}
+// SetTagStore sets the registry resource tags are recorded in.
+func (s *Service) SetTagStore(store *tags.Store) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tags = store
+}
 
-	switch action {
-	case "CreateCluster":
-		s.createCluster(w, params)
-	case "DeleteCluster":
-		s.deleteCluster(w, params)
-	case "DescribeClusters":
-		s.describeClusters(w, params)
-	case "ListClusters":
-		s.listClusters(w, params)
-	case "RegisterTaskDefinition":
-		s.registerTaskDefinition(w, params)
-	case "DeregisterTaskDefinition":
-		s.deregisterTaskDefinition(w, params)
-	case "ListTaskDefinitions":
-		s.listTaskDefinitions(w, params)
-	case "RunTask":
-		s.runTask(w, params)
-	case "StopTask":
-		s.stopTask(w, params)
-	case "ListTasks":
-		s.listTasks(w, params)
-	case "DescribeTasks":
-		s.describeTasks(w, params)
-	case "CreateService":
-		s.createService(w, params)
-	case "DeleteService":
-		s.deleteService(w, params)
-	case "UpdateService":
-		s.updateService(w, params)
-	case "ListServices":
-		s.listServices(w, params)
-	case "DescribeServices":
-		s.describeServices(w, params)
-	default:
-		h.WriteJSONError(w, "UnknownOperationException", fmt.Sprintf("action %q is not supported", action), http.StatusBadRequest)
-	}
+// SetTransitions sets how long new tasks stay PENDING.
+func (s *Service) SetTransitions(t *lifecycle.Transitions) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.transitions = t
 }
 
 func (s *Service) createCluster(w http.ResponseWriter, params map[string]interface{}) {
@@ -190,6 +171,7 @@ func (s *Service) createCluster(w http.ResponseWriter, params map[string]interfa
 		status: "ACTIVE",
 	}
 	s.clusters[name] = c
+	s.tags.Tag(c.arn, tags.FromList(params["tags"], "key", "value"))
 	s.mu.Unlock()
 
 	h.WriteJSON(w, http.StatusOK, map[string]interface{}{
@@ -210,6 +192,7 @@ func (s *Service) deleteCluster(w http.ResponseWriter, params map[string]interfa
 	}
 	c.status = "INACTIVE"
 	delete(s.clusters, name)
+	s.tags.Delete(c.arn)
 	s.mu.Unlock()
 
 	h.WriteJSON(w, http.StatusOK, map[string]interface{}{
@@ -291,6 +274,7 @@ func (s *Service) registerTaskDefinition(w http.ResponseWriter, params map[strin
 		containers: containers,
 	}
 	s.taskDefs[key] = td
+	s.tags.Tag(td.arn, tags.FromList(params["tags"], "key", "value"))
 	s.mu.Unlock()
 
 	h.WriteJSON(w, http.StatusOK, map[string]interface{}{
@@ -359,9 +343,11 @@ func (s *Service) runTask(w http.ResponseWriter, params map[string]interface{})
 			lastStatus:    "RUNNING",
 			desiredStatus: "RUNNING",
 			startedAt:     time.Now().UTC(),
+			ready:         s.transitions.Begin(),
 		}
 		s.tasks[taskArn] = t
-		tasks = append(tasks, taskResp(t))
+		s.tags.Tag(taskArn, tags.FromList(params["tags"], "key", "value"))
+		tasks = append(tasks, s.taskResp(t))
 	}
 	s.mu.Unlock()
 
@@ -386,7 +372,7 @@ func (s *Service) stopTask(w http.ResponseWriter, params map[string]interface{})
 	s.mu.Unlock()
 
 	h.WriteJSON(w, http.StatusOK, map[string]interface{}{
-		"task": taskResp(t),
+		"task": s.taskResp(t),
 	})
 }
 
@@ -417,7 +403,7 @@ func (s *Service) describeTasks(w http.ResponseWriter, params map[string]interfa
 	for _, ta := range taskArns {
 		arn, _ := ta.(string)
 		if t, exists := s.tasks[arn]; exists {
-			tasks = append(tasks, taskResp(t))
+			tasks = append(tasks, s.taskResp(t))
 		}
 	}
 	s.mu.RUnlock()
@@ -456,6 +442,7 @@ func (s *Service) createService(w http.ResponseWriter, params map[string]interfa
 		status:       "ACTIVE",
 	}
 	s.services[name] = svc
+	s.tags.Tag(svc.arn, tags.FromList(params["tags"], "key", "value"))
 	s.mu.Unlock()
 
 	h.WriteJSON(w, http.StatusOK, map[string]interface{}{
@@ -475,6 +462,7 @@ func (s *Service) deleteService(w http.ResponseWriter, params map[string]interfa
 	}
 	svc.status = "INACTIVE"
 	delete(s.services, name)
+	s.tags.Delete(svc.arn)
 	s.mu.Unlock()
 
 	h.WriteJSON(w, http.StatusOK, map[string]interface{}{
@@ -577,12 +565,16 @@ func taskDefResp(td *taskDefinition) map[string]interface{} {
 	}
 }
 
-func taskResp(t *task) map[string]interface{} {
+func (s *Service) taskResp(t *task) map[string]interface{} {
+	status := t.lastStatus
+	if status == "RUNNING" {
+		status = s.transitions.Status(t.ready, "PENDING", status)
+	}
 	return map[string]interface{}{
 		"taskArn":           t.arn,
 		"taskDefinitionArn": t.taskDefArn,
 		"clusterArn":        t.clusterArn,
-		"lastStatus":        t.lastStatus,
+		"lastStatus":        status,
 		"desiredStatus":     t.desiredStatus,
 		"startedAt":         float64(t.startedAt.Unix()),
 	}
@@ -599,3 +591,109 @@ func serviceResp(svc *ecsService) map[string]interface{} {
 		"status":         svc.status,
 	}
 }
+
+// Deliver adjusts the service identified by arn, as Application Auto
+// Scaling does. A payload of {"desiredCount": n} sets its desired count, and
+// an empty object leaves it; either way the response reports the service's
+// desiredCount and runningCount.
+func (s *Service) Deliver(arn string, payload []byte) ([]byte, error) {
+	var req struct {
+		DesiredCount *int `json:"desiredCount"`
+	}
+	if len(payload) > 0 {
+		if err := json.Unmarshal(payload, &req); err != nil {
+			return nil, fmt.Errorf("invalid payload for %s: %w", arn, err)
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var svc *ecsService
+	for _, candidate := range s.services {
+		if candidate.arn == arn {
+			svc = candidate
+			break
+		}
+	}
+	if svc == nil {
+		return nil, fmt.Errorf("service %s not found", arn)
+	}
+	if req.DesiredCount != nil {
+		if *req.DesiredCount < 0 {
+			return nil, fmt.Errorf("desiredCount must not be negative")
+		}
+		svc.desiredCount = *req.DesiredCount
+		svc.runningCount = *req.DesiredCount
+	}
+	return json.Marshal(map[string]int{
+		"desiredCount": svc.desiredCount,
+		"runningCount": svc.runningCount,
+	})
+}
+
+// hasResource reports whether arn names a cluster, task definition, task, or
+// service. Caller must hold s.mu.
+func (s *Service) hasResource(arn string) bool {
+	for _, c := range s.clusters {
+		if c.arn == arn {
+			return true
+		}
+	}
+	for _, td := range s.taskDefs {
+		if td.arn == arn {
+			return true
+		}
+	}
+	if _, ok := s.tasks[arn]; ok {
+		return true
+	}
+	for _, svc := range s.services {
+		if svc.arn == arn {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Service) tagResource(w http.ResponseWriter, params map[string]interface{}) {
+	arn := h.GetString(params, "resourceArn")
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if !s.hasResource(arn) {
+		h.WriteJSONError(w, "ResourceNotFoundException", "The specified resource could not be found: "+arn, http.StatusBadRequest)
+		return
+	}
+	s.tags.Tag(arn, tags.FromList(params["tags"], "key", "value"))
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{})
+}
+
+func (s *Service) untagResource(w http.ResponseWriter, params map[string]interface{}) {
+	arn := h.GetString(params, "resourceArn")
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if !s.hasResource(arn) {
+		h.WriteJSONError(w, "ResourceNotFoundException", "The specified resource could not be found: "+arn, http.StatusBadRequest)
+		return
+	}
+	s.tags.Untag(arn, tags.Keys(params["tagKeys"]))
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{})
+}
+
+func (s *Service) listTagsForResource(w http.ResponseWriter, params map[string]interface{}) {
+	arn := h.GetString(params, "resourceArn")
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if !s.hasResource(arn) {
+		h.WriteJSONError(w, "ResourceNotFoundException", "The specified resource could not be found: "+arn, http.StatusBadRequest)
+		return
+	}
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"tags": tags.ToList(s.tags.Get(arn), "key", "value"),
+	})
+}
@@ -7,6 +7,12 @@
 //   - CreateMountTarget
 //   - DescribeMountTargets
 //   - DeleteMountTarget
+//   - TagResource
+//   - UntagResource
+//   - ListTagsForResource
+//
+// File systems hold files, which other services read and write directly
+// (see WriteFile and ReadFile) as no NFS server is provided.
 package efs
 
 import (
@@ -19,6 +25,7 @@ import (
 	"time"
 
 	h "github.com/riyanimam/goto/internal/mockhelpers"
+	"github.com/riyanimam/goto/internal/tags"
 )
 
 // Service implements the EFS mock.
@@ -26,6 +33,7 @@ type Service struct {
 	mu           sync.RWMutex
 	fileSystems  map[string]*fileSystem
 	mountTargets map[string]*mountTarget
+	tags         *tags.Store
 }
 
 type fileSystem struct {
@@ -36,6 +44,7 @@ type fileSystem struct {
 	lifeCycleState  string
 	sizeInBytes     int64
 	created         time.Time
+	files           map[string][]byte // contents by absolute path
 }
 
 type mountTarget struct {
@@ -51,6 +60,7 @@ func New() *Service {
 	return &Service{
 		fileSystems:  make(map[string]*fileSystem),
 		mountTargets: make(map[string]*mountTarget),
+		tags:         tags.New(),
 	}
 }
 
@@ -68,6 +78,14 @@ func (s *Service) Reset() {
 	defer s.mu.Unlock()
 	s.fileSystems = make(map[string]*fileSystem)
 	s.mountTargets = make(map[string]*mountTarget)
+	s.tags.DeleteService("elasticfilesystem")
+}
+
+// SetTagStore sets the registry file system tags are recorded in.
+func (s *Service) SetTagStore(store *tags.Store) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tags = store
 }
 
 func (s *Service) handle(w http.ResponseWriter, r *http.Request) {
@@ -75,6 +93,11 @@ func (s *Service) handle(w http.ResponseWriter, r *http.Request) {
 	method := r.Method
 
 	switch {
+	// TagResource, UntagResource, ListTagsForResource:
+	// /2015-02-01/resource-tags/{resourceId}
+	case strings.HasPrefix(path, "/2015-02-01/resource-tags/"):
+		s.handleTags(w, r, strings.TrimPrefix(path, "/2015-02-01/resource-tags/"))
+
 	// DeleteFileSystem: DELETE /2015-02-01/file-systems/{fsId}
 	case strings.HasPrefix(path, "/2015-02-01/file-systems/") && method == http.MethodDelete:
 		s.deleteFileSystem(w, r, path)
@@ -143,18 +166,21 @@ func (s *Service) createFileSystem(w http.ResponseWriter, r *http.Request) {
 		lifeCycleState:  "available",
 		sizeInBytes:     0,
 		created:         now,
+		files:           make(map[string][]byte),
 	}
 	s.fileSystems[id] = fs
+	s.tags.Tag(fileSystemArn(id), tags.FromList(params["Tags"], "Key", "Value"))
+	resp := s.fileSystemResp(fs)
 	s.mu.Unlock()
 
-	h.WriteJSON(w, http.StatusCreated, fileSystemResp(fs))
+	h.WriteJSON(w, http.StatusCreated, resp)
 }
 
 func (s *Service) describeFileSystems(w http.ResponseWriter, _ *http.Request) {
 	s.mu.RLock()
 	var systems []map[string]interface{}
 	for _, fs := range s.fileSystems {
-		systems = append(systems, fileSystemResp(fs))
+		systems = append(systems, s.fileSystemResp(fs))
 	}
 	s.mu.RUnlock()
 
@@ -182,6 +208,7 @@ func (s *Service) deleteFileSystem(w http.ResponseWriter, _ *http.Request, path
 		return
 	}
 	delete(s.fileSystems, fsId)
+	s.tags.Delete(fileSystemArn(fsId))
 	s.mu.Unlock()
 
 	w.WriteHeader(http.StatusNoContent)
@@ -268,9 +295,19 @@ func (s *Service) deleteMountTarget(w http.ResponseWriter, _ *http.Request, path
 	w.WriteHeader(http.StatusNoContent)
 }
 
-func fileSystemResp(fs *fileSystem) map[string]interface{} {
+func fileSystemArn(id string) string {
+	return fmt.Sprintf("arn:aws:elasticfilesystem:us-east-1:%s:file-system/%s", h.DefaultAccountID, id)
+}
+
+// fileSystemResp describes fs, named by its Name tag. The caller must hold
+// s.mu.
+func (s *Service) fileSystemResp(fs *fileSystem) map[string]interface{} {
+	t := s.tags.Get(fileSystemArn(fs.id))
 	return map[string]interface{}{
 		"FileSystemId":    fs.id,
+		"FileSystemArn":   fileSystemArn(fs.id),
+		"Name":            t["Name"],
+		"Tags":            tags.ToList(t, "Key", "Value"),
 		"CreationToken":   fs.creationToken,
 		"PerformanceMode": fs.performanceMode,
 		"Encrypted":       fs.encrypted,
@@ -291,3 +328,33 @@ func mountTargetResp(mt *mountTarget) map[string]interface{} {
 		"LifeCycleState": mt.lifeCycleState,
 	}
 }
+
+// handleTags serves TagResource (POST), UntagResource (DELETE), and
+// ListTagsForResource (GET) for a file system named by its ID or ARN.
+func (s *Service) handleTags(w http.ResponseWriter, r *http.Request, resource string) {
+	id := strings.TrimPrefix(resource, fileSystemArn(""))
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if _, ok := s.fileSystems[id]; !ok {
+		h.WriteJSONError(w, "FileSystemNotFound", "File system "+id+" not found", http.StatusNotFound)
+		return
+	}
+	arn := fileSystemArn(id)
+
+	switch r.Method {
+	case http.MethodPost:
+		var params map[string]interface{}
+		bodyBytes, _ := io.ReadAll(r.Body)
+		json.Unmarshal(bodyBytes, &params)
+		s.tags.Tag(arn, tags.FromList(params["Tags"], "Key", "Value"))
+		w.WriteHeader(http.StatusOK)
+	case http.MethodDelete:
+		s.tags.Untag(arn, r.URL.Query()["tagKeys"])
+		w.WriteHeader(http.StatusOK)
+	default:
+		h.WriteJSON(w, http.StatusOK, map[string]interface{}{
+			"Tags": tags.ToList(s.tags.Get(arn), "Key", "Value"),
+		})
+	}
+}
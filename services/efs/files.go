@@ -0,0 +1,100 @@
+package efs
+
+import (
+	"errors"
+	"path"
+	"sort"
+	"strings"
+)
+
+// ErrFileSystemNotFound is returned by the file operations when the file
+// system does not exist.
+var ErrFileSystemNotFound = errors.New("FileSystemNotFound: the file system does not exist")
+
+// ErrNoSuchFile is returned by ReadFile and DeleteFile when the path is not
+// a file.
+var ErrNoSuchFile = errors.New("no such file")
+
+// cleanPath makes p absolute and removes any "." and ".." elements.
+func cleanPath(p string) string {
+	return path.Clean("/" + p)
+}
+
+// lookupFiles returns the files of the file system. The caller must hold
+// s.mu.
+func (s *Service) lookupFiles(fileSystemID string) (*fileSystem, error) {
+	fs, exists := s.fileSystems[fileSystemID]
+	if !exists {
+		return nil, ErrFileSystemNotFound
+	}
+	return fs, nil
+}
+
+// WriteFile stores data as the file at p, replacing any file already there.
+func (s *Service) WriteFile(fileSystemID, p string, data []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	fs, err := s.lookupFiles(fileSystemID)
+	if err != nil {
+		return err
+	}
+	p = cleanPath(p)
+	if p == "/" {
+		return errors.New("cannot write to the root directory")
+	}
+	fs.sizeInBytes += int64(len(data)) - int64(len(fs.files[p]))
+	fs.files[p] = append([]byte(nil), data...)
+	return nil
+}
+
+// ReadFile returns the contents of the file at p.
+func (s *Service) ReadFile(fileSystemID, p string) ([]byte, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	fs, err := s.lookupFiles(fileSystemID)
+	if err != nil {
+		return nil, err
+	}
+	data, ok := fs.files[cleanPath(p)]
+	if !ok {
+		return nil, ErrNoSuchFile
+	}
+	return append([]byte(nil), data...), nil
+}
+
+// DeleteFile removes the file at p.
+func (s *Service) DeleteFile(fileSystemID, p string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	fs, err := s.lookupFiles(fileSystemID)
+	if err != nil {
+		return err
+	}
+	p = cleanPath(p)
+	data, ok := fs.files[p]
+	if !ok {
+		return ErrNoSuchFile
+	}
+	fs.sizeInBytes -= int64(len(data))
+	delete(fs.files, p)
+	return nil
+}
+
+// ListFiles returns the sorted paths of the files under dir, at any depth.
+func (s *Service) ListFiles(fileSystemID, dir string) ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	fs, err := s.lookupFiles(fileSystemID)
+	if err != nil {
+		return nil, err
+	}
+	prefix := strings.TrimSuffix(cleanPath(dir), "/") + "/"
+	var paths []string
+	for p := range fs.files {
+		if strings.HasPrefix(p, prefix) {
+			paths = append(paths, p)
+		}
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
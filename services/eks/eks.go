@@ -9,6 +9,9 @@
 //   - DescribeNodegroup
 //   - DeleteNodegroup
 //   - ListNodegroups
+//   - TagResource
+//   - UntagResource
+//   - ListTagsForResource
 package eks
 
 import (
@@ -21,13 +24,18 @@ import (
 	"sync"
 	"time"
 
+	"github.com/riyanimam/goto/internal/lifecycle"
 	h "github.com/riyanimam/goto/internal/mockhelpers"
+	"github.com/riyanimam/goto/internal/tags"
 )
 
 // Service implements the EKS mock.
 type Service struct {
 	mu       sync.RWMutex
 	clusters map[string]*cluster
+	tags     *tags.Store
+
+	transitions *lifecycle.Transitions
 }
 
 type cluster struct {
@@ -38,6 +46,7 @@ type cluster struct {
 	roleArn    string
 	endpoint   string
 	created    time.Time
+	ready      lifecycle.Transition
 	nodegroups map[string]*nodegroup
 }
 
@@ -51,12 +60,14 @@ type nodegroup struct {
 	maxSize  int32
 	subnets  []string
 	created  time.Time
+	ready    lifecycle.Transition
 }
 
 // New creates a new EKS mock service.
 func New() *Service {
 	return &Service{
 		clusters: make(map[string]*cluster),
+		tags:     tags.New(),
 	}
 }
 
@@ -73,6 +84,21 @@ func (s *Service) Reset() {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	s.clusters = make(map[string]*cluster)
+	s.tags.DeleteService("eks")
+}
+
+// SetTagStore sets the registry resource tags are recorded in.
+func (s *Service) SetTagStore(store *tags.Store) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tags = store
+}
+
+// SetTransitions sets how long new clusters and node groups stay CREATING.
+func (s *Service) SetTransitions(t *lifecycle.Transitions) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.transitions = t
 }
 
 func (s *Service) handle(w http.ResponseWriter, r *http.Request) {
@@ -80,6 +106,8 @@ func (s *Service) handle(w http.ResponseWriter, r *http.Request) {
 	method := r.Method
 
 	switch {
+	case strings.Contains(path, "/tags/"):
+		s.handleTags(w, r, path[strings.Index(path, "/tags/")+len("/tags/"):])
 	// Nodegroups: /clusters/{name}/node-groups/{ngName}
 	case strings.Contains(path, "/node-groups/") && method == http.MethodGet:
 		s.describeNodegroup(w, r, path)
@@ -154,13 +182,15 @@ func (s *Service) createCluster(w http.ResponseWriter, r *http.Request) {
 		roleArn:    roleArn,
 		endpoint:   endpoint,
 		created:    now,
+		ready:      s.transitions.Begin(),
 		nodegroups: make(map[string]*nodegroup),
 	}
 	s.clusters[name] = c
+	s.tags.Tag(arn, tags.FromMap(params["tags"]))
 	s.mu.Unlock()
 
 	h.WriteJSON(w, http.StatusOK, map[string]interface{}{
-		"cluster": clusterResp(c),
+		"cluster": s.clusterResp(c),
 	})
 }
 
@@ -177,7 +207,7 @@ func (s *Service) describeCluster(w http.ResponseWriter, _ *http.Request, path s
 	}
 
 	h.WriteJSON(w, http.StatusOK, map[string]interface{}{
-		"cluster": clusterResp(c),
+		"cluster": s.clusterResp(c),
 	})
 }
 
@@ -192,7 +222,11 @@ func (s *Service) deleteCluster(w http.ResponseWriter, _ *http.Request, path str
 		return
 	}
 	c.status = "DELETING"
-	resp := clusterResp(c)
+	resp := s.clusterResp(c)
+	for _, ng := range c.nodegroups {
+		s.tags.Delete(ng.arn)
+	}
+	s.tags.Delete(c.arn)
 	delete(s.clusters, name)
 	s.mu.Unlock()
 
@@ -258,12 +292,14 @@ func (s *Service) createNodegroup(w http.ResponseWriter, r *http.Request, path s
 		maxSize:  int32(h.GetInt(params, "maxSize", 3)),
 		subnets:  subnets,
 		created:  time.Now().UTC(),
+		ready:    s.transitions.Begin(),
 	}
 	c.nodegroups[ngName] = ng
+	s.tags.Tag(arn, tags.FromMap(params["tags"]))
 	s.mu.Unlock()
 
 	h.WriteJSON(w, http.StatusOK, map[string]interface{}{
-		"nodegroup": nodegroupResp(ng, clusterName),
+		"nodegroup": s.nodegroupResp(ng, clusterName),
 	})
 }
 
@@ -292,7 +328,7 @@ func (s *Service) describeNodegroup(w http.ResponseWriter, _ *http.Request, path
 	}
 
 	h.WriteJSON(w, http.StatusOK, map[string]interface{}{
-		"nodegroup": nodegroupResp(ng, clusterName),
+		"nodegroup": s.nodegroupResp(ng, clusterName),
 	})
 }
 
@@ -319,7 +355,8 @@ func (s *Service) deleteNodegroup(w http.ResponseWriter, _ *http.Request, path s
 		return
 	}
 	ng.status = "DELETING"
-	resp := nodegroupResp(ng, clusterName)
+	resp := s.nodegroupResp(ng, clusterName)
+	s.tags.Delete(ng.arn)
 	delete(c.nodegroups, ngName)
 	s.mu.Unlock()
 
@@ -352,11 +389,15 @@ func (s *Service) listNodegroups(w http.ResponseWriter, _ *http.Request, path st
 	})
 }
 
-func clusterResp(c *cluster) map[string]interface{} {
+func (s *Service) clusterResp(c *cluster) map[string]interface{} {
+	status := c.status
+	if status == "ACTIVE" {
+		status = s.transitions.Status(c.ready, "CREATING", status)
+	}
 	return map[string]interface{}{
 		"name":            c.name,
 		"arn":             c.arn,
-		"status":          c.status,
+		"status":          status,
 		"version":         c.version,
 		"roleArn":         c.roleArn,
 		"endpoint":        c.endpoint,
@@ -365,12 +406,16 @@ func clusterResp(c *cluster) map[string]interface{} {
 	}
 }
 
-func nodegroupResp(ng *nodegroup, clusterName string) map[string]interface{} {
+func (s *Service) nodegroupResp(ng *nodegroup, clusterName string) map[string]interface{} {
+	status := ng.status
+	if status == "ACTIVE" {
+		status = s.transitions.Status(ng.ready, "CREATING", status)
+	}
 	return map[string]interface{}{
 		"nodegroupName": ng.name,
 		"nodegroupArn":  ng.arn,
 		"clusterName":   clusterName,
-		"status":        ng.status,
+		"status":        status,
 		"nodeRole":      ng.nodeRole,
 		"subnets":       ng.subnets,
 		"scalingConfig": map[string]interface{}{
@@ -381,3 +426,46 @@ func nodegroupResp(ng *nodegroup, clusterName string) map[string]interface{} {
 		"createdAt": float64(ng.created.Unix()),
 	}
 }
+
+// hasResource reports whether arn names an existing resource.
+// The caller must hold s.mu.
+func (s *Service) hasResource(arn string) bool {
+	for _, c := range s.clusters {
+		if c.arn == arn {
+			return true
+		}
+		for _, ng := range c.nodegroups {
+			if ng.arn == arn {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// handleTags serves TagResource (POST), UntagResource (DELETE), and
+// ListTagsForResource (GET) on /tags/{arn}.
+func (s *Service) handleTags(w http.ResponseWriter, r *http.Request, arn string) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if !s.hasResource(arn) {
+		h.WriteJSONError(w, "ResourceNotFoundException", "Resource not found: "+arn, http.StatusNotFound)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPost:
+		var params map[string]interface{}
+		bodyBytes, _ := io.ReadAll(r.Body)
+		json.Unmarshal(bodyBytes, &params)
+		s.tags.Tag(arn, tags.FromMap(params["tags"]))
+		h.WriteJSON(w, http.StatusOK, map[string]interface{}{})
+	case http.MethodDelete:
+		s.tags.Untag(arn, r.URL.Query()["tagKeys"])
+		h.WriteJSON(w, http.StatusOK, map[string]interface{}{})
+	default:
+		h.WriteJSON(w, http.StatusOK, map[string]interface{}{
+			"tags": s.tags.Get(arn),
+		})
+	}
+}
@@ -8,6 +8,21 @@
 //   - CreateReplicationGroup
 //   - DeleteReplicationGroup
 //   - DescribeReplicationGroups
+//   - AddTagsToResource
+//   - RemoveTagsFromResource
+//   - ListTagsForResource
+//   - CreateSnapshot
+//   - DescribeSnapshots
+//   - CopySnapshot
+//   - DeleteSnapshot
+//   - DescribeEvents
+//
+// ModifyCacheCluster applies changes at once with ApplyImmediately, the
+// cluster showing "modifying" for the status transition. Otherwise the
+// changes are reported in PendingModifiedValues until the cluster's
+// preferred maintenance window opens on the mock clock. Creating, changing,
+// snapshotting, and deleting clusters and replication groups record the
+// events DescribeEvents returns.
 package elasticache
 
 import (
@@ -15,10 +30,14 @@ import (
 	"fmt"
 	"net/http"
 	"sort"
+	"strconv"
 	"sync"
 	"time"
 
+	"github.com/riyanimam/goto/internal/clock"
+	"github.com/riyanimam/goto/internal/lifecycle"
 	h "github.com/riyanimam/goto/internal/mockhelpers"
+	"github.com/riyanimam/goto/internal/tags"
 )
 
 // Service implements the ElastiCache mock.
@@ -26,6 +45,12 @@ type Service struct {
 	mu                sync.RWMutex
 	clusters          map[string]*cacheCluster
 	replicationGroups map[string]*replicationGroup
+	snapshots         map[string]*snapshot
+	events            []event
+	tags              *tags.Store
+	transitions       *lifecycle.Transitions
+	objects           h.ObjectStore
+	clock             *clock.Clock
 }
 
 type cacheCluster struct {
@@ -37,8 +62,23 @@ type cacheCluster struct {
 	nodeType  string
 	numNodes  int
 	created   time.Time
+	ready     lifecycle.Transition
+
+	maintenanceWindow string
+	pending           pendingValues
+	modified          lifecycle.Transition
+}
+
+// pendingValues are modifications waiting for a cluster's maintenance
+// window. Zero fields are unchanged.
+type pendingValues struct {
+	nodeType  string
+	engineVer string
+	numNodes  int
 }
 
+func (p pendingValues) empty() bool { return p == pendingValues{} }
+
 type replicationGroup struct {
 	id          string
 	arn         string
@@ -47,6 +87,7 @@ type replicationGroup struct {
 	nodeType    string
 	numClusters int
 	created     time.Time
+	ready       lifecycle.Transition
 }
 
 // New creates a new ElastiCache mock service.
@@ -54,6 +95,8 @@ func New() *Service {
 	return &Service{
 		clusters:          make(map[string]*cacheCluster),
 		replicationGroups: make(map[string]*replicationGroup),
+		snapshots:         make(map[string]*snapshot),
+		tags:              tags.New(),
 	}
 }
 
@@ -62,7 +105,25 @@ func (s *Service) Name() string { return "elasticache" }
 
 // Handler returns the HTTP handler for ElastiCache requests.
 func (s *Service) Handler() http.Handler {
-	return http.HandlerFunc(s.handle)
+	return h.QueryRouter{
+		Actions: map[string]http.HandlerFunc{
+			"CreateCacheCluster":        s.createCacheCluster,
+			"DeleteCacheCluster":        s.deleteCacheCluster,
+			"DescribeCacheClusters":     s.describeCacheClusters,
+			"ModifyCacheCluster":        s.modifyCacheCluster,
+			"CreateReplicationGroup":    s.createReplicationGroup,
+			"DeleteReplicationGroup":    s.deleteReplicationGroup,
+			"DescribeReplicationGroups": s.describeReplicationGroups,
+			"AddTagsToResource":         s.addTagsToResource,
+			"RemoveTagsFromResource":    s.removeTagsFromResource,
+			"ListTagsForResource":       s.listTagsForResource,
+			"CreateSnapshot":            s.createSnapshot,
+			"DescribeSnapshots":         s.describeSnapshots,
+			"CopySnapshot":              s.copySnapshot,
+			"DeleteSnapshot":            s.deleteSnapshot,
+			"DescribeEvents":            s.describeEvents,
+		},
+	}
 }
 
 // Reset clears all state.
@@ -71,32 +132,48 @@ func (s *Service) Reset() {
 	defer s.mu.Unlock()
 	s.clusters = make(map[string]*cacheCluster)
 	s.replicationGroups = make(map[string]*replicationGroup)
+	s.snapshots = make(map[string]*snapshot)
+	s.events = nil
+	s.tags.DeleteService("elasticache")
 }
 
-func (s *Service) handle(w http.ResponseWriter, r *http.Request) {
-	action := r.URL.Query().Get("Action")
-	if action == "" {
-		action = r.FormValue("Action")
-	}
-
-	switch action {
-	case "CreateCacheCluster":
-		s.createCacheCluster(w, r)
-	case "DeleteCacheCluster":
-		s.deleteCacheCluster(w, r)
-	case "DescribeCacheClusters":
-		s.describeCacheClusters(w, r)
-	case "ModifyCacheCluster":
-		s.modifyCacheCluster(w, r)
-	case "CreateReplicationGroup":
-		s.createReplicationGroup(w, r)
-	case "DeleteReplicationGroup":
-		s.deleteReplicationGroup(w, r)
-	case "DescribeReplicationGroups":
-		s.describeReplicationGroups(w, r)
-	default:
-		h.WriteXMLError(w, "Sender", "InvalidAction", fmt.Sprintf("action %q is not supported", action), http.StatusBadRequest)
+// SetTagStore sets the registry resource tags are recorded in.
+func (s *Service) SetTagStore(store *tags.Store) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tags = store
+}
+
+// SetTransitions sets how long new clusters and replication groups stay
+// "creating".
+func (s *Service) SetTransitions(t *lifecycle.Transitions) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.transitions = t
+}
+
+// SetObjectStore sets the store snapshots exported by CopySnapshot with a
+// TargetBucket are written to.
+func (s *Service) SetObjectStore(store h.ObjectStore) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.objects = store
+}
+
+// SetClock attaches the mock clock that events are dated by, applying
+// pending modifications as maintenance windows open while it advances.
+func (s *Service) SetClock(c *clock.Clock) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.clock = c
+	c.OnAdvance(s.runMaintenance)
+}
+
+func (s *Service) now() time.Time {
+	if s.clock != nil {
+		return s.clock.Now()
 	}
+	return time.Now().UTC()
 }
 
 func getFormVal(r *http.Request, key string) string {
@@ -131,6 +208,22 @@ func (s *Service) createCacheCluster(w http.ResponseWriter, r *http.Request) {
 	if nodeType == "" {
 		nodeType = "cache.t3.micro"
 	}
+	numNodes := 1
+	if v := getFormVal(r, "NumCacheNodes"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			h.WriteXMLError(w, "Sender", "InvalidParameterValue", "NumCacheNodes must be a positive integer", http.StatusBadRequest)
+			return
+		}
+		numNodes = n
+	}
+	window := getFormVal(r, "PreferredMaintenanceWindow")
+	if window == "" {
+		window = defaultMaintenanceWindow
+	} else if _, err := parseWindow(window); err != nil {
+		h.WriteXMLError(w, "Sender", "InvalidParameterValue", err.Error(), http.StatusBadRequest)
+		return
+	}
 
 	s.mu.Lock()
 	if _, exists := s.clusters[id]; exists {
@@ -147,10 +240,15 @@ func (s *Service) createCacheCluster(w http.ResponseWriter, r *http.Request) {
 		engine:    engine,
 		engineVer: engineVer,
 		nodeType:  nodeType,
-		numNodes:  1,
-		created:   time.Now().UTC(),
+		numNodes:  numNodes,
+		created:   s.now(),
+		ready:     s.transitions.Begin(),
+
+		maintenanceWindow: window,
 	}
 	s.clusters[id] = cc
+	s.tags.Tag(arn, tags.FromForm(r.Form, "Tags.Tag."))
+	s.record(id, sourceCacheCluster, "Cache cluster created")
 	s.mu.Unlock()
 
 	type ccResult struct {
@@ -161,7 +259,7 @@ func (s *Service) createCacheCluster(w http.ResponseWriter, r *http.Request) {
 		XMLName xml.Name `xml:"CreateCacheClusterResponse"`
 		Result  ccResult `xml:"CreateCacheClusterResult"`
 	}
-	h.WriteXML(w, http.StatusOK, ccResp{Result: ccResult{CacheCluster: clusterToXML(cc)}})
+	h.WriteXML(w, http.StatusOK, ccResp{Result: ccResult{CacheCluster: s.clusterToXML(cc)}})
 }
 
 func (s *Service) deleteCacheCluster(w http.ResponseWriter, r *http.Request) {
@@ -176,8 +274,10 @@ func (s *Service) deleteCacheCluster(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	cc.status = "deleting"
-	resp := clusterToXML(cc)
+	resp := s.clusterToXML(cc)
 	delete(s.clusters, id)
+	s.tags.Delete(cc.arn)
+	s.record(id, sourceCacheCluster, "Cache cluster deleted")
 	s.mu.Unlock()
 
 	type delResult struct {
@@ -199,11 +299,11 @@ func (s *Service) describeCacheClusters(w http.ResponseWriter, r *http.Request)
 	var items []ccXML
 	if id != "" {
 		if cc, exists := s.clusters[id]; exists {
-			items = append(items, clusterToXML(cc))
+			items = append(items, s.clusterToXML(cc))
 		}
 	} else {
 		for _, cc := range s.clusters {
-			items = append(items, clusterToXML(cc))
+			items = append(items, s.clusterToXML(cc))
 		}
 	}
 	s.mu.RUnlock()
@@ -235,12 +335,50 @@ func (s *Service) modifyCacheCluster(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if nodeType := getFormVal(r, "CacheNodeType"); nodeType != "" {
-		cc.nodeType = nodeType
+	changes := pendingValues{
+		nodeType:  getFormVal(r, "CacheNodeType"),
+		engineVer: getFormVal(r, "EngineVersion"),
+	}
+	if v := getFormVal(r, "NumCacheNodes"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			s.mu.Unlock()
+			h.WriteXMLError(w, "Sender", "InvalidParameterValue", "NumCacheNodes must be a positive integer", http.StatusBadRequest)
+			return
+		}
+		if n != cc.numNodes {
+			changes.numNodes = n
+		}
+	}
+	if changes.nodeType == cc.nodeType {
+		changes.nodeType = ""
+	}
+	if changes.engineVer == cc.engineVer {
+		changes.engineVer = ""
+	}
+	window := getFormVal(r, "PreferredMaintenanceWindow")
+	if window != "" {
+		if _, err := parseWindow(window); err != nil {
+			s.mu.Unlock()
+			h.WriteXMLError(w, "Sender", "InvalidParameterValue", err.Error(), http.StatusBadRequest)
+			return
+		}
 	}
-	if engineVer := getFormVal(r, "EngineVersion"); engineVer != "" {
-		cc.engineVer = engineVer
+	if !changes.empty() {
+		if status := s.clusterToXML(cc).CacheClusterStatus; status != "available" {
+			s.mu.Unlock()
+			h.WriteXMLError(w, "Sender", "InvalidCacheClusterState", "Cache cluster "+id+" is not in available state", http.StatusBadRequest)
+			return
+		}
+	}
+	if window != "" {
+		cc.maintenanceWindow = window
+	}
+	cc.pending = cc.pending.merge(changes)
+	if getFormVal(r, "ApplyImmediately") == "true" && !cc.pending.empty() {
+		s.applyPending(cc)
 	}
+	resp := s.clusterToXML(cc)
 	s.mu.Unlock()
 
 	type modResult struct {
@@ -251,7 +389,7 @@ func (s *Service) modifyCacheCluster(w http.ResponseWriter, r *http.Request) {
 		XMLName xml.Name  `xml:"ModifyCacheClusterResponse"`
 		Result  modResult `xml:"ModifyCacheClusterResult"`
 	}
-	h.WriteXML(w, http.StatusOK, modResp{Result: modResult{CacheCluster: clusterToXML(cc)}})
+	h.WriteXML(w, http.StatusOK, modResp{Result: modResult{CacheCluster: resp}})
 }
 
 func (s *Service) createReplicationGroup(w http.ResponseWriter, r *http.Request) {
@@ -283,9 +421,12 @@ func (s *Service) createReplicationGroup(w http.ResponseWriter, r *http.Request)
 		status:      "available",
 		nodeType:    nodeType,
 		numClusters: 1,
-		created:     time.Now().UTC(),
+		created:     s.now(),
+		ready:       s.transitions.Begin(),
 	}
 	s.replicationGroups[id] = rg
+	s.tags.Tag(arn, tags.FromForm(r.Form, "Tags.Tag."))
+	s.record(id, sourceReplicationGroup, "Replication group "+id+" created")
 	s.mu.Unlock()
 
 	type rgResult struct {
@@ -296,7 +437,7 @@ func (s *Service) createReplicationGroup(w http.ResponseWriter, r *http.Request)
 		XMLName xml.Name `xml:"CreateReplicationGroupResponse"`
 		Result  rgResult `xml:"CreateReplicationGroupResult"`
 	}
-	h.WriteXML(w, http.StatusOK, rgResp{Result: rgResult{ReplicationGroup: rgToXML(rg)}})
+	h.WriteXML(w, http.StatusOK, rgResp{Result: rgResult{ReplicationGroup: s.rgToXML(rg)}})
 }
 
 func (s *Service) deleteReplicationGroup(w http.ResponseWriter, r *http.Request) {
@@ -311,8 +452,10 @@ func (s *Service) deleteReplicationGroup(w http.ResponseWriter, r *http.Request)
 		return
 	}
 	rg.status = "deleting"
-	resp := rgToXML(rg)
+	resp := s.rgToXML(rg)
 	delete(s.replicationGroups, id)
+	s.tags.Delete(rg.arn)
+	s.record(id, sourceReplicationGroup, "Replication group "+id+" deleted")
 	s.mu.Unlock()
 
 	type delResult struct {
@@ -334,11 +477,11 @@ func (s *Service) describeReplicationGroups(w http.ResponseWriter, r *http.Reque
 	var items []rgXML
 	if id != "" {
 		if rg, exists := s.replicationGroups[id]; exists {
-			items = append(items, rgToXML(rg))
+			items = append(items, s.rgToXML(rg))
 		}
 	} else {
 		for _, rg := range s.replicationGroups {
-			items = append(items, rgToXML(rg))
+			items = append(items, s.rgToXML(rg))
 		}
 	}
 	s.mu.RUnlock()
@@ -358,25 +501,118 @@ func (s *Service) describeReplicationGroups(w http.ResponseWriter, r *http.Reque
 	h.WriteXML(w, http.StatusOK, descResp{Result: descResult{ReplicationGroups: items}})
 }
 
+// hasResource reports whether arn names an existing cache cluster,
+// replication group, or snapshot. The caller must hold s.mu.
+func (s *Service) hasResource(arn string) bool {
+	for _, snap := range s.snapshots {
+		if snap.arn == arn {
+			return true
+		}
+	}
+	for _, cc := range s.clusters {
+		if cc.arn == arn {
+			return true
+		}
+	}
+	for _, rg := range s.replicationGroups {
+		if rg.arn == arn {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Service) addTagsToResource(w http.ResponseWriter, r *http.Request) {
+	s.updateTags(w, r, "AddTagsToResource", func(arn string) {
+		s.tags.Tag(arn, tags.FromForm(r.Form, "Tags.Tag."))
+	})
+}
+
+func (s *Service) removeTagsFromResource(w http.ResponseWriter, r *http.Request) {
+	s.updateTags(w, r, "RemoveTagsFromResource", func(arn string) {
+		s.tags.Untag(arn, tags.KeysFromForm(r.Form, "TagKeys.member."))
+	})
+}
+
+func (s *Service) listTagsForResource(w http.ResponseWriter, r *http.Request) {
+	s.updateTags(w, r, "ListTagsForResource", func(string) {})
+}
+
+// updateTags applies update to the resource named by ResourceName and
+// writes its resulting tags in the response of the given action.
+func (s *Service) updateTags(w http.ResponseWriter, r *http.Request, action string, update func(arn string)) {
+	arn := getFormVal(r, "ResourceName")
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if !s.hasResource(arn) {
+		h.WriteXMLError(w, "Sender", "InvalidARN", "Resource "+arn+" not found", http.StatusBadRequest)
+		return
+	}
+	update(arn)
+
+	type tag struct {
+		Key   string `xml:"Key"`
+		Value string `xml:"Value"`
+	}
+	type tagListResult struct {
+		XMLName xml.Name
+		TagList []tag `xml:"TagList>Tag"`
+	}
+	type tagListResp struct {
+		XMLName xml.Name
+		Result  tagListResult
+	}
+	var list []tag
+	for _, t := range tags.ToList(s.tags.Get(arn), "Key", "Value") {
+		list = append(list, tag{Key: t["Key"], Value: t["Value"]})
+	}
+	resp := tagListResp{XMLName: xml.Name{Local: action + "Response"}, Result: tagListResult{XMLName: xml.Name{Local: action + "Result"}, TagList: list}}
+	h.WriteXML(w, http.StatusOK, resp)
+}
+
 type ccXML struct {
-	CacheClusterId     string `xml:"CacheClusterId"`
-	ARN                string `xml:"ARN"`
-	CacheClusterStatus string `xml:"CacheClusterStatus"`
-	Engine             string `xml:"Engine"`
-	EngineVersion      string `xml:"EngineVersion"`
-	CacheNodeType      string `xml:"CacheNodeType"`
-	NumCacheNodes      int    `xml:"NumCacheNodes"`
+	CacheClusterId             string     `xml:"CacheClusterId"`
+	ARN                        string     `xml:"ARN"`
+	CacheClusterStatus         string     `xml:"CacheClusterStatus"`
+	CacheClusterCreateTime     string     `xml:"CacheClusterCreateTime"`
+	Engine                     string     `xml:"Engine"`
+	EngineVersion              string     `xml:"EngineVersion"`
+	CacheNodeType              string     `xml:"CacheNodeType"`
+	NumCacheNodes              int        `xml:"NumCacheNodes"`
+	PreferredMaintenanceWindow string     `xml:"PreferredMaintenanceWindow"`
+	PendingModifiedValues      pendingXML `xml:"PendingModifiedValues"`
 }
 
-func clusterToXML(cc *cacheCluster) ccXML {
+type pendingXML struct {
+	NumCacheNodes int    `xml:"NumCacheNodes,omitempty"`
+	EngineVersion string `xml:"EngineVersion,omitempty"`
+	CacheNodeType string `xml:"CacheNodeType,omitempty"`
+}
+
+func (s *Service) clusterToXML(cc *cacheCluster) ccXML {
+	status := cc.status
+	if status == "available" {
+		status = s.transitions.Status(cc.ready, "creating", status)
+	}
+	if status == "available" {
+		status = s.transitions.Status(cc.modified, "modifying", status)
+	}
 	return ccXML{
-		CacheClusterId:     cc.id,
-		ARN:                cc.arn,
-		CacheClusterStatus: cc.status,
-		Engine:             cc.engine,
-		EngineVersion:      cc.engineVer,
-		CacheNodeType:      cc.nodeType,
-		NumCacheNodes:      cc.numNodes,
+		CacheClusterId:             cc.id,
+		ARN:                        cc.arn,
+		CacheClusterStatus:         status,
+		CacheClusterCreateTime:     cc.created.Format(time.RFC3339),
+		Engine:                     cc.engine,
+		EngineVersion:              cc.engineVer,
+		CacheNodeType:              cc.nodeType,
+		NumCacheNodes:              cc.numNodes,
+		PreferredMaintenanceWindow: cc.maintenanceWindow,
+		PendingModifiedValues: pendingXML{
+			NumCacheNodes: cc.pending.numNodes,
+			EngineVersion: cc.pending.engineVer,
+			CacheNodeType: cc.pending.nodeType,
+		},
 	}
 }
 
@@ -389,12 +625,16 @@ type rgXML struct {
 	MemberClusters     int    `xml:"MemberClusters"`
 }
 
-func rgToXML(rg *replicationGroup) rgXML {
+func (s *Service) rgToXML(rg *replicationGroup) rgXML {
+	status := rg.status
+	if status == "available" {
+		status = s.transitions.Status(rg.ready, "creating", status)
+	}
 	return rgXML{
 		ReplicationGroupId: rg.id,
 		ARN:                rg.arn,
 		Description:        rg.description,
-		Status:             rg.status,
+		Status:             status,
 		CacheNodeType:      rg.nodeType,
 		MemberClusters:     rg.numClusters,
 	}
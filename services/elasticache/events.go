@@ -0,0 +1,248 @@
+package elasticache
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	h "github.com/riyanimam/goto/internal/mockhelpers"
+	"github.com/riyanimam/goto/internal/paginate"
+)
+
+// Event source types.
+const (
+	sourceCacheCluster     = "cache-cluster"
+	sourceReplicationGroup = "replication-group"
+)
+
+// defaultMaintenanceWindow is the weekly window clusters created without
+// a preferred one are given.
+const defaultMaintenanceWindow = "sun:05:00-sun:06:00"
+
+// event is an entry in the service's event log.
+type event struct {
+	sourceID   string
+	sourceType string
+	message    string
+	date       time.Time
+}
+
+// record adds an event for the source. The caller must hold s.mu for
+// writing.
+func (s *Service) record(sourceID, sourceType, message string) {
+	s.events = append(s.events, event{sourceID: sourceID, sourceType: sourceType, message: message, date: s.now()})
+}
+
+// merge returns p with the non-zero fields of q applied over it.
+func (p pendingValues) merge(q pendingValues) pendingValues {
+	if q.nodeType != "" {
+		p.nodeType = q.nodeType
+	}
+	if q.engineVer != "" {
+		p.engineVer = q.engineVer
+	}
+	if q.numNodes != 0 {
+		p.numNodes = q.numNodes
+	}
+	return p
+}
+
+// applyPending applies the cluster's pending modifications, starting its
+// "modifying" transition. The caller must hold s.mu for writing.
+func (s *Service) applyPending(cc *cacheCluster) {
+	p := cc.pending
+	if p.nodeType != "" {
+		s.record(cc.id, sourceCacheCluster, fmt.Sprintf("Cache cluster node type modified from %s to %s", cc.nodeType, p.nodeType))
+		cc.nodeType = p.nodeType
+	}
+	if p.engineVer != "" {
+		s.record(cc.id, sourceCacheCluster, fmt.Sprintf("Cache cluster engine version upgraded from %s to %s", cc.engineVer, p.engineVer))
+		cc.engineVer = p.engineVer
+	}
+	if p.numNodes != 0 {
+		if p.numNodes > cc.numNodes {
+			s.record(cc.id, sourceCacheCluster, fmt.Sprintf("Added cache nodes %s", nodeRange(cc.numNodes+1, p.numNodes)))
+		} else {
+			s.record(cc.id, sourceCacheCluster, fmt.Sprintf("Removed cache nodes %s", nodeRange(p.numNodes+1, cc.numNodes)))
+		}
+		cc.numNodes = p.numNodes
+	}
+	cc.pending = pendingValues{}
+	cc.modified = s.transitions.Begin()
+}
+
+// nodeRange lists cache node IDs from first to last.
+func nodeRange(first, last int) string {
+	ids := make([]string, 0, last-first+1)
+	for i := first; i <= last; i++ {
+		ids = append(ids, fmt.Sprintf("%04d", i))
+	}
+	return strings.Join(ids, ",")
+}
+
+// runMaintenance applies the pending modifications of clusters whose
+// maintenance window opened as the mock clock advanced from from to to.
+func (s *Service) runMaintenance(from, to time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ids := make([]string, 0, len(s.clusters))
+	for id := range s.clusters {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		cc := s.clusters[id]
+		if cc.pending.empty() {
+			continue
+		}
+		start, err := parseWindow(cc.maintenanceWindow)
+		if err != nil {
+			continue
+		}
+		if next := start.next(from); !next.After(to) {
+			s.applyPending(cc)
+		}
+	}
+}
+
+// windowStart is the weekly opening of a maintenance window.
+type windowStart struct {
+	day          time.Weekday
+	hour, minute int
+}
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+// parseWindow parses a maintenance window in the form
+// ddd:hh24:mi-ddd:hh24:mi, returning when it opens.
+func parseWindow(window string) (windowStart, error) {
+	invalid := fmt.Errorf("invalid maintenance window %q: must be in the format ddd:hh24:mi-ddd:hh24:mi", window)
+	from, to, ok := strings.Cut(strings.ToLower(window), "-")
+	if !ok {
+		return windowStart{}, invalid
+	}
+	var start windowStart
+	for i, bound := range []string{from, to} {
+		parts := strings.Split(bound, ":")
+		if len(parts) != 3 {
+			return windowStart{}, invalid
+		}
+		day, ok := weekdays[parts[0]]
+		hour, herr := strconv.Atoi(parts[1])
+		minute, merr := strconv.Atoi(parts[2])
+		if !ok || herr != nil || merr != nil || hour < 0 || hour > 23 || minute < 0 || minute > 59 {
+			return windowStart{}, invalid
+		}
+		if i == 0 {
+			start = windowStart{day: day, hour: hour, minute: minute}
+		}
+	}
+	return start, nil
+}
+
+// next returns the first opening of the window after t.
+func (w windowStart) next(t time.Time) time.Time {
+	t = t.UTC()
+	days := (int(w.day) - int(t.Weekday()) + 7) % 7
+	open := time.Date(t.Year(), t.Month(), t.Day()+days, w.hour, w.minute, 0, 0, time.UTC)
+	if !open.After(t) {
+		open = open.AddDate(0, 0, 7)
+	}
+	return open
+}
+
+func (s *Service) describeEvents(w http.ResponseWriter, r *http.Request) {
+	r.ParseForm()
+	sourceID := getFormVal(r, "SourceIdentifier")
+	sourceType := getFormVal(r, "SourceType")
+	if sourceID != "" && sourceType == "" {
+		h.WriteXMLError(w, "Sender", "InvalidParameterCombination", "SourceType is required when SourceIdentifier is given", http.StatusBadRequest)
+		return
+	}
+
+	s.mu.RLock()
+	now := s.now()
+	end := now
+	if v := getFormVal(r, "EndTime"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			s.mu.RUnlock()
+			h.WriteXMLError(w, "Sender", "InvalidParameterValue", "EndTime must be an ISO 8601 timestamp", http.StatusBadRequest)
+			return
+		}
+		end = t
+	}
+	// Events from the last hour are returned unless a start time or
+	// duration in minutes is given.
+	start := end.Add(-time.Hour)
+	if v := getFormVal(r, "Duration"); v != "" {
+		minutes, err := strconv.Atoi(v)
+		if err != nil || minutes < 0 {
+			s.mu.RUnlock()
+			h.WriteXMLError(w, "Sender", "InvalidParameterValue", "Duration must be a number of minutes", http.StatusBadRequest)
+			return
+		}
+		start = end.Add(-time.Duration(minutes) * time.Minute)
+	}
+	if v := getFormVal(r, "StartTime"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			s.mu.RUnlock()
+			h.WriteXMLError(w, "Sender", "InvalidParameterValue", "StartTime must be an ISO 8601 timestamp", http.StatusBadRequest)
+			return
+		}
+		start = t
+	}
+	// Most recent first, as the service lists them. Events are only ever
+	// added, so each is keyed by its position in the log.
+	var matched []event
+	var positions []int
+	for i := len(s.events) - 1; i >= 0; i-- {
+		e := s.events[i]
+		if (sourceType != "" && e.sourceType != sourceType) || (sourceID != "" && e.sourceID != sourceID) {
+			continue
+		}
+		if e.date.Before(start) || e.date.After(end) {
+			continue
+		}
+		matched = append(matched, e)
+		positions = append(positions, i)
+	}
+	s.mu.RUnlock()
+
+	limit, _ := strconv.Atoi(getFormVal(r, "MaxRecords"))
+	key := func(i int) string { return paginate.Desc(paginate.Seq(positions[i])) }
+	page, next, err := paginate.Page(matched, key, getFormVal(r, "Marker"), limit, 100)
+	if err != nil {
+		h.WriteXMLError(w, "Sender", "InvalidParameterValue", "Invalid marker", http.StatusBadRequest)
+		return
+	}
+
+	type eventXML struct {
+		SourceIdentifier string `xml:"SourceIdentifier"`
+		SourceType       string `xml:"SourceType"`
+		Message          string `xml:"Message"`
+		Date             string `xml:"Date"`
+	}
+	type descResult struct {
+		XMLName xml.Name   `xml:"DescribeEventsResult"`
+		Marker  string     `xml:"Marker,omitempty"`
+		Events  []eventXML `xml:"Events>Event"`
+	}
+	type descResp struct {
+		XMLName xml.Name   `xml:"DescribeEventsResponse"`
+		Result  descResult `xml:"DescribeEventsResult"`
+	}
+	items := make([]eventXML, 0, len(page))
+	for _, e := range page {
+		items = append(items, eventXML{SourceIdentifier: e.sourceID, SourceType: e.sourceType, Message: e.message, Date: e.date.Format(time.RFC3339)})
+	}
+	h.WriteXML(w, http.StatusOK, descResp{Result: descResult{Marker: next, Events: items}})
+}
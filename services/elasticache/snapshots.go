@@ -0,0 +1,314 @@
+package elasticache
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/riyanimam/goto/internal/lifecycle"
+	h "github.com/riyanimam/goto/internal/mockhelpers"
+	"github.com/riyanimam/goto/internal/paginate"
+	"github.com/riyanimam/goto/internal/tags"
+)
+
+// snapshot is a backup of a Redis cache cluster or replication group.
+type snapshot struct {
+	name               string
+	arn                string
+	source             string // "manual" or "system"
+	clusterID          string
+	replicationGroupID string
+	rgDescription      string
+	engine             string
+	engineVer          string
+	nodeType           string
+	numNodes           int
+	maintenanceWindow  string
+	clusterCreated     time.Time
+	created            time.Time
+	ready              lifecycle.Transition
+}
+
+func snapshotARN(name string) string {
+	return fmt.Sprintf("arn:aws:elasticache:us-east-1:%s:snapshot:%s", h.DefaultAccountID, name)
+}
+
+func (s *Service) createSnapshot(w http.ResponseWriter, r *http.Request) {
+	r.ParseForm()
+	name := getFormVal(r, "SnapshotName")
+	clusterID := getFormVal(r, "CacheClusterId")
+	rgID := getFormVal(r, "ReplicationGroupId")
+	if name == "" {
+		h.WriteXMLError(w, "Sender", "InvalidParameterValue", "SnapshotName is required", http.StatusBadRequest)
+		return
+	}
+	if (clusterID == "") == (rgID == "") {
+		h.WriteXMLError(w, "Sender", "InvalidParameterCombination", "Exactly one of CacheClusterId and ReplicationGroupId must be given", http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	if _, exists := s.snapshots[name]; exists {
+		s.mu.Unlock()
+		h.WriteXMLError(w, "Sender", "SnapshotAlreadyExistsFault", "Snapshot "+name+" already exists", http.StatusBadRequest)
+		return
+	}
+	snap := &snapshot{
+		name:    name,
+		arn:     snapshotARN(name),
+		source:  "manual",
+		created: s.now(),
+		ready:   s.transitions.Begin(),
+	}
+	if clusterID != "" {
+		cc, exists := s.clusters[clusterID]
+		if !exists {
+			s.mu.Unlock()
+			h.WriteXMLError(w, "Sender", "CacheClusterNotFound", "Cache cluster "+clusterID+" not found", http.StatusNotFound)
+			return
+		}
+		if cc.engine != "redis" {
+			s.mu.Unlock()
+			h.WriteXMLError(w, "Sender", "InvalidParameterCombination", "Snapshots are not supported for "+cc.engine+" cache clusters", http.StatusBadRequest)
+			return
+		}
+		if status := s.clusterToXML(cc).CacheClusterStatus; status != "available" {
+			s.mu.Unlock()
+			h.WriteXMLError(w, "Sender", "InvalidCacheClusterState", "Cache cluster "+clusterID+" is not in available state", http.StatusBadRequest)
+			return
+		}
+		snap.clusterID = cc.id
+		snap.engine, snap.engineVer, snap.nodeType = cc.engine, cc.engineVer, cc.nodeType
+		snap.numNodes, snap.maintenanceWindow, snap.clusterCreated = cc.numNodes, cc.maintenanceWindow, cc.created
+		s.record(cc.id, sourceCacheCluster, fmt.Sprintf("Snapshot succeeded for snapshot with ID '%s' of cache cluster with ID '%s'", name, cc.id))
+	} else {
+		rg, exists := s.replicationGroups[rgID]
+		if !exists {
+			s.mu.Unlock()
+			h.WriteXMLError(w, "Sender", "ReplicationGroupNotFoundFault", "Replication group "+rgID+" not found", http.StatusNotFound)
+			return
+		}
+		if status := s.rgToXML(rg).Status; status != "available" {
+			s.mu.Unlock()
+			h.WriteXMLError(w, "Sender", "InvalidReplicationGroupState", "Replication group "+rgID+" is not in available state", http.StatusBadRequest)
+			return
+		}
+		snap.replicationGroupID, snap.rgDescription = rg.id, rg.description
+		snap.engine, snap.engineVer, snap.nodeType = "redis", "7.0", rg.nodeType
+		snap.numNodes, snap.maintenanceWindow, snap.clusterCreated = rg.numClusters, defaultMaintenanceWindow, rg.created
+		s.record(rg.id, sourceReplicationGroup, fmt.Sprintf("Snapshot succeeded for snapshot with ID '%s' of replication group with ID '%s'", name, rg.id))
+	}
+	s.snapshots[name] = snap
+	s.tags.Tag(snap.arn, tags.FromForm(r.Form, "Tags.Tag."))
+	resp := s.snapshotToXML(snap)
+	s.mu.Unlock()
+
+	writeSnapshot(w, "CreateSnapshot", resp)
+}
+
+func (s *Service) describeSnapshots(w http.ResponseWriter, r *http.Request) {
+	r.ParseForm()
+	name := getFormVal(r, "SnapshotName")
+	clusterID := getFormVal(r, "CacheClusterId")
+	rgID := getFormVal(r, "ReplicationGroupId")
+	source := getFormVal(r, "SnapshotSource")
+
+	s.mu.RLock()
+	var items []snapshotXML
+	for _, snap := range s.snapshots {
+		if (name != "" && snap.name != name) || (clusterID != "" && snap.clusterID != clusterID) ||
+			(rgID != "" && snap.replicationGroupID != rgID) || (source != "" && snap.source != source) {
+			continue
+		}
+		items = append(items, s.snapshotToXML(snap))
+	}
+	s.mu.RUnlock()
+
+	if name != "" && len(items) == 0 {
+		h.WriteXMLError(w, "Sender", "SnapshotNotFoundFault", "Snapshot "+name+" not found", http.StatusNotFound)
+		return
+	}
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].SnapshotName < items[j].SnapshotName
+	})
+	limit, _ := strconv.Atoi(getFormVal(r, "MaxRecords"))
+	page, next, err := paginate.Page(items, func(i int) string { return items[i].SnapshotName }, getFormVal(r, "Marker"), limit, 50)
+	if err != nil {
+		h.WriteXMLError(w, "Sender", "InvalidParameterValue", "Invalid marker", http.StatusBadRequest)
+		return
+	}
+
+	type descResult struct {
+		XMLName   xml.Name      `xml:"DescribeSnapshotsResult"`
+		Marker    string        `xml:"Marker,omitempty"`
+		Snapshots []snapshotXML `xml:"Snapshots>Snapshot"`
+	}
+	type descResp struct {
+		XMLName xml.Name   `xml:"DescribeSnapshotsResponse"`
+		Result  descResult `xml:"DescribeSnapshotsResult"`
+	}
+	h.WriteXML(w, http.StatusOK, descResp{Result: descResult{Marker: next, Snapshots: page}})
+}
+
+// copySnapshot copies a snapshot under a new name or, with a TargetBucket,
+// exports it to S3 as one .rdb object per node.
+func (s *Service) copySnapshot(w http.ResponseWriter, r *http.Request) {
+	r.ParseForm()
+	sourceName := getFormVal(r, "SourceSnapshotName")
+	targetName := getFormVal(r, "TargetSnapshotName")
+	bucket := getFormVal(r, "TargetBucket")
+	if sourceName == "" || targetName == "" {
+		h.WriteXMLError(w, "Sender", "InvalidParameterValue", "SourceSnapshotName and TargetSnapshotName are required", http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	src, exists := s.snapshots[sourceName]
+	if !exists {
+		s.mu.Unlock()
+		h.WriteXMLError(w, "Sender", "SnapshotNotFoundFault", "Snapshot "+sourceName+" not found", http.StatusNotFound)
+		return
+	}
+	if status := s.snapshotToXML(src).SnapshotStatus; status != "available" {
+		s.mu.Unlock()
+		h.WriteXMLError(w, "Sender", "InvalidSnapshotState", "Snapshot "+sourceName+" is not in available state", http.StatusBadRequest)
+		return
+	}
+	cp := *src
+	cp.name, cp.arn, cp.source = targetName, snapshotARN(targetName), "manual"
+	cp.created, cp.ready = s.now(), s.transitions.Begin()
+
+	if bucket != "" {
+		objects := s.objects
+		resp := s.snapshotToXML(&cp)
+		s.mu.Unlock()
+		if objects == nil {
+			h.WriteXMLError(w, "Sender", "InvalidParameterValue", "Exporting snapshots to S3 is not available", http.StatusBadRequest)
+			return
+		}
+		for i := 1; i <= cp.numNodes; i++ {
+			key := fmt.Sprintf("%s-%04d.rdb", targetName, i)
+			if err := objects.PutObject(bucket, key, []byte("REDIS0009")); err != nil {
+				h.WriteXMLError(w, "Sender", "InvalidParameterValue", "Unable to export snapshot to bucket "+bucket+": "+err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+		writeSnapshot(w, "CopySnapshot", resp)
+		return
+	}
+
+	if _, exists := s.snapshots[targetName]; exists {
+		s.mu.Unlock()
+		h.WriteXMLError(w, "Sender", "SnapshotAlreadyExistsFault", "Snapshot "+targetName+" already exists", http.StatusBadRequest)
+		return
+	}
+	s.snapshots[targetName] = &cp
+	s.tags.Tag(cp.arn, tags.FromForm(r.Form, "Tags.Tag."))
+	resp := s.snapshotToXML(&cp)
+	s.mu.Unlock()
+
+	writeSnapshot(w, "CopySnapshot", resp)
+}
+
+func (s *Service) deleteSnapshot(w http.ResponseWriter, r *http.Request) {
+	r.ParseForm()
+	name := getFormVal(r, "SnapshotName")
+
+	s.mu.Lock()
+	snap, exists := s.snapshots[name]
+	if !exists {
+		s.mu.Unlock()
+		h.WriteXMLError(w, "Sender", "SnapshotNotFoundFault", "Snapshot "+name+" not found", http.StatusNotFound)
+		return
+	}
+	resp := s.snapshotToXML(snap)
+	if resp.SnapshotStatus != "available" {
+		s.mu.Unlock()
+		h.WriteXMLError(w, "Sender", "InvalidSnapshotState", "Snapshot "+name+" is not in available state", http.StatusBadRequest)
+		return
+	}
+	resp.SnapshotStatus = "deleting"
+	delete(s.snapshots, name)
+	s.tags.Delete(snap.arn)
+	s.mu.Unlock()
+
+	writeSnapshot(w, "DeleteSnapshot", resp)
+}
+
+// writeSnapshot writes the response of an action returning one snapshot.
+func writeSnapshot(w http.ResponseWriter, action string, snap snapshotXML) {
+	type snapResult struct {
+		XMLName  xml.Name
+		Snapshot snapshotXML `xml:"Snapshot"`
+	}
+	type snapResp struct {
+		XMLName xml.Name
+		Result  snapResult
+	}
+	h.WriteXML(w, http.StatusOK, snapResp{
+		XMLName: xml.Name{Local: action + "Response"},
+		Result:  snapResult{XMLName: xml.Name{Local: action + "Result"}, Snapshot: snap},
+	})
+}
+
+type nodeSnapshotXML struct {
+	CacheClusterId      string `xml:"CacheClusterId,omitempty"`
+	CacheNodeId         string `xml:"CacheNodeId"`
+	CacheSize           string `xml:"CacheSize"`
+	CacheNodeCreateTime string `xml:"CacheNodeCreateTime"`
+	SnapshotCreateTime  string `xml:"SnapshotCreateTime"`
+}
+
+type snapshotXML struct {
+	SnapshotName                string            `xml:"SnapshotName"`
+	ARN                         string            `xml:"ARN"`
+	SnapshotStatus              string            `xml:"SnapshotStatus"`
+	SnapshotSource              string            `xml:"SnapshotSource"`
+	CacheClusterId              string            `xml:"CacheClusterId,omitempty"`
+	ReplicationGroupId          string            `xml:"ReplicationGroupId,omitempty"`
+	ReplicationGroupDescription string            `xml:"ReplicationGroupDescription,omitempty"`
+	Engine                      string            `xml:"Engine"`
+	EngineVersion               string            `xml:"EngineVersion"`
+	CacheNodeType               string            `xml:"CacheNodeType"`
+	NumCacheNodes               int               `xml:"NumCacheNodes,omitempty"`
+	PreferredMaintenanceWindow  string            `xml:"PreferredMaintenanceWindow"`
+	CacheClusterCreateTime      string            `xml:"CacheClusterCreateTime"`
+	NodeSnapshots               []nodeSnapshotXML `xml:"NodeSnapshots>NodeSnapshot"`
+}
+
+func (s *Service) snapshotToXML(snap *snapshot) snapshotXML {
+	x := snapshotXML{
+		SnapshotName:                snap.name,
+		ARN:                         snap.arn,
+		SnapshotStatus:              s.transitions.Status(snap.ready, "creating", "available"),
+		SnapshotSource:              snap.source,
+		CacheClusterId:              snap.clusterID,
+		ReplicationGroupId:          snap.replicationGroupID,
+		ReplicationGroupDescription: snap.rgDescription,
+		Engine:                      snap.engine,
+		EngineVersion:               snap.engineVer,
+		CacheNodeType:               snap.nodeType,
+		PreferredMaintenanceWindow:  snap.maintenanceWindow,
+		CacheClusterCreateTime:      snap.clusterCreated.Format(time.RFC3339),
+	}
+	for i := 1; i <= snap.numNodes; i++ {
+		node := nodeSnapshotXML{
+			CacheNodeId:         fmt.Sprintf("%04d", i),
+			CacheSize:           "5 MB",
+			CacheNodeCreateTime: snap.clusterCreated.Format(time.RFC3339),
+			SnapshotCreateTime:  snap.created.Format(time.RFC3339),
+		}
+		if snap.replicationGroupID != "" {
+			node.CacheClusterId = fmt.Sprintf("%s-%03d", snap.replicationGroupID, i)
+			node.CacheNodeId = "0001"
+		}
+		x.NodeSnapshots = append(x.NodeSnapshots, node)
+	}
+	if snap.clusterID != "" {
+		x.NumCacheNodes = snap.numNodes
+	}
+	return x
+}
@@ -0,0 +1,87 @@
+package elbv2
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"sort"
+
+	h "github.com/riyanimam/goto/internal/mockhelpers"
+)
+
+// defaultTargetGroupAttributes are the attributes of a new target group.
+var defaultTargetGroupAttributes = map[string]string{
+	"deregistration_delay.timeout_seconds":  "300",
+	"stickiness.enabled":                    "false",
+	"stickiness.type":                       "lb_cookie",
+	"stickiness.lb_cookie.duration_seconds": "86400",
+	"slow_start.duration_seconds":           "0",
+	"load_balancing.algorithm.type":         "round_robin",
+}
+
+func (s *Service) describeTargetGroupAttributes(w http.ResponseWriter, r *http.Request) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	tg := s.targetGroups[r.FormValue("TargetGroupArn")]
+	if tg == nil {
+		writeELBError(w, "TargetGroupNotFound", "One or more target groups not found", http.StatusBadRequest)
+		return
+	}
+
+	h.WriteXML(w, http.StatusOK, describeTargetGroupAttributesResponse{
+		Result:    targetGroupAttributesResult{Attributes: attributesToXML(tg.attributes)},
+		RequestID: h.NewRequestID(),
+	})
+}
+
+func (s *Service) modifyTargetGroupAttributes(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	tg := s.targetGroups[r.FormValue("TargetGroupArn")]
+	if tg == nil {
+		writeELBError(w, "TargetGroupNotFound", "One or more target groups not found", http.StatusBadRequest)
+		return
+	}
+	for i := 1; ; i++ {
+		key := r.FormValue(fmt.Sprintf("Attributes.member.%d.Key", i))
+		if key == "" {
+			break
+		}
+		tg.attributes[key] = r.FormValue(fmt.Sprintf("Attributes.member.%d.Value", i))
+	}
+
+	h.WriteXML(w, http.StatusOK, modifyTargetGroupAttributesResponse{
+		Result:    targetGroupAttributesResult{Attributes: attributesToXML(tg.attributes)},
+		RequestID: h.NewRequestID(),
+	})
+}
+
+func attributesToXML(attrs map[string]string) []xmlAttribute {
+	list := make([]xmlAttribute, 0, len(attrs))
+	for k, v := range attrs {
+		list = append(list, xmlAttribute{Key: k, Value: v})
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Key < list[j].Key })
+	return list
+}
+
+type xmlAttribute struct {
+	Key   string `xml:"Key"`
+	Value string `xml:"Value"`
+}
+
+type targetGroupAttributesResult struct {
+	Attributes []xmlAttribute `xml:"Attributes>member"`
+}
+
+type describeTargetGroupAttributesResponse struct {
+	XMLName   xml.Name                    `xml:"DescribeTargetGroupAttributesResponse"`
+	Result    targetGroupAttributesResult `xml:"DescribeTargetGroupAttributesResult"`
+	RequestID string                      `xml:"ResponseMetadata>RequestId"`
+}
+
+type modifyTargetGroupAttributesResponse struct {
+	XMLName   xml.Name                    `xml:"ModifyTargetGroupAttributesResponse"`
+	Result    targetGroupAttributesResult `xml:"ModifyTargetGroupAttributesResult"`
+	RequestID string                      `xml:"ResponseMetadata>RequestId"`
+}
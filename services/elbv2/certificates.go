@@ -0,0 +1,163 @@
+package elbv2
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/http"
+
+	h "github.com/riyanimam/goto/internal/mockhelpers"
+)
+
+// SetResolver sets the function used to check that listener certificates
+// exist.
+func (s *Service) SetResolver(r h.Resolver) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.resolve = r
+}
+
+// formCertificates collects the Certificates.member.N.CertificateArn fields
+// of a request, writing an error and returning false if any of them is not
+// the ARN of an existing certificate.
+func (s *Service) formCertificates(w http.ResponseWriter, r *http.Request) ([]string, bool) {
+	s.mu.RLock()
+	resolve := s.resolve
+	s.mu.RUnlock()
+
+	var certs []string
+	for i := 1; ; i++ {
+		cert := r.FormValue(fmt.Sprintf("Certificates.member.%d.CertificateArn", i))
+		if cert == "" {
+			return certs, true
+		}
+		if resolve != nil && resolve(cert) != nil {
+			writeELBError(w, "CertificateNotFound", "Certificate '"+cert+"' not found", http.StatusBadRequest)
+			return nil, false
+		}
+		certs = append(certs, cert)
+	}
+}
+
+// certificatesToXML lists the default certificate of ln, if any, followed
+// by its additional certificates.
+func certificatesToXML(ln *listener, withDefault bool) []xmlCertificate {
+	var certs []xmlCertificate
+	if withDefault && ln.defaultCert != "" {
+		certs = append(certs, xmlCertificate{CertificateArn: ln.defaultCert, IsDefault: true})
+	}
+	for _, c := range ln.extraCerts {
+		certs = append(certs, xmlCertificate{CertificateArn: c})
+	}
+	return certs
+}
+
+func (s *Service) addListenerCertificates(w http.ResponseWriter, r *http.Request) {
+	certs, ok := s.formCertificates(w, r)
+	if !ok {
+		return
+	}
+	if len(certs) == 0 {
+		writeELBError(w, "ValidationError", "Certificates is required", http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ln := s.listeners[r.FormValue("ListenerArn")]
+	if ln == nil {
+		writeELBError(w, "ListenerNotFound", "One or more listeners not found", http.StatusBadRequest)
+		return
+	}
+	for _, c := range certs {
+		if c != ln.defaultCert && !contains(ln.extraCerts, c) {
+			ln.extraCerts = append(ln.extraCerts, c)
+		}
+	}
+
+	h.WriteXML(w, http.StatusOK, addListenerCertificatesResponse{
+		Result:    listenerCertificatesResult{Certificates: certificatesToXML(ln, false)},
+		RequestID: h.NewRequestID(),
+	})
+}
+
+func (s *Service) removeListenerCertificates(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ln := s.listeners[r.FormValue("ListenerArn")]
+	if ln == nil {
+		writeELBError(w, "ListenerNotFound", "One or more listeners not found", http.StatusBadRequest)
+		return
+	}
+	var remove []string
+	for i := 1; ; i++ {
+		cert := r.FormValue(fmt.Sprintf("Certificates.member.%d.CertificateArn", i))
+		if cert == "" {
+			break
+		}
+		if cert == ln.defaultCert {
+			writeELBError(w, "OperationNotPermitted", "The default certificate cannot be removed from a listener", http.StatusBadRequest)
+			return
+		}
+		remove = append(remove, cert)
+	}
+	kept := ln.extraCerts[:0]
+	for _, c := range ln.extraCerts {
+		if !contains(remove, c) {
+			kept = append(kept, c)
+		}
+	}
+	ln.extraCerts = kept
+
+	h.WriteXML(w, http.StatusOK, removeListenerCertificatesResponse{RequestID: h.NewRequestID()})
+}
+
+func (s *Service) describeListenerCertificates(w http.ResponseWriter, r *http.Request) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	ln := s.listeners[r.FormValue("ListenerArn")]
+	if ln == nil {
+		writeELBError(w, "ListenerNotFound", "One or more listeners not found", http.StatusBadRequest)
+		return
+	}
+
+	h.WriteXML(w, http.StatusOK, describeListenerCertificatesResponse{
+		Result:    listenerCertificatesResult{Certificates: certificatesToXML(ln, true)},
+		RequestID: h.NewRequestID(),
+	})
+}
+
+func contains(list []string, v string) bool {
+	for _, item := range list {
+		if item == v {
+			return true
+		}
+	}
+	return false
+}
+
+type xmlCertificate struct {
+	CertificateArn string `xml:"CertificateArn"`
+	IsDefault      bool   `xml:"IsDefault,omitempty"`
+}
+
+type listenerCertificatesResult struct {
+	Certificates []xmlCertificate `xml:"Certificates>member"`
+}
+
+type addListenerCertificatesResponse struct {
+	XMLName   xml.Name                   `xml:"AddListenerCertificatesResponse"`
+	Result    listenerCertificatesResult `xml:"AddListenerCertificatesResult"`
+	RequestID string                     `xml:"ResponseMetadata>RequestId"`
+}
+
+type removeListenerCertificatesResponse struct {
+	XMLName   xml.Name `xml:"RemoveListenerCertificatesResponse"`
+	Result    struct{} `xml:"RemoveListenerCertificatesResult"`
+	RequestID string   `xml:"ResponseMetadata>RequestId"`
+}
+
+type describeListenerCertificatesResponse struct {
+	XMLName   xml.Name                   `xml:"DescribeListenerCertificatesResponse"`
+	Result    listenerCertificatesResult `xml:"DescribeListenerCertificatesResult"`
+	RequestID string                     `xml:"ResponseMetadata>RequestId"`
+}
@@ -13,6 +13,14 @@
 //   - CreateListener
 //   - DeleteListener
 //   - DescribeListeners
+//   - AddListenerCertificates
+//   - RemoveListenerCertificates
+//   - DescribeListenerCertificates
+//   - DescribeTargetGroupAttributes
+//   - ModifyTargetGroupAttributes
+//   - AddTags
+//   - RemoveTags
+//   - DescribeTags
 package elbv2
 
 import (
@@ -24,6 +32,7 @@ import (
 	"time"
 
 	h "github.com/riyanimam/goto/internal/mockhelpers"
+	"github.com/riyanimam/goto/internal/tags"
 )
 
 // Service implements the ELBv2 mock.
@@ -35,6 +44,8 @@ type Service struct {
 	lbCounter    int
 	tgCounter    int
 	lnCounter    int
+	tags         *tags.Store
+	resolve      h.Resolver
 }
 
 type loadBalancer struct {
@@ -49,12 +60,13 @@ type loadBalancer struct {
 }
 
 type targetGroup struct {
-	name     string
-	arn      string
-	protocol string
-	port     int
-	vpcID    string
-	targets  map[string]*targetEntry
+	name       string
+	arn        string
+	protocol   string
+	port       int
+	vpcID      string
+	targets    map[string]*targetEntry
+	attributes map[string]string
 }
 
 type targetEntry struct {
@@ -64,10 +76,12 @@ type targetEntry struct {
 }
 
 type listener struct {
-	arn      string
-	lbArn    string
-	protocol string
-	port     int
+	arn         string
+	lbArn       string
+	protocol    string
+	port        int
+	defaultCert string
+	extraCerts  []string
 }
 
 // New creates a new ELBv2 mock service.
@@ -76,6 +90,7 @@ func New() *Service {
 		lbs:          make(map[string]*loadBalancer),
 		targetGroups: make(map[string]*targetGroup),
 		listeners:    make(map[string]*listener),
+		tags:         tags.New(),
 	}
 }
 
@@ -84,7 +99,31 @@ func (s *Service) Name() string { return "elasticloadbalancing" }
 
 // Handler returns the HTTP handler for ELBv2 requests.
 func (s *Service) Handler() http.Handler {
-	return http.HandlerFunc(s.handle)
+	return h.QueryRouter{
+		Actions: map[string]http.HandlerFunc{
+			"CreateLoadBalancer":            s.createLoadBalancer,
+			"DeleteLoadBalancer":            s.deleteLoadBalancer,
+			"DescribeLoadBalancers":         s.describeLoadBalancers,
+			"CreateTargetGroup":             s.createTargetGroup,
+			"DeleteTargetGroup":             s.deleteTargetGroup,
+			"DescribeTargetGroups":          s.describeTargetGroups,
+			"RegisterTargets":               s.registerTargets,
+			"DeregisterTargets":             s.deregisterTargets,
+			"DescribeTargetHealth":          s.describeTargetHealth,
+			"CreateListener":                s.createListener,
+			"DeleteListener":                s.deleteListener,
+			"DescribeListeners":             s.describeListeners,
+			"AddListenerCertificates":       s.addListenerCertificates,
+			"RemoveListenerCertificates":    s.removeListenerCertificates,
+			"DescribeListenerCertificates":  s.describeListenerCertificates,
+			"DescribeTargetGroupAttributes": s.describeTargetGroupAttributes,
+			"ModifyTargetGroupAttributes":   s.modifyTargetGroupAttributes,
+			"AddTags":                       s.addTags,
+			"RemoveTags":                    s.removeTags,
+			"DescribeTags":                  s.describeTags,
+		},
+		Error: writeELBError,
+	}
 }
 
 // Reset clears all state.
@@ -97,43 +136,7 @@ func (s *Service) Reset() {
 	s.lbCounter = 0
 	s.tgCounter = 0
 	s.lnCounter = 0
-}
-
-func (s *Service) handle(w http.ResponseWriter, r *http.Request) {
-	if err := r.ParseForm(); err != nil {
-		writeELBError(w, "InvalidInput", "could not parse request", http.StatusBadRequest)
-		return
-	}
-
-	action := r.FormValue("Action")
-	switch action {
-	case "CreateLoadBalancer":
-		s.createLoadBalancer(w, r)
-	case "DeleteLoadBalancer":
-		s.deleteLoadBalancer(w, r)
-	case "DescribeLoadBalancers":
-		s.describeLoadBalancers(w, r)
-	case "CreateTargetGroup":
-		s.createTargetGroup(w, r)
-	case "DeleteTargetGroup":
-		s.deleteTargetGroup(w, r)
-	case "DescribeTargetGroups":
-		s.describeTargetGroups(w, r)
-	case "RegisterTargets":
-		s.registerTargets(w, r)
-	case "DeregisterTargets":
-		s.deregisterTargets(w, r)
-	case "DescribeTargetHealth":
-		s.describeTargetHealth(w, r)
-	case "CreateListener":
-		s.createListener(w, r)
-	case "DeleteListener":
-		s.deleteListener(w, r)
-	case "DescribeListeners":
-		s.describeListeners(w, r)
-	default:
-		writeELBError(w, "UnsupportedOperation", fmt.Sprintf("action %q is not supported", action), http.StatusBadRequest)
-	}
+	s.tags.DeleteService("elasticloadbalancing")
 }
 
 func (s *Service) createLoadBalancer(w http.ResponseWriter, r *http.Request) {
@@ -147,10 +150,18 @@ func (s *Service) createLoadBalancer(w http.ResponseWriter, r *http.Request) {
 		lbType = "application"
 	}
 
+	kind := "app"
+	switch lbType {
+	case "network":
+		kind = "net"
+	case "gateway":
+		kind = "gwy"
+	}
+
 	s.mu.Lock()
 	s.lbCounter++
-	arn := fmt.Sprintf("arn:aws:elasticloadbalancing:us-east-1:%s:loadbalancer/app/%s/%s",
-		h.DefaultAccountID, name, h.RandomHex(16))
+	arn := fmt.Sprintf("arn:aws:elasticloadbalancing:us-east-1:%s:loadbalancer/%s/%s/%s",
+		h.DefaultAccountID, kind, name, h.RandomHex(16))
 	lb := &loadBalancer{
 		name:    name,
 		arn:     arn,
@@ -161,6 +172,7 @@ func (s *Service) createLoadBalancer(w http.ResponseWriter, r *http.Request) {
 		created: time.Now().UTC(),
 	}
 	s.lbs[arn] = lb
+	s.tags.Tag(arn, tags.FromForm(r.Form, "Tags.member."))
 	s.mu.Unlock()
 
 	resp := createLBResponse{
@@ -175,6 +187,7 @@ func (s *Service) deleteLoadBalancer(w http.ResponseWriter, r *http.Request) {
 
 	s.mu.Lock()
 	delete(s.lbs, arn)
+	s.tags.Delete(arn)
 	s.mu.Unlock()
 
 	resp := deleteLBResponse{RequestID: h.NewRequestID()}
@@ -213,14 +226,19 @@ func (s *Service) createTargetGroup(w http.ResponseWriter, r *http.Request) {
 	arn := fmt.Sprintf("arn:aws:elasticloadbalancing:us-east-1:%s:targetgroup/%s/%s",
 		h.DefaultAccountID, name, h.RandomHex(16))
 	tg := &targetGroup{
-		name:     name,
-		arn:      arn,
-		protocol: protocol,
-		port:     port,
-		vpcID:    vpcID,
-		targets:  make(map[string]*targetEntry),
+		name:       name,
+		arn:        arn,
+		protocol:   protocol,
+		port:       port,
+		vpcID:      vpcID,
+		targets:    make(map[string]*targetEntry),
+		attributes: make(map[string]string, len(defaultTargetGroupAttributes)),
+	}
+	for k, v := range defaultTargetGroupAttributes {
+		tg.attributes[k] = v
 	}
 	s.targetGroups[arn] = tg
+	s.tags.Tag(arn, tags.FromForm(r.Form, "Tags.member."))
 	s.mu.Unlock()
 
 	resp := createTGResponse{
@@ -235,6 +253,7 @@ func (s *Service) deleteTargetGroup(w http.ResponseWriter, r *http.Request) {
 
 	s.mu.Lock()
 	delete(s.targetGroups, arn)
+	s.tags.Delete(arn)
 	s.mu.Unlock()
 
 	resp := deleteTGResponse{RequestID: h.NewRequestID()}
@@ -343,6 +362,14 @@ func (s *Service) createListener(w http.ResponseWriter, r *http.Request) {
 	}
 	port := 80
 	fmt.Sscanf(r.FormValue("Port"), "%d", &port)
+	certs, ok := s.formCertificates(w, r)
+	if !ok {
+		return
+	}
+	if (protocol == "HTTPS" || protocol == "TLS") && len(certs) == 0 {
+		writeELBError(w, "ValidationError", "A certificate must be specified for "+protocol+" listeners", http.StatusBadRequest)
+		return
+	}
 
 	s.mu.Lock()
 	s.lnCounter++
@@ -354,7 +381,11 @@ func (s *Service) createListener(w http.ResponseWriter, r *http.Request) {
 		protocol: protocol,
 		port:     port,
 	}
+	if len(certs) > 0 {
+		ln.defaultCert = certs[0]
+	}
 	s.listeners[arn] = ln
+	s.tags.Tag(arn, tags.FromForm(r.Form, "Tags.member."))
 	s.mu.Unlock()
 
 	resp := createListenerResponse{
@@ -369,6 +400,7 @@ func (s *Service) deleteListener(w http.ResponseWriter, r *http.Request) {
 
 	s.mu.Lock()
 	delete(s.listeners, arn)
+	s.tags.Delete(arn)
 	s.mu.Unlock()
 
 	resp := deleteListenerResponse{RequestID: h.NewRequestID()}
@@ -418,12 +450,16 @@ func tgToXML(tg *targetGroup) xmlTargetGroup {
 }
 
 func listenerToXML(ln *listener) xmlListener {
-	return xmlListener{
+	x := xmlListener{
 		Arn:      ln.arn,
 		LBArn:    ln.lbArn,
 		Protocol: ln.protocol,
 		Port:     ln.port,
 	}
+	if ln.defaultCert != "" {
+		x.Certificates = []xmlCertificate{{CertificateArn: ln.defaultCert}}
+	}
+	return x
 }
 
 // XML types.
@@ -450,10 +486,11 @@ type xmlTargetGroup struct {
 }
 
 type xmlListener struct {
-	Arn      string `xml:"ListenerArn"`
-	LBArn    string `xml:"LoadBalancerArn"`
-	Protocol string `xml:"Protocol"`
-	Port     int    `xml:"Port"`
+	Arn          string           `xml:"ListenerArn"`
+	LBArn        string           `xml:"LoadBalancerArn"`
+	Protocol     string           `xml:"Protocol"`
+	Port         int              `xml:"Port"`
+	Certificates []xmlCertificate `xml:"Certificates>member,omitempty"`
 }
 
 type xmlTarget struct {
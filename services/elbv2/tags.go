@@ -0,0 +1,156 @@
+package elbv2
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	h "github.com/riyanimam/goto/internal/mockhelpers"
+	"github.com/riyanimam/goto/internal/tags"
+)
+
+// SetTagStore sets the registry resource tags are recorded in.
+func (s *Service) SetTagStore(store *tags.Store) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tags = store
+}
+
+// HasResource reports whether the load balancer, target group, or listener
+// identified by arn exists.
+func (s *Service) HasResource(arn string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.checkResource(arn) == ""
+}
+
+// checkResource returns the error code for a missing resource of the kind
+// named by arn, or "" if it exists. The caller must hold s.mu.
+func (s *Service) checkResource(arn string) string {
+	switch {
+	case strings.Contains(arn, ":loadbalancer/"):
+		if s.lbs[arn] == nil {
+			return "LoadBalancerNotFound"
+		}
+	case strings.Contains(arn, ":targetgroup/"):
+		if s.targetGroups[arn] == nil {
+			return "TargetGroupNotFound"
+		}
+	case strings.Contains(arn, ":listener/"):
+		if s.listeners[arn] == nil {
+			return "ListenerNotFound"
+		}
+	default:
+		return "ValidationError"
+	}
+	return ""
+}
+
+// resourceArns collects the ResourceArns.member.N fields of a request and
+// writes an error if any of them does not exist. The caller must hold s.mu.
+func (s *Service) resourceArns(w http.ResponseWriter, r *http.Request) ([]string, bool) {
+	var arns []string
+	for i := 1; ; i++ {
+		arn := r.FormValue(fmt.Sprintf("ResourceArns.member.%d", i))
+		if arn == "" {
+			break
+		}
+		if code := s.checkResource(arn); code != "" {
+			writeELBError(w, code, "Resource '"+arn+"' not found", http.StatusBadRequest)
+			return nil, false
+		}
+		arns = append(arns, arn)
+	}
+	if len(arns) == 0 {
+		writeELBError(w, "ValidationError", "ResourceArns is required", http.StatusBadRequest)
+		return nil, false
+	}
+	return arns, true
+}
+
+func (s *Service) addTags(w http.ResponseWriter, r *http.Request) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	arns, ok := s.resourceArns(w, r)
+	if !ok {
+		return
+	}
+	tagMap := tags.FromForm(r.Form, "Tags.member.")
+	for _, arn := range arns {
+		s.tags.Tag(arn, tagMap)
+	}
+	h.WriteXML(w, http.StatusOK, addTagsResponse{RequestID: h.NewRequestID()})
+}
+
+func (s *Service) removeTags(w http.ResponseWriter, r *http.Request) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	arns, ok := s.resourceArns(w, r)
+	if !ok {
+		return
+	}
+	keys := tags.KeysFromForm(r.Form, "TagKeys.member.")
+	for _, arn := range arns {
+		s.tags.Untag(arn, keys)
+	}
+	h.WriteXML(w, http.StatusOK, removeTagsResponse{RequestID: h.NewRequestID()})
+}
+
+func (s *Service) describeTags(w http.ResponseWriter, r *http.Request) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	arns, ok := s.resourceArns(w, r)
+	if !ok {
+		return
+	}
+	var descs []xmlTagDescription
+	for _, arn := range arns {
+		descs = append(descs, xmlTagDescription{ResourceArn: arn, Tags: xmlTags(s.tags.Get(arn))})
+	}
+	h.WriteXML(w, http.StatusOK, describeTagsResponse{
+		Result:    describeTagsResult{TagDescriptions: descs},
+		RequestID: h.NewRequestID(),
+	})
+}
+
+func xmlTags(m map[string]string) []xmlTag {
+	list := make([]xmlTag, 0, len(m))
+	for k, v := range m {
+		list = append(list, xmlTag{Key: k, Value: v})
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Key < list[j].Key })
+	return list
+}
+
+type xmlTag struct {
+	Key   string `xml:"Key"`
+	Value string `xml:"Value"`
+}
+
+type xmlTagDescription struct {
+	ResourceArn string   `xml:"ResourceArn"`
+	Tags        []xmlTag `xml:"Tags>member"`
+}
+
+type addTagsResponse struct {
+	XMLName   xml.Name `xml:"AddTagsResponse"`
+	Result    struct{} `xml:"AddTagsResult"`
+	RequestID string   `xml:"ResponseMetadata>RequestId"`
+}
+
+type removeTagsResponse struct {
+	XMLName   xml.Name `xml:"RemoveTagsResponse"`
+	Result    struct{} `xml:"RemoveTagsResult"`
+	RequestID string   `xml:"ResponseMetadata>RequestId"`
+}
+
+type describeTagsResponse struct {
+	XMLName   xml.Name           `xml:"DescribeTagsResponse"`
+	Result    describeTagsResult `xml:"DescribeTagsResult"`
+	RequestID string             `xml:"ResponseMetadata>RequestId"`
+}
+type describeTagsResult struct {
+	TagDescriptions []xmlTagDescription `xml:"TagDescriptions>member"`
+}
@@ -7,25 +7,26 @@
 //   - TerminateJobFlows
 //   - AddJobFlowSteps
 //   - ListSteps
+//   - AddTags
+//   - RemoveTags
 package emr
 
 import (
-	"encoding/json"
 	"fmt"
-	"io"
 	"net/http"
 	"sort"
-	"strings"
 	"sync"
 	"time"
 
 	h "github.com/riyanimam/goto/internal/mockhelpers"
+	"github.com/riyanimam/goto/internal/tags"
 )
 
 // Service implements the EMR mock.
 type Service struct {
 	mu       sync.RWMutex
 	clusters map[string]*cluster
+	tags     *tags.Store
 }
 
 type cluster struct {
@@ -52,6 +53,7 @@ type step struct {
 func New() *Service {
 	return &Service{
 		clusters: make(map[string]*cluster),
+		tags:     tags.New(),
 	}
 }
 
@@ -60,7 +62,16 @@ func (s *Service) Name() string { return "elasticmapreduce" }
 
 // Handler returns the HTTP handler for EMR requests.
 func (s *Service) Handler() http.Handler {
-	return http.HandlerFunc(s.handle)
+	return h.JSONRouter{
+		"RunJobFlow":        s.runJobFlow,
+		"DescribeCluster":   s.describeCluster,
+		"ListClusters":      s.listClusters,
+		"TerminateJobFlows": s.terminateJobFlows,
+		"AddJobFlowSteps":   s.addJobFlowSteps,
+		"ListSteps":         s.listSteps,
+		"AddTags":           s.addTags,
+		"RemoveTags":        s.removeTags,
+	}
 }
 
 // Reset clears all state.
@@ -68,49 +79,14 @@ func (s *Service) Reset() {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	s.clusters = make(map[string]*cluster)
+	s.tags.DeleteService("elasticmapreduce")
 }
 
-func (s *Service) handle(w http.ResponseWriter, r *http.Request) {
-	target := r.Header.Get("X-Amz-Target")
-
-	bodyBytes, err := io.ReadAll(r.Body)
-	if err != nil {
-		h.WriteJSONError(w, "InternalFailure", "could not read request body", http.StatusInternalServerError)
-		return
-	}
-
-	var params map[string]interface{}
-	if len(bodyBytes) > 0 {
-		json.Unmarshal(bodyBytes, &params)
-	}
-	if params == nil {
-		params = make(map[string]interface{})
-	}
-
-	action := ""
-	if target != "" {
-		parts := strings.SplitN(target, ".", 2)
-		if len(parts) == 2 {
-			action = parts[1]
-		}
-	}
-
-	switch action {
-	case "RunJobFlow":
-		s.runJobFlow(w, params)
-	case "DescribeCluster":
-		s.describeCluster(w, params)
-	case "ListClusters":
-		s.listClusters(w, params)
-	case "TerminateJobFlows":
-		s.terminateJobFlows(w, params)
-	case "AddJobFlowSteps":
-		s.addJobFlowSteps(w, params)
-	case "ListSteps":
-		s.listSteps(w, params)
-	default:
-		h.WriteJSONError(w, "UnknownOperationException", fmt.Sprintf("action %q is not supported", action), http.StatusBadRequest)
-	}
+// SetTagStore sets the registry resource tags are recorded in.
+func (s *Service) SetTagStore(store *tags.Store) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tags = store
 }
 
 func (s *Service) runJobFlow(w http.ResponseWriter, params map[string]interface{}) {
@@ -160,6 +136,7 @@ func (s *Service) runJobFlow(w http.ResponseWriter, params map[string]interface{
 		created:       time.Now().UTC(),
 	}
 	s.clusters[id] = c
+	s.tags.Tag(clusterARN(id), tags.FromList(params["Tags"], "Key", "Value"))
 	s.mu.Unlock()
 
 	h.WriteJSON(w, http.StatusOK, map[string]interface{}{
@@ -180,7 +157,7 @@ func (s *Service) describeCluster(w http.ResponseWriter, params map[string]inter
 	}
 
 	h.WriteJSON(w, http.StatusOK, map[string]interface{}{
-		"Cluster": clusterResp(c),
+		"Cluster": s.clusterResp(c),
 	})
 }
 
@@ -296,9 +273,45 @@ func (s *Service) listSteps(w http.ResponseWriter, params map[string]interface{}
 	})
 }
 
-func clusterResp(c *cluster) map[string]interface{} {
+// clusterARN returns the ARN of the cluster with the given ID.
+func clusterARN(id string) string {
+	return fmt.Sprintf("arn:aws:elasticmapreduce:us-east-1:%s:cluster/%s", h.DefaultAccountID, id)
+}
+
+func (s *Service) addTags(w http.ResponseWriter, params map[string]interface{}) {
+	id := h.GetString(params, "ResourceId")
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.clusters[id] == nil {
+		h.WriteJSONError(w, "InvalidRequestException", "Cluster not found: "+id, http.StatusBadRequest)
+		return
+	}
+	s.tags.Tag(clusterARN(id), tags.FromList(params["Tags"], "Key", "Value"))
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{})
+}
+
+func (s *Service) removeTags(w http.ResponseWriter, params map[string]interface{}) {
+	id := h.GetString(params, "ResourceId")
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.clusters[id] == nil {
+		h.WriteJSONError(w, "InvalidRequestException", "Cluster not found: "+id, http.StatusBadRequest)
+		return
+	}
+	s.tags.Untag(clusterARN(id), tags.Keys(params["TagKeys"]))
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{})
+}
+
+// clusterResp renders a cluster along with its tags.
+func (s *Service) clusterResp(c *cluster) map[string]interface{} {
 	resp := map[string]interface{}{
 		"Id":                    c.id,
+		"ClusterArn":            clusterARN(c.id),
+		"Tags":                  tags.ToList(s.tags.Get(clusterARN(c.id)), "Key", "Value"),
 		"Name":                  c.name,
 		"Status":                statusResp(c),
 		"Ec2InstanceAttributes": map[string]interface{}{},
@@ -3,40 +3,60 @@
 // Supported actions:
 //   - CreateEventBus
 //   - DeleteEventBus
+//   - DescribeEventBus
 //   - ListEventBuses
+//   - PutPermission
+//   - RemovePermission
 //   - PutRule
 //   - DeleteRule
+//   - DescribeRule
 //   - ListRules
 //   - PutTargets
 //   - RemoveTargets
 //   - ListTargetsByRule
 //   - PutEvents
+//   - TagResource
+//   - UntagResource
+//   - ListTagsForResource
+//
+// Rules belong to an event bus, the default bus unless EventBusName names
+// another. PutEvents delivers each event to the targets of the enabled rules
+// on its bus whose event pattern it matches. A target may be another event
+// bus, which routes the event through that bus's rules in turn.
 package eventbridge
 
 import (
 	"encoding/json"
 	"fmt"
-	"io"
 	"math/rand"
 	"net/http"
 	"sort"
 	"strings"
 	"sync"
+	"time"
+
+	"github.com/riyanimam/goto/internal/arn"
+	"github.com/riyanimam/goto/internal/awserr"
+	"github.com/riyanimam/goto/internal/mockhelpers"
+	"github.com/riyanimam/goto/internal/tags"
 )
 
 const defaultAccountID = "123456789012"
 
 // Service implements the EventBridge mock.
 type Service struct {
-	mu      sync.RWMutex
-	buses   map[string]*eventBus // keyed by name
-	rules   map[string]*rule     // keyed by name
-	targets map[string][]*target // keyed by rule name
+	mu       sync.RWMutex
+	buses    map[string]*eventBus // keyed by name
+	rules    map[string]*rule     // keyed by ruleKey
+	tags     *tags.Store
+	resolve  mockhelpers.Resolver
+	dispatch mockhelpers.Dispatcher
 }
 
 type eventBus struct {
-	name string
-	arn  string
+	name       string
+	arn        string
+	statements []map[string]interface{} // resource policy, in order
 }
 
 type rule struct {
@@ -47,26 +67,50 @@ type rule struct {
 	scheduleExpr string
 	state        string
 	description  string
+	targets      []*target
 }
 
 type target struct {
-	id       string
-	arn      string
-	ruleName string
+	id        string
+	arn       string
+	input     string
+	inputPath string
+}
+
+// ruleKey identifies a rule by its bus and name, since rule names are only
+// unique within a bus.
+func ruleKey(bus, name string) string {
+	return bus + "/" + name
+}
+
+// busName returns the name of the bus given by an EventBusName parameter,
+// which may be a name or an ARN, defaulting to "default".
+func busName(param string) string {
+	if _, name, ok := strings.Cut(param, ":event-bus/"); ok {
+		return name
+	}
+	if param == "" {
+		return "default"
+	}
+	return param
+}
+
+func newBus(name string) *eventBus {
+	return &eventBus{
+		name: name,
+		arn:  fmt.Sprintf("arn:aws:events:us-east-1:%s:event-bus/%s", defaultAccountID, name),
+	}
 }
 
 // New creates a new EventBridge mock service.
 func New() *Service {
 	s := &Service{
-		buses:   make(map[string]*eventBus),
-		rules:   make(map[string]*rule),
-		targets: make(map[string][]*target),
+		buses: make(map[string]*eventBus),
+		rules: make(map[string]*rule),
+		tags:  tags.New(),
 	}
 	// Create the default event bus.
-	s.buses["default"] = &eventBus{
-		name: "default",
-		arn:  fmt.Sprintf("arn:aws:events:us-east-1:%s:event-bus/default", defaultAccountID),
-	}
+	s.buses["default"] = newBus("default")
 	return s
 }
 
@@ -75,7 +119,25 @@ func (s *Service) Name() string { return "events" }
 
 // Handler returns the HTTP handler for EventBridge requests.
 func (s *Service) Handler() http.Handler {
-	return http.HandlerFunc(s.handle)
+	return mockhelpers.JSONRouter{
+		"CreateEventBus":      s.createEventBus,
+		"DeleteEventBus":      s.deleteEventBus,
+		"DescribeEventBus":    s.describeEventBus,
+		"ListEventBuses":      s.listEventBuses,
+		"PutPermission":       s.putPermission,
+		"RemovePermission":    s.removePermission,
+		"PutRule":             s.putRule,
+		"DeleteRule":          s.deleteRule,
+		"DescribeRule":        s.describeRule,
+		"ListRules":           s.listRules,
+		"PutTargets":          s.putTargets,
+		"RemoveTargets":       s.removeTargets,
+		"ListTargetsByRule":   s.listTargetsByRule,
+		"PutEvents":           s.putEvents,
+		"TagResource":         s.tagResource,
+		"UntagResource":       s.untagResource,
+		"ListTagsForResource": s.listTagsForResource,
+	}
 }
 
 // Reset clears all state.
@@ -84,65 +146,37 @@ func (s *Service) Reset() {
 	defer s.mu.Unlock()
 	s.buses = make(map[string]*eventBus)
 	s.rules = make(map[string]*rule)
-	s.targets = make(map[string][]*target)
-	s.buses["default"] = &eventBus{
-		name: "default",
-		arn:  fmt.Sprintf("arn:aws:events:us-east-1:%s:event-bus/default", defaultAccountID),
-	}
+	s.buses["default"] = newBus("default")
+	s.tags.DeleteService("events")
 }
 
-func (s *Service) handle(w http.ResponseWriter, r *http.Request) {
-	target := r.Header.Get("X-Amz-Target")
-
-	bodyBytes, err := io.ReadAll(r.Body)
-	if err != nil {
-		writeJSONError(w, "InternalException", "could not read request body", http.StatusInternalServerError)
-		return
-	}
+// SetTagStore sets the registry bus and rule tags are recorded in.
+func (s *Service) SetTagStore(store *tags.Store) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tags = store
+}
 
-	var params map[string]interface{}
-	if len(bodyBytes) > 0 {
-		if err := json.Unmarshal(bodyBytes, &params); err != nil {
-			writeJSONError(w, "InvalidRequestException", "could not parse request body", http.StatusBadRequest)
-			return
-		}
-	}
-	if params == nil {
-		params = make(map[string]interface{})
-	}
+// SetResolver sets the function used to check that rule targets exist.
+func (s *Service) SetResolver(r mockhelpers.Resolver) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.resolve = r
+}
 
-	action := ""
-	if target != "" {
-		parts := strings.SplitN(target, ".", 2)
-		if len(parts) == 2 {
-			action = parts[1]
-		}
-	}
+// SetDispatcher sets the function used to deliver matched events to rule
+// targets.
+func (s *Service) SetDispatcher(d mockhelpers.Dispatcher) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.dispatch = d
+}
 
-	switch action {
-	case "CreateEventBus":
-		s.createEventBus(w, params)
-	case "DeleteEventBus":
-		s.deleteEventBus(w, params)
-	case "ListEventBuses":
-		s.listEventBuses(w, params)
-	case "PutRule":
-		s.putRule(w, params)
-	case "DeleteRule":
-		s.deleteRule(w, params)
-	case "ListRules":
-		s.listRules(w, params)
-	case "PutTargets":
-		s.putTargets(w, params)
-	case "RemoveTargets":
-		s.removeTargets(w, params)
-	case "ListTargetsByRule":
-		s.listTargetsByRule(w, params)
-	case "PutEvents":
-		s.putEvents(w, params)
-	default:
-		writeJSONError(w, "UnknownOperationException", fmt.Sprintf("action %q is not supported", action), http.StatusBadRequest)
-	}
+// HasResource reports whether the event bus or rule identified by arn exists.
+func (s *Service) HasResource(arn string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.hasResource(arn)
 }
 
 func (s *Service) createEventBus(w http.ResponseWriter, params map[string]interface{}) {
@@ -152,40 +186,82 @@ func (s *Service) createEventBus(w http.ResponseWriter, params map[string]interf
 		return
 	}
 
-	arn := fmt.Sprintf("arn:aws:events:us-east-1:%s:event-bus/%s", defaultAccountID, name)
-
 	s.mu.Lock()
 	if _, exists := s.buses[name]; exists {
 		s.mu.Unlock()
 		writeJSONError(w, "ResourceAlreadyExistsException", "Event bus "+name+" already exists.", http.StatusBadRequest)
 		return
 	}
-	s.buses[name] = &eventBus{name: name, arn: arn}
+	b := newBus(name)
+	s.buses[name] = b
+	s.tags.Tag(b.arn, tags.FromList(params["Tags"], "Key", "Value"))
 	s.mu.Unlock()
 
 	writeJSON(w, http.StatusOK, map[string]interface{}{
-		"EventBusArn": arn,
+		"EventBusArn": b.arn,
 	})
 }
 
 func (s *Service) deleteEventBus(w http.ResponseWriter, params map[string]interface{}) {
 	name := getString(params, "Name")
+	if name == "default" {
+		writeJSONError(w, "ValidationException", "Cannot delete event bus default.", http.StatusBadRequest)
+		return
+	}
 
 	s.mu.Lock()
+	if b, exists := s.buses[name]; exists {
+		s.tags.Delete(b.arn)
+	}
 	delete(s.buses, name)
+	for key, rl := range s.rules {
+		if rl.eventBusName == name {
+			s.tags.Delete(rl.arn)
+			delete(s.rules, key)
+		}
+	}
 	s.mu.Unlock()
 
 	writeJSON(w, http.StatusOK, map[string]interface{}{})
 }
 
-func (s *Service) listEventBuses(w http.ResponseWriter, _ map[string]interface{}) {
+func (s *Service) describeEventBus(w http.ResponseWriter, params map[string]interface{}) {
+	name := busName(getString(params, "Name"))
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	b, exists := s.buses[name]
+	if !exists {
+		writeJSONError(w, "ResourceNotFoundException", "Event bus "+name+" does not exist.", http.StatusBadRequest)
+		return
+	}
+	resp := map[string]interface{}{
+		"Name": b.name,
+		"Arn":  b.arn,
+	}
+	if policy := b.policy(); policy != "" {
+		resp["Policy"] = policy
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (s *Service) listEventBuses(w http.ResponseWriter, params map[string]interface{}) {
+	prefix := getString(params, "NamePrefix")
+
 	s.mu.RLock()
 	var buses []map[string]interface{}
 	for _, b := range s.buses {
-		buses = append(buses, map[string]interface{}{
+		if !strings.HasPrefix(b.name, prefix) {
+			continue
+		}
+		entry := map[string]interface{}{
 			"Name": b.name,
 			"Arn":  b.arn,
-		})
+		}
+		if policy := b.policy(); policy != "" {
+			entry["Policy"] = policy
+		}
+		buses = append(buses, entry)
 	}
 	s.mu.RUnlock()
 
@@ -204,23 +280,42 @@ func (s *Service) putRule(w http.ResponseWriter, params map[string]interface{})
 		writeJSONError(w, "ValidationException", "Name is required", http.StatusBadRequest)
 		return
 	}
+	pattern := getString(params, "EventPattern")
+	if pattern != "" {
+		var parsed map[string]interface{}
+		if err := json.Unmarshal([]byte(pattern), &parsed); err != nil {
+			writeJSONError(w, "InvalidEventPatternException", "Event pattern is not valid. Reason: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	state := getString(params, "State")
+	if state == "" {
+		state = "ENABLED"
+	}
+	bus := busName(getString(params, "EventBusName"))
 
 	arn := fmt.Sprintf("arn:aws:events:us-east-1:%s:rule/%s", defaultAccountID, name)
-	busName := getString(params, "EventBusName")
-	if busName == "" {
-		busName = "default"
+	if bus != "default" {
+		arn = fmt.Sprintf("arn:aws:events:us-east-1:%s:rule/%s/%s", defaultAccountID, bus, name)
 	}
 
 	s.mu.Lock()
-	s.rules[name] = &rule{
-		name:         name,
-		arn:          arn,
-		eventBusName: busName,
-		eventPattern: getString(params, "EventPattern"),
-		scheduleExpr: getString(params, "ScheduleExpression"),
-		state:        "ENABLED",
-		description:  getString(params, "Description"),
+	if _, exists := s.buses[bus]; !exists {
+		s.mu.Unlock()
+		writeJSONError(w, "ResourceNotFoundException", "Event bus "+bus+" does not exist.", http.StatusBadRequest)
+		return
+	}
+	key := ruleKey(bus, name)
+	rl, exists := s.rules[key]
+	if !exists {
+		rl = &rule{name: name, arn: arn, eventBusName: bus}
+		s.rules[key] = rl
 	}
+	rl.eventPattern = pattern
+	rl.scheduleExpr = getString(params, "ScheduleExpression")
+	rl.state = state
+	rl.description = getString(params, "Description")
+	s.tags.Tag(arn, tags.FromList(params["Tags"], "Key", "Value"))
 	s.mu.Unlock()
 
 	writeJSON(w, http.StatusOK, map[string]interface{}{
@@ -229,27 +324,134 @@ func (s *Service) putRule(w http.ResponseWriter, params map[string]interface{})
 }
 
 func (s *Service) deleteRule(w http.ResponseWriter, params map[string]interface{}) {
-	name := getString(params, "Name")
+	key := ruleKey(busName(getString(params, "EventBusName")), getString(params, "Name"))
 
 	s.mu.Lock()
-	delete(s.rules, name)
-	delete(s.targets, name)
-	s.mu.Unlock()
+	defer s.mu.Unlock()
+	rl, exists := s.rules[key]
+	if !exists {
+		writeJSON(w, http.StatusOK, map[string]interface{}{})
+		return
+	}
+	if len(rl.targets) > 0 {
+		writeJSONError(w, "ValidationException", "Rule can't be deleted since it has targets.", http.StatusBadRequest)
+		return
+	}
+	s.tags.Delete(rl.arn)
+	delete(s.rules, key)
+	writeJSON(w, http.StatusOK, map[string]interface{}{})
+}
+
+// ruleParam returns the rule named by the Name or Rule parameter on the bus
+// given by EventBusName, writing an error and returning nil if it does not
+// exist. The caller must hold s.mu.
+func (s *Service) ruleParam(w http.ResponseWriter, params map[string]interface{}, nameKey string) *rule {
+	bus := busName(getString(params, "EventBusName"))
+	name := getString(params, nameKey)
+	rl, exists := s.rules[ruleKey(bus, name)]
+	if !exists {
+		writeJSONError(w, "ResourceNotFoundException", "Rule "+name+" does not exist on EventBus "+bus+".", http.StatusBadRequest)
+		return nil
+	}
+	return rl
+}
+
+func (s *Service) describeRule(w http.ResponseWriter, params map[string]interface{}) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	rl := s.ruleParam(w, params, "Name")
+	if rl == nil {
+		return
+	}
+	writeJSON(w, http.StatusOK, ruleResp(rl))
+}
+
+func ruleResp(rl *rule) map[string]interface{} {
+	resp := map[string]interface{}{
+		"Name":         rl.name,
+		"Arn":          rl.arn,
+		"State":        rl.state,
+		"Description":  rl.description,
+		"EventBusName": rl.eventBusName,
+	}
+	if rl.eventPattern != "" {
+		resp["EventPattern"] = rl.eventPattern
+	}
+	if rl.scheduleExpr != "" {
+		resp["ScheduleExpression"] = rl.scheduleExpr
+	}
+	return resp
+}
+
+func (s *Service) tagResource(w http.ResponseWriter, params map[string]interface{}) {
+	arn := getString(params, "ResourceARN")
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if !s.hasResource(arn) {
+		writeJSONError(w, "ResourceNotFoundException", "Resource "+arn+" does not exist.", http.StatusBadRequest)
+		return
+	}
+	s.tags.Tag(arn, tags.FromList(params["Tags"], "Key", "Value"))
+	writeJSON(w, http.StatusOK, map[string]interface{}{})
+}
 
+func (s *Service) untagResource(w http.ResponseWriter, params map[string]interface{}) {
+	arn := getString(params, "ResourceARN")
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if !s.hasResource(arn) {
+		writeJSONError(w, "ResourceNotFoundException", "Resource "+arn+" does not exist.", http.StatusBadRequest)
+		return
+	}
+	s.tags.Untag(arn, tags.Keys(params["TagKeys"]))
 	writeJSON(w, http.StatusOK, map[string]interface{}{})
 }
 
-func (s *Service) listRules(w http.ResponseWriter, _ map[string]interface{}) {
+func (s *Service) listTagsForResource(w http.ResponseWriter, params map[string]interface{}) {
+	arn := getString(params, "ResourceARN")
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if !s.hasResource(arn) {
+		writeJSONError(w, "ResourceNotFoundException", "Resource "+arn+" does not exist.", http.StatusBadRequest)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"Tags": tags.ToList(s.tags.Get(arn), "Key", "Value"),
+	})
+}
+
+// hasResource reports whether arn names an event bus or rule. Caller must
+// hold s.mu.
+func (s *Service) hasResource(arn string) bool {
+	for _, b := range s.buses {
+		if b.arn == arn {
+			return true
+		}
+	}
+	for _, rl := range s.rules {
+		if rl.arn == arn {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Service) listRules(w http.ResponseWriter, params map[string]interface{}) {
+	bus := busName(getString(params, "EventBusName"))
+	prefix := getString(params, "NamePrefix")
+
 	s.mu.RLock()
 	var rulesList []map[string]interface{}
 	for _, rl := range s.rules {
-		rulesList = append(rulesList, map[string]interface{}{
-			"Name":         rl.name,
-			"Arn":          rl.arn,
-			"State":        rl.state,
-			"Description":  rl.description,
-			"EventBusName": rl.eventBusName,
-		})
+		if rl.eventBusName == bus && strings.HasPrefix(rl.name, prefix) {
+			rulesList = append(rulesList, ruleResp(rl))
+		}
 	}
 	s.mu.RUnlock()
 
@@ -263,39 +465,81 @@ func (s *Service) listRules(w http.ResponseWriter, _ map[string]interface{}) {
 }
 
 func (s *Service) putTargets(w http.ResponseWriter, params map[string]interface{}) {
-	ruleName := getString(params, "Rule")
-
-	s.mu.Lock()
-	if _, exists := s.rules[ruleName]; !exists {
-		s.mu.Unlock()
-		writeJSONError(w, "ResourceNotFoundException", "Rule "+ruleName+" does not exist.", http.StatusBadRequest)
-		return
-	}
-
+	// Targets are checked before taking the lock, since a target may be a
+	// bus owned by this service.
+	var accepted []*target
+	failed := []map[string]interface{}{}
 	if targetsRaw, ok := params["Targets"].([]interface{}); ok {
 		for _, t := range targetsRaw {
 			if tm, ok := t.(map[string]interface{}); ok {
 				tgt := &target{
-					id:       getString(tm, "Id"),
-					arn:      getString(tm, "Arn"),
-					ruleName: ruleName,
+					id:        getString(tm, "Id"),
+					arn:       getString(tm, "Arn"),
+					input:     getString(tm, "Input"),
+					inputPath: getString(tm, "InputPath"),
 				}
-				s.targets[ruleName] = append(s.targets[ruleName], tgt)
+				if code, msg := s.checkTarget(tgt.arn); code != "" {
+					failed = append(failed, map[string]interface{}{
+						"TargetId":     tgt.id,
+						"ErrorCode":    code,
+						"ErrorMessage": msg,
+					})
+					continue
+				}
+				accepted = append(accepted, tgt)
+			}
+		}
+	}
+
+	s.mu.Lock()
+	rl := s.ruleParam(w, params, "Rule")
+	if rl == nil {
+		s.mu.Unlock()
+		return
+	}
+	for _, tgt := range accepted {
+		replaced := false
+		for i, existing := range rl.targets {
+			if existing.id == tgt.id {
+				rl.targets[i] = tgt
+				replaced = true
 			}
 		}
+		if !replaced {
+			rl.targets = append(rl.targets, tgt)
+		}
 	}
 	s.mu.Unlock()
 
 	writeJSON(w, http.StatusOK, map[string]interface{}{
-		"FailedEntryCount": 0,
-		"FailedEntries":    []interface{}{},
+		"FailedEntryCount": len(failed),
+		"FailedEntries":    failed,
 	})
 }
 
-func (s *Service) removeTargets(w http.ResponseWriter, params map[string]interface{}) {
-	ruleName := getString(params, "Rule")
+// checkTarget returns an error code and message if target is not an ARN or
+// names a resource that does not exist, and empty strings otherwise.
+func (s *Service) checkTarget(target string) (code, message string) {
+	if _, err := arn.Parse(target); err != nil {
+		return "ValidationException", "Parameter " + target + " is not valid. Reason: Provided Arn is not in correct format."
+	}
 
+	s.mu.RLock()
+	resolve := s.resolve
+	s.mu.RUnlock()
+	if resolve != nil && resolve(target) != nil {
+		return "ResourceNotFoundException", "Target " + target + " does not exist."
+	}
+	return "", ""
+}
+
+func (s *Service) removeTargets(w http.ResponseWriter, params map[string]interface{}) {
 	s.mu.Lock()
+	rl := s.ruleParam(w, params, "Rule")
+	if rl == nil {
+		s.mu.Unlock()
+		return
+	}
 	if ids, ok := params["Ids"].([]interface{}); ok {
 		idSet := make(map[string]bool)
 		for _, id := range ids {
@@ -304,12 +548,12 @@ func (s *Service) removeTargets(w http.ResponseWriter, params map[string]interfa
 			}
 		}
 		var remaining []*target
-		for _, t := range s.targets[ruleName] {
+		for _, t := range rl.targets {
 			if !idSet[t.id] {
 				remaining = append(remaining, t)
 			}
 		}
-		s.targets[ruleName] = remaining
+		rl.targets = remaining
 	}
 	s.mu.Unlock()
 
@@ -320,15 +564,25 @@ func (s *Service) removeTargets(w http.ResponseWriter, params map[string]interfa
 }
 
 func (s *Service) listTargetsByRule(w http.ResponseWriter, params map[string]interface{}) {
-	ruleName := getString(params, "Rule")
-
 	s.mu.RLock()
-	var targetsList []map[string]interface{}
-	for _, t := range s.targets[ruleName] {
-		targetsList = append(targetsList, map[string]interface{}{
+	rl := s.ruleParam(w, params, "Rule")
+	if rl == nil {
+		s.mu.RUnlock()
+		return
+	}
+	targetsList := []map[string]interface{}{}
+	for _, t := range rl.targets {
+		entry := map[string]interface{}{
 			"Id":  t.id,
 			"Arn": t.arn,
-		})
+		}
+		if t.input != "" {
+			entry["Input"] = t.input
+		}
+		if t.inputPath != "" {
+			entry["InputPath"] = t.inputPath
+		}
+		targetsList = append(targetsList, entry)
 	}
 	s.mu.RUnlock()
 
@@ -339,24 +593,70 @@ func (s *Service) listTargetsByRule(w http.ResponseWriter, params map[string]int
 
 func (s *Service) putEvents(w http.ResponseWriter, params map[string]interface{}) {
 	entries, _ := params["Entries"].([]interface{})
-	count := len(entries)
-	if count == 0 {
-		count = 1
-	}
 
-	var resultEntries []map[string]interface{}
-	for i := 0; i < count; i++ {
+	resultEntries := []map[string]interface{}{}
+	failedCount := 0
+	for _, e := range entries {
+		entry, _ := e.(map[string]interface{})
+		event, code, msg := s.newEvent(entry)
+		if code == "" {
+			bus := busName(getString(entry, "EventBusName"))
+			if !s.route(bus, event, map[string]bool{}) {
+				code, msg = "ResourceNotFoundException", "Event bus "+bus+" does not exist."
+			}
+		}
+		if code != "" {
+			failedCount++
+			resultEntries = append(resultEntries, map[string]interface{}{
+				"ErrorCode":    code,
+				"ErrorMessage": msg,
+			})
+			continue
+		}
 		resultEntries = append(resultEntries, map[string]interface{}{
-			"EventId": newRequestID(),
+			"EventId": event["id"],
 		})
 	}
 
 	writeJSON(w, http.StatusOK, map[string]interface{}{
 		"Entries":          resultEntries,
-		"FailedEntryCount": 0,
+		"FailedEntryCount": failedCount,
 	})
 }
 
+// newEvent builds the event delivered to targets from a PutEvents entry, or
+// returns an error code and message for an invalid entry.
+func (s *Service) newEvent(entry map[string]interface{}) (event map[string]interface{}, code, message string) {
+	for _, field := range []string{"Source", "DetailType", "Detail"} {
+		if getString(entry, field) == "" {
+			return nil, "InvalidArgument", "Parameter " + field + " is not valid. Reason: " + field + " is a required argument."
+		}
+	}
+	var detail map[string]interface{}
+	if err := json.Unmarshal([]byte(getString(entry, "Detail")), &detail); err != nil {
+		return nil, "MalformedDetail", "Detail is malformed."
+	}
+	at := time.Now().UTC()
+	if ts, ok := entry["Time"].(float64); ok {
+		at = time.Unix(0, int64(ts*float64(time.Second))).UTC()
+	}
+	resources, _ := entry["Resources"].([]interface{})
+	if resources == nil {
+		resources = []interface{}{}
+	}
+	return map[string]interface{}{
+		"version":     "0",
+		"id":          newRequestID(),
+		"detail-type": getString(entry, "DetailType"),
+		"source":      getString(entry, "Source"),
+		"account":     defaultAccountID,
+		"time":        at.Format(time.RFC3339),
+		"region":      "us-east-1",
+		"resources":   resources,
+		"detail":      detail,
+	}, "", ""
+}
+
 // Helper functions.
 
 func getString(params map[string]interface{}, key string) string {
@@ -368,6 +668,11 @@ func getString(params map[string]interface{}, key string) string {
 	return ""
 }
 
+func getBool(params map[string]interface{}, key string) bool {
+	b, _ := params[key].(bool)
+	return b
+}
+
 func writeJSON(w http.ResponseWriter, status int, v interface{}) {
 	w.Header().Set("Content-Type", "application/x-amz-json-1.1")
 	w.WriteHeader(status)
@@ -375,12 +680,7 @@ func writeJSON(w http.ResponseWriter, status int, v interface{}) {
 }
 
 func writeJSONError(w http.ResponseWriter, code, message string, status int) {
-	w.Header().Set("Content-Type", "application/x-amz-json-1.1")
-	w.WriteHeader(status)
-	json.NewEncoder(w).Encode(map[string]interface{}{
-		"__type":  code,
-		"message": message,
-	})
+	awserr.WriteJSON(w, awserr.JSON11, code, message, status)
 }
 
 func newRequestID() string {
@@ -0,0 +1,116 @@
+package eventbridge
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// policy returns the bus's resource policy document, or "" if it has none.
+func (b *eventBus) policy() string {
+	if len(b.statements) == 0 {
+		return ""
+	}
+	doc, _ := json.Marshal(map[string]interface{}{
+		"Version":   "2012-10-17",
+		"Statement": b.statements,
+	})
+	return string(doc)
+}
+
+func (s *Service) putPermission(w http.ResponseWriter, params map[string]interface{}) {
+	name := busName(getString(params, "EventBusName"))
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	b, exists := s.buses[name]
+	if !exists {
+		writeJSONError(w, "ResourceNotFoundException", "Event bus "+name+" does not exist.", http.StatusBadRequest)
+		return
+	}
+
+	// A whole policy document replaces the bus's policy.
+	if doc := getString(params, "Policy"); doc != "" {
+		var policy struct {
+			Statement []map[string]interface{}
+		}
+		if err := json.Unmarshal([]byte(doc), &policy); err != nil || len(policy.Statement) == 0 {
+			writeJSONError(w, "ValidationException", "Policy is not a valid policy document.", http.StatusBadRequest)
+			return
+		}
+		b.statements = policy.Statement
+		writeJSON(w, http.StatusOK, map[string]interface{}{})
+		return
+	}
+
+	sid := getString(params, "StatementId")
+	principal := getString(params, "Principal")
+	if sid == "" || principal == "" {
+		writeJSONError(w, "ValidationException", "StatementId and Principal are required unless Policy is given.", http.StatusBadRequest)
+		return
+	}
+	action := getString(params, "Action")
+	if action != "events:PutEvents" {
+		writeJSONError(w, "ValidationException", "Provided value in parameter 'action' is not supported.", http.StatusBadRequest)
+		return
+	}
+
+	statement := map[string]interface{}{
+		"Sid":      sid,
+		"Effect":   "Allow",
+		"Action":   action,
+		"Resource": b.arn,
+	}
+	if principal == "*" {
+		statement["Principal"] = "*"
+	} else {
+		statement["Principal"] = map[string]interface{}{"AWS": "arn:aws:iam::" + principal + ":root"}
+	}
+	if cond, ok := params["Condition"].(map[string]interface{}); ok {
+		statement["Condition"] = map[string]interface{}{
+			getString(cond, "Type"): map[string]interface{}{
+				getString(cond, "Key"): getString(cond, "Value"),
+			},
+		}
+	}
+
+	for i, existing := range b.statements {
+		if existing["Sid"] == sid {
+			b.statements[i] = statement
+			writeJSON(w, http.StatusOK, map[string]interface{}{})
+			return
+		}
+	}
+	b.statements = append(b.statements, statement)
+	writeJSON(w, http.StatusOK, map[string]interface{}{})
+}
+
+func (s *Service) removePermission(w http.ResponseWriter, params map[string]interface{}) {
+	name := busName(getString(params, "EventBusName"))
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	b, exists := s.buses[name]
+	if !exists {
+		writeJSONError(w, "ResourceNotFoundException", "Event bus "+name+" does not exist.", http.StatusBadRequest)
+		return
+	}
+	if len(b.statements) == 0 {
+		writeJSONError(w, "ResourceNotFoundException", "EventBus does not have a policy.", http.StatusBadRequest)
+		return
+	}
+	if getBool(params, "RemoveAllPermissions") {
+		b.statements = nil
+		writeJSON(w, http.StatusOK, map[string]interface{}{})
+		return
+	}
+
+	sid := getString(params, "StatementId")
+	for i, existing := range b.statements {
+		if existing["Sid"] == sid {
+			b.statements = append(b.statements[:i], b.statements[i+1:]...)
+			writeJSON(w, http.StatusOK, map[string]interface{}{})
+			return
+		}
+	}
+	writeJSONError(w, "ResourceNotFoundException", "Statement with the provided id does not exist.", http.StatusBadRequest)
+}
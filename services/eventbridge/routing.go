@@ -0,0 +1,119 @@
+package eventbridge
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/riyanimam/goto/internal/eventpattern"
+)
+
+// Deliver puts the event in payload, an EventBridge event in the JSON form
+// targets receive, on the event bus identified by arn.
+func (s *Service) Deliver(arn string, payload []byte) ([]byte, error) {
+	var event map[string]interface{}
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return nil, fmt.Errorf("event is not a JSON object: %w", err)
+	}
+
+	s.mu.RLock()
+	bus := s.ownBus(arn)
+	s.mu.RUnlock()
+
+	if bus == "" || !s.route(bus, event, map[string]bool{}) {
+		return nil, fmt.Errorf("event bus %s does not exist", arn)
+	}
+	return nil, nil
+}
+
+// route delivers event to the targets of the enabled rules on bus whose
+// pattern it matches, and reports whether the bus exists. Targets that are
+// buses of this service are routed in turn, skipping buses in visited so that
+// rules forwarding between buses cannot loop. Delivery failures are dropped,
+// as EventBridge does once it has exhausted its retries.
+func (s *Service) route(bus string, event map[string]interface{}, visited map[string]bool) bool {
+	raw, _ := json.Marshal(event)
+
+	type delivery struct {
+		arn     string
+		payload []byte
+	}
+	var deliveries []delivery
+	var forwards []string
+
+	s.mu.RLock()
+	if _, exists := s.buses[bus]; !exists {
+		s.mu.RUnlock()
+		return false
+	}
+	visited[bus] = true
+	dispatch := s.dispatch
+	for _, rl := range s.rules {
+		if rl.eventBusName != bus || rl.state != "ENABLED" || rl.eventPattern == "" {
+			continue
+		}
+		var pattern map[string]interface{}
+		if json.Unmarshal([]byte(rl.eventPattern), &pattern) != nil || !eventpattern.Match(pattern, event) {
+			continue
+		}
+		for _, t := range rl.targets {
+			if name := s.ownBus(t.arn); name != "" {
+				if !visited[name] {
+					forwards = append(forwards, name)
+				}
+				continue
+			}
+			if payload, ok := t.payload(event, raw); ok {
+				deliveries = append(deliveries, delivery{arn: t.arn, payload: payload})
+			}
+		}
+	}
+	s.mu.RUnlock()
+
+	for _, name := range forwards {
+		if !visited[name] {
+			s.route(name, event, visited)
+		}
+	}
+	if dispatch != nil {
+		for _, d := range deliveries {
+			dispatch(d.arn, d.payload)
+		}
+	}
+	return true
+}
+
+// ownBus returns the name of the bus of this service identified by arn, or
+// "" if arn names anything else. The caller must hold s.mu.
+func (s *Service) ownBus(arn string) string {
+	for _, b := range s.buses {
+		if b.arn == arn {
+			return b.name
+		}
+	}
+	return ""
+}
+
+// payload returns what t receives for event: its constant Input, the part
+// of the event selected by its InputPath, or the whole event. It reports
+// false if the InputPath selects nothing.
+func (t *target) payload(event map[string]interface{}, raw []byte) ([]byte, bool) {
+	switch {
+	case t.input != "":
+		return []byte(t.input), true
+	case t.inputPath != "" && t.inputPath != "$":
+		var v interface{} = event
+		for _, field := range strings.Split(strings.TrimPrefix(t.inputPath, "$."), ".") {
+			m, ok := v.(map[string]interface{})
+			if !ok {
+				return nil, false
+			}
+			if v, ok = m[field]; !ok {
+				return nil, false
+			}
+		}
+		b, _ := json.Marshal(v)
+		return b, true
+	}
+	return raw, true
+}
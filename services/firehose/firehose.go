@@ -6,40 +6,67 @@
 //   - DescribeDeliveryStream
 //   - ListDeliveryStreams
 //   - PutRecord
+//   - PutRecordBatch
+//   - TagDeliveryStream
+//   - UntagDeliveryStream
+//   - ListTagsForDeliveryStream
+//
+// When a stream's destination has a ProcessingConfiguration with a Lambda
+// processor, the records of each PutRecord or PutRecordBatch call are passed
+// to that function in the data transformation event format, and the records
+// it returns are what the stream delivers.
+//
+// Streams with an S3 or extended S3 destination write the records they
+// deliver to the destination bucket in the S3 mock, concatenated as
+// Firehose writes them, once the destination's buffering interval has
+// passed on the mock clock or its buffer size is reached. The SNS mock
+// delivers the messages of topics with firehose subscriptions to the
+// subscribed stream.
 package firehose
 
 import (
-	"encoding/json"
+	"encoding/base64"
 	"fmt"
-	"io"
 	"net/http"
 	"sort"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/riyanimam/goto/internal/clock"
 	h "github.com/riyanimam/goto/internal/mockhelpers"
+	"github.com/riyanimam/goto/internal/tags"
 )
 
 // Service implements the Firehose mock.
 type Service struct {
-	mu      sync.RWMutex
-	streams map[string]*deliveryStream
+	mu       sync.RWMutex
+	streams  map[string]*deliveryStream
+	tags     *tags.Store
+	dispatch h.Dispatcher
+	store    h.ObjectStore
+	clock    *clock.Clock
 }
 
 type deliveryStream struct {
-	name    string
-	arn     string
-	status  string
-	destID  string
-	created time.Time
-	records [][]byte
+	name          string
+	arn           string
+	status        string
+	destID        string
+	destKey       string // e.g. ExtendedS3DestinationConfiguration
+	destConfig    map[string]interface{}
+	lambdaArn     string
+	lambdaRetries int
+	created       time.Time
+	records       []Record
+	s3            *s3Destination // nil for other destinations
 }
 
 // New creates a new Firehose mock service.
 func New() *Service {
 	return &Service{
 		streams: make(map[string]*deliveryStream),
+		tags:    tags.New(),
 	}
 }
 
@@ -48,7 +75,17 @@ func (s *Service) Name() string { return "firehose" }
 
 // Handler returns the HTTP handler for Firehose requests.
 func (s *Service) Handler() http.Handler {
-	return http.HandlerFunc(s.handle)
+	return h.JSONRouter{
+		"CreateDeliveryStream":      s.createDeliveryStream,
+		"DeleteDeliveryStream":      s.deleteDeliveryStream,
+		"DescribeDeliveryStream":    s.describeDeliveryStream,
+		"ListDeliveryStreams":       s.listDeliveryStreams,
+		"PutRecord":                 s.putRecord,
+		"PutRecordBatch":            s.putRecordBatch,
+		"TagDeliveryStream":         s.tagDeliveryStream,
+		"UntagDeliveryStream":       s.untagDeliveryStream,
+		"ListTagsForDeliveryStream": s.listTagsForDeliveryStream,
+	}
 }
 
 // Reset clears all state.
@@ -56,47 +93,51 @@ func (s *Service) Reset() {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	s.streams = make(map[string]*deliveryStream)
+	s.tags.DeleteService("firehose")
 }
 
-func (s *Service) handle(w http.ResponseWriter, r *http.Request) {
-	target := r.Header.Get("X-Amz-Target")
-
-	bodyBytes, err := io.ReadAll(r.Body)
-	if err != nil {
-		h.WriteJSONError(w, "InternalFailure", "could not read request body", http.StatusInternalServerError)
-		return
-	}
+// SetTagStore sets the registry resource tags are recorded in.
+func (s *Service) SetTagStore(store *tags.Store) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tags = store
+}
 
-	var params map[string]interface{}
-	if len(bodyBytes) > 0 {
-		json.Unmarshal(bodyBytes, &params)
-	}
-	if params == nil {
-		params = make(map[string]interface{})
+// HasResource reports whether the delivery stream identified by arn exists.
+func (s *Service) HasResource(arn string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, ds := range s.streams {
+		if ds.arn == arn {
+			return true
+		}
 	}
+	return false
+}
 
-	action := ""
-	if target != "" {
-		parts := strings.SplitN(target, ".", 2)
-		if len(parts) == 2 {
-			action = parts[1]
+// Deliver puts payload on the delivery stream identified by arn as a
+// single record, as SNS delivers messages to firehose subscriptions.
+func (s *Service) Deliver(arn string, payload []byte) ([]byte, error) {
+	s.mu.RLock()
+	name := ""
+	for _, ds := range s.streams {
+		if ds.arn == arn {
+			name = ds.name
+			break
 		}
 	}
+	s.mu.RUnlock()
+	if name == "" {
+		return nil, fmt.Errorf("delivery stream %s does not exist", arn)
+	}
 
-	switch action {
-	case "CreateDeliveryStream":
-		s.createDeliveryStream(w, params)
-	case "DeleteDeliveryStream":
-		s.deleteDeliveryStream(w, params)
-	case "DescribeDeliveryStream":
-		s.describeDeliveryStream(w, params)
-	case "ListDeliveryStreams":
-		s.listDeliveryStreams(w, params)
-	case "PutRecord":
-		s.putRecord(w, params)
-	default:
-		h.WriteJSONError(w, "UnknownOperationException", fmt.Sprintf("action %q is not supported", action), http.StatusBadRequest)
+	rec := incoming{
+		id:      h.RandomHex(56),
+		data:    base64.StdEncoding.EncodeToString(payload),
+		arrived: time.Now().UTC(),
 	}
+	s.deliver(name, []incoming{rec})
+	return []byte(rec.id), nil
 }
 
 func (s *Service) createDeliveryStream(w http.ResponseWriter, params map[string]interface{}) {
@@ -119,9 +160,18 @@ func (s *Service) createDeliveryStream(w http.ResponseWriter, params map[string]
 		arn:     arn,
 		status:  "ACTIVE",
 		destID:  "destinationId-" + h.RandomHex(12),
-		created: time.Now().UTC(),
+		created: s.now(),
+	}
+	for key, v := range params {
+		if dest, ok := v.(map[string]interface{}); ok && strings.HasSuffix(key, "DestinationConfiguration") {
+			ds.destKey, ds.destConfig = key, dest
+			ds.lambdaArn, ds.lambdaRetries = lambdaProcessor(dest)
+			ds.s3 = newS3Destination(key, dest)
+			break
+		}
 	}
 	s.streams[name] = ds
+	s.tags.Tag(arn, tags.FromList(params["Tags"], "Key", "Value"))
 	s.mu.Unlock()
 
 	h.WriteJSON(w, http.StatusOK, map[string]interface{}{
@@ -133,12 +183,14 @@ func (s *Service) deleteDeliveryStream(w http.ResponseWriter, params map[string]
 	name := h.GetString(params, "DeliveryStreamName")
 
 	s.mu.Lock()
-	if _, exists := s.streams[name]; !exists {
+	ds, exists := s.streams[name]
+	if !exists {
 		s.mu.Unlock()
 		h.WriteJSONError(w, "ResourceNotFoundException", "Delivery stream "+name+" not found", http.StatusNotFound)
 		return
 	}
 	delete(s.streams, name)
+	s.tags.Delete(ds.arn)
 	s.mu.Unlock()
 
 	h.WriteJSON(w, http.StatusOK, map[string]interface{}{})
@@ -156,6 +208,12 @@ func (s *Service) describeDeliveryStream(w http.ResponseWriter, params map[strin
 		return
 	}
 
+	dest := map[string]interface{}{
+		"DestinationId": ds.destID,
+	}
+	if ds.destConfig != nil {
+		dest[destinationDescription(ds.destKey)] = ds.destConfig
+	}
 	h.WriteJSON(w, http.StatusOK, map[string]interface{}{
 		"DeliveryStreamDescription": map[string]interface{}{
 			"DeliveryStreamName":   ds.name,
@@ -164,11 +222,7 @@ func (s *Service) describeDeliveryStream(w http.ResponseWriter, params map[strin
 			"DeliveryStreamType":   "DirectPut",
 			"CreateTimestamp":      float64(ds.created.Unix()),
 			"HasMoreDestinations":  false,
-			"Destinations": []map[string]interface{}{
-				{
-					"DestinationId": ds.destID,
-				},
-			},
+			"Destinations":         []map[string]interface{}{dest},
 		},
 	})
 }
@@ -189,26 +243,113 @@ func (s *Service) listDeliveryStreams(w http.ResponseWriter, _ map[string]interf
 	})
 }
 
+func (s *Service) tagDeliveryStream(w http.ResponseWriter, params map[string]interface{}) {
+	name := h.GetString(params, "DeliveryStreamName")
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	ds := s.streams[name]
+	if ds == nil {
+		h.WriteJSONError(w, "ResourceNotFoundException", "Delivery stream "+name+" not found", http.StatusNotFound)
+		return
+	}
+	s.tags.Tag(ds.arn, tags.FromList(params["Tags"], "Key", "Value"))
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{})
+}
+
+func (s *Service) untagDeliveryStream(w http.ResponseWriter, params map[string]interface{}) {
+	name := h.GetString(params, "DeliveryStreamName")
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	ds := s.streams[name]
+	if ds == nil {
+		h.WriteJSONError(w, "ResourceNotFoundException", "Delivery stream "+name+" not found", http.StatusNotFound)
+		return
+	}
+	s.tags.Untag(ds.arn, tags.Keys(params["TagKeys"]))
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{})
+}
+
+func (s *Service) listTagsForDeliveryStream(w http.ResponseWriter, params map[string]interface{}) {
+	name := h.GetString(params, "DeliveryStreamName")
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	ds := s.streams[name]
+	if ds == nil {
+		h.WriteJSONError(w, "ResourceNotFoundException", "Delivery stream "+name+" not found", http.StatusNotFound)
+		return
+	}
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"Tags":        tags.ToList(s.tags.Get(ds.arn), "Key", "Value"),
+		"HasMoreTags": false,
+	})
+}
+
 func (s *Service) putRecord(w http.ResponseWriter, params map[string]interface{}) {
 	name := h.GetString(params, "DeliveryStreamName")
 
-	s.mu.Lock()
-	ds, exists := s.streams[name]
+	s.mu.RLock()
+	_, exists := s.streams[name]
+	s.mu.RUnlock()
 	if !exists {
-		s.mu.Unlock()
 		h.WriteJSONError(w, "ResourceNotFoundException", "Delivery stream "+name+" not found", http.StatusNotFound)
 		return
 	}
 
-	if record, ok := params["Record"].(map[string]interface{}); ok {
-		if data, ok := record["Data"].(string); ok {
-			ds.records = append(ds.records, []byte(data))
-		}
-	}
-	s.mu.Unlock()
+	record, _ := params["Record"].(map[string]interface{})
+	rec := newIncoming(record)
+	s.deliver(name, []incoming{rec})
 
 	h.WriteJSON(w, http.StatusOK, map[string]interface{}{
-		"RecordId":  h.NewRequestID(),
+		"RecordId":  rec.id,
 		"Encrypted": false,
 	})
 }
+
+func (s *Service) putRecordBatch(w http.ResponseWriter, params map[string]interface{}) {
+	name := h.GetString(params, "DeliveryStreamName")
+	list, _ := params["Records"].([]interface{})
+	if len(list) == 0 || len(list) > 500 {
+		h.WriteJSONError(w, "InvalidArgumentException", "Records must contain between 1 and 500 records", http.StatusBadRequest)
+		return
+	}
+
+	s.mu.RLock()
+	_, exists := s.streams[name]
+	s.mu.RUnlock()
+	if !exists {
+		h.WriteJSONError(w, "ResourceNotFoundException", "Delivery stream "+name+" not found", http.StatusNotFound)
+		return
+	}
+
+	records := make([]incoming, 0, len(list))
+	responses := make([]map[string]interface{}, 0, len(list))
+	for _, v := range list {
+		record, _ := v.(map[string]interface{})
+		rec := newIncoming(record)
+		records = append(records, rec)
+		responses = append(responses, map[string]interface{}{"RecordId": rec.id})
+	}
+	s.deliver(name, records)
+
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"FailedPutCount":   0,
+		"Encrypted":        false,
+		"RequestResponses": responses,
+	})
+}
+
+// newIncoming assigns an ID to a record from a PutRecord or PutRecordBatch
+// request.
+func newIncoming(record map[string]interface{}) incoming {
+	return incoming{
+		id:      h.RandomHex(56),
+		data:    h.GetString(record, "Data"),
+		arrived: time.Now().UTC(),
+	}
+}
@@ -0,0 +1,150 @@
+package firehose
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/riyanimam/goto/internal/clock"
+	h "github.com/riyanimam/goto/internal/mockhelpers"
+)
+
+// s3Destination is where a stream with an S3 or extended S3 destination
+// writes its records.
+type s3Destination struct {
+	bucket   string
+	prefix   string
+	interval time.Duration
+	size     int // bytes
+	gzip     bool
+
+	buffer []byte
+	since  time.Time // when the first buffered record arrived
+}
+
+// object is an S3 object to write once s.mu is released.
+type object struct {
+	bucket, key string
+	data        []byte
+}
+
+// newS3Destination reads an S3 or extended S3 destination configuration,
+// applying Firehose's default buffering hints of 300 seconds and 5 MiB. It
+// returns nil for other destinations.
+func newS3Destination(key string, dest map[string]interface{}) *s3Destination {
+	if key != "S3DestinationConfiguration" && key != "ExtendedS3DestinationConfiguration" {
+		return nil
+	}
+	bucket, ok := strings.CutPrefix(h.GetString(dest, "BucketARN"), "arn:aws:s3:::")
+	if !ok {
+		return nil
+	}
+	d := &s3Destination{
+		bucket:   bucket,
+		prefix:   h.GetString(dest, "Prefix"),
+		interval: 300 * time.Second,
+		size:     5 << 20,
+		gzip:     h.GetString(dest, "CompressionFormat") == "GZIP",
+	}
+	hints, _ := dest["BufferingHints"].(map[string]interface{})
+	if v, ok := hints["IntervalInSeconds"].(float64); ok {
+		d.interval = time.Duration(v) * time.Second
+	}
+	if v, ok := hints["SizeInMBs"].(float64); ok && v > 0 {
+		d.size = int(v) << 20
+	}
+	return d
+}
+
+// SetObjectStore sets the store streams with an S3 destination write to.
+func (s *Service) SetObjectStore(store h.ObjectStore) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.store = store
+}
+
+// SetClock attaches the mock clock, which times the buffering of records
+// for S3 destinations.
+func (s *Service) SetClock(c *clock.Clock) {
+	s.mu.Lock()
+	s.clock = c
+	s.mu.Unlock()
+	c.OnAdvance(func(_, now time.Time) {
+		s.mu.Lock()
+		var objects []object
+		for _, ds := range s.streams {
+			if d := ds.s3; d != nil && len(d.buffer) > 0 && !now.Before(d.since.Add(d.interval)) {
+				objects = append(objects, ds.flush(now))
+			}
+		}
+		s.mu.Unlock()
+		s.write(objects)
+	})
+}
+
+func (s *Service) now() time.Time {
+	if s.clock != nil {
+		return s.clock.Now()
+	}
+	return time.Now().UTC()
+}
+
+// bufferRecords adds delivered records to the stream's S3 buffer, returning
+// the object to write if the buffer is full or the destination does not
+// buffer. Records the transformation failed are not written, since they
+// would go to the error output prefix. The caller must hold s.mu.
+func (ds *deliveryStream) bufferRecords(records []Record, now time.Time) []object {
+	d := ds.s3
+	if d == nil {
+		return nil
+	}
+	for _, rec := range records {
+		if rec.ProcessingFailed {
+			continue
+		}
+		if len(d.buffer) == 0 {
+			d.since = now
+		}
+		d.buffer = append(d.buffer, rec.Data...)
+	}
+	if len(d.buffer) == 0 || (d.interval > 0 && len(d.buffer) < d.size) {
+		return nil
+	}
+	return []object{ds.flush(now)}
+}
+
+// flush empties the stream's S3 buffer into an object keyed as Firehose
+// keys them: the prefix, the UTC hour the buffer was started in, and a
+// unique name. The caller must hold s.mu.
+func (ds *deliveryStream) flush(now time.Time) object {
+	d := ds.s3
+	data := d.buffer
+	d.buffer = nil
+	key := fmt.Sprintf("%s%s%s-1-%s-%s", d.prefix, d.since.Format("2006/01/02/15/"), ds.name,
+		now.Format("2006-01-02-15-04-05"), h.NewRequestID())
+	if d.gzip {
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		zw.Write(data)
+		zw.Close()
+		data, key = buf.Bytes(), key+".gz"
+	}
+	return object{bucket: d.bucket, key: key, data: data}
+}
+
+// write puts objects in the S3 mock. Objects whose bucket does not exist
+// are dropped, as Firehose drops records it cannot deliver once retries
+// are exhausted.
+func (s *Service) write(objects []object) {
+	s.mu.RLock()
+	store := s.store
+	s.mu.RUnlock()
+	if store == nil {
+		return
+	}
+	for _, o := range objects {
+		store.PutObject(o.bucket, o.key, o.data)
+	}
+}
@@ -0,0 +1,187 @@
+package firehose
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	h "github.com/riyanimam/goto/internal/mockhelpers"
+)
+
+// Record is a record a delivery stream has delivered to its destination.
+type Record struct {
+	RecordID string
+	Data     []byte
+	// ProcessingFailed reports that the transformation Lambda failed the
+	// record, or could not be invoked, so that AWS would deliver the
+	// original data to the destination's error output instead.
+	ProcessingFailed bool
+}
+
+// incoming is a record put on a stream, before transformation.
+type incoming struct {
+	id      string
+	data    string // base64, as sent by the client
+	arrived time.Time
+}
+
+// SetDispatcher sets the function used to invoke transformation Lambdas.
+func (s *Service) SetDispatcher(d h.Dispatcher) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.dispatch = d
+}
+
+// Records returns the records delivered by the named stream, after any
+// transformation, in the order they were put. Records the transformation
+// dropped are omitted.
+func (s *Service) Records(name string) ([]Record, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	ds, exists := s.streams[name]
+	if !exists {
+		return nil, fmt.Errorf("delivery stream %s does not exist", name)
+	}
+	return append([]Record(nil), ds.records...), nil
+}
+
+// lambdaProcessor returns the ARN of the transformation Lambda and the
+// number of retries configured in a destination's ProcessingConfiguration,
+// or "" if records are not transformed.
+func lambdaProcessor(dest map[string]interface{}) (string, int) {
+	cfg, _ := dest["ProcessingConfiguration"].(map[string]interface{})
+	if enabled, ok := cfg["Enabled"].(bool); !ok || !enabled {
+		return "", 0
+	}
+	processors, _ := cfg["Processors"].([]interface{})
+	for _, p := range processors {
+		proc, _ := p.(map[string]interface{})
+		if h.GetString(proc, "Type") != "Lambda" {
+			continue
+		}
+		fn, retries := "", 3
+		params, _ := proc["Parameters"].([]interface{})
+		for _, v := range params {
+			param, _ := v.(map[string]interface{})
+			switch h.GetString(param, "ParameterName") {
+			case "LambdaArn":
+				fn = h.GetString(param, "ParameterValue")
+			case "NumberOfRetries":
+				if n, err := strconv.Atoi(h.GetString(param, "ParameterValue")); err == nil {
+					retries = n
+				}
+			}
+		}
+		return fn, retries
+	}
+	return "", 0
+}
+
+// deliver transforms records with the stream's Lambda, if it has one, and
+// appends the results to the stream. It must be called without holding s.mu,
+// since the Lambda is invoked through the dispatcher.
+func (s *Service) deliver(name string, records []incoming) {
+	s.mu.RLock()
+	ds, exists := s.streams[name]
+	if !exists {
+		s.mu.RUnlock()
+		return
+	}
+	streamArn, fn, retries, dispatch := ds.arn, ds.lambdaArn, ds.lambdaRetries, s.dispatch
+	s.mu.RUnlock()
+
+	var out []Record
+	if fn == "" {
+		for _, rec := range records {
+			out = append(out, Record{RecordID: rec.id, Data: decode(rec.data)})
+		}
+	} else {
+		out = transform(dispatch, streamArn, fn, retries, records)
+	}
+
+	s.mu.Lock()
+	var objects []object
+	if ds, exists := s.streams[name]; exists {
+		ds.records = append(ds.records, out...)
+		objects = ds.bufferRecords(out, s.now())
+	}
+	s.mu.Unlock()
+	s.write(objects)
+}
+
+// transform invokes fn with records in the Firehose data transformation
+// event format, retrying failed invocations, and applies the records it
+// returns. Records the function fails, leaves out of its response, or
+// returns with an unknown result are marked ProcessingFailed, as are all of
+// them if the function cannot be invoked.
+func transform(dispatch h.Dispatcher, streamArn, fn string, retries int, records []incoming) []Record {
+	event := map[string]interface{}{
+		"invocationId":      h.NewRequestID(),
+		"deliveryStreamArn": streamArn,
+		"region":            "us-east-1",
+	}
+	var list []map[string]interface{}
+	for _, rec := range records {
+		list = append(list, map[string]interface{}{
+			"recordId":                    rec.id,
+			"approximateArrivalTimestamp": rec.arrived.UnixMilli(),
+			"data":                        rec.data,
+		})
+	}
+	event["records"] = list
+	payload, _ := json.Marshal(event)
+
+	var resp struct {
+		Records []struct {
+			RecordID string `json:"recordId"`
+			Result   string `json:"result"`
+			Data     string `json:"data"`
+		} `json:"records"`
+	}
+	invoked := false
+	for attempt := 0; attempt <= retries && dispatch != nil; attempt++ {
+		resp.Records = nil
+		body, err := dispatch(fn, payload)
+		if err == nil && json.Unmarshal(body, &resp) == nil {
+			invoked = true
+			break
+		}
+	}
+
+	results := make(map[string]int, len(resp.Records))
+	for i, r := range resp.Records {
+		results[r.RecordID] = i
+	}
+	var out []Record
+	for _, rec := range records {
+		i, ok := results[rec.id]
+		switch {
+		case invoked && ok && resp.Records[i].Result == "Ok":
+			out = append(out, Record{RecordID: rec.id, Data: decode(resp.Records[i].Data)})
+		case invoked && ok && resp.Records[i].Result == "Dropped":
+			// Intentionally filtered out by the function.
+		default:
+			out = append(out, Record{RecordID: rec.id, Data: decode(rec.data), ProcessingFailed: true})
+		}
+	}
+	return out
+}
+
+// decode returns the bytes of base64 data, or data itself if it is not
+// valid base64.
+func decode(data string) []byte {
+	if b, err := base64.StdEncoding.DecodeString(data); err == nil {
+		return b
+	}
+	return []byte(data)
+}
+
+// destinationDescription returns the key DescribeDeliveryStream reports a
+// destination configuration under, such as ExtendedS3DestinationDescription
+// for ExtendedS3DestinationConfiguration.
+func destinationDescription(key string) string {
+	return strings.TrimSuffix(key, "Configuration") + "Description"
+}
@@ -6,24 +6,25 @@
 //   - DeleteFileSystem
 //   - UpdateFileSystem
 //   - TagResource
+//   - UntagResource
+//   - ListTagsForResource
 package fsx
 
 import (
-	"encoding/json"
 	"fmt"
-	"io"
 	"net/http"
-	"strings"
 	"sync"
 	"time"
 
 	h "github.com/riyanimam/goto/internal/mockhelpers"
+	"github.com/riyanimam/goto/internal/tags"
 )
 
 // Service implements the FSx mock.
 type Service struct {
 	mu          sync.RWMutex
 	fileSystems map[string]*fileSystem
+	tags        *tags.Store
 }
 
 type fileSystem struct {
@@ -35,13 +36,13 @@ type fileSystem struct {
 	creationTime    time.Time
 	arn             string
 	subnetIDs       []string
-	tags            []map[string]interface{}
 }
 
 // New creates a new FSx mock service.
 func New() *Service {
 	return &Service{
 		fileSystems: make(map[string]*fileSystem),
+		tags:        tags.New(),
 	}
 }
 
@@ -50,7 +51,15 @@ func (s *Service) Name() string { return "fsx" }
 
 // Handler returns the HTTP handler for FSx requests.
 func (s *Service) Handler() http.Handler {
-	return http.HandlerFunc(s.handle)
+	return h.JSONRouter{
+		"CreateFileSystem":    s.createFileSystem,
+		"DescribeFileSystems": s.describeFileSystems,
+		"DeleteFileSystem":    s.deleteFileSystem,
+		"UpdateFileSystem":    s.updateFileSystem,
+		"TagResource":         s.tagResource,
+		"UntagResource":       s.untagResource,
+		"ListTagsForResource": s.listTagsForResource,
+	}
 }
 
 // Reset clears all state.
@@ -58,50 +67,14 @@ func (s *Service) Reset() {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	s.fileSystems = make(map[string]*fileSystem)
+	s.tags.DeleteService("fsx")
 }
 
-func (s *Service) handle(w http.ResponseWriter, r *http.Request) {
-	target := r.Header.Get("X-Amz-Target")
-
-	bodyBytes, err := io.ReadAll(r.Body)
-	if err != nil {
-		h.WriteJSONError(w, "InternalServerError", "could not read request body", http.StatusInternalServerError)
-		return
-	}
-
-	var params map[string]interface{}
-	if len(bodyBytes) > 0 {
-		if err := json.Unmarshal(bodyBytes, &params); err != nil {
-			h.WriteJSONError(w, "SerializationException", "could not parse request body", http.StatusBadRequest)
-			return
-		}
-	}
-	if params == nil {
-		params = make(map[string]interface{})
-	}
-
-	action := ""
-	if target != "" {
-		parts := strings.SplitN(target, ".", 2)
-		if len(parts) == 2 {
-			action = parts[1]
-		}
-	}
-
-	switch action {
-	case "CreateFileSystem":
-		s.createFileSystem(w, params)
-	case "DescribeFileSystems":
-		s.describeFileSystems(w, params)
-	case "DeleteFileSystem":
-		s.deleteFileSystem(w, params)
-	case "UpdateFileSystem":
-		s.updateFileSystem(w, params)
-	case "TagResource":
-		s.tagResource(w, params)
-	default:
-		h.WriteJSONError(w, "UnknownOperationException", fmt.Sprintf("action %q is not supported", action), http.StatusBadRequest)
-	}
+// SetTagStore sets the registry resource tags are recorded in.
+func (s *Service) SetTagStore(store *tags.Store) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tags = store
 }
 
 func (s *Service) createFileSystem(w http.ResponseWriter, params map[string]interface{}) {
@@ -118,15 +91,6 @@ func (s *Service) createFileSystem(w http.ResponseWriter, params map[string]inte
 		}
 	}
 
-	var tags []map[string]interface{}
-	if raw, ok := params["Tags"].([]interface{}); ok {
-		for _, v := range raw {
-			if m, ok := v.(map[string]interface{}); ok {
-				tags = append(tags, m)
-			}
-		}
-	}
-
 	fsID := "fs-" + h.RandomHex(17)
 	arn := fmt.Sprintf("arn:aws:fsx:us-east-1:%s:file-system/%s", h.DefaultAccountID, fsID)
 	now := time.Now().UTC()
@@ -140,15 +104,15 @@ func (s *Service) createFileSystem(w http.ResponseWriter, params map[string]inte
 		creationTime:    now,
 		arn:             arn,
 		subnetIDs:       subnetIDs,
-		tags:            tags,
 	}
 
 	s.mu.Lock()
 	s.fileSystems[fsID] = fs
+	s.tags.Tag(arn, tags.FromList(params["Tags"], "Key", "Value"))
 	s.mu.Unlock()
 
 	h.WriteJSON(w, http.StatusOK, map[string]interface{}{
-		"FileSystem": fsResp(fs),
+		"FileSystem": s.fsResp(fs),
 	})
 }
 
@@ -167,12 +131,12 @@ func (s *Service) describeFileSystems(w http.ResponseWriter, params map[string]i
 	if len(filterIDs) > 0 {
 		for _, id := range filterIDs {
 			if fs, ok := s.fileSystems[id]; ok {
-				list = append(list, fsResp(fs))
+				list = append(list, s.fsResp(fs))
 			}
 		}
 	} else {
 		for _, fs := range s.fileSystems {
-			list = append(list, fsResp(fs))
+			list = append(list, s.fsResp(fs))
 		}
 	}
 	s.mu.RUnlock()
@@ -190,13 +154,14 @@ func (s *Service) deleteFileSystem(w http.ResponseWriter, params map[string]inte
 	}
 
 	s.mu.Lock()
-	_, exists := s.fileSystems[fsID]
+	fs, exists := s.fileSystems[fsID]
 	if !exists {
 		s.mu.Unlock()
 		h.WriteJSONError(w, "FileSystemNotFound", fmt.Sprintf("File system %q not found", fsID), http.StatusNotFound)
 		return
 	}
 	delete(s.fileSystems, fsID)
+	s.tags.Delete(fs.arn)
 	s.mu.Unlock()
 
 	h.WriteJSON(w, http.StatusOK, map[string]interface{}{
@@ -226,40 +191,66 @@ func (s *Service) updateFileSystem(w http.ResponseWriter, params map[string]inte
 	s.mu.Unlock()
 
 	h.WriteJSON(w, http.StatusOK, map[string]interface{}{
-		"FileSystem": fsResp(fs),
+		"FileSystem": s.fsResp(fs),
 	})
 }
 
+// hasFileSystem reports whether arn names a file system. Caller must hold
+// s.mu.
+func (s *Service) hasFileSystem(arn string) bool {
+	for _, fs := range s.fileSystems {
+		if fs.arn == arn {
+			return true
+		}
+	}
+	return false
+}
+
 func (s *Service) tagResource(w http.ResponseWriter, params map[string]interface{}) {
-	resourceARN := h.GetString(params, "ResourceARN")
-	if resourceARN == "" {
-		h.WriteJSONError(w, "BadRequest", "ResourceARN is required", http.StatusBadRequest)
+	arn := h.GetString(params, "ResourceARN")
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if !s.hasFileSystem(arn) {
+		h.WriteJSONError(w, "ResourceNotFound", "Resource not found: "+arn, http.StatusBadRequest)
 		return
 	}
+	s.tags.Tag(arn, tags.FromList(params["Tags"], "Key", "Value"))
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{})
+}
 
-	var newTags []map[string]interface{}
-	if raw, ok := params["Tags"].([]interface{}); ok {
-		for _, v := range raw {
-			if m, ok := v.(map[string]interface{}); ok {
-				newTags = append(newTags, m)
-			}
-		}
-	}
+func (s *Service) untagResource(w http.ResponseWriter, params map[string]interface{}) {
+	arn := h.GetString(params, "ResourceARN")
 
-	// Find the file system by ARN and append tags.
-	s.mu.Lock()
-	for _, fs := range s.fileSystems {
-		if fs.arn == resourceARN {
-			fs.tags = append(fs.tags, newTags...)
-			break
-		}
-	}
-	s.mu.Unlock()
+	s.mu.RLock()
+	defer s.mu.RUnlock()
 
+	if !s.hasFileSystem(arn) {
+		h.WriteJSONError(w, "ResourceNotFound", "Resource not found: "+arn, http.StatusBadRequest)
+		return
+	}
+	s.tags.Untag(arn, tags.Keys(params["TagKeys"]))
 	h.WriteJSON(w, http.StatusOK, map[string]interface{}{})
 }
 
-func fsResp(fs *fileSystem) map[string]interface{} {
+func (s *Service) listTagsForResource(w http.ResponseWriter, params map[string]interface{}) {
+	arn := h.GetString(params, "ResourceARN")
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if !s.hasFileSystem(arn) {
+		h.WriteJSONError(w, "ResourceNotFound", "Resource not found: "+arn, http.StatusBadRequest)
+		return
+	}
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"Tags": tags.ToList(s.tags.Get(arn), "Key", "Value"),
+	})
+}
+
+// fsResp renders a file system along with its tags.
+func (s *Service) fsResp(fs *fileSystem) map[string]interface{} {
 	return map[string]interface{}{
 		"FileSystemId":    fs.id,
 		"FileSystemType":  fs.fileSystemType,
@@ -268,7 +259,7 @@ func fsResp(fs *fileSystem) map[string]interface{} {
 		"Lifecycle":       fs.lifecycle,
 		"CreationTime":    float64(fs.creationTime.Unix()),
 		"ResourceARN":     fs.arn,
-		"Tags":            fs.tags,
+		"Tags":            tags.ToList(s.tags.Get(fs.arn), "Key", "Value"),
 		"SubnetIds":       fs.subnetIDs,
 	}
 }
@@ -0,0 +1,417 @@
+// Package globalaccelerator provides a mock implementation of AWS Global
+// Accelerator.
+//
+// Supported actions:
+//   - CreateAccelerator, DescribeAccelerator, ListAccelerators,
+//     UpdateAccelerator, DeleteAccelerator
+//   - CreateListener, DescribeListener, ListListeners, UpdateListener,
+//     DeleteListener
+//   - CreateEndpointGroup, DescribeEndpointGroup, ListEndpointGroups,
+//     UpdateEndpointGroup, DeleteEndpointGroup
+//   - TagResource, UntagResource, ListTagsForResource
+//
+// No traffic is routed. Each accelerator is allocated two static IPv4
+// addresses, and two IPv6 addresses when dual-stack, and is IN_PROGRESS
+// after every change to it, its listeners, or its endpoint groups until its
+// lifecycle transition completes, and then DEPLOYED. Endpoints must exist:
+// load balancer ARNs are checked against the ELBv2 mock and instance IDs
+// against the EC2 mock. Endpoint health is reported from the endpoint's
+// current state, so an endpoint whose instance is no longer running or whose
+// load balancer is deleted becomes UNHEALTHY.
+package globalaccelerator
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/riyanimam/goto/internal/clock"
+	"github.com/riyanimam/goto/internal/lifecycle"
+	h "github.com/riyanimam/goto/internal/mockhelpers"
+	"github.com/riyanimam/goto/internal/paginate"
+	"github.com/riyanimam/goto/internal/tags"
+)
+
+// Service implements the Global Accelerator mock.
+type Service struct {
+	mu             sync.RWMutex
+	accelerators   map[string]*accelerator
+	listeners      map[string]*listener
+	endpointGroups map[string]*endpointGroup
+	allocated      int // static IP address pairs handed out
+
+	resolve     h.Resolver
+	list        h.ResourceLister
+	tags        *tags.Store
+	clock       *clock.Clock
+	transitions *lifecycle.Transitions
+}
+
+type accelerator struct {
+	arn              string
+	name             string
+	idempotencyToken string
+	ipAddressType    string // IPV4 or DUAL_STACK
+	enabled          bool
+	ipv4             []string
+	index            int // allocation order, from which IPv6 addresses are derived
+	dnsName          string
+	created          time.Time
+	modified         time.Time
+	deployed         lifecycle.Transition
+}
+
+// New creates a new Global Accelerator mock service.
+func New() *Service {
+	return &Service{
+		accelerators:   make(map[string]*accelerator),
+		listeners:      make(map[string]*listener),
+		endpointGroups: make(map[string]*endpointGroup),
+		tags:           tags.New(),
+	}
+}
+
+// Name returns the service identifier.
+func (s *Service) Name() string { return "globalaccelerator" }
+
+// Handler returns the HTTP handler for Global Accelerator requests.
+func (s *Service) Handler() http.Handler {
+	return h.JSONRouter{
+		"CreateAccelerator":     s.createAccelerator,
+		"DescribeAccelerator":   s.describeAccelerator,
+		"ListAccelerators":      s.listAccelerators,
+		"UpdateAccelerator":     s.updateAccelerator,
+		"DeleteAccelerator":     s.deleteAccelerator,
+		"CreateListener":        s.createListener,
+		"DescribeListener":      s.describeListener,
+		"ListListeners":         s.listListeners,
+		"UpdateListener":        s.updateListener,
+		"DeleteListener":        s.deleteListener,
+		"CreateEndpointGroup":   s.createEndpointGroup,
+		"DescribeEndpointGroup": s.describeEndpointGroup,
+		"ListEndpointGroups":    s.listEndpointGroups,
+		"UpdateEndpointGroup":   s.updateEndpointGroup,
+		"DeleteEndpointGroup":   s.deleteEndpointGroup,
+		"TagResource":           s.tagResource,
+		"UntagResource":         s.untagResource,
+		"ListTagsForResource":   s.listTagsForResource,
+	}
+}
+
+// Reset clears all state.
+func (s *Service) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.accelerators = make(map[string]*accelerator)
+	s.listeners = make(map[string]*listener)
+	s.endpointGroups = make(map[string]*endpointGroup)
+	s.allocated = 0
+	s.tags.DeleteService("globalaccelerator")
+}
+
+// SetResolver sets the function used to check that load balancer endpoints
+// exist.
+func (s *Service) SetResolver(r h.Resolver) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.resolve = r
+}
+
+// SetResourceLister sets how the EC2 mock's instances are found.
+func (s *Service) SetResourceLister(l h.ResourceLister) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.list = l
+}
+
+// SetTagStore sets the registry accelerator tags are recorded in.
+func (s *Service) SetTagStore(store *tags.Store) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tags = store
+}
+
+// SetClock attaches the mock clock used for creation and modification
+// times.
+func (s *Service) SetClock(c *clock.Clock) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.clock = c
+}
+
+// SetTransitions sets how long accelerators stay IN_PROGRESS after a change.
+func (s *Service) SetTransitions(t *lifecycle.Transitions) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.transitions = t
+}
+
+func (s *Service) now() time.Time {
+	if s.clock != nil {
+		return s.clock.Now()
+	}
+	return time.Now().UTC()
+}
+
+func writeError(w http.ResponseWriter, code, message string) {
+	h.WriteJSONError(w, code, message, http.StatusBadRequest)
+}
+
+// touch records a change to the accelerator, which deploys it again. The
+// caller must hold s.mu.
+func (s *Service) touch(acc *accelerator) {
+	acc.modified = s.now()
+	acc.deployed = s.transitions.Begin()
+}
+
+// allocate hands out the static IPv4 addresses of a new accelerator, one
+// from each of two address ranges as AWS allocates them from separate
+// network zones, and returns them with the accelerator's allocation index.
+// The caller must hold s.mu.
+func (s *Service) allocate() ([]string, int) {
+	s.allocated++
+	n := s.allocated
+	return []string{
+		fmt.Sprintf("75.2.%d.%d", n/254, n%254+1),
+		fmt.Sprintf("99.83.%d.%d", n/254, n%254+1),
+	}, n
+}
+
+// ipv6 returns a dual-stack accelerator's static IPv6 addresses.
+func (acc *accelerator) ipv6() []string {
+	return []string{
+		fmt.Sprintf("2600:9000:a400::%x", acc.index),
+		fmt.Sprintf("2600:9000:a500::%x", acc.index),
+	}
+}
+
+func (s *Service) createAccelerator(w http.ResponseWriter, params map[string]interface{}) {
+	name := h.GetString(params, "Name")
+	token := h.GetString(params, "IdempotencyToken")
+	if name == "" || len(name) > 64 || token == "" {
+		writeError(w, "InvalidArgumentException", "Name (at most 64 characters) and IdempotencyToken are required.")
+		return
+	}
+	ipAddressType := h.GetString(params, "IpAddressType")
+	if ipAddressType == "" {
+		ipAddressType = "IPV4"
+	}
+	if ipAddressType != "IPV4" && ipAddressType != "DUAL_STACK" {
+		writeError(w, "InvalidArgumentException", "IpAddressType must be IPV4 or DUAL_STACK.")
+		return
+	}
+	byoip := stringList(params["IpAddresses"])
+	if len(byoip) > 2 {
+		writeError(w, "InvalidArgumentException", "At most two IpAddresses can be specified.")
+		return
+	}
+	enabled := true
+	if v, ok := params["Enabled"].(bool); ok {
+		enabled = v
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, acc := range s.accelerators {
+		if acc.idempotencyToken == token {
+			h.WriteJSON(w, http.StatusOK, map[string]interface{}{"Accelerator": s.acceleratorResp(acc)})
+			return
+		}
+	}
+	ipv4, index := s.allocate()
+	copy(ipv4, byoip)
+	now := s.now()
+	acc := &accelerator{
+		arn:              fmt.Sprintf("arn:aws:globalaccelerator::%s:accelerator/%s", h.DefaultAccountID, h.NewRequestID()),
+		name:             name,
+		idempotencyToken: token,
+		ipAddressType:    ipAddressType,
+		enabled:          enabled,
+		ipv4:             ipv4,
+		index:            index,
+		dnsName:          h.RandomHex(16),
+		created:          now,
+		modified:         now,
+		deployed:         s.transitions.Begin(),
+	}
+	s.accelerators[acc.arn] = acc
+	s.tags.Tag(acc.arn, tags.FromList(params["Tags"], "Key", "Value"))
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{"Accelerator": s.acceleratorResp(acc)})
+}
+
+// acceleratorResp returns the accelerator as DescribeAccelerator reports
+// it. The caller must hold s.mu.
+func (s *Service) acceleratorResp(acc *accelerator) map[string]interface{} {
+	ipSets := []map[string]interface{}{{"IpFamily": "IPv4", "IpAddressFamily": "IPv4", "IpAddresses": acc.ipv4}}
+	resp := map[string]interface{}{
+		"AcceleratorArn":   acc.arn,
+		"Name":             acc.name,
+		"IpAddressType":    acc.ipAddressType,
+		"Enabled":          acc.enabled,
+		"DnsName":          "a" + acc.dnsName + ".awsglobalaccelerator.com",
+		"Status":           s.transitions.Status(acc.deployed, "IN_PROGRESS", "DEPLOYED"),
+		"CreatedTime":      float64(acc.created.Unix()),
+		"LastModifiedTime": float64(acc.modified.Unix()),
+	}
+	if acc.ipAddressType == "DUAL_STACK" {
+		ipSets = append(ipSets, map[string]interface{}{"IpFamily": "IPv6", "IpAddressFamily": "IPv6", "IpAddresses": acc.ipv6()})
+		resp["DualStackDnsName"] = "a" + acc.dnsName + ".dualstack.awsglobalaccelerator.com"
+	}
+	resp["IpSets"] = ipSets
+	return resp
+}
+
+// lookupAccelerator returns the accelerator, or writes
+// AcceleratorNotFoundException. The caller must hold s.mu.
+func (s *Service) lookupAccelerator(w http.ResponseWriter, arn string) (*accelerator, bool) {
+	acc, exists := s.accelerators[arn]
+	if !exists {
+		writeError(w, "AcceleratorNotFoundException", "Accelerator "+arn+" not found.")
+	}
+	return acc, exists
+}
+
+func (s *Service) describeAccelerator(w http.ResponseWriter, params map[string]interface{}) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	acc, ok := s.lookupAccelerator(w, h.GetString(params, "AcceleratorArn"))
+	if !ok {
+		return
+	}
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{"Accelerator": s.acceleratorResp(acc)})
+}
+
+func (s *Service) listAccelerators(w http.ResponseWriter, params map[string]interface{}) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	out := make([]map[string]interface{}, 0, len(s.accelerators))
+	for _, arn := range sortedKeys(s.accelerators) {
+		out = append(out, s.acceleratorResp(s.accelerators[arn]))
+	}
+	writePage(w, out, params, "Accelerators", "AcceleratorArn")
+}
+
+func (s *Service) updateAccelerator(w http.ResponseWriter, params map[string]interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	acc, ok := s.lookupAccelerator(w, h.GetString(params, "AcceleratorArn"))
+	if !ok {
+		return
+	}
+	if v := h.GetString(params, "IpAddressType"); v != "" {
+		if v != "IPV4" && v != "DUAL_STACK" {
+			writeError(w, "InvalidArgumentException", "IpAddressType must be IPV4 or DUAL_STACK.")
+			return
+		}
+		acc.ipAddressType = v
+	}
+	if v := h.GetString(params, "Name"); v != "" {
+		acc.name = v
+	}
+	if v, ok := params["Enabled"].(bool); ok {
+		acc.enabled = v
+	}
+	s.touch(acc)
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{"Accelerator": s.acceleratorResp(acc)})
+}
+
+func (s *Service) deleteAccelerator(w http.ResponseWriter, params map[string]interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	acc, ok := s.lookupAccelerator(w, h.GetString(params, "AcceleratorArn"))
+	if !ok {
+		return
+	}
+	if acc.enabled {
+		writeError(w, "AcceleratorNotDisabledException", "The accelerator must be disabled before it can be deleted.")
+		return
+	}
+	for _, l := range s.listeners {
+		if l.acceleratorArn == acc.arn {
+			writeError(w, "AssociatedListenerFoundException", "The accelerator has listeners. Delete them first.")
+			return
+		}
+	}
+	delete(s.accelerators, acc.arn)
+	s.tags.Delete(acc.arn)
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{})
+}
+
+func stringList(v interface{}) []string {
+	raw, _ := v.([]interface{})
+	out := make([]string, 0, len(raw))
+	for _, item := range raw {
+		if s, ok := item.(string); ok {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+func sortedKeys[T any](m map[string]T) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// writePage writes a page of items, which are sorted by their field named
+// field, under key, with MaxResults and NextToken from params.
+func writePage(w http.ResponseWriter, items []map[string]interface{}, params map[string]interface{}, key, field string) {
+	page, next, err := paginate.Page(items, func(i int) string { return items[i][field].(string) }, h.GetString(params, "NextToken"), h.GetInt(params, "MaxResults", 100), 100)
+	if err != nil {
+		writeError(w, "InvalidNextTokenException", "The NextToken is not valid.")
+		return
+	}
+	resp := map[string]interface{}{key: page}
+	if next != "" {
+		resp["NextToken"] = next
+	}
+	h.WriteJSON(w, http.StatusOK, resp)
+}
+
+func (s *Service) tagResource(w http.ResponseWriter, params map[string]interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	acc, ok := s.lookupAccelerator(w, h.GetString(params, "ResourceArn"))
+	if !ok {
+		return
+	}
+	s.tags.Tag(acc.arn, tags.FromList(params["Tags"], "Key", "Value"))
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{})
+}
+
+func (s *Service) untagResource(w http.ResponseWriter, params map[string]interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	acc, ok := s.lookupAccelerator(w, h.GetString(params, "ResourceArn"))
+	if !ok {
+		return
+	}
+	s.tags.Untag(acc.arn, tags.Keys(params["TagKeys"]))
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{})
+}
+
+func (s *Service) listTagsForResource(w http.ResponseWriter, params map[string]interface{}) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	acc, ok := s.lookupAccelerator(w, h.GetString(params, "ResourceArn"))
+	if !ok {
+		return
+	}
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{"Tags": tags.ToList(s.tags.Get(acc.arn), "Key", "Value")})
+}
+
+// acceleratorOf returns the ARN of the accelerator a listener or endpoint
+// group ARN belongs to.
+func acceleratorOf(arn string) string {
+	if i := strings.Index(arn, "/listener/"); i >= 0 {
+		return arn[:i]
+	}
+	return arn
+}
@@ -0,0 +1,582 @@
+package globalaccelerator
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/riyanimam/goto/internal/arn"
+	h "github.com/riyanimam/goto/internal/mockhelpers"
+)
+
+var regionPattern = regexp.MustCompile(`^[a-z]{2}(-[a-z]+)+-\d$`)
+
+type listener struct {
+	arn            string
+	acceleratorArn string
+	protocol       string // TCP or UDP
+	clientAffinity string // NONE or SOURCE_IP
+	portRanges     []portRange
+}
+
+type portRange struct{ from, to int }
+
+type endpointGroup struct {
+	arn                 string
+	listenerArn         string
+	region              string
+	endpoints           []endpointConfig
+	trafficDial         float64
+	healthCheckPort     int
+	healthCheckProtocol string // TCP, HTTP, or HTTPS
+	healthCheckPath     string
+	healthCheckInterval int
+	thresholdCount      int
+	portOverrides       []interface{}
+}
+
+type endpointConfig struct {
+	id         string
+	weight     int
+	preserveIP bool
+}
+
+// parsePortRanges reads a PortRanges list, returning a message describing
+// the first problem if it is invalid.
+func parsePortRanges(v interface{}) ([]portRange, string) {
+	raw, _ := v.([]interface{})
+	if len(raw) == 0 || len(raw) > 10 {
+		return nil, "A listener requires between 1 and 10 port ranges."
+	}
+	ranges := make([]portRange, 0, len(raw))
+	for _, item := range raw {
+		m, _ := item.(map[string]interface{})
+		r := portRange{from: h.GetInt(m, "FromPort", 0), to: h.GetInt(m, "ToPort", 0)}
+		if r.from < 1 || r.to > 65535 || r.from > r.to {
+			return nil, fmt.Sprintf("Invalid port range %d-%d.", r.from, r.to)
+		}
+		ranges = append(ranges, r)
+	}
+	return ranges, ""
+}
+
+func portRangesResp(ranges []portRange) []map[string]interface{} {
+	out := make([]map[string]interface{}, 0, len(ranges))
+	for _, r := range ranges {
+		out = append(out, map[string]interface{}{"FromPort": r.from, "ToPort": r.to})
+	}
+	return out
+}
+
+// overlapping returns another listener of the accelerator for the same
+// protocol whose ports overlap ranges, or nil. The caller must hold s.mu.
+func (s *Service) overlapping(self *listener, acceleratorArn, protocol string, ranges []portRange) *listener {
+	for _, l := range s.listeners {
+		if l == self || l.acceleratorArn != acceleratorArn || l.protocol != protocol {
+			continue
+		}
+		for _, a := range l.portRanges {
+			for _, b := range ranges {
+				if a.from <= b.to && b.from <= a.to {
+					return l
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func (l *listener) resp() map[string]interface{} {
+	return map[string]interface{}{
+		"ListenerArn":    l.arn,
+		"Protocol":       l.protocol,
+		"ClientAffinity": l.clientAffinity,
+		"PortRanges":     portRangesResp(l.portRanges),
+	}
+}
+
+// lookupListener returns the listener, or writes ListenerNotFoundException.
+// The caller must hold s.mu.
+func (s *Service) lookupListener(w http.ResponseWriter, arn string) (*listener, bool) {
+	l, exists := s.listeners[arn]
+	if !exists {
+		writeError(w, "ListenerNotFoundException", "Listener "+arn+" not found.")
+	}
+	return l, exists
+}
+
+func (s *Service) createListener(w http.ResponseWriter, params map[string]interface{}) {
+	protocol := h.GetString(params, "Protocol")
+	if protocol != "TCP" && protocol != "UDP" {
+		writeError(w, "InvalidArgumentException", "Protocol must be TCP or UDP.")
+		return
+	}
+	affinity := h.GetString(params, "ClientAffinity")
+	if affinity == "" {
+		affinity = "NONE"
+	}
+	if affinity != "NONE" && affinity != "SOURCE_IP" {
+		writeError(w, "InvalidArgumentException", "ClientAffinity must be NONE or SOURCE_IP.")
+		return
+	}
+	ranges, msg := parsePortRanges(params["PortRanges"])
+	if msg != "" {
+		writeError(w, "InvalidPortRangeException", msg)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	acc, ok := s.lookupAccelerator(w, h.GetString(params, "AcceleratorArn"))
+	if !ok {
+		return
+	}
+	if other := s.overlapping(nil, acc.arn, protocol, ranges); other != nil {
+		writeError(w, "InvalidPortRangeException", "The port ranges overlap those of listener "+other.arn+".")
+		return
+	}
+	l := &listener{
+		arn:            acc.arn + "/listener/" + h.RandomHex(8),
+		acceleratorArn: acc.arn,
+		protocol:       protocol,
+		clientAffinity: affinity,
+		portRanges:     ranges,
+	}
+	s.listeners[l.arn] = l
+	s.touch(acc)
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{"Listener": l.resp()})
+}
+
+func (s *Service) describeListener(w http.ResponseWriter, params map[string]interface{}) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	l, ok := s.lookupListener(w, h.GetString(params, "ListenerArn"))
+	if !ok {
+		return
+	}
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{"Listener": l.resp()})
+}
+
+func (s *Service) listListeners(w http.ResponseWriter, params map[string]interface{}) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	acc, ok := s.lookupAccelerator(w, h.GetString(params, "AcceleratorArn"))
+	if !ok {
+		return
+	}
+	var out []map[string]interface{}
+	for _, arn := range sortedKeys(s.listeners) {
+		if l := s.listeners[arn]; l.acceleratorArn == acc.arn {
+			out = append(out, l.resp())
+		}
+	}
+	writePage(w, out, params, "Listeners", "ListenerArn")
+}
+
+func (s *Service) updateListener(w http.ResponseWriter, params map[string]interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	l, ok := s.lookupListener(w, h.GetString(params, "ListenerArn"))
+	if !ok {
+		return
+	}
+	protocol, affinity, ranges := l.protocol, l.clientAffinity, l.portRanges
+	if v := h.GetString(params, "Protocol"); v != "" {
+		if v != "TCP" && v != "UDP" {
+			writeError(w, "InvalidArgumentException", "Protocol must be TCP or UDP.")
+			return
+		}
+		protocol = v
+	}
+	if v := h.GetString(params, "ClientAffinity"); v != "" {
+		if v != "NONE" && v != "SOURCE_IP" {
+			writeError(w, "InvalidArgumentException", "ClientAffinity must be NONE or SOURCE_IP.")
+			return
+		}
+		affinity = v
+	}
+	if _, ok := params["PortRanges"]; ok {
+		var msg string
+		if ranges, msg = parsePortRanges(params["PortRanges"]); msg != "" {
+			writeError(w, "InvalidPortRangeException", msg)
+			return
+		}
+	}
+	if other := s.overlapping(l, l.acceleratorArn, protocol, ranges); other != nil {
+		writeError(w, "InvalidPortRangeException", "The port ranges overlap those of listener "+other.arn+".")
+		return
+	}
+	l.protocol, l.clientAffinity, l.portRanges = protocol, affinity, ranges
+	s.touch(s.accelerators[l.acceleratorArn])
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{"Listener": l.resp()})
+}
+
+func (s *Service) deleteListener(w http.ResponseWriter, params map[string]interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	l, ok := s.lookupListener(w, h.GetString(params, "ListenerArn"))
+	if !ok {
+		return
+	}
+	for _, g := range s.endpointGroups {
+		if g.listenerArn == l.arn {
+			writeError(w, "AssociatedEndpointGroupFoundException", "The listener has endpoint groups. Delete them first.")
+			return
+		}
+	}
+	delete(s.listeners, l.arn)
+	s.touch(s.accelerators[l.acceleratorArn])
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{})
+}
+
+// parseEndpoints reads an EndpointConfigurations list, returning a message
+// describing the first problem if it is invalid. Endpoints are load
+// balancer ARNs in the group's region, EC2 instance IDs, or Elastic IP
+// allocation IDs.
+func parseEndpoints(v interface{}, region string) ([]endpointConfig, string) {
+	raw, _ := v.([]interface{})
+	if len(raw) > 10 {
+		return nil, "An endpoint group can have at most 10 endpoints."
+	}
+	endpoints := make([]endpointConfig, 0, len(raw))
+	for _, item := range raw {
+		m, _ := item.(map[string]interface{})
+		e := endpointConfig{id: h.GetString(m, "EndpointId"), weight: h.GetInt(m, "Weight", 128), preserveIP: true}
+		switch {
+		case strings.HasPrefix(e.id, "arn:"):
+			a, err := arn.Parse(e.id)
+			if err != nil || a.Service != "elasticloadbalancing" || !strings.HasPrefix(a.Resource, "loadbalancer/") {
+				return nil, "Endpoint " + e.id + " is not a load balancer ARN."
+			}
+			if a.Region != region {
+				return nil, "Endpoint " + e.id + " is not in region " + region + "."
+			}
+			// Network Load Balancers do not preserve client addresses
+			// unless asked to.
+			e.preserveIP = !strings.HasPrefix(a.Resource, "loadbalancer/net/")
+		case strings.HasPrefix(e.id, "i-"), strings.HasPrefix(e.id, "eipalloc-"):
+		default:
+			return nil, "Invalid endpoint ID: " + e.id
+		}
+		if v, ok := m["ClientIPPreservationEnabled"].(bool); ok {
+			e.preserveIP = v
+		}
+		if e.weight < 0 || e.weight > 255 {
+			return nil, "Endpoint weight must be between 0 and 255."
+		}
+		endpoints = append(endpoints, e)
+	}
+	return endpoints, ""
+}
+
+func endpointIDs(endpoints []endpointConfig) []string {
+	ids := make([]string, 0, len(endpoints))
+	for _, e := range endpoints {
+		ids = append(ids, e.id)
+	}
+	return ids
+}
+
+// endpointHealth is an endpoint's health as Global Accelerator reports it.
+type endpointHealth struct {
+	state  string // HEALTHY or UNHEALTHY
+	reason string
+	exists bool
+}
+
+// health checks the endpoints against the mocks that own them: load
+// balancers must exist in the ELBv2 mock, and instances in the EC2 mock,
+// healthy while running. Elastic IP allocations are not checked, as the EC2
+// mock does not hold them. s.mu must not be held, as other services are
+// consulted.
+func (s *Service) health(ids []string) map[string]endpointHealth {
+	s.mu.RLock()
+	resolve, list := s.resolve, s.list
+	s.mu.RUnlock()
+
+	var instances map[string]string // instance state by ID
+	if list != nil {
+		instances = make(map[string]string)
+		for _, r := range list("ec2") {
+			if r.Type == "aws_instance" {
+				instances[r.ID], _ = r.Attributes["instance_state"].(string)
+			}
+		}
+	}
+	out := make(map[string]endpointHealth, len(ids))
+	for _, id := range ids {
+		healthy := endpointHealth{state: "HEALTHY", exists: true}
+		switch {
+		case strings.HasPrefix(id, "arn:"):
+			if resolve != nil && resolve(id) != nil {
+				healthy = endpointHealth{state: "UNHEALTHY", reason: "Load balancer not found"}
+			}
+		case strings.HasPrefix(id, "i-") && instances != nil:
+			state, ok := instances[id]
+			switch {
+			case !ok:
+				healthy = endpointHealth{state: "UNHEALTHY", reason: "Instance not found"}
+			case state != "running":
+				healthy = endpointHealth{state: "UNHEALTHY", reason: "Instance is " + state, exists: true}
+			}
+		}
+		out[id] = healthy
+	}
+	return out
+}
+
+// checkEndpoints writes InvalidArgumentException and returns false if any
+// of the endpoints does not exist. s.mu must not be held.
+func (s *Service) checkEndpoints(w http.ResponseWriter, endpoints []endpointConfig) bool {
+	health := s.health(endpointIDs(endpoints))
+	for _, e := range endpoints {
+		if !health[e.id].exists {
+			writeError(w, "InvalidArgumentException", "Endpoint "+e.id+" does not exist.")
+			return false
+		}
+	}
+	return true
+}
+
+// setHealthCheck applies the health check settings in params to the group,
+// returning a message describing the first problem if they are invalid.
+func (g *endpointGroup) setHealthCheck(params map[string]interface{}) string {
+	if v := h.GetString(params, "HealthCheckProtocol"); v != "" {
+		if v != "TCP" && v != "HTTP" && v != "HTTPS" {
+			return "HealthCheckProtocol must be TCP, HTTP, or HTTPS."
+		}
+		g.healthCheckProtocol = v
+	}
+	if v := h.GetString(params, "HealthCheckPath"); v != "" {
+		g.healthCheckPath = v
+	}
+	g.healthCheckPort = h.GetInt(params, "HealthCheckPort", g.healthCheckPort)
+	g.healthCheckInterval = h.GetInt(params, "HealthCheckIntervalSeconds", g.healthCheckInterval)
+	g.thresholdCount = h.GetInt(params, "ThresholdCount", g.thresholdCount)
+	if v, ok := params["TrafficDialPercentage"].(float64); ok {
+		g.trafficDial = v
+	}
+	if v, ok := params["PortOverrides"].([]interface{}); ok {
+		g.portOverrides = v
+	}
+	switch {
+	case g.healthCheckPort < 1 || g.healthCheckPort > 65535:
+		return "HealthCheckPort must be between 1 and 65535."
+	case g.healthCheckInterval != 10 && g.healthCheckInterval != 30:
+		return "HealthCheckIntervalSeconds must be 10 or 30."
+	case g.thresholdCount < 1 || g.thresholdCount > 10:
+		return "ThresholdCount must be between 1 and 10."
+	case g.trafficDial < 0 || g.trafficDial > 100:
+		return "TrafficDialPercentage must be between 0 and 100."
+	}
+	return ""
+}
+
+// resp returns the group as DescribeEndpointGroup reports it, without
+// endpoint health, which withHealth fills in.
+func (g *endpointGroup) resp() map[string]interface{} {
+	descriptions := make([]map[string]interface{}, 0, len(g.endpoints))
+	for _, e := range g.endpoints {
+		descriptions = append(descriptions, map[string]interface{}{
+			"EndpointId":                  e.id,
+			"Weight":                      e.weight,
+			"ClientIPPreservationEnabled": e.preserveIP,
+		})
+	}
+	resp := map[string]interface{}{
+		"EndpointGroupArn":           g.arn,
+		"EndpointGroupRegion":        g.region,
+		"EndpointDescriptions":       descriptions,
+		"TrafficDialPercentage":      g.trafficDial,
+		"HealthCheckPort":            g.healthCheckPort,
+		"HealthCheckProtocol":        g.healthCheckProtocol,
+		"HealthCheckIntervalSeconds": g.healthCheckInterval,
+		"ThresholdCount":             g.thresholdCount,
+		"PortOverrides":              g.portOverrides,
+	}
+	if g.healthCheckProtocol != "TCP" {
+		resp["HealthCheckPath"] = g.healthCheckPath
+	}
+	return resp
+}
+
+// withHealth fills in the health of the endpoints of group responses. s.mu
+// must not be held.
+func (s *Service) withHealth(groups ...map[string]interface{}) {
+	var ids []string
+	for _, g := range groups {
+		for _, d := range g["EndpointDescriptions"].([]map[string]interface{}) {
+			ids = append(ids, d["EndpointId"].(string))
+		}
+	}
+	health := s.health(ids)
+	for _, g := range groups {
+		for _, d := range g["EndpointDescriptions"].([]map[string]interface{}) {
+			e := health[d["EndpointId"].(string)]
+			d["HealthState"] = e.state
+			if e.reason != "" {
+				d["HealthReason"] = e.reason
+			}
+		}
+	}
+}
+
+// lookupEndpointGroup returns the endpoint group, or writes
+// EndpointGroupNotFoundException. The caller must hold s.mu.
+func (s *Service) lookupEndpointGroup(w http.ResponseWriter, arn string) (*endpointGroup, bool) {
+	g, exists := s.endpointGroups[arn]
+	if !exists {
+		writeError(w, "EndpointGroupNotFoundException", "Endpoint group "+arn+" not found.")
+	}
+	return g, exists
+}
+
+func (s *Service) createEndpointGroup(w http.ResponseWriter, params map[string]interface{}) {
+	region := h.GetString(params, "EndpointGroupRegion")
+	if !regionPattern.MatchString(region) {
+		writeError(w, "InvalidArgumentException", "Invalid EndpointGroupRegion: "+region)
+		return
+	}
+	endpoints, msg := parseEndpoints(params["EndpointConfigurations"], region)
+	if msg != "" {
+		writeError(w, "InvalidArgumentException", msg)
+		return
+	}
+	if !s.checkEndpoints(w, endpoints) {
+		return
+	}
+
+	s.mu.Lock()
+	l, ok := s.lookupListener(w, h.GetString(params, "ListenerArn"))
+	if !ok {
+		s.mu.Unlock()
+		return
+	}
+	for _, g := range s.endpointGroups {
+		if g.listenerArn == l.arn && g.region == region {
+			s.mu.Unlock()
+			writeError(w, "EndpointGroupAlreadyExistsException", "The listener already has an endpoint group in "+region+".")
+			return
+		}
+	}
+	g := &endpointGroup{
+		arn:                 l.arn + "/endpoint-group/" + h.RandomHex(12),
+		listenerArn:         l.arn,
+		region:              region,
+		endpoints:           endpoints,
+		trafficDial:         100,
+		healthCheckPort:     l.portRanges[0].from,
+		healthCheckProtocol: "TCP",
+		healthCheckPath:     "/",
+		healthCheckInterval: 30,
+		thresholdCount:      3,
+		portOverrides:       []interface{}{},
+	}
+	if msg := g.setHealthCheck(params); msg != "" {
+		s.mu.Unlock()
+		writeError(w, "InvalidArgumentException", msg)
+		return
+	}
+	s.endpointGroups[g.arn] = g
+	s.touch(s.accelerators[l.acceleratorArn])
+	resp := g.resp()
+	s.mu.Unlock()
+
+	s.withHealth(resp)
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{"EndpointGroup": resp})
+}
+
+func (s *Service) describeEndpointGroup(w http.ResponseWriter, params map[string]interface{}) {
+	s.mu.RLock()
+	g, ok := s.lookupEndpointGroup(w, h.GetString(params, "EndpointGroupArn"))
+	if !ok {
+		s.mu.RUnlock()
+		return
+	}
+	resp := g.resp()
+	s.mu.RUnlock()
+
+	s.withHealth(resp)
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{"EndpointGroup": resp})
+}
+
+func (s *Service) listEndpointGroups(w http.ResponseWriter, params map[string]interface{}) {
+	s.mu.RLock()
+	l, ok := s.lookupListener(w, h.GetString(params, "ListenerArn"))
+	if !ok {
+		s.mu.RUnlock()
+		return
+	}
+	var out []map[string]interface{}
+	for _, arn := range sortedKeys(s.endpointGroups) {
+		if g := s.endpointGroups[arn]; g.listenerArn == l.arn {
+			out = append(out, g.resp())
+		}
+	}
+	s.mu.RUnlock()
+
+	s.withHealth(out...)
+	writePage(w, out, params, "EndpointGroups", "EndpointGroupArn")
+}
+
+func (s *Service) updateEndpointGroup(w http.ResponseWriter, params map[string]interface{}) {
+	arn := h.GetString(params, "EndpointGroupArn")
+	s.mu.RLock()
+	g, ok := s.lookupEndpointGroup(w, arn)
+	var region string
+	if ok {
+		region = g.region
+	}
+	s.mu.RUnlock()
+	if !ok {
+		return
+	}
+	var endpoints []endpointConfig
+	_, replace := params["EndpointConfigurations"]
+	if replace {
+		var msg string
+		if endpoints, msg = parseEndpoints(params["EndpointConfigurations"], region); msg != "" {
+			writeError(w, "InvalidArgumentException", msg)
+			return
+		}
+		if !s.checkEndpoints(w, endpoints) {
+			return
+		}
+	}
+
+	s.mu.Lock()
+	g, ok = s.lookupEndpointGroup(w, arn)
+	if !ok {
+		s.mu.Unlock()
+		return
+	}
+	updated := *g
+	if msg := updated.setHealthCheck(params); msg != "" {
+		s.mu.Unlock()
+		writeError(w, "InvalidArgumentException", msg)
+		return
+	}
+	if replace {
+		updated.endpoints = endpoints
+	}
+	*g = updated
+	s.touch(s.accelerators[acceleratorOf(g.arn)])
+	resp := g.resp()
+	s.mu.Unlock()
+
+	s.withHealth(resp)
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{"EndpointGroup": resp})
+}
+
+func (s *Service) deleteEndpointGroup(w http.ResponseWriter, params map[string]interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	g, ok := s.lookupEndpointGroup(w, h.GetString(params, "EndpointGroupArn"))
+	if !ok {
+		return
+	}
+	delete(s.endpointGroups, g.arn)
+	s.touch(s.accelerators[acceleratorOf(g.arn)])
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{})
+}
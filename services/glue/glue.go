@@ -14,19 +14,33 @@
 //   - DeleteCrawler
 //   - StartCrawler
 //   - ListCrawlers
+//   - TagResource
+//   - UntagResource
+//   - GetTags
+//   - CreateSession
+//   - GetSession
+//   - ListSessions
+//   - StopSession
+//   - DeleteSession
+//   - RunStatement
+//   - GetStatement
+//   - ListStatements
+//   - CancelStatement
+//
+// Interactive sessions run no code. Statements succeed with no output unless
+// Go code has been registered for the session with SetStatementHandler.
 package glue
 
 import (
-	"encoding/json"
 	"fmt"
-	"io"
 	"net/http"
 	"sort"
-	"strings"
 	"sync"
 	"time"
 
+	"github.com/riyanimam/goto/internal/lifecycle"
 	h "github.com/riyanimam/goto/internal/mockhelpers"
+	"github.com/riyanimam/goto/internal/tags"
 )
 
 // Service implements the Glue mock.
@@ -34,6 +48,11 @@ type Service struct {
 	mu        sync.RWMutex
 	databases map[string]*glueDatabase
 	crawlers  map[string]*glueCrawler
+	sessions  map[string]*session
+	tags      *tags.Store
+
+	statementHandlers map[string]StatementFunc
+	transitions       *lifecycle.Transitions
 }
 
 type glueDatabase struct {
@@ -75,6 +94,10 @@ func New() *Service {
 	return &Service{
 		databases: make(map[string]*glueDatabase),
 		crawlers:  make(map[string]*glueCrawler),
+		sessions:  make(map[string]*session),
+		tags:      tags.New(),
+
+		statementHandlers: make(map[string]StatementFunc),
 	}
 }
 
@@ -83,7 +106,34 @@ func (s *Service) Name() string { return "glue" }
 
 // Handler returns the HTTP handler for Glue requests.
 func (s *Service) Handler() http.Handler {
-	return http.HandlerFunc(s.handle)
+	return h.JSONRouter{
+		"CreateDatabase": s.createDatabase,
+		"GetDatabase":    s.getDatabase,
+		"DeleteDatabase": s.deleteDatabase,
+		"GetDatabases":   s.getDatabases,
+		"CreateTable":    s.createTable,
+		"GetTable":       s.getTable,
+		"DeleteTable":    s.deleteTable,
+		"GetTables":      s.getTables,
+		"CreateCrawler":  s.createCrawler,
+		"GetCrawler":     s.getCrawler,
+		"DeleteCrawler":  s.deleteCrawler,
+		"StartCrawler":   s.startCrawler,
+		"ListCrawlers":   s.listCrawlers,
+		"TagResource":    s.tagResource,
+		"UntagResource":  s.untagResource,
+		"GetTags":        s.getTags,
+
+		"CreateSession":   s.createSession,
+		"GetSession":      s.getSession,
+		"ListSessions":    s.listSessions,
+		"StopSession":     s.stopSession,
+		"DeleteSession":   s.deleteSession,
+		"RunStatement":    s.runStatement,
+		"GetStatement":    s.getStatement,
+		"ListStatements":  s.listStatements,
+		"CancelStatement": s.cancelStatement,
+	}
 }
 
 // Reset clears all state.
@@ -92,63 +142,15 @@ func (s *Service) Reset() {
 	defer s.mu.Unlock()
 	s.databases = make(map[string]*glueDatabase)
 	s.crawlers = make(map[string]*glueCrawler)
+	s.sessions = make(map[string]*session)
+	s.tags.DeleteService("glue")
 }
 
-func (s *Service) handle(w http.ResponseWriter, r *http.Request) {
-	target := r.Header.Get("X-Amz-Target")
-
-	bodyBytes, err := io.ReadAll(r.Body)
-	if err != nil {
-		h.WriteJSONError(w, "InternalFailure", "could not read request body", http.StatusInternalServerError)
-		return
-	}
-
-	var params map[string]interface{}
-	if len(bodyBytes) > 0 {
-		json.Unmarshal(bodyBytes, &params)
-	}
-	if params == nil {
-		params = make(map[string]interface{})
-	}
-
-	action := ""
-	if target != "" {
-		parts := strings.SplitN(target, ".", 2)
-		if len(parts) == 2 {
-			action = parts[1]
-		}
-	}
-
-	switch action {
-	case "CreateDatabase":
-		s.createDatabase(w, params)
-	case "GetDatabase":
-		s.getDatabase(w, params)
-	case "DeleteDatabase":
-		s.deleteDatabase(w, params)
-	case "GetDatabases":
-		s.getDatabases(w, params)
-	case "CreateTable":
-		s.createTable(w, params)
-	case "GetTable":
-		s.getTable(w, params)
-	case "DeleteTable":
-		s.deleteTable(w, params)
-	case "GetTables":
-		s.getTables(w, params)
-	case "CreateCrawler":
-		s.createCrawler(w, params)
-	case "GetCrawler":
-		s.getCrawler(w, params)
-	case "DeleteCrawler":
-		s.deleteCrawler(w, params)
-	case "StartCrawler":
-		s.startCrawler(w, params)
-	case "ListCrawlers":
-		s.listCrawlers(w, params)
-	default:
-		h.WriteJSONError(w, "UnknownOperationException", fmt.Sprintf("action %q is not supported", action), http.StatusBadRequest)
-	}
+// SetTagStore sets the registry resource tags are recorded in.
+func (s *Service) SetTagStore(store *tags.Store) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tags = store
 }
 
 func (s *Service) createDatabase(w http.ResponseWriter, params map[string]interface{}) {
@@ -176,6 +178,7 @@ func (s *Service) createDatabase(w http.ResponseWriter, params map[string]interf
 		created:     time.Now().UTC(),
 		tables:      make(map[string]*glueTable),
 	}
+	s.tags.Tag(resourceARN("database", name), tags.FromMap(params["Tags"]))
 	s.mu.Unlock()
 
 	h.WriteJSON(w, http.StatusOK, map[string]interface{}{})
@@ -208,6 +211,7 @@ func (s *Service) deleteDatabase(w http.ResponseWriter, params map[string]interf
 		return
 	}
 	delete(s.databases, name)
+	s.tags.Delete(resourceARN("database", name))
 	s.mu.Unlock()
 
 	h.WriteJSON(w, http.StatusOK, map[string]interface{}{})
@@ -383,6 +387,7 @@ func (s *Service) createCrawler(w http.ResponseWriter, params map[string]interfa
 		state:   "READY",
 		created: time.Now().UTC(),
 	}
+	s.tags.Tag(resourceARN("crawler", name), tags.FromMap(params["Tags"]))
 	s.mu.Unlock()
 
 	h.WriteJSON(w, http.StatusOK, map[string]interface{}{})
@@ -415,6 +420,7 @@ func (s *Service) deleteCrawler(w http.ResponseWriter, params map[string]interfa
 		return
 	}
 	delete(s.crawlers, name)
+	s.tags.Delete(resourceARN("crawler", name))
 	s.mu.Unlock()
 
 	h.WriteJSON(w, http.StatusOK, map[string]interface{}{})
@@ -451,6 +457,77 @@ func (s *Service) listCrawlers(w http.ResponseWriter, _ map[string]interface{})
 	})
 }
 
+// resourceARN returns the ARN of the database, crawler, or session with the
+// given name.
+func resourceARN(kind, name string) string {
+	return fmt.Sprintf("arn:aws:glue:us-east-1:%s:%s/%s", h.DefaultAccountID, kind, name)
+}
+
+// hasResource reports whether arn names an existing database, crawler, or
+// session.
+// The caller must hold s.mu.
+func (s *Service) hasResource(arn string) bool {
+	for name := range s.databases {
+		if resourceARN("database", name) == arn {
+			return true
+		}
+	}
+	for name := range s.crawlers {
+		if resourceARN("crawler", name) == arn {
+			return true
+		}
+	}
+	for id := range s.sessions {
+		if resourceARN("session", id) == arn {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Service) tagResource(w http.ResponseWriter, params map[string]interface{}) {
+	arn := h.GetString(params, "ResourceArn")
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if !s.hasResource(arn) {
+		h.WriteJSONError(w, "EntityNotFoundException", "Resource not found: "+arn, http.StatusBadRequest)
+		return
+	}
+	s.tags.Tag(arn, tags.FromMap(params["TagsToAdd"]))
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{})
+}
+
+func (s *Service) untagResource(w http.ResponseWriter, params map[string]interface{}) {
+	arn := h.GetString(params, "ResourceArn")
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if !s.hasResource(arn) {
+		h.WriteJSONError(w, "EntityNotFoundException", "Resource not found: "+arn, http.StatusBadRequest)
+		return
+	}
+	s.tags.Untag(arn, tags.Keys(params["TagsToRemove"]))
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{})
+}
+
+func (s *Service) getTags(w http.ResponseWriter, params map[string]interface{}) {
+	arn := h.GetString(params, "ResourceArn")
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if !s.hasResource(arn) {
+		h.WriteJSONError(w, "EntityNotFoundException", "Resource not found: "+arn, http.StatusBadRequest)
+		return
+	}
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"Tags": s.tags.Get(arn),
+	})
+}
+
 func dbResp(db *glueDatabase) map[string]interface{} {
 	return map[string]interface{}{
 		"Name":        db.name,
@@ -0,0 +1,344 @@
+package glue
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/riyanimam/goto/internal/lifecycle"
+	h "github.com/riyanimam/goto/internal/mockhelpers"
+	"github.com/riyanimam/goto/internal/tags"
+)
+
+// StatementFunc runs the code of a statement submitted to an interactive
+// session and returns its text/plain output. A non-nil error fails the
+// statement, reporting the error as its ErrorValue.
+type StatementFunc func(code string) (string, error)
+
+// session is a Glue interactive session.
+type session struct {
+	id          string
+	role        string
+	description string
+	command     map[string]interface{}
+	glueVersion string
+	workerType  string
+	workers     int
+	idleTimeout int
+	created     time.Time
+	ready       lifecycle.Transition
+	stopped     bool
+	statements  []*statement
+}
+
+// statement is a block of code run in a session.
+type statement struct {
+	id        int
+	code      string
+	state     string // RUNNING, AVAILABLE, or CANCELLED
+	output    string
+	failed    bool
+	errValue  string
+	started   time.Time
+	completed time.Time
+}
+
+// SetTransitions sets how long new interactive sessions stay PROVISIONING.
+func (s *Service) SetTransitions(t *lifecycle.Transitions) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.transitions = t
+}
+
+// SetStatementHandler makes statements run in the interactive session with
+// the given ID return fn's output. Statements in sessions without a handler
+// succeed with no output. The handler applies to any session created with
+// that ID, and survives Reset.
+func (s *Service) SetStatementHandler(sessionID string, fn StatementFunc) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.statementHandlers[sessionID] = fn
+}
+
+// status returns the session's status. The caller must hold s.mu.
+func (s *Service) status(sess *session) string {
+	if sess.stopped {
+		return "STOPPED"
+	}
+	return s.transitions.Status(sess.ready, "PROVISIONING", "READY")
+}
+
+func (s *Service) createSession(w http.ResponseWriter, params map[string]interface{}) {
+	id := h.GetString(params, "Id")
+	role := h.GetString(params, "Role")
+	command, _ := params["Command"].(map[string]interface{})
+	switch {
+	case id == "":
+		h.WriteJSONError(w, "InvalidInputException", "Id is required", http.StatusBadRequest)
+		return
+	case role == "":
+		h.WriteJSONError(w, "InvalidInputException", "Role is required", http.StatusBadRequest)
+		return
+	case command == nil:
+		h.WriteJSONError(w, "InvalidInputException", "Command is required", http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, exists := s.sessions[id]; exists {
+		h.WriteJSONError(w, "AlreadyExistsException", "Session "+id+" already exists", http.StatusBadRequest)
+		return
+	}
+	sess := &session{
+		id:          id,
+		role:        role,
+		description: h.GetString(params, "Description"),
+		command:     command,
+		glueVersion: h.GetString(params, "GlueVersion"),
+		workerType:  h.GetString(params, "WorkerType"),
+		workers:     h.GetInt(params, "NumberOfWorkers", 5),
+		idleTimeout: h.GetInt(params, "IdleTimeout", 2880),
+		created:     time.Now().UTC(),
+		ready:       s.transitions.Begin(),
+	}
+	if sess.glueVersion == "" {
+		sess.glueVersion = "4.0"
+	}
+	if sess.workerType == "" {
+		sess.workerType = "G.1X"
+	}
+	s.sessions[id] = sess
+	s.tags.Tag(resourceARN("session", id), tags.FromMap(params["Tags"]))
+
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"Session": s.sessionResp(sess),
+	})
+}
+
+func (s *Service) getSession(w http.ResponseWriter, params map[string]interface{}) {
+	id := h.GetString(params, "Id")
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	sess, exists := s.sessions[id]
+	if !exists {
+		writeSessionNotFound(w, id)
+		return
+	}
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"Session": s.sessionResp(sess),
+	})
+}
+
+func (s *Service) listSessions(w http.ResponseWriter, _ map[string]interface{}) {
+	s.mu.RLock()
+	ids := make([]string, 0, len(s.sessions))
+	list := make([]map[string]interface{}, 0, len(s.sessions))
+	for id := range s.sessions {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		list = append(list, s.sessionResp(s.sessions[id]))
+	}
+	s.mu.RUnlock()
+
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"Ids":      ids,
+		"Sessions": list,
+	})
+}
+
+func (s *Service) stopSession(w http.ResponseWriter, params map[string]interface{}) {
+	id := h.GetString(params, "Id")
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sess, exists := s.sessions[id]
+	if !exists {
+		writeSessionNotFound(w, id)
+		return
+	}
+	sess.stopped = true
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{"Id": id})
+}
+
+func (s *Service) deleteSession(w http.ResponseWriter, params map[string]interface{}) {
+	id := h.GetString(params, "Id")
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, exists := s.sessions[id]; !exists {
+		writeSessionNotFound(w, id)
+		return
+	}
+	delete(s.sessions, id)
+	s.tags.Delete(resourceARN("session", id))
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{"Id": id})
+}
+
+func (s *Service) runStatement(w http.ResponseWriter, params map[string]interface{}) {
+	id := h.GetString(params, "SessionId")
+	code := h.GetString(params, "Code")
+	if code == "" {
+		h.WriteJSONError(w, "InvalidInputException", "Code is required", http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	sess, exists := s.sessions[id]
+	if !exists {
+		s.mu.Unlock()
+		writeSessionNotFound(w, id)
+		return
+	}
+	if status := s.status(sess); status != "READY" {
+		s.mu.Unlock()
+		h.WriteJSONError(w, "IllegalSessionStateException",
+			fmt.Sprintf("Session %s is %s, not READY", id, status), http.StatusBadRequest)
+		return
+	}
+	st := &statement{
+		id:      len(sess.statements),
+		code:    code,
+		state:   "RUNNING",
+		started: time.Now().UTC(),
+	}
+	sess.statements = append(sess.statements, st)
+	fn := s.statementHandlers[id]
+	s.mu.Unlock()
+
+	// Run the handler outside s.mu: it is test code and may call the mock.
+	var output string
+	var err error
+	if fn != nil {
+		output, err = fn(code)
+	}
+
+	s.mu.Lock()
+	st.completed = time.Now().UTC()
+	st.state, st.output = "AVAILABLE", output
+	if err != nil {
+		st.failed, st.errValue = true, err.Error()
+	}
+	s.mu.Unlock()
+
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{"Id": st.id})
+}
+
+// statementByID returns the statement the request identifies, writing an
+// error response if there is none. The caller must hold s.mu.
+func (s *Service) statementByID(w http.ResponseWriter, params map[string]interface{}) *statement {
+	id := h.GetString(params, "SessionId")
+	sess, exists := s.sessions[id]
+	if !exists {
+		writeSessionNotFound(w, id)
+		return nil
+	}
+	n := h.GetInt(params, "Id", -1)
+	if n < 0 || n >= len(sess.statements) {
+		h.WriteJSONError(w, "EntityNotFoundException",
+			fmt.Sprintf("Statement %d not found in session %s", n, id), http.StatusBadRequest)
+		return nil
+	}
+	return sess.statements[n]
+}
+
+func (s *Service) getStatement(w http.ResponseWriter, params map[string]interface{}) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if st := s.statementByID(w, params); st != nil {
+		h.WriteJSON(w, http.StatusOK, map[string]interface{}{
+			"Statement": st.toMap(),
+		})
+	}
+}
+
+func (s *Service) listStatements(w http.ResponseWriter, params map[string]interface{}) {
+	id := h.GetString(params, "SessionId")
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	sess, exists := s.sessions[id]
+	if !exists {
+		writeSessionNotFound(w, id)
+		return
+	}
+	list := make([]map[string]interface{}, 0, len(sess.statements))
+	for _, st := range sess.statements {
+		list = append(list, st.toMap())
+	}
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"Statements": list,
+	})
+}
+
+func (s *Service) cancelStatement(w http.ResponseWriter, params map[string]interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	st := s.statementByID(w, params)
+	if st == nil {
+		return
+	}
+	// Statements complete as soon as they run, so there is rarely anything
+	// left to cancel; a finished statement keeps its result.
+	if st.state == "RUNNING" {
+		st.state = "CANCELLED"
+		st.completed = time.Now().UTC()
+	}
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{})
+}
+
+func writeSessionNotFound(w http.ResponseWriter, id string) {
+	h.WriteJSONError(w, "EntityNotFoundException", "Session "+id+" not found", http.StatusBadRequest)
+}
+
+// sessionResp returns the Session structure of sess. The caller must hold
+// s.mu.
+func (s *Service) sessionResp(sess *session) map[string]interface{} {
+	return map[string]interface{}{
+		"Id":              sess.id,
+		"Role":            sess.role,
+		"Description":     sess.description,
+		"Command":         sess.command,
+		"GlueVersion":     sess.glueVersion,
+		"WorkerType":      sess.workerType,
+		"NumberOfWorkers": sess.workers,
+		"IdleTimeout":     sess.idleTimeout,
+		"MaxCapacity":     float64(sess.workers),
+		"Status":          s.status(sess),
+		"CreatedOn":       float64(sess.created.Unix()),
+	}
+}
+
+func (st *statement) toMap() map[string]interface{} {
+	// As in Livy, a statement whose code raised is still AVAILABLE; its
+	// output carries the error.
+	output := map[string]interface{}{
+		"ExecutionCount": st.id,
+		"Status":         st.state,
+	}
+	switch {
+	case st.failed:
+		output["Status"] = "ERROR"
+		output["ErrorName"] = "Error"
+		output["ErrorValue"] = st.errValue
+		output["Traceback"] = []string{}
+	case st.state == "AVAILABLE":
+		output["Data"] = map[string]interface{}{"TextPlain": st.output}
+	}
+	m := map[string]interface{}{
+		"Id":        st.id,
+		"Code":      st.code,
+		"State":     st.state,
+		"Output":    output,
+		"Progress":  1.0,
+		"StartedOn": st.started.UnixMilli(),
+	}
+	if !st.completed.IsZero() {
+		m["CompletedOn"] = st.completed.UnixMilli()
+	}
+	return m
+}
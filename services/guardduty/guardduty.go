@@ -6,6 +6,9 @@
 //   - DeleteDetector
 //   - ListDetectors
 //   - UpdateDetector
+//   - TagResource
+//   - UntagResource
+//   - ListTagsForResource
 package guardduty
 
 import (
@@ -18,12 +21,14 @@ import (
 	"time"
 
 	h "github.com/riyanimam/goto/internal/mockhelpers"
+	"github.com/riyanimam/goto/internal/tags"
 )
 
 // Service implements the GuardDuty mock.
 type Service struct {
 	mu        sync.RWMutex
 	detectors map[string]*detector
+	tags      *tags.Store
 }
 
 type detector struct {
@@ -39,6 +44,7 @@ type detector struct {
 func New() *Service {
 	return &Service{
 		detectors: make(map[string]*detector),
+		tags:      tags.New(),
 	}
 }
 
@@ -55,6 +61,14 @@ func (s *Service) Reset() {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	s.detectors = make(map[string]*detector)
+	s.tags.DeleteService("guardduty")
+}
+
+// SetTagStore sets the registry detector tags are recorded in.
+func (s *Service) SetTagStore(store *tags.Store) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tags = store
 }
 
 func (s *Service) handle(w http.ResponseWriter, r *http.Request) {
@@ -62,6 +76,10 @@ func (s *Service) handle(w http.ResponseWriter, r *http.Request) {
 	method := r.Method
 
 	switch {
+	// Tags: /tags/{arn}
+	case strings.HasPrefix(path, "/tags/"):
+		s.handleTags(w, r, strings.TrimPrefix(path, "/tags/"))
+
 	// Single detector: /detector/{detectorId}
 	case strings.HasPrefix(path, "/detector/") && method == http.MethodGet:
 		s.getDetector(w, r, path)
@@ -113,6 +131,7 @@ func (s *Service) createDetector(w http.ResponseWriter, r *http.Request) {
 
 	s.mu.Lock()
 	s.detectors[id] = d
+	s.tags.Tag(detectorArn(id), tags.FromMap(params["tags"]))
 	s.mu.Unlock()
 
 	h.WriteJSON(w, http.StatusOK, map[string]interface{}{
@@ -124,15 +143,14 @@ func (s *Service) getDetector(w http.ResponseWriter, _ *http.Request, path strin
 	id := extractDetectorID(path)
 
 	s.mu.RLock()
+	defer s.mu.RUnlock()
 	d, exists := s.detectors[id]
-	s.mu.RUnlock()
-
 	if !exists {
 		h.WriteJSONError(w, "BadRequestException", "The request is rejected because the input detectorId is not owned by the current account.", http.StatusBadRequest)
 		return
 	}
 
-	h.WriteJSON(w, http.StatusOK, detectorResp(d))
+	h.WriteJSON(w, http.StatusOK, s.detectorResp(d))
 }
 
 func (s *Service) deleteDetector(w http.ResponseWriter, _ *http.Request, path string) {
@@ -146,6 +164,7 @@ func (s *Service) deleteDetector(w http.ResponseWriter, _ *http.Request, path st
 		return
 	}
 	delete(s.detectors, id)
+	s.tags.Delete(detectorArn(id))
 	s.mu.Unlock()
 
 	h.WriteJSON(w, http.StatusOK, map[string]interface{}{})
@@ -188,12 +207,47 @@ func (s *Service) updateDetector(w http.ResponseWriter, r *http.Request, path st
 	h.WriteJSON(w, http.StatusOK, map[string]interface{}{})
 }
 
-func detectorResp(d *detector) map[string]interface{} {
+func detectorArn(id string) string {
+	return fmt.Sprintf("arn:aws:guardduty:us-east-1:%s:detector/%s", h.DefaultAccountID, id)
+}
+
+// detectorResp describes d. The caller must hold s.mu.
+func (s *Service) detectorResp(d *detector) map[string]interface{} {
 	return map[string]interface{}{
 		"createdAt":                  d.created.Format(time.RFC3339),
 		"updatedAt":                  d.updated.Format(time.RFC3339),
 		"status":                     d.status,
 		"findingPublishingFrequency": d.findingPublishingFrequency,
 		"serviceRole":                d.serviceRole,
+		"tags":                       s.tags.Get(detectorArn(d.id)),
+	}
+}
+
+// handleTags serves TagResource (POST), UntagResource (DELETE), and
+// ListTagsForResource (GET) on /tags/{arn}. Detectors are the taggable
+// resources.
+func (s *Service) handleTags(w http.ResponseWriter, r *http.Request, arn string) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	id, ok := strings.CutPrefix(arn, detectorArn(""))
+	if _, exists := s.detectors[id]; !ok || !exists {
+		h.WriteJSONError(w, "BadRequestException", "The request is rejected because the resource "+arn+" does not exist.", http.StatusBadRequest)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPost:
+		var params map[string]interface{}
+		bodyBytes, _ := io.ReadAll(r.Body)
+		json.Unmarshal(bodyBytes, &params)
+		s.tags.Tag(arn, tags.FromMap(params["tags"]))
+		w.WriteHeader(http.StatusNoContent)
+	case http.MethodDelete:
+		s.tags.Untag(arn, r.URL.Query()["tagKeys"])
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		h.WriteJSON(w, http.StatusOK, map[string]interface{}{
+			"tags": s.tags.Get(arn),
+		})
 	}
 }
@@ -15,6 +15,12 @@
 //   - ListPolicies
 //   - AttachRolePolicy
 //   - DetachRolePolicy
+//   - TagRole
+//   - UntagRole
+//   - ListRoleTags
+//   - TagUser
+//   - UntagUser
+//   - ListUserTags
 package iam
 
 import (
@@ -23,8 +29,14 @@ import (
 	"math/rand"
 	"net/http"
 	"sort"
+	"strconv"
 	"sync"
 	"time"
+
+	"github.com/riyanimam/goto/internal/awserr"
+	"github.com/riyanimam/goto/internal/mockhelpers"
+	"github.com/riyanimam/goto/internal/paginate"
+	"github.com/riyanimam/goto/internal/tags"
 )
 
 const defaultAccountID = "123456789012"
@@ -36,6 +48,7 @@ type Service struct {
 	roles        map[string]*role
 	policies     map[string]*policy
 	rolePolicies map[string]map[string]bool // roleArn -> set of policyArns
+	tags         *tags.Store
 }
 
 type user struct {
@@ -72,6 +85,7 @@ func New() *Service {
 		roles:        make(map[string]*role),
 		policies:     make(map[string]*policy),
 		rolePolicies: make(map[string]map[string]bool),
+		tags:         tags.New(),
 	}
 }
 
@@ -80,7 +94,31 @@ func (s *Service) Name() string { return "iam" }
 
 // Handler returns the HTTP handler for IAM requests.
 func (s *Service) Handler() http.Handler {
-	return http.HandlerFunc(s.handle)
+	return mockhelpers.QueryRouter{
+		Actions: map[string]http.HandlerFunc{
+			"CreateUser":       s.createUser,
+			"GetUser":          s.getUser,
+			"DeleteUser":       s.deleteUser,
+			"ListUsers":        s.listUsers,
+			"CreateRole":       s.createRole,
+			"GetRole":          s.getRole,
+			"DeleteRole":       s.deleteRole,
+			"ListRoles":        s.listRoles,
+			"CreatePolicy":     s.createPolicy,
+			"GetPolicy":        s.getPolicy,
+			"DeletePolicy":     s.deletePolicy,
+			"ListPolicies":     s.listPolicies,
+			"AttachRolePolicy": s.attachRolePolicy,
+			"DetachRolePolicy": s.detachRolePolicy,
+			"TagRole":          s.roleTags(s.tagEntity),
+			"UntagRole":        s.roleTags(s.untagEntity),
+			"ListRoleTags":     s.roleTags(s.listEntityTags),
+			"TagUser":          s.userTags(s.tagEntity),
+			"UntagUser":        s.userTags(s.untagEntity),
+			"ListUserTags":     s.userTags(s.listEntityTags),
+		},
+		Error: writeIAMError,
+	}
 }
 
 // Reset clears all state.
@@ -91,47 +129,49 @@ func (s *Service) Reset() {
 	s.roles = make(map[string]*role)
 	s.policies = make(map[string]*policy)
 	s.rolePolicies = make(map[string]map[string]bool)
+	s.tags.DeleteService("iam")
 }
 
-func (s *Service) handle(w http.ResponseWriter, r *http.Request) {
-	if err := r.ParseForm(); err != nil {
-		writeIAMError(w, "InvalidInput", "could not parse request", http.StatusBadRequest)
-		return
+// SetTagStore sets the registry user and role tags are recorded in.
+func (s *Service) SetTagStore(store *tags.Store) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tags = store
+}
+
+// HasResource reports whether the user, role, or policy identified by arn
+// exists.
+func (s *Service) HasResource(arn string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, u := range s.users {
+		if u.arn == arn {
+			return true
+		}
+	}
+	for _, r := range s.roles {
+		if r.arn == arn {
+			return true
+		}
+	}
+	for _, p := range s.policies {
+		if p.arn == arn {
+			return true
+		}
 	}
+	return false
+}
 
-	action := r.FormValue("Action")
-	switch action {
-	case "CreateUser":
-		s.createUser(w, r)
-	case "GetUser":
-		s.getUser(w, r)
-	case "DeleteUser":
-		s.deleteUser(w, r)
-	case "ListUsers":
-		s.listUsers(w, r)
-	case "CreateRole":
-		s.createRole(w, r)
-	case "GetRole":
-		s.getRole(w, r)
-	case "DeleteRole":
-		s.deleteRole(w, r)
-	case "ListRoles":
-		s.listRoles(w, r)
-	case "CreatePolicy":
-		s.createPolicy(w, r)
-	case "GetPolicy":
-		s.getPolicy(w, r)
-	case "DeletePolicy":
-		s.deletePolicy(w, r)
-	case "ListPolicies":
-		s.listPolicies(w, r)
-	case "AttachRolePolicy":
-		s.attachRolePolicy(w, r)
-	case "DetachRolePolicy":
-		s.detachRolePolicy(w, r)
-	default:
-		writeIAMError(w, "InvalidAction", fmt.Sprintf("action %q is not supported", action), http.StatusBadRequest)
+// RoleCreated returns when the role with the given ARN was created.
+func (s *Service) RoleCreated(roleArn string) (time.Time, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, rl := range s.roles {
+		if rl.arn == roleArn {
+			return rl.created, true
+		}
 	}
+	return time.Time{}, false
 }
 
 func (s *Service) createUser(w http.ResponseWriter, r *http.Request) {
@@ -156,6 +196,7 @@ func (s *Service) createUser(w http.ResponseWriter, r *http.Request) {
 		created: time.Now().UTC(),
 	}
 	s.users[name] = u
+	s.tags.Tag(u.arn, tags.FromForm(r.Form, "Tags.member."))
 	s.mu.Unlock()
 
 	resp := createUserResponse{
@@ -177,8 +218,10 @@ func (s *Service) getUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	x := userXML(u)
+	x.Tags = tagMembers(s.tags.Get(u.arn))
 	resp := getUserResponse{
-		Result:    getUserResult{User: userXML(u)},
+		Result:    getUserResult{User: x},
 		RequestID: newRequestID(),
 	}
 	writeXML(w, http.StatusOK, resp)
@@ -193,6 +236,7 @@ func (s *Service) deleteUser(w http.ResponseWriter, r *http.Request) {
 		writeIAMError(w, "NoSuchEntity", "The user with name "+name+" cannot be found.", http.StatusNotFound)
 		return
 	}
+	s.tags.Delete(s.users[name].arn)
 	delete(s.users, name)
 	s.mu.Unlock()
 
@@ -200,7 +244,7 @@ func (s *Service) deleteUser(w http.ResponseWriter, r *http.Request) {
 	writeXML(w, http.StatusOK, resp)
 }
 
-func (s *Service) listUsers(w http.ResponseWriter, _ *http.Request) {
+func (s *Service) listUsers(w http.ResponseWriter, r *http.Request) {
 	s.mu.RLock()
 	var members []iamUser
 	for _, u := range s.users {
@@ -212,8 +256,12 @@ func (s *Service) listUsers(w http.ResponseWriter, _ *http.Request) {
 		return members[i].UserName < members[j].UserName
 	})
 
+	page, marker, ok := pageMembers(w, r, members, func(i int) string { return members[i].UserName })
+	if !ok {
+		return
+	}
 	resp := listUsersResponse{
-		Result:    listUsersResult{Users: members, IsTruncated: false},
+		Result:    listUsersResult{Users: page, IsTruncated: marker != "", Marker: marker},
 		RequestID: newRequestID(),
 	}
 	writeXML(w, http.StatusOK, resp)
@@ -245,6 +293,7 @@ func (s *Service) createRole(w http.ResponseWriter, r *http.Request) {
 		created:             time.Now().UTC(),
 	}
 	s.roles[name] = rl
+	s.tags.Tag(rl.arn, tags.FromForm(r.Form, "Tags.member."))
 	s.mu.Unlock()
 
 	resp := createRoleResponse{
@@ -266,8 +315,10 @@ func (s *Service) getRole(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	x := roleXML(rl)
+	x.Tags = tagMembers(s.tags.Get(rl.arn))
 	resp := getRoleResponse{
-		Result:    getRoleResult{Role: roleXML(rl)},
+		Result:    getRoleResult{Role: x},
 		RequestID: newRequestID(),
 	}
 	writeXML(w, http.StatusOK, resp)
@@ -282,6 +333,7 @@ func (s *Service) deleteRole(w http.ResponseWriter, r *http.Request) {
 		writeIAMError(w, "NoSuchEntity", "The role with name "+name+" cannot be found.", http.StatusNotFound)
 		return
 	}
+	s.tags.Delete(s.roles[name].arn)
 	delete(s.roles, name)
 	s.mu.Unlock()
 
@@ -289,7 +341,7 @@ func (s *Service) deleteRole(w http.ResponseWriter, r *http.Request) {
 	writeXML(w, http.StatusOK, resp)
 }
 
-func (s *Service) listRoles(w http.ResponseWriter, _ *http.Request) {
+func (s *Service) listRoles(w http.ResponseWriter, r *http.Request) {
 	s.mu.RLock()
 	var members []iamRole
 	for _, rl := range s.roles {
@@ -301,8 +353,12 @@ func (s *Service) listRoles(w http.ResponseWriter, _ *http.Request) {
 		return members[i].RoleName < members[j].RoleName
 	})
 
+	page, marker, ok := pageMembers(w, r, members, func(i int) string { return members[i].RoleName })
+	if !ok {
+		return
+	}
 	resp := listRolesResponse{
-		Result:    listRolesResult{Roles: members, IsTruncated: false},
+		Result:    listRolesResult{Roles: page, IsTruncated: marker != "", Marker: marker},
 		RequestID: newRequestID(),
 	}
 	writeXML(w, http.StatusOK, resp)
@@ -438,8 +494,84 @@ func (s *Service) detachRolePolicy(w http.ResponseWriter, r *http.Request) {
 	writeXML(w, http.StatusOK, resp)
 }
 
+// roleTags returns a handler that applies op to the tags of the role named
+// in the request.
+func (s *Service) roleTags(op func(w http.ResponseWriter, r *http.Request, arn string)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		name := r.FormValue("RoleName")
+		s.mu.RLock()
+		rl, exists := s.roles[name]
+		s.mu.RUnlock()
+		if !exists {
+			writeIAMError(w, "NoSuchEntity", "The role with name "+name+" cannot be found.", http.StatusNotFound)
+			return
+		}
+		op(w, r, rl.arn)
+	}
+}
+
+// userTags returns a handler that applies op to the tags of the user named
+// in the request.
+func (s *Service) userTags(op func(w http.ResponseWriter, r *http.Request, arn string)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		name := r.FormValue("UserName")
+		s.mu.RLock()
+		u, exists := s.users[name]
+		s.mu.RUnlock()
+		if !exists {
+			writeIAMError(w, "NoSuchEntity", "The user with name "+name+" cannot be found.", http.StatusNotFound)
+			return
+		}
+		op(w, r, u.arn)
+	}
+}
+
+// tagEntity serves TagRole and TagUser on the entity with the given ARN.
+func (s *Service) tagEntity(w http.ResponseWriter, r *http.Request, arn string) {
+	s.tags.Tag(arn, tags.FromForm(r.Form, "Tags.member."))
+	writeTagAction(w, r.FormValue("Action"))
+}
+
+// untagEntity serves UntagRole and UntagUser on the entity with the given
+// ARN.
+func (s *Service) untagEntity(w http.ResponseWriter, r *http.Request, arn string) {
+	s.tags.Untag(arn, tags.KeysFromForm(r.Form, "TagKeys.member."))
+	writeTagAction(w, r.FormValue("Action"))
+}
+
+// listEntityTags serves ListRoleTags and ListUserTags on the entity with the
+// given ARN.
+func (s *Service) listEntityTags(w http.ResponseWriter, r *http.Request, arn string) {
+	action := r.FormValue("Action")
+	writeXML(w, http.StatusOK, listTagsResponse{
+		XMLName:   xml.Name{Local: action + "Response"},
+		Result:    listTagsResult{XMLName: xml.Name{Local: action + "Result"}, Tags: tagMembers(s.tags.Get(arn))},
+		RequestID: newRequestID(),
+	})
+}
+
+func writeTagAction(w http.ResponseWriter, action string) {
+	writeXML(w, http.StatusOK, tagActionResponse{
+		XMLName:   xml.Name{Local: action + "Response"},
+		RequestID: newRequestID(),
+	})
+}
+
 // XML type helpers.
 
+func tagMembers(m map[string]string) []iamTag {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	members := make([]iamTag, 0, len(keys))
+	for _, k := range keys {
+		members = append(members, iamTag{Key: k, Value: m[k]})
+	}
+	return members
+}
+
 func userXML(u *user) iamUser {
 	return iamUser{
 		UserName:   u.name,
@@ -475,21 +607,28 @@ func policyXML(p *policy) iamPolicy {
 // XML response types.
 
 type iamUser struct {
-	UserName   string `xml:"UserName"`
-	UserId     string `xml:"UserId"`
-	Arn        string `xml:"Arn"`
-	Path       string `xml:"Path"`
-	CreateDate string `xml:"CreateDate"`
+	UserName   string   `xml:"UserName"`
+	UserId     string   `xml:"UserId"`
+	Arn        string   `xml:"Arn"`
+	Path       string   `xml:"Path"`
+	CreateDate string   `xml:"CreateDate"`
+	Tags       []iamTag `xml:"Tags>member,omitempty"`
 }
 
 type iamRole struct {
-	RoleName                 string `xml:"RoleName"`
-	RoleId                   string `xml:"RoleId"`
-	Arn                      string `xml:"Arn"`
-	Path                     string `xml:"Path"`
-	AssumeRolePolicyDocument string `xml:"AssumeRolePolicyDocument"`
-	Description              string `xml:"Description"`
-	CreateDate               string `xml:"CreateDate"`
+	RoleName                 string   `xml:"RoleName"`
+	RoleId                   string   `xml:"RoleId"`
+	Arn                      string   `xml:"Arn"`
+	Path                     string   `xml:"Path"`
+	AssumeRolePolicyDocument string   `xml:"AssumeRolePolicyDocument"`
+	Description              string   `xml:"Description"`
+	CreateDate               string   `xml:"CreateDate"`
+	Tags                     []iamTag `xml:"Tags>member,omitempty"`
+}
+
+type iamTag struct {
+	Key   string `xml:"Key"`
+	Value string `xml:"Value"`
 }
 
 type iamPolicy struct {
@@ -535,6 +674,7 @@ type listUsersResponse struct {
 type listUsersResult struct {
 	Users       []iamUser `xml:"Users>member"`
 	IsTruncated bool      `xml:"IsTruncated"`
+	Marker      string    `xml:"Marker,omitempty"`
 }
 
 type createRoleResponse struct {
@@ -572,6 +712,7 @@ type listRolesResponse struct {
 type listRolesResult struct {
 	Roles       []iamRole `xml:"Roles>member"`
 	IsTruncated bool      `xml:"IsTruncated"`
+	Marker      string    `xml:"Marker,omitempty"`
 }
 
 type createPolicyResponse struct {
@@ -623,16 +764,25 @@ type detachRolePolicyResponse struct {
 	RequestID string   `xml:"ResponseMetadata>RequestId"`
 }
 
-type iamErrorResponse struct {
-	XMLName   xml.Name `xml:"ErrorResponse"`
-	Error     iamError `xml:"Error"`
-	RequestID string   `xml:"RequestId"`
+// tagActionResponse is the empty response of the Tag* and Untag* actions;
+// XMLName is set to the action's response element.
+type tagActionResponse struct {
+	XMLName   xml.Name
+	XMLNS     string `xml:"xmlns,attr"`
+	RequestID string `xml:"ResponseMetadata>RequestId"`
 }
 
-type iamError struct {
-	Type    string `xml:"Type"`
-	Code    string `xml:"Code"`
-	Message string `xml:"Message"`
+// listTagsResponse is the response of ListRoleTags and ListUserTags.
+type listTagsResponse struct {
+	XMLName   xml.Name
+	XMLNS     string `xml:"xmlns,attr"`
+	Result    listTagsResult
+	RequestID string `xml:"ResponseMetadata>RequestId"`
+}
+type listTagsResult struct {
+	XMLName     xml.Name
+	Tags        []iamTag `xml:"Tags>member"`
+	IsTruncated bool     `xml:"IsTruncated"`
 }
 
 // Helper functions.
@@ -644,16 +794,24 @@ func writeXML(w http.ResponseWriter, status int, v interface{}) {
 	xml.NewEncoder(w).Encode(v)
 }
 
-func writeIAMError(w http.ResponseWriter, code, message string, status int) {
-	resp := iamErrorResponse{
-		Error: iamError{
-			Type:    "Sender",
-			Code:    code,
-			Message: message,
-		},
-		RequestID: newRequestID(),
+// pageMembers returns the page of a listing sorted by key selected by the
+// request's Marker and MaxItems. It writes an error and returns false if the
+// marker is invalid.
+func pageMembers[T any](w http.ResponseWriter, r *http.Request, members []T, key func(i int) string) ([]T, string, bool) {
+	limit, _ := strconv.Atoi(r.FormValue("MaxItems"))
+	if limit <= 0 {
+		limit = 100
+	}
+	page, marker, err := paginate.Page(members, key, r.FormValue("Marker"), limit, 1000)
+	if err != nil {
+		writeIAMError(w, "InvalidInput", "Invalid Marker.", http.StatusBadRequest)
+		return nil, "", false
 	}
-	writeXML(w, status, resp)
+	return page, marker, true
+}
+
+func writeIAMError(w http.ResponseWriter, code, message string, status int) {
+	awserr.WriteQuery(w, "", code, message, status)
 }
 
 func newRequestID() string {
@@ -0,0 +1,59 @@
+package iam
+
+import (
+	"sort"
+
+	"github.com/riyanimam/goto/internal/mockhelpers"
+)
+
+// ExportState lists the users, roles, and managed policies, with the
+// policies attached to each role.
+func (s *Service) ExportState() []mockhelpers.Resource {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	resources := make([]mockhelpers.Resource, 0, len(s.users)+len(s.roles)+len(s.policies))
+	for _, u := range s.users {
+		resources = append(resources, mockhelpers.Resource{
+			Type: "aws_iam_user",
+			ID:   u.name,
+			ARN:  u.arn,
+			Attributes: map[string]interface{}{
+				"name": u.name,
+				"path": u.path,
+			},
+		})
+	}
+	for _, rl := range s.roles {
+		attached := make([]string, 0, len(s.rolePolicies[rl.arn]))
+		for policyArn := range s.rolePolicies[rl.arn] {
+			attached = append(attached, policyArn)
+		}
+		sort.Strings(attached)
+		resources = append(resources, mockhelpers.Resource{
+			Type: "aws_iam_role",
+			ID:   rl.name,
+			ARN:  rl.arn,
+			Attributes: map[string]interface{}{
+				"name":                rl.name,
+				"path":                rl.path,
+				"description":         rl.description,
+				"assume_role_policy":  rl.assumeRolePolicyDoc,
+				"managed_policy_arns": attached,
+			},
+		})
+	}
+	for _, p := range s.policies {
+		resources = append(resources, mockhelpers.Resource{
+			Type: "aws_iam_policy",
+			ID:   p.arn,
+			ARN:  p.arn,
+			Attributes: map[string]interface{}{
+				"name":   p.name,
+				"path":   p.path,
+				"policy": p.document,
+			},
+		})
+	}
+	return resources
+}
@@ -0,0 +1,319 @@
+// Package imds provides a mock of the EC2 instance metadata service, as
+// seen from inside an instance.
+//
+// Supported paths:
+//   - PUT /latest/api/token
+//   - GET /latest/meta-data/ and the instance's ID, type, AMI, hostname,
+//     address, and placement beneath it
+//   - GET /latest/meta-data/iam/info
+//   - GET /latest/meta-data/iam/security-credentials/ and
+//     /latest/meta-data/iam/security-credentials/{role}
+//   - GET /latest/dynamic/instance-identity/document
+//
+// Role credentials are issued by the STS mock for the instance's role, so
+// code using the default credential chain with no keys configured gets
+// mock credentials once AWS_EC2_METADATA_SERVICE_ENDPOINT points at the
+// mock. Session tokens expire on the mock clock; credentials are reissued
+// shortly before the ones STS issued expire.
+package imds
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/riyanimam/goto/internal/clock"
+	h "github.com/riyanimam/goto/internal/mockhelpers"
+)
+
+const (
+	tokenHeader    = "X-Aws-Ec2-Metadata-Token"
+	tokenTTLHeader = "X-Aws-Ec2-Metadata-Token-Ttl-Seconds"
+	maxTokenTTL    = 6 * time.Hour
+
+	credentialLifetime = 6 * time.Hour
+	// credentialRefresh is how long before they expire credentials are
+	// replaced, as the instance metadata service does.
+	credentialRefresh = 15 * time.Minute
+)
+
+// Instance describes the instance the metadata service reports on.
+type Instance struct {
+	// RoleName is the role of the instance profile. Without one the
+	// instance has no credentials.
+	RoleName string
+	// InstanceID defaults to a generated ID, and InstanceType to
+	// "t3.micro".
+	InstanceID   string
+	InstanceType string
+	// AvailabilityZone defaults to "us-east-1a"; the region is the zone
+	// without its letter.
+	AvailabilityZone string
+	// RequireToken rejects requests without a session token, as instances
+	// that require IMDSv2 do.
+	RequireToken bool
+}
+
+// Service implements the instance metadata mock.
+type Service struct {
+	mu       sync.RWMutex
+	instance Instance
+	tokens   map[string]time.Time // session token -> expiry
+	creds    *h.Credentials
+	issued   time.Time
+
+	issuer h.CredentialIssuer
+	clock  *clock.Clock
+}
+
+// New creates a new instance metadata mock service.
+func New() *Service {
+	return &Service{
+		instance: defaultInstance(Instance{}),
+		tokens:   make(map[string]time.Time),
+	}
+}
+
+func defaultInstance(i Instance) Instance {
+	if i.InstanceID == "" {
+		i.InstanceID = "i-" + strings.ToLower(h.RandomHex(17))
+	}
+	if i.InstanceType == "" {
+		i.InstanceType = "t3.micro"
+	}
+	if i.AvailabilityZone == "" {
+		i.AvailabilityZone = "us-east-1a"
+	}
+	return i
+}
+
+// Name returns the service identifier.
+func (s *Service) Name() string { return "imds" }
+
+// Handler returns the HTTP handler for instance metadata requests.
+func (s *Service) Handler() http.Handler {
+	return http.HandlerFunc(s.handle)
+}
+
+// Reset clears all state, leaving an instance without a role.
+func (s *Service) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.instance = defaultInstance(Instance{})
+	s.tokens = make(map[string]time.Time)
+	s.creds = nil
+}
+
+// SetCredentialIssuer sets how role credentials are issued.
+func (s *Service) SetCredentialIssuer(c h.CredentialIssuer) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.issuer = c
+}
+
+// SetClock attaches the mock clock that session tokens expire on.
+func (s *Service) SetClock(c *clock.Clock) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.clock = c
+}
+
+func (s *Service) now() time.Time {
+	if s.clock != nil {
+		return s.clock.Now()
+	}
+	return time.Now().UTC()
+}
+
+// SetInstance sets the instance the metadata service reports on, filling
+// in the defaults noted on [Instance]. Credentials issued for the previous
+// instance are discarded; session tokens remain valid.
+func (s *Service) SetInstance(i Instance) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.instance = defaultInstance(i)
+	s.creds = nil
+}
+
+func (s *Service) handle(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == "/latest/api/token" {
+		s.createToken(w, r)
+		return
+	}
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	s.mu.Lock()
+	token := r.Header.Get(tokenHeader)
+	expires, ok := s.tokens[token]
+	if token != "" && (!ok || !s.now().Before(expires)) {
+		delete(s.tokens, token)
+		s.mu.Unlock()
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if token == "" && s.instance.RequireToken {
+		s.mu.Unlock()
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	instance := s.instance
+	s.mu.Unlock()
+
+	if role, ok := strings.CutPrefix(r.URL.Path, "/latest/meta-data/iam/security-credentials/"); ok && role != "" {
+		s.securityCredentials(w, instance, role)
+		return
+	}
+	body, ok := metadata(instance)[strings.TrimPrefix(r.URL.Path, "/latest/")]
+	if !ok {
+		http.Error(w, "Not Found", http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain")
+	fmt.Fprint(w, body)
+}
+
+// createToken starts a session, whose token the other paths accept until
+// its TTL elapses.
+func (s *Service) createToken(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	// Tokens cannot be requested through a proxy.
+	if r.Header.Get("X-Forwarded-For") != "" {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+	seconds, err := strconv.Atoi(r.Header.Get(tokenTTLHeader))
+	if err != nil || seconds < 1 || time.Duration(seconds)*time.Second > maxTokenTTL {
+		http.Error(w, "Bad Request", http.StatusBadRequest)
+		return
+	}
+
+	token := h.RandomHex(56)
+	s.mu.Lock()
+	s.tokens[token] = s.now().Add(time.Duration(seconds) * time.Second)
+	s.mu.Unlock()
+	w.Header().Set("Content-Type", "text/plain")
+	w.Header().Set(tokenTTLHeader, strconv.Itoa(seconds))
+	fmt.Fprint(w, token)
+}
+
+// metadata returns the instance's metadata by path under /latest/,
+// including the directory listings of paths ending in "/".
+func metadata(i Instance) map[string]string {
+	region := i.AvailabilityZone[:len(i.AvailabilityZone)-1]
+	address := "10.0.0.10"
+	hostname := "ip-10-0-0-10.ec2.internal"
+	values := map[string]string{
+		"meta-data/ami-id":                      "ami-0abcdef1234567890",
+		"meta-data/instance-id":                 i.InstanceID,
+		"meta-data/instance-type":               i.InstanceType,
+		"meta-data/local-hostname":              hostname,
+		"meta-data/local-ipv4":                  address,
+		"meta-data/placement/availability-zone": i.AvailabilityZone,
+		"meta-data/placement/region":            region,
+	}
+	if i.RoleName != "" {
+		info, _ := json.MarshalIndent(map[string]string{
+			"Code":               "Success",
+			"LastUpdated":        time.Now().UTC().Format(time.RFC3339),
+			"InstanceProfileArn": "arn:aws:iam::" + h.DefaultAccountID + ":instance-profile/" + i.RoleName,
+			"InstanceProfileId":  "AIPA" + strings.ToUpper(strings.TrimPrefix(i.InstanceID, "i-")),
+		}, "", "  ")
+		values["meta-data/iam/info"] = string(info)
+		values["meta-data/iam/security-credentials/"] = i.RoleName
+	}
+	document, _ := json.MarshalIndent(map[string]interface{}{
+		"accountId":        h.DefaultAccountID,
+		"architecture":     "x86_64",
+		"availabilityZone": i.AvailabilityZone,
+		"imageId":          values["meta-data/ami-id"],
+		"instanceId":       i.InstanceID,
+		"instanceType":     i.InstanceType,
+		"privateIp":        address,
+		"region":           region,
+		"version":          "2017-09-30",
+	}, "", "  ")
+	values["dynamic/instance-identity/document"] = string(document)
+
+	// Each directory lists its entries, subdirectories with a trailing
+	// slash.
+	dirs := map[string]map[string]bool{}
+	for path := range values {
+		for {
+			idx := strings.LastIndexByte(strings.TrimSuffix(path, "/"), '/')
+			if idx < 0 {
+				break
+			}
+			dir, entry := path[:idx+1], path[idx+1:]
+			if dirs[dir] == nil {
+				dirs[dir] = map[string]bool{}
+			}
+			dirs[dir][entry] = true
+			path = dir
+		}
+	}
+	for dir, entries := range dirs {
+		if _, ok := values[dir]; ok {
+			continue
+		}
+		list := make([]string, 0, len(entries))
+		for entry := range entries {
+			list = append(list, entry)
+		}
+		sort.Strings(list)
+		values[dir] = strings.Join(list, "\n")
+	}
+	return values
+}
+
+// securityCredentials serves the credentials of the instance's role,
+// issuing new ones once those held are about to expire.
+func (s *Service) securityCredentials(w http.ResponseWriter, i Instance, role string) {
+	if role != i.RoleName {
+		http.Error(w, "Not Found", http.StatusNotFound)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.creds == nil || time.Until(s.creds.Expiration) < credentialRefresh {
+		if s.issuer == nil {
+			http.Error(w, "Not Found", http.StatusNotFound)
+			return
+		}
+		creds, err := s.issuer.IssueCredentials("arn:aws:iam::"+h.DefaultAccountID+":role/"+role, credentialLifetime)
+		if err != nil {
+			body, _ := json.MarshalIndent(map[string]string{
+				"Code":        "AssumeRoleUnauthorizedAccess",
+				"Message":     err.Error(),
+				"LastUpdated": time.Now().UTC().Format(time.RFC3339),
+			}, "", "  ")
+			w.Header().Set("Content-Type", "text/plain")
+			w.Write(body)
+			return
+		}
+		s.creds = &creds
+		s.issued = time.Now().UTC()
+	}
+	body, _ := json.MarshalIndent(map[string]string{
+		"Code":            "Success",
+		"LastUpdated":     s.issued.Format(time.RFC3339),
+		"Type":            "AWS-HMAC",
+		"AccessKeyId":     s.creds.AccessKeyID,
+		"SecretAccessKey": s.creds.SecretAccessKey,
+		"Token":           s.creds.SessionToken,
+		"Expiration":      s.creds.Expiration.Format(time.RFC3339),
+	}, "", "  ")
+	w.Header().Set("Content-Type", "text/plain")
+	w.Write(body)
+}
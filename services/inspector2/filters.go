@@ -0,0 +1,158 @@
+package inspector2
+
+import (
+	"fmt"
+	"strings"
+)
+
+// findingFields maps the FilterCriteria fields ListFindings supports to the
+// finding fields they filter on, as dotted paths. Arrays along a path are
+// flattened, so "resources.id" is the ID of every resource of a finding.
+var findingFields = map[string]string{
+	"findingArn":             "findingArn",
+	"awsAccountId":           "awsAccountId",
+	"findingType":            "type",
+	"findingStatus":          "status",
+	"severity":               "severity",
+	"title":                  "title",
+	"fixAvailable":           "fixAvailable",
+	"exploitAvailable":       "exploitAvailable",
+	"inspectorScore":         "inspectorScore",
+	"firstObservedAt":        "firstObservedAt",
+	"lastObservedAt":         "lastObservedAt",
+	"updatedAt":              "updatedAt",
+	"resourceType":           "resources.type",
+	"resourceId":             "resources.id",
+	"ecrImageRepositoryName": "resources.details.awsEcrContainerImage.repositoryName",
+	"ecrImageTags":           "resources.details.awsEcrContainerImage.imageTags",
+	"ecrImageHash":           "resources.details.awsEcrContainerImage.imageHash",
+	"ec2InstanceImageId":     "resources.details.awsEc2Instance.imageId",
+	"ec2InstanceSubnetId":    "resources.details.awsEc2Instance.subnetId",
+	"lambdaFunctionName":     "resources.details.awsLambdaFunction.functionName",
+	"lambdaFunctionRuntime":  "resources.details.awsLambdaFunction.runtime",
+	"vulnerabilityId":        "packageVulnerabilityDetails.vulnerabilityId",
+	"vulnerabilitySource":    "packageVulnerabilityDetails.source",
+}
+
+// coverageFields maps the CoverageFilterCriteria fields ListCoverage supports
+// to the covered resource fields they filter on.
+var coverageFields = map[string]string{
+	"accountId":          "accountId",
+	"resourceId":         "resourceId",
+	"resourceType":       "resourceType",
+	"scanType":           "scanType",
+	"scanStatusCode":     "scanStatus.statusCode",
+	"scanStatusReason":   "scanStatus.reason",
+	"lastScannedAt":      "lastScannedAt",
+	"ecrRepositoryName":  "resourceMetadata.ecrRepository.name",
+	"ecrImageTags":       "resourceMetadata.ecrImage.tags",
+	"ec2InstanceTags":    "resourceMetadata.ec2.tags",
+	"lambdaFunctionName": "resourceMetadata.lambdaFunction.functionName",
+}
+
+// fieldValues returns the values at path in data, flattening arrays.
+func fieldValues(data interface{}, path string) []interface{} {
+	current := []interface{}{data}
+	for _, part := range strings.Split(path, ".") {
+		var next []interface{}
+		for _, v := range current {
+			m, ok := v.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			switch child := m[part].(type) {
+			case nil:
+			case []interface{}:
+				next = append(next, child...)
+			case []string:
+				for _, c := range child {
+					next = append(next, c)
+				}
+			case []map[string]interface{}:
+				for _, c := range child {
+					next = append(next, c)
+				}
+			default:
+				next = append(next, child)
+			}
+		}
+		current = next
+	}
+	return current
+}
+
+// matchFilters reports whether data matches every field of filters, whose
+// names fields maps to paths in data; unknown fields are ignored. Within a
+// field, positive string filters (EQUALS, PREFIX) match if any of them does,
+// while NOT_EQUALS filters must all match; number and date range filters on
+// a field match if any of them does.
+func matchFilters(data map[string]interface{}, filters map[string]interface{}, fields map[string]string) bool {
+	for field, list := range filters {
+		path, known := fields[field]
+		conditions, _ := list.([]interface{})
+		if !known || len(conditions) == 0 {
+			continue
+		}
+		values := fieldValues(data, path)
+		anyPositive, positiveMatched := false, false
+		for _, c := range conditions {
+			cond, _ := c.(map[string]interface{})
+			negative, ok := matchCondition(values, cond)
+			if negative {
+				if !ok {
+					return false
+				}
+				continue
+			}
+			anyPositive = true
+			positiveMatched = positiveMatched || ok
+		}
+		if anyPositive && !positiveMatched {
+			return false
+		}
+	}
+	return true
+}
+
+// matchCondition evaluates one filter condition against a field's values,
+// reporting whether it is a negative condition and whether it matched.
+func matchCondition(values []interface{}, cond map[string]interface{}) (negative, ok bool) {
+	switch {
+	case cond["comparison"] != nil:
+		comparison, _ := cond["comparison"].(string)
+		want, _ := cond["value"].(string)
+		return matchString(values, comparison, want)
+	case cond["startInclusive"] != nil || cond["endInclusive"] != nil:
+		return false, matchRange(values, cond["startInclusive"], cond["endInclusive"])
+	default:
+		return false, matchRange(values, cond["lowerInclusive"], cond["upperInclusive"])
+	}
+}
+
+// matchString applies a string comparison to a field's values. Positive
+// comparisons match if any value does; NOT_EQUALS if no value is equal.
+func matchString(values []interface{}, comparison, want string) (negative, ok bool) {
+	negative = comparison == "NOT_EQUALS"
+	for _, v := range values {
+		got := fmt.Sprint(v)
+		if (comparison == "PREFIX" && strings.HasPrefix(got, want)) || (comparison != "PREFIX" && got == want) {
+			return negative, !negative
+		}
+	}
+	return negative, negative
+}
+
+// matchRange reports whether any number among values, including timestamps
+// as epoch seconds, lies within the inclusive bounds that are set.
+func matchRange(values []interface{}, lower, upper interface{}) bool {
+	lo, hasLower := lower.(float64)
+	hi, hasUpper := upper.(float64)
+	for _, v := range values {
+		n, isNumber := v.(float64)
+		if !isNumber || (hasLower && n < lo) || (hasUpper && n > hi) {
+			continue
+		}
+		return true
+	}
+	return false
+}
@@ -0,0 +1,636 @@
+// Package inspector2 provides a mock implementation of Amazon Inspector.
+//
+// Supported actions:
+//   - Enable
+//   - Disable
+//   - BatchGetAccountStatus
+//   - ListFindings
+//   - ListCoverage
+//
+// Nothing is scanned. Coverage lists the EC2 instances, ECR repositories and
+// images, and Lambda functions held by those mocks for each enabled resource
+// type, and findings are the vulnerabilities injected with
+// [Service.InjectFinding] against such resources. Findings are only listed
+// while scanning of their resource type is enabled, and are CLOSED once
+// their resource is gone.
+package inspector2
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/riyanimam/goto/internal/clock"
+	h "github.com/riyanimam/goto/internal/mockhelpers"
+	"github.com/riyanimam/goto/internal/paginate"
+)
+
+// resourceTypes maps the resource types scanning is enabled for to the
+// members of resourceState and resourceStatus that report them.
+var resourceTypes = map[string]string{
+	"EC2":         "ec2",
+	"ECR":         "ecr",
+	"LAMBDA":      "lambda",
+	"LAMBDA_CODE": "lambdaCode",
+}
+
+// Vulnerability is a package vulnerability to report as a finding.
+type Vulnerability struct {
+	// ID is the vulnerability's identifier, such as "CVE-2024-3094".
+	ID string
+	// Severity is CRITICAL, HIGH, MEDIUM, LOW, INFORMATIONAL, or UNTRIAGED;
+	// HIGH by default.
+	Severity string
+	// Score is the Inspector score, 0 to 10.
+	Score float64
+	// Title and Description describe the finding. Title defaults to the ID
+	// and the package name.
+	Title       string
+	Description string
+	// PackageName and PackageVersion identify the vulnerable package, and
+	// FixedInVersion is the version that fixes it, if any.
+	PackageName    string
+	PackageVersion string
+	FixedInVersion string
+	// PackageManager is the package's ecosystem, such as "OS" or "NPM".
+	PackageManager string
+}
+
+// Service implements the Inspector mock.
+type Service struct {
+	mu       sync.RWMutex
+	enabled  map[string]map[string]bool // account ID to enabled resource types
+	findings []*finding
+	list     h.ResourceLister
+	clock    *clock.Clock
+}
+
+type finding struct {
+	arn          string
+	resource     target
+	vuln         Vulnerability
+	firstObserve time.Time
+}
+
+// target is a resource that findings and coverage report on.
+type target struct {
+	scanType     string // EC2, ECR, or LAMBDA, as enabled
+	resourceType string // AWS_EC2_INSTANCE, AWS_ECR_CONTAINER_IMAGE, ...
+	id           string
+	details      map[string]interface{} // resource details, keyed by kind
+	metadata     map[string]interface{} // coverage resourceMetadata
+}
+
+// New creates a new Inspector mock service.
+func New() *Service {
+	return &Service{
+		enabled: make(map[string]map[string]bool),
+	}
+}
+
+// Name returns the service identifier.
+func (s *Service) Name() string { return "inspector2" }
+
+// Handler returns the HTTP handler for Inspector requests.
+func (s *Service) Handler() http.Handler {
+	return http.HandlerFunc(s.handle)
+}
+
+// Reset clears all state, disabling scanning.
+func (s *Service) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.enabled = make(map[string]map[string]bool)
+	s.findings = nil
+}
+
+// SetResourceLister sets how the resources of the EC2, ECR, and Lambda mocks
+// are found.
+func (s *Service) SetResourceLister(l h.ResourceLister) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.list = l
+}
+
+// SetClock attaches the mock clock that finding timestamps use.
+func (s *Service) SetClock(c *clock.Clock) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.clock = c
+}
+
+func (s *Service) now() time.Time {
+	if s.clock != nil {
+		return s.clock.Now()
+	}
+	return time.Now().UTC()
+}
+
+// InjectFinding reports v as a finding on a resource held by another mock:
+// an EC2 instance ID, an ECR image as "repository:tag", "repository@digest",
+// or its ARN, or a Lambda function name or ARN.
+func (s *Service) InjectFinding(resource string, v Vulnerability) error {
+	if v.ID == "" {
+		return fmt.Errorf("vulnerability ID is required")
+	}
+	if v.Severity == "" {
+		v.Severity = "HIGH"
+	}
+	if v.Title == "" {
+		v.Title = strings.TrimSpace(v.ID + " - " + v.PackageName)
+	}
+	if v.PackageManager == "" {
+		v.PackageManager = "OS"
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, t := range s.targets() {
+		if !t.matches(resource) {
+			continue
+		}
+		s.findings = append(s.findings, &finding{
+			arn:          fmt.Sprintf("arn:aws:inspector2:us-east-1:%s:finding/%s", h.DefaultAccountID, h.RandomHex(32)),
+			resource:     t,
+			vuln:         v,
+			firstObserve: s.now(),
+		})
+		return nil
+	}
+	return fmt.Errorf("no EC2 instance, ECR image, or Lambda function %q", resource)
+}
+
+// targets returns the resources of the EC2, ECR, and Lambda mocks. The
+// caller must hold s.mu.
+func (s *Service) targets() []target {
+	if s.list == nil {
+		return nil
+	}
+	var out []target
+	for _, r := range s.list("ec2") {
+		if r.Type != "aws_instance" {
+			continue
+		}
+		out = append(out, target{
+			scanType:     "EC2",
+			resourceType: "AWS_EC2_INSTANCE",
+			id:           r.ID,
+			details: map[string]interface{}{"awsEc2Instance": map[string]interface{}{
+				"imageId":  r.Attributes["ami"],
+				"type":     r.Attributes["instance_type"],
+				"subnetId": r.Attributes["subnet_id"],
+				"platform": "LINUX",
+			}},
+			metadata: map[string]interface{}{"ec2": map[string]interface{}{
+				"amiId":    r.Attributes["ami"],
+				"platform": "LINUX",
+			}},
+		})
+	}
+	for _, r := range s.list("ecr") {
+		switch r.Type {
+		case "aws_ecr_repository":
+			out = append(out, target{
+				scanType:     "ECR",
+				resourceType: "AWS_ECR_REPOSITORY",
+				id:           r.ID,
+				metadata: map[string]interface{}{"ecrRepository": map[string]interface{}{
+					"name":          r.ID,
+					"scanFrequency": "SCAN_ON_PUSH",
+				}},
+			})
+		case "aws_ecr_image":
+			out = append(out, target{
+				scanType:     "ECR",
+				resourceType: "AWS_ECR_CONTAINER_IMAGE",
+				id:           r.ARN,
+				details: map[string]interface{}{"awsEcrContainerImage": map[string]interface{}{
+					"repositoryName": r.Attributes["repository_name"],
+					"imageHash":      r.Attributes["image_digest"],
+					"imageTags":      r.Attributes["image_tags"],
+					"registry":       h.DefaultAccountID,
+					"architecture":   "amd64",
+					"platform":       "LINUX",
+				}},
+				metadata: map[string]interface{}{"ecrImage": map[string]interface{}{
+					"tags": r.Attributes["image_tags"],
+				}},
+			})
+		}
+	}
+	for _, r := range s.list("lambda") {
+		if r.Type != "aws_lambda_function" {
+			continue
+		}
+		out = append(out, target{
+			scanType:     "LAMBDA",
+			resourceType: "AWS_LAMBDA_FUNCTION",
+			id:           r.ARN,
+			details: map[string]interface{}{"awsLambdaFunction": map[string]interface{}{
+				"functionName":     r.ID,
+				"runtime":          r.Attributes["runtime"],
+				"executionRoleArn": r.Attributes["role"],
+				"codeSha256":       r.Attributes["source_code_hash"],
+			}},
+			metadata: map[string]interface{}{"lambdaFunction": map[string]interface{}{
+				"functionName": r.ID,
+				"runtime":      r.Attributes["runtime"],
+			}},
+		})
+	}
+	return out
+}
+
+// matches reports whether resource names t, as [Service.InjectFinding]
+// accepts.
+func (t target) matches(resource string) bool {
+	if t.id == resource {
+		return true
+	}
+	switch t.resourceType {
+	case "AWS_ECR_CONTAINER_IMAGE":
+		image, _ := t.details["awsEcrContainerImage"].(map[string]interface{})
+		repo, _ := image["repositoryName"].(string)
+		digest, _ := image["imageHash"].(string)
+		if resource == repo+"@"+digest {
+			return true
+		}
+		imageTags, _ := image["imageTags"].([]string)
+		for _, tag := range imageTags {
+			if resource == repo+":"+tag {
+				return true
+			}
+		}
+	case "AWS_LAMBDA_FUNCTION":
+		fn, _ := t.details["awsLambdaFunction"].(map[string]interface{})
+		return fn["functionName"] == resource
+	}
+	return false
+}
+
+func (s *Service) handle(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		h.WriteJSONError(w, "ValidationException", "unsupported operation", http.StatusBadRequest)
+		return
+	}
+	switch r.URL.Path {
+	case "/enable":
+		s.enable(w, r)
+	case "/disable":
+		s.disable(w, r)
+	case "/status/batch/get":
+		s.batchGetAccountStatus(w, r)
+	case "/findings/list":
+		s.listFindings(w, r)
+	case "/coverage/list":
+		s.listCoverage(w, r)
+	default:
+		h.WriteJSONError(w, "ValidationException", "unsupported operation", http.StatusBadRequest)
+	}
+}
+
+func readBody(r *http.Request) map[string]interface{} {
+	bodyBytes, _ := io.ReadAll(r.Body)
+	var params map[string]interface{}
+	json.Unmarshal(bodyBytes, &params)
+	return params
+}
+
+// stringList returns the strings of a list parameter.
+func stringList(params map[string]interface{}, key string) []string {
+	list, _ := params[key].([]interface{})
+	out := make([]string, 0, len(list))
+	for _, v := range list {
+		if str, ok := v.(string); ok {
+			out = append(out, str)
+		}
+	}
+	return out
+}
+
+// accountIDs returns the accountIds parameter, or the caller's account.
+func accountIDs(params map[string]interface{}) []string {
+	ids := stringList(params, "accountIds")
+	if len(ids) == 0 {
+		ids = []string{h.DefaultAccountID}
+	}
+	return ids
+}
+
+// resourceStatus renders the scan status of each resource type for an
+// account. The caller must hold s.mu.
+func (s *Service) resourceStatus(accountID string, nested bool) map[string]interface{} {
+	out := map[string]interface{}{}
+	for resourceType, member := range resourceTypes {
+		status := "DISABLED"
+		if s.enabled[accountID][resourceType] {
+			status = "ENABLED"
+		}
+		if nested {
+			out[member] = map[string]interface{}{"status": status}
+		} else {
+			out[member] = status
+		}
+	}
+	return out
+}
+
+// accountStatus is ENABLED if scanning of any resource type is. The caller
+// must hold s.mu.
+func (s *Service) accountStatus(accountID string) string {
+	if len(s.enabled[accountID]) > 0 {
+		return "ENABLED"
+	}
+	return "DISABLED"
+}
+
+func (s *Service) enable(w http.ResponseWriter, r *http.Request) {
+	params := readBody(r)
+	types := stringList(params, "resourceTypes")
+	if len(types) == 0 {
+		h.WriteJSONError(w, "ValidationException", "resourceTypes is required.", http.StatusBadRequest)
+		return
+	}
+	for _, t := range types {
+		if _, ok := resourceTypes[t]; !ok {
+			h.WriteJSONError(w, "ValidationException", "Invalid resource type "+t+".", http.StatusBadRequest)
+			return
+		}
+	}
+	s.setEnabled(w, accountIDs(params), types, true)
+}
+
+func (s *Service) disable(w http.ResponseWriter, r *http.Request) {
+	params := readBody(r)
+	types := stringList(params, "resourceTypes")
+	if len(types) == 0 {
+		for t := range resourceTypes {
+			types = append(types, t)
+		}
+	}
+	s.setEnabled(w, accountIDs(params), types, false)
+}
+
+// setEnabled turns scanning of types on or off for each account. Only the
+// mock's own account can be managed; others fail as accounts outside an
+// organization do.
+func (s *Service) setEnabled(w http.ResponseWriter, ids, types []string, on bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	accounts := []map[string]interface{}{}
+	failed := []map[string]interface{}{}
+	for _, id := range ids {
+		if id != h.DefaultAccountID {
+			failed = append(failed, map[string]interface{}{
+				"accountId":      id,
+				"status":         "DISABLED",
+				"resourceStatus": s.resourceStatus(id, false),
+				"errorCode":      "ACCESS_DENIED",
+				"errorMessage":   "Account " + id + " is not a member of the organization.",
+			})
+			continue
+		}
+		for _, t := range types {
+			if on {
+				if s.enabled[id] == nil {
+					s.enabled[id] = make(map[string]bool)
+				}
+				s.enabled[id][t] = true
+			} else {
+				delete(s.enabled[id], t)
+			}
+		}
+		if len(s.enabled[id]) == 0 {
+			delete(s.enabled, id)
+		}
+		accounts = append(accounts, map[string]interface{}{
+			"accountId":      id,
+			"status":         s.accountStatus(id),
+			"resourceStatus": s.resourceStatus(id, false),
+		})
+	}
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"accounts":       accounts,
+		"failedAccounts": failed,
+	})
+}
+
+func (s *Service) batchGetAccountStatus(w http.ResponseWriter, r *http.Request) {
+	params := readBody(r)
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	accounts := []map[string]interface{}{}
+	failed := []map[string]interface{}{}
+	for _, id := range accountIDs(params) {
+		if id != h.DefaultAccountID {
+			failed = append(failed, map[string]interface{}{
+				"accountId":    id,
+				"errorCode":    "ACCESS_DENIED",
+				"errorMessage": "Account " + id + " is not a member of the organization.",
+			})
+			continue
+		}
+		accounts = append(accounts, map[string]interface{}{
+			"accountId":     id,
+			"state":         map[string]interface{}{"status": s.accountStatus(id)},
+			"resourceState": s.resourceStatus(id, true),
+		})
+	}
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"accounts":       accounts,
+		"failedAccounts": failed,
+	})
+}
+
+// severityRank orders severities for sorting, most severe first.
+var severityRank = map[string]int{
+	"CRITICAL":      5,
+	"HIGH":          4,
+	"MEDIUM":        3,
+	"LOW":           2,
+	"INFORMATIONAL": 1,
+	"UNTRIAGED":     0,
+}
+
+func (s *Service) listFindings(w http.ResponseWriter, r *http.Request) {
+	params := readBody(r)
+	filters, _ := params["filterCriteria"].(map[string]interface{})
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	live := map[string]bool{}
+	for _, t := range s.targets() {
+		live[t.id] = true
+	}
+	now := s.now()
+	sortCriteria, _ := params["sortCriteria"].(map[string]interface{})
+	field := h.GetString(sortCriteria, "field")
+	desc := h.GetString(sortCriteria, "sortOrder") != "ASC"
+	type entry struct {
+		finding map[string]interface{}
+		key     string
+	}
+	var matched []entry
+	for i, f := range s.findings {
+		if !s.enabled[h.DefaultAccountID][f.resource.scanType] {
+			continue
+		}
+		m := f.toMap(live[f.resource.id], now)
+		if matchFilters(m, filters, findingFields) {
+			matched = append(matched, entry{m, findingKey(m, field, desc, i)})
+		}
+	}
+	sort.Slice(matched, func(i, j int) bool { return matched[i].key < matched[j].key })
+
+	page, next, err := paginate.Page(matched, func(i int) string { return matched[i].key }, h.GetString(params, "nextToken"), h.GetInt(params, "maxResults", 0), 100)
+	if err != nil {
+		h.WriteJSONError(w, "ValidationException", "Invalid nextToken", http.StatusBadRequest)
+		return
+	}
+	findings := make([]map[string]interface{}, 0, len(page))
+	for _, e := range page {
+		findings = append(findings, e.finding)
+	}
+	resp := map[string]interface{}{
+		"findings": findings,
+	}
+	if next != "" {
+		resp["nextToken"] = next
+	}
+	h.WriteJSON(w, http.StatusOK, resp)
+}
+
+// findingKey returns the key findings are listed in order of: the sort
+// field's value, in descending order if desc, then the order the findings
+// were reported in, pos being the finding's position among them.
+func findingKey(m map[string]interface{}, field string, desc bool, pos int) string {
+	var value string
+	switch field {
+	case "INSPECTOR_SCORE":
+		value = fmt.Sprintf("%024.6f", m["inspectorScore"])
+	case "FIRST_OBSERVED_AT":
+		value = fmt.Sprintf("%024.6f", m["firstObservedAt"])
+	case "LAST_OBSERVED_AT":
+		value = fmt.Sprintf("%024.6f", m["lastObservedAt"])
+	case "VULNERABILITY_ID":
+		value = vulnerabilityID(m)
+	default:
+		value = fmt.Sprint(severityRank[m["severity"].(string)])
+	}
+	if desc {
+		value = paginate.Desc(value)
+	}
+	return paginate.Join(value, paginate.Seq(pos))
+}
+
+func vulnerabilityID(m map[string]interface{}) string {
+	details, _ := m["packageVulnerabilityDetails"].(map[string]interface{})
+	id, _ := details["vulnerabilityId"].(string)
+	return id
+}
+
+// toMap renders the finding; it is CLOSED if its resource no longer exists.
+func (f *finding) toMap(live bool, now time.Time) map[string]interface{} {
+	status := "ACTIVE"
+	updated := f.firstObserve
+	if !live {
+		status = "CLOSED"
+		updated = now
+	}
+	fix := "NO"
+	if f.vuln.FixedInVersion != "" {
+		fix = "YES"
+	}
+	resource := map[string]interface{}{
+		"type":      f.resource.resourceType,
+		"id":        f.resource.id,
+		"partition": "aws",
+		"region":    "us-east-1",
+	}
+	if f.resource.details != nil {
+		resource["details"] = f.resource.details
+	}
+	return map[string]interface{}{
+		"findingArn":       f.arn,
+		"awsAccountId":     h.DefaultAccountID,
+		"type":             "PACKAGE_VULNERABILITY",
+		"status":           status,
+		"severity":         f.vuln.Severity,
+		"inspectorScore":   f.vuln.Score,
+		"title":            f.vuln.Title,
+		"description":      f.vuln.Description,
+		"fixAvailable":     fix,
+		"exploitAvailable": "NO",
+		"firstObservedAt":  float64(f.firstObserve.Unix()),
+		"lastObservedAt":   float64(updated.Unix()),
+		"updatedAt":        float64(updated.Unix()),
+		"resources":        []map[string]interface{}{resource},
+		"remediation": map[string]interface{}{
+			"recommendation": map[string]interface{}{"text": "None Provided"},
+		},
+		"packageVulnerabilityDetails": map[string]interface{}{
+			"vulnerabilityId": f.vuln.ID,
+			"source":          "NVD",
+			"vulnerablePackages": []map[string]interface{}{{
+				"name":           f.vuln.PackageName,
+				"version":        f.vuln.PackageVersion,
+				"fixedInVersion": f.vuln.FixedInVersion,
+				"packageManager": f.vuln.PackageManager,
+			}},
+		},
+	}
+}
+
+func (s *Service) listCoverage(w http.ResponseWriter, r *http.Request) {
+	params := readBody(r)
+	filters, _ := params["filterCriteria"].(map[string]interface{})
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	now := float64(s.now().Unix())
+	var covered []map[string]interface{}
+	for _, t := range s.targets() {
+		if !s.enabled[h.DefaultAccountID][t.scanType] {
+			continue
+		}
+		m := map[string]interface{}{
+			"accountId":        h.DefaultAccountID,
+			"resourceId":       t.id,
+			"resourceType":     t.resourceType,
+			"scanType":         "PACKAGE",
+			"scanMode":         "EC2_SSM_AGENT_BASED",
+			"lastScannedAt":    now,
+			"resourceMetadata": t.metadata,
+			"scanStatus":       map[string]interface{}{"statusCode": "ACTIVE", "reason": "SUCCESSFUL"},
+		}
+		if t.scanType != "EC2" {
+			delete(m, "scanMode")
+		}
+		if matchFilters(m, filters, coverageFields) {
+			covered = append(covered, m)
+		}
+	}
+	key := func(i int) string { return covered[i]["resourceId"].(string) }
+	sort.Slice(covered, func(i, j int) bool { return key(i) < key(j) })
+
+	page, next, err := paginate.Page(covered, key, h.GetString(params, "nextToken"), h.GetInt(params, "maxResults", 0), 200)
+	if err != nil {
+		h.WriteJSONError(w, "ValidationException", "Invalid nextToken", http.StatusBadRequest)
+		return
+	}
+	resp := map[string]interface{}{
+		"coveredResources": append([]map[string]interface{}{}, page...),
+	}
+	if next != "" {
+		resp["nextToken"] = next
+	}
+	h.WriteJSON(w, http.StatusOK, resp)
+}
@@ -0,0 +1,615 @@
+// Package iot provides a mock implementation of the AWS IoT Core control
+// plane.
+//
+// Supported actions:
+//   - CreateThing, DescribeThing, ListThings, DeleteThing
+//   - CreateKeysAndCertificate, DescribeCertificate, ListCertificates,
+//     UpdateCertificate, DeleteCertificate
+//   - AttachThingPrincipal, DetachThingPrincipal, ListThingPrincipals
+//   - CreateTopicRule, GetTopicRule, ListTopicRules, DeleteTopicRule
+//   - DescribeEndpoint
+//
+// CreateKeysAndCertificate returns a real RSA key pair and an X.509
+// certificate for it, signed by a mock CA. Messages published to a topic
+// with the iotdata mock, including its shadow topics, are run through the
+// enabled topic rules, whose sqs, sns, lambda, kinesis, and republish
+// actions deliver the rule's output to the other mocks. Rule SQL supports a
+// subset of the AWS IoT SQL language; see [query].
+package iot
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/riyanimam/goto/internal/clock"
+	h "github.com/riyanimam/goto/internal/mockhelpers"
+	"github.com/riyanimam/goto/internal/paginate"
+	"github.com/riyanimam/goto/internal/tags"
+)
+
+// Service implements the IoT mock.
+type Service struct {
+	mu           sync.RWMutex
+	things       map[string]*thing
+	certificates map[string]*certificate // by certificate ID
+	rules        map[string]*topicRule
+
+	baseURL  string
+	dispatch h.Dispatcher
+	tags     *tags.Store
+	clock    *clock.Clock
+}
+
+type thing struct {
+	name       string
+	id         string
+	arn        string
+	thingType  string
+	attributes map[string]interface{}
+	version    int
+	principals []string // certificate ARNs, in attachment order
+}
+
+type certificate struct {
+	id      string
+	arn     string
+	pem     string
+	status  string // ACTIVE or INACTIVE
+	created time.Time
+}
+
+// New creates a new IoT mock service.
+func New() *Service {
+	return &Service{
+		things:       make(map[string]*thing),
+		certificates: make(map[string]*certificate),
+		rules:        make(map[string]*topicRule),
+		tags:         tags.New(),
+	}
+}
+
+// Name returns the service identifier.
+func (s *Service) Name() string { return "iot" }
+
+// Handler returns the HTTP handler for IoT requests.
+func (s *Service) Handler() http.Handler {
+	return http.HandlerFunc(s.handle)
+}
+
+// Reset clears all state.
+func (s *Service) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.things = make(map[string]*thing)
+	s.certificates = make(map[string]*certificate)
+	s.rules = make(map[string]*topicRule)
+	s.tags.DeleteService("iot")
+}
+
+// SetBaseURL records the mock server URL, whose host DescribeEndpoint
+// returns.
+func (s *Service) SetBaseURL(u string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.baseURL = u
+}
+
+// SetDispatcher sets the function topic rule actions deliver with.
+func (s *Service) SetDispatcher(d h.Dispatcher) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.dispatch = d
+}
+
+// SetTagStore sets the registry topic rule tags are recorded in.
+func (s *Service) SetTagStore(store *tags.Store) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tags = store
+}
+
+// SetClock attaches the mock clock used for creation times and the
+// timestamp() rule function.
+func (s *Service) SetClock(c *clock.Clock) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.clock = c
+}
+
+func (s *Service) now() time.Time {
+	if s.clock != nil {
+		return s.clock.Now()
+	}
+	return time.Now().UTC()
+}
+
+// HasResource reports whether the thing, certificate, or topic rule
+// identified by arn exists. Topic ARNs always exist.
+func (s *Service) HasResource(arn string) bool {
+	resource := arn[strings.LastIndex(arn, ":")+1:]
+	kind, name, _ := strings.Cut(resource, "/")
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	switch kind {
+	case "thing":
+		_, ok := s.things[name]
+		return ok
+	case "cert":
+		_, ok := s.certificates[name]
+		return ok
+	case "rule":
+		_, ok := s.rules[name]
+		return ok
+	case "topic":
+		return true
+	}
+	return false
+}
+
+func (s *Service) handle(w http.ResponseWriter, r *http.Request) {
+	var params map[string]interface{}
+	if body, _ := io.ReadAll(r.Body); len(body) > 0 {
+		if err := json.Unmarshal(body, &params); err != nil {
+			writeInvalid(w, "Request body is not valid JSON.")
+			return
+		}
+	}
+
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	for i, p := range parts {
+		parts[i], _ = url.PathUnescape(p)
+	}
+	q := r.URL.Query()
+	switch {
+	case len(parts) == 1 && parts[0] == "endpoint" && r.Method == http.MethodGet:
+		s.describeEndpoint(w, q.Get("endpointType"))
+	case len(parts) == 1 && parts[0] == "things" && r.Method == http.MethodGet:
+		s.listThings(w, q)
+	case len(parts) == 2 && parts[0] == "things":
+		switch r.Method {
+		case http.MethodPost:
+			s.createThing(w, parts[1], params)
+		case http.MethodGet:
+			s.describeThing(w, parts[1])
+		case http.MethodDelete:
+			s.deleteThing(w, parts[1])
+		default:
+			writeUnsupported(w)
+		}
+	case len(parts) == 3 && parts[0] == "things" && parts[2] == "principals":
+		principal := r.Header.Get("x-amzn-principal")
+		switch r.Method {
+		case http.MethodPut:
+			s.attachThingPrincipal(w, parts[1], principal)
+		case http.MethodDelete:
+			s.detachThingPrincipal(w, parts[1], principal)
+		case http.MethodGet:
+			s.listThingPrincipals(w, parts[1])
+		default:
+			writeUnsupported(w)
+		}
+	case len(parts) == 1 && parts[0] == "keys-and-certificate" && r.Method == http.MethodPost:
+		s.createKeysAndCertificate(w, q.Get("setAsActive") == "true")
+	case len(parts) == 1 && parts[0] == "certificates" && r.Method == http.MethodGet:
+		s.listCertificates(w, q)
+	case len(parts) == 2 && parts[0] == "certificates":
+		switch r.Method {
+		case http.MethodGet:
+			s.describeCertificate(w, parts[1])
+		case http.MethodPut:
+			s.updateCertificate(w, parts[1], q.Get("newStatus"))
+		case http.MethodDelete:
+			s.deleteCertificate(w, parts[1])
+		default:
+			writeUnsupported(w)
+		}
+	case len(parts) == 1 && parts[0] == "rules" && r.Method == http.MethodGet:
+		s.listTopicRules(w, q)
+	case len(parts) == 2 && parts[0] == "rules":
+		switch r.Method {
+		case http.MethodPost:
+			s.createTopicRule(w, parts[1], params, r.Header.Get("x-amz-tagging"))
+		case http.MethodGet:
+			s.getTopicRule(w, parts[1])
+		case http.MethodDelete:
+			s.deleteTopicRule(w, parts[1])
+		default:
+			writeUnsupported(w)
+		}
+	default:
+		writeUnsupported(w)
+	}
+}
+
+func writeUnsupported(w http.ResponseWriter) {
+	h.WriteJSONError(w, "InvalidRequestException", "unsupported operation", http.StatusBadRequest)
+}
+
+func writeInvalid(w http.ResponseWriter, message string) {
+	h.WriteJSONError(w, "InvalidRequestException", message, http.StatusBadRequest)
+}
+
+func writeNotFound(w http.ResponseWriter, message string) {
+	h.WriteJSONError(w, "ResourceNotFoundException", message, http.StatusNotFound)
+}
+
+func resourceArn(kind, name string) string {
+	return fmt.Sprintf("arn:aws:iot:us-east-1:%s:%s/%s", h.DefaultAccountID, kind, name)
+}
+
+// writeList writes a page of items, sorted by itemKey as in
+// [paginate.Page], under key, using the nextToken and maxResults query
+// parameters.
+func writeList(w http.ResponseWriter, q url.Values, key string, items []map[string]interface{}, itemKey func(i int) string) {
+	limit := 250
+	fmt.Sscan(q.Get("maxResults"), &limit)
+	page, next, err := paginate.Page(items, itemKey, q.Get("nextToken"), limit, 250)
+	if err != nil {
+		writeInvalid(w, "Invalid nextToken.")
+		return
+	}
+	resp := map[string]interface{}{key: page}
+	if next != "" {
+		resp["nextToken"] = next
+	}
+	h.WriteJSON(w, http.StatusOK, resp)
+}
+
+func (s *Service) describeEndpoint(w http.ResponseWriter, endpointType string) {
+	switch endpointType {
+	case "", "iot:Data", "iot:Data-ATS", "iot:CredentialProvider", "iot:Jobs":
+	default:
+		writeInvalid(w, "Invalid endpointType: "+endpointType)
+		return
+	}
+	s.mu.RLock()
+	base := s.baseURL
+	s.mu.RUnlock()
+	u, _ := url.Parse(base)
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{"endpointAddress": u.Host})
+}
+
+func (s *Service) createThing(w http.ResponseWriter, name string, params map[string]interface{}) {
+	payload, _ := params["attributePayload"].(map[string]interface{})
+	attributes, _ := payload["attributes"].(map[string]interface{})
+	if attributes == nil {
+		attributes = map[string]interface{}{}
+	}
+	thingType := h.GetString(params, "thingTypeName")
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if t, exists := s.things[name]; exists {
+		// Creating an identical thing again succeeds, as in AWS IoT.
+		if t.thingType != thingType || fmt.Sprint(t.attributes) != fmt.Sprint(attributes) {
+			h.WriteJSONError(w, "ResourceAlreadyExistsException", "Thing "+name+" already exists in account with different attributes", http.StatusConflict)
+			return
+		}
+	} else {
+		s.things[name] = &thing{
+			name:       name,
+			id:         h.NewRequestID(),
+			arn:        resourceArn("thing", name),
+			thingType:  thingType,
+			attributes: attributes,
+			version:    1,
+		}
+	}
+	t := s.things[name]
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"thingName": t.name,
+		"thingArn":  t.arn,
+		"thingId":   t.id,
+	})
+}
+
+func (t *thing) description() map[string]interface{} {
+	d := map[string]interface{}{
+		"thingName":       t.name,
+		"thingId":         t.id,
+		"thingArn":        t.arn,
+		"attributes":      t.attributes,
+		"version":         t.version,
+		"defaultClientId": t.name,
+	}
+	if t.thingType != "" {
+		d["thingTypeName"] = t.thingType
+	}
+	return d
+}
+
+func (s *Service) describeThing(w http.ResponseWriter, name string) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	t, exists := s.things[name]
+	if !exists {
+		writeNotFound(w, "Thing "+name+" cannot be found.")
+		return
+	}
+	h.WriteJSON(w, http.StatusOK, t.description())
+}
+
+func (s *Service) listThings(w http.ResponseWriter, q url.Values) {
+	attrName, attrValue, thingType := q.Get("attributeName"), q.Get("attributeValue"), q.Get("thingTypeName")
+	s.mu.RLock()
+	var things []map[string]interface{}
+	for _, t := range s.things {
+		if thingType != "" && t.thingType != thingType {
+			continue
+		}
+		if attrName != "" && fmt.Sprint(t.attributes[attrName]) != attrValue {
+			continue
+		}
+		d := t.description()
+		delete(d, "thingId")
+		delete(d, "defaultClientId")
+		things = append(things, d)
+	}
+	s.mu.RUnlock()
+	key := func(i int) string { return things[i]["thingName"].(string) }
+	sort.Slice(things, func(i, j int) bool { return key(i) < key(j) })
+	if things == nil {
+		things = []map[string]interface{}{}
+	}
+	writeList(w, q, "things", things, key)
+}
+
+func (s *Service) deleteThing(w http.ResponseWriter, name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.things, name)
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{})
+}
+
+// lookupCertificate returns the certificate with the given ID or ARN. The
+// caller must hold s.mu.
+func (s *Service) lookupCertificate(idOrArn string) (*certificate, bool) {
+	c, exists := s.certificates[strings.TrimPrefix(idOrArn, resourceArn("cert", ""))]
+	return c, exists
+}
+
+func (s *Service) attachThingPrincipal(w http.ResponseWriter, name, principal string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t, exists := s.things[name]
+	if !exists {
+		writeNotFound(w, "Thing "+name+" cannot be found.")
+		return
+	}
+	c, exists := s.lookupCertificate(principal)
+	if !exists {
+		writeNotFound(w, "Principal "+principal+" cannot be found.")
+		return
+	}
+	for _, p := range t.principals {
+		if p == c.arn {
+			h.WriteJSON(w, http.StatusOK, map[string]interface{}{})
+			return
+		}
+	}
+	t.principals = append(t.principals, c.arn)
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{})
+}
+
+func (s *Service) detachThingPrincipal(w http.ResponseWriter, name, principal string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t, exists := s.things[name]
+	if !exists {
+		writeNotFound(w, "Thing "+name+" cannot be found.")
+		return
+	}
+	for i, p := range t.principals {
+		if p == principal {
+			t.principals = append(t.principals[:i:i], t.principals[i+1:]...)
+			break
+		}
+	}
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{})
+}
+
+func (s *Service) listThingPrincipals(w http.ResponseWriter, name string) {
+	s.mu.RLock()
+	t, exists := s.things[name]
+	var principals []string
+	if exists {
+		principals = append([]string{}, t.principals...)
+	}
+	s.mu.RUnlock()
+	if !exists {
+		writeNotFound(w, "Thing "+name+" cannot be found.")
+		return
+	}
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{"principals": principals})
+}
+
+// attached reports whether the certificate is attached to a thing. The
+// caller must hold s.mu.
+func (s *Service) attached(c *certificate) bool {
+	for _, t := range s.things {
+		for _, p := range t.principals {
+			if p == c.arn {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// mockCA is the certificate authority that signs device certificates.
+var mockCA struct {
+	once sync.Once
+	key  *ecdsa.PrivateKey
+	cert *x509.Certificate
+}
+
+func caCertificate() (*ecdsa.PrivateKey, *x509.Certificate) {
+	mockCA.once.Do(func() {
+		key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		tmpl := &x509.Certificate{
+			SerialNumber:          big.NewInt(1),
+			Subject:               pkix.Name{OrganizationalUnit: []string{"Amazon Web Services"}, Organization: []string{"Amazon.com Inc."}, Locality: []string{"Seattle"}, Province: []string{"Washington"}, Country: []string{"US"}},
+			NotBefore:             time.Now().Add(-time.Hour),
+			NotAfter:              time.Now().AddDate(30, 0, 0),
+			KeyUsage:              x509.KeyUsageCertSign,
+			BasicConstraintsValid: true,
+			IsCA:                  true,
+		}
+		der, _ := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+		mockCA.key = key
+		mockCA.cert, _ = x509.ParseCertificate(der)
+	})
+	return mockCA.key, mockCA.cert
+}
+
+func (s *Service) createKeysAndCertificate(w http.ResponseWriter, setAsActive bool) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		h.WriteJSONError(w, "InternalFailureException", err.Error(), http.StatusInternalServerError)
+		return
+	}
+	caKey, ca := caCertificate()
+	serial, _ := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	now := s.now()
+	der, err := x509.CreateCertificate(rand.Reader, &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: "AWS IoT Certificate"},
+		NotBefore:    now,
+		NotAfter:     time.Date(2049, 12, 31, 23, 59, 59, 0, time.UTC),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}, ca, &key.PublicKey, caKey)
+	if err != nil {
+		h.WriteJSONError(w, "InternalFailureException", err.Error(), http.StatusInternalServerError)
+		return
+	}
+	pub, _ := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	sum := sha256.Sum256(der)
+	c := &certificate{
+		id:      hex.EncodeToString(sum[:]),
+		pem:     string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})),
+		status:  "INACTIVE",
+		created: now,
+	}
+	c.arn = resourceArn("cert", c.id)
+	if setAsActive {
+		c.status = "ACTIVE"
+	}
+
+	s.mu.Lock()
+	s.certificates[c.id] = c
+	s.mu.Unlock()
+
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"certificateArn": c.arn,
+		"certificateId":  c.id,
+		"certificatePem": c.pem,
+		"keyPair": map[string]string{
+			"PublicKey":  string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pub})),
+			"PrivateKey": string(pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})),
+		},
+	})
+}
+
+func (c *certificate) summary() map[string]interface{} {
+	return map[string]interface{}{
+		"certificateArn":  c.arn,
+		"certificateId":   c.id,
+		"status":          c.status,
+		"certificateMode": "DEFAULT",
+		"creationDate":    float64(c.created.Unix()),
+	}
+}
+
+func (s *Service) describeCertificate(w http.ResponseWriter, id string) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	c, exists := s.certificates[id]
+	if !exists {
+		writeNotFound(w, "Certificate "+id+" cannot be found.")
+		return
+	}
+	desc := c.summary()
+	desc["certificatePem"] = c.pem
+	desc["ownedBy"] = h.DefaultAccountID
+	desc["lastModifiedDate"] = float64(c.created.Unix())
+	desc["customerVersion"] = 1
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{"certificateDescription": desc})
+}
+
+func (s *Service) listCertificates(w http.ResponseWriter, q url.Values) {
+	s.mu.RLock()
+	certs := make([]map[string]interface{}, 0, len(s.certificates))
+	for _, c := range s.certificates {
+		certs = append(certs, c.summary())
+	}
+	s.mu.RUnlock()
+	descending := q.Get("ascendingOrder") == "false"
+	key := func(i int) string {
+		k := paginate.Join(fmt.Sprintf("%024.6f", certs[i]["creationDate"]), certs[i]["certificateId"].(string))
+		if descending {
+			return paginate.Desc(k)
+		}
+		return k
+	}
+	sort.Slice(certs, func(i, j int) bool { return key(i) < key(j) })
+	q.Set("maxResults", q.Get("pageSize"))
+	q.Set("nextToken", q.Get("marker"))
+	writeList(w, q, "certificates", certs, key)
+}
+
+func (s *Service) updateCertificate(w http.ResponseWriter, id, status string) {
+	switch status {
+	case "ACTIVE", "INACTIVE", "REVOKED":
+	default:
+		writeInvalid(w, "Invalid newStatus: "+status)
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c, exists := s.certificates[id]
+	if !exists {
+		writeNotFound(w, "Certificate "+id+" cannot be found.")
+		return
+	}
+	if c.status == "REVOKED" && status != "REVOKED" {
+		h.WriteJSONError(w, "CertificateStateException", "Certificate "+id+" is revoked and cannot be activated.", http.StatusNotAcceptable)
+		return
+	}
+	c.status = status
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{})
+}
+
+func (s *Service) deleteCertificate(w http.ResponseWriter, id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c, exists := s.certificates[id]
+	switch {
+	case !exists:
+		writeNotFound(w, "Certificate "+id+" cannot be found.")
+	case c.status == "ACTIVE":
+		h.WriteJSONError(w, "CertificateStateException", "Certificate must be deactivated (not ACTIVE) before deletion.", http.StatusNotAcceptable)
+	case s.attached(c):
+		h.WriteJSONError(w, "DeleteConflictException", "Things must be detached before deletion (arn: "+c.arn+")", http.StatusConflict)
+	default:
+		delete(s.certificates, id)
+		h.WriteJSON(w, http.StatusOK, map[string]interface{}{})
+	}
+}
@@ -0,0 +1,243 @@
+package iot
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	h "github.com/riyanimam/goto/internal/mockhelpers"
+)
+
+type topicRule struct {
+	name        string
+	arn         string
+	sql         string
+	query       *query
+	description string
+	actions     []map[string]interface{}
+	disabled    bool
+	sqlVersion  string
+	created     time.Time
+}
+
+// ruleActions lists the action types topic rules can deliver with.
+var ruleActions = []string{"sqs", "sns", "lambda", "kinesis", "republish"}
+
+func (s *Service) createTopicRule(w http.ResponseWriter, name string, params map[string]interface{}, tagging string) {
+	payload, _ := params["topicRulePayload"].(map[string]interface{})
+	sql := h.GetString(payload, "sql")
+	q, err := parseQuery(sql)
+	if err != nil {
+		h.WriteJSONError(w, "SqlParseException", err.Error(), http.StatusBadRequest)
+		return
+	}
+	rawActions, _ := payload["actions"].([]interface{})
+	actions := make([]map[string]interface{}, 0, len(rawActions))
+	for _, raw := range rawActions {
+		a, _ := raw.(map[string]interface{})
+		if len(a) != 1 {
+			writeInvalid(w, "Each action must have exactly one action type.")
+			return
+		}
+		for kind := range a {
+			supported := false
+			for _, k := range ruleActions {
+				supported = supported || k == kind
+			}
+			if !supported {
+				writeInvalid(w, "Action type "+kind+" is not supported by the mock.")
+				return
+			}
+		}
+		actions = append(actions, a)
+	}
+	disabled, _ := payload["ruleDisabled"].(bool)
+	version := h.GetString(payload, "awsIotSqlVersion")
+	if version == "" {
+		version = "2015-10-08"
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, exists := s.rules[name]; exists {
+		h.WriteJSONError(w, "ResourceAlreadyExistsException", "Rule "+name+" already exists", http.StatusConflict)
+		return
+	}
+	r := &topicRule{
+		name:        name,
+		arn:         resourceArn("rule", name),
+		sql:         sql,
+		query:       q,
+		description: h.GetString(payload, "description"),
+		actions:     actions,
+		disabled:    disabled,
+		sqlVersion:  version,
+		created:     s.now(),
+	}
+	s.rules[name] = r
+	if tagging != "" {
+		values, _ := url.ParseQuery(tagging)
+		t := make(map[string]string, len(values))
+		for k := range values {
+			t[k] = values.Get(k)
+		}
+		s.tags.Tag(r.arn, t)
+	}
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{})
+}
+
+func (s *Service) getTopicRule(w http.ResponseWriter, name string) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	r, exists := s.rules[name]
+	if !exists {
+		h.WriteJSONError(w, "UnauthorizedException", "Rule "+name+" does not exist", http.StatusUnauthorized)
+		return
+	}
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"ruleArn": r.arn,
+		"rule": map[string]interface{}{
+			"ruleName":         r.name,
+			"sql":              r.sql,
+			"description":      r.description,
+			"actions":          r.actions,
+			"ruleDisabled":     r.disabled,
+			"awsIotSqlVersion": r.sqlVersion,
+			"createdAt":        float64(r.created.Unix()),
+		},
+	})
+}
+
+func (s *Service) listTopicRules(w http.ResponseWriter, q url.Values) {
+	topic := q.Get("topic")
+	s.mu.RLock()
+	rules := make([]map[string]interface{}, 0, len(s.rules))
+	for _, r := range s.rules {
+		if topic != "" && r.query.topicFilter != topic {
+			continue
+		}
+		if q.Get("ruleDisabled") != "" && fmt.Sprint(r.disabled) != q.Get("ruleDisabled") {
+			continue
+		}
+		rules = append(rules, map[string]interface{}{
+			"ruleArn":      r.arn,
+			"ruleName":     r.name,
+			"topicPattern": r.query.topicFilter,
+			"createdAt":    float64(r.created.Unix()),
+			"ruleDisabled": r.disabled,
+		})
+	}
+	s.mu.RUnlock()
+	key := func(i int) string { return rules[i]["ruleName"].(string) }
+	sort.Slice(rules, func(i, j int) bool { return key(i) < key(j) })
+	writeList(w, q, "rules", rules, key)
+}
+
+func (s *Service) deleteTopicRule(w http.ResponseWriter, name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r, exists := s.rules[name]
+	if !exists {
+		h.WriteJSONError(w, "UnauthorizedException", "Rule "+name+" does not exist", http.StatusUnauthorized)
+		return
+	}
+	delete(s.rules, name)
+	s.tags.Delete(r.arn)
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{})
+}
+
+// delivery is one rule action's output, ready to dispatch.
+type delivery struct {
+	arn     string
+	payload []byte
+}
+
+// Deliver runs a message published to the topic identified by arn through
+// the enabled topic rules, and delivers each matching rule's output to its
+// actions. A payload that is not a JSON object only matches rules that
+// select * without a WHERE clause, and is passed through unchanged. Action
+// failures are ignored, as they are without an error action in AWS IoT.
+func (s *Service) Deliver(arn string, payload []byte) ([]byte, error) {
+	_, topic, ok := strings.Cut(arn[strings.LastIndex(arn, ":")+1:], "topic/")
+	if !ok {
+		return nil, fmt.Errorf("%s is not an IoT topic", arn)
+	}
+	m := &message{topic: topic}
+	if json.Unmarshal(payload, &m.payload) != nil {
+		m.payload = nil
+	}
+
+	s.mu.RLock()
+	m.now = s.now()
+	dispatch := s.dispatch
+	var deliveries []delivery
+	for _, r := range s.rules {
+		if r.disabled || !r.query.matches(topic) {
+			continue
+		}
+		out := payload
+		if m.payload != nil {
+			selected, matched := r.query.apply(m)
+			if !matched {
+				continue
+			}
+			out, _ = json.Marshal(selected)
+		} else if !r.query.selectAll || len(r.query.items) > 0 || r.query.where != nil {
+			continue
+		}
+		for _, a := range r.actions {
+			d := actionDelivery(a, out)
+			// A rule republishing to a topic it matches would loop forever.
+			if republished, ok := strings.CutPrefix(d.arn, resourceArn("topic", "")); ok && r.query.matches(republished) {
+				continue
+			}
+			deliveries = append(deliveries, d)
+		}
+	}
+	s.mu.RUnlock()
+
+	if dispatch != nil {
+		for _, d := range deliveries {
+			if d.arn != "" {
+				dispatch(d.arn, d.payload)
+			}
+		}
+	}
+	return []byte(topic), nil
+}
+
+// actionDelivery returns where a rule action sends out, and in what form.
+func actionDelivery(action map[string]interface{}, out []byte) delivery {
+	for kind, raw := range action {
+		params, _ := raw.(map[string]interface{})
+		switch kind {
+		case "sqs":
+			u, err := url.Parse(h.GetString(params, "queueUrl"))
+			if err != nil {
+				return delivery{}
+			}
+			parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+			if len(parts) < 2 {
+				return delivery{}
+			}
+			if useBase64, _ := params["useBase64"].(bool); useBase64 {
+				out = []byte(base64.StdEncoding.EncodeToString(out))
+			}
+			return delivery{fmt.Sprintf("arn:aws:sqs:us-east-1:%s:%s", parts[len(parts)-2], parts[len(parts)-1]), out}
+		case "sns":
+			return delivery{h.GetString(params, "targetArn"), out}
+		case "lambda":
+			return delivery{h.GetString(params, "functionArn"), out}
+		case "kinesis":
+			return delivery{fmt.Sprintf("arn:aws:kinesis:us-east-1:%s:stream/%s", h.DefaultAccountID, h.GetString(params, "streamName")), out}
+		case "republish":
+			return delivery{resourceArn("topic", h.GetString(params, "topic")), out}
+		}
+	}
+	return delivery{}
+}
@@ -0,0 +1,431 @@
+package iot
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	h "github.com/riyanimam/goto/internal/mockhelpers"
+)
+
+// query is a parsed topic rule SQL statement:
+//
+//	SELECT <* | expression [AS alias], ...> FROM '<topic filter>' [WHERE <condition>]
+//
+// Expressions are JSON paths into the message (a.b.c), string, number, and
+// boolean literals, and the functions topic(), topic(n), clientid(),
+// timestamp(), and newuuid(). Conditions compare expressions with =, <>,
+// !=, <, <=, >, and >=, and combine them with AND, OR, NOT, and
+// parentheses.
+type query struct {
+	selectAll   bool
+	items       []selectItem
+	topicFilter string
+	where       node // nil if there is no WHERE clause
+}
+
+type selectItem struct {
+	expr  node
+	alias string
+}
+
+// message is what a rule query is evaluated against.
+type message struct {
+	topic    string
+	clientID string
+	payload  map[string]interface{} // nil if the payload is not a JSON object
+	now      time.Time
+}
+
+// undefined is the value of a path that is not in the message. Comparisons
+// with it are false, so a WHERE clause on a missing field does not match.
+type undefined struct{}
+
+type node interface {
+	eval(m *message) interface{}
+}
+
+type pathNode []string
+
+type literalNode struct{ v interface{} }
+
+type callNode struct {
+	name string
+	args []node
+}
+
+type binaryNode struct {
+	op          string
+	left, right node
+}
+
+type notNode struct{ x node }
+
+func (p pathNode) eval(m *message) interface{} {
+	var v interface{} = m.payload
+	for _, part := range p {
+		obj, ok := v.(map[string]interface{})
+		if !ok {
+			return undefined{}
+		}
+		if v, ok = obj[part]; !ok {
+			return undefined{}
+		}
+	}
+	return v
+}
+
+func (l literalNode) eval(*message) interface{} { return l.v }
+
+func (c callNode) eval(m *message) interface{} {
+	switch c.name {
+	case "topic":
+		if len(c.args) == 0 {
+			return m.topic
+		}
+		n, _ := c.args[0].eval(m).(float64)
+		levels := strings.Split(m.topic, "/")
+		if n < 1 || int(n) > len(levels) {
+			return undefined{}
+		}
+		return levels[int(n)-1]
+	case "clientid":
+		return m.clientID
+	case "timestamp":
+		return float64(m.now.UnixMilli())
+	case "newuuid":
+		return h.NewRequestID()
+	}
+	return undefined{}
+}
+
+func (b binaryNode) eval(m *message) interface{} {
+	switch b.op {
+	case "AND":
+		return truthy(b.left.eval(m)) && truthy(b.right.eval(m))
+	case "OR":
+		return truthy(b.left.eval(m)) || truthy(b.right.eval(m))
+	}
+	l, r := b.left.eval(m), b.right.eval(m)
+	if ln, ok := l.(float64); ok {
+		if rn, ok := r.(float64); ok {
+			return compare(b.op, ln-rn)
+		}
+	}
+	if ls, ok := l.(string); ok {
+		if rs, ok := r.(string); ok {
+			return compare(b.op, float64(strings.Compare(ls, rs)))
+		}
+	}
+	if lb, ok := l.(bool); ok {
+		if rb, ok := r.(bool); ok {
+			switch b.op {
+			case "=":
+				return lb == rb
+			case "<>":
+				return lb != rb
+			}
+		}
+	}
+	return false
+}
+
+func (n notNode) eval(m *message) interface{} { return !truthy(n.x.eval(m)) }
+
+func truthy(v interface{}) bool {
+	b, _ := v.(bool)
+	return b
+}
+
+// compare applies a comparison operator to the sign of diff.
+func compare(op string, diff float64) bool {
+	switch op {
+	case "=":
+		return diff == 0
+	case "<>":
+		return diff != 0
+	case "<":
+		return diff < 0
+	case "<=":
+		return diff <= 0
+	case ">":
+		return diff > 0
+	case ">=":
+		return diff >= 0
+	}
+	return false
+}
+
+// name returns the key a select item without an alias is output as.
+func (it selectItem) name() string {
+	if it.alias != "" {
+		return it.alias
+	}
+	switch e := it.expr.(type) {
+	case pathNode:
+		return e[len(e)-1]
+	case callNode:
+		return e.name
+	}
+	return "_"
+}
+
+// matches reports whether the query's topic filter matches topic.
+func (q *query) matches(topic string) bool {
+	filter := strings.Split(q.topicFilter, "/")
+	levels := strings.Split(topic, "/")
+	for i, f := range filter {
+		switch {
+		case f == "#":
+			return true
+		case i >= len(levels):
+			return false
+		case f != "+" && f != levels[i]:
+			return false
+		}
+	}
+	return len(filter) == len(levels)
+}
+
+// apply evaluates the query against m, returning the selected object and
+// whether the WHERE clause matched.
+func (q *query) apply(m *message) (map[string]interface{}, bool) {
+	if q.where != nil && !truthy(q.where.eval(m)) {
+		return nil, false
+	}
+	out := map[string]interface{}{}
+	if q.selectAll {
+		for k, v := range m.payload {
+			out[k] = v
+		}
+	}
+	for _, it := range q.items {
+		v := it.expr.eval(m)
+		if _, missing := v.(undefined); !missing {
+			out[it.name()] = v
+		}
+	}
+	return out, true
+}
+
+// parseQuery parses a topic rule SQL statement.
+func parseQuery(sql string) (*query, error) {
+	toks, err := tokenize(sql)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{toks: toks}
+	q := &query{}
+	if !p.keyword("SELECT") {
+		return nil, fmt.Errorf("expected SELECT")
+	}
+	for {
+		if p.peek() == "*" {
+			p.pos++
+			q.selectAll = true
+		} else {
+			expr, err := p.operand()
+			if err != nil {
+				return nil, err
+			}
+			it := selectItem{expr: expr}
+			if p.keyword("AS") {
+				if it.alias = p.next(); !isIdent(it.alias) {
+					return nil, fmt.Errorf("expected an alias after AS")
+				}
+			}
+			q.items = append(q.items, it)
+		}
+		if p.peek() != "," {
+			break
+		}
+		p.pos++
+	}
+	if !p.keyword("FROM") {
+		return nil, fmt.Errorf("expected FROM")
+	}
+	filter := p.next()
+	if !strings.HasPrefix(filter, "'") {
+		return nil, fmt.Errorf("expected a quoted topic filter after FROM")
+	}
+	q.topicFilter = filter[1 : len(filter)-1]
+	if p.keyword("WHERE") {
+		if q.where, err = p.condition(); err != nil {
+			return nil, err
+		}
+	}
+	if p.pos < len(p.toks) {
+		return nil, fmt.Errorf("unexpected %q", p.toks[p.pos])
+	}
+	return q, nil
+}
+
+// tokenize splits SQL into identifiers and paths, quoted strings, numbers,
+// and operators.
+func tokenize(sql string) ([]string, error) {
+	var toks []string
+	for i := 0; i < len(sql); {
+		c := sql[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '\'':
+			end := strings.IndexByte(sql[i+1:], '\'')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated string")
+			}
+			toks = append(toks, sql[i:i+end+2])
+			i += end + 2
+		case strings.HasPrefix(sql[i:], "<>") || strings.HasPrefix(sql[i:], "!=") ||
+			strings.HasPrefix(sql[i:], "<=") || strings.HasPrefix(sql[i:], ">="):
+			toks = append(toks, sql[i:i+2])
+			i += 2
+		case strings.IndexByte("*,()=<>", c) >= 0:
+			toks = append(toks, string(c))
+			i++
+		default:
+			j := i
+			for j < len(sql) && strings.IndexByte(" \t\n\r'*,()=<>!", sql[j]) < 0 {
+				j++
+			}
+			if j == i {
+				return nil, fmt.Errorf("unexpected %q", c)
+			}
+			toks = append(toks, sql[i:j])
+			i = j
+		}
+	}
+	return toks, nil
+}
+
+type parser struct {
+	toks []string
+	pos  int
+}
+
+func (p *parser) peek() string {
+	if p.pos < len(p.toks) {
+		return p.toks[p.pos]
+	}
+	return ""
+}
+
+func (p *parser) next() string {
+	t := p.peek()
+	p.pos++
+	return t
+}
+
+// keyword consumes the next token if it is kw, in any case.
+func (p *parser) keyword(kw string) bool {
+	if strings.EqualFold(p.peek(), kw) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *parser) condition() (node, error) {
+	left, err := p.conjunction()
+	for err == nil && p.keyword("OR") {
+		var right node
+		if right, err = p.conjunction(); err == nil {
+			left = binaryNode{"OR", left, right}
+		}
+	}
+	return left, err
+}
+
+func (p *parser) conjunction() (node, error) {
+	left, err := p.negation()
+	for err == nil && p.keyword("AND") {
+		var right node
+		if right, err = p.negation(); err == nil {
+			left = binaryNode{"AND", left, right}
+		}
+	}
+	return left, err
+}
+
+func (p *parser) negation() (node, error) {
+	if p.keyword("NOT") {
+		x, err := p.negation()
+		return notNode{x}, err
+	}
+	if p.peek() == "(" {
+		p.pos++
+		c, err := p.condition()
+		if err == nil && p.next() != ")" {
+			err = fmt.Errorf("expected )")
+		}
+		return c, err
+	}
+	left, err := p.operand()
+	if err != nil {
+		return nil, err
+	}
+	switch op := p.peek(); op {
+	case "=", "<>", "!=", "<", "<=", ">", ">=":
+		p.pos++
+		right, err := p.operand()
+		if op == "!=" {
+			op = "<>"
+		}
+		return binaryNode{op, left, right}, err
+	}
+	return left, nil
+}
+
+func (p *parser) operand() (node, error) {
+	t := p.next()
+	switch {
+	case t == "":
+		return nil, fmt.Errorf("unexpected end of statement")
+	case strings.HasPrefix(t, "'"):
+		return literalNode{t[1 : len(t)-1]}, nil
+	case strings.EqualFold(t, "true"), strings.EqualFold(t, "false"):
+		return literalNode{strings.EqualFold(t, "true")}, nil
+	}
+	if n, err := strconv.ParseFloat(t, 64); err == nil {
+		return literalNode{n}, nil
+	}
+	if !isIdent(t) {
+		return nil, fmt.Errorf("unexpected %q", t)
+	}
+	if p.peek() != "(" {
+		return pathNode(strings.Split(t, ".")), nil
+	}
+	p.pos++
+	call := callNode{name: strings.ToLower(t)}
+	switch call.name {
+	case "topic", "clientid", "timestamp", "newuuid":
+	default:
+		return nil, fmt.Errorf("function %s() is not supported", t)
+	}
+	for p.peek() != ")" {
+		arg, err := p.operand()
+		if err != nil {
+			return nil, err
+		}
+		call.args = append(call.args, arg)
+		if p.peek() == "," {
+			p.pos++
+		}
+	}
+	p.pos++
+	return call, nil
+}
+
+func isIdent(t string) bool {
+	if t == "" {
+		return false
+	}
+	for i, c := range t {
+		letter := c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+		if !letter && (i == 0 || c != '.' && (c < '0' || c > '9')) {
+			return false
+		}
+	}
+	return true
+}
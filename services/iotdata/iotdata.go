@@ -0,0 +1,436 @@
+// Package iotdata provides a mock implementation of the AWS IoT data plane.
+//
+// Supported actions:
+//   - Publish
+//   - GetThingShadow, UpdateThingShadow, DeleteThingShadow
+//   - ListNamedShadowsForThing
+//
+// Published messages are handed to the iot mock, which runs them through
+// its topic rules. Shadow updates merge desired and reported state the way
+// the Device Shadow service does (null removes a key, objects merge, other
+// values replace), track per-key metadata timestamps and the document
+// version, compute the delta between desired and reported, and publish the
+// update/accepted, update/documents, update/delta, and delete/accepted
+// messages to the shadow's reserved topics.
+package iotdata
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/riyanimam/goto/internal/clock"
+	h "github.com/riyanimam/goto/internal/mockhelpers"
+)
+
+// maxShadowSize is the largest shadow state document accepted, in bytes.
+const maxShadowSize = 8 * 1024
+
+// Service implements the IoT data plane mock.
+type Service struct {
+	mu      sync.RWMutex
+	shadows map[string]map[string]*shadow // thing name -> shadow name ("" for classic) -> shadow
+
+	dispatch h.Dispatcher
+	clock    *clock.Clock
+}
+
+type shadow struct {
+	desired  map[string]interface{}
+	reported map[string]interface{}
+	// metadata mirrors desired and reported, holding a
+	// {"timestamp": n} object for each leaf value.
+	desiredMeta  map[string]interface{}
+	reportedMeta map[string]interface{}
+	version      int
+}
+
+// New creates a new IoT data plane mock service.
+func New() *Service {
+	return &Service{shadows: make(map[string]map[string]*shadow)}
+}
+
+// Name returns the service identifier. The IoT data plane signs requests
+// as iotdevicegateway.
+func (s *Service) Name() string { return "iotdevicegateway" }
+
+// Handler returns the HTTP handler for IoT data plane requests.
+func (s *Service) Handler() http.Handler {
+	return http.HandlerFunc(s.handle)
+}
+
+// Reset clears all state.
+func (s *Service) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.shadows = make(map[string]map[string]*shadow)
+}
+
+// SetDispatcher sets the function published messages are delivered with.
+func (s *Service) SetDispatcher(d h.Dispatcher) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.dispatch = d
+}
+
+// SetClock attaches the mock clock used for shadow timestamps.
+func (s *Service) SetClock(c *clock.Clock) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.clock = c
+}
+
+func (s *Service) now() time.Time {
+	if s.clock != nil {
+		return s.clock.Now()
+	}
+	return time.Now().UTC()
+}
+
+func (s *Service) handle(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	path := strings.Trim(r.URL.Path, "/")
+	shadowName := r.URL.Query().Get("name")
+	switch {
+	case strings.HasPrefix(path, "topics/") && r.Method == http.MethodPost:
+		s.publish(w, strings.TrimPrefix(path, "topics/"), body)
+	case strings.HasPrefix(path, "things/") && strings.HasSuffix(path, "/shadow"):
+		thing := strings.TrimSuffix(strings.TrimPrefix(path, "things/"), "/shadow")
+		switch r.Method {
+		case http.MethodGet:
+			s.getThingShadow(w, thing, shadowName)
+		case http.MethodPost:
+			s.updateThingShadow(w, thing, shadowName, body)
+		case http.MethodDelete:
+			s.deleteThingShadow(w, thing, shadowName)
+		default:
+			writeUnsupported(w)
+		}
+	case strings.HasPrefix(path, "api/things/shadow/ListNamedShadowsForThing/") && r.Method == http.MethodGet:
+		s.listNamedShadowsForThing(w, strings.TrimPrefix(path, "api/things/shadow/ListNamedShadowsForThing/"))
+	default:
+		writeUnsupported(w)
+	}
+}
+
+func writeUnsupported(w http.ResponseWriter) {
+	h.WriteJSONError(w, "InvalidRequestException", "unsupported operation", http.StatusBadRequest)
+}
+
+func writeInvalid(w http.ResponseWriter, message string) {
+	h.WriteJSONError(w, "InvalidRequestException", message, http.StatusBadRequest)
+}
+
+func topicArn(topic string) string {
+	return fmt.Sprintf("arn:aws:iot:us-east-1:%s:topic/%s", h.DefaultAccountID, topic)
+}
+
+// shadowTopic returns the reserved topic for a shadow operation, such as
+// "update/accepted".
+func shadowTopic(thing, shadowName, op string) string {
+	if shadowName == "" {
+		return "$aws/things/" + thing + "/shadow/" + op
+	}
+	return "$aws/things/" + thing + "/shadow/name/" + shadowName + "/" + op
+}
+
+func (s *Service) publish(w http.ResponseWriter, topic string, payload []byte) {
+	if topic == "" || strings.ContainsAny(topic, "+#") {
+		writeInvalid(w, "Invalid topic: "+topic)
+		return
+	}
+	if len(payload) > 128*1024 {
+		h.WriteJSONError(w, "RequestEntityTooLargeException", "The payload exceeds the maximum size allowed", http.StatusRequestEntityTooLarge)
+		return
+	}
+	s.mu.RLock()
+	dispatch := s.dispatch
+	s.mu.RUnlock()
+	if dispatch != nil {
+		// Without a subscriber, a message is silently dropped, so delivery
+		// errors are not reported to the publisher.
+		dispatch(topicArn(topic), payload)
+	}
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{})
+}
+
+// document renders the shadow's full state document.
+func (sh *shadow) document(now time.Time) map[string]interface{} {
+	state := map[string]interface{}{}
+	metadata := map[string]interface{}{}
+	if sh.desired != nil {
+		state["desired"] = sh.desired
+		metadata["desired"] = sh.desiredMeta
+	}
+	if sh.reported != nil {
+		state["reported"] = sh.reported
+		metadata["reported"] = sh.reportedMeta
+	}
+	if delta := sh.delta(); len(delta) > 0 {
+		state["delta"] = delta
+	}
+	return map[string]interface{}{
+		"state":     state,
+		"metadata":  metadata,
+		"version":   sh.version,
+		"timestamp": now.Unix(),
+	}
+}
+
+// delta returns the desired values that differ from the reported ones.
+func (sh *shadow) delta() map[string]interface{} {
+	return diff(sh.desired, sh.reported)
+}
+
+func diff(desired, reported map[string]interface{}) map[string]interface{} {
+	out := map[string]interface{}{}
+	for k, want := range desired {
+		have, ok := reported[k]
+		wantObj, wantIsObj := want.(map[string]interface{})
+		haveObj, haveIsObj := have.(map[string]interface{})
+		switch {
+		case wantIsObj && haveIsObj:
+			if d := diff(wantObj, haveObj); len(d) > 0 {
+				out[k] = d
+			}
+		case !ok || !reflect.DeepEqual(want, have):
+			out[k] = want
+		}
+	}
+	return out
+}
+
+// merge applies update to state and meta in place: null removes a key,
+// objects merge recursively, and other values replace. Every updated leaf
+// gets a metadata timestamp. It returns the metadata for the updated keys.
+func merge(state, meta, update map[string]interface{}, ts int64) map[string]interface{} {
+	updated := map[string]interface{}{}
+	for k, v := range update {
+		switch v := v.(type) {
+		case nil:
+			delete(state, k)
+			delete(meta, k)
+			updated[k] = map[string]interface{}{"timestamp": ts}
+		case map[string]interface{}:
+			sub, ok := state[k].(map[string]interface{})
+			if !ok {
+				sub = map[string]interface{}{}
+				state[k] = sub
+			}
+			subMeta, ok := meta[k].(map[string]interface{})
+			if !ok || isLeafMeta(subMeta) {
+				subMeta = map[string]interface{}{}
+				meta[k] = subMeta
+			}
+			updated[k] = merge(sub, subMeta, v, ts)
+		default:
+			state[k] = v
+			meta[k] = map[string]interface{}{"timestamp": ts}
+			updated[k] = meta[k]
+		}
+	}
+	return updated
+}
+
+// isLeafMeta reports whether m is the metadata of a single value rather
+// than of an object.
+func isLeafMeta(m map[string]interface{}) bool {
+	_, ok := m["timestamp"].(int64)
+	return ok && len(m) == 1
+}
+
+func (s *Service) getThingShadow(w http.ResponseWriter, thing, shadowName string) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	sh, exists := s.shadows[thing][shadowName]
+	if !exists {
+		writeShadowNotFound(w, shadowName)
+		return
+	}
+	h.WriteJSON(w, http.StatusOK, sh.document(s.now()))
+}
+
+func writeShadowNotFound(w http.ResponseWriter, shadowName string) {
+	if shadowName == "" {
+		shadowName = "Unnamed Shadow"
+	}
+	h.WriteJSONError(w, "ResourceNotFoundException", "No shadow exists with name: '"+shadowName+"'", http.StatusNotFound)
+}
+
+// published is a message to deliver once the lock is released.
+type published struct {
+	topic   string
+	payload map[string]interface{}
+}
+
+func (s *Service) updateThingShadow(w http.ResponseWriter, thing, shadowName string, body []byte) {
+	var update struct {
+		State       map[string]interface{} `json:"state"`
+		Version     *int                   `json:"version"`
+		ClientToken string                 `json:"clientToken"`
+	}
+	if err := json.Unmarshal(body, &update); err != nil {
+		writeInvalid(w, "Payload contains invalid json")
+		return
+	}
+	if update.State == nil {
+		writeInvalid(w, "Missing required node: state")
+		return
+	}
+	if len(body) > maxShadowSize {
+		h.WriteJSONError(w, "RequestEntityTooLargeException", "The payload exceeds the maximum size allowed", http.StatusRequestEntityTooLarge)
+		return
+	}
+	desired, desiredOK := update.State["desired"].(map[string]interface{})
+	reported, reportedOK := update.State["reported"].(map[string]interface{})
+	for _, section := range []string{"desired", "reported"} {
+		if v, ok := update.State[section]; ok && v != nil {
+			if _, isObj := v.(map[string]interface{}); !isObj {
+				writeInvalid(w, "State node must be an object")
+				return
+			}
+		}
+	}
+
+	s.mu.Lock()
+	now := s.now()
+	ts := now.Unix()
+	if s.shadows[thing] == nil {
+		s.shadows[thing] = make(map[string]*shadow)
+	}
+	sh, exists := s.shadows[thing][shadowName]
+	if !exists {
+		sh = &shadow{}
+	}
+	if update.Version != nil && *update.Version != sh.version {
+		s.mu.Unlock()
+		h.WriteJSONError(w, "ConflictException", "Version conflict", http.StatusConflict)
+		return
+	}
+	var previous map[string]interface{}
+	if exists {
+		previous = copyJSON(sh.document(now))
+	}
+
+	state := map[string]interface{}{}
+	metadata := map[string]interface{}{}
+	// A null section removes all of that section's state.
+	if v, ok := update.State["desired"]; ok && v == nil {
+		sh.desired, sh.desiredMeta = nil, nil
+		state["desired"] = nil
+	}
+	if v, ok := update.State["reported"]; ok && v == nil {
+		sh.reported, sh.reportedMeta = nil, nil
+		state["reported"] = nil
+	}
+	if desiredOK {
+		if sh.desired == nil {
+			sh.desired, sh.desiredMeta = map[string]interface{}{}, map[string]interface{}{}
+		}
+		metadata["desired"] = merge(sh.desired, sh.desiredMeta, desired, ts)
+		state["desired"] = desired
+	}
+	if reportedOK {
+		if sh.reported == nil {
+			sh.reported, sh.reportedMeta = map[string]interface{}{}, map[string]interface{}{}
+		}
+		metadata["reported"] = merge(sh.reported, sh.reportedMeta, reported, ts)
+		state["reported"] = reported
+	}
+	sh.version++
+	s.shadows[thing][shadowName] = sh
+
+	accepted := map[string]interface{}{
+		"state":     state,
+		"metadata":  metadata,
+		"version":   sh.version,
+		"timestamp": ts,
+	}
+	if update.ClientToken != "" {
+		accepted["clientToken"] = update.ClientToken
+	}
+	current := copyJSON(sh.document(now))
+	documents := map[string]interface{}{
+		"previous":  previous,
+		"current":   current,
+		"timestamp": ts,
+	}
+	messages := []published{
+		{shadowTopic(thing, shadowName, "update/accepted"), accepted},
+		{shadowTopic(thing, shadowName, "update/documents"), documents},
+	}
+	if delta := sh.delta(); len(delta) > 0 && desiredOK {
+		messages = append(messages, published{shadowTopic(thing, shadowName, "update/delta"), map[string]interface{}{
+			"state":     delta,
+			"version":   sh.version,
+			"timestamp": ts,
+		}})
+	}
+	dispatch := s.dispatch
+	s.mu.Unlock()
+
+	s.deliver(dispatch, messages)
+	h.WriteJSON(w, http.StatusOK, accepted)
+}
+
+func (s *Service) deleteThingShadow(w http.ResponseWriter, thing, shadowName string) {
+	s.mu.Lock()
+	sh, exists := s.shadows[thing][shadowName]
+	if !exists {
+		s.mu.Unlock()
+		writeShadowNotFound(w, shadowName)
+		return
+	}
+	delete(s.shadows[thing], shadowName)
+	if len(s.shadows[thing]) == 0 {
+		delete(s.shadows, thing)
+	}
+	resp := map[string]interface{}{"version": sh.version, "timestamp": s.now().Unix()}
+	dispatch := s.dispatch
+	s.mu.Unlock()
+
+	s.deliver(dispatch, []published{{shadowTopic(thing, shadowName, "delete/accepted"), resp}})
+	h.WriteJSON(w, http.StatusOK, resp)
+}
+
+func (s *Service) listNamedShadowsForThing(w http.ResponseWriter, thing string) {
+	s.mu.RLock()
+	names := []string{}
+	for name := range s.shadows[thing] {
+		if name != "" {
+			names = append(names, name)
+		}
+	}
+	s.mu.RUnlock()
+	sort.Strings(names)
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"results":   names,
+		"timestamp": s.now().Unix(),
+	})
+}
+
+// deliver publishes shadow messages, ignoring delivery errors as publish
+// does.
+func (s *Service) deliver(dispatch h.Dispatcher, messages []published) {
+	if dispatch == nil {
+		return
+	}
+	for _, m := range messages {
+		payload, _ := json.Marshal(m.payload)
+		dispatch(topicArn(m.topic), payload)
+	}
+}
+
+// copyJSON deep-copies a document so later updates do not change it.
+func copyJSON(v map[string]interface{}) map[string]interface{} {
+	b, _ := json.Marshal(v)
+	var out map[string]interface{}
+	json.Unmarshal(b, &out)
+	return out
+}
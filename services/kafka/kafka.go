@@ -6,6 +6,9 @@
 //   - DeleteCluster
 //   - ListClusters
 //   - UpdateBrokerCount
+//   - TagResource
+//   - UntagResource
+//   - ListTagsForResource
 package kafka
 
 import (
@@ -18,12 +21,14 @@ import (
 	"time"
 
 	h "github.com/riyanimam/goto/internal/mockhelpers"
+	"github.com/riyanimam/goto/internal/tags"
 )
 
 // Service implements the MSK mock.
 type Service struct {
 	mu       sync.RWMutex
 	clusters map[string]*cluster // keyed by ARN
+	tags     *tags.Store
 }
 
 type cluster struct {
@@ -41,6 +46,7 @@ type cluster struct {
 func New() *Service {
 	return &Service{
 		clusters: make(map[string]*cluster),
+		tags:     tags.New(),
 	}
 }
 
@@ -57,6 +63,14 @@ func (s *Service) Reset() {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	s.clusters = make(map[string]*cluster)
+	s.tags.DeleteService("kafka")
+}
+
+// SetTagStore sets the registry resource tags are recorded in.
+func (s *Service) SetTagStore(store *tags.Store) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tags = store
 }
 
 func (s *Service) handle(w http.ResponseWriter, r *http.Request) {
@@ -64,6 +78,8 @@ func (s *Service) handle(w http.ResponseWriter, r *http.Request) {
 	method := r.Method
 
 	switch {
+	case strings.Contains(path, "/v1/tags/"):
+		s.handleTags(w, r, path[strings.Index(path, "/v1/tags/")+len("/v1/tags/"):])
 	// UpdateBrokerCount: PUT /v1/clusters/{clusterArn}/nodes/count
 	case strings.HasSuffix(path, "/nodes/count") && method == http.MethodPut:
 		s.updateBrokerCount(w, r, path)
@@ -146,6 +162,7 @@ func (s *Service) createCluster(w http.ResponseWriter, r *http.Request) {
 		created:         now,
 	}
 	s.clusters[arn] = c
+	s.tags.Tag(arn, tags.FromMap(params["tags"]))
 	s.mu.Unlock()
 
 	h.WriteJSON(w, http.StatusOK, map[string]interface{}{
@@ -184,6 +201,7 @@ func (s *Service) deleteCluster(w http.ResponseWriter, _ *http.Request, path str
 	}
 	c.state = "DELETING"
 	arn := c.arn
+	s.tags.Delete(arn)
 	delete(s.clusters, arn)
 	s.mu.Unlock()
 
@@ -275,3 +293,37 @@ func clusterInfoResp(c *cluster) map[string]interface{} {
 		},
 	}
 }
+
+// hasResource reports whether arn names an existing resource.
+// The caller must hold s.mu.
+func (s *Service) hasResource(arn string) bool {
+	_, ok := s.clusters[arn]
+	return ok
+}
+
+// handleTags serves TagResource (POST), UntagResource (DELETE), and
+// ListTagsForResource (GET) on /v1/tags/{arn}.
+func (s *Service) handleTags(w http.ResponseWriter, r *http.Request, arn string) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if !s.hasResource(arn) {
+		h.WriteJSONError(w, "NotFoundException", "Resource not found: "+arn, http.StatusNotFound)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPost:
+		var params map[string]interface{}
+		bodyBytes, _ := io.ReadAll(r.Body)
+		json.Unmarshal(bodyBytes, &params)
+		s.tags.Tag(arn, tags.FromMap(params["tags"]))
+		h.WriteJSON(w, http.StatusNoContent, map[string]interface{}{})
+	case http.MethodDelete:
+		s.tags.Untag(arn, r.URL.Query()["tagKeys"])
+		h.WriteJSON(w, http.StatusNoContent, map[string]interface{}{})
+	default:
+		h.WriteJSON(w, http.StatusOK, map[string]interface{}{
+			"tags": s.tags.Get(arn),
+		})
+	}
+}
@@ -0,0 +1,256 @@
+package kinesis
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/riyanimam/goto/internal/lifecycle"
+	"github.com/riyanimam/goto/internal/paginate"
+)
+
+const (
+	// onDemandShards is the shard count on-demand streams start with.
+	onDemandShards = 4
+	// maxConsumers is the number of enhanced fan-out consumers a stream can
+	// have registered.
+	maxConsumers = 20
+)
+
+// consumer is an enhanced fan-out consumer registered with a stream.
+type consumer struct {
+	name    string
+	arn     string
+	created time.Time
+	ready   lifecycle.Transition
+}
+
+// streamMode returns the StreamMode of a StreamModeDetails parameter, or ""
+// if it is absent.
+func streamMode(params map[string]interface{}) string {
+	details, _ := params["StreamModeDetails"].(map[string]interface{})
+	return getString(details, "StreamMode")
+}
+
+// status returns the stream's status: CREATING until it is provisioned,
+// then UPDATING while a mode change is in progress. The caller must hold
+// s.mu.
+func (s *Service) status(st *stream) string {
+	if !s.transitions.Done(st.ready) {
+		return "CREATING"
+	}
+	return s.transitions.Status(st.updated, "UPDATING", st.status)
+}
+
+func (s *Service) describeStreamSummary(w http.ResponseWriter, params map[string]interface{}) {
+	st := s.namedStream(w, params)
+	if st == nil {
+		return
+	}
+
+	s.mu.RLock()
+	status := s.status(st)
+	s.mu.RUnlock()
+
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"StreamDescriptionSummary": map[string]interface{}{
+			"StreamName":              st.name,
+			"StreamARN":               st.arn,
+			"StreamStatus":            status,
+			"StreamModeDetails":       map[string]interface{}{"StreamMode": st.mode},
+			"RetentionPeriodHours":    st.retentionHours,
+			"StreamCreationTimestamp": float64(st.created.Unix()),
+			"EnhancedMonitoring":      []map[string]interface{}{{"ShardLevelMetrics": []string{}}},
+			"EncryptionType":          "NONE",
+			"OpenShardCount":          st.shardCount,
+			"ConsumerCount":           len(st.consumers),
+		},
+	})
+}
+
+// updateStreamMode switches a stream between PROVISIONED and ON_DEMAND
+// capacity. The stream is UPDATING until the switch completes.
+func (s *Service) updateStreamMode(w http.ResponseWriter, params map[string]interface{}) {
+	if getString(params, "StreamARN") == "" {
+		writeJSONError(w, "ValidationException", "StreamARN is required", http.StatusBadRequest)
+		return
+	}
+	mode := streamMode(params)
+	if mode != "PROVISIONED" && mode != "ON_DEMAND" {
+		writeJSONError(w, "ValidationException", fmt.Sprintf("Invalid StreamMode %q", mode), http.StatusBadRequest)
+		return
+	}
+	st := s.namedStream(w, params)
+	if st == nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if status := s.status(st); status != "ACTIVE" {
+		writeJSONError(w, "ResourceInUseException",
+			fmt.Sprintf("Stream %s under account %s not ACTIVE, instead in state %s", st.name, defaultAccountID, status), http.StatusBadRequest)
+		return
+	}
+	st.mu.Lock()
+	if st.mode != mode {
+		st.mode = mode
+		st.updated = s.transitions.Begin()
+		if mode == "ON_DEMAND" && st.shardCount < onDemandShards {
+			st.shardCount = onDemandShards
+		}
+	}
+	st.mu.Unlock()
+	writeJSON(w, http.StatusOK, map[string]interface{}{})
+}
+
+// streamByARN returns the stream identified by the StreamARN parameter,
+// writing an error and returning nil if it does not exist. The caller must
+// hold s.mu.
+func (s *Service) streamByARN(w http.ResponseWriter, arn string) *stream {
+	_, name, _ := strings.Cut(arn, ":stream/")
+	st, exists := s.streams[name]
+	if !exists || st.arn != arn {
+		writeJSONError(w, "ResourceNotFoundException", "Stream "+name+" under account "+defaultAccountID+" not found.", http.StatusBadRequest)
+		return nil
+	}
+	return st
+}
+
+func (s *Service) registerStreamConsumer(w http.ResponseWriter, params map[string]interface{}) {
+	name := getString(params, "ConsumerName")
+	if name == "" {
+		writeJSONError(w, "ValidationException", "ConsumerName is required", http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	st := s.streamByARN(w, getString(params, "StreamARN"))
+	if st == nil {
+		return
+	}
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if _, exists := st.consumers[name]; exists {
+		writeJSONError(w, "ResourceInUseException",
+			fmt.Sprintf("Consumer %s under stream %s already exists for account %s.", name, st.name, defaultAccountID), http.StatusBadRequest)
+		return
+	}
+	if len(st.consumers) >= maxConsumers {
+		writeJSONError(w, "LimitExceededException",
+			fmt.Sprintf("Stream %s under account %s has reached its limit of %d consumers.", st.name, defaultAccountID, maxConsumers), http.StatusBadRequest)
+		return
+	}
+	now := time.Now().UTC()
+	c := &consumer{
+		name:    name,
+		arn:     fmt.Sprintf("%s/consumer/%s:%d", st.arn, name, now.Unix()),
+		created: now,
+		ready:   s.transitions.Begin(),
+	}
+	st.consumers[name] = c
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"Consumer": s.consumerResp(c),
+	})
+}
+
+// namedConsumer returns the consumer identified by ConsumerARN, or by
+// StreamARN and ConsumerName, and its stream, writing an error and returning
+// nil if it does not exist. The caller must hold s.mu.
+func (s *Service) namedConsumer(w http.ResponseWriter, params map[string]interface{}) (*stream, *consumer) {
+	streamARN, name := getString(params, "StreamARN"), getString(params, "ConsumerName")
+	consumerARN := getString(params, "ConsumerARN")
+	if consumerARN != "" {
+		var rest string
+		streamARN, rest, _ = strings.Cut(consumerARN, "/consumer/")
+		name, _, _ = strings.Cut(rest, ":")
+	}
+	st := s.streamByARN(w, streamARN)
+	if st == nil {
+		return nil, nil
+	}
+	st.mu.Lock()
+	c, exists := st.consumers[name]
+	st.mu.Unlock()
+	if !exists || (consumerARN != "" && c.arn != consumerARN) {
+		writeJSONError(w, "ResourceNotFoundException",
+			fmt.Sprintf("Consumer %s under stream %s not found for account %s.", name, st.name, defaultAccountID), http.StatusBadRequest)
+		return nil, nil
+	}
+	return st, c
+}
+
+func (s *Service) describeStreamConsumer(w http.ResponseWriter, params map[string]interface{}) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	st, c := s.namedConsumer(w, params)
+	if c == nil {
+		return
+	}
+	desc := s.consumerResp(c)
+	desc["StreamARN"] = st.arn
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"ConsumerDescription": desc,
+	})
+}
+
+func (s *Service) deregisterStreamConsumer(w http.ResponseWriter, params map[string]interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	st, c := s.namedConsumer(w, params)
+	if c == nil {
+		return
+	}
+	st.mu.Lock()
+	delete(st.consumers, c.name)
+	st.mu.Unlock()
+	writeJSON(w, http.StatusOK, map[string]interface{}{})
+}
+
+func (s *Service) listStreamConsumers(w http.ResponseWriter, params map[string]interface{}) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	st := s.streamByARN(w, getString(params, "StreamARN"))
+	if st == nil {
+		return
+	}
+
+	st.mu.Lock()
+	list := make([]*consumer, 0, len(st.consumers))
+	for _, c := range st.consumers {
+		list = append(list, c)
+	}
+	st.mu.Unlock()
+	sort.Slice(list, func(i, j int) bool { return list[i].name < list[j].name })
+
+	page, next, err := paginate.Page(list, func(i int) string { return list[i].name }, getString(params, "NextToken"), getInt(params, "MaxResults", 0), 10000)
+	if err != nil {
+		writeJSONError(w, "ExpiredNextTokenException", "The NextToken is invalid or has expired.", http.StatusBadRequest)
+		return
+	}
+	consumers := make([]map[string]interface{}, 0, len(page))
+	for _, c := range page {
+		consumers = append(consumers, s.consumerResp(c))
+	}
+	resp := map[string]interface{}{"Consumers": consumers}
+	if next != "" {
+		resp["NextToken"] = next
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// consumerResp returns the Consumer structure of c. The caller must hold
+// s.mu.
+func (s *Service) consumerResp(c *consumer) map[string]interface{} {
+	return map[string]interface{}{
+		"ConsumerName":              c.name,
+		"ConsumerARN":               c.arn,
+		"ConsumerStatus":            s.transitions.Status(c.ready, "CREATING", "ACTIVE"),
+		"ConsumerCreationTimestamp": float64(c.created.Unix()),
+	}
+}
@@ -0,0 +1,76 @@
+package kinesis
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strconv"
+)
+
+// ReadEvents returns up to max records of the stream identified by arn,
+// shaped as Kinesis event records, starting at position: "" or
+// "TRIM_HORIZON", "LATEST", or a position previously returned.
+func (s *Service) ReadEvents(arn, position string, max int) ([]map[string]interface{}, string, error) {
+	s.mu.RLock()
+	var st *stream
+	for _, candidate := range s.streams {
+		if candidate.arn == arn {
+			st = candidate
+			break
+		}
+	}
+	s.mu.RUnlock()
+	if st == nil {
+		return nil, "", fmt.Errorf("stream %s does not exist", arn)
+	}
+
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.trim(s.now())
+	var pos int
+	switch position {
+	case "", "TRIM_HORIZON":
+		pos = st.trimmed
+	case "LATEST":
+		pos = st.trimmed + len(st.records)
+	default:
+		n, err := strconv.Atoi(position)
+		if err != nil {
+			return nil, "", fmt.Errorf("invalid stream position %q", position)
+		}
+		pos = n
+	}
+
+	start := pos - st.trimmed
+	if start < 0 {
+		start = 0
+	}
+	if start > len(st.records) {
+		start = len(st.records)
+	}
+	end := len(st.records)
+	if max > 0 && start+max < end {
+		end = start + max
+	}
+	var events []map[string]interface{}
+	for _, rec := range st.records[start:end] {
+		events = append(events, map[string]interface{}{
+			"kinesisSchemaVersion":        "1.0",
+			"partitionKey":                rec.partitionKey,
+			"sequenceNumber":              rec.sequenceNumber,
+			"data":                        base64.StdEncoding.EncodeToString(rec.data),
+			"approximateArrivalTimestamp": float64(rec.timestamp.UnixMilli()) / 1000,
+			"eventSource":                 "aws:kinesis",
+			"eventVersion":                "1.0",
+			"eventID":                     "shardId-000000000000:" + rec.sequenceNumber,
+			"eventName":                   "aws:kinesis:record",
+			"awsRegion":                   "us-east-1",
+			"eventSourceARN":              st.arn,
+		})
+	}
+	return events, strconv.Itoa(st.trimmed + end), nil
+}
+
+// AckEvents does nothing: stream consumers track their own position.
+func (s *Service) AckEvents(string, []map[string]interface{}, bool) error {
+	return nil
+}
@@ -4,23 +4,44 @@
 //   - CreateStream
 //   - DeleteStream
 //   - DescribeStream
+//   - DescribeStreamSummary
 //   - ListStreams
+//   - UpdateStreamMode
 //   - PutRecord
 //   - GetRecords
 //   - GetShardIterator
+//   - IncreaseStreamRetentionPeriod
+//   - DecreaseStreamRetentionPeriod
+//   - AddTagsToStream
+//   - RemoveTagsFromStream
+//   - ListTagsForStream
+//   - RegisterStreamConsumer
+//   - DescribeStreamConsumer
+//   - DeregisterStreamConsumer
+//   - ListStreamConsumers
+//
+// Streams are PROVISIONED unless created with an ON_DEMAND StreamModeDetails;
+// on-demand streams start with four shards. Other mocks, such as IoT topic
+// rules, put records on a stream through Deliver.
 package kinesis
 
 import (
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
-	"io"
 	"math/rand"
 	"net/http"
 	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
+
+	"github.com/riyanimam/goto/internal/awserr"
+	"github.com/riyanimam/goto/internal/clock"
+	"github.com/riyanimam/goto/internal/lifecycle"
+	h "github.com/riyanimam/goto/internal/mockhelpers"
+	"github.com/riyanimam/goto/internal/tags"
 )
 
 const defaultAccountID = "123456789012"
@@ -29,16 +50,26 @@ const defaultAccountID = "123456789012"
 type Service struct {
 	mu      sync.RWMutex
 	streams map[string]*stream
+	tags    *tags.Store
+	clock   *clock.Clock
+
+	transitions *lifecycle.Transitions
 }
 
 type stream struct {
-	name       string
-	arn        string
-	status     string
-	shardCount int
-	records    []*record
-	created    time.Time
-	mu         sync.Mutex
+	name           string
+	arn            string
+	status         string
+	mode           string // PROVISIONED or ON_DEMAND
+	shardCount     int
+	retentionHours int
+	records        []*record
+	trimmed        int // records aged out before records[0]
+	created        time.Time
+	ready          lifecycle.Transition
+	updated        lifecycle.Transition // the last mode change
+	consumers      map[string]*consumer
+	mu             sync.Mutex
 }
 
 type record struct {
@@ -52,6 +83,7 @@ type record struct {
 func New() *Service {
 	return &Service{
 		streams: make(map[string]*stream),
+		tags:    tags.New(),
 	}
 }
 
@@ -60,7 +92,26 @@ func (s *Service) Name() string { return "kinesis" }
 
 // Handler returns the HTTP handler for Kinesis requests.
 func (s *Service) Handler() http.Handler {
-	return http.HandlerFunc(s.handle)
+	return h.JSONRouter{
+		"CreateStream":                  s.createStream,
+		"DeleteStream":                  s.deleteStream,
+		"DescribeStream":                s.describeStream,
+		"DescribeStreamSummary":         s.describeStreamSummary,
+		"ListStreams":                   s.listStreams,
+		"UpdateStreamMode":              s.updateStreamMode,
+		"PutRecord":                     s.putRecord,
+		"GetRecords":                    s.getRecords,
+		"GetShardIterator":              s.getShardIterator,
+		"IncreaseStreamRetentionPeriod": s.increaseStreamRetentionPeriod,
+		"DecreaseStreamRetentionPeriod": s.decreaseStreamRetentionPeriod,
+		"AddTagsToStream":               s.addTagsToStream,
+		"RemoveTagsFromStream":          s.removeTagsFromStream,
+		"ListTagsForStream":             s.listTagsForStream,
+		"RegisterStreamConsumer":        s.registerStreamConsumer,
+		"DescribeStreamConsumer":        s.describeStreamConsumer,
+		"DeregisterStreamConsumer":      s.deregisterStreamConsumer,
+		"ListStreamConsumers":           s.listStreamConsumers,
+	}
 }
 
 // Reset clears all streams and records.
@@ -68,54 +119,63 @@ func (s *Service) Reset() {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	s.streams = make(map[string]*stream)
+	s.tags.DeleteService("kinesis")
 }
 
-func (s *Service) handle(w http.ResponseWriter, r *http.Request) {
-	target := r.Header.Get("X-Amz-Target")
+// SetTagStore sets the registry stream tags are recorded in.
+func (s *Service) SetTagStore(store *tags.Store) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tags = store
+}
 
-	bodyBytes, err := io.ReadAll(r.Body)
-	if err != nil {
-		writeJSONError(w, "InternalFailure", "could not read request body", http.StatusInternalServerError)
-		return
-	}
+// SetTransitions sets how long new streams stay CREATING.
+func (s *Service) SetTransitions(t *lifecycle.Transitions) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.transitions = t
+}
 
-	var params map[string]interface{}
-	if len(bodyBytes) > 0 {
-		if err := json.Unmarshal(bodyBytes, &params); err != nil {
-			writeJSONError(w, "SerializationException", "could not parse request body", http.StatusBadRequest)
-			return
+// HasResource reports whether the stream identified by arn exists.
+func (s *Service) HasResource(arn string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, st := range s.streams {
+		if st.arn == arn {
+			return true
 		}
 	}
-	if params == nil {
-		params = make(map[string]interface{})
-	}
+	return false
+}
 
-	action := ""
-	if target != "" {
-		parts := strings.SplitN(target, ".", 2)
-		if len(parts) == 2 {
-			action = parts[1]
+// Deliver puts payload as a record on the stream identified by arn, with a
+// random partition key, and returns its sequence number.
+func (s *Service) Deliver(arn string, payload []byte) ([]byte, error) {
+	s.mu.RLock()
+	var st *stream
+	for _, candidate := range s.streams {
+		if candidate.arn == arn {
+			st = candidate
+			break
 		}
 	}
+	s.mu.RUnlock()
 
-	switch action {
-	case "CreateStream":
-		s.createStream(w, params)
-	case "DeleteStream":
-		s.deleteStream(w, params)
-	case "DescribeStream":
-		s.describeStream(w, params)
-	case "ListStreams":
-		s.listStreams(w, params)
-	case "PutRecord":
-		s.putRecord(w, params)
-	case "GetRecords":
-		s.getRecords(w, params)
-	case "GetShardIterator":
-		s.getShardIterator(w, params)
-	default:
-		writeJSONError(w, "UnknownOperationException", fmt.Sprintf("action %q is not supported", action), http.StatusBadRequest)
+	if st == nil {
+		return nil, fmt.Errorf("stream %s does not exist", arn)
+	}
+
+	rec := &record{
+		sequenceNumber: fmt.Sprintf("%020d", time.Now().UnixNano()),
+		partitionKey:   h.NewRequestID(),
+		data:           payload,
+		timestamp:      s.now(),
 	}
+	st.mu.Lock()
+	st.records = append(st.records, rec)
+	st.mu.Unlock()
+
+	return []byte(rec.sequenceNumber), nil
 }
 
 func (s *Service) createStream(w http.ResponseWriter, params map[string]interface{}) {
@@ -125,7 +185,21 @@ func (s *Service) createStream(w http.ResponseWriter, params map[string]interfac
 		return
 	}
 
+	mode := streamMode(params)
+	if mode == "" {
+		mode = "PROVISIONED"
+	}
 	shardCount := getInt(params, "ShardCount", 1)
+	switch {
+	case mode == "ON_DEMAND" && params["ShardCount"] != nil:
+		writeJSONError(w, "ValidationException", "ShardCount is not supported for streams in ON_DEMAND mode", http.StatusBadRequest)
+		return
+	case mode == "ON_DEMAND":
+		shardCount = onDemandShards
+	case mode != "PROVISIONED":
+		writeJSONError(w, "ValidationException", fmt.Sprintf("Invalid StreamMode %q", mode), http.StatusBadRequest)
+		return
+	}
 
 	s.mu.Lock()
 	if _, exists := s.streams[name]; exists {
@@ -134,13 +208,19 @@ func (s *Service) createStream(w http.ResponseWriter, params map[string]interfac
 		return
 	}
 
-	s.streams[name] = &stream{
-		name:       name,
-		arn:        fmt.Sprintf("arn:aws:kinesis:us-east-1:%s:stream/%s", defaultAccountID, name),
-		status:     "ACTIVE",
-		shardCount: shardCount,
-		created:    time.Now().UTC(),
+	st := &stream{
+		name:           name,
+		arn:            fmt.Sprintf("arn:aws:kinesis:us-east-1:%s:stream/%s", defaultAccountID, name),
+		status:         "ACTIVE",
+		mode:           mode,
+		shardCount:     shardCount,
+		retentionHours: defaultRetentionHours,
+		created:        time.Now().UTC(),
+		ready:          s.transitions.Begin(),
+		consumers:      make(map[string]*consumer),
 	}
+	s.streams[name] = st
+	s.tags.Tag(st.arn, tags.FromMap(params["Tags"]))
 	s.mu.Unlock()
 
 	writeJSON(w, http.StatusOK, map[string]interface{}{})
@@ -153,11 +233,13 @@ func (s *Service) deleteStream(w http.ResponseWriter, params map[string]interfac
 	}
 
 	s.mu.Lock()
-	if _, exists := s.streams[name]; !exists {
+	st, exists := s.streams[name]
+	if !exists {
 		s.mu.Unlock()
 		writeJSONError(w, "ResourceNotFoundException", "Stream "+name+" under account "+defaultAccountID+" not found.", http.StatusBadRequest)
 		return
 	}
+	s.tags.Delete(st.arn)
 	delete(s.streams, name)
 	s.mu.Unlock()
 
@@ -169,6 +251,10 @@ func (s *Service) describeStream(w http.ResponseWriter, params map[string]interf
 
 	s.mu.RLock()
 	st, exists := s.streams[name]
+	var status string
+	if exists {
+		status = s.status(st)
+	}
 	s.mu.RUnlock()
 
 	if !exists {
@@ -176,8 +262,12 @@ func (s *Service) describeStream(w http.ResponseWriter, params map[string]interf
 		return
 	}
 
+	st.mu.Lock()
+	retention, mode, shardCount := st.retentionHours, st.mode, st.shardCount
+	st.mu.Unlock()
+
 	var shards []map[string]interface{}
-	for i := 0; i < st.shardCount; i++ {
+	for i := 0; i < shardCount; i++ {
 		shards = append(shards, map[string]interface{}{
 			"ShardId": fmt.Sprintf("shardId-%012d", i),
 			"HashKeyRange": map[string]interface{}{
@@ -194,10 +284,11 @@ func (s *Service) describeStream(w http.ResponseWriter, params map[string]interf
 		"StreamDescription": map[string]interface{}{
 			"StreamName":              st.name,
 			"StreamARN":               st.arn,
-			"StreamStatus":            st.status,
+			"StreamStatus":            status,
+			"StreamModeDetails":       map[string]interface{}{"StreamMode": mode},
 			"Shards":                  shards,
 			"HasMoreShards":           false,
-			"RetentionPeriodHours":    24,
+			"RetentionPeriodHours":    retention,
 			"StreamCreationTimestamp": float64(st.created.Unix()),
 		},
 	})
@@ -240,7 +331,7 @@ func (s *Service) putRecord(w http.ResponseWriter, params map[string]interface{}
 		sequenceNumber: seqNum,
 		partitionKey:   partKey,
 		data:           data,
-		timestamp:      time.Now().UTC(),
+		timestamp:      s.now(),
 	}
 
 	st.mu.Lock()
@@ -257,7 +348,7 @@ func (s *Service) getShardIterator(w http.ResponseWriter, params map[string]inte
 	name := getString(params, "StreamName")
 
 	s.mu.RLock()
-	_, exists := s.streams[name]
+	st, exists := s.streams[name]
 	s.mu.RUnlock()
 
 	if !exists {
@@ -265,8 +356,40 @@ func (s *Service) getShardIterator(w http.ResponseWriter, params map[string]inte
 		return
 	}
 
-	// Return a simple iterator token.
-	iterator := base64.StdEncoding.EncodeToString([]byte(name + ":0"))
+	now := s.now()
+	st.mu.Lock()
+	st.trim(now)
+	pos := st.trimmed
+	switch iterType := getString(params, "ShardIteratorType"); iterType {
+	case "", "TRIM_HORIZON":
+	case "LATEST":
+		pos += len(st.records)
+	case "AT_SEQUENCE_NUMBER", "AFTER_SEQUENCE_NUMBER":
+		seq := getString(params, "StartingSequenceNumber")
+		i := 0
+		for i < len(st.records) && st.records[i].sequenceNumber < seq {
+			i++
+		}
+		if iterType == "AFTER_SEQUENCE_NUMBER" && i < len(st.records) && st.records[i].sequenceNumber == seq {
+			i++
+		}
+		pos += i
+	case "AT_TIMESTAMP":
+		ts, _ := params["Timestamp"].(float64)
+		at := time.Unix(0, int64(ts*float64(time.Second)))
+		i := 0
+		for i < len(st.records) && st.records[i].timestamp.Before(at) {
+			i++
+		}
+		pos += i
+	default:
+		st.mu.Unlock()
+		writeJSONError(w, "InvalidArgumentException", fmt.Sprintf("Invalid ShardIteratorType %q", iterType), http.StatusBadRequest)
+		return
+	}
+	st.mu.Unlock()
+
+	iterator := base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf("%s:%d", name, pos)))
 
 	writeJSON(w, http.StatusOK, map[string]interface{}{
 		"ShardIterator": iterator,
@@ -276,14 +399,15 @@ func (s *Service) getShardIterator(w http.ResponseWriter, params map[string]inte
 func (s *Service) getRecords(w http.ResponseWriter, params map[string]interface{}) {
 	iteratorToken := getString(params, "ShardIterator")
 
-	// Decode the stream name from the iterator.
+	// Decode the stream name and position from the iterator.
 	decoded, err := base64.StdEncoding.DecodeString(iteratorToken)
 	if err != nil {
 		writeJSONError(w, "InvalidArgumentException", "Invalid ShardIterator", http.StatusBadRequest)
 		return
 	}
-	parts := strings.SplitN(string(decoded), ":", 2)
-	name := parts[0]
+	name, posStr, _ := strings.Cut(string(decoded), ":")
+	pos, _ := strconv.Atoi(posStr)
+	limit := getInt(params, "Limit", 10000)
 
 	s.mu.RLock()
 	st, exists := s.streams[name]
@@ -294,9 +418,24 @@ func (s *Service) getRecords(w http.ResponseWriter, params map[string]interface{
 		return
 	}
 
+	now := s.now()
 	st.mu.Lock()
-	var records []map[string]interface{}
-	for _, rec := range st.records {
+	st.trim(now)
+	// Iterators that point at records which have aged out resume at the
+	// trim horizon.
+	start := pos - st.trimmed
+	if start < 0 {
+		start = 0
+	}
+	if start > len(st.records) {
+		start = len(st.records)
+	}
+	end := len(st.records)
+	if limit > 0 && start+limit < end {
+		end = start + limit
+	}
+	records := []map[string]interface{}{}
+	for _, rec := range st.records[start:end] {
 		records = append(records, map[string]interface{}{
 			"SequenceNumber":              rec.sequenceNumber,
 			"PartitionKey":                rec.partitionKey,
@@ -304,14 +443,65 @@ func (s *Service) getRecords(w http.ResponseWriter, params map[string]interface{
 			"ApproximateArrivalTimestamp": float64(rec.timestamp.Unix()),
 		})
 	}
+	next := st.trimmed + end
+	var behind int64
+	if end < len(st.records) {
+		behind = now.Sub(st.records[end].timestamp).Milliseconds()
+	}
 	st.mu.Unlock()
 
-	nextIterator := base64.StdEncoding.EncodeToString([]byte(name + ":" + fmt.Sprintf("%d", len(records))))
+	nextIterator := base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf("%s:%d", name, next)))
 
 	writeJSON(w, http.StatusOK, map[string]interface{}{
 		"Records":            records,
 		"NextShardIterator":  nextIterator,
-		"MillisBehindLatest": 0,
+		"MillisBehindLatest": behind,
+	})
+}
+
+// namedStream returns the stream named by the StreamName or StreamARN
+// parameter, writing an error and returning nil if it does not exist.
+func (s *Service) namedStream(w http.ResponseWriter, params map[string]interface{}) *stream {
+	name := getString(params, "StreamName")
+	if name == "" {
+		_, name, _ = strings.Cut(getString(params, "StreamARN"), ":stream/")
+	}
+	s.mu.RLock()
+	st, exists := s.streams[name]
+	s.mu.RUnlock()
+	if !exists {
+		writeJSONError(w, "ResourceNotFoundException", "Stream "+name+" under account "+defaultAccountID+" not found.", http.StatusBadRequest)
+		return nil
+	}
+	return st
+}
+
+func (s *Service) addTagsToStream(w http.ResponseWriter, params map[string]interface{}) {
+	st := s.namedStream(w, params)
+	if st == nil {
+		return
+	}
+	s.tags.Tag(st.arn, tags.FromMap(params["Tags"]))
+	writeJSON(w, http.StatusOK, map[string]interface{}{})
+}
+
+func (s *Service) removeTagsFromStream(w http.ResponseWriter, params map[string]interface{}) {
+	st := s.namedStream(w, params)
+	if st == nil {
+		return
+	}
+	s.tags.Untag(st.arn, tags.Keys(params["TagKeys"]))
+	writeJSON(w, http.StatusOK, map[string]interface{}{})
+}
+
+func (s *Service) listTagsForStream(w http.ResponseWriter, params map[string]interface{}) {
+	st := s.namedStream(w, params)
+	if st == nil {
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"Tags":        tags.ToList(s.tags.Get(st.arn), "Key", "Value"),
+		"HasMoreTags": false,
 	})
 }
 
@@ -345,12 +535,7 @@ func writeJSON(w http.ResponseWriter, status int, v interface{}) {
 }
 
 func writeJSONError(w http.ResponseWriter, code, message string, status int) {
-	w.Header().Set("Content-Type", "application/x-amz-json-1.1")
-	w.WriteHeader(status)
-	json.NewEncoder(w).Encode(map[string]interface{}{
-		"__type":  code,
-		"message": message,
-	})
+	awserr.WriteJSON(w, awserr.JSON11, code, message, status)
 }
 
 func newRequestID() string {
@@ -0,0 +1,88 @@
+package kinesis
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/riyanimam/goto/internal/clock"
+)
+
+const (
+	defaultRetentionHours = 24
+	maxRetentionHours     = 8760
+)
+
+// SetClock attaches the mock clock that records are timestamped with and
+// aged out by.
+func (s *Service) SetClock(c *clock.Clock) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.clock = c
+}
+
+// now returns the current time of the mock clock, or the wall-clock time if
+// none is attached.
+func (s *Service) now() time.Time {
+	s.mu.RLock()
+	c := s.clock
+	s.mu.RUnlock()
+	if c == nil {
+		return time.Now().UTC()
+	}
+	return c.Now().UTC()
+}
+
+// trim drops records older than the stream's retention period as of now.
+// Positions of the remaining records are unchanged. The caller must hold
+// st.mu.
+func (st *stream) trim(now time.Time) {
+	cutoff := now.Add(-time.Duration(st.retentionHours) * time.Hour)
+	n := 0
+	for n < len(st.records) && !st.records[n].timestamp.After(cutoff) {
+		n++
+	}
+	st.records = st.records[n:]
+	st.trimmed += n
+}
+
+func (s *Service) increaseStreamRetentionPeriod(w http.ResponseWriter, params map[string]interface{}) {
+	s.setRetention(w, params, true)
+}
+
+func (s *Service) decreaseStreamRetentionPeriod(w http.ResponseWriter, params map[string]interface{}) {
+	s.setRetention(w, params, false)
+}
+
+// setRetention changes a stream's retention period, which must grow when
+// increase is set and shrink otherwise.
+func (s *Service) setRetention(w http.ResponseWriter, params map[string]interface{}, increase bool) {
+	st := s.namedStream(w, params)
+	if st == nil {
+		return
+	}
+	hours := getInt(params, "RetentionPeriodHours", 0)
+	if hours < defaultRetentionHours || hours > maxRetentionHours {
+		writeJSONError(w, "InvalidArgumentException",
+			fmt.Sprintf("Minimum allowed retention period is %d hours. Maximum allowed retention period is %d hours.", defaultRetentionHours, maxRetentionHours),
+			http.StatusBadRequest)
+		return
+	}
+
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	switch {
+	case increase && hours < st.retentionHours:
+		writeJSONError(w, "InvalidArgumentException",
+			fmt.Sprintf("Requested retention period (%d hours) for stream %s can not be shorter than existing retention period (%d hours). Use DecreaseRetentionPeriod API.", hours, st.name, st.retentionHours),
+			http.StatusBadRequest)
+		return
+	case !increase && hours > st.retentionHours:
+		writeJSONError(w, "InvalidArgumentException",
+			fmt.Sprintf("Requested retention period (%d hours) for stream %s can not be longer than existing retention period (%d hours). Use IncreaseRetentionPeriod API.", hours, st.name, st.retentionHours),
+			http.StatusBadRequest)
+		return
+	}
+	st.retentionHours = hours
+	writeJSON(w, http.StatusOK, map[string]interface{}{})
+}
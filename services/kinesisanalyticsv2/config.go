@@ -0,0 +1,256 @@
+package kinesisanalyticsv2
+
+import (
+	"strings"
+
+	"github.com/riyanimam/goto/internal/arn"
+	h "github.com/riyanimam/goto/internal/mockhelpers"
+)
+
+// appConfig is an application's ApplicationConfiguration, with the
+// defaults AWS fills in applied.
+type appConfig struct {
+	codeType      string // ZIPFILE or PLAINTEXT, or "" if no code is configured
+	bucketARN     string
+	fileKey       string
+	objectVersion string
+	textContent   string
+	zipSize       int
+
+	checkpointType     string // DEFAULT or CUSTOM
+	checkpointing      bool
+	checkpointInterval int
+	minPause           int
+
+	parallelismType   string // DEFAULT or CUSTOM
+	parallelism       int
+	parallelismPerKPU int
+	autoScaling       bool
+
+	// propertyGroups holds the EnvironmentProperties PropertyGroups as
+	// given.
+	propertyGroups []interface{}
+	snapshots      bool
+}
+
+func defaultConfig() appConfig {
+	return appConfig{
+		checkpointType:     "DEFAULT",
+		checkpointing:      true,
+		checkpointInterval: 60000,
+		minPause:           5000,
+		parallelismType:    "DEFAULT",
+		parallelism:        1,
+		parallelismPerKPU:  1,
+		autoScaling:        true,
+	}
+}
+
+// parseConfig reads a CreateApplication ApplicationConfiguration,
+// returning a message describing the first problem if it is invalid.
+func parseConfig(raw interface{}) (appConfig, string) {
+	cfg := defaultConfig()
+	params, _ := raw.(map[string]interface{})
+
+	if code, ok := params["ApplicationCodeConfiguration"].(map[string]interface{}); ok {
+		cfg.codeType = h.GetString(code, "CodeContentType")
+		content, _ := code["CodeContent"].(map[string]interface{})
+		if loc, ok := content["S3ContentLocation"].(map[string]interface{}); ok {
+			cfg.bucketARN = h.GetString(loc, "BucketARN")
+			cfg.fileKey = h.GetString(loc, "FileKey")
+			cfg.objectVersion = h.GetString(loc, "ObjectVersion")
+		}
+		cfg.textContent = h.GetString(content, "TextContent")
+		cfg.zipSize = len(h.GetString(content, "ZipFileContent")) * 3 / 4
+	}
+	flink, _ := params["FlinkApplicationConfiguration"].(map[string]interface{})
+	if cp, ok := flink["CheckpointConfiguration"].(map[string]interface{}); ok && h.GetString(cp, "ConfigurationType") == "CUSTOM" {
+		cfg.checkpointType = "CUSTOM"
+		if v, ok := cp["CheckpointingEnabled"].(bool); ok {
+			cfg.checkpointing = v
+		}
+		cfg.checkpointInterval = h.GetInt(cp, "CheckpointInterval", cfg.checkpointInterval)
+		cfg.minPause = h.GetInt(cp, "MinPauseBetweenCheckpoints", cfg.minPause)
+	}
+	if p, ok := flink["ParallelismConfiguration"].(map[string]interface{}); ok && h.GetString(p, "ConfigurationType") == "CUSTOM" {
+		cfg.parallelismType = "CUSTOM"
+		cfg.parallelism = h.GetInt(p, "Parallelism", cfg.parallelism)
+		cfg.parallelismPerKPU = h.GetInt(p, "ParallelismPerKPU", cfg.parallelismPerKPU)
+		cfg.autoScaling = h.GetBool(p, "AutoScalingEnabled")
+	}
+	if env, ok := params["EnvironmentProperties"].(map[string]interface{}); ok {
+		cfg.propertyGroups, _ = env["PropertyGroups"].([]interface{})
+	}
+	if snap, ok := params["ApplicationSnapshotConfiguration"].(map[string]interface{}); ok {
+		cfg.snapshots = h.GetBool(snap, "SnapshotsEnabled")
+	}
+	return cfg, cfg.validate()
+}
+
+// update applies an UpdateApplication ApplicationConfigurationUpdate to a
+// copy of the configuration, returning a message describing the first
+// problem if the result is invalid.
+func (cfg appConfig) update(raw interface{}) (appConfig, string) {
+	params, _ := raw.(map[string]interface{})
+
+	if code, ok := params["ApplicationCodeConfigurationUpdate"].(map[string]interface{}); ok {
+		if v := h.GetString(code, "CodeContentTypeUpdate"); v != "" {
+			cfg.codeType = v
+		}
+		content, _ := code["CodeContentUpdate"].(map[string]interface{})
+		if loc, ok := content["S3ContentLocationUpdate"].(map[string]interface{}); ok {
+			if v := h.GetString(loc, "BucketARNUpdate"); v != "" {
+				cfg.bucketARN = v
+			}
+			if v := h.GetString(loc, "FileKeyUpdate"); v != "" {
+				cfg.fileKey = v
+			}
+			cfg.objectVersion = h.GetString(loc, "ObjectVersionUpdate")
+			cfg.textContent = ""
+		}
+		if v := h.GetString(content, "TextContentUpdate"); v != "" {
+			cfg.textContent = v
+			cfg.bucketARN, cfg.fileKey, cfg.objectVersion = "", "", ""
+		}
+		if v := h.GetString(content, "ZipFileContentUpdate"); v != "" {
+			cfg.zipSize = len(v) * 3 / 4
+			cfg.bucketARN, cfg.fileKey, cfg.objectVersion = "", "", ""
+		}
+	}
+	flink, _ := params["FlinkApplicationConfigurationUpdate"].(map[string]interface{})
+	if cp, ok := flink["CheckpointConfigurationUpdate"].(map[string]interface{}); ok {
+		if v := h.GetString(cp, "ConfigurationTypeUpdate"); v != "" {
+			cfg.checkpointType = v
+		}
+		if cfg.checkpointType == "DEFAULT" {
+			d := defaultConfig()
+			cfg.checkpointing, cfg.checkpointInterval, cfg.minPause = d.checkpointing, d.checkpointInterval, d.minPause
+		} else {
+			if v, ok := cp["CheckpointingEnabledUpdate"].(bool); ok {
+				cfg.checkpointing = v
+			}
+			cfg.checkpointInterval = h.GetInt(cp, "CheckpointIntervalUpdate", cfg.checkpointInterval)
+			cfg.minPause = h.GetInt(cp, "MinPauseBetweenCheckpointsUpdate", cfg.minPause)
+		}
+	}
+	if p, ok := flink["ParallelismConfigurationUpdate"].(map[string]interface{}); ok {
+		if v := h.GetString(p, "ConfigurationTypeUpdate"); v != "" {
+			cfg.parallelismType = v
+		}
+		if cfg.parallelismType == "DEFAULT" {
+			d := defaultConfig()
+			cfg.parallelism, cfg.parallelismPerKPU, cfg.autoScaling = d.parallelism, d.parallelismPerKPU, d.autoScaling
+		} else {
+			cfg.parallelism = h.GetInt(p, "ParallelismUpdate", cfg.parallelism)
+			cfg.parallelismPerKPU = h.GetInt(p, "ParallelismPerKPUUpdate", cfg.parallelismPerKPU)
+			if v, ok := p["AutoScalingEnabledUpdate"].(bool); ok {
+				cfg.autoScaling = v
+			}
+		}
+	}
+	if env, ok := params["EnvironmentPropertyUpdates"].(map[string]interface{}); ok {
+		cfg.propertyGroups, _ = env["PropertyGroups"].([]interface{})
+	}
+	if snap, ok := params["ApplicationSnapshotConfigurationUpdate"].(map[string]interface{}); ok {
+		if v, ok := snap["SnapshotsEnabledUpdate"].(bool); ok {
+			cfg.snapshots = v
+		}
+	}
+	return cfg, cfg.validate()
+}
+
+// validate checks the configuration against the service limits.
+func (cfg appConfig) validate() string {
+	switch cfg.codeType {
+	case "":
+	case "ZIPFILE":
+		if cfg.bucketARN == "" && cfg.zipSize == 0 {
+			return "ZIPFILE code content requires an S3ContentLocation or ZipFileContent."
+		}
+		if cfg.bucketARN != "" {
+			if a, err := arn.Parse(cfg.bucketARN); err != nil || a.Service != "s3" {
+				return "BucketARN must be an S3 bucket ARN."
+			}
+			if cfg.fileKey == "" {
+				return "FileKey is required with BucketARN."
+			}
+		}
+	case "PLAINTEXT":
+		if cfg.textContent == "" {
+			return "PLAINTEXT code content requires TextContent."
+		}
+	default:
+		return "Invalid CodeContentType: " + cfg.codeType
+	}
+	if cfg.checkpointInterval < 1 || cfg.minPause < 0 {
+		return "CheckpointInterval must be positive and MinPauseBetweenCheckpoints non-negative."
+	}
+	if cfg.parallelism < 1 || cfg.parallelismPerKPU < 1 || cfg.parallelismPerKPU > 8 {
+		return "Parallelism must be at least 1 and ParallelismPerKPU between 1 and 8."
+	}
+	return ""
+}
+
+// codeLocation returns the bucket and key of the application code, or ""
+// if it is not held in S3.
+func (cfg appConfig) codeLocation() (bucket, key string) {
+	if cfg.bucketARN == "" {
+		return "", ""
+	}
+	return strings.TrimPrefix(cfg.bucketARN, "arn:aws:s3:::"), cfg.fileKey
+}
+
+// resp returns the configuration in the shape DescribeApplication reports
+// it. Flink settings are reported only for Flink runtimes.
+func (cfg appConfig) resp(runtime string) map[string]interface{} {
+	groups := cfg.propertyGroups
+	if groups == nil {
+		groups = []interface{}{}
+	}
+	desc := map[string]interface{}{
+		"EnvironmentPropertyDescriptions":             map[string]interface{}{"PropertyGroupDescriptions": groups},
+		"ApplicationSnapshotConfigurationDescription": map[string]interface{}{"SnapshotsEnabled": cfg.snapshots},
+	}
+	if cfg.codeType != "" {
+		content := map[string]interface{}{}
+		switch {
+		case cfg.bucketARN != "":
+			loc := map[string]interface{}{"BucketARN": cfg.bucketARN, "FileKey": cfg.fileKey}
+			if cfg.objectVersion != "" {
+				loc["ObjectVersion"] = cfg.objectVersion
+			}
+			content["S3ApplicationCodeLocationDescription"] = loc
+		case cfg.textContent != "":
+			content["TextContent"] = cfg.textContent
+		default:
+			content["CodeSize"] = cfg.zipSize
+		}
+		desc["ApplicationCodeConfigurationDescription"] = map[string]interface{}{
+			"CodeContentType":        cfg.codeType,
+			"CodeContentDescription": content,
+		}
+	}
+	if strings.HasPrefix(runtime, "FLINK-") {
+		desc["FlinkApplicationConfigurationDescription"] = map[string]interface{}{
+			"CheckpointConfigurationDescription": map[string]interface{}{
+				"ConfigurationType":          cfg.checkpointType,
+				"CheckpointingEnabled":       cfg.checkpointing,
+				"CheckpointInterval":         cfg.checkpointInterval,
+				"MinPauseBetweenCheckpoints": cfg.minPause,
+			},
+			"ParallelismConfigurationDescription": map[string]interface{}{
+				"ConfigurationType":  cfg.parallelismType,
+				"Parallelism":        cfg.parallelism,
+				"ParallelismPerKPU":  cfg.parallelismPerKPU,
+				"AutoScalingEnabled": cfg.autoScaling,
+				"CurrentParallelism": cfg.parallelism,
+			},
+			"MonitoringConfigurationDescription": map[string]interface{}{
+				"ConfigurationType": "DEFAULT",
+				"MetricsLevel":      "APPLICATION",
+				"LogLevel":          "INFO",
+			},
+		}
+	}
+	return desc
+}
@@ -0,0 +1,562 @@
+// Package kinesisanalyticsv2 provides a mock implementation of Amazon
+// Managed Service for Apache Flink (Kinesis Data Analytics v2).
+//
+// Supported actions:
+//   - CreateApplication, DescribeApplication, ListApplications,
+//     UpdateApplication, DeleteApplication
+//   - StartApplication, StopApplication
+//   - CreateApplicationSnapshot, DescribeApplicationSnapshot,
+//     ListApplicationSnapshots, DeleteApplicationSnapshot
+//   - TagResource, UntagResource, ListTagsForResource
+//
+// No Flink job runs. Applications are READY once created, then STARTING
+// for their lifecycle transition and RUNNING after StartApplication, and
+// STOPPING, or FORCE_STOPPING, and READY again after StopApplication.
+// Updating a running application makes it UPDATING until the transition
+// completes. Code stored in S3 must exist in the S3 mock. Stopping an
+// application with snapshots enabled takes a snapshot, and starting one
+// restores from the latest or a named snapshot, as its run configuration
+// asks.
+package kinesisanalyticsv2
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/riyanimam/goto/internal/clock"
+	"github.com/riyanimam/goto/internal/lifecycle"
+	h "github.com/riyanimam/goto/internal/mockhelpers"
+	"github.com/riyanimam/goto/internal/paginate"
+	"github.com/riyanimam/goto/internal/tags"
+)
+
+var runtimes = map[string]bool{
+	"FLINK-1_15":         true,
+	"FLINK-1_18":         true,
+	"FLINK-1_19":         true,
+	"FLINK-1_20":         true,
+	"SQL-1_0":            true,
+	"ZEPPELIN-FLINK-3_0": true,
+}
+
+var namePattern = regexp.MustCompile(`^[a-zA-Z0-9_.-]{1,128}$`)
+
+// Service implements the Managed Service for Apache Flink mock.
+type Service struct {
+	mu           sync.RWMutex
+	applications map[string]*application
+
+	store       h.ObjectStore
+	tags        *tags.Store
+	clock       *clock.Clock
+	transitions *lifecycle.Transitions
+}
+
+type application struct {
+	name        string
+	arn         string
+	description string
+	runtime     string
+	mode        string // STREAMING or INTERACTIVE
+	role        string
+	version     int
+	created     time.Time
+	updated     time.Time
+	config      appConfig
+
+	// restoreType, restoreSnapshot, and allowNonRestored are the run
+	// configuration of the last start.
+	restoreType      string
+	restoreSnapshot  string
+	allowNonRestored bool
+
+	status  string // transitional status while changed is in progress
+	settled string // status once changed completes
+	changed lifecycle.Transition
+
+	snapshots map[string]*snapshot
+}
+
+// New creates a new Managed Service for Apache Flink mock service.
+func New() *Service {
+	return &Service{
+		applications: make(map[string]*application),
+		tags:         tags.New(),
+	}
+}
+
+// Name returns the service identifier.
+func (s *Service) Name() string { return "kinesisanalytics" }
+
+// Handler returns the HTTP handler for Managed Service for Apache Flink
+// requests.
+func (s *Service) Handler() http.Handler {
+	return h.JSONRouter{
+		"CreateApplication":           s.createApplication,
+		"DescribeApplication":         s.describeApplication,
+		"ListApplications":            s.listApplications,
+		"UpdateApplication":           s.updateApplication,
+		"DeleteApplication":           s.deleteApplication,
+		"StartApplication":            s.startApplication,
+		"StopApplication":             s.stopApplication,
+		"CreateApplicationSnapshot":   s.createApplicationSnapshot,
+		"DescribeApplicationSnapshot": s.describeApplicationSnapshot,
+		"ListApplicationSnapshots":    s.listApplicationSnapshots,
+		"DeleteApplicationSnapshot":   s.deleteApplicationSnapshot,
+		"TagResource":                 s.tagResource,
+		"UntagResource":               s.untagResource,
+		"ListTagsForResource":         s.listTagsForResource,
+	}
+}
+
+// Reset clears all state.
+func (s *Service) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.applications = make(map[string]*application)
+	s.tags.DeleteService("kinesisanalytics")
+}
+
+// SetObjectStore sets the store application code locations are checked
+// against.
+func (s *Service) SetObjectStore(store h.ObjectStore) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.store = store
+}
+
+// SetTagStore sets the registry application tags are recorded in.
+func (s *Service) SetTagStore(store *tags.Store) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tags = store
+}
+
+// SetClock attaches the mock clock used for creation, update, and snapshot
+// times.
+func (s *Service) SetClock(c *clock.Clock) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.clock = c
+}
+
+// SetTransitions sets how long applications stay STARTING, STOPPING, and
+// UPDATING, and snapshots CREATING.
+func (s *Service) SetTransitions(t *lifecycle.Transitions) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.transitions = t
+}
+
+func (s *Service) now() time.Time {
+	if s.clock != nil {
+		return s.clock.Now()
+	}
+	return time.Now().UTC()
+}
+
+func writeError(w http.ResponseWriter, code, message string) {
+	h.WriteJSONError(w, code, message, http.StatusBadRequest)
+}
+
+// lookup returns the named application, or writes
+// ResourceNotFoundException. The caller must hold s.mu.
+func (s *Service) lookup(w http.ResponseWriter, name string) (*application, bool) {
+	app, exists := s.applications[name]
+	if !exists {
+		writeError(w, "ResourceNotFoundException", fmt.Sprintf("Application %s not found in account %s.", name, h.DefaultAccountID))
+	}
+	return app, exists
+}
+
+// state returns the application's status. The caller must hold s.mu.
+func (s *Service) state(app *application) string {
+	return s.transitions.Status(app.changed, app.status, app.settled)
+}
+
+// begin moves the application to status until its lifecycle transition
+// completes, and then to settled. The caller must hold s.mu.
+func (s *Service) begin(app *application, status, settled string) {
+	app.status, app.settled = status, settled
+	app.changed = s.transitions.Begin()
+}
+
+// checkCode reports a problem with the application code's S3 location: the
+// object must exist in the S3 mock. The caller must hold s.mu.
+func (s *Service) checkCode(cfg appConfig) string {
+	bucket, key := cfg.codeLocation()
+	if bucket == "" || s.store == nil {
+		return ""
+	}
+	if _, err := s.store.GetObject(bucket, key); err != nil {
+		return fmt.Sprintf("Given S3 file location %s/%s does not exist.", cfg.bucketARN, key)
+	}
+	return ""
+}
+
+func (s *Service) createApplication(w http.ResponseWriter, params map[string]interface{}) {
+	name := h.GetString(params, "ApplicationName")
+	runtime := h.GetString(params, "RuntimeEnvironment")
+	role := h.GetString(params, "ServiceExecutionRole")
+	if !namePattern.MatchString(name) {
+		writeError(w, "InvalidArgumentException", "Invalid application name: "+name)
+		return
+	}
+	if !runtimes[runtime] {
+		writeError(w, "InvalidArgumentException", "Invalid RuntimeEnvironment: "+runtime)
+		return
+	}
+	if !strings.HasPrefix(role, "arn:aws:iam::") {
+		writeError(w, "InvalidArgumentException", "ServiceExecutionRole must be an IAM role ARN.")
+		return
+	}
+	mode := h.GetString(params, "ApplicationMode")
+	if mode == "" {
+		mode = "STREAMING"
+	}
+	if mode != "STREAMING" && mode != "INTERACTIVE" {
+		writeError(w, "InvalidArgumentException", "Invalid ApplicationMode: "+mode)
+		return
+	}
+	cfg, msg := parseConfig(params["ApplicationConfiguration"])
+	if msg != "" {
+		writeError(w, "InvalidArgumentException", msg)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, exists := s.applications[name]; exists {
+		writeError(w, "ResourceInUseException", "Application "+name+" already exists.")
+		return
+	}
+	if msg := s.checkCode(cfg); msg != "" {
+		writeError(w, "InvalidArgumentException", msg)
+		return
+	}
+	now := s.now()
+	app := &application{
+		name:        name,
+		arn:         fmt.Sprintf("arn:aws:kinesisanalytics:us-east-1:%s:application/%s", h.DefaultAccountID, name),
+		description: h.GetString(params, "ApplicationDescription"),
+		runtime:     runtime,
+		mode:        mode,
+		role:        role,
+		version:     1,
+		created:     now,
+		updated:     now,
+		config:      cfg,
+		status:      "READY",
+		settled:     "READY",
+		snapshots:   make(map[string]*snapshot),
+	}
+	s.applications[name] = app
+	s.tags.Tag(app.arn, tags.FromList(params["Tags"], "Key", "Value"))
+
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{"ApplicationDetail": s.detail(app)})
+}
+
+// detail returns the application as DescribeApplication reports it. The
+// caller must hold s.mu.
+func (s *Service) detail(app *application) map[string]interface{} {
+	cfg := app.config.resp(app.runtime)
+	if app.restoreType != "" {
+		restore := map[string]interface{}{"ApplicationRestoreType": app.restoreType}
+		if app.restoreSnapshot != "" {
+			restore["SnapshotName"] = app.restoreSnapshot
+		}
+		cfg["RunConfigurationDescription"] = map[string]interface{}{
+			"ApplicationRestoreConfigurationDescription": restore,
+			"FlinkRunConfigurationDescription":           map[string]interface{}{"AllowNonRestoredState": app.allowNonRestored},
+		}
+	}
+	return map[string]interface{}{
+		"ApplicationARN":                      app.arn,
+		"ApplicationName":                     app.name,
+		"ApplicationDescription":              app.description,
+		"RuntimeEnvironment":                  app.runtime,
+		"ApplicationMode":                     app.mode,
+		"ServiceExecutionRole":                app.role,
+		"ApplicationStatus":                   s.state(app),
+		"ApplicationVersionId":                app.version,
+		"CreateTimestamp":                     float64(app.created.Unix()),
+		"LastUpdateTimestamp":                 float64(app.updated.Unix()),
+		"ApplicationConfigurationDescription": cfg,
+	}
+}
+
+func (s *Service) describeApplication(w http.ResponseWriter, params map[string]interface{}) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	app, ok := s.lookup(w, h.GetString(params, "ApplicationName"))
+	if !ok {
+		return
+	}
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{"ApplicationDetail": s.detail(app)})
+}
+
+func (s *Service) listApplications(w http.ResponseWriter, params map[string]interface{}) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	names := make([]string, 0, len(s.applications))
+	for name := range s.applications {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	page, next, err := paginate.Page(names, func(i int) string { return names[i] }, h.GetString(params, "NextToken"), h.GetInt(params, "Limit", 50), 50)
+	if err != nil {
+		writeError(w, "InvalidArgumentException", "Invalid NextToken.")
+		return
+	}
+	summaries := make([]map[string]interface{}, 0, len(page))
+	for _, name := range page {
+		app := s.applications[name]
+		summaries = append(summaries, map[string]interface{}{
+			"ApplicationName":      app.name,
+			"ApplicationARN":       app.arn,
+			"ApplicationStatus":    s.state(app),
+			"ApplicationVersionId": app.version,
+			"RuntimeEnvironment":   app.runtime,
+			"ApplicationMode":      app.mode,
+		})
+	}
+	resp := map[string]interface{}{"ApplicationSummaries": summaries}
+	if next != "" {
+		resp["NextToken"] = next
+	}
+	h.WriteJSON(w, http.StatusOK, resp)
+}
+
+// settledApplication returns the named application if it is READY or
+// RUNNING, writing an error and returning nil otherwise. The caller must
+// hold s.mu.
+func (s *Service) settledApplication(w http.ResponseWriter, name string) *application {
+	app, ok := s.lookup(w, name)
+	if !ok {
+		return nil
+	}
+	if status := s.state(app); status != "READY" && status != "RUNNING" {
+		writeError(w, "ResourceInUseException", fmt.Sprintf("Application %s is in %s status.", name, status))
+		return nil
+	}
+	return app
+}
+
+func (s *Service) updateApplication(w http.ResponseWriter, params map[string]interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	app := s.settledApplication(w, h.GetString(params, "ApplicationName"))
+	if app == nil {
+		return
+	}
+	if v := h.GetInt(params, "CurrentApplicationVersionId", 0); v != app.version && h.GetString(params, "ConditionalToken") == "" {
+		writeError(w, "ConcurrentModificationException", fmt.Sprintf("Current application version is %d, but %d was given.", app.version, v))
+		return
+	}
+	cfg, msg := app.config.update(params["ApplicationConfigurationUpdate"])
+	if msg != "" {
+		writeError(w, "InvalidArgumentException", msg)
+		return
+	}
+	if msg := s.checkCode(cfg); msg != "" {
+		writeError(w, "InvalidArgumentException", msg)
+		return
+	}
+	runtime := app.runtime
+	if v := h.GetString(params, "RuntimeEnvironmentUpdate"); v != "" {
+		if !runtimes[v] || !strings.HasPrefix(v, "FLINK-") || v < runtime {
+			writeError(w, "InvalidArgumentException", "Cannot update RuntimeEnvironment from "+runtime+" to "+v+".")
+			return
+		}
+		runtime = v
+	}
+	if v := h.GetString(params, "ServiceExecutionRoleUpdate"); v != "" {
+		app.role = v
+	}
+	if run, ok := params["RunConfigurationUpdate"].(map[string]interface{}); ok {
+		if msg := s.setRunConfig(app, run, "ApplicationRestoreConfiguration", "FlinkRunConfiguration"); msg != "" {
+			writeError(w, "InvalidArgumentException", msg)
+			return
+		}
+	}
+	app.config = cfg
+	app.runtime = runtime
+	app.version++
+	app.updated = s.now()
+	if s.state(app) == "RUNNING" {
+		s.begin(app, "UPDATING", "RUNNING")
+	}
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"ApplicationDetail": s.detail(app),
+		"OperationId":       h.RandomID(12),
+	})
+}
+
+func (s *Service) deleteApplication(w http.ResponseWriter, params map[string]interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	app, ok := s.lookup(w, h.GetString(params, "ApplicationName"))
+	if !ok {
+		return
+	}
+	if ts, ok := params["CreateTimestamp"].(float64); !ok || int64(ts) != app.created.Unix() {
+		writeError(w, "InvalidArgumentException", "CreateTimestamp does not match the application's creation time.")
+		return
+	}
+	delete(s.applications, app.name)
+	s.tags.Delete(app.arn)
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{})
+}
+
+// setRunConfig records a run configuration's restore settings, read from
+// the members named restoreKey and flinkKey (StartApplication and
+// UpdateApplication name them differently). The caller must hold s.mu.
+func (s *Service) setRunConfig(app *application, run map[string]interface{}, restoreKey, flinkKey string) string {
+	restore, _ := run[restoreKey].(map[string]interface{})
+	restoreType := h.GetString(restore, "ApplicationRestoreType")
+	if restoreType == "" {
+		restoreType = h.GetString(restore, "ApplicationRestoreTypeUpdate")
+	}
+	snapshotName := h.GetString(restore, "SnapshotName")
+	if snapshotName == "" {
+		snapshotName = h.GetString(restore, "SnapshotNameUpdate")
+	}
+	switch restoreType {
+	case "":
+	case "SKIP_RESTORE_FROM_SNAPSHOT", "RESTORE_FROM_LATEST_SNAPSHOT":
+		snapshotName = ""
+	case "RESTORE_FROM_CUSTOM_SNAPSHOT":
+		if snapshotName == "" {
+			return "SnapshotName is required to restore from a custom snapshot."
+		}
+	default:
+		return "Invalid ApplicationRestoreType: " + restoreType
+	}
+	if restoreType != "" {
+		app.restoreType, app.restoreSnapshot = restoreType, snapshotName
+	}
+	if flink, ok := run[flinkKey].(map[string]interface{}); ok {
+		if v, ok := flink["AllowNonRestoredState"].(bool); ok {
+			app.allowNonRestored = v
+		}
+		if v, ok := flink["AllowNonRestoredStateUpdate"].(bool); ok {
+			app.allowNonRestored = v
+		}
+	}
+	return ""
+}
+
+func (s *Service) startApplication(w http.ResponseWriter, params map[string]interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	app, ok := s.lookup(w, h.GetString(params, "ApplicationName"))
+	if !ok {
+		return
+	}
+	if status := s.state(app); status != "READY" {
+		writeError(w, "ResourceInUseException", fmt.Sprintf("Application %s is in %s status and cannot be started.", app.name, status))
+		return
+	}
+	if app.config.codeType == "" && app.mode == "STREAMING" {
+		writeError(w, "InvalidApplicationConfigurationException", "Application "+app.name+" has no application code configured.")
+		return
+	}
+	if msg := s.checkCode(app.config); msg != "" {
+		writeError(w, "InvalidApplicationConfigurationException", msg)
+		return
+	}
+	if run, ok := params["RunConfiguration"].(map[string]interface{}); ok {
+		if msg := s.setRunConfig(app, run, "ApplicationRestoreConfiguration", "FlinkRunConfiguration"); msg != "" {
+			writeError(w, "InvalidArgumentException", msg)
+			return
+		}
+	}
+	if app.restoreType == "" && strings.HasPrefix(app.runtime, "FLINK-") {
+		app.restoreType = "RESTORE_FROM_LATEST_SNAPSHOT"
+	}
+	if app.restoreType == "RESTORE_FROM_CUSTOM_SNAPSHOT" {
+		if !app.config.snapshots {
+			writeError(w, "InvalidApplicationConfigurationException", "Snapshots are not enabled for application "+app.name+".")
+			return
+		}
+		if snap, exists := app.snapshots[app.restoreSnapshot]; !exists || s.snapshotState(snap) != "READY" {
+			writeError(w, "ResourceNotFoundException", "Snapshot "+app.restoreSnapshot+" not found for application "+app.name+".")
+			return
+		}
+	}
+	s.begin(app, "STARTING", "RUNNING")
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{"OperationId": h.RandomID(12)})
+}
+
+func (s *Service) stopApplication(w http.ResponseWriter, params map[string]interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	app, ok := s.lookup(w, h.GetString(params, "ApplicationName"))
+	if !ok {
+		return
+	}
+	force := h.GetBool(params, "Force")
+	status := s.state(app)
+	if status != "RUNNING" && !(force && (status == "STARTING" || status == "UPDATING")) {
+		writeError(w, "ResourceInUseException", fmt.Sprintf("Application %s is in %s status and cannot be stopped.", app.name, status))
+		return
+	}
+	if force {
+		s.begin(app, "FORCE_STOPPING", "READY")
+	} else {
+		if app.config.snapshots && strings.HasPrefix(app.runtime, "FLINK-") {
+			s.takeSnapshot(app, fmt.Sprintf("ka-app-%s-%s", app.name, h.RandomHex(16)))
+		}
+		s.begin(app, "STOPPING", "READY")
+	}
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{"OperationId": h.RandomID(12)})
+}
+
+// lookupARN returns the application with the given ARN, or writes
+// ResourceNotFoundException. The caller must hold s.mu.
+func (s *Service) lookupARN(w http.ResponseWriter, resourceARN string) (*application, bool) {
+	for _, app := range s.applications {
+		if app.arn == resourceARN {
+			return app, true
+		}
+	}
+	writeError(w, "ResourceNotFoundException", "Resource "+resourceARN+" not found.")
+	return nil, false
+}
+
+func (s *Service) tagResource(w http.ResponseWriter, params map[string]interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	app, ok := s.lookupARN(w, h.GetString(params, "ResourceARN"))
+	if !ok {
+		return
+	}
+	s.tags.Tag(app.arn, tags.FromList(params["Tags"], "Key", "Value"))
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{})
+}
+
+func (s *Service) untagResource(w http.ResponseWriter, params map[string]interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	app, ok := s.lookupARN(w, h.GetString(params, "ResourceARN"))
+	if !ok {
+		return
+	}
+	s.tags.Untag(app.arn, tags.Keys(params["TagKeys"]))
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{})
+}
+
+func (s *Service) listTagsForResource(w http.ResponseWriter, params map[string]interface{}) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	app, ok := s.lookupARN(w, h.GetString(params, "ResourceARN"))
+	if !ok {
+		return
+	}
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{"Tags": tags.ToList(s.tags.Get(app.arn), "Key", "Value")})
+}
@@ -0,0 +1,170 @@
+package kinesisanalyticsv2
+
+import (
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/riyanimam/goto/internal/lifecycle"
+	h "github.com/riyanimam/goto/internal/mockhelpers"
+	"github.com/riyanimam/goto/internal/paginate"
+)
+
+var snapshotNamePattern = regexp.MustCompile(`^[a-zA-Z0-9_.-]{1,256}$`)
+
+type snapshot struct {
+	name    string
+	version int // the application version the snapshot was taken of
+	runtime string
+	created time.Time
+	ready   lifecycle.Transition
+}
+
+// takeSnapshot records a snapshot of the application, CREATING until its
+// lifecycle transition completes. The caller must hold s.mu.
+func (s *Service) takeSnapshot(app *application, name string) *snapshot {
+	snap := &snapshot{
+		name:    name,
+		version: app.version,
+		runtime: app.runtime,
+		created: s.now(),
+		ready:   s.transitions.Begin(),
+	}
+	app.snapshots[name] = snap
+	return snap
+}
+
+// snapshotState returns the snapshot's status. The caller must hold s.mu.
+func (s *Service) snapshotState(snap *snapshot) string {
+	return s.transitions.Status(snap.ready, "CREATING", "READY")
+}
+
+// snapshotDetails returns the snapshot as DescribeApplicationSnapshot and
+// ListApplicationSnapshots report it. The caller must hold s.mu.
+func (s *Service) snapshotDetails(snap *snapshot) map[string]interface{} {
+	return map[string]interface{}{
+		"SnapshotName":              snap.name,
+		"SnapshotStatus":            s.snapshotState(snap),
+		"ApplicationVersionId":      snap.version,
+		"SnapshotCreationTimestamp": float64(snap.created.Unix()),
+		"RuntimeEnvironment":        snap.runtime,
+	}
+}
+
+// flinkApplication returns the named application if it can hold snapshots,
+// writing an error and returning nil otherwise. The caller must hold s.mu.
+func (s *Service) flinkApplication(w http.ResponseWriter, name string) *application {
+	app, ok := s.lookup(w, name)
+	if !ok {
+		return nil
+	}
+	if !strings.HasPrefix(app.runtime, "FLINK-") {
+		writeError(w, "UnsupportedOperationException", "Snapshots are not supported for "+app.runtime+" applications.")
+		return nil
+	}
+	return app
+}
+
+func (s *Service) createApplicationSnapshot(w http.ResponseWriter, params map[string]interface{}) {
+	name := h.GetString(params, "SnapshotName")
+	if !snapshotNamePattern.MatchString(name) {
+		writeError(w, "InvalidArgumentException", "Invalid snapshot name: "+name)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	app := s.flinkApplication(w, h.GetString(params, "ApplicationName"))
+	if app == nil {
+		return
+	}
+	if !app.config.snapshots {
+		writeError(w, "InvalidApplicationConfigurationException", "Snapshots are not enabled for application "+app.name+".")
+		return
+	}
+	if status := s.state(app); status != "RUNNING" {
+		writeError(w, "ResourceInUseException", "Application "+app.name+" is in "+status+" status. Snapshots can only be taken of RUNNING applications.")
+		return
+	}
+	if _, exists := app.snapshots[name]; exists {
+		writeError(w, "ResourceInUseException", "Snapshot "+name+" already exists for application "+app.name+".")
+		return
+	}
+	s.takeSnapshot(app, name)
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{})
+}
+
+func (s *Service) describeApplicationSnapshot(w http.ResponseWriter, params map[string]interface{}) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	app := s.flinkApplication(w, h.GetString(params, "ApplicationName"))
+	if app == nil {
+		return
+	}
+	name := h.GetString(params, "SnapshotName")
+	snap, exists := app.snapshots[name]
+	if !exists {
+		writeError(w, "ResourceNotFoundException", "Snapshot "+name+" not found for application "+app.name+".")
+		return
+	}
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{"SnapshotDetails": s.snapshotDetails(snap)})
+}
+
+func (s *Service) listApplicationSnapshots(w http.ResponseWriter, params map[string]interface{}) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	app := s.flinkApplication(w, h.GetString(params, "ApplicationName"))
+	if app == nil {
+		return
+	}
+	snapshots := make([]*snapshot, 0, len(app.snapshots))
+	for _, snap := range app.snapshots {
+		snapshots = append(snapshots, snap)
+	}
+	// Newest first.
+	key := func(i int) string {
+		return paginate.Join(paginate.Desc(paginate.Time(snapshots[i].created)), snapshots[i].name)
+	}
+	sort.Slice(snapshots, func(i, j int) bool { return key(i) < key(j) })
+	page, next, err := paginate.Page(snapshots, key, h.GetString(params, "NextToken"), h.GetInt(params, "Limit", 50), 50)
+	if err != nil {
+		writeError(w, "InvalidArgumentException", "Invalid NextToken.")
+		return
+	}
+	summaries := make([]map[string]interface{}, 0, len(page))
+	for _, snap := range page {
+		summaries = append(summaries, s.snapshotDetails(snap))
+	}
+	resp := map[string]interface{}{"SnapshotSummaries": summaries}
+	if next != "" {
+		resp["NextToken"] = next
+	}
+	h.WriteJSON(w, http.StatusOK, resp)
+}
+
+func (s *Service) deleteApplicationSnapshot(w http.ResponseWriter, params map[string]interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	app := s.flinkApplication(w, h.GetString(params, "ApplicationName"))
+	if app == nil {
+		return
+	}
+	name := h.GetString(params, "SnapshotName")
+	snap, exists := app.snapshots[name]
+	if !exists {
+		writeError(w, "ResourceNotFoundException", "Snapshot "+name+" not found for application "+app.name+".")
+		return
+	}
+	if ts, ok := params["SnapshotCreationTimestamp"].(float64); !ok || int64(ts) != snap.created.Unix() {
+		writeError(w, "InvalidArgumentException", "SnapshotCreationTimestamp does not match the snapshot's creation time.")
+		return
+	}
+	if status := s.snapshotState(snap); status != "READY" {
+		writeError(w, "ResourceInUseException", "Snapshot "+name+" is in "+status+" status.")
+		return
+	}
+	delete(app.snapshots, name)
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{})
+}
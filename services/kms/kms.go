@@ -11,19 +11,25 @@
 //   - ListAliases
 //   - DeleteAlias
 //   - ScheduleKeyDeletion
+//   - TagResource
+//   - UntagResource
+//   - ListResourceTags
 package kms
 
 import (
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
-	"io"
 	"math/rand"
 	"net/http"
 	"sort"
 	"strings"
 	"sync"
 	"time"
+
+	"github.com/riyanimam/goto/internal/awserr"
+	"github.com/riyanimam/goto/internal/mockhelpers"
+	"github.com/riyanimam/goto/internal/tags"
 )
 
 const defaultAccountID = "123456789012"
@@ -33,6 +39,7 @@ type Service struct {
 	mu      sync.RWMutex
 	keys    map[string]*key   // keyed by key ID
 	aliases map[string]*alias // keyed by alias name
+	tags    *tags.Store
 }
 
 type key struct {
@@ -57,6 +64,7 @@ func New() *Service {
 	return &Service{
 		keys:    make(map[string]*key),
 		aliases: make(map[string]*alias),
+		tags:    tags.New(),
 	}
 }
 
@@ -65,7 +73,21 @@ func (s *Service) Name() string { return "kms" }
 
 // Handler returns the HTTP handler for KMS requests.
 func (s *Service) Handler() http.Handler {
-	return http.HandlerFunc(s.handle)
+	return mockhelpers.JSONRouter{
+		"CreateKey":           s.createKey,
+		"DescribeKey":         s.describeKey,
+		"ListKeys":            s.listKeys,
+		"Encrypt":             s.encrypt,
+		"Decrypt":             s.decrypt,
+		"GenerateDataKey":     s.generateDataKey,
+		"CreateAlias":         s.createAlias,
+		"ListAliases":         s.listAliases,
+		"DeleteAlias":         s.deleteAlias,
+		"ScheduleKeyDeletion": s.scheduleKeyDeletion,
+		"TagResource":         s.tagResource,
+		"UntagResource":       s.untagResource,
+		"ListResourceTags":    s.listResourceTags,
+	}
 }
 
 // Reset clears all keys and aliases.
@@ -74,60 +96,14 @@ func (s *Service) Reset() {
 	defer s.mu.Unlock()
 	s.keys = make(map[string]*key)
 	s.aliases = make(map[string]*alias)
+	s.tags.DeleteService("kms")
 }
 
-func (s *Service) handle(w http.ResponseWriter, r *http.Request) {
-	target := r.Header.Get("X-Amz-Target")
-
-	bodyBytes, err := io.ReadAll(r.Body)
-	if err != nil {
-		writeJSONError(w, "KMSInternalException", "could not read request body", http.StatusInternalServerError)
-		return
-	}
-
-	var params map[string]interface{}
-	if len(bodyBytes) > 0 {
-		if err := json.Unmarshal(bodyBytes, &params); err != nil {
-			writeJSONError(w, "ValidationException", "could not parse request body", http.StatusBadRequest)
-			return
-		}
-	}
-	if params == nil {
-		params = make(map[string]interface{})
-	}
-
-	action := ""
-	if target != "" {
-		parts := strings.SplitN(target, ".", 2)
-		if len(parts) == 2 {
-			action = parts[1]
-		}
-	}
-
-	switch action {
-	case "CreateKey":
-		s.createKey(w, params)
-	case "DescribeKey":
-		s.describeKey(w, params)
-	case "ListKeys":
-		s.listKeys(w, params)
-	case "Encrypt":
-		s.encrypt(w, params)
-	case "Decrypt":
-		s.decrypt(w, params)
-	case "GenerateDataKey":
-		s.generateDataKey(w, params)
-	case "CreateAlias":
-		s.createAlias(w, params)
-	case "ListAliases":
-		s.listAliases(w, params)
-	case "DeleteAlias":
-		s.deleteAlias(w, params)
-	case "ScheduleKeyDeletion":
-		s.scheduleKeyDeletion(w, params)
-	default:
-		writeJSONError(w, "UnsupportedOperationException", fmt.Sprintf("action %q is not supported", action), http.StatusBadRequest)
-	}
+// SetTagStore sets the registry key tags are recorded in.
+func (s *Service) SetTagStore(store *tags.Store) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tags = store
 }
 
 func (s *Service) createKey(w http.ResponseWriter, params map[string]interface{}) {
@@ -153,6 +129,7 @@ func (s *Service) createKey(w http.ResponseWriter, params map[string]interface{}
 		keySpec:     keySpec,
 	}
 	s.keys[id] = k
+	s.tags.Tag(k.arn, tags.FromList(params["Tags"], "TagKey", "TagValue"))
 	s.mu.Unlock()
 
 	writeJSON(w, http.StatusOK, map[string]interface{}{
@@ -356,6 +333,63 @@ func (s *Service) scheduleKeyDeletion(w http.ResponseWriter, params map[string]i
 	})
 }
 
+func (s *Service) tagResource(w http.ResponseWriter, params map[string]interface{}) {
+	keyID := getString(params, "KeyId")
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	k := s.findKey(keyID)
+	if k == nil {
+		writeJSONError(w, "NotFoundException", "Key '"+keyID+"' does not exist", http.StatusBadRequest)
+		return
+	}
+
+	s.tags.Tag(k.arn, tags.FromList(params["Tags"], "TagKey", "TagValue"))
+	writeJSON(w, http.StatusOK, map[string]interface{}{})
+}
+
+func (s *Service) untagResource(w http.ResponseWriter, params map[string]interface{}) {
+	keyID := getString(params, "KeyId")
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	k := s.findKey(keyID)
+	if k == nil {
+		writeJSONError(w, "NotFoundException", "Key '"+keyID+"' does not exist", http.StatusBadRequest)
+		return
+	}
+
+	s.tags.Untag(k.arn, tags.Keys(params["TagKeys"]))
+	writeJSON(w, http.StatusOK, map[string]interface{}{})
+}
+
+func (s *Service) listResourceTags(w http.ResponseWriter, params map[string]interface{}) {
+	keyID := getString(params, "KeyId")
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	k := s.findKey(keyID)
+	if k == nil {
+		writeJSONError(w, "NotFoundException", "Key '"+keyID+"' does not exist", http.StatusBadRequest)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"Tags":      tags.ToList(s.tags.Get(k.arn), "TagKey", "TagValue"),
+		"Truncated": false,
+	})
+}
+
+// HasResource reports whether arn is the ARN of an existing key or alias.
+func (s *Service) HasResource(arn string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if _, name, ok := strings.Cut(arn, ":alias/"); ok {
+		return s.findKey("alias/"+name) != nil
+	}
+	return s.findKey(arn) != nil
+}
+
 // findKey looks up a key by ID, ARN, or alias. Caller must hold s.mu.
 func (s *Service) findKey(keyID string) *key {
 	// Direct ID lookup.
@@ -414,12 +448,7 @@ func writeJSON(w http.ResponseWriter, status int, v interface{}) {
 }
 
 func writeJSONError(w http.ResponseWriter, code, message string, status int) {
-	w.Header().Set("Content-Type", "application/x-amz-json-1.1")
-	w.WriteHeader(status)
-	json.NewEncoder(w).Encode(map[string]interface{}{
-		"__type":  code,
-		"message": message,
-	})
+	awserr.WriteJSON(w, awserr.JSON11, code, message, status)
 }
 
 func newKeyID() string {
@@ -0,0 +1,37 @@
+package kms
+
+import "github.com/riyanimam/goto/internal/mockhelpers"
+
+// ExportState lists the keys and their aliases.
+func (s *Service) ExportState() []mockhelpers.Resource {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	resources := make([]mockhelpers.Resource, 0, len(s.keys)+len(s.aliases))
+	for _, k := range s.keys {
+		resources = append(resources, mockhelpers.Resource{
+			Type: "aws_kms_key",
+			ID:   k.id,
+			ARN:  k.arn,
+			Attributes: map[string]interface{}{
+				"key_id":      k.id,
+				"description": k.description,
+				"key_usage":   k.keyUsage,
+				"key_spec":    k.keySpec,
+				"key_state":   k.state,
+			},
+		})
+	}
+	for _, a := range s.aliases {
+		resources = append(resources, mockhelpers.Resource{
+			Type: "aws_kms_alias",
+			ID:   a.name,
+			ARN:  a.arn,
+			Attributes: map[string]interface{}{
+				"name":          a.name,
+				"target_key_id": a.targetID,
+			},
+		})
+	}
+	return resources
+}
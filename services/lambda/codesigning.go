@@ -0,0 +1,323 @@
+package lambda
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/riyanimam/goto/internal/paginate"
+)
+
+// maxSigningProfiles is the number of signing profile versions a code
+// signing config can allow.
+const maxSigningProfiles = 20
+
+// codeSigningConfig lists the signing profiles allowed to sign a function's
+// code. The mock records which functions use it but does not check
+// signatures.
+type codeSigningConfig struct {
+	id           string
+	arn          string
+	description  string
+	profiles     []string
+	untrusted    string // Warn or Enforce
+	lastModified string
+}
+
+// handleCodeSigningConfigs serves the code signing config APIs under
+// /2020-04-22/code-signing-configs.
+func (s *Service) handleCodeSigningConfigs(w http.ResponseWriter, r *http.Request) {
+	rest := strings.Trim(strings.TrimPrefix(r.URL.Path, "/2020-04-22/code-signing-configs"), "/")
+	switch {
+	case rest == "" && r.Method == http.MethodPost:
+		s.createCodeSigningConfig(w, r)
+	case rest == "":
+		s.listCodeSigningConfigs(w, r)
+	case strings.HasSuffix(rest, "/functions"):
+		s.listFunctionsByCodeSigningConfig(w, r, strings.TrimSuffix(rest, "/functions"))
+	case r.Method == http.MethodPost:
+		s.updateCodeSigningConfig(w, r, rest)
+	case r.Method == http.MethodDelete:
+		s.deleteCodeSigningConfig(w, rest)
+	default:
+		s.getCodeSigningConfig(w, rest)
+	}
+}
+
+func (s *Service) createCodeSigningConfig(w http.ResponseWriter, r *http.Request) {
+	bodyBytes, _ := io.ReadAll(r.Body)
+	var params map[string]interface{}
+	if err := json.Unmarshal(bodyBytes, &params); err != nil {
+		writeJSONError(w, "InvalidParameterValueException", "could not parse request body", http.StatusBadRequest)
+		return
+	}
+	allowed, _ := params["AllowedPublishers"].(map[string]interface{})
+	profiles := getStrings(allowed, "SigningProfileVersionArns")
+	if !checkSigningProfiles(w, profiles) {
+		return
+	}
+	untrusted, ok := untrustedArtifactPolicy(w, params, "Warn")
+	if !ok {
+		return
+	}
+
+	id := newCodeSigningConfigID()
+	csc := &codeSigningConfig{
+		id:           id,
+		arn:          fmt.Sprintf("arn:aws:lambda:us-east-1:%s:code-signing-config:%s", defaultAccountID, id),
+		description:  getString(params, "Description"),
+		profiles:     profiles,
+		untrusted:    untrusted,
+		lastModified: time.Now().UTC().Format(time.RFC3339),
+	}
+
+	s.mu.Lock()
+	s.codeSigningConfigs[csc.arn] = csc
+	s.mu.Unlock()
+
+	writeJSON(w, http.StatusCreated, map[string]interface{}{
+		"CodeSigningConfig": csc.toMap(),
+	})
+}
+
+func (s *Service) updateCodeSigningConfig(w http.ResponseWriter, r *http.Request, arn string) {
+	bodyBytes, _ := io.ReadAll(r.Body)
+	var params map[string]interface{}
+	if len(bodyBytes) > 0 {
+		json.Unmarshal(bodyBytes, &params)
+	}
+	var profiles []string
+	if allowed, ok := params["AllowedPublishers"].(map[string]interface{}); ok {
+		profiles = getStrings(allowed, "SigningProfileVersionArns")
+		if !checkSigningProfiles(w, profiles) {
+			return
+		}
+	}
+	untrusted, ok := untrustedArtifactPolicy(w, params, "")
+	if !ok {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	csc := s.namedCodeSigningConfig(w, arn)
+	if csc == nil {
+		return
+	}
+	if profiles != nil {
+		csc.profiles = profiles
+	}
+	if untrusted != "" {
+		csc.untrusted = untrusted
+	}
+	if _, ok := params["Description"]; ok {
+		csc.description = getString(params, "Description")
+	}
+	csc.lastModified = time.Now().UTC().Format(time.RFC3339)
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"CodeSigningConfig": csc.toMap(),
+	})
+}
+
+func (s *Service) getCodeSigningConfig(w http.ResponseWriter, arn string) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	csc := s.namedCodeSigningConfig(w, arn)
+	if csc == nil {
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"CodeSigningConfig": csc.toMap(),
+	})
+}
+
+// deleteCodeSigningConfig deletes a code signing config. As in Lambda, a
+// config still attached to a function cannot be deleted.
+func (s *Service) deleteCodeSigningConfig(w http.ResponseWriter, arn string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.namedCodeSigningConfig(w, arn) == nil {
+		return
+	}
+	if fns := s.functionsUsing(arn); len(fns) > 0 {
+		writeJSONError(w, "ResourceConflictException",
+			fmt.Sprintf("The code signing config %s is in use by function %s.", arn, fns[0].name), http.StatusConflict)
+		return
+	}
+	delete(s.codeSigningConfigs, arn)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Service) listCodeSigningConfigs(w http.ResponseWriter, r *http.Request) {
+	s.mu.RLock()
+	list := make([]map[string]interface{}, 0, len(s.codeSigningConfigs))
+	for _, csc := range s.codeSigningConfigs {
+		list = append(list, csc.toMap())
+	}
+	s.mu.RUnlock()
+	sort.Slice(list, func(i, j int) bool {
+		return list[i]["CodeSigningConfigArn"].(string) < list[j]["CodeSigningConfigArn"].(string)
+	})
+
+	limit, _ := strconv.Atoi(r.URL.Query().Get("MaxItems"))
+	page, next, err := paginate.Page(list, func(i int) string { return list[i]["CodeSigningConfigArn"].(string) }, r.URL.Query().Get("Marker"), limit, 10000)
+	if err != nil {
+		writeJSONError(w, "InvalidParameterValueException", "Invalid Marker.", http.StatusBadRequest)
+		return
+	}
+	resp := map[string]interface{}{"CodeSigningConfigs": page}
+	if next != "" {
+		resp["NextMarker"] = next
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (s *Service) listFunctionsByCodeSigningConfig(w http.ResponseWriter, r *http.Request, arn string) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.namedCodeSigningConfig(w, arn) == nil {
+		return
+	}
+	fns := s.functionsUsing(arn)
+	arns := make([]string, 0, len(fns))
+	for _, fn := range fns {
+		arns = append(arns, fn.arn)
+	}
+
+	limit, _ := strconv.Atoi(r.URL.Query().Get("MaxItems"))
+	page, next, err := paginate.Page(arns, func(i int) string { return arns[i] }, r.URL.Query().Get("Marker"), limit, 10000)
+	if err != nil {
+		writeJSONError(w, "InvalidParameterValueException", "Invalid Marker.", http.StatusBadRequest)
+		return
+	}
+	resp := map[string]interface{}{"FunctionArns": page}
+	if next != "" {
+		resp["NextMarker"] = next
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// handleFunctionCodeSigningConfig serves PutFunctionCodeSigningConfig,
+// GetFunctionCodeSigningConfig, and DeleteFunctionCodeSigningConfig.
+func (s *Service) handleFunctionCodeSigningConfig(w http.ResponseWriter, r *http.Request, name string) {
+	var arn string
+	if r.Method == http.MethodPut {
+		bodyBytes, _ := io.ReadAll(r.Body)
+		var params map[string]interface{}
+		json.Unmarshal(bodyBytes, &params)
+		arn = getString(params, "CodeSigningConfigArn")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	fn := s.namedFunction(w, name)
+	if fn == nil {
+		return
+	}
+	switch r.Method {
+	case http.MethodPut:
+		if s.namedCodeSigningConfig(w, arn) == nil {
+			return
+		}
+		fn.codeSigningConfig = arn
+	case http.MethodDelete:
+		fn.codeSigningConfig = ""
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"CodeSigningConfigArn": fn.codeSigningConfig,
+		"FunctionName":         fn.name,
+	})
+}
+
+// namedCodeSigningConfig returns the code signing config with the given
+// ARN, writing an error and returning nil if it does not exist. The caller
+// must hold s.mu.
+func (s *Service) namedCodeSigningConfig(w http.ResponseWriter, arn string) *codeSigningConfig {
+	csc, exists := s.codeSigningConfigs[arn]
+	if !exists {
+		writeJSONError(w, "CodeSigningConfigNotFoundException",
+			fmt.Sprintf("The code signing configuration cannot be found. Check that the provided configuration is not deleted: %s.", arn), http.StatusNotFound)
+		return nil
+	}
+	return csc
+}
+
+// functionsUsing returns the functions the code signing config is attached
+// to, sorted by name. The caller must hold s.mu.
+func (s *Service) functionsUsing(arn string) []*function {
+	var fns []*function
+	for _, fn := range s.functions {
+		if fn.codeSigningConfig == arn {
+			fns = append(fns, fn)
+		}
+	}
+	sort.Slice(fns, func(i, j int) bool { return fns[i].name < fns[j].name })
+	return fns
+}
+
+func (csc *codeSigningConfig) toMap() map[string]interface{} {
+	return map[string]interface{}{
+		"CodeSigningConfigId":  csc.id,
+		"CodeSigningConfigArn": csc.arn,
+		"Description":          csc.description,
+		"AllowedPublishers": map[string]interface{}{
+			"SigningProfileVersionArns": csc.profiles,
+		},
+		"CodeSigningPolicies": map[string]interface{}{
+			"UntrustedArtifactOnDeployment": csc.untrusted,
+		},
+		"LastModified": csc.lastModified,
+	}
+}
+
+// checkSigningProfiles writes an error and returns false unless profiles
+// holds between 1 and maxSigningProfiles signing profile version ARNs.
+func checkSigningProfiles(w http.ResponseWriter, profiles []string) bool {
+	if len(profiles) == 0 || len(profiles) > maxSigningProfiles {
+		writeJSONError(w, "ValidationException",
+			fmt.Sprintf("1 validation error detected: Value at 'allowedPublishers.signingProfileVersionArns' failed to satisfy constraint: Member must have length between 1 and %d", maxSigningProfiles), http.StatusBadRequest)
+		return false
+	}
+	for _, p := range profiles {
+		if !strings.HasPrefix(p, "arn:aws:signer:") {
+			writeJSONError(w, "ValidationException",
+				fmt.Sprintf("1 validation error detected: Value '%s' at 'allowedPublishers.signingProfileVersionArns' failed to satisfy constraint: Member must be a signing profile version ARN", p), http.StatusBadRequest)
+			return false
+		}
+	}
+	return true
+}
+
+// untrustedArtifactPolicy returns the UntrustedArtifactOnDeployment policy
+// of a request, or def if it is absent, writing an error and returning false
+// if it is invalid.
+func untrustedArtifactPolicy(w http.ResponseWriter, params map[string]interface{}, def string) (string, bool) {
+	policies, _ := params["CodeSigningPolicies"].(map[string]interface{})
+	switch v := getString(policies, "UntrustedArtifactOnDeployment"); v {
+	case "":
+		return def, true
+	case "Warn", "Enforce":
+		return v, true
+	default:
+		writeJSONError(w, "ValidationException",
+			fmt.Sprintf("1 validation error detected: Value '%s' at 'codeSigningPolicies.untrustedArtifactOnDeployment' failed to satisfy enum value set: [Warn, Enforce]", v), http.StatusBadRequest)
+		return "", false
+	}
+}
+
+func newCodeSigningConfigID() string {
+	const chars = "0123456789abcdef"
+	b := []byte("csc-")
+	for i := 0; i < 17; i++ {
+		b = append(b, chars[rand.Intn(len(chars))])
+	}
+	return string(b)
+}
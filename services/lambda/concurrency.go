@@ -0,0 +1,245 @@
+package lambda
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/riyanimam/goto/internal/lifecycle"
+	"github.com/riyanimam/goto/internal/paginate"
+)
+
+// Default account limits, as in a new AWS account.
+const (
+	defaultConcurrentExecutions = 1000
+	defaultCodeStorage          = 75 << 30
+	codeSizeZipped              = 50 << 20
+	codeSizeUnzipped            = 250 << 20
+	// minUnreservedConcurrency is the concurrency Lambda keeps free for
+	// functions without reserved or provisioned concurrency.
+	minUnreservedConcurrency = 100
+)
+
+// provisionedConcurrency is the provisioned concurrency of one version or
+// alias of a function.
+type provisionedConcurrency struct {
+	requested    int
+	lastModified string
+	ready        lifecycle.Transition
+}
+
+// SetQuota sets an account limit: "concurrent-executions" caps the sum of
+// provisioned concurrency, and "code-storage" caps, in bytes, the code that
+// functions and layers hold.
+func (s *Service) SetQuota(resource string, limit int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch resource {
+	case "concurrent-executions":
+		s.concurrencyLimit = limit
+	case "code-storage":
+		s.codeStorageLimit = int64(limit)
+	default:
+		return fmt.Errorf("lambda has no %q quota", resource)
+	}
+	return nil
+}
+
+// codeStorage returns the bytes of code functions and layers hold. The
+// caller must hold s.mu.
+func (s *Service) codeStorage() int64 {
+	var total int64
+	for _, fn := range s.functions {
+		total += fn.codeSize
+	}
+	for _, l := range s.layers {
+		for _, lv := range l.versions {
+			total += lv.codeSize
+		}
+	}
+	return total
+}
+
+// checkCodeStorage writes an error and returns false if adding size bytes of
+// code would exceed the account's code storage. The caller must hold s.mu.
+func (s *Service) checkCodeStorage(w http.ResponseWriter, size int64) bool {
+	if s.codeStorage()+size > s.codeStorageLimit {
+		writeJSONError(w, "CodeStorageExceededException", "Code storage limit exceeded.", http.StatusBadRequest)
+		return false
+	}
+	return true
+}
+
+func (s *Service) getAccountSettings(w http.ResponseWriter) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"AccountLimit": map[string]interface{}{
+			"TotalCodeSize":                  s.codeStorageLimit,
+			"CodeSizeZipped":                 codeSizeZipped,
+			"CodeSizeUnzipped":               codeSizeUnzipped,
+			"ConcurrentExecutions":           s.concurrencyLimit,
+			"UnreservedConcurrentExecutions": s.concurrencyLimit,
+		},
+		"AccountUsage": map[string]interface{}{
+			"TotalCodeSize": s.codeStorage(),
+			"FunctionCount": len(s.functions),
+		},
+	})
+}
+
+// handleProvisionedConcurrency serves the provisioned concurrency APIs on
+// /2019-09-30/functions/{name}/provisioned-concurrency. The Qualifier must
+// name a published version or alias; since the mock does not model them, any
+// qualifier other than $LATEST is accepted.
+func (s *Service) handleProvisionedConcurrency(w http.ResponseWriter, r *http.Request, name string) {
+	qualifier := r.URL.Query().Get("Qualifier")
+	switch {
+	case r.URL.Query().Get("List") == "ALL":
+		s.listProvisionedConcurrencyConfigs(w, r, name)
+	case qualifier == "" || qualifier == "$LATEST":
+		writeJSONError(w, "InvalidParameterValueException",
+			"Provisioned Concurrency Configs cannot be applied to unpublished function versions.", http.StatusBadRequest)
+	case r.Method == http.MethodPut:
+		s.putProvisionedConcurrencyConfig(w, r, name, qualifier)
+	case r.Method == http.MethodDelete:
+		s.deleteProvisionedConcurrencyConfig(w, name, qualifier)
+	default:
+		s.getProvisionedConcurrencyConfig(w, name, qualifier)
+	}
+}
+
+// putProvisionedConcurrencyConfig allocates concurrency to a version or
+// alias. The config is IN_PROGRESS until the allocation completes, and the
+// account must keep minUnreservedConcurrency unallocated.
+func (s *Service) putProvisionedConcurrencyConfig(w http.ResponseWriter, r *http.Request, name, qualifier string) {
+	bodyBytes, _ := io.ReadAll(r.Body)
+	var params map[string]interface{}
+	if err := json.Unmarshal(bodyBytes, &params); err != nil {
+		writeJSONError(w, "InvalidParameterValueException", "could not parse request body", http.StatusBadRequest)
+		return
+	}
+	requested := getInt(params, "ProvisionedConcurrentExecutions", 0)
+	if requested < 1 {
+		writeJSONError(w, "ValidationException",
+			"1 validation error detected: Value at 'provisionedConcurrentExecutions' failed to satisfy constraint: Member must have value greater than or equal to 1", http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	fn := s.namedFunction(w, name)
+	if fn == nil {
+		return
+	}
+	allocated := requested
+	for _, other := range s.functions {
+		for q, pc := range other.provisioned {
+			if other != fn || q != qualifier {
+				allocated += pc.requested
+			}
+		}
+	}
+	if allocated > s.concurrencyLimit-minUnreservedConcurrency {
+		writeJSONError(w, "InvalidParameterValueException",
+			fmt.Sprintf("Specified ConcurrentExecutions for function decreases account's UnreservedConcurrentExecution below its minimum value of [%d].", minUnreservedConcurrency), http.StatusBadRequest)
+		return
+	}
+	pc := &provisionedConcurrency{
+		requested:    requested,
+		lastModified: time.Now().UTC().Format(time.RFC3339),
+		ready:        s.transitions.Begin(),
+	}
+	fn.provisioned[qualifier] = pc
+	writeJSON(w, http.StatusAccepted, s.provisionedConcurrencyResp(pc))
+}
+
+func (s *Service) getProvisionedConcurrencyConfig(w http.ResponseWriter, name, qualifier string) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	fn := s.namedFunction(w, name)
+	if fn == nil {
+		return
+	}
+	pc, exists := fn.provisioned[qualifier]
+	if !exists {
+		writeJSONError(w, "ProvisionedConcurrencyConfigNotFoundException",
+			"No Provisioned Concurrency Config found for this function", http.StatusNotFound)
+		return
+	}
+	writeJSON(w, http.StatusOK, s.provisionedConcurrencyResp(pc))
+}
+
+func (s *Service) deleteProvisionedConcurrencyConfig(w http.ResponseWriter, name, qualifier string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	fn := s.namedFunction(w, name)
+	if fn == nil {
+		return
+	}
+	delete(fn.provisioned, qualifier)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Service) listProvisionedConcurrencyConfigs(w http.ResponseWriter, r *http.Request, name string) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	fn := s.namedFunction(w, name)
+	if fn == nil {
+		return
+	}
+	qualifiers := make([]string, 0, len(fn.provisioned))
+	for q := range fn.provisioned {
+		qualifiers = append(qualifiers, q)
+	}
+	sort.Strings(qualifiers)
+
+	limit, _ := strconv.Atoi(r.URL.Query().Get("MaxItems"))
+	page, next, err := paginate.Page(qualifiers, func(i int) string { return qualifiers[i] }, r.URL.Query().Get("Marker"), limit, 50)
+	if err != nil {
+		writeJSONError(w, "InvalidParameterValueException", "Invalid Marker.", http.StatusBadRequest)
+		return
+	}
+	configs := make([]map[string]interface{}, 0, len(page))
+	for _, q := range page {
+		config := s.provisionedConcurrencyResp(fn.provisioned[q])
+		config["FunctionArn"] = fn.arn + ":" + q
+		configs = append(configs, config)
+	}
+	resp := map[string]interface{}{"ProvisionedConcurrencyConfigs": configs}
+	if next != "" {
+		resp["NextMarker"] = next
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// provisionedConcurrencyResp returns the description of pc. Nothing is
+// available until the allocation completes. The caller must hold s.mu.
+func (s *Service) provisionedConcurrencyResp(pc *provisionedConcurrency) map[string]interface{} {
+	available := 0
+	if s.transitions.Done(pc.ready) {
+		available = pc.requested
+	}
+	return map[string]interface{}{
+		"RequestedProvisionedConcurrentExecutions": pc.requested,
+		"AvailableProvisionedConcurrentExecutions": available,
+		"AllocatedProvisionedConcurrentExecutions": available,
+		"Status":       s.transitions.Status(pc.ready, "IN_PROGRESS", "READY"),
+		"LastModified": pc.lastModified,
+	}
+}
+
+// namedFunction returns the named function, writing an error and returning
+// nil if it does not exist. The caller must hold s.mu.
+func (s *Service) namedFunction(w http.ResponseWriter, name string) *function {
+	fn, exists := s.functions[name]
+	if !exists {
+		writeJSONError(w, "ResourceNotFoundException", "Function not found: arn:aws:lambda:us-east-1:"+defaultAccountID+":function:"+name, http.StatusNotFound)
+		return nil
+	}
+	return fn
+}
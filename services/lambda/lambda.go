@@ -8,18 +8,53 @@
 //   - Invoke
 //   - UpdateFunctionCode
 //   - UpdateFunctionConfiguration
+//   - TagResource
+//   - UntagResource
+//   - ListTags
+//   - PublishLayerVersion
+//   - GetLayerVersion
+//   - GetLayerVersionByArn
+//   - DeleteLayerVersion
+//   - ListLayerVersions
+//   - ListLayers
+//   - CreateCodeSigningConfig
+//   - GetCodeSigningConfig
+//   - UpdateCodeSigningConfig
+//   - DeleteCodeSigningConfig
+//   - ListCodeSigningConfigs
+//   - ListFunctionsByCodeSigningConfig
+//   - PutFunctionCodeSigningConfig
+//   - GetFunctionCodeSigningConfig
+//   - DeleteFunctionCodeSigningConfig
+//   - GetAccountSettings
+//   - PutProvisionedConcurrencyConfig
+//   - GetProvisionedConcurrencyConfig
+//   - DeleteProvisionedConcurrencyConfig
+//   - ListProvisionedConcurrencyConfigs
+//
+// Functions echo their payload unless Go code has been registered for them
+// with SetHandler.
 package lambda
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"math/rand"
 	"net/http"
 	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
+
+	"github.com/riyanimam/goto/internal/arn"
+	"github.com/riyanimam/goto/internal/awserr"
+	"github.com/riyanimam/goto/internal/lifecycle"
+	"github.com/riyanimam/goto/internal/mockhelpers"
+	"github.com/riyanimam/goto/internal/paginate"
+	"github.com/riyanimam/goto/internal/tags"
 )
 
 const defaultAccountID = "123456789012"
@@ -28,8 +63,24 @@ const defaultAccountID = "123456789012"
 type Service struct {
 	mu        sync.RWMutex
 	functions map[string]*function // keyed by function name
+	handlers  map[string]HandlerFunc
+	tags      *tags.Store
+	resolve   mockhelpers.Resolver
+
+	layers             map[string]*layer             // keyed by layer name
+	codeSigningConfigs map[string]*codeSigningConfig // keyed by ARN
+
+	concurrencyLimit int
+	codeStorageLimit int64
+
+	transitions *lifecycle.Transitions
 }
 
+// HandlerFunc is Go code standing in for a function's deployment package. It
+// receives the invocation payload and returns the function's response; an
+// error is reported to the caller as an unhandled function error.
+type HandlerFunc func(ctx context.Context, payload []byte) ([]byte, error)
+
 type function struct {
 	name         string
 	arn          string
@@ -44,12 +95,37 @@ type function struct {
 	version      string
 	lastModified string
 	environment  map[string]string
+	layers       []functionLayer
+	invocations  []Invocation
+	ready        lifecycle.Transition // of the create
+	updated      lifecycle.Transition // of the last code or configuration update
+
+	codeSigningConfig string                             // ARN, or "" if code signing is off
+	provisioned       map[string]*provisionedConcurrency // keyed by qualifier
+}
+
+// Invocation records one call to a function.
+type Invocation struct {
+	FunctionName string
+	// InvocationType is RequestResponse, Event, or DryRun. Payloads
+	// delivered by other services (schedules, event rules) are Event.
+	InvocationType string
+	Payload        []byte
+	Timestamp      time.Time
 }
 
 // New creates a new Lambda mock service.
 func New() *Service {
 	return &Service{
 		functions: make(map[string]*function),
+		handlers:  make(map[string]HandlerFunc),
+		tags:      tags.New(),
+
+		layers:             make(map[string]*layer),
+		codeSigningConfigs: make(map[string]*codeSigningConfig),
+
+		concurrencyLimit: defaultConcurrentExecutions,
+		codeStorageLimit: defaultCodeStorage,
 	}
 }
 
@@ -61,17 +137,66 @@ func (s *Service) Handler() http.Handler {
 	return http.HandlerFunc(s.handle)
 }
 
-// Reset clears all functions.
+// Reset clears all functions, layers, and code signing configs.
 func (s *Service) Reset() {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	s.functions = make(map[string]*function)
+	s.layers = make(map[string]*layer)
+	s.codeSigningConfigs = make(map[string]*codeSigningConfig)
+	s.tags.DeleteService("lambda")
+}
+
+// SetTagStore sets the registry function tags are recorded in.
+func (s *Service) SetTagStore(store *tags.Store) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tags = store
+}
+
+// SetResolver sets the function used to check that execution roles exist.
+func (s *Service) SetResolver(r mockhelpers.Resolver) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.resolve = r
+}
+
+// SetTransitions sets how long new functions stay Pending and updates stay
+// InProgress.
+func (s *Service) SetTransitions(t *lifecycle.Transitions) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.transitions = t
+}
+
+// SetHandler makes invocations of the named function run fn instead of
+// echoing their payload. The handler applies to any function created with
+// that name, and survives Reset.
+func (s *Service) SetHandler(name string, fn HandlerFunc) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.handlers[name] = fn
 }
 
 func (s *Service) handle(w http.ResponseWriter, r *http.Request) {
 	path := r.URL.Path
 
 	switch {
+	case strings.Contains(path, "/tags/"):
+		arn := path[strings.Index(path, "/tags/")+len("/tags/"):]
+		s.handleTags(w, r, arn)
+	case strings.HasPrefix(path, "/2016-08-19/account-settings"):
+		s.getAccountSettings(w)
+	case strings.HasPrefix(path, "/2018-10-31/layers"):
+		s.handleLayers(w, r)
+	case strings.HasPrefix(path, "/2020-04-22/code-signing-configs"):
+		s.handleCodeSigningConfigs(w, r)
+	case strings.Contains(path, "/functions/") && strings.HasSuffix(path, "/code-signing-config"):
+		name := extractFunctionName(path, "/code-signing-config")
+		s.handleFunctionCodeSigningConfig(w, r, name)
+	case strings.Contains(path, "/functions/") && strings.HasSuffix(path, "/provisioned-concurrency"):
+		name := extractFunctionName(path, "/provisioned-concurrency")
+		s.handleProvisionedConcurrency(w, r, name)
 	case strings.HasSuffix(path, "/functions") && r.Method == http.MethodGet:
 		s.listFunctions(w, r)
 	case strings.HasSuffix(path, "/functions") && r.Method == http.MethodPost:
@@ -131,6 +256,9 @@ func (s *Service) createFunction(w http.ResponseWriter, r *http.Request) {
 		writeJSONError(w, "InvalidParameterValueException", "FunctionName is required", http.StatusBadRequest)
 		return
 	}
+	if !s.checkRole(w, getString(params, "Role")) {
+		return
+	}
 
 	s.mu.Lock()
 	if _, exists := s.functions[name]; exists {
@@ -138,6 +266,20 @@ func (s *Service) createFunction(w http.ResponseWriter, r *http.Request) {
 		writeJSONError(w, "ResourceConflictException", "Function already exist: "+name, http.StatusConflict)
 		return
 	}
+	layers, ok := s.functionLayers(w, getStrings(params, "Layers"))
+	if !ok {
+		s.mu.Unlock()
+		return
+	}
+	csc := getString(params, "CodeSigningConfigArn")
+	if csc != "" && s.namedCodeSigningConfig(w, csc) == nil {
+		s.mu.Unlock()
+		return
+	}
+	if !s.checkCodeStorage(w, 1024) {
+		s.mu.Unlock()
+		return
+	}
 
 	fn := &function{
 		name:         name,
@@ -152,6 +294,11 @@ func (s *Service) createFunction(w http.ResponseWriter, r *http.Request) {
 		codeSHA256:   "abc123def456",
 		version:      "$LATEST",
 		lastModified: time.Now().UTC().Format(time.RFC3339),
+		layers:       layers,
+		ready:        s.transitions.Begin(),
+
+		codeSigningConfig: csc,
+		provisioned:       make(map[string]*provisionedConcurrency),
 	}
 
 	if env, ok := params["Environment"].(map[string]interface{}); ok {
@@ -166,6 +313,9 @@ func (s *Service) createFunction(w http.ResponseWriter, r *http.Request) {
 	}
 
 	s.functions[name] = fn
+	if raw, ok := params["Tags"].(map[string]interface{}); ok {
+		s.tags.Tag(fn.arn, toStringMap(raw))
+	}
 	s.mu.Unlock()
 
 	writeJSON(w, http.StatusCreated, s.functionConfig(fn))
@@ -187,23 +337,26 @@ func (s *Service) getFunction(w http.ResponseWriter, _ *http.Request, name strin
 			"RepositoryType": "S3",
 			"Location":       "https://awslambda-us-east-1-tasks.s3.us-east-1.amazonaws.com/...",
 		},
+		"Tags": s.tags.Get(fn.arn),
 	})
 }
 
 func (s *Service) deleteFunction(w http.ResponseWriter, _ *http.Request, name string) {
 	s.mu.Lock()
-	if _, exists := s.functions[name]; !exists {
+	fn, exists := s.functions[name]
+	if !exists {
 		s.mu.Unlock()
 		writeJSONError(w, "ResourceNotFoundException", "Function not found: arn:aws:lambda:us-east-1:"+defaultAccountID+":function:"+name, http.StatusNotFound)
 		return
 	}
+	s.tags.Delete(fn.arn)
 	delete(s.functions, name)
 	s.mu.Unlock()
 
 	w.WriteHeader(http.StatusNoContent)
 }
 
-func (s *Service) listFunctions(w http.ResponseWriter, _ *http.Request) {
+func (s *Service) listFunctions(w http.ResponseWriter, r *http.Request) {
 	s.mu.RLock()
 	var fns []map[string]interface{}
 	for _, fn := range s.functions {
@@ -215,33 +368,173 @@ func (s *Service) listFunctions(w http.ResponseWriter, _ *http.Request) {
 		return fns[i]["FunctionName"].(string) < fns[j]["FunctionName"].(string)
 	})
 
-	writeJSON(w, http.StatusOK, map[string]interface{}{
-		"Functions": fns,
-	})
-}
-
-func (s *Service) invoke(w http.ResponseWriter, r *http.Request, name string) {
-	s.mu.RLock()
-	_, exists := s.functions[name]
-	s.mu.RUnlock()
-
-	if !exists {
-		writeJSONError(w, "ResourceNotFoundException", "Function not found: arn:aws:lambda:us-east-1:"+defaultAccountID+":function:"+name, http.StatusNotFound)
+	limit, _ := strconv.Atoi(r.URL.Query().Get("MaxItems"))
+	if limit <= 0 {
+		limit = 50
+	}
+	page, next, err := paginate.Page(fns, func(i int) string { return fns[i]["FunctionName"].(string) }, r.URL.Query().Get("Marker"), limit, 10000)
+	if err != nil {
+		writeJSONError(w, "InvalidParameterValueException", "Invalid Marker.", http.StatusBadRequest)
 		return
 	}
+	resp := map[string]interface{}{
+		"Functions": page,
+	}
+	if next != "" {
+		resp["NextMarker"] = next
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
 
+func (s *Service) invoke(w http.ResponseWriter, r *http.Request, name string) {
 	// Read the payload.
 	payload, _ := io.ReadAll(r.Body)
 	if len(payload) == 0 {
 		payload = []byte("{}")
 	}
 
-	// Return the payload as the response (echo function behavior).
+	invocationType := r.Header.Get("X-Amz-Invocation-Type")
+	if invocationType == "" {
+		invocationType = "RequestResponse"
+	}
+	if !s.record(name, invocationType, payload) {
+		writeJSONError(w, "ResourceNotFoundException", "Function not found: arn:aws:lambda:us-east-1:"+defaultAccountID+":function:"+name, http.StatusNotFound)
+		return
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	w.Header().Set("X-Amz-Executed-Version", "$LATEST")
+
+	out, err := s.run(r.Context(), name, payload)
+	if err != nil {
+		w.Header().Set("X-Amz-Function-Error", "Unhandled")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"errorMessage": err.Error(),
+			"errorType":    fmt.Sprintf("%T", err),
+		})
+		return
+	}
+
 	w.Header().Set("X-Amz-Function-Error", "")
 	w.WriteHeader(http.StatusOK)
-	w.Write(payload)
+	w.Write(out)
+}
+
+// run executes the function's registered handler, or echoes the payload if
+// it has none.
+func (s *Service) run(ctx context.Context, name string, payload []byte) ([]byte, error) {
+	s.mu.RLock()
+	fn := s.handlers[name]
+	s.mu.RUnlock()
+
+	if fn == nil {
+		return payload, nil
+	}
+	return fn(ctx, payload)
+}
+
+// HasResource reports whether the function identified by arn exists. A
+// version or alias qualifier on the ARN is ignored.
+func (s *Service) HasResource(arn string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	_, exists := s.functions[functionName(arn)]
+	return exists
+}
+
+// Deliver invokes the function identified by arn with payload and returns
+// the function's response.
+func (s *Service) Deliver(arn string, payload []byte) ([]byte, error) {
+	name := functionName(arn)
+	if len(payload) == 0 {
+		payload = []byte("{}")
+	}
+	if !s.record(name, "Event", payload) {
+		return nil, fmt.Errorf("function %s does not exist", arn)
+	}
+	return s.run(context.Background(), name, payload)
+}
+
+// functionName extracts the function name from a function ARN, dropping any
+// version or alias qualifier. Names that are not ARNs are returned as is.
+func functionName(arn string) string {
+	name := arn
+	if i := strings.Index(arn, ":function:"); i >= 0 {
+		name = arn[i+len(":function:"):]
+	}
+	if i := strings.Index(name, ":"); i >= 0 {
+		name = name[:i] // strip version or alias qualifier
+	}
+	return name
+}
+
+// record appends an invocation to the function's history, reporting false if
+// the function does not exist.
+func (s *Service) record(name, invocationType string, payload []byte) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	fn, exists := s.functions[name]
+	if !exists {
+		return false
+	}
+	fn.invocations = append(fn.invocations, Invocation{
+		FunctionName:   name,
+		InvocationType: invocationType,
+		Payload:        append([]byte(nil), payload...),
+		Timestamp:      time.Now().UTC(),
+	})
+	return true
+}
+
+// Invocations returns the calls made to the named function, oldest first.
+// name may also be the function's ARN.
+func (s *Service) Invocations(name string) ([]Invocation, error) {
+	if i := strings.Index(name, ":function:"); i >= 0 {
+		name = name[i+len(":function:"):]
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	fn, exists := s.functions[name]
+	if !exists {
+		return nil, fmt.Errorf("function %s does not exist", name)
+	}
+	return append([]Invocation(nil), fn.invocations...), nil
+}
+
+// handleTags serves TagResource (POST), UntagResource (DELETE), and ListTags
+// (GET) on /2017-03-31/tags/{arn}.
+func (s *Service) handleTags(w http.ResponseWriter, r *http.Request, arn string) {
+	name := arn
+	if i := strings.Index(arn, ":function:"); i >= 0 {
+		name = arn[i+len(":function:"):]
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	fn, exists := s.functions[name]
+	if !exists {
+		writeJSONError(w, "ResourceNotFoundException", "Function not found: "+arn, http.StatusNotFound)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPost:
+		var params map[string]interface{}
+		bodyBytes, _ := io.ReadAll(r.Body)
+		json.Unmarshal(bodyBytes, &params)
+		raw, _ := params["Tags"].(map[string]interface{})
+		s.tags.Tag(fn.arn, toStringMap(raw))
+		w.WriteHeader(http.StatusNoContent)
+	case http.MethodDelete:
+		s.tags.Untag(fn.arn, r.URL.Query()["tagKeys"])
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"Tags": s.tags.Get(fn.arn),
+		})
+	}
 }
 
 func (s *Service) updateFunctionCode(w http.ResponseWriter, r *http.Request, name string) {
@@ -253,6 +546,7 @@ func (s *Service) updateFunctionCode(w http.ResponseWriter, r *http.Request, nam
 		return
 	}
 	fn.lastModified = time.Now().UTC().Format(time.RFC3339)
+	fn.updated = s.transitions.Begin()
 	fn.codeSHA256 = "updated-sha256"
 	config := s.functionConfig(fn)
 	s.mu.Unlock()
@@ -266,6 +560,10 @@ func (s *Service) updateFunctionConfiguration(w http.ResponseWriter, r *http.Req
 	if len(bodyBytes) > 0 {
 		json.Unmarshal(bodyBytes, &params)
 	}
+	role := getString(params, "Role")
+	if role != "" && !s.checkRole(w, role) {
+		return
+	}
 
 	s.mu.Lock()
 	fn, exists := s.functions[name]
@@ -274,7 +572,18 @@ func (s *Service) updateFunctionConfiguration(w http.ResponseWriter, r *http.Req
 		writeJSONError(w, "ResourceNotFoundException", "Function not found: "+name, http.StatusNotFound)
 		return
 	}
+	if _, ok := params["Layers"]; ok {
+		layers, ok := s.functionLayers(w, getStrings(params, "Layers"))
+		if !ok {
+			s.mu.Unlock()
+			return
+		}
+		fn.layers = layers
+	}
 
+	if role != "" {
+		fn.role = role
+	}
 	if v := getString(params, "Description"); v != "" {
 		fn.description = v
 	}
@@ -291,12 +600,32 @@ func (s *Service) updateFunctionConfiguration(w http.ResponseWriter, r *http.Req
 		fn.memorySize = v
 	}
 	fn.lastModified = time.Now().UTC().Format(time.RFC3339)
+	fn.updated = s.transitions.Begin()
 	config := s.functionConfig(fn)
 	s.mu.Unlock()
 
 	writeJSON(w, http.StatusOK, config)
 }
 
+// checkRole writes an error and returns false unless role is the ARN of an
+// IAM role that exists, as Lambda requires of execution roles.
+func (s *Service) checkRole(w http.ResponseWriter, role string) bool {
+	a, err := arn.Parse(role)
+	if err != nil || a.Service != "iam" || a.ResourceType() != "role" {
+		writeJSONError(w, "ValidationException", "1 validation error detected: Value '"+role+"' at 'role' failed to satisfy constraint: Member must be an IAM role ARN", http.StatusBadRequest)
+		return false
+	}
+
+	s.mu.RLock()
+	resolve := s.resolve
+	s.mu.RUnlock()
+	if resolve != nil && resolve(role) != nil {
+		writeJSONError(w, "InvalidParameterValueException", "The role defined for the function cannot be assumed by Lambda.", http.StatusBadRequest)
+		return false
+	}
+	return true
+}
+
 func (s *Service) functionConfig(fn *function) map[string]interface{} {
 	cfg := map[string]interface{}{
 		"FunctionName":     fn.name,
@@ -311,14 +640,21 @@ func (s *Service) functionConfig(fn *function) map[string]interface{} {
 		"CodeSha256":       fn.codeSHA256,
 		"Version":          fn.version,
 		"LastModified":     fn.lastModified,
-		"State":            "Active",
-		"LastUpdateStatus": "Successful",
+		"State":            s.transitions.Status(fn.ready, "Pending", "Active"),
+		"LastUpdateStatus": s.transitions.Status(fn.updated, "InProgress", "Successful"),
 	}
 	if fn.environment != nil {
 		cfg["Environment"] = map[string]interface{}{
 			"Variables": fn.environment,
 		}
 	}
+	if len(fn.layers) > 0 {
+		layers := make([]map[string]interface{}, 0, len(fn.layers))
+		for _, l := range fn.layers {
+			layers = append(layers, map[string]interface{}{"Arn": l.arn, "CodeSize": l.codeSize})
+		}
+		cfg["Layers"] = layers
+	}
 	return cfg
 }
 
@@ -352,12 +688,7 @@ func writeJSON(w http.ResponseWriter, status int, v interface{}) {
 }
 
 func writeJSONError(w http.ResponseWriter, code, message string, status int) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(status)
-	json.NewEncoder(w).Encode(map[string]interface{}{
-		"Type":    code,
-		"Message": message,
-	})
+	awserr.WriteJSON(w, "application/json", code, message, status)
 }
 
 func newRequestID() string {
@@ -377,3 +708,13 @@ func newRequestID() string {
 	}
 	return string(b[:pos])
 }
+
+func toStringMap(raw map[string]interface{}) map[string]string {
+	m := make(map[string]string, len(raw))
+	for k, v := range raw {
+		if sv, ok := v.(string); ok {
+			m[k] = sv
+		}
+	}
+	return m
+}
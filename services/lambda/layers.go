@@ -0,0 +1,336 @@
+package lambda
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/riyanimam/goto/internal/paginate"
+)
+
+// maxLayers is the number of layers a function can use.
+const maxLayers = 5
+
+// layer is a Lambda layer and its published versions.
+type layer struct {
+	name     string
+	arn      string
+	versions []*layerVersion // oldest first; deleted versions are removed
+	next     int             // version number of the next publish
+}
+
+type layerVersion struct {
+	version       int
+	arn           string
+	description   string
+	created       string
+	runtimes      []string
+	architectures []string
+	license       string
+	codeSize      int64
+	codeSHA256    string
+}
+
+// handleLayers serves the layer APIs under /2018-10-31/layers:
+// ListLayers and GetLayerVersionByArn on the collection, PublishLayerVersion
+// and ListLayerVersions on {name}/versions, and GetLayerVersion and
+// DeleteLayerVersion on {name}/versions/{number}.
+func (s *Service) handleLayers(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/2018-10-31/layers"), "/"), "/")
+	switch {
+	case parts[0] == "" && r.URL.Query().Get("find") == "LayerVersion":
+		s.getLayerVersionByArn(w, r.URL.Query().Get("Arn"))
+	case parts[0] == "":
+		s.listLayers(w, r)
+	case len(parts) == 2 && r.Method == http.MethodPost:
+		s.publishLayerVersion(w, r, parts[0])
+	case len(parts) == 2:
+		s.listLayerVersions(w, r, parts[0])
+	case len(parts) == 3:
+		version, _ := strconv.Atoi(parts[2])
+		if r.Method == http.MethodDelete {
+			s.deleteLayerVersion(w, parts[0], version)
+		} else {
+			s.getLayerVersion(w, parts[0], version)
+		}
+	default:
+		writeJSONError(w, "InvalidAction", "unsupported operation", http.StatusBadRequest)
+	}
+}
+
+func (s *Service) publishLayerVersion(w http.ResponseWriter, r *http.Request, name string) {
+	bodyBytes, _ := io.ReadAll(r.Body)
+	var params map[string]interface{}
+	if err := json.Unmarshal(bodyBytes, &params); err != nil {
+		writeJSONError(w, "InvalidParameterValueException", "could not parse request body", http.StatusBadRequest)
+		return
+	}
+	content, _ := params["Content"].(map[string]interface{})
+	var code []byte
+	switch {
+	case getString(content, "ZipFile") != "":
+		code, _ = base64.StdEncoding.DecodeString(getString(content, "ZipFile"))
+	case getString(content, "S3Bucket") != "" && getString(content, "S3Key") != "":
+		// The object is not read; its location stands in for the code.
+		code = []byte("s3://" + getString(content, "S3Bucket") + "/" + getString(content, "S3Key"))
+	default:
+		writeJSONError(w, "InvalidParameterValueException", "Content must specify ZipFile or S3Bucket and S3Key", http.StatusBadRequest)
+		return
+	}
+	sum := sha256.Sum256(code)
+
+	s.mu.Lock()
+	if !s.checkCodeStorage(w, int64(len(code))) {
+		s.mu.Unlock()
+		return
+	}
+	l, exists := s.layers[name]
+	if !exists {
+		l = &layer{
+			name: name,
+			arn:  fmt.Sprintf("arn:aws:lambda:us-east-1:%s:layer:%s", defaultAccountID, name),
+			next: 1,
+		}
+		s.layers[name] = l
+	}
+	lv := &layerVersion{
+		version:       l.next,
+		arn:           fmt.Sprintf("%s:%d", l.arn, l.next),
+		description:   getString(params, "Description"),
+		created:       time.Now().UTC().Format("2006-01-02T15:04:05.000-0700"),
+		runtimes:      getStrings(params, "CompatibleRuntimes"),
+		architectures: getStrings(params, "CompatibleArchitectures"),
+		license:       getString(params, "LicenseInfo"),
+		codeSize:      int64(len(code)),
+		codeSHA256:    base64.StdEncoding.EncodeToString(sum[:]),
+	}
+	l.versions = append(l.versions, lv)
+	l.next++
+	resp := lv.toMap(l, true)
+	s.mu.Unlock()
+
+	writeJSON(w, http.StatusCreated, resp)
+}
+
+// layerVersionByNumber returns the numbered version of the named layer, or
+// nil. The caller must hold s.mu.
+func (s *Service) layerVersionByNumber(name string, version int) (*layer, *layerVersion) {
+	l, exists := s.layers[name]
+	if !exists {
+		return nil, nil
+	}
+	for _, lv := range l.versions {
+		if lv.version == version {
+			return l, lv
+		}
+	}
+	return l, nil
+}
+
+// layerVersionByARN returns the layer version identified by arn, or nil.
+// The caller must hold s.mu.
+func (s *Service) layerVersionByARN(arn string) (*layer, *layerVersion) {
+	i := strings.LastIndex(arn, ":")
+	_, name, ok := strings.Cut(arn[:i+1], ":layer:")
+	if i < 0 || !ok {
+		return nil, nil
+	}
+	version, err := strconv.Atoi(arn[i+1:])
+	if err != nil {
+		return nil, nil
+	}
+	l, lv := s.layerVersionByNumber(strings.TrimSuffix(name, ":"), version)
+	if lv == nil || lv.arn != arn {
+		return nil, nil
+	}
+	return l, lv
+}
+
+func (s *Service) getLayerVersion(w http.ResponseWriter, name string, version int) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	l, lv := s.layerVersionByNumber(name, version)
+	if lv == nil {
+		writeJSONError(w, "ResourceNotFoundException", "The resource you requested does not exist.", http.StatusNotFound)
+		return
+	}
+	writeJSON(w, http.StatusOK, lv.toMap(l, true))
+}
+
+func (s *Service) getLayerVersionByArn(w http.ResponseWriter, arn string) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	l, lv := s.layerVersionByARN(arn)
+	if lv == nil {
+		writeJSONError(w, "ResourceNotFoundException", "The resource you requested does not exist.", http.StatusNotFound)
+		return
+	}
+	writeJSON(w, http.StatusOK, lv.toMap(l, true))
+}
+
+// deleteLayerVersion deletes a layer version. Its number is not reused, and
+// functions already using it keep working, as in Lambda.
+func (s *Service) deleteLayerVersion(w http.ResponseWriter, name string, version int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if l, exists := s.layers[name]; exists {
+		for i, lv := range l.versions {
+			if lv.version == version {
+				l.versions = append(l.versions[:i], l.versions[i+1:]...)
+				break
+			}
+		}
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// compatible reports whether lv matches the CompatibleRuntime and
+// CompatibleArchitecture filters of a list request.
+func (lv *layerVersion) compatible(r *http.Request) bool {
+	runtime := r.URL.Query().Get("CompatibleRuntime")
+	arch := r.URL.Query().Get("CompatibleArchitecture")
+	return (runtime == "" || containsString(lv.runtimes, runtime)) &&
+		(arch == "" || containsString(lv.architectures, arch))
+}
+
+func (s *Service) listLayerVersions(w http.ResponseWriter, r *http.Request, name string) {
+	s.mu.RLock()
+	var list []map[string]interface{}
+	if l, exists := s.layers[name]; exists {
+		for i := len(l.versions) - 1; i >= 0; i-- {
+			if lv := l.versions[i]; lv.compatible(r) {
+				list = append(list, lv.toMap(l, false))
+			}
+		}
+	}
+	s.mu.RUnlock()
+
+	// Newest first.
+	s.writeLayerPage(w, r, "LayerVersions", list, func(i int) string {
+		return paginate.Desc(paginate.Seq(list[i]["Version"].(int)))
+	})
+}
+
+func (s *Service) listLayers(w http.ResponseWriter, r *http.Request) {
+	s.mu.RLock()
+	names := make([]string, 0, len(s.layers))
+	for name := range s.layers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var list []map[string]interface{}
+	for _, name := range names {
+		l := s.layers[name]
+		for i := len(l.versions) - 1; i >= 0; i-- {
+			if lv := l.versions[i]; lv.compatible(r) {
+				list = append(list, map[string]interface{}{
+					"LayerName":             l.name,
+					"LayerArn":              l.arn,
+					"LatestMatchingVersion": lv.toMap(l, false),
+				})
+				break
+			}
+		}
+	}
+	s.mu.RUnlock()
+
+	s.writeLayerPage(w, r, "Layers", list, func(i int) string { return list[i]["LayerName"].(string) })
+}
+
+// writeLayerPage writes the page of list, sorted by itemKey as in
+// [paginate.Page], selected by the Marker and MaxItems query parameters
+// under key.
+func (s *Service) writeLayerPage(w http.ResponseWriter, r *http.Request, key string, list []map[string]interface{}, itemKey func(i int) string) {
+	limit, _ := strconv.Atoi(r.URL.Query().Get("MaxItems"))
+	page, next, err := paginate.Page(list, itemKey, r.URL.Query().Get("Marker"), limit, 50)
+	if err != nil {
+		writeJSONError(w, "InvalidParameterValueException", "Invalid Marker.", http.StatusBadRequest)
+		return
+	}
+	if page == nil {
+		page = []map[string]interface{}{}
+	}
+	resp := map[string]interface{}{key: page}
+	if next != "" {
+		resp["NextMarker"] = next
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// toMap returns the layer version's description. full adds the Content and
+// LayerArn fields that GetLayerVersion and PublishLayerVersion return.
+func (lv *layerVersion) toMap(l *layer, full bool) map[string]interface{} {
+	m := map[string]interface{}{
+		"LayerVersionArn":         lv.arn,
+		"Version":                 lv.version,
+		"Description":             lv.description,
+		"CreatedDate":             lv.created,
+		"CompatibleRuntimes":      lv.runtimes,
+		"CompatibleArchitectures": lv.architectures,
+		"LicenseInfo":             lv.license,
+	}
+	if full {
+		m["LayerArn"] = l.arn
+		m["Content"] = map[string]interface{}{
+			"Location":   fmt.Sprintf("https://awslambda-us-east-1-layers.s3.us-east-1.amazonaws.com/snapshots/%s/%s-%d", defaultAccountID, l.name, lv.version),
+			"CodeSha256": lv.codeSHA256,
+			"CodeSize":   lv.codeSize,
+		}
+	}
+	return m
+}
+
+// functionLayers resolves the Layers parameter of a create or update
+// request, writing an error and returning false if it names a layer version
+// that does not exist. The caller must hold s.mu.
+func (s *Service) functionLayers(w http.ResponseWriter, arns []string) ([]functionLayer, bool) {
+	if len(arns) > maxLayers {
+		writeJSONError(w, "InvalidParameterValueException",
+			fmt.Sprintf("Cannot reference more than %d layers.", maxLayers), http.StatusBadRequest)
+		return nil, false
+	}
+	layers := make([]functionLayer, 0, len(arns))
+	for _, arn := range arns {
+		_, lv := s.layerVersionByARN(arn)
+		if lv == nil {
+			writeJSONError(w, "InvalidParameterValueException",
+				fmt.Sprintf("Layer version %s does not exist.", arn), http.StatusBadRequest)
+			return nil, false
+		}
+		layers = append(layers, functionLayer{arn: arn, codeSize: lv.codeSize})
+	}
+	return layers, true
+}
+
+// functionLayer is a layer version a function uses.
+type functionLayer struct {
+	arn      string
+	codeSize int64
+}
+
+func getStrings(params map[string]interface{}, key string) []string {
+	raw, _ := params[key].([]interface{})
+	values := make([]string, 0, len(raw))
+	for _, v := range raw {
+		if s, ok := v.(string); ok {
+			values = append(values, s)
+		}
+	}
+	return values
+}
+
+func containsString(values []string, v string) bool {
+	for _, s := range values {
+		if s == v {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,35 @@
+package lambda
+
+import "github.com/riyanimam/goto/internal/mockhelpers"
+
+// ExportState lists the functions with their configuration.
+func (s *Service) ExportState() []mockhelpers.Resource {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	resources := make([]mockhelpers.Resource, 0, len(s.functions))
+	for _, fn := range s.functions {
+		layers := make([]string, 0, len(fn.layers))
+		for _, l := range fn.layers {
+			layers = append(layers, l.arn)
+		}
+		resources = append(resources, mockhelpers.Resource{
+			Type: "aws_lambda_function",
+			ID:   fn.name,
+			ARN:  fn.arn,
+			Attributes: map[string]interface{}{
+				"function_name":    fn.name,
+				"runtime":          fn.runtime,
+				"role":             fn.role,
+				"handler":          fn.handler,
+				"description":      fn.description,
+				"timeout":          fn.timeout,
+				"memory_size":      fn.memorySize,
+				"environment":      fn.environment,
+				"layers":           layers,
+				"source_code_hash": fn.codeSHA256,
+			},
+		})
+	}
+	return resources
+}
@@ -0,0 +1,678 @@
+// Package mediaconvert provides a mock implementation of AWS Elemental
+// MediaConvert.
+//
+// Supported actions:
+//   - DescribeEndpoints
+//   - CreateJob
+//   - GetJob
+//   - ListJobs
+//   - CancelJob
+//
+// No transcoding happens. A job is SUBMITTED, then PROGRESSING, advancing
+// one status each time the lifecycle transition delay passes, and then
+// COMPLETE: its output files are written to their S3 destinations as
+// placeholders, with HLS and DASH manifests that reference them. A job whose
+// input object does not exist, or whose destination bucket does not exist,
+// ends in ERROR instead. Completion is seen at once without a transition
+// delay, and otherwise when the mock clock advances past it or the job is
+// fetched, and is sent to the default EventBridge bus as a "MediaConvert Job
+// State Change" event.
+package mediaconvert
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/riyanimam/goto/internal/clock"
+	"github.com/riyanimam/goto/internal/lifecycle"
+	h "github.com/riyanimam/goto/internal/mockhelpers"
+	"github.com/riyanimam/goto/internal/paginate"
+	"github.com/riyanimam/goto/internal/tags"
+)
+
+const apiVersion = "/2017-08-29"
+
+// defaultBusArn is the EventBridge bus that job state changes are sent to,
+// as AWS does.
+var defaultBusArn = fmt.Sprintf("arn:aws:events:us-east-1:%s:event-bus/default", h.DefaultAccountID)
+
+// defaultQueue is the queue jobs are submitted to unless they name another.
+var defaultQueue = fmt.Sprintf("arn:aws:mediaconvert:us-east-1:%s:queues/Default", h.DefaultAccountID)
+
+// Service implements the MediaConvert mock.
+type Service struct {
+	mu   sync.RWMutex
+	jobs map[string]*job
+
+	baseURL     string
+	store       h.ObjectStore
+	dispatch    h.Dispatcher
+	tags        *tags.Store
+	clock       *clock.Clock
+	transitions *lifecycle.Transitions
+}
+
+type job struct {
+	id       string
+	arn      string
+	params   map[string]interface{}
+	input    string // the S3 URI of the first input
+	queue    string
+	created  time.Time
+	finished time.Time
+	ready    lifecycle.Transition
+	canceled bool
+	done     bool // the final status has been seen
+	// errorCode and errorMessage are set, when the job is created, for jobs
+	// that will end in ERROR.
+	errorCode    int
+	errorMessage string
+	outputs      []outputGroup
+}
+
+// outputGroup is the files a job writes for one of its output groups.
+type outputGroup struct {
+	groupType string // FILE_GROUP, HLS_GROUP, DASH_ISO_GROUP, or CMAF_GROUP
+	manifests []string
+	outputs   []output
+}
+
+type output struct {
+	files         []string
+	width, height int
+}
+
+// New creates a new MediaConvert mock service.
+func New() *Service {
+	return &Service{
+		jobs: make(map[string]*job),
+		tags: tags.New(),
+	}
+}
+
+// Name returns the service identifier.
+func (s *Service) Name() string { return "mediaconvert" }
+
+// Handler returns the HTTP handler for MediaConvert requests.
+func (s *Service) Handler() http.Handler {
+	return http.HandlerFunc(s.handle)
+}
+
+// Reset clears all state.
+func (s *Service) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.jobs = make(map[string]*job)
+	s.tags.DeleteService("mediaconvert")
+}
+
+// SetBaseURL records the mock server URL, the endpoint DescribeEndpoints
+// returns.
+func (s *Service) SetBaseURL(u string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.baseURL = u
+}
+
+// SetObjectStore sets the store job inputs are read from and outputs are
+// written to.
+func (s *Service) SetObjectStore(store h.ObjectStore) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.store = store
+}
+
+// SetDispatcher sets the function used to send job state change events to
+// EventBridge.
+func (s *Service) SetDispatcher(d h.Dispatcher) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.dispatch = d
+}
+
+// SetTagStore sets the registry job tags are recorded in.
+func (s *Service) SetTagStore(store *tags.Store) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tags = store
+}
+
+// SetTransitions sets how long jobs stay in each status before COMPLETE.
+func (s *Service) SetTransitions(t *lifecycle.Transitions) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.transitions = t
+}
+
+// SetClock attaches the mock clock. Jobs that finish as it advances write
+// their outputs and send their state change.
+func (s *Service) SetClock(c *clock.Clock) {
+	s.mu.Lock()
+	s.clock = c
+	s.mu.Unlock()
+	c.OnAdvance(func(_, _ time.Time) {
+		s.mu.Lock()
+		events := s.settle()
+		s.mu.Unlock()
+		s.emit(events)
+	})
+}
+
+func (s *Service) now() time.Time {
+	if s.clock != nil {
+		return s.clock.Now()
+	}
+	return time.Now().UTC()
+}
+
+func (s *Service) handle(w http.ResponseWriter, r *http.Request) {
+	p := strings.TrimPrefix(r.URL.Path, apiVersion)
+	switch {
+	case p == "/endpoints" && r.Method == http.MethodPost:
+		s.describeEndpoints(w)
+	case p == "/jobs" && r.Method == http.MethodPost:
+		s.createJob(w, r)
+	case p == "/jobs" && r.Method == http.MethodGet:
+		s.listJobs(w, r)
+	case strings.HasPrefix(p, "/jobs/") && r.Method == http.MethodGet:
+		s.getJob(w, strings.TrimPrefix(p, "/jobs/"))
+	case strings.HasPrefix(p, "/jobs/") && r.Method == http.MethodDelete:
+		s.cancelJob(w, strings.TrimPrefix(p, "/jobs/"))
+	default:
+		h.WriteJSONError(w, "NotFoundException", "unsupported operation", http.StatusNotFound)
+	}
+}
+
+func writeBadRequest(w http.ResponseWriter, message string) {
+	h.WriteJSONError(w, "BadRequestException", message, http.StatusBadRequest)
+}
+
+func writeNotFound(w http.ResponseWriter, id string) {
+	h.WriteJSONError(w, "NotFoundException", "The specified job "+id+" does not exist.", http.StatusNotFound)
+}
+
+func (s *Service) describeEndpoints(w http.ResponseWriter) {
+	s.mu.RLock()
+	url := s.baseURL
+	s.mu.RUnlock()
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"endpoints": []map[string]string{{"url": url}},
+	})
+}
+
+func (s *Service) createJob(w http.ResponseWriter, r *http.Request) {
+	params := map[string]interface{}{}
+	if body, _ := io.ReadAll(r.Body); len(body) > 0 {
+		if err := json.Unmarshal(body, &params); err != nil {
+			writeBadRequest(w, "Request body is not valid JSON.")
+			return
+		}
+	}
+	settings, _ := params["settings"].(map[string]interface{})
+	inputs, _ := settings["inputs"].([]interface{})
+	groups, _ := settings["outputGroups"].([]interface{})
+	switch {
+	case h.GetString(params, "role") == "":
+		writeBadRequest(w, "/role: Should be present.")
+		return
+	case settings == nil:
+		writeBadRequest(w, "/settings: Should be present.")
+		return
+	case len(inputs) == 0:
+		writeBadRequest(w, "/settings/inputs: Should have at least 1 items.")
+		return
+	case len(groups) == 0:
+		writeBadRequest(w, "/settings/outputGroups: Should have at least 1 items.")
+		return
+	}
+	input, _ := inputs[0].(map[string]interface{})
+	source := h.GetString(input, "fileInput")
+	srcBucket, srcKey, ok := splitS3URI(source)
+	if !ok {
+		writeBadRequest(w, "/settings/inputs/0/fileInput: Should be an s3:// URI.")
+		return
+	}
+	base := strings.TrimSuffix(path.Base(srcKey), path.Ext(srcKey))
+
+	j := &job{params: params, input: source, queue: h.GetString(params, "queue")}
+	if j.queue == "" {
+		j.queue = defaultQueue
+	}
+	for i, g := range groups {
+		group, err := planGroup(g, base)
+		if err != nil {
+			writeBadRequest(w, fmt.Sprintf("/settings/outputGroups/%d: %v", i, err))
+			return
+		}
+		j.outputs = append(j.outputs, group)
+	}
+
+	s.mu.Lock()
+	if s.store != nil {
+		if _, err := s.store.GetObject(srcBucket, srcKey); err != nil {
+			j.errorCode = 1030
+			j.errorMessage = "Failed probe/open: [Failed to open input file [" + source + "]: [No such file or directory]]"
+		} else if dest := j.missingDestination(s.store); dest != "" {
+			j.errorCode = 1401
+			j.errorMessage = "Unable to write to output file [" + dest + "]: [Failed to write data: Access Denied]"
+		}
+	}
+	j.created = s.now()
+	j.id = fmt.Sprintf("%d-%s", j.created.UnixMilli(), strings.ToLower(h.RandomID(6)))
+	j.arn = fmt.Sprintf("arn:aws:mediaconvert:us-east-1:%s:jobs/%s", h.DefaultAccountID, j.id)
+	j.ready = s.transitions.Begin()
+	s.jobs[j.id] = j
+	s.tags.Tag(j.arn, tags.FromMap(params["tags"]))
+	events := s.settle()
+	view := s.jobView(j)
+	s.mu.Unlock()
+	s.emit(events)
+
+	h.WriteJSON(w, http.StatusCreated, map[string]interface{}{"job": view})
+}
+
+func (s *Service) getJob(w http.ResponseWriter, id string) {
+	s.mu.Lock()
+	events := s.settle()
+	j, exists := s.jobs[id]
+	var view map[string]interface{}
+	if exists {
+		view = s.jobView(j)
+	}
+	s.mu.Unlock()
+	s.emit(events)
+
+	if !exists {
+		writeNotFound(w, id)
+		return
+	}
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{"job": view})
+}
+
+func (s *Service) listJobs(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	queue, status := q.Get("queue"), q.Get("status")
+	if queue != "" && !strings.HasPrefix(queue, "arn:") {
+		queue = fmt.Sprintf("arn:aws:mediaconvert:us-east-1:%s:queues/%s", h.DefaultAccountID, queue)
+	}
+
+	s.mu.Lock()
+	events := s.settle()
+	var matched []*job
+	for _, j := range s.jobs {
+		if (queue == "" || j.queue == queue) && (status == "" || s.status(j) == status) {
+			matched = append(matched, j)
+		}
+	}
+	descending := q.Get("order") != "ASCENDING"
+	key := func(i int) string {
+		k := paginate.Join(paginate.Time(matched[i].created), matched[i].id)
+		if descending {
+			return paginate.Desc(k)
+		}
+		return k
+	}
+	sort.Slice(matched, func(a, b int) bool { return key(a) < key(b) })
+	views := make([]map[string]interface{}, 0, len(matched))
+	for _, j := range matched {
+		views = append(views, s.jobView(j))
+	}
+	s.mu.Unlock()
+	s.emit(events)
+
+	maxResults := 20
+	if v := q.Get("maxResults"); v != "" {
+		fmt.Sscan(v, &maxResults)
+	}
+	page, next, err := paginate.Page(views, key, q.Get("nextToken"), maxResults, 20)
+	if err != nil {
+		writeBadRequest(w, "Invalid nextToken.")
+		return
+	}
+	resp := map[string]interface{}{"jobs": page}
+	if next != "" {
+		resp["nextToken"] = next
+	}
+	h.WriteJSON(w, http.StatusOK, resp)
+}
+
+func (s *Service) cancelJob(w http.ResponseWriter, id string) {
+	s.mu.Lock()
+	events := s.settle()
+	j, exists := s.jobs[id]
+	var status string
+	if exists {
+		status = s.status(j)
+		if status == "SUBMITTED" || status == "PROGRESSING" {
+			j.canceled = true
+			j.done = true
+			j.finished = s.now()
+		}
+	}
+	s.mu.Unlock()
+	s.emit(events)
+
+	switch {
+	case !exists:
+		writeNotFound(w, id)
+	case status != "SUBMITTED" && status != "PROGRESSING":
+		h.WriteJSONError(w, "ConflictException", "The job "+id+" is "+status+" and can no longer be canceled.", http.StatusConflict)
+	default:
+		h.WriteJSON(w, http.StatusAccepted, map[string]interface{}{})
+	}
+}
+
+// status returns j's current status. The caller must hold s.mu.
+func (s *Service) status(j *job) string {
+	if j.canceled {
+		return "CANCELED"
+	}
+	final := "COMPLETE"
+	if j.errorCode != 0 {
+		final = "ERROR"
+	}
+	return s.transitions.Stage(j.ready, "SUBMITTED", "PROGRESSING", final)
+}
+
+// jobView returns j as GetJob reports it. The caller must hold s.mu.
+func (s *Service) jobView(j *job) map[string]interface{} {
+	status := s.status(j)
+	settings := j.params["settings"]
+	view := map[string]interface{}{
+		"arn":                  j.arn,
+		"id":                   j.id,
+		"createdAt":            float64(j.created.Unix()),
+		"status":               status,
+		"role":                 h.GetString(j.params, "role"),
+		"queue":                j.queue,
+		"settings":             settings,
+		"userMetadata":         j.params["userMetadata"],
+		"billingTagsSource":    "JOB",
+		"priority":             h.GetInt(j.params, "priority", 0),
+		"statusUpdateInterval": h.GetString(j.params, "statusUpdateInterval"),
+		"accelerationStatus":   "NOT_APPLICABLE",
+		"retryCount":           0,
+		"messages":             map[string]interface{}{"info": []string{}, "warning": []string{}},
+	}
+	if view["statusUpdateInterval"] == "" {
+		view["statusUpdateInterval"] = "SECONDS_60"
+	}
+	if tmpl := h.GetString(j.params, "jobTemplate"); tmpl != "" {
+		view["jobTemplate"] = tmpl
+	}
+	timing := map[string]interface{}{"submitTime": float64(j.created.Unix())}
+	switch status {
+	case "PROGRESSING":
+		timing["startTime"] = float64(j.created.Unix())
+		view["currentPhase"] = "TRANSCODING"
+		view["jobPercentComplete"] = 50
+	case "COMPLETE":
+		timing["startTime"] = float64(j.created.Unix())
+		timing["finishTime"] = float64(j.finished.Unix())
+		view["outputGroupDetails"] = j.outputGroupDetails(false)
+	case "ERROR":
+		timing["startTime"] = float64(j.created.Unix())
+		timing["finishTime"] = float64(j.finished.Unix())
+		view["errorCode"] = j.errorCode
+		view["errorMessage"] = j.errorMessage
+	case "CANCELED":
+		timing["finishTime"] = float64(j.finished.Unix())
+	}
+	view["timing"] = timing
+	return view
+}
+
+// outputGroupDetails reports the outputs j wrote, with their S3 paths as job
+// state change events carry them if withPaths is set.
+func (j *job) outputGroupDetails(withPaths bool) []map[string]interface{} {
+	details := make([]map[string]interface{}, 0, len(j.outputs))
+	for _, g := range j.outputs {
+		outputs := make([]map[string]interface{}, 0, len(g.outputs))
+		for _, o := range g.outputs {
+			out := map[string]interface{}{
+				"durationInMs": 60000,
+				"videoDetails": map[string]int{"widthInPx": o.width, "heightInPx": o.height},
+			}
+			if withPaths && g.groupType == "FILE_GROUP" {
+				out["outputFilePaths"] = o.files
+			}
+			outputs = append(outputs, out)
+		}
+		detail := map[string]interface{}{"outputDetails": outputs}
+		if withPaths {
+			detail["type"] = g.groupType
+			if len(g.manifests) > 0 {
+				detail["playlistFilePaths"] = g.manifests
+			}
+		}
+		details = append(details, detail)
+	}
+	return details
+}
+
+// missingDestination returns the first output destination of j whose
+// bucket does not exist, or "" if they all do.
+func (j *job) missingDestination(store h.ObjectStore) string {
+	for _, g := range j.outputs {
+		files := append([]string{}, g.manifests...)
+		for _, o := range g.outputs {
+			files = append(files, o.files...)
+		}
+		for _, f := range files {
+			bucket, _, _ := splitS3URI(f)
+			if _, err := store.ListObjects(bucket, ""); err != nil {
+				return f
+			}
+		}
+	}
+	return ""
+}
+
+// settle writes the outputs of the jobs that have completed since it last
+// ran and returns their state change events. The caller must hold s.mu and
+// emit the events after releasing it.
+func (s *Service) settle() []map[string]interface{} {
+	var events []map[string]interface{}
+	for _, j := range s.jobs {
+		if j.done {
+			continue
+		}
+		status := s.status(j)
+		if status != "COMPLETE" && status != "ERROR" {
+			continue
+		}
+		j.done = true
+		j.finished = s.now()
+		if status == "COMPLETE" && s.store != nil {
+			j.write(s.store)
+		}
+		events = append(events, s.stateChange(j, status))
+	}
+	sort.Slice(events, func(a, b int) bool {
+		return events[a]["resources"].([]string)[0] < events[b]["resources"].([]string)[0]
+	})
+	return events
+}
+
+// write puts j's output files and manifests in store.
+func (j *job) write(store h.ObjectStore) {
+	for _, g := range j.outputs {
+		var variants []string
+		for _, o := range g.outputs {
+			for _, f := range o.files {
+				bucket, key, _ := splitS3URI(f)
+				var body string
+				switch path.Ext(f) {
+				case ".m3u8":
+					segment := strings.TrimSuffix(path.Base(f), ".m3u8") + "_00001.ts"
+					body = "#EXTM3U\n#EXT-X-VERSION:3\n#EXT-X-TARGETDURATION:10\n#EXTINF:10,\n" + segment + "\n#EXT-X-ENDLIST\n"
+					variants = append(variants, path.Base(f))
+				default:
+					body = "mock MediaConvert output of " + j.input + "\n"
+				}
+				store.PutObject(bucket, key, []byte(body))
+			}
+		}
+		for _, m := range g.manifests {
+			bucket, key, _ := splitS3URI(m)
+			var body string
+			if path.Ext(m) == ".mpd" {
+				body = `<?xml version="1.0" encoding="UTF-8"?>` + "\n" +
+					`<MPD xmlns="urn:mpeg:dash:schema:mpd:2011" type="static" mediaPresentationDuration="PT60S"/>` + "\n"
+			} else {
+				body = "#EXTM3U\n#EXT-X-VERSION:3\n"
+				for _, v := range variants {
+					body += "#EXT-X-STREAM-INF:BANDWIDTH=5000000\n" + v + "\n"
+				}
+			}
+			store.PutObject(bucket, key, []byte(body))
+		}
+	}
+}
+
+// stateChange builds the "MediaConvert Job State Change" event for j
+// reaching status. The caller must hold s.mu.
+func (s *Service) stateChange(j *job, status string) map[string]interface{} {
+	detail := map[string]interface{}{
+		"timestamp":    j.finished.UnixMilli(),
+		"accountId":    h.DefaultAccountID,
+		"queue":        j.queue,
+		"jobId":        j.id,
+		"status":       status,
+		"userMetadata": j.params["userMetadata"],
+	}
+	if detail["userMetadata"] == nil {
+		detail["userMetadata"] = map[string]interface{}{}
+	}
+	if status == "COMPLETE" {
+		detail["outputGroupDetails"] = j.outputGroupDetails(true)
+	} else {
+		detail["errorCode"] = j.errorCode
+		detail["errorMessage"] = j.errorMessage
+	}
+	return map[string]interface{}{
+		"version":     "0",
+		"id":          h.NewRequestID(),
+		"detail-type": "MediaConvert Job State Change",
+		"source":      "aws.mediaconvert",
+		"account":     h.DefaultAccountID,
+		"time":        j.finished.UTC().Format(time.RFC3339),
+		"region":      "us-east-1",
+		"resources":   []string{j.arn},
+		"detail":      detail,
+	}
+}
+
+// emit sends events to the default event bus. It must be called without
+// holding s.mu, since the bus may deliver them back to this service.
+// Delivery failures, such as EventBridge not running, are
+// ignored.
+func (s *Service) emit(events []map[string]interface{}) {
+	s.mu.RLock()
+	dispatch := s.dispatch
+	s.mu.RUnlock()
+	if dispatch == nil {
+		return
+	}
+	for _, e := range events {
+		payload, _ := json.Marshal(e)
+		dispatch(defaultBusArn, payload)
+	}
+}
+
+// planGroup works out the files an output group writes for an input whose
+// file name, without extension, is base.
+func planGroup(g interface{}, base string) (outputGroup, error) {
+	group, _ := g.(map[string]interface{})
+	settings, _ := group["outputGroupSettings"].(map[string]interface{})
+	groupType := h.GetString(settings, "type")
+
+	var field, manifestExt, segmentExt string
+	var og outputGroup
+	switch groupType {
+	case "FILE_GROUP_SETTINGS":
+		field, og.groupType = "fileGroupSettings", "FILE_GROUP"
+	case "HLS_GROUP_SETTINGS":
+		field, og.groupType, manifestExt = "hlsGroupSettings", "HLS_GROUP", ".m3u8"
+	case "DASH_ISO_GROUP_SETTINGS":
+		field, og.groupType, manifestExt, segmentExt = "dashIsoGroupSettings", "DASH_ISO_GROUP", ".mpd", ".mp4"
+	case "CMAF_GROUP_SETTINGS":
+		field, og.groupType, manifestExt, segmentExt = "cmafGroupSettings", "CMAF_GROUP", ".mpd", ".cmfv"
+	default:
+		return og, fmt.Errorf("outputGroupSettings.type %q is not supported", groupType)
+	}
+	typeSettings, _ := settings[field].(map[string]interface{})
+	destination := h.GetString(typeSettings, "destination")
+	if _, _, ok := splitS3URI(destination); !ok {
+		return og, fmt.Errorf("%s.destination must be an s3:// URI", field)
+	}
+	// A destination ending in a slash is a folder that outputs are named
+	// after the input in; otherwise its last element names them.
+	if strings.HasSuffix(destination, "/") {
+		destination += base
+	}
+	if manifestExt != "" {
+		og.manifests = append(og.manifests, destination+manifestExt)
+	}
+
+	outputs, _ := group["outputs"].([]interface{})
+	if len(outputs) == 0 {
+		return og, fmt.Errorf("outputs: Should have at least 1 items")
+	}
+	for _, o := range outputs {
+		out, _ := o.(map[string]interface{})
+		name := destination + h.GetString(out, "nameModifier")
+		video, _ := out["videoDescription"].(map[string]interface{})
+		planned := output{width: h.GetInt(video, "width", 1920), height: h.GetInt(video, "height", 1080)}
+		switch {
+		case og.groupType == "FILE_GROUP":
+			ext := h.GetString(out, "extension")
+			if ext == "" {
+				container, _ := out["containerSettings"].(map[string]interface{})
+				ext = containerExtension(h.GetString(container, "container"))
+			}
+			planned.files = []string{name + "." + ext}
+		case og.groupType == "HLS_GROUP":
+			planned.files = []string{name + ".m3u8", name + "_00001.ts"}
+		default:
+			planned.files = []string{name + "_00001" + segmentExt}
+		}
+		og.outputs = append(og.outputs, planned)
+	}
+	return og, nil
+}
+
+// containerExtension returns the default file extension for a container.
+func containerExtension(container string) string {
+	switch container {
+	case "MOV":
+		return "mov"
+	case "M2TS", "M3U8":
+		return "m2ts"
+	case "MXF":
+		return "mxf"
+	case "WEBM":
+		return "webm"
+	case "RAW":
+		return "raw"
+	default:
+		return "mp4"
+	}
+}
+
+// splitS3URI splits an s3://bucket/key URI.
+func splitS3URI(uri string) (bucket, key string, ok bool) {
+	rest, ok := strings.CutPrefix(uri, "s3://")
+	if !ok {
+		return "", "", false
+	}
+	bucket, key, _ = strings.Cut(rest, "/")
+	return bucket, key, bucket != ""
+}
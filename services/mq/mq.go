@@ -6,6 +6,9 @@
 //   - DeleteBroker
 //   - ListBrokers
 //   - UpdateBroker
+//   - CreateTags
+//   - DeleteTags
+//   - ListTags
 package mq
 
 import (
@@ -17,13 +20,18 @@ import (
 	"sync"
 	"time"
 
+	"github.com/riyanimam/goto/internal/lifecycle"
 	h "github.com/riyanimam/goto/internal/mockhelpers"
+	"github.com/riyanimam/goto/internal/tags"
 )
 
 // Service implements the Amazon MQ mock.
 type Service struct {
 	mu      sync.RWMutex
 	brokers map[string]*broker
+	tags    *tags.Store
+
+	transitions *lifecycle.Transitions
 }
 
 type broker struct {
@@ -37,12 +45,14 @@ type broker struct {
 	deploymentMode     string
 	publiclyAccessible bool
 	created            time.Time
+	ready              lifecycle.Transition
 }
 
 // New creates a new Amazon MQ mock service.
 func New() *Service {
 	return &Service{
 		brokers: make(map[string]*broker),
+		tags:    tags.New(),
 	}
 }
 
@@ -59,6 +69,21 @@ func (s *Service) Reset() {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	s.brokers = make(map[string]*broker)
+	s.tags.DeleteService("mq")
+}
+
+// SetTagStore sets the registry resource tags are recorded in.
+func (s *Service) SetTagStore(store *tags.Store) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tags = store
+}
+
+// SetTransitions sets how long new brokers stay CREATION_IN_PROGRESS.
+func (s *Service) SetTransitions(t *lifecycle.Transitions) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.transitions = t
 }
 
 func (s *Service) handle(w http.ResponseWriter, r *http.Request) {
@@ -66,6 +91,8 @@ func (s *Service) handle(w http.ResponseWriter, r *http.Request) {
 	method := r.Method
 
 	switch {
+	case strings.Contains(path, "/v1/tags/"):
+		s.handleTags(w, r, path[strings.Index(path, "/v1/tags/")+len("/v1/tags/"):])
 	// Single broker: /v1/brokers/{brokerId}
 	case strings.HasPrefix(path, "/v1/brokers/") && method == http.MethodGet:
 		s.describeBroker(w, r, path)
@@ -132,7 +159,9 @@ func (s *Service) createBroker(w http.ResponseWriter, r *http.Request) {
 	}
 
 	s.mu.Lock()
+	b.ready = s.transitions.Begin()
 	s.brokers[brokerID] = b
+	s.tags.Tag(arn, tags.FromMap(params["tags"]))
 	s.mu.Unlock()
 
 	h.WriteJSON(w, http.StatusOK, map[string]interface{}{
@@ -153,19 +182,20 @@ func (s *Service) describeBroker(w http.ResponseWriter, _ *http.Request, path st
 		return
 	}
 
-	h.WriteJSON(w, http.StatusOK, brokerResp(b))
+	h.WriteJSON(w, http.StatusOK, s.brokerResp(b))
 }
 
 func (s *Service) deleteBroker(w http.ResponseWriter, _ *http.Request, path string) {
 	brokerID := extractBrokerID(path)
 
 	s.mu.Lock()
-	_, exists := s.brokers[brokerID]
+	b, exists := s.brokers[brokerID]
 	if !exists {
 		s.mu.Unlock()
 		h.WriteJSONError(w, "NotFoundException", "Broker "+brokerID+" not found", http.StatusNotFound)
 		return
 	}
+	s.tags.Delete(b.brokerArn)
 	delete(s.brokers, brokerID)
 	s.mu.Unlock()
 
@@ -182,7 +212,7 @@ func (s *Service) listBrokers(w http.ResponseWriter, _ *http.Request) {
 			"brokerId":       b.brokerID,
 			"brokerArn":      b.brokerArn,
 			"brokerName":     b.brokerName,
-			"brokerState":    b.brokerState,
+			"brokerState":    s.brokerState(b),
 			"engineType":     b.engineType,
 			"deploymentMode": b.deploymentMode,
 			"created":        b.created.Format(time.RFC3339),
@@ -224,12 +254,21 @@ func (s *Service) updateBroker(w http.ResponseWriter, r *http.Request, path stri
 	})
 }
 
-func brokerResp(b *broker) map[string]interface{} {
+// brokerState returns the state of b, which is CREATION_IN_PROGRESS until
+// the broker has been provisioned.
+func (s *Service) brokerState(b *broker) string {
+	if b.brokerState != "RUNNING" {
+		return b.brokerState
+	}
+	return s.transitions.Status(b.ready, "CREATION_IN_PROGRESS", b.brokerState)
+}
+
+func (s *Service) brokerResp(b *broker) map[string]interface{} {
 	return map[string]interface{}{
 		"brokerId":         b.brokerID,
 		"brokerArn":        b.brokerArn,
 		"brokerName":       b.brokerName,
-		"brokerState":      b.brokerState,
+		"brokerState":      s.brokerState(b),
 		"engineType":       b.engineType,
 		"engineVersion":    b.engineVersion,
 		"hostInstanceType": b.hostInstanceType,
@@ -237,3 +276,41 @@ func brokerResp(b *broker) map[string]interface{} {
 		"created":          b.created.Format(time.RFC3339),
 	}
 }
+
+// hasResource reports whether arn names an existing resource.
+// The caller must hold s.mu.
+func (s *Service) hasResource(arn string) bool {
+	for _, b := range s.brokers {
+		if b.brokerArn == arn {
+			return true
+		}
+	}
+	return false
+}
+
+// handleTags serves TagResource (POST), UntagResource (DELETE), and
+// ListTagsForResource (GET) on /v1/tags/{arn}.
+func (s *Service) handleTags(w http.ResponseWriter, r *http.Request, arn string) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if !s.hasResource(arn) {
+		h.WriteJSONError(w, "NotFoundException", "Resource not found: "+arn, http.StatusNotFound)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPost:
+		var params map[string]interface{}
+		bodyBytes, _ := io.ReadAll(r.Body)
+		json.Unmarshal(bodyBytes, &params)
+		s.tags.Tag(arn, tags.FromMap(params["tags"]))
+		h.WriteJSON(w, http.StatusNoContent, map[string]interface{}{})
+	case http.MethodDelete:
+		s.tags.Untag(arn, r.URL.Query()["tagKeys"])
+		h.WriteJSON(w, http.StatusNoContent, map[string]interface{}{})
+	default:
+		h.WriteJSON(w, http.StatusOK, map[string]interface{}{
+			"tags": s.tags.Get(arn),
+		})
+	}
+}
@@ -0,0 +1,536 @@
+// Package mwaa provides a mock implementation of Amazon Managed Workflows
+// for Apache Airflow (MWAA).
+//
+// Supported actions:
+//   - CreateEnvironment
+//   - GetEnvironment
+//   - ListEnvironments
+//   - UpdateEnvironment
+//   - DeleteEnvironment
+//   - CreateCliToken
+//   - CreateWebLoginToken
+//
+// No Airflow runs. Environments are CREATING, or UPDATING after a change,
+// until their lifecycle transition completes and then AVAILABLE. The DAG
+// folder and the plugins, requirements, and startup script objects an
+// environment names must exist in its source bucket in the S3 mock, so
+// deployment tooling that forgets to upload them fails as it would in AWS.
+// CLI and web login tokens are random strings for the environment's web
+// server hostname.
+package mwaa
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/riyanimam/goto/internal/arn"
+	"github.com/riyanimam/goto/internal/clock"
+	"github.com/riyanimam/goto/internal/lifecycle"
+	h "github.com/riyanimam/goto/internal/mockhelpers"
+	"github.com/riyanimam/goto/internal/paginate"
+	"github.com/riyanimam/goto/internal/tags"
+)
+
+// airflowVersions are the versions environments can run, oldest first; new
+// environments run the last.
+var airflowVersions = []string{"2.7.2", "2.8.1", "2.9.2", "2.10.1", "2.10.3"}
+
+var environmentClasses = map[string]bool{
+	"mw1.micro":   true,
+	"mw1.small":   true,
+	"mw1.medium":  true,
+	"mw1.large":   true,
+	"mw1.xlarge":  true,
+	"mw1.2xlarge": true,
+}
+
+var namePattern = regexp.MustCompile(`^[a-zA-Z][0-9a-zA-Z_-]{0,79}$`)
+
+// logTypes are the LoggingConfiguration members and the suffixes of their
+// CloudWatch log groups.
+var logTypes = map[string]string{
+	"DagProcessingLogs": "DAGProcessing",
+	"SchedulerLogs":     "Scheduler",
+	"TaskLogs":          "Task",
+	"WebserverLogs":     "WebServer",
+	"WorkerLogs":        "Worker",
+}
+
+// Service implements the MWAA mock.
+type Service struct {
+	mu           sync.RWMutex
+	environments map[string]*environment
+
+	store       h.ObjectStore
+	tags        *tags.Store
+	clock       *clock.Clock
+	transitions *lifecycle.Transitions
+}
+
+type environment struct {
+	name     string
+	arn      string
+	hostname string
+	// config holds the settings given at creation and by later updates,
+	// as named in the API.
+	config     map[string]interface{}
+	status     string // CREATING or UPDATING while changed is in progress
+	changed    lifecycle.Transition
+	created    time.Time
+	lastUpdate map[string]interface{}
+}
+
+// New creates a new MWAA mock service.
+func New() *Service {
+	return &Service{
+		environments: make(map[string]*environment),
+		tags:         tags.New(),
+	}
+}
+
+// Name returns the service identifier.
+func (s *Service) Name() string { return "airflow" }
+
+// Handler returns the HTTP handler for MWAA requests.
+func (s *Service) Handler() http.Handler {
+	return http.HandlerFunc(s.handle)
+}
+
+// Reset clears all state.
+func (s *Service) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.environments = make(map[string]*environment)
+	s.tags.DeleteService("airflow")
+}
+
+// SetObjectStore sets the store source bucket objects are checked against.
+func (s *Service) SetObjectStore(store h.ObjectStore) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.store = store
+}
+
+// SetTagStore sets the registry environment tags are recorded in.
+func (s *Service) SetTagStore(store *tags.Store) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tags = store
+}
+
+// SetClock attaches the mock clock used for creation and update times.
+func (s *Service) SetClock(c *clock.Clock) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.clock = c
+}
+
+// SetTransitions sets how long environments stay CREATING and UPDATING.
+func (s *Service) SetTransitions(t *lifecycle.Transitions) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.transitions = t
+}
+
+func (s *Service) now() time.Time {
+	if s.clock != nil {
+		return s.clock.Now()
+	}
+	return time.Now().UTC()
+}
+
+func (s *Service) handle(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(r.URL.EscapedPath(), "/"), "/")
+	var name string
+	if len(parts) == 2 {
+		name, _ = url.PathUnescape(parts[1])
+	}
+
+	switch {
+	case len(parts) == 1 && parts[0] == "environments" && r.Method == http.MethodGet:
+		s.listEnvironments(w, r)
+	case len(parts) == 2 && parts[0] == "environments" && r.Method == http.MethodPut:
+		s.createEnvironment(w, r, name)
+	case len(parts) == 2 && parts[0] == "environments" && r.Method == http.MethodGet:
+		s.getEnvironment(w, name)
+	case len(parts) == 2 && parts[0] == "environments" && r.Method == http.MethodPatch:
+		s.updateEnvironment(w, r, name)
+	case len(parts) == 2 && parts[0] == "environments" && r.Method == http.MethodDelete:
+		s.deleteEnvironment(w, name)
+	case len(parts) == 2 && parts[0] == "clitoken" && r.Method == http.MethodPost:
+		s.createCliToken(w, name)
+	case len(parts) == 2 && parts[0] == "webtoken" && r.Method == http.MethodPost:
+		s.createWebLoginToken(w, name)
+	default:
+		h.WriteJSONError(w, "ResourceNotFoundException", "unsupported operation", http.StatusNotFound)
+	}
+}
+
+func writeValidation(w http.ResponseWriter, message string) {
+	h.WriteJSONError(w, "ValidationException", message, http.StatusBadRequest)
+}
+
+func writeNotFound(w http.ResponseWriter, name string) {
+	h.WriteJSONError(w, "ResourceNotFoundException", "Environment "+name+" not found.", http.StatusNotFound)
+}
+
+func readParams(r *http.Request) (map[string]interface{}, error) {
+	params := map[string]interface{}{}
+	body, _ := io.ReadAll(r.Body)
+	if len(body) == 0 {
+		return params, nil
+	}
+	err := json.Unmarshal(body, &params)
+	return params, err
+}
+
+// validate checks an environment's settings, cfg, against its source bucket
+// and the MWAA limits, returning a message describing the first problem.
+// The caller must hold s.mu.
+func (s *Service) validate(cfg map[string]interface{}) string {
+	for _, key := range []string{"SourceBucketArn", "DagS3Path", "ExecutionRoleArn"} {
+		if h.GetString(cfg, key) == "" {
+			return key + " is required."
+		}
+	}
+	network, _ := cfg["NetworkConfiguration"].(map[string]interface{})
+	if subnets, _ := network["SubnetIds"].([]interface{}); len(subnets) != 2 {
+		return "NetworkConfiguration must specify exactly 2 SubnetIds."
+	}
+	if !environmentClasses[h.GetString(cfg, "EnvironmentClass")] {
+		return "Invalid EnvironmentClass: " + h.GetString(cfg, "EnvironmentClass")
+	}
+	minWorkers, maxWorkers := h.GetInt(cfg, "MinWorkers", 0), h.GetInt(cfg, "MaxWorkers", 0)
+	if minWorkers < 1 || maxWorkers > 25 || minWorkers > maxWorkers {
+		return "MinWorkers and MaxWorkers must satisfy 1 <= MinWorkers <= MaxWorkers <= 25."
+	}
+	if schedulers := h.GetInt(cfg, "Schedulers", 0); schedulers < 2 || schedulers > 5 {
+		return "Schedulers must be between 2 and 5."
+	}
+	if mode := h.GetString(cfg, "WebserverAccessMode"); mode != "PRIVATE_ONLY" && mode != "PUBLIC_ONLY" {
+		return "Invalid WebserverAccessMode: " + mode
+	}
+
+	if s.store == nil {
+		return ""
+	}
+	bucketArn, err := arn.Parse(h.GetString(cfg, "SourceBucketArn"))
+	if err != nil || bucketArn.Service != "s3" {
+		return "SourceBucketArn must be an S3 bucket ARN."
+	}
+	bucket := bucketArn.Resource
+	dags := strings.TrimSuffix(h.GetString(cfg, "DagS3Path"), "/") + "/"
+	keys, err := s.store.ListObjects(bucket, dags)
+	if err != nil {
+		return "Unable to access bucket " + bucket + "."
+	}
+	if len(keys) == 0 {
+		return fmt.Sprintf("DagS3Path s3://%s/%s does not exist.", bucket, dags)
+	}
+	for _, key := range []string{"PluginsS3Path", "RequirementsS3Path", "StartupScriptS3Path"} {
+		path := h.GetString(cfg, key)
+		if path == "" {
+			continue
+		}
+		if _, err := s.store.GetObject(bucket, path); err != nil {
+			return fmt.Sprintf("Unable to read %s s3://%s/%s.", key, bucket, path)
+		}
+	}
+	return ""
+}
+
+func (s *Service) createEnvironment(w http.ResponseWriter, r *http.Request, name string) {
+	params, err := readParams(r)
+	if err != nil {
+		writeValidation(w, "Request body is not valid JSON.")
+		return
+	}
+	if !namePattern.MatchString(name) {
+		writeValidation(w, "Invalid environment name: "+name)
+		return
+	}
+
+	cfg := map[string]interface{}{
+		"AirflowVersion":               airflowVersions[len(airflowVersions)-1],
+		"EnvironmentClass":             "mw1.small",
+		"MinWorkers":                   1.0,
+		"MaxWorkers":                   10.0,
+		"Schedulers":                   2.0,
+		"WebserverAccessMode":          "PRIVATE_ONLY",
+		"EndpointManagement":           "SERVICE",
+		"WeeklyMaintenanceWindowStart": "SUN:03:30",
+	}
+	tagMap := tags.FromMap(params["Tags"])
+	delete(params, "Tags")
+	delete(params, "Name")
+	for k, v := range params {
+		cfg[k] = v
+	}
+	if !knownVersion(h.GetString(cfg, "AirflowVersion")) {
+		writeValidation(w, "Unsupported AirflowVersion: "+h.GetString(cfg, "AirflowVersion"))
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, exists := s.environments[name]; exists {
+		writeValidation(w, "Environment "+name+" already exists.")
+		return
+	}
+	if msg := s.validate(cfg); msg != "" {
+		writeValidation(w, msg)
+		return
+	}
+	env := &environment{
+		name:     name,
+		arn:      fmt.Sprintf("arn:aws:airflow:us-east-1:%s:environment/%s", h.DefaultAccountID, name),
+		hostname: h.NewRequestID() + ".c2.us-east-1.airflow.amazonaws.com",
+		config:   cfg,
+		status:   "CREATING",
+		changed:  s.transitions.Begin(),
+		created:  s.now(),
+	}
+	s.environments[name] = env
+	s.tags.Tag(env.arn, tagMap)
+
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{"Arn": env.arn})
+}
+
+func knownVersion(v string) bool {
+	for _, known := range airflowVersions {
+		if v == known {
+			return true
+		}
+	}
+	return false
+}
+
+func versionIndex(v string) int {
+	for i, known := range airflowVersions {
+		if v == known {
+			return i
+		}
+	}
+	return -1
+}
+
+// state returns the environment's status. The caller must hold s.mu.
+func (s *Service) state(env *environment) string {
+	return s.transitions.Status(env.changed, env.status, "AVAILABLE")
+}
+
+// description returns the environment as GetEnvironment reports it. The
+// caller must hold s.mu.
+func (s *Service) description(env *environment) map[string]interface{} {
+	desc := make(map[string]interface{}, len(env.config)+8)
+	for k, v := range env.config {
+		desc[k] = v
+	}
+	desc["Name"] = env.name
+	desc["Arn"] = env.arn
+	desc["Status"] = s.state(env)
+	desc["CreatedAt"] = float64(env.created.Unix())
+	desc["WebserverUrl"] = env.hostname
+	desc["ServiceRoleArn"] = fmt.Sprintf("arn:aws:iam::%s:role/aws-service-role/airflow.amazonaws.com/AWSServiceRoleForAmazonMWAA", h.DefaultAccountID)
+	desc["Tags"] = s.tags.Get(env.arn)
+	if logging, ok := env.config["LoggingConfiguration"].(map[string]interface{}); ok {
+		out := make(map[string]interface{}, len(logging))
+		for key, v := range logging {
+			lc, _ := v.(map[string]interface{})
+			entry := map[string]interface{}{
+				"Enabled":  lc["Enabled"],
+				"LogLevel": lc["LogLevel"],
+			}
+			if suffix, ok := logTypes[key]; ok {
+				entry["CloudWatchLogGroupArn"] = fmt.Sprintf("arn:aws:logs:us-east-1:%s:log-group:airflow-%s-%s", h.DefaultAccountID, env.name, suffix)
+			}
+			out[key] = entry
+		}
+		desc["LoggingConfiguration"] = out
+	}
+	if env.lastUpdate != nil {
+		update := make(map[string]interface{}, len(env.lastUpdate)+1)
+		for k, v := range env.lastUpdate {
+			update[k] = v
+		}
+		update["Status"] = s.transitions.Status(env.changed, "PENDING", "SUCCESS")
+		desc["LastUpdate"] = update
+	}
+	return desc
+}
+
+func (s *Service) getEnvironment(w http.ResponseWriter, name string) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	env, exists := s.environments[name]
+	if !exists {
+		writeNotFound(w, name)
+		return
+	}
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{"Environment": s.description(env)})
+}
+
+func (s *Service) listEnvironments(w http.ResponseWriter, r *http.Request) {
+	s.mu.RLock()
+	names := make([]string, 0, len(s.environments))
+	for name := range s.environments {
+		names = append(names, name)
+	}
+	s.mu.RUnlock()
+	sort.Strings(names)
+
+	q := r.URL.Query()
+	limit := 25
+	if l := q.Get("MaxResults"); l != "" {
+		fmt.Sscan(l, &limit)
+	}
+	page, next, err := paginate.Page(names, func(i int) string { return names[i] }, q.Get("NextToken"), limit, 25)
+	if err != nil {
+		writeValidation(w, "Invalid NextToken.")
+		return
+	}
+	resp := map[string]interface{}{"Environments": page}
+	if next != "" {
+		resp["NextToken"] = next
+	}
+	h.WriteJSON(w, http.StatusOK, resp)
+}
+
+// updatable are the settings UpdateEnvironment can change.
+var updatable = map[string]bool{
+	"AirflowConfigurationOptions":  true,
+	"AirflowVersion":               true,
+	"DagS3Path":                    true,
+	"EnvironmentClass":             true,
+	"ExecutionRoleArn":             true,
+	"LoggingConfiguration":         true,
+	"MaxWebservers":                true,
+	"MaxWorkers":                   true,
+	"MinWebservers":                true,
+	"MinWorkers":                   true,
+	"NetworkConfiguration":         true,
+	"PluginsS3ObjectVersion":       true,
+	"PluginsS3Path":                true,
+	"RequirementsS3ObjectVersion":  true,
+	"RequirementsS3Path":           true,
+	"Schedulers":                   true,
+	"SourceBucketArn":              true,
+	"StartupScriptS3ObjectVersion": true,
+	"StartupScriptS3Path":          true,
+	"WebserverAccessMode":          true,
+	"WeeklyMaintenanceWindowStart": true,
+}
+
+func (s *Service) updateEnvironment(w http.ResponseWriter, r *http.Request, name string) {
+	params, err := readParams(r)
+	if err != nil {
+		writeValidation(w, "Request body is not valid JSON.")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	env, exists := s.environments[name]
+	if !exists {
+		writeNotFound(w, name)
+		return
+	}
+	if status := s.state(env); status != "AVAILABLE" {
+		writeValidation(w, "Environment "+name+" is "+status+" and cannot be updated.")
+		return
+	}
+
+	cfg := make(map[string]interface{}, len(env.config))
+	for k, v := range env.config {
+		cfg[k] = v
+	}
+	for k, v := range params {
+		if k == "Name" {
+			continue
+		}
+		if !updatable[k] {
+			writeValidation(w, k+" cannot be updated.")
+			return
+		}
+		cfg[k] = v
+	}
+	if v := h.GetString(cfg, "AirflowVersion"); versionIndex(v) < versionIndex(h.GetString(env.config, "AirflowVersion")) {
+		writeValidation(w, "AirflowVersion "+v+" is not a supported upgrade from "+h.GetString(env.config, "AirflowVersion")+".")
+		return
+	}
+	if msg := s.validate(cfg); msg != "" {
+		writeValidation(w, msg)
+		return
+	}
+
+	env.config = cfg
+	env.status = "UPDATING"
+	env.changed = s.transitions.Begin()
+	env.lastUpdate = map[string]interface{}{
+		"CreatedAt": float64(s.now().Unix()),
+		"Source":    "Updater",
+	}
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{"Arn": env.arn})
+}
+
+func (s *Service) deleteEnvironment(w http.ResponseWriter, name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	env, exists := s.environments[name]
+	if !exists {
+		writeNotFound(w, name)
+		return
+	}
+	delete(s.environments, name)
+	s.tags.Delete(env.arn)
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{})
+}
+
+// available returns the named environment if it can serve its web server,
+// writing an error and returning nil otherwise.
+func (s *Service) available(w http.ResponseWriter, name string) *environment {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	env, exists := s.environments[name]
+	if !exists {
+		writeNotFound(w, name)
+		return nil
+	}
+	if status := s.state(env); status != "AVAILABLE" && status != "UPDATING" {
+		writeValidation(w, "Environment "+name+" is "+status+".")
+		return nil
+	}
+	return env
+}
+
+func (s *Service) createCliToken(w http.ResponseWriter, name string) {
+	env := s.available(w, name)
+	if env == nil {
+		return
+	}
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"CliToken":          h.RandomID(64),
+		"WebServerHostname": env.hostname,
+	})
+}
+
+func (s *Service) createWebLoginToken(w http.ResponseWriter, name string) {
+	env := s.available(w, name)
+	if env == nil {
+		return
+	}
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"WebToken":          h.RandomID(64),
+		"WebServerHostname": env.hostname,
+		"IamIdentity":       fmt.Sprintf("arn:aws:iam::%s:user/test", h.DefaultAccountID),
+		"AirflowIdentity":   "assumed-role/test",
+	})
+}
@@ -8,6 +8,9 @@
 //   - CreateDBInstance
 //   - DescribeDBInstances
 //   - DeleteDBInstance
+//   - AddTagsToResource
+//   - RemoveTagsFromResource
+//   - ListTagsForResource
 package neptune
 
 import (
@@ -19,6 +22,7 @@ import (
 	"time"
 
 	h "github.com/riyanimam/goto/internal/mockhelpers"
+	"github.com/riyanimam/goto/internal/tags"
 )
 
 // Service implements the Neptune mock.
@@ -26,6 +30,7 @@ type Service struct {
 	mu        sync.RWMutex
 	clusters  map[string]*cluster
 	instances map[string]*instance
+	tags      *tags.Store
 }
 
 type cluster struct {
@@ -57,6 +62,7 @@ func New() *Service {
 	return &Service{
 		clusters:  make(map[string]*cluster),
 		instances: make(map[string]*instance),
+		tags:      tags.New(),
 	}
 }
 
@@ -65,39 +71,42 @@ func (s *Service) Name() string { return "neptune" }
 
 // Handler returns the HTTP handler for Neptune requests.
 func (s *Service) Handler() http.Handler {
-	return http.HandlerFunc(s.handle)
+	return h.QueryRouter{
+		Actions: map[string]http.HandlerFunc{
+			"CreateDBCluster":        s.createDBCluster,
+			"DescribeDBClusters":     s.describeDBClusters,
+			"DeleteDBCluster":        s.deleteDBCluster,
+			"ModifyDBCluster":        s.modifyDBCluster,
+			"CreateDBInstance":       s.createDBInstance,
+			"DescribeDBInstances":    s.describeDBInstances,
+			"DeleteDBInstance":       s.deleteDBInstance,
+			"AddTagsToResource":      s.addTagsToResource,
+			"RemoveTagsFromResource": s.removeTagsFromResource,
+			"ListTagsForResource":    s.listTagsForResource,
+		},
+	}
 }
 
-// Reset clears all state.
+// Reset clears all state. Neptune resources have rds ARNs, so their tags
+// are removed one by one rather than by service.
 func (s *Service) Reset() {
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	for _, c := range s.clusters {
+		s.tags.Delete(c.arn)
+	}
+	for _, inst := range s.instances {
+		s.tags.Delete(inst.arn)
+	}
 	s.clusters = make(map[string]*cluster)
 	s.instances = make(map[string]*instance)
 }
 
-func (s *Service) handle(w http.ResponseWriter, r *http.Request) {
-	r.ParseForm()
-	action := r.FormValue("Action")
-
-	switch action {
-	case "CreateDBCluster":
-		s.createDBCluster(w, r)
-	case "DescribeDBClusters":
-		s.describeDBClusters(w, r)
-	case "DeleteDBCluster":
-		s.deleteDBCluster(w, r)
-	case "ModifyDBCluster":
-		s.modifyDBCluster(w, r)
-	case "CreateDBInstance":
-		s.createDBInstance(w, r)
-	case "DescribeDBInstances":
-		s.describeDBInstances(w, r)
-	case "DeleteDBInstance":
-		s.deleteDBInstance(w, r)
-	default:
-		h.WriteXMLError(w, "Sender", "InvalidAction", fmt.Sprintf("action %q is not supported", action), http.StatusBadRequest)
-	}
+// SetTagStore sets the registry resource tags are recorded in.
+func (s *Service) SetTagStore(store *tags.Store) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tags = store
 }
 
 // --- Cluster operations ---
@@ -136,6 +145,7 @@ func (s *Service) createDBCluster(w http.ResponseWriter, r *http.Request) {
 		created:         time.Now().UTC(),
 	}
 	s.clusters[id] = c
+	s.tags.Tag(arn, tags.FromForm(r.Form, "Tags.Tag."))
 	s.mu.Unlock()
 
 	type result struct {
@@ -201,6 +211,7 @@ func (s *Service) deleteDBCluster(w http.ResponseWriter, r *http.Request) {
 	c.status = "deleting"
 	x := clusterToXML(c)
 	delete(s.clusters, id)
+	s.tags.Delete(c.arn)
 	s.mu.Unlock()
 
 	type result struct {
@@ -291,6 +302,7 @@ func (s *Service) createDBInstance(w http.ResponseWriter, r *http.Request) {
 		created:       time.Now().UTC(),
 	}
 	s.instances[id] = inst
+	s.tags.Tag(arn, tags.FromForm(r.Form, "Tags.Tag."))
 	s.mu.Unlock()
 
 	type result struct {
@@ -356,6 +368,7 @@ func (s *Service) deleteDBInstance(w http.ResponseWriter, r *http.Request) {
 	inst.status = "deleting"
 	x := instanceToXML(inst)
 	delete(s.instances, id)
+	s.tags.Delete(inst.arn)
 	s.mu.Unlock()
 
 	type result struct {
@@ -389,6 +402,92 @@ type clusterXML struct {
 	Port                int    `xml:"Port"`
 }
 
+// --- Tag operations ---
+
+// hasResource reports whether arn names an existing cluster or instance.
+// The caller must hold s.mu.
+func (s *Service) hasResource(arn string) bool {
+	for _, c := range s.clusters {
+		if c.arn == arn {
+			return true
+		}
+	}
+	for _, inst := range s.instances {
+		if inst.arn == arn {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Service) addTagsToResource(w http.ResponseWriter, r *http.Request) {
+	arn := r.FormValue("ResourceName")
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if !s.hasResource(arn) {
+		h.WriteXMLError(w, "Sender", "DBClusterNotFoundFault", "Resource "+arn+" not found", http.StatusNotFound)
+		return
+	}
+	s.tags.Tag(arn, tags.FromForm(r.Form, "Tags.Tag."))
+
+	type resp struct {
+		XMLName  xml.Name     `xml:"AddTagsToResourceResponse"`
+		Metadata responseMeta `xml:"ResponseMetadata"`
+	}
+	h.WriteXML(w, http.StatusOK, resp{Metadata: responseMeta{RequestID: h.NewRequestID()}})
+}
+
+func (s *Service) removeTagsFromResource(w http.ResponseWriter, r *http.Request) {
+	arn := r.FormValue("ResourceName")
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if !s.hasResource(arn) {
+		h.WriteXMLError(w, "Sender", "DBClusterNotFoundFault", "Resource "+arn+" not found", http.StatusNotFound)
+		return
+	}
+	s.tags.Untag(arn, tags.KeysFromForm(r.Form, "TagKeys.member."))
+
+	type resp struct {
+		XMLName  xml.Name     `xml:"RemoveTagsFromResourceResponse"`
+		Metadata responseMeta `xml:"ResponseMetadata"`
+	}
+	h.WriteXML(w, http.StatusOK, resp{Metadata: responseMeta{RequestID: h.NewRequestID()}})
+}
+
+func (s *Service) listTagsForResource(w http.ResponseWriter, r *http.Request) {
+	arn := r.FormValue("ResourceName")
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if !s.hasResource(arn) {
+		h.WriteXMLError(w, "Sender", "DBClusterNotFoundFault", "Resource "+arn+" not found", http.StatusNotFound)
+		return
+	}
+
+	type tag struct {
+		Key   string `xml:"Key"`
+		Value string `xml:"Value"`
+	}
+	type result struct {
+		TagList []tag `xml:"TagList>Tag"`
+	}
+	type resp struct {
+		XMLName  xml.Name     `xml:"ListTagsForResourceResponse"`
+		Result   result       `xml:"ListTagsForResourceResult"`
+		Metadata responseMeta `xml:"ResponseMetadata"`
+	}
+	var list []tag
+	for _, t := range tags.ToList(s.tags.Get(arn), "Key", "Value") {
+		list = append(list, tag{Key: t["Key"], Value: t["Value"]})
+	}
+	h.WriteXML(w, http.StatusOK, resp{
+		Result:   result{TagList: list},
+		Metadata: responseMeta{RequestID: h.NewRequestID()},
+	})
+}
+
 func clusterToXML(c *cluster) clusterXML {
 	return clusterXML{
 		DBClusterIdentifier: c.identifier,
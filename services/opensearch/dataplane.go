@@ -0,0 +1,599 @@
+package opensearch
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httputil"
+	"sort"
+	"strings"
+
+	h "github.com/riyanimam/goto/internal/mockhelpers"
+)
+
+// dataPlanePrefix is the path under which domain endpoints are served.
+const dataPlanePrefix = "/_opensearch/"
+
+// index is a single OpenSearch index in the embedded engine.
+type index struct {
+	docs    map[string]map[string]interface{}
+	version map[string]int
+	order   []string // document IDs in insertion order
+}
+
+func newIndex() *index {
+	return &index{
+		docs:    make(map[string]map[string]interface{}),
+		version: make(map[string]int),
+	}
+}
+
+func (idx *index) put(id string, source map[string]interface{}) (string, int) {
+	result := "updated"
+	if _, exists := idx.docs[id]; !exists {
+		result = "created"
+		idx.order = append(idx.order, id)
+	}
+	idx.docs[id] = source
+	idx.version[id]++
+	return result, idx.version[id]
+}
+
+func (idx *index) remove(id string) bool {
+	if _, exists := idx.docs[id]; !exists {
+		return false
+	}
+	delete(idx.docs, id)
+	delete(idx.version, id)
+	for i, v := range idx.order {
+		if v == id {
+			idx.order = append(idx.order[:i], idx.order[i+1:]...)
+			break
+		}
+	}
+	return true
+}
+
+// serveDataPlane handles requests made to a domain endpoint.
+func (s *Service) serveDataPlane(w http.ResponseWriter, r *http.Request) {
+	rest := strings.TrimPrefix(r.URL.Path, dataPlanePrefix)
+	name, subPath, _ := strings.Cut(rest, "/")
+	subPath = "/" + subPath
+
+	s.mu.RLock()
+	d, exists := s.domains[name]
+	proxy := s.proxy
+	s.mu.RUnlock()
+
+	if !exists {
+		writeSearchError(w, "index_not_found_exception", "no such domain ["+name+"]", http.StatusNotFound)
+		return
+	}
+
+	if proxy != nil {
+		rp := httputil.NewSingleHostReverseProxy(proxy)
+		r.URL.Path = subPath
+		r.URL.RawPath = ""
+		r.Host = proxy.Host
+		rp.ServeHTTP(w, r)
+		return
+	}
+
+	body, _ := io.ReadAll(r.Body)
+	parts := strings.Split(strings.Trim(subPath, "/"), "/")
+	method := r.Method
+
+	switch {
+	case subPath == "/" && method == http.MethodGet:
+		h.WriteJSON(w, http.StatusOK, map[string]interface{}{
+			"name":         d.name,
+			"cluster_name": h.DefaultAccountID + ":" + d.name,
+			"version": map[string]interface{}{
+				"distribution": "opensearch",
+				"number":       strings.TrimPrefix(d.engineVersion, "OpenSearch_"),
+			},
+			"tagline": "The OpenSearch Project: https://opensearch.org/",
+		})
+	case parts[0] == "_bulk" && method == http.MethodPost:
+		s.bulk(w, d, "", body)
+	case len(parts) == 2 && parts[1] == "_bulk" && method == http.MethodPost:
+		s.bulk(w, d, parts[0], body)
+	case len(parts) == 1 && method == http.MethodPut:
+		s.createIndex(w, d, parts[0])
+	case len(parts) == 1 && method == http.MethodHead:
+		s.mu.RLock()
+		_, ok := d.indices[parts[0]]
+		s.mu.RUnlock()
+		if ok {
+			w.WriteHeader(http.StatusOK)
+		} else {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	case len(parts) == 1 && method == http.MethodDelete:
+		s.deleteIndex(w, d, parts[0])
+	case len(parts) == 2 && parts[1] == "_refresh":
+		h.WriteJSON(w, http.StatusOK, map[string]interface{}{
+			"_shards": map[string]interface{}{"total": 1, "successful": 1, "failed": 0},
+		})
+	case len(parts) == 2 && (parts[1] == "_search" || parts[1] == "_count"):
+		s.search(w, d, parts[0], body, parts[1] == "_count")
+	case len(parts) == 2 && parts[1] == "_doc" && method == http.MethodPost:
+		s.indexDocument(w, d, parts[0], h.RandomHex(20), body)
+	case len(parts) == 3 && (parts[1] == "_doc" || parts[1] == "_create") && (method == http.MethodPut || method == http.MethodPost):
+		s.indexDocument(w, d, parts[0], parts[2], body)
+	case len(parts) == 3 && parts[1] == "_doc" && method == http.MethodGet:
+		s.getDocument(w, d, parts[0], parts[2])
+	case len(parts) == 3 && parts[1] == "_doc" && method == http.MethodDelete:
+		s.deleteDocument(w, d, parts[0], parts[2])
+	default:
+		writeSearchError(w, "illegal_argument_exception", fmt.Sprintf("unsupported request [%s %s]", method, subPath), http.StatusBadRequest)
+	}
+}
+
+func (s *Service) createIndex(w http.ResponseWriter, d *domain, name string) {
+	s.mu.Lock()
+	if _, exists := d.indices[name]; exists {
+		s.mu.Unlock()
+		writeSearchError(w, "resource_already_exists_exception", "index ["+name+"] already exists", http.StatusBadRequest)
+		return
+	}
+	d.indices[name] = newIndex()
+	s.mu.Unlock()
+
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"acknowledged":        true,
+		"shards_acknowledged": true,
+		"index":               name,
+	})
+}
+
+func (s *Service) deleteIndex(w http.ResponseWriter, d *domain, name string) {
+	s.mu.Lock()
+	if _, exists := d.indices[name]; !exists {
+		s.mu.Unlock()
+		writeSearchError(w, "index_not_found_exception", "no such index ["+name+"]", http.StatusNotFound)
+		return
+	}
+	delete(d.indices, name)
+	s.mu.Unlock()
+
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{"acknowledged": true})
+}
+
+func (s *Service) indexDocument(w http.ResponseWriter, d *domain, indexName, id string, body []byte) {
+	var source map[string]interface{}
+	if err := json.Unmarshal(body, &source); err != nil {
+		writeSearchError(w, "mapper_parsing_exception", "failed to parse document", http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	idx, ok := d.indices[indexName]
+	if !ok {
+		// Like OpenSearch, indexing into a missing index creates it.
+		idx = newIndex()
+		d.indices[indexName] = idx
+	}
+	result, version := idx.put(id, source)
+	s.mu.Unlock()
+
+	status := http.StatusOK
+	if result == "created" {
+		status = http.StatusCreated
+	}
+	h.WriteJSON(w, status, map[string]interface{}{
+		"_index":   indexName,
+		"_id":      id,
+		"_version": version,
+		"result":   result,
+	})
+}
+
+func (s *Service) getDocument(w http.ResponseWriter, d *domain, indexName, id string) {
+	s.mu.RLock()
+	var source map[string]interface{}
+	var version int
+	idx, ok := d.indices[indexName]
+	if ok {
+		source = idx.docs[id]
+		version = idx.version[id]
+	}
+	s.mu.RUnlock()
+
+	if !ok {
+		writeSearchError(w, "index_not_found_exception", "no such index ["+indexName+"]", http.StatusNotFound)
+		return
+	}
+	if source == nil {
+		h.WriteJSON(w, http.StatusNotFound, map[string]interface{}{
+			"_index": indexName,
+			"_id":    id,
+			"found":  false,
+		})
+		return
+	}
+
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"_index":   indexName,
+		"_id":      id,
+		"_version": version,
+		"found":    true,
+		"_source":  source,
+	})
+}
+
+func (s *Service) deleteDocument(w http.ResponseWriter, d *domain, indexName, id string) {
+	s.mu.Lock()
+	removed := false
+	if idx, ok := d.indices[indexName]; ok {
+		removed = idx.remove(id)
+	}
+	s.mu.Unlock()
+
+	result, status := "deleted", http.StatusOK
+	if !removed {
+		result, status = "not_found", http.StatusNotFound
+	}
+	h.WriteJSON(w, status, map[string]interface{}{
+		"_index": indexName,
+		"_id":    id,
+		"result": result,
+	})
+}
+
+// bulk applies newline-delimited index, create, and delete actions.
+func (s *Service) bulk(w http.ResponseWriter, d *domain, defaultIndex string, body []byte) {
+	var items []map[string]interface{}
+	hasErrors := false
+
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	scanner.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+
+	s.mu.Lock()
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var action map[string]map[string]interface{}
+		if err := json.Unmarshal(line, &action); err != nil {
+			hasErrors = true
+			continue
+		}
+		for op, meta := range action {
+			indexName := h.GetString(meta, "_index")
+			if indexName == "" {
+				indexName = defaultIndex
+			}
+			id := h.GetString(meta, "_id")
+			if id == "" {
+				id = h.RandomHex(20)
+			}
+			idx, ok := d.indices[indexName]
+			if !ok {
+				idx = newIndex()
+				d.indices[indexName] = idx
+			}
+
+			item := map[string]interface{}{"_index": indexName, "_id": id}
+			switch op {
+			case "index", "create":
+				if !scanner.Scan() {
+					break
+				}
+				var source map[string]interface{}
+				if err := json.Unmarshal(scanner.Bytes(), &source); err != nil {
+					item["status"] = http.StatusBadRequest
+					item["error"] = map[string]interface{}{"type": "mapper_parsing_exception"}
+					hasErrors = true
+					break
+				}
+				result, version := idx.put(id, source)
+				item["result"] = result
+				item["_version"] = version
+				item["status"] = http.StatusCreated
+				if result == "updated" {
+					item["status"] = http.StatusOK
+				}
+			case "delete":
+				if idx.remove(id) {
+					item["result"] = "deleted"
+					item["status"] = http.StatusOK
+				} else {
+					item["result"] = "not_found"
+					item["status"] = http.StatusNotFound
+				}
+			default:
+				item["status"] = http.StatusBadRequest
+				item["error"] = map[string]interface{}{"type": "illegal_argument_exception", "reason": "unsupported bulk action " + op}
+				hasErrors = true
+			}
+			items = append(items, map[string]interface{}{op: item})
+		}
+	}
+	s.mu.Unlock()
+
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"took":   1,
+		"errors": hasErrors,
+		"items":  items,
+	})
+}
+
+// search evaluates a query DSL request against the named index. Supported
+// queries are match_all, match, term, terms, ids, range, exists, and bool
+// (must, filter, should, must_not).
+func (s *Service) search(w http.ResponseWriter, d *domain, indexName string, body []byte, countOnly bool) {
+	var req map[string]interface{}
+	if len(body) > 0 {
+		if err := json.Unmarshal(body, &req); err != nil {
+			writeSearchError(w, "parsing_exception", "failed to parse search request", http.StatusBadRequest)
+			return
+		}
+	}
+	query, _ := req["query"].(map[string]interface{})
+
+	type hit struct {
+		id     string
+		source map[string]interface{}
+	}
+
+	s.mu.RLock()
+	idx, ok := d.indices[indexName]
+	var hits []hit
+	if ok {
+		for _, id := range idx.order {
+			if matchQuery(query, id, idx.docs[id]) {
+				hits = append(hits, hit{id: id, source: idx.docs[id]})
+			}
+		}
+	}
+	s.mu.RUnlock()
+
+	if !ok {
+		writeSearchError(w, "index_not_found_exception", "no such index ["+indexName+"]", http.StatusNotFound)
+		return
+	}
+
+	if countOnly {
+		h.WriteJSON(w, http.StatusOK, map[string]interface{}{"count": len(hits)})
+		return
+	}
+
+	if sorts, ok := req["sort"].([]interface{}); ok && len(sorts) > 0 {
+		field, desc := sortSpec(sorts[0])
+		sort.SliceStable(hits, func(i, j int) bool {
+			c := compareValues(lookup(hits[i].source, field), lookup(hits[j].source, field))
+			if desc {
+				return c > 0
+			}
+			return c < 0
+		})
+	}
+
+	total := len(hits)
+	from := h.GetInt(req, "from", 0)
+	size := h.GetInt(req, "size", 10)
+	if from > len(hits) {
+		from = len(hits)
+	}
+	hits = hits[from:]
+	if size < len(hits) {
+		hits = hits[:size]
+	}
+
+	results := make([]map[string]interface{}, len(hits))
+	for i, ht := range hits {
+		results[i] = map[string]interface{}{
+			"_index":  indexName,
+			"_id":     ht.id,
+			"_score":  1.0,
+			"_source": ht.source,
+		}
+	}
+
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"took":      1,
+		"timed_out": false,
+		"hits": map[string]interface{}{
+			"total":     map[string]interface{}{"value": total, "relation": "eq"},
+			"max_score": 1.0,
+			"hits":      results,
+		},
+	})
+}
+
+func sortSpec(v interface{}) (string, bool) {
+	switch spec := v.(type) {
+	case string:
+		return spec, false
+	case map[string]interface{}:
+		for field, opts := range spec {
+			switch o := opts.(type) {
+			case string:
+				return field, o == "desc"
+			case map[string]interface{}:
+				return field, h.GetString(o, "order") == "desc"
+			}
+		}
+	}
+	return "", false
+}
+
+func matchQuery(query map[string]interface{}, id string, doc map[string]interface{}) bool {
+	if len(query) == 0 {
+		return true
+	}
+	for kind, raw := range query {
+		clause, _ := raw.(map[string]interface{})
+		switch kind {
+		case "match_all":
+		case "match_none":
+			return false
+		case "ids":
+			values, _ := clause["values"].([]interface{})
+			found := false
+			for _, v := range values {
+				if fmt.Sprint(v) == id {
+					found = true
+				}
+			}
+			if !found {
+				return false
+			}
+		case "term":
+			for field, want := range clause {
+				if m, ok := want.(map[string]interface{}); ok {
+					want = m["value"]
+				}
+				if compareValues(lookup(doc, field), want) != 0 {
+					return false
+				}
+			}
+		case "terms":
+			for field, want := range clause {
+				values, _ := want.([]interface{})
+				found := false
+				for _, v := range values {
+					if compareValues(lookup(doc, field), v) == 0 {
+						found = true
+					}
+				}
+				if !found {
+					return false
+				}
+			}
+		case "match", "match_phrase":
+			for field, want := range clause {
+				if m, ok := want.(map[string]interface{}); ok {
+					want = m["query"]
+				}
+				got := strings.ToLower(fmt.Sprint(lookup(doc, field)))
+				if !strings.Contains(got, strings.ToLower(fmt.Sprint(want))) {
+					return false
+				}
+			}
+		case "exists":
+			if lookup(doc, h.GetString(clause, "field")) == nil {
+				return false
+			}
+		case "range":
+			for field, raw := range clause {
+				bounds, _ := raw.(map[string]interface{})
+				got := lookup(doc, field)
+				if got == nil {
+					return false
+				}
+				for op, bound := range bounds {
+					c := compareValues(got, bound)
+					if (op == "gt" && c <= 0) || (op == "gte" && c < 0) ||
+						(op == "lt" && c >= 0) || (op == "lte" && c > 0) {
+						return false
+					}
+				}
+			}
+		case "bool":
+			if !matchBool(clause, id, doc) {
+				return false
+			}
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func matchBool(clause map[string]interface{}, id string, doc map[string]interface{}) bool {
+	clauses := func(key string) []map[string]interface{} {
+		var out []map[string]interface{}
+		switch v := clause[key].(type) {
+		case map[string]interface{}:
+			out = append(out, v)
+		case []interface{}:
+			for _, c := range v {
+				if m, ok := c.(map[string]interface{}); ok {
+					out = append(out, m)
+				}
+			}
+		}
+		return out
+	}
+
+	for _, key := range []string{"must", "filter"} {
+		for _, q := range clauses(key) {
+			if !matchQuery(q, id, doc) {
+				return false
+			}
+		}
+	}
+	for _, q := range clauses("must_not") {
+		if matchQuery(q, id, doc) {
+			return false
+		}
+	}
+	if should := clauses("should"); len(should) > 0 {
+		matched := 0
+		for _, q := range should {
+			if matchQuery(q, id, doc) {
+				matched++
+			}
+		}
+		minimum := 1
+		if len(clauses("must")) > 0 || len(clauses("filter")) > 0 {
+			minimum = 0
+		}
+		minimum = h.GetInt(clause, "minimum_should_match", minimum)
+		if matched < minimum {
+			return false
+		}
+	}
+	return true
+}
+
+// lookup resolves a dotted field path within a document. Keyword subfields
+// ("name.keyword") resolve to the parent field.
+func lookup(doc map[string]interface{}, field string) interface{} {
+	field = strings.TrimSuffix(field, ".keyword")
+	var cur interface{} = doc
+	for _, part := range strings.Split(field, ".") {
+		m, ok := cur.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		cur = m[part]
+	}
+	return cur
+}
+
+// compareValues orders two JSON values, comparing numerically when both are
+// numbers and lexically otherwise.
+func compareValues(a, b interface{}) int {
+	af, aNum := a.(float64)
+	bf, bNum := b.(float64)
+	if aNum && bNum {
+		switch {
+		case af < bf:
+			return -1
+		case af > bf:
+			return 1
+		}
+		return 0
+	}
+	return strings.Compare(fmt.Sprint(a), fmt.Sprint(b))
+}
+
+func writeSearchError(w http.ResponseWriter, errType, reason string, status int) {
+	h.WriteJSON(w, status, map[string]interface{}{
+		"error": map[string]interface{}{
+			"type":   errType,
+			"reason": reason,
+			"root_cause": []map[string]interface{}{
+				{"type": errType, "reason": reason},
+			},
+		},
+		"status": status,
+	})
+}
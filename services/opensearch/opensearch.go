@@ -6,6 +6,15 @@
 //   - DeleteDomain
 //   - ListDomainNames
 //   - UpdateDomainConfig
+//   - DescribeDomainConfig
+//   - AddTags
+//   - RemoveTags
+//   - ListTags
+//
+// Each domain's Endpoint points back at the mock server, where a minimal
+// embedded index/search engine serves the domain's data plane (see
+// dataplane.go). Use [Service.SetProxy] to forward data-plane requests to a
+// real OpenSearch cluster instead.
 package opensearch
 
 import (
@@ -13,35 +22,64 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"sort"
 	"strings"
 	"sync"
 	"time"
 
 	h "github.com/riyanimam/goto/internal/mockhelpers"
+	"github.com/riyanimam/goto/internal/tags"
 )
 
+// configOptions lists the DomainConfig sections stored verbatim from
+// CreateDomain and UpdateDomainConfig requests.
+var configOptions = []string{
+	"ClusterConfig",
+	"EBSOptions",
+	"SnapshotOptions",
+	"VPCOptions",
+	"CognitoOptions",
+	"EncryptionAtRestOptions",
+	"NodeToNodeEncryptionOptions",
+	"AdvancedOptions",
+	"LogPublishingOptions",
+	"DomainEndpointOptions",
+	"AdvancedSecurityOptions",
+	"AutoTuneOptions",
+	"OffPeakWindowOptions",
+	"SoftwareUpdateOptions",
+}
+
 // Service implements the OpenSearch mock.
 type Service struct {
 	mu      sync.RWMutex
 	domains map[string]*domain
+	baseURL string
+	proxy   *url.URL
+	tags    *tags.Store
 }
 
 type domain struct {
-	name          string
-	arn           string
-	domainID      string
-	engineVersion string
-	endpoint      string
-	clusterConfig interface{}
-	processing    bool
-	created       time.Time
+	name           string
+	arn            string
+	domainID       string
+	engineVersion  string
+	endpoint       string
+	accessPolicies string
+	options        map[string]interface{}
+	updated        map[string]time.Time
+	updateVersion  int
+	processing     bool
+	created        time.Time
+	indices        map[string]*index
 }
 
 // New creates a new OpenSearch mock service.
 func New() *Service {
 	return &Service{
 		domains: make(map[string]*domain),
+		tags:    tags.New(),
 	}
 }
 
@@ -58,6 +96,38 @@ func (s *Service) Reset() {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	s.domains = make(map[string]*domain)
+	s.tags.DeleteService("es")
+}
+
+// SetTagStore sets the registry domain tags are recorded in.
+func (s *Service) SetTagStore(store *tags.Store) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tags = store
+}
+
+// SetBaseURL records the mock server URL so domain endpoints resolve to it.
+func (s *Service) SetBaseURL(u string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.baseURL = u
+}
+
+// SetProxy forwards every domain's data-plane requests to target (for
+// example a local OpenSearch container) instead of the embedded engine.
+// An empty target restores the embedded engine.
+func (s *Service) SetProxy(target string) error {
+	var u *url.URL
+	if target != "" {
+		var err error
+		if u, err = url.Parse(target); err != nil {
+			return fmt.Errorf("invalid proxy URL %q: %w", target, err)
+		}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.proxy = u
+	return nil
 }
 
 func (s *Service) handle(w http.ResponseWriter, r *http.Request) {
@@ -65,10 +135,30 @@ func (s *Service) handle(w http.ResponseWriter, r *http.Request) {
 	method := r.Method
 
 	switch {
+	// Data plane: /_opensearch/{name}/...
+	case strings.HasPrefix(path, dataPlanePrefix):
+		s.serveDataPlane(w, r)
+
+	// AddTags: POST /2021-01-01/tags
+	case path == "/2021-01-01/tags" && method == http.MethodPost:
+		s.addTags(w, r)
+
+	// RemoveTags: POST /2021-01-01/tags-removal
+	case path == "/2021-01-01/tags-removal" && method == http.MethodPost:
+		s.removeTags(w, r)
+
+	// ListTags: GET /2021-01-01/tags/?arn={arn}
+	case strings.TrimSuffix(path, "/") == "/2021-01-01/tags" && method == http.MethodGet:
+		s.listTags(w, r)
+
 	// UpdateDomainConfig: POST /2021-01-01/opensearch/domain/{name}/config
 	case strings.HasSuffix(path, "/config") && strings.Contains(path, "/2021-01-01/opensearch/domain/") && method == http.MethodPost:
 		s.updateDomainConfig(w, r, path)
 
+	// DescribeDomainConfig: GET /2021-01-01/opensearch/domain/{name}/config
+	case strings.HasSuffix(path, "/config") && strings.Contains(path, "/2021-01-01/opensearch/domain/") && method == http.MethodGet:
+		s.describeDomainConfig(w, r, path)
+
 	// DescribeDomain: GET /2021-01-01/opensearch/domain/{name}
 	case strings.HasPrefix(path, "/2021-01-01/opensearch/domain/") && method == http.MethodGet:
 		s.describeDomain(w, r, path)
@@ -115,11 +205,6 @@ func (s *Service) createDomain(w http.ResponseWriter, r *http.Request) {
 		engineVersion = "OpenSearch_2.11"
 	}
 
-	var clusterConfig interface{}
-	if cc, ok := params["ClusterConfig"]; ok {
-		clusterConfig = cc
-	}
-
 	s.mu.Lock()
 	if _, exists := s.domains[name]; exists {
 		s.mu.Unlock()
@@ -130,18 +215,27 @@ func (s *Service) createDomain(w http.ResponseWriter, r *http.Request) {
 	domainID := h.RandomHex(12)
 	arn := fmt.Sprintf("arn:aws:es:us-east-1:%s:domain/%s", h.DefaultAccountID, name)
 	endpoint := fmt.Sprintf("search-%s-%s.us-east-1.es.amazonaws.com", name, h.RandomHex(28))
+	if s.baseURL != "" {
+		endpoint = strings.TrimPrefix(s.baseURL, "http://") + dataPlanePrefix + name
+	}
 
+	now := time.Now().UTC()
 	d := &domain{
-		name:          name,
-		arn:           arn,
-		domainID:      domainID,
-		engineVersion: engineVersion,
-		endpoint:      endpoint,
-		clusterConfig: clusterConfig,
-		processing:    false,
-		created:       time.Now().UTC(),
+		name:           name,
+		arn:            arn,
+		domainID:       domainID,
+		engineVersion:  engineVersion,
+		endpoint:       endpoint,
+		accessPolicies: h.GetString(params, "AccessPolicies"),
+		options:        make(map[string]interface{}),
+		updated:        make(map[string]time.Time),
+		processing:     false,
+		created:        now,
+		indices:        make(map[string]*index),
 	}
+	d.applyOptions(params, now)
 	s.domains[name] = d
+	s.tags.Tag(arn, tags.FromList(params["TagList"], "Key", "Value"))
 	s.mu.Unlock()
 
 	h.WriteJSON(w, http.StatusOK, map[string]interface{}{
@@ -178,6 +272,7 @@ func (s *Service) deleteDomain(w http.ResponseWriter, _ *http.Request, path stri
 	}
 	resp := domainResp(d)
 	delete(s.domains, name)
+	s.tags.Delete(d.arn)
 	s.mu.Unlock()
 
 	h.WriteJSON(w, http.StatusOK, map[string]interface{}{
@@ -223,21 +318,90 @@ func (s *Service) updateDomainConfig(w http.ResponseWriter, r *http.Request, pat
 		return
 	}
 
+	now := time.Now().UTC()
 	if v := h.GetString(params, "EngineVersion"); v != "" {
 		d.engineVersion = v
+		d.updated["EngineVersion"] = now
 	}
-	if cc, ok := params["ClusterConfig"]; ok {
-		d.clusterConfig = cc
+	if _, ok := params["AccessPolicies"]; ok {
+		d.accessPolicies = h.GetString(params, "AccessPolicies")
+		d.updated["AccessPolicies"] = now
 	}
+	d.applyOptions(params, now)
+	d.updateVersion++
 	d.processing = true
-	resp := domainResp(d)
+	resp := domainConfigResp(d)
 	s.mu.Unlock()
 
 	h.WriteJSON(w, http.StatusOK, map[string]interface{}{
-		"DomainStatus": resp,
+		"DomainConfig": resp,
+	})
+}
+
+func (s *Service) describeDomainConfig(w http.ResponseWriter, _ *http.Request, path string) {
+	name := extractDomainName(path)
+
+	s.mu.RLock()
+	d, exists := s.domains[name]
+	var resp map[string]interface{}
+	if exists {
+		resp = domainConfigResp(d)
+	}
+	s.mu.RUnlock()
+
+	if !exists {
+		h.WriteJSONError(w, "ResourceNotFoundException", "Domain "+name+" not found", http.StatusNotFound)
+		return
+	}
+
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"DomainConfig": resp,
 	})
 }
 
+// applyOptions stores any DomainConfig sections present in params.
+func (d *domain) applyOptions(params map[string]interface{}, now time.Time) {
+	for _, key := range configOptions {
+		if v, ok := params[key]; ok {
+			d.options[key] = v
+			d.updated[key] = now
+		}
+	}
+}
+
+func domainConfigResp(d *domain) map[string]interface{} {
+	status := func(key string) map[string]interface{} {
+		updated, ok := d.updated[key]
+		if !ok {
+			updated = d.created
+		}
+		return map[string]interface{}{
+			"CreationDate":  float64(d.created.Unix()),
+			"UpdateDate":    float64(updated.Unix()),
+			"UpdateVersion": d.updateVersion,
+			"State":         "Active",
+		}
+	}
+
+	resp := map[string]interface{}{
+		"EngineVersion": map[string]interface{}{
+			"Options": d.engineVersion,
+			"Status":  status("EngineVersion"),
+		},
+		"AccessPolicies": map[string]interface{}{
+			"Options": d.accessPolicies,
+			"Status":  status("AccessPolicies"),
+		},
+	}
+	for key, v := range d.options {
+		resp[key] = map[string]interface{}{
+			"Options": v,
+			"Status":  status(key),
+		}
+	}
+	return resp
+}
+
 func domainResp(d *domain) map[string]interface{} {
 	resp := map[string]interface{}{
 		"DomainName":    d.name,
@@ -249,8 +413,68 @@ func domainResp(d *domain) map[string]interface{} {
 		"Created":       true,
 		"CreatedAt":     float64(d.created.Unix()),
 	}
-	if d.clusterConfig != nil {
-		resp["ClusterConfig"] = d.clusterConfig
+	if d.accessPolicies != "" {
+		resp["AccessPolicies"] = d.accessPolicies
+	}
+	for key, v := range d.options {
+		resp[key] = v
 	}
 	return resp
 }
+
+// hasDomain reports whether arn names an existing domain. The caller must
+// hold s.mu.
+func (s *Service) hasDomain(arn string) bool {
+	for _, d := range s.domains {
+		if d.arn == arn {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Service) addTags(w http.ResponseWriter, r *http.Request) {
+	bodyBytes, _ := io.ReadAll(r.Body)
+	var params map[string]interface{}
+	json.Unmarshal(bodyBytes, &params)
+	arn := h.GetString(params, "ARN")
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if !s.hasDomain(arn) {
+		h.WriteJSONError(w, "ResourceNotFoundException", "Domain "+arn+" not found", http.StatusNotFound)
+		return
+	}
+	s.tags.Tag(arn, tags.FromList(params["TagList"], "Key", "Value"))
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{})
+}
+
+func (s *Service) removeTags(w http.ResponseWriter, r *http.Request) {
+	bodyBytes, _ := io.ReadAll(r.Body)
+	var params map[string]interface{}
+	json.Unmarshal(bodyBytes, &params)
+	arn := h.GetString(params, "ARN")
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if !s.hasDomain(arn) {
+		h.WriteJSONError(w, "ResourceNotFoundException", "Domain "+arn+" not found", http.StatusNotFound)
+		return
+	}
+	s.tags.Untag(arn, tags.Keys(params["TagKeys"]))
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{})
+}
+
+func (s *Service) listTags(w http.ResponseWriter, r *http.Request) {
+	arn := r.URL.Query().Get("arn")
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if !s.hasDomain(arn) {
+		h.WriteJSONError(w, "ResourceNotFoundException", "Domain "+arn+" not found", http.StatusNotFound)
+		return
+	}
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"TagList": tags.ToList(s.tags.Get(arn), "Key", "Value"),
+	})
+}
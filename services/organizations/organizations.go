@@ -8,18 +8,19 @@
 //   - DescribeAccount
 //   - CreateOrganizationalUnit
 //   - ListOrganizationalUnitsForParent
+//   - TagResource
+//   - UntagResource
+//   - ListTagsForResource
 package organizations
 
 import (
-	"encoding/json"
 	"fmt"
-	"io"
 	"net/http"
-	"strings"
 	"sync"
 	"time"
 
 	h "github.com/riyanimam/goto/internal/mockhelpers"
+	"github.com/riyanimam/goto/internal/tags"
 )
 
 // Service implements the Organizations mock.
@@ -29,6 +30,7 @@ type Service struct {
 	accounts map[string]*account
 	ous      map[string]*organizationalUnit
 	rootID   string
+	tags     *tags.Store
 }
 
 type organization struct {
@@ -61,6 +63,7 @@ func New() *Service {
 	return &Service{
 		accounts: make(map[string]*account),
 		ous:      make(map[string]*organizationalUnit),
+		tags:     tags.New(),
 	}
 }
 
@@ -69,7 +72,22 @@ func (s *Service) Name() string { return "organizations" }
 
 // Handler returns the HTTP handler for Organizations requests.
 func (s *Service) Handler() http.Handler {
-	return http.HandlerFunc(s.handle)
+	return h.JSONRouter{
+		"CreateOrganization": s.createOrganization,
+		"DescribeOrganization": func(w http.ResponseWriter, _ map[string]interface{}) {
+			s.describeOrganization(w)
+		},
+		"ListAccounts": func(w http.ResponseWriter, _ map[string]interface{}) {
+			s.listAccounts(w)
+		},
+		"CreateAccount":                    s.createAccount,
+		"DescribeAccount":                  s.describeAccount,
+		"CreateOrganizationalUnit":         s.createOrganizationalUnit,
+		"ListOrganizationalUnitsForParent": s.listOrganizationalUnitsForParent,
+		"TagResource":                      s.tagResource,
+		"UntagResource":                    s.untagResource,
+		"ListTagsForResource":              s.listTagsForResource,
+	}
 }
 
 // Reset clears all state.
@@ -80,51 +98,14 @@ func (s *Service) Reset() {
 	s.accounts = make(map[string]*account)
 	s.ous = make(map[string]*organizationalUnit)
 	s.rootID = ""
+	s.tags.DeleteService("organizations")
 }
 
-func (s *Service) handle(w http.ResponseWriter, r *http.Request) {
-	target := r.Header.Get("X-Amz-Target")
-
-	bodyBytes, err := io.ReadAll(r.Body)
-	if err != nil {
-		h.WriteJSONError(w, "InternalFailure", "could not read request body", http.StatusInternalServerError)
-		return
-	}
-
-	var params map[string]interface{}
-	if len(bodyBytes) > 0 {
-		json.Unmarshal(bodyBytes, &params)
-	}
-	if params == nil {
-		params = make(map[string]interface{})
-	}
-
-	action := ""
-	if target != "" {
-		parts := strings.SplitN(target, ".", 2)
-		if len(parts) == 2 {
-			action = parts[1]
-		}
-	}
-
-	switch action {
-	case "CreateOrganization":
-		s.createOrganization(w, params)
-	case "DescribeOrganization":
-		s.describeOrganization(w)
-	case "ListAccounts":
-		s.listAccounts(w)
-	case "CreateAccount":
-		s.createAccount(w, params)
-	case "DescribeAccount":
-		s.describeAccount(w, params)
-	case "CreateOrganizationalUnit":
-		s.createOrganizationalUnit(w, params)
-	case "ListOrganizationalUnitsForParent":
-		s.listOrganizationalUnitsForParent(w, params)
-	default:
-		h.WriteJSONError(w, "UnknownOperationException", fmt.Sprintf("action %q is not supported", action), http.StatusBadRequest)
-	}
+// SetTagStore sets the registry resource tags are recorded in.
+func (s *Service) SetTagStore(store *tags.Store) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tags = store
 }
 
 func (s *Service) createOrganization(w http.ResponseWriter, params map[string]interface{}) {
@@ -240,6 +221,7 @@ func (s *Service) createAccount(w http.ResponseWriter, params map[string]interfa
 		joinedTimestamp: time.Now().UTC(),
 	}
 	s.accounts[acctID] = a
+	s.tags.Tag(a.arn, tags.FromList(params["Tags"], "Key", "Value"))
 	s.mu.Unlock()
 
 	h.WriteJSON(w, http.StatusOK, map[string]interface{}{
@@ -303,6 +285,7 @@ func (s *Service) createOrganizationalUnit(w http.ResponseWriter, params map[str
 		parentID: parentID,
 	}
 	s.ous[ouID] = ou
+	s.tags.Tag(ou.arn, tags.FromList(params["Tags"], "Key", "Value"))
 	s.mu.Unlock()
 
 	h.WriteJSON(w, http.StatusOK, map[string]interface{}{
@@ -339,6 +322,64 @@ func (s *Service) listOrganizationalUnitsForParent(w http.ResponseWriter, params
 	})
 }
 
+// resourceARN returns the ARN of the account, root or organizational unit
+// with the given ID, or "" if there is none. The caller must hold s.mu.
+func (s *Service) resourceARN(id string) string {
+	if a, ok := s.accounts[id]; ok {
+		return a.arn
+	}
+	if ou, ok := s.ous[id]; ok {
+		return ou.arn
+	}
+	return ""
+}
+
+func (s *Service) tagResource(w http.ResponseWriter, params map[string]interface{}) {
+	id := h.GetString(params, "ResourceId")
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	arn := s.resourceARN(id)
+	if arn == "" {
+		h.WriteJSONError(w, "TargetNotFoundException", "Target not found: "+id, http.StatusBadRequest)
+		return
+	}
+	s.tags.Tag(arn, tags.FromList(params["Tags"], "Key", "Value"))
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{})
+}
+
+func (s *Service) untagResource(w http.ResponseWriter, params map[string]interface{}) {
+	id := h.GetString(params, "ResourceId")
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	arn := s.resourceARN(id)
+	if arn == "" {
+		h.WriteJSONError(w, "TargetNotFoundException", "Target not found: "+id, http.StatusBadRequest)
+		return
+	}
+	s.tags.Untag(arn, tags.Keys(params["TagKeys"]))
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{})
+}
+
+func (s *Service) listTagsForResource(w http.ResponseWriter, params map[string]interface{}) {
+	id := h.GetString(params, "ResourceId")
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	arn := s.resourceARN(id)
+	if arn == "" {
+		h.WriteJSONError(w, "TargetNotFoundException", "Target not found: "+id, http.StatusBadRequest)
+		return
+	}
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"Tags": tags.ToList(s.tags.Get(arn), "Key", "Value"),
+	})
+}
+
 func orgResp(o *organization) map[string]interface{} {
 	return map[string]interface{}{
 		"Id":                 o.id,
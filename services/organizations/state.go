@@ -0,0 +1,41 @@
+package organizations
+
+import "github.com/riyanimam/goto/internal/mockhelpers"
+
+// ExportState lists the organization's accounts and organizational units.
+// Accounts are created in the root, which is listed as an organizational
+// unit with no parent.
+func (s *Service) ExportState() []mockhelpers.Resource {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.org == nil {
+		return nil
+	}
+	resources := make([]mockhelpers.Resource, 0, len(s.accounts)+len(s.ous))
+	for _, a := range s.accounts {
+		resources = append(resources, mockhelpers.Resource{
+			Type: "aws_organizations_account",
+			ID:   a.id,
+			ARN:  a.arn,
+			Attributes: map[string]interface{}{
+				"name":      a.name,
+				"email":     a.email,
+				"status":    a.status,
+				"parent_id": s.rootID,
+			},
+		})
+	}
+	for _, ou := range s.ous {
+		resources = append(resources, mockhelpers.Resource{
+			Type: "aws_organizations_organizational_unit",
+			ID:   ou.id,
+			ARN:  ou.arn,
+			Attributes: map[string]interface{}{
+				"name":      ou.name,
+				"parent_id": ou.parentID,
+			},
+		})
+	}
+	return resources
+}
@@ -0,0 +1,429 @@
+// Package paymentcryptography provides a mock implementation of the AWS
+// Payment Cryptography control plane.
+//
+// Supported actions:
+//   - CreateKey, GetKey, ListKeys
+//   - GetPublicKeyCertificate
+//
+// Keys are generated for real, so their key check values are the ones an
+// HSM would report: ANSI X9.24 check values encrypt a zero block, and CMAC
+// check values take its CMAC. Asymmetric key pairs report the start of the
+// SHA-1 digest of their public key instead, and GetPublicKeyCertificate
+// returns a certificate for the public key issued by a certificate
+// authority private to the mock. No data plane operations are provided.
+package paymentcryptography
+
+import (
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/des"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/riyanimam/goto/internal/clock"
+	h "github.com/riyanimam/goto/internal/mockhelpers"
+	"github.com/riyanimam/goto/internal/paginate"
+	"github.com/riyanimam/goto/internal/tags"
+)
+
+// keySizes are the lengths in bytes of symmetric keys by KeyAlgorithm.
+var keySizes = map[string]int{
+	"TDES_2KEY": 16,
+	"TDES_3KEY": 24,
+	"AES_128":   16,
+	"AES_192":   24,
+	"AES_256":   32,
+}
+
+// modesOfUse are the KeyModesOfUse flags.
+var modesOfUse = []string{"Encrypt", "Decrypt", "Wrap", "Unwrap", "Generate", "Sign", "Verify", "DeriveKey", "NoRestrictions"}
+
+// Service implements the Payment Cryptography mock.
+type Service struct {
+	mu   sync.RWMutex
+	keys map[string]*key
+	// ca issues public key certificates. It is created on first use.
+	ca     *x509.Certificate
+	caKey  crypto.Signer
+	caPEM  []byte
+	serial int64
+
+	tags  *tags.Store
+	clock *clock.Clock
+}
+
+type key struct {
+	arn        string
+	attributes map[string]interface{}
+	class      string
+	algorithm  string
+	exportable bool
+	enabled    bool
+	kcv        string
+	kcvAlg     string
+	secret     []byte        // symmetric keys
+	pair       crypto.Signer // asymmetric key pairs
+	created    time.Time
+}
+
+// New creates a new Payment Cryptography mock service.
+func New() *Service {
+	return &Service{
+		keys: make(map[string]*key),
+		tags: tags.New(),
+	}
+}
+
+// Name returns the service identifier.
+func (s *Service) Name() string { return "payment-cryptography" }
+
+// Handler returns the HTTP handler for Payment Cryptography requests.
+func (s *Service) Handler() http.Handler {
+	return h.JSONRouter{
+		"CreateKey":               s.createKey,
+		"GetKey":                  s.getKey,
+		"ListKeys":                s.listKeys,
+		"GetPublicKeyCertificate": s.getPublicKeyCertificate,
+	}
+}
+
+// Reset clears all state.
+func (s *Service) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.keys = make(map[string]*key)
+	s.ca, s.caKey, s.caPEM = nil, nil, nil
+	s.tags.DeleteService("payment-cryptography")
+}
+
+// SetTagStore sets the registry key tags are recorded in.
+func (s *Service) SetTagStore(store *tags.Store) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tags = store
+}
+
+// SetClock attaches the mock clock used for creation times.
+func (s *Service) SetClock(c *clock.Clock) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.clock = c
+}
+
+func (s *Service) now() time.Time {
+	if s.clock != nil {
+		return s.clock.Now()
+	}
+	return time.Now().UTC()
+}
+
+func writeError(w http.ResponseWriter, code, message string) {
+	h.WriteJSONError(w, code, message, http.StatusBadRequest)
+}
+
+// generatePair generates an asymmetric key pair for the KeyAlgorithm.
+func generatePair(algorithm string) (crypto.Signer, error) {
+	switch algorithm {
+	case "RSA_2048":
+		return rsa.GenerateKey(rand.Reader, 2048)
+	case "RSA_3072":
+		return rsa.GenerateKey(rand.Reader, 3072)
+	case "RSA_4096":
+		return rsa.GenerateKey(rand.Reader, 4096)
+	case "ECC_NIST_P256":
+		return ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case "ECC_NIST_P384":
+		return ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	}
+	return nil, fmt.Errorf("unsupported KeyAlgorithm %s", algorithm)
+}
+
+// blockCipher returns the block cipher of a symmetric key. Two-key triple
+// DES keys are expanded to K1 K2 K1.
+func blockCipher(algorithm string, secret []byte) (cipher.Block, error) {
+	switch algorithm {
+	case "TDES_2KEY":
+		return des.NewTripleDESCipher(append(append([]byte{}, secret...), secret[:8]...))
+	case "TDES_3KEY":
+		return des.NewTripleDESCipher(secret)
+	}
+	return aes.NewCipher(secret)
+}
+
+// checkValue returns the key check value of a symmetric key: the first
+// three bytes of an encrypted zero block (ANSI_X9_24) or of its CMAC.
+func checkValue(block cipher.Block, algorithm string) string {
+	out := make([]byte, block.BlockSize())
+	block.Encrypt(out, out)
+	if algorithm == "CMAC" {
+		// The CMAC of a single complete block M is E(M xor K1), where K1
+		// doubles E(0) in GF(2^n); M is zero, so it is E(K1).
+		rb := byte(0x87)
+		if len(out) == 8 {
+			rb = 0x1b
+		}
+		msb := out[0] & 0x80
+		for i := range out {
+			out[i] <<= 1
+			if i+1 < len(out) {
+				out[i] |= out[i+1] >> 7
+			}
+		}
+		if msb != 0 {
+			out[len(out)-1] ^= rb
+		}
+		block.Encrypt(out, out)
+	}
+	return strings.ToUpper(hex.EncodeToString(out[:3]))
+}
+
+func (s *Service) createKey(w http.ResponseWriter, params map[string]interface{}) {
+	attrs, _ := params["KeyAttributes"].(map[string]interface{})
+	exportable, ok := params["Exportable"].(bool)
+	if attrs == nil || !ok {
+		writeError(w, "ValidationException", "KeyAttributes and Exportable are required.")
+		return
+	}
+	k := &key{
+		attributes: attrs,
+		class:      h.GetString(attrs, "KeyClass"),
+		algorithm:  h.GetString(attrs, "KeyAlgorithm"),
+		exportable: exportable,
+		enabled:    true,
+		kcvAlg:     h.GetString(params, "KeyCheckValueAlgorithm"),
+	}
+	if v, ok := params["Enabled"].(bool); ok {
+		k.enabled = v
+	}
+	usage := h.GetString(attrs, "KeyUsage")
+	if !strings.HasPrefix(usage, "TR31_") {
+		writeError(w, "ValidationException", "KeyUsage must be a TR-31 key usage.")
+		return
+	}
+	modes, _ := attrs["KeyModesOfUse"].(map[string]interface{})
+	anyMode := false
+	for _, m := range modesOfUse {
+		anyMode = anyMode || h.GetBool(modes, m)
+	}
+	if !anyMode {
+		writeError(w, "ValidationException", "KeyModesOfUse must allow at least one mode.")
+		return
+	}
+
+	var err error
+	switch k.class {
+	case "SYMMETRIC_KEY":
+		size, ok := keySizes[k.algorithm]
+		if !ok || strings.Contains(usage, "_ASYMMETRIC_") {
+			writeError(w, "ValidationException", "KeyAlgorithm "+k.algorithm+" and KeyUsage "+usage+" are not valid for a symmetric key.")
+			return
+		}
+		if k.kcvAlg == "" {
+			k.kcvAlg = "CMAC"
+			if strings.HasPrefix(k.algorithm, "TDES") {
+				k.kcvAlg = "ANSI_X9_24"
+			}
+		}
+		if k.kcvAlg != "CMAC" && k.kcvAlg != "ANSI_X9_24" {
+			writeError(w, "ValidationException", "KeyCheckValueAlgorithm must be CMAC or ANSI_X9_24.")
+			return
+		}
+		k.secret = make([]byte, size)
+		rand.Read(k.secret)
+		var block cipher.Block
+		if block, err = blockCipher(k.algorithm, k.secret); err == nil {
+			k.kcv = checkValue(block, k.kcvAlg)
+		}
+	case "ASYMMETRIC_KEY_PAIR":
+		if !strings.Contains(usage, "_ASYMMETRIC_") {
+			writeError(w, "ValidationException", "KeyUsage "+usage+" is not valid for an asymmetric key pair.")
+			return
+		}
+		if k.pair, err = generatePair(k.algorithm); err != nil {
+			writeError(w, "ValidationException", err.Error())
+			return
+		}
+		k.kcvAlg = "SHA_1"
+		var der []byte
+		if der, err = x509.MarshalPKIXPublicKey(k.pair.Public()); err == nil {
+			sum := sha1.Sum(der)
+			k.kcv = strings.ToUpper(hex.EncodeToString(sum[:3]))
+		}
+	default:
+		writeError(w, "ValidationException", "KeyClass must be SYMMETRIC_KEY or ASYMMETRIC_KEY_PAIR; public and private keys can only be imported.")
+		return
+	}
+	if err != nil {
+		writeError(w, "InternalServerException", err.Error())
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	k.arn = fmt.Sprintf("arn:aws:payment-cryptography:us-east-1:%s:key/%s", h.DefaultAccountID, h.RandomHex(16))
+	k.created = s.now()
+	s.keys[k.arn] = k
+	s.tags.Tag(k.arn, tags.FromList(params["Tags"], "Key", "Value"))
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{"Key": k.toMap()})
+}
+
+func (k *key) toMap() map[string]interface{} {
+	return map[string]interface{}{
+		"KeyArn":                 k.arn,
+		"KeyAttributes":          k.attributes,
+		"KeyCheckValue":          k.kcv,
+		"KeyCheckValueAlgorithm": k.kcvAlg,
+		"Enabled":                k.enabled,
+		"Exportable":             k.exportable,
+		"KeyState":               "CREATE_COMPLETE",
+		"KeyOrigin":              "AWS_PAYMENT_CRYPTOGRAPHY",
+		"CreateTimestamp":        float64(k.created.Unix()),
+		"UsageStartTimestamp":    float64(k.created.Unix()),
+	}
+}
+
+// lookup returns the key named by KeyIdentifier, or writes an error. The
+// caller must hold s.mu.
+func (s *Service) lookup(w http.ResponseWriter, params map[string]interface{}) *key {
+	id := h.GetString(params, "KeyIdentifier")
+	k := s.keys[id]
+	if k == nil {
+		writeError(w, "ResourceNotFoundException", "Key "+id+" not found.")
+	}
+	return k
+}
+
+func (s *Service) getKey(w http.ResponseWriter, params map[string]interface{}) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if k := s.lookup(w, params); k != nil {
+		h.WriteJSON(w, http.StatusOK, map[string]interface{}{"Key": k.toMap()})
+	}
+}
+
+func (s *Service) listKeys(w http.ResponseWriter, params map[string]interface{}) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	arns := make([]string, 0, len(s.keys))
+	for a := range s.keys {
+		arns = append(arns, a)
+	}
+	sort.Strings(arns)
+	// Every key is CREATE_COMPLETE, as keys cannot be deleted.
+	if state := h.GetString(params, "KeyState"); state != "" && state != "CREATE_COMPLETE" {
+		arns = nil
+	}
+	out := make([]map[string]interface{}, 0, len(arns))
+	for _, a := range arns {
+		k := s.keys[a]
+		out = append(out, map[string]interface{}{
+			"KeyArn":        k.arn,
+			"KeyAttributes": k.attributes,
+			"KeyCheckValue": k.kcv,
+			"Enabled":       k.enabled,
+			"Exportable":    k.exportable,
+			"KeyState":      "CREATE_COMPLETE",
+		})
+	}
+	page, next, err := paginate.Page(out, func(i int) string { return out[i]["KeyArn"].(string) }, h.GetString(params, "NextToken"), h.GetInt(params, "MaxResults", 100), 100)
+	if err != nil {
+		writeError(w, "ValidationException", "Invalid NextToken.")
+		return
+	}
+	resp := map[string]interface{}{"Keys": page}
+	if next != "" {
+		resp["NextToken"] = next
+	}
+	h.WriteJSON(w, http.StatusOK, resp)
+}
+
+// authority returns the mock's certificate authority, creating it on first
+// use. The caller must hold s.mu for writing.
+func (s *Service) authority() error {
+	if s.ca != nil {
+		return nil
+	}
+	caKey, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	if err != nil {
+		return err
+	}
+	now := s.now()
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "AWS Payment Cryptography Mock Root CA", Organization: []string{"goto"}},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.AddDate(10, 0, 0),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, caKey.Public(), caKey)
+	if err != nil {
+		return err
+	}
+	if s.ca, err = x509.ParseCertificate(der); err != nil {
+		return err
+	}
+	s.caKey = caKey
+	s.caPEM = pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	s.serial = 1
+	return nil
+}
+
+// getPublicKeyCertificate certifies the public key of an asymmetric key
+// pair. Like the service, it returns base64-encoded PEM.
+func (s *Service) getPublicKeyCertificate(w http.ResponseWriter, params map[string]interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	k := s.lookup(w, params)
+	if k == nil {
+		return
+	}
+	if k.pair == nil {
+		writeError(w, "ValidationException", "Key "+k.arn+" is not an asymmetric key pair.")
+		return
+	}
+	if err := s.authority(); err != nil {
+		writeError(w, "InternalServerException", err.Error())
+		return
+	}
+	s.serial++
+	now := s.now()
+	usage := x509.KeyUsageDigitalSignature
+	if h.GetString(k.attributes, "KeyUsage") != "TR31_S0_ASYMMETRIC_KEY_FOR_DIGITAL_SIGNATURE" {
+		usage = x509.KeyUsageKeyEncipherment | x509.KeyUsageDataEncipherment
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(s.serial),
+		Subject:      pkix.Name{CommonName: k.arn, Organization: []string{"goto"}},
+		NotBefore:    now.Add(-time.Hour),
+		NotAfter:     now.AddDate(1, 0, 0),
+		KeyUsage:     usage,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, s.ca, k.pair.Public(), s.caKey)
+	if err != nil {
+		writeError(w, "InternalServerException", err.Error())
+		return
+	}
+	cert := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"KeyCertificate":      base64.StdEncoding.EncodeToString(cert),
+		"KeyCertificateChain": base64.StdEncoding.EncodeToString(s.caPEM),
+	})
+}
@@ -0,0 +1,477 @@
+// Package pipes provides a mock implementation of Amazon EventBridge Pipes.
+//
+// Supported actions:
+//   - CreatePipe
+//   - DescribePipe
+//   - ListPipes
+//   - DeletePipe
+//   - StartPipe
+//   - StopPipe
+//
+// Pipes read SQS queues, Kinesis streams, and DynamoDB table streams, and
+// deliver the records they read to their target through the mock server:
+// Lambda functions and Step Functions state machines receive each batch as a
+// JSON array, event buses receive each record as the detail of an event,
+// and other targets receive each record on its own. Records that match none
+// of the pipe's FilterCriteria are dropped, and a pipe with an Enrichment
+// Lambda function delivers that function's output in place of the batch.
+//
+// Running pipes poll their source when they start, when the mock clock
+// advances, and on demand via [Service.Poll]. A batch that cannot be
+// enriched or delivered stays at the source to be retried on the next poll,
+// and the failure is reported as the pipe's StateReason. Input templates are
+// not applied.
+package pipes
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/riyanimam/goto/internal/arn"
+	"github.com/riyanimam/goto/internal/clock"
+	"github.com/riyanimam/goto/internal/lifecycle"
+	h "github.com/riyanimam/goto/internal/mockhelpers"
+	"github.com/riyanimam/goto/internal/paginate"
+	"github.com/riyanimam/goto/internal/tags"
+)
+
+// Service implements the EventBridge Pipes mock.
+type Service struct {
+	mu    sync.RWMutex
+	pipes map[string]*pipe
+
+	// pollMu serializes polling, so that a pipe's batches are delivered in
+	// order and its position is advanced by one poll at a time.
+	pollMu sync.Mutex
+
+	clock       *clock.Clock
+	dispatch    h.Dispatcher
+	resolve     h.Resolver
+	source      h.EventSource
+	tags        *tags.Store
+	transitions *lifecycle.Transitions
+}
+
+type pipe struct {
+	name             string
+	arn              string
+	description      string
+	roleArn          string
+	source           string
+	sourceParams     map[string]interface{}
+	enrichment       string
+	enrichmentParams map[string]interface{}
+	target           string
+	targetParams     map[string]interface{}
+	filters          []map[string]interface{}
+	batchSize        int
+	desired          string // RUNNING or STOPPED
+	transitional     string // CREATING, STARTING or STOPPING
+	changed          lifecycle.Transition
+	stateReason      string
+	position         string // where the next poll reads a stream from
+	created          time.Time
+	modified         time.Time
+}
+
+// New creates a new EventBridge Pipes mock service.
+func New() *Service {
+	return &Service{
+		pipes: make(map[string]*pipe),
+		tags:  tags.New(),
+	}
+}
+
+// Name returns the service identifier.
+func (s *Service) Name() string { return "pipes" }
+
+// Handler returns the HTTP handler for EventBridge Pipes requests.
+func (s *Service) Handler() http.Handler {
+	return http.HandlerFunc(s.handle)
+}
+
+// Reset clears all state.
+func (s *Service) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pipes = make(map[string]*pipe)
+	s.tags.DeleteService("pipes")
+}
+
+// SetClock attaches the mock clock. Running pipes poll their sources as it
+// advances.
+func (s *Service) SetClock(c *clock.Clock) {
+	s.mu.Lock()
+	s.clock = c
+	s.mu.Unlock()
+	c.OnAdvance(func(_, _ time.Time) { s.pollAll() })
+}
+
+// SetDispatcher sets the function used to deliver records to enrichments
+// and targets.
+func (s *Service) SetDispatcher(d h.Dispatcher) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.dispatch = d
+}
+
+// SetResolver sets the function used to check that sources, enrichments,
+// and targets exist.
+func (s *Service) SetResolver(r h.Resolver) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.resolve = r
+}
+
+// SetEventSource sets the reader pipes poll their sources with.
+func (s *Service) SetEventSource(src h.EventSource) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.source = src
+}
+
+// SetTagStore sets the registry pipe tags are recorded in.
+func (s *Service) SetTagStore(store *tags.Store) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tags = store
+}
+
+// SetTransitions sets how long pipes stay CREATING, STARTING, and STOPPING.
+func (s *Service) SetTransitions(t *lifecycle.Transitions) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.transitions = t
+}
+
+func (s *Service) now() time.Time {
+	if s.clock != nil {
+		return s.clock.Now()
+	}
+	return time.Now().UTC()
+}
+
+// currentState returns the state p is in. The caller must hold s.mu.
+func (s *Service) currentState(p *pipe) string {
+	return s.transitions.Status(p.changed, p.transitional, p.desired)
+}
+
+func (s *Service) handle(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(r.URL.EscapedPath(), "/"), "/")
+	if len(parts) < 2 || parts[0] != "v1" || parts[1] != "pipes" {
+		h.WriteJSONError(w, "NotFoundException", "unsupported operation", http.StatusNotFound)
+		return
+	}
+	var name string
+	if len(parts) > 2 {
+		name, _ = url.PathUnescape(parts[2])
+	}
+
+	switch {
+	case len(parts) == 2 && r.Method == http.MethodGet:
+		s.listPipes(w, r)
+	case len(parts) == 3 && r.Method == http.MethodPost:
+		s.createPipe(w, r, name)
+	case len(parts) == 3 && r.Method == http.MethodGet:
+		s.describePipe(w, name)
+	case len(parts) == 3 && r.Method == http.MethodDelete:
+		s.deletePipe(w, name)
+	case len(parts) == 4 && parts[3] == "start" && r.Method == http.MethodPost:
+		s.setDesiredState(w, name, "RUNNING")
+	case len(parts) == 4 && parts[3] == "stop" && r.Method == http.MethodPost:
+		s.setDesiredState(w, name, "STOPPED")
+	default:
+		h.WriteJSONError(w, "NotFoundException", "unsupported operation", http.StatusNotFound)
+	}
+}
+
+func writeValidation(w http.ResponseWriter, message string) {
+	h.WriteJSONError(w, "ValidationException", message, http.StatusBadRequest)
+}
+
+func writeNotFound(w http.ResponseWriter, name string) {
+	h.WriteJSONError(w, "NotFoundException", "Pipe "+name+" does not exist.", http.StatusNotFound)
+}
+
+// sourceSettings returns the name of the SourceParameters member that
+// configures a source ARN, and its default batch size, or "" for sources the
+// mock cannot read.
+func sourceSettings(source string) (string, int) {
+	a, err := arn.Parse(source)
+	if err != nil {
+		return "", 0
+	}
+	switch {
+	case a.Service == "sqs":
+		return "SqsQueueParameters", 10
+	case a.Service == "kinesis":
+		return "KinesisStreamParameters", 100
+	case a.Service == "dynamodb" && strings.Contains(a.Resource, "/stream/"):
+		return "DynamoDBStreamParameters", 100
+	}
+	return "", 0
+}
+
+func (s *Service) createPipe(w http.ResponseWriter, r *http.Request, name string) {
+	var params map[string]interface{}
+	body, _ := io.ReadAll(r.Body)
+	if err := json.Unmarshal(body, &params); err != nil {
+		writeValidation(w, "Request body is not valid JSON.")
+		return
+	}
+	if name == "" || len(name) > 64 {
+		writeValidation(w, "Name must be between 1 and 64 characters.")
+		return
+	}
+
+	p := &pipe{
+		name:             name,
+		arn:              fmt.Sprintf("arn:aws:pipes:us-east-1:%s:pipe/%s", h.DefaultAccountID, name),
+		description:      h.GetString(params, "Description"),
+		roleArn:          h.GetString(params, "RoleArn"),
+		source:           h.GetString(params, "Source"),
+		enrichment:       h.GetString(params, "Enrichment"),
+		target:           h.GetString(params, "Target"),
+		desired:          h.GetString(params, "DesiredState"),
+		transitional:     "CREATING",
+		position:         "TRIM_HORIZON",
+		sourceParams:     objectParam(params, "SourceParameters"),
+		enrichmentParams: objectParam(params, "EnrichmentParameters"),
+		targetParams:     objectParam(params, "TargetParameters"),
+	}
+	if p.desired == "" {
+		p.desired = "RUNNING"
+	}
+	if p.desired != "RUNNING" && p.desired != "STOPPED" {
+		writeValidation(w, "DesiredState must be RUNNING or STOPPED.")
+		return
+	}
+	if p.roleArn == "" || p.source == "" || p.target == "" {
+		writeValidation(w, "RoleArn, Source, and Target are required.")
+		return
+	}
+
+	member, batchSize := sourceSettings(p.source)
+	if member == "" {
+		writeValidation(w, "Source "+p.source+" is not supported by the mock; use an SQS queue, Kinesis stream, or DynamoDB stream.")
+		return
+	}
+	settings := objectParam(p.sourceParams, member)
+	p.batchSize = h.GetInt(settings, "BatchSize", batchSize)
+	if p.batchSize < 1 || p.batchSize > 10000 {
+		writeValidation(w, "BatchSize must be between 1 and 10000.")
+		return
+	}
+	if member != "SqsQueueParameters" {
+		switch p.position = h.GetString(settings, "StartingPosition"); p.position {
+		case "TRIM_HORIZON", "LATEST":
+		default:
+			writeValidation(w, "SourceParameters."+member+".StartingPosition must be TRIM_HORIZON or LATEST.")
+			return
+		}
+	}
+
+	filterCriteria := objectParam(p.sourceParams, "FilterCriteria")
+	filters, _ := filterCriteria["Filters"].([]interface{})
+	if len(filters) > 5 {
+		writeValidation(w, "FilterCriteria may contain at most 5 filters.")
+		return
+	}
+	for _, f := range filters {
+		filter, _ := f.(map[string]interface{})
+		var pattern map[string]interface{}
+		if err := json.Unmarshal([]byte(h.GetString(filter, "Pattern")), &pattern); err != nil || pattern == nil {
+			writeValidation(w, "Filter pattern is not a valid JSON object.")
+			return
+		}
+		p.filters = append(p.filters, pattern)
+	}
+
+	s.mu.RLock()
+	resolve, src := s.resolve, s.source
+	s.mu.RUnlock()
+	for _, ref := range []string{p.source, p.enrichment, p.target} {
+		if ref == "" {
+			continue
+		}
+		if _, err := arn.Parse(ref); err != nil {
+			writeValidation(w, "Parameter "+ref+" is not valid. Reason: Provided Arn is not in correct format.")
+			return
+		}
+		if resolve != nil && resolve(ref) != nil {
+			writeValidation(w, "Resource "+ref+" does not exist.")
+			return
+		}
+	}
+	// A pipe reading from LATEST starts after the records its stream already
+	// holds, not those there when it first polls.
+	if p.position == "LATEST" && src != nil {
+		_, next, err := src.ReadEvents(p.source, "LATEST", 1)
+		if err != nil {
+			writeValidation(w, "Source "+p.source+" does not exist.")
+			return
+		}
+		p.position = next
+	}
+
+	s.mu.Lock()
+	if _, exists := s.pipes[name]; exists {
+		s.mu.Unlock()
+		h.WriteJSONError(w, "ConflictException", "Pipe "+name+" already exists.", http.StatusConflict)
+		return
+	}
+	p.created = s.now()
+	p.modified = p.created
+	p.changed = s.transitions.Begin()
+	s.pipes[name] = p
+	s.tags.Tag(p.arn, tags.FromMap(params["Tags"]))
+	resp := s.stateResponse(p)
+	s.mu.Unlock()
+
+	s.pollAll()
+	h.WriteJSON(w, http.StatusOK, resp)
+}
+
+// objectParam returns the object held by params[key], or an empty one.
+func objectParam(params map[string]interface{}, key string) map[string]interface{} {
+	if v, ok := params[key].(map[string]interface{}); ok {
+		return v
+	}
+	return map[string]interface{}{}
+}
+
+// stateResponse returns the body of the responses that change a pipe's
+// state. The caller must hold s.mu.
+func (s *Service) stateResponse(p *pipe) map[string]interface{} {
+	return map[string]interface{}{
+		"Arn":              p.arn,
+		"Name":             p.name,
+		"DesiredState":     p.desired,
+		"CurrentState":     s.currentState(p),
+		"CreationTime":     float64(p.created.Unix()),
+		"LastModifiedTime": float64(p.modified.Unix()),
+	}
+}
+
+func (s *Service) describePipe(w http.ResponseWriter, name string) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	p, exists := s.pipes[name]
+	if !exists {
+		writeNotFound(w, name)
+		return
+	}
+	resp := s.stateResponse(p)
+	resp["Description"] = p.description
+	resp["RoleArn"] = p.roleArn
+	resp["Source"] = p.source
+	resp["SourceParameters"] = p.sourceParams
+	resp["Target"] = p.target
+	resp["TargetParameters"] = p.targetParams
+	resp["Tags"] = s.tags.Get(p.arn)
+	if p.enrichment != "" {
+		resp["Enrichment"] = p.enrichment
+		resp["EnrichmentParameters"] = p.enrichmentParams
+	}
+	if p.stateReason != "" {
+		resp["StateReason"] = p.stateReason
+	}
+	h.WriteJSON(w, http.StatusOK, resp)
+}
+
+func (s *Service) listPipes(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	s.mu.RLock()
+	var summaries []map[string]interface{}
+	for _, p := range s.pipes {
+		state := s.currentState(p)
+		if !strings.HasPrefix(p.name, q.Get("NamePrefix")) ||
+			!strings.HasPrefix(p.source, q.Get("SourcePrefix")) ||
+			!strings.HasPrefix(p.target, q.Get("TargetPrefix")) ||
+			q.Get("DesiredState") != "" && q.Get("DesiredState") != p.desired ||
+			q.Get("CurrentState") != "" && q.Get("CurrentState") != state {
+			continue
+		}
+		summary := s.stateResponse(p)
+		summary["Source"] = p.source
+		summary["Target"] = p.target
+		if p.enrichment != "" {
+			summary["Enrichment"] = p.enrichment
+		}
+		if p.stateReason != "" {
+			summary["StateReason"] = p.stateReason
+		}
+		summaries = append(summaries, summary)
+	}
+	s.mu.RUnlock()
+	sort.Slice(summaries, func(i, j int) bool {
+		return summaries[i]["Name"].(string) < summaries[j]["Name"].(string)
+	})
+
+	limit := 100
+	if l := q.Get("Limit"); l != "" {
+		fmt.Sscan(l, &limit)
+	}
+	page, next, err := paginate.Page(summaries, func(i int) string { return summaries[i]["Name"].(string) }, q.Get("NextToken"), limit, 100)
+	if err != nil {
+		writeValidation(w, "Invalid NextToken.")
+		return
+	}
+	resp := map[string]interface{}{"Pipes": page}
+	if next != "" {
+		resp["NextToken"] = next
+	}
+	h.WriteJSON(w, http.StatusOK, resp)
+}
+
+func (s *Service) deletePipe(w http.ResponseWriter, name string) {
+	s.mu.Lock()
+	p, exists := s.pipes[name]
+	if !exists {
+		s.mu.Unlock()
+		writeNotFound(w, name)
+		return
+	}
+	delete(s.pipes, name)
+	s.tags.Delete(p.arn)
+	resp := s.stateResponse(p)
+	s.mu.Unlock()
+
+	resp["DesiredState"] = "DELETED"
+	resp["CurrentState"] = "DELETING"
+	h.WriteJSON(w, http.StatusOK, resp)
+}
+
+// setDesiredState starts or stops the named pipe, which passes through
+// STARTING or STOPPING on the way. A pipe already in, or moving to, the
+// desired state is left as it is.
+func (s *Service) setDesiredState(w http.ResponseWriter, name, desired string) {
+	s.mu.Lock()
+	p, exists := s.pipes[name]
+	if !exists {
+		s.mu.Unlock()
+		writeNotFound(w, name)
+		return
+	}
+	if p.desired != desired {
+		p.desired = desired
+		p.transitional = "STARTING"
+		if desired == "STOPPED" {
+			p.transitional = "STOPPING"
+		}
+		p.changed = s.transitions.Begin()
+		p.modified = s.now()
+	}
+	resp := s.stateResponse(p)
+	s.mu.Unlock()
+
+	s.pollAll()
+	h.WriteJSON(w, http.StatusOK, resp)
+}
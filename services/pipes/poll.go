@@ -0,0 +1,219 @@
+package pipes
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/riyanimam/goto/internal/arn"
+	"github.com/riyanimam/goto/internal/eventpattern"
+	h "github.com/riyanimam/goto/internal/mockhelpers"
+)
+
+// Poll moves the records waiting at the named pipe's source to its target
+// now, without waiting for the mock clock. It returns an error if the pipe
+// does not exist or is not RUNNING, or if a batch could not be enriched or
+// delivered.
+func (s *Service) Poll(name string) error {
+	s.mu.RLock()
+	p, exists := s.pipes[name]
+	running := exists && s.currentState(p) == "RUNNING"
+	s.mu.RUnlock()
+	if !exists {
+		return fmt.Errorf("pipe %s does not exist", name)
+	}
+	if !running {
+		return fmt.Errorf("pipe %s is not RUNNING", name)
+	}
+	return s.poll(p)
+}
+
+// pollAll polls the source of every running pipe, in name order. Failures
+// are kept as each pipe's StateReason.
+func (s *Service) pollAll() {
+	s.mu.RLock()
+	var running []*pipe
+	for _, p := range s.pipes {
+		if s.currentState(p) == "RUNNING" {
+			running = append(running, p)
+		}
+	}
+	s.mu.RUnlock()
+	sort.Slice(running, func(i, j int) bool { return running[i].name < running[j].name })
+
+	for _, p := range running {
+		s.poll(p)
+	}
+}
+
+// poll reads batches from p's source and processes them until the source
+// has nothing more or a batch fails. Queue messages of a failed batch become
+// visible again, and stream positions stay at its first record, so the
+// batch is retried on the next poll.
+func (s *Service) poll(p *pipe) error {
+	s.pollMu.Lock()
+	defer s.pollMu.Unlock()
+
+	s.mu.RLock()
+	src, dispatch, position := s.source, s.dispatch, p.position
+	s.mu.RUnlock()
+	if src == nil || dispatch == nil {
+		return fmt.Errorf("pipe %s is not connected to a mock server", p.name)
+	}
+
+	for {
+		events, next, err := src.ReadEvents(p.source, position, p.batchSize)
+		if err == nil && len(events) > 0 {
+			err = s.process(p, dispatch, events)
+			src.AckEvents(p.source, events, err == nil)
+		}
+
+		s.mu.Lock()
+		if err != nil {
+			p.stateReason = err.Error()
+			s.mu.Unlock()
+			return err
+		}
+		p.stateReason = ""
+		p.position = next
+		s.mu.Unlock()
+
+		if len(events) == 0 {
+			return nil
+		}
+		position = next
+	}
+}
+
+// process filters, enriches, and delivers a batch of source records.
+func (s *Service) process(p *pipe, dispatch h.Dispatcher, events []map[string]interface{}) error {
+	batch := p.filter(events)
+	if len(batch) == 0 {
+		return nil
+	}
+	if p.enrichment != "" {
+		payload, _ := json.Marshal(batch)
+		out, err := dispatch(p.enrichment, payload)
+		if err != nil {
+			return fmt.Errorf("enrichment %s failed: %w", p.enrichment, err)
+		}
+		if batch, err = enriched(out); err != nil {
+			return fmt.Errorf("enrichment %s returned %w", p.enrichment, err)
+		}
+		if len(batch) == 0 {
+			return nil
+		}
+	}
+	return p.deliver(dispatch, batch, s.now())
+}
+
+// filter returns the events that match any of p's filter patterns, or all
+// of them if p has none. Patterns see SQS message bodies and Kinesis record
+// data that hold JSON objects as those objects, as in Pipes.
+func (p *pipe) filter(events []map[string]interface{}) []map[string]interface{} {
+	if len(p.filters) == 0 {
+		return events
+	}
+	var kept []map[string]interface{}
+	for _, e := range events {
+		view := make(map[string]interface{}, len(e))
+		for k, v := range e {
+			view[k] = v
+		}
+		if body, ok := e["body"].(string); ok {
+			var obj map[string]interface{}
+			if json.Unmarshal([]byte(body), &obj) == nil {
+				view["body"] = obj
+			}
+		}
+		if data, ok := e["data"].(string); ok {
+			var obj map[string]interface{}
+			if raw, err := base64.StdEncoding.DecodeString(data); err == nil && json.Unmarshal(raw, &obj) == nil {
+				view["data"] = obj
+			}
+		}
+		// Round-trip the view so that patterns compare against the JSON
+		// types they were parsed as.
+		raw, _ := json.Marshal(view)
+		view = nil
+		json.Unmarshal(raw, &view)
+		for _, pattern := range p.filters {
+			if eventpattern.Match(pattern, view) {
+				kept = append(kept, e)
+				break
+			}
+		}
+	}
+	return kept
+}
+
+// enriched parses an enrichment's output, a JSON array of events or a
+// single event, into the batch to deliver.
+func enriched(out []byte) ([]map[string]interface{}, error) {
+	var batch []map[string]interface{}
+	if json.Unmarshal(out, &batch) == nil {
+		return batch, nil
+	}
+	var event map[string]interface{}
+	if err := json.Unmarshal(out, &event); err != nil {
+		return nil, fmt.Errorf("output that is not a JSON array or object")
+	}
+	return []map[string]interface{}{event}, nil
+}
+
+// deliver sends a batch to p's target: whole to Lambda functions and state
+// machines, as events on event buses, and one record at a time elsewhere.
+func (p *pipe) deliver(dispatch h.Dispatcher, batch []map[string]interface{}, now time.Time) error {
+	a, _ := arn.Parse(p.target)
+	switch a.Service {
+	case "lambda", "states":
+		payload, _ := json.Marshal(batch)
+		if _, err := dispatch(p.target, payload); err != nil {
+			return fmt.Errorf("target %s failed: %w", p.target, err)
+		}
+		return nil
+	}
+
+	for _, e := range batch {
+		payload, _ := json.Marshal(e)
+		if a.Service == "events" {
+			payload, _ = json.Marshal(p.busEvent(e, now))
+		}
+		if _, err := dispatch(p.target, payload); err != nil {
+			return fmt.Errorf("target %s failed: %w", p.target, err)
+		}
+	}
+	return nil
+}
+
+// busEvent wraps a record as the EventBridge event a pipe puts on an event
+// bus, with the source and detail type set by EventBridgeEventBusParameters
+// or the Pipes defaults.
+func (p *pipe) busEvent(e map[string]interface{}, now time.Time) map[string]interface{} {
+	params := objectParam(p.targetParams, "EventBridgeEventBusParameters")
+	source := h.GetString(params, "Source")
+	if source == "" {
+		source = "Pipe " + p.name
+	}
+	detailType := h.GetString(params, "DetailType")
+	if detailType == "" {
+		detailType = "Event from " + h.GetString(e, "eventSource")
+	}
+	resources := []interface{}{p.arn}
+	if extra, ok := params["Resources"].([]interface{}); ok {
+		resources = append(resources, extra...)
+	}
+	return map[string]interface{}{
+		"version":     "0",
+		"id":          h.NewRequestID(),
+		"detail-type": detailType,
+		"source":      source,
+		"account":     h.DefaultAccountID,
+		"time":        now.UTC().Format("2006-01-02T15:04:05Z"),
+		"region":      "us-east-1",
+		"resources":   resources,
+		"detail":      e,
+	}
+}
@@ -0,0 +1,759 @@
+// Package quicksight provides a mock implementation of Amazon QuickSight.
+//
+// Supported actions:
+//   - RegisterUser
+//   - DescribeUser
+//   - ListUsers
+//   - DeleteUser
+//   - CreateDataSource
+//   - DescribeDataSource
+//   - DeleteDataSource
+//   - CreateDataSet
+//   - DescribeDataSet
+//   - DeleteDataSet
+//   - CreateDashboard
+//   - DescribeDashboard
+//   - DeleteDashboard
+//   - GenerateEmbedUrlForRegisteredUser
+//
+// Only the default namespace exists. Resources are created at once and
+// checked against each other: data sets must name existing data sources,
+// dashboards existing data sets, and embed URLs existing users and
+// dashboards. Embed URLs are well-formed but fake; nothing is served at them.
+package quicksight
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/riyanimam/goto/internal/clock"
+	h "github.com/riyanimam/goto/internal/mockhelpers"
+	"github.com/riyanimam/goto/internal/tags"
+)
+
+// Service implements the QuickSight mock.
+type Service struct {
+	mu          sync.RWMutex
+	users       map[string]*user // by user name
+	dataSources map[string]*dataSource
+	dataSets    map[string]*dataSet
+	dashboards  map[string]*dashboard
+	tags        *tags.Store
+	clock       *clock.Clock
+}
+
+type user struct {
+	arn          string
+	name         string
+	email        string
+	role         string
+	identityType string
+	principalID  string
+}
+
+type dataSource struct {
+	id         string
+	arn        string
+	name       string
+	sourceType string
+	parameters map[string]interface{}
+	created    time.Time
+}
+
+type dataSet struct {
+	id             string
+	arn            string
+	name           string
+	importMode     string
+	physicalTables map[string]interface{}
+	logicalTables  map[string]interface{}
+	created        time.Time
+}
+
+type dashboard struct {
+	id          string
+	arn         string
+	name        string
+	dataSetArns []string
+	created     time.Time
+}
+
+// New creates a new QuickSight mock service.
+func New() *Service {
+	return &Service{
+		users:       make(map[string]*user),
+		dataSources: make(map[string]*dataSource),
+		dataSets:    make(map[string]*dataSet),
+		dashboards:  make(map[string]*dashboard),
+		tags:        tags.New(),
+	}
+}
+
+// Name returns the service identifier.
+func (s *Service) Name() string { return "quicksight" }
+
+// Handler returns the HTTP handler for QuickSight requests.
+func (s *Service) Handler() http.Handler {
+	return http.HandlerFunc(s.handle)
+}
+
+// Reset clears all state.
+func (s *Service) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.users = make(map[string]*user)
+	s.dataSources = make(map[string]*dataSource)
+	s.dataSets = make(map[string]*dataSet)
+	s.dashboards = make(map[string]*dashboard)
+	s.tags.DeleteService("quicksight")
+}
+
+// SetTagStore sets the registry resource tags are recorded in.
+func (s *Service) SetTagStore(store *tags.Store) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tags = store
+}
+
+// SetClock attaches the mock clock used for creation times.
+func (s *Service) SetClock(c *clock.Clock) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.clock = c
+}
+
+func (s *Service) now() time.Time {
+	if s.clock != nil {
+		return s.clock.Now()
+	}
+	return time.Now().UTC()
+}
+
+// handle routes /accounts/{AwsAccountId}/... requests. Path segments are
+// unescaped individually, since user names such as "role/session" arrive
+// with an escaped slash.
+func (s *Service) handle(w http.ResponseWriter, r *http.Request) {
+	var parts []string
+	for _, seg := range strings.Split(strings.Trim(r.URL.EscapedPath(), "/"), "/") {
+		v, err := url.PathUnescape(seg)
+		if err != nil {
+			v = seg
+		}
+		parts = append(parts, v)
+	}
+	if len(parts) < 3 || parts[0] != "accounts" {
+		h.WriteJSONError(w, "UnsupportedOperationException", "unsupported operation", http.StatusNotFound)
+		return
+	}
+	if parts[1] != h.DefaultAccountID {
+		h.WriteJSONError(w, "AccessDeniedException", "Account "+parts[1]+" is not authorized to access QuickSight resources in this account.", http.StatusUnauthorized)
+		return
+	}
+	route := parts[2:]
+	method := r.Method
+
+	switch {
+	case len(route) >= 3 && route[0] == "namespaces" && route[2] == "users":
+		if route[1] != "default" {
+			writeNotFound(w, "Namespace "+route[1]+" not found.")
+			return
+		}
+		switch {
+		case len(route) == 3 && method == http.MethodPost:
+			s.registerUser(w, r)
+		case len(route) == 3 && method == http.MethodGet:
+			s.listUsers(w)
+		case len(route) == 4 && method == http.MethodGet:
+			s.describeUser(w, route[3])
+		case len(route) == 4 && method == http.MethodDelete:
+			s.deleteUser(w, route[3])
+		default:
+			writeUnsupported(w)
+		}
+	case len(route) == 1 && route[0] == "data-sources" && method == http.MethodPost:
+		s.createDataSource(w, r)
+	case len(route) == 2 && route[0] == "data-sources" && method == http.MethodGet:
+		s.describeDataSource(w, route[1])
+	case len(route) == 2 && route[0] == "data-sources" && method == http.MethodDelete:
+		s.deleteDataSource(w, route[1])
+	case len(route) == 1 && route[0] == "data-sets" && method == http.MethodPost:
+		s.createDataSet(w, r)
+	case len(route) == 2 && route[0] == "data-sets" && method == http.MethodGet:
+		s.describeDataSet(w, route[1])
+	case len(route) == 2 && route[0] == "data-sets" && method == http.MethodDelete:
+		s.deleteDataSet(w, route[1])
+	case len(route) == 2 && route[0] == "dashboards" && method == http.MethodPost:
+		s.createDashboard(w, r, route[1])
+	case len(route) == 2 && route[0] == "dashboards" && method == http.MethodGet:
+		s.describeDashboard(w, route[1])
+	case len(route) == 2 && route[0] == "dashboards" && method == http.MethodDelete:
+		s.deleteDashboard(w, route[1])
+	case len(route) == 2 && route[0] == "embed-url" && route[1] == "registered-user" && method == http.MethodPost:
+		s.generateEmbedURLForRegisteredUser(w, r)
+	default:
+		writeUnsupported(w)
+	}
+}
+
+func readBody(r *http.Request) map[string]interface{} {
+	bodyBytes, _ := io.ReadAll(r.Body)
+	var params map[string]interface{}
+	json.Unmarshal(bodyBytes, &params)
+	return params
+}
+
+func resourceArn(kind, id string) string {
+	return fmt.Sprintf("arn:aws:quicksight:us-east-1:%s:%s/%s", h.DefaultAccountID, kind, id)
+}
+
+// writeResult writes a successful response. QuickSight repeats the HTTP
+// status and request ID in every response body.
+func writeResult(w http.ResponseWriter, status int, body map[string]interface{}) {
+	body["Status"] = status
+	body["RequestId"] = h.NewRequestID()
+	h.WriteJSON(w, status, body)
+}
+
+func writeNotFound(w http.ResponseWriter, message string) {
+	h.WriteJSONError(w, "ResourceNotFoundException", message, http.StatusNotFound)
+}
+
+func writeExists(w http.ResponseWriter, message string) {
+	h.WriteJSONError(w, "ResourceExistsException", message, http.StatusConflict)
+}
+
+func writeInvalid(w http.ResponseWriter, message string) {
+	h.WriteJSONError(w, "InvalidParameterValueException", message, http.StatusBadRequest)
+}
+
+func writeUnsupported(w http.ResponseWriter) {
+	h.WriteJSONError(w, "UnsupportedOperationException", "unsupported operation", http.StatusNotFound)
+}
+
+// --- Users ---
+
+// userName returns the QuickSight user name a registration creates: the
+// UserName of a QuickSight user, or for an IAM identity the IAM user name
+// or "{role}/{session}".
+func userName(params map[string]interface{}) (string, error) {
+	switch identityType := h.GetString(params, "IdentityType"); identityType {
+	case "QUICKSIGHT":
+		if name := h.GetString(params, "UserName"); name != "" {
+			return name, nil
+		}
+		return "", fmt.Errorf("UserName is required for QUICKSIGHT users.")
+	case "IAM":
+		iamArn := h.GetString(params, "IamArn")
+		_, resource, ok := strings.Cut(iamArn, ":user/")
+		if ok {
+			return resource[strings.LastIndex(resource, "/")+1:], nil
+		}
+		_, resource, ok = strings.Cut(iamArn, ":role/")
+		if !ok {
+			return "", fmt.Errorf("IamArn must be the ARN of an IAM user or role.")
+		}
+		session := h.GetString(params, "SessionName")
+		if session == "" {
+			return "", fmt.Errorf("SessionName is required for IAM role users.")
+		}
+		return resource[strings.LastIndex(resource, "/")+1:] + "/" + session, nil
+	default:
+		return "", fmt.Errorf("IdentityType %q is not supported; use IAM or QUICKSIGHT.", identityType)
+	}
+}
+
+func (s *Service) registerUser(w http.ResponseWriter, r *http.Request) {
+	params := readBody(r)
+	name, err := userName(params)
+	if err != nil {
+		writeInvalid(w, err.Error())
+		return
+	}
+	if h.GetString(params, "Email") == "" {
+		writeInvalid(w, "Email is required.")
+		return
+	}
+	switch h.GetString(params, "UserRole") {
+	case "ADMIN", "AUTHOR", "READER", "ADMIN_PRO", "AUTHOR_PRO", "READER_PRO":
+	default:
+		writeInvalid(w, "UserRole must be ADMIN, AUTHOR, READER, ADMIN_PRO, AUTHOR_PRO, or READER_PRO.")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, exists := s.users[name]; exists {
+		writeExists(w, "The user with the name "+name+" already exists.")
+		return
+	}
+	u := &user{
+		arn:          resourceArn("user", "default/"+name),
+		name:         name,
+		email:        h.GetString(params, "Email"),
+		role:         h.GetString(params, "UserRole"),
+		identityType: h.GetString(params, "IdentityType"),
+	}
+	if u.identityType == "IAM" {
+		u.principalID = "federated/iam/AIDA" + strings.ToUpper(h.RandomHex(8))
+	} else {
+		u.principalID = "user/d-" + h.RandomHex(5) + "/" + h.NewRequestID()
+	}
+	s.users[name] = u
+	s.tags.Replace(u.arn, tags.FromList(params["Tags"], "Key", "Value"))
+
+	writeResult(w, http.StatusCreated, map[string]interface{}{
+		"User": u.toMap(),
+	})
+}
+
+func (s *Service) describeUser(w http.ResponseWriter, name string) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	u, exists := s.users[name]
+	if !exists {
+		writeNotFound(w, "The user "+name+" does not exist.")
+		return
+	}
+	writeResult(w, http.StatusOK, map[string]interface{}{"User": u.toMap()})
+}
+
+func (s *Service) listUsers(w http.ResponseWriter) {
+	s.mu.RLock()
+	names := make([]string, 0, len(s.users))
+	for name := range s.users {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	list := make([]map[string]interface{}, 0, len(names))
+	for _, name := range names {
+		list = append(list, s.users[name].toMap())
+	}
+	s.mu.RUnlock()
+	writeResult(w, http.StatusOK, map[string]interface{}{"UserList": list})
+}
+
+func (s *Service) deleteUser(w http.ResponseWriter, name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	u, exists := s.users[name]
+	if !exists {
+		writeNotFound(w, "The user "+name+" does not exist.")
+		return
+	}
+	delete(s.users, name)
+	s.tags.Delete(u.arn)
+	writeResult(w, http.StatusOK, map[string]interface{}{})
+}
+
+func (u *user) toMap() map[string]interface{} {
+	return map[string]interface{}{
+		"Arn":          u.arn,
+		"UserName":     u.name,
+		"Email":        u.email,
+		"Role":         u.role,
+		"IdentityType": u.identityType,
+		"Active":       true,
+		"PrincipalId":  u.principalID,
+	}
+}
+
+// --- Data sources and data sets ---
+
+func (s *Service) createDataSource(w http.ResponseWriter, r *http.Request) {
+	params := readBody(r)
+	id := h.GetString(params, "DataSourceId")
+	switch {
+	case id == "":
+		writeInvalid(w, "DataSourceId is required.")
+		return
+	case h.GetString(params, "Name") == "":
+		writeInvalid(w, "Name is required.")
+		return
+	case h.GetString(params, "Type") == "":
+		writeInvalid(w, "Type is required.")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, exists := s.dataSources[id]; exists {
+		writeExists(w, "Data source "+id+" already exists.")
+		return
+	}
+	parameters, _ := params["DataSourceParameters"].(map[string]interface{})
+	ds := &dataSource{
+		id:         id,
+		arn:        resourceArn("datasource", id),
+		name:       h.GetString(params, "Name"),
+		sourceType: h.GetString(params, "Type"),
+		parameters: parameters,
+		created:    s.now(),
+	}
+	s.dataSources[id] = ds
+	s.tags.Replace(ds.arn, tags.FromList(params["Tags"], "Key", "Value"))
+
+	writeResult(w, http.StatusAccepted, map[string]interface{}{
+		"Arn":            ds.arn,
+		"DataSourceId":   id,
+		"CreationStatus": "CREATION_SUCCESSFUL",
+	})
+}
+
+func (s *Service) describeDataSource(w http.ResponseWriter, id string) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	ds, exists := s.dataSources[id]
+	if !exists {
+		writeNotFound(w, "Data source "+id+" not found.")
+		return
+	}
+	out := map[string]interface{}{
+		"Arn":             ds.arn,
+		"DataSourceId":    ds.id,
+		"Name":            ds.name,
+		"Type":            ds.sourceType,
+		"Status":          "CREATION_SUCCESSFUL",
+		"CreatedTime":     float64(ds.created.Unix()),
+		"LastUpdatedTime": float64(ds.created.Unix()),
+	}
+	if ds.parameters != nil {
+		out["DataSourceParameters"] = ds.parameters
+	}
+	writeResult(w, http.StatusOK, map[string]interface{}{"DataSource": out})
+}
+
+func (s *Service) deleteDataSource(w http.ResponseWriter, id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ds, exists := s.dataSources[id]
+	if !exists {
+		writeNotFound(w, "Data source "+id+" not found.")
+		return
+	}
+	delete(s.dataSources, id)
+	s.tags.Delete(ds.arn)
+	writeResult(w, http.StatusOK, map[string]interface{}{
+		"Arn":          ds.arn,
+		"DataSourceId": id,
+	})
+}
+
+// dataSourceArns returns the data source ARNs a PhysicalTableMap's relational
+// tables, custom SQL, and S3 sources read from.
+func dataSourceArns(physicalTables map[string]interface{}) []string {
+	var arns []string
+	for _, table := range physicalTables {
+		entry, _ := table.(map[string]interface{})
+		for _, kind := range []string{"RelationalTable", "CustomSql", "S3Source"} {
+			if source, ok := entry[kind].(map[string]interface{}); ok {
+				arns = append(arns, h.GetString(source, "DataSourceArn"))
+			}
+		}
+	}
+	return arns
+}
+
+// dataSourceByArn returns the data source with the given ARN. The caller
+// must hold s.mu.
+func (s *Service) dataSourceByArn(arn string) *dataSource {
+	for _, ds := range s.dataSources {
+		if ds.arn == arn {
+			return ds
+		}
+	}
+	return nil
+}
+
+func (s *Service) createDataSet(w http.ResponseWriter, r *http.Request) {
+	params := readBody(r)
+	id := h.GetString(params, "DataSetId")
+	physicalTables, _ := params["PhysicalTableMap"].(map[string]interface{})
+	importMode := h.GetString(params, "ImportMode")
+	switch {
+	case id == "":
+		writeInvalid(w, "DataSetId is required.")
+		return
+	case h.GetString(params, "Name") == "":
+		writeInvalid(w, "Name is required.")
+		return
+	case len(physicalTables) == 0:
+		writeInvalid(w, "PhysicalTableMap must contain at least one table.")
+		return
+	case importMode != "SPICE" && importMode != "DIRECT_QUERY":
+		writeInvalid(w, "ImportMode must be SPICE or DIRECT_QUERY.")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, exists := s.dataSets[id]; exists {
+		writeExists(w, "Data set "+id+" already exists.")
+		return
+	}
+	for _, arn := range dataSourceArns(physicalTables) {
+		if s.dataSourceByArn(arn) == nil {
+			writeInvalid(w, "Data source "+arn+" does not exist.")
+			return
+		}
+	}
+	logicalTables, _ := params["LogicalTableMap"].(map[string]interface{})
+	ds := &dataSet{
+		id:             id,
+		arn:            resourceArn("dataset", id),
+		name:           h.GetString(params, "Name"),
+		importMode:     importMode,
+		physicalTables: physicalTables,
+		logicalTables:  logicalTables,
+		created:        s.now(),
+	}
+	s.dataSets[id] = ds
+	s.tags.Replace(ds.arn, tags.FromList(params["Tags"], "Key", "Value"))
+
+	out := map[string]interface{}{
+		"Arn":       ds.arn,
+		"DataSetId": id,
+	}
+	// SPICE data sets begin an initial ingestion, which completes at once.
+	if importMode == "SPICE" {
+		ingestionID := h.NewRequestID()
+		out["IngestionId"] = ingestionID
+		out["IngestionArn"] = ds.arn + "/ingestion/" + ingestionID
+	}
+	writeResult(w, http.StatusCreated, out)
+}
+
+func (s *Service) describeDataSet(w http.ResponseWriter, id string) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	ds, exists := s.dataSets[id]
+	if !exists {
+		writeNotFound(w, "Data set "+id+" not found.")
+		return
+	}
+	out := map[string]interface{}{
+		"Arn":              ds.arn,
+		"DataSetId":        ds.id,
+		"Name":             ds.name,
+		"ImportMode":       ds.importMode,
+		"PhysicalTableMap": ds.physicalTables,
+		"CreatedTime":      float64(ds.created.Unix()),
+		"LastUpdatedTime":  float64(ds.created.Unix()),
+	}
+	if ds.logicalTables != nil {
+		out["LogicalTableMap"] = ds.logicalTables
+	}
+	writeResult(w, http.StatusOK, map[string]interface{}{"DataSet": out})
+}
+
+func (s *Service) deleteDataSet(w http.ResponseWriter, id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ds, exists := s.dataSets[id]
+	if !exists {
+		writeNotFound(w, "Data set "+id+" not found.")
+		return
+	}
+	delete(s.dataSets, id)
+	s.tags.Delete(ds.arn)
+	writeResult(w, http.StatusOK, map[string]interface{}{
+		"Arn":       ds.arn,
+		"DataSetId": id,
+	})
+}
+
+// --- Dashboards ---
+
+// dashboardDataSetArns returns the data set ARNs a CreateDashboard request
+// declares, from its Definition or its SourceEntity template.
+func dashboardDataSetArns(params map[string]interface{}) ([]string, bool) {
+	var refs []interface{}
+	if def, ok := params["Definition"].(map[string]interface{}); ok {
+		refs, _ = def["DataSetIdentifierDeclarations"].([]interface{})
+	} else if source, ok := params["SourceEntity"].(map[string]interface{}); ok {
+		template, _ := source["SourceTemplate"].(map[string]interface{})
+		refs, _ = template["DataSetReferences"].([]interface{})
+	} else {
+		return nil, false
+	}
+	arns := make([]string, 0, len(refs))
+	for _, ref := range refs {
+		entry, _ := ref.(map[string]interface{})
+		arns = append(arns, h.GetString(entry, "DataSetArn"))
+	}
+	return arns, true
+}
+
+func (s *Service) createDashboard(w http.ResponseWriter, r *http.Request, id string) {
+	params := readBody(r)
+	if h.GetString(params, "Name") == "" {
+		writeInvalid(w, "Name is required.")
+		return
+	}
+	arns, ok := dashboardDataSetArns(params)
+	if !ok {
+		writeInvalid(w, "Either SourceEntity or Definition is required.")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, exists := s.dashboards[id]; exists {
+		writeExists(w, "Dashboard "+id+" already exists.")
+		return
+	}
+	for _, arn := range arns {
+		if !s.hasDataSet(arn) {
+			writeInvalid(w, "Data set "+arn+" does not exist.")
+			return
+		}
+	}
+	d := &dashboard{
+		id:          id,
+		arn:         resourceArn("dashboard", id),
+		name:        h.GetString(params, "Name"),
+		dataSetArns: arns,
+		created:     s.now(),
+	}
+	s.dashboards[id] = d
+	s.tags.Replace(d.arn, tags.FromList(params["Tags"], "Key", "Value"))
+
+	writeResult(w, http.StatusAccepted, map[string]interface{}{
+		"Arn":            d.arn,
+		"VersionArn":     d.arn + "/version/1",
+		"DashboardId":    id,
+		"CreationStatus": "CREATION_SUCCESSFUL",
+	})
+}
+
+// hasDataSet reports whether arn names an existing data set. The caller must
+// hold s.mu.
+func (s *Service) hasDataSet(arn string) bool {
+	for _, ds := range s.dataSets {
+		if ds.arn == arn {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Service) describeDashboard(w http.ResponseWriter, id string) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	d, exists := s.dashboards[id]
+	if !exists {
+		writeNotFound(w, "Dashboard "+id+" not found.")
+		return
+	}
+	created := float64(d.created.Unix())
+	writeResult(w, http.StatusOK, map[string]interface{}{
+		"Dashboard": map[string]interface{}{
+			"DashboardId": d.id,
+			"Arn":         d.arn,
+			"Name":        d.name,
+			"Version": map[string]interface{}{
+				"VersionNumber": 1,
+				"Arn":           d.arn + "/version/1",
+				"Status":        "CREATION_SUCCESSFUL",
+				"DataSetArns":   d.dataSetArns,
+				"CreatedTime":   created,
+			},
+			"CreatedTime":       created,
+			"LastPublishedTime": created,
+			"LastUpdatedTime":   created,
+		},
+	})
+}
+
+func (s *Service) deleteDashboard(w http.ResponseWriter, id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	d, exists := s.dashboards[id]
+	if !exists {
+		writeNotFound(w, "Dashboard "+id+" not found.")
+		return
+	}
+	delete(s.dashboards, id)
+	s.tags.Delete(d.arn)
+	writeResult(w, http.StatusOK, map[string]interface{}{
+		"Arn":         d.arn,
+		"DashboardId": id,
+	})
+}
+
+// --- Embedding ---
+
+// embedPath returns the path an embed URL opens for an
+// ExperienceConfiguration, checking that a dashboard it names exists. The
+// caller must hold s.mu.
+func (s *Service) embedPath(experience map[string]interface{}) (string, error) {
+	dashboardPath := func(id string) (string, error) {
+		if _, exists := s.dashboards[id]; !exists {
+			return "", fmt.Errorf("Dashboard %s not found.", id)
+		}
+		return "/dashboards/" + id, nil
+	}
+	if cfg, ok := experience["Dashboard"].(map[string]interface{}); ok {
+		return dashboardPath(h.GetString(cfg, "InitialDashboardId"))
+	}
+	if cfg, ok := experience["DashboardVisual"].(map[string]interface{}); ok {
+		visual, _ := cfg["InitialDashboardVisualId"].(map[string]interface{})
+		p, err := dashboardPath(h.GetString(visual, "DashboardId"))
+		if err != nil {
+			return "", err
+		}
+		return p + "/sheets/" + h.GetString(visual, "SheetId") + "/visuals/" + h.GetString(visual, "VisualId"), nil
+	}
+	if _, ok := experience["QuickSightConsole"]; ok {
+		return "/start", nil
+	}
+	if _, ok := experience["QSearchBar"]; ok {
+		return "/q/search", nil
+	}
+	return "", nil
+}
+
+func (s *Service) generateEmbedURLForRegisteredUser(w http.ResponseWriter, r *http.Request) {
+	params := readBody(r)
+	lifetime := h.GetInt(params, "SessionLifetimeInMinutes", 600)
+	if lifetime < 15 || lifetime > 600 {
+		writeInvalid(w, "SessionLifetimeInMinutes must be between 15 and 600.")
+		return
+	}
+	experience, _ := params["ExperienceConfiguration"].(map[string]interface{})
+	userArn := h.GetString(params, "UserArn")
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	found := false
+	for _, u := range s.users {
+		if u.arn == userArn {
+			found = true
+			break
+		}
+	}
+	if !found {
+		writeNotFound(w, "The user "+userArn+" does not exist.")
+		return
+	}
+	embedPath, err := s.embedPath(experience)
+	if err != nil {
+		writeNotFound(w, err.Error())
+		return
+	}
+	if embedPath == "" {
+		writeInvalid(w, "ExperienceConfiguration must set one of Dashboard, DashboardVisual, QuickSightConsole, or QSearchBar.")
+		return
+	}
+
+	writeResult(w, http.StatusOK, map[string]interface{}{
+		"EmbedUrl": "https://us-east-1.quicksight.aws.amazon.com/embed/" + h.RandomHex(16) + embedPath +
+			"?code=" + h.RandomHex(32) + "&identityprovider=quicksight&isauthcode=true",
+	})
+}
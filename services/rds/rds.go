@@ -8,6 +8,15 @@
 //   - CreateDBCluster
 //   - DeleteDBCluster
 //   - DescribeDBClusters
+//   - AddTagsToResource
+//   - RemoveTagsFromResource
+//   - ListTagsForResource
+//
+// Tests can put instances and clusters into a status such as "backing-up",
+// "maintenance", or "storage-full" with [Service.SetStatus], to see how
+// code copes with maintenance windows: modifying or deleting them fails
+// with InvalidDBInstanceState or InvalidDBClusterStateFault until they are
+// available again.
 package rds
 
 import (
@@ -18,7 +27,9 @@ import (
 	"sync"
 	"time"
 
+	"github.com/riyanimam/goto/internal/lifecycle"
 	h "github.com/riyanimam/goto/internal/mockhelpers"
+	"github.com/riyanimam/goto/internal/tags"
 )
 
 // Service implements the RDS mock.
@@ -26,6 +37,9 @@ type Service struct {
 	mu        sync.RWMutex
 	instances map[string]*dbInstance
 	clusters  map[string]*dbCluster
+	tags      *tags.Store
+
+	transitions *lifecycle.Transitions
 }
 
 type dbInstance struct {
@@ -40,6 +54,7 @@ type dbInstance struct {
 	endpoint         string
 	port             int
 	created          time.Time
+	ready            lifecycle.Transition
 }
 
 type dbCluster struct {
@@ -53,6 +68,7 @@ type dbCluster struct {
 	readerEndpoint string
 	port           int
 	created        time.Time
+	ready          lifecycle.Transition
 }
 
 // New creates a new RDS mock service.
@@ -60,6 +76,7 @@ func New() *Service {
 	return &Service{
 		instances: make(map[string]*dbInstance),
 		clusters:  make(map[string]*dbCluster),
+		tags:      tags.New(),
 	}
 }
 
@@ -68,7 +85,21 @@ func (s *Service) Name() string { return "rds" }
 
 // Handler returns the HTTP handler for RDS requests.
 func (s *Service) Handler() http.Handler {
-	return http.HandlerFunc(s.handle)
+	return h.QueryRouter{
+		Actions: map[string]http.HandlerFunc{
+			"CreateDBInstance":       s.createDBInstance,
+			"DeleteDBInstance":       s.deleteDBInstance,
+			"DescribeDBInstances":    s.describeDBInstances,
+			"ModifyDBInstance":       s.modifyDBInstance,
+			"CreateDBCluster":        s.createDBCluster,
+			"DeleteDBCluster":        s.deleteDBCluster,
+			"DescribeDBClusters":     s.describeDBClusters,
+			"AddTagsToResource":      s.addTagsToResource,
+			"RemoveTagsFromResource": s.removeTagsFromResource,
+			"ListTagsForResource":    s.listTagsForResource,
+		},
+		Error: writeRDSError,
+	}
 }
 
 // Reset clears all state.
@@ -77,33 +108,45 @@ func (s *Service) Reset() {
 	defer s.mu.Unlock()
 	s.instances = make(map[string]*dbInstance)
 	s.clusters = make(map[string]*dbCluster)
+	s.tags.DeleteService("rds")
 }
 
-func (s *Service) handle(w http.ResponseWriter, r *http.Request) {
-	if err := r.ParseForm(); err != nil {
-		writeRDSError(w, "InvalidParameterValue", "could not parse request", http.StatusBadRequest)
-		return
-	}
+// SetTagStore sets the registry resource tags are recorded in.
+func (s *Service) SetTagStore(store *tags.Store) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tags = store
+}
 
-	action := r.FormValue("Action")
-	switch action {
-	case "CreateDBInstance":
-		s.createDBInstance(w, r)
-	case "DeleteDBInstance":
-		s.deleteDBInstance(w, r)
-	case "DescribeDBInstances":
-		s.describeDBInstances(w, r)
-	case "ModifyDBInstance":
-		s.modifyDBInstance(w, r)
-	case "CreateDBCluster":
-		s.createDBCluster(w, r)
-	case "DeleteDBCluster":
-		s.deleteDBCluster(w, r)
-	case "DescribeDBClusters":
-		s.describeDBClusters(w, r)
-	default:
-		writeRDSError(w, "UnsupportedOperation", fmt.Sprintf("action %q is not supported", action), http.StatusBadRequest)
+// SetTransitions sets how long new instances and clusters stay "creating".
+func (s *Service) SetTransitions(t *lifecycle.Transitions) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.transitions = t
+}
+
+// SetStatus puts the DB instance or cluster arn names into status until it
+// is set back to "available". A storage-full instance can still be given
+// more storage, which makes it available again.
+func (s *Service) SetStatus(arn, status string) error {
+	if status == "" {
+		return fmt.Errorf("status is required")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, inst := range s.instances {
+		if inst.arn == arn {
+			inst.status = status
+			return nil
+		}
 	}
+	for _, cl := range s.clusters {
+		if cl.arn == arn {
+			cl.status = status
+			return nil
+		}
+	}
+	return fmt.Errorf("DB instance or cluster %s not found", arn)
 }
 
 func (s *Service) createDBInstance(w http.ResponseWriter, r *http.Request) {
@@ -149,12 +192,14 @@ func (s *Service) createDBInstance(w http.ResponseWriter, r *http.Request) {
 		endpoint:         fmt.Sprintf("%s.c%s.us-east-1.rds.amazonaws.com", id, h.RandomHex(12)),
 		port:             port,
 		created:          time.Now().UTC(),
+		ready:            s.transitions.Begin(),
 	}
 	s.instances[id] = inst
+	s.tags.Tag(inst.arn, tags.FromForm(r.Form, "Tags.Tag."))
 	s.mu.Unlock()
 
 	resp := createDBInstanceResponse{
-		Result:    createDBInstanceResult{DBInstance: instanceToXML(inst)},
+		Result:    createDBInstanceResult{DBInstance: s.instanceToXML(inst)},
 		RequestID: h.NewRequestID(),
 	}
 	h.WriteXML(w, http.StatusOK, resp)
@@ -170,12 +215,18 @@ func (s *Service) deleteDBInstance(w http.ResponseWriter, r *http.Request) {
 		writeRDSError(w, "DBInstanceNotFound", "DB instance "+id+" not found", http.StatusNotFound)
 		return
 	}
+	if inst.status != "available" {
+		s.mu.Unlock()
+		writeRDSError(w, "InvalidDBInstanceState", "Instance "+id+" is not in available state.", http.StatusBadRequest)
+		return
+	}
 	inst.status = "deleting"
 	delete(s.instances, id)
+	s.tags.Delete(inst.arn)
 	s.mu.Unlock()
 
 	resp := deleteDBInstanceResponse{
-		Result:    deleteDBInstanceResult{DBInstance: instanceToXML(inst)},
+		Result:    deleteDBInstanceResult{DBInstance: s.instanceToXML(inst)},
 		RequestID: h.NewRequestID(),
 	}
 	h.WriteXML(w, http.StatusOK, resp)
@@ -188,11 +239,11 @@ func (s *Service) describeDBInstances(w http.ResponseWriter, r *http.Request) {
 	var members []xmlDBInstance
 	if id != "" {
 		if inst, exists := s.instances[id]; exists {
-			members = append(members, instanceToXML(inst))
+			members = append(members, s.instanceToXML(inst))
 		}
 	} else {
 		for _, inst := range s.instances {
-			members = append(members, instanceToXML(inst))
+			members = append(members, s.instanceToXML(inst))
 		}
 	}
 	s.mu.RUnlock()
@@ -216,17 +267,27 @@ func (s *Service) modifyDBInstance(w http.ResponseWriter, r *http.Request) {
 		writeRDSError(w, "DBInstanceNotFound", "DB instance "+id+" not found", http.StatusNotFound)
 		return
 	}
+	storage := inst.allocatedStorage
+	if v := r.FormValue("AllocatedStorage"); v != "" {
+		fmt.Sscanf(v, "%d", &storage)
+	}
+	switch {
+	case inst.status == "storage-full" && storage > inst.allocatedStorage:
+		inst.status = "available"
+	case inst.status != "available":
+		s.mu.Unlock()
+		writeRDSError(w, "InvalidDBInstanceState", "Instance "+id+" is not in available state.", http.StatusBadRequest)
+		return
+	}
 
 	if v := r.FormValue("DBInstanceClass"); v != "" {
 		inst.instanceClass = v
 	}
-	if v := r.FormValue("AllocatedStorage"); v != "" {
-		fmt.Sscanf(v, "%d", &inst.allocatedStorage)
-	}
+	inst.allocatedStorage = storage
 	s.mu.Unlock()
 
 	resp := modifyDBInstanceResponse{
-		Result:    modifyDBInstanceResult{DBInstance: instanceToXML(inst)},
+		Result:    modifyDBInstanceResult{DBInstance: s.instanceToXML(inst)},
 		RequestID: h.NewRequestID(),
 	}
 	h.WriteXML(w, http.StatusOK, resp)
@@ -264,12 +325,14 @@ func (s *Service) createDBCluster(w http.ResponseWriter, r *http.Request) {
 		readerEndpoint: fmt.Sprintf("%s.cluster-ro-c%s.us-east-1.rds.amazonaws.com", id, h.RandomHex(12)),
 		port:           port,
 		created:        time.Now().UTC(),
+		ready:          s.transitions.Begin(),
 	}
 	s.clusters[id] = cl
+	s.tags.Tag(cl.arn, tags.FromForm(r.Form, "Tags.Tag."))
 	s.mu.Unlock()
 
 	resp := createDBClusterResponse{
-		Result:    createDBClusterResult{DBCluster: clusterToXML(cl)},
+		Result:    createDBClusterResult{DBCluster: s.clusterToXML(cl)},
 		RequestID: h.NewRequestID(),
 	}
 	h.WriteXML(w, http.StatusOK, resp)
@@ -285,12 +348,18 @@ func (s *Service) deleteDBCluster(w http.ResponseWriter, r *http.Request) {
 		writeRDSError(w, "DBClusterNotFoundFault", "DB cluster "+id+" not found", http.StatusNotFound)
 		return
 	}
+	if cl.status != "available" {
+		s.mu.Unlock()
+		writeRDSError(w, "InvalidDBClusterStateFault", "DbCluster "+id+" is not in available state.", http.StatusBadRequest)
+		return
+	}
 	cl.status = "deleting"
 	delete(s.clusters, id)
+	s.tags.Delete(cl.arn)
 	s.mu.Unlock()
 
 	resp := deleteDBClusterResponse{
-		Result:    deleteDBClusterResult{DBCluster: clusterToXML(cl)},
+		Result:    deleteDBClusterResult{DBCluster: s.clusterToXML(cl)},
 		RequestID: h.NewRequestID(),
 	}
 	h.WriteXML(w, http.StatusOK, resp)
@@ -303,11 +372,11 @@ func (s *Service) describeDBClusters(w http.ResponseWriter, r *http.Request) {
 	var members []xmlDBCluster
 	if id != "" {
 		if cl, exists := s.clusters[id]; exists {
-			members = append(members, clusterToXML(cl))
+			members = append(members, s.clusterToXML(cl))
 		}
 	} else {
 		for _, cl := range s.clusters {
-			members = append(members, clusterToXML(cl))
+			members = append(members, s.clusterToXML(cl))
 		}
 	}
 	s.mu.RUnlock()
@@ -321,16 +390,78 @@ func (s *Service) describeDBClusters(w http.ResponseWriter, r *http.Request) {
 	h.WriteXML(w, http.StatusOK, resp)
 }
 
+// hasResource reports whether arn names an existing DB instance or
+// cluster. The caller must hold s.mu.
+func (s *Service) hasResource(arn string) bool {
+	for _, inst := range s.instances {
+		if inst.arn == arn {
+			return true
+		}
+	}
+	for _, cl := range s.clusters {
+		if cl.arn == arn {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Service) addTagsToResource(w http.ResponseWriter, r *http.Request) {
+	arn := r.FormValue("ResourceName")
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if !s.hasResource(arn) {
+		writeRDSError(w, "DBInstanceNotFound", "Resource "+arn+" not found", http.StatusNotFound)
+		return
+	}
+	s.tags.Tag(arn, tags.FromForm(r.Form, "Tags.Tag."))
+	h.WriteXML(w, http.StatusOK, tagsResponse{XMLName: xml.Name{Local: "AddTagsToResourceResponse"}, RequestID: h.NewRequestID()})
+}
+
+func (s *Service) removeTagsFromResource(w http.ResponseWriter, r *http.Request) {
+	arn := r.FormValue("ResourceName")
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if !s.hasResource(arn) {
+		writeRDSError(w, "DBInstanceNotFound", "Resource "+arn+" not found", http.StatusNotFound)
+		return
+	}
+	s.tags.Untag(arn, tags.KeysFromForm(r.Form, "TagKeys.member."))
+	h.WriteXML(w, http.StatusOK, tagsResponse{XMLName: xml.Name{Local: "RemoveTagsFromResourceResponse"}, RequestID: h.NewRequestID()})
+}
+
+func (s *Service) listTagsForResource(w http.ResponseWriter, r *http.Request) {
+	arn := r.FormValue("ResourceName")
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if !s.hasResource(arn) {
+		writeRDSError(w, "DBInstanceNotFound", "Resource "+arn+" not found", http.StatusNotFound)
+		return
+	}
+	var list []xmlTag
+	for _, t := range tags.ToList(s.tags.Get(arn), "Key", "Value") {
+		list = append(list, xmlTag{Key: t["Key"], Value: t["Value"]})
+	}
+	h.WriteXML(w, http.StatusOK, listTagsForResourceResponse{
+		Result:    listTagsForResourceResult{TagList: list},
+		RequestID: h.NewRequestID(),
+	})
+}
+
 // XML helpers.
 
-func instanceToXML(inst *dbInstance) xmlDBInstance {
+func (s *Service) instanceToXML(inst *dbInstance) xmlDBInstance {
+	status := inst.status
+	if status == "available" {
+		status = s.transitions.Status(inst.ready, "creating", status)
+	}
 	return xmlDBInstance{
 		Identifier:       inst.id,
 		Arn:              inst.arn,
 		InstanceClass:    inst.instanceClass,
 		Engine:           inst.engine,
 		EngineVersion:    inst.engineVersion,
-		Status:           inst.status,
+		Status:           status,
 		MasterUsername:   inst.masterUsername,
 		AllocatedStorage: inst.allocatedStorage,
 		Endpoint: xmlEndpoint{
@@ -340,13 +471,17 @@ func instanceToXML(inst *dbInstance) xmlDBInstance {
 	}
 }
 
-func clusterToXML(cl *dbCluster) xmlDBCluster {
+func (s *Service) clusterToXML(cl *dbCluster) xmlDBCluster {
+	status := cl.status
+	if status == "available" {
+		status = s.transitions.Status(cl.ready, "creating", status)
+	}
 	return xmlDBCluster{
 		Identifier:     cl.id,
 		Arn:            cl.arn,
 		Engine:         cl.engine,
 		EngineVersion:  cl.engineVersion,
-		Status:         cl.status,
+		Status:         status,
 		MasterUsername: cl.masterUsername,
 		Endpoint:       cl.endpoint,
 		ReaderEndpoint: cl.readerEndpoint,
@@ -448,6 +583,25 @@ type describeDBClustersResult struct {
 	DBClusters []xmlDBCluster `xml:"DBClusters>DBCluster"`
 }
 
+type xmlTag struct {
+	Key   string `xml:"Key"`
+	Value string `xml:"Value"`
+}
+
+type tagsResponse struct {
+	XMLName   xml.Name
+	RequestID string `xml:"ResponseMetadata>RequestId"`
+}
+
+type listTagsForResourceResponse struct {
+	XMLName   xml.Name                  `xml:"ListTagsForResourceResponse"`
+	Result    listTagsForResourceResult `xml:"ListTagsForResourceResult"`
+	RequestID string                    `xml:"ResponseMetadata>RequestId"`
+}
+type listTagsForResourceResult struct {
+	TagList []xmlTag `xml:"TagList>Tag"`
+}
+
 func writeRDSError(w http.ResponseWriter, code, message string, status int) {
 	h.WriteXMLError(w, "Sender", code, message, status)
 }
@@ -5,6 +5,13 @@
 //   - DescribeClusters
 //   - DeleteCluster
 //   - ModifyCluster
+//   - CreateTags
+//   - DeleteTags
+//   - DescribeTags
+//
+// Tests can put clusters into a status such as "maintenance", "rebooting",
+// or "storage-full" with [Service.SetStatus]: modifying or deleting them
+// fails with InvalidClusterState until they are available again.
 package redshift
 
 import (
@@ -16,13 +23,18 @@ import (
 	"sync"
 	"time"
 
+	"github.com/riyanimam/goto/internal/lifecycle"
 	h "github.com/riyanimam/goto/internal/mockhelpers"
+	"github.com/riyanimam/goto/internal/tags"
 )
 
 // Service implements the Redshift mock.
 type Service struct {
 	mu       sync.RWMutex
 	clusters map[string]*cluster
+	tags     *tags.Store
+
+	transitions *lifecycle.Transitions
 }
 
 type endpoint struct {
@@ -40,12 +52,14 @@ type cluster struct {
 	endpoint       endpoint
 	dbName         string
 	created        time.Time
+	ready          lifecycle.Transition
 }
 
 // New creates a new Redshift mock service.
 func New() *Service {
 	return &Service{
 		clusters: make(map[string]*cluster),
+		tags:     tags.New(),
 	}
 }
 
@@ -54,7 +68,17 @@ func (s *Service) Name() string { return "redshift" }
 
 // Handler returns the HTTP handler for Redshift requests.
 func (s *Service) Handler() http.Handler {
-	return http.HandlerFunc(s.handle)
+	return h.QueryRouter{
+		Actions: map[string]http.HandlerFunc{
+			"CreateCluster":    s.createCluster,
+			"DescribeClusters": s.describeClusters,
+			"DeleteCluster":    s.deleteCluster,
+			"ModifyCluster":    s.modifyCluster,
+			"CreateTags":       s.createTags,
+			"DeleteTags":       s.deleteTags,
+			"DescribeTags":     s.describeTags,
+		},
+	}
 }
 
 // Reset clears all state.
@@ -62,24 +86,38 @@ func (s *Service) Reset() {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	s.clusters = make(map[string]*cluster)
+	s.tags.DeleteService("redshift")
+}
+
+// SetTagStore sets the registry resource tags are recorded in.
+func (s *Service) SetTagStore(store *tags.Store) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tags = store
 }
 
-func (s *Service) handle(w http.ResponseWriter, r *http.Request) {
-	r.ParseForm()
-	action := r.FormValue("Action")
-
-	switch action {
-	case "CreateCluster":
-		s.createCluster(w, r)
-	case "DescribeClusters":
-		s.describeClusters(w, r)
-	case "DeleteCluster":
-		s.deleteCluster(w, r)
-	case "ModifyCluster":
-		s.modifyCluster(w, r)
-	default:
-		h.WriteXMLError(w, "Sender", "InvalidAction", fmt.Sprintf("action %q is not supported", action), http.StatusBadRequest)
+// SetTransitions sets how long new clusters stay "creating".
+func (s *Service) SetTransitions(t *lifecycle.Transitions) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.transitions = t
+}
+
+// SetStatus puts the cluster arn names into status until it is set back to
+// "available". A storage-full cluster can still be resized to more nodes,
+// which makes it available again.
+func (s *Service) SetStatus(arn, status string) error {
+	if status == "" {
+		return fmt.Errorf("status is required")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c := s.clusterByARN(arn)
+	if c == nil {
+		return fmt.Errorf("cluster %s not found", arn)
 	}
+	c.status = status
+	return nil
 }
 
 func (s *Service) createCluster(w http.ResponseWriter, r *http.Request) {
@@ -131,8 +169,10 @@ func (s *Service) createCluster(w http.ResponseWriter, r *http.Request) {
 		},
 		dbName:  dbName,
 		created: time.Now().UTC(),
+		ready:   s.transitions.Begin(),
 	}
 	s.clusters[id] = c
+	s.tags.Tag(arn, tags.FromForm(r.Form, "Tags.Tag."))
 	s.mu.Unlock()
 
 	type result struct {
@@ -145,7 +185,7 @@ func (s *Service) createCluster(w http.ResponseWriter, r *http.Request) {
 		Metadata responseMeta `xml:"ResponseMetadata"`
 	}
 	h.WriteXML(w, http.StatusOK, resp{
-		Result:   result{Cluster: clusterToXML(c)},
+		Result:   result{Cluster: s.clusterToXML(c)},
 		Metadata: responseMeta{RequestID: h.NewRequestID()},
 	})
 }
@@ -157,11 +197,11 @@ func (s *Service) describeClusters(w http.ResponseWriter, r *http.Request) {
 	var items []clusterXML
 	if id != "" {
 		if c, exists := s.clusters[id]; exists {
-			items = append(items, clusterToXML(c))
+			items = append(items, s.clusterToXML(c))
 		}
 	} else {
 		for _, c := range s.clusters {
-			items = append(items, clusterToXML(c))
+			items = append(items, s.clusterToXML(c))
 		}
 	}
 	s.mu.RUnlock()
@@ -195,9 +235,15 @@ func (s *Service) deleteCluster(w http.ResponseWriter, r *http.Request) {
 		h.WriteXMLError(w, "Sender", "ClusterNotFound", "Cluster "+id+" not found", http.StatusNotFound)
 		return
 	}
+	if c.status != "available" {
+		s.mu.Unlock()
+		h.WriteXMLError(w, "Sender", "InvalidClusterState", "Cluster "+id+" is not in available state: "+c.status, http.StatusBadRequest)
+		return
+	}
 	c.status = "deleting"
-	x := clusterToXML(c)
+	x := s.clusterToXML(c)
 	delete(s.clusters, id)
+	s.tags.Delete(c.arn)
 	s.mu.Unlock()
 
 	type result struct {
@@ -225,15 +271,25 @@ func (s *Service) modifyCluster(w http.ResponseWriter, r *http.Request) {
 		h.WriteXMLError(w, "Sender", "ClusterNotFound", "Cluster "+id+" not found", http.StatusNotFound)
 		return
 	}
-
-	if nodeType := r.FormValue("NodeType"); nodeType != "" {
-		c.nodeType = nodeType
-	}
+	nodes := c.numberOfNodes
 	if v := r.FormValue("NumberOfNodes"); v != "" {
 		if n, err := strconv.Atoi(v); err == nil && n > 0 {
-			c.numberOfNodes = n
+			nodes = n
 		}
 	}
+	switch {
+	case c.status == "storage-full" && nodes > c.numberOfNodes:
+		c.status = "available"
+	case c.status != "available":
+		s.mu.Unlock()
+		h.WriteXMLError(w, "Sender", "InvalidClusterState", "Cluster "+id+" is not in available state: "+c.status, http.StatusBadRequest)
+		return
+	}
+
+	if nodeType := r.FormValue("NodeType"); nodeType != "" {
+		c.nodeType = nodeType
+	}
+	c.numberOfNodes = nodes
 	s.mu.Unlock()
 
 	type result struct {
@@ -246,7 +302,103 @@ func (s *Service) modifyCluster(w http.ResponseWriter, r *http.Request) {
 		Metadata responseMeta `xml:"ResponseMetadata"`
 	}
 	h.WriteXML(w, http.StatusOK, resp{
-		Result:   result{Cluster: clusterToXML(c)},
+		Result:   result{Cluster: s.clusterToXML(c)},
+		Metadata: responseMeta{RequestID: h.NewRequestID()},
+	})
+}
+
+// clusterByARN returns the cluster with the given ARN, or nil.
+// The caller must hold s.mu.
+func (s *Service) clusterByARN(arn string) *cluster {
+	for _, c := range s.clusters {
+		if c.arn == arn {
+			return c
+		}
+	}
+	return nil
+}
+
+func (s *Service) createTags(w http.ResponseWriter, r *http.Request) {
+	arn := r.FormValue("ResourceName")
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.clusterByARN(arn) == nil {
+		h.WriteXMLError(w, "Sender", "ResourceNotFound", "Resource "+arn+" not found", http.StatusNotFound)
+		return
+	}
+	s.tags.Tag(arn, tags.FromForm(r.Form, "Tags.Tag."))
+
+	type resp struct {
+		XMLName  xml.Name     `xml:"CreateTagsResponse"`
+		Metadata responseMeta `xml:"ResponseMetadata"`
+	}
+	h.WriteXML(w, http.StatusOK, resp{Metadata: responseMeta{RequestID: h.NewRequestID()}})
+}
+
+func (s *Service) deleteTags(w http.ResponseWriter, r *http.Request) {
+	arn := r.FormValue("ResourceName")
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.clusterByARN(arn) == nil {
+		h.WriteXMLError(w, "Sender", "ResourceNotFound", "Resource "+arn+" not found", http.StatusNotFound)
+		return
+	}
+	s.tags.Untag(arn, tags.KeysFromForm(r.Form, "TagKeys.TagKey."))
+
+	type resp struct {
+		XMLName  xml.Name     `xml:"DeleteTagsResponse"`
+		Metadata responseMeta `xml:"ResponseMetadata"`
+	}
+	h.WriteXML(w, http.StatusOK, resp{Metadata: responseMeta{RequestID: h.NewRequestID()}})
+}
+
+func (s *Service) describeTags(w http.ResponseWriter, r *http.Request) {
+	filter := r.FormValue("ResourceName")
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	type tag struct {
+		Key   string `xml:"Key"`
+		Value string `xml:"Value"`
+	}
+	type taggedResource struct {
+		Tag          tag    `xml:"Tag"`
+		ResourceName string `xml:"ResourceName"`
+		ResourceType string `xml:"ResourceType"`
+	}
+	ids := make([]string, 0, len(s.clusters))
+	for id := range s.clusters {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	var list []taggedResource
+	for _, id := range ids {
+		c := s.clusters[id]
+		if filter != "" && filter != c.arn {
+			continue
+		}
+		for _, t := range tags.ToList(s.tags.Get(c.arn), "Key", "Value") {
+			list = append(list, taggedResource{
+				Tag:          tag{Key: t["Key"], Value: t["Value"]},
+				ResourceName: c.arn,
+				ResourceType: "cluster",
+			})
+		}
+	}
+
+	type result struct {
+		TaggedResources []taggedResource `xml:"TaggedResources>TaggedResource"`
+	}
+	type resp struct {
+		XMLName  xml.Name     `xml:"DescribeTagsResponse"`
+		Result   result       `xml:"DescribeTagsResult"`
+		Metadata responseMeta `xml:"ResponseMetadata"`
+	}
+	h.WriteXML(w, http.StatusOK, resp{
+		Result:   result{TaggedResources: list},
 		Metadata: responseMeta{RequestID: h.NewRequestID()},
 	})
 }
@@ -271,13 +423,17 @@ type clusterXML struct {
 	DBName            string      `xml:"DBName"`
 }
 
-func clusterToXML(c *cluster) clusterXML {
+func (s *Service) clusterToXML(c *cluster) clusterXML {
+	status := c.status
+	if status == "available" {
+		status = s.transitions.Status(c.ready, "creating", status)
+	}
 	return clusterXML{
 		ClusterIdentifier: c.identifier,
 		NodeType:          c.nodeType,
 		MasterUsername:    c.masterUsername,
 		NumberOfNodes:     c.numberOfNodes,
-		ClusterStatus:     c.status,
+		ClusterStatus:     status,
 		ARN:               c.arn,
 		Endpoint: endpointXML{
 			Address: c.endpoint.address,
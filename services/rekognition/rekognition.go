@@ -0,0 +1,219 @@
+// Package rekognition provides a mock implementation of Amazon Rekognition
+// image analysis.
+//
+// Supported actions:
+//   - DetectLabels
+//   - DetectFaces
+//
+// No image analysis runs. What an image in S3 contains is the [Image]
+// fixture set for its bucket and key with [Service.SetImage]; S3 objects
+// without a fixture, and images passed as bytes, contain nothing.
+package rekognition
+
+import (
+	"net/http"
+	"sort"
+	"sync"
+
+	h "github.com/riyanimam/goto/internal/mockhelpers"
+)
+
+// Image is what Rekognition detects in an image.
+type Image struct {
+	Labels []Label
+	Faces  []Face
+}
+
+// Label is an object, scene, or concept detected in an image.
+type Label struct {
+	Name       string
+	Confidence float64 // percent; 99 if zero
+	Parents    []string
+	Instances  []BoundingBox // where the label's objects are, if any
+}
+
+// Face is a face detected in an image.
+type Face struct {
+	BoundingBox BoundingBox
+	Confidence  float64 // percent; 99.9 if zero
+	AgeLow      int
+	AgeHigh     int
+	Smile       bool
+	Emotion     string // such as HAPPY or CALM; the face's main emotion
+}
+
+// BoundingBox is a rectangle in image-relative coordinates.
+type BoundingBox struct {
+	Left, Top, Width, Height float64
+}
+
+// Service implements the Rekognition mock.
+type Service struct {
+	mu     sync.RWMutex
+	images map[string]Image // by "bucket/key"
+	store  h.ObjectStore
+}
+
+// New creates a new Rekognition mock service.
+func New() *Service {
+	return &Service{images: make(map[string]Image)}
+}
+
+// Name returns the service identifier.
+func (s *Service) Name() string { return "rekognition" }
+
+// Handler returns the HTTP handler for Rekognition requests.
+func (s *Service) Handler() http.Handler {
+	return h.JSONRouter{
+		"DetectLabels": s.detectLabels,
+		"DetectFaces":  s.detectFaces,
+	}
+}
+
+// Reset clears all state, including image fixtures.
+func (s *Service) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.images = make(map[string]Image)
+}
+
+// SetObjectStore sets the store S3 images are checked against.
+func (s *Service) SetObjectStore(store h.ObjectStore) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.store = store
+}
+
+// SetImage sets what is detected in the S3 object at bucket/key.
+func (s *Service) SetImage(bucket, key string, img Image) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.images[bucket+"/"+key] = img
+}
+
+// image returns the fixture for an Image parameter, or writes an error if
+// it names an S3 object that does not exist.
+func (s *Service) image(w http.ResponseWriter, params map[string]interface{}) (Image, bool) {
+	img, _ := params["Image"].(map[string]interface{})
+	object, _ := img["S3Object"].(map[string]interface{})
+	if object == nil {
+		if _, ok := img["Bytes"].(string); ok {
+			return Image{}, true
+		}
+		h.WriteJSONError(w, "InvalidParameterException", "Request has invalid parameters: Image must set S3Object or Bytes.", http.StatusBadRequest)
+		return Image{}, false
+	}
+	bucket, key := h.GetString(object, "Bucket"), h.GetString(object, "Name")
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.store != nil {
+		if _, err := s.store.GetObject(bucket, key); err != nil {
+			h.WriteJSONError(w, "InvalidS3ObjectException", "Unable to get object metadata from S3. Check object key, region and/or access permissions.", http.StatusBadRequest)
+			return Image{}, false
+		}
+	}
+	return s.images[bucket+"/"+key], true
+}
+
+func (s *Service) detectLabels(w http.ResponseWriter, params map[string]interface{}) {
+	img, ok := s.image(w, params)
+	if !ok {
+		return
+	}
+	maxLabels := h.GetInt(params, "MaxLabels", 0)
+	minConfidence := 55.0
+	if v, ok := params["MinConfidence"].(float64); ok {
+		minConfidence = v
+	}
+
+	labels := append([]Label(nil), img.Labels...)
+	for i := range labels {
+		if labels[i].Confidence == 0 {
+			labels[i].Confidence = 99
+		}
+	}
+	sort.SliceStable(labels, func(i, j int) bool { return labels[i].Confidence > labels[j].Confidence })
+	out := []map[string]interface{}{}
+	for _, l := range labels {
+		if l.Confidence < minConfidence || maxLabels > 0 && len(out) == maxLabels {
+			continue
+		}
+		parents := make([]map[string]string, len(l.Parents))
+		for i, p := range l.Parents {
+			parents[i] = map[string]string{"Name": p}
+		}
+		instances := make([]map[string]interface{}, len(l.Instances))
+		for i, b := range l.Instances {
+			instances[i] = map[string]interface{}{"BoundingBox": b.toMap(), "Confidence": l.Confidence}
+		}
+		out = append(out, map[string]interface{}{
+			"Name":       l.Name,
+			"Confidence": l.Confidence,
+			"Parents":    parents,
+			"Instances":  instances,
+		})
+	}
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"Labels":            out,
+		"LabelModelVersion": "3.0",
+	})
+}
+
+func (s *Service) detectFaces(w http.ResponseWriter, params map[string]interface{}) {
+	img, ok := s.image(w, params)
+	if !ok {
+		return
+	}
+	all := false
+	attributes, _ := params["Attributes"].([]interface{})
+	for _, a := range attributes {
+		all = all || a == "ALL"
+	}
+
+	details := make([]map[string]interface{}, 0, len(img.Faces))
+	for _, f := range img.Faces {
+		confidence := f.Confidence
+		if confidence == 0 {
+			confidence = 99.9
+		}
+		d := map[string]interface{}{
+			"BoundingBox": f.BoundingBox.toMap(),
+			"Confidence":  confidence,
+			"Pose":        map[string]float64{"Roll": 0, "Yaw": 0, "Pitch": 0},
+			"Quality":     map[string]float64{"Brightness": 80, "Sharpness": 90},
+			"Landmarks":   f.landmarks(),
+		}
+		if all {
+			d["AgeRange"] = map[string]int{"Low": f.AgeLow, "High": f.AgeHigh}
+			d["Smile"] = map[string]interface{}{"Value": f.Smile, "Confidence": 99.0}
+			if f.Emotion != "" {
+				d["Emotions"] = []map[string]interface{}{{"Type": f.Emotion, "Confidence": 99.0}}
+			} else {
+				d["Emotions"] = []map[string]interface{}{}
+			}
+		}
+		details = append(details, d)
+	}
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{"FaceDetails": details})
+}
+
+func (b BoundingBox) toMap() map[string]float64 {
+	return map[string]float64{"Left": b.Left, "Top": b.Top, "Width": b.Width, "Height": b.Height}
+}
+
+// landmarks places the eyes, nose, and mouth corners of a face at the usual
+// proportions of its bounding box.
+func (f Face) landmarks() []map[string]interface{} {
+	b := f.BoundingBox
+	point := func(name string, x, y float64) map[string]interface{} {
+		return map[string]interface{}{"Type": name, "X": b.Left + x*b.Width, "Y": b.Top + y*b.Height}
+	}
+	return []map[string]interface{}{
+		point("eyeLeft", 0.3, 0.4),
+		point("eyeRight", 0.7, 0.4),
+		point("nose", 0.5, 0.6),
+		point("mouthLeft", 0.35, 0.8),
+		point("mouthRight", 0.65, 0.8),
+	}
+}
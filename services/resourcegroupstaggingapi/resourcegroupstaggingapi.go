@@ -6,29 +6,32 @@
 //   - GetResources
 //   - GetTagKeys
 //   - GetTagValues
+//
+// Tags are kept in the registry shared with the other mock services, so
+// GetResources also reports tags applied through service APIs such as SQS
+// TagQueue or S3 PutBucketTagging.
 package resourcegroupstaggingapi
 
 import (
-	"encoding/json"
-	"io"
 	"net/http"
 	"sort"
 	"strings"
 	"sync"
 
 	h "github.com/riyanimam/goto/internal/mockhelpers"
+	"github.com/riyanimam/goto/internal/tags"
 )
 
 // Service implements the Resource Groups Tagging API mock.
 type Service struct {
 	mu   sync.RWMutex
-	tags map[string]map[string]string // ARN -> tag key -> tag value
+	tags *tags.Store
 }
 
 // New creates a new Resource Groups Tagging API mock service.
 func New() *Service {
 	return &Service{
-		tags: make(map[string]map[string]string),
+		tags: tags.New(),
 	}
 }
 
@@ -37,96 +40,62 @@ func (s *Service) Name() string { return "tagging" }
 
 // Handler returns the HTTP handler for Resource Groups Tagging API requests.
 func (s *Service) Handler() http.Handler {
-	return http.HandlerFunc(s.handle)
+	return h.JSONRouter{
+		"TagResources":   s.tagResources,
+		"UntagResources": s.untagResources,
+		"GetResources":   s.getResources,
+		"GetTagKeys":     func(w http.ResponseWriter, _ map[string]interface{}) { s.getTagKeys(w) },
+		"GetTagValues":   s.getTagValues,
+	}
 }
 
-// Reset clears all state.
+// Reset clears all tags in the registry.
 func (s *Service) Reset() {
+	s.store().Clear()
+}
+
+// SetTagStore sets the registry the tagging API reads and writes.
+func (s *Service) SetTagStore(store *tags.Store) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	s.tags = make(map[string]map[string]string)
+	s.tags = store
 }
 
-func (s *Service) handle(w http.ResponseWriter, r *http.Request) {
-	target := r.Header.Get("X-Amz-Target")
-	parts := strings.SplitN(target, ".", 2)
-	if len(parts) != 2 {
-		h.WriteJSONError(w, "InvalidAction", "missing or invalid X-Amz-Target header", http.StatusBadRequest)
-		return
-	}
-	action := parts[1]
-
-	switch action {
-	case "TagResources":
-		s.tagResources(w, r)
-	case "UntagResources":
-		s.untagResources(w, r)
-	case "GetResources":
-		s.getResources(w, r)
-	case "GetTagKeys":
-		s.getTagKeys(w)
-	case "GetTagValues":
-		s.getTagValues(w, r)
-	default:
-		h.WriteJSONError(w, "InvalidAction", "unsupported action: "+action, http.StatusBadRequest)
-	}
+func (s *Service) store() *tags.Store {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.tags
 }
 
-func (s *Service) tagResources(w http.ResponseWriter, r *http.Request) {
-	bodyBytes, _ := io.ReadAll(r.Body)
-	var params map[string]interface{}
-	json.Unmarshal(bodyBytes, &params)
-
+func (s *Service) tagResources(w http.ResponseWriter, params map[string]interface{}) {
 	arns := toStringSlice(params["ResourceARNList"])
 	tagsInput := toStringMap(params["Tags"])
 
-	s.mu.Lock()
+	store := s.store()
 	for _, arn := range arns {
-		if s.tags[arn] == nil {
-			s.tags[arn] = make(map[string]string)
-		}
-		for k, v := range tagsInput {
-			s.tags[arn][k] = v
-		}
+		store.Tag(arn, tagsInput)
 	}
-	s.mu.Unlock()
 
 	h.WriteJSON(w, http.StatusOK, map[string]interface{}{
 		"FailedResourcesMap": map[string]interface{}{},
 	})
 }
 
-func (s *Service) untagResources(w http.ResponseWriter, r *http.Request) {
-	bodyBytes, _ := io.ReadAll(r.Body)
-	var params map[string]interface{}
-	json.Unmarshal(bodyBytes, &params)
-
+func (s *Service) untagResources(w http.ResponseWriter, params map[string]interface{}) {
 	arns := toStringSlice(params["ResourceARNList"])
 	tagKeys := toStringSlice(params["TagKeys"])
 
-	s.mu.Lock()
+	store := s.store()
 	for _, arn := range arns {
-		if m := s.tags[arn]; m != nil {
-			for _, k := range tagKeys {
-				delete(m, k)
-			}
-			if len(m) == 0 {
-				delete(s.tags, arn)
-			}
-		}
+		store.Untag(arn, tagKeys)
 	}
-	s.mu.Unlock()
 
 	h.WriteJSON(w, http.StatusOK, map[string]interface{}{
 		"FailedResourcesMap": map[string]interface{}{},
 	})
 }
 
-func (s *Service) getResources(w http.ResponseWriter, r *http.Request) {
-	bodyBytes, _ := io.ReadAll(r.Body)
-	var params map[string]interface{}
-	json.Unmarshal(bodyBytes, &params)
-
+func (s *Service) getResources(w http.ResponseWriter, params map[string]interface{}) {
 	var filters []tagFilter
 	if raw, ok := params["TagFilters"].([]interface{}); ok {
 		for _, f := range raw {
@@ -138,10 +107,11 @@ func (s *Service) getResources(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	s.mu.RLock()
+	typeFilters := toStringSlice(params["ResourceTypeFilters"])
+
 	var list []map[string]interface{}
-	for arn, tagsMap := range s.tags {
-		if !matchFilters(tagsMap, filters) {
+	for arn, tagsMap := range s.store().All() {
+		if len(tagsMap) == 0 || !matchFilters(tagsMap, filters) || !matchResourceType(arn, typeFilters) {
 			continue
 		}
 		var tagList []map[string]string
@@ -156,7 +126,6 @@ func (s *Service) getResources(w http.ResponseWriter, r *http.Request) {
 			"Tags":        tagList,
 		})
 	}
-	s.mu.RUnlock()
 
 	sort.Slice(list, func(i, j int) bool {
 		return list[i]["ResourceARN"].(string) < list[j]["ResourceARN"].(string)
@@ -169,14 +138,12 @@ func (s *Service) getResources(w http.ResponseWriter, r *http.Request) {
 }
 
 func (s *Service) getTagKeys(w http.ResponseWriter) {
-	s.mu.RLock()
 	keySet := make(map[string]struct{})
-	for _, tagsMap := range s.tags {
+	for _, tagsMap := range s.store().All() {
 		for k := range tagsMap {
 			keySet[k] = struct{}{}
 		}
 	}
-	s.mu.RUnlock()
 
 	keys := make([]string, 0, len(keySet))
 	for k := range keySet {
@@ -190,21 +157,15 @@ func (s *Service) getTagKeys(w http.ResponseWriter) {
 	})
 }
 
-func (s *Service) getTagValues(w http.ResponseWriter, r *http.Request) {
-	bodyBytes, _ := io.ReadAll(r.Body)
-	var params map[string]interface{}
-	json.Unmarshal(bodyBytes, &params)
-
+func (s *Service) getTagValues(w http.ResponseWriter, params map[string]interface{}) {
 	key := h.GetString(params, "Key")
 
-	s.mu.RLock()
 	valueSet := make(map[string]struct{})
-	for _, tagsMap := range s.tags {
+	for _, tagsMap := range s.store().All() {
 		if v, ok := tagsMap[key]; ok {
 			valueSet[v] = struct{}{}
 		}
 	}
-	s.mu.RUnlock()
 
 	values := make([]string, 0, len(valueSet))
 	for v := range valueSet {
@@ -245,6 +206,37 @@ func matchFilters(tags map[string]string, filters []tagFilter) bool {
 	return true
 }
 
+// matchResourceType reports whether arn matches one of the filters, which
+// are either a service ("sqs") or a service and resource type
+// ("lambda:function"). No filters match everything.
+func matchResourceType(arn string, filters []string) bool {
+	if len(filters) == 0 {
+		return true
+	}
+	parts := strings.SplitN(arn, ":", 6)
+	if len(parts) < 6 {
+		return false
+	}
+	service := parts[2]
+	resourceType := parts[5]
+	if i := strings.IndexAny(resourceType, ":/"); i >= 0 {
+		resourceType = resourceType[:i]
+	}
+	// Bucket and queue ARNs carry only the resource name.
+	switch service {
+	case "s3":
+		resourceType = "bucket"
+	case "sqs":
+		resourceType = "queue"
+	}
+	for _, f := range filters {
+		if f == service || f == service+":"+resourceType {
+			return true
+		}
+	}
+	return false
+}
+
 func toStringSlice(v interface{}) []string {
 	arr, ok := v.([]interface{})
 	if !ok {
@@ -7,6 +7,9 @@
 //   - ListHostedZones
 //   - ChangeResourceRecordSets
 //   - ListResourceRecordSets
+//   - ChangeTagsForResource
+//   - ListTagsForResource
+//   - ListTagsForResources
 package route53
 
 import (
@@ -21,6 +24,7 @@ import (
 	"time"
 
 	h "github.com/riyanimam/goto/internal/mockhelpers"
+	"github.com/riyanimam/goto/internal/tags"
 )
 
 // Service implements the Route 53 mock.
@@ -28,6 +32,7 @@ type Service struct {
 	mu          sync.RWMutex
 	hostedZones map[string]*hostedZone
 	zoneCounter int
+	tags        *tags.Store
 }
 
 type hostedZone struct {
@@ -44,12 +49,14 @@ type resourceRecordSet struct {
 	rrType  string
 	ttl     int
 	records []string
+	alias   *xmlAliasTarget
 }
 
 // New creates a new Route 53 mock service.
 func New() *Service {
 	return &Service{
 		hostedZones: make(map[string]*hostedZone),
+		tags:        tags.New(),
 	}
 }
 
@@ -67,12 +74,26 @@ func (s *Service) Reset() {
 	defer s.mu.Unlock()
 	s.hostedZones = make(map[string]*hostedZone)
 	s.zoneCounter = 0
+	s.tags.DeleteService("route53")
+}
+
+// SetTagStore sets the registry hosted zone tags are recorded in.
+func (s *Service) SetTagStore(store *tags.Store) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tags = store
 }
 
 func (s *Service) handle(w http.ResponseWriter, r *http.Request) {
 	path := r.URL.Path
 
 	switch {
+	case strings.HasSuffix(path, "/tags/hostedzone") && r.Method == http.MethodPost:
+		s.listTagsForResources(w, r)
+	case strings.Contains(path, "/tags/hostedzone/") && r.Method == http.MethodPost:
+		s.changeTagsForResource(w, r, extractLastSegment(path))
+	case strings.Contains(path, "/tags/hostedzone/") && r.Method == http.MethodGet:
+		s.listTagsForResource(w, r, extractLastSegment(path))
 	case strings.HasSuffix(path, "/hostedzone") && r.Method == http.MethodGet:
 		s.listHostedZones(w, r)
 	case strings.HasSuffix(path, "/hostedzone") && r.Method == http.MethodPost:
@@ -189,6 +210,7 @@ func (s *Service) deleteHostedZone(w http.ResponseWriter, _ *http.Request, id st
 		return
 	}
 	delete(s.hostedZones, id)
+	s.tags.Delete(zoneArn(id))
 	s.mu.Unlock()
 
 	resp := deleteHostedZoneResp{
@@ -245,6 +267,7 @@ func (s *Service) changeResourceRecordSets(w http.ResponseWriter, r *http.Reques
 							Value string `xml:"Value"`
 						} `xml:"ResourceRecord"`
 					} `xml:"ResourceRecords"`
+					AliasTarget *xmlAliasTarget `xml:"AliasTarget"`
 				} `xml:"ResourceRecordSet"`
 			} `xml:"Changes>Change"`
 		} `xml:"ChangeBatch"`
@@ -269,6 +292,7 @@ func (s *Service) changeResourceRecordSets(w http.ResponseWriter, r *http.Reques
 				rrType:  rrs.Type,
 				ttl:     rrs.TTL,
 				records: records,
+				alias:   rrs.AliasTarget,
 			})
 		case "DELETE":
 			zone.recordSets = removeRecordSet(zone.recordSets, rrs.Name, rrs.Type)
@@ -306,6 +330,7 @@ func (s *Service) listResourceRecordSets(w http.ResponseWriter, _ *http.Request,
 			Type:            rrs.rrType,
 			TTL:             rrs.ttl,
 			ResourceRecords: records,
+			AliasTarget:     rrs.alias,
 		})
 	}
 	s.mu.RUnlock()
@@ -330,6 +355,84 @@ func removeRecordSet(sets []*resourceRecordSet, name, rrType string) []*resource
 
 // XML types.
 
+// zoneArn returns the ARN hosted zone tags are recorded under.
+func zoneArn(id string) string {
+	return "arn:aws:route53:::hostedzone/" + id
+}
+
+// changeTagsForResource adds and removes the tags of a hosted zone.
+func (s *Service) changeTagsForResource(w http.ResponseWriter, r *http.Request, id string) {
+	bodyBytes, _ := io.ReadAll(r.Body)
+	var req struct {
+		XMLName       xml.Name `xml:"ChangeTagsForResourceRequest"`
+		AddTags       []xmlTag `xml:"AddTags>Tag"`
+		RemoveTagKeys []string `xml:"RemoveTagKeys>Key"`
+	}
+	if err := xml.Unmarshal(bodyBytes, &req); err != nil {
+		h.WriteXMLError(w, "Sender", "InvalidInput", "could not parse request body", http.StatusBadRequest)
+		return
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if _, exists := s.hostedZones[id]; !exists {
+		h.WriteXMLError(w, "Sender", "NoSuchHostedZone", "No hosted zone found with ID: "+id, http.StatusNotFound)
+		return
+	}
+	add := make(map[string]string, len(req.AddTags))
+	for _, t := range req.AddTags {
+		add[t.Key] = t.Value
+	}
+	s.tags.Tag(zoneArn(id), add)
+	s.tags.Untag(zoneArn(id), req.RemoveTagKeys)
+	h.WriteXML(w, http.StatusOK, changeTagsForResourceResp{})
+}
+
+func (s *Service) listTagsForResource(w http.ResponseWriter, _ *http.Request, id string) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if _, exists := s.hostedZones[id]; !exists {
+		h.WriteXMLError(w, "Sender", "NoSuchHostedZone", "No hosted zone found with ID: "+id, http.StatusNotFound)
+		return
+	}
+	h.WriteXML(w, http.StatusOK, listTagsForResourceResp{ResourceTagSet: s.tagSet(id)})
+}
+
+func (s *Service) listTagsForResources(w http.ResponseWriter, r *http.Request) {
+	bodyBytes, _ := io.ReadAll(r.Body)
+	var req struct {
+		XMLName     xml.Name `xml:"ListTagsForResourcesRequest"`
+		ResourceIds []string `xml:"ResourceIds>ResourceId"`
+	}
+	if err := xml.Unmarshal(bodyBytes, &req); err != nil {
+		h.WriteXMLError(w, "Sender", "InvalidInput", "could not parse request body", http.StatusBadRequest)
+		return
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	resp := listTagsForResourcesResp{}
+	for _, id := range req.ResourceIds {
+		if _, exists := s.hostedZones[id]; !exists {
+			h.WriteXMLError(w, "Sender", "NoSuchHostedZone", "No hosted zone found with ID: "+id, http.StatusNotFound)
+			return
+		}
+		resp.ResourceTagSets = append(resp.ResourceTagSets, s.tagSet(id))
+	}
+	h.WriteXML(w, http.StatusOK, resp)
+}
+
+// tagSet lists the tags of the hosted zone sorted by key. The caller must
+// hold s.mu.
+func (s *Service) tagSet(id string) xmlResourceTagSet {
+	set := xmlResourceTagSet{ResourceType: "hostedzone", ResourceID: id}
+	for k, v := range s.tags.Get(zoneArn(id)) {
+		set.Tags = append(set.Tags, xmlTag{Key: k, Value: v})
+	}
+	sort.Slice(set.Tags, func(i, j int) bool { return set.Tags[i].Key < set.Tags[j].Key })
+	return set
+}
+
 func zoneToXML(z *hostedZone) xmlHostedZone {
 	return xmlHostedZone{
 		ID:                     "/hostedzone/" + z.id,
@@ -355,8 +458,17 @@ type xmlHostedZoneConfig struct {
 type xmlResourceRecordSet struct {
 	Name            string              `xml:"Name"`
 	Type            string              `xml:"Type"`
-	TTL             int                 `xml:"TTL"`
+	TTL             int                 `xml:"TTL,omitempty"`
 	ResourceRecords []xmlResourceRecord `xml:"ResourceRecords>ResourceRecord"`
+	AliasTarget     *xmlAliasTarget     `xml:"AliasTarget,omitempty"`
+}
+
+// xmlAliasTarget points an alias record at an AWS resource, such as an API
+// Gateway custom domain, instead of listing record values.
+type xmlAliasTarget struct {
+	HostedZoneID         string `xml:"HostedZoneId"`
+	DNSName              string `xml:"DNSName"`
+	EvaluateTargetHealth bool   `xml:"EvaluateTargetHealth"`
 }
 
 type xmlResourceRecord struct {
@@ -409,3 +521,28 @@ type listResourceRecordSetsResp struct {
 	IsTruncated        bool                   `xml:"IsTruncated"`
 	MaxItems           string                 `xml:"MaxItems"`
 }
+
+type xmlTag struct {
+	Key   string `xml:"Key"`
+	Value string `xml:"Value"`
+}
+
+type xmlResourceTagSet struct {
+	ResourceType string   `xml:"ResourceType"`
+	ResourceID   string   `xml:"ResourceId"`
+	Tags         []xmlTag `xml:"Tags>Tag"`
+}
+
+type changeTagsForResourceResp struct {
+	XMLName xml.Name `xml:"ChangeTagsForResourceResponse"`
+}
+
+type listTagsForResourceResp struct {
+	XMLName        xml.Name          `xml:"ListTagsForResourceResponse"`
+	ResourceTagSet xmlResourceTagSet `xml:"ResourceTagSet"`
+}
+
+type listTagsForResourcesResp struct {
+	XMLName         xml.Name            `xml:"ListTagsForResourcesResponse"`
+	ResourceTagSets []xmlResourceTagSet `xml:"ResourceTagSets>ResourceTagSet"`
+}
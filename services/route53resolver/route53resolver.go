@@ -0,0 +1,591 @@
+// Package route53resolver provides a mock implementation of Amazon Route 53
+// Resolver endpoints and forwarding rules.
+//
+// Supported actions:
+//   - CreateResolverEndpoint, GetResolverEndpoint, ListResolverEndpoints,
+//     DeleteResolverEndpoint, ListResolverEndpointIpAddresses
+//   - CreateResolverRule, GetResolverRule, ListResolverRules,
+//     UpdateResolverRule, DeleteResolverRule
+//   - AssociateResolverRule, DisassociateResolverRule,
+//     GetResolverRuleAssociation, ListResolverRuleAssociations
+//   - TagResource, UntagResource, ListTagsForResource
+//
+// No DNS queries are resolved. Endpoints are checked against the EC2 mock:
+// their subnets must exist and share a VPC, their security groups must
+// belong to that VPC, and addresses are allocated from the subnets' CIDR
+// blocks unless given. Endpoints are CREATING and rule associations
+// CREATING until their lifecycle transition completes, and then
+// OPERATIONAL and COMPLETE. Forwarding rules must name an outbound
+// endpoint, and a VPC can be associated with one rule per domain name.
+package route53resolver
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/riyanimam/goto/internal/clock"
+	"github.com/riyanimam/goto/internal/lifecycle"
+	h "github.com/riyanimam/goto/internal/mockhelpers"
+	"github.com/riyanimam/goto/internal/paginate"
+	"github.com/riyanimam/goto/internal/tags"
+)
+
+// Service implements the Route 53 Resolver mock.
+type Service struct {
+	mu           sync.RWMutex
+	endpoints    map[string]*endpoint
+	rules        map[string]*rule
+	associations map[string]*association
+
+	list        h.ResourceLister
+	tags        *tags.Store
+	clock       *clock.Clock
+	transitions *lifecycle.Transitions
+}
+
+type endpoint struct {
+	id               string
+	arn              string
+	creatorRequestID string
+	name             string
+	direction        string // INBOUND or OUTBOUND
+	vpcID            string
+	securityGroupIDs []string
+	ips              []*endpointIP
+	created          time.Time
+	ready            lifecycle.Transition
+}
+
+type endpointIP struct {
+	id       string
+	subnetID string
+	ip       string
+}
+
+// New creates a new Route 53 Resolver mock service.
+func New() *Service {
+	return &Service{
+		endpoints:    make(map[string]*endpoint),
+		rules:        make(map[string]*rule),
+		associations: make(map[string]*association),
+		tags:         tags.New(),
+	}
+}
+
+// Name returns the service identifier.
+func (s *Service) Name() string { return "route53resolver" }
+
+// Handler returns the HTTP handler for Route 53 Resolver requests.
+func (s *Service) Handler() http.Handler {
+	return h.JSONRouter{
+		"CreateResolverEndpoint":          s.createResolverEndpoint,
+		"GetResolverEndpoint":             s.getResolverEndpoint,
+		"ListResolverEndpoints":           s.listResolverEndpoints,
+		"DeleteResolverEndpoint":          s.deleteResolverEndpoint,
+		"ListResolverEndpointIpAddresses": s.listResolverEndpointIPAddresses,
+		"CreateResolverRule":              s.createResolverRule,
+		"GetResolverRule":                 s.getResolverRule,
+		"ListResolverRules":               s.listResolverRules,
+		"UpdateResolverRule":              s.updateResolverRule,
+		"DeleteResolverRule":              s.deleteResolverRule,
+		"AssociateResolverRule":           s.associateResolverRule,
+		"DisassociateResolverRule":        s.disassociateResolverRule,
+		"GetResolverRuleAssociation":      s.getResolverRuleAssociation,
+		"ListResolverRuleAssociations":    s.listResolverRuleAssociations,
+		"TagResource":                     s.tagResource,
+		"UntagResource":                   s.untagResource,
+		"ListTagsForResource":             s.listTagsForResource,
+	}
+}
+
+// Reset clears all state.
+func (s *Service) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.endpoints = make(map[string]*endpoint)
+	s.rules = make(map[string]*rule)
+	s.associations = make(map[string]*association)
+	s.tags.DeleteService("route53resolver")
+}
+
+// SetResourceLister sets how the EC2 mock's VPCs, subnets, and security
+// groups are found.
+func (s *Service) SetResourceLister(l h.ResourceLister) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.list = l
+}
+
+// SetTagStore sets the registry endpoint and rule tags are recorded in.
+func (s *Service) SetTagStore(store *tags.Store) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tags = store
+}
+
+// SetClock attaches the mock clock used for creation times.
+func (s *Service) SetClock(c *clock.Clock) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.clock = c
+}
+
+// SetTransitions sets how long endpoints and rule associations stay
+// CREATING.
+func (s *Service) SetTransitions(t *lifecycle.Transitions) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.transitions = t
+}
+
+func (s *Service) now() time.Time {
+	if s.clock != nil {
+		return s.clock.Now()
+	}
+	return time.Now().UTC()
+}
+
+func writeError(w http.ResponseWriter, code, message string) {
+	h.WriteJSONError(w, code, message, http.StatusBadRequest)
+}
+
+func resourceARN(kind, id string) string {
+	return fmt.Sprintf("arn:aws:route53resolver:us-east-1:%s:%s/%s", h.DefaultAccountID, kind, id)
+}
+
+func timestamp(t time.Time) string {
+	return t.Format(time.RFC3339)
+}
+
+func stringList(v interface{}) []string {
+	raw, _ := v.([]interface{})
+	out := make([]string, 0, len(raw))
+	for _, item := range raw {
+		if s, ok := item.(string); ok {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// network is the EC2 mock's VPCs, subnets, and security groups as resolver
+// endpoints see them.
+type network struct {
+	vpcs           map[string]bool
+	subnets        map[string]subnet
+	securityGroups map[string]string // VPC ID by group ID
+}
+
+type subnet struct {
+	vpcID string
+	cidr  *net.IPNet
+}
+
+// network reads the EC2 mock's VPCs, subnets, and security groups. It
+// returns nil if no EC2 mock is registered, in which case references to
+// them are not checked. s.mu must not be held.
+func (s *Service) network() *network {
+	s.mu.RLock()
+	list := s.list
+	s.mu.RUnlock()
+	if list == nil {
+		return nil
+	}
+	n := &network{
+		vpcs:           make(map[string]bool),
+		subnets:        make(map[string]subnet),
+		securityGroups: make(map[string]string),
+	}
+	for _, r := range list("ec2") {
+		vpcID, _ := r.Attributes["vpc_id"].(string)
+		switch r.Type {
+		case "aws_vpc":
+			n.vpcs[r.ID] = true
+		case "aws_subnet":
+			cidr, _ := r.Attributes["cidr_block"].(string)
+			_, block, _ := net.ParseCIDR(cidr)
+			n.subnets[r.ID] = subnet{vpcID: vpcID, cidr: block}
+		case "aws_security_group":
+			n.securityGroups[r.ID] = vpcID
+		}
+	}
+	return n
+}
+
+// allocate returns the first free address in the subnet after the four AWS
+// reserves, or "" if the subnet is full. The caller must hold s.mu.
+func (s *Service) allocate(sn subnet, used map[string]bool) string {
+	if sn.cidr == nil {
+		return ""
+	}
+	base := sn.cidr.IP.To4()
+	if base == nil {
+		return ""
+	}
+	start := binary.BigEndian.Uint32(base)
+	ones, bits := sn.cidr.Mask.Size()
+	size := uint32(1) << (bits - ones)
+	for i := uint32(4); i+1 < size; i++ {
+		ip := make(net.IP, 4)
+		binary.BigEndian.PutUint32(ip, start+i)
+		if addr := ip.String(); !used[addr] {
+			return addr
+		}
+	}
+	return ""
+}
+
+// usedIPs returns the addresses held by endpoints. The caller must hold
+// s.mu.
+func (s *Service) usedIPs() map[string]bool {
+	used := make(map[string]bool)
+	for _, ep := range s.endpoints {
+		for _, ip := range ep.ips {
+			used[ip.ip] = true
+		}
+	}
+	return used
+}
+
+func (s *Service) createResolverEndpoint(w http.ResponseWriter, params map[string]interface{}) {
+	requestID := h.GetString(params, "CreatorRequestId")
+	direction := h.GetString(params, "Direction")
+	groups := stringList(params["SecurityGroupIds"])
+	if requestID == "" {
+		writeError(w, "InvalidParameterException", "CreatorRequestId is required.")
+		return
+	}
+	if direction != "INBOUND" && direction != "OUTBOUND" {
+		writeError(w, "InvalidParameterException", "Direction must be INBOUND or OUTBOUND.")
+		return
+	}
+	if len(groups) == 0 {
+		writeError(w, "InvalidParameterException", "At least one security group is required.")
+		return
+	}
+	rawIPs, _ := params["IpAddresses"].([]interface{})
+	if len(rawIPs) < 2 || len(rawIPs) > 6 {
+		writeError(w, "InvalidParameterException", "A resolver endpoint requires between 2 and 6 IP addresses.")
+		return
+	}
+	netw := s.network()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, ep := range s.endpoints {
+		if ep.creatorRequestID == requestID {
+			h.WriteJSON(w, http.StatusOK, map[string]interface{}{"ResolverEndpoint": s.endpointResp(ep)})
+			return
+		}
+	}
+
+	used := s.usedIPs()
+	var vpcID string
+	ips := make([]*endpointIP, 0, len(rawIPs))
+	for _, item := range rawIPs {
+		m, _ := item.(map[string]interface{})
+		subnetID, addr := h.GetString(m, "SubnetId"), h.GetString(m, "Ip")
+		var sn subnet
+		if netw != nil {
+			var exists bool
+			if sn, exists = netw.subnets[subnetID]; !exists {
+				writeError(w, "InvalidParameterException", "The subnet ID '"+subnetID+"' does not exist.")
+				return
+			}
+			if vpcID != "" && sn.vpcID != vpcID {
+				writeError(w, "InvalidParameterException", "All subnets of a resolver endpoint must be in the same VPC.")
+				return
+			}
+			vpcID = sn.vpcID
+		}
+		switch {
+		case addr != "":
+			ip := net.ParseIP(addr)
+			if ip == nil || (sn.cidr != nil && !sn.cidr.Contains(ip)) {
+				writeError(w, "InvalidParameterException", "The IP address "+addr+" is not in the CIDR block of subnet "+subnetID+".")
+				return
+			}
+			if used[addr] {
+				writeError(w, "ResourceExistsException", "The IP address "+addr+" is already in use.")
+				return
+			}
+		case sn.cidr != nil:
+			if addr = s.allocate(sn, used); addr == "" {
+				writeError(w, "ResourceUnavailableException", "No free IP addresses remain in subnet "+subnetID+".")
+				return
+			}
+		default:
+			writeError(w, "InvalidParameterException", "An IP address is required for subnet "+subnetID+".")
+			return
+		}
+		used[addr] = true
+		ips = append(ips, &endpointIP{id: "rni-" + h.RandomHex(17), subnetID: subnetID, ip: addr})
+	}
+	if netw != nil {
+		for _, g := range groups {
+			groupVPC, exists := netw.securityGroups[g]
+			if !exists {
+				writeError(w, "InvalidParameterException", "The security group '"+g+"' does not exist.")
+				return
+			}
+			if groupVPC != vpcID {
+				writeError(w, "InvalidParameterException", "The security group '"+g+"' is not in VPC "+vpcID+".")
+				return
+			}
+		}
+	}
+
+	prefix := "rslvr-in-"
+	if direction == "OUTBOUND" {
+		prefix = "rslvr-out-"
+	}
+	id := prefix + h.RandomHex(17)
+	ep := &endpoint{
+		id:               id,
+		arn:              resourceARN("resolver-endpoint", id),
+		creatorRequestID: requestID,
+		name:             h.GetString(params, "Name"),
+		direction:        direction,
+		vpcID:            vpcID,
+		securityGroupIDs: groups,
+		ips:              ips,
+		created:          s.now(),
+		ready:            s.transitions.Begin(),
+	}
+	s.endpoints[id] = ep
+	s.tags.Tag(ep.arn, tags.FromList(params["Tags"], "Key", "Value"))
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{"ResolverEndpoint": s.endpointResp(ep)})
+}
+
+// endpointStatus returns the endpoint's status. The caller must hold s.mu.
+func (s *Service) endpointStatus(ep *endpoint) string {
+	return s.transitions.Status(ep.ready, "CREATING", "OPERATIONAL")
+}
+
+// endpointResp returns the endpoint as GetResolverEndpoint reports it. The
+// caller must hold s.mu.
+func (s *Service) endpointResp(ep *endpoint) map[string]interface{} {
+	status := s.endpointStatus(ep)
+	message := "Creating the Resolver Endpoint"
+	if status == "OPERATIONAL" {
+		message = "This Resolver Endpoint is operational."
+	}
+	return map[string]interface{}{
+		"Id":                   ep.id,
+		"Arn":                  ep.arn,
+		"CreatorRequestId":     ep.creatorRequestID,
+		"Name":                 ep.name,
+		"Direction":            ep.direction,
+		"HostVPCId":            ep.vpcID,
+		"SecurityGroupIds":     ep.securityGroupIDs,
+		"IpAddressCount":       len(ep.ips),
+		"Status":               status,
+		"StatusMessage":        message,
+		"ResolverEndpointType": "IPV4",
+		"CreationTime":         timestamp(ep.created),
+		"ModificationTime":     timestamp(ep.created),
+	}
+}
+
+// lookupEndpoint returns the endpoint, or writes ResourceNotFoundException.
+// The caller must hold s.mu.
+func (s *Service) lookupEndpoint(w http.ResponseWriter, id string) (*endpoint, bool) {
+	ep, exists := s.endpoints[id]
+	if !exists {
+		writeError(w, "ResourceNotFoundException", "Resolver endpoint with ID '"+id+"' does not exist.")
+	}
+	return ep, exists
+}
+
+func (s *Service) getResolverEndpoint(w http.ResponseWriter, params map[string]interface{}) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	ep, ok := s.lookupEndpoint(w, h.GetString(params, "ResolverEndpointId"))
+	if !ok {
+		return
+	}
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{"ResolverEndpoint": s.endpointResp(ep)})
+}
+
+func (s *Service) listResolverEndpoints(w http.ResponseWriter, params map[string]interface{}) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	filters := parseFilters(params["Filters"])
+	var out []map[string]interface{}
+	for _, id := range sortedKeys(s.endpoints) {
+		resp := s.endpointResp(s.endpoints[id])
+		if filters.match(resp) {
+			out = append(out, resp)
+		}
+	}
+	writePage(w, out, params, "ResolverEndpoints", func(i int) string { return out[i]["Id"].(string) })
+}
+
+func (s *Service) deleteResolverEndpoint(w http.ResponseWriter, params map[string]interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ep, ok := s.lookupEndpoint(w, h.GetString(params, "ResolverEndpointId"))
+	if !ok {
+		return
+	}
+	for _, r := range s.rules {
+		if r.endpointID == ep.id {
+			writeError(w, "InvalidRequestException", "Cannot delete resolver endpoint "+ep.id+" because it is used by resolver rule "+r.id+".")
+			return
+		}
+	}
+	resp := s.endpointResp(ep)
+	resp["Status"] = "DELETING"
+	delete(s.endpoints, ep.id)
+	s.tags.Delete(ep.arn)
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{"ResolverEndpoint": resp})
+}
+
+func (s *Service) listResolverEndpointIPAddresses(w http.ResponseWriter, params map[string]interface{}) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	ep, ok := s.lookupEndpoint(w, h.GetString(params, "ResolverEndpointId"))
+	if !ok {
+		return
+	}
+	status := "ATTACHED"
+	if s.endpointStatus(ep) == "CREATING" {
+		status = "CREATING"
+	}
+	out := make([]map[string]interface{}, 0, len(ep.ips))
+	for _, ip := range ep.ips {
+		out = append(out, map[string]interface{}{
+			"IpId":             ip.id,
+			"SubnetId":         ip.subnetID,
+			"Ip":               ip.ip,
+			"Status":           status,
+			"StatusMessage":    "",
+			"CreationTime":     timestamp(ep.created),
+			"ModificationTime": timestamp(ep.created),
+		})
+	}
+	// An endpoint's addresses are fixed when it is created.
+	writePage(w, out, params, "IpAddresses", paginate.Seq)
+}
+
+// filters are the Filters of a List* request: each names a response field
+// and the values it may take.
+type filters map[string][]string
+
+func parseFilters(v interface{}) filters {
+	f := make(filters)
+	raw, _ := v.([]interface{})
+	for _, item := range raw {
+		m, _ := item.(map[string]interface{})
+		f[h.GetString(m, "Name")] = stringList(m["Values"])
+	}
+	return f
+}
+
+// fieldNames maps filter names to the response fields they match, where
+// the two differ.
+var fieldNames = map[string]string{
+	"Type": "RuleType",
+}
+
+func (f filters) match(resp map[string]interface{}) bool {
+	for name, values := range f {
+		field := name
+		if mapped, ok := fieldNames[name]; ok {
+			field = mapped
+		}
+		got := fmt.Sprint(resp[field])
+		found := false
+		for _, v := range values {
+			if v == got {
+				found = true
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+func sortedKeys[T any](m map[string]T) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// writePage writes a page of items, sorted by itemKey, under key, with
+// MaxResults and NextToken from params.
+func writePage(w http.ResponseWriter, items []map[string]interface{}, params map[string]interface{}, key string, itemKey func(i int) string) {
+	limit := h.GetInt(params, "MaxResults", 100)
+	page, next, err := paginate.Page(items, itemKey, h.GetString(params, "NextToken"), limit, 100)
+	if err != nil {
+		writeError(w, "InvalidNextTokenException", "The NextToken is not valid.")
+		return
+	}
+	if page == nil {
+		page = []map[string]interface{}{}
+	}
+	resp := map[string]interface{}{key: page, "MaxResults": limit}
+	if next != "" {
+		resp["NextToken"] = next
+	}
+	h.WriteJSON(w, http.StatusOK, resp)
+}
+
+// lookupARN returns the ARN if it names an endpoint or rule, or writes
+// ResourceNotFoundException. The caller must hold s.mu.
+func (s *Service) lookupARN(w http.ResponseWriter, resourceARN string) bool {
+	for _, ep := range s.endpoints {
+		if ep.arn == resourceARN {
+			return true
+		}
+	}
+	for _, r := range s.rules {
+		if r.arn == resourceARN {
+			return true
+		}
+	}
+	writeError(w, "ResourceNotFoundException", "Resource "+resourceARN+" does not exist.")
+	return false
+}
+
+func (s *Service) tagResource(w http.ResponseWriter, params map[string]interface{}) {
+	resourceARN := h.GetString(params, "ResourceArn")
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.lookupARN(w, resourceARN) {
+		return
+	}
+	s.tags.Tag(resourceARN, tags.FromList(params["Tags"], "Key", "Value"))
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{})
+}
+
+func (s *Service) untagResource(w http.ResponseWriter, params map[string]interface{}) {
+	resourceARN := h.GetString(params, "ResourceArn")
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.lookupARN(w, resourceARN) {
+		return
+	}
+	s.tags.Untag(resourceARN, tags.Keys(params["TagKeys"]))
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{})
+}
+
+func (s *Service) listTagsForResource(w http.ResponseWriter, params map[string]interface{}) {
+	resourceARN := h.GetString(params, "ResourceArn")
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if !s.lookupARN(w, resourceARN) {
+		return
+	}
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{"Tags": tags.ToList(s.tags.Get(resourceARN), "Key", "Value")})
+}
@@ -0,0 +1,356 @@
+package route53resolver
+
+import (
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/riyanimam/goto/internal/lifecycle"
+	h "github.com/riyanimam/goto/internal/mockhelpers"
+	"github.com/riyanimam/goto/internal/tags"
+)
+
+type rule struct {
+	id               string
+	arn              string
+	creatorRequestID string
+	name             string
+	ruleType         string // FORWARD or SYSTEM
+	domainName       string // lower case, with a trailing dot
+	targetIPs        []map[string]interface{}
+	endpointID       string
+	created          time.Time
+	modified         time.Time
+}
+
+type association struct {
+	id     string
+	ruleID string
+	name   string
+	vpcID  string
+	ready  lifecycle.Transition
+}
+
+// normalizeDomain returns a domain name as Resolver reports it: lower case
+// with a trailing dot.
+func normalizeDomain(name string) string {
+	return strings.ToLower(strings.TrimSuffix(name, ".")) + "."
+}
+
+// parseTargetIPs reads a TargetIps list, filling in port 53, and returns a
+// message describing the first problem if it is invalid.
+func parseTargetIPs(v interface{}) ([]map[string]interface{}, string) {
+	raw, _ := v.([]interface{})
+	if len(raw) > 6 {
+		return nil, "A resolver rule can have at most 6 target IP addresses."
+	}
+	targets := make([]map[string]interface{}, 0, len(raw))
+	for _, item := range raw {
+		m, _ := item.(map[string]interface{})
+		ip := h.GetString(m, "Ip")
+		if net.ParseIP(ip) == nil {
+			return nil, "Invalid target IP address: " + ip
+		}
+		port := h.GetInt(m, "Port", 53)
+		if port < 1 || port > 65535 {
+			return nil, "Target port must be between 1 and 65535."
+		}
+		targets = append(targets, map[string]interface{}{"Ip": ip, "Port": port})
+	}
+	return targets, ""
+}
+
+// checkRule validates a rule's targets and endpoint for its type, writing an
+// error and returning false if they are invalid. The caller must hold s.mu.
+func (s *Service) checkRule(w http.ResponseWriter, ruleType string, targets []map[string]interface{}, endpointID string) bool {
+	switch ruleType {
+	case "FORWARD":
+		if len(targets) == 0 {
+			writeError(w, "InvalidRequestException", "TargetIps is required for FORWARD rules.")
+			return false
+		}
+		if endpointID == "" {
+			writeError(w, "InvalidRequestException", "ResolverEndpointId is required for FORWARD rules.")
+			return false
+		}
+		ep, ok := s.lookupEndpoint(w, endpointID)
+		if !ok {
+			return false
+		}
+		if ep.direction != "OUTBOUND" {
+			writeError(w, "InvalidRequestException", "Resolver endpoint "+endpointID+" is not an outbound endpoint.")
+			return false
+		}
+	case "SYSTEM":
+		if len(targets) > 0 || endpointID != "" {
+			writeError(w, "InvalidRequestException", "SYSTEM rules cannot have TargetIps or a ResolverEndpointId.")
+			return false
+		}
+	case "RECURSIVE":
+		writeError(w, "InvalidRequestException", "RECURSIVE rules are defined by Resolver and cannot be created.")
+		return false
+	default:
+		writeError(w, "InvalidParameterException", "RuleType must be FORWARD or SYSTEM.")
+		return false
+	}
+	return true
+}
+
+func (s *Service) createResolverRule(w http.ResponseWriter, params map[string]interface{}) {
+	requestID := h.GetString(params, "CreatorRequestId")
+	domain := h.GetString(params, "DomainName")
+	if requestID == "" || domain == "" {
+		writeError(w, "InvalidParameterException", "CreatorRequestId and DomainName are required.")
+		return
+	}
+	targets, msg := parseTargetIPs(params["TargetIps"])
+	if msg != "" {
+		writeError(w, "InvalidParameterException", msg)
+		return
+	}
+	ruleType := h.GetString(params, "RuleType")
+	endpointID := h.GetString(params, "ResolverEndpointId")
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, r := range s.rules {
+		if r.creatorRequestID == requestID {
+			h.WriteJSON(w, http.StatusOK, map[string]interface{}{"ResolverRule": s.ruleResp(r)})
+			return
+		}
+	}
+	if !s.checkRule(w, ruleType, targets, endpointID) {
+		return
+	}
+	now := s.now()
+	id := "rslvr-rr-" + h.RandomHex(17)
+	r := &rule{
+		id:               id,
+		arn:              resourceARN("resolver-rule", id),
+		creatorRequestID: requestID,
+		name:             h.GetString(params, "Name"),
+		ruleType:         ruleType,
+		domainName:       normalizeDomain(domain),
+		targetIPs:        targets,
+		endpointID:       endpointID,
+		created:          now,
+		modified:         now,
+	}
+	s.rules[id] = r
+	s.tags.Tag(r.arn, tags.FromList(params["Tags"], "Key", "Value"))
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{"ResolverRule": s.ruleResp(r)})
+}
+
+// ruleResp returns the rule as GetResolverRule reports it. The caller must
+// hold s.mu.
+func (s *Service) ruleResp(r *rule) map[string]interface{} {
+	resp := map[string]interface{}{
+		"Id":               r.id,
+		"Arn":              r.arn,
+		"CreatorRequestId": r.creatorRequestID,
+		"Name":             r.name,
+		"RuleType":         r.ruleType,
+		"DomainName":       r.domainName,
+		"Status":           "COMPLETE",
+		"StatusMessage":    "Successfully created Resolver Rule",
+		"OwnerId":          h.DefaultAccountID,
+		"ShareStatus":      "NOT_SHARED",
+		"CreationTime":     timestamp(r.created),
+		"ModificationTime": timestamp(r.modified),
+	}
+	if r.ruleType == "FORWARD" {
+		resp["TargetIps"] = r.targetIPs
+		resp["ResolverEndpointId"] = r.endpointID
+	}
+	return resp
+}
+
+// lookupRule returns the rule, or writes ResourceNotFoundException. The
+// caller must hold s.mu.
+func (s *Service) lookupRule(w http.ResponseWriter, id string) (*rule, bool) {
+	r, exists := s.rules[id]
+	if !exists {
+		writeError(w, "ResourceNotFoundException", "Resolver rule with ID '"+id+"' does not exist.")
+	}
+	return r, exists
+}
+
+func (s *Service) getResolverRule(w http.ResponseWriter, params map[string]interface{}) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	r, ok := s.lookupRule(w, h.GetString(params, "ResolverRuleId"))
+	if !ok {
+		return
+	}
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{"ResolverRule": s.ruleResp(r)})
+}
+
+func (s *Service) listResolverRules(w http.ResponseWriter, params map[string]interface{}) {
+	f := parseFilters(params["Filters"])
+	if domains, ok := f["DomainName"]; ok {
+		for i, d := range domains {
+			domains[i] = normalizeDomain(d)
+		}
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var out []map[string]interface{}
+	for _, id := range sortedKeys(s.rules) {
+		resp := s.ruleResp(s.rules[id])
+		if f.match(resp) {
+			out = append(out, resp)
+		}
+	}
+	writePage(w, out, params, "ResolverRules", func(i int) string { return out[i]["Id"].(string) })
+}
+
+func (s *Service) updateResolverRule(w http.ResponseWriter, params map[string]interface{}) {
+	config, _ := params["Config"].(map[string]interface{})
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r, ok := s.lookupRule(w, h.GetString(params, "ResolverRuleId"))
+	if !ok {
+		return
+	}
+	targets, endpointID := r.targetIPs, r.endpointID
+	if _, ok := config["TargetIps"]; ok {
+		var msg string
+		if targets, msg = parseTargetIPs(config["TargetIps"]); msg != "" {
+			writeError(w, "InvalidParameterException", msg)
+			return
+		}
+	}
+	if v := h.GetString(config, "ResolverEndpointId"); v != "" {
+		endpointID = v
+	}
+	if !s.checkRule(w, r.ruleType, targets, endpointID) {
+		return
+	}
+	if _, ok := config["Name"]; ok {
+		r.name = h.GetString(config, "Name")
+	}
+	r.targetIPs, r.endpointID = targets, endpointID
+	r.modified = s.now()
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{"ResolverRule": s.ruleResp(r)})
+}
+
+func (s *Service) deleteResolverRule(w http.ResponseWriter, params map[string]interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r, ok := s.lookupRule(w, h.GetString(params, "ResolverRuleId"))
+	if !ok {
+		return
+	}
+	for _, a := range s.associations {
+		if a.ruleID == r.id {
+			writeError(w, "ResourceInUseException", "Resolver rule "+r.id+" is still associated with VPC "+a.vpcID+".")
+			return
+		}
+	}
+	resp := s.ruleResp(r)
+	resp["Status"] = "DELETING"
+	delete(s.rules, r.id)
+	s.tags.Delete(r.arn)
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{"ResolverRule": resp})
+}
+
+// associationResp returns the association as GetResolverRuleAssociation
+// reports it. The caller must hold s.mu.
+func (s *Service) associationResp(a *association) map[string]interface{} {
+	status := s.transitions.Status(a.ready, "CREATING", "COMPLETE")
+	return map[string]interface{}{
+		"Id":             a.id,
+		"ResolverRuleId": a.ruleID,
+		"Name":           a.name,
+		"VPCId":          a.vpcID,
+		"Status":         status,
+		"StatusMessage":  "",
+	}
+}
+
+func (s *Service) associateResolverRule(w http.ResponseWriter, params map[string]interface{}) {
+	ruleID := h.GetString(params, "ResolverRuleId")
+	vpcID := h.GetString(params, "VPCId")
+	if netw := s.network(); netw != nil && !netw.vpcs[vpcID] {
+		writeError(w, "InvalidParameterException", "The VPC ID '"+vpcID+"' does not exist.")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r, ok := s.lookupRule(w, ruleID)
+	if !ok {
+		return
+	}
+	for _, a := range s.associations {
+		if a.vpcID != vpcID {
+			continue
+		}
+		if a.ruleID == ruleID {
+			writeError(w, "ResourceExistsException", "Resolver rule "+ruleID+" is already associated with VPC "+vpcID+".")
+			return
+		}
+		if other := s.rules[a.ruleID]; other != nil && other.domainName == r.domainName {
+			writeError(w, "InvalidRequestException", "Cannot associate rules with same domain name with same VPC. Conflict with resolver rule "+other.id+".")
+			return
+		}
+	}
+	a := &association{
+		id:     "rslvr-rrassoc-" + h.RandomHex(17),
+		ruleID: ruleID,
+		name:   h.GetString(params, "Name"),
+		vpcID:  vpcID,
+		ready:  s.transitions.Begin(),
+	}
+	s.associations[a.id] = a
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{"ResolverRuleAssociation": s.associationResp(a)})
+}
+
+func (s *Service) disassociateResolverRule(w http.ResponseWriter, params map[string]interface{}) {
+	ruleID := h.GetString(params, "ResolverRuleId")
+	vpcID := h.GetString(params, "VPCId")
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for id, a := range s.associations {
+		if a.ruleID == ruleID && a.vpcID == vpcID {
+			resp := s.associationResp(a)
+			resp["Status"] = "DELETING"
+			delete(s.associations, id)
+			h.WriteJSON(w, http.StatusOK, map[string]interface{}{"ResolverRuleAssociation": resp})
+			return
+		}
+	}
+	writeError(w, "ResourceNotFoundException", "Resolver rule "+ruleID+" is not associated with VPC "+vpcID+".")
+}
+
+func (s *Service) getResolverRuleAssociation(w http.ResponseWriter, params map[string]interface{}) {
+	id := h.GetString(params, "ResolverRuleAssociationId")
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	a, exists := s.associations[id]
+	if !exists {
+		writeError(w, "ResourceNotFoundException", "Resolver rule association with ID '"+id+"' does not exist.")
+		return
+	}
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{"ResolverRuleAssociation": s.associationResp(a)})
+}
+
+func (s *Service) listResolverRuleAssociations(w http.ResponseWriter, params map[string]interface{}) {
+	f := parseFilters(params["Filters"])
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var out []map[string]interface{}
+	for _, id := range sortedKeys(s.associations) {
+		resp := s.associationResp(s.associations[id])
+		if f.match(resp) {
+			out = append(out, resp)
+		}
+	}
+	writePage(w, out, params, "ResolverRuleAssociations", func(i int) string { return out[i]["Id"].(string) })
+}
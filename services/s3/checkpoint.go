@@ -0,0 +1,110 @@
+package s3
+
+import (
+	"time"
+
+	"github.com/riyanimam/goto/internal/cow"
+)
+
+// savedBucket is a bucket as it was at the last checkpoint.
+type savedBucket struct {
+	region  string
+	created time.Time
+	objects cow.Snapshot[string, *object]
+
+	encryption encryption
+	cors       []corsRule
+	objectLock lockConfig
+	website    *websiteConfiguration
+}
+
+// Checkpoint records the current buckets and objects so that Reset restores
+// them rather than clearing everything. Taking a checkpoint copies the
+// object index once; each restore then costs time proportional to the
+// number of buckets, not objects.
+func (s *Service) Checkpoint() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.unpinCheckpoint()
+
+	saved := make(map[string]savedBucket, len(s.buckets))
+	for name, b := range s.buckets {
+		b.objectsMu.Lock()
+		snap := b.objects.Snapshot()
+		snap.Range(func(_ string, obj *object) bool {
+			obj.pinned = true
+			return true
+		})
+		b.objectsMu.Unlock()
+		saved[name] = savedBucket{region: b.region, created: b.created, objects: snap, encryption: b.encryption, cors: b.cors, objectLock: b.objectLock, website: b.website}
+	}
+	s.checkpoint = saved
+}
+
+// ClearCheckpoint discards the checkpoint, so Reset clears everything again.
+func (s *Service) ClearCheckpoint() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.unpinCheckpoint()
+	s.checkpoint = nil
+}
+
+// unpinCheckpoint releases the checkpoint's hold on object bodies, removing
+// the files of objects that have since been overwritten or deleted. Caller
+// must hold s.mu.
+func (s *Service) unpinCheckpoint() {
+	for name, saved := range s.checkpoint {
+		live := s.buckets[name]
+		if live != nil {
+			live.objectsMu.Lock()
+		}
+		saved.objects.Range(func(key string, obj *object) bool {
+			obj.pinned = false
+			if live == nil {
+				obj.release()
+			} else if cur, ok := live.objects.Get(key); !ok || !cur.sharesBody(obj) {
+				obj.release()
+			} else {
+				cur.pinned = false
+			}
+			return true
+		})
+		if live != nil {
+			live.objectsMu.Unlock()
+		}
+	}
+}
+
+// restoreCheckpoint returns fresh buckets holding the checkpointed objects,
+// or no buckets if there is no checkpoint. Caller must hold s.mu.
+func (s *Service) restoreCheckpoint() map[string]*bucket {
+	buckets := make(map[string]*bucket, len(s.checkpoint))
+	for name, saved := range s.checkpoint {
+		buckets[name] = &bucket{
+			name:    name,
+			region:  saved.region,
+			created: saved.created,
+			objects: cow.FromSnapshot(saved.objects),
+
+			encryption: saved.encryption,
+			cors:       saved.cors,
+			objectLock: saved.objectLock,
+			website:    saved.website,
+		}
+	}
+	return buckets
+}
+
+// release removes the object's spilled body unless a checkpoint holds it.
+// Caller must hold the bucket's objectsMu.
+func (o *object) release() {
+	if !o.pinned {
+		o.body.discard()
+	}
+}
+
+// sharesBody reports whether o is obj or a copy of it made by
+// [bucket.own], holding the same body.
+func (o *object) sharesBody(obj *object) bool {
+	return o == obj || (o.body.path != "" && o.body.path == obj.body.path)
+}
@@ -0,0 +1,104 @@
+package s3
+
+import "time"
+
+// staleListing is what listings showed for a key before it was last written,
+// kept until the write has propagated.
+type staleListing struct {
+	obj   *object // nil if the key was absent
+	until time.Time
+}
+
+// SetListDelay makes object writes and deletes take d to show up in
+// ListObjectsV2, as they can on eventually consistent storage. Reads of
+// individual objects are unaffected. Zero restores read-after-write listings.
+func (s *Service) SetListDelay(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.listDelay = d
+}
+
+func (s *Service) currentListDelay() time.Duration {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.listDelay
+}
+
+// put stores obj under key, keeping the previous listing for delay. It
+// reports false, storing nothing, if guard protects the object already
+// stored there.
+func (b *bucket) put(key string, obj *object, delay time.Duration, guard lockGuard) bool {
+	b.objectsMu.Lock()
+	defer b.objectsMu.Unlock()
+	old, ok := b.objects.Get(key)
+	if ok && guard.protects(old.lock) {
+		return false
+	}
+	b.noteChange(key, delay)
+	if ok {
+		old.release()
+	}
+	b.objects.Set(key, obj)
+	return true
+}
+
+// remove deletes key, keeping the previous listing for delay. It reports
+// false, deleting nothing, if guard protects the object stored there.
+func (b *bucket) remove(key string, delay time.Duration, guard lockGuard) bool {
+	b.objectsMu.Lock()
+	defer b.objectsMu.Unlock()
+	old, ok := b.objects.Get(key)
+	if ok && guard.protects(old.lock) {
+		return false
+	}
+	b.noteChange(key, delay)
+	if ok {
+		old.release()
+	}
+	b.objects.Delete(key)
+	return true
+}
+
+// noteChange records the listing for key before a write. Repeated writes
+// within the window extend it but keep the original listing. Caller must
+// hold b.objectsMu.
+func (b *bucket) noteChange(key string, delay time.Duration) {
+	if delay <= 0 {
+		return
+	}
+	now := time.Now()
+	if b.stale == nil {
+		b.stale = make(map[string]staleListing)
+	}
+	for k, st := range b.stale {
+		if !now.Before(st.until) {
+			delete(b.stale, k)
+		}
+	}
+	if st, ok := b.stale[key]; ok {
+		st.until = now.Add(delay)
+		b.stale[key] = st
+		return
+	}
+	prev, _ := b.objects.Get(key)
+	b.stale[key] = staleListing{obj: prev, until: now.Add(delay)}
+}
+
+// listed returns the objects a listing shows right now. Caller must hold
+// b.objectsMu.
+func (b *bucket) listed() []*object {
+	now := time.Now()
+	objs := make([]*object, 0, b.objects.Len())
+	b.objects.Range(func(key string, obj *object) bool {
+		if st, ok := b.stale[key]; !ok || !now.Before(st.until) {
+			objs = append(objs, obj)
+		}
+		return true
+	})
+	for _, st := range b.stale {
+		if st.obj != nil && now.Before(st.until) {
+			objs = append(objs, st.obj)
+		}
+	}
+	return objs
+}
@@ -0,0 +1,230 @@
+package s3
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// maxCORSRules is the number of rules a bucket's CORS configuration can
+// hold.
+const maxCORSRules = 100
+
+const corsNotAllowed = "CORSResponse: This CORS request is not allowed. This is usually because the evalution of Origin, request method / Access-Control-Request-Method or Access-Control-Request-Headers are not whitelisted by the resource's CORS spec."
+
+type corsConfiguration struct {
+	XMLName xml.Name   `xml:"CORSConfiguration"`
+	XMLNS   string     `xml:"xmlns,attr,omitempty"`
+	Rules   []corsRule `xml:"CORSRule"`
+}
+
+type corsRule struct {
+	ID             string   `xml:"ID,omitempty"`
+	AllowedHeaders []string `xml:"AllowedHeader"`
+	AllowedMethods []string `xml:"AllowedMethod"`
+	AllowedOrigins []string `xml:"AllowedOrigin"`
+	ExposeHeaders  []string `xml:"ExposeHeader"`
+	MaxAgeSeconds  *int     `xml:"MaxAgeSeconds"`
+}
+
+// bucketCORS serves PutBucketCors, GetBucketCors, and DeleteBucketCors on
+// /bucket?cors.
+func (s *Service) bucketCORS(w http.ResponseWriter, r *http.Request, name string) {
+	var req corsConfiguration
+	if r.Method == http.MethodPut {
+		if err := xml.NewDecoder(r.Body).Decode(&req); err != nil || len(req.Rules) == 0 {
+			writeS3Error(w, "MalformedXML", "The XML you provided was not well-formed or did not validate against our published schema", http.StatusBadRequest)
+			return
+		}
+		if !checkCORSRules(w, req.Rules) {
+			return
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	b, exists := s.buckets[name]
+	if !exists {
+		writeS3Error(w, "NoSuchBucket", "The specified bucket does not exist", http.StatusNotFound)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPut:
+		b.cors = req.Rules
+		w.WriteHeader(http.StatusOK)
+	case http.MethodDelete:
+		b.cors = nil
+		w.WriteHeader(http.StatusNoContent)
+	case http.MethodGet:
+		if len(b.cors) == 0 {
+			writeS3Error(w, "NoSuchCORSConfiguration", "The CORS configuration does not exist", http.StatusNotFound)
+			return
+		}
+		writeXML(w, http.StatusOK, corsConfiguration{
+			XMLNS: "http://s3.amazonaws.com/doc/2006-03-01/",
+			Rules: b.cors,
+		})
+	default:
+		writeS3Error(w, "MethodNotAllowed", "The specified method is not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// checkCORSRules writes an error and returns false unless every rule allows
+// at least one origin and only methods S3 supports.
+func checkCORSRules(w http.ResponseWriter, rules []corsRule) bool {
+	if len(rules) > maxCORSRules {
+		writeS3Error(w, "InvalidRequest", fmt.Sprintf("The CORS configuration can contain at most %d rules", maxCORSRules), http.StatusBadRequest)
+		return false
+	}
+	for _, rule := range rules {
+		if len(rule.AllowedOrigins) == 0 || len(rule.AllowedMethods) == 0 {
+			writeS3Error(w, "MalformedXML", "The XML you provided was not well-formed or did not validate against our published schema", http.StatusBadRequest)
+			return false
+		}
+		for _, m := range rule.AllowedMethods {
+			switch m {
+			case http.MethodGet, http.MethodPut, http.MethodPost, http.MethodDelete, http.MethodHead:
+			default:
+				writeS3Error(w, "InvalidRequest", "Found unsupported HTTP method in CORS config. Unsupported method is "+m, http.StatusBadRequest)
+				return false
+			}
+		}
+		for _, o := range rule.AllowedOrigins {
+			if strings.Count(o, "*") > 1 {
+				writeS3Error(w, "InvalidRequest", "AllowedOrigin \""+o+"\" can not have more than one wildcard.", http.StatusBadRequest)
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// matchingCORSRule returns the first of the bucket's CORS rules that allows
+// a request from origin using method with headers, or nil. The caller must
+// hold s.mu.
+func matchingCORSRule(rules []corsRule, origin, method string, headers []string) *corsRule {
+	for i, rule := range rules {
+		if !anyWildcardMatch(rule.AllowedOrigins, origin, false) || !contains(rule.AllowedMethods, method) {
+			continue
+		}
+		allowed := true
+		for _, h := range headers {
+			if !anyWildcardMatch(rule.AllowedHeaders, h, true) {
+				allowed = false
+				break
+			}
+		}
+		if allowed {
+			return &rules[i]
+		}
+	}
+	return nil
+}
+
+// preflight answers a browser's OPTIONS preflight request from the bucket's
+// CORS configuration.
+func (s *Service) preflight(w http.ResponseWriter, r *http.Request, name string) {
+	origin := r.Header.Get("Origin")
+	method := r.Header.Get("Access-Control-Request-Method")
+	if origin == "" {
+		writeS3Error(w, "BadRequest", "Insufficient information. Origin request header needed.", http.StatusBadRequest)
+		return
+	}
+	if method == "" {
+		writeS3Error(w, "BadRequest", "Invalid Access-Control-Request-Method: null", http.StatusBadRequest)
+		return
+	}
+	var headers []string
+	for _, h := range strings.Split(r.Header.Get("Access-Control-Request-Headers"), ",") {
+		if h = strings.TrimSpace(h); h != "" {
+			headers = append(headers, strings.ToLower(h))
+		}
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	b, exists := s.buckets[name]
+	if !exists {
+		writeS3Error(w, "NoSuchBucket", "The specified bucket does not exist", http.StatusNotFound)
+		return
+	}
+	if len(b.cors) == 0 {
+		writeS3Error(w, "AccessForbidden", "CORSResponse: CORS is not enabled for this bucket.", http.StatusForbidden)
+		return
+	}
+	rule := matchingCORSRule(b.cors, origin, method, headers)
+	if rule == nil {
+		writeS3Error(w, "AccessForbidden", corsNotAllowed, http.StatusForbidden)
+		return
+	}
+	setCORSHeaders(w, rule, origin)
+	if len(headers) > 0 {
+		w.Header().Set("Access-Control-Allow-Headers", strings.Join(headers, ", "))
+	}
+	w.Header().Set("Vary", "Origin, Access-Control-Request-Headers, Access-Control-Request-Method")
+	w.WriteHeader(http.StatusOK)
+}
+
+// applyCORS adds the Access-Control headers of the matching CORS rule to
+// the response to a cross-origin request, as S3 does for requests carrying
+// an Origin header.
+func (s *Service) applyCORS(w http.ResponseWriter, r *http.Request, name string) {
+	origin := r.Header.Get("Origin")
+	if origin == "" || name == "" {
+		return
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	b, exists := s.buckets[name]
+	if !exists {
+		return
+	}
+	if rule := matchingCORSRule(b.cors, origin, r.Method, nil); rule != nil {
+		setCORSHeaders(w, rule, origin)
+		w.Header().Set("Vary", "Origin, Access-Control-Request-Headers, Access-Control-Request-Method")
+	}
+}
+
+func setCORSHeaders(w http.ResponseWriter, rule *corsRule, origin string) {
+	if contains(rule.AllowedOrigins, "*") {
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+	} else {
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+		w.Header().Set("Access-Control-Allow-Credentials", "true")
+	}
+	w.Header().Set("Access-Control-Allow-Methods", strings.Join(rule.AllowedMethods, ", "))
+	if len(rule.ExposeHeaders) > 0 {
+		w.Header().Set("Access-Control-Expose-Headers", strings.Join(rule.ExposeHeaders, ", "))
+	}
+	if rule.MaxAgeSeconds != nil {
+		w.Header().Set("Access-Control-Max-Age", strconv.Itoa(*rule.MaxAgeSeconds))
+	}
+}
+
+// anyWildcardMatch reports whether v matches one of patterns, each of which
+// may contain a single * wildcard.
+func anyWildcardMatch(patterns []string, v string, foldCase bool) bool {
+	for _, p := range patterns {
+		if foldCase {
+			p, v = strings.ToLower(p), strings.ToLower(v)
+		}
+		prefix, suffix, wildcard := strings.Cut(p, "*")
+		if !wildcard && p == v ||
+			wildcard && len(v) >= len(prefix)+len(suffix) && strings.HasPrefix(v, prefix) && strings.HasSuffix(v, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+func contains(values []string, v string) bool {
+	for _, s := range values {
+		if s == v {
+			return true
+		}
+	}
+	return false
+}
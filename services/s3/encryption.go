@@ -0,0 +1,180 @@
+package s3
+
+import (
+	"encoding/xml"
+	"net/http"
+	"strings"
+
+	"github.com/riyanimam/goto/internal/mockhelpers"
+)
+
+const defaultAccountID = "123456789012"
+
+// encryption is how an object is encrypted at rest, or how a bucket
+// encrypts objects that do not ask for anything else.
+type encryption struct {
+	algorithm string // AES256, aws:kms, or aws:kms:dsse
+	kmsKeyID  string // key ARN; empty for AES256 and the AWS managed key
+	bucketKey bool
+}
+
+// defaultEncryption is the SSE-S3 encryption S3 applies to every bucket
+// without a configuration of its own.
+var defaultEncryption = encryption{algorithm: "AES256"}
+
+// SetResolver sets the function used to check that KMS keys named for
+// encryption exist.
+func (s *Service) SetResolver(r mockhelpers.Resolver) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.resolve = r
+}
+
+// kmsKeyARN returns the ARN of the KMS key named by id, a key ID, alias
+// name, or ARN, writing an error and returning false if the key does not
+// exist.
+func (s *Service) kmsKeyARN(w http.ResponseWriter, id string) (string, bool) {
+	arn := id
+	switch {
+	case strings.HasPrefix(id, "arn:"):
+	case strings.HasPrefix(id, "alias/"):
+		arn = "arn:aws:kms:us-east-1:" + defaultAccountID + ":" + id
+	default:
+		arn = "arn:aws:kms:us-east-1:" + defaultAccountID + ":key/" + id
+	}
+
+	s.mu.RLock()
+	resolve := s.resolve
+	s.mu.RUnlock()
+	if resolve != nil && resolve(arn) != nil {
+		writeS3Error(w, "KMS.NotFoundException", "Invalid keyId "+id, http.StatusBadRequest)
+		return "", false
+	}
+	return arn, true
+}
+
+// checkEncryption writes an error and returns false unless algorithm and
+// keyID make a valid combination. On success keyID is replaced by the ARN
+// of the key.
+func (s *Service) checkEncryption(w http.ResponseWriter, e *encryption) bool {
+	switch e.algorithm {
+	case "AES256":
+		if e.kmsKeyID != "" {
+			writeS3Error(w, "InvalidArgument", "Server Side Encryption with AWS KMS managed key requires HTTP header x-amz-server-side-encryption : aws:kms", http.StatusBadRequest)
+			return false
+		}
+	case "aws:kms", "aws:kms:dsse":
+		if e.kmsKeyID != "" {
+			arn, ok := s.kmsKeyARN(w, e.kmsKeyID)
+			if !ok {
+				return false
+			}
+			e.kmsKeyID = arn
+		}
+	default:
+		writeS3Error(w, "InvalidArgument", "The encryption method specified is not supported", http.StatusBadRequest)
+		return false
+	}
+	return true
+}
+
+// requestEncryption returns the encryption a PutObject or CopyObject request
+// asks for with its x-amz-server-side-encryption headers, or the bucket's
+// default encryption if it asks for none.
+func (s *Service) requestEncryption(w http.ResponseWriter, r *http.Request, b *bucket) (encryption, bool) {
+	e := encryption{
+		algorithm: r.Header.Get("X-Amz-Server-Side-Encryption"),
+		kmsKeyID:  r.Header.Get("X-Amz-Server-Side-Encryption-Aws-Kms-Key-Id"),
+		bucketKey: r.Header.Get("X-Amz-Server-Side-Encryption-Bucket-Key-Enabled") == "true",
+	}
+	if e.algorithm == "" && e.kmsKeyID == "" {
+		s.mu.RLock()
+		defer s.mu.RUnlock()
+		return b.encryption, true
+	}
+	if e.algorithm == "" {
+		e.algorithm = "AES256"
+	}
+	return e, s.checkEncryption(w, &e)
+}
+
+// setEncryptionHeaders reports how an object is encrypted in the headers of
+// a response.
+func setEncryptionHeaders(w http.ResponseWriter, e encryption) {
+	w.Header().Set("X-Amz-Server-Side-Encryption", e.algorithm)
+	if e.kmsKeyID != "" {
+		w.Header().Set("X-Amz-Server-Side-Encryption-Aws-Kms-Key-Id", e.kmsKeyID)
+	}
+	if e.bucketKey {
+		w.Header().Set("X-Amz-Server-Side-Encryption-Bucket-Key-Enabled", "true")
+	}
+}
+
+// bucketEncryption serves PutBucketEncryption, GetBucketEncryption, and
+// DeleteBucketEncryption on /bucket?encryption. Deleting the configuration
+// restores the SSE-S3 default.
+func (s *Service) bucketEncryption(w http.ResponseWriter, r *http.Request, name string) {
+	var e encryption
+	if r.Method == http.MethodPut {
+		var req serverSideEncryptionConfiguration
+		if err := xml.NewDecoder(r.Body).Decode(&req); err != nil || len(req.Rules) != 1 || req.Rules[0].Default == nil {
+			writeS3Error(w, "MalformedXML", "The XML you provided was not well-formed or did not validate against our published schema", http.StatusBadRequest)
+			return
+		}
+		rule := req.Rules[0]
+		e = encryption{
+			algorithm: rule.Default.SSEAlgorithm,
+			kmsKeyID:  rule.Default.KMSMasterKeyID,
+			bucketKey: rule.BucketKeyEnabled,
+		}
+		if !s.checkEncryption(w, &e) {
+			return
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	b, exists := s.buckets[name]
+	if !exists {
+		writeS3Error(w, "NoSuchBucket", "The specified bucket does not exist", http.StatusNotFound)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPut:
+		b.encryption = e
+		w.WriteHeader(http.StatusOK)
+	case http.MethodDelete:
+		b.encryption = defaultEncryption
+		w.WriteHeader(http.StatusNoContent)
+	case http.MethodGet:
+		writeXML(w, http.StatusOK, serverSideEncryptionConfiguration{
+			XMLNS: "http://s3.amazonaws.com/doc/2006-03-01/",
+			Rules: []sseRule{{
+				Default: &sseDefault{
+					SSEAlgorithm:   b.encryption.algorithm,
+					KMSMasterKeyID: b.encryption.kmsKeyID,
+				},
+				BucketKeyEnabled: b.encryption.bucketKey,
+			}},
+		})
+	default:
+		writeS3Error(w, "MethodNotAllowed", "The specified method is not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+type serverSideEncryptionConfiguration struct {
+	XMLName xml.Name  `xml:"ServerSideEncryptionConfiguration"`
+	XMLNS   string    `xml:"xmlns,attr,omitempty"`
+	Rules   []sseRule `xml:"Rule"`
+}
+
+type sseRule struct {
+	Default          *sseDefault `xml:"ApplyServerSideEncryptionByDefault"`
+	BucketKeyEnabled bool        `xml:"BucketKeyEnabled"`
+}
+
+type sseDefault struct {
+	SSEAlgorithm   string `xml:"SSEAlgorithm"`
+	KMSMasterKeyID string `xml:"KMSMasterKeyID,omitempty"`
+}
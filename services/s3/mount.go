@@ -0,0 +1,97 @@
+package s3
+
+import (
+	"crypto/md5"
+	"fmt"
+	"io/fs"
+	"mime"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/riyanimam/goto/internal/cow"
+)
+
+// Mount makes the files under dir the initial contents of bucket, creating
+// the bucket if needed. Files are indexed now and read only when an object
+// is fetched, so large fixture trees cost nothing until used; the key of
+// each object is its path relative to dir. Objects written over or deleted
+// leave the files alone, and Reset mounts the directory again.
+//
+// ETags of mounted objects are derived from each file's size and
+// modification time rather than its contents, which are not read up front.
+func (s *Service) Mount(bucket, dir string) error {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("s3 mount %s: not a directory", dir)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.mounts == nil {
+		s.mounts = make(map[string]string)
+	}
+	s.mounts[bucket] = dir
+	return s.mount(bucket, dir)
+}
+
+// mountAll mounts the directories of buckets that do not exist, as after a
+// reset. Caller must hold s.mu.
+func (s *Service) mountAll() {
+	for bucket, dir := range s.mounts {
+		if _, exists := s.buckets[bucket]; !exists {
+			s.mount(bucket, dir)
+		}
+	}
+}
+
+// mount indexes dir into bucket. Caller must hold s.mu.
+func (s *Service) mount(bucketName, dir string) error {
+	b, exists := s.buckets[bucketName]
+	if !exists {
+		b = &bucket{
+			name:    bucketName,
+			region:  "us-east-1",
+			created: time.Now().UTC(),
+			objects: cow.New[string, *object](),
+
+			encryption: defaultEncryption,
+		}
+		s.buckets[bucketName] = b
+	}
+
+	b.objectsMu.Lock()
+	defer b.objectsMu.Unlock()
+	return filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || !d.Type().IsRegular() {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		key := filepath.ToSlash(rel)
+		contentType := mime.TypeByExtension(filepath.Ext(path))
+		if contentType == "" {
+			contentType = "binary/octet-stream"
+		}
+		stamp := md5.Sum([]byte(key + "\x00" + strconv.FormatInt(info.Size(), 10) + "\x00" + info.ModTime().UTC().String()))
+		b.objects.Set(key, &object{
+			key:          key,
+			body:         content{path: path, size: info.Size(), etag: etagOf(stamp[:]), mounted: true},
+			contentType:  contentType,
+			lastModified: info.ModTime().UTC(),
+			metadata:     map[string]string{},
+			encryption:   b.encryption,
+		})
+		return nil
+	})
+}
@@ -0,0 +1,449 @@
+package s3
+
+import (
+	"encoding/xml"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/riyanimam/goto/internal/clock"
+)
+
+// maxDeleteObjects is the number of keys one DeleteObjects request can name.
+const maxDeleteObjects = 1000
+
+// ErrObjectLocked is returned by DeleteObject and PutObject when object
+// lock protects the object stored under the key.
+var ErrObjectLocked = errors.New("AccessDenied: access denied because object protected by object lock")
+
+const objectLockedMessage = "Access Denied because object protected by object lock."
+
+// lockConfig is a bucket's object lock configuration. Days and years are
+// the default retention of new objects; at most one of them is set.
+type lockConfig struct {
+	enabled bool
+	mode    string // GOVERNANCE or COMPLIANCE, or "" for no default retention
+	days    int
+	years   int
+}
+
+// lockState is an object's retention and legal hold.
+type lockState struct {
+	mode      string // GOVERNANCE or COMPLIANCE, if retained
+	until     time.Time
+	legalHold bool
+}
+
+// lockGuard decides whether object lock protects an object from being
+// overwritten or deleted at a given time.
+type lockGuard struct {
+	now    time.Time
+	bypass bool // x-amz-bypass-governance-retention was set
+}
+
+// protects reports whether an object locked by l may not be overwritten or
+// deleted: it is under legal hold, under compliance retention, or under
+// governance retention that the request does not bypass.
+func (g lockGuard) protects(l lockState) bool {
+	if l.legalHold {
+		return true
+	}
+	if !g.now.Before(l.until) {
+		return false
+	}
+	return l.mode == "COMPLIANCE" || !g.bypass
+}
+
+// SetClock attaches the mock clock that object lock retention runs on.
+func (s *Service) SetClock(c *clock.Clock) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.clock = c
+}
+
+func (s *Service) now() time.Time {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.clock != nil {
+		return s.clock.Now()
+	}
+	return time.Now().UTC()
+}
+
+// advancedSince returns how far the mock clock has been advanced since the
+// wall time t.
+func (s *Service) advancedSince(t time.Time) time.Duration {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.clock != nil {
+		return s.clock.AdvancedSince(t)
+	}
+	return 0
+}
+
+// retain applies the bucket's default retention, if any, to an object
+// stored at now.
+func (cfg lockConfig) retain(obj *object, now time.Time) {
+	if cfg.mode != "" {
+		obj.lock.mode = cfg.mode
+		obj.lock.until = now.AddDate(cfg.years, 0, cfg.days)
+	}
+}
+
+// guard returns the lock guard for a request, or for direct access if r is
+// nil.
+func (s *Service) guard(r *http.Request) lockGuard {
+	g := lockGuard{now: s.now()}
+	if r != nil {
+		g.bypass = r.Header.Get("X-Amz-Bypass-Governance-Retention") == "true"
+	}
+	return g
+}
+
+// requestLock sets the retention and legal hold a PutObject or CopyObject
+// request asks for with its x-amz-object-lock headers on obj, applying the
+// bucket's default retention if it asks for none. It writes an error and
+// returns false if the headers are invalid.
+func (s *Service) requestLock(w http.ResponseWriter, r *http.Request, b *bucket, obj *object) bool {
+	mode := r.Header.Get("X-Amz-Object-Lock-Mode")
+	until := r.Header.Get("X-Amz-Object-Lock-Retain-Until-Date")
+	hold := r.Header.Get("X-Amz-Object-Lock-Legal-Hold")
+
+	s.mu.RLock()
+	cfg := b.objectLock
+	s.mu.RUnlock()
+	if !cfg.enabled {
+		if mode != "" || until != "" || hold != "" {
+			writeS3Error(w, "InvalidRequest", "Bucket is missing Object Lock Configuration", http.StatusBadRequest)
+			return false
+		}
+		return true
+	}
+
+	switch hold {
+	case "", "OFF":
+	case "ON":
+		obj.lock.legalHold = true
+	default:
+		writeS3Error(w, "InvalidArgument", "Legal Hold must be either of 'ON' or 'OFF'", http.StatusBadRequest)
+		return false
+	}
+	if mode == "" && until == "" {
+		cfg.retain(obj, s.now())
+		return true
+	}
+	if mode == "" || until == "" {
+		writeS3Error(w, "InvalidArgument", "x-amz-object-lock-retain-until-date and x-amz-object-lock-mode must both be supplied", http.StatusBadRequest)
+		return false
+	}
+	t, ok := s.retention(w, mode, until)
+	if !ok {
+		return false
+	}
+	obj.lock.mode, obj.lock.until = mode, t
+	return true
+}
+
+// retention parses and checks a retention mode and date, writing an error
+// and returning false if they are invalid or the date has passed.
+func (s *Service) retention(w http.ResponseWriter, mode, until string) (time.Time, bool) {
+	if mode != "GOVERNANCE" && mode != "COMPLIANCE" {
+		writeS3Error(w, "InvalidArgument", "Unknown wormMode directive.", http.StatusBadRequest)
+		return time.Time{}, false
+	}
+	t, err := time.Parse(time.RFC3339, until)
+	if err != nil {
+		writeS3Error(w, "InvalidArgument", "The retain until date is not valid", http.StatusBadRequest)
+		return time.Time{}, false
+	}
+	if !t.After(s.now()) {
+		writeS3Error(w, "InvalidArgument", "The retain until date must be in the future!", http.StatusBadRequest)
+		return time.Time{}, false
+	}
+	return t.UTC(), true
+}
+
+// setLockHeaders reports an object's retention and legal hold in the
+// headers of a response.
+func setLockHeaders(w http.ResponseWriter, l lockState) {
+	if l.mode != "" {
+		w.Header().Set("X-Amz-Object-Lock-Mode", l.mode)
+		w.Header().Set("X-Amz-Object-Lock-Retain-Until-Date", l.until.Format(time.RFC3339))
+	}
+	if l.legalHold {
+		w.Header().Set("X-Amz-Object-Lock-Legal-Hold", "ON")
+	}
+}
+
+// bucketObjectLock serves PutObjectLockConfiguration and
+// GetObjectLockConfiguration on /bucket?object-lock. Object lock cannot be
+// disabled once enabled.
+func (s *Service) bucketObjectLock(w http.ResponseWriter, r *http.Request, name string) {
+	var cfg lockConfig
+	if r.Method == http.MethodPut {
+		var req objectLockConfiguration
+		if err := xml.NewDecoder(r.Body).Decode(&req); err != nil || req.ObjectLockEnabled != "Enabled" {
+			writeS3Error(w, "MalformedXML", "The XML you provided was not well-formed or did not validate against our published schema", http.StatusBadRequest)
+			return
+		}
+		cfg.enabled = true
+		if req.Rule != nil {
+			d := req.Rule.DefaultRetention
+			if (d.Days > 0) == (d.Years > 0) || d.Days < 0 || d.Years < 0 {
+				writeS3Error(w, "MalformedXML", "The XML you provided was not well-formed or did not validate against our published schema", http.StatusBadRequest)
+				return
+			}
+			if d.Mode != "GOVERNANCE" && d.Mode != "COMPLIANCE" {
+				writeS3Error(w, "MalformedXML", "The XML you provided was not well-formed or did not validate against our published schema", http.StatusBadRequest)
+				return
+			}
+			cfg.mode, cfg.days, cfg.years = d.Mode, d.Days, d.Years
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	b, exists := s.buckets[name]
+	if !exists {
+		writeS3Error(w, "NoSuchBucket", "The specified bucket does not exist", http.StatusNotFound)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPut:
+		b.objectLock = cfg
+		w.WriteHeader(http.StatusOK)
+	case http.MethodGet:
+		if !b.objectLock.enabled {
+			writeS3Error(w, "ObjectLockConfigurationNotFoundError", "Object Lock configuration does not exist for this bucket", http.StatusNotFound)
+			return
+		}
+		resp := objectLockConfiguration{
+			XMLNS:             "http://s3.amazonaws.com/doc/2006-03-01/",
+			ObjectLockEnabled: "Enabled",
+		}
+		if b.objectLock.mode != "" {
+			resp.Rule = &objectLockRule{DefaultRetention: defaultRetention{
+				Mode:  b.objectLock.mode,
+				Days:  b.objectLock.days,
+				Years: b.objectLock.years,
+			}}
+		}
+		writeXML(w, http.StatusOK, resp)
+	default:
+		writeS3Error(w, "MethodNotAllowed", "The specified method is not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// lockedObject returns the bucket and the object stored under key, writing
+// an error and returning nil if either does not exist or the bucket does not
+// have object lock enabled.
+func (s *Service) lockedObject(w http.ResponseWriter, bucketName, key string) (*bucket, *object) {
+	s.mu.RLock()
+	b, exists := s.buckets[bucketName]
+	enabled := exists && b.objectLock.enabled
+	s.mu.RUnlock()
+	if !exists {
+		writeS3Error(w, "NoSuchBucket", "The specified bucket does not exist", http.StatusNotFound)
+		return nil, nil
+	}
+	if !enabled {
+		writeS3Error(w, "InvalidRequest", "Bucket is missing Object Lock Configuration", http.StatusBadRequest)
+		return nil, nil
+	}
+
+	b.objectsMu.RLock()
+	obj, exists := b.objects.Get(key)
+	b.objectsMu.RUnlock()
+	if !exists {
+		writeS3Error(w, "NoSuchKey", "The specified key does not exist.", http.StatusNotFound)
+		return nil, nil
+	}
+	return b, obj
+}
+
+// own returns obj, the object stored under key, for changing in place. If a
+// checkpoint shares obj, it stores and returns a copy instead, sharing the
+// body, so the change does not reach the checkpoint. Caller must hold
+// objectsMu.
+func (b *bucket) own(key string, obj *object) *object {
+	if b.objects.Owned(key) {
+		return obj
+	}
+	clone := *obj
+	b.objects.Set(key, &clone)
+	return &clone
+}
+
+// objectRetention serves PutObjectRetention and GetObjectRetention on
+// /bucket/key?retention. Compliance retention can only be extended;
+// governance retention can be shortened or removed only by requests that
+// bypass it.
+func (s *Service) objectRetention(w http.ResponseWriter, r *http.Request, bucketName, key string) {
+	b, obj := s.lockedObject(w, bucketName, key)
+	if obj == nil {
+		return
+	}
+
+	if r.Method == http.MethodGet {
+		b.objectsMu.RLock()
+		l := obj.lock
+		b.objectsMu.RUnlock()
+		if l.mode == "" {
+			writeS3Error(w, "NoSuchObjectLockConfiguration", "The specified object does not have a ObjectLock configuration", http.StatusNotFound)
+			return
+		}
+		writeXML(w, http.StatusOK, objectRetention{
+			XMLNS:           "http://s3.amazonaws.com/doc/2006-03-01/",
+			Mode:            l.mode,
+			RetainUntilDate: l.until.Format(time.RFC3339),
+		})
+		return
+	}
+
+	var req objectRetention
+	if err := xml.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeS3Error(w, "MalformedXML", "The XML you provided was not well-formed or did not validate against our published schema", http.StatusBadRequest)
+		return
+	}
+	var until time.Time
+	if req.Mode != "" || req.RetainUntilDate != "" {
+		var ok bool
+		if until, ok = s.retention(w, req.Mode, req.RetainUntilDate); !ok {
+			return
+		}
+	}
+
+	guard := s.guard(r)
+	b.objectsMu.Lock()
+	defer b.objectsMu.Unlock()
+	obj = b.own(key, obj)
+	if guard.now.Before(obj.lock.until) {
+		weakened := req.Mode != obj.lock.mode || until.Before(obj.lock.until)
+		if weakened && (obj.lock.mode == "COMPLIANCE" || !guard.bypass) {
+			writeS3Error(w, "AccessDenied", objectLockedMessage, http.StatusForbidden)
+			return
+		}
+	}
+	obj.lock.mode, obj.lock.until = req.Mode, until
+	w.WriteHeader(http.StatusOK)
+}
+
+// objectLegalHold serves PutObjectLegalHold and GetObjectLegalHold on
+// /bucket/key?legal-hold.
+func (s *Service) objectLegalHold(w http.ResponseWriter, r *http.Request, bucketName, key string) {
+	b, obj := s.lockedObject(w, bucketName, key)
+	if obj == nil {
+		return
+	}
+
+	if r.Method == http.MethodGet {
+		b.objectsMu.RLock()
+		status := "OFF"
+		if obj.lock.legalHold {
+			status = "ON"
+		}
+		b.objectsMu.RUnlock()
+		writeXML(w, http.StatusOK, legalHold{
+			XMLNS:  "http://s3.amazonaws.com/doc/2006-03-01/",
+			Status: status,
+		})
+		return
+	}
+
+	var req legalHold
+	if err := xml.NewDecoder(r.Body).Decode(&req); err != nil || (req.Status != "ON" && req.Status != "OFF") {
+		writeS3Error(w, "MalformedXML", "The XML you provided was not well-formed or did not validate against our published schema", http.StatusBadRequest)
+		return
+	}
+	b.objectsMu.Lock()
+	obj = b.own(key, obj)
+	obj.lock.legalHold = req.Status == "ON"
+	b.objectsMu.Unlock()
+	w.WriteHeader(http.StatusOK)
+}
+
+// deleteObjects serves DeleteObjects on POST /bucket?delete, reporting the
+// outcome for each key. Missing keys count as deleted, as in S3; keys that
+// object lock protects fail with AccessDenied.
+func (s *Service) deleteObjects(w http.ResponseWriter, r *http.Request, bucketName string) {
+	s.mu.RLock()
+	b, exists := s.buckets[bucketName]
+	s.mu.RUnlock()
+	if !exists {
+		writeS3Error(w, "NoSuchBucket", "The specified bucket does not exist", http.StatusNotFound)
+		return
+	}
+
+	var req deleteRequest
+	if err := xml.NewDecoder(r.Body).Decode(&req); err != nil || len(req.Objects) == 0 || len(req.Objects) > maxDeleteObjects {
+		writeS3Error(w, "MalformedXML", "The XML you provided was not well-formed or did not validate against our published schema", http.StatusBadRequest)
+		return
+	}
+
+	guard, delay := s.guard(r), s.currentListDelay()
+	resp := deleteResult{XMLNS: "http://s3.amazonaws.com/doc/2006-03-01/"}
+	for _, o := range req.Objects {
+		if !b.remove(o.Key, delay, guard) {
+			resp.Errors = append(resp.Errors, deleteError{Key: o.Key, Code: "AccessDenied", Message: objectLockedMessage})
+		} else if !req.Quiet {
+			resp.Deleted = append(resp.Deleted, deletedObject{Key: o.Key})
+		}
+	}
+	writeXML(w, http.StatusOK, resp)
+}
+
+type objectLockConfiguration struct {
+	XMLName           xml.Name        `xml:"ObjectLockConfiguration"`
+	XMLNS             string          `xml:"xmlns,attr,omitempty"`
+	ObjectLockEnabled string          `xml:"ObjectLockEnabled"`
+	Rule              *objectLockRule `xml:"Rule"`
+}
+
+type objectLockRule struct {
+	DefaultRetention defaultRetention `xml:"DefaultRetention"`
+}
+
+type defaultRetention struct {
+	Mode  string `xml:"Mode"`
+	Days  int    `xml:"Days,omitempty"`
+	Years int    `xml:"Years,omitempty"`
+}
+
+type objectRetention struct {
+	XMLName         xml.Name `xml:"Retention"`
+	XMLNS           string   `xml:"xmlns,attr,omitempty"`
+	Mode            string   `xml:"Mode,omitempty"`
+	RetainUntilDate string   `xml:"RetainUntilDate,omitempty"`
+}
+
+type legalHold struct {
+	XMLName xml.Name `xml:"LegalHold"`
+	XMLNS   string   `xml:"xmlns,attr,omitempty"`
+	Status  string   `xml:"Status"`
+}
+
+type deleteRequest struct {
+	XMLName xml.Name `xml:"Delete"`
+	Quiet   bool     `xml:"Quiet"`
+	Objects []struct {
+		Key string `xml:"Key"`
+	} `xml:"Object"`
+}
+
+type deleteResult struct {
+	XMLName xml.Name        `xml:"DeleteResult"`
+	XMLNS   string          `xml:"xmlns,attr"`
+	Deleted []deletedObject `xml:"Deleted"`
+	Errors  []deleteError   `xml:"Error"`
+}
+
+type deletedObject struct {
+	Key string `xml:"Key"`
+}
+
+type deleteError struct {
+	Key     string `xml:"Key"`
+	Code    string `xml:"Code"`
+	Message string `xml:"Message"`
+}
@@ -0,0 +1,104 @@
+package s3
+
+import (
+	"net/http"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxPresignExpiry is the longest a presigned URL can be valid for.
+const maxPresignExpiry = 7 * 24 * time.Hour
+
+// presignClockSkew is how far in the future a presigned URL's signing time
+// can be before S3 treats it as not yet valid.
+const presignClockSkew = 15 * time.Minute
+
+const signatureMismatch = "The request signature we calculated does not match the signature you provided. Check your key and signing method."
+
+// presignParams are the query parameters of query-string (presigned)
+// Signature Version 4 authentication.
+var presignParams = []string{"X-Amz-Algorithm", "X-Amz-Credential", "X-Amz-Date", "X-Amz-Expires", "X-Amz-SignedHeaders", "X-Amz-Signature"}
+
+// checkPresigned validates the query-string authentication of a presigned
+// URL, writing an error and returning false if it is malformed, expired, or
+// not yet valid, or if a header it signed is missing from the request.
+// Requests without query-string authentication pass. Signatures themselves
+// are not checked, as the mock does not know the signer's secret key.
+func (s *Service) checkPresigned(w http.ResponseWriter, r *http.Request) bool {
+	q := r.URL.Query()
+	presigned := false
+	for _, p := range presignParams {
+		presigned = presigned || q.Has(p)
+	}
+	if !presigned {
+		return true
+	}
+
+	for _, p := range presignParams {
+		if q.Get(p) == "" {
+			writeS3Error(w, "AuthorizationQueryParametersError", "Query-string authentication version 4 requires the X-Amz-Algorithm, X-Amz-Credential, X-Amz-Signature, X-Amz-Date, X-Amz-SignedHeaders, and X-Amz-Expires parameters.", http.StatusBadRequest)
+			return false
+		}
+	}
+	if q.Get("X-Amz-Algorithm") != "AWS4-HMAC-SHA256" {
+		writeS3Error(w, "AuthorizationQueryParametersError", `X-Amz-Algorithm only supports "AWS4-HMAC-SHA256"`, http.StatusBadRequest)
+		return false
+	}
+	signed, err := time.Parse("20060102T150405Z", q.Get("X-Amz-Date"))
+	if err != nil {
+		writeS3Error(w, "AuthorizationQueryParametersError", "X-Amz-Date must be in the ISO8601 Long Format \"yyyyMMdd'T'HHmmss'Z'\"", http.StatusBadRequest)
+		return false
+	}
+	// Credential is access-key-id/date/region/service/aws4_request.
+	scope := strings.Split(q.Get("X-Amz-Credential"), "/")
+	if len(scope) != 5 || scope[4] != "aws4_request" || scope[1] != signed.Format("20060102") {
+		writeS3Error(w, "AuthorizationQueryParametersError", "Error parsing the X-Amz-Credential parameter; the Credential is mal-formed; expecting \"<YOUR-AKID>/YYYYMMDD/REGION/SERVICE/aws4_request\".", http.StatusBadRequest)
+		return false
+	}
+	seconds, err := strconv.Atoi(q.Get("X-Amz-Expires"))
+	if err != nil || seconds < 0 {
+		writeS3Error(w, "AuthorizationQueryParametersError", "X-Amz-Expires should be a number", http.StatusBadRequest)
+		return false
+	}
+	expiry := time.Duration(seconds) * time.Second
+	if expiry > maxPresignExpiry {
+		writeS3Error(w, "AuthorizationQueryParametersError", "X-Amz-Expires must be less than a week (in seconds) that is 604800", http.StatusBadRequest)
+		return false
+	}
+
+	// Clients sign with their wall clock, so validity is measured on the
+	// wall clock too, moved on by however far the mock clock has been
+	// advanced since the URL was signed: a URL expires when the clock is
+	// advanced past its expiry, but one signed afterwards is valid, and
+	// neither depends on how long the server has been up. X-Amz-Date has
+	// whole seconds, so advances within the second of signing count.
+	now := time.Now().UTC().Add(s.advancedSince(signed))
+	if signed.After(now.Add(presignClockSkew)) {
+		writeS3Error(w, "AccessDenied", "Request is not valid yet", http.StatusForbidden)
+		return false
+	}
+	if now.After(signed.Add(expiry)) {
+		writeS3Error(w, "AccessDenied", "Request has expired", http.StatusForbidden)
+		return false
+	}
+	for _, name := range strings.Split(q.Get("X-Amz-SignedHeaders"), ";") {
+		switch name {
+		case "host":
+			continue
+		case "content-length":
+			// The server moves Content-Length out of the headers.
+			if r.ContentLength < 0 {
+				writeS3Error(w, "SignatureDoesNotMatch", signatureMismatch, http.StatusForbidden)
+				return false
+			}
+			continue
+		}
+		if _, ok := r.Header[textproto.CanonicalMIMEHeaderKey(name)]; !ok {
+			writeS3Error(w, "SignatureDoesNotMatch", signatureMismatch, http.StatusForbidden)
+			return false
+		}
+	}
+	return true
+}
@@ -11,49 +11,123 @@
 //   - DeleteObject
 //   - ListObjectsV2
 //   - CopyObject
+//   - PutBucketTagging
+//   - GetBucketTagging
+//   - DeleteBucketTagging
+//   - PutBucketEncryption
+//   - GetBucketEncryption
+//   - DeleteBucketEncryption
+//   - PutBucketCors
+//   - GetBucketCors
+//   - DeleteBucketCors
+//   - DeleteObjects
+//   - PutObjectLockConfiguration
+//   - GetObjectLockConfiguration
+//   - PutObjectRetention
+//   - GetObjectRetention
+//   - PutObjectLegalHold
+//   - GetObjectLegalHold
+//   - PutBucketWebsite
+//   - GetBucketWebsite
+//   - DeleteBucketWebsite
+//
+// OPTIONS preflight requests are answered from the bucket's CORS
+// configuration, and responses to requests carrying an Origin header get the
+// Access-Control headers of the rule that allows them.
+//
+// Buckets are not versioned, so object lock protects the only copy of an
+// object: objects under retention or legal hold can be neither deleted nor
+// overwritten. Retention runs on the mock clock.
+//
+// Objects are encrypted with the bucket's default encryption, SSE-S3 unless
+// configured otherwise, or as their x-amz-server-side-encryption headers ask.
+// KMS keys named for encryption must exist in the KMS mock.
+//
+// Requests are not authenticated: unsigned requests are served like signed
+// ones, so objects can be fetched anonymously. Presigned URLs are checked
+// for well-formed query-string authentication parameters, expiry on the
+// mock clock, and the presence of the headers they signed, but their
+// signatures are not verified.
+//
+// Buckets are addressed path style (localhost/bucket/key) or virtual-hosted
+// style (bucket.localhost/key). Buckets with a website configuration also
+// serve their objects, index documents, and error documents to unsigned GET
+// requests at the website endpoint (bucket.s3-website.localhost/key).
 package s3
 
 import (
 	"bytes"
-	"crypto/md5"
-	"encoding/hex"
 	"encoding/xml"
+	"errors"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
 	"sort"
 	"strings"
 	"sync"
 	"time"
+
+	"github.com/riyanimam/goto/internal/awserr"
+	"github.com/riyanimam/goto/internal/clock"
+	"github.com/riyanimam/goto/internal/cow"
+	"github.com/riyanimam/goto/internal/mockhelpers"
+	"github.com/riyanimam/goto/internal/paginate"
+	"github.com/riyanimam/goto/internal/tags"
 )
 
 // Service implements the S3 mock.
 type Service struct {
-	mu      sync.RWMutex
-	buckets map[string]*bucket
+	mu          sync.RWMutex
+	buckets     map[string]*bucket
+	tags        *tags.Store
+	listDelay   time.Duration
+	bucketQuota int
+	resolve     mockhelpers.Resolver
+	clock       *clock.Clock
+
+	spillThreshold int64
+	spillDir       string
+	mounts         map[string]string // bucket name to directory
+
+	checkpoint map[string]savedBucket
 }
 
 type bucket struct {
 	name      string
 	region    string
 	created   time.Time
-	objects   map[string]*object
+	objects   *cow.Map[string, *object]
+	stale     map[string]staleListing
 	objectsMu sync.RWMutex
+
+	encryption encryption            // guarded by Service.mu
+	cors       []corsRule            // guarded by Service.mu
+	objectLock lockConfig            // guarded by Service.mu
+	website    *websiteConfiguration // guarded by Service.mu
 }
 
 type object struct {
 	key          string
-	data         []byte
+	body         content
 	contentType  string
-	etag         string
 	lastModified time.Time
 	metadata     map[string]string
+	encryption   encryption
+	lock         lockState // guarded by the bucket's objectsMu
+	pinned       bool      // part of a checkpoint, so its body must be kept
+
+	// websiteRedirect is where the website endpoint redirects requests for
+	// the object instead of serving it.
+	websiteRedirect string
 }
 
 // New creates a new S3 mock service.
 func New() *Service {
 	return &Service{
-		buckets: make(map[string]*bucket),
+		buckets:        make(map[string]*bucket),
+		tags:           tags.New(),
+		spillThreshold: defaultSpillThreshold,
 	}
 }
 
@@ -65,11 +139,42 @@ func (s *Service) Handler() http.Handler {
 	return http.HandlerFunc(s.handle)
 }
 
-// Reset clears all buckets and objects.
+// Reset clears all buckets and objects, removing any spilled object files.
+// After [Service.Checkpoint], it restores the checkpointed buckets instead.
+// Mounted directories are mounted again.
 func (s *Service) Reset() {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	s.buckets = make(map[string]*bucket)
+	for _, b := range s.buckets {
+		b.objectsMu.Lock()
+		b.objects.Range(func(_ string, obj *object) bool {
+			obj.release()
+			return true
+		})
+		b.objectsMu.Unlock()
+	}
+	s.buckets = s.restoreCheckpoint()
+	s.mountAll()
+	s.tags.DeleteService("s3")
+}
+
+// SetTagStore sets the registry bucket tags are recorded in.
+func (s *Service) SetTagStore(store *tags.Store) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tags = store
+}
+
+// SetQuota limits how many resources of a kind can exist. The only
+// supported resource is "buckets".
+func (s *Service) SetQuota(resource string, limit int) error {
+	if resource != "buckets" {
+		return fmt.Errorf("s3 has no %q quota", resource)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.bucketQuota = limit
+	return nil
 }
 
 func (s *Service) handle(w http.ResponseWriter, r *http.Request) {
@@ -78,9 +183,56 @@ func (s *Service) handle(w http.ResponseWriter, r *http.Request) {
 	path := strings.TrimPrefix(r.URL.Path, "/")
 	bucketName, key := parsePath(path)
 
+	// Virtual-hosted-style and website requests name the bucket in the host
+	// instead.
+	website := websiteBucket(r.Host)
+	if website != "" {
+		bucketName, key = website, path
+	} else if name := hostBucket(r.Host); name != "" {
+		bucketName, key = name, path
+	}
+
+	_, tagging := r.URL.Query()["tagging"]
+	_, encrypted := r.URL.Query()["encryption"]
+	_, cors := r.URL.Query()["cors"]
+	_, objectLock := r.URL.Query()["object-lock"]
+	_, retention := r.URL.Query()["retention"]
+	_, legalHold := r.URL.Query()["legal-hold"]
+	_, multiDelete := r.URL.Query()["delete"]
+	_, websiteConfig := r.URL.Query()["website"]
+
+	if r.Method == http.MethodOptions && bucketName != "" {
+		s.preflight(w, r, bucketName)
+		return
+	}
+	s.applyCORS(w, r, bucketName)
+	if !s.checkPresigned(w, r) {
+		return
+	}
+	if website != "" {
+		s.serveWebsite(w, r, website, key)
+		return
+	}
+
 	switch {
 	case bucketName == "" && r.Method == http.MethodGet:
 		s.listBuckets(w, r)
+	case key == "" && tagging:
+		s.bucketTagging(w, r, bucketName)
+	case key == "" && encrypted:
+		s.bucketEncryption(w, r, bucketName)
+	case key == "" && cors:
+		s.bucketCORS(w, r, bucketName)
+	case key == "" && websiteConfig:
+		s.bucketWebsite(w, r, bucketName)
+	case key == "" && objectLock:
+		s.bucketObjectLock(w, r, bucketName)
+	case key == "" && multiDelete && r.Method == http.MethodPost:
+		s.deleteObjects(w, r, bucketName)
+	case key != "" && retention:
+		s.objectRetention(w, r, bucketName, key)
+	case key != "" && legalHold:
+		s.objectLegalHold(w, r, bucketName, key)
 	case key == "" && r.Method == http.MethodPut:
 		s.createBucket(w, r, bucketName)
 	case key == "" && r.Method == http.MethodDelete:
@@ -131,7 +283,7 @@ func (s *Service) listBuckets(w http.ResponseWriter, _ *http.Request) {
 	writeXML(w, http.StatusOK, resp)
 }
 
-func (s *Service) createBucket(w http.ResponseWriter, _ *http.Request, name string) {
+func (s *Service) createBucket(w http.ResponseWriter, r *http.Request, name string) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -139,12 +291,19 @@ func (s *Service) createBucket(w http.ResponseWriter, _ *http.Request, name stri
 		writeS3Error(w, "BucketAlreadyOwnedByYou", "Your previous request to create the named bucket succeeded and you already own it.", http.StatusConflict)
 		return
 	}
+	if s.bucketQuota > 0 && len(s.buckets) >= s.bucketQuota {
+		writeS3Error(w, "TooManyBuckets", "You have attempted to create more buckets than allowed", http.StatusBadRequest)
+		return
+	}
 
 	s.buckets[name] = &bucket{
 		name:    name,
 		region:  "us-east-1",
 		created: time.Now().UTC(),
-		objects: make(map[string]*object),
+		objects: cow.New[string, *object](),
+
+		encryption: defaultEncryption,
+		objectLock: lockConfig{enabled: r.Header.Get("X-Amz-Bucket-Object-Lock-Enabled") == "true"},
 	}
 
 	w.Header().Set("Location", "/"+name)
@@ -162,7 +321,7 @@ func (s *Service) deleteBucket(w http.ResponseWriter, _ *http.Request, name stri
 	}
 
 	b.objectsMu.RLock()
-	count := len(b.objects)
+	count := b.objects.Len()
 	b.objectsMu.RUnlock()
 
 	if count > 0 {
@@ -171,6 +330,7 @@ func (s *Service) deleteBucket(w http.ResponseWriter, _ *http.Request, name stri
 	}
 
 	delete(s.buckets, name)
+	s.tags.Delete(bucketARN(name))
 	w.WriteHeader(http.StatusNoContent)
 }
 
@@ -187,6 +347,54 @@ func (s *Service) headBucket(w http.ResponseWriter, _ *http.Request, name string
 	w.WriteHeader(http.StatusOK)
 }
 
+// bucketTagging serves PutBucketTagging, GetBucketTagging, and
+// DeleteBucketTagging on /bucket?tagging.
+func (s *Service) bucketTagging(w http.ResponseWriter, r *http.Request, name string) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if _, exists := s.buckets[name]; !exists {
+		writeS3Error(w, "NoSuchBucket", "The specified bucket does not exist", http.StatusNotFound)
+		return
+	}
+	arn := bucketARN(name)
+
+	switch r.Method {
+	case http.MethodPut:
+		var req tagging
+		if err := xml.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeS3Error(w, "MalformedXML", "The XML you provided was not well-formed", http.StatusBadRequest)
+			return
+		}
+		set := make(map[string]string, len(req.TagSet))
+		for _, t := range req.TagSet {
+			set[t.Key] = t.Value
+		}
+		s.tags.Replace(arn, set)
+		w.WriteHeader(http.StatusNoContent)
+	case http.MethodDelete:
+		s.tags.Delete(arn)
+		w.WriteHeader(http.StatusNoContent)
+	case http.MethodGet:
+		set := s.tags.Get(arn)
+		if len(set) == 0 {
+			writeS3Error(w, "NoSuchTagSet", "The TagSet does not exist", http.StatusNotFound)
+			return
+		}
+		resp := tagging{XMLNS: "http://s3.amazonaws.com/doc/2006-03-01/"}
+		for _, t := range tags.ToList(set, "Key", "Value") {
+			resp.TagSet = append(resp.TagSet, tag{Key: t["Key"], Value: t["Value"]})
+		}
+		writeXML(w, http.StatusOK, resp)
+	default:
+		writeS3Error(w, "MethodNotAllowed", "The specified method is not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func bucketARN(name string) string {
+	return "arn:aws:s3:::" + name
+}
+
 func (s *Service) listObjects(w http.ResponseWriter, r *http.Request, bucketName string) {
 	s.mu.RLock()
 	b, exists := s.buckets[bucketName]
@@ -208,7 +416,7 @@ func (s *Service) listObjects(w http.ResponseWriter, r *http.Request, bucketName
 	b.objectsMu.RLock()
 	var contents []listObjectEntry
 	commonPrefixes := make(map[string]bool)
-	for _, obj := range b.objects {
+	for _, obj := range b.listed() {
 		if prefix != "" && !strings.HasPrefix(obj.key, prefix) {
 			continue
 		}
@@ -225,43 +433,89 @@ func (s *Service) listObjects(w http.ResponseWriter, r *http.Request, bucketName
 		contents = append(contents, listObjectEntry{
 			Key:          obj.key,
 			LastModified: obj.lastModified.Format(time.RFC3339),
-			ETag:         obj.etag,
-			Size:         len(obj.data),
+			ETag:         obj.body.etag,
+			Size:         obj.body.size,
 			StorageClass: "STANDARD",
 		})
 	}
 	b.objectsMu.RUnlock()
 
-	sort.Slice(contents, func(i, j int) bool {
-		return contents[i].Key < contents[j].Key
-	})
-
-	if len(contents) > maxKeys {
-		contents = contents[:maxKeys]
+	// Objects and common prefixes are paged through together, in key order.
+	var entries []listing
+	for _, c := range contents {
+		entries = append(entries, listing{object: c})
 	}
-
-	var prefixEntries []commonPrefix
 	for p := range commonPrefixes {
-		prefixEntries = append(prefixEntries, commonPrefix{Prefix: p})
+		entries = append(entries, listing{prefix: p})
 	}
-	sort.Slice(prefixEntries, func(i, j int) bool {
-		return prefixEntries[i].Prefix < prefixEntries[j].Prefix
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].key() < entries[j].key()
 	})
 
+	// ListObjects (V1) resumes after the marker key; ListObjectsV2 resumes
+	// after start-after and then after the last key of the previous page,
+	// which the continuation token holds.
+	listV2 := r.URL.Query().Get("list-type") == "2"
+	after := r.URL.Query().Get("marker")
+	if listV2 {
+		after = r.URL.Query().Get("start-after")
+	}
+	if after != "" {
+		entries = entries[sort.Search(len(entries), func(i int) bool { return entries[i].key() > after }):]
+	}
+	token := ""
+	if listV2 {
+		token = r.URL.Query().Get("continuation-token")
+	}
+	page, next, err := paginate.Page(entries, func(i int) string { return entries[i].key() }, token, maxKeys, 1000)
+	if err != nil {
+		writeS3Error(w, "InvalidArgument", "The continuation token provided is incorrect", http.StatusBadRequest)
+		return
+	}
+
 	resp := listBucketResult{
-		XMLNS:          "http://s3.amazonaws.com/doc/2006-03-01/",
-		Name:           bucketName,
-		Prefix:         prefix,
-		Delimiter:      delimiter,
-		MaxKeys:        maxKeys,
-		KeyCount:       len(contents),
-		IsTruncated:    false,
-		Contents:       contents,
-		CommonPrefixes: prefixEntries,
+		XMLNS:       "http://s3.amazonaws.com/doc/2006-03-01/",
+		Name:        bucketName,
+		Prefix:      prefix,
+		Delimiter:   delimiter,
+		MaxKeys:     maxKeys,
+		KeyCount:    len(page),
+		IsTruncated: next != "",
+	}
+	for _, e := range page {
+		if e.prefix != "" {
+			resp.CommonPrefixes = append(resp.CommonPrefixes, commonPrefix{Prefix: e.prefix})
+		} else {
+			resp.Contents = append(resp.Contents, e.object)
+		}
+	}
+	if listV2 {
+		resp.StartAfter = after
+		resp.ContinuationToken = token
+		resp.NextContinuationToken = next
+	} else {
+		resp.Marker = after
+		if next != "" {
+			resp.NextMarker = page[len(page)-1].key()
+		}
 	}
 	writeXML(w, http.StatusOK, resp)
 }
 
+// listing is one entry of a bucket listing: an object, or a common prefix
+// standing for every key that shares it.
+type listing struct {
+	object listObjectEntry
+	prefix string
+}
+
+func (l listing) key() string {
+	if l.prefix != "" {
+		return l.prefix
+	}
+	return l.object.Key
+}
+
 func (s *Service) putObject(w http.ResponseWriter, r *http.Request, bucketName, key string) {
 	s.mu.RLock()
 	b, exists := s.buckets[bucketName]
@@ -271,20 +525,28 @@ func (s *Service) putObject(w http.ResponseWriter, r *http.Request, bucketName,
 		writeS3Error(w, "NoSuchBucket", "The specified bucket does not exist", http.StatusNotFound)
 		return
 	}
+	enc, ok := s.requestEncryption(w, r, b)
+	if !ok {
+		return
+	}
 
-	data, err := io.ReadAll(r.Body)
+	body, err := s.store(r.Body)
 	if err != nil {
 		writeS3Error(w, "InternalError", "could not read request body", http.StatusInternalServerError)
 		return
 	}
 
-	hash := md5.Sum(data)
-	etag := `"` + hex.EncodeToString(hash[:]) + `"`
-
 	contentType := r.Header.Get("Content-Type")
 	if contentType == "" {
 		contentType = "binary/octet-stream"
 	}
+	redirect := r.Header.Get("X-Amz-Website-Redirect-Location")
+	if redirect != "" && !strings.HasPrefix(redirect, "/") &&
+		!strings.HasPrefix(redirect, "http://") && !strings.HasPrefix(redirect, "https://") {
+		body.discard()
+		writeS3Error(w, "InvalidRedirectLocation", "The website redirect location must have a prefix of 'http://' or 'https://' or '/'.", http.StatusBadRequest)
+		return
+	}
 
 	// Collect user metadata (X-Amz-Meta-* headers).
 	metadata := make(map[string]string)
@@ -298,18 +560,28 @@ func (s *Service) putObject(w http.ResponseWriter, r *http.Request, bucketName,
 
 	obj := &object{
 		key:          key,
-		data:         data,
+		body:         body,
 		contentType:  contentType,
-		etag:         etag,
 		lastModified: time.Now().UTC(),
 		metadata:     metadata,
+		encryption:   enc,
+
+		websiteRedirect: redirect,
+	}
+	if !s.requestLock(w, r, b, obj) {
+		body.discard()
+		return
 	}
 
-	b.objectsMu.Lock()
-	b.objects[key] = obj
-	b.objectsMu.Unlock()
+	if !b.put(key, obj, s.currentListDelay(), s.guard(r)) {
+		body.discard()
+		writeS3Error(w, "AccessDenied", objectLockedMessage, http.StatusForbidden)
+		return
+	}
 
-	w.Header().Set("ETag", etag)
+	setEncryptionHeaders(w, enc)
+	setLockHeaders(w, obj.lock)
+	w.Header().Set("ETag", body.etag)
 	w.WriteHeader(http.StatusOK)
 }
 
@@ -324,23 +596,40 @@ func (s *Service) getObject(w http.ResponseWriter, _ *http.Request, bucketName,
 	}
 
 	b.objectsMu.RLock()
-	obj, exists := b.objects[key]
+	obj, exists := b.objects.Get(key)
+	var rc io.ReadCloser
+	var lock lockState
+	var err error
+	if exists {
+		rc, err = obj.body.open()
+		lock = obj.lock
+	}
 	b.objectsMu.RUnlock()
 
 	if !exists {
 		writeS3Error(w, "NoSuchKey", "The specified key does not exist.", http.StatusNotFound)
 		return
 	}
+	if err != nil {
+		writeS3Error(w, "InternalError", "could not read object body", http.StatusInternalServerError)
+		return
+	}
+	defer rc.Close()
 
 	w.Header().Set("Content-Type", obj.contentType)
-	w.Header().Set("ETag", obj.etag)
+	w.Header().Set("ETag", obj.body.etag)
 	w.Header().Set("Last-Modified", obj.lastModified.Format(http.TimeFormat))
-	w.Header().Set("Content-Length", fmt.Sprintf("%d", len(obj.data)))
+	w.Header().Set("Content-Length", fmt.Sprintf("%d", obj.body.size))
 	for k, v := range obj.metadata {
 		w.Header().Set("X-Amz-Meta-"+k, v)
 	}
+	setEncryptionHeaders(w, obj.encryption)
+	setLockHeaders(w, lock)
+	if obj.websiteRedirect != "" {
+		w.Header().Set("X-Amz-Website-Redirect-Location", obj.websiteRedirect)
+	}
 	w.WriteHeader(http.StatusOK)
-	w.Write(obj.data)
+	io.Copy(w, rc)
 }
 
 func (s *Service) headObject(w http.ResponseWriter, _ *http.Request, bucketName, key string) {
@@ -354,7 +643,11 @@ func (s *Service) headObject(w http.ResponseWriter, _ *http.Request, bucketName,
 	}
 
 	b.objectsMu.RLock()
-	obj, exists := b.objects[key]
+	obj, exists := b.objects.Get(key)
+	var lock lockState
+	if exists {
+		lock = obj.lock
+	}
 	b.objectsMu.RUnlock()
 
 	if !exists {
@@ -363,16 +656,21 @@ func (s *Service) headObject(w http.ResponseWriter, _ *http.Request, bucketName,
 	}
 
 	w.Header().Set("Content-Type", obj.contentType)
-	w.Header().Set("ETag", obj.etag)
+	w.Header().Set("ETag", obj.body.etag)
 	w.Header().Set("Last-Modified", obj.lastModified.Format(http.TimeFormat))
-	w.Header().Set("Content-Length", fmt.Sprintf("%d", len(obj.data)))
+	w.Header().Set("Content-Length", fmt.Sprintf("%d", obj.body.size))
 	for k, v := range obj.metadata {
 		w.Header().Set("X-Amz-Meta-"+k, v)
 	}
+	setEncryptionHeaders(w, obj.encryption)
+	setLockHeaders(w, lock)
+	if obj.websiteRedirect != "" {
+		w.Header().Set("X-Amz-Website-Redirect-Location", obj.websiteRedirect)
+	}
 	w.WriteHeader(http.StatusOK)
 }
 
-func (s *Service) deleteObject(w http.ResponseWriter, _ *http.Request, bucketName, key string) {
+func (s *Service) deleteObject(w http.ResponseWriter, r *http.Request, bucketName, key string) {
 	s.mu.RLock()
 	b, exists := s.buckets[bucketName]
 	s.mu.RUnlock()
@@ -382,9 +680,10 @@ func (s *Service) deleteObject(w http.ResponseWriter, _ *http.Request, bucketNam
 		return
 	}
 
-	b.objectsMu.Lock()
-	delete(b.objects, key)
-	b.objectsMu.Unlock()
+	if !b.remove(key, s.currentListDelay(), s.guard(r)) {
+		writeS3Error(w, "AccessDenied", objectLockedMessage, http.StatusForbidden)
+		return
+	}
 
 	w.WriteHeader(http.StatusNoContent)
 }
@@ -413,17 +712,20 @@ func (s *Service) copyObject(w http.ResponseWriter, r *http.Request, destBucket,
 		return
 	}
 	s.mu.RUnlock()
+	enc, ok := s.requestEncryption(w, r, db)
+	if !ok {
+		return
+	}
 
 	sb.objectsMu.RLock()
-	srcObj, exists := sb.objects[srcKey]
+	srcObj, exists := sb.objects.Get(srcKey)
 	if !exists {
 		sb.objectsMu.RUnlock()
 		writeS3Error(w, "NoSuchKey", "The specified key does not exist.", http.StatusNotFound)
 		return
 	}
-	// Copy data while holding the lock.
-	dataCopy := make([]byte, len(srcObj.data))
-	copy(dataCopy, srcObj.data)
+	// Open the source while holding the lock; the copy streams after it.
+	rc, err := srcObj.body.open()
 	contentType := srcObj.contentType
 	metadata := make(map[string]string)
 	for k, v := range srcObj.metadata {
@@ -431,25 +733,40 @@ func (s *Service) copyObject(w http.ResponseWriter, r *http.Request, destBucket,
 	}
 	sb.objectsMu.RUnlock()
 
-	hash := md5.Sum(dataCopy)
-	etag := `"` + hex.EncodeToString(hash[:]) + `"`
+	if err != nil {
+		writeS3Error(w, "InternalError", "could not read object body", http.StatusInternalServerError)
+		return
+	}
+	body, err := s.store(rc)
+	rc.Close()
+	if err != nil {
+		writeS3Error(w, "InternalError", "could not copy object body", http.StatusInternalServerError)
+		return
+	}
 	now := time.Now().UTC()
 
 	newObj := &object{
 		key:          destKey,
-		data:         dataCopy,
+		body:         body,
 		contentType:  contentType,
-		etag:         etag,
 		lastModified: now,
 		metadata:     metadata,
+		encryption:   enc,
+	}
+	if !s.requestLock(w, r, db, newObj) {
+		body.discard()
+		return
 	}
 
-	db.objectsMu.Lock()
-	db.objects[destKey] = newObj
-	db.objectsMu.Unlock()
+	if !db.put(destKey, newObj, s.currentListDelay(), s.guard(r)) {
+		body.discard()
+		writeS3Error(w, "AccessDenied", objectLockedMessage, http.StatusForbidden)
+		return
+	}
+	setEncryptionHeaders(w, enc)
 
 	resp := copyObjectResult{
-		ETag:         etag,
+		ETag:         body.etag,
 		LastModified: now.Format(time.RFC3339),
 	}
 	writeXML(w, http.StatusOK, resp)
@@ -475,23 +792,28 @@ type listBucketEntry struct {
 }
 
 type listBucketResult struct {
-	XMLName        xml.Name          `xml:"ListBucketResult"`
-	XMLNS          string            `xml:"xmlns,attr"`
-	Name           string            `xml:"Name"`
-	Prefix         string            `xml:"Prefix"`
-	Delimiter      string            `xml:"Delimiter,omitempty"`
-	MaxKeys        int               `xml:"MaxKeys"`
-	KeyCount       int               `xml:"KeyCount"`
-	IsTruncated    bool              `xml:"IsTruncated"`
-	Contents       []listObjectEntry `xml:"Contents"`
-	CommonPrefixes []commonPrefix    `xml:"CommonPrefixes,omitempty"`
+	XMLName               xml.Name          `xml:"ListBucketResult"`
+	XMLNS                 string            `xml:"xmlns,attr"`
+	Name                  string            `xml:"Name"`
+	Prefix                string            `xml:"Prefix"`
+	Delimiter             string            `xml:"Delimiter,omitempty"`
+	MaxKeys               int               `xml:"MaxKeys"`
+	KeyCount              int               `xml:"KeyCount"`
+	IsTruncated           bool              `xml:"IsTruncated"`
+	Marker                string            `xml:"Marker,omitempty"`
+	NextMarker            string            `xml:"NextMarker,omitempty"`
+	StartAfter            string            `xml:"StartAfter,omitempty"`
+	ContinuationToken     string            `xml:"ContinuationToken,omitempty"`
+	NextContinuationToken string            `xml:"NextContinuationToken,omitempty"`
+	Contents              []listObjectEntry `xml:"Contents"`
+	CommonPrefixes        []commonPrefix    `xml:"CommonPrefixes,omitempty"`
 }
 
 type listObjectEntry struct {
 	Key          string `xml:"Key"`
 	LastModified string `xml:"LastModified"`
 	ETag         string `xml:"ETag"`
-	Size         int    `xml:"Size"`
+	Size         int64  `xml:"Size"`
 	StorageClass string `xml:"StorageClass"`
 }
 
@@ -505,12 +827,15 @@ type copyObjectResult struct {
 	LastModified string   `xml:"LastModified"`
 }
 
-type s3ErrorResponse struct {
-	XMLName   xml.Name `xml:"Error"`
-	Code      string   `xml:"Code"`
-	Message   string   `xml:"Message"`
-	Resource  string   `xml:"Resource"`
-	RequestID string   `xml:"RequestId"`
+type tagging struct {
+	XMLName xml.Name `xml:"Tagging"`
+	XMLNS   string   `xml:"xmlns,attr,omitempty"`
+	TagSet  []tag    `xml:"TagSet>Tag"`
+}
+
+type tag struct {
+	Key   string `xml:"Key"`
+	Value string `xml:"Value"`
 }
 
 // Helper functions.
@@ -526,6 +851,24 @@ func parsePath(path string) (bucket, key string) {
 	return path[:idx], path[idx+1:]
 }
 
+// hostBucket returns the bucket named by a virtual-hosted-style Host header,
+// such as "photos.localhost:8080" or "photos.s3.localhost", or "" for a
+// path-style request.
+func hostBucket(host string) string {
+	if hostname, _, err := net.SplitHostPort(host); err == nil {
+		host = hostname
+	}
+	name, ok := strings.CutSuffix(host, ".localhost")
+	if !ok {
+		return ""
+	}
+	name = strings.TrimSuffix(name, ".s3")
+	if name == "s3" {
+		return ""
+	}
+	return name
+}
+
 func writeXML(w http.ResponseWriter, status int, v interface{}) {
 	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
 	w.WriteHeader(status)
@@ -534,17 +877,156 @@ func writeXML(w http.ResponseWriter, status int, v interface{}) {
 }
 
 func writeS3Error(w http.ResponseWriter, code, message string, status int) {
-	resp := s3ErrorResponse{
-		Code:      code,
-		Message:   message,
-		RequestID: "mock-request-id",
+	awserr.WriteRestXML(w, code, message, status)
+}
+
+// Direct object access, used by other mock services that read or write
+// bucket contents in-process.
+
+// ErrNoSuchBucket is returned by the direct access methods when the bucket
+// does not exist.
+var ErrNoSuchBucket = errors.New("NoSuchBucket: the specified bucket does not exist")
+
+// ErrNoSuchKey is returned by GetObject when the key does not exist.
+var ErrNoSuchKey = errors.New("NoSuchKey: the specified key does not exist")
+
+func (s *Service) lookupBucket(name string) (*bucket, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	b, ok := s.buckets[name]
+	if !ok {
+		return nil, ErrNoSuchBucket
 	}
+	return b, nil
+}
 
-	var buf bytes.Buffer
-	buf.WriteString(xml.Header)
-	xml.NewEncoder(&buf).Encode(resp)
+// PutObject stores data under key in an existing bucket.
+func (s *Service) PutObject(bucketName, key string, data []byte) error {
+	b, err := s.lookupBucket(bucketName)
+	if err != nil {
+		return err
+	}
 
-	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
-	w.WriteHeader(status)
-	w.Write(buf.Bytes())
+	body, err := s.store(bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	now := s.now()
+	s.mu.RLock()
+	enc, lock := b.encryption, b.objectLock
+	s.mu.RUnlock()
+	obj := &object{
+		key:          key,
+		body:         body,
+		contentType:  "binary/octet-stream",
+		lastModified: time.Now().UTC(),
+		metadata:     make(map[string]string),
+		encryption:   enc,
+	}
+
+	lock.retain(obj, now)
+	if !b.put(key, obj, s.currentListDelay(), s.guard(nil)) {
+		body.discard()
+		return ErrObjectLocked
+	}
+	return nil
+}
+
+// GetObject returns a copy of the object stored under key.
+func (s *Service) GetObject(bucketName, key string) ([]byte, error) {
+	b, err := s.lookupBucket(bucketName)
+	if err != nil {
+		return nil, err
+	}
+
+	b.objectsMu.RLock()
+	defer b.objectsMu.RUnlock()
+	obj, ok := b.objects.Get(key)
+	if !ok {
+		return nil, ErrNoSuchKey
+	}
+	return obj.body.bytes()
+}
+
+// Object is a snapshot of a stored object.
+type Object struct {
+	Key          string
+	Body         []byte
+	ContentType  string
+	ETag         string
+	LastModified time.Time
+	Metadata     map[string]string
+	// ServerSideEncryption is AES256, aws:kms, or aws:kms:dsse, and
+	// SSEKMSKeyID the ARN of the KMS key, if one was named.
+	ServerSideEncryption string
+	SSEKMSKeyID          string
+}
+
+// Object returns the object stored under key, including its metadata.
+func (s *Service) Object(bucketName, key string) (Object, error) {
+	b, err := s.lookupBucket(bucketName)
+	if err != nil {
+		return Object{}, err
+	}
+
+	b.objectsMu.RLock()
+	defer b.objectsMu.RUnlock()
+	obj, ok := b.objects.Get(key)
+	if !ok {
+		return Object{}, ErrNoSuchKey
+	}
+	data, err := obj.body.bytes()
+	if err != nil {
+		return Object{}, err
+	}
+	meta := make(map[string]string, len(obj.metadata))
+	for k, v := range obj.metadata {
+		meta[k] = v
+	}
+	return Object{
+		Key:          obj.key,
+		Body:         data,
+		ContentType:  obj.contentType,
+		ETag:         obj.body.etag,
+		LastModified: obj.lastModified,
+		Metadata:     meta,
+
+		ServerSideEncryption: obj.encryption.algorithm,
+		SSEKMSKeyID:          obj.encryption.kmsKeyID,
+	}, nil
+}
+
+// DeleteObject removes key from the bucket. Deleting a missing key is not
+// an error, matching S3; deleting an object that object lock protects is.
+func (s *Service) DeleteObject(bucketName, key string) error {
+	b, err := s.lookupBucket(bucketName)
+	if err != nil {
+		return err
+	}
+
+	if !b.remove(key, s.currentListDelay(), s.guard(nil)) {
+		return ErrObjectLocked
+	}
+	return nil
+}
+
+// ListObjects returns the sorted keys in the bucket that start with prefix.
+func (s *Service) ListObjects(bucketName, prefix string) ([]string, error) {
+	b, err := s.lookupBucket(bucketName)
+	if err != nil {
+		return nil, err
+	}
+
+	b.objectsMu.RLock()
+	var keys []string
+	b.objects.Range(func(k string, _ *object) bool {
+		if strings.HasPrefix(k, prefix) {
+			keys = append(keys, k)
+		}
+		return true
+	})
+	b.objectsMu.RUnlock()
+
+	sort.Strings(keys)
+	return keys, nil
 }
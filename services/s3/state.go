@@ -0,0 +1,38 @@
+package s3
+
+import "github.com/riyanimam/goto/internal/mockhelpers"
+
+// ExportState lists the buckets, with their configuration and object count.
+func (s *Service) ExportState() []mockhelpers.Resource {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	resources := make([]mockhelpers.Resource, 0, len(s.buckets))
+	for name, b := range s.buckets {
+		b.objectsMu.Lock()
+		count := b.objects.Len()
+		b.objectsMu.Unlock()
+
+		attrs := map[string]interface{}{
+			"bucket":              name,
+			"region":              b.region,
+			"object_count":        count,
+			"object_lock_enabled": b.objectLock.enabled,
+			"cors_rules":          len(b.cors),
+			"website":             b.website != nil,
+		}
+		if b.encryption.algorithm != "" {
+			attrs["sse_algorithm"] = b.encryption.algorithm
+		}
+		if b.encryption.kmsKeyID != "" {
+			attrs["kms_master_key_id"] = b.encryption.kmsKeyID
+		}
+		resources = append(resources, mockhelpers.Resource{
+			Type:       "aws_s3_bucket",
+			ID:         name,
+			ARN:        "arn:aws:s3:::" + name,
+			Attributes: attrs,
+		})
+	}
+	return resources
+}
@@ -0,0 +1,344 @@
+package s3
+
+import (
+	"encoding/xml"
+	"fmt"
+	"html"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// maxRoutingRules is the number of routing rules a website configuration
+// can hold.
+const maxRoutingRules = 50
+
+type websiteConfiguration struct {
+	XMLName               xml.Name        `xml:"WebsiteConfiguration"`
+	XMLNS                 string          `xml:"xmlns,attr,omitempty"`
+	IndexDocument         *indexDocument  `xml:"IndexDocument"`
+	ErrorDocument         *errorDocument  `xml:"ErrorDocument"`
+	RedirectAllRequestsTo *redirectTarget `xml:"RedirectAllRequestsTo"`
+	RoutingRules          []routingRule   `xml:"RoutingRules>RoutingRule"`
+}
+
+type indexDocument struct {
+	Suffix string `xml:"Suffix"`
+}
+
+type errorDocument struct {
+	Key string `xml:"Key"`
+}
+
+type redirectTarget struct {
+	HostName string `xml:"HostName"`
+	Protocol string `xml:"Protocol,omitempty"`
+}
+
+type routingRule struct {
+	Condition *routingCondition `xml:"Condition"`
+	Redirect  *routingRedirect  `xml:"Redirect"`
+}
+
+type routingCondition struct {
+	HttpErrorCodeReturnedEquals string `xml:"HttpErrorCodeReturnedEquals,omitempty"`
+	KeyPrefixEquals             string `xml:"KeyPrefixEquals,omitempty"`
+}
+
+type routingRedirect struct {
+	HostName             string `xml:"HostName,omitempty"`
+	HttpRedirectCode     string `xml:"HttpRedirectCode,omitempty"`
+	Protocol             string `xml:"Protocol,omitempty"`
+	ReplaceKeyPrefixWith string `xml:"ReplaceKeyPrefixWith,omitempty"`
+	ReplaceKeyWith       string `xml:"ReplaceKeyWith,omitempty"`
+}
+
+// websiteBucket returns the bucket named by a website endpoint Host header,
+// such as "docs.s3-website.localhost:8080" or
+// "docs.s3-website-us-east-1.localhost", or "" for any other host.
+func websiteBucket(host string) string {
+	if hostname, _, err := net.SplitHostPort(host); err == nil {
+		host = hostname
+	}
+	name, ok := strings.CutSuffix(host, ".localhost")
+	if !ok {
+		return ""
+	}
+	i := strings.LastIndex(name, ".s3-website")
+	if i <= 0 {
+		return ""
+	}
+	if rest := name[i+len(".s3-website"):]; rest != "" && rest[0] != '-' && rest[0] != '.' {
+		return ""
+	}
+	return name[:i]
+}
+
+// bucketWebsite serves PutBucketWebsite, GetBucketWebsite, and
+// DeleteBucketWebsite on /bucket?website.
+func (s *Service) bucketWebsite(w http.ResponseWriter, r *http.Request, name string) {
+	var req websiteConfiguration
+	if r.Method == http.MethodPut {
+		if err := xml.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeS3Error(w, "MalformedXML", "The XML you provided was not well-formed or did not validate against our published schema", http.StatusBadRequest)
+			return
+		}
+		if !checkWebsite(w, &req) {
+			return
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	b, exists := s.buckets[name]
+	if !exists {
+		writeS3Error(w, "NoSuchBucket", "The specified bucket does not exist", http.StatusNotFound)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPut:
+		req.XMLNS = "http://s3.amazonaws.com/doc/2006-03-01/"
+		b.website = &req
+		w.WriteHeader(http.StatusOK)
+	case http.MethodDelete:
+		b.website = nil
+		w.WriteHeader(http.StatusNoContent)
+	case http.MethodGet:
+		if b.website == nil {
+			writeS3Error(w, "NoSuchWebsiteConfiguration", "The specified bucket does not have a website configuration", http.StatusNotFound)
+			return
+		}
+		writeXML(w, http.StatusOK, b.website)
+	default:
+		writeS3Error(w, "MethodNotAllowed", "The specified method is not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// checkWebsite writes an error and returns false unless cfg either
+// redirects every request or names an index document, with well-formed
+// routing rules.
+func checkWebsite(w http.ResponseWriter, cfg *websiteConfiguration) bool {
+	if cfg.RedirectAllRequestsTo != nil {
+		if cfg.IndexDocument != nil || cfg.ErrorDocument != nil || len(cfg.RoutingRules) > 0 {
+			writeS3Error(w, "InvalidArgument", "RedirectAllRequestsTo cannot be provided in conjunction with other Routing/Redirect configurations.", http.StatusBadRequest)
+			return false
+		}
+		if cfg.RedirectAllRequestsTo.HostName == "" {
+			writeS3Error(w, "InvalidArgument", "RedirectAllRequestsTo must have a HostName", http.StatusBadRequest)
+			return false
+		}
+		return checkProtocol(w, cfg.RedirectAllRequestsTo.Protocol)
+	}
+	if cfg.IndexDocument == nil || cfg.IndexDocument.Suffix == "" {
+		writeS3Error(w, "InvalidArgument", "A value for IndexDocument Suffix must be provided if RedirectAllRequestsTo is empty", http.StatusBadRequest)
+		return false
+	}
+	if strings.Contains(cfg.IndexDocument.Suffix, "/") {
+		writeS3Error(w, "InvalidArgument", "The IndexDocument Suffix is not well formed", http.StatusBadRequest)
+		return false
+	}
+	if len(cfg.RoutingRules) > maxRoutingRules {
+		writeS3Error(w, "InvalidRequest", fmt.Sprintf("The website configuration can contain at most %d routing rules", maxRoutingRules), http.StatusBadRequest)
+		return false
+	}
+	for _, rule := range cfg.RoutingRules {
+		if rule.Redirect == nil {
+			writeS3Error(w, "MalformedXML", "The XML you provided was not well-formed or did not validate against our published schema", http.StatusBadRequest)
+			return false
+		}
+		if rule.Redirect.ReplaceKeyWith != "" && rule.Redirect.ReplaceKeyPrefixWith != "" {
+			writeS3Error(w, "InvalidRequest", "You can only define ReplaceKeyPrefix or ReplaceKey but not both.", http.StatusBadRequest)
+			return false
+		}
+		if code := rule.Redirect.HttpRedirectCode; code != "" {
+			if n, err := strconv.Atoi(code); err != nil || n < 300 || n > 399 {
+				writeS3Error(w, "InvalidRequest", "The provided HTTP redirect code ("+code+") is not valid. Valid codes are 3XX except 300.", http.StatusBadRequest)
+				return false
+			}
+		}
+		if !checkProtocol(w, rule.Redirect.Protocol) {
+			return false
+		}
+	}
+	return true
+}
+
+func checkProtocol(w http.ResponseWriter, protocol string) bool {
+	switch protocol {
+	case "", "http", "https":
+		return true
+	}
+	writeS3Error(w, "InvalidRequest", "Invalid protocol, protocol can be http or https. If not defined the protocol will be selected automatically.", http.StatusBadRequest)
+	return false
+}
+
+// serveWebsite answers a GET or HEAD request to a bucket's website endpoint
+// the way S3 static website hosting does: redirecting by the configuration
+// and its routing rules, serving index documents for folder keys, and
+// serving the error document, with the error's status, for missing keys.
+func (s *Service) serveWebsite(w http.ResponseWriter, r *http.Request, name, key string) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		writeWebsiteError(w, r, "MethodNotAllowed", "The specified method is not allowed against this resource.", http.StatusMethodNotAllowed)
+		return
+	}
+
+	s.mu.RLock()
+	b, exists := s.buckets[name]
+	var cfg *websiteConfiguration
+	if exists {
+		cfg = b.website
+	}
+	s.mu.RUnlock()
+
+	switch {
+	case !exists:
+		writeWebsiteError(w, r, "NoSuchBucket", "The specified bucket does not exist", http.StatusNotFound)
+		return
+	case cfg == nil:
+		writeWebsiteError(w, r, "NoSuchWebsiteConfiguration", "The specified bucket does not have a website configuration", http.StatusNotFound)
+		return
+	case cfg.RedirectAllRequestsTo != nil:
+		to := cfg.RedirectAllRequestsTo
+		websiteRedirect(w, r, to.Protocol, to.HostName, "/"+key, http.StatusMovedPermanently)
+		return
+	}
+	if rule := cfg.routingRule(key, ""); rule != nil {
+		rule.redirect(w, r, key)
+		return
+	}
+
+	target := key
+	if target == "" || strings.HasSuffix(target, "/") {
+		target += cfg.IndexDocument.Suffix
+	}
+	if s.serveWebsiteObject(w, r, b, target, http.StatusOK) {
+		return
+	}
+	// A folder key without its trailing slash redirects to the folder, as
+	// long as the folder has an index document.
+	if key != "" && target == key && b.hasObject(key+"/"+cfg.IndexDocument.Suffix) {
+		websiteRedirect(w, r, "", "", "/"+key+"/", http.StatusFound)
+		return
+	}
+	if rule := cfg.routingRule(key, "404"); rule != nil {
+		rule.redirect(w, r, key)
+		return
+	}
+	if cfg.ErrorDocument != nil && s.serveWebsiteObject(w, r, b, cfg.ErrorDocument.Key, http.StatusNotFound) {
+		return
+	}
+	writeWebsiteError(w, r, "NoSuchKey", "The specified key does not exist.", http.StatusNotFound)
+}
+
+// serveWebsiteObject writes the object stored under key with status,
+// following its website redirect location if it has one. It reports false,
+// writing nothing, if there is no such object.
+func (s *Service) serveWebsiteObject(w http.ResponseWriter, r *http.Request, b *bucket, key string, status int) bool {
+	b.objectsMu.RLock()
+	obj, exists := b.objects.Get(key)
+	var rc io.ReadCloser
+	var err error
+	if exists && obj.websiteRedirect == "" {
+		rc, err = obj.body.open()
+	}
+	b.objectsMu.RUnlock()
+
+	switch {
+	case !exists:
+		return false
+	case obj.websiteRedirect != "":
+		w.Header().Set("Location", obj.websiteRedirect)
+		w.WriteHeader(http.StatusMovedPermanently)
+		return true
+	case err != nil:
+		writeWebsiteError(w, r, "InternalError", "could not read object body", http.StatusInternalServerError)
+		return true
+	}
+	defer rc.Close()
+
+	w.Header().Set("Content-Type", obj.contentType)
+	w.Header().Set("ETag", obj.body.etag)
+	w.Header().Set("Last-Modified", obj.lastModified.Format(http.TimeFormat))
+	w.Header().Set("Content-Length", strconv.FormatInt(obj.body.size, 10))
+	w.WriteHeader(status)
+	if r.Method == http.MethodGet {
+		io.Copy(w, rc)
+	}
+	return true
+}
+
+// hasObject reports whether an object is stored under key.
+func (b *bucket) hasObject(key string) bool {
+	b.objectsMu.RLock()
+	defer b.objectsMu.RUnlock()
+	_, exists := b.objects.Get(key)
+	return exists
+}
+
+// routingRule returns the first routing rule whose condition matches key
+// and, if errorCode is not empty, the error a lookup of key returned. Rules
+// with an error code condition only match after a failed lookup.
+func (cfg *websiteConfiguration) routingRule(key, errorCode string) *routingRule {
+	for i, rule := range cfg.RoutingRules {
+		cond := rule.Condition
+		if cond == nil {
+			cond = &routingCondition{}
+		}
+		if cond.HttpErrorCodeReturnedEquals != errorCode || !strings.HasPrefix(key, cond.KeyPrefixEquals) {
+			continue
+		}
+		return &cfg.RoutingRules[i]
+	}
+	return nil
+}
+
+// redirect sends a website request for key where the rule says.
+func (rule *routingRule) redirect(w http.ResponseWriter, r *http.Request, key string) {
+	rd := rule.Redirect
+	switch {
+	case rd.ReplaceKeyWith != "":
+		key = rd.ReplaceKeyWith
+	case rd.ReplaceKeyPrefixWith != "":
+		prefix := ""
+		if rule.Condition != nil {
+			prefix = rule.Condition.KeyPrefixEquals
+		}
+		key = rd.ReplaceKeyPrefixWith + strings.TrimPrefix(key, prefix)
+	}
+	status := http.StatusMovedPermanently
+	if code, err := strconv.Atoi(rd.HttpRedirectCode); err == nil {
+		status = code
+	}
+	websiteRedirect(w, r, rd.Protocol, rd.HostName, "/"+key, status)
+}
+
+// websiteRedirect redirects to path on host, or on the website endpoint
+// itself if host is empty.
+func websiteRedirect(w http.ResponseWriter, r *http.Request, protocol, host, path string, status int) {
+	if host == "" {
+		host = r.Host
+	}
+	if protocol == "" {
+		protocol = "http"
+	}
+	w.Header().Set("Location", protocol+"://"+host+path)
+	w.WriteHeader(status)
+}
+
+// writeWebsiteError writes the HTML error page website endpoints return in
+// place of S3's XML errors.
+func writeWebsiteError(w http.ResponseWriter, r *http.Request, code, message string, status int) {
+	title := fmt.Sprintf("%d %s", status, http.StatusText(status))
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("X-Amz-Error-Code", code)
+	w.Header().Set("X-Amz-Error-Message", message)
+	w.WriteHeader(status)
+	if r.Method == http.MethodHead {
+		return
+	}
+	fmt.Fprintf(w, "<html>\n<head><title>%s</title></head>\n<body>\n<h1>%s</h1>\n<ul>\n<li>Code: %s</li>\n<li>Message: %s</li>\n</ul>\n<hr/>\n</body>\n</html>\n",
+		title, title, html.EscapeString(code), html.EscapeString(message))
+}
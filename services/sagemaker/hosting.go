@@ -0,0 +1,269 @@
+package sagemaker
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/riyanimam/goto/internal/lifecycle"
+	h "github.com/riyanimam/goto/internal/mockhelpers"
+	"github.com/riyanimam/goto/internal/tags"
+)
+
+type model struct {
+	name             string
+	arn              string
+	executionRoleArn string
+	primaryContainer map[string]interface{}
+	containers       []interface{}
+	created          time.Time
+}
+
+type endpointConfig struct {
+	name     string
+	arn      string
+	variants []interface{}
+	created  time.Time
+}
+
+type endpoint struct {
+	name       string
+	arn        string
+	configName string
+	created    time.Time
+	ready      lifecycle.Transition
+}
+
+// --- Models ---
+
+func (s *Service) createModel(w http.ResponseWriter, params map[string]interface{}) {
+	name := h.GetString(params, "ModelName")
+	primary, _ := params["PrimaryContainer"].(map[string]interface{})
+	containers, _ := params["Containers"].([]interface{})
+	switch {
+	case name == "":
+		writeValidation(w, "ModelName is required.")
+		return
+	case primary == nil && len(containers) == 0:
+		writeValidation(w, "Either PrimaryContainer or Containers is required.")
+		return
+	case h.GetString(params, "ExecutionRoleArn") == "":
+		writeValidation(w, "ExecutionRoleArn is required.")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, exists := s.models[name]; exists {
+		writeValidation(w, "Cannot create already existing model \""+resourceArn("model", name)+"\".")
+		return
+	}
+	m := &model{
+		name:             name,
+		arn:              resourceArn("model", name),
+		executionRoleArn: h.GetString(params, "ExecutionRoleArn"),
+		primaryContainer: primary,
+		containers:       containers,
+		created:          s.now(),
+	}
+	s.models[name] = m
+	s.tags.Replace(m.arn, tags.FromList(params["Tags"], "Key", "Value"))
+
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{"ModelArn": m.arn})
+}
+
+func (s *Service) describeModel(w http.ResponseWriter, params map[string]interface{}) {
+	name := h.GetString(params, "ModelName")
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	m, exists := s.models[name]
+	if !exists {
+		writeValidation(w, "Could not find model \""+resourceArn("model", name)+"\".")
+		return
+	}
+	out := map[string]interface{}{
+		"ModelName":        m.name,
+		"ModelArn":         m.arn,
+		"ExecutionRoleArn": m.executionRoleArn,
+		"CreationTime":     float64(m.created.Unix()),
+	}
+	if m.primaryContainer != nil {
+		out["PrimaryContainer"] = m.primaryContainer
+	}
+	if m.containers != nil {
+		out["Containers"] = m.containers
+	}
+	h.WriteJSON(w, http.StatusOK, out)
+}
+
+func (s *Service) deleteModel(w http.ResponseWriter, params map[string]interface{}) {
+	name := h.GetString(params, "ModelName")
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	m, exists := s.models[name]
+	if !exists {
+		writeValidation(w, "Could not find model \""+resourceArn("model", name)+"\".")
+		return
+	}
+	delete(s.models, name)
+	s.tags.Delete(m.arn)
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{})
+}
+
+// --- Endpoint configurations ---
+
+func (s *Service) createEndpointConfig(w http.ResponseWriter, params map[string]interface{}) {
+	name := h.GetString(params, "EndpointConfigName")
+	variants, _ := params["ProductionVariants"].([]interface{})
+	switch {
+	case name == "":
+		writeValidation(w, "EndpointConfigName is required.")
+		return
+	case len(variants) == 0:
+		writeValidation(w, "ProductionVariants must contain at least one variant.")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, exists := s.endpointConfigs[name]; exists {
+		writeValidation(w, "Cannot create already existing endpoint configuration \""+resourceArn("endpoint-config", name)+"\".")
+		return
+	}
+	for _, v := range variants {
+		variant, _ := v.(map[string]interface{})
+		if h.GetString(variant, "VariantName") == "" {
+			writeValidation(w, "Each production variant requires a VariantName.")
+			return
+		}
+		if modelName := h.GetString(variant, "ModelName"); s.models[modelName] == nil {
+			writeValidation(w, "Could not find model \""+resourceArn("model", modelName)+"\".")
+			return
+		}
+	}
+	c := &endpointConfig{
+		name:     name,
+		arn:      resourceArn("endpoint-config", name),
+		variants: variants,
+		created:  s.now(),
+	}
+	s.endpointConfigs[name] = c
+	s.tags.Replace(c.arn, tags.FromList(params["Tags"], "Key", "Value"))
+
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{"EndpointConfigArn": c.arn})
+}
+
+func (s *Service) describeEndpointConfig(w http.ResponseWriter, params map[string]interface{}) {
+	name := h.GetString(params, "EndpointConfigName")
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	c, exists := s.endpointConfigs[name]
+	if !exists {
+		writeValidation(w, "Could not find endpoint configuration \""+resourceArn("endpoint-config", name)+"\".")
+		return
+	}
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"EndpointConfigName": c.name,
+		"EndpointConfigArn":  c.arn,
+		"ProductionVariants": c.variants,
+		"CreationTime":       float64(c.created.Unix()),
+	})
+}
+
+func (s *Service) deleteEndpointConfig(w http.ResponseWriter, params map[string]interface{}) {
+	name := h.GetString(params, "EndpointConfigName")
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c, exists := s.endpointConfigs[name]
+	if !exists {
+		writeValidation(w, "Could not find endpoint configuration \""+resourceArn("endpoint-config", name)+"\".")
+		return
+	}
+	delete(s.endpointConfigs, name)
+	s.tags.Delete(c.arn)
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{})
+}
+
+// --- Endpoints ---
+
+func (s *Service) createEndpoint(w http.ResponseWriter, params map[string]interface{}) {
+	name := h.GetString(params, "EndpointName")
+	configName := h.GetString(params, "EndpointConfigName")
+	if name == "" {
+		writeValidation(w, "EndpointName is required.")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, exists := s.endpoints[name]; exists {
+		writeValidation(w, "Cannot create already existing endpoint \""+resourceArn("endpoint", name)+"\".")
+		return
+	}
+	if s.endpointConfigs[configName] == nil {
+		writeValidation(w, "Could not find endpoint configuration \""+resourceArn("endpoint-config", configName)+"\".")
+		return
+	}
+	e := &endpoint{
+		name:       name,
+		arn:        resourceArn("endpoint", name),
+		configName: configName,
+		created:    s.now(),
+		ready:      s.transitions.Begin(),
+	}
+	s.endpoints[name] = e
+	s.tags.Replace(e.arn, tags.FromList(params["Tags"], "Key", "Value"))
+
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{"EndpointArn": e.arn})
+}
+
+func (s *Service) describeEndpoint(w http.ResponseWriter, params map[string]interface{}) {
+	name := h.GetString(params, "EndpointName")
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	e, exists := s.endpoints[name]
+	if !exists {
+		writeValidation(w, "Could not find endpoint \""+resourceArn("endpoint", name)+"\".")
+		return
+	}
+	status := s.transitions.Status(e.ready, "Creating", "InService")
+	out := map[string]interface{}{
+		"EndpointName":       e.name,
+		"EndpointArn":        e.arn,
+		"EndpointConfigName": e.configName,
+		"EndpointStatus":     status,
+		"CreationTime":       float64(e.created.Unix()),
+		"LastModifiedTime":   float64(e.created.Unix()),
+	}
+	// The variants are reported once the endpoint is deployed, from the
+	// configuration it was created with if that still exists.
+	if c := s.endpointConfigs[e.configName]; c != nil && status == "InService" {
+		variants := make([]map[string]interface{}, 0, len(c.variants))
+		for _, v := range c.variants {
+			variant, _ := v.(map[string]interface{})
+			count := h.GetInt(variant, "InitialInstanceCount", 1)
+			variants = append(variants, map[string]interface{}{
+				"VariantName":          h.GetString(variant, "VariantName"),
+				"CurrentWeight":        1.0,
+				"DesiredWeight":        1.0,
+				"CurrentInstanceCount": count,
+				"DesiredInstanceCount": count,
+			})
+		}
+		out["ProductionVariants"] = variants
+	}
+	h.WriteJSON(w, http.StatusOK, out)
+}
+
+func (s *Service) deleteEndpoint(w http.ResponseWriter, params map[string]interface{}) {
+	name := h.GetString(params, "EndpointName")
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, exists := s.endpoints[name]
+	if !exists {
+		writeValidation(w, "Could not find endpoint \""+resourceArn("endpoint", name)+"\".")
+		return
+	}
+	delete(s.endpoints, name)
+	s.tags.Delete(e.arn)
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{})
+}
@@ -0,0 +1,118 @@
+package sagemaker
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	"github.com/riyanimam/goto/internal/awserr"
+	h "github.com/riyanimam/goto/internal/mockhelpers"
+)
+
+// SetEndpointHandler makes InvokeEndpoint requests for the named endpoint
+// run handler instead of echoing their body. The handler receives a POST to
+// /invocations with the request's body and its Content-Type, Accept, and
+// custom attributes headers, as a model container would. A 2xx response is
+// returned to the caller; any other status is reported as a ModelError. The
+// handler applies to any endpoint created with that name, and survives
+// Reset.
+func (s *Service) SetEndpointHandler(name string, handler http.Handler) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.handlers[name] = handler
+}
+
+// invocationEndpoint returns the endpoint name of an InvokeEndpoint path,
+// /endpoints/{EndpointName}/invocations.
+func invocationEndpoint(path string) (string, bool) {
+	rest, ok := strings.CutPrefix(path, "/endpoints/")
+	if !ok {
+		return "", false
+	}
+	name, ok := strings.CutSuffix(rest, "/invocations")
+	return name, ok && name != "" && !strings.Contains(name, "/")
+}
+
+func writeRuntimeValidation(w http.ResponseWriter, message string) {
+	awserr.WriteJSON(w, "application/json", "ValidationError", message, http.StatusBadRequest)
+}
+
+func (s *Service) invokeEndpoint(w http.ResponseWriter, r *http.Request, name string) {
+	s.mu.RLock()
+	e, exists := s.endpoints[name]
+	var variants []string
+	ready := exists && s.transitions.Done(e.ready)
+	if exists {
+		if c := s.endpointConfigs[e.configName]; c != nil {
+			for _, v := range c.variants {
+				variant, _ := v.(map[string]interface{})
+				variants = append(variants, h.GetString(variant, "VariantName"))
+			}
+		}
+	}
+	handler := s.handlers[name]
+	s.mu.RUnlock()
+
+	if !exists {
+		writeRuntimeValidation(w, fmt.Sprintf("Endpoint %s of account %s not found.", name, h.DefaultAccountID))
+		return
+	}
+	if !ready || len(variants) == 0 {
+		writeRuntimeValidation(w, fmt.Sprintf("Endpoint %s of account %s is not in service.", name, h.DefaultAccountID))
+		return
+	}
+	variant := variants[0]
+	if target := r.Header.Get("X-Amzn-SageMaker-Target-Variant"); target != "" {
+		found := false
+		for _, v := range variants {
+			found = found || v == target
+		}
+		if !found {
+			writeRuntimeValidation(w, fmt.Sprintf("Endpoint %s does not have target variant %s.", name, target))
+			return
+		}
+		variant = target
+	}
+
+	body, _ := io.ReadAll(r.Body)
+	contentType := r.Header.Get("Content-Type")
+	rec := httptest.NewRecorder()
+	if handler == nil {
+		rec.Header().Set("Content-Type", contentType)
+		rec.Write(body)
+	} else {
+		req, _ := http.NewRequestWithContext(r.Context(), http.MethodPost, "/invocations", strings.NewReader(string(body)))
+		for _, header := range []string{"Content-Type", "Accept", "X-Amzn-SageMaker-Custom-Attributes"} {
+			if v := r.Header.Get(header); v != "" {
+				req.Header.Set(header, v)
+			}
+		}
+		handler.ServeHTTP(rec, req)
+	}
+
+	if rec.Code < 200 || rec.Code > 299 {
+		message := rec.Body.String()
+		w.Header().Set("X-Amzn-ErrorType", "ModelError")
+		h.WriteJSON(w, http.StatusFailedDependency, map[string]interface{}{
+			"__type":             "ModelError",
+			"message":            fmt.Sprintf("Received client error (%d) from primary with message %q. See the CloudWatch logs for more details.", rec.Code, message),
+			"OriginalStatusCode": rec.Code,
+			"OriginalMessage":    message,
+			"LogStreamArn":       fmt.Sprintf("arn:aws:logs:us-east-1:%s:log-group:/aws/sagemaker/Endpoints/%s", h.DefaultAccountID, name),
+		})
+		return
+	}
+	respType := rec.Header().Get("Content-Type")
+	if respType == "" {
+		respType = "application/json"
+	}
+	w.Header().Set("Content-Type", respType)
+	w.Header().Set("X-Amzn-Invoked-Production-Variant", variant)
+	if v := rec.Header().Get("X-Amzn-SageMaker-Custom-Attributes"); v != "" {
+		w.Header().Set("X-Amzn-SageMaker-Custom-Attributes", v)
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write(rec.Body.Bytes())
+}
@@ -0,0 +1,298 @@
+// Package sagemaker provides a mock implementation of Amazon SageMaker and
+// the SageMaker Runtime.
+//
+// Supported actions:
+//   - CreateTrainingJob
+//   - DescribeTrainingJob
+//   - StopTrainingJob
+//   - CreateModel
+//   - DescribeModel
+//   - DeleteModel
+//   - CreateEndpointConfig
+//   - DescribeEndpointConfig
+//   - DeleteEndpointConfig
+//   - CreateEndpoint
+//   - DescribeEndpoint
+//   - DeleteEndpoint
+//   - InvokeEndpoint (SageMaker Runtime)
+//
+// No training code runs. A training job passes through the secondary
+// statuses Starting, Downloading, Training, and Uploading to Completed, one
+// step per lifecycle transition, and reports its model artifacts under its
+// output path. Endpoints are Creating until their transition completes and
+// then InService. InvokeEndpoint, which the runtime signs for the same
+// "sagemaker" scope, sends the request to the http.Handler registered for
+// the endpoint with [Service.SetEndpointHandler], the way SageMaker sends
+// it to a model container's /invocations route; endpoints without a handler
+// echo the request body.
+package sagemaker
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/riyanimam/goto/internal/clock"
+	"github.com/riyanimam/goto/internal/lifecycle"
+	h "github.com/riyanimam/goto/internal/mockhelpers"
+	"github.com/riyanimam/goto/internal/tags"
+)
+
+// Service implements the SageMaker mock.
+type Service struct {
+	mu              sync.RWMutex
+	trainingJobs    map[string]*trainingJob
+	models          map[string]*model
+	endpointConfigs map[string]*endpointConfig
+	endpoints       map[string]*endpoint
+	handlers        map[string]http.Handler // by endpoint name
+	tags            *tags.Store
+
+	clock       *clock.Clock
+	transitions *lifecycle.Transitions
+}
+
+// New creates a new SageMaker mock service.
+func New() *Service {
+	return &Service{
+		trainingJobs:    make(map[string]*trainingJob),
+		models:          make(map[string]*model),
+		endpointConfigs: make(map[string]*endpointConfig),
+		endpoints:       make(map[string]*endpoint),
+		handlers:        make(map[string]http.Handler),
+		tags:            tags.New(),
+	}
+}
+
+// Name returns the service identifier.
+func (s *Service) Name() string { return "sagemaker" }
+
+// Handler returns the HTTP handler for SageMaker and SageMaker Runtime
+// requests.
+func (s *Service) Handler() http.Handler {
+	api := h.JSONRouter{
+		"CreateTrainingJob":      s.createTrainingJob,
+		"DescribeTrainingJob":    s.describeTrainingJob,
+		"StopTrainingJob":        s.stopTrainingJob,
+		"CreateModel":            s.createModel,
+		"DescribeModel":          s.describeModel,
+		"DeleteModel":            s.deleteModel,
+		"CreateEndpointConfig":   s.createEndpointConfig,
+		"DescribeEndpointConfig": s.describeEndpointConfig,
+		"DeleteEndpointConfig":   s.deleteEndpointConfig,
+		"CreateEndpoint":         s.createEndpoint,
+		"DescribeEndpoint":       s.describeEndpoint,
+		"DeleteEndpoint":         s.deleteEndpoint,
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if name, ok := invocationEndpoint(r.URL.Path); ok && r.Method == http.MethodPost {
+			s.invokeEndpoint(w, r, name)
+			return
+		}
+		api.ServeHTTP(w, r)
+	})
+}
+
+// Reset clears all state. Endpoint handlers survive.
+func (s *Service) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.trainingJobs = make(map[string]*trainingJob)
+	s.models = make(map[string]*model)
+	s.endpointConfigs = make(map[string]*endpointConfig)
+	s.endpoints = make(map[string]*endpoint)
+	s.tags.DeleteService("sagemaker")
+}
+
+// SetTagStore sets the registry resource tags are recorded in.
+func (s *Service) SetTagStore(store *tags.Store) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tags = store
+}
+
+// SetTransitions sets how long endpoints stay Creating and each training job
+// status lasts.
+func (s *Service) SetTransitions(t *lifecycle.Transitions) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.transitions = t
+}
+
+// SetClock attaches the mock clock used for creation times.
+func (s *Service) SetClock(c *clock.Clock) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.clock = c
+}
+
+func (s *Service) now() time.Time {
+	if s.clock != nil {
+		return s.clock.Now()
+	}
+	return time.Now().UTC()
+}
+
+// resourceArn returns the ARN of a SageMaker resource. SageMaker lowercases
+// resource names in ARNs.
+func resourceArn(kind, name string) string {
+	return fmt.Sprintf("arn:aws:sagemaker:us-east-1:%s:%s/%s", h.DefaultAccountID, kind, strings.ToLower(name))
+}
+
+func writeValidation(w http.ResponseWriter, message string) {
+	h.WriteJSONError(w, "ValidationException", message, http.StatusBadRequest)
+}
+
+// --- Training jobs ---
+
+// secondaryStatuses are the secondary statuses a training job passes
+// through on its way to completion.
+var secondaryStatuses = []string{"Starting", "Downloading", "Training", "Uploading", "Completed"}
+
+type trainingJob struct {
+	name          string
+	arn           string
+	roleArn       string
+	algorithm     map[string]interface{}
+	hyperParams   map[string]interface{}
+	inputData     []interface{}
+	outputPath    string
+	resources     map[string]interface{}
+	stopCondition map[string]interface{}
+	created       time.Time
+	run           lifecycle.Transition
+	stopped       bool
+	stoppedAt     time.Time
+}
+
+// trainingStatus returns j's primary and secondary status. The caller must
+// hold s.mu.
+func (s *Service) trainingStatus(j *trainingJob) (string, string) {
+	if j.stopped {
+		return "Stopped", "Stopped"
+	}
+	secondary := s.transitions.Stage(j.run, secondaryStatuses...)
+	if secondary == "Completed" {
+		return "Completed", secondary
+	}
+	return "InProgress", secondary
+}
+
+func (s *Service) createTrainingJob(w http.ResponseWriter, params map[string]interface{}) {
+	name := h.GetString(params, "TrainingJobName")
+	algorithm, _ := params["AlgorithmSpecification"].(map[string]interface{})
+	output, _ := params["OutputDataConfig"].(map[string]interface{})
+	resources, _ := params["ResourceConfig"].(map[string]interface{})
+	stopCondition, _ := params["StoppingCondition"].(map[string]interface{})
+	switch {
+	case name == "":
+		writeValidation(w, "TrainingJobName is required.")
+		return
+	case h.GetString(params, "RoleArn") == "":
+		writeValidation(w, "RoleArn is required.")
+		return
+	case h.GetString(algorithm, "TrainingImage") == "" && h.GetString(algorithm, "AlgorithmName") == "":
+		writeValidation(w, "AlgorithmSpecification must set TrainingImage or AlgorithmName.")
+		return
+	case !strings.HasPrefix(h.GetString(output, "S3OutputPath"), "s3://"):
+		writeValidation(w, "OutputDataConfig.S3OutputPath must be an s3:// URI.")
+		return
+	case resources == nil:
+		writeValidation(w, "ResourceConfig is required.")
+		return
+	case stopCondition == nil:
+		writeValidation(w, "StoppingCondition is required.")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, exists := s.trainingJobs[name]; exists {
+		h.WriteJSONError(w, "ResourceInUse", "Training job names must be unique within an AWS account and region, and a training job with this name already exists ("+resourceArn("training-job", name)+")", http.StatusBadRequest)
+		return
+	}
+	hyperParams, _ := params["HyperParameters"].(map[string]interface{})
+	inputData, _ := params["InputDataConfig"].([]interface{})
+	j := &trainingJob{
+		name:          name,
+		arn:           resourceArn("training-job", name),
+		roleArn:       h.GetString(params, "RoleArn"),
+		algorithm:     algorithm,
+		hyperParams:   hyperParams,
+		inputData:     inputData,
+		outputPath:    strings.TrimSuffix(h.GetString(output, "S3OutputPath"), "/"),
+		resources:     resources,
+		stopCondition: stopCondition,
+		created:       s.now(),
+		run:           s.transitions.Begin(),
+	}
+	s.trainingJobs[name] = j
+	s.tags.Replace(j.arn, tags.FromList(params["Tags"], "Key", "Value"))
+
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{"TrainingJobArn": j.arn})
+}
+
+func (s *Service) describeTrainingJob(w http.ResponseWriter, params map[string]interface{}) {
+	name := h.GetString(params, "TrainingJobName")
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	j, exists := s.trainingJobs[name]
+	if !exists {
+		h.WriteJSONError(w, "ResourceNotFound", "Requested resource not found.", http.StatusBadRequest)
+		return
+	}
+	status, secondary := s.trainingStatus(j)
+	created := float64(j.created.Unix())
+	out := map[string]interface{}{
+		"TrainingJobName":        j.name,
+		"TrainingJobArn":         j.arn,
+		"TrainingJobStatus":      status,
+		"SecondaryStatus":        secondary,
+		"RoleArn":                j.roleArn,
+		"AlgorithmSpecification": j.algorithm,
+		"OutputDataConfig":       map[string]interface{}{"S3OutputPath": j.outputPath},
+		"ResourceConfig":         j.resources,
+		"StoppingCondition":      j.stopCondition,
+		"CreationTime":           created,
+		"LastModifiedTime":       created,
+		"TrainingStartTime":      created,
+	}
+	if j.hyperParams != nil {
+		out["HyperParameters"] = j.hyperParams
+	}
+	if j.inputData != nil {
+		out["InputDataConfig"] = j.inputData
+	}
+	switch status {
+	case "Completed":
+		out["ModelArtifacts"] = map[string]interface{}{
+			"S3ModelArtifacts": j.outputPath + "/" + j.name + "/output/model.tar.gz",
+		}
+		out["TrainingEndTime"] = created
+		out["BillableTimeInSeconds"] = 0
+	case "Stopped":
+		out["TrainingEndTime"] = float64(j.stoppedAt.Unix())
+		out["LastModifiedTime"] = float64(j.stoppedAt.Unix())
+	}
+	h.WriteJSON(w, http.StatusOK, out)
+}
+
+func (s *Service) stopTrainingJob(w http.ResponseWriter, params map[string]interface{}) {
+	name := h.GetString(params, "TrainingJobName")
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	j, exists := s.trainingJobs[name]
+	if !exists {
+		h.WriteJSONError(w, "ResourceNotFound", "Requested resource not found.", http.StatusBadRequest)
+		return
+	}
+	if status, _ := s.trainingStatus(j); status != "InProgress" {
+		writeValidation(w, "The request was rejected because the training job is in status "+status+".")
+		return
+	}
+	j.stopped = true
+	j.stoppedAt = s.now()
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{})
+}
@@ -0,0 +1,240 @@
+package scheduler
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// validateExpression checks that expr is an at(), rate(), or cron()
+// expression the mock knows how to evaluate. An empty expression is accepted.
+func validateExpression(expr string) error {
+	if expr == "" {
+		return nil
+	}
+	switch {
+	case strings.HasPrefix(expr, "at("):
+		_, err := parseAt(expr, time.UTC)
+		return err
+	case strings.HasPrefix(expr, "rate("):
+		_, err := parseRate(expr)
+		return err
+	case strings.HasPrefix(expr, "cron("):
+		_, err := parseCron(expr)
+		return err
+	}
+	return fmt.Errorf("invalid schedule expression %s", expr)
+}
+
+// occurrences returns the times in (from, to] at which sched should fire.
+func occurrences(sched *schedule, from, to time.Time) []time.Time {
+	loc := time.UTC
+	if sched.timezone != "" {
+		if l, err := time.LoadLocation(sched.timezone); err == nil {
+			loc = l
+		}
+	}
+
+	var times []time.Time
+	expr := sched.scheduleExpression
+	switch {
+	case strings.HasPrefix(expr, "at("):
+		if t, err := parseAt(expr, loc); err == nil && t.After(from) && !t.After(to) {
+			times = append(times, t)
+		}
+	case strings.HasPrefix(expr, "rate("):
+		interval, err := parseRate(expr)
+		if err != nil {
+			return nil
+		}
+		anchor := sched.created
+		if sched.startDate != nil {
+			anchor = *sched.startDate
+		}
+		// First occurrence strictly after from.
+		n := int64(1)
+		if from.After(anchor) {
+			n = int64(from.Sub(anchor)/interval) + 1
+		}
+		for t := anchor.Add(time.Duration(n) * interval); !t.After(to); t = t.Add(interval) {
+			times = append(times, t)
+		}
+	case strings.HasPrefix(expr, "cron("):
+		c, err := parseCron(expr)
+		if err != nil {
+			return nil
+		}
+		for t := from.Truncate(time.Minute).Add(time.Minute); !t.After(to); t = t.Add(time.Minute) {
+			if c.matches(t.In(loc)) {
+				times = append(times, t)
+			}
+		}
+	}
+
+	// Drop occurrences outside the schedule's active window.
+	kept := times[:0]
+	for _, t := range times {
+		if sched.startDate != nil && t.Before(*sched.startDate) {
+			continue
+		}
+		if sched.endDate != nil && t.After(*sched.endDate) {
+			continue
+		}
+		kept = append(kept, t)
+	}
+	return kept
+}
+
+func inner(expr, prefix string) string {
+	return strings.TrimSuffix(strings.TrimPrefix(expr, prefix+"("), ")")
+}
+
+func parseAt(expr string, loc *time.Location) (time.Time, error) {
+	t, err := time.ParseInLocation("2006-01-02T15:04:05", inner(expr, "at"), loc)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid schedule expression %s", expr)
+	}
+	return t.UTC(), nil
+}
+
+func parseRate(expr string) (time.Duration, error) {
+	fields := strings.Fields(inner(expr, "rate"))
+	if len(fields) != 2 {
+		return 0, fmt.Errorf("invalid schedule expression %s", expr)
+	}
+	n, err := strconv.Atoi(fields[0])
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid schedule expression %s", expr)
+	}
+	var unit time.Duration
+	switch strings.TrimSuffix(fields[1], "s") {
+	case "minute":
+		unit = time.Minute
+	case "hour":
+		unit = time.Hour
+	case "day":
+		unit = 24 * time.Hour
+	default:
+		return 0, fmt.Errorf("invalid schedule expression %s", expr)
+	}
+	return time.Duration(n) * unit, nil
+}
+
+// cronExpr is a parsed six-field AWS cron expression:
+// minutes hours day-of-month month day-of-week year.
+type cronExpr struct {
+	minutes, hours, days, months, weekdays, years map[int]bool
+}
+
+var (
+	monthNames   = map[string]int{"JAN": 1, "FEB": 2, "MAR": 3, "APR": 4, "MAY": 5, "JUN": 6, "JUL": 7, "AUG": 8, "SEP": 9, "OCT": 10, "NOV": 11, "DEC": 12}
+	weekdayNames = map[string]int{"SUN": 1, "MON": 2, "TUE": 3, "WED": 4, "THU": 5, "FRI": 6, "SAT": 7}
+)
+
+func parseCron(expr string) (*cronExpr, error) {
+	fields := strings.Fields(inner(expr, "cron"))
+	if len(fields) != 6 {
+		return nil, fmt.Errorf("invalid schedule expression %s", expr)
+	}
+
+	specs := []struct {
+		lo, hi int
+		names  map[string]int
+	}{
+		{0, 59, nil},
+		{0, 23, nil},
+		{1, 31, nil},
+		{1, 12, monthNames},
+		{1, 7, weekdayNames},
+		{1970, 2199, nil},
+	}
+
+	sets := make([]map[int]bool, len(fields))
+	for i, f := range fields {
+		set, err := parseCronField(f, specs[i].lo, specs[i].hi, specs[i].names)
+		if err != nil {
+			return nil, fmt.Errorf("invalid schedule expression %s", expr)
+		}
+		sets[i] = set
+	}
+
+	return &cronExpr{
+		minutes:  sets[0],
+		hours:    sets[1],
+		days:     sets[2],
+		months:   sets[3],
+		weekdays: sets[4],
+		years:    sets[5],
+	}, nil
+}
+
+// parseCronField expands a single cron field into the set of values it
+// matches. A nil set means the field matches everything ("*" or "?").
+func parseCronField(field string, minVal, maxVal int, names map[string]int) (map[int]bool, error) {
+	if field == "*" || field == "?" {
+		return nil, nil
+	}
+
+	value := func(s string) (int, error) {
+		if n, ok := names[strings.ToUpper(s)]; ok {
+			return n, nil
+		}
+		n, err := strconv.Atoi(s)
+		if err != nil || n < minVal || n > maxVal {
+			return 0, fmt.Errorf("invalid cron value %q", s)
+		}
+		return n, nil
+	}
+
+	set := make(map[int]bool)
+	for _, part := range strings.Split(field, ",") {
+		step := 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			n, err := strconv.Atoi(part[i+1:])
+			if err != nil || n <= 0 {
+				return nil, fmt.Errorf("invalid cron step %q", part)
+			}
+			step = n
+			part = part[:i]
+		}
+
+		lo, hi := minVal, maxVal
+		switch {
+		case part == "*":
+		case strings.Contains(part, "-"):
+			bounds := strings.SplitN(part, "-", 2)
+			var err error
+			if lo, err = value(bounds[0]); err != nil {
+				return nil, err
+			}
+			if hi, err = value(bounds[1]); err != nil {
+				return nil, err
+			}
+		default:
+			n, err := value(part)
+			if err != nil {
+				return nil, err
+			}
+			lo = n
+			if step == 1 {
+				hi = n
+			}
+		}
+
+		for v := lo; v <= hi; v += step {
+			set[v] = true
+		}
+	}
+	return set, nil
+}
+
+func (c *cronExpr) matches(t time.Time) bool {
+	match := func(set map[int]bool, v int) bool { return set == nil || set[v] }
+	return match(c.minutes, t.Minute()) &&
+		match(c.hours, t.Hour()) &&
+		match(c.days, t.Day()) &&
+		match(c.months, int(t.Month())) &&
+		match(c.weekdays, int(t.Weekday())+1) &&
+		match(c.years, t.Year())
+}
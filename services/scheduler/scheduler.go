@@ -6,6 +6,21 @@
 //   - DeleteSchedule
 //   - ListSchedules
 //   - UpdateSchedule
+//   - CreateScheduleGroup
+//   - GetScheduleGroup
+//   - DeleteScheduleGroup
+//   - ListScheduleGroups
+//   - TagResource
+//   - UntagResource
+//   - ListTagsForResource
+//
+// Enabled schedules fire when the mock clock is advanced past one of their
+// occurrences, or on demand via [Service.Trigger]. Each invocation delivers
+// the target's Input to the target ARN (Lambda, SQS, SNS, Step Functions).
+// Failed deliveries are retried with exponential backoff as the clock
+// advances further, within the target's RetryPolicy, and the input is sent
+// to the DeadLetterConfig queue once the policy's attempts or maximum event
+// age run out.
 package scheduler
 
 import (
@@ -18,33 +33,95 @@ import (
 	"sync"
 	"time"
 
+	"github.com/riyanimam/goto/internal/clock"
 	h "github.com/riyanimam/goto/internal/mockhelpers"
+	"github.com/riyanimam/goto/internal/tags"
+)
+
+const (
+	defaultGroup = "default"
+
+	// defaultMaxRetryAttempts and defaultMaxEventAge match the AWS default
+	// retry policy.
+	defaultMaxRetryAttempts = 185
+	defaultMaxEventAge      = 24 * time.Hour
+
+	// Retries back off exponentially from retryBaseDelay up to
+	// retryMaxDelay.
+	retryBaseDelay = time.Second
+	retryMaxDelay  = 10 * time.Minute
 )
 
 // Service implements the EventBridge Scheduler mock.
 type Service struct {
-	mu        sync.RWMutex
-	schedules map[string]*schedule
+	mu          sync.RWMutex
+	schedules   map[string]*schedule // keyed by group + "/" + name
+	groups      map[string]*scheduleGroup
+	invocations []*Invocation
+	retries     []*retry
+
+	clock    *clock.Clock
+	dispatch h.Dispatcher
+	tags     *tags.Store
 }
 
 type schedule struct {
-	name               string
-	arn                string
-	scheduleExpression string
-	target             interface{}
-	flexibleTimeWindow interface{}
-	state              string
-	groupName          string
-	description        string
-	created            time.Time
-	modified           time.Time
+	name                  string
+	arn                   string
+	scheduleExpression    string
+	timezone              string
+	target                map[string]interface{}
+	flexibleTimeWindow    interface{}
+	state                 string
+	groupName             string
+	description           string
+	actionAfterCompletion string
+	startDate             *time.Time
+	endDate               *time.Time
+	created               time.Time
+	modified              time.Time
+}
+
+type scheduleGroup struct {
+	name     string
+	arn      string
+	state    string
+	created  time.Time
+	modified time.Time
+}
+
+// retry is a failed delivery waiting for the clock to reach its next
+// attempt.
+type retry struct {
+	inv      *Invocation
+	target   map[string]interface{}
+	at       time.Time
+	deadline time.Time
+	max      int
+}
+
+// Invocation records a schedule's delivery to its target, across the first
+// attempt and any retries.
+type Invocation struct {
+	ScheduleName string
+	GroupName    string
+	TargetArn    string
+	Input        string
+	Time         time.Time
+	Attempts     int
+	// Err is the error of the latest attempt. It stays set while retries
+	// are pending.
+	Err error
+	// DeadLettered is true when all attempts failed and the input was sent
+	// to the target's dead-letter queue.
+	DeadLettered bool
 }
 
 // New creates a new Scheduler mock service.
 func New() *Service {
-	return &Service{
-		schedules: make(map[string]*schedule),
-	}
+	s := &Service{tags: tags.New()}
+	s.Reset()
+	return s
 }
 
 // Name returns the service identifier.
@@ -59,7 +136,81 @@ func (s *Service) Handler() http.Handler {
 func (s *Service) Reset() {
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	now := s.now()
 	s.schedules = make(map[string]*schedule)
+	s.groups = map[string]*scheduleGroup{
+		defaultGroup: {
+			name:     defaultGroup,
+			arn:      groupArn(defaultGroup),
+			state:    "ACTIVE",
+			created:  now,
+			modified: now,
+		},
+	}
+	s.invocations = nil
+	s.retries = nil
+	s.tags.DeleteService("scheduler")
+}
+
+// SetTagStore sets the registry schedule group tags are recorded in.
+func (s *Service) SetTagStore(store *tags.Store) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tags = store
+}
+
+// SetClock attaches the mock clock. Schedules fire as the clock advances.
+func (s *Service) SetClock(c *clock.Clock) {
+	s.mu.Lock()
+	s.clock = c
+	s.mu.Unlock()
+	c.OnAdvance(s.fireDue)
+}
+
+// SetDispatcher sets the function used to deliver schedule input to targets.
+func (s *Service) SetDispatcher(d h.Dispatcher) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.dispatch = d
+}
+
+// Invocations returns every target invocation performed so far, oldest first.
+func (s *Service) Invocations() []Invocation {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	out := make([]Invocation, len(s.invocations))
+	for i, inv := range s.invocations {
+		out[i] = *inv
+	}
+	return out
+}
+
+// Trigger fires the named schedule immediately, regardless of its expression
+// or state, and returns the error of the first attempt. Retries follow as
+// the clock advances. The name may be qualified with its group as
+// "group/name"; unqualified names refer to the default group.
+func (s *Service) Trigger(name string) error {
+	group := defaultGroup
+	if i := strings.Index(name, "/"); i >= 0 {
+		group, name = name[:i], name[i+1:]
+	}
+
+	s.mu.RLock()
+	sched, ok := s.schedules[scheduleKey(group, name)]
+	s.mu.RUnlock()
+	if !ok {
+		return fmt.Errorf("schedule %s/%s not found", group, name)
+	}
+
+	inv := s.invoke(sched)
+	return inv.Err
+}
+
+func (s *Service) now() time.Time {
+	if s.clock != nil {
+		return s.clock.Now()
+	}
+	return time.Now().UTC()
 }
 
 func (s *Service) handle(w http.ResponseWriter, r *http.Request) {
@@ -77,6 +228,20 @@ func (s *Service) handle(w http.ResponseWriter, r *http.Request) {
 		s.updateSchedule(w, r, path)
 	case strings.HasPrefix(path, "/schedules/") && method == http.MethodDelete:
 		s.deleteSchedule(w, r, path)
+	case path == "/schedule-groups" && method == http.MethodGet:
+		s.listScheduleGroups(w, r)
+	case strings.HasPrefix(path, "/schedule-groups/") && method == http.MethodPost:
+		s.createScheduleGroup(w, r, path)
+	case strings.HasPrefix(path, "/schedule-groups/") && method == http.MethodGet:
+		s.getScheduleGroup(w, r, path)
+	case strings.HasPrefix(path, "/schedule-groups/") && method == http.MethodDelete:
+		s.deleteScheduleGroup(w, r, path)
+	case strings.HasPrefix(path, "/tags/") && method == http.MethodPost:
+		s.tagResource(w, r, strings.TrimPrefix(path, "/tags/"))
+	case strings.HasPrefix(path, "/tags/") && method == http.MethodDelete:
+		s.untagResource(w, r, strings.TrimPrefix(path, "/tags/"))
+	case strings.HasPrefix(path, "/tags/") && method == http.MethodGet:
+		s.listTagsForResource(w, strings.TrimPrefix(path, "/tags/"))
 	default:
 		h.WriteJSONError(w, "NotFoundException", "unsupported operation", http.StatusNotFound)
 	}
@@ -90,6 +255,21 @@ func extractName(path string) string {
 	return ""
 }
 
+func scheduleKey(group, name string) string {
+	return group + "/" + name
+}
+
+func groupArn(name string) string {
+	return fmt.Sprintf("arn:aws:scheduler:us-east-1:%s:schedule-group/%s", h.DefaultAccountID, name)
+}
+
+func groupParam(r *http.Request) string {
+	if g := r.URL.Query().Get("groupName"); g != "" {
+		return g
+	}
+	return defaultGroup
+}
+
 func (s *Service) createSchedule(w http.ResponseWriter, r *http.Request, path string) {
 	name := extractName(path)
 	if name == "" {
@@ -101,15 +281,31 @@ func (s *Service) createSchedule(w http.ResponseWriter, r *http.Request, path st
 	var params map[string]interface{}
 	json.Unmarshal(bodyBytes, &params)
 
+	group := h.GetString(params, "GroupName")
+	if group == "" {
+		group = defaultGroup
+	}
+	expr := h.GetString(params, "ScheduleExpression")
+	if err := validateExpression(expr); err != nil {
+		h.WriteJSONError(w, "ValidationException", err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	s.mu.Lock()
-	if _, exists := s.schedules[name]; exists {
+	if _, ok := s.groups[group]; !ok {
+		s.mu.Unlock()
+		h.WriteJSONError(w, "ResourceNotFoundException", "Schedule group "+group+" does not exist.", http.StatusNotFound)
+		return
+	}
+	key := scheduleKey(group, name)
+	if _, exists := s.schedules[key]; exists {
 		s.mu.Unlock()
 		h.WriteJSONError(w, "ConflictException", "Schedule "+name+" already exists", http.StatusConflict)
 		return
 	}
 
-	now := time.Now().UTC()
-	arn := fmt.Sprintf("arn:aws:scheduler:us-east-1:%s:schedule/default/%s", h.DefaultAccountID, name)
+	now := s.now()
+	arn := fmt.Sprintf("arn:aws:scheduler:us-east-1:%s:schedule/%s/%s", h.DefaultAccountID, group, name)
 
 	state := h.GetString(params, "State")
 	if state == "" {
@@ -117,18 +313,15 @@ func (s *Service) createSchedule(w http.ResponseWriter, r *http.Request, path st
 	}
 
 	sched := &schedule{
-		name:               name,
-		arn:                arn,
-		scheduleExpression: h.GetString(params, "ScheduleExpression"),
-		target:             params["Target"],
-		flexibleTimeWindow: params["FlexibleTimeWindow"],
-		state:              state,
-		groupName:          h.GetString(params, "GroupName"),
-		description:        h.GetString(params, "Description"),
-		created:            now,
-		modified:           now,
-	}
-	s.schedules[name] = sched
+		name:      name,
+		arn:       arn,
+		state:     state,
+		groupName: group,
+		created:   now,
+		modified:  now,
+	}
+	applyScheduleParams(sched, params)
+	s.schedules[key] = sched
 	s.mu.Unlock()
 
 	h.WriteJSON(w, http.StatusOK, map[string]interface{}{
@@ -136,11 +329,16 @@ func (s *Service) createSchedule(w http.ResponseWriter, r *http.Request, path st
 	})
 }
 
-func (s *Service) getSchedule(w http.ResponseWriter, _ *http.Request, path string) {
+func (s *Service) getSchedule(w http.ResponseWriter, r *http.Request, path string) {
 	name := extractName(path)
+	group := groupParam(r)
 
 	s.mu.RLock()
-	sched, exists := s.schedules[name]
+	sched, exists := s.schedules[scheduleKey(group, name)]
+	var resp map[string]interface{}
+	if exists {
+		resp = scheduleResp(sched)
+	}
 	s.mu.RUnlock()
 
 	if !exists {
@@ -148,41 +346,63 @@ func (s *Service) getSchedule(w http.ResponseWriter, _ *http.Request, path strin
 		return
 	}
 
-	h.WriteJSON(w, http.StatusOK, scheduleResp(sched))
+	h.WriteJSON(w, http.StatusOK, resp)
 }
 
-func (s *Service) deleteSchedule(w http.ResponseWriter, _ *http.Request, path string) {
+func (s *Service) deleteSchedule(w http.ResponseWriter, r *http.Request, path string) {
 	name := extractName(path)
+	key := scheduleKey(groupParam(r), name)
 
 	s.mu.Lock()
-	_, exists := s.schedules[name]
+	_, exists := s.schedules[key]
 	if !exists {
 		s.mu.Unlock()
 		h.WriteJSONError(w, "ResourceNotFoundException", "Schedule "+name+" not found", http.StatusNotFound)
 		return
 	}
-	delete(s.schedules, name)
+	delete(s.schedules, key)
 	s.mu.Unlock()
 
 	h.WriteJSON(w, http.StatusOK, map[string]interface{}{})
 }
 
-func (s *Service) listSchedules(w http.ResponseWriter, _ *http.Request) {
+func (s *Service) listSchedules(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	group := q.Get("ScheduleGroup")
+	prefix := q.Get("NamePrefix")
+	state := q.Get("State")
+
 	s.mu.RLock()
-	var items []map[string]interface{}
-	var names []string
-	for name := range s.schedules {
-		names = append(names, name)
+	var keys []string
+	for key, sched := range s.schedules {
+		if group != "" && sched.groupName != group {
+			continue
+		}
+		if prefix != "" && !strings.HasPrefix(sched.name, prefix) {
+			continue
+		}
+		if state != "" && sched.state != state {
+			continue
+		}
+		keys = append(keys, key)
 	}
-	s.mu.RUnlock()
-
-	sort.Strings(names)
+	sort.Strings(keys)
 
-	s.mu.RLock()
-	for _, name := range names {
-		if sched, ok := s.schedules[name]; ok {
-			items = append(items, scheduleResp(sched))
+	var items []map[string]interface{}
+	for _, key := range keys {
+		sched := s.schedules[key]
+		item := map[string]interface{}{
+			"Name":                 sched.name,
+			"Arn":                  sched.arn,
+			"GroupName":            sched.groupName,
+			"State":                sched.state,
+			"CreationDate":         float64(sched.created.Unix()),
+			"LastModificationDate": float64(sched.modified.Unix()),
+		}
+		if arn := h.GetString(sched.target, "Arn"); arn != "" {
+			item["Target"] = map[string]interface{}{"Arn": arn}
 		}
+		items = append(items, item)
 	}
 	s.mu.RUnlock()
 
@@ -198,38 +418,72 @@ func (s *Service) updateSchedule(w http.ResponseWriter, r *http.Request, path st
 	var params map[string]interface{}
 	json.Unmarshal(bodyBytes, &params)
 
+	group := h.GetString(params, "GroupName")
+	if group == "" {
+		group = defaultGroup
+	}
+	if err := validateExpression(h.GetString(params, "ScheduleExpression")); err != nil {
+		h.WriteJSONError(w, "ValidationException", err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	s.mu.Lock()
-	sched, exists := s.schedules[name]
+	sched, exists := s.schedules[scheduleKey(group, name)]
 	if !exists {
 		s.mu.Unlock()
 		h.WriteJSONError(w, "ResourceNotFoundException", "Schedule "+name+" not found", http.StatusNotFound)
 		return
 	}
 
+	// UpdateSchedule replaces the whole definition, like the real API.
+	sched.scheduleExpression = ""
+	sched.timezone = ""
+	sched.target = nil
+	sched.flexibleTimeWindow = nil
+	sched.description = ""
+	sched.actionAfterCompletion = ""
+	sched.startDate = nil
+	sched.endDate = nil
+	applyScheduleParams(sched, params)
+	if v := h.GetString(params, "State"); v != "" {
+		sched.state = v
+	}
+	sched.modified = s.now()
+	arn := sched.arn
+	s.mu.Unlock()
+
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"ScheduleArn": arn,
+	})
+}
+
+func applyScheduleParams(sched *schedule, params map[string]interface{}) {
 	if v := h.GetString(params, "ScheduleExpression"); v != "" {
 		sched.scheduleExpression = v
 	}
-	if v, ok := params["Target"]; ok {
+	if v := h.GetString(params, "ScheduleExpressionTimezone"); v != "" {
+		sched.timezone = v
+	}
+	if v, ok := params["Target"].(map[string]interface{}); ok {
 		sched.target = v
 	}
 	if v, ok := params["FlexibleTimeWindow"]; ok {
 		sched.flexibleTimeWindow = v
 	}
-	if v := h.GetString(params, "State"); v != "" {
-		sched.state = v
-	}
-	if v := h.GetString(params, "GroupName"); v != "" {
-		sched.groupName = v
-	}
 	if _, ok := params["Description"]; ok {
 		sched.description = h.GetString(params, "Description")
 	}
-	sched.modified = time.Now().UTC()
-	s.mu.Unlock()
-
-	h.WriteJSON(w, http.StatusOK, map[string]interface{}{
-		"ScheduleArn": sched.arn,
-	})
+	if v := h.GetString(params, "ActionAfterCompletion"); v != "" {
+		sched.actionAfterCompletion = v
+	}
+	if v, ok := params["StartDate"].(float64); ok {
+		t := time.Unix(int64(v), 0).UTC()
+		sched.startDate = &t
+	}
+	if v, ok := params["EndDate"].(float64); ok {
+		t := time.Unix(int64(v), 0).UTC()
+		sched.endDate = &t
+	}
 }
 
 func scheduleResp(sched *schedule) map[string]interface{} {
@@ -238,6 +492,7 @@ func scheduleResp(sched *schedule) map[string]interface{} {
 		"Arn":                  sched.arn,
 		"ScheduleExpression":   sched.scheduleExpression,
 		"State":                sched.state,
+		"GroupName":            sched.groupName,
 		"CreationDate":         float64(sched.created.Unix()),
 		"LastModificationDate": float64(sched.modified.Unix()),
 	}
@@ -247,11 +502,347 @@ func scheduleResp(sched *schedule) map[string]interface{} {
 	if sched.flexibleTimeWindow != nil {
 		resp["FlexibleTimeWindow"] = sched.flexibleTimeWindow
 	}
-	if sched.groupName != "" {
-		resp["GroupName"] = sched.groupName
-	}
 	if sched.description != "" {
 		resp["Description"] = sched.description
 	}
+	if sched.timezone != "" {
+		resp["ScheduleExpressionTimezone"] = sched.timezone
+	}
+	if sched.actionAfterCompletion != "" {
+		resp["ActionAfterCompletion"] = sched.actionAfterCompletion
+	}
+	if sched.startDate != nil {
+		resp["StartDate"] = float64(sched.startDate.Unix())
+	}
+	if sched.endDate != nil {
+		resp["EndDate"] = float64(sched.endDate.Unix())
+	}
 	return resp
 }
+
+// Schedule groups.
+
+func (s *Service) createScheduleGroup(w http.ResponseWriter, r *http.Request, path string) {
+	name := extractName(path)
+	if name == "" {
+		h.WriteJSONError(w, "ValidationException", "name is required", http.StatusBadRequest)
+		return
+	}
+	bodyBytes, _ := io.ReadAll(r.Body)
+	var params map[string]interface{}
+	json.Unmarshal(bodyBytes, &params)
+
+	s.mu.Lock()
+	if _, exists := s.groups[name]; exists {
+		s.mu.Unlock()
+		h.WriteJSONError(w, "ConflictException", "Schedule group "+name+" already exists", http.StatusConflict)
+		return
+	}
+	now := s.now()
+	g := &scheduleGroup{
+		name:     name,
+		arn:      groupArn(name),
+		state:    "ACTIVE",
+		created:  now,
+		modified: now,
+	}
+	s.groups[name] = g
+	s.tags.Tag(g.arn, tags.FromList(params["Tags"], "Key", "Value"))
+	s.mu.Unlock()
+
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"ScheduleGroupArn": g.arn,
+	})
+}
+
+func (s *Service) getScheduleGroup(w http.ResponseWriter, _ *http.Request, path string) {
+	name := extractName(path)
+
+	s.mu.RLock()
+	g, exists := s.groups[name]
+	var resp map[string]interface{}
+	if exists {
+		resp = groupResp(g)
+	}
+	s.mu.RUnlock()
+
+	if !exists {
+		h.WriteJSONError(w, "ResourceNotFoundException", "Schedule group "+name+" does not exist.", http.StatusNotFound)
+		return
+	}
+
+	h.WriteJSON(w, http.StatusOK, resp)
+}
+
+func (s *Service) deleteScheduleGroup(w http.ResponseWriter, _ *http.Request, path string) {
+	name := extractName(path)
+	if name == defaultGroup {
+		h.WriteJSONError(w, "ValidationException", "The default schedule group cannot be deleted.", http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	if _, exists := s.groups[name]; !exists {
+		s.mu.Unlock()
+		h.WriteJSONError(w, "ResourceNotFoundException", "Schedule group "+name+" does not exist.", http.StatusNotFound)
+		return
+	}
+	delete(s.groups, name)
+	s.tags.Delete(groupArn(name))
+	for key, sched := range s.schedules {
+		if sched.groupName == name {
+			delete(s.schedules, key)
+		}
+	}
+	s.mu.Unlock()
+
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{})
+}
+
+func (s *Service) listScheduleGroups(w http.ResponseWriter, r *http.Request) {
+	prefix := r.URL.Query().Get("NamePrefix")
+
+	s.mu.RLock()
+	var names []string
+	for name := range s.groups {
+		if prefix == "" || strings.HasPrefix(name, prefix) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var items []map[string]interface{}
+	for _, name := range names {
+		items = append(items, groupResp(s.groups[name]))
+	}
+	s.mu.RUnlock()
+
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"ScheduleGroups": items,
+	})
+}
+
+func groupResp(g *scheduleGroup) map[string]interface{} {
+	return map[string]interface{}{
+		"Name":                 g.name,
+		"Arn":                  g.arn,
+		"State":                g.state,
+		"CreationDate":         float64(g.created.Unix()),
+		"LastModificationDate": float64(g.modified.Unix()),
+	}
+}
+
+// Tags. Schedule groups are the taggable resources.
+
+func (s *Service) hasGroup(arn string) bool {
+	for _, g := range s.groups {
+		if g.arn == arn {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Service) tagResource(w http.ResponseWriter, r *http.Request, arn string) {
+	bodyBytes, _ := io.ReadAll(r.Body)
+	var params map[string]interface{}
+	json.Unmarshal(bodyBytes, &params)
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if !s.hasGroup(arn) {
+		h.WriteJSONError(w, "ResourceNotFoundException", "Resource "+arn+" does not exist.", http.StatusNotFound)
+		return
+	}
+	s.tags.Tag(arn, tags.FromList(params["Tags"], "Key", "Value"))
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{})
+}
+
+func (s *Service) untagResource(w http.ResponseWriter, r *http.Request, arn string) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if !s.hasGroup(arn) {
+		h.WriteJSONError(w, "ResourceNotFoundException", "Resource "+arn+" does not exist.", http.StatusNotFound)
+		return
+	}
+	s.tags.Untag(arn, r.URL.Query()["TagKeys"])
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{})
+}
+
+func (s *Service) listTagsForResource(w http.ResponseWriter, arn string) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if !s.hasGroup(arn) {
+		h.WriteJSONError(w, "ResourceNotFoundException", "Resource "+arn+" does not exist.", http.StatusNotFound)
+		return
+	}
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"Tags": tags.ToList(s.tags.Get(arn), "Key", "Value"),
+	})
+}
+
+// Invocation.
+
+// fireDue invokes every enabled schedule with an occurrence in (from, to],
+// then retries the failed deliveries that have fallen due.
+func (s *Service) fireDue(from, to time.Time) {
+	type firing struct {
+		sched       *schedule
+		at          time.Time
+		deleteAfter bool
+	}
+
+	s.mu.RLock()
+	var due []firing
+	for _, sched := range s.schedules {
+		if sched.state != "ENABLED" {
+			continue
+		}
+		deleteAfter := sched.actionAfterCompletion == "DELETE" && strings.HasPrefix(sched.scheduleExpression, "at(")
+		for _, t := range occurrences(sched, from, to) {
+			due = append(due, firing{sched: sched, at: t, deleteAfter: deleteAfter})
+		}
+	}
+	s.mu.RUnlock()
+
+	sort.SliceStable(due, func(i, j int) bool { return due[i].at.Before(due[j].at) })
+	for _, f := range due {
+		s.invoke(f.sched)
+		if f.deleteAfter {
+			s.mu.Lock()
+			delete(s.schedules, scheduleKey(f.sched.groupName, f.sched.name))
+			s.mu.Unlock()
+		}
+	}
+
+	for r := s.nextRetry(to); r != nil; r = s.nextRetry(to) {
+		s.attempt(r)
+	}
+}
+
+// nextRetry removes and returns the earliest retry due by to, or nil if
+// there is none.
+func (s *Service) nextRetry(to time.Time) *retry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	next := -1
+	for i, r := range s.retries {
+		if r.at.After(to) {
+			continue
+		}
+		if next < 0 || r.at.Before(s.retries[next].at) {
+			next = i
+		}
+	}
+	if next < 0 {
+		return nil
+	}
+	r := s.retries[next]
+	s.retries = append(s.retries[:next], s.retries[next+1:]...)
+	return r
+}
+
+// invoke delivers the schedule's input to its target. A failed delivery is
+// retried on later clock advances within the target's retry policy.
+func (s *Service) invoke(sched *schedule) Invocation {
+	s.mu.RLock()
+	target := sched.target
+	inv := &Invocation{
+		ScheduleName: sched.name,
+		GroupName:    sched.groupName,
+		TargetArn:    h.GetString(target, "Arn"),
+		Input:        h.GetString(target, "Input"),
+		Time:         s.now(),
+	}
+	s.mu.RUnlock()
+
+	if inv.Input == "" {
+		inv.Input = "{}"
+	}
+
+	r := &retry{
+		inv:      inv,
+		target:   target,
+		deadline: inv.Time.Add(defaultMaxEventAge),
+		max:      defaultMaxRetryAttempts,
+	}
+	if rp, ok := target["RetryPolicy"].(map[string]interface{}); ok {
+		r.max = h.GetInt(rp, "MaximumRetryAttempts", defaultMaxRetryAttempts)
+		age := h.GetInt(rp, "MaximumEventAgeInSeconds", int(defaultMaxEventAge/time.Second))
+		r.deadline = inv.Time.Add(time.Duration(age) * time.Second)
+	}
+
+	s.mu.Lock()
+	s.invocations = append(s.invocations, inv)
+	s.mu.Unlock()
+
+	s.attempt(r)
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return *inv
+}
+
+// attempt makes one delivery attempt of r's invocation. On failure it
+// schedules the next retry, or sends the input to the dead-letter queue once
+// the retry policy is exhausted.
+func (s *Service) attempt(r *retry) {
+	s.mu.RLock()
+	dispatch := s.dispatch
+	inv := r.inv
+	arn, input := inv.TargetArn, inv.Input
+	s.mu.RUnlock()
+
+	var err error
+	switch {
+	case dispatch == nil:
+		err = fmt.Errorf("no dispatcher configured for target %s", arn)
+	case arn == "":
+		err = fmt.Errorf("schedule %s has no target", inv.ScheduleName)
+	default:
+		_, err = dispatch(arn, []byte(input))
+	}
+
+	s.mu.Lock()
+	inv.Attempts++
+	inv.Err = err
+	retries := inv.Attempts - 1
+	if err == nil || dispatch == nil || arn == "" {
+		s.mu.Unlock()
+		return
+	}
+	if retries < r.max {
+		at := r.at
+		if at.IsZero() {
+			at = inv.Time
+		}
+		at = at.Add(retryDelay(retries))
+		if !at.After(r.deadline) {
+			r.at = at
+			s.retries = append(s.retries, r)
+			s.mu.Unlock()
+			return
+		}
+	}
+	s.mu.Unlock()
+
+	if dlq, ok := r.target["DeadLetterConfig"].(map[string]interface{}); ok {
+		if dlqArn := h.GetString(dlq, "Arn"); dlqArn != "" {
+			if _, err := dispatch(dlqArn, []byte(input)); err == nil {
+				s.mu.Lock()
+				inv.DeadLettered = true
+				s.mu.Unlock()
+			}
+		}
+	}
+}
+
+// retryDelay returns the backoff before the retry that follows the given
+// number of retries.
+func retryDelay(retries int) time.Duration {
+	d := retryBaseDelay
+	for i := 0; i < retries && d < retryMaxDelay; i++ {
+		d *= 2
+	}
+	return min(d, retryMaxDelay)
+}
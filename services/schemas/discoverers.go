@@ -0,0 +1,179 @@
+package schemas
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/riyanimam/goto/internal/arn"
+	h "github.com/riyanimam/goto/internal/mockhelpers"
+	"github.com/riyanimam/goto/internal/tags"
+)
+
+type discoverer struct {
+	id           string
+	arn          string
+	sourceArn    string
+	description  string
+	crossAccount bool
+	state        string // STARTED or STOPPED
+}
+
+func (s *Service) handleDiscoverers(w http.ResponseWriter, method string, parts []string, params map[string]interface{}, q url.Values) {
+	switch {
+	case len(parts) == 0 && method == http.MethodPost:
+		s.createDiscoverer(w, params)
+	case len(parts) == 0 && method == http.MethodGet:
+		s.listDiscoverers(w, q)
+	case len(parts) == 2 && parts[0] == "id":
+		switch method {
+		case http.MethodGet:
+			s.describeDiscoverer(w, parts[1])
+		case http.MethodPut:
+			s.updateDiscoverer(w, parts[1], params)
+		case http.MethodDelete:
+			s.deleteDiscoverer(w, parts[1])
+		default:
+			writeUnsupported(w)
+		}
+	case len(parts) == 3 && parts[0] == "id" && parts[2] == "start" && method == http.MethodPost:
+		s.setDiscovererState(w, parts[1], "STARTED")
+	case len(parts) == 3 && parts[0] == "id" && parts[2] == "stop" && method == http.MethodPost:
+		s.setDiscovererState(w, parts[1], "STOPPED")
+	default:
+		writeUnsupported(w)
+	}
+}
+
+// createDiscoverer records a discoverer for an event bus. Its ID is derived
+// from the bus name, as in EventBridge, so a bus has at most one.
+func (s *Service) createDiscoverer(w http.ResponseWriter, params map[string]interface{}) {
+	source := h.GetString(params, "SourceArn")
+	a, err := arn.Parse(source)
+	if err != nil || a.Service != "events" || !strings.HasPrefix(a.Resource, "event-bus/") {
+		writeBadRequest(w, "SourceArn must be an event bus ARN.")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.resolve != nil {
+		if err := s.resolve(source); errors.Is(err, arn.ErrNotFound) {
+			writeBadRequest(w, "Event bus "+source+" does not exist.")
+			return
+		}
+	}
+	id := "events-event-bus-" + strings.TrimPrefix(a.Resource, "event-bus/")
+	if _, exists := s.discoverers[id]; exists {
+		writeConflict(w, "Discoverer for "+source+" already exists.")
+		return
+	}
+	crossAccount := true
+	if v, ok := params["CrossAccount"].(bool); ok {
+		crossAccount = v
+	}
+	d := &discoverer{
+		id:           id,
+		arn:          resourceArn("discoverer/" + id),
+		sourceArn:    source,
+		description:  h.GetString(params, "Description"),
+		crossAccount: crossAccount,
+		state:        "STARTED",
+	}
+	s.discoverers[id] = d
+	s.tags.Tag(d.arn, tags.FromMap(params["Tags"]))
+	if _, exists := s.registries[discoveredRegistry]; !exists {
+		s.addRegistry(discoveredRegistry, "")
+	}
+	h.WriteJSON(w, http.StatusCreated, s.discovererDescription(d))
+}
+
+// discovererDescription renders a discoverer. The caller must hold s.mu.
+func (s *Service) discovererDescription(d *discoverer) map[string]interface{} {
+	desc := map[string]interface{}{
+		"DiscovererId":  d.id,
+		"DiscovererArn": d.arn,
+		"SourceArn":     d.sourceArn,
+		"State":         d.state,
+		"CrossAccount":  d.crossAccount,
+		"Tags":          s.tags.Get(d.arn),
+	}
+	if d.description != "" {
+		desc["Description"] = d.description
+	}
+	return desc
+}
+
+func (s *Service) describeDiscoverer(w http.ResponseWriter, id string) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	d, exists := s.discoverers[id]
+	if !exists {
+		writeNotFound(w, "Discoverer "+id+" does not exist.")
+		return
+	}
+	h.WriteJSON(w, http.StatusOK, s.discovererDescription(d))
+}
+
+func (s *Service) listDiscoverers(w http.ResponseWriter, q url.Values) {
+	idPrefix, sourcePrefix := q.Get("discovererIdPrefix"), q.Get("sourceArnPrefix")
+	s.mu.RLock()
+	discoverers := []map[string]interface{}{}
+	for _, d := range s.discoverers {
+		if !strings.HasPrefix(d.id, idPrefix) || !strings.HasPrefix(d.sourceArn, sourcePrefix) {
+			continue
+		}
+		desc := s.discovererDescription(d)
+		delete(desc, "Description")
+		discoverers = append(discoverers, desc)
+	}
+	s.mu.RUnlock()
+	sort.Slice(discoverers, func(i, j int) bool {
+		return discoverers[i]["DiscovererId"].(string) < discoverers[j]["DiscovererId"].(string)
+	})
+	writePage(w, q, "Discoverers", discoverers, func(i int) string { return discoverers[i]["DiscovererId"].(string) })
+}
+
+func (s *Service) updateDiscoverer(w http.ResponseWriter, id string, params map[string]interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	d, exists := s.discoverers[id]
+	if !exists {
+		writeNotFound(w, "Discoverer "+id+" does not exist.")
+		return
+	}
+	if _, ok := params["Description"]; ok {
+		d.description = h.GetString(params, "Description")
+	}
+	if v, ok := params["CrossAccount"].(bool); ok {
+		d.crossAccount = v
+	}
+	h.WriteJSON(w, http.StatusOK, s.discovererDescription(d))
+}
+
+func (s *Service) deleteDiscoverer(w http.ResponseWriter, id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	d, exists := s.discoverers[id]
+	if !exists {
+		writeNotFound(w, "Discoverer "+id+" does not exist.")
+		return
+	}
+	s.tags.Delete(d.arn)
+	delete(s.discoverers, id)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Service) setDiscovererState(w http.ResponseWriter, id, state string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	d, exists := s.discoverers[id]
+	if !exists {
+		writeNotFound(w, "Discoverer "+id+" does not exist.")
+		return
+	}
+	d.state = state
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{"DiscovererId": d.id, "State": d.state})
+}
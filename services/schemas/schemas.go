@@ -0,0 +1,708 @@
+// Package schemas provides a mock implementation of the Amazon EventBridge
+// schema registry.
+//
+// Supported actions:
+//   - CreateRegistry, DescribeRegistry, ListRegistries, UpdateRegistry,
+//     DeleteRegistry
+//   - CreateSchema, DescribeSchema, ListSchemas, SearchSchemas,
+//     UpdateSchema, DeleteSchema, ListSchemaVersions, DeleteSchemaVersion
+//   - CreateDiscoverer, DescribeDiscoverer, ListDiscoverers,
+//     UpdateDiscoverer, DeleteDiscoverer, StartDiscoverer, StopDiscoverer
+//   - TagResource, UntagResource, ListTagsForResource
+//
+// Schema content must be a JSON document. Each change to a schema's content
+// adds a version, numbered from 1; DescribeSchema returns the latest unless
+// asked for another. Discoverers are recorded with their state, and creating
+// the first one adds the discovered-schemas registry, but they do not infer
+// schemas from the events on their bus.
+package schemas
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/riyanimam/goto/internal/clock"
+	h "github.com/riyanimam/goto/internal/mockhelpers"
+	"github.com/riyanimam/goto/internal/paginate"
+	"github.com/riyanimam/goto/internal/tags"
+)
+
+// discoveredRegistry is the registry discoverers add their schemas to.
+const discoveredRegistry = "discovered-schemas"
+
+// maxContentSize is the largest schema document accepted, in bytes.
+const maxContentSize = 100 * 1024
+
+var namePattern = regexp.MustCompile(`^[\.\-_A-Za-z@]+[\.\-_A-Za-z0-9@]*$`)
+
+var schemaTypes = map[string]bool{"OpenApi3": true, "JSONSchemaDraft4": true}
+
+// Service implements the schema registry mock.
+type Service struct {
+	mu          sync.RWMutex
+	registries  map[string]*registry
+	discoverers map[string]*discoverer
+
+	resolve h.Resolver
+	tags    *tags.Store
+	clock   *clock.Clock
+}
+
+type registry struct {
+	name        string
+	arn         string
+	description string
+	schemas     map[string]*schema
+}
+
+type schema struct {
+	name        string
+	arn         string
+	description string
+	schemaType  string
+	versions    []*schemaVersion // oldest first
+	modified    time.Time
+	nextVersion int
+}
+
+type schemaVersion struct {
+	version string
+	content string
+	created time.Time
+}
+
+// New creates a new schema registry mock service.
+func New() *Service {
+	return &Service{
+		registries:  make(map[string]*registry),
+		discoverers: make(map[string]*discoverer),
+		tags:        tags.New(),
+	}
+}
+
+// Name returns the service identifier.
+func (s *Service) Name() string { return "schemas" }
+
+// Handler returns the HTTP handler for schema registry requests.
+func (s *Service) Handler() http.Handler {
+	return http.HandlerFunc(s.handle)
+}
+
+// Reset clears all state.
+func (s *Service) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.registries = make(map[string]*registry)
+	s.discoverers = make(map[string]*discoverer)
+	s.tags.DeleteService("schemas")
+}
+
+// SetResolver sets the function used to check that discoverer source event
+// buses exist.
+func (s *Service) SetResolver(r h.Resolver) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.resolve = r
+}
+
+// SetTagStore sets the registry, schema, and discoverer tags are recorded
+// in.
+func (s *Service) SetTagStore(store *tags.Store) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tags = store
+}
+
+// SetClock attaches the mock clock used for schema modification times.
+func (s *Service) SetClock(c *clock.Clock) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.clock = c
+}
+
+func (s *Service) now() time.Time {
+	if s.clock != nil {
+		return s.clock.Now()
+	}
+	return time.Now().UTC()
+}
+
+// HasResource reports whether the registry, schema, or discoverer
+// identified by arn exists.
+func (s *Service) HasResource(arn string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, r := range s.registries {
+		if r.arn == arn {
+			return true
+		}
+		for _, sc := range r.schemas {
+			if sc.arn == arn {
+				return true
+			}
+		}
+	}
+	for _, d := range s.discoverers {
+		if d.arn == arn {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Service) handle(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(r.URL.EscapedPath(), "/"), "/")
+	for i, p := range parts {
+		parts[i], _ = url.PathUnescape(p)
+	}
+	params := map[string]interface{}{}
+	if body, _ := io.ReadAll(r.Body); len(body) > 0 {
+		if err := json.Unmarshal(body, &params); err != nil {
+			writeBadRequest(w, "Request body is not valid JSON.")
+			return
+		}
+	}
+	q := r.URL.Query()
+
+	if parts[0] == "tags" && len(parts) > 1 {
+		s.handleTags(w, r.Method, strings.Join(parts[1:], "/"), params, q)
+		return
+	}
+	if len(parts) < 2 || parts[0] != "v1" {
+		writeUnsupported(w)
+		return
+	}
+	parts = parts[1:]
+	switch {
+	case parts[0] == "discoverers":
+		s.handleDiscoverers(w, r.Method, parts[1:], params, q)
+	case len(parts) == 1 && parts[0] == "registries" && r.Method == http.MethodGet:
+		s.listRegistries(w, q)
+	case len(parts) == 3 && parts[0] == "registries" && parts[1] == "name":
+		switch r.Method {
+		case http.MethodPost:
+			s.createRegistry(w, parts[2], params)
+		case http.MethodGet:
+			s.describeRegistry(w, parts[2])
+		case http.MethodPut:
+			s.updateRegistry(w, parts[2], params)
+		case http.MethodDelete:
+			s.deleteRegistry(w, parts[2])
+		default:
+			writeUnsupported(w)
+		}
+	case len(parts) >= 4 && parts[0] == "registries" && parts[1] == "name" && parts[3] == "schemas":
+		s.handleSchemas(w, r.Method, parts[2], parts[4:], params, q)
+	default:
+		writeUnsupported(w)
+	}
+}
+
+func (s *Service) handleSchemas(w http.ResponseWriter, method, registryName string, parts []string, params map[string]interface{}, q url.Values) {
+	switch {
+	case len(parts) == 0 && method == http.MethodGet:
+		s.listSchemas(w, registryName, q)
+	case len(parts) == 1 && parts[0] == "search" && method == http.MethodGet:
+		s.searchSchemas(w, registryName, q)
+	case len(parts) == 2 && parts[0] == "name":
+		switch method {
+		case http.MethodPost:
+			s.createSchema(w, registryName, parts[1], params)
+		case http.MethodGet:
+			s.describeSchema(w, registryName, parts[1], q.Get("schemaVersion"))
+		case http.MethodPut:
+			s.updateSchema(w, registryName, parts[1], params)
+		case http.MethodDelete:
+			s.deleteSchema(w, registryName, parts[1])
+		default:
+			writeUnsupported(w)
+		}
+	case len(parts) == 3 && parts[0] == "name" && parts[2] == "versions" && method == http.MethodGet:
+		s.listSchemaVersions(w, registryName, parts[1], q)
+	case len(parts) == 4 && parts[0] == "name" && parts[2] == "version" && method == http.MethodDelete:
+		s.deleteSchemaVersion(w, registryName, parts[1], parts[3])
+	default:
+		writeUnsupported(w)
+	}
+}
+
+func writeUnsupported(w http.ResponseWriter) {
+	h.WriteJSONError(w, "NotFoundException", "unsupported operation", http.StatusNotFound)
+}
+
+func writeBadRequest(w http.ResponseWriter, message string) {
+	h.WriteJSONError(w, "BadRequestException", message, http.StatusBadRequest)
+}
+
+func writeNotFound(w http.ResponseWriter, message string) {
+	h.WriteJSONError(w, "NotFoundException", message, http.StatusNotFound)
+}
+
+func writeConflict(w http.ResponseWriter, message string) {
+	h.WriteJSONError(w, "ConflictException", message, http.StatusConflict)
+}
+
+func resourceArn(resource string) string {
+	return fmt.Sprintf("arn:aws:schemas:us-east-1:%s:%s", h.DefaultAccountID, resource)
+}
+
+func timestamp(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
+
+// writePage writes a page of items, sorted by itemKey as in [paginate.Page],
+// under key, using the limit and nextToken query parameters.
+func writePage(w http.ResponseWriter, q url.Values, key string, items []map[string]interface{}, itemKey func(i int) string) {
+	limit := 100
+	if v, err := strconv.Atoi(q.Get("limit")); err == nil {
+		limit = v
+	}
+	page, next, err := paginate.Page(items, itemKey, q.Get("nextToken"), limit, 100)
+	if err != nil {
+		writeBadRequest(w, "Invalid nextToken.")
+		return
+	}
+	resp := map[string]interface{}{key: page}
+	if next != "" {
+		resp["NextToken"] = next
+	}
+	h.WriteJSON(w, http.StatusOK, resp)
+}
+
+func (s *Service) createRegistry(w http.ResponseWriter, name string, params map[string]interface{}) {
+	if !namePattern.MatchString(name) || len(name) > 64 {
+		writeBadRequest(w, "Invalid registry name: "+name)
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, exists := s.registries[name]; exists {
+		writeConflict(w, "Registry with name "+name+" already exists.")
+		return
+	}
+	reg := s.addRegistry(name, h.GetString(params, "Description"))
+	s.tags.Tag(reg.arn, tags.FromMap(params["Tags"]))
+	h.WriteJSON(w, http.StatusCreated, s.registryDescription(reg))
+}
+
+// addRegistry creates a registry. The caller must hold s.mu.
+func (s *Service) addRegistry(name, description string) *registry {
+	reg := &registry{
+		name:        name,
+		arn:         resourceArn("registry/" + name),
+		description: description,
+		schemas:     make(map[string]*schema),
+	}
+	s.registries[name] = reg
+	return reg
+}
+
+// registryDescription renders a registry. The caller must hold s.mu.
+func (s *Service) registryDescription(reg *registry) map[string]interface{} {
+	d := map[string]interface{}{
+		"RegistryName": reg.name,
+		"RegistryArn":  reg.arn,
+		"Tags":         s.tags.Get(reg.arn),
+	}
+	if reg.description != "" {
+		d["Description"] = reg.description
+	}
+	return d
+}
+
+func (s *Service) describeRegistry(w http.ResponseWriter, name string) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	reg, exists := s.registries[name]
+	if !exists {
+		writeNotFound(w, "Registry "+name+" does not exist.")
+		return
+	}
+	h.WriteJSON(w, http.StatusOK, s.registryDescription(reg))
+}
+
+// listRegistries lists the registries. They are all LOCAL: the mock holds
+// no AWS-provided registries.
+func (s *Service) listRegistries(w http.ResponseWriter, q url.Values) {
+	prefix, scope := q.Get("registryNamePrefix"), q.Get("scope")
+	s.mu.RLock()
+	registries := []map[string]interface{}{}
+	for _, reg := range s.registries {
+		if !strings.HasPrefix(reg.name, prefix) || scope == "AWS" {
+			continue
+		}
+		d := s.registryDescription(reg)
+		delete(d, "Description")
+		registries = append(registries, d)
+	}
+	s.mu.RUnlock()
+	sort.Slice(registries, func(i, j int) bool {
+		return registries[i]["RegistryName"].(string) < registries[j]["RegistryName"].(string)
+	})
+	writePage(w, q, "Registries", registries, func(i int) string { return registries[i]["RegistryName"].(string) })
+}
+
+func (s *Service) updateRegistry(w http.ResponseWriter, name string, params map[string]interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	reg, exists := s.registries[name]
+	if !exists {
+		writeNotFound(w, "Registry "+name+" does not exist.")
+		return
+	}
+	if _, ok := params["Description"]; ok {
+		reg.description = h.GetString(params, "Description")
+	}
+	h.WriteJSON(w, http.StatusOK, s.registryDescription(reg))
+}
+
+func (s *Service) deleteRegistry(w http.ResponseWriter, name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	reg, exists := s.registries[name]
+	if !exists {
+		writeNotFound(w, "Registry "+name+" does not exist.")
+		return
+	}
+	for _, sc := range reg.schemas {
+		s.tags.Delete(sc.arn)
+	}
+	s.tags.Delete(reg.arn)
+	delete(s.registries, name)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// validContent returns a message describing why content is not a schema
+// document of the given type, or "" if it is.
+func validContent(content, schemaType string) string {
+	if content == "" {
+		return "Content is required."
+	}
+	if len(content) > maxContentSize {
+		return "Content exceeds the maximum size of 100 KB."
+	}
+	var doc map[string]interface{}
+	if err := json.Unmarshal([]byte(content), &doc); err != nil {
+		return "Content is not a valid JSON document: " + err.Error()
+	}
+	if _, ok := doc["openapi"]; schemaType == "OpenApi3" && !ok {
+		return "Content is not a valid OpenApi3 document: missing openapi field."
+	}
+	return ""
+}
+
+// lookupRegistry returns the named registry, writing NotFoundException if
+// it does not exist. The caller must hold s.mu.
+func (s *Service) lookupRegistry(w http.ResponseWriter, name string) (*registry, bool) {
+	reg, exists := s.registries[name]
+	if !exists {
+		writeNotFound(w, "Registry "+name+" does not exist.")
+	}
+	return reg, exists
+}
+
+// lookupSchema returns the named schema, writing NotFoundException if it or
+// its registry does not exist. The caller must hold s.mu.
+func (s *Service) lookupSchema(w http.ResponseWriter, registryName, name string) (*schema, bool) {
+	reg, ok := s.lookupRegistry(w, registryName)
+	if !ok {
+		return nil, false
+	}
+	sc, exists := reg.schemas[name]
+	if !exists {
+		writeNotFound(w, "Schema "+name+" does not exist in registry "+registryName+".")
+	}
+	return sc, exists
+}
+
+func (s *Service) createSchema(w http.ResponseWriter, registryName, name string, params map[string]interface{}) {
+	schemaType := h.GetString(params, "Type")
+	if !schemaTypes[schemaType] {
+		writeBadRequest(w, "Type must be OpenApi3 or JSONSchemaDraft4.")
+		return
+	}
+	if !namePattern.MatchString(name) || len(name) > 385 {
+		writeBadRequest(w, "Invalid schema name: "+name)
+		return
+	}
+	content := h.GetString(params, "Content")
+	if msg := validContent(content, schemaType); msg != "" {
+		writeBadRequest(w, msg)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	reg, ok := s.lookupRegistry(w, registryName)
+	if !ok {
+		return
+	}
+	if _, exists := reg.schemas[name]; exists {
+		writeConflict(w, "Schema with name "+name+" already exists.")
+		return
+	}
+	sc := &schema{
+		name:        name,
+		arn:         resourceArn("schema/" + registryName + "/" + name),
+		description: h.GetString(params, "Description"),
+		schemaType:  schemaType,
+	}
+	sc.addVersion(content, s.now())
+	reg.schemas[name] = sc
+	s.tags.Tag(sc.arn, tags.FromMap(params["Tags"]))
+
+	d := s.schemaDescription(sc, sc.latest())
+	delete(d, "Content")
+	h.WriteJSON(w, http.StatusCreated, d)
+}
+
+func (sc *schema) addVersion(content string, now time.Time) {
+	sc.nextVersion++
+	sc.versions = append(sc.versions, &schemaVersion{
+		version: strconv.Itoa(sc.nextVersion),
+		content: content,
+		created: now,
+	})
+	sc.modified = now
+}
+
+func (sc *schema) latest() *schemaVersion {
+	return sc.versions[len(sc.versions)-1]
+}
+
+func (sc *schema) version(v string) *schemaVersion {
+	if v == "" {
+		return sc.latest()
+	}
+	for _, sv := range sc.versions {
+		if sv.version == v {
+			return sv
+		}
+	}
+	return nil
+}
+
+// schemaDescription renders a version of a schema. The caller must hold
+// s.mu.
+func (s *Service) schemaDescription(sc *schema, sv *schemaVersion) map[string]interface{} {
+	d := map[string]interface{}{
+		"SchemaName":         sc.name,
+		"SchemaArn":          sc.arn,
+		"SchemaVersion":      sv.version,
+		"Type":               sc.schemaType,
+		"Content":            sv.content,
+		"LastModified":       timestamp(sc.modified),
+		"VersionCreatedDate": timestamp(sv.created),
+		"Tags":               s.tags.Get(sc.arn),
+	}
+	if sc.description != "" {
+		d["Description"] = sc.description
+	}
+	return d
+}
+
+func (s *Service) describeSchema(w http.ResponseWriter, registryName, name, version string) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	sc, ok := s.lookupSchema(w, registryName, name)
+	if !ok {
+		return
+	}
+	sv := sc.version(version)
+	if sv == nil {
+		writeNotFound(w, "Version "+version+" of schema "+name+" does not exist.")
+		return
+	}
+	h.WriteJSON(w, http.StatusOK, s.schemaDescription(sc, sv))
+}
+
+// updateSchema changes a schema's description, and adds a version if its
+// content or type changes.
+func (s *Service) updateSchema(w http.ResponseWriter, registryName, name string, params map[string]interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sc, ok := s.lookupSchema(w, registryName, name)
+	if !ok {
+		return
+	}
+	schemaType := sc.schemaType
+	if t := h.GetString(params, "Type"); t != "" {
+		if !schemaTypes[t] {
+			writeBadRequest(w, "Type must be OpenApi3 or JSONSchemaDraft4.")
+			return
+		}
+		schemaType = t
+	}
+	content := h.GetString(params, "Content")
+	if content == "" {
+		content = sc.latest().content
+	}
+	if msg := validContent(content, schemaType); msg != "" {
+		writeBadRequest(w, msg)
+		return
+	}
+	if _, ok := params["Description"]; ok {
+		sc.description = h.GetString(params, "Description")
+		sc.modified = s.now()
+	}
+	if content != sc.latest().content || schemaType != sc.schemaType {
+		sc.schemaType = schemaType
+		sc.addVersion(content, s.now())
+	}
+	d := s.schemaDescription(sc, sc.latest())
+	delete(d, "Content")
+	h.WriteJSON(w, http.StatusOK, d)
+}
+
+func (s *Service) deleteSchema(w http.ResponseWriter, registryName, name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sc, ok := s.lookupSchema(w, registryName, name)
+	if !ok {
+		return
+	}
+	s.tags.Delete(sc.arn)
+	delete(s.registries[registryName].schemas, name)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// deleteSchemaVersion deletes one version of a schema. The latest version
+// cannot be deleted; delete the schema instead.
+func (s *Service) deleteSchemaVersion(w http.ResponseWriter, registryName, name, version string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sc, ok := s.lookupSchema(w, registryName, name)
+	if !ok {
+		return
+	}
+	for i, sv := range sc.versions {
+		if sv.version != version {
+			continue
+		}
+		if i == len(sc.versions)-1 {
+			writeBadRequest(w, "The latest version of a schema cannot be deleted.")
+			return
+		}
+		sc.versions = append(sc.versions[:i:i], sc.versions[i+1:]...)
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	writeNotFound(w, "Version "+version+" of schema "+name+" does not exist.")
+}
+
+func (s *Service) listSchemas(w http.ResponseWriter, registryName string, q url.Values) {
+	prefix := q.Get("schemaNamePrefix")
+	s.mu.RLock()
+	reg, ok := s.lookupRegistry(w, registryName)
+	if !ok {
+		s.mu.RUnlock()
+		return
+	}
+	schemas := []map[string]interface{}{}
+	for _, sc := range reg.schemas {
+		if !strings.HasPrefix(sc.name, prefix) {
+			continue
+		}
+		schemas = append(schemas, map[string]interface{}{
+			"SchemaName":   sc.name,
+			"SchemaArn":    sc.arn,
+			"LastModified": timestamp(sc.modified),
+			"VersionCount": len(sc.versions),
+			"Tags":         s.tags.Get(sc.arn),
+		})
+	}
+	s.mu.RUnlock()
+	sort.Slice(schemas, func(i, j int) bool { return schemas[i]["SchemaName"].(string) < schemas[j]["SchemaName"].(string) })
+	writePage(w, q, "Schemas", schemas, func(i int) string { return schemas[i]["SchemaName"].(string) })
+}
+
+// searchSchemas returns the schemas whose names contain the keywords, with
+// their versions.
+func (s *Service) searchSchemas(w http.ResponseWriter, registryName string, q url.Values) {
+	keywords := strings.ToLower(q.Get("keywords"))
+	if keywords == "" {
+		writeBadRequest(w, "keywords is required.")
+		return
+	}
+	s.mu.RLock()
+	reg, ok := s.lookupRegistry(w, registryName)
+	if !ok {
+		s.mu.RUnlock()
+		return
+	}
+	schemas := []map[string]interface{}{}
+	for _, sc := range reg.schemas {
+		if !strings.Contains(strings.ToLower(sc.name), keywords) {
+			continue
+		}
+		versions := make([]map[string]interface{}, 0, len(sc.versions))
+		for i := len(sc.versions) - 1; i >= 0; i-- {
+			versions = append(versions, map[string]interface{}{
+				"SchemaVersion": sc.versions[i].version,
+				"CreatedDate":   timestamp(sc.versions[i].created),
+				"Type":          sc.schemaType,
+			})
+		}
+		schemas = append(schemas, map[string]interface{}{
+			"RegistryName":   reg.name,
+			"SchemaName":     sc.name,
+			"SchemaArn":      sc.arn,
+			"SchemaVersions": versions,
+		})
+	}
+	s.mu.RUnlock()
+	sort.Slice(schemas, func(i, j int) bool { return schemas[i]["SchemaName"].(string) < schemas[j]["SchemaName"].(string) })
+	writePage(w, q, "Schemas", schemas, func(i int) string { return schemas[i]["SchemaName"].(string) })
+}
+
+func (s *Service) listSchemaVersions(w http.ResponseWriter, registryName, name string, q url.Values) {
+	s.mu.RLock()
+	sc, ok := s.lookupSchema(w, registryName, name)
+	if !ok {
+		s.mu.RUnlock()
+		return
+	}
+	versions := make([]map[string]interface{}, 0, len(sc.versions))
+	for _, sv := range sc.versions {
+		versions = append(versions, map[string]interface{}{
+			"SchemaName":    sc.name,
+			"SchemaArn":     sc.arn,
+			"SchemaVersion": sv.version,
+			"Type":          sc.schemaType,
+		})
+	}
+	s.mu.RUnlock()
+	// Versions are numbered in the order they were created.
+	writePage(w, q, "SchemaVersions", versions, func(i int) string {
+		n, _ := strconv.Atoi(versions[i]["SchemaVersion"].(string))
+		return paginate.Seq(n)
+	})
+}
+
+func (s *Service) handleTags(w http.ResponseWriter, method, arn string, params map[string]interface{}, q url.Values) {
+	if !s.HasResource(arn) {
+		writeNotFound(w, "Resource "+arn+" does not exist.")
+		return
+	}
+	switch method {
+	case http.MethodPost:
+		s.tags.Tag(arn, tags.FromMap(params["Tags"]))
+		w.WriteHeader(http.StatusNoContent)
+	case http.MethodDelete:
+		s.tags.Untag(arn, q["tagKeys"])
+		w.WriteHeader(http.StatusNoContent)
+	case http.MethodGet:
+		h.WriteJSON(w, http.StatusOK, map[string]interface{}{"Tags": s.tags.Get(arn)})
+	default:
+		writeUnsupported(w)
+	}
+}
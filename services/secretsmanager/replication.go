@@ -0,0 +1,169 @@
+package secretsmanager
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/riyanimam/goto/internal/lifecycle"
+)
+
+// primaryRegion is the region every secret is created in.
+const primaryRegion = "us-east-1"
+
+// replica is a copy of a secret in another region.
+type replica struct {
+	region   string
+	kmsKeyID string
+	ready    lifecycle.Transition
+}
+
+// SetTransitions sets how long new replicas stay InProgress before they are
+// InSync.
+func (s *Service) SetTransitions(t *lifecycle.Transitions) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.transitions = t
+}
+
+// replicaArn returns the ARN of sec's replica in region, which differs from
+// the primary ARN only in its region.
+func (sec *secret) replicaArn(region string) string {
+	return strings.Replace(sec.arn, ":"+primaryRegion+":", ":"+region+":", 1)
+}
+
+// addReplicas replicates sec to the regions listed in the AddReplicaRegions
+// parameter. Regions sec is already replicated to are left as they are. The
+// caller must hold s.mu.
+func (s *Service) addReplicas(sec *secret, param interface{}) error {
+	list, _ := param.([]interface{})
+	var added []*replica
+	seen := make(map[string]bool)
+	for _, r := range sec.replicas {
+		seen[r.region] = true
+	}
+	for _, item := range list {
+		m, _ := item.(map[string]interface{})
+		region := getString(m, "Region")
+		switch {
+		case region == "":
+			return fmt.Errorf("region is required for each replica")
+		case region == primaryRegion:
+			return fmt.Errorf("invalid replica region %s: it is the primary region of the secret", region)
+		case seen[region]:
+			continue
+		}
+		seen[region] = true
+		added = append(added, &replica{
+			region:   region,
+			kmsKeyID: getString(m, "KmsKeyId"),
+			ready:    s.transitions.Begin(),
+		})
+	}
+	sec.replicas = append(sec.replicas, added...)
+	return nil
+}
+
+// replica returns sec's replica in region, or nil.
+func (sec *secret) replica(region string) *replica {
+	for _, r := range sec.replicas {
+		if r.region == region {
+			return r
+		}
+	}
+	return nil
+}
+
+// replicaRegions returns the regions sec is replicated to.
+func (sec *secret) replicaRegions() []string {
+	regions := make([]string, len(sec.replicas))
+	for i, r := range sec.replicas {
+		regions[i] = r.region
+	}
+	return regions
+}
+
+// replicationStatus describes sec's replicas. The caller must hold s.mu.
+func (s *Service) replicationStatus(sec *secret) []map[string]interface{} {
+	out := make([]map[string]interface{}, 0, len(sec.replicas))
+	for _, r := range sec.replicas {
+		status := s.transitions.Status(r.ready, "InProgress", "InSync")
+		entry := map[string]interface{}{
+			"Region":        r.region,
+			"Status":        status,
+			"StatusMessage": "Replication succeeded",
+		}
+		if status == "InProgress" {
+			entry["StatusMessage"] = "Replication in progress"
+		}
+		if r.kmsKeyID != "" {
+			entry["KmsKeyId"] = r.kmsKeyID
+		}
+		out = append(out, entry)
+	}
+	return out
+}
+
+func (s *Service) replicateSecretToRegions(w http.ResponseWriter, params map[string]interface{}) {
+	secretID := getString(params, "SecretId")
+	if _, ok := params["AddReplicaRegions"].([]interface{}); !ok {
+		writeJSONError(w, "InvalidParameterException", "AddReplicaRegions is required", http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sec := s.findSecret(secretID)
+	if sec == nil || sec.deleted {
+		writeJSONError(w, "ResourceNotFoundException", "Secrets Manager can't find the specified secret.", http.StatusBadRequest)
+		return
+	}
+	if err := s.addReplicas(sec, params["AddReplicaRegions"]); err != nil {
+		writeJSONError(w, "InvalidParameterException", err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"ARN":               sec.arn,
+		"ReplicationStatus": s.replicationStatus(sec),
+	})
+}
+
+func (s *Service) removeRegionsFromReplication(w http.ResponseWriter, params map[string]interface{}) {
+	secretID := getString(params, "SecretId")
+	regions, _ := params["RemoveReplicaRegions"].([]interface{})
+	if len(regions) == 0 {
+		writeJSONError(w, "InvalidParameterException", "RemoveReplicaRegions is required", http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sec := s.findSecret(secretID)
+	if sec == nil || sec.deleted {
+		writeJSONError(w, "ResourceNotFoundException", "Secrets Manager can't find the specified secret.", http.StatusBadRequest)
+		return
+	}
+	remove := make(map[string]bool, len(regions))
+	for _, v := range regions {
+		region, _ := v.(string)
+		if sec.replica(region) == nil {
+			writeJSONError(w, "InvalidParameterException",
+				fmt.Sprintf("The secret %s is not replicated to region %s.", sec.name, region), http.StatusBadRequest)
+			return
+		}
+		remove[region] = true
+	}
+	kept := sec.replicas[:0]
+	for _, r := range sec.replicas {
+		if !remove[r.region] {
+			kept = append(kept, r)
+		}
+	}
+	sec.replicas = kept
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"ARN":               sec.arn,
+		"ReplicationStatus": s.replicationStatus(sec),
+	})
+}
@@ -8,26 +8,36 @@
 //   - ListSecrets
 //   - DescribeSecret
 //   - UpdateSecret
+//   - TagResource
+//   - UntagResource
+//   - ReplicateSecretToRegions
+//   - RemoveRegionsFromReplication
 package secretsmanager
 
 import (
 	"encoding/json"
 	"fmt"
-	"io"
 	"math/rand"
 	"net/http"
 	"sort"
 	"strings"
 	"sync"
 	"time"
+
+	"github.com/riyanimam/goto/internal/awserr"
+	"github.com/riyanimam/goto/internal/lifecycle"
+	"github.com/riyanimam/goto/internal/mockhelpers"
+	"github.com/riyanimam/goto/internal/tags"
 )
 
 const defaultAccountID = "123456789012"
 
 // Service implements the Secrets Manager mock.
 type Service struct {
-	mu      sync.RWMutex
-	secrets map[string]*secret // keyed by name
+	mu          sync.RWMutex
+	secrets     map[string]*secret // keyed by name
+	tags        *tags.Store
+	transitions *lifecycle.Transitions
 }
 
 type secret struct {
@@ -40,12 +50,14 @@ type secret struct {
 	created      time.Time
 	lastChanged  time.Time
 	deleted      bool
+	replicas     []*replica
 }
 
 // New creates a new Secrets Manager mock service.
 func New() *Service {
 	return &Service{
 		secrets: make(map[string]*secret),
+		tags:    tags.New(),
 	}
 }
 
@@ -54,7 +66,19 @@ func (s *Service) Name() string { return "secretsmanager" }
 
 // Handler returns the HTTP handler for Secrets Manager requests.
 func (s *Service) Handler() http.Handler {
-	return http.HandlerFunc(s.handle)
+	return mockhelpers.JSONRouter{
+		"CreateSecret":                 s.createSecret,
+		"GetSecretValue":               s.getSecretValue,
+		"PutSecretValue":               s.putSecretValue,
+		"DeleteSecret":                 s.deleteSecret,
+		"ListSecrets":                  s.listSecrets,
+		"DescribeSecret":               s.describeSecret,
+		"UpdateSecret":                 s.updateSecret,
+		"TagResource":                  s.tagResource,
+		"UntagResource":                s.untagResource,
+		"ReplicateSecretToRegions":     s.replicateSecretToRegions,
+		"RemoveRegionsFromReplication": s.removeRegionsFromReplication,
+	}
 }
 
 // Reset clears all secrets.
@@ -62,54 +86,14 @@ func (s *Service) Reset() {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	s.secrets = make(map[string]*secret)
+	s.tags.DeleteService("secretsmanager")
 }
 
-func (s *Service) handle(w http.ResponseWriter, r *http.Request) {
-	target := r.Header.Get("X-Amz-Target")
-
-	bodyBytes, err := io.ReadAll(r.Body)
-	if err != nil {
-		writeJSONError(w, "InternalServiceError", "could not read request body", http.StatusInternalServerError)
-		return
-	}
-
-	var params map[string]interface{}
-	if len(bodyBytes) > 0 {
-		if err := json.Unmarshal(bodyBytes, &params); err != nil {
-			writeJSONError(w, "InvalidRequestException", "could not parse request body", http.StatusBadRequest)
-			return
-		}
-	}
-	if params == nil {
-		params = make(map[string]interface{})
-	}
-
-	action := ""
-	if target != "" {
-		parts := strings.SplitN(target, ".", 2)
-		if len(parts) == 2 {
-			action = parts[1]
-		}
-	}
-
-	switch action {
-	case "CreateSecret":
-		s.createSecret(w, params)
-	case "GetSecretValue":
-		s.getSecretValue(w, params)
-	case "PutSecretValue":
-		s.putSecretValue(w, params)
-	case "DeleteSecret":
-		s.deleteSecret(w, params)
-	case "ListSecrets":
-		s.listSecrets(w, params)
-	case "DescribeSecret":
-		s.describeSecret(w, params)
-	case "UpdateSecret":
-		s.updateSecret(w, params)
-	default:
-		writeJSONError(w, "InvalidAction", fmt.Sprintf("action %q is not supported", action), http.StatusBadRequest)
-	}
+// SetTagStore sets the registry secret tags are recorded in.
+func (s *Service) SetTagStore(store *tags.Store) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tags = store
 }
 
 func (s *Service) createSecret(w http.ResponseWriter, params map[string]interface{}) {
@@ -140,15 +124,26 @@ func (s *Service) createSecret(w http.ResponseWriter, params map[string]interfac
 	if v := getString(params, "SecretString"); v != "" {
 		sec.secretString = v
 	}
+	if err := s.addReplicas(sec, params["AddReplicaRegions"]); err != nil {
+		s.mu.Unlock()
+		writeJSONError(w, "InvalidParameterException", err.Error(), http.StatusBadRequest)
+		return
+	}
 
 	s.secrets[name] = sec
-	s.mu.Unlock()
+	s.tags.Tag(sec.arn, tags.FromList(params["Tags"], "Key", "Value"))
 
-	writeJSON(w, http.StatusOK, map[string]interface{}{
+	resp := map[string]interface{}{
 		"ARN":       sec.arn,
 		"Name":      sec.name,
 		"VersionId": sec.versionID,
-	})
+	}
+	if len(sec.replicas) > 0 {
+		resp["ReplicationStatus"] = s.replicationStatus(sec)
+	}
+	s.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, resp)
 }
 
 func (s *Service) getSecretValue(w http.ResponseWriter, params map[string]interface{}) {
@@ -216,6 +211,12 @@ func (s *Service) deleteSecret(w http.ResponseWriter, params map[string]interfac
 		writeJSONError(w, "ResourceNotFoundException", "Secrets Manager can't find the specified secret.", http.StatusBadRequest)
 		return
 	}
+	if len(sec.replicas) > 0 {
+		msg := fmt.Sprintf("You can't delete secret %s that still has replica regions [%s].", sec.name, strings.Join(sec.replicaRegions(), ", "))
+		s.mu.Unlock()
+		writeJSONError(w, "InvalidRequestException", msg, http.StatusBadRequest)
+		return
+	}
 
 	now := time.Now().UTC()
 	sec.deleted = true
@@ -260,15 +261,14 @@ func (s *Service) describeSecret(w http.ResponseWriter, params map[string]interf
 	secretID := getString(params, "SecretId")
 
 	s.mu.RLock()
+	defer s.mu.RUnlock()
 	sec := s.findSecret(secretID)
-	s.mu.RUnlock()
-
 	if sec == nil {
 		writeJSONError(w, "ResourceNotFoundException", "Secrets Manager can't find the specified secret.", http.StatusBadRequest)
 		return
 	}
 
-	writeJSON(w, http.StatusOK, map[string]interface{}{
+	resp := map[string]interface{}{
 		"ARN":             sec.arn,
 		"Name":            sec.name,
 		"Description":     sec.description,
@@ -277,7 +277,13 @@ func (s *Service) describeSecret(w http.ResponseWriter, params map[string]interf
 		"VersionIdsToStages": map[string][]string{
 			sec.versionID: {"AWSCURRENT"},
 		},
-	})
+		"Tags":          tags.ToList(s.tags.Get(sec.arn), "Key", "Value"),
+		"PrimaryRegion": primaryRegion,
+	}
+	if len(sec.replicas) > 0 {
+		resp["ReplicationStatus"] = s.replicationStatus(sec)
+	}
+	writeJSON(w, http.StatusOK, resp)
 }
 
 func (s *Service) updateSecret(w http.ResponseWriter, params map[string]interface{}) {
@@ -311,7 +317,38 @@ func (s *Service) updateSecret(w http.ResponseWriter, params map[string]interfac
 	})
 }
 
-// findSecret looks up a secret by name or ARN. Caller must hold s.mu.
+func (s *Service) tagResource(w http.ResponseWriter, params map[string]interface{}) {
+	secretID := getString(params, "SecretId")
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	sec := s.findSecret(secretID)
+	if sec == nil {
+		writeJSONError(w, "ResourceNotFoundException", "Secrets Manager can't find the specified secret.", http.StatusBadRequest)
+		return
+	}
+
+	s.tags.Tag(sec.arn, tags.FromList(params["Tags"], "Key", "Value"))
+	writeJSON(w, http.StatusOK, map[string]interface{}{})
+}
+
+func (s *Service) untagResource(w http.ResponseWriter, params map[string]interface{}) {
+	secretID := getString(params, "SecretId")
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	sec := s.findSecret(secretID)
+	if sec == nil {
+		writeJSONError(w, "ResourceNotFoundException", "Secrets Manager can't find the specified secret.", http.StatusBadRequest)
+		return
+	}
+
+	s.tags.Untag(sec.arn, tags.Keys(params["TagKeys"]))
+	writeJSON(w, http.StatusOK, map[string]interface{}{})
+}
+
+// findSecret looks up a secret by name, ARN, or the ARN of one of its
+// replicas. Caller must hold s.mu.
 func (s *Service) findSecret(secretID string) *secret {
 	// Try direct name lookup.
 	if sec, ok := s.secrets[secretID]; ok {
@@ -322,6 +359,11 @@ func (s *Service) findSecret(secretID string) *secret {
 		if sec.arn == secretID {
 			return sec
 		}
+		for _, r := range sec.replicas {
+			if sec.replicaArn(r.region) == secretID {
+				return sec
+			}
+		}
 	}
 	return nil
 }
@@ -344,12 +386,7 @@ func writeJSON(w http.ResponseWriter, status int, v interface{}) {
 }
 
 func writeJSONError(w http.ResponseWriter, code, message string, status int) {
-	w.Header().Set("Content-Type", "application/x-amz-json-1.1")
-	w.WriteHeader(status)
-	json.NewEncoder(w).Encode(map[string]interface{}{
-		"__type":  code,
-		"message": message,
-	})
+	awserr.WriteJSON(w, awserr.JSON11, code, message, status)
 }
 
 func newRequestID() string {
@@ -0,0 +1,32 @@
+package secretsmanager
+
+import "github.com/riyanimam/goto/internal/mockhelpers"
+
+// ExportState lists the secrets that are not scheduled for deletion. Secret
+// values are left out.
+func (s *Service) ExportState() []mockhelpers.Resource {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	resources := make([]mockhelpers.Resource, 0, len(s.secrets))
+	for _, sec := range s.secrets {
+		if sec.deleted {
+			continue
+		}
+		regions := make([]string, 0, len(sec.replicas))
+		for _, r := range sec.replicas {
+			regions = append(regions, r.region)
+		}
+		resources = append(resources, mockhelpers.Resource{
+			Type: "aws_secretsmanager_secret",
+			ID:   sec.arn,
+			ARN:  sec.arn,
+			Attributes: map[string]interface{}{
+				"name":        sec.name,
+				"description": sec.description,
+				"replica":     regions,
+			},
+		})
+	}
+	return resources
+}
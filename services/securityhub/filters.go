@@ -0,0 +1,222 @@
+package securityhub
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	h "github.com/riyanimam/goto/internal/mockhelpers"
+)
+
+// filterPaths maps the AwsSecurityFindingFilters fields whose names differ
+// from the finding fields they filter on to those fields, as dotted paths.
+// Arrays along a path are flattened, so "Resources.Type" is the type of every
+// resource of a finding. Other filter fields name top-level finding fields.
+var filterPaths = map[string]string{
+	"Type":                            "Types",
+	"SeverityLabel":                   "Severity.Label",
+	"SeverityNormalized":              "Severity.Normalized",
+	"SeverityProduct":                 "Severity.Product",
+	"WorkflowStatus":                  "Workflow.Status",
+	"ComplianceStatus":                "Compliance.Status",
+	"ComplianceSecurityControlId":     "Compliance.SecurityControlId",
+	"ComplianceAssociatedStandardsId": "Compliance.AssociatedStandards.StandardsId",
+	"NoteText":                        "Note.Text",
+	"NoteUpdatedAt":                   "Note.UpdatedAt",
+	"NoteUpdatedBy":                   "Note.UpdatedBy",
+	"RecommendationText":              "Remediation.Recommendation.Text",
+	"RelatedFindingsId":               "RelatedFindings.Id",
+	"RelatedFindingsProductArn":       "RelatedFindings.ProductArn",
+	"ResourceId":                      "Resources.Id",
+	"ResourceType":                    "Resources.Type",
+	"ResourceRegion":                  "Resources.Region",
+	"ResourcePartition":               "Resources.Partition",
+	"ResourceTags":                    "Resources.Tags",
+	"ResourceDetailsOther":            "Resources.Details.Other",
+	"SourceUrl":                       "SourceUrl",
+}
+
+// fieldPath returns the finding field a filter, sort, or group-by field
+// names.
+func fieldPath(name string) string {
+	if path, ok := filterPaths[name]; ok {
+		return path
+	}
+	return name
+}
+
+// fieldValues returns the values at path in data, flattening arrays.
+func fieldValues(data interface{}, path string) []interface{} {
+	current := []interface{}{data}
+	for _, part := range strings.Split(path, ".") {
+		var next []interface{}
+		for _, v := range current {
+			m, ok := v.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			switch child := m[part].(type) {
+			case nil:
+			case []interface{}:
+				next = append(next, child...)
+			default:
+				next = append(next, child)
+			}
+		}
+		current = next
+	}
+	return current
+}
+
+// firstValue returns the first value at path in data, or nil.
+func firstValue(data map[string]interface{}, path string) interface{} {
+	if vs := fieldValues(data, path); len(vs) > 0 {
+		return vs[0]
+	}
+	return nil
+}
+
+// sortValue returns v as a key that orders numbers numerically and
+// everything else as strings, with missing values first.
+func sortValue(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case float64:
+		return fmt.Sprintf("%024.6f", v)
+	}
+	return fmt.Sprint(v)
+}
+
+// matchFilters reports whether a finding matches every field of filters.
+// Within a field, positive string and map filters (EQUALS, PREFIX, CONTAINS)
+// match if any of them does, while negative ones (NOT_EQUALS,
+// PREFIX_NOT_EQUALS, NOT_CONTAINS) must all match; number, date, and boolean
+// filters on a field match if any of them does.
+func matchFilters(data map[string]interface{}, filters map[string]interface{}, now time.Time) bool {
+	for field, list := range filters {
+		conditions, _ := list.([]interface{})
+		if len(conditions) == 0 {
+			continue
+		}
+		values := fieldValues(data, fieldPath(field))
+		anyPositive, positiveMatched := false, false
+		for _, c := range conditions {
+			cond, _ := c.(map[string]interface{})
+			negative, ok := matchCondition(values, cond, now)
+			if negative {
+				if !ok {
+					return false
+				}
+				continue
+			}
+			anyPositive = true
+			positiveMatched = positiveMatched || ok
+		}
+		if anyPositive && !positiveMatched {
+			return false
+		}
+	}
+	return true
+}
+
+// matchCondition evaluates one filter condition against a field's values,
+// reporting whether it is a negative condition and whether it matched.
+func matchCondition(values []interface{}, cond map[string]interface{}, now time.Time) (negative, ok bool) {
+	switch {
+	case cond["Key"] != nil:
+		key := h.GetString(cond, "Key")
+		var mapped []interface{}
+		for _, v := range values {
+			if m, isMap := v.(map[string]interface{}); isMap {
+				if mv, exists := m[key]; exists {
+					mapped = append(mapped, mv)
+				}
+			}
+		}
+		return matchString(mapped, h.GetString(cond, "Comparison"), h.GetString(cond, "Value"))
+	case cond["Comparison"] != nil:
+		return matchString(values, h.GetString(cond, "Comparison"), h.GetString(cond, "Value"))
+	case cond["Start"] != nil || cond["End"] != nil || cond["DateRange"] != nil:
+		return false, matchDate(values, cond, now)
+	case cond["Gte"] != nil || cond["Lte"] != nil || cond["Eq"] != nil || cond["Gt"] != nil || cond["Lt"] != nil:
+		return false, matchNumber(values, cond)
+	default:
+		want, isBool := cond["Value"].(bool)
+		for _, v := range values {
+			if got, _ := v.(bool); isBool && got == want {
+				return false, true
+			}
+		}
+		return false, false
+	}
+}
+
+// matchString applies a string comparison to a field's values. Positive
+// comparisons match if any value does; negative ones if no value fails them.
+func matchString(values []interface{}, comparison, want string) (negative, ok bool) {
+	test := func(got string) bool {
+		switch comparison {
+		case "PREFIX", "PREFIX_NOT_EQUALS":
+			return strings.HasPrefix(got, want)
+		case "CONTAINS", "NOT_CONTAINS":
+			return strings.Contains(got, want)
+		default:
+			return got == want
+		}
+	}
+	negative = strings.HasPrefix(comparison, "NOT_") || comparison == "PREFIX_NOT_EQUALS"
+	for _, v := range values {
+		if test(fmt.Sprint(v)) {
+			return negative, !negative
+		}
+	}
+	return negative, negative
+}
+
+func matchNumber(values []interface{}, cond map[string]interface{}) bool {
+	for _, v := range values {
+		n, isNumber := v.(float64)
+		if !isNumber {
+			continue
+		}
+		if bound, set := cond["Eq"].(float64); set && n != bound {
+			continue
+		}
+		if bound, set := cond["Gte"].(float64); set && n < bound {
+			continue
+		}
+		if bound, set := cond["Gt"].(float64); set && n <= bound {
+			continue
+		}
+		if bound, set := cond["Lte"].(float64); set && n > bound {
+			continue
+		}
+		if bound, set := cond["Lt"].(float64); set && n >= bound {
+			continue
+		}
+		return true
+	}
+	return false
+}
+
+func matchDate(values []interface{}, cond map[string]interface{}, now time.Time) bool {
+	var start, end time.Time
+	if r, ok := cond["DateRange"].(map[string]interface{}); ok {
+		end = now
+		start = now.AddDate(0, 0, -h.GetInt(r, "Value", 0))
+	} else {
+		start, _ = time.Parse(time.RFC3339Nano, h.GetString(cond, "Start"))
+		end, _ = time.Parse(time.RFC3339Nano, h.GetString(cond, "End"))
+	}
+	for _, v := range values {
+		t, err := time.Parse(time.RFC3339Nano, fmt.Sprint(v))
+		if err != nil {
+			continue
+		}
+		if (start.IsZero() || !t.Before(start)) && (end.IsZero() || !t.After(end)) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,204 @@
+package securityhub
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+
+	h "github.com/riyanimam/goto/internal/mockhelpers"
+	"github.com/riyanimam/goto/internal/paginate"
+)
+
+// insight is a saved finding filter whose results are grouped by an
+// attribute, as on the Security Hub insights dashboard.
+type insight struct {
+	arn              string
+	name             string
+	filters          map[string]interface{}
+	groupByAttribute string
+}
+
+func (i *insight) toMap() map[string]interface{} {
+	return map[string]interface{}{
+		"InsightArn":       i.arn,
+		"Name":             i.name,
+		"Filters":          i.filters,
+		"GroupByAttribute": i.groupByAttribute,
+	}
+}
+
+func writeInsightNotFound(w http.ResponseWriter, arn string) {
+	h.WriteJSONError(w, "ResourceNotFoundException", "Insight "+arn+" not found.", http.StatusNotFound)
+}
+
+func (s *Service) createInsight(w http.ResponseWriter, r *http.Request) {
+	params := readBody(r)
+	name := h.GetString(params, "Name")
+	filters, _ := params["Filters"].(map[string]interface{})
+	groupBy := h.GetString(params, "GroupByAttribute")
+	if name == "" || filters == nil || groupBy == "" {
+		h.WriteJSONError(w, "InvalidInputException", "Name, Filters, and GroupByAttribute are required.", http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.subscribed(w) {
+		return
+	}
+	i := &insight{
+		arn:              fmt.Sprintf("arn:aws:securityhub:us-east-1:%s:insight/%s/custom/%s", h.DefaultAccountID, h.DefaultAccountID, h.NewRequestID()),
+		name:             name,
+		filters:          filters,
+		groupByAttribute: groupBy,
+	}
+	s.insights[i.arn] = i
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"InsightArn": i.arn,
+	})
+}
+
+func (s *Service) getInsights(w http.ResponseWriter, r *http.Request) {
+	params := readBody(r)
+	arns, _ := params["InsightArns"].([]interface{})
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if !s.subscribed(w) {
+		return
+	}
+	// Requested insights are listed in the order requested.
+	var list []*insight
+	key := paginate.Seq
+	if len(arns) == 0 {
+		for _, i := range s.insights {
+			list = append(list, i)
+		}
+		key = func(i int) string { return list[i].arn }
+		sort.Slice(list, func(a, b int) bool { return key(a) < key(b) })
+	} else {
+		for _, a := range arns {
+			arn, _ := a.(string)
+			i, exists := s.insights[arn]
+			if !exists {
+				writeInsightNotFound(w, arn)
+				return
+			}
+			list = append(list, i)
+		}
+	}
+	page, next, err := paginate.Page(list, key, h.GetString(params, "NextToken"), h.GetInt(params, "MaxResults", 0), 100)
+	if err != nil {
+		h.WriteJSONError(w, "InvalidInputException", "Invalid NextToken", http.StatusBadRequest)
+		return
+	}
+	insights := make([]map[string]interface{}, 0, len(page))
+	for _, i := range page {
+		insights = append(insights, i.toMap())
+	}
+	resp := map[string]interface{}{
+		"Insights": insights,
+	}
+	if next != "" {
+		resp["NextToken"] = next
+	}
+	h.WriteJSON(w, http.StatusOK, resp)
+}
+
+func (s *Service) updateInsight(w http.ResponseWriter, r *http.Request, arn string) {
+	params := readBody(r)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.subscribed(w) {
+		return
+	}
+	i, exists := s.insights[arn]
+	if !exists {
+		writeInsightNotFound(w, arn)
+		return
+	}
+	if name := h.GetString(params, "Name"); name != "" {
+		i.name = name
+	}
+	if filters, ok := params["Filters"].(map[string]interface{}); ok {
+		i.filters = filters
+	}
+	if groupBy := h.GetString(params, "GroupByAttribute"); groupBy != "" {
+		i.groupByAttribute = groupBy
+	}
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{})
+}
+
+func (s *Service) deleteInsight(w http.ResponseWriter, arn string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.subscribed(w) {
+		return
+	}
+	if _, exists := s.insights[arn]; !exists {
+		writeInsightNotFound(w, arn)
+		return
+	}
+	delete(s.insights, arn)
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"InsightArn": arn,
+	})
+}
+
+// getInsightResults counts the findings matching an insight's filters by
+// each value of its group-by attribute, largest group first. A finding with
+// several values, such as several resources, counts toward each.
+func (s *Service) getInsightResults(w http.ResponseWriter, arn string) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if !s.subscribed(w) {
+		return
+	}
+	i, exists := s.insights[arn]
+	if !exists {
+		writeInsightNotFound(w, arn)
+		return
+	}
+
+	now := s.now()
+	counts := map[string]int{}
+	for _, f := range s.findings {
+		if !matchFilters(f.data, i.filters, now) {
+			continue
+		}
+		seen := map[string]bool{}
+		for _, v := range fieldValues(f.data, fieldPath(i.groupByAttribute)) {
+			value := fmt.Sprint(v)
+			if !seen[value] {
+				seen[value] = true
+				counts[value]++
+			}
+		}
+	}
+	values := make([]map[string]interface{}, 0, len(counts))
+	for value, count := range counts {
+		values = append(values, map[string]interface{}{
+			"GroupByAttributeValue": value,
+			"Count":                 count,
+		})
+	}
+	sort.Slice(values, func(a, b int) bool {
+		ca, cb := values[a]["Count"].(int), values[b]["Count"].(int)
+		if ca != cb {
+			return ca > cb
+		}
+		return values[a]["GroupByAttributeValue"].(string) < values[b]["GroupByAttributeValue"].(string)
+	})
+	if len(values) > 100 {
+		values = values[:100]
+	}
+
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"InsightResults": map[string]interface{}{
+			"InsightArn":       i.arn,
+			"GroupByAttribute": i.groupByAttribute,
+			"ResultValues":     values,
+		},
+	})
+}
@@ -0,0 +1,568 @@
+// Package securityhub provides a mock implementation of AWS Security Hub.
+//
+// Supported actions:
+//   - EnableSecurityHub
+//   - DescribeHub
+//   - DisableSecurityHub
+//   - BatchImportFindings
+//   - GetFindings
+//   - BatchUpdateFindings
+//   - CreateInsight
+//   - GetInsights
+//   - UpdateInsight
+//   - DeleteInsight
+//   - GetInsightResults
+//   - TagResource (hub)
+//   - UntagResource (hub)
+//   - ListTagsForResource (hub)
+//
+// Findings are stored as imported, in the AWS Security Finding Format, and
+// identified by their ProductArn and Id. Importing a finding again replaces
+// it, except for the fields customers manage with BatchUpdateFindings:
+// Note, UserDefinedFields, VerificationState, and Workflow always keep their
+// values, and Confidence, Criticality, RelatedFindings, Severity, and Types
+// keep theirs once BatchUpdateFindings has set them.
+//
+// GetFindings and insights support the string, number, date, map, and
+// boolean filters of AwsSecurityFindingFilters.
+package securityhub
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/riyanimam/goto/internal/clock"
+	h "github.com/riyanimam/goto/internal/mockhelpers"
+	"github.com/riyanimam/goto/internal/paginate"
+	"github.com/riyanimam/goto/internal/tags"
+)
+
+// hubArn is the ARN of the account's hub.
+var hubArn = fmt.Sprintf("arn:aws:securityhub:us-east-1:%s:hub/default", h.DefaultAccountID)
+
+// Service implements the Security Hub mock.
+type Service struct {
+	mu       sync.RWMutex
+	enabled  bool
+	hub      hub
+	findings map[string]*finding // keyed by findingKey
+	insights map[string]*insight // keyed by ARN
+	seq      int
+	tags     *tags.Store
+	clock    *clock.Clock
+}
+
+type hub struct {
+	subscribedAt            time.Time
+	autoEnableControls      bool
+	controlFindingGenerator string
+}
+
+type finding struct {
+	data map[string]interface{}
+	seq  int // import order, for a stable listing
+
+	// customerSet records the fields BatchUpdateFindings has set, which
+	// later imports leave alone.
+	customerSet map[string]bool
+}
+
+// customerFields are the finding fields that BatchImportFindings only sets
+// on new findings.
+var customerFields = []string{"Note", "UserDefinedFields", "VerificationState", "Workflow", "WorkflowState"}
+
+// updatableFields are the finding fields BatchUpdateFindings sets, which
+// BatchImportFindings updates only until then.
+var updatableFields = []string{"Confidence", "Criticality", "RelatedFindings", "Severity", "Types"}
+
+// requiredFields are the ASFF fields every imported finding must have.
+var requiredFields = []string{"AwsAccountId", "CreatedAt", "Description", "GeneratorId", "Id", "ProductArn", "Resources", "SchemaVersion", "Severity", "Title", "UpdatedAt"}
+
+// New creates a new Security Hub mock service.
+func New() *Service {
+	return &Service{
+		findings: make(map[string]*finding),
+		insights: make(map[string]*insight),
+		tags:     tags.New(),
+	}
+}
+
+// Name returns the service identifier.
+func (s *Service) Name() string { return "securityhub" }
+
+// Handler returns the HTTP handler for Security Hub requests.
+func (s *Service) Handler() http.Handler {
+	return http.HandlerFunc(s.handle)
+}
+
+// Reset clears all state, disabling the hub.
+func (s *Service) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.enabled = false
+	s.hub = hub{}
+	s.findings = make(map[string]*finding)
+	s.insights = make(map[string]*insight)
+	s.tags.DeleteService("securityhub")
+}
+
+// SetTagStore sets the registry resource tags are recorded in.
+func (s *Service) SetTagStore(store *tags.Store) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tags = store
+}
+
+// SetClock attaches the mock clock that finding and hub timestamps, and
+// relative date filters, use.
+func (s *Service) SetClock(c *clock.Clock) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.clock = c
+}
+
+func (s *Service) now() time.Time {
+	if s.clock != nil {
+		return s.clock.Now()
+	}
+	return time.Now().UTC()
+}
+
+func (s *Service) handle(w http.ResponseWriter, r *http.Request) {
+	path := r.URL.Path
+	method := r.Method
+
+	switch {
+	case strings.HasPrefix(path, "/tags/"):
+		s.handleTags(w, r, strings.TrimPrefix(path, "/tags/"))
+	case path == "/accounts" && method == http.MethodPost:
+		s.enableSecurityHub(w, r)
+	case path == "/accounts" && method == http.MethodGet:
+		s.describeHub(w)
+	case path == "/accounts" && method == http.MethodDelete:
+		s.disableSecurityHub(w)
+	case path == "/findings/import" && method == http.MethodPost:
+		s.batchImportFindings(w, r)
+	case path == "/findings" && method == http.MethodPost:
+		s.getFindings(w, r)
+	case path == "/findings/batchupdate" && method == http.MethodPatch:
+		s.batchUpdateFindings(w, r)
+	case path == "/insights" && method == http.MethodPost:
+		s.createInsight(w, r)
+	case path == "/insights/get" && method == http.MethodPost:
+		s.getInsights(w, r)
+	case strings.HasPrefix(path, "/insights/results/") && method == http.MethodGet:
+		s.getInsightResults(w, strings.TrimPrefix(path, "/insights/results/"))
+	case strings.HasPrefix(path, "/insights/") && method == http.MethodPatch:
+		s.updateInsight(w, r, strings.TrimPrefix(path, "/insights/"))
+	case strings.HasPrefix(path, "/insights/") && method == http.MethodDelete:
+		s.deleteInsight(w, strings.TrimPrefix(path, "/insights/"))
+	default:
+		h.WriteJSONError(w, "InvalidInputException", "unsupported operation", http.StatusBadRequest)
+	}
+}
+
+func readBody(r *http.Request) map[string]interface{} {
+	bodyBytes, _ := io.ReadAll(r.Body)
+	var params map[string]interface{}
+	json.Unmarshal(bodyBytes, &params)
+	return params
+}
+
+func findingKey(productArn, id string) string {
+	return productArn + "\x00" + id
+}
+
+// subscribed writes the error for an account without a hub and reports
+// false if the hub is not enabled. The caller must hold s.mu.
+func (s *Service) subscribed(w http.ResponseWriter) bool {
+	if !s.enabled {
+		h.WriteJSONError(w, "InvalidAccessException", fmt.Sprintf("Account %s is not subscribed to AWS Security Hub", h.DefaultAccountID), http.StatusUnauthorized)
+		return false
+	}
+	return true
+}
+
+func (s *Service) enableSecurityHub(w http.ResponseWriter, r *http.Request) {
+	params := readBody(r)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.enabled {
+		h.WriteJSONError(w, "ResourceConflictException", fmt.Sprintf("Account %s is already subscribed to Security Hub", h.DefaultAccountID), http.StatusConflict)
+		return
+	}
+	generator := h.GetString(params, "ControlFindingGenerator")
+	if generator == "" {
+		generator = "SECURITY_CONTROL"
+	}
+	s.enabled = true
+	s.hub = hub{
+		subscribedAt:            s.now(),
+		autoEnableControls:      true,
+		controlFindingGenerator: generator,
+	}
+	s.tags.Replace(hubArn, tags.FromMap(params["Tags"]))
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{})
+}
+
+func (s *Service) describeHub(w http.ResponseWriter) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if !s.subscribed(w) {
+		return
+	}
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"HubArn":                  hubArn,
+		"SubscribedAt":            s.hub.subscribedAt.Format(time.RFC3339Nano),
+		"AutoEnableControls":      s.hub.autoEnableControls,
+		"ControlFindingGenerator": s.hub.controlFindingGenerator,
+	})
+}
+
+// disableSecurityHub disables the hub, discarding its findings and
+// insights.
+func (s *Service) disableSecurityHub(w http.ResponseWriter) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.subscribed(w) {
+		return
+	}
+	s.enabled = false
+	s.findings = make(map[string]*finding)
+	s.insights = make(map[string]*insight)
+	s.tags.Delete(hubArn)
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{})
+}
+
+func (s *Service) batchImportFindings(w http.ResponseWriter, r *http.Request) {
+	params := readBody(r)
+	findings, _ := params["Findings"].([]interface{})
+	if len(findings) == 0 || len(findings) > 100 {
+		h.WriteJSONError(w, "InvalidInputException", "Findings must contain between 1 and 100 findings.", http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.subscribed(w) {
+		return
+	}
+
+	now := s.now().Format(time.RFC3339Nano)
+	failed := []map[string]interface{}{}
+	for _, f := range findings {
+		data, _ := f.(map[string]interface{})
+		if msg := validateFinding(data); msg != "" {
+			failed = append(failed, map[string]interface{}{
+				"Id":           h.GetString(data, "Id"),
+				"ErrorCode":    "InvalidInput",
+				"ErrorMessage": msg,
+			})
+			continue
+		}
+
+		key := findingKey(h.GetString(data, "ProductArn"), h.GetString(data, "Id"))
+		data["ProcessedAt"] = now
+		if old, exists := s.findings[key]; exists {
+			for _, field := range customerFields {
+				keepField(data, old.data, field)
+			}
+			for _, field := range updatableFields {
+				if old.customerSet[field] {
+					keepField(data, old.data, field)
+				}
+			}
+			old.data = data
+			continue
+		}
+
+		setDefaults(data)
+		s.seq++
+		s.findings[key] = &finding{data: data, seq: s.seq, customerSet: map[string]bool{}}
+	}
+
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"SuccessCount":   len(findings) - len(failed),
+		"FailedCount":    len(failed),
+		"FailedFindings": failed,
+	})
+}
+
+// validateFinding returns why data is not a valid ASFF finding, or "".
+func validateFinding(data map[string]interface{}) string {
+	if data == nil {
+		return "Finding must be an object."
+	}
+	for _, field := range requiredFields {
+		if v, ok := data[field]; !ok || v == nil || v == "" {
+			return "Finding is missing required field " + field + "."
+		}
+	}
+	if resources, _ := data["Resources"].([]interface{}); len(resources) == 0 {
+		return "Finding must have at least one resource."
+	}
+	for _, field := range []string{"CreatedAt", "UpdatedAt", "FirstObservedAt", "LastObservedAt"} {
+		if v, ok := data[field].(string); ok {
+			if _, err := time.Parse(time.RFC3339Nano, v); err != nil {
+				return field + " must be an ISO 8601 timestamp."
+			}
+		}
+	}
+	return ""
+}
+
+// setDefaults fills in the fields Security Hub sets on new findings.
+func setDefaults(data map[string]interface{}) {
+	if _, ok := data["RecordState"]; !ok {
+		data["RecordState"] = "ACTIVE"
+	}
+	if _, ok := data["Workflow"]; !ok {
+		data["Workflow"] = map[string]interface{}{"Status": "NEW"}
+	}
+	if _, ok := data["WorkflowState"]; !ok {
+		data["WorkflowState"] = "NEW"
+	}
+	if _, ok := data["Region"]; !ok {
+		data["Region"] = "us-east-1"
+	}
+	// Product ARNs end in product/{company}/{product}.
+	parts := strings.Split(h.GetString(data, "ProductArn"), "/")
+	if len(parts) >= 3 {
+		if _, ok := data["CompanyName"]; !ok {
+			data["CompanyName"] = parts[len(parts)-2]
+		}
+		if _, ok := data["ProductName"]; !ok {
+			data["ProductName"] = parts[len(parts)-1]
+		}
+	}
+}
+
+// keepField copies field from old to data, or removes it from data if old
+// does not have it.
+func keepField(data, old map[string]interface{}, field string) {
+	if v, ok := old[field]; ok {
+		data[field] = v
+	} else {
+		delete(data, field)
+	}
+}
+
+func (s *Service) getFindings(w http.ResponseWriter, r *http.Request) {
+	params := readBody(r)
+	filters, _ := params["Filters"].(map[string]interface{})
+	criteria, _ := params["SortCriteria"].([]interface{})
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if !s.subscribed(w) {
+		return
+	}
+
+	now := s.now()
+	var matched []*finding
+	for _, f := range s.findings {
+		if matchFilters(f.data, filters, now) {
+			matched = append(matched, f)
+		}
+	}
+	key := func(i int) string { return sortKey(matched[i], criteria) }
+	sort.Slice(matched, func(i, j int) bool { return key(i) < key(j) })
+
+	page, next, err := paginate.Page(matched, key, h.GetString(params, "NextToken"), h.GetInt(params, "MaxResults", 0), 100)
+	if err != nil {
+		h.WriteJSONError(w, "InvalidInputException", "Invalid NextToken", http.StatusBadRequest)
+		return
+	}
+	findings := make([]map[string]interface{}, 0, len(page))
+	for _, f := range page {
+		findings = append(findings, f.data)
+	}
+	resp := map[string]interface{}{
+		"Findings": findings,
+	}
+	if next != "" {
+		resp["NextToken"] = next
+	}
+	h.WriteJSON(w, http.StatusOK, resp)
+}
+
+// sortKey returns the key GetFindings lists f in order of: its values for
+// each of the request's SortCriteria, newest update first by default, and
+// then the order findings were imported in.
+func sortKey(f *finding, criteria []interface{}) string {
+	if len(criteria) == 0 {
+		criteria = []interface{}{map[string]interface{}{"Field": "UpdatedAt", "SortOrder": "desc"}}
+	}
+	parts := make([]string, 0, len(criteria)+1)
+	for _, c := range criteria {
+		cm, _ := c.(map[string]interface{})
+		part := sortValue(firstValue(f.data, fieldPath(h.GetString(cm, "Field"))))
+		if strings.EqualFold(h.GetString(cm, "SortOrder"), "desc") {
+			part = paginate.Desc(part)
+		}
+		parts = append(parts, part)
+	}
+	return paginate.Join(append(parts, paginate.Seq(f.seq))...)
+}
+
+func (s *Service) batchUpdateFindings(w http.ResponseWriter, r *http.Request) {
+	params := readBody(r)
+	identifiers, _ := params["FindingIdentifiers"].([]interface{})
+	if len(identifiers) == 0 || len(identifiers) > 100 {
+		h.WriteJSONError(w, "InvalidInputException", "FindingIdentifiers must contain between 1 and 100 identifiers.", http.StatusBadRequest)
+		return
+	}
+	if msg := validateUpdate(params); msg != "" {
+		h.WriteJSONError(w, "InvalidInputException", msg, http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.subscribed(w) {
+		return
+	}
+
+	now := s.now().Format(time.RFC3339Nano)
+	processed := []map[string]interface{}{}
+	unprocessed := []map[string]interface{}{}
+	for _, id := range identifiers {
+		idm, _ := id.(map[string]interface{})
+		ident := map[string]interface{}{
+			"Id":         h.GetString(idm, "Id"),
+			"ProductArn": h.GetString(idm, "ProductArn"),
+		}
+		f, exists := s.findings[findingKey(h.GetString(idm, "ProductArn"), h.GetString(idm, "Id"))]
+		if !exists {
+			unprocessed = append(unprocessed, map[string]interface{}{
+				"FindingIdentifier": ident,
+				"ErrorCode":         "FindingNotFound",
+				"ErrorMessage":      "Finding Not Found",
+			})
+			continue
+		}
+		applyUpdate(f, params, now)
+		processed = append(processed, ident)
+	}
+
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"ProcessedFindings":   processed,
+		"UnprocessedFindings": unprocessed,
+	})
+}
+
+// validateUpdate returns why a BatchUpdateFindings request is invalid, or "".
+func validateUpdate(params map[string]interface{}) string {
+	if workflow, ok := params["Workflow"].(map[string]interface{}); ok {
+		switch h.GetString(workflow, "Status") {
+		case "NEW", "NOTIFIED", "RESOLVED", "SUPPRESSED":
+		default:
+			return "Workflow.Status must be one of NEW, NOTIFIED, RESOLVED, or SUPPRESSED."
+		}
+	}
+	if severity, ok := params["Severity"].(map[string]interface{}); ok {
+		switch label := h.GetString(severity, "Label"); label {
+		case "", "INFORMATIONAL", "LOW", "MEDIUM", "HIGH", "CRITICAL":
+		default:
+			return "Severity.Label must be one of INFORMATIONAL, LOW, MEDIUM, HIGH, or CRITICAL."
+		}
+	}
+	if note, ok := params["Note"].(map[string]interface{}); ok {
+		if h.GetString(note, "Text") == "" || h.GetString(note, "UpdatedBy") == "" {
+			return "Note requires Text and UpdatedBy."
+		}
+	}
+	return ""
+}
+
+// severityNormalized maps severity labels to the normalized scores
+// Security Hub gives them.
+var severityNormalized = map[string]int{
+	"INFORMATIONAL": 0,
+	"LOW":           1,
+	"MEDIUM":        40,
+	"HIGH":          70,
+	"CRITICAL":      90,
+}
+
+// applyUpdate applies the fields of a BatchUpdateFindings request to f.
+func applyUpdate(f *finding, params map[string]interface{}, now string) {
+	for _, field := range []string{"Confidence", "Criticality", "RelatedFindings", "Types", "VerificationState"} {
+		if v, ok := params[field]; ok {
+			f.data[field] = v
+			f.customerSet[field] = true
+		}
+	}
+	if fields, ok := params["UserDefinedFields"].(map[string]interface{}); ok {
+		existing, _ := f.data["UserDefinedFields"].(map[string]interface{})
+		if existing == nil {
+			existing = map[string]interface{}{}
+		}
+		for k, v := range fields {
+			existing[k] = v
+		}
+		f.data["UserDefinedFields"] = existing
+	}
+	if note, ok := params["Note"].(map[string]interface{}); ok {
+		f.data["Note"] = map[string]interface{}{
+			"Text":      h.GetString(note, "Text"),
+			"UpdatedBy": h.GetString(note, "UpdatedBy"),
+			"UpdatedAt": now,
+		}
+	}
+	if workflow, ok := params["Workflow"].(map[string]interface{}); ok {
+		status := h.GetString(workflow, "Status")
+		f.data["Workflow"] = map[string]interface{}{"Status": status}
+		f.data["WorkflowState"] = map[string]string{
+			"NEW": "NEW", "NOTIFIED": "ASSIGNED", "RESOLVED": "RESOLVED", "SUPPRESSED": "RESOLVED",
+		}[status]
+	}
+	if severity, ok := params["Severity"].(map[string]interface{}); ok {
+		existing, _ := f.data["Severity"].(map[string]interface{})
+		updated := map[string]interface{}{}
+		for k, v := range existing {
+			updated[k] = v
+		}
+		for k, v := range severity {
+			updated[k] = v
+		}
+		if label := h.GetString(severity, "Label"); label != "" {
+			if _, ok := severity["Normalized"]; !ok {
+				updated["Normalized"] = severityNormalized[label]
+			}
+		}
+		f.data["Severity"] = updated
+		f.customerSet["Severity"] = true
+	}
+}
+
+// handleTags serves the tagging API. The hub is the only taggable
+// resource.
+func (s *Service) handleTags(w http.ResponseWriter, r *http.Request, arn string) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if arn != hubArn || !s.enabled {
+		h.WriteJSONError(w, "ResourceNotFoundException", "Resource not found: "+arn, http.StatusNotFound)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPost:
+		s.tags.Tag(arn, tags.FromMap(readBody(r)["Tags"]))
+		h.WriteJSON(w, http.StatusOK, map[string]interface{}{})
+	case http.MethodDelete:
+		s.tags.Untag(arn, r.URL.Query()["tagKeys"])
+		h.WriteJSON(w, http.StatusOK, map[string]interface{}{})
+	default:
+		h.WriteJSON(w, http.StatusOK, map[string]interface{}{
+			"Tags": s.tags.Get(arn),
+		})
+	}
+}
@@ -9,18 +9,19 @@
 //   - RegisterInstance
 //   - DeregisterInstance
 //   - ListInstances
+//   - TagResource
+//   - UntagResource
+//   - ListTagsForResource
 package servicediscovery
 
 import (
-	"encoding/json"
 	"fmt"
-	"io"
 	"net/http"
 	"sort"
-	"strings"
 	"sync"
 
 	h "github.com/riyanimam/goto/internal/mockhelpers"
+	"github.com/riyanimam/goto/internal/tags"
 )
 
 // Service implements the Cloud Map mock.
@@ -29,6 +30,7 @@ type Service struct {
 	namespaces map[string]*namespace
 	services   map[string]*service
 	instances  map[string][]*instance // keyed by service ID
+	tags       *tags.Store
 }
 
 type namespace struct {
@@ -60,6 +62,7 @@ func New() *Service {
 		namespaces: make(map[string]*namespace),
 		services:   make(map[string]*service),
 		instances:  make(map[string][]*instance),
+		tags:       tags.New(),
 	}
 }
 
@@ -68,7 +71,19 @@ func (s *Service) Name() string { return "servicediscovery" }
 
 // Handler returns the HTTP handler for Cloud Map requests.
 func (s *Service) Handler() http.Handler {
-	return http.HandlerFunc(s.handle)
+	return h.JSONRouter{
+		"CreatePrivateDnsNamespace": s.createPrivateDnsNamespace,
+		"CreateService":             s.createService,
+		"GetService":                s.getService,
+		"DeleteService":             s.deleteService,
+		"ListServices":              s.listServices,
+		"RegisterInstance":          s.registerInstance,
+		"DeregisterInstance":        s.deregisterInstance,
+		"ListInstances":             s.listInstances,
+		"TagResource":               s.tagResource,
+		"UntagResource":             s.untagResource,
+		"ListTagsForResource":       s.listTagsForResource,
+	}
 }
 
 // Reset clears all state.
@@ -78,53 +93,14 @@ func (s *Service) Reset() {
 	s.namespaces = make(map[string]*namespace)
 	s.services = make(map[string]*service)
 	s.instances = make(map[string][]*instance)
+	s.tags.DeleteService("servicediscovery")
 }
 
-func (s *Service) handle(w http.ResponseWriter, r *http.Request) {
-	target := r.Header.Get("X-Amz-Target")
-
-	bodyBytes, err := io.ReadAll(r.Body)
-	if err != nil {
-		h.WriteJSONError(w, "InternalFailure", "could not read request body", http.StatusInternalServerError)
-		return
-	}
-
-	var params map[string]interface{}
-	if len(bodyBytes) > 0 {
-		json.Unmarshal(bodyBytes, &params)
-	}
-	if params == nil {
-		params = make(map[string]interface{})
-	}
-
-	action := ""
-	if target != "" {
-		parts := strings.SplitN(target, ".", 2)
-		if len(parts) == 2 {
-			action = parts[1]
-		}
-	}
-
-	switch action {
-	case "CreatePrivateDnsNamespace":
-		s.createPrivateDnsNamespace(w, params)
-	case "CreateService":
-		s.createService(w, params)
-	case "GetService":
-		s.getService(w, params)
-	case "DeleteService":
-		s.deleteService(w, params)
-	case "ListServices":
-		s.listServices(w, params)
-	case "RegisterInstance":
-		s.registerInstance(w, params)
-	case "DeregisterInstance":
-		s.deregisterInstance(w, params)
-	case "ListInstances":
-		s.listInstances(w, params)
-	default:
-		h.WriteJSONError(w, "UnknownOperationException", fmt.Sprintf("action %q is not supported", action), http.StatusBadRequest)
-	}
+// SetTagStore sets the registry resource tags are recorded in.
+func (s *Service) SetTagStore(store *tags.Store) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tags = store
 }
 
 func (s *Service) createPrivateDnsNamespace(w http.ResponseWriter, params map[string]interface{}) {
@@ -148,6 +124,7 @@ func (s *Service) createPrivateDnsNamespace(w http.ResponseWriter, params map[st
 		vpc:         vpc,
 		description: description,
 	}
+	s.tags.Tag(arn, tags.FromList(params["Tags"], "Key", "Value"))
 	s.mu.Unlock()
 
 	h.WriteJSON(w, http.StatusOK, map[string]interface{}{
@@ -176,6 +153,7 @@ func (s *Service) createService(w http.ResponseWriter, params map[string]interfa
 		dnsConfig:   dnsConfig,
 	}
 	s.services[id] = svc
+	s.tags.Tag(arn, tags.FromList(params["Tags"], "Key", "Value"))
 	s.mu.Unlock()
 
 	h.WriteJSON(w, http.StatusOK, map[string]interface{}{
@@ -204,12 +182,14 @@ func (s *Service) deleteService(w http.ResponseWriter, params map[string]interfa
 	id := h.GetString(params, "Id")
 
 	s.mu.Lock()
-	if _, exists := s.services[id]; !exists {
+	svc, exists := s.services[id]
+	if !exists {
 		s.mu.Unlock()
 		h.WriteJSONError(w, "ServiceNotFound", "Service not found: "+id, http.StatusBadRequest)
 		return
 	}
 	delete(s.services, id)
+	s.tags.Delete(svc.arn)
 	delete(s.instances, id)
 	s.mu.Unlock()
 
@@ -337,3 +317,62 @@ func svcResp(svc *service) map[string]interface{} {
 	}
 	return resp
 }
+
+// hasResource reports whether arn names a namespace or service. Caller must
+// hold s.mu.
+func (s *Service) hasResource(arn string) bool {
+	for _, ns := range s.namespaces {
+		if ns.arn == arn {
+			return true
+		}
+	}
+	for _, svc := range s.services {
+		if svc.arn == arn {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Service) tagResource(w http.ResponseWriter, params map[string]interface{}) {
+	arn := h.GetString(params, "ResourceARN")
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if !s.hasResource(arn) {
+		h.WriteJSONError(w, "ResourceNotFoundException", "Resource not found: "+arn, http.StatusBadRequest)
+		return
+	}
+	s.tags.Tag(arn, tags.FromList(params["Tags"], "Key", "Value"))
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{})
+}
+
+func (s *Service) untagResource(w http.ResponseWriter, params map[string]interface{}) {
+	arn := h.GetString(params, "ResourceARN")
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if !s.hasResource(arn) {
+		h.WriteJSONError(w, "ResourceNotFoundException", "Resource not found: "+arn, http.StatusBadRequest)
+		return
+	}
+	s.tags.Untag(arn, tags.Keys(params["TagKeys"]))
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{})
+}
+
+func (s *Service) listTagsForResource(w http.ResponseWriter, params map[string]interface{}) {
+	arn := h.GetString(params, "ResourceARN")
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if !s.hasResource(arn) {
+		h.WriteJSONError(w, "ResourceNotFoundException", "Resource not found: "+arn, http.StatusBadRequest)
+		return
+	}
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"Tags": tags.ToList(s.tags.Get(arn), "Key", "Value"),
+	})
+}
@@ -6,6 +6,9 @@
 //   - ListEmailIdentities
 //   - SendEmail
 //   - DeleteEmailIdentity
+//   - TagResource
+//   - UntagResource
+//   - ListTagsForResource
 package ses
 
 import (
@@ -19,6 +22,7 @@ import (
 	"time"
 
 	h "github.com/riyanimam/goto/internal/mockhelpers"
+	"github.com/riyanimam/goto/internal/tags"
 )
 
 // Service implements the SES mock.
@@ -26,6 +30,7 @@ type Service struct {
 	mu         sync.RWMutex
 	identities map[string]*emailIdentity
 	sentEmails []*sentEmail
+	tags       *tags.Store
 }
 
 type emailIdentity struct {
@@ -48,6 +53,7 @@ type sentEmail struct {
 func New() *Service {
 	return &Service{
 		identities: make(map[string]*emailIdentity),
+		tags:       tags.New(),
 	}
 }
 
@@ -65,6 +71,14 @@ func (s *Service) Reset() {
 	defer s.mu.Unlock()
 	s.identities = make(map[string]*emailIdentity)
 	s.sentEmails = nil
+	s.tags.DeleteService("ses")
+}
+
+// SetTagStore sets the registry email identity tags are recorded in.
+func (s *Service) SetTagStore(store *tags.Store) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tags = store
 }
 
 func (s *Service) handle(w http.ResponseWriter, r *http.Request) {
@@ -83,6 +97,12 @@ func (s *Service) handle(w http.ResponseWriter, r *http.Request) {
 		s.deleteEmailIdentity(w, r, identity)
 	case strings.HasSuffix(path, "/v2/email/outbound-emails") && r.Method == http.MethodPost:
 		s.sendEmail(w, r)
+	case strings.HasSuffix(path, "/v2/email/tags") && r.Method == http.MethodPost:
+		s.tagResource(w, r)
+	case strings.HasSuffix(path, "/v2/email/tags") && r.Method == http.MethodDelete:
+		s.untagResource(w, r)
+	case strings.HasSuffix(path, "/v2/email/tags") && r.Method == http.MethodGet:
+		s.listTagsForResource(w, r)
 	default:
 		h.WriteJSONError(w, "NotFoundException", "unsupported operation", http.StatusBadRequest)
 	}
@@ -96,6 +116,10 @@ func extractLastSegment(path string) string {
 	return ""
 }
 
+func identityArn(identity string) string {
+	return fmt.Sprintf("arn:aws:ses:us-east-1:%s:identity/%s", h.DefaultAccountID, identity)
+}
+
 func (s *Service) createEmailIdentity(w http.ResponseWriter, r *http.Request) {
 	bodyBytes, _ := io.ReadAll(r.Body)
 	var params map[string]interface{}
@@ -119,6 +143,7 @@ func (s *Service) createEmailIdentity(w http.ResponseWriter, r *http.Request) {
 		verified:     true, // Auto-verify in mock.
 		created:      time.Now().UTC(),
 	}
+	s.tags.Tag(identityArn(identity), tags.FromList(params["Tags"], "Key", "Value"))
 	s.mu.Unlock()
 
 	h.WriteJSON(w, http.StatusOK, map[string]interface{}{
@@ -135,6 +160,7 @@ func (s *Service) createEmailIdentity(w http.ResponseWriter, r *http.Request) {
 func (s *Service) getEmailIdentity(w http.ResponseWriter, _ *http.Request, identity string) {
 	s.mu.RLock()
 	id, exists := s.identities[identity]
+	identityTags := s.tags.Get(identityArn(identity))
 	s.mu.RUnlock()
 
 	if !exists {
@@ -146,18 +172,74 @@ func (s *Service) getEmailIdentity(w http.ResponseWriter, _ *http.Request, ident
 		"IdentityType":             id.identityType,
 		"VerifiedForSendingStatus": id.verified,
 		"FeedbackForwardingStatus": true,
+		"Tags":                     tags.ToList(identityTags, "Key", "Value"),
 	})
 }
 
 func (s *Service) deleteEmailIdentity(w http.ResponseWriter, _ *http.Request, identity string) {
 	s.mu.Lock()
 	delete(s.identities, identity)
+	s.tags.Delete(identityArn(identity))
 	s.mu.Unlock()
 
 	w.WriteHeader(http.StatusOK)
 	w.Write([]byte("{}"))
 }
 
+// Tags. Email identities are the taggable resources.
+
+func (s *Service) hasIdentity(arn string) bool {
+	for name := range s.identities {
+		if identityArn(name) == arn {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Service) tagResource(w http.ResponseWriter, r *http.Request) {
+	bodyBytes, _ := io.ReadAll(r.Body)
+	var params map[string]interface{}
+	json.Unmarshal(bodyBytes, &params)
+	arn := h.GetString(params, "ResourceArn")
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if !s.hasIdentity(arn) {
+		h.WriteJSONError(w, "NotFoundException", "Resource "+arn+" does not exist.", http.StatusNotFound)
+		return
+	}
+	s.tags.Tag(arn, tags.FromList(params["Tags"], "Key", "Value"))
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{})
+}
+
+func (s *Service) untagResource(w http.ResponseWriter, r *http.Request) {
+	arn := r.URL.Query().Get("ResourceArn")
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if !s.hasIdentity(arn) {
+		h.WriteJSONError(w, "NotFoundException", "Resource "+arn+" does not exist.", http.StatusNotFound)
+		return
+	}
+	s.tags.Untag(arn, r.URL.Query()["TagKeys"])
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{})
+}
+
+func (s *Service) listTagsForResource(w http.ResponseWriter, r *http.Request) {
+	arn := r.URL.Query().Get("ResourceArn")
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if !s.hasIdentity(arn) {
+		h.WriteJSONError(w, "NotFoundException", "Resource "+arn+" does not exist.", http.StatusNotFound)
+		return
+	}
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"Tags": tags.ToList(s.tags.Get(arn), "Key", "Value"),
+	})
+}
+
 func (s *Service) listEmailIdentities(w http.ResponseWriter, _ *http.Request) {
 	s.mu.RLock()
 	var identities []map[string]interface{}
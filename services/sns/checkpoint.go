@@ -0,0 +1,50 @@
+package sns
+
+// Checkpoint records the current topics and subscriptions so that Reset
+// restores them rather than clearing everything.
+func (s *Service) Checkpoint() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.checkpoint = &snapshot{
+		topics:        cloneTopics(s.topics),
+		subscriptions: cloneSubscriptions(s.subscriptions),
+	}
+}
+
+// ClearCheckpoint discards the checkpoint, so Reset clears everything again.
+func (s *Service) ClearCheckpoint() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.checkpoint = nil
+}
+
+// snapshot is the state recorded by Checkpoint.
+type snapshot struct {
+	topics        map[string]*topic
+	subscriptions map[string]*subscription
+}
+
+func cloneTopics(topics map[string]*topic) map[string]*topic {
+	c := make(map[string]*topic, len(topics))
+	for arn, t := range topics {
+		c[arn] = &topic{
+			arn:       t.arn,
+			name:      t.name,
+			published: append([]Publication(nil), t.published...),
+		}
+	}
+	return c
+}
+
+func cloneSubscriptions(subs map[string]*subscription) map[string]*subscription {
+	c := make(map[string]*subscription, len(subs))
+	for arn, sub := range subs {
+		cp := *sub
+		cp.attributes = make(map[string]string, len(sub.attributes))
+		for k, v := range sub.attributes {
+			cp.attributes[k] = v
+		}
+		c[arn] = &cp
+	}
+	return c
+}
@@ -0,0 +1,229 @@
+package sns
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/riyanimam/goto/internal/arn"
+	"github.com/riyanimam/goto/internal/mockhelpers"
+)
+
+// Dependencies reports that SNS needs the SQS mock, which its sqs
+// subscriptions deliver to.
+func (s *Service) Dependencies() []string { return []string{"sqs"} }
+
+// SetDispatcher sets the function used to deliver messages to subscribed
+// queues and functions.
+func (s *Service) SetDispatcher(d mockhelpers.Dispatcher) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.dispatch = d
+}
+
+// SetResolver sets the function used to check that the queues, functions,
+// and delivery streams named by subscription endpoints exist.
+func (s *Service) SetResolver(r mockhelpers.Resolver) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.resolve = r
+}
+
+// endpointServices maps subscription protocols whose endpoints are ARNs to
+// the service that owns them.
+var endpointServices = map[string]string{
+	"sqs":      "sqs",
+	"lambda":   "lambda",
+	"firehose": "firehose",
+}
+
+// checkEndpoint writes an error and returns false if protocol takes an ARN
+// endpoint and endpoint is not an ARN of that service's resources or names a
+// resource that does not exist.
+func (s *Service) checkEndpoint(w http.ResponseWriter, protocol, endpoint string) bool {
+	service, ok := endpointServices[protocol]
+	if !ok {
+		return true
+	}
+	a, err := arn.Parse(endpoint)
+	if err != nil || a.Service != service {
+		writeSNSError(w, "InvalidParameter", "Invalid parameter: "+strings.ToUpper(protocol)+" endpoint ARN", http.StatusBadRequest)
+		return false
+	}
+
+	s.mu.RLock()
+	resolve := s.resolve
+	s.mu.RUnlock()
+	if resolve != nil && resolve(endpoint) != nil {
+		writeSNSError(w, "NotFound", "Endpoint "+endpoint+" does not exist", http.StatusNotFound)
+		return false
+	}
+	return true
+}
+
+// subscriptionAttributes collects the Attributes.entry.N.key/value form
+// fields sent with Subscribe.
+func subscriptionAttributes(r *http.Request) map[string]string {
+	attrs := make(map[string]string)
+	for i := 1; ; i++ {
+		prefix := fmt.Sprintf("Attributes.entry.%d.", i)
+		key := r.FormValue(prefix + "key")
+		if key == "" {
+			break
+		}
+		attrs[key] = r.FormValue(prefix + "value")
+	}
+	return attrs
+}
+
+func (s *Service) setSubscriptionAttributes(w http.ResponseWriter, r *http.Request) {
+	subArn := r.FormValue("SubscriptionArn")
+	name := r.FormValue("AttributeName")
+
+	s.mu.Lock()
+	sub, exists := s.subscriptions[subArn]
+	if !exists {
+		s.mu.Unlock()
+		writeSNSError(w, "NotFound", "Subscription does not exist", http.StatusNotFound)
+		return
+	}
+	sub.attributes[name] = r.FormValue("AttributeValue")
+	s.mu.Unlock()
+
+	writeXML(w, http.StatusOK, setSubscriptionAttributesResponse{
+		RequestID: newRequestID(),
+	})
+}
+
+func (s *Service) getSubscriptionAttributes(w http.ResponseWriter, r *http.Request) {
+	subArn := r.FormValue("SubscriptionArn")
+
+	s.mu.RLock()
+	sub, exists := s.subscriptions[subArn]
+	if !exists {
+		s.mu.RUnlock()
+		writeSNSError(w, "NotFound", "Subscription does not exist", http.StatusNotFound)
+		return
+	}
+	attrs := map[string]string{
+		"SubscriptionArn":     sub.arn,
+		"TopicArn":            sub.topicArn,
+		"Protocol":            sub.protocol,
+		"Endpoint":            sub.endpoint,
+		"Owner":               defaultAccountID,
+		"RawMessageDelivery":  "false",
+		"PendingConfirmation": "false",
+	}
+	for k, v := range sub.attributes {
+		attrs[k] = v
+	}
+	s.mu.RUnlock()
+
+	entries := make([]attributeEntry, 0, len(attrs))
+	for k, v := range attrs {
+		entries = append(entries, attributeEntry{Key: k, Value: v})
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Key < entries[j].Key })
+
+	writeXML(w, http.StatusOK, getSubscriptionAttributesResponse{
+		Result:    getSubscriptionAttributesResult{Attributes: entries},
+		RequestID: newRequestID(),
+	})
+}
+
+// fanOut delivers pub to the topic's SQS, Lambda, and Firehose subscriptions.
+// Firehose records are the notification, or the message itself with raw
+// message delivery, followed by a newline, so archived messages land in S3 as
+// JSON lines. Delivery failures are dropped, as SNS drops messages it cannot
+// deliver once retries are exhausted.
+func (s *Service) fanOut(pub Publication, subs []subscription) {
+	s.mu.RLock()
+	dispatch := s.dispatch
+	s.mu.RUnlock()
+
+	if dispatch == nil {
+		return
+	}
+	for _, sub := range subs {
+		var payload []byte
+		switch sub.protocol {
+		case "sqs":
+			if sub.attributes["RawMessageDelivery"] == "true" {
+				payload = []byte(pub.Message)
+			} else {
+				payload, _ = json.Marshal(envelope(pub, sub))
+			}
+		case "firehose":
+			if sub.attributes["RawMessageDelivery"] == "true" {
+				payload = []byte(pub.Message + "\n")
+			} else {
+				notification, _ := json.Marshal(envelope(pub, sub))
+				payload = append(notification, '\n')
+			}
+		case "lambda":
+			payload, _ = json.Marshal(map[string]interface{}{
+				"Records": []map[string]interface{}{{
+					"EventSource":          "aws:sns",
+					"EventVersion":         "1.0",
+					"EventSubscriptionArn": sub.arn,
+					"Sns":                  envelope(pub, sub),
+				}},
+			})
+		default:
+			continue
+		}
+		dispatch(sub.endpoint, payload)
+	}
+}
+
+// envelope is the JSON notification SNS wraps messages in.
+func envelope(pub Publication, sub subscription) map[string]interface{} {
+	env := map[string]interface{}{
+		"Type":             "Notification",
+		"MessageId":        pub.MessageID,
+		"TopicArn":         pub.TopicArn,
+		"Message":          pub.Message,
+		"Timestamp":        pub.Timestamp.Format(time.RFC3339Nano),
+		"SignatureVersion": "1",
+		"Signature":        "EXAMPLE",
+		"SigningCertURL":   "https://sns.us-east-1.amazonaws.com/SimpleNotificationService.pem",
+		"UnsubscribeURL":   "https://sns.us-east-1.amazonaws.com/?Action=Unsubscribe&SubscriptionArn=" + sub.arn,
+	}
+	if pub.Subject != "" {
+		env["Subject"] = pub.Subject
+	}
+	if len(pub.MessageAttributes) > 0 {
+		attrs := make(map[string]interface{}, len(pub.MessageAttributes))
+		for k, v := range pub.MessageAttributes {
+			attrs[k] = map[string]string{"Type": "String", "Value": v}
+		}
+		env["MessageAttributes"] = attrs
+	}
+	return env
+}
+
+type setSubscriptionAttributesResponse struct {
+	XMLName   xml.Name `xml:"SetSubscriptionAttributesResponse"`
+	XMLNS     string   `xml:"xmlns,attr"`
+	RequestID string   `xml:"ResponseMetadata>RequestId"`
+}
+
+type getSubscriptionAttributesResponse struct {
+	XMLName   xml.Name                        `xml:"GetSubscriptionAttributesResponse"`
+	XMLNS     string                          `xml:"xmlns,attr"`
+	Result    getSubscriptionAttributesResult `xml:"GetSubscriptionAttributesResult"`
+	RequestID string                          `xml:"ResponseMetadata>RequestId"`
+}
+
+type getSubscriptionAttributesResult struct {
+	Attributes []attributeEntry `xml:"Attributes>entry"`
+}
+
+type attributeEntry struct {
+	Key   string `xml:"key"`
+	Value string `xml:"value"`
+}
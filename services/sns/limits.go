@@ -0,0 +1,198 @@
+package sns
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// maxMessageSize is the largest message, plus message attributes, SNS
+	// accepts, and the largest total size of a batch.
+	maxMessageSize = 262144
+
+	maxMessageAttributes = 10
+	maxBatchEntries      = 10
+)
+
+var batchEntryID = regexp.MustCompile(`^[A-Za-z0-9_\-]{1,80}$`)
+
+// invalidMessage reports why a message cannot be published, with the error
+// code SNS returns for it.
+type invalidMessage struct {
+	code    string
+	message string
+}
+
+func invalidAttribute(format string, args ...interface{}) *invalidMessage {
+	return &invalidMessage{code: "ParameterValueInvalid", message: fmt.Sprintf(format, args...)}
+}
+
+// validateMessage checks the message and MessageAttributes.entry.N.* fields
+// under prefix in form as SNS does, and returns their size as counted
+// against the message size limit.
+func validateMessage(form url.Values, prefix string) (int, *invalidMessage) {
+	message := form.Get(prefix + "Message")
+	if message == "" {
+		return 0, &invalidMessage{code: "InvalidParameter", message: "Invalid parameter: Empty message"}
+	}
+	size := len(message)
+	count := 0
+	for i := 1; ; i++ {
+		entry := fmt.Sprintf("%sMessageAttributes.entry.%d.", prefix, i)
+		name := form.Get(entry + "Name")
+		if name == "" {
+			break
+		}
+		count++
+		dataType := form.Get(entry + "Value.DataType")
+		value := form.Get(entry + "Value.StringValue")
+		switch base, _, _ := strings.Cut(dataType, "."); base {
+		case "String":
+			if value == "" {
+				return 0, invalidAttribute("The message attribute '%s' must contain non-empty message attribute value for message attribute type '%s'.", name, dataType)
+			}
+			if dataType == "String.Array" {
+				var list []interface{}
+				if json.Unmarshal([]byte(value), &list) != nil {
+					return 0, invalidAttribute("The message attribute '%s' with type 'String.Array' must contain a valid JSON array.", name)
+				}
+			}
+		case "Number":
+			if _, err := strconv.ParseFloat(value, 64); err != nil {
+				return 0, invalidAttribute("Could not cast message attribute '%s' value to number.", name)
+			}
+		case "Binary":
+			b, err := base64.StdEncoding.DecodeString(form.Get(entry + "Value.BinaryValue"))
+			if err != nil || len(b) == 0 {
+				return 0, invalidAttribute("The message attribute '%s' must contain non-empty message attribute value for message attribute type '%s'.", name, dataType)
+			}
+			value = string(b)
+		default:
+			return 0, invalidAttribute("The message attribute '%s' has an invalid message attribute type, the set of supported type prefixes is Binary, Number, and String.", name)
+		}
+		size += len(name) + len(dataType) + len(value)
+	}
+	if count > maxMessageAttributes {
+		return 0, invalidAttribute("Number of message attributes [%d] exceeds the allowed maximum [%d].", count, maxMessageAttributes)
+	}
+	if size > maxMessageSize {
+		return 0, &invalidMessage{code: "InvalidParameter", message: "Invalid parameter: Message too long"}
+	}
+	return size, nil
+}
+
+func (s *Service) publishBatch(w http.ResponseWriter, r *http.Request) {
+	topicArn := r.FormValue("TopicArn")
+	s.mu.RLock()
+	_, exists := s.topics[topicArn]
+	s.mu.RUnlock()
+	if !exists {
+		writeSNSError(w, "NotFound", "Topic does not exist", http.StatusNotFound)
+		return
+	}
+
+	var ids []string
+	for i := 1; ; i++ {
+		prefix := fmt.Sprintf("PublishBatchRequestEntries.member.%d.", i)
+		if _, ok := r.Form[prefix+"Id"]; !ok {
+			break
+		}
+		ids = append(ids, r.FormValue(prefix+"Id"))
+	}
+	switch {
+	case len(ids) == 0:
+		writeSNSError(w, "EmptyBatchRequest", "The batch request doesn't contain any entries.", http.StatusBadRequest)
+		return
+	case len(ids) > maxBatchEntries:
+		writeSNSError(w, "TooManyEntriesInBatchRequest",
+			fmt.Sprintf("The batch request contains more entries than permissible. Maximum number of entries per request is %d.", maxBatchEntries), http.StatusBadRequest)
+		return
+	}
+
+	seen := make(map[string]bool, len(ids))
+	invalid := make([]*invalidMessage, len(ids))
+	total := 0
+	for i, id := range ids {
+		if !batchEntryID.MatchString(id) {
+			writeSNSError(w, "InvalidBatchEntryId",
+				"The Id of a batch entry in a batch request doesn't abide by the specification.", http.StatusBadRequest)
+			return
+		}
+		if seen[id] {
+			writeSNSError(w, "BatchEntryIdsNotDistinct", "Two or more batch entries in the request have the same Id.", http.StatusBadRequest)
+			return
+		}
+		seen[id] = true
+		size, bad := validateMessage(r.Form, fmt.Sprintf("PublishBatchRequestEntries.member.%d.", i+1))
+		invalid[i] = bad
+		total += size
+	}
+	if total > maxMessageSize {
+		writeSNSError(w, "BatchRequestTooLong",
+			fmt.Sprintf("The length of all the messages put together is more than the limit of %d bytes.", maxMessageSize), http.StatusBadRequest)
+		return
+	}
+
+	var result publishBatchResult
+	for i, id := range ids {
+		if invalid[i] != nil {
+			result.Failed = append(result.Failed, batchResultError{
+				ID:          id,
+				Code:        invalid[i].code,
+				Message:     invalid[i].message,
+				SenderFault: true,
+			})
+			continue
+		}
+		prefix := fmt.Sprintf("PublishBatchRequestEntries.member.%d.", i+1)
+		pub := Publication{
+			MessageID:         newRequestID(),
+			TopicArn:          topicArn,
+			Subject:           r.FormValue(prefix + "Subject"),
+			Message:           r.FormValue(prefix + "Message"),
+			MessageAttributes: messageAttributes(r.Form, prefix),
+			Timestamp:         time.Now().UTC(),
+		}
+		if subs, ok := s.record(pub); ok {
+			s.fanOut(pub, subs)
+		}
+		result.Successful = append(result.Successful, publishBatchResultEntry{ID: id, MessageID: pub.MessageID})
+	}
+
+	writeXML(w, http.StatusOK, publishBatchResponse{
+		Result:    result,
+		RequestID: newRequestID(),
+	})
+}
+
+type publishBatchResponse struct {
+	XMLName   xml.Name           `xml:"PublishBatchResponse"`
+	XMLNS     string             `xml:"xmlns,attr"`
+	Result    publishBatchResult `xml:"PublishBatchResult"`
+	RequestID string             `xml:"ResponseMetadata>RequestId"`
+}
+
+type publishBatchResult struct {
+	Successful []publishBatchResultEntry `xml:"Successful>member"`
+	Failed     []batchResultError        `xml:"Failed>member"`
+}
+
+type publishBatchResultEntry struct {
+	ID        string `xml:"Id"`
+	MessageID string `xml:"MessageId"`
+}
+
+type batchResultError struct {
+	ID          string `xml:"Id"`
+	Code        string `xml:"Code"`
+	Message     string `xml:"Message"`
+	SenderFault bool   `xml:"SenderFault"`
+}
@@ -8,6 +8,19 @@
 //   - Unsubscribe
 //   - ListSubscriptions
 //   - Publish
+//   - PublishBatch
+//   - SetSubscriptionAttributes
+//   - GetSubscriptionAttributes
+//   - TagResource
+//   - UntagResource
+//   - ListTagsForResource
+//
+// Published messages are delivered to the topic's SQS, Lambda, and Firehose
+// subscriptions, wrapped in the SNS notification envelope unless the
+// subscription has RawMessageDelivery set. Messages are held to the limits
+// SNS enforces: 256 KiB including message attributes, at most ten attributes
+// of the String, String.Array, Number and Binary types, and batches of at
+// most ten entries totalling no more than 256 KiB.
 package sns
 
 import (
@@ -15,8 +28,15 @@ import (
 	"fmt"
 	"math/rand"
 	"net/http"
+	"net/url"
 	"sort"
 	"sync"
+	"time"
+
+	"github.com/riyanimam/goto/internal/awserr"
+	"github.com/riyanimam/goto/internal/mockhelpers"
+	"github.com/riyanimam/goto/internal/paginate"
+	"github.com/riyanimam/goto/internal/tags"
 )
 
 const defaultAccountID = "123456789012"
@@ -26,18 +46,34 @@ type Service struct {
 	mu            sync.RWMutex
 	topics        map[string]*topic        // keyed by ARN
 	subscriptions map[string]*subscription // keyed by subscription ARN
+	dispatch      mockhelpers.Dispatcher
+	resolve       mockhelpers.Resolver
+	checkpoint    *snapshot
+	tags          *tags.Store
 }
 
 type topic struct {
-	arn  string
-	name string
+	arn       string
+	name      string
+	published []Publication
+}
+
+// Publication is a message published to a topic.
+type Publication struct {
+	MessageID         string
+	TopicArn          string
+	Subject           string
+	Message           string
+	MessageAttributes map[string]string
+	Timestamp         time.Time
 }
 
 type subscription struct {
-	arn      string
-	topicArn string
-	protocol string
-	endpoint string
+	arn        string
+	topicArn   string
+	protocol   string
+	endpoint   string
+	attributes map[string]string
 }
 
 // New creates a new SNS mock service.
@@ -45,6 +81,7 @@ func New() *Service {
 	return &Service{
 		topics:        make(map[string]*topic),
 		subscriptions: make(map[string]*subscription),
+		tags:          tags.New(),
 	}
 }
 
@@ -53,42 +90,46 @@ func (s *Service) Name() string { return "sns" }
 
 // Handler returns the HTTP handler for SNS requests.
 func (s *Service) Handler() http.Handler {
-	return http.HandlerFunc(s.handle)
+	return mockhelpers.QueryRouter{
+		Actions: map[string]http.HandlerFunc{
+			"CreateTopic":               s.createTopic,
+			"DeleteTopic":               s.deleteTopic,
+			"ListTopics":                s.listTopics,
+			"Subscribe":                 s.subscribe,
+			"Unsubscribe":               s.unsubscribe,
+			"ListSubscriptions":         s.listSubscriptions,
+			"Publish":                   s.publish,
+			"PublishBatch":              s.publishBatch,
+			"SetSubscriptionAttributes": s.setSubscriptionAttributes,
+			"GetSubscriptionAttributes": s.getSubscriptionAttributes,
+			"TagResource":               s.tagResource,
+			"UntagResource":             s.untagResource,
+			"ListTagsForResource":       s.listTagsForResource,
+		},
+		Error: writeSNSError,
+	}
 }
 
-// Reset clears all topics and subscriptions.
+// Reset clears all topics and subscriptions. After [Service.Checkpoint],
+// it restores the checkpointed ones instead.
 func (s *Service) Reset() {
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	s.tags.DeleteService("sns")
+	if s.checkpoint != nil {
+		s.topics = cloneTopics(s.checkpoint.topics)
+		s.subscriptions = cloneSubscriptions(s.checkpoint.subscriptions)
+		return
+	}
 	s.topics = make(map[string]*topic)
 	s.subscriptions = make(map[string]*subscription)
 }
 
-func (s *Service) handle(w http.ResponseWriter, r *http.Request) {
-	if err := r.ParseForm(); err != nil {
-		writeSNSError(w, "InvalidParameterValue", "could not parse request", http.StatusBadRequest)
-		return
-	}
-
-	action := r.FormValue("Action")
-	switch action {
-	case "CreateTopic":
-		s.createTopic(w, r)
-	case "DeleteTopic":
-		s.deleteTopic(w, r)
-	case "ListTopics":
-		s.listTopics(w, r)
-	case "Subscribe":
-		s.subscribe(w, r)
-	case "Unsubscribe":
-		s.unsubscribe(w, r)
-	case "ListSubscriptions":
-		s.listSubscriptions(w, r)
-	case "Publish":
-		s.publish(w, r)
-	default:
-		writeSNSError(w, "InvalidAction", fmt.Sprintf("action %q is not supported", action), http.StatusBadRequest)
-	}
+// SetTagStore sets the registry topic tags are recorded in.
+func (s *Service) SetTagStore(store *tags.Store) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tags = store
 }
 
 func (s *Service) createTopic(w http.ResponseWriter, r *http.Request) {
@@ -116,6 +157,7 @@ func (s *Service) createTopic(w http.ResponseWriter, r *http.Request) {
 		arn:  arn,
 		name: name,
 	}
+	s.tags.Tag(arn, tags.FromForm(r.Form, "Tags.member."))
 	s.mu.Unlock()
 
 	resp := createTopicResponse{
@@ -130,6 +172,7 @@ func (s *Service) deleteTopic(w http.ResponseWriter, r *http.Request) {
 
 	s.mu.Lock()
 	delete(s.topics, arn)
+	s.tags.Delete(arn)
 	// Remove subscriptions for this topic.
 	for subArn, sub := range s.subscriptions {
 		if sub.topicArn == arn {
@@ -144,7 +187,7 @@ func (s *Service) deleteTopic(w http.ResponseWriter, r *http.Request) {
 	writeXML(w, http.StatusOK, resp)
 }
 
-func (s *Service) listTopics(w http.ResponseWriter, _ *http.Request) {
+func (s *Service) listTopics(w http.ResponseWriter, r *http.Request) {
 	s.mu.RLock()
 	var members []topicMember
 	for _, t := range s.topics {
@@ -156,8 +199,13 @@ func (s *Service) listTopics(w http.ResponseWriter, _ *http.Request) {
 		return members[i].TopicArn < members[j].TopicArn
 	})
 
+	page, next, err := paginate.Page(members, func(i int) string { return members[i].TopicArn }, r.FormValue("NextToken"), 100, 100)
+	if err != nil {
+		writeSNSError(w, "InvalidParameter", "Invalid parameter: NextToken", http.StatusBadRequest)
+		return
+	}
 	resp := listTopicsResponse{
-		Result:    listTopicsResult{Topics: members},
+		Result:    listTopicsResult{Topics: page, NextToken: next},
 		RequestID: newRequestID(),
 	}
 	writeXML(w, http.StatusOK, resp)
@@ -167,6 +215,9 @@ func (s *Service) subscribe(w http.ResponseWriter, r *http.Request) {
 	topicArn := r.FormValue("TopicArn")
 	protocol := r.FormValue("Protocol")
 	endpoint := r.FormValue("Endpoint")
+	if !s.checkEndpoint(w, protocol, endpoint) {
+		return
+	}
 
 	s.mu.Lock()
 	if _, exists := s.topics[topicArn]; !exists {
@@ -177,10 +228,11 @@ func (s *Service) subscribe(w http.ResponseWriter, r *http.Request) {
 
 	subArn := fmt.Sprintf("%s:%s", topicArn, newRequestID())
 	sub := &subscription{
-		arn:      subArn,
-		topicArn: topicArn,
-		protocol: protocol,
-		endpoint: endpoint,
+		arn:        subArn,
+		topicArn:   topicArn,
+		protocol:   protocol,
+		endpoint:   endpoint,
+		attributes: subscriptionAttributes(r),
 	}
 	s.subscriptions[subArn] = sub
 	s.mu.Unlock()
@@ -205,7 +257,7 @@ func (s *Service) unsubscribe(w http.ResponseWriter, r *http.Request) {
 	writeXML(w, http.StatusOK, resp)
 }
 
-func (s *Service) listSubscriptions(w http.ResponseWriter, _ *http.Request) {
+func (s *Service) listSubscriptions(w http.ResponseWriter, r *http.Request) {
 	s.mu.RLock()
 	var members []subscriptionMember
 	for _, sub := range s.subscriptions {
@@ -223,8 +275,13 @@ func (s *Service) listSubscriptions(w http.ResponseWriter, _ *http.Request) {
 		return members[i].SubscriptionArn < members[j].SubscriptionArn
 	})
 
+	page, next, err := paginate.Page(members, func(i int) string { return members[i].SubscriptionArn }, r.FormValue("NextToken"), 100, 100)
+	if err != nil {
+		writeSNSError(w, "InvalidParameter", "Invalid parameter: NextToken", http.StatusBadRequest)
+		return
+	}
 	resp := listSubscriptionsResponse{
-		Result:    listSubscriptionsResult{Subscriptions: members},
+		Result:    listSubscriptionsResult{Subscriptions: page, NextToken: next},
 		RequestID: newRequestID(),
 	}
 	writeXML(w, http.StatusOK, resp)
@@ -232,25 +289,162 @@ func (s *Service) listSubscriptions(w http.ResponseWriter, _ *http.Request) {
 
 func (s *Service) publish(w http.ResponseWriter, r *http.Request) {
 	topicArn := r.FormValue("TopicArn")
-	_ = r.FormValue("Message") // Accept the message but we don't need to store it.
-
-	s.mu.RLock()
-	_, exists := s.topics[topicArn]
-	s.mu.RUnlock()
+	if _, invalid := validateMessage(r.Form, ""); invalid != nil {
+		writeSNSError(w, invalid.code, invalid.message, http.StatusBadRequest)
+		return
+	}
+	pub := Publication{
+		MessageID:         newRequestID(),
+		TopicArn:          topicArn,
+		Subject:           r.FormValue("Subject"),
+		Message:           r.FormValue("Message"),
+		MessageAttributes: messageAttributes(r.Form, ""),
+		Timestamp:         time.Now().UTC(),
+	}
 
-	if !exists {
+	subs, ok := s.record(pub)
+	if !ok {
 		writeSNSError(w, "NotFound", "Topic does not exist", http.StatusNotFound)
 		return
 	}
+	s.fanOut(pub, subs)
 
-	msgID := newRequestID()
 	resp := publishResponse{
-		Result:    publishResult{MessageId: msgID},
+		Result:    publishResult{MessageId: pub.MessageID},
 		RequestID: newRequestID(),
 	}
 	writeXML(w, http.StatusOK, resp)
 }
 
+// HasResource reports whether the topic or subscription identified by arn
+// exists.
+func (s *Service) HasResource(arn string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	_, topic := s.topics[arn]
+	_, sub := s.subscriptions[arn]
+	return topic || sub
+}
+
+// Deliver publishes payload to the topic identified by arn.
+func (s *Service) Deliver(arn string, payload []byte) ([]byte, error) {
+	pub := Publication{
+		MessageID: newRequestID(),
+		TopicArn:  arn,
+		Message:   string(payload),
+		Timestamp: time.Now().UTC(),
+	}
+	subs, ok := s.record(pub)
+	if !ok {
+		return nil, fmt.Errorf("topic %s does not exist", arn)
+	}
+	s.fanOut(pub, subs)
+	return []byte(pub.MessageID), nil
+}
+
+// record appends pub to its topic's history and returns copies of the
+// topic's subscriptions, reporting false if the topic does not exist.
+func (s *Service) record(pub Publication) ([]subscription, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t, exists := s.topics[pub.TopicArn]
+	if !exists {
+		return nil, false
+	}
+	t.published = append(t.published, pub)
+
+	var subs []subscription
+	for _, sub := range s.subscriptions {
+		if sub.topicArn == pub.TopicArn {
+			c := *sub
+			c.attributes = make(map[string]string, len(sub.attributes))
+			for k, v := range sub.attributes {
+				c.attributes[k] = v
+			}
+			subs = append(subs, c)
+		}
+	}
+	return subs, true
+}
+
+// Published returns the messages published to the topic, oldest first.
+func (s *Service) Published(topicArn string) ([]Publication, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	t, exists := s.topics[topicArn]
+	if !exists {
+		return nil, fmt.Errorf("topic %s does not exist", topicArn)
+	}
+	return append([]Publication(nil), t.published...), nil
+}
+
+func (s *Service) tagResource(w http.ResponseWriter, r *http.Request) {
+	arn := r.FormValue("ResourceArn")
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.topics[arn]; !ok {
+		writeSNSError(w, "ResourceNotFound", "Resource does not exist", http.StatusNotFound)
+		return
+	}
+	s.tags.Tag(arn, tags.FromForm(r.Form, "Tags.member."))
+	writeXML(w, http.StatusOK, tagResourceResponse{RequestID: newRequestID()})
+}
+
+func (s *Service) untagResource(w http.ResponseWriter, r *http.Request) {
+	arn := r.FormValue("ResourceArn")
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.topics[arn]; !ok {
+		writeSNSError(w, "ResourceNotFound", "Resource does not exist", http.StatusNotFound)
+		return
+	}
+	s.tags.Untag(arn, tags.KeysFromForm(r.Form, "TagKeys.member."))
+	writeXML(w, http.StatusOK, untagResourceResponse{RequestID: newRequestID()})
+}
+
+func (s *Service) listTagsForResource(w http.ResponseWriter, r *http.Request) {
+	arn := r.FormValue("ResourceArn")
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if _, ok := s.topics[arn]; !ok {
+		writeSNSError(w, "ResourceNotFound", "Resource does not exist", http.StatusNotFound)
+		return
+	}
+	current := s.tags.Get(arn)
+	keys := make([]string, 0, len(current))
+	for k := range current {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var members []tagMember
+	for _, k := range keys {
+		members = append(members, tagMember{Key: k, Value: current[k]})
+	}
+	writeXML(w, http.StatusOK, listTagsForResourceResponse{
+		Result:    listTagsForResourceResult{Tags: members},
+		RequestID: newRequestID(),
+	})
+}
+
+// messageAttributes collects the string values of the
+// MessageAttributes.entry.N.* form fields under prefix.
+func messageAttributes(form url.Values, prefix string) map[string]string {
+	attrs := make(map[string]string)
+	for i := 1; ; i++ {
+		entry := fmt.Sprintf("%sMessageAttributes.entry.%d.", prefix, i)
+		name := form.Get(entry + "Name")
+		if name == "" {
+			break
+		}
+		value := form.Get(entry + "Value.StringValue")
+		if value == "" {
+			value = form.Get(entry + "Value.BinaryValue")
+		}
+		attrs[name] = value
+	}
+	return attrs
+}
+
 // XML response types.
 
 type createTopicResponse struct {
@@ -278,7 +472,8 @@ type listTopicsResponse struct {
 }
 
 type listTopicsResult struct {
-	Topics []topicMember `xml:"Topics>member"`
+	Topics    []topicMember `xml:"Topics>member"`
+	NextToken string        `xml:"NextToken,omitempty"`
 }
 
 type topicMember struct {
@@ -311,6 +506,7 @@ type listSubscriptionsResponse struct {
 
 type listSubscriptionsResult struct {
 	Subscriptions []subscriptionMember `xml:"Subscriptions>member"`
+	NextToken     string               `xml:"NextToken,omitempty"`
 }
 
 type subscriptionMember struct {
@@ -332,16 +528,34 @@ type publishResult struct {
 	MessageId string `xml:"MessageId"`
 }
 
-type snsErrorResponse struct {
-	XMLName   xml.Name `xml:"ErrorResponse"`
-	Error     snsError `xml:"Error"`
-	RequestID string   `xml:"RequestId"`
+type tagResourceResponse struct {
+	XMLName   xml.Name `xml:"TagResourceResponse"`
+	XMLNS     string   `xml:"xmlns,attr"`
+	Result    struct{} `xml:"TagResourceResult"`
+	RequestID string   `xml:"ResponseMetadata>RequestId"`
 }
 
-type snsError struct {
-	Type    string `xml:"Type"`
-	Code    string `xml:"Code"`
-	Message string `xml:"Message"`
+type untagResourceResponse struct {
+	XMLName   xml.Name `xml:"UntagResourceResponse"`
+	XMLNS     string   `xml:"xmlns,attr"`
+	Result    struct{} `xml:"UntagResourceResult"`
+	RequestID string   `xml:"ResponseMetadata>RequestId"`
+}
+
+type listTagsForResourceResponse struct {
+	XMLName   xml.Name                  `xml:"ListTagsForResourceResponse"`
+	XMLNS     string                    `xml:"xmlns,attr"`
+	Result    listTagsForResourceResult `xml:"ListTagsForResourceResult"`
+	RequestID string                    `xml:"ResponseMetadata>RequestId"`
+}
+
+type listTagsForResourceResult struct {
+	Tags []tagMember `xml:"Tags>member"`
+}
+
+type tagMember struct {
+	Key   string `xml:"Key"`
+	Value string `xml:"Value"`
 }
 
 // Helper functions.
@@ -354,15 +568,7 @@ func writeXML(w http.ResponseWriter, status int, v interface{}) {
 }
 
 func writeSNSError(w http.ResponseWriter, code, message string, status int) {
-	resp := snsErrorResponse{
-		Error: snsError{
-			Type:    "Sender",
-			Code:    code,
-			Message: message,
-		},
-		RequestID: newRequestID(),
-	}
-	writeXML(w, status, resp)
+	awserr.WriteQuery(w, "", code, message, status)
 }
 
 func newRequestID() string {
@@ -0,0 +1,33 @@
+package sns
+
+import "github.com/riyanimam/goto/internal/mockhelpers"
+
+// ExportState lists the topics and their subscriptions.
+func (s *Service) ExportState() []mockhelpers.Resource {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	resources := make([]mockhelpers.Resource, 0, len(s.topics)+len(s.subscriptions))
+	for _, t := range s.topics {
+		resources = append(resources, mockhelpers.Resource{
+			Type:       "aws_sns_topic",
+			ID:         t.arn,
+			ARN:        t.arn,
+			Attributes: map[string]interface{}{"name": t.name},
+		})
+	}
+	for _, sub := range s.subscriptions {
+		resources = append(resources, mockhelpers.Resource{
+			Type: "aws_sns_topic_subscription",
+			ID:   sub.arn,
+			ARN:  sub.arn,
+			Attributes: map[string]interface{}{
+				"topic_arn":            sub.topicArn,
+				"protocol":             sub.protocol,
+				"endpoint":             sub.endpoint,
+				"raw_message_delivery": sub.attributes["RawMessageDelivery"] == "true",
+			},
+		})
+	}
+	return resources
+}
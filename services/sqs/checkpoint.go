@@ -0,0 +1,53 @@
+package sqs
+
+// Checkpoint records the current queues and their messages so that Reset
+// restores them rather than clearing everything.
+func (s *Service) Checkpoint() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	saved := make(map[string]*queue, len(s.queues))
+	for url, q := range s.queues {
+		q.mu.Lock()
+		saved[url] = q.clone()
+		q.mu.Unlock()
+	}
+	s.checkpoint = saved
+}
+
+// ClearCheckpoint discards the checkpoint, so Reset clears everything again.
+func (s *Service) ClearCheckpoint() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.checkpoint = nil
+}
+
+// restoreCheckpoint returns copies of the checkpointed queues, or no queues
+// if there is no checkpoint. Caller must hold s.mu.
+func (s *Service) restoreCheckpoint() map[string]*queue {
+	queues := make(map[string]*queue, len(s.checkpoint))
+	for url, q := range s.checkpoint {
+		queues[url] = q.clone()
+	}
+	return queues
+}
+
+// clone returns a deep copy of the queue. Caller must hold q.mu, or own q.
+func (q *queue) clone() *queue {
+	c := &queue{
+		name:       q.name,
+		url:        q.url,
+		arn:        q.arn,
+		attributes: make(map[string]string, len(q.attributes)),
+		messages:   make([]*message, len(q.messages)),
+		created:    q.created,
+	}
+	for k, v := range q.attributes {
+		c.attributes[k] = v
+	}
+	for i, m := range q.messages {
+		msg := *m
+		c.messages[i] = &msg
+	}
+	return c
+}
@@ -0,0 +1,101 @@
+package sqs
+
+import (
+	"encoding/base64"
+	"fmt"
+)
+
+// queueByARN returns the queue identified by arn, or nil. The caller must
+// hold s.mu.
+func (s *Service) queueByARN(arn string) *queue {
+	for _, q := range s.queues {
+		if q.arn == arn {
+			return q
+		}
+	}
+	return nil
+}
+
+// ReadEvents receives up to max visible messages from the queue identified
+// by arn, shaped as SQS event records, and hides them for the queue's
+// visibility timeout or until they are acknowledged with
+// [Service.AckEvents]. The position is ignored.
+func (s *Service) ReadEvents(arn, _ string, max int) ([]map[string]interface{}, string, error) {
+	s.mu.RLock()
+	q := s.queueByARN(arn)
+	c, now := s.clock, s.now()
+	s.mu.RUnlock()
+	if q == nil {
+		return nil, "", fmt.Errorf("queue %s does not exist", arn)
+	}
+
+	s.redrive(q, c)
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	var events []map[string]interface{}
+	for _, msg := range q.messages {
+		if len(events) == max {
+			break
+		}
+		if !msg.visible(c) {
+			continue
+		}
+		msg.receive(c, now, q.seconds("VisibilityTimeout"))
+		attrs := make(map[string]interface{}, len(msg.attributes))
+		for name, attr := range msg.attributes {
+			value := map[string]interface{}{"dataType": attr.dataType}
+			if attr.binaryValue != nil {
+				value["binaryValue"] = base64.StdEncoding.EncodeToString(attr.binaryValue)
+			} else {
+				value["stringValue"] = attr.stringValue
+			}
+			attrs[name] = value
+		}
+		events = append(events, map[string]interface{}{
+			"messageId":         msg.id,
+			"receiptHandle":     msg.receiptHandle,
+			"body":              msg.body,
+			"attributes":        msg.systemAttributes(),
+			"messageAttributes": attrs,
+			"md5OfBody":         msg.md5,
+			"eventSource":       "aws:sqs",
+			"eventSourceARN":    q.arn,
+			"awsRegion":         "us-east-1",
+		})
+	}
+	return events, "", nil
+}
+
+// AckEvents deletes the messages of events read with [Service.ReadEvents]
+// if they were processed, and otherwise makes them visible again.
+func (s *Service) AckEvents(arn string, events []map[string]interface{}, processed bool) error {
+	s.mu.RLock()
+	q := s.queueByARN(arn)
+	s.mu.RUnlock()
+	if q == nil {
+		return fmt.Errorf("queue %s does not exist", arn)
+	}
+
+	handles := make(map[string]bool, len(events))
+	for _, e := range events {
+		if handle, ok := e["receiptHandle"].(string); ok {
+			handles[handle] = true
+		}
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	kept := q.messages[:0]
+	for _, msg := range q.messages {
+		switch {
+		case !handles[msg.receiptHandle]:
+		case processed:
+			continue
+		default:
+			msg.hiddenFor = 0
+		}
+		kept = append(kept, msg)
+	}
+	q.messages = kept
+	return nil
+}
@@ -0,0 +1,296 @@
+package sqs
+
+import (
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	// maxMessageSize is the largest message body, plus message attributes,
+	// SQS accepts, and the largest total size of a batch.
+	maxMessageSize = 262144
+
+	maxMessageAttributes = 10
+	maxBatchEntries      = 10
+)
+
+var (
+	attributeName = regexp.MustCompile(`^[A-Za-z0-9_\-.]{1,256}$`)
+	batchEntryID  = regexp.MustCompile(`^[A-Za-z0-9_\-]{1,80}$`)
+)
+
+// messageAttribute is a user-defined message attribute.
+type messageAttribute struct {
+	dataType    string
+	stringValue string
+	binaryValue []byte
+}
+
+// invalidMessage reports why a message cannot be sent, with the error code
+// SQS returns for it.
+type invalidMessage struct {
+	code    string
+	message string
+}
+
+func invalidParameter(format string, args ...interface{}) *invalidMessage {
+	return &invalidMessage{code: "InvalidParameterValue", message: fmt.Sprintf(format, args...)}
+}
+
+// parseMessageAttributes validates the MessageAttributes of a message as SQS
+// does: at most ten attributes, with valid names and String, Number or
+// Binary types, each carrying a value of its type.
+func parseMessageAttributes(v interface{}) (map[string]messageAttribute, *invalidMessage) {
+	raw, _ := v.(map[string]interface{})
+	if len(raw) > maxMessageAttributes {
+		return nil, invalidParameter("Number of message attributes [%d] exceeds the allowed maximum [%d].", len(raw), maxMessageAttributes)
+	}
+	attrs := make(map[string]messageAttribute, len(raw))
+	for name, value := range raw {
+		lower := strings.ToLower(name)
+		if !attributeName.MatchString(name) || strings.HasPrefix(lower, "aws.") || strings.HasPrefix(lower, "amazon.") ||
+			strings.HasPrefix(name, ".") || strings.HasSuffix(name, ".") || strings.Contains(name, "..") {
+			return nil, invalidParameter("Message (user) attribute name '%s' is invalid.", name)
+		}
+		fields, _ := value.(map[string]interface{})
+		attr := messageAttribute{
+			dataType:    getString(fields, "DataType"),
+			stringValue: getString(fields, "StringValue"),
+		}
+		switch base, _, _ := strings.Cut(attr.dataType, "."); base {
+		case "String":
+			if attr.stringValue == "" {
+				return nil, invalidParameter("Message (user) attribute '%s' must contain a non-empty value of message attribute type '%s'.", name, attr.dataType)
+			}
+		case "Number":
+			if _, err := strconv.ParseFloat(attr.stringValue, 64); err != nil {
+				return nil, invalidParameter("Can't cast the value of message (user) attribute '%s' to a number.", name)
+			}
+		case "Binary":
+			b, err := base64.StdEncoding.DecodeString(getString(fields, "BinaryValue"))
+			if err != nil || len(b) == 0 {
+				return nil, invalidParameter("Message (user) attribute '%s' must contain a non-empty value of message attribute type '%s'.", name, attr.dataType)
+			}
+			attr.binaryValue = b
+		default:
+			return nil, invalidParameter("The type of message (user) attribute '%s' is invalid. You must use only the following supported type prefixes: Binary, Number, String.", name)
+		}
+		attrs[name] = attr
+	}
+	return attrs, nil
+}
+
+// messageSize returns the size SQS counts against the message size limit:
+// the body plus each attribute's name, type and value.
+func messageSize(body string, attrs map[string]messageAttribute) int {
+	size := len(body)
+	for name, attr := range attrs {
+		size += len(name) + len(attr.dataType) + len(attr.stringValue) + len(attr.binaryValue)
+	}
+	return size
+}
+
+// validateMessage checks a message against the queue's MaximumMessageSize
+// and the characters SQS allows in message bodies. The caller must hold
+// q.mu.
+func validateMessage(q *queue, body string, attrs map[string]messageAttribute) *invalidMessage {
+	if body == "" {
+		return &invalidMessage{code: "MissingParameter", message: "The request must contain the parameter MessageBody."}
+	}
+	limit, err := strconv.Atoi(q.attributes["MaximumMessageSize"])
+	if err != nil || limit <= 0 {
+		limit = maxMessageSize
+	}
+	if messageSize(body, attrs) > limit {
+		return invalidParameter("One or more parameters are invalid. Reason: Message must be shorter than %d bytes.", limit)
+	}
+	for _, r := range body {
+		if !validMessageRune(r) {
+			return &invalidMessage{code: "InvalidMessageContents", message: "Invalid characters found. Valid unicode characters are #x9 | #xA | #xD | #x20 to #xD7FF | #xE000 to #xFFFD | #x10000 to #x10FFFF"}
+		}
+	}
+	return nil
+}
+
+// validMessageRune reports whether SQS allows r in a message body.
+func validMessageRune(r rune) bool {
+	switch {
+	case r == utf8.RuneError:
+		return false
+	case r == 0x9 || r == 0xA || r == 0xD:
+		return true
+	case r >= 0x20 && r <= 0xD7FF, r >= 0xE000 && r <= 0xFFFD, r >= 0x10000 && r <= 0x10FFFF:
+		return true
+	}
+	return false
+}
+
+// md5OfMessageAttributes returns the MD5 digest SQS reports for a message's
+// attributes, computed over the attributes sorted by name as the SDKs do to
+// verify it, or "" if there are none.
+func md5OfMessageAttributes(attrs map[string]messageAttribute) string {
+	if len(attrs) == 0 {
+		return ""
+	}
+	names := make([]string, 0, len(attrs))
+	for name := range attrs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	h := md5.New()
+	writeField := func(b []byte) {
+		var n [4]byte
+		binary.BigEndian.PutUint32(n[:], uint32(len(b)))
+		h.Write(n[:])
+		h.Write(b)
+	}
+	for _, name := range names {
+		attr := attrs[name]
+		writeField([]byte(name))
+		writeField([]byte(attr.dataType))
+		if attr.binaryValue != nil {
+			h.Write([]byte{2})
+			writeField(attr.binaryValue)
+		} else {
+			h.Write([]byte{1})
+			writeField([]byte(attr.stringValue))
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// selectAttributes returns the attributes whose names match the
+// MessageAttributeNames of a ReceiveMessage request: "All" or ".*" for every
+// attribute, a name, or a prefix followed by ".*".
+func selectAttributes(attrs map[string]messageAttribute, patterns []string) map[string]messageAttribute {
+	out := make(map[string]messageAttribute)
+	for name, attr := range attrs {
+		for _, p := range patterns {
+			prefix, wildcard := strings.CutSuffix(p, ".*")
+			if p == "All" || p == ".*" || p == name || (wildcard && strings.HasPrefix(name, prefix+".")) {
+				out[name] = attr
+				break
+			}
+		}
+	}
+	return out
+}
+
+// attributesToMap renders attrs as the MessageAttributes of a received
+// message.
+func attributesToMap(attrs map[string]messageAttribute) map[string]interface{} {
+	out := make(map[string]interface{}, len(attrs))
+	for name, attr := range attrs {
+		value := map[string]interface{}{"DataType": attr.dataType}
+		if attr.binaryValue != nil {
+			value["BinaryValue"] = base64.StdEncoding.EncodeToString(attr.binaryValue)
+		} else {
+			value["StringValue"] = attr.stringValue
+		}
+		out[name] = value
+	}
+	return out
+}
+
+func (s *Service) sendMessageBatch(w http.ResponseWriter, params map[string]interface{}) {
+	queueURL := getString(params, "QueueUrl")
+	entries, _ := params["Entries"].([]interface{})
+
+	s.mu.RLock()
+	q, exists := s.queues[queueURL]
+	c, now := s.clock, s.now()
+	s.mu.RUnlock()
+
+	if !exists {
+		writeJSONError(w, "AWS.SimpleQueueService.NonExistentQueue", "The specified queue does not exist.", http.StatusBadRequest)
+		return
+	}
+
+	switch {
+	case len(entries) == 0:
+		writeJSONError(w, "EmptyBatchRequest", "There should be at least one SendMessageBatchRequestEntry in the request.", http.StatusBadRequest)
+		return
+	case len(entries) > maxBatchEntries:
+		writeJSONError(w, "TooManyEntriesInBatchRequest",
+			fmt.Sprintf("Maximum number of entries per request are %d. You have sent %d.", maxBatchEntries, len(entries)), http.StatusBadRequest)
+		return
+	}
+
+	seen := make(map[string]bool, len(entries))
+	total := 0
+	attrs := make([]map[string]messageAttribute, len(entries))
+	invalid := make([]*invalidMessage, len(entries))
+	for i, e := range entries {
+		entry, _ := e.(map[string]interface{})
+		id := getString(entry, "Id")
+		if !batchEntryID.MatchString(id) {
+			writeJSONError(w, "InvalidBatchEntryId",
+				"A batch entry id can only contain alphanumeric characters, hyphens and underscores. It can be at most 80 letters long.", http.StatusBadRequest)
+			return
+		}
+		if seen[id] {
+			writeJSONError(w, "BatchEntryIdsNotDistinct", fmt.Sprintf("Id %s repeated.", id), http.StatusBadRequest)
+			return
+		}
+		seen[id] = true
+		attrs[i], invalid[i] = parseMessageAttributes(entry["MessageAttributes"])
+		total += messageSize(getString(entry, "MessageBody"), attrs[i])
+	}
+	if total > maxMessageSize {
+		writeJSONError(w, "BatchRequestTooLong",
+			fmt.Sprintf("Batch requested message too long. Total size of all messages in the batch must be at most %d bytes.", maxMessageSize), http.StatusBadRequest)
+		return
+	}
+
+	successful := []map[string]interface{}{}
+	failed := []map[string]interface{}{}
+	q.mu.Lock()
+	for i, e := range entries {
+		entry := e.(map[string]interface{})
+		id, body := getString(entry, "Id"), getString(entry, "MessageBody")
+		bad := invalid[i]
+		delay, badDelay := durationParam(entry, "DelaySeconds", q.seconds("DelaySeconds"), maxDelaySeconds)
+		if bad == nil {
+			bad = badDelay
+		}
+		if bad == nil {
+			bad = validateMessage(q, body, attrs[i])
+		}
+		if bad != nil {
+			failed = append(failed, map[string]interface{}{
+				"Id":          id,
+				"Code":        bad.code,
+				"Message":     bad.message,
+				"SenderFault": true,
+			})
+			continue
+		}
+		msg := newMessage(body, attrs[i], c, now, delay)
+		q.messages = append(q.messages, msg)
+		result := map[string]interface{}{
+			"Id":               id,
+			"MessageId":        msg.id,
+			"MD5OfMessageBody": msg.md5,
+		}
+		if msg.md5OfAttributes != "" {
+			result["MD5OfMessageAttributes"] = msg.md5OfAttributes
+		}
+		successful = append(successful, result)
+	}
+	q.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"Successful": successful,
+		"Failed":     failed,
+	})
+}
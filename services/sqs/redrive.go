@@ -0,0 +1,293 @@
+package sqs
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/riyanimam/goto/internal/clock"
+	"github.com/riyanimam/goto/internal/paginate"
+)
+
+// maxReceiveCountLimit is the largest maxReceiveCount a redrive policy can
+// name.
+const maxReceiveCountLimit = 1000
+
+// redrivePolicy is a queue's RedrivePolicy attribute.
+type redrivePolicy struct {
+	deadLetterTargetArn string
+	maxReceiveCount     int
+}
+
+// parseRedrivePolicy parses a RedrivePolicy attribute value, in which
+// maxReceiveCount may be a number or a string.
+func parseRedrivePolicy(value string) (redrivePolicy, *invalidMessage) {
+	invalid := func(reason string) (redrivePolicy, *invalidMessage) {
+		return redrivePolicy{}, invalidParameter("Value %s for parameter RedrivePolicy is invalid. Reason: %s", value, reason)
+	}
+	var raw map[string]interface{}
+	if err := json.Unmarshal([]byte(value), &raw); err != nil {
+		return invalid("Invalid value for RedrivePolicy: must be a JSON object.")
+	}
+	target, _ := raw["deadLetterTargetArn"].(string)
+	if target == "" {
+		return invalid("Redrive policy does not contain mandatory attribute: deadLetterTargetArn.")
+	}
+	var count int
+	switch n := raw["maxReceiveCount"].(type) {
+	case float64:
+		count = int(n)
+	case string:
+		count, _ = strconv.Atoi(n)
+	case nil:
+		return invalid("Redrive policy does not contain mandatory attribute: maxReceiveCount.")
+	}
+	if count < 1 || count > maxReceiveCountLimit {
+		return invalid("Invalid value for maxReceiveCount: " + strconv.Itoa(count) + ", valid values are from 1 to 1000 both inclusive.")
+	}
+	return redrivePolicy{deadLetterTargetArn: target, maxReceiveCount: count}, nil
+}
+
+// checkRedrivePolicy validates the RedrivePolicy among attrs, given to
+// CreateQueue or SetQueueAttributes for the queue called name: its
+// dead-letter queue must exist and be of the same type. An empty policy
+// removes the queue's redrive policy. The caller must not hold s.mu.
+func (s *Service) checkRedrivePolicy(name string, attrs map[string]interface{}) *invalidMessage {
+	value, _ := attrs["RedrivePolicy"].(string)
+	if value == "" {
+		return nil
+	}
+	policy, invalid := parseRedrivePolicy(value)
+	if invalid != nil {
+		return invalid
+	}
+
+	s.mu.RLock()
+	dlq := s.queueByARN(policy.deadLetterTargetArn)
+	s.mu.RUnlock()
+	if dlq == nil {
+		return invalidParameter("Value %s for parameter RedrivePolicy is invalid. Reason: Dead letter target does not exist.", value)
+	}
+	if strings.HasSuffix(dlq.name, ".fifo") != strings.HasSuffix(name, ".fifo") {
+		return invalidParameter("Value %s for parameter RedrivePolicy is invalid. Reason: Dead-letter queue must be same type of queue as the source.", value)
+	}
+	return nil
+}
+
+// redrive moves the messages of q that have been received maxReceiveCount
+// times and are visible again to the queue's dead-letter queue, as SQS does
+// instead of returning them to a consumer once more. The messages keep their
+// ID and receive count. The caller must not hold s.mu or q.mu.
+func (s *Service) redrive(q *queue, c *clock.Clock) {
+	s.mu.RLock()
+	q.mu.Lock()
+	policy, invalid := parseRedrivePolicy(q.attributes["RedrivePolicy"])
+	var dlq *queue
+	if invalid == nil {
+		dlq = s.queueByARN(policy.deadLetterTargetArn)
+	}
+	s.mu.RUnlock()
+	if dlq == nil || dlq == q {
+		q.mu.Unlock()
+		return
+	}
+
+	var moved []*message
+	kept := q.messages[:0]
+	for _, msg := range q.messages {
+		if msg.visible(c) && msg.receiveCount >= policy.maxReceiveCount {
+			msg.deadLetterSource = q.arn
+			moved = append(moved, msg)
+			continue
+		}
+		kept = append(kept, msg)
+	}
+	q.messages = kept
+	q.mu.Unlock()
+
+	if len(moved) > 0 {
+		dlq.mu.Lock()
+		dlq.messages = append(dlq.messages, moved...)
+		dlq.mu.Unlock()
+	}
+}
+
+func (s *Service) listDeadLetterSourceQueues(w http.ResponseWriter, params map[string]interface{}) {
+	queueURL := getString(params, "QueueUrl")
+
+	s.mu.RLock()
+	dlq, exists := s.queues[queueURL]
+	var urls []string
+	if exists {
+		for _, q := range s.queues {
+			q.mu.Lock()
+			policy, invalid := parseRedrivePolicy(q.attributes["RedrivePolicy"])
+			q.mu.Unlock()
+			if invalid == nil && policy.deadLetterTargetArn == dlq.arn {
+				urls = append(urls, q.url)
+			}
+		}
+	}
+	s.mu.RUnlock()
+
+	if !exists {
+		writeJSONError(w, "AWS.SimpleQueueService.NonExistentQueue", "The specified queue does not exist.", http.StatusBadRequest)
+		return
+	}
+	sort.Strings(urls)
+
+	limit := getInt(params, "MaxResults", 0)
+	page, next, err := paginate.Page(urls, func(i int) string { return urls[i] }, getString(params, "NextToken"), limit, 1000)
+	if err != nil {
+		writeJSONError(w, "InvalidParameterValue", "Invalid NextToken value.", http.StatusBadRequest)
+		return
+	}
+	resp := map[string]interface{}{
+		"queueUrls": page,
+	}
+	if limit > 0 && next != "" {
+		resp["NextToken"] = next
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// moveTask is a message move task started with StartMessageMoveTask. The
+// mock moves the messages when the task starts, so every task is COMPLETED.
+type moveTask struct {
+	handle         string
+	sourceARN      string
+	destinationARN string
+	maxPerSecond   int
+	moved          int
+	started        time.Time
+}
+
+func (s *Service) startMessageMoveTask(w http.ResponseWriter, params map[string]interface{}) {
+	sourceARN := getString(params, "SourceArn")
+	destinationARN := getString(params, "DestinationArn")
+	maxPerSecond := getInt(params, "MaxNumberOfMessagesPerSecond", 0)
+	if sourceARN == "" {
+		writeJSONError(w, "MissingParameter", "The request must contain the parameter SourceArn.", http.StatusBadRequest)
+		return
+	}
+	if maxPerSecond < 0 || maxPerSecond > 500 {
+		writeJSONError(w, "InvalidParameterValue", "Value "+strconv.Itoa(maxPerSecond)+" for parameter MaxNumberOfMessagesPerSecond is invalid. Reason: Must be between 1 and 500.", http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	source := s.queueByARN(sourceARN)
+	if source == nil {
+		writeJSONError(w, "ResourceNotFoundException", "The resource that you specified for the SourceArn parameter doesn't exist.", http.StatusBadRequest)
+		return
+	}
+	isDeadLetterQueue := false
+	for _, q := range s.queues {
+		q.mu.Lock()
+		policy, invalid := parseRedrivePolicy(q.attributes["RedrivePolicy"])
+		q.mu.Unlock()
+		if invalid == nil && policy.deadLetterTargetArn == sourceARN {
+			isDeadLetterQueue = true
+			break
+		}
+	}
+	if !isDeadLetterQueue {
+		writeJSONError(w, "InvalidParameterValue", "Source queue must be configured as a Dead Letter Queue.", http.StatusBadRequest)
+		return
+	}
+	var destination *queue
+	if destinationARN != "" {
+		if destination = s.queueByARN(destinationARN); destination == nil || destination == source {
+			writeJSONError(w, "InvalidParameterValue", "The resource that you specified for the DestinationArn parameter doesn't exist.", http.StatusBadRequest)
+			return
+		}
+	}
+
+	// Visible messages go to the destination, or without one back to the
+	// queue each was moved to the dead-letter queue from. They start over
+	// there, so they get the full maxReceiveCount again.
+	c, now := s.clock, s.now()
+	byTarget := make(map[*queue][]*message)
+	source.mu.Lock()
+	kept := source.messages[:0]
+	for _, msg := range source.messages {
+		target := destination
+		if target == nil {
+			target = s.queueByARN(msg.deadLetterSource)
+		}
+		if target == nil || target == source || !msg.visible(c) {
+			kept = append(kept, msg)
+			continue
+		}
+		msg.receiveCount = 0
+		msg.firstReceived = time.Time{}
+		msg.deadLetterSource = ""
+		byTarget[target] = append(byTarget[target], msg)
+	}
+	source.messages = kept
+	source.mu.Unlock()
+
+	task := &moveTask{
+		handle:         newMessageID(),
+		sourceARN:      sourceARN,
+		destinationARN: destinationARN,
+		maxPerSecond:   maxPerSecond,
+		started:        now,
+	}
+	for target, msgs := range byTarget {
+		target.mu.Lock()
+		target.messages = append(target.messages, msgs...)
+		target.mu.Unlock()
+		task.moved += len(msgs)
+	}
+	s.moveTasks = append(s.moveTasks, task)
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"TaskHandle": task.handle,
+	})
+}
+
+func (s *Service) listMessageMoveTasks(w http.ResponseWriter, params map[string]interface{}) {
+	sourceARN := getString(params, "SourceArn")
+	maxResults := getInt(params, "MaxResults", 1)
+	if maxResults < 1 || maxResults > 10 {
+		writeJSONError(w, "InvalidParameterValue", "Value "+strconv.Itoa(maxResults)+" for parameter MaxResults is invalid. Reason: Must be between 1 and 10.", http.StatusBadRequest)
+		return
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.queueByARN(sourceARN) == nil {
+		writeJSONError(w, "ResourceNotFoundException", "The resource that you specified for the SourceArn parameter doesn't exist.", http.StatusBadRequest)
+		return
+	}
+	results := []map[string]interface{}{}
+	for i := len(s.moveTasks) - 1; i >= 0 && len(results) < maxResults; i-- {
+		task := s.moveTasks[i]
+		if task.sourceARN != sourceARN {
+			continue
+		}
+		result := map[string]interface{}{
+			"TaskHandle":                        task.handle,
+			"Status":                            "COMPLETED",
+			"SourceArn":                         task.sourceARN,
+			"ApproximateNumberOfMessagesMoved":  task.moved,
+			"ApproximateNumberOfMessagesToMove": task.moved,
+			"StartedTimestamp":                  task.started.UnixMilli(),
+		}
+		if task.destinationARN != "" {
+			result["DestinationArn"] = task.destinationARN
+		}
+		if task.maxPerSecond > 0 {
+			result["MaxNumberOfMessagesPerSecond"] = task.maxPerSecond
+		}
+		results = append(results, result)
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"Results": results,
+	})
+}
@@ -7,10 +7,35 @@
 //   - GetQueueUrl
 //   - GetQueueAttributes
 //   - SendMessage
+//   - SendMessageBatch
 //   - ReceiveMessage
+//   - ChangeMessageVisibility
 //   - DeleteMessage
 //   - PurgeQueue
 //   - SetQueueAttributes
+//   - TagQueue
+//   - UntagQueue
+//   - ListQueueTags
+//   - ListDeadLetterSourceQueues
+//   - StartMessageMoveTask
+//   - ListMessageMoveTasks
+//
+// Messages are held to the limits SQS enforces: bodies and attributes
+// together no larger than the queue's MaximumMessageSize (256 KiB by
+// default), at most ten message attributes of the String, Number and Binary
+// types, and batches of at most ten entries totalling no more than 256 KiB.
+//
+// Received messages stay hidden for the queue's VisibilityTimeout, or the
+// one given to ReceiveMessage, and sent messages for their DelaySeconds,
+// both measured in real time or on the mock clock, whichever has moved
+// further, so messages come back on their own and at once when a test
+// advances the clock. Each receive counts toward the
+// message's ApproximateReceiveCount and issues a new receipt handle.
+//
+// A queue with a RedrivePolicy moves a message to its dead-letter queue
+// once the message has been received maxReceiveCount times and would be
+// received again. StartMessageMoveTask moves the messages of a dead-letter
+// queue back to their source queues, or to another queue, at once.
 package sqs
 
 import (
@@ -18,13 +43,19 @@ import (
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
-	"io"
 	"math/rand"
 	"net/http"
 	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
+
+	"github.com/riyanimam/goto/internal/awserr"
+	"github.com/riyanimam/goto/internal/clock"
+	"github.com/riyanimam/goto/internal/mockhelpers"
+	"github.com/riyanimam/goto/internal/paginate"
+	"github.com/riyanimam/goto/internal/tags"
 )
 
 const defaultAccountID = "123456789012"
@@ -33,6 +64,11 @@ const defaultAccountID = "123456789012"
 type Service struct {
 	mu     sync.RWMutex
 	queues map[string]*queue // keyed by queue URL
+	tags   *tags.Store
+	clock  *clock.Clock
+
+	moveTasks  []*moveTask
+	checkpoint map[string]*queue
 }
 
 type queue struct {
@@ -46,18 +82,46 @@ type queue struct {
 }
 
 type message struct {
-	id            string
-	body          string
-	md5           string
-	receiptHandle string
-	sentTimestamp string
-	visible       bool
+	id              string
+	body            string
+	md5             string
+	attributes      map[string]messageAttribute
+	md5OfAttributes string
+	receiptHandle   string
+	sentTimestamp   string
+	// hidden is when the message was last hidden, and hiddenFor for how
+	// long: its delay, or its visibility timeout once received.
+	hidden        clock.Mark
+	hiddenFor     time.Duration
+	receiveCount  int
+	firstReceived time.Time
+	// deadLetterSource is the ARN of the queue the message was moved to
+	// the dead-letter queue from, if it was.
+	deadLetterSource string
+}
+
+// newMessage returns a message with the given body and attributes sent at
+// now, which becomes visible after delay on c.
+func newMessage(body string, attrs map[string]messageAttribute, c *clock.Clock, now time.Time, delay time.Duration) *message {
+	hash := md5.Sum([]byte(body))
+	return &message{
+		id:              newMessageID(),
+		body:            body,
+		md5:             hex.EncodeToString(hash[:]),
+		attributes:      attrs,
+		md5OfAttributes: md5OfMessageAttributes(attrs),
+		receiptHandle:   newMessageID() + newMessageID(),
+		sentTimestamp:   fmt.Sprintf("%d", now.UnixMilli()),
+		hidden:          c.Mark(),
+		hiddenFor:       delay,
+	}
 }
 
 // New creates a new SQS mock service.
 func New() *Service {
 	return &Service{
 		queues: make(map[string]*queue),
+		tags:   tags.New(),
 	}
 }
 
@@ -66,72 +130,59 @@ func (s *Service) Name() string { return "sqs" }
 
 // Handler returns the HTTP handler for SQS requests.
 func (s *Service) Handler() http.Handler {
-	return http.HandlerFunc(s.handle)
+	return mockhelpers.JSONRouter{
+		"CreateQueue":                s.createQueue,
+		"DeleteQueue":                s.deleteQueue,
+		"ListQueues":                 s.listQueues,
+		"GetQueueUrl":                s.getQueueURL,
+		"GetQueueAttributes":         s.getQueueAttributes,
+		"SetQueueAttributes":         s.setQueueAttributes,
+		"SendMessage":                s.sendMessage,
+		"SendMessageBatch":           s.sendMessageBatch,
+		"ReceiveMessage":             s.receiveMessage,
+		"ChangeMessageVisibility":    s.changeMessageVisibility,
+		"DeleteMessage":              s.deleteMessage,
+		"PurgeQueue":                 s.purgeQueue,
+		"TagQueue":                   s.tagQueue,
+		"UntagQueue":                 s.untagQueue,
+		"ListQueueTags":              s.listQueueTags,
+		"ListDeadLetterSourceQueues": s.listDeadLetterSourceQueues,
+		"StartMessageMoveTask":       s.startMessageMoveTask,
+		"ListMessageMoveTasks":       s.listMessageMoveTasks,
+	}.JSON10()
 }
 
-// Reset clears all queues and messages.
+// Reset clears all queues and messages. After [Service.Checkpoint], it
+// restores the checkpointed queues instead.
 func (s *Service) Reset() {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	s.queues = make(map[string]*queue)
+	s.queues = s.restoreCheckpoint()
+	s.moveTasks = nil
+	s.tags.DeleteService("sqs")
 }
 
-func (s *Service) handle(w http.ResponseWriter, r *http.Request) {
-	// AWS SDK v2 SQS uses the JSON protocol with X-Amz-Target header.
-	target := r.Header.Get("X-Amz-Target")
-
-	// Read request body.
-	bodyBytes, err := io.ReadAll(r.Body)
-	if err != nil {
-		writeJSONError(w, "InternalError", "could not read request body", http.StatusInternalServerError)
-		return
-	}
-
-	// Parse the JSON body into a generic map.
-	var params map[string]interface{}
-	if len(bodyBytes) > 0 {
-		if err := json.Unmarshal(bodyBytes, &params); err != nil {
-			writeJSONError(w, "InvalidParameterValue", "could not parse request body", http.StatusBadRequest)
-			return
-		}
-	}
-	if params == nil {
-		params = make(map[string]interface{})
-	}
+// SetTagStore sets the registry queue tags are recorded in.
+func (s *Service) SetTagStore(store *tags.Store) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tags = store
+}
 
-	// Route based on X-Amz-Target header.
-	action := ""
-	if target != "" {
-		parts := strings.SplitN(target, ".", 2)
-		if len(parts) == 2 {
-			action = parts[1]
-		}
-	}
+// SetClock attaches the mock clock that, alongside real time, visibility
+// timeouts and delays are measured on.
+func (s *Service) SetClock(c *clock.Clock) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.clock = c
+}
 
-	switch action {
-	case "CreateQueue":
-		s.createQueue(w, params)
-	case "DeleteQueue":
-		s.deleteQueue(w, params)
-	case "ListQueues":
-		s.listQueues(w, params)
-	case "GetQueueUrl":
-		s.getQueueURL(w, params)
-	case "GetQueueAttributes":
-		s.getQueueAttributes(w, params)
-	case "SetQueueAttributes":
-		s.setQueueAttributes(w, params)
-	case "SendMessage":
-		s.sendMessage(w, params)
-	case "ReceiveMessage":
-		s.receiveMessage(w, params)
-	case "DeleteMessage":
-		s.deleteMessage(w, params)
-	case "PurgeQueue":
-		s.purgeQueue(w, params)
-	default:
-		writeJSONError(w, "InvalidAction", fmt.Sprintf("action %q is not supported", action), http.StatusBadRequest)
+// now returns the current mock time. The caller must hold s.mu.
+func (s *Service) now() time.Time {
+	if s.clock != nil {
+		return s.clock.Now()
 	}
+	return time.Now().UTC()
 }
 
 func (s *Service) createQueue(w http.ResponseWriter, params map[string]interface{}) {
@@ -142,6 +193,15 @@ func (s *Service) createQueue(w http.ResponseWriter, params map[string]interface
 	}
 
 	queueURL := fmt.Sprintf("http://localhost/%s/%s", defaultAccountID, name)
+	attrs, _ := params["Attributes"].(map[string]interface{})
+	invalid := validateQueueAttributes(attrs)
+	if invalid == nil {
+		invalid = s.checkRedrivePolicy(name, attrs)
+	}
+	if invalid != nil {
+		writeJSONError(w, invalid.code, invalid.message, http.StatusBadRequest)
+		return
+	}
 
 	s.mu.Lock()
 	// Check if queue with same name already exists.
@@ -155,18 +215,19 @@ func (s *Service) createQueue(w http.ResponseWriter, params map[string]interface
 		}
 	}
 
+	now := s.now()
 	q := &queue{
 		name:    name,
 		url:     queueURL,
 		arn:     fmt.Sprintf("arn:aws:sqs:us-east-1:%s:%s", defaultAccountID, name),
-		created: time.Now().UTC(),
+		created: now,
 		attributes: map[string]string{
 			"QueueArn":                              fmt.Sprintf("arn:aws:sqs:us-east-1:%s:%s", defaultAccountID, name),
 			"ApproximateNumberOfMessages":           "0",
 			"ApproximateNumberOfMessagesDelayed":    "0",
 			"ApproximateNumberOfMessagesNotVisible": "0",
-			"CreatedTimestamp":                      fmt.Sprintf("%d", time.Now().Unix()),
-			"LastModifiedTimestamp":                 fmt.Sprintf("%d", time.Now().Unix()),
+			"CreatedTimestamp":                      fmt.Sprintf("%d", now.Unix()),
+			"LastModifiedTimestamp":                 fmt.Sprintf("%d", now.Unix()),
 			"VisibilityTimeout":                     "30",
 			"MaximumMessageSize":                    "262144",
 			"MessageRetentionPeriod":                "345600",
@@ -175,18 +236,17 @@ func (s *Service) createQueue(w http.ResponseWriter, params map[string]interface
 		},
 	}
 	s.queues[queueURL] = q
+	s.tags.Tag(q.arn, getStringMap(params, "tags"))
 	s.mu.Unlock()
 
 	// Apply any attribute overrides from the request.
-	if attrs, ok := params["Attributes"].(map[string]interface{}); ok {
-		q.mu.Lock()
-		for k, v := range attrs {
-			if sv, ok := v.(string); ok {
-				q.attributes[k] = sv
-			}
+	q.mu.Lock()
+	for k, v := range attrs {
+		if sv, ok := v.(string); ok {
+			q.attributes[k] = sv
 		}
-		q.mu.Unlock()
 	}
+	q.mu.Unlock()
 
 	writeJSON(w, http.StatusOK, map[string]interface{}{
 		"QueueUrl": queueURL,
@@ -197,6 +257,9 @@ func (s *Service) deleteQueue(w http.ResponseWriter, params map[string]interface
 	queueURL := getString(params, "QueueUrl")
 
 	s.mu.Lock()
+	if q, ok := s.queues[queueURL]; ok {
+		s.tags.Delete(q.arn)
+	}
 	delete(s.queues, queueURL)
 	s.mu.Unlock()
 
@@ -217,9 +280,20 @@ func (s *Service) listQueues(w http.ResponseWriter, params map[string]interface{
 
 	sort.Strings(urls)
 
-	writeJSON(w, http.StatusOK, map[string]interface{}{
-		"QueueUrls": urls,
-	})
+	// Without MaxResults, ListQueues returns up to 1000 queues and no token.
+	limit := getInt(params, "MaxResults", 0)
+	page, next, err := paginate.Page(urls, func(i int) string { return urls[i] }, getString(params, "NextToken"), limit, 1000)
+	if err != nil {
+		writeJSONError(w, "InvalidParameterValue", "Invalid NextToken value.", http.StatusBadRequest)
+		return
+	}
+	resp := map[string]interface{}{
+		"QueueUrls": page,
+	}
+	if limit > 0 && next != "" {
+		resp["NextToken"] = next
+	}
+	writeJSON(w, http.StatusOK, resp)
 }
 
 func (s *Service) getQueueURL(w http.ResponseWriter, params map[string]interface{}) {
@@ -245,6 +319,7 @@ func (s *Service) getQueueAttributes(w http.ResponseWriter, params map[string]in
 
 	s.mu.RLock()
 	q, exists := s.queues[queueURL]
+	c := s.clock
 	s.mu.RUnlock()
 
 	if !exists {
@@ -270,7 +345,10 @@ func (s *Service) getQueueAttributes(w http.ResponseWriter, params map[string]in
 	}
 
 	q.mu.Lock()
-	q.attributes["ApproximateNumberOfMessages"] = fmt.Sprintf("%d", countVisible(q))
+	visible, inFlight, delayed := countMessages(q, c)
+	q.attributes["ApproximateNumberOfMessages"] = strconv.Itoa(visible)
+	q.attributes["ApproximateNumberOfMessagesNotVisible"] = strconv.Itoa(inFlight)
+	q.attributes["ApproximateNumberOfMessagesDelayed"] = strconv.Itoa(delayed)
 	attrs := make(map[string]string)
 	for k, v := range q.attributes {
 		if requestAll || requestedNames[k] {
@@ -296,25 +374,92 @@ func (s *Service) setQueueAttributes(w http.ResponseWriter, params map[string]in
 		return
 	}
 
-	if attrs, ok := params["Attributes"].(map[string]interface{}); ok {
-		q.mu.Lock()
-		for k, v := range attrs {
-			if sv, ok := v.(string); ok {
-				q.attributes[k] = sv
-			}
+	attrs, _ := params["Attributes"].(map[string]interface{})
+	invalid := validateQueueAttributes(attrs)
+	if invalid == nil {
+		invalid = s.checkRedrivePolicy(q.name, attrs)
+	}
+	if invalid != nil {
+		writeJSONError(w, invalid.code, invalid.message, http.StatusBadRequest)
+		return
+	}
+	q.mu.Lock()
+	for k, v := range attrs {
+		sv, ok := v.(string)
+		switch {
+		case !ok:
+		case k == "RedrivePolicy" && sv == "":
+			delete(q.attributes, k)
+		default:
+			q.attributes[k] = sv
 		}
-		q.mu.Unlock()
 	}
+	q.mu.Unlock()
 
 	writeJSON(w, http.StatusOK, map[string]interface{}{})
 }
 
+func (s *Service) tagQueue(w http.ResponseWriter, params map[string]interface{}) {
+	queueURL := getString(params, "QueueUrl")
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	q, exists := s.queues[queueURL]
+	if !exists {
+		writeJSONError(w, "AWS.SimpleQueueService.NonExistentQueue", "The specified queue does not exist.", http.StatusBadRequest)
+		return
+	}
+
+	s.tags.Tag(q.arn, getStringMap(params, "Tags"))
+	writeJSON(w, http.StatusOK, map[string]interface{}{})
+}
+
+func (s *Service) untagQueue(w http.ResponseWriter, params map[string]interface{}) {
+	queueURL := getString(params, "QueueUrl")
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	q, exists := s.queues[queueURL]
+	if !exists {
+		writeJSONError(w, "AWS.SimpleQueueService.NonExistentQueue", "The specified queue does not exist.", http.StatusBadRequest)
+		return
+	}
+
+	var keys []string
+	if list, ok := params["TagKeys"].([]interface{}); ok {
+		for _, k := range list {
+			if ks, ok := k.(string); ok {
+				keys = append(keys, ks)
+			}
+		}
+	}
+	s.tags.Untag(q.arn, keys)
+	writeJSON(w, http.StatusOK, map[string]interface{}{})
+}
+
+func (s *Service) listQueueTags(w http.ResponseWriter, params map[string]interface{}) {
+	queueURL := getString(params, "QueueUrl")
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	q, exists := s.queues[queueURL]
+	if !exists {
+		writeJSONError(w, "AWS.SimpleQueueService.NonExistentQueue", "The specified queue does not exist.", http.StatusBadRequest)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"Tags": s.tags.Get(q.arn),
+	})
+}
+
 func (s *Service) sendMessage(w http.ResponseWriter, params map[string]interface{}) {
 	queueURL := getString(params, "QueueUrl")
 	body := getString(params, "MessageBody")
 
 	s.mu.RLock()
 	q, exists := s.queues[queueURL]
+	c, now := s.clock, s.now()
 	s.mu.RUnlock()
 
 	if !exists {
@@ -322,37 +467,153 @@ func (s *Service) sendMessage(w http.ResponseWriter, params map[string]interface
 		return
 	}
 
-	hash := md5.Sum([]byte(body))
-	md5Hex := hex.EncodeToString(hash[:])
-
-	msg := &message{
-		id:            newMessageID(),
-		body:          body,
-		md5:           md5Hex,
-		receiptHandle: newMessageID() + newMessageID(),
-		sentTimestamp: fmt.Sprintf("%d", time.Now().UnixMilli()),
-		visible:       true,
+	attrs, invalid := parseMessageAttributes(params["MessageAttributes"])
+	if invalid != nil {
+		writeJSONError(w, invalid.code, invalid.message, http.StatusBadRequest)
+		return
 	}
 
 	q.mu.Lock()
+	delay, invalid := durationParam(params, "DelaySeconds", q.seconds("DelaySeconds"), maxDelaySeconds)
+	if invalid == nil {
+		invalid = validateMessage(q, body, attrs)
+	}
+	if invalid != nil {
+		q.mu.Unlock()
+		writeJSONError(w, invalid.code, invalid.message, http.StatusBadRequest)
+		return
+	}
+	msg := newMessage(body, attrs, c, now, delay)
 	q.messages = append(q.messages, msg)
 	q.mu.Unlock()
 
-	writeJSON(w, http.StatusOK, map[string]interface{}{
+	resp := map[string]interface{}{
 		"MessageId":        msg.id,
-		"MD5OfMessageBody": md5Hex,
-	})
+		"MD5OfMessageBody": msg.md5,
+	}
+	if msg.md5OfAttributes != "" {
+		resp["MD5OfMessageAttributes"] = msg.md5OfAttributes
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// HasResource reports whether the queue identified by arn exists.
+func (s *Service) HasResource(arn string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, q := range s.queues {
+		if q.arn == arn {
+			return true
+		}
+	}
+	return false
+}
+
+// Deliver enqueues payload as a message body on the queue identified by arn.
+func (s *Service) Deliver(arn string, payload []byte) ([]byte, error) {
+	s.mu.RLock()
+	q := s.queueByARN(arn)
+	c, now := s.clock, s.now()
+	s.mu.RUnlock()
+
+	if q == nil {
+		return nil, fmt.Errorf("queue %s does not exist", arn)
+	}
+
+	q.mu.Lock()
+	msg := newMessage(string(payload), nil, c, now, q.seconds("DelaySeconds"))
+	q.messages = append(q.messages, msg)
+	q.mu.Unlock()
+
+	return []byte(msg.id), nil
+}
+
+// Message is a snapshot of a message held in a queue.
+type Message struct {
+	MessageID     string
+	Body          string
+	MD5OfBody     string
+	SentTimestamp time.Time
+	// InFlight reports whether the message has been received and is hidden
+	// from other consumers.
+	InFlight bool
+	// Delayed reports whether the message is waiting out its DelaySeconds.
+	Delayed bool
+	// ReceiveCount is the number of times the message has been received.
+	ReceiveCount int
+}
+
+// Queues returns the URLs of the queues, sorted.
+func (s *Service) Queues() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	urls := make([]string, 0, len(s.queues))
+	for url := range s.queues {
+		urls = append(urls, url)
+	}
+	sort.Strings(urls)
+	return urls
+}
+
+// Messages returns the messages currently in the queue, including in-flight
+// ones, in the order they were sent. It does not change their visibility.
+func (s *Service) Messages(queueURL string) ([]Message, error) {
+	s.mu.RLock()
+	q, exists := s.queues[queueURL]
+	c := s.clock
+	s.mu.RUnlock()
+
+	if !exists {
+		return nil, fmt.Errorf("queue %s does not exist", queueURL)
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	msgs := make([]Message, 0, len(q.messages))
+	for _, msg := range q.messages {
+		ms, _ := strconv.ParseInt(msg.sentTimestamp, 10, 64)
+		msgs = append(msgs, Message{
+			MessageID:     msg.id,
+			Body:          msg.body,
+			MD5OfBody:     msg.md5,
+			SentTimestamp: time.UnixMilli(ms).UTC(),
+			InFlight:      msg.inFlight(c),
+			Delayed:       msg.delayed(c),
+			ReceiveCount:  msg.receiveCount,
+		})
+	}
+	return msgs, nil
 }
 
 func (s *Service) receiveMessage(w http.ResponseWriter, params map[string]interface{}) {
 	queueURL := getString(params, "QueueUrl")
 	maxMessages := getInt(params, "MaxNumberOfMessages", 1)
+	var attributeNames []string
+	if names, ok := params["MessageAttributeNames"].([]interface{}); ok {
+		for _, n := range names {
+			if name, ok := n.(string); ok {
+				attributeNames = append(attributeNames, name)
+			}
+		}
+	}
+	// System attributes are asked for with MessageSystemAttributeNames, or
+	// the older AttributeNames.
+	systemNames := make(map[string]bool)
+	for _, key := range []string{"AttributeNames", "MessageSystemAttributeNames"} {
+		names, _ := params[key].([]interface{})
+		for _, n := range names {
+			if name, ok := n.(string); ok {
+				systemNames[name] = true
+			}
+		}
+	}
 	if maxMessages > 10 {
 		maxMessages = 10
 	}
 
 	s.mu.RLock()
 	q, exists := s.queues[queueURL]
+	c, now := s.clock, s.now()
 	s.mu.RUnlock()
 
 	if !exists {
@@ -360,23 +621,43 @@ func (s *Service) receiveMessage(w http.ResponseWriter, params map[string]interf
 		return
 	}
 
+	s.redrive(q, c)
 	q.mu.Lock()
+	timeout, invalid := durationParam(params, "VisibilityTimeout", q.seconds("VisibilityTimeout"), maxVisibilityTimeout)
+	if invalid != nil {
+		q.mu.Unlock()
+		writeJSONError(w, invalid.code, invalid.message, http.StatusBadRequest)
+		return
+	}
 	var received []map[string]interface{}
-	count := 0
 	for _, msg := range q.messages {
-		if count >= maxMessages {
+		if len(received) >= maxMessages {
 			break
 		}
-		if msg.visible {
-			msg.visible = false
-			received = append(received, map[string]interface{}{
-				"MessageId":     msg.id,
-				"ReceiptHandle": msg.receiptHandle,
-				"Body":          msg.body,
-				"MD5OfBody":     msg.md5,
-			})
-			count++
+		if !msg.visible(c) {
+			continue
+		}
+		msg.receive(c, now, timeout)
+		m := map[string]interface{}{
+			"MessageId":     msg.id,
+			"ReceiptHandle": msg.receiptHandle,
+			"Body":          msg.body,
+			"MD5OfBody":     msg.md5,
+		}
+		if attrs := selectAttributes(msg.attributes, attributeNames); len(attrs) > 0 {
+			m["MessageAttributes"] = attributesToMap(attrs)
+			m["MD5OfMessageAttributes"] = md5OfMessageAttributes(attrs)
+		}
+		if len(systemNames) > 0 {
+			attrs := make(map[string]string)
+			for name, value := range msg.systemAttributes() {
+				if systemNames["All"] || systemNames[name] {
+					attrs[name] = value
+				}
+			}
+			m["Attributes"] = attrs
 		}
+		received = append(received, m)
 	}
 	q.mu.Unlock()
 
@@ -440,6 +721,18 @@ func getString(params map[string]interface{}, key string) string {
 	return ""
 }
 
+func getStringMap(params map[string]interface{}, key string) map[string]string {
+	m := make(map[string]string)
+	if raw, ok := params[key].(map[string]interface{}); ok {
+		for k, v := range raw {
+			if s, ok := v.(string); ok {
+				m[k] = s
+			}
+		}
+	}
+	return m
+}
+
 func getInt(params map[string]interface{}, key string, defaultVal int) int {
 	if v, ok := params[key]; ok {
 		switch n := v.(type) {
@@ -452,16 +745,6 @@ func getInt(params map[string]interface{}, key string, defaultVal int) int {
 	return defaultVal
 }
 
-func countVisible(q *queue) int {
-	count := 0
-	for _, msg := range q.messages {
-		if msg.visible {
-			count++
-		}
-	}
-	return count
-}
-
 func writeJSON(w http.ResponseWriter, status int, v interface{}) {
 	w.Header().Set("Content-Type", "application/x-amz-json-1.0")
 	w.WriteHeader(status)
@@ -469,12 +752,7 @@ func writeJSON(w http.ResponseWriter, status int, v interface{}) {
 }
 
 func writeJSONError(w http.ResponseWriter, code, message string, status int) {
-	w.Header().Set("Content-Type", "application/x-amz-json-1.0")
-	w.WriteHeader(status)
-	json.NewEncoder(w).Encode(map[string]interface{}{
-		"__type":  code,
-		"message": message,
-	})
+	awserr.WriteJSON(w, awserr.JSON10, code, message, status)
 }
 
 func newRequestID() string {
@@ -0,0 +1,38 @@
+package sqs
+
+import (
+	"strings"
+
+	"github.com/riyanimam/goto/internal/mockhelpers"
+)
+
+// ExportState lists the queues with their configured attributes, leaving
+// out timestamps and message counts.
+func (s *Service) ExportState() []mockhelpers.Resource {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	resources := make([]mockhelpers.Resource, 0, len(s.queues))
+	for _, q := range s.queues {
+		q.mu.Lock()
+		attrs := make(map[string]string, len(q.attributes))
+		for k, v := range q.attributes {
+			if k != "QueueArn" && !strings.HasPrefix(k, "Approximate") && !strings.HasSuffix(k, "Timestamp") {
+				attrs[k] = v
+			}
+		}
+		q.mu.Unlock()
+
+		resources = append(resources, mockhelpers.Resource{
+			Type: "aws_sqs_queue",
+			ID:   q.name,
+			ARN:  q.arn,
+			Attributes: map[string]interface{}{
+				"name":       q.name,
+				"fifo_queue": strings.HasSuffix(q.name, ".fifo"),
+				"attributes": attrs,
+			},
+		})
+	}
+	return resources
+}
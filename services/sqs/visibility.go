@@ -0,0 +1,164 @@
+package sqs
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/riyanimam/goto/internal/clock"
+)
+
+const (
+	// maxVisibilityTimeout is the longest a received message can be hidden.
+	maxVisibilityTimeout = 12 * 60 * 60
+	// maxDelaySeconds is the longest a message's delivery can be delayed.
+	maxDelaySeconds = 15 * 60
+)
+
+// visible reports whether the message can be received, its delay or
+// visibility timeout having passed on c.
+func (m *message) visible(c *clock.Clock) bool {
+	return c.Since(m.hidden) >= m.hiddenFor
+}
+
+// delayed reports whether the message has not been delivered yet because of
+// its DelaySeconds.
+func (m *message) delayed(c *clock.Clock) bool {
+	return m.receiveCount == 0 && !m.visible(c)
+}
+
+// inFlight reports whether the message has been received and is hidden from
+// other consumers.
+func (m *message) inFlight(c *clock.Clock) bool {
+	return m.receiveCount > 0 && !m.visible(c)
+}
+
+// hide makes the message invisible for d from now on c.
+func (m *message) hide(c *clock.Clock, d time.Duration) {
+	m.hidden, m.hiddenFor = c.Mark(), d
+}
+
+// receive hides the message for timeout and gives it a new receipt handle,
+// as SQS does on every receive at now.
+func (m *message) receive(c *clock.Clock, now time.Time, timeout time.Duration) {
+	m.receiveCount++
+	if m.firstReceived.IsZero() {
+		m.firstReceived = now
+	}
+	m.hide(c, timeout)
+	m.receiptHandle = newMessageID() + newMessageID()
+}
+
+// systemAttributes returns the message's system attributes as received
+// messages report them.
+func (m *message) systemAttributes() map[string]string {
+	attrs := map[string]string{
+		"ApproximateReceiveCount": strconv.Itoa(m.receiveCount),
+		"SentTimestamp":           m.sentTimestamp,
+		"SenderId":                defaultAccountID,
+	}
+	if !m.firstReceived.IsZero() {
+		attrs["ApproximateFirstReceiveTimestamp"] = strconv.FormatInt(m.firstReceived.UnixMilli(), 10)
+	}
+	if m.deadLetterSource != "" {
+		attrs["DeadLetterQueueSourceArn"] = m.deadLetterSource
+	}
+	return attrs
+}
+
+// seconds returns a queue attribute holding a number of seconds. The caller
+// must hold q.mu.
+func (q *queue) seconds(name string) time.Duration {
+	n, _ := strconv.Atoi(q.attributes[name])
+	return time.Duration(n) * time.Second
+}
+
+// durationParam returns the number of seconds in params[key], or def if
+// it is not set, checking that it is between 0 and max.
+func durationParam(params map[string]interface{}, key string, def time.Duration, max int) (time.Duration, *invalidMessage) {
+	if _, set := params[key]; !set {
+		return def, nil
+	}
+	n := getInt(params, key, -1)
+	if n < 0 || n > max {
+		return 0, invalidParameter("Value %v for parameter %s is invalid. Reason: Must be between 0 and %d, if provided.", params[key], key, max)
+	}
+	return time.Duration(n) * time.Second, nil
+}
+
+// validateQueueAttributes checks the attributes given to CreateQueue or
+// SetQueueAttributes whose values the mock acts on.
+func validateQueueAttributes(attrs map[string]interface{}) *invalidMessage {
+	limits := map[string]int{
+		"VisibilityTimeout": maxVisibilityTimeout,
+		"DelaySeconds":      maxDelaySeconds,
+	}
+	for name, max := range limits {
+		v, ok := attrs[name]
+		if !ok {
+			continue
+		}
+		s, _ := v.(string)
+		if n, err := strconv.Atoi(s); err != nil || n < 0 || n > max {
+			return &invalidMessage{code: "InvalidAttributeValue", message: fmt.Sprintf("Invalid value for the parameter %s.", name)}
+		}
+	}
+	return nil
+}
+
+// countMessages returns the queue's visible, in-flight, and delayed
+// messages. The caller must hold q.mu.
+func countMessages(q *queue, c *clock.Clock) (visible, inFlight, delayed int) {
+	for _, msg := range q.messages {
+		switch {
+		case msg.visible(c):
+			visible++
+		case msg.delayed(c):
+			delayed++
+		default:
+			inFlight++
+		}
+	}
+	return visible, inFlight, delayed
+}
+
+func (s *Service) changeMessageVisibility(w http.ResponseWriter, params map[string]interface{}) {
+	queueURL := getString(params, "QueueUrl")
+	receiptHandle := getString(params, "ReceiptHandle")
+
+	s.mu.RLock()
+	q, exists := s.queues[queueURL]
+	c := s.clock
+	s.mu.RUnlock()
+
+	if !exists {
+		writeJSONError(w, "AWS.SimpleQueueService.NonExistentQueue", "The specified queue does not exist.", http.StatusBadRequest)
+		return
+	}
+	if _, set := params["VisibilityTimeout"]; !set {
+		writeJSONError(w, "MissingParameter", "The request must contain the parameter VisibilityTimeout.", http.StatusBadRequest)
+		return
+	}
+	timeout, invalid := durationParam(params, "VisibilityTimeout", 0, maxVisibilityTimeout)
+	if invalid != nil {
+		writeJSONError(w, invalid.code, invalid.message, http.StatusBadRequest)
+		return
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for _, msg := range q.messages {
+		if msg.receiptHandle != receiptHandle {
+			continue
+		}
+		if !msg.inFlight(c) {
+			writeJSONError(w, "AWS.SimpleQueueService.MessageNotInflight", "Value "+receiptHandle+" for parameter ReceiptHandle is invalid. Reason: Message does not exist or is not available for visibility timeout change.", http.StatusBadRequest)
+			return
+		}
+		msg.hide(c, timeout)
+		writeJSON(w, http.StatusOK, map[string]interface{}{})
+		return
+	}
+	writeJSONError(w, "ReceiptHandleIsInvalid", "The input receipt handle \""+receiptHandle+"\" is not a valid receipt handle.", http.StatusBadRequest)
+}
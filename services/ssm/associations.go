@@ -0,0 +1,323 @@
+package ssm
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/riyanimam/goto/internal/paginate"
+)
+
+type association struct {
+	id              string
+	name            string // document name
+	associationName string
+	version         int
+	targets         []target
+	parameters      map[string]interface{}
+	scheduleExpr    string
+	sched           *schedule
+	applyOnlyAtCron bool
+	created         time.Time
+	executions      []*associationExecution // oldest first
+}
+
+type associationExecution struct {
+	id      string
+	version int
+	status  string // Success or Failed
+	created time.Time
+	targets []*executionTarget
+}
+
+type executionTarget struct {
+	resourceID     string
+	status         string
+	detailedStatus string
+}
+
+// association returns the association named by the AssociationId
+// parameter, writing AssociationDoesNotExist if there is none. The caller
+// must hold s.mu.
+func (s *Service) association(w http.ResponseWriter, params map[string]interface{}) *association {
+	id := getString(params, "AssociationId")
+	a, ok := s.associations[id]
+	if !ok {
+		writeJSONError(w, "AssociationDoesNotExist", "The specified association does not exist.", http.StatusBadRequest)
+	}
+	return a
+}
+
+// createAssociation creates an association and, unless
+// ApplyOnlyAtCronInterval is set, applies it at once, as State Manager
+// does.
+func (s *Service) createAssociation(w http.ResponseWriter, params map[string]interface{}) {
+	name := getString(params, "Name")
+	targets, ok := parseTargets(params["Targets"])
+	if instanceID := getString(params, "InstanceId"); instanceID != "" {
+		targets = append(targets, target{key: "InstanceIds", values: []string{instanceID}})
+	}
+	if name == "" || !ok || len(targets) == 0 {
+		writeJSONError(w, "ValidationException", "Name and Targets or InstanceId are required", http.StatusBadRequest)
+		return
+	}
+	for _, t := range targets {
+		if t.key == "WindowTargetIds" {
+			writeJSONError(w, "InvalidTarget", "WindowTargetIds is not a valid association target", http.StatusBadRequest)
+			return
+		}
+	}
+	expr := getString(params, "ScheduleExpression")
+	var sched *schedule
+	if expr != "" {
+		var err error
+		if sched, err = parseSchedule(expr); err != nil {
+			writeJSONError(w, "InvalidSchedule", err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	parameters, _ := params["Parameters"].(map[string]interface{})
+	instances := s.managedInstances()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	a := &association{
+		id:              newRequestID(),
+		name:            name,
+		associationName: getString(params, "AssociationName"),
+		version:         1,
+		targets:         targets,
+		parameters:      parameters,
+		scheduleExpr:    expr,
+		sched:           sched,
+		applyOnlyAtCron: getBool(params, "ApplyOnlyAtCronInterval"),
+		created:         s.now(),
+	}
+	s.associations[a.id] = a
+	if !a.applyOnlyAtCron {
+		a.run(a.created, instances)
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"AssociationDescription": a.description()})
+}
+
+// run records an execution of the association at t on every instance its
+// targets select. Instances that are not running fail as undeliverable,
+// failing the execution. The caller must hold s.mu.
+func (a *association) run(at time.Time, instances map[string]managedInstance) {
+	ex := &associationExecution{id: newRequestID(), version: a.version, status: "Success", created: at}
+	for _, id := range resolve(a.targets, instances) {
+		t := &executionTarget{resourceID: id, status: "Success", detailedStatus: "Success"}
+		if !reachable(id, instances) {
+			t.status, t.detailedStatus = "Failed", "Undeliverable"
+			ex.status = "Failed"
+		}
+		ex.targets = append(ex.targets, t)
+	}
+	a.executions = append(a.executions, ex)
+}
+
+// counts returns how many of the execution's targets ended in each status.
+func (ex *associationExecution) counts() map[string]int {
+	counts := make(map[string]int)
+	for _, t := range ex.targets {
+		counts[t.status]++
+	}
+	return counts
+}
+
+func (a *association) description() map[string]interface{} {
+	d := map[string]interface{}{
+		"AssociationId":           a.id,
+		"AssociationVersion":      strconv.Itoa(a.version),
+		"Name":                    a.name,
+		"Targets":                 targetsResp(a.targets),
+		"Date":                    float64(a.created.Unix()),
+		"ApplyOnlyAtCronInterval": a.applyOnlyAtCron,
+	}
+	if a.associationName != "" {
+		d["AssociationName"] = a.associationName
+	}
+	if a.scheduleExpr != "" {
+		d["ScheduleExpression"] = a.scheduleExpr
+	}
+	if len(a.parameters) > 0 {
+		d["Parameters"] = a.parameters
+	}
+	overview := map[string]interface{}{"Status": "Pending", "DetailedStatus": "Creating"}
+	if n := len(a.executions); n > 0 {
+		last := a.executions[n-1]
+		overview = map[string]interface{}{
+			"Status":                           last.status,
+			"DetailedStatus":                   last.status,
+			"AssociationStatusAggregatedCount": last.counts(),
+		}
+		d["LastExecutionDate"] = float64(last.created.Unix())
+	}
+	d["Overview"] = overview
+	return d
+}
+
+func (s *Service) describeAssociation(w http.ResponseWriter, params map[string]interface{}) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	a := s.association(w, params)
+	if a == nil {
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"AssociationDescription": a.description()})
+}
+
+func (s *Service) deleteAssociation(w http.ResponseWriter, params map[string]interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	a := s.association(w, params)
+	if a == nil {
+		return
+	}
+	delete(s.associations, a.id)
+	writeJSON(w, http.StatusOK, map[string]interface{}{})
+}
+
+func (s *Service) listAssociations(w http.ResponseWriter, params map[string]interface{}) {
+	s.mu.RLock()
+	associations := make([]*association, 0, len(s.associations))
+	for _, a := range s.associations {
+		associations = append(associations, a)
+	}
+	sort.Slice(associations, func(i, j int) bool { return associations[i].id < associations[j].id })
+	page, next, err := paginate.Page(associations, func(i int) string { return associations[i].id }, getString(params, "NextToken"), getInt(params, "MaxResults", 50), 50)
+	if err != nil {
+		s.mu.RUnlock()
+		writeJSONError(w, "InvalidNextToken", "The specified token is not valid.", http.StatusBadRequest)
+		return
+	}
+	out := make([]map[string]interface{}, 0, len(page))
+	for _, a := range page {
+		d := a.description()
+		delete(d, "Parameters")
+		delete(d, "ApplyOnlyAtCronInterval")
+		out = append(out, d)
+	}
+	s.mu.RUnlock()
+
+	resp := map[string]interface{}{"Associations": out}
+	if next != "" {
+		resp["NextToken"] = next
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (s *Service) describeAssociationExecutions(w http.ResponseWriter, params map[string]interface{}) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	a := s.association(w, params)
+	if a == nil {
+		return
+	}
+	// Newest first.
+	executions := make([]*associationExecution, 0, len(a.executions))
+	for i := len(a.executions) - 1; i >= 0; i-- {
+		executions = append(executions, a.executions[i])
+	}
+	n := len(executions)
+	key := func(i int) string { return paginate.Desc(paginate.Seq(n - 1 - i)) }
+	page, next, err := paginate.Page(executions, key, getString(params, "NextToken"), getInt(params, "MaxResults", 50), 50)
+	if err != nil {
+		writeJSONError(w, "InvalidNextToken", "The specified token is not valid.", http.StatusBadRequest)
+		return
+	}
+	out := make([]map[string]interface{}, 0, len(page))
+	for _, ex := range page {
+		counts := ex.counts()
+		byStatus := make([]string, 0, len(counts))
+		for status, n := range counts {
+			byStatus = append(byStatus, status+"="+strconv.Itoa(n))
+		}
+		sort.Strings(byStatus)
+		out = append(out, map[string]interface{}{
+			"AssociationId":         a.id,
+			"AssociationVersion":    strconv.Itoa(ex.version),
+			"ExecutionId":           ex.id,
+			"Status":                ex.status,
+			"DetailedStatus":        ex.status,
+			"CreatedTime":           float64(ex.created.Unix()),
+			"LastExecutionDate":     float64(ex.created.Unix()),
+			"ResourceCountByStatus": "{" + strings.Join(byStatus, ", ") + "}",
+		})
+	}
+	resp := map[string]interface{}{"AssociationExecutions": out}
+	if next != "" {
+		resp["NextToken"] = next
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (s *Service) describeAssociationExecutionTargets(w http.ResponseWriter, params map[string]interface{}) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	a := s.association(w, params)
+	if a == nil {
+		return
+	}
+	id := getString(params, "ExecutionId")
+	var ex *associationExecution
+	for _, candidate := range a.executions {
+		if candidate.id == id {
+			ex = candidate
+		}
+	}
+	if ex == nil {
+		writeJSONError(w, "AssociationExecutionDoesNotExist", "The specified execution ID does not exist.", http.StatusBadRequest)
+		return
+	}
+	page, next, err := paginate.Page(ex.targets, paginate.Seq, getString(params, "NextToken"), getInt(params, "MaxResults", 50), 50)
+	if err != nil {
+		writeJSONError(w, "InvalidNextToken", "The specified token is not valid.", http.StatusBadRequest)
+		return
+	}
+	out := make([]map[string]interface{}, 0, len(page))
+	for _, t := range page {
+		out = append(out, map[string]interface{}{
+			"AssociationId":      a.id,
+			"AssociationVersion": strconv.Itoa(ex.version),
+			"ExecutionId":        ex.id,
+			"ResourceId":         t.resourceID,
+			"ResourceType":       "ManagedInstance",
+			"Status":             t.status,
+			"DetailedStatus":     t.detailedStatus,
+			"LastExecutionDate":  float64(ex.created.Unix()),
+		})
+	}
+	resp := map[string]interface{}{"AssociationExecutionTargets": out}
+	if next != "" {
+		resp["NextToken"] = next
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// runSchedules records the maintenance window executions and association
+// runs due between from and to on the mock clock.
+func (s *Service) runSchedules(from, to time.Time) {
+	instances := s.managedInstances()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, mw := range s.windows {
+		if !mw.enabled {
+			continue
+		}
+		for _, at := range mw.sched.occurrences(mw.created, from, to) {
+			mw.run(at, instances)
+		}
+	}
+	for _, a := range s.associations {
+		if a.sched == nil {
+			continue
+		}
+		for _, at := range a.sched.occurrences(a.created, from, to) {
+			a.run(at, instances)
+		}
+	}
+}
@@ -0,0 +1,261 @@
+package ssm
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/riyanimam/goto/internal/paginate"
+)
+
+type command struct {
+	id           string
+	documentName string
+	comment      string
+	instanceIDs  []string
+	targets      []target
+	parameters   map[string]interface{}
+	requested    time.Time
+	invocations  []*commandInvocation
+}
+
+type commandInvocation struct {
+	instanceID    string
+	status        string // Success or TimedOut
+	statusDetails string // Success or DeliveryTimedOut
+}
+
+// status is the command's overall status: Success if every invocation
+// succeeded, TimedOut otherwise.
+func (c *command) status() string {
+	for _, inv := range c.invocations {
+		if inv.status != "Success" {
+			return inv.status
+		}
+	}
+	return "Success"
+}
+
+// sendCommand runs a document on managed instances. Instances named by ID
+// must be online; instances selected by tag that are not online time out
+// undelivered. Documents are not executed: invocations on online
+// instances succeed with no output.
+func (s *Service) sendCommand(w http.ResponseWriter, params map[string]interface{}) {
+	document := getString(params, "DocumentName")
+	if document == "" {
+		writeJSONError(w, "ValidationException", "DocumentName is required", http.StatusBadRequest)
+		return
+	}
+	instanceIDs := stringValues(params["InstanceIds"])
+	targets, ok := parseTargets(params["Targets"])
+	if !ok || (len(instanceIDs) == 0) == (len(targets) == 0) {
+		writeJSONError(w, "ValidationException", "Exactly one of InstanceIds and Targets is required", http.StatusBadRequest)
+		return
+	}
+	instances := s.managedInstances()
+	for _, id := range instanceIDs {
+		if !reachable(id, instances) {
+			writeJSONError(w, "InvalidInstanceId", "Instances ["+id+"] not in a valid state for account "+defaultAccountID, http.StatusBadRequest)
+			return
+		}
+	}
+	parameters, _ := params["Parameters"].(map[string]interface{})
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c := &command{
+		id:           newRequestID(),
+		documentName: document,
+		comment:      getString(params, "Comment"),
+		instanceIDs:  instanceIDs,
+		targets:      targets,
+		parameters:   parameters,
+		requested:    s.now(),
+	}
+	selected := targets
+	if len(instanceIDs) > 0 {
+		selected = []target{{key: "InstanceIds", values: instanceIDs}}
+	}
+	for _, id := range resolve(selected, instances) {
+		inv := &commandInvocation{instanceID: id, status: "Success", statusDetails: "Success"}
+		if !reachable(id, instances) {
+			inv.status, inv.statusDetails = "TimedOut", "DeliveryTimedOut"
+		}
+		c.invocations = append(c.invocations, inv)
+	}
+	s.commands = append(s.commands, c)
+	writeJSON(w, http.StatusOK, map[string]interface{}{"Command": c.resp()})
+}
+
+func (c *command) resp() map[string]interface{} {
+	counts := map[string]int{}
+	for _, inv := range c.invocations {
+		counts[inv.status]++
+	}
+	instanceIDs := c.instanceIDs
+	if instanceIDs == nil {
+		instanceIDs = []string{}
+	}
+	return map[string]interface{}{
+		"CommandId":             c.id,
+		"DocumentName":          c.documentName,
+		"Comment":               c.comment,
+		"InstanceIds":           instanceIDs,
+		"Targets":               targetsResp(c.targets),
+		"Parameters":            c.parameters,
+		"RequestedDateTime":     float64(c.requested.Unix()),
+		"Status":                c.status(),
+		"StatusDetails":         c.status(),
+		"TargetCount":           len(c.invocations),
+		"CompletedCount":        len(c.invocations),
+		"ErrorCount":            0,
+		"DeliveryTimedOutCount": counts["TimedOut"],
+	}
+}
+
+func (s *Service) listCommands(w http.ResponseWriter, params map[string]interface{}) {
+	id := getString(params, "CommandId")
+	instanceID := getString(params, "InstanceId")
+
+	s.mu.RLock()
+	var commands []*command
+	var positions []int
+	// Newest first. Commands are never removed, so each is keyed by its
+	// position among them.
+	for i := len(s.commands) - 1; i >= 0; i-- {
+		c := s.commands[i]
+		if id != "" && c.id != id {
+			continue
+		}
+		if instanceID != "" && c.invocation(instanceID) == nil {
+			continue
+		}
+		commands = append(commands, c)
+		positions = append(positions, i)
+	}
+	if id != "" && len(commands) == 0 {
+		s.mu.RUnlock()
+		writeJSONError(w, "InvalidCommandId", "Command "+id+" does not exist.", http.StatusBadRequest)
+		return
+	}
+	key := func(i int) string { return paginate.Desc(paginate.Seq(positions[i])) }
+	page, next, err := paginate.Page(commands, key, getString(params, "NextToken"), getInt(params, "MaxResults", 50), 50)
+	if err != nil {
+		s.mu.RUnlock()
+		writeJSONError(w, "InvalidNextToken", "The specified token is not valid.", http.StatusBadRequest)
+		return
+	}
+	out := make([]map[string]interface{}, 0, len(page))
+	for _, c := range page {
+		out = append(out, c.resp())
+	}
+	s.mu.RUnlock()
+
+	resp := map[string]interface{}{"Commands": out}
+	if next != "" {
+		resp["NextToken"] = next
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (c *command) invocation(instanceID string) *commandInvocation {
+	for _, inv := range c.invocations {
+		if inv.instanceID == instanceID {
+			return inv
+		}
+	}
+	return nil
+}
+
+func (s *Service) listCommandInvocations(w http.ResponseWriter, params map[string]interface{}) {
+	id := getString(params, "CommandId")
+	instanceID := getString(params, "InstanceId")
+	details := getBool(params, "Details")
+
+	s.mu.RLock()
+	type entry struct {
+		c   *command
+		inv *commandInvocation
+		key string
+	}
+	var entries []entry
+	for i := len(s.commands) - 1; i >= 0; i-- {
+		c := s.commands[i]
+		if id != "" && c.id != id {
+			continue
+		}
+		for j, inv := range c.invocations {
+			if instanceID == "" || inv.instanceID == instanceID {
+				entries = append(entries, entry{c, inv, paginate.Join(paginate.Desc(paginate.Seq(i)), paginate.Seq(j))})
+			}
+		}
+	}
+	page, next, err := paginate.Page(entries, func(i int) string { return entries[i].key }, getString(params, "NextToken"), getInt(params, "MaxResults", 50), 50)
+	if err != nil {
+		s.mu.RUnlock()
+		writeJSONError(w, "InvalidNextToken", "The specified token is not valid.", http.StatusBadRequest)
+		return
+	}
+	out := make([]map[string]interface{}, 0, len(page))
+	for _, e := range page {
+		resp := map[string]interface{}{
+			"CommandId":         e.c.id,
+			"InstanceId":        e.inv.instanceID,
+			"DocumentName":      e.c.documentName,
+			"Comment":           e.c.comment,
+			"RequestedDateTime": float64(e.c.requested.Unix()),
+			"Status":            e.inv.status,
+			"StatusDetails":     e.inv.statusDetails,
+		}
+		if details {
+			resp["CommandPlugins"] = []map[string]interface{}{{
+				"Name":          "aws:runShellScript",
+				"Status":        e.inv.status,
+				"StatusDetails": e.inv.statusDetails,
+				"Output":        "",
+			}}
+		}
+		out = append(out, resp)
+	}
+	s.mu.RUnlock()
+
+	resp := map[string]interface{}{"CommandInvocations": out}
+	if next != "" {
+		resp["NextToken"] = next
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (s *Service) getCommandInvocation(w http.ResponseWriter, params map[string]interface{}) {
+	id := getString(params, "CommandId")
+	instanceID := getString(params, "InstanceId")
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, c := range s.commands {
+		if c.id != id {
+			continue
+		}
+		inv := c.invocation(instanceID)
+		if inv == nil {
+			break
+		}
+		responseCode := 0
+		if inv.status != "Success" {
+			responseCode = -1
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"CommandId":             c.id,
+			"InstanceId":            inv.instanceID,
+			"DocumentName":          c.documentName,
+			"Comment":               c.comment,
+			"PluginName":            "aws:runShellScript",
+			"ResponseCode":          responseCode,
+			"Status":                inv.status,
+			"StatusDetails":         inv.statusDetails,
+			"StandardOutputContent": "",
+			"StandardErrorContent":  "",
+		})
+		return
+	}
+	writeJSONError(w, "InvocationDoesNotExist", "The command "+id+" was not invoked on instance "+instanceID+".", http.StatusBadRequest)
+}
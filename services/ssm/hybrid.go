@@ -0,0 +1,366 @@
+package ssm
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	h "github.com/riyanimam/goto/internal/mockhelpers"
+	"github.com/riyanimam/goto/internal/paginate"
+	"github.com/riyanimam/goto/internal/tags"
+)
+
+// agentVersion is the SSM Agent version managed instances report.
+const agentVersion = "3.3.40.0"
+
+type activation struct {
+	id                  string
+	code                string
+	description         string
+	defaultInstanceName string
+	iamRole             string
+	limit               int
+	registered          int
+	expiration          time.Time
+	created             time.Time
+	tags                map[string]string
+}
+
+// ManagedInstance describes a hybrid machine registering with an
+// activation, as the SSM Agent on it reports itself.
+type ManagedInstance struct {
+	ComputerName    string
+	IPAddress       string
+	PlatformType    string // Linux, Windows, or MacOS; defaults to Linux
+	PlatformName    string
+	PlatformVersion string
+}
+
+type managedNode struct {
+	ManagedInstance
+	id           string
+	name         string
+	activationID string
+	iamRole      string
+	pingStatus   string // Online, ConnectionLost, or Inactive
+	registered   time.Time
+}
+
+func managedInstanceARN(id string) string {
+	return fmt.Sprintf("arn:aws:ssm:us-east-1:%s:managed-instance/%s", defaultAccountID, id)
+}
+
+func (s *Service) createActivation(w http.ResponseWriter, params map[string]interface{}) {
+	role := getString(params, "IamRole")
+	if role == "" {
+		writeJSONError(w, "ValidationException", "IamRole is required", http.StatusBadRequest)
+		return
+	}
+	limit := getInt(params, "RegistrationLimit", 1)
+	if limit < 1 || limit > 1000 {
+		writeJSONError(w, "ValidationException", "RegistrationLimit must be between 1 and 1000", http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	expiration := now.Add(24 * time.Hour)
+	if v, ok := params["ExpirationDate"].(float64); ok {
+		expiration = time.Unix(int64(v), 0).UTC()
+		if !expiration.After(now) || expiration.After(now.Add(30*24*time.Hour)) {
+			writeJSONError(w, "InvalidParameters", "ExpirationDate must be in the next 30 days.", http.StatusBadRequest)
+			return
+		}
+	}
+	a := &activation{
+		id:                  newRequestID(),
+		code:                h.RandomID(20),
+		description:         getString(params, "Description"),
+		defaultInstanceName: getString(params, "DefaultInstanceName"),
+		iamRole:             role,
+		limit:               limit,
+		expiration:          expiration,
+		created:             now,
+		tags:                tags.FromList(params["Tags"], "Key", "Value"),
+	}
+	s.activations[a.id] = a
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"ActivationId":   a.id,
+		"ActivationCode": a.code,
+	})
+}
+
+func (s *Service) deleteActivation(w http.ResponseWriter, params map[string]interface{}) {
+	id := getString(params, "ActivationId")
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.activations[id]; !ok {
+		writeJSONError(w, "InvalidActivationId", "The activation ID "+id+" is not valid.", http.StatusBadRequest)
+		return
+	}
+	delete(s.activations, id)
+	writeJSON(w, http.StatusOK, map[string]interface{}{})
+}
+
+func (s *Service) describeActivations(w http.ResponseWriter, params map[string]interface{}) {
+	type filter struct {
+		key    string
+		values []string
+	}
+	var filters []filter
+	rawFilters, _ := params["Filters"].([]interface{})
+	for _, raw := range rawFilters {
+		f, _ := raw.(map[string]interface{})
+		key := getString(f, "FilterKey")
+		switch key {
+		case "ActivationIds", "DefaultInstanceName", "IamRole":
+		default:
+			writeJSONError(w, "InvalidFilter", "The filter key "+key+" is not valid.", http.StatusBadRequest)
+			return
+		}
+		filters = append(filters, filter{key, stringValues(f["FilterValues"])})
+	}
+
+	s.mu.RLock()
+	now := s.now()
+	var activations []*activation
+	for _, a := range s.activations {
+		keep := true
+		for _, f := range filters {
+			field := map[string]string{"ActivationIds": a.id, "DefaultInstanceName": a.defaultInstanceName, "IamRole": a.iamRole}[f.key]
+			keep = keep && contains(f.values, field)
+		}
+		if keep {
+			activations = append(activations, a)
+		}
+	}
+	key := func(i int) string { return paginate.Join(paginate.Time(activations[i].created), activations[i].id) }
+	sort.Slice(activations, func(i, j int) bool { return key(i) < key(j) })
+	page, next, err := paginate.Page(activations, key, getString(params, "NextToken"), getInt(params, "MaxResults", 50), 50)
+	if err != nil {
+		s.mu.RUnlock()
+		writeJSONError(w, "InvalidNextToken", "The specified token is not valid.", http.StatusBadRequest)
+		return
+	}
+	out := make([]map[string]interface{}, 0, len(page))
+	for _, a := range page {
+		out = append(out, map[string]interface{}{
+			"ActivationId":        a.id,
+			"Description":         a.description,
+			"DefaultInstanceName": a.defaultInstanceName,
+			"IamRole":             a.iamRole,
+			"RegistrationLimit":   a.limit,
+			"RegistrationsCount":  a.registered,
+			"ExpirationDate":      float64(a.expiration.Unix()),
+			"Expired":             !now.Before(a.expiration),
+			"CreatedDate":         float64(a.created.Unix()),
+			"Tags":                tags.ToList(a.tags, "Key", "Value"),
+		})
+	}
+	s.mu.RUnlock()
+
+	resp := map[string]interface{}{"ActivationList": out}
+	if next != "" {
+		resp["NextToken"] = next
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// RegisterManagedInstance registers a machine with the activation, as the
+// SSM Agent does when it is installed with the activation's ID and code,
+// and returns the new managed instance's mi-* ID. The instance is online
+// until its status is set otherwise, and carries the activation's tags.
+func (s *Service) RegisterManagedInstance(activationID, activationCode string, inst ManagedInstance) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	a, ok := s.activations[activationID]
+	if !ok || a.code != activationCode {
+		return "", fmt.Errorf("activation %s does not exist or its code does not match", activationID)
+	}
+	if !s.now().Before(a.expiration) {
+		return "", fmt.Errorf("activation %s has expired", activationID)
+	}
+	if a.registered >= a.limit {
+		return "", fmt.Errorf("activation %s has reached its registration limit of %d", activationID, a.limit)
+	}
+	if inst.PlatformType == "" {
+		inst.PlatformType = "Linux"
+	}
+	a.registered++
+	node := &managedNode{
+		ManagedInstance: inst,
+		id:              "mi-" + h.RandomHex(17),
+		name:            a.defaultInstanceName,
+		activationID:    a.id,
+		iamRole:         a.iamRole,
+		pingStatus:      "Online",
+		registered:      s.now(),
+	}
+	s.nodes[node.id] = node
+	s.tags.Tag(managedInstanceARN(node.id), a.tags)
+	return node.id, nil
+}
+
+// SetStatus sets the ping status of the managed instance arn names:
+// Online, ConnectionLost, or Inactive. Commands, associations, and
+// maintenance window tasks cannot be delivered to an instance that is not
+// online.
+func (s *Service) SetStatus(arn, status string) error {
+	id := strings.TrimPrefix(arn, managedInstanceARN(""))
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	node, ok := s.nodes[id]
+	if !ok || managedInstanceARN(id) != arn {
+		return fmt.Errorf("managed instance %s does not exist", arn)
+	}
+	switch status {
+	case "Online", "ConnectionLost", "Inactive":
+	default:
+		return fmt.Errorf("managed instance status must be Online, ConnectionLost, or Inactive")
+	}
+	node.pingStatus = status
+	return nil
+}
+
+func (s *Service) deregisterManagedInstance(w http.ResponseWriter, params map[string]interface{}) {
+	id := getString(params, "InstanceId")
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.nodes[id]; !ok {
+		writeJSONError(w, "InvalidInstanceId", "Instance Id "+id+" is not a registered managed instance.", http.StatusBadRequest)
+		return
+	}
+	delete(s.nodes, id)
+	s.tags.Delete(managedInstanceARN(id))
+	writeJSON(w, http.StatusOK, map[string]interface{}{})
+}
+
+// infoFields maps DescribeInstanceInformation filter keys to the fields of
+// the instance information they match.
+var infoFields = map[string]string{
+	"PingStatus":    "PingStatus",
+	"PlatformTypes": "PlatformType",
+	"ActivationIds": "ActivationId",
+	"IamRole":       "IamRole",
+	"ResourceType":  "ResourceType",
+	"AgentVersion":  "AgentVersion",
+}
+
+// describeInstanceInformation lists the managed instances: the EC2 mock's
+// instances that have not been terminated, online while running, and the
+// registered hybrid instances.
+func (s *Service) describeInstanceInformation(w http.ResponseWriter, params map[string]interface{}) {
+	type filter struct {
+		key    string
+		values []string
+	}
+	var filters []filter
+	legacy, _ := params["InstanceInformationFilterList"].([]interface{})
+	for _, raw := range legacy {
+		f, _ := raw.(map[string]interface{})
+		filters = append(filters, filter{getString(f, "key"), stringValues(f["valueSet"])})
+	}
+	current, _ := params["Filters"].([]interface{})
+	for _, raw := range current {
+		f, _ := raw.(map[string]interface{})
+		filters = append(filters, filter{getString(f, "Key"), stringValues(f["Values"])})
+	}
+	for _, f := range filters {
+		if f.key != "InstanceIds" && infoFields[f.key] == "" && f.key != "tag-key" && !strings.HasPrefix(f.key, "tag:") {
+			writeJSONError(w, "InvalidInstanceInformationFilterValue", "The filter key "+f.key+" is not valid.", http.StatusBadRequest)
+			return
+		}
+	}
+
+	instances := s.managedInstances()
+	ids := make([]string, 0, len(instances))
+	for id := range instances {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	s.mu.RLock()
+	var out []map[string]interface{}
+	for _, id := range ids {
+		inst := instances[id]
+		info := map[string]interface{}{
+			"InstanceId":       id,
+			"PingStatus":       inst.pingStatus,
+			"LastPingDateTime": float64(s.now().Unix()),
+			"AgentVersion":     agentVersion,
+			"IsLatestVersion":  true,
+			"PlatformType":     "Linux",
+			"PlatformName":     "Amazon Linux",
+			"PlatformVersion":  "2023",
+			"ResourceType":     "EC2Instance",
+		}
+		if node, ok := s.nodes[id]; ok {
+			info["ResourceType"] = "ManagedInstance"
+			info["PlatformType"] = node.PlatformType
+			info["PlatformName"] = node.PlatformName
+			info["PlatformVersion"] = node.PlatformVersion
+			info["ComputerName"] = node.ComputerName
+			info["IPAddress"] = node.IPAddress
+			info["ActivationId"] = node.activationID
+			info["IamRole"] = node.iamRole
+			info["RegistrationDate"] = float64(node.registered.Unix())
+			if node.name != "" {
+				info["Name"] = node.name
+			}
+		}
+		keep := true
+		for _, f := range filters {
+			switch {
+			case f.key == "InstanceIds":
+				keep = keep && contains(f.values, id)
+			case infoFields[f.key] != "":
+				value, _ := info[infoFields[f.key]].(string)
+				keep = keep && contains(f.values, value)
+			default:
+				keep = keep && len(resolve([]target{{key: f.key, values: f.values}}, map[string]managedInstance{id: inst})) > 0
+			}
+		}
+		if keep {
+			out = append(out, info)
+		}
+	}
+	s.mu.RUnlock()
+
+	page, next, err := paginate.Page(out, func(i int) string { return out[i]["InstanceId"].(string) }, getString(params, "NextToken"), getInt(params, "MaxResults", 50), 50)
+	if err != nil {
+		writeJSONError(w, "InvalidNextToken", "The specified token is not valid.", http.StatusBadRequest)
+		return
+	}
+	if page == nil {
+		page = []map[string]interface{}{}
+	}
+	resp := map[string]interface{}{"InstanceInformationList": page}
+	if next != "" {
+		resp["NextToken"] = next
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// stringValues returns the strings in a JSON list.
+func stringValues(v interface{}) []string {
+	list, _ := v.([]interface{})
+	out := make([]string, 0, len(list))
+	for _, item := range list {
+		if str, ok := item.(string); ok {
+			out = append(out, str)
+		}
+	}
+	return out
+}
+
+func contains(values []string, v string) bool {
+	for _, candidate := range values {
+		if candidate == v {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,574 @@
+package ssm
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"time"
+
+	h "github.com/riyanimam/goto/internal/mockhelpers"
+	"github.com/riyanimam/goto/internal/paginate"
+)
+
+type maintenanceWindow struct {
+	id                       string
+	name                     string
+	description              string
+	schedule                 string
+	sched                    *schedule
+	duration                 int
+	cutoff                   int
+	allowUnassociatedTargets bool
+	enabled                  bool
+	created                  time.Time
+	targets                  map[string]*windowTarget
+	tasks                    map[string]*windowTask
+	executions               []*windowExecution // oldest first
+}
+
+type windowTarget struct {
+	id               string
+	name             string
+	resourceType     string
+	ownerInformation string
+	targets          []target
+}
+
+type windowTask struct {
+	id             string
+	name           string
+	taskArn        string
+	taskType       string
+	priority       int
+	maxConcurrency string
+	maxErrors      string
+	targets        []target
+}
+
+type windowExecution struct {
+	id     string
+	status string
+	start  time.Time
+	end    time.Time
+	tasks  []*taskExecution
+}
+
+type taskExecution struct {
+	id          string
+	task        *windowTask
+	status      string
+	start       time.Time
+	end         time.Time
+	invocations []*taskInvocation
+}
+
+type taskInvocation struct {
+	id            string
+	executionID   string
+	windowTarget  string
+	instanceID    string
+	status        string
+	statusDetails string
+}
+
+// window returns the maintenance window named by the WindowId parameter,
+// writing DoesNotExistException if there is none. The caller must hold
+// s.mu.
+func (s *Service) window(w http.ResponseWriter, params map[string]interface{}) *maintenanceWindow {
+	id := getString(params, "WindowId")
+	mw, ok := s.windows[id]
+	if !ok {
+		writeJSONError(w, "DoesNotExistException", "Maintenance window "+id+" does not exist", http.StatusBadRequest)
+	}
+	return mw
+}
+
+func (s *Service) createMaintenanceWindow(w http.ResponseWriter, params map[string]interface{}) {
+	name := getString(params, "Name")
+	expr := getString(params, "Schedule")
+	duration := getInt(params, "Duration", 0)
+	cutoff := getInt(params, "Cutoff", -1)
+	if name == "" || expr == "" || duration < 1 || duration > 24 || cutoff < 0 || cutoff >= duration {
+		writeJSONError(w, "ValidationException", "Name, Schedule, Duration between 1 and 24, and a Cutoff less than Duration are required", http.StatusBadRequest)
+		return
+	}
+	sched, err := parseSchedule(expr)
+	if err != nil {
+		writeJSONError(w, "ValidationException", err.Error(), http.StatusBadRequest)
+		return
+	}
+	enabled := true
+	if v, ok := params["Enabled"].(bool); ok {
+		enabled = v
+	}
+
+	s.mu.Lock()
+	mw := &maintenanceWindow{
+		id:                       "mw-" + h.RandomHex(17),
+		name:                     name,
+		description:              getString(params, "Description"),
+		schedule:                 expr,
+		sched:                    sched,
+		duration:                 duration,
+		cutoff:                   cutoff,
+		allowUnassociatedTargets: getBool(params, "AllowUnassociatedTargets"),
+		enabled:                  enabled,
+		created:                  s.now(),
+		targets:                  make(map[string]*windowTarget),
+		tasks:                    make(map[string]*windowTask),
+	}
+	s.windows[mw.id] = mw
+	s.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{"WindowId": mw.id})
+}
+
+func (s *Service) getMaintenanceWindow(w http.ResponseWriter, params map[string]interface{}) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	mw := s.window(w, params)
+	if mw == nil {
+		return
+	}
+	resp := mw.identity()
+	resp["AllowUnassociatedTargets"] = mw.allowUnassociatedTargets
+	resp["CreatedDate"] = float64(mw.created.Unix())
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (mw *maintenanceWindow) identity() map[string]interface{} {
+	resp := map[string]interface{}{
+		"WindowId": mw.id,
+		"Name":     mw.name,
+		"Schedule": mw.schedule,
+		"Duration": mw.duration,
+		"Cutoff":   mw.cutoff,
+		"Enabled":  mw.enabled,
+	}
+	if mw.description != "" {
+		resp["Description"] = mw.description
+	}
+	return resp
+}
+
+func (s *Service) updateMaintenanceWindow(w http.ResponseWriter, params map[string]interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	mw := s.window(w, params)
+	if mw == nil {
+		return
+	}
+	if expr := getString(params, "Schedule"); expr != "" {
+		sched, err := parseSchedule(expr)
+		if err != nil {
+			writeJSONError(w, "ValidationException", err.Error(), http.StatusBadRequest)
+			return
+		}
+		mw.schedule, mw.sched = expr, sched
+	}
+	if v := getString(params, "Name"); v != "" {
+		mw.name = v
+	}
+	if _, ok := params["Description"]; ok {
+		mw.description = getString(params, "Description")
+	}
+	mw.duration = getInt(params, "Duration", mw.duration)
+	mw.cutoff = getInt(params, "Cutoff", mw.cutoff)
+	if v, ok := params["Enabled"].(bool); ok {
+		mw.enabled = v
+	}
+	if v, ok := params["AllowUnassociatedTargets"].(bool); ok {
+		mw.allowUnassociatedTargets = v
+	}
+	writeJSON(w, http.StatusOK, mw.identity())
+}
+
+func (s *Service) deleteMaintenanceWindow(w http.ResponseWriter, params map[string]interface{}) {
+	id := getString(params, "WindowId")
+	s.mu.Lock()
+	delete(s.windows, id)
+	s.mu.Unlock()
+	writeJSON(w, http.StatusOK, map[string]interface{}{"WindowId": id})
+}
+
+func (s *Service) describeMaintenanceWindows(w http.ResponseWriter, params map[string]interface{}) {
+	s.mu.RLock()
+	windows := make([]*maintenanceWindow, 0, len(s.windows))
+	for _, mw := range s.windows {
+		windows = append(windows, mw)
+	}
+	sort.Slice(windows, func(i, j int) bool { return windows[i].id < windows[j].id })
+	page, next, err := paginate.Page(windows, func(i int) string { return windows[i].id }, getString(params, "NextToken"), getInt(params, "MaxResults", 50), 100)
+	if err != nil {
+		s.mu.RUnlock()
+		writeJSONError(w, "InvalidNextToken", "The specified token is not valid.", http.StatusBadRequest)
+		return
+	}
+	out := make([]map[string]interface{}, 0, len(page))
+	for _, mw := range page {
+		out = append(out, mw.identity())
+	}
+	s.mu.RUnlock()
+
+	resp := map[string]interface{}{"WindowIdentities": out}
+	if next != "" {
+		resp["NextToken"] = next
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (s *Service) registerTargetWithMaintenanceWindow(w http.ResponseWriter, params map[string]interface{}) {
+	targets, ok := parseTargets(params["Targets"])
+	resourceType := getString(params, "ResourceType")
+	if !ok || len(targets) == 0 || resourceType != "INSTANCE" {
+		writeJSONError(w, "ValidationException", "ResourceType INSTANCE and Targets keyed by InstanceIds or tags are required", http.StatusBadRequest)
+		return
+	}
+	for _, t := range targets {
+		if t.key == "WindowTargetIds" {
+			writeJSONError(w, "ValidationException", "Window targets cannot select other window targets", http.StatusBadRequest)
+			return
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	mw := s.window(w, params)
+	if mw == nil {
+		return
+	}
+	wt := &windowTarget{
+		id:               newRequestID(),
+		name:             getString(params, "Name"),
+		resourceType:     resourceType,
+		ownerInformation: getString(params, "OwnerInformation"),
+		targets:          targets,
+	}
+	mw.targets[wt.id] = wt
+	writeJSON(w, http.StatusOK, map[string]interface{}{"WindowTargetId": wt.id})
+}
+
+func (s *Service) deregisterTargetFromMaintenanceWindow(w http.ResponseWriter, params map[string]interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	mw := s.window(w, params)
+	if mw == nil {
+		return
+	}
+	id := getString(params, "WindowTargetId")
+	if _, ok := mw.targets[id]; !ok {
+		writeJSONError(w, "DoesNotExistException", "Maintenance window target "+id+" does not exist", http.StatusBadRequest)
+		return
+	}
+	delete(mw.targets, id)
+	writeJSON(w, http.StatusOK, map[string]interface{}{"WindowId": mw.id, "WindowTargetId": id})
+}
+
+func (s *Service) describeMaintenanceWindowTargets(w http.ResponseWriter, params map[string]interface{}) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	mw := s.window(w, params)
+	if mw == nil {
+		return
+	}
+	targets := make([]*windowTarget, 0, len(mw.targets))
+	for _, wt := range mw.targets {
+		targets = append(targets, wt)
+	}
+	sort.Slice(targets, func(i, j int) bool { return targets[i].id < targets[j].id })
+	out := make([]map[string]interface{}, 0, len(targets))
+	for _, wt := range targets {
+		out = append(out, map[string]interface{}{
+			"WindowId":         mw.id,
+			"WindowTargetId":   wt.id,
+			"Name":             wt.name,
+			"ResourceType":     wt.resourceType,
+			"OwnerInformation": wt.ownerInformation,
+			"Targets":          targetsResp(wt.targets),
+		})
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"Targets": out})
+}
+
+func (s *Service) registerTaskWithMaintenanceWindow(w http.ResponseWriter, params map[string]interface{}) {
+	taskArn := getString(params, "TaskArn")
+	taskType := getString(params, "TaskType")
+	targets, ok := parseTargets(params["Targets"])
+	if !ok || taskArn == "" {
+		writeJSONError(w, "ValidationException", "TaskArn and Targets keyed by WindowTargetIds or InstanceIds are required", http.StatusBadRequest)
+		return
+	}
+	switch taskType {
+	case "RUN_COMMAND", "AUTOMATION", "LAMBDA", "STEP_FUNCTIONS":
+	default:
+		writeJSONError(w, "ValidationException", "TaskType must be RUN_COMMAND, AUTOMATION, LAMBDA, or STEP_FUNCTIONS", http.StatusBadRequest)
+		return
+	}
+	for _, t := range targets {
+		if t.key != "WindowTargetIds" && t.key != "InstanceIds" {
+			writeJSONError(w, "ValidationException", "Task targets must be keyed by WindowTargetIds or InstanceIds", http.StatusBadRequest)
+			return
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	mw := s.window(w, params)
+	if mw == nil {
+		return
+	}
+	for _, t := range targets {
+		if t.key == "InstanceIds" && !mw.allowUnassociatedTargets {
+			writeJSONError(w, "ValidationException", "Maintenance window "+mw.id+" does not allow unregistered targets", http.StatusBadRequest)
+			return
+		}
+		if t.key != "WindowTargetIds" {
+			continue
+		}
+		for _, id := range t.values {
+			if _, ok := mw.targets[id]; !ok {
+				writeJSONError(w, "DoesNotExistException", "Maintenance window target "+id+" does not exist", http.StatusBadRequest)
+				return
+			}
+		}
+	}
+	task := &windowTask{
+		id:             newRequestID(),
+		name:           getString(params, "Name"),
+		taskArn:        taskArn,
+		taskType:       taskType,
+		priority:       getInt(params, "Priority", 1),
+		maxConcurrency: getString(params, "MaxConcurrency"),
+		maxErrors:      getString(params, "MaxErrors"),
+		targets:        targets,
+	}
+	mw.tasks[task.id] = task
+	writeJSON(w, http.StatusOK, map[string]interface{}{"WindowTaskId": task.id})
+}
+
+func (s *Service) deregisterTaskFromMaintenanceWindow(w http.ResponseWriter, params map[string]interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	mw := s.window(w, params)
+	if mw == nil {
+		return
+	}
+	id := getString(params, "WindowTaskId")
+	if _, ok := mw.tasks[id]; !ok {
+		writeJSONError(w, "DoesNotExistException", "Maintenance window task "+id+" does not exist", http.StatusBadRequest)
+		return
+	}
+	delete(mw.tasks, id)
+	writeJSON(w, http.StatusOK, map[string]interface{}{"WindowId": mw.id, "WindowTaskId": id})
+}
+
+func (s *Service) describeMaintenanceWindowTasks(w http.ResponseWriter, params map[string]interface{}) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	mw := s.window(w, params)
+	if mw == nil {
+		return
+	}
+	out := make([]map[string]interface{}, 0, len(mw.tasks))
+	for _, task := range mw.sortedTasks() {
+		out = append(out, map[string]interface{}{
+			"WindowId":       mw.id,
+			"WindowTaskId":   task.id,
+			"Name":           task.name,
+			"TaskArn":        task.taskArn,
+			"Type":           task.taskType,
+			"Priority":       task.priority,
+			"MaxConcurrency": task.maxConcurrency,
+			"MaxErrors":      task.maxErrors,
+			"Targets":        targetsResp(task.targets),
+		})
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"Tasks": out})
+}
+
+// sortedTasks returns the window's tasks in the order they run: by
+// priority, lowest first.
+func (mw *maintenanceWindow) sortedTasks() []*windowTask {
+	tasks := make([]*windowTask, 0, len(mw.tasks))
+	for _, task := range mw.tasks {
+		tasks = append(tasks, task)
+	}
+	sort.Slice(tasks, func(i, j int) bool {
+		if tasks[i].priority != tasks[j].priority {
+			return tasks[i].priority < tasks[j].priority
+		}
+		return tasks[i].id < tasks[j].id
+	})
+	return tasks
+}
+
+// run records an execution of the window at t. Each task runs once
+// per instance its targets select; a run on an instance that is not
+// running fails as undeliverable, failing its task and the execution.
+// Windows without tasks record nothing. The caller must hold s.mu.
+func (mw *maintenanceWindow) run(at time.Time, instances map[string]managedInstance) {
+	tasks := mw.sortedTasks()
+	if len(tasks) == 0 {
+		return
+	}
+	ex := &windowExecution{id: newRequestID(), status: "SUCCESS", start: at, end: at}
+	for _, task := range tasks {
+		te := &taskExecution{id: newRequestID(), task: task, status: "SUCCESS", start: at, end: at}
+		commandID := newRequestID()
+		run := func(selected []target, windowTarget string) {
+			for _, id := range resolve(selected, instances) {
+				inv := &taskInvocation{
+					id:           newRequestID(),
+					executionID:  commandID,
+					windowTarget: windowTarget,
+					instanceID:   id,
+					status:       "SUCCESS",
+				}
+				if !reachable(id, instances) {
+					inv.status, inv.statusDetails = "FAILED", "Undeliverable"
+					te.status, ex.status = "FAILED", "FAILED"
+				}
+				te.invocations = append(te.invocations, inv)
+			}
+		}
+		for _, t := range task.targets {
+			if t.key == "InstanceIds" {
+				run([]target{t}, "")
+				continue
+			}
+			for _, id := range t.values {
+				if wt, ok := mw.targets[id]; ok {
+					run(wt.targets, id)
+				}
+			}
+		}
+		ex.tasks = append(ex.tasks, te)
+	}
+	mw.executions = append(mw.executions, ex)
+}
+
+func (s *Service) describeMaintenanceWindowExecutions(w http.ResponseWriter, params map[string]interface{}) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	mw := s.window(w, params)
+	if mw == nil {
+		return
+	}
+	// Newest first.
+	executions := make([]*windowExecution, 0, len(mw.executions))
+	for i := len(mw.executions) - 1; i >= 0; i-- {
+		executions = append(executions, mw.executions[i])
+	}
+	n := len(executions)
+	key := func(i int) string { return paginate.Desc(paginate.Seq(n - 1 - i)) }
+	page, next, err := paginate.Page(executions, key, getString(params, "NextToken"), getInt(params, "MaxResults", 50), 100)
+	if err != nil {
+		writeJSONError(w, "InvalidNextToken", "The specified token is not valid.", http.StatusBadRequest)
+		return
+	}
+	out := make([]map[string]interface{}, 0, len(page))
+	for _, ex := range page {
+		out = append(out, map[string]interface{}{
+			"WindowId":          mw.id,
+			"WindowExecutionId": ex.id,
+			"Status":            ex.status,
+			"StartTime":         float64(ex.start.Unix()),
+			"EndTime":           float64(ex.end.Unix()),
+		})
+	}
+	resp := map[string]interface{}{"WindowExecutions": out}
+	if next != "" {
+		resp["NextToken"] = next
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// windowExecution returns the execution named by the WindowExecutionId
+// parameter, writing DoesNotExistException if there is none. The caller
+// must hold s.mu.
+func (s *Service) windowExecution(w http.ResponseWriter, params map[string]interface{}) (*maintenanceWindow, *windowExecution) {
+	id := getString(params, "WindowExecutionId")
+	for _, mw := range s.windows {
+		for _, ex := range mw.executions {
+			if ex.id == id {
+				return mw, ex
+			}
+		}
+	}
+	writeJSONError(w, "DoesNotExistException", "Maintenance window execution "+id+" does not exist", http.StatusBadRequest)
+	return nil, nil
+}
+
+func (s *Service) describeMaintenanceWindowExecutionTasks(w http.ResponseWriter, params map[string]interface{}) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	_, ex := s.windowExecution(w, params)
+	if ex == nil {
+		return
+	}
+	out := make([]map[string]interface{}, 0, len(ex.tasks))
+	for _, te := range ex.tasks {
+		out = append(out, map[string]interface{}{
+			"WindowExecutionId": ex.id,
+			"TaskExecutionId":   te.id,
+			"TaskArn":           te.task.taskArn,
+			"TaskType":          te.task.taskType,
+			"Status":            te.status,
+			"StartTime":         float64(te.start.Unix()),
+			"EndTime":           float64(te.end.Unix()),
+		})
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"WindowExecutionTaskIdentities": out})
+}
+
+// describeMaintenanceWindowExecutionTaskInvocations lists a task
+// execution's runs, one per instance. The instance each ran on is in its
+// Parameters, as the instanceIds of the command.
+func (s *Service) describeMaintenanceWindowExecutionTaskInvocations(w http.ResponseWriter, params map[string]interface{}) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	mw, ex := s.windowExecution(w, params)
+	if ex == nil {
+		return
+	}
+	taskID := getString(params, "TaskId")
+	var te *taskExecution
+	for _, candidate := range ex.tasks {
+		if candidate.id == taskID {
+			te = candidate
+		}
+	}
+	if te == nil {
+		writeJSONError(w, "DoesNotExistException", "Maintenance window task execution "+taskID+" does not exist", http.StatusBadRequest)
+		return
+	}
+	out := make([]map[string]interface{}, 0, len(te.invocations))
+	for _, inv := range te.invocations {
+		parameters, _ := json.Marshal(map[string]interface{}{
+			"documentName": te.task.taskArn,
+			"instanceIds":  []string{inv.instanceID},
+		})
+		resp := map[string]interface{}{
+			"WindowExecutionId": ex.id,
+			"TaskExecutionId":   te.id,
+			"InvocationId":      inv.id,
+			"ExecutionId":       inv.executionID,
+			"TaskType":          te.task.taskType,
+			"Parameters":        string(parameters),
+			"Status":            inv.status,
+			"StartTime":         float64(te.start.Unix()),
+			"EndTime":           float64(te.end.Unix()),
+		}
+		if inv.statusDetails != "" {
+			resp["StatusDetails"] = inv.statusDetails
+		}
+		if wt, ok := mw.targets[inv.windowTarget]; ok {
+			resp["WindowTargetId"] = wt.id
+			if wt.ownerInformation != "" {
+				resp["OwnerInformation"] = wt.ownerInformation
+			}
+		}
+		out = append(out, resp)
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"WindowExecutionTaskInvocationIdentities": out})
+}
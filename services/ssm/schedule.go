@@ -0,0 +1,165 @@
+package ssm
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// schedule is a parsed rate() or cron() expression of a maintenance window
+// or association.
+type schedule struct {
+	every time.Duration // rate() interval, or zero for cron()
+	cron  *cronExpr
+}
+
+// parseSchedule parses expr, which must be a rate() or six-field cron()
+// expression.
+func parseSchedule(expr string) (*schedule, error) {
+	inner := func(prefix string) string {
+		return strings.TrimSuffix(strings.TrimPrefix(expr, prefix+"("), ")")
+	}
+	switch {
+	case strings.HasPrefix(expr, "rate("):
+		fields := strings.Fields(inner("rate"))
+		if len(fields) != 2 {
+			break
+		}
+		n, err := strconv.Atoi(fields[0])
+		if err != nil || n <= 0 {
+			break
+		}
+		var unit time.Duration
+		switch strings.TrimSuffix(fields[1], "s") {
+		case "minute":
+			unit = time.Minute
+		case "hour":
+			unit = time.Hour
+		case "day":
+			unit = 24 * time.Hour
+		default:
+			return nil, fmt.Errorf("invalid schedule expression %s", expr)
+		}
+		return &schedule{every: time.Duration(n) * unit}, nil
+	case strings.HasPrefix(expr, "cron("):
+		c, err := parseCron(inner("cron"))
+		if err != nil {
+			break
+		}
+		return &schedule{cron: c}, nil
+	}
+	return nil, fmt.Errorf("invalid schedule expression %s", expr)
+}
+
+// occurrences returns the times in (from, to] the schedule fires at. Rate
+// schedules count from anchor.
+func (sc *schedule) occurrences(anchor, from, to time.Time) []time.Time {
+	var times []time.Time
+	if sc.cron != nil {
+		for t := from.Truncate(time.Minute).Add(time.Minute); !t.After(to); t = t.Add(time.Minute) {
+			if sc.cron.matches(t) {
+				times = append(times, t)
+			}
+		}
+		return times
+	}
+	n := int64(1)
+	if from.After(anchor) {
+		n = int64(from.Sub(anchor)/sc.every) + 1
+	}
+	for t := anchor.Add(time.Duration(n) * sc.every); !t.After(to); t = t.Add(sc.every) {
+		times = append(times, t)
+	}
+	return times
+}
+
+// cronExpr is a parsed six-field cron expression:
+// minutes hours day-of-month month day-of-week year.
+type cronExpr struct {
+	fields [6]map[int]bool // nil matches everything
+}
+
+var cronSpecs = [6]struct {
+	lo, hi int
+	names  map[string]int
+}{
+	{0, 59, nil},
+	{0, 23, nil},
+	{1, 31, nil},
+	{1, 12, map[string]int{"JAN": 1, "FEB": 2, "MAR": 3, "APR": 4, "MAY": 5, "JUN": 6, "JUL": 7, "AUG": 8, "SEP": 9, "OCT": 10, "NOV": 11, "DEC": 12}},
+	{1, 7, map[string]int{"SUN": 1, "MON": 2, "TUE": 3, "WED": 4, "THU": 5, "FRI": 6, "SAT": 7}},
+	{1970, 2199, nil},
+}
+
+func parseCron(expr string) (*cronExpr, error) {
+	fields := strings.Fields(expr)
+	if len(fields) != 6 {
+		return nil, fmt.Errorf("cron expressions have six fields")
+	}
+	c := &cronExpr{}
+	for i, f := range fields {
+		if f == "*" || f == "?" {
+			continue
+		}
+		spec := cronSpecs[i]
+		value := func(s string) (int, error) {
+			if n, ok := spec.names[strings.ToUpper(s)]; ok {
+				return n, nil
+			}
+			n, err := strconv.Atoi(s)
+			if err != nil || n < spec.lo || n > spec.hi {
+				return 0, fmt.Errorf("invalid cron value %q", s)
+			}
+			return n, nil
+		}
+		set := make(map[int]bool)
+		for _, part := range strings.Split(f, ",") {
+			step := 1
+			if j := strings.Index(part, "/"); j >= 0 {
+				n, err := strconv.Atoi(part[j+1:])
+				if err != nil || n <= 0 {
+					return nil, fmt.Errorf("invalid cron step %q", part)
+				}
+				step, part = n, part[:j]
+			}
+			lo, hi := spec.lo, spec.hi
+			switch {
+			case part == "*":
+			case strings.Contains(part, "-"):
+				bounds := strings.SplitN(part, "-", 2)
+				var err error
+				if lo, err = value(bounds[0]); err != nil {
+					return nil, err
+				}
+				if hi, err = value(bounds[1]); err != nil {
+					return nil, err
+				}
+			default:
+				n, err := value(part)
+				if err != nil {
+					return nil, err
+				}
+				lo = n
+				if step == 1 {
+					hi = n
+				}
+			}
+			for v := lo; v <= hi; v += step {
+				set[v] = true
+			}
+		}
+		c.fields[i] = set
+	}
+	return c, nil
+}
+
+func (c *cronExpr) matches(t time.Time) bool {
+	values := [6]int{t.Minute(), t.Hour(), t.Day(), int(t.Month()), int(t.Weekday()) + 1, t.Year()}
+	for i, set := range c.fields {
+		if set != nil && !set[values[i]] {
+			return false
+		}
+	}
+	return true
+}
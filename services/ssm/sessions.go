@@ -0,0 +1,215 @@
+package ssm
+
+import (
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"time"
+
+	h "github.com/riyanimam/goto/internal/mockhelpers"
+	"github.com/riyanimam/goto/internal/paginate"
+)
+
+// sessionOwner is the IAM user sessions are started as, matching the
+// caller the STS mock reports.
+const sessionOwner = "moto"
+
+// sessionTarget matches the targets Session Manager can connect to: EC2
+// instances, hybrid managed nodes, and ECS containers.
+var sessionTarget = regexp.MustCompile(`^(i-[0-9a-f]{8,17}|mi-[0-9a-f]{17}|ecs:.+)$`)
+
+type session struct {
+	id           string
+	target       string
+	documentName string
+	reason       string
+	parameters   map[string]interface{}
+	status       string // Connected or Terminated
+	started      time.Time
+	ended        time.Time
+}
+
+func (s *Service) startSession(w http.ResponseWriter, params map[string]interface{}) {
+	target := getString(params, "Target")
+	if target == "" {
+		writeJSONError(w, "ValidationException", "Target is required", http.StatusBadRequest)
+		return
+	}
+	if !sessionTarget.MatchString(target) {
+		writeJSONError(w, "TargetNotConnected", target+" is not connected.", http.StatusBadRequest)
+		return
+	}
+	document := getString(params, "DocumentName")
+	if document == "" {
+		document = "SSM-SessionManagerRunShell"
+	}
+	sessionParams, _ := params["Parameters"].(map[string]interface{})
+
+	s.mu.Lock()
+	sess := &session{
+		id:           sessionOwner + "-" + h.RandomHex(17),
+		target:       target,
+		documentName: document,
+		reason:       getString(params, "Reason"),
+		parameters:   sessionParams,
+		status:       "Connected",
+		started:      s.now(),
+	}
+	s.sessions[sess.id] = sess
+	resp := s.sessionStream(sess)
+	s.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// sessionStream returns the session's data channel URL and a fresh token
+// for it. The URL points at the mock server, which does not serve the data
+// channel, so clients that open it fail instead of reaching AWS. The caller
+// must hold s.mu.
+func (s *Service) sessionStream(sess *session) map[string]interface{} {
+	stream := url.URL{Scheme: "wss", Host: "ssmmessages.us-east-1.amazonaws.com"}
+	if u, err := url.Parse(s.baseURL); err == nil && u.Host != "" {
+		stream.Scheme, stream.Host = "ws", u.Host
+	}
+	stream.Path = "/v1/data-channel/" + sess.id
+	stream.RawQuery = "role=publish_subscribe"
+	return map[string]interface{}{
+		"SessionId":  sess.id,
+		"StreamUrl":  stream.String(),
+		"TokenValue": h.RandomHex(64),
+	}
+}
+
+func (s *Service) resumeSession(w http.ResponseWriter, params map[string]interface{}) {
+	id := getString(params, "SessionId")
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	sess, exists := s.sessions[id]
+	if !exists || sess.status == "Terminated" {
+		writeJSONError(w, "DoesNotExistException", "Session "+id+" does not exist or is no longer active.", http.StatusBadRequest)
+		return
+	}
+	writeJSON(w, http.StatusOK, s.sessionStream(sess))
+}
+
+// terminateSession ends the session. Terminating a session that does not
+// exist or has already ended succeeds, as in Session Manager.
+func (s *Service) terminateSession(w http.ResponseWriter, params map[string]interface{}) {
+	id := getString(params, "SessionId")
+	s.mu.Lock()
+	if sess, exists := s.sessions[id]; exists && sess.status != "Terminated" {
+		sess.status = "Terminated"
+		sess.ended = s.now()
+	}
+	s.mu.Unlock()
+	writeJSON(w, http.StatusOK, map[string]interface{}{"SessionId": id})
+}
+
+func (sess *session) description() map[string]interface{} {
+	d := map[string]interface{}{
+		"SessionId":          sess.id,
+		"Target":             sess.target,
+		"Status":             sess.status,
+		"StartDate":          float64(sess.started.Unix()),
+		"DocumentName":       sess.documentName,
+		"Owner":              "arn:aws:iam::" + defaultAccountID + ":user/" + sessionOwner,
+		"MaxSessionDuration": "1200",
+		"OutputUrl":          map[string]interface{}{},
+	}
+	if sess.reason != "" {
+		d["Reason"] = sess.reason
+	}
+	if !sess.ended.IsZero() {
+		d["EndDate"] = float64(sess.ended.Unix())
+	}
+	return d
+}
+
+// matches reports whether the session passes a DescribeSessions filter.
+func (sess *session) matches(key, value string) (bool, error) {
+	switch key {
+	case "InvokedAfter", "InvokedBefore":
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return false, err
+		}
+		if key == "InvokedAfter" {
+			return !sess.started.Before(t), nil
+		}
+		return !sess.started.After(t), nil
+	case "Target":
+		return sess.target == value, nil
+	case "Owner":
+		return value == sessionOwner || value == "arn:aws:iam::"+defaultAccountID+":user/"+sessionOwner, nil
+	case "Status":
+		return sess.status == value, nil
+	case "SessionId":
+		return sess.id == value, nil
+	}
+	return false, nil
+}
+
+func (s *Service) describeSessions(w http.ResponseWriter, params map[string]interface{}) {
+	state := getString(params, "State")
+	if state != "Active" && state != "History" {
+		writeJSONError(w, "ValidationException", "State must be Active or History", http.StatusBadRequest)
+		return
+	}
+	type filter struct{ key, value string }
+	var filters []filter
+	rawFilters, _ := params["Filters"].([]interface{})
+	for _, raw := range rawFilters {
+		f, _ := raw.(map[string]interface{})
+		key := getString(f, "key")
+		switch key {
+		case "InvokedAfter", "InvokedBefore", "Target", "Owner", "Status", "SessionId":
+		default:
+			writeJSONError(w, "InvalidFilterKey", "The specified key "+key+" is not valid.", http.StatusBadRequest)
+			return
+		}
+		filters = append(filters, filter{key, getString(f, "value")})
+	}
+
+	s.mu.RLock()
+	var sessions []*session
+	for _, sess := range s.sessions {
+		if (sess.status == "Terminated") != (state == "History") {
+			continue
+		}
+		keep := true
+		for _, f := range filters {
+			ok, err := sess.matches(f.key, f.value)
+			if err != nil {
+				s.mu.RUnlock()
+				writeJSONError(w, "InvalidFilterValue", "The filter value "+f.value+" is not valid for "+f.key+".", http.StatusBadRequest)
+				return
+			}
+			keep = keep && ok
+		}
+		if keep {
+			sessions = append(sessions, sess)
+		}
+	}
+	s.mu.RUnlock()
+
+	// Newest first, as Session Manager lists them.
+	key := func(i int) string {
+		return paginate.Join(paginate.Desc(paginate.Time(sessions[i].started)), sessions[i].id)
+	}
+	sort.Slice(sessions, func(i, j int) bool { return key(i) < key(j) })
+	page, next, err := paginate.Page(sessions, key, getString(params, "NextToken"), getInt(params, "MaxResults", 200), 200)
+	if err != nil {
+		writeJSONError(w, "InvalidNextToken", "The specified token is not valid.", http.StatusBadRequest)
+		return
+	}
+	out := make([]map[string]interface{}, 0, len(page))
+	for _, sess := range page {
+		out = append(out, sess.description())
+	}
+	resp := map[string]interface{}{"Sessions": out}
+	if next != "" {
+		resp["NextToken"] = next
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
@@ -1,4 +1,6 @@
-// Package ssm provides a mock implementation of AWS Systems Manager Parameter Store.
+// Package ssm provides a mock implementation of AWS Systems Manager Parameter
+// Store, Session Manager, Maintenance Windows, State Manager, Run Command, and
+// hybrid activations.
 //
 // Supported actions:
 //   - PutParameter
@@ -7,26 +9,90 @@
 //   - DeleteParameter
 //   - DescribeParameters
 //   - GetParametersByPath
+//   - AddTagsToResource
+//   - RemoveTagsFromResource
+//   - ListTagsForResource
+//   - StartSession
+//   - ResumeSession
+//   - TerminateSession
+//   - DescribeSessions
+//   - CreateMaintenanceWindow
+//   - GetMaintenanceWindow
+//   - UpdateMaintenanceWindow
+//   - DeleteMaintenanceWindow
+//   - DescribeMaintenanceWindows
+//   - RegisterTargetWithMaintenanceWindow
+//   - DeregisterTargetFromMaintenanceWindow
+//   - DescribeMaintenanceWindowTargets
+//   - RegisterTaskWithMaintenanceWindow
+//   - DeregisterTaskFromMaintenanceWindow
+//   - DescribeMaintenanceWindowTasks
+//   - DescribeMaintenanceWindowExecutions
+//   - DescribeMaintenanceWindowExecutionTasks
+//   - DescribeMaintenanceWindowExecutionTaskInvocations
+//   - CreateAssociation
+//   - DescribeAssociation
+//   - DeleteAssociation
+//   - ListAssociations
+//   - DescribeAssociationExecutions
+//   - DescribeAssociationExecutionTargets
+//   - CreateActivation
+//   - DescribeActivations
+//   - DeleteActivation
+//   - DeregisterManagedInstance
+//   - DescribeInstanceInformation
+//   - SendCommand
+//   - ListCommands
+//   - ListCommandInvocations
+//   - GetCommandInvocation
+//
+// Sessions are recorded for auditing but carry no traffic: StartSession
+// returns a stream URL on the mock server that does not serve the Session
+// Manager data channel, and a random token.
+//
+// Managed instances are the EC2 mock's instances, online while running,
+// and hybrid instances registered with an activation through
+// RegisterManagedInstance. Maintenance windows and associations run on
+// their rate() or cron() schedules as the mock clock advances, and
+// associations also run once when created. They, and SendCommand, target
+// managed instances by ID or tag. Tasks and documents are not executed:
+// each run is recorded per instance, and succeeds if the instance is online
+// or fails otherwise.
 package ssm
 
 import (
 	"encoding/json"
 	"fmt"
-	"io"
 	"math/rand"
 	"net/http"
 	"sort"
 	"strings"
 	"sync"
 	"time"
+
+	"github.com/riyanimam/goto/internal/awserr"
+	"github.com/riyanimam/goto/internal/clock"
+	h "github.com/riyanimam/goto/internal/mockhelpers"
+	"github.com/riyanimam/goto/internal/tags"
 )
 
 const defaultAccountID = "123456789012"
 
 // Service implements the SSM Parameter Store mock.
 type Service struct {
-	mu     sync.RWMutex
-	params map[string]*parameter // keyed by name
+	mu       sync.RWMutex
+	params   map[string]*parameter // keyed by name
+	sessions map[string]*session   // keyed by session ID
+	tags     *tags.Store
+	clock    *clock.Clock
+	baseURL  string
+
+	windows      map[string]*maintenanceWindow // keyed by window ID
+	associations map[string]*association       // keyed by association ID
+	activations  map[string]*activation        // keyed by activation ID
+	nodes        map[string]*managedNode       // hybrid instances, keyed by mi-* ID
+	commands     []*command                    // oldest first
+	resources    h.ResourceLister
 }
 
 type parameter struct {
@@ -42,7 +108,13 @@ type parameter struct {
 // New creates a new SSM mock service.
 func New() *Service {
 	return &Service{
-		params: make(map[string]*parameter),
+		params:       make(map[string]*parameter),
+		sessions:     make(map[string]*session),
+		tags:         tags.New(),
+		windows:      make(map[string]*maintenanceWindow),
+		associations: make(map[string]*association),
+		activations:  make(map[string]*activation),
+		nodes:        make(map[string]*managedNode),
 	}
 }
 
@@ -51,60 +123,105 @@ func (s *Service) Name() string { return "ssm" }
 
 // Handler returns the HTTP handler for SSM requests.
 func (s *Service) Handler() http.Handler {
-	return http.HandlerFunc(s.handle)
+	return h.JSONRouter{
+		"PutParameter":                                      s.putParameter,
+		"GetParameter":                                      s.getParameter,
+		"GetParameters":                                     s.getParameters,
+		"DeleteParameter":                                   s.deleteParameter,
+		"DescribeParameters":                                s.describeParameters,
+		"GetParametersByPath":                               s.getParametersByPath,
+		"AddTagsToResource":                                 s.addTagsToResource,
+		"RemoveTagsFromResource":                            s.removeTagsFromResource,
+		"ListTagsForResource":                               s.listTagsForResource,
+		"StartSession":                                      s.startSession,
+		"ResumeSession":                                     s.resumeSession,
+		"TerminateSession":                                  s.terminateSession,
+		"DescribeSessions":                                  s.describeSessions,
+		"CreateMaintenanceWindow":                           s.createMaintenanceWindow,
+		"GetMaintenanceWindow":                              s.getMaintenanceWindow,
+		"UpdateMaintenanceWindow":                           s.updateMaintenanceWindow,
+		"DeleteMaintenanceWindow":                           s.deleteMaintenanceWindow,
+		"DescribeMaintenanceWindows":                        s.describeMaintenanceWindows,
+		"RegisterTargetWithMaintenanceWindow":               s.registerTargetWithMaintenanceWindow,
+		"DeregisterTargetFromMaintenanceWindow":             s.deregisterTargetFromMaintenanceWindow,
+		"DescribeMaintenanceWindowTargets":                  s.describeMaintenanceWindowTargets,
+		"RegisterTaskWithMaintenanceWindow":                 s.registerTaskWithMaintenanceWindow,
+		"DeregisterTaskFromMaintenanceWindow":               s.deregisterTaskFromMaintenanceWindow,
+		"DescribeMaintenanceWindowTasks":                    s.describeMaintenanceWindowTasks,
+		"DescribeMaintenanceWindowExecutions":               s.describeMaintenanceWindowExecutions,
+		"DescribeMaintenanceWindowExecutionTasks":           s.describeMaintenanceWindowExecutionTasks,
+		"DescribeMaintenanceWindowExecutionTaskInvocations": s.describeMaintenanceWindowExecutionTaskInvocations,
+		"CreateAssociation":                                 s.createAssociation,
+		"DescribeAssociation":                               s.describeAssociation,
+		"DeleteAssociation":                                 s.deleteAssociation,
+		"ListAssociations":                                  s.listAssociations,
+		"DescribeAssociationExecutions":                     s.describeAssociationExecutions,
+		"DescribeAssociationExecutionTargets":               s.describeAssociationExecutionTargets,
+		"CreateActivation":                                  s.createActivation,
+		"DescribeActivations":                               s.describeActivations,
+		"DeleteActivation":                                  s.deleteActivation,
+		"DeregisterManagedInstance":                         s.deregisterManagedInstance,
+		"DescribeInstanceInformation":                       s.describeInstanceInformation,
+		"SendCommand":                                       s.sendCommand,
+		"ListCommands":                                      s.listCommands,
+		"ListCommandInvocations":                            s.listCommandInvocations,
+		"GetCommandInvocation":                              s.getCommandInvocation,
+	}
 }
 
-// Reset clears all parameters.
+// Reset clears all parameters, sessions, maintenance windows, associations,
+// activations, hybrid instances, and commands.
 func (s *Service) Reset() {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	s.params = make(map[string]*parameter)
+	s.sessions = make(map[string]*session)
+	s.windows = make(map[string]*maintenanceWindow)
+	s.associations = make(map[string]*association)
+	s.activations = make(map[string]*activation)
+	s.nodes = make(map[string]*managedNode)
+	s.commands = nil
+	s.tags.DeleteService("ssm")
 }
 
-func (s *Service) handle(w http.ResponseWriter, r *http.Request) {
-	target := r.Header.Get("X-Amz-Target")
+// SetTagStore sets the registry parameter tags are recorded in.
+func (s *Service) SetTagStore(store *tags.Store) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tags = store
+}
 
-	bodyBytes, err := io.ReadAll(r.Body)
-	if err != nil {
-		writeJSONError(w, "InternalServerError", "could not read request body", http.StatusInternalServerError)
-		return
-	}
+// SetClock attaches the mock clock used for session start and end times.
+// Advancing it runs the maintenance windows and associations that fall
+// due.
+func (s *Service) SetClock(c *clock.Clock) {
+	s.mu.Lock()
+	s.clock = c
+	s.mu.Unlock()
+	c.OnAdvance(s.runSchedules)
+}
 
-	var params map[string]interface{}
-	if len(bodyBytes) > 0 {
-		if err := json.Unmarshal(bodyBytes, &params); err != nil {
-			writeJSONError(w, "ValidationException", "could not parse request body", http.StatusBadRequest)
-			return
-		}
-	}
-	if params == nil {
-		params = make(map[string]interface{})
-	}
+// SetResourceLister sets the function the EC2 instances maintenance
+// windows and associations target are read through.
+func (s *Service) SetResourceLister(l h.ResourceLister) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.resources = l
+}
 
-	action := ""
-	if target != "" {
-		parts := strings.SplitN(target, ".", 2)
-		if len(parts) == 2 {
-			action = parts[1]
-		}
-	}
+// SetBaseURL records the mock server URL, which session stream URLs point
+// at.
+func (s *Service) SetBaseURL(u string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.baseURL = u
+}
 
-	switch action {
-	case "PutParameter":
-		s.putParameter(w, params)
-	case "GetParameter":
-		s.getParameter(w, params)
-	case "GetParameters":
-		s.getParameters(w, params)
-	case "DeleteParameter":
-		s.deleteParameter(w, params)
-	case "DescribeParameters":
-		s.describeParameters(w, params)
-	case "GetParametersByPath":
-		s.getParametersByPath(w, params)
-	default:
-		writeJSONError(w, "UnknownOperationException", fmt.Sprintf("action %q is not supported", action), http.StatusBadRequest)
+func (s *Service) now() time.Time {
+	if s.clock != nil {
+		return s.clock.Now()
 	}
+	return time.Now().UTC()
 }
 
 func (s *Service) putParameter(w http.ResponseWriter, params map[string]interface{}) {
@@ -135,7 +252,7 @@ func (s *Service) putParameter(w http.ResponseWriter, params map[string]interfac
 		version = existing.version + 1
 	}
 
-	s.params[name] = &parameter{
+	p := &parameter{
 		name:         name,
 		paramType:    paramType,
 		value:        value,
@@ -144,6 +261,8 @@ func (s *Service) putParameter(w http.ResponseWriter, params map[string]interfac
 		lastModified: time.Now().UTC(),
 		arn:          fmt.Sprintf("arn:aws:ssm:us-east-1:%s:parameter%s", defaultAccountID, name),
 	}
+	s.params[name] = p
+	s.tags.Tag(p.arn, tags.FromList(params["Tags"], "Key", "Value"))
 	s.mu.Unlock()
 
 	writeJSON(w, http.StatusOK, map[string]interface{}{
@@ -198,17 +317,79 @@ func (s *Service) deleteParameter(w http.ResponseWriter, params map[string]inter
 	name := getString(params, "Name")
 
 	s.mu.Lock()
-	if _, exists := s.params[name]; !exists {
+	p, exists := s.params[name]
+	if !exists {
 		s.mu.Unlock()
 		writeJSONError(w, "ParameterNotFound", "Parameter "+name+" not found.", http.StatusBadRequest)
 		return
 	}
+	s.tags.Delete(p.arn)
 	delete(s.params, name)
 	s.mu.Unlock()
 
 	writeJSON(w, http.StatusOK, map[string]interface{}{})
 }
 
+func (s *Service) addTagsToResource(w http.ResponseWriter, params map[string]interface{}) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	arn, ok := s.taggedResource(w, params)
+	if !ok {
+		return
+	}
+
+	s.tags.Tag(arn, tags.FromList(params["Tags"], "Key", "Value"))
+	writeJSON(w, http.StatusOK, map[string]interface{}{})
+}
+
+func (s *Service) removeTagsFromResource(w http.ResponseWriter, params map[string]interface{}) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	arn, ok := s.taggedResource(w, params)
+	if !ok {
+		return
+	}
+
+	s.tags.Untag(arn, tags.Keys(params["TagKeys"]))
+	writeJSON(w, http.StatusOK, map[string]interface{}{})
+}
+
+func (s *Service) listTagsForResource(w http.ResponseWriter, params map[string]interface{}) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	arn, ok := s.taggedResource(w, params)
+	if !ok {
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"TagList": tags.ToList(s.tags.Get(arn), "Key", "Value"),
+	})
+}
+
+// taggedResource resolves the ResourceType/ResourceId pair of a tagging
+// request, a parameter or a hybrid managed instance, to the ARN its tags
+// are recorded under, writing the error response if it cannot. Caller must
+// hold s.mu.
+func (s *Service) taggedResource(w http.ResponseWriter, params map[string]interface{}) (string, bool) {
+	id := getString(params, "ResourceId")
+	switch rt := getString(params, "ResourceType"); rt {
+	case "Parameter":
+		if p, exists := s.params[id]; exists {
+			return p.arn, true
+		}
+	case "ManagedInstance":
+		if _, exists := s.nodes[id]; exists {
+			return managedInstanceARN(id), true
+		}
+	default:
+		writeJSONError(w, "InvalidResourceType", "The resource type "+rt+" is not supported.", http.StatusBadRequest)
+		return "", false
+	}
+	writeJSONError(w, "InvalidResourceId", "The resource ID "+id+" is not valid.", http.StatusBadRequest)
+	return "", false
+}
+
 func (s *Service) describeParameters(w http.ResponseWriter, _ map[string]interface{}) {
 	s.mu.RLock()
 	var paramList []map[string]interface{}
@@ -297,6 +478,13 @@ func getBool(params map[string]interface{}, key string) bool {
 	return false
 }
 
+func getInt(params map[string]interface{}, key string, def int) int {
+	if v, ok := params[key].(float64); ok {
+		return int(v)
+	}
+	return def
+}
+
 func writeJSON(w http.ResponseWriter, status int, v interface{}) {
 	w.Header().Set("Content-Type", "application/x-amz-json-1.1")
 	w.WriteHeader(status)
@@ -304,12 +492,7 @@ func writeJSON(w http.ResponseWriter, status int, v interface{}) {
 }
 
 func writeJSONError(w http.ResponseWriter, code, message string, status int) {
-	w.Header().Set("Content-Type", "application/x-amz-json-1.1")
-	w.WriteHeader(status)
-	json.NewEncoder(w).Encode(map[string]interface{}{
-		"__type":  code,
-		"message": message,
-	})
+	awserr.WriteJSON(w, awserr.JSON11, code, message, status)
 }
 
 func newRequestID() string {
@@ -0,0 +1,26 @@
+package ssm
+
+import "github.com/riyanimam/goto/internal/mockhelpers"
+
+// ExportState lists the parameters with their current values.
+func (s *Service) ExportState() []mockhelpers.Resource {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	resources := make([]mockhelpers.Resource, 0, len(s.params))
+	for _, p := range s.params {
+		resources = append(resources, mockhelpers.Resource{
+			Type: "aws_ssm_parameter",
+			ID:   p.name,
+			ARN:  p.arn,
+			Attributes: map[string]interface{}{
+				"name":        p.name,
+				"type":        p.paramType,
+				"value":       p.value,
+				"description": p.description,
+				"version":     p.version,
+			},
+		})
+	}
+	return resources
+}
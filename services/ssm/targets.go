@@ -0,0 +1,129 @@
+package ssm
+
+import (
+	"sort"
+	"strings"
+)
+
+// target selects managed instances, by InstanceIds or by tag:<key>, the
+// forms maintenance windows and associations accept.
+type target struct {
+	key    string
+	values []string
+}
+
+// parseTargets reads a Targets list. Keys other than InstanceIds, tag:<key>,
+// tag-key, and WindowTargetIds are rejected.
+func parseTargets(v interface{}) ([]target, bool) {
+	raw, _ := v.([]interface{})
+	targets := make([]target, 0, len(raw))
+	for _, item := range raw {
+		m, _ := item.(map[string]interface{})
+		t := target{key: getString(m, "Key")}
+		switch {
+		case t.key == "InstanceIds", t.key == "WindowTargetIds", t.key == "tag-key", strings.HasPrefix(t.key, "tag:"):
+		default:
+			return nil, false
+		}
+		values, _ := m["Values"].([]interface{})
+		for _, val := range values {
+			if str, ok := val.(string); ok {
+				t.values = append(t.values, str)
+			}
+		}
+		if len(t.values) == 0 {
+			return nil, false
+		}
+		targets = append(targets, t)
+	}
+	return targets, true
+}
+
+func targetsResp(targets []target) []map[string]interface{} {
+	out := make([]map[string]interface{}, 0, len(targets))
+	for _, t := range targets {
+		out = append(out, map[string]interface{}{"Key": t.key, "Values": t.values})
+	}
+	return out
+}
+
+// managedInstance is an EC2 instance or registered hybrid instance as
+// State Manager, Run Command, and maintenance windows see it.
+type managedInstance struct {
+	pingStatus string // Online, ConnectionLost, or Inactive
+	tags       map[string]string
+}
+
+// managedInstances returns the managed instances, keyed by ID: the EC2
+// mock's instances that have not been terminated, online while running,
+// and the registered hybrid instances. s.mu must not be held, as EC2
+// instances are read from another service.
+func (s *Service) managedInstances() map[string]managedInstance {
+	s.mu.RLock()
+	resources, store := s.resources, s.tags
+	instances := make(map[string]managedInstance, len(s.nodes))
+	for id, node := range s.nodes {
+		instances[id] = managedInstance{pingStatus: node.pingStatus, tags: store.Get(managedInstanceARN(id))}
+	}
+	s.mu.RUnlock()
+
+	if resources == nil {
+		return instances
+	}
+	for _, r := range resources("ec2") {
+		if r.Type != "aws_instance" {
+			continue
+		}
+		status := "ConnectionLost"
+		if r.Attributes["instance_state"] == "running" {
+			status = "Online"
+		}
+		instances[r.ID] = managedInstance{pingStatus: status, tags: store.Get(r.ARN)}
+	}
+	return instances
+}
+
+// resolve returns the IDs of the instances the targets select, sorted.
+// Instances named by ID are included whether or not they exist, so that
+// their runs can be reported as undeliverable.
+func resolve(targets []target, instances map[string]managedInstance) []string {
+	seen := make(map[string]bool)
+	for _, t := range targets {
+		switch {
+		case t.key == "InstanceIds":
+			for _, id := range t.values {
+				seen[id] = true
+			}
+		case t.key == "tag-key":
+			for id, inst := range instances {
+				for _, k := range t.values {
+					if _, ok := inst.tags[k]; ok {
+						seen[id] = true
+					}
+				}
+			}
+		case strings.HasPrefix(t.key, "tag:"):
+			key := strings.TrimPrefix(t.key, "tag:")
+			for id, inst := range instances {
+				for _, v := range t.values {
+					if tv, ok := inst.tags[key]; ok && tv == v {
+						seen[id] = true
+					}
+				}
+			}
+		}
+	}
+	ids := make([]string, 0, len(seen))
+	for id := range seen {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// reachable reports whether a run on the instance can be delivered: only
+// online instances have an agent listening.
+func reachable(id string, instances map[string]managedInstance) bool {
+	inst, ok := instances[id]
+	return ok && inst.pingStatus == "Online"
+}
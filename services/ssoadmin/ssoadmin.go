@@ -7,18 +7,19 @@
 //   - ListPermissionSets
 //   - CreateAccountAssignment
 //   - ListAccountAssignments
+//   - TagResource
+//   - UntagResource
+//   - ListTagsForResource
 package ssoadmin
 
 import (
-	"encoding/json"
 	"fmt"
-	"io"
 	"net/http"
-	"strings"
 	"sync"
 	"time"
 
 	h "github.com/riyanimam/goto/internal/mockhelpers"
+	"github.com/riyanimam/goto/internal/tags"
 )
 
 // Service implements the SSO Admin mock.
@@ -26,6 +27,7 @@ type Service struct {
 	mu          sync.RWMutex
 	permSets    map[string]*permissionSet
 	assignments map[string]*accountAssignment
+	tags        *tags.Store
 }
 
 type permissionSet struct {
@@ -51,6 +53,7 @@ func New() *Service {
 	return &Service{
 		permSets:    make(map[string]*permissionSet),
 		assignments: make(map[string]*accountAssignment),
+		tags:        tags.New(),
 	}
 }
 
@@ -59,7 +62,17 @@ func (s *Service) Name() string { return "sso" }
 
 // Handler returns the HTTP handler for SSO Admin requests.
 func (s *Service) Handler() http.Handler {
-	return http.HandlerFunc(s.handle)
+	return h.JSONRouter{
+		"CreatePermissionSet":     s.createPermissionSet,
+		"DescribePermissionSet":   s.describePermissionSet,
+		"DeletePermissionSet":     s.deletePermissionSet,
+		"ListPermissionSets":      s.listPermissionSets,
+		"CreateAccountAssignment": s.createAccountAssignment,
+		"ListAccountAssignments":  s.listAccountAssignments,
+		"TagResource":             s.tagResource,
+		"UntagResource":           s.untagResource,
+		"ListTagsForResource":     s.listTagsForResource,
+	}
 }
 
 // Reset clears all state.
@@ -68,52 +81,14 @@ func (s *Service) Reset() {
 	defer s.mu.Unlock()
 	s.permSets = make(map[string]*permissionSet)
 	s.assignments = make(map[string]*accountAssignment)
+	s.tags.DeleteService("sso")
 }
 
-func (s *Service) handle(w http.ResponseWriter, r *http.Request) {
-	target := r.Header.Get("X-Amz-Target")
-
-	bodyBytes, err := io.ReadAll(r.Body)
-	if err != nil {
-		h.WriteJSONError(w, "InternalServerError", "could not read request body", http.StatusInternalServerError)
-		return
-	}
-
-	var params map[string]interface{}
-	if len(bodyBytes) > 0 {
-		if err := json.Unmarshal(bodyBytes, &params); err != nil {
-			h.WriteJSONError(w, "SerializationException", "could not parse request body", http.StatusBadRequest)
-			return
-		}
-	}
-	if params == nil {
-		params = make(map[string]interface{})
-	}
-
-	action := ""
-	if target != "" {
-		parts := strings.SplitN(target, ".", 2)
-		if len(parts) == 2 {
-			action = parts[1]
-		}
-	}
-
-	switch action {
-	case "CreatePermissionSet":
-		s.createPermissionSet(w, params)
-	case "DescribePermissionSet":
-		s.describePermissionSet(w, params)
-	case "DeletePermissionSet":
-		s.deletePermissionSet(w, params)
-	case "ListPermissionSets":
-		s.listPermissionSets(w, params)
-	case "CreateAccountAssignment":
-		s.createAccountAssignment(w, params)
-	case "ListAccountAssignments":
-		s.listAccountAssignments(w, params)
-	default:
-		h.WriteJSONError(w, "UnknownOperationException", fmt.Sprintf("action %q is not supported", action), http.StatusBadRequest)
-	}
+// SetTagStore sets the registry resource tags are recorded in.
+func (s *Service) SetTagStore(store *tags.Store) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tags = store
 }
 
 func (s *Service) createPermissionSet(w http.ResponseWriter, params map[string]interface{}) {
@@ -146,6 +121,7 @@ func (s *Service) createPermissionSet(w http.ResponseWriter, params map[string]i
 
 	s.mu.Lock()
 	s.permSets[arn] = ps
+	s.tags.Tag(arn, tags.FromList(params["Tags"], "Key", "Value"))
 	s.mu.Unlock()
 
 	h.WriteJSON(w, http.StatusOK, map[string]interface{}{
@@ -181,6 +157,7 @@ func (s *Service) deletePermissionSet(w http.ResponseWriter, params map[string]i
 		return
 	}
 	delete(s.permSets, permSetArn)
+	s.tags.Delete(permSetArn)
 	s.mu.Unlock()
 
 	h.WriteJSON(w, http.StatusOK, map[string]interface{}{})
@@ -199,6 +176,49 @@ func (s *Service) listPermissionSets(w http.ResponseWriter, params map[string]in
 	})
 }
 
+func (s *Service) tagResource(w http.ResponseWriter, params map[string]interface{}) {
+	arn := h.GetString(params, "ResourceArn")
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.permSets[arn] == nil {
+		h.WriteJSONError(w, "ResourceNotFoundException", "Resource not found: "+arn, http.StatusBadRequest)
+		return
+	}
+	s.tags.Tag(arn, tags.FromList(params["Tags"], "Key", "Value"))
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{})
+}
+
+func (s *Service) untagResource(w http.ResponseWriter, params map[string]interface{}) {
+	arn := h.GetString(params, "ResourceArn")
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.permSets[arn] == nil {
+		h.WriteJSONError(w, "ResourceNotFoundException", "Resource not found: "+arn, http.StatusBadRequest)
+		return
+	}
+	s.tags.Untag(arn, tags.Keys(params["TagKeys"]))
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{})
+}
+
+func (s *Service) listTagsForResource(w http.ResponseWriter, params map[string]interface{}) {
+	arn := h.GetString(params, "ResourceArn")
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.permSets[arn] == nil {
+		h.WriteJSONError(w, "ResourceNotFoundException", "Resource not found: "+arn, http.StatusBadRequest)
+		return
+	}
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"Tags": tags.ToList(s.tags.Get(arn), "Key", "Value"),
+	})
+}
+
 func (s *Service) createAccountAssignment(w http.ResponseWriter, params map[string]interface{}) {
 	instanceArn := h.GetString(params, "InstanceArn")
 	targetId := h.GetString(params, "TargetId")
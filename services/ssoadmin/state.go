@@ -0,0 +1,46 @@
+package ssoadmin
+
+import (
+	"strings"
+
+	"github.com/riyanimam/goto/internal/mockhelpers"
+)
+
+// ExportState lists the permission sets and the account assignments that
+// grant them.
+func (s *Service) ExportState() []mockhelpers.Resource {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	resources := make([]mockhelpers.Resource, 0, len(s.permSets)+len(s.assignments))
+	for _, ps := range s.permSets {
+		resources = append(resources, mockhelpers.Resource{
+			Type: "aws_ssoadmin_permission_set",
+			ID:   ps.arn + "," + ps.instanceArn,
+			ARN:  ps.arn,
+			Attributes: map[string]interface{}{
+				"name":             ps.name,
+				"description":      ps.description,
+				"session_duration": ps.sessionDuration,
+				"instance_arn":     ps.instanceArn,
+			},
+		})
+	}
+	for _, aa := range s.assignments {
+		resources = append(resources, mockhelpers.Resource{
+			Type: "aws_ssoadmin_account_assignment",
+			ID: strings.Join([]string{
+				aa.principalId, aa.principalType, aa.targetId, aa.targetType, aa.permissionSetArn, aa.instanceArn,
+			}, ","),
+			Attributes: map[string]interface{}{
+				"instance_arn":       aa.instanceArn,
+				"permission_set_arn": aa.permissionSetArn,
+				"principal_id":       aa.principalId,
+				"principal_type":     aa.principalType,
+				"target_id":          aa.targetId,
+				"target_type":        aa.targetType,
+			},
+		})
+	}
+	return resources
+}
@@ -0,0 +1,346 @@
+// Package ssooidc provides a mock implementation of the IAM Identity Center
+// OIDC service (SSO OIDC).
+//
+// Supported actions:
+//   - RegisterClient
+//   - StartDeviceAuthorization
+//   - CreateToken, with the device code and refresh token grants
+//
+// A device authorization is approved by visiting its verification URI,
+// which the mock serves under /_sso/device, as a user signing in through
+// the browser would. Until then CreateToken fails with
+// AuthorizationPendingException. Tokens and registrations expire on the
+// mock clock, and the SSO portal mock accepts the access tokens issued
+// here.
+package ssooidc
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/riyanimam/goto/internal/clock"
+	h "github.com/riyanimam/goto/internal/mockhelpers"
+)
+
+// VerificationPath is where the mock serves device verification URIs.
+const VerificationPath = "/_sso/device"
+
+const (
+	registrationLifetime = 90 * 24 * time.Hour
+	deviceCodeLifetime   = 10 * time.Minute
+	tokenLifetime        = 8 * time.Hour
+)
+
+// Service implements the SSO OIDC mock.
+type Service struct {
+	mu            sync.RWMutex
+	clients       map[string]*client
+	devices       map[string]*device // keyed by device code
+	tokens        map[string]*token  // keyed by access token
+	refreshTokens map[string]*token  // keyed by refresh token
+
+	baseURL string
+	clock   *clock.Clock
+}
+
+type client struct {
+	id, secret string
+	name       string
+	scopes     []string
+	issued     time.Time
+	expires    time.Time
+}
+
+type device struct {
+	code, userCode string
+	clientID       string
+	startURL       string
+	expires        time.Time
+	approved       bool
+	redeemed       bool
+}
+
+type token struct {
+	access, refresh string
+	clientID        string
+	startURL        string
+	expires         time.Time
+}
+
+// New creates a new SSO OIDC mock service.
+func New() *Service {
+	return &Service{
+		clients:       make(map[string]*client),
+		devices:       make(map[string]*device),
+		tokens:        make(map[string]*token),
+		refreshTokens: make(map[string]*token),
+	}
+}
+
+// Name returns the service identifier.
+func (s *Service) Name() string { return "sso-oauth" }
+
+// Handler returns the HTTP handler for SSO OIDC requests.
+func (s *Service) Handler() http.Handler {
+	return http.HandlerFunc(s.handle)
+}
+
+// Reset clears all state.
+func (s *Service) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.clients = make(map[string]*client)
+	s.devices = make(map[string]*device)
+	s.tokens = make(map[string]*token)
+	s.refreshTokens = make(map[string]*token)
+}
+
+// SetBaseURL sets the URL verification URIs are served at.
+func (s *Service) SetBaseURL(url string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.baseURL = url
+}
+
+// SetClock attaches the mock clock tokens expire on.
+func (s *Service) SetClock(c *clock.Clock) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.clock = c
+}
+
+func (s *Service) now() time.Time {
+	if s.clock != nil {
+		return s.clock.Now()
+	}
+	return time.Now().UTC()
+}
+
+// VerifyToken reports an error unless accessToken was issued here and has
+// neither expired nor been revoked.
+func (s *Service) VerifyToken(accessToken string) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	t, ok := s.tokens[accessToken]
+	if !ok || !s.now().Before(t.expires) {
+		return fmt.Errorf("the access token is invalid or expired")
+	}
+	return nil
+}
+
+// RevokeToken invalidates accessToken and its refresh token, as signing out
+// of the portal does.
+func (s *Service) RevokeToken(accessToken string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if t, ok := s.tokens[accessToken]; ok {
+		delete(s.tokens, accessToken)
+		delete(s.refreshTokens, t.refresh)
+	}
+}
+
+func (s *Service) handle(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == VerificationPath {
+		s.verify(w, r)
+		return
+	}
+	params := map[string]interface{}{}
+	if body, _ := io.ReadAll(r.Body); len(body) > 0 {
+		if err := json.Unmarshal(body, &params); err != nil {
+			writeError(w, "InvalidRequestException", "invalid_request", "Request body is not valid JSON.", http.StatusBadRequest)
+			return
+		}
+	}
+	switch {
+	case r.Method == http.MethodPost && r.URL.Path == "/client/register":
+		s.registerClient(w, params)
+	case r.Method == http.MethodPost && r.URL.Path == "/device_authorization":
+		s.startDeviceAuthorization(w, params)
+	case r.Method == http.MethodPost && r.URL.Path == "/token":
+		s.createToken(w, params)
+	default:
+		writeError(w, "InvalidRequestException", "invalid_request", "unsupported operation", http.StatusBadRequest)
+	}
+}
+
+// writeError writes an OAuth error, which SSO OIDC reports in the "error"
+// and "error_description" members as well as the error type.
+func writeError(w http.ResponseWriter, code, oauthError, description string, status int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Amzn-ErrorType", code)
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{
+		"error":             oauthError,
+		"error_description": description,
+	})
+}
+
+func (s *Service) registerClient(w http.ResponseWriter, params map[string]interface{}) {
+	name, clientType := h.GetString(params, "clientName"), h.GetString(params, "clientType")
+	if name == "" || clientType == "" {
+		writeError(w, "InvalidRequestException", "invalid_request", "clientName and clientType are required.", http.StatusBadRequest)
+		return
+	}
+	if clientType != "public" {
+		writeError(w, "InvalidClientMetadataException", "invalid_client_metadata", "clientType must be public.", http.StatusBadRequest)
+		return
+	}
+	var scopes []string
+	if list, ok := params["scopes"].([]interface{}); ok {
+		for _, v := range list {
+			if scope, ok := v.(string); ok {
+				scopes = append(scopes, scope)
+			}
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	c := &client{
+		id:      h.RandomID(22),
+		secret:  h.RandomHex(64),
+		name:    name,
+		scopes:  scopes,
+		issued:  now,
+		expires: now.Add(registrationLifetime),
+	}
+	s.clients[c.id] = c
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"clientId":              c.id,
+		"clientSecret":          c.secret,
+		"clientIdIssuedAt":      c.issued.Unix(),
+		"clientSecretExpiresAt": c.expires.Unix(),
+	})
+}
+
+// authenticate returns the registered client identified by the request's
+// clientId and clientSecret, writing an error if there is none. The caller
+// must hold s.mu.
+func (s *Service) authenticate(w http.ResponseWriter, params map[string]interface{}) *client {
+	c, ok := s.clients[h.GetString(params, "clientId")]
+	if !ok || c.secret != h.GetString(params, "clientSecret") {
+		writeError(w, "InvalidClientException", "invalid_client", "Invalid client credentials.", http.StatusUnauthorized)
+		return nil
+	}
+	if !s.now().Before(c.expires) {
+		writeError(w, "InvalidClientException", "invalid_client", "The client registration has expired.", http.StatusUnauthorized)
+		return nil
+	}
+	return c
+}
+
+func (s *Service) startDeviceAuthorization(w http.ResponseWriter, params map[string]interface{}) {
+	startURL := h.GetString(params, "startUrl")
+	if startURL == "" {
+		writeError(w, "InvalidRequestException", "invalid_request", "startUrl is required.", http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c := s.authenticate(w, params)
+	if c == nil {
+		return
+	}
+	d := &device{
+		code:     h.RandomHex(32),
+		userCode: h.RandomID(4) + "-" + h.RandomID(4),
+		clientID: c.id,
+		startURL: startURL,
+		expires:  s.now().Add(deviceCodeLifetime),
+	}
+	s.devices[d.code] = d
+	verificationURI := s.baseURL + VerificationPath
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"deviceCode":              d.code,
+		"userCode":                d.userCode,
+		"verificationUri":         verificationURI,
+		"verificationUriComplete": verificationURI + "?user_code=" + d.userCode,
+		"expiresIn":               int(deviceCodeLifetime / time.Second),
+		"interval":                1,
+	})
+}
+
+// verify approves the device authorization whose user code is given, as a
+// user signing in at the verification URI does.
+func (s *Service) verify(w http.ResponseWriter, r *http.Request) {
+	userCode := strings.ToUpper(r.URL.Query().Get("user_code"))
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, d := range s.devices {
+		if d.userCode == userCode && s.now().Before(d.expires) {
+			d.approved = true
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			fmt.Fprintf(w, "Request approved. You can close this window.\n")
+			return
+		}
+	}
+	http.Error(w, "Unknown or expired code.", http.StatusNotFound)
+}
+
+func (s *Service) createToken(w http.ResponseWriter, params map[string]interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c := s.authenticate(w, params)
+	if c == nil {
+		return
+	}
+	now := s.now()
+
+	var startURL string
+	switch grant := h.GetString(params, "grantType"); grant {
+	case "urn:ietf:params:oauth:grant-type:device_code":
+		d, ok := s.devices[h.GetString(params, "deviceCode")]
+		switch {
+		case !ok || d.clientID != c.id || d.redeemed:
+			writeError(w, "InvalidGrantException", "invalid_grant", "Invalid device code.", http.StatusBadRequest)
+			return
+		case !now.Before(d.expires):
+			writeError(w, "ExpiredTokenException", "expired_token", "The device code has expired.", http.StatusBadRequest)
+			return
+		case !d.approved:
+			writeError(w, "AuthorizationPendingException", "authorization_pending", "The user has not yet approved the request.", http.StatusBadRequest)
+			return
+		}
+		d.redeemed = true
+		startURL = d.startURL
+	case "refresh_token":
+		old, ok := s.refreshTokens[h.GetString(params, "refreshToken")]
+		if !ok || old.clientID != c.id {
+			writeError(w, "InvalidGrantException", "invalid_grant", "Invalid refresh token.", http.StatusBadRequest)
+			return
+		}
+		delete(s.refreshTokens, old.refresh)
+		delete(s.tokens, old.access)
+		startURL = old.startURL
+	case "":
+		writeError(w, "InvalidRequestException", "invalid_request", "grantType is required.", http.StatusBadRequest)
+		return
+	default:
+		writeError(w, "UnsupportedGrantTypeException", "unsupported_grant_type", "Unsupported grant type: "+grant+".", http.StatusBadRequest)
+		return
+	}
+
+	t := &token{
+		access:   "aoa" + h.RandomHex(60),
+		refresh:  "aor" + h.RandomHex(60),
+		clientID: c.id,
+		startURL: startURL,
+		expires:  now.Add(tokenLifetime),
+	}
+	s.tokens[t.access] = t
+	s.refreshTokens[t.refresh] = t
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"accessToken":  t.access,
+		"tokenType":    "Bearer",
+		"expiresIn":    int(tokenLifetime / time.Second),
+		"refreshToken": t.refresh,
+	})
+}
@@ -0,0 +1,307 @@
+// Package ssoportal provides a mock implementation of the IAM Identity
+// Center portal (the "sso" client of the SDK), which exchanges the access
+// tokens issued by the SSO OIDC mock for role credentials.
+//
+// Supported actions:
+//   - ListAccounts
+//   - ListAccountRoles
+//   - GetRoleCredentials
+//   - Logout
+//
+// The accounts and roles a token can reach are the account assignments
+// held by the SSO admin mock, a role being named after the permission set
+// assigned. While there are no assignments, the mock account is listed
+// with the roles held by the IAM mock, and credentials are issued for any
+// role in it.
+package ssoportal
+
+import (
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/riyanimam/goto/internal/clock"
+	h "github.com/riyanimam/goto/internal/mockhelpers"
+	"github.com/riyanimam/goto/internal/paginate"
+)
+
+// bearerHeader carries the access token on every portal request.
+const bearerHeader = "X-Amz-Sso_bearer_token"
+
+const defaultSessionDuration = time.Hour
+
+// Service implements the SSO portal mock.
+type Service struct {
+	mu       sync.RWMutex
+	verifier h.TokenVerifier
+	list     h.ResourceLister
+	clock    *clock.Clock
+}
+
+// New creates a new SSO portal mock service.
+func New() *Service {
+	return &Service{}
+}
+
+// Name returns the service identifier. The portal signs as "awsssoportal"
+// but its requests are unsigned, so the mock routes them by their bearer
+// token header under the portal's endpoint prefix.
+func (s *Service) Name() string { return "portal.sso" }
+
+// Handler returns the HTTP handler for SSO portal requests.
+func (s *Service) Handler() http.Handler {
+	return http.HandlerFunc(s.handle)
+}
+
+// Reset clears all state. The portal holds none of its own.
+func (s *Service) Reset() {}
+
+// SetTokenVerifier sets how the access tokens presented are checked.
+func (s *Service) SetTokenVerifier(v h.TokenVerifier) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.verifier = v
+}
+
+// SetResourceLister sets how the SSO admin assignments and IAM roles are
+// found.
+func (s *Service) SetResourceLister(l h.ResourceLister) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.list = l
+}
+
+// SetClock attaches the mock clock that credential expirations use.
+func (s *Service) SetClock(c *clock.Clock) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.clock = c
+}
+
+func (s *Service) now() time.Time {
+	if s.clock != nil {
+		return s.clock.Now()
+	}
+	return time.Now().UTC()
+}
+
+func writeUnauthorized(w http.ResponseWriter) {
+	h.WriteJSONError(w, "UnauthorizedException", "Session token not found or invalid", http.StatusUnauthorized)
+}
+
+func writeNotFound(w http.ResponseWriter, msg string) {
+	h.WriteJSONError(w, "ResourceNotFoundException", msg, http.StatusNotFound)
+}
+
+func writeInvalid(w http.ResponseWriter, msg string) {
+	h.WriteJSONError(w, "InvalidRequestException", msg, http.StatusBadRequest)
+}
+
+func (s *Service) handle(w http.ResponseWriter, r *http.Request) {
+	s.mu.RLock()
+	verifier := s.verifier
+	s.mu.RUnlock()
+	accessToken := r.Header.Get(bearerHeader)
+	if accessToken == "" || verifier == nil || verifier.VerifyToken(accessToken) != nil {
+		writeUnauthorized(w)
+		return
+	}
+
+	q := r.URL.Query()
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == "/assignment/accounts":
+		s.listAccounts(w, q.Get("next_token"), q.Get("max_result"))
+	case r.Method == http.MethodGet && r.URL.Path == "/assignment/roles":
+		s.listAccountRoles(w, q.Get("account_id"), q.Get("next_token"), q.Get("max_result"))
+	case r.Method == http.MethodGet && r.URL.Path == "/federation/credentials":
+		s.getRoleCredentials(w, q.Get("account_id"), q.Get("role_name"))
+	case r.Method == http.MethodPost && r.URL.Path == "/logout":
+		verifier.RevokeToken(accessToken)
+		w.WriteHeader(http.StatusOK)
+	default:
+		writeInvalid(w, "unsupported operation")
+	}
+}
+
+// role is a role a token can assume in an account, with the session
+// duration its credentials last for.
+type role struct {
+	account  string
+	name     string
+	duration time.Duration
+}
+
+// roles returns the roles reachable through the portal, and whether they
+// come from account assignments rather than the IAM mock.
+func (s *Service) roles() ([]role, bool) {
+	s.mu.RLock()
+	list := s.list
+	s.mu.RUnlock()
+	if list == nil {
+		return nil, false
+	}
+
+	permissionSets := map[string]h.Resource{}
+	var assigned []role
+	for _, res := range list("sso") {
+		if res.Type == "aws_ssoadmin_permission_set" {
+			permissionSets[res.ARN] = res
+		}
+	}
+	seen := map[role]bool{}
+	for _, res := range list("sso") {
+		if res.Type != "aws_ssoadmin_account_assignment" {
+			continue
+		}
+		ps, ok := permissionSets[h.GetString(res.Attributes, "permission_set_arn")]
+		if !ok {
+			continue
+		}
+		rl := role{
+			account:  h.GetString(res.Attributes, "target_id"),
+			name:     h.GetString(ps.Attributes, "name"),
+			duration: parseDuration(h.GetString(ps.Attributes, "session_duration")),
+		}
+		if !seen[rl] {
+			seen[rl] = true
+			assigned = append(assigned, rl)
+		}
+	}
+	if len(assigned) > 0 {
+		return assigned, true
+	}
+
+	var roles []role
+	for _, res := range list("iam") {
+		if res.Type == "aws_iam_role" {
+			roles = append(roles, role{account: h.DefaultAccountID, name: res.ID, duration: defaultSessionDuration})
+		}
+	}
+	return roles, false
+}
+
+var durationPattern = regexp.MustCompile(`^PT(?:(\d+)H)?(?:(\d+)M)?(?:(\d+)S)?$`)
+
+// parseDuration parses an ISO 8601 session duration such as "PT8H",
+// defaulting to an hour.
+func parseDuration(v string) time.Duration {
+	m := durationPattern.FindStringSubmatch(v)
+	if m == nil {
+		return defaultSessionDuration
+	}
+	var d time.Duration
+	for i, unit := range []time.Duration{time.Hour, time.Minute, time.Second} {
+		n, _ := strconv.Atoi(m[i+1])
+		d += time.Duration(n) * unit
+	}
+	if d <= 0 {
+		return defaultSessionDuration
+	}
+	return d
+}
+
+func maxResults(v string) int {
+	n, _ := strconv.Atoi(v)
+	return n
+}
+
+func (s *Service) listAccounts(w http.ResponseWriter, token, limit string) {
+	roles, _ := s.roles()
+	var accounts []string
+	seen := map[string]bool{}
+	for _, rl := range roles {
+		if !seen[rl.account] {
+			seen[rl.account] = true
+			accounts = append(accounts, rl.account)
+		}
+	}
+	if len(roles) == 0 {
+		accounts = append(accounts, h.DefaultAccountID)
+	}
+	sort.Strings(accounts)
+
+	page, next, err := paginate.Page(accounts, func(i int) string { return accounts[i] }, token, maxResults(limit), 1000)
+	if err != nil {
+		writeInvalid(w, "Invalid next_token")
+		return
+	}
+	list := make([]map[string]interface{}, 0, len(page))
+	for _, id := range page {
+		list = append(list, map[string]interface{}{
+			"accountId":    id,
+			"accountName":  "account-" + id,
+			"emailAddress": "admin+" + id + "@example.com",
+		})
+	}
+	resp := map[string]interface{}{"accountList": list}
+	if next != "" {
+		resp["nextToken"] = next
+	}
+	h.WriteJSON(w, http.StatusOK, resp)
+}
+
+func (s *Service) listAccountRoles(w http.ResponseWriter, account, token, limit string) {
+	if account == "" {
+		writeInvalid(w, "account_id is required")
+		return
+	}
+	roles, _ := s.roles()
+	var names []string
+	for _, rl := range roles {
+		if rl.account == account {
+			names = append(names, rl.name)
+		}
+	}
+	sort.Strings(names)
+
+	page, next, err := paginate.Page(names, func(i int) string { return names[i] }, token, maxResults(limit), 1000)
+	if err != nil {
+		writeInvalid(w, "Invalid next_token")
+		return
+	}
+	list := make([]map[string]interface{}, 0, len(page))
+	for _, name := range page {
+		list = append(list, map[string]interface{}{"roleName": name, "accountId": account})
+	}
+	resp := map[string]interface{}{"roleList": list}
+	if next != "" {
+		resp["nextToken"] = next
+	}
+	h.WriteJSON(w, http.StatusOK, resp)
+}
+
+func (s *Service) getRoleCredentials(w http.ResponseWriter, account, name string) {
+	if account == "" || name == "" {
+		writeInvalid(w, "account_id and role_name are required")
+		return
+	}
+	roles, assigned := s.roles()
+	duration := time.Duration(0)
+	for _, rl := range roles {
+		if rl.account == account && rl.name == name {
+			duration = rl.duration
+			break
+		}
+	}
+	// Without assignments, any role in the mock account can be assumed, so
+	// profiles need not create the IAM role first.
+	if duration == 0 && !assigned && account == h.DefaultAccountID {
+		duration = defaultSessionDuration
+	}
+	if duration == 0 {
+		writeNotFound(w, "No access to role "+name+" in account "+account)
+		return
+	}
+
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"roleCredentials": map[string]interface{}{
+			"accessKeyId":     "ASIA" + h.RandomID(16),
+			"secretAccessKey": h.RandomHex(40),
+			"sessionToken":    h.RandomHex(128),
+			"expiration":      s.now().Add(duration).UnixMilli(),
+		},
+	})
+}
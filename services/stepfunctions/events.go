@@ -0,0 +1,118 @@
+package stepfunctions
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	h "github.com/riyanimam/goto/internal/mockhelpers"
+)
+
+// defaultBusArn is the EventBridge bus that execution status changes are
+// sent to, as AWS does.
+var defaultBusArn = fmt.Sprintf("arn:aws:events:us-east-1:%s:event-bus/default", h.DefaultAccountID)
+
+// SetDispatcher sets the function used to send execution status change
+// events to EventBridge.
+func (s *Service) SetDispatcher(d h.Dispatcher) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.dispatch = d
+}
+
+// Succeed completes the running execution identified by arn with output.
+func (s *Service) Succeed(arn, output string) error {
+	return s.finish(arn, "SUCCEEDED", func(exec *execution) {
+		exec.output = output
+	})
+}
+
+// Fail completes the running execution identified by arn with the given
+// error name and cause.
+func (s *Service) Fail(arn, errName, cause string) error {
+	return s.finish(arn, "FAILED", func(exec *execution) {
+		exec.errName = errName
+		exec.cause = cause
+	})
+}
+
+// finish moves the running execution identified by arn to status, applying
+// set to it, and emits the status change.
+func (s *Service) finish(arn, status string, set func(*execution)) error {
+	s.mu.Lock()
+	exec, exists := s.executions[arn]
+	if !exists {
+		s.mu.Unlock()
+		return fmt.Errorf("execution %s does not exist", arn)
+	}
+	if exec.status != "RUNNING" {
+		s.mu.Unlock()
+		return fmt.Errorf("execution %s is %s, not RUNNING", arn, exec.status)
+	}
+	now := time.Now().UTC()
+	exec.status = status
+	exec.stopDate = &now
+	set(exec)
+	event := s.statusEvent(exec)
+	s.mu.Unlock()
+
+	s.emit(event)
+	return nil
+}
+
+// statusEvent builds the "Step Functions Execution Status Change" event for
+// the current status of exec. The caller must hold s.mu.
+func (s *Service) statusEvent(exec *execution) map[string]interface{} {
+	detail := map[string]interface{}{
+		"executionArn":    exec.arn,
+		"stateMachineArn": exec.stateMachineArn,
+		"name":            exec.name,
+		"status":          exec.status,
+		"startDate":       exec.startDate.UnixMilli(),
+		"stopDate":        nil,
+		"input":           exec.input,
+		"inputDetails":    map[string]interface{}{"included": true},
+		"output":          nil,
+		"outputDetails":   nil,
+		"error":           nil,
+		"cause":           nil,
+	}
+	if exec.stopDate != nil {
+		detail["stopDate"] = exec.stopDate.UnixMilli()
+	}
+	if exec.status == "SUCCEEDED" {
+		detail["output"] = exec.output
+		detail["outputDetails"] = map[string]interface{}{"included": true}
+	}
+	if exec.errName != "" {
+		detail["error"] = exec.errName
+	}
+	if exec.cause != "" {
+		detail["cause"] = exec.cause
+	}
+	return map[string]interface{}{
+		"version":     "0",
+		"id":          h.NewRequestID(),
+		"detail-type": "Step Functions Execution Status Change",
+		"source":      "aws.states",
+		"account":     h.DefaultAccountID,
+		"time":        time.Now().UTC().Format(time.RFC3339),
+		"region":      "us-east-1",
+		"resources":   []string{exec.arn},
+		"detail":      detail,
+	}
+}
+
+// emit sends event to the default event bus. It must be called without
+// holding s.mu, since the bus may deliver the event back to this service.
+// Delivery failures, such as EventBridge not running, are ignored.
+func (s *Service) emit(event map[string]interface{}) {
+	s.mu.RLock()
+	dispatch := s.dispatch
+	s.mu.RUnlock()
+	if dispatch == nil {
+		return
+	}
+	payload, _ := json.Marshal(event)
+	dispatch(defaultBusArn, payload)
+}
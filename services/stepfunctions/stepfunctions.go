@@ -9,12 +9,17 @@
 //   - DescribeExecution
 //   - ListExecutions
 //   - StopExecution
+//   - TagResource
+//   - UntagResource
+//   - ListTagsForResource
+//
+// Executions run until they are stopped or completed with [Service.Succeed]
+// or [Service.Fail]. Each status change is sent to the EventBridge default
+// bus as a "Step Functions Execution Status Change" event.
 package stepfunctions
 
 import (
-	"encoding/json"
 	"fmt"
-	"io"
 	"net/http"
 	"sort"
 	"strings"
@@ -22,6 +27,7 @@ import (
 	"time"
 
 	h "github.com/riyanimam/goto/internal/mockhelpers"
+	"github.com/riyanimam/goto/internal/tags"
 )
 
 // Service implements the Step Functions mock.
@@ -29,6 +35,8 @@ type Service struct {
 	mu            sync.RWMutex
 	stateMachines map[string]*stateMachine
 	executions    map[string]*execution
+	tags          *tags.Store
+	dispatch      h.Dispatcher
 }
 
 type stateMachine struct {
@@ -48,6 +56,8 @@ type execution struct {
 	status          string
 	input           string
 	output          string
+	errName         string
+	cause           string
 	startDate       time.Time
 	stopDate        *time.Time
 }
@@ -57,6 +67,7 @@ func New() *Service {
 	return &Service{
 		stateMachines: make(map[string]*stateMachine),
 		executions:    make(map[string]*execution),
+		tags:          tags.New(),
 	}
 }
 
@@ -65,7 +76,19 @@ func (s *Service) Name() string { return "states" }
 
 // Handler returns the HTTP handler for Step Functions requests.
 func (s *Service) Handler() http.Handler {
-	return http.HandlerFunc(s.handle)
+	return h.JSONRouter{
+		"CreateStateMachine":   s.createStateMachine,
+		"DeleteStateMachine":   s.deleteStateMachine,
+		"DescribeStateMachine": s.describeStateMachine,
+		"ListStateMachines":    s.listStateMachines,
+		"StartExecution":       s.startExecution,
+		"DescribeExecution":    s.describeExecution,
+		"ListExecutions":       s.listExecutions,
+		"StopExecution":        s.stopExecution,
+		"TagResource":          s.tagResource,
+		"UntagResource":        s.untagResource,
+		"ListTagsForResource":  s.listTagsForResource,
+	}
 }
 
 // Reset clears all state.
@@ -74,53 +97,14 @@ func (s *Service) Reset() {
 	defer s.mu.Unlock()
 	s.stateMachines = make(map[string]*stateMachine)
 	s.executions = make(map[string]*execution)
+	s.tags.DeleteService("states")
 }
 
-func (s *Service) handle(w http.ResponseWriter, r *http.Request) {
-	target := r.Header.Get("X-Amz-Target")
-
-	bodyBytes, err := io.ReadAll(r.Body)
-	if err != nil {
-		h.WriteJSONError(w, "InternalError", "could not read request body", http.StatusInternalServerError)
-		return
-	}
-
-	var params map[string]interface{}
-	if len(bodyBytes) > 0 {
-		json.Unmarshal(bodyBytes, &params)
-	}
-	if params == nil {
-		params = make(map[string]interface{})
-	}
-
-	action := ""
-	if target != "" {
-		parts := strings.SplitN(target, ".", 2)
-		if len(parts) == 2 {
-			action = parts[1]
-		}
-	}
-
-	switch action {
-	case "CreateStateMachine":
-		s.createStateMachine(w, params)
-	case "DeleteStateMachine":
-		s.deleteStateMachine(w, params)
-	case "DescribeStateMachine":
-		s.describeStateMachine(w, params)
-	case "ListStateMachines":
-		s.listStateMachines(w, params)
-	case "StartExecution":
-		s.startExecution(w, params)
-	case "DescribeExecution":
-		s.describeExecution(w, params)
-	case "ListExecutions":
-		s.listExecutions(w, params)
-	case "StopExecution":
-		s.stopExecution(w, params)
-	default:
-		h.WriteJSONError(w, "UnknownOperationException", fmt.Sprintf("action %q is not supported", action), http.StatusBadRequest)
-	}
+// SetTagStore sets the registry resource tags are recorded in.
+func (s *Service) SetTagStore(store *tags.Store) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tags = store
 }
 
 func (s *Service) createStateMachine(w http.ResponseWriter, params map[string]interface{}) {
@@ -155,6 +139,7 @@ func (s *Service) createStateMachine(w http.ResponseWriter, params map[string]in
 		created:    time.Now().UTC(),
 	}
 	s.stateMachines[arn] = sm
+	s.tags.Tag(arn, tags.FromList(params["tags"], "key", "value"))
 	s.mu.Unlock()
 
 	h.WriteJSON(w, http.StatusOK, map[string]interface{}{
@@ -173,6 +158,7 @@ func (s *Service) deleteStateMachine(w http.ResponseWriter, params map[string]in
 		return
 	}
 	delete(s.stateMachines, arn)
+	s.tags.Delete(arn)
 	s.mu.Unlock()
 
 	h.WriteJSON(w, http.StatusOK, map[string]interface{}{})
@@ -255,14 +241,57 @@ func (s *Service) startExecution(w http.ResponseWriter, params map[string]interf
 		startDate:       time.Now().UTC(),
 	}
 	s.executions[execArn] = exec
+	event := s.statusEvent(exec)
 	s.mu.Unlock()
 
+	s.emit(event)
+
 	h.WriteJSON(w, http.StatusOK, map[string]interface{}{
 		"executionArn": execArn,
 		"startDate":    float64(exec.startDate.Unix()),
 	})
 }
 
+// HasResource reports whether the state machine or execution identified by
+// arn exists.
+func (s *Service) HasResource(arn string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	_, machine := s.stateMachines[arn]
+	_, exec := s.executions[arn]
+	return machine || exec
+}
+
+// Deliver starts an execution of the state machine identified by arn with
+// payload as input and returns the new execution ARN.
+func (s *Service) Deliver(arn string, payload []byte) ([]byte, error) {
+	s.mu.Lock()
+	if _, exists := s.stateMachines[arn]; !exists {
+		s.mu.Unlock()
+		return nil, fmt.Errorf("state machine %s does not exist", arn)
+	}
+
+	name := h.NewRequestID()
+	execArn := fmt.Sprintf("arn:aws:states:us-east-1:%s:execution:%s:%s",
+		h.DefaultAccountID,
+		arn[strings.LastIndex(arn, ":")+1:],
+		name)
+	exec := &execution{
+		arn:             execArn,
+		name:            name,
+		stateMachineArn: arn,
+		status:          "RUNNING",
+		input:           string(payload),
+		startDate:       time.Now().UTC(),
+	}
+	s.executions[execArn] = exec
+	event := s.statusEvent(exec)
+	s.mu.Unlock()
+
+	s.emit(event)
+	return []byte(execArn), nil
+}
+
 func (s *Service) describeExecution(w http.ResponseWriter, params map[string]interface{}) {
 	execArn := h.GetString(params, "executionArn")
 
@@ -289,6 +318,10 @@ func (s *Service) describeExecution(w http.ResponseWriter, params map[string]int
 	if exec.stopDate != nil {
 		result["stopDate"] = float64(exec.stopDate.Unix())
 	}
+	if exec.errName != "" {
+		result["error"] = exec.errName
+		result["cause"] = exec.cause
+	}
 
 	h.WriteJSON(w, http.StatusOK, result)
 }
@@ -330,9 +363,61 @@ func (s *Service) stopExecution(w http.ResponseWriter, params map[string]interfa
 	now := time.Now().UTC()
 	exec.status = "ABORTED"
 	exec.stopDate = &now
+	if v := h.GetString(params, "error"); v != "" {
+		exec.errName = v
+	}
+	if v := h.GetString(params, "cause"); v != "" {
+		exec.cause = v
+	}
+	event := s.statusEvent(exec)
 	s.mu.Unlock()
 
+	s.emit(event)
+
 	h.WriteJSON(w, http.StatusOK, map[string]interface{}{
 		"stopDate": float64(now.Unix()),
 	})
 }
+
+func (s *Service) tagResource(w http.ResponseWriter, params map[string]interface{}) {
+	arn := h.GetString(params, "resourceArn")
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.stateMachines[arn] == nil {
+		h.WriteJSONError(w, "ResourceNotFound", "Resource not found: "+arn, http.StatusBadRequest)
+		return
+	}
+	s.tags.Tag(arn, tags.FromList(params["tags"], "key", "value"))
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{})
+}
+
+func (s *Service) untagResource(w http.ResponseWriter, params map[string]interface{}) {
+	arn := h.GetString(params, "resourceArn")
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.stateMachines[arn] == nil {
+		h.WriteJSONError(w, "ResourceNotFound", "Resource not found: "+arn, http.StatusBadRequest)
+		return
+	}
+	s.tags.Untag(arn, tags.Keys(params["tagKeys"]))
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{})
+}
+
+func (s *Service) listTagsForResource(w http.ResponseWriter, params map[string]interface{}) {
+	arn := h.GetString(params, "resourceArn")
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.stateMachines[arn] == nil {
+		h.WriteJSONError(w, "ResourceNotFound", "Resource not found: "+arn, http.StatusBadRequest)
+		return
+	}
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"tags": tags.ToList(s.tags.Get(arn), "key", "value"),
+	})
+}
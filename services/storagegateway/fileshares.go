@@ -0,0 +1,324 @@
+package storagegateway
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/riyanimam/goto/internal/arn"
+	"github.com/riyanimam/goto/internal/lifecycle"
+	h "github.com/riyanimam/goto/internal/mockhelpers"
+	"github.com/riyanimam/goto/internal/tags"
+)
+
+var (
+	storageClasses = []string{"S3_STANDARD", "S3_INTELLIGENT_TIERING", "S3_STANDARD_IA", "S3_ONEZONE_IA"}
+	objectACLs     = []string{"private", "public-read", "public-read-write", "authenticated-read", "bucket-owner-read", "bucket-owner-full-control", "aws-exec-read"}
+	squashes       = []string{"RootSquash", "NoSquash", "AllSquash"}
+	authentication = []string{"ActiveDirectory", "GuestAccess"}
+)
+
+type fileShare struct {
+	arn          string
+	id           string
+	kind         string // NFS or SMB
+	gatewayArn   string
+	clientToken  string
+	role         string
+	locationArn  string
+	name         string
+	storageClass string
+	objectACL    string
+	readOnly     bool
+	kmsKey       string
+	ready        lifecycle.Transition
+	// settings are the protocol-specific settings, as
+	// DescribeNFSFileShares or DescribeSMBFileShares report them.
+	settings map[string]interface{}
+}
+
+// oneOf returns params[key], or def if it is not set, and whether the
+// value is one of allowed.
+func oneOf(params map[string]interface{}, key, def string, allowed []string) (string, bool) {
+	v := h.GetString(params, key)
+	if v == "" {
+		v = def
+	}
+	for _, a := range allowed {
+		if v == a {
+			return v, true
+		}
+	}
+	return v, false
+}
+
+// parseLocation splits a file share LocationARN, the ARN of a bucket
+// optionally followed by a prefix, into the bucket name.
+func parseLocation(location string) (string, bool) {
+	a, err := arn.Parse(location)
+	if err != nil || a.Service != "s3" || a.Region != "" || a.AccountID != "" {
+		return "", false
+	}
+	bucket, _, _ := strings.Cut(a.Resource, "/")
+	return bucket, bucket != ""
+}
+
+// createFileShare returns the handler creating file shares of the kind,
+// NFS or SMB.
+func (s *Service) createFileShare(kind string) h.JSONHandler {
+	return func(w http.ResponseWriter, params map[string]interface{}) {
+		token := h.GetString(params, "ClientToken")
+		if len(token) < 5 || len(token) > 100 {
+			writeError(w, "InvalidParameters", "ClientToken must be between 5 and 100 characters.")
+			return
+		}
+		role := h.GetString(params, "Role")
+		if role == "" {
+			writeError(w, "InvalidParameters", "Role is required.")
+			return
+		}
+		location := h.GetString(params, "LocationARN")
+		bucket, ok := parseLocation(location)
+		if !ok {
+			writeError(w, "InvalidParameters", "LocationARN must be the ARN of an S3 bucket, optionally followed by a prefix.")
+			return
+		}
+		fs := &fileShare{
+			kind:        kind,
+			clientToken: token,
+			role:        role,
+			locationArn: location,
+			name:        h.GetString(params, "FileShareName"),
+			readOnly:    h.GetBool(params, "ReadOnly"),
+			kmsKey:      h.GetString(params, "KMSKey"),
+		}
+		if fs.name == "" {
+			fs.name = bucket
+		}
+		if fs.kmsKey == "" && h.GetBool(params, "KMSEncrypted") {
+			writeError(w, "InvalidParameters", "KMSKey is required when KMSEncrypted is true.")
+			return
+		}
+		var valid bool
+		if fs.storageClass, valid = oneOf(params, "DefaultStorageClass", "S3_STANDARD", storageClasses); !valid {
+			writeError(w, "InvalidParameters", "Invalid DefaultStorageClass: "+fs.storageClass)
+			return
+		}
+		if fs.objectACL, valid = oneOf(params, "ObjectACL", "private", objectACLs); !valid {
+			writeError(w, "InvalidParameters", "Invalid ObjectACL: "+fs.objectACL)
+			return
+		}
+		if kind == "NFS" {
+			msg := fs.nfsSettings(params)
+			if msg != "" {
+				writeError(w, "InvalidParameters", msg)
+				return
+			}
+		} else if msg := fs.smbSettings(params); msg != "" {
+			writeError(w, "InvalidParameters", msg)
+			return
+		}
+
+		s.mu.RLock()
+		objects, resolve := s.objects, s.resolve
+		s.mu.RUnlock()
+		if objects != nil {
+			if _, err := objects.ListObjects(bucket, ""); err != nil {
+				writeError(w, "InvalidParameters", "The bucket "+bucket+" does not exist.")
+				return
+			}
+		}
+		if resolve != nil {
+			if err := resolve(role); err != nil {
+				writeError(w, "InvalidParameters", "The role "+role+" does not exist.")
+				return
+			}
+		}
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		g, ok := s.lookupGateway(w, h.GetString(params, "GatewayARN"))
+		if !ok {
+			return
+		}
+		if g.kind != "FILE_S3" {
+			writeError(w, "NotSupported", "File shares can only be created on S3 File Gateways.")
+			return
+		}
+		for _, other := range s.fileShares {
+			if other.gatewayArn != g.arn {
+				continue
+			}
+			if other.clientToken == token {
+				h.WriteJSON(w, http.StatusOK, map[string]interface{}{"FileShareARN": other.arn})
+				return
+			}
+			if other.name == fs.name {
+				writeError(w, "InvalidParameters", "The gateway already has a file share named "+fs.name+".")
+				return
+			}
+		}
+		fs.id = "share-" + strings.ToUpper(h.RandomHex(8))
+		fs.arn = strings.Replace(g.arn, ":gateway/"+g.id, ":share/"+fs.id, 1)
+		fs.gatewayArn = g.arn
+		fs.ready = s.transitions.Begin()
+		s.fileShares[fs.arn] = fs
+		s.tags.Tag(fs.arn, tags.FromList(params["Tags"], "Key", "Value"))
+		h.WriteJSON(w, http.StatusOK, map[string]interface{}{"FileShareARN": fs.arn})
+	}
+}
+
+// nfsSettings reads the NFS-specific settings of a new file share,
+// returning a message describing the first problem if they are invalid.
+func (fs *fileShare) nfsSettings(params map[string]interface{}) string {
+	squash, valid := oneOf(params, "Squash", "RootSquash", squashes)
+	if !valid {
+		return "Invalid Squash: " + squash
+	}
+	clients, _ := params["ClientList"].([]interface{})
+	if len(clients) == 0 {
+		clients = []interface{}{"0.0.0.0/0"}
+	}
+	defaults := map[string]interface{}{"FileMode": "0666", "DirectoryMode": "0777", "GroupId": 65534, "OwnerId": 65534}
+	if given, ok := params["NFSFileShareDefaults"].(map[string]interface{}); ok {
+		for k, v := range given {
+			defaults[k] = v
+		}
+	}
+	fs.settings = map[string]interface{}{
+		"Path":                 "/" + fs.name,
+		"ClientList":           clients,
+		"Squash":               squash,
+		"NFSFileShareDefaults": defaults,
+	}
+	return ""
+}
+
+// smbSettings reads the SMB-specific settings of a new file share,
+// returning a message describing the first problem if they are invalid.
+func (fs *fileShare) smbSettings(params map[string]interface{}) string {
+	auth, valid := oneOf(params, "Authentication", "ActiveDirectory", authentication)
+	if !valid {
+		return "Invalid Authentication: " + auth
+	}
+	users := func(key string) []interface{} {
+		if v, ok := params[key].([]interface{}); ok {
+			return v
+		}
+		return []interface{}{}
+	}
+	if auth == "GuestAccess" && (len(users("ValidUserList")) > 0 || len(users("AdminUserList")) > 0) {
+		return "User lists require ActiveDirectory authentication."
+	}
+	caseSensitivity, valid := oneOf(params, "CaseSensitivity", "ClientSpecified", []string{"ClientSpecified", "CaseSensitive"})
+	if !valid {
+		return "Invalid CaseSensitivity: " + caseSensitivity
+	}
+	fs.settings = map[string]interface{}{
+		"Path":                   "/" + fs.name,
+		"Authentication":         auth,
+		"ValidUserList":          users("ValidUserList"),
+		"InvalidUserList":        users("InvalidUserList"),
+		"AdminUserList":          users("AdminUserList"),
+		"CaseSensitivity":        caseSensitivity,
+		"SMBACLEnabled":          h.GetBool(params, "SMBACLEnabled"),
+		"AccessBasedEnumeration": h.GetBool(params, "AccessBasedEnumeration"),
+	}
+	return ""
+}
+
+// status returns the file share's status. The caller must hold s.mu.
+func (s *Service) status(fs *fileShare) string {
+	return s.transitions.Status(fs.ready, "CREATING", "AVAILABLE")
+}
+
+// fileShareResp returns the file share as DescribeNFSFileShares or
+// DescribeSMBFileShares reports it. The caller must hold s.mu.
+func (s *Service) fileShareResp(fs *fileShare) map[string]interface{} {
+	resp := map[string]interface{}{
+		"FileShareARN":         fs.arn,
+		"FileShareId":          fs.id,
+		"FileShareStatus":      s.status(fs),
+		"FileShareName":        fs.name,
+		"GatewayARN":           fs.gatewayArn,
+		"Role":                 fs.role,
+		"LocationARN":          fs.locationArn,
+		"DefaultStorageClass":  fs.storageClass,
+		"ObjectACL":            fs.objectACL,
+		"ReadOnly":             fs.readOnly,
+		"KMSEncrypted":         fs.kmsKey != "",
+		"GuessMIMETypeEnabled": true,
+		"RequesterPays":        false,
+		"Tags":                 tags.ToList(s.tags.Get(fs.arn), "Key", "Value"),
+	}
+	if fs.kmsKey != "" {
+		resp["KMSKey"] = fs.kmsKey
+	}
+	for k, v := range fs.settings {
+		resp[k] = v
+	}
+	return resp
+}
+
+// describeFileShares returns the handler describing file shares of the
+// kind, NFS or SMB.
+func (s *Service) describeFileShares(kind string) h.JSONHandler {
+	return func(w http.ResponseWriter, params map[string]interface{}) {
+		arns, _ := params["FileShareARNList"].([]interface{})
+		if len(arns) == 0 || len(arns) > 10 {
+			writeError(w, "InvalidParameters", "FileShareARNList requires between 1 and 10 ARNs.")
+			return
+		}
+		s.mu.RLock()
+		defer s.mu.RUnlock()
+		infos := make([]map[string]interface{}, 0, len(arns))
+		for _, v := range arns {
+			a, _ := v.(string)
+			fs, exists := s.fileShares[a]
+			if !exists || fs.kind != kind {
+				writeError(w, "InvalidParameters", "The specified "+kind+" file share was not found: "+a)
+				return
+			}
+			infos = append(infos, s.fileShareResp(fs))
+		}
+		h.WriteJSON(w, http.StatusOK, map[string]interface{}{kind + "FileShareInfoList": infos})
+	}
+}
+
+func (s *Service) listFileShares(w http.ResponseWriter, params map[string]interface{}) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	gatewayArn := h.GetString(params, "GatewayARN")
+	if gatewayArn != "" {
+		if _, ok := s.lookupGateway(w, gatewayArn); !ok {
+			return
+		}
+	}
+	var out []map[string]interface{}
+	for _, a := range sortedKeys(s.fileShares) {
+		fs := s.fileShares[a]
+		if gatewayArn != "" && fs.gatewayArn != gatewayArn {
+			continue
+		}
+		out = append(out, map[string]interface{}{
+			"FileShareType":   fs.kind,
+			"FileShareARN":    fs.arn,
+			"FileShareId":     fs.id,
+			"FileShareStatus": s.status(fs),
+			"GatewayARN":      fs.gatewayArn,
+		})
+	}
+	writePage(w, out, params, "FileShareInfoList", "FileShareARN")
+}
+
+func (s *Service) deleteFileShare(w http.ResponseWriter, params map[string]interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	a := h.GetString(params, "FileShareARN")
+	if s.fileShares[a] == nil {
+		writeError(w, "InvalidParameters", "The specified file share was not found: "+a)
+		return
+	}
+	delete(s.fileShares, a)
+	s.tags.Delete(a)
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{"FileShareARN": a})
+}
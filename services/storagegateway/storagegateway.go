@@ -0,0 +1,378 @@
+// Package storagegateway provides a mock implementation of AWS Storage
+// Gateway.
+//
+// Supported actions:
+//   - ActivateGateway, DescribeGatewayInformation, ListGateways,
+//     UpdateGatewayInformation, DeleteGateway
+//   - CreateNFSFileShare, CreateSMBFileShare, DescribeNFSFileShares,
+//     DescribeSMBFileShares, ListFileShares, DeleteFileShare
+//   - AddTagsToResource, RemoveTagsFromResource, ListTagsForResource
+//
+// There is no gateway appliance: activation keys are accepted once each
+// and the gateway is RUNNING at once. File shares, which only S3 File
+// Gateways (FILE_S3) can hold, must name an existing bucket in the S3 mock
+// and an existing IAM role, and are CREATING until their lifecycle
+// transition completes, and then AVAILABLE. No NFS or SMB server is
+// provided.
+package storagegateway
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/riyanimam/goto/internal/clock"
+	"github.com/riyanimam/goto/internal/lifecycle"
+	h "github.com/riyanimam/goto/internal/mockhelpers"
+	"github.com/riyanimam/goto/internal/paginate"
+	"github.com/riyanimam/goto/internal/tags"
+)
+
+// gatewayTypes are the kinds of gateway that can be activated.
+var gatewayTypes = map[string]bool{
+	"STORED":        true,
+	"CACHED":        true,
+	"VTL":           true,
+	"FILE_S3":       true,
+	"FILE_FSX_SMB":  true,
+	"VTL_SNOW":      true,
+	"FILE_S3_SNOW":  true,
+	"CACHED_SNOW":   true,
+	"STORED_SNOW":   true,
+	"FILE_FSX_SNOW": true,
+}
+
+// Service implements the Storage Gateway mock.
+type Service struct {
+	mu         sync.RWMutex
+	gateways   map[string]*gateway
+	fileShares map[string]*fileShare
+	// activated records the activation keys used, which cannot be reused.
+	activated map[string]bool
+
+	objects     h.ObjectStore
+	resolve     h.Resolver
+	tags        *tags.Store
+	clock       *clock.Clock
+	transitions *lifecycle.Transitions
+}
+
+type gateway struct {
+	arn       string
+	id        string
+	name      string
+	timezone  string
+	kind      string
+	ipAddress string
+	logGroup  string
+	created   time.Time
+}
+
+// New creates a new Storage Gateway mock service.
+func New() *Service {
+	return &Service{
+		gateways:   make(map[string]*gateway),
+		fileShares: make(map[string]*fileShare),
+		activated:  make(map[string]bool),
+		tags:       tags.New(),
+	}
+}
+
+// Name returns the service identifier.
+func (s *Service) Name() string { return "storagegateway" }
+
+// Handler returns the HTTP handler for Storage Gateway requests.
+func (s *Service) Handler() http.Handler {
+	return h.JSONRouter{
+		"ActivateGateway":            s.activateGateway,
+		"DescribeGatewayInformation": s.describeGatewayInformation,
+		"ListGateways":               s.listGateways,
+		"UpdateGatewayInformation":   s.updateGatewayInformation,
+		"DeleteGateway":              s.deleteGateway,
+		"CreateNFSFileShare":         s.createFileShare("NFS"),
+		"CreateSMBFileShare":         s.createFileShare("SMB"),
+		"DescribeNFSFileShares":      s.describeFileShares("NFS"),
+		"DescribeSMBFileShares":      s.describeFileShares("SMB"),
+		"ListFileShares":             s.listFileShares,
+		"DeleteFileShare":            s.deleteFileShare,
+		"AddTagsToResource":          s.addTagsToResource,
+		"RemoveTagsFromResource":     s.removeTagsFromResource,
+		"ListTagsForResource":        s.listTagsForResource,
+	}
+}
+
+// Reset clears all state.
+func (s *Service) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.gateways = make(map[string]*gateway)
+	s.fileShares = make(map[string]*fileShare)
+	s.activated = make(map[string]bool)
+	s.tags.DeleteService("storagegateway")
+}
+
+// SetObjectStore sets the S3 buckets file shares are backed by.
+func (s *Service) SetObjectStore(store h.ObjectStore) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.objects = store
+}
+
+// SetResolver sets the function used to check file share roles.
+func (s *Service) SetResolver(r h.Resolver) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.resolve = r
+}
+
+// SetTagStore sets the registry resource tags are recorded in.
+func (s *Service) SetTagStore(store *tags.Store) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tags = store
+}
+
+// SetClock attaches the mock clock used for activation times.
+func (s *Service) SetClock(c *clock.Clock) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.clock = c
+}
+
+// SetTransitions sets how long file shares stay CREATING.
+func (s *Service) SetTransitions(t *lifecycle.Transitions) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.transitions = t
+}
+
+func (s *Service) now() time.Time {
+	if s.clock != nil {
+		return s.clock.Now()
+	}
+	return time.Now().UTC()
+}
+
+// writeError writes an InvalidGatewayRequestException, the error Storage
+// Gateway reports for client errors, with its detailed error code.
+func writeError(w http.ResponseWriter, errorCode, message string) {
+	w.Header().Set("X-Amzn-ErrorType", "InvalidGatewayRequestException")
+	h.WriteJSON(w, http.StatusBadRequest, map[string]interface{}{
+		"__type":  "InvalidGatewayRequestException",
+		"message": message,
+		"error":   map[string]interface{}{"errorCode": errorCode},
+	})
+}
+
+func (s *Service) activateGateway(w http.ResponseWriter, params map[string]interface{}) {
+	key := h.GetString(params, "ActivationKey")
+	name := h.GetString(params, "GatewayName")
+	timezone := h.GetString(params, "GatewayTimezone")
+	region := h.GetString(params, "GatewayRegion")
+	if key == "" || len(key) > 50 || name == "" || len(name) > 255 || timezone == "" || region == "" {
+		writeError(w, "InvalidParameters", "ActivationKey, GatewayName, GatewayTimezone, and GatewayRegion are required.")
+		return
+	}
+	if !strings.HasPrefix(timezone, "GMT") {
+		writeError(w, "InvalidParameters", "GatewayTimezone must be of the form GMT, GMT-hr:mm, or GMT+hr:mm.")
+		return
+	}
+	kind := h.GetString(params, "GatewayType")
+	if kind == "" {
+		kind = "STORED"
+	}
+	if !gatewayTypes[kind] {
+		writeError(w, "InvalidParameters", "Invalid GatewayType: "+kind)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.activated[key] {
+		writeError(w, "ActivationKeyInvalid", "The activation key has already been used.")
+		return
+	}
+	s.activated[key] = true
+	n := len(s.activated)
+	id := "sgw-" + strings.ToUpper(h.RandomHex(8))
+	g := &gateway{
+		arn:       fmt.Sprintf("arn:aws:storagegateway:%s:%s:gateway/%s", region, h.DefaultAccountID, id),
+		id:        id,
+		name:      name,
+		timezone:  timezone,
+		kind:      kind,
+		ipAddress: fmt.Sprintf("10.0.%d.%d", n/254, n%254+1),
+		created:   s.now(),
+	}
+	s.gateways[g.arn] = g
+	s.tags.Tag(g.arn, tags.FromList(params["Tags"], "Key", "Value"))
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{"GatewayARN": g.arn})
+}
+
+// lookupGateway returns the gateway, or writes an error. The caller must
+// hold s.mu.
+func (s *Service) lookupGateway(w http.ResponseWriter, arn string) (*gateway, bool) {
+	g, exists := s.gateways[arn]
+	if !exists {
+		writeError(w, "GatewayNotFound", "The specified gateway was not found.")
+	}
+	return g, exists
+}
+
+func (s *Service) describeGatewayInformation(w http.ResponseWriter, params map[string]interface{}) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	g, ok := s.lookupGateway(w, h.GetString(params, "GatewayARN"))
+	if !ok {
+		return
+	}
+	resp := map[string]interface{}{
+		"GatewayARN":               g.arn,
+		"GatewayId":                g.id,
+		"GatewayName":              g.name,
+		"GatewayTimezone":          g.timezone,
+		"GatewayState":             "RUNNING",
+		"GatewayType":              g.kind,
+		"GatewayNetworkInterfaces": []map[string]interface{}{{"Ipv4Address": g.ipAddress}},
+		"HostEnvironment":          "VMWARE",
+		"EndpointType":             "STANDARD",
+		"SoftwareVersion":          "2.0",
+		"Tags":                     tags.ToList(s.tags.Get(g.arn), "Key", "Value"),
+	}
+	if g.logGroup != "" {
+		resp["CloudWatchLogGroupARN"] = g.logGroup
+	}
+	h.WriteJSON(w, http.StatusOK, resp)
+}
+
+func (s *Service) listGateways(w http.ResponseWriter, params map[string]interface{}) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	out := make([]map[string]interface{}, 0, len(s.gateways))
+	for _, arn := range sortedKeys(s.gateways) {
+		g := s.gateways[arn]
+		out = append(out, map[string]interface{}{
+			"GatewayARN":              g.arn,
+			"GatewayId":               g.id,
+			"GatewayName":             g.name,
+			"GatewayType":             g.kind,
+			"GatewayOperationalState": "ACTIVE",
+			"HostEnvironment":         "VMWARE",
+		})
+	}
+	writePage(w, out, params, "Gateways", "GatewayARN")
+}
+
+func (s *Service) updateGatewayInformation(w http.ResponseWriter, params map[string]interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	g, ok := s.lookupGateway(w, h.GetString(params, "GatewayARN"))
+	if !ok {
+		return
+	}
+	if v := h.GetString(params, "GatewayTimezone"); v != "" {
+		if !strings.HasPrefix(v, "GMT") {
+			writeError(w, "InvalidParameters", "GatewayTimezone must be of the form GMT, GMT-hr:mm, or GMT+hr:mm.")
+			return
+		}
+		g.timezone = v
+	}
+	if v := h.GetString(params, "GatewayName"); v != "" {
+		g.name = v
+	}
+	if v, ok := params["CloudWatchLogGroupARN"].(string); ok {
+		g.logGroup = v
+	}
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{"GatewayARN": g.arn, "GatewayName": g.name})
+}
+
+// deleteGateway removes the gateway and its file shares, as the objects in
+// their buckets stay where they are.
+func (s *Service) deleteGateway(w http.ResponseWriter, params map[string]interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	g, ok := s.lookupGateway(w, h.GetString(params, "GatewayARN"))
+	if !ok {
+		return
+	}
+	for arn, fs := range s.fileShares {
+		if fs.gatewayArn == g.arn {
+			delete(s.fileShares, arn)
+			s.tags.Delete(arn)
+		}
+	}
+	delete(s.gateways, g.arn)
+	s.tags.Delete(g.arn)
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{"GatewayARN": g.arn})
+}
+
+func sortedKeys[T any](m map[string]T) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// writePage writes a page of items, which are sorted by their field named
+// field, under key, with Limit and Marker from params.
+func writePage(w http.ResponseWriter, items []map[string]interface{}, params map[string]interface{}, key, field string) {
+	page, next, err := paginate.Page(items, func(i int) string { return items[i][field].(string) }, h.GetString(params, "Marker"), h.GetInt(params, "Limit", 100), 100)
+	if err != nil {
+		writeError(w, "InvalidParameters", "Invalid Marker.")
+		return
+	}
+	resp := map[string]interface{}{key: page}
+	if next != "" {
+		resp["Marker"] = next
+	}
+	h.WriteJSON(w, http.StatusOK, resp)
+}
+
+// taggable reports whether arn names a gateway or file share. The caller
+// must hold s.mu.
+func (s *Service) taggable(arn string) bool {
+	return s.gateways[arn] != nil || s.fileShares[arn] != nil
+}
+
+func (s *Service) addTagsToResource(w http.ResponseWriter, params map[string]interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	arn := h.GetString(params, "ResourceARN")
+	if !s.taggable(arn) {
+		writeError(w, "InvalidParameters", "Resource "+arn+" not found.")
+		return
+	}
+	s.tags.Tag(arn, tags.FromList(params["Tags"], "Key", "Value"))
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{"ResourceARN": arn})
+}
+
+func (s *Service) removeTagsFromResource(w http.ResponseWriter, params map[string]interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	arn := h.GetString(params, "ResourceARN")
+	if !s.taggable(arn) {
+		writeError(w, "InvalidParameters", "Resource "+arn+" not found.")
+		return
+	}
+	s.tags.Untag(arn, tags.Keys(params["TagKeys"]))
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{"ResourceARN": arn})
+}
+
+func (s *Service) listTagsForResource(w http.ResponseWriter, params map[string]interface{}) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	arn := h.GetString(params, "ResourceARN")
+	if !s.taggable(arn) {
+		writeError(w, "InvalidParameters", "Resource "+arn+" not found.")
+		return
+	}
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"ResourceARN": arn,
+		"Tags":        tags.ToList(s.tags.Get(arn), "Key", "Value"),
+	})
+}
@@ -0,0 +1,90 @@
+package sts
+
+import (
+	"encoding/base64"
+	"encoding/xml"
+	"math/rand"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+var accessKeyID = regexp.MustCompile(`^\w{16,128}$`)
+
+// authorizationMessagePrefix marks messages encoded by
+// EncodeAuthorizationMessage.
+const authorizationMessagePrefix = "awsmock:"
+
+// EncodeAuthorizationMessage encodes decoded, a JSON authorization failure
+// document, the way services return it in the "Encoded authorization
+// failure message" of an access denied error. DecodeAuthorizationMessage
+// returns decoded for the result.
+func EncodeAuthorizationMessage(decoded string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(authorizationMessagePrefix + decoded))
+}
+
+// newAccessKeyID returns a temporary access key ID and records the account
+// it belongs to for GetAccessKeyInfo.
+func (s *Service) newAccessKeyID(account string) string {
+	const chars = "ABCDEFGHIJKLMNOPQRSTUVWXYZ234567"
+	b := []byte("ASIA")
+	for i := 0; i < 16; i++ {
+		b = append(b, chars[rand.Intn(len(chars))])
+	}
+	id := string(b)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.accessKeys[id] = account
+	return id
+}
+
+// getAccessKeyInfo returns the account of temporary credentials STS issued,
+// and the mock's account for any other key.
+func (s *Service) getAccessKeyInfo(w http.ResponseWriter, r *http.Request) {
+	id := r.FormValue("AccessKeyId")
+	if !accessKeyID.MatchString(id) {
+		writeSTSError(w, "ValidationError", "1 validation error detected: Value at 'accessKeyId' failed to satisfy constraint", http.StatusBadRequest)
+		return
+	}
+
+	s.mu.RLock()
+	account, ok := s.accessKeys[id]
+	if !ok {
+		account = s.accountID
+	}
+	s.mu.RUnlock()
+
+	writeXML(w, http.StatusOK, getAccessKeyInfoResponse{
+		Account:   account,
+		RequestID: newRequestID(),
+	})
+}
+
+func (s *Service) decodeAuthorizationMessage(w http.ResponseWriter, r *http.Request) {
+	raw, err := base64.RawURLEncoding.DecodeString(r.FormValue("EncodedMessage"))
+	decoded, ok := strings.CutPrefix(string(raw), authorizationMessagePrefix)
+	if err != nil || !ok {
+		writeSTSError(w, "InvalidAuthorizationMessageException",
+			"The error message cannot be decoded because it is not a valid encoded authorization message.", http.StatusBadRequest)
+		return
+	}
+	writeXML(w, http.StatusOK, decodeAuthorizationMessageResponse{
+		DecodedMessage: decoded,
+		RequestID:      newRequestID(),
+	})
+}
+
+type getAccessKeyInfoResponse struct {
+	XMLName   xml.Name `xml:"GetAccessKeyInfoResponse"`
+	XMLNS     string   `xml:"xmlns,attr"`
+	Account   string   `xml:"GetAccessKeyInfoResult>Account"`
+	RequestID string   `xml:"ResponseMetadata>RequestId"`
+}
+
+type decodeAuthorizationMessageResponse struct {
+	XMLName        xml.Name `xml:"DecodeAuthorizationMessageResponse"`
+	XMLNS          string   `xml:"xmlns,attr"`
+	DecodedMessage string   `xml:"DecodeAuthorizationMessageResult>DecodedMessage"`
+	RequestID      string   `xml:"ResponseMetadata>RequestId"`
+}
@@ -0,0 +1,64 @@
+package sts
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// endpoint identifies the STS endpoint a request was sent to, from the host
+// it addresses: "sts.localhost" for the global endpoint, as
+// sts.amazonaws.com, or "sts.{region}.localhost" for a regional one.
+type endpoint struct {
+	global bool
+	region string // "" when the host names no endpoint
+}
+
+func endpointOf(r *http.Request) endpoint {
+	host, _, err := net.SplitHostPort(r.Host)
+	if err != nil {
+		host = r.Host
+	}
+	rest, ok := strings.CutPrefix(host, "sts.")
+	if !ok {
+		return endpoint{}
+	}
+	if rest == "localhost" {
+		return endpoint{global: true, region: "us-east-1"}
+	}
+	region, ok := strings.CutSuffix(rest, ".localhost")
+	if !ok {
+		return endpoint{}
+	}
+	return endpoint{region: region}
+}
+
+// checkScope reports the error STS returns when a request is signed for a
+// region other than the endpoint's: the global endpoint only accepts
+// signatures for us-east-1, and each regional endpoint for its own region.
+func (e endpoint) checkScope(r *http.Request) error {
+	if e.region == "" {
+		return nil
+	}
+	auth := r.Header.Get("Authorization")
+	idx := strings.Index(auth, "Credential=")
+	if idx < 0 {
+		return nil
+	}
+	parts := strings.Split(auth[idx:], "/")
+	if len(parts) < 4 || parts[2] == e.region {
+		return nil
+	}
+	return fmt.Errorf("Credential should be scoped to a valid region, not '%s'.", parts[2])
+}
+
+// sessionToken returns a new session token. Regional endpoints issue
+// version 2 tokens, valid in every region; the global endpoint issues the
+// shorter version 1 tokens, valid only in regions enabled by default.
+func (e endpoint) sessionToken() string {
+	if e.region != "" && !e.global {
+		return "IQoJb3JpZ2luX2VjEBY" + newRequestID() + newRequestID()
+	}
+	return "FwoGZXIvYXdzEBY" + newRequestID()
+}
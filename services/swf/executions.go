@@ -0,0 +1,460 @@
+package swf
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	h "github.com/riyanimam/goto/internal/mockhelpers"
+	"github.com/riyanimam/goto/internal/paginate"
+)
+
+type execution struct {
+	domain          *domain
+	workflowID      string
+	runID           string
+	workflowType    typeKey
+	config          map[string]interface{} // executionConfiguration
+	tags            []interface{}
+	started         time.Time
+	closed          time.Time
+	closeStatus     string // "" while the execution is open
+	cancelRequested bool
+	events          []map[string]interface{}
+
+	// decisionScheduled and decisionStarted are the event IDs of the
+	// execution's queued and in-flight decision tasks, or 0. A decision is
+	// pending if events arrive while one is in flight; another decision
+	// task is scheduled when it completes.
+	decisionScheduled int
+	decisionStarted   int
+	decisionPending   bool
+	lastDecision      int // started event ID of the last completed decision
+
+	activities     map[string]*activity // open activities by activity ID
+	timers         map[string]*timer    // running timers by timer ID
+	latestContext  string
+	latestActivity time.Time
+}
+
+type activity struct {
+	id              string
+	activityType    typeKey
+	taskList        string
+	input           string
+	scheduledID     int
+	startedID       int
+	cancelRequested bool
+	task            *task
+}
+
+type timer struct {
+	id        string
+	startedID int
+	fireAt    time.Time
+}
+
+func (e *execution) open() bool { return e.closeStatus == "" }
+
+func (e *execution) ref() map[string]string {
+	return map[string]string{"workflowId": e.workflowID, "runId": e.runID}
+}
+
+func (e *execution) taskList() string {
+	tl, _ := e.config["taskList"].(map[string]interface{})
+	return h.GetString(tl, "name")
+}
+
+// addEvent appends an event to e's history and returns its ID. Its
+// attributes are reported under the event type's attributes field, as in
+// "activityTaskScheduledEventAttributes".
+func (e *execution) addEvent(now time.Time, eventType string, attrs map[string]interface{}) int {
+	id := len(e.events) + 1
+	e.events = append(e.events, map[string]interface{}{
+		"eventId":        id,
+		"eventTimestamp": float64(now.UnixMilli()) / 1000,
+		"eventType":      eventType,
+		strings.ToLower(eventType[:1]) + eventType[1:] + "EventAttributes": attrs,
+	})
+	return id
+}
+
+func (e *execution) info() map[string]interface{} {
+	info := map[string]interface{}{
+		"execution":       e.ref(),
+		"workflowType":    e.workflowType.ref(),
+		"startTimestamp":  float64(e.started.Unix()),
+		"executionStatus": "OPEN",
+		"cancelRequested": e.cancelRequested,
+	}
+	if len(e.tags) > 0 {
+		info["tagList"] = e.tags
+	}
+	if !e.open() {
+		info["executionStatus"] = "CLOSED"
+		info["closeStatus"] = e.closeStatus
+		info["closeTimestamp"] = float64(e.closed.Unix())
+	}
+	return info
+}
+
+func writeUnknownExecution(w http.ResponseWriter, workflowID, runID string) {
+	writeFault(w, "UnknownResourceFault", fmt.Sprintf("Unknown execution: WorkflowExecution=[workflowId=%s, runId=%s]", workflowID, runID))
+}
+
+// lookupExecution returns the execution of workflowID in d with runID, or
+// its open execution if runID is empty, or writes UnknownResourceFault. The
+// caller must hold s.mu.
+func (s *Service) lookupExecution(w http.ResponseWriter, d *domain, workflowID, runID string) (*execution, bool) {
+	if runID != "" {
+		if e, exists := s.executions[runID]; exists && e.domain == d && e.workflowID == workflowID {
+			return e, true
+		}
+	} else if e := s.openExecution(d, workflowID); e != nil {
+		return e, true
+	}
+	writeUnknownExecution(w, workflowID, runID)
+	return nil, false
+}
+
+// openExecution returns the open execution of workflowID in d, or nil. The
+// caller must hold s.mu.
+func (s *Service) openExecution(d *domain, workflowID string) *execution {
+	for _, e := range s.executions {
+		if e.domain == d && e.workflowID == workflowID && e.open() {
+			return e
+		}
+	}
+	return nil
+}
+
+// configValue returns the named parameter, or the type's default for it,
+// registered as "default" followed by the parameter's name or, if set,
+// defaultName.
+func configValue(params map[string]interface{}, t *registeredType, name, defaultName string) interface{} {
+	if v, ok := params[name]; ok {
+		return v
+	}
+	if defaultName == "" {
+		defaultName = strings.ToUpper(name[:1]) + name[1:]
+	}
+	return t.defaults["default"+defaultName]
+}
+
+func (s *Service) startWorkflowExecution(w http.ResponseWriter, params map[string]interface{}) {
+	workflowID := h.GetString(params, "workflowId")
+	ref, _ := params["workflowType"].(map[string]interface{})
+	key := typeKey{h.GetString(ref, "name"), h.GetString(ref, "version")}
+	tags, _ := params["tagList"].([]interface{})
+	if workflowID == "" {
+		writeFault(w, "ValidationException", "workflowId is required")
+		return
+	}
+	if len(tags) > 5 {
+		writeFault(w, "ValidationException", "tagList must have no more than 5 tags")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	d, ok := s.lookupDomain(w, h.GetString(params, "domain"))
+	if !ok {
+		return
+	}
+	t, exists := d.workflowTypes[key]
+	if !exists {
+		writeFault(w, "UnknownResourceFault", fmt.Sprintf("Unknown type: WorkflowType=[name=%s, version=%s]", key.name, key.version))
+		return
+	}
+	config := map[string]interface{}{}
+	for _, name := range []string{"taskList", "executionStartToCloseTimeout", "taskStartToCloseTimeout", "childPolicy"} {
+		v := configValue(params, t, name, "")
+		if v == nil {
+			writeFault(w, "DefaultUndefinedFault", name+" was not specified and the workflow type has no default")
+			return
+		}
+		config[name] = v
+	}
+	if p := configValue(params, t, "taskPriority", ""); p != nil {
+		config["taskPriority"] = p
+	}
+	if s.openExecution(d, workflowID) != nil {
+		writeFault(w, "WorkflowExecutionAlreadyStartedFault", "Workflow execution already started: "+workflowID)
+		return
+	}
+
+	e := &execution{
+		domain:       d,
+		workflowID:   workflowID,
+		runID:        h.RandomHex(22),
+		workflowType: key,
+		config:       config,
+		tags:         tags,
+		started:      s.now(),
+		activities:   make(map[string]*activity),
+		timers:       make(map[string]*timer),
+	}
+	attrs := map[string]interface{}{"workflowType": key.ref()}
+	for k, v := range config {
+		attrs[k] = v
+	}
+	if input := h.GetString(params, "input"); input != "" {
+		attrs["input"] = input
+	}
+	if len(tags) > 0 {
+		attrs["tagList"] = tags
+	}
+	e.addEvent(e.started, "WorkflowExecutionStarted", attrs)
+	s.executions[e.runID] = e
+	s.scheduleDecision(e)
+
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{"runId": e.runID})
+}
+
+func (s *Service) describeWorkflowExecution(w http.ResponseWriter, params map[string]interface{}) {
+	ref, _ := params["execution"].(map[string]interface{})
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	d, ok := s.lookupDomain(w, h.GetString(params, "domain"))
+	if !ok {
+		return
+	}
+	e, ok := s.lookupExecution(w, d, h.GetString(ref, "workflowId"), h.GetString(ref, "runId"))
+	if !ok {
+		return
+	}
+
+	openDecisions := 0
+	if e.decisionScheduled != 0 || e.decisionStarted != 0 {
+		openDecisions = 1
+	}
+	resp := map[string]interface{}{
+		"executionInfo":          e.info(),
+		"executionConfiguration": e.config,
+		"openCounts": map[string]int{
+			"openActivityTasks":           len(e.activities),
+			"openDecisionTasks":           openDecisions,
+			"openTimers":                  len(e.timers),
+			"openChildWorkflowExecutions": 0,
+			"openLambdaFunctions":         0,
+		},
+	}
+	if e.latestContext != "" {
+		resp["latestExecutionContext"] = e.latestContext
+	}
+	if !e.latestActivity.IsZero() {
+		resp["latestActivityTaskTimestamp"] = float64(e.latestActivity.Unix())
+	}
+	h.WriteJSON(w, http.StatusOK, resp)
+}
+
+func (s *Service) getWorkflowExecutionHistory(w http.ResponseWriter, params map[string]interface{}) {
+	ref, _ := params["execution"].(map[string]interface{})
+	runID := h.GetString(ref, "runId")
+	if runID == "" {
+		writeFault(w, "ValidationException", "execution.runId is required")
+		return
+	}
+	s.mu.RLock()
+	d, ok := s.lookupDomain(w, h.GetString(params, "domain"))
+	if !ok {
+		s.mu.RUnlock()
+		return
+	}
+	e, ok := s.lookupExecution(w, d, h.GetString(ref, "workflowId"), runID)
+	if !ok {
+		s.mu.RUnlock()
+		return
+	}
+	events := e.history(params)
+	s.mu.RUnlock()
+	writePage(w, params, "events", events, historyKey(params, events))
+}
+
+// history returns a copy of e's events, newest first if the request sets
+// reverseOrder. The caller must hold s.mu.
+func (e *execution) history(params map[string]interface{}) []map[string]interface{} {
+	events := append([]map[string]interface{}(nil), e.events...)
+	if reverse, _ := params["reverseOrder"].(bool); reverse {
+		reverseInfos(events)
+	}
+	return events
+}
+
+// historyKey returns the key events, a history returned by
+// [execution.history], are sorted by.
+func historyKey(params map[string]interface{}, events []map[string]interface{}) func(i int) string {
+	return ordered(params, func(i int) string { return paginate.Seq(events[i]["eventId"].(int)) })
+}
+
+func (s *Service) listOpenWorkflowExecutions(w http.ResponseWriter, params map[string]interface{}) {
+	s.listExecutions(w, params, true)
+}
+
+func (s *Service) listClosedWorkflowExecutions(w http.ResponseWriter, params map[string]interface{}) {
+	s.listExecutions(w, params, false)
+}
+
+// listExecutions lists the open or closed executions in a domain that match
+// the request's filters, most recently started first unless it sets
+// reverseOrder.
+func (s *Service) listExecutions(w http.ResponseWriter, params map[string]interface{}, open bool) {
+	executionFilter, _ := params["executionFilter"].(map[string]interface{})
+	typeFilter, _ := params["typeFilter"].(map[string]interface{})
+	tagFilter, _ := params["tagFilter"].(map[string]interface{})
+	statusFilter, _ := params["closeStatusFilter"].(map[string]interface{})
+	startFilter, _ := params["startTimeFilter"].(map[string]interface{})
+	if open && startFilter == nil {
+		writeFault(w, "ValidationException", "startTimeFilter is required")
+		return
+	}
+	oldest, _ := startFilter["oldestDate"].(float64)
+	latest, _ := startFilter["latestDate"].(float64)
+
+	s.mu.RLock()
+	d, ok := s.lookupDomain(w, h.GetString(params, "domain"))
+	if !ok {
+		s.mu.RUnlock()
+		return
+	}
+	var matched []*execution
+	for _, e := range s.executions {
+		started := float64(e.started.Unix())
+		switch {
+		case e.domain != d || e.open() != open:
+		case workflowIDFilter(executionFilter) != "" && e.workflowID != workflowIDFilter(executionFilter):
+		case h.GetString(typeFilter, "name") != "" && e.workflowType.name != h.GetString(typeFilter, "name"):
+		case h.GetString(typeFilter, "version") != "" && e.workflowType.version != h.GetString(typeFilter, "version"):
+		case h.GetString(tagFilter, "tag") != "" && !hasTag(e.tags, h.GetString(tagFilter, "tag")):
+		case h.GetString(statusFilter, "status") != "" && e.closeStatus != h.GetString(statusFilter, "status"):
+		case startFilter != nil && (started < oldest || latest != 0 && started > latest):
+		default:
+			matched = append(matched, e)
+		}
+	}
+	key := ordered(params, func(i int) string {
+		return paginate.Join(paginate.Desc(paginate.Time(matched[i].started)), matched[i].runID)
+	})
+	sort.Slice(matched, func(i, j int) bool { return key(i) < key(j) })
+	infos := make([]map[string]interface{}, 0, len(matched))
+	for _, e := range matched {
+		infos = append(infos, e.info())
+	}
+	s.mu.RUnlock()
+	writePage(w, params, "executionInfos", infos, key)
+}
+
+func workflowIDFilter(filter map[string]interface{}) string {
+	return h.GetString(filter, "workflowId")
+}
+
+func hasTag(tags []interface{}, tag string) bool {
+	for _, t := range tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// targetExecution returns the execution a signal, cancellation request, or
+// termination names by domain, workflowId, and optional runId, or writes
+// UnknownResourceFault if there is no such open execution. The caller must
+// hold s.mu.
+func (s *Service) targetExecution(w http.ResponseWriter, params map[string]interface{}) (*execution, bool) {
+	d, ok := s.lookupDomain(w, h.GetString(params, "domain"))
+	if !ok {
+		return nil, false
+	}
+	workflowID, runID := h.GetString(params, "workflowId"), h.GetString(params, "runId")
+	e, ok := s.lookupExecution(w, d, workflowID, runID)
+	if ok && !e.open() {
+		writeUnknownExecution(w, workflowID, runID)
+		return nil, false
+	}
+	return e, ok
+}
+
+func (s *Service) signalWorkflowExecution(w http.ResponseWriter, params map[string]interface{}) {
+	signal := h.GetString(params, "signalName")
+	if signal == "" {
+		writeFault(w, "ValidationException", "signalName is required")
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.targetExecution(w, params)
+	if !ok {
+		return
+	}
+	attrs := map[string]interface{}{"signalName": signal}
+	if input := h.GetString(params, "input"); input != "" {
+		attrs["input"] = input
+	}
+	e.addEvent(s.now(), "WorkflowExecutionSignaled", attrs)
+	s.scheduleDecision(e)
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{})
+}
+
+func (s *Service) requestCancelWorkflowExecution(w http.ResponseWriter, params map[string]interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.targetExecution(w, params)
+	if !ok {
+		return
+	}
+	if !e.cancelRequested {
+		e.cancelRequested = true
+		e.addEvent(s.now(), "WorkflowExecutionCancelRequested", map[string]interface{}{})
+		s.scheduleDecision(e)
+	}
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{})
+}
+
+func (s *Service) terminateWorkflowExecution(w http.ResponseWriter, params map[string]interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.targetExecution(w, params)
+	if !ok {
+		return
+	}
+	childPolicy := h.GetString(params, "childPolicy")
+	if childPolicy == "" {
+		childPolicy = h.GetString(e.config, "childPolicy")
+	}
+	attrs := map[string]interface{}{"childPolicy": childPolicy}
+	for _, k := range []string{"reason", "details"} {
+		if v := h.GetString(params, k); v != "" {
+			attrs[k] = v
+		}
+	}
+	e.addEvent(s.now(), "WorkflowExecutionTerminated", attrs)
+	s.close(e, "TERMINATED")
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{})
+}
+
+// close closes e with closeStatus, discarding its queued and in-flight
+// tasks and running timers. The caller must hold s.mu.
+func (s *Service) close(e *execution, closeStatus string) {
+	e.closeStatus = closeStatus
+	e.closed = s.now()
+	e.decisionScheduled, e.decisionStarted, e.decisionPending = 0, 0, false
+	e.activities = make(map[string]*activity)
+	e.timers = make(map[string]*timer)
+	for token, t := range s.tasks {
+		if t.execution == e {
+			delete(s.tasks, token)
+		}
+	}
+	for key, queue := range s.queues {
+		kept := queue[:0]
+		for _, t := range queue {
+			if t.execution != e {
+				kept = append(kept, t)
+			}
+		}
+		s.queues[key] = kept
+	}
+}
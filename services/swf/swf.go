@@ -0,0 +1,382 @@
+// Package swf provides a mock implementation of Amazon Simple Workflow
+// Service.
+//
+// Supported actions:
+//   - RegisterDomain, DescribeDomain, ListDomains
+//   - RegisterWorkflowType, DescribeWorkflowType, ListWorkflowTypes
+//   - RegisterActivityType, DescribeActivityType, ListActivityTypes
+//   - StartWorkflowExecution, DescribeWorkflowExecution,
+//     GetWorkflowExecutionHistory, ListOpenWorkflowExecutions,
+//     ListClosedWorkflowExecutions, SignalWorkflowExecution,
+//     RequestCancelWorkflowExecution, TerminateWorkflowExecution
+//   - PollForDecisionTask, RespondDecisionTaskCompleted,
+//     CountPendingDecisionTasks
+//   - PollForActivityTask, RespondActivityTaskCompleted,
+//     RespondActivityTaskFailed, RespondActivityTaskCanceled,
+//     RecordActivityTaskHeartbeat, CountPendingActivityTasks
+//
+// Executions keep a full event history, and decision and activity tasks are
+// queued on their task lists as SWF queues them, so deciders and activity
+// workers can be driven through a workflow end to end. Polls do not wait:
+// with no task queued they return at once with an empty task token, as a
+// long poll does when it times out. Decisions may schedule activity tasks,
+// start and cancel timers, record markers, and complete, fail, or cancel the
+// execution. Timers fire when the mock clock advances past them; task and
+// execution timeouts are not enforced.
+package swf
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/riyanimam/goto/internal/clock"
+	h "github.com/riyanimam/goto/internal/mockhelpers"
+	"github.com/riyanimam/goto/internal/paginate"
+)
+
+// Service implements the SWF mock.
+type Service struct {
+	mu         sync.RWMutex
+	domains    map[string]*domain
+	executions map[string]*execution // by run ID
+	tasks      map[string]*task      // in-flight tasks by task token
+	queues     map[string][]*task    // queued tasks by queueKey
+
+	clock *clock.Clock
+}
+
+type domain struct {
+	name          string
+	description   string
+	retentionDays string
+	created       time.Time
+	workflowTypes map[typeKey]*registeredType
+	activityTypes map[typeKey]*registeredType
+}
+
+type typeKey struct{ name, version string }
+
+// registeredType is a registered workflow or activity type.
+type registeredType struct {
+	key         typeKey
+	description string
+	created     time.Time
+	// defaults holds the type's default* configuration parameters.
+	defaults map[string]interface{}
+}
+
+// New creates a new SWF mock service.
+func New() *Service {
+	return &Service{
+		domains:    make(map[string]*domain),
+		executions: make(map[string]*execution),
+		tasks:      make(map[string]*task),
+		queues:     make(map[string][]*task),
+	}
+}
+
+// Name returns the service identifier.
+func (s *Service) Name() string { return "swf" }
+
+// Handler returns the HTTP handler for SWF requests.
+func (s *Service) Handler() http.Handler {
+	return h.JSONRouter{
+		"RegisterDomain":                 s.registerDomain,
+		"DescribeDomain":                 s.describeDomain,
+		"ListDomains":                    s.listDomains,
+		"RegisterWorkflowType":           s.registerWorkflowType,
+		"DescribeWorkflowType":           s.describeWorkflowType,
+		"ListWorkflowTypes":              s.listWorkflowTypes,
+		"RegisterActivityType":           s.registerActivityType,
+		"DescribeActivityType":           s.describeActivityType,
+		"ListActivityTypes":              s.listActivityTypes,
+		"StartWorkflowExecution":         s.startWorkflowExecution,
+		"DescribeWorkflowExecution":      s.describeWorkflowExecution,
+		"GetWorkflowExecutionHistory":    s.getWorkflowExecutionHistory,
+		"ListOpenWorkflowExecutions":     s.listOpenWorkflowExecutions,
+		"ListClosedWorkflowExecutions":   s.listClosedWorkflowExecutions,
+		"SignalWorkflowExecution":        s.signalWorkflowExecution,
+		"RequestCancelWorkflowExecution": s.requestCancelWorkflowExecution,
+		"TerminateWorkflowExecution":     s.terminateWorkflowExecution,
+		"PollForDecisionTask":            s.pollForDecisionTask,
+		"RespondDecisionTaskCompleted":   s.respondDecisionTaskCompleted,
+		"CountPendingDecisionTasks":      s.countPendingDecisionTasks,
+		"PollForActivityTask":            s.pollForActivityTask,
+		"RespondActivityTaskCompleted":   s.respondActivityTaskCompleted,
+		"RespondActivityTaskFailed":      s.respondActivityTaskFailed,
+		"RespondActivityTaskCanceled":    s.respondActivityTaskCanceled,
+		"RecordActivityTaskHeartbeat":    s.recordActivityTaskHeartbeat,
+		"CountPendingActivityTasks":      s.countPendingActivityTasks,
+	}
+}
+
+// Reset clears all state.
+func (s *Service) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.domains = make(map[string]*domain)
+	s.executions = make(map[string]*execution)
+	s.tasks = make(map[string]*task)
+	s.queues = make(map[string][]*task)
+}
+
+// SetClock attaches the mock clock. Timers fire as it advances.
+func (s *Service) SetClock(c *clock.Clock) {
+	s.mu.Lock()
+	s.clock = c
+	s.mu.Unlock()
+	c.OnAdvance(func(_, _ time.Time) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.fireTimers()
+	})
+}
+
+func (s *Service) now() time.Time {
+	if s.clock != nil {
+		return s.clock.Now()
+	}
+	return time.Now().UTC()
+}
+
+func writeFault(w http.ResponseWriter, fault, message string) {
+	h.WriteJSONError(w, fault, message, http.StatusBadRequest)
+}
+
+func writeUnknownDomain(w http.ResponseWriter, name string) {
+	writeFault(w, "UnknownResourceFault", "Unknown domain: "+name)
+}
+
+// lookupDomain returns the named domain, or writes UnknownResourceFault.
+// The caller must hold s.mu.
+func (s *Service) lookupDomain(w http.ResponseWriter, name string) (*domain, bool) {
+	d, exists := s.domains[name]
+	if !exists {
+		writeUnknownDomain(w, name)
+	}
+	return d, exists
+}
+
+func (d *domain) info() map[string]interface{} {
+	return map[string]interface{}{
+		"name":        d.name,
+		"status":      "REGISTERED",
+		"description": d.description,
+		"arn":         fmt.Sprintf("arn:aws:swf:us-east-1:%s:/domain/%s", h.DefaultAccountID, d.name),
+	}
+}
+
+func (s *Service) registerDomain(w http.ResponseWriter, params map[string]interface{}) {
+	name := h.GetString(params, "name")
+	retention := h.GetString(params, "workflowExecutionRetentionPeriodInDays")
+	if name == "" || retention == "" {
+		writeFault(w, "ValidationException", "name and workflowExecutionRetentionPeriodInDays are required")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, exists := s.domains[name]; exists {
+		writeFault(w, "DomainAlreadyExistsFault", name)
+		return
+	}
+	s.domains[name] = &domain{
+		name:          name,
+		description:   h.GetString(params, "description"),
+		retentionDays: retention,
+		created:       s.now(),
+		workflowTypes: make(map[typeKey]*registeredType),
+		activityTypes: make(map[typeKey]*registeredType),
+	}
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{})
+}
+
+func (s *Service) describeDomain(w http.ResponseWriter, params map[string]interface{}) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	d, ok := s.lookupDomain(w, h.GetString(params, "name"))
+	if !ok {
+		return
+	}
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"domainInfo":    d.info(),
+		"configuration": map[string]string{"workflowExecutionRetentionPeriodInDays": d.retentionDays},
+	})
+}
+
+func (s *Service) listDomains(w http.ResponseWriter, params map[string]interface{}) {
+	if status := h.GetString(params, "registrationStatus"); status != "REGISTERED" {
+		// Domains cannot be deprecated in the mock.
+		h.WriteJSON(w, http.StatusOK, map[string]interface{}{"domainInfos": []interface{}{}})
+		return
+	}
+	s.mu.RLock()
+	infos := make([]map[string]interface{}, 0, len(s.domains))
+	for _, d := range s.domains {
+		infos = append(infos, d.info())
+	}
+	s.mu.RUnlock()
+	key := ordered(params, func(i int) string { return infos[i]["name"].(string) })
+	sort.Slice(infos, func(i, j int) bool { return key(i) < key(j) })
+	writePage(w, params, "domainInfos", infos, key)
+}
+
+// ordered returns key, reversed if the request sets reverseOrder.
+func ordered(params map[string]interface{}, key func(i int) string) func(i int) string {
+	if reverse, _ := params["reverseOrder"].(bool); !reverse {
+		return key
+	}
+	return func(i int) string { return paginate.Desc(key(i)) }
+}
+
+// writePage writes a page of items, sorted by itemKey as in
+// [paginate.Page], under key, using SWF's nextPageToken and maximumPageSize
+// parameters.
+func writePage(w http.ResponseWriter, params map[string]interface{}, key string, items []map[string]interface{}, itemKey func(i int) string) {
+	page, next, err := paginate.Page(items, itemKey, h.GetString(params, "nextPageToken"), h.GetInt(params, "maximumPageSize", 1000), 1000)
+	if err != nil {
+		writeFault(w, "ValidationException", "Invalid nextPageToken")
+		return
+	}
+	resp := map[string]interface{}{key: page}
+	if next != "" {
+		resp["nextPageToken"] = next
+	}
+	h.WriteJSON(w, http.StatusOK, resp)
+}
+
+func reverseInfos(infos []map[string]interface{}) {
+	for i, j := 0, len(infos)-1; i < j; i, j = i+1, j-1 {
+		infos[i], infos[j] = infos[j], infos[i]
+	}
+}
+
+// registerType registers a workflow or activity type, kind, in the map of
+// its domain that types selects.
+func (s *Service) registerType(w http.ResponseWriter, params map[string]interface{}, kind string, types func(*domain) map[typeKey]*registeredType) {
+	key := typeKey{h.GetString(params, "name"), h.GetString(params, "version")}
+	if key.name == "" || key.version == "" {
+		writeFault(w, "ValidationException", "name and version are required")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	d, ok := s.lookupDomain(w, h.GetString(params, "domain"))
+	if !ok {
+		return
+	}
+	if _, exists := types(d)[key]; exists {
+		writeFault(w, "TypeAlreadyExistsFault", fmt.Sprintf("%s=[name=%s, version=%s]", kind, key.name, key.version))
+		return
+	}
+	t := &registeredType{
+		key:         key,
+		description: h.GetString(params, "description"),
+		created:     s.now(),
+		defaults:    make(map[string]interface{}),
+	}
+	for k, v := range params {
+		if strings.HasPrefix(k, "default") {
+			t.defaults[k] = v
+		}
+	}
+	types(d)[key] = t
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{})
+}
+
+// describeType describes a workflow or activity type; field is the name of
+// the type parameter and of the type in the response.
+func (s *Service) describeType(w http.ResponseWriter, params map[string]interface{}, kind, field string, types func(*domain) map[typeKey]*registeredType) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	d, ok := s.lookupDomain(w, h.GetString(params, "domain"))
+	if !ok {
+		return
+	}
+	ref, _ := params[field].(map[string]interface{})
+	key := typeKey{h.GetString(ref, "name"), h.GetString(ref, "version")}
+	t, exists := types(d)[key]
+	if !exists {
+		writeFault(w, "UnknownResourceFault", fmt.Sprintf("Unknown type: %s=[name=%s, version=%s]", kind, key.name, key.version))
+		return
+	}
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"typeInfo":      t.info(field),
+		"configuration": t.defaults,
+	})
+}
+
+// listTypes lists the registered workflow or activity types, filtered by
+// name if the request sets one.
+func (s *Service) listTypes(w http.ResponseWriter, params map[string]interface{}, field string, types func(*domain) map[typeKey]*registeredType) {
+	s.mu.RLock()
+	d, ok := s.lookupDomain(w, h.GetString(params, "domain"))
+	if !ok {
+		s.mu.RUnlock()
+		return
+	}
+	name := h.GetString(params, "name")
+	var infos []map[string]interface{}
+	if h.GetString(params, "registrationStatus") == "REGISTERED" {
+		for _, t := range types(d) {
+			if name == "" || t.key.name == name {
+				infos = append(infos, t.info(field))
+			}
+		}
+	}
+	s.mu.RUnlock()
+	key := ordered(params, func(i int) string {
+		ref := infos[i][field].(map[string]string)
+		return paginate.Join(ref["name"], ref["version"])
+	})
+	sort.Slice(infos, func(i, j int) bool { return key(i) < key(j) })
+	if infos == nil {
+		infos = []map[string]interface{}{}
+	}
+	writePage(w, params, "typeInfos", infos, key)
+}
+
+func (t *registeredType) info(field string) map[string]interface{} {
+	return map[string]interface{}{
+		field:          t.key.ref(),
+		"status":       "REGISTERED",
+		"description":  t.description,
+		"creationDate": float64(t.created.Unix()),
+	}
+}
+
+func (k typeKey) ref() map[string]string {
+	return map[string]string{"name": k.name, "version": k.version}
+}
+
+func workflowTypes(d *domain) map[typeKey]*registeredType { return d.workflowTypes }
+func activityTypes(d *domain) map[typeKey]*registeredType { return d.activityTypes }
+
+func (s *Service) registerWorkflowType(w http.ResponseWriter, params map[string]interface{}) {
+	s.registerType(w, params, "WorkflowType", workflowTypes)
+}
+
+func (s *Service) describeWorkflowType(w http.ResponseWriter, params map[string]interface{}) {
+	s.describeType(w, params, "WorkflowType", "workflowType", workflowTypes)
+}
+
+func (s *Service) listWorkflowTypes(w http.ResponseWriter, params map[string]interface{}) {
+	s.listTypes(w, params, "workflowType", workflowTypes)
+}
+
+func (s *Service) registerActivityType(w http.ResponseWriter, params map[string]interface{}) {
+	s.registerType(w, params, "ActivityType", activityTypes)
+}
+
+func (s *Service) describeActivityType(w http.ResponseWriter, params map[string]interface{}) {
+	s.describeType(w, params, "ActivityType", "activityType", activityTypes)
+}
+
+func (s *Service) listActivityTypes(w http.ResponseWriter, params map[string]interface{}) {
+	s.listTypes(w, params, "activityType", activityTypes)
+}
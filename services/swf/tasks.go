@@ -0,0 +1,487 @@
+package swf
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	h "github.com/riyanimam/goto/internal/mockhelpers"
+	"github.com/riyanimam/goto/internal/paginate"
+)
+
+// task is a decision or activity task, queued on its task list until it is
+// polled and then in flight under its token until it is responded to.
+type task struct {
+	token       string
+	execution   *execution
+	activity    *activity // nil for decision tasks
+	scheduledID int       // the DecisionTaskScheduled event of a decision task
+}
+
+// queueKey identifies the queue of decision or activity tasks on a task
+// list in a domain.
+func queueKey(kind string, d *domain, taskList string) string {
+	return kind + "/" + d.name + "/" + taskList
+}
+
+// scheduleDecision schedules a decision task for e, unless one is already
+// queued; if one is in flight, another is scheduled when it completes. The
+// caller must hold s.mu.
+func (s *Service) scheduleDecision(e *execution) {
+	switch {
+	case !e.open() || e.decisionScheduled != 0:
+	case e.decisionStarted != 0:
+		e.decisionPending = true
+	default:
+		e.decisionScheduled = e.addEvent(s.now(), "DecisionTaskScheduled", map[string]interface{}{
+			"taskList":            e.config["taskList"],
+			"startToCloseTimeout": e.config["taskStartToCloseTimeout"],
+		})
+		key := queueKey("decision", e.domain, e.taskList())
+		s.queues[key] = append(s.queues[key], &task{execution: e, scheduledID: e.decisionScheduled})
+	}
+}
+
+// dequeue removes and returns the first task queued on a task list, or nil.
+// The caller must hold s.mu.
+func (s *Service) dequeue(key string) *task {
+	queue := s.queues[key]
+	if len(queue) == 0 {
+		return nil
+	}
+	t := queue[0]
+	s.queues[key] = queue[1:]
+	t.token = h.RandomHex(32)
+	s.tasks[t.token] = t
+	return t
+}
+
+func (s *Service) pollForDecisionTask(w http.ResponseWriter, params map[string]interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	d, ok := s.lookupDomain(w, h.GetString(params, "domain"))
+	if !ok {
+		return
+	}
+
+	// A page token continues the history of a task already handed out.
+	var t *task
+	pageToken := h.GetString(params, "nextPageToken")
+	if pageToken != "" {
+		var taskToken string
+		taskToken, pageToken, _ = strings.Cut(pageToken, ".")
+		if t = s.tasks[taskToken]; t == nil || t.activity != nil {
+			writeFault(w, "ValidationException", "Invalid nextPageToken")
+			return
+		}
+	} else {
+		tl, _ := params["taskList"].(map[string]interface{})
+		if t = s.dequeue(queueKey("decision", d, h.GetString(tl, "name"))); t == nil {
+			h.WriteJSON(w, http.StatusOK, map[string]interface{}{
+				"taskToken":              "",
+				"startedEventId":         0,
+				"previousStartedEventId": 0,
+			})
+			return
+		}
+		e := t.execution
+		attrs := map[string]interface{}{"scheduledEventId": t.scheduledID}
+		if identity := h.GetString(params, "identity"); identity != "" {
+			attrs["identity"] = identity
+		}
+		e.decisionStarted = e.addEvent(s.now(), "DecisionTaskStarted", attrs)
+		e.decisionScheduled = 0
+	}
+
+	e := t.execution
+	events := e.history(params)
+	page, next, err := paginate.Page(events, historyKey(params, events), pageToken, h.GetInt(params, "maximumPageSize", 1000), 1000)
+	if err != nil {
+		writeFault(w, "ValidationException", "Invalid nextPageToken")
+		return
+	}
+	resp := map[string]interface{}{
+		"taskToken":              t.token,
+		"startedEventId":         e.decisionStarted,
+		"previousStartedEventId": e.lastDecision,
+		"workflowExecution":      e.ref(),
+		"workflowType":           e.workflowType.ref(),
+		"events":                 page,
+	}
+	if next != "" {
+		resp["nextPageToken"] = t.token + "." + next
+	}
+	h.WriteJSON(w, http.StatusOK, resp)
+}
+
+// supportedDecisions are the decision types RespondDecisionTaskCompleted
+// accepts.
+var supportedDecisions = map[string]bool{
+	"ScheduleActivityTask":      true,
+	"RequestCancelActivityTask": true,
+	"StartTimer":                true,
+	"CancelTimer":               true,
+	"RecordMarker":              true,
+	"CompleteWorkflowExecution": true,
+	"FailWorkflowExecution":     true,
+	"CancelWorkflowExecution":   true,
+}
+
+func (s *Service) respondDecisionTaskCompleted(w http.ResponseWriter, params map[string]interface{}) {
+	decisions, _ := params["decisions"].([]interface{})
+	for _, d := range decisions {
+		decision, _ := d.(map[string]interface{})
+		if decisionType := h.GetString(decision, "decisionType"); !supportedDecisions[decisionType] {
+			writeFault(w, "ValidationException", fmt.Sprintf("decision type %q is not supported", decisionType))
+			return
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t, ok := s.lookupTask(w, params, false)
+	if !ok {
+		return
+	}
+	delete(s.tasks, t.token)
+	e := t.execution
+	attrs := map[string]interface{}{
+		"scheduledEventId": t.scheduledID,
+		"startedEventId":   e.decisionStarted,
+	}
+	if ctx := h.GetString(params, "executionContext"); ctx != "" {
+		attrs["executionContext"] = ctx
+		e.latestContext = ctx
+	}
+	completed := e.addEvent(s.now(), "DecisionTaskCompleted", attrs)
+	// Events that arrived while the decider worked have not been seen, so
+	// closing the execution now would be an unhandled decision.
+	unhandled := e.decisionPending
+
+	for _, d := range decisions {
+		decision, _ := d.(map[string]interface{})
+		decisionType := h.GetString(decision, "decisionType")
+		attrs, _ := decision[strings.ToLower(decisionType[:1])+decisionType[1:]+"DecisionAttributes"].(map[string]interface{})
+		if attrs == nil {
+			attrs = map[string]interface{}{}
+		}
+		if !e.open() {
+			break
+		}
+		s.decide(e, decisionType, attrs, completed, unhandled)
+	}
+
+	e.lastDecision = e.decisionStarted
+	e.decisionStarted = 0
+	if e.decisionPending {
+		e.decisionPending = false
+		s.scheduleDecision(e)
+	}
+	s.fireTimers()
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{})
+}
+
+// decide carries out a decision made by the decision task completed in
+// event completed. Decisions that cannot be carried out record a failure
+// event and schedule a decision task. The caller must hold s.mu.
+func (s *Service) decide(e *execution, decisionType string, attrs map[string]interface{}, completed int, unhandled bool) {
+	now := s.now()
+	fail := func(eventType, cause string, extra map[string]interface{}) {
+		failure := map[string]interface{}{"cause": cause, "decisionTaskCompletedEventId": completed}
+		for k, v := range extra {
+			failure[k] = v
+		}
+		e.addEvent(now, eventType, failure)
+		s.scheduleDecision(e)
+	}
+
+	switch decisionType {
+	case "ScheduleActivityTask":
+		ref, _ := attrs["activityType"].(map[string]interface{})
+		key := typeKey{h.GetString(ref, "name"), h.GetString(ref, "version")}
+		id := h.GetString(attrs, "activityId")
+		failed := map[string]interface{}{"activityType": key.ref(), "activityId": id}
+		t, registered := e.domain.activityTypes[key]
+		if !registered {
+			fail("ScheduleActivityTaskFailed", "ACTIVITY_TYPE_DOES_NOT_EXIST", failed)
+			return
+		}
+		if _, open := e.activities[id]; open {
+			fail("ScheduleActivityTaskFailed", "ACTIVITY_ID_ALREADY_IN_USE", failed)
+			return
+		}
+		scheduled := map[string]interface{}{
+			"activityType":                 key.ref(),
+			"activityId":                   id,
+			"decisionTaskCompletedEventId": completed,
+		}
+		for name, defaultName := range map[string]string{
+			"taskList":               "TaskList",
+			"taskPriority":           "TaskPriority",
+			"scheduleToCloseTimeout": "TaskScheduleToCloseTimeout",
+			"scheduleToStartTimeout": "TaskScheduleToStartTimeout",
+			"startToCloseTimeout":    "TaskStartToCloseTimeout",
+			"heartbeatTimeout":       "TaskHeartbeatTimeout",
+		} {
+			if v := configValue(attrs, t, name, defaultName); v != nil {
+				scheduled[name] = v
+			}
+		}
+		tl, _ := scheduled["taskList"].(map[string]interface{})
+		if h.GetString(tl, "name") == "" {
+			fail("ScheduleActivityTaskFailed", "DEFAULT_TASK_LIST_UNDEFINED", failed)
+			return
+		}
+		for _, k := range []string{"input", "control"} {
+			if v := h.GetString(attrs, k); v != "" {
+				scheduled[k] = v
+			}
+		}
+		a := &activity{
+			id:           id,
+			activityType: key,
+			taskList:     h.GetString(tl, "name"),
+			input:        h.GetString(attrs, "input"),
+		}
+		a.scheduledID = e.addEvent(now, "ActivityTaskScheduled", scheduled)
+		a.task = &task{execution: e, activity: a}
+		e.activities[id] = a
+		qk := queueKey("activity", e.domain, a.taskList)
+		s.queues[qk] = append(s.queues[qk], a.task)
+
+	case "RequestCancelActivityTask":
+		id := h.GetString(attrs, "activityId")
+		a, open := e.activities[id]
+		if !open {
+			fail("RequestCancelActivityTaskFailed", "ACTIVITY_ID_UNKNOWN", map[string]interface{}{"activityId": id})
+			return
+		}
+		e.addEvent(now, "ActivityTaskCancelRequested", map[string]interface{}{"activityId": id, "decisionTaskCompletedEventId": completed})
+		a.cancelRequested = true
+		if a.startedID == 0 {
+			// The task has not been polled, so it is canceled at once.
+			s.unqueue(a.task)
+			delete(e.activities, id)
+			e.addEvent(now, "ActivityTaskCanceled", map[string]interface{}{"scheduledEventId": a.scheduledID, "latestCancelRequestedEventId": len(e.events)})
+			s.scheduleDecision(e)
+		}
+
+	case "StartTimer":
+		id := h.GetString(attrs, "timerId")
+		timeout := h.GetString(attrs, "startToFireTimeout")
+		seconds, err := strconv.Atoi(timeout)
+		if _, running := e.timers[id]; running {
+			fail("StartTimerFailed", "TIMER_ID_ALREADY_IN_USE", map[string]interface{}{"timerId": id})
+			return
+		}
+		if err != nil || seconds < 0 {
+			fail("StartTimerFailed", "OPERATION_NOT_PERMITTED", map[string]interface{}{"timerId": id})
+			return
+		}
+		started := map[string]interface{}{"timerId": id, "startToFireTimeout": timeout, "decisionTaskCompletedEventId": completed}
+		if control := h.GetString(attrs, "control"); control != "" {
+			started["control"] = control
+		}
+		e.timers[id] = &timer{
+			id:        id,
+			startedID: e.addEvent(now, "TimerStarted", started),
+			fireAt:    now.Add(time.Duration(seconds) * time.Second),
+		}
+
+	case "CancelTimer":
+		id := h.GetString(attrs, "timerId")
+		tm, running := e.timers[id]
+		if !running {
+			fail("CancelTimerFailed", "TIMER_ID_UNKNOWN", map[string]interface{}{"timerId": id})
+			return
+		}
+		delete(e.timers, id)
+		e.addEvent(now, "TimerCanceled", map[string]interface{}{"timerId": id, "startedEventId": tm.startedID, "decisionTaskCompletedEventId": completed})
+
+	case "RecordMarker":
+		marker := map[string]interface{}{"markerName": h.GetString(attrs, "markerName"), "decisionTaskCompletedEventId": completed}
+		if details := h.GetString(attrs, "details"); details != "" {
+			marker["details"] = details
+		}
+		e.addEvent(now, "MarkerRecorded", marker)
+
+	case "CompleteWorkflowExecution", "FailWorkflowExecution", "CancelWorkflowExecution":
+		closing := map[string]struct{ event, status string }{
+			"CompleteWorkflowExecution": {"WorkflowExecutionCompleted", "COMPLETED"},
+			"FailWorkflowExecution":     {"WorkflowExecutionFailed", "FAILED"},
+			"CancelWorkflowExecution":   {"WorkflowExecutionCanceled", "CANCELED"},
+		}[decisionType]
+		if unhandled {
+			fail(decisionType+"Failed", "UNHANDLED_DECISION", nil)
+			return
+		}
+		closed := map[string]interface{}{"decisionTaskCompletedEventId": completed}
+		for _, k := range []string{"result", "reason", "details"} {
+			if v := h.GetString(attrs, k); v != "" {
+				closed[k] = v
+			}
+		}
+		e.addEvent(now, closing.event, closed)
+		s.close(e, closing.status)
+	}
+}
+
+// unqueue removes a task that has not been polled from its queue. The
+// caller must hold s.mu.
+func (s *Service) unqueue(t *task) {
+	key := queueKey("activity", t.execution.domain, t.activity.taskList)
+	queue := s.queues[key]
+	for i, queued := range queue {
+		if queued == t {
+			s.queues[key] = append(queue[:i:i], queue[i+1:]...)
+			return
+		}
+	}
+}
+
+// lookupTask returns the in-flight decision or activity task with the
+// request's taskToken, or writes UnknownResourceFault. The caller must hold
+// s.mu.
+func (s *Service) lookupTask(w http.ResponseWriter, params map[string]interface{}, activityTask bool) (*task, bool) {
+	t, exists := s.tasks[h.GetString(params, "taskToken")]
+	if !exists || (t.activity != nil) != activityTask {
+		writeFault(w, "UnknownResourceFault", "Unknown task token")
+		return nil, false
+	}
+	return t, true
+}
+
+func (s *Service) countPendingDecisionTasks(w http.ResponseWriter, params map[string]interface{}) {
+	s.countPending(w, params, "decision")
+}
+
+func (s *Service) countPendingActivityTasks(w http.ResponseWriter, params map[string]interface{}) {
+	s.countPending(w, params, "activity")
+}
+
+func (s *Service) countPending(w http.ResponseWriter, params map[string]interface{}, kind string) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	d, ok := s.lookupDomain(w, h.GetString(params, "domain"))
+	if !ok {
+		return
+	}
+	tl, _ := params["taskList"].(map[string]interface{})
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"count":     len(s.queues[queueKey(kind, d, h.GetString(tl, "name"))]),
+		"truncated": false,
+	})
+}
+
+func (s *Service) pollForActivityTask(w http.ResponseWriter, params map[string]interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	d, ok := s.lookupDomain(w, h.GetString(params, "domain"))
+	if !ok {
+		return
+	}
+	tl, _ := params["taskList"].(map[string]interface{})
+	t := s.dequeue(queueKey("activity", d, h.GetString(tl, "name")))
+	if t == nil {
+		h.WriteJSON(w, http.StatusOK, map[string]interface{}{"taskToken": "", "activityId": "", "startedEventId": 0})
+		return
+	}
+
+	e, a := t.execution, t.activity
+	attrs := map[string]interface{}{"scheduledEventId": a.scheduledID}
+	if identity := h.GetString(params, "identity"); identity != "" {
+		attrs["identity"] = identity
+	}
+	a.startedID = e.addEvent(s.now(), "ActivityTaskStarted", attrs)
+	e.latestActivity = s.now()
+
+	resp := map[string]interface{}{
+		"taskToken":         t.token,
+		"activityId":        a.id,
+		"startedEventId":    a.startedID,
+		"workflowExecution": e.ref(),
+		"activityType":      a.activityType.ref(),
+	}
+	if a.input != "" {
+		resp["input"] = a.input
+	}
+	h.WriteJSON(w, http.StatusOK, resp)
+}
+
+// finishActivity closes the activity task with the request's taskToken,
+// recording eventType with the request's named fields, and schedules a
+// decision task.
+func (s *Service) finishActivity(w http.ResponseWriter, params map[string]interface{}, eventType string, fields ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t, ok := s.lookupTask(w, params, true)
+	if !ok {
+		return
+	}
+	e, a := t.execution, t.activity
+	attrs := map[string]interface{}{"scheduledEventId": a.scheduledID, "startedEventId": a.startedID}
+	for _, k := range fields {
+		if v := h.GetString(params, k); v != "" {
+			attrs[k] = v
+		}
+	}
+	delete(s.tasks, t.token)
+	delete(e.activities, a.id)
+	e.addEvent(s.now(), eventType, attrs)
+	s.scheduleDecision(e)
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{})
+}
+
+func (s *Service) respondActivityTaskCompleted(w http.ResponseWriter, params map[string]interface{}) {
+	s.finishActivity(w, params, "ActivityTaskCompleted", "result")
+}
+
+func (s *Service) respondActivityTaskFailed(w http.ResponseWriter, params map[string]interface{}) {
+	s.finishActivity(w, params, "ActivityTaskFailed", "reason", "details")
+}
+
+func (s *Service) respondActivityTaskCanceled(w http.ResponseWriter, params map[string]interface{}) {
+	s.finishActivity(w, params, "ActivityTaskCanceled", "details")
+}
+
+func (s *Service) recordActivityTaskHeartbeat(w http.ResponseWriter, params map[string]interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t, ok := s.lookupTask(w, params, true)
+	if !ok {
+		return
+	}
+	t.execution.latestActivity = s.now()
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{"cancelRequested": t.activity.cancelRequested})
+}
+
+// fireTimers fires the timers of open executions that are due, earliest
+// first, scheduling a decision task for each. The caller must hold s.mu.
+func (s *Service) fireTimers() {
+	now := s.now()
+	for _, e := range s.executions {
+		if !e.open() {
+			continue
+		}
+		var due []*timer
+		for _, tm := range e.timers {
+			if !tm.fireAt.After(now) {
+				due = append(due, tm)
+			}
+		}
+		sort.Slice(due, func(i, j int) bool {
+			if !due[i].fireAt.Equal(due[j].fireAt) {
+				return due[i].fireAt.Before(due[j].fireAt)
+			}
+			return due[i].startedID < due[j].startedID
+		})
+		for _, tm := range due {
+			delete(e.timers, tm.id)
+			e.addEvent(now, "TimerFired", map[string]interface{}{"timerId": tm.id, "startedEventId": tm.startedID})
+			s.scheduleDecision(e)
+		}
+	}
+}
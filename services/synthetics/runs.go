@@ -0,0 +1,182 @@
+package synthetics
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	h "github.com/riyanimam/goto/internal/mockhelpers"
+)
+
+// runResult is the outcome injected for a canary's runs.
+type runResult struct {
+	passed bool
+	reason string
+}
+
+// run is a single canary run.
+type run struct {
+	id        string
+	name      string
+	state     string // PASSED or FAILED
+	reason    string
+	started   time.Time
+	completed time.Time
+	artifacts string
+}
+
+// SetResult sets the outcome of the named canary's subsequent runs. Failed
+// runs report reason as their StateReason, typically the error the canary
+// script would have thrown. Canaries pass until a result is set.
+func (s *Service) SetResult(name string, passed bool, reason string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c, exists := s.canaries[name]
+	if !exists {
+		return fmt.Errorf("canary %s does not exist", name)
+	}
+	c.result = runResult{passed: passed, reason: reason}
+	return nil
+}
+
+// Run runs the named canary now, as its schedule would. The canary must be
+// RUNNING.
+func (s *Service) Run(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c, exists := s.canaries[name]
+	if !exists {
+		return fmt.Errorf("canary %s does not exist", name)
+	}
+	if c.state != "RUNNING" {
+		return fmt.Errorf("canary %s is %s, not RUNNING", name, s.status(c))
+	}
+	s.run(c, s.now())
+	return nil
+}
+
+// run records a run of c at t with its injected result. Runs complete at
+// once, and a canary scheduled to run once stops after its run. The caller
+// must hold s.mu.
+func (s *Service) run(c *canary, t time.Time) {
+	r := &run{
+		id:        h.NewRequestID(),
+		name:      c.name,
+		state:     "PASSED",
+		started:   t,
+		completed: t,
+		artifacts: fmt.Sprintf("%s/canary/us-east-1/%s/%s",
+			strings.TrimSuffix(strings.TrimPrefix(c.artifactLocation, "s3://"), "/"), c.name, t.Format("2006/01/02/15/04-05-000")),
+	}
+	if !c.result.passed {
+		r.state = "FAILED"
+		r.reason = c.result.reason
+	}
+	c.runs = append(c.runs, r)
+
+	if _, once, _ := parseRate(c.expression); once {
+		s.stop(c, t)
+	}
+}
+
+// runDue runs every RUNNING canary with a rate schedule once for each
+// period that ends in (from, to], and stops canaries whose
+// DurationInSeconds elapses.
+func (s *Service) runDue(from, to time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, c := range s.canaries {
+		every, _, _ := parseRate(c.expression)
+		if c.state != "RUNNING" || every == 0 {
+			continue
+		}
+		var end time.Time
+		if c.duration > 0 {
+			end = c.lastStarted.Add(time.Duration(c.duration) * time.Second)
+		}
+		next := c.runs[len(c.runs)-1].started.Add(every)
+		for !next.After(to) && (end.IsZero() || next.Before(end)) {
+			s.run(c, next)
+			next = next.Add(every)
+		}
+		if !end.IsZero() && !end.After(to) {
+			s.stop(c, end)
+		}
+	}
+}
+
+// parseRate parses a canary schedule expression. It returns how often a
+// rate expression runs, and whether the canary runs only once, as
+// "rate(0 minute)" canaries do. Cron expressions are accepted but only run
+// when started or on demand.
+func parseRate(expr string) (every time.Duration, once bool, err error) {
+	if strings.HasPrefix(expr, "cron(") && strings.HasSuffix(expr, ")") {
+		return 0, false, nil
+	}
+	if !strings.HasPrefix(expr, "rate(") || !strings.HasSuffix(expr, ")") {
+		return 0, false, fmt.Errorf("invalid schedule expression %q", expr)
+	}
+	fields := strings.Fields(strings.TrimSuffix(strings.TrimPrefix(expr, "rate("), ")"))
+	if len(fields) != 2 {
+		return 0, false, fmt.Errorf("invalid schedule expression %q", expr)
+	}
+	n, err := strconv.Atoi(fields[0])
+	if err != nil || n < 0 {
+		return 0, false, fmt.Errorf("invalid schedule expression %q", expr)
+	}
+	switch strings.TrimSuffix(fields[1], "s") {
+	case "minute":
+		every = time.Duration(n) * time.Minute
+	case "hour":
+		every = time.Duration(n) * time.Hour
+	default:
+		return 0, false, fmt.Errorf("invalid schedule expression %q", expr)
+	}
+	return every, n == 0, nil
+}
+
+func (r *run) toMap() map[string]interface{} {
+	status := map[string]interface{}{"State": r.state}
+	if r.state == "FAILED" {
+		status["StateReason"] = r.reason
+		status["StateReasonCode"] = "CANARY_FAILURE"
+	}
+	return map[string]interface{}{
+		"Id":     r.id,
+		"Name":   r.name,
+		"Status": status,
+		"Timeline": map[string]interface{}{
+			"Started":   float64(r.started.Unix()),
+			"Completed": float64(r.completed.Unix()),
+		},
+		"ArtifactS3Location": r.artifacts,
+	}
+}
+
+func (s *Service) getCanaryRuns(w http.ResponseWriter, r *http.Request, name string) {
+	params := readBody(r)
+	maxResults := h.GetInt(params, "MaxResults", 100)
+	start, _ := strconv.Atoi(h.GetString(params, "NextToken"))
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	c, exists := s.canaries[name]
+	if !exists {
+		writeNotFound(w, name)
+		return
+	}
+
+	// Runs are listed newest first.
+	list := []map[string]interface{}{}
+	i := start
+	for ; i < len(c.runs) && len(list) < maxResults; i++ {
+		list = append(list, c.runs[len(c.runs)-1-i].toMap())
+	}
+	resp := map[string]interface{}{"CanaryRuns": list}
+	if i < len(c.runs) {
+		resp["NextToken"] = strconv.Itoa(i)
+	}
+	h.WriteJSON(w, http.StatusOK, resp)
+}
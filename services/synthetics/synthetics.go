@@ -0,0 +1,478 @@
+// Package synthetics provides a mock implementation of Amazon CloudWatch
+// Synthetics.
+//
+// Supported actions:
+//   - CreateCanary
+//   - GetCanary
+//   - DescribeCanaries
+//   - DescribeCanariesLastRun
+//   - StartCanary
+//   - StopCanary
+//   - DeleteCanary
+//   - GetCanaryRuns
+//   - TagResource
+//   - UntagResource
+//   - ListTagsForResource
+//
+// Canaries are CREATING until their transition completes and then READY.
+// A started canary runs at once and then on its rate schedule as the mock
+// clock advances; a "rate(0 minute)" canary runs once and stops. No script
+// is executed: runs pass unless a result has been injected with
+// [Service.SetResult].
+package synthetics
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/riyanimam/goto/internal/clock"
+	"github.com/riyanimam/goto/internal/lifecycle"
+	h "github.com/riyanimam/goto/internal/mockhelpers"
+	"github.com/riyanimam/goto/internal/tags"
+)
+
+// canaryName matches the names AWS accepts for canaries.
+var canaryName = regexp.MustCompile(`^[0-9a-z_\-]{1,255}$`)
+
+// Service implements the CloudWatch Synthetics mock.
+type Service struct {
+	mu       sync.RWMutex
+	canaries map[string]*canary
+	tags     *tags.Store
+
+	clock       *clock.Clock
+	transitions *lifecycle.Transitions
+}
+
+type canary struct {
+	id               string
+	name             string
+	arn              string
+	code             map[string]interface{}
+	executionRoleArn string
+	expression       string
+	duration         int
+	runConfig        map[string]interface{}
+	successRetention int
+	failureRetention int
+	artifactLocation string
+	runtimeVersion   string
+	vpcConfig        map[string]interface{}
+	state            string // READY once created, then RUNNING or STOPPED
+	ready            lifecycle.Transition
+	created          time.Time
+	modified         time.Time
+	lastStarted      *time.Time
+	lastStopped      *time.Time
+	result           runResult
+	runs             []*run // newest last
+}
+
+// New creates a new Synthetics mock service.
+func New() *Service {
+	return &Service{
+		canaries: make(map[string]*canary),
+		tags:     tags.New(),
+	}
+}
+
+// Name returns the service identifier.
+func (s *Service) Name() string { return "synthetics" }
+
+// Handler returns the HTTP handler for Synthetics requests.
+func (s *Service) Handler() http.Handler {
+	return http.HandlerFunc(s.handle)
+}
+
+// Reset clears all state.
+func (s *Service) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.canaries = make(map[string]*canary)
+	s.tags.DeleteService("synthetics")
+}
+
+// SetTagStore sets the registry resource tags are recorded in.
+func (s *Service) SetTagStore(store *tags.Store) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tags = store
+}
+
+// SetTransitions sets how long new canaries stay CREATING.
+func (s *Service) SetTransitions(t *lifecycle.Transitions) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.transitions = t
+}
+
+// SetClock attaches the mock clock. Running canaries run on their schedule
+// as the clock advances.
+func (s *Service) SetClock(c *clock.Clock) {
+	s.mu.Lock()
+	s.clock = c
+	s.mu.Unlock()
+	c.OnAdvance(s.runDue)
+}
+
+// HasResource reports whether arn names an existing canary.
+func (s *Service) HasResource(arn string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.hasResource(arn)
+}
+
+func (s *Service) hasResource(arn string) bool {
+	for _, c := range s.canaries {
+		if c.arn == arn {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Service) now() time.Time {
+	if s.clock != nil {
+		return s.clock.Now()
+	}
+	return time.Now().UTC()
+}
+
+func (s *Service) handle(w http.ResponseWriter, r *http.Request) {
+	path := r.URL.Path
+	method := r.Method
+
+	switch {
+	case strings.HasPrefix(path, "/tags/"):
+		s.handleTags(w, r, strings.TrimPrefix(path, "/tags/"))
+	case path == "/canary" && method == http.MethodPost:
+		s.createCanary(w, r)
+	case path == "/canaries" && method == http.MethodPost:
+		s.describeCanaries(w, r)
+	case path == "/canaries/last-run" && method == http.MethodPost:
+		s.describeCanariesLastRun(w, r)
+	case strings.HasPrefix(path, "/canary/") && strings.HasSuffix(path, "/start") && method == http.MethodPost:
+		s.startCanary(w, extractName(path))
+	case strings.HasPrefix(path, "/canary/") && strings.HasSuffix(path, "/stop") && method == http.MethodPost:
+		s.stopCanary(w, extractName(path))
+	case strings.HasPrefix(path, "/canary/") && strings.HasSuffix(path, "/runs") && method == http.MethodPost:
+		s.getCanaryRuns(w, r, extractName(path))
+	case strings.HasPrefix(path, "/canary/") && method == http.MethodGet:
+		s.getCanary(w, extractName(path))
+	case strings.HasPrefix(path, "/canary/") && method == http.MethodDelete:
+		s.deleteCanary(w, extractName(path))
+	default:
+		h.WriteJSONError(w, "NotFoundException", "unsupported operation", http.StatusNotFound)
+	}
+}
+
+// extractName returns the canary name from a /canary/{name}[/...] path.
+func extractName(path string) string {
+	parts := strings.Split(strings.TrimPrefix(path, "/"), "/")
+	if len(parts) >= 2 {
+		return parts[1]
+	}
+	return ""
+}
+
+func readBody(r *http.Request) map[string]interface{} {
+	bodyBytes, _ := io.ReadAll(r.Body)
+	var params map[string]interface{}
+	json.Unmarshal(bodyBytes, &params)
+	return params
+}
+
+func canaryArn(name string) string {
+	return fmt.Sprintf("arn:aws:synthetics:us-east-1:%s:canary:%s", h.DefaultAccountID, name)
+}
+
+func writeNotFound(w http.ResponseWriter, name string) {
+	h.WriteJSONError(w, "ResourceNotFoundException", "Canary "+name+" not found.", http.StatusNotFound)
+}
+
+// status returns c's current state. The caller must hold s.mu.
+func (s *Service) status(c *canary) string {
+	if c.state == "READY" {
+		return s.transitions.Status(c.ready, "CREATING", "READY")
+	}
+	return c.state
+}
+
+func (s *Service) createCanary(w http.ResponseWriter, r *http.Request) {
+	params := readBody(r)
+	name := h.GetString(params, "Name")
+	code, _ := params["Code"].(map[string]interface{})
+	schedule, _ := params["Schedule"].(map[string]interface{})
+	expression := h.GetString(schedule, "Expression")
+
+	switch {
+	case !canaryName.MatchString(name):
+		h.WriteJSONError(w, "ValidationException", "Name must match pattern ^[0-9a-z_\\-]+$ and be at most 255 characters.", http.StatusBadRequest)
+		return
+	case h.GetString(code, "Handler") == "":
+		h.WriteJSONError(w, "ValidationException", "Code.Handler is required.", http.StatusBadRequest)
+		return
+	case h.GetString(params, "ArtifactS3Location") == "":
+		h.WriteJSONError(w, "ValidationException", "ArtifactS3Location is required.", http.StatusBadRequest)
+		return
+	case h.GetString(params, "ExecutionRoleArn") == "":
+		h.WriteJSONError(w, "ValidationException", "ExecutionRoleArn is required.", http.StatusBadRequest)
+		return
+	case h.GetString(params, "RuntimeVersion") == "":
+		h.WriteJSONError(w, "ValidationException", "RuntimeVersion is required.", http.StatusBadRequest)
+		return
+	}
+	if _, _, err := parseRate(expression); err != nil {
+		h.WriteJSONError(w, "ValidationException", err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, exists := s.canaries[name]; exists {
+		h.WriteJSONError(w, "ConflictException", "Canary "+name+" already exists.", http.StatusConflict)
+		return
+	}
+
+	now := s.now()
+	runConfig, _ := params["RunConfig"].(map[string]interface{})
+	if runConfig == nil {
+		runConfig = map[string]interface{}{}
+	}
+	if _, ok := runConfig["TimeoutInSeconds"]; !ok {
+		runConfig["TimeoutInSeconds"] = 840
+	}
+	if _, ok := runConfig["MemoryInMB"]; !ok {
+		runConfig["MemoryInMB"] = 1000
+	}
+	vpcConfig, _ := params["VpcConfig"].(map[string]interface{})
+	c := &canary{
+		id:   h.NewRequestID(),
+		name: name,
+		arn:  canaryArn(name),
+		code: map[string]interface{}{
+			"Handler":           h.GetString(code, "Handler"),
+			"SourceLocationArn": fmt.Sprintf("arn:aws:lambda:us-east-1:%s:layer:cwsyn-%s-%s:1", h.DefaultAccountID, name, h.RandomHex(16)),
+		},
+		executionRoleArn: h.GetString(params, "ExecutionRoleArn"),
+		expression:       expression,
+		duration:         h.GetInt(schedule, "DurationInSeconds", 0),
+		runConfig:        runConfig,
+		successRetention: h.GetInt(params, "SuccessRetentionPeriodInDays", 31),
+		failureRetention: h.GetInt(params, "FailureRetentionPeriodInDays", 31),
+		artifactLocation: h.GetString(params, "ArtifactS3Location"),
+		runtimeVersion:   h.GetString(params, "RuntimeVersion"),
+		vpcConfig:        vpcConfig,
+		state:            "READY",
+		ready:            s.transitions.Begin(),
+		created:          now,
+		modified:         now,
+		result:           runResult{passed: true},
+	}
+	s.canaries[name] = c
+	s.tags.Replace(c.arn, tags.FromMap(params["Tags"]))
+
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"Canary": s.canaryToMap(c),
+	})
+}
+
+func (s *Service) getCanary(w http.ResponseWriter, name string) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	c, exists := s.canaries[name]
+	if !exists {
+		writeNotFound(w, name)
+		return
+	}
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"Canary": s.canaryToMap(c),
+	})
+}
+
+// selectCanaries returns the canaries named in the Names parameter, or all
+// canaries if it is empty, sorted by name. The caller must hold s.mu.
+func (s *Service) selectCanaries(params map[string]interface{}) []*canary {
+	names, _ := params["Names"].([]interface{})
+	want := make(map[string]bool, len(names))
+	for _, n := range names {
+		if name, ok := n.(string); ok {
+			want[name] = true
+		}
+	}
+	var out []*canary
+	for name, c := range s.canaries {
+		if len(want) == 0 || want[name] {
+			out = append(out, c)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].name < out[j].name })
+	return out
+}
+
+func (s *Service) describeCanaries(w http.ResponseWriter, r *http.Request) {
+	params := readBody(r)
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	list := []map[string]interface{}{}
+	for _, c := range s.selectCanaries(params) {
+		list = append(list, s.canaryToMap(c))
+	}
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"Canaries": list,
+	})
+}
+
+func (s *Service) describeCanariesLastRun(w http.ResponseWriter, r *http.Request) {
+	params := readBody(r)
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	list := []map[string]interface{}{}
+	for _, c := range s.selectCanaries(params) {
+		if len(c.runs) == 0 {
+			continue
+		}
+		list = append(list, map[string]interface{}{
+			"CanaryName": c.name,
+			"LastRun":    c.runs[len(c.runs)-1].toMap(),
+		})
+	}
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"CanariesLastRun": list,
+	})
+}
+
+func (s *Service) startCanary(w http.ResponseWriter, name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c, exists := s.canaries[name]
+	if !exists {
+		writeNotFound(w, name)
+		return
+	}
+	if state := s.status(c); state != "READY" && state != "STOPPED" {
+		h.WriteJSONError(w, "ConflictException",
+			fmt.Sprintf("Canary %s is in %s state and cannot be started.", name, state), http.StatusConflict)
+		return
+	}
+
+	now := s.now()
+	c.state = "RUNNING"
+	c.lastStarted = &now
+	c.modified = now
+	s.run(c, now)
+
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{})
+}
+
+func (s *Service) stopCanary(w http.ResponseWriter, name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c, exists := s.canaries[name]
+	if !exists {
+		writeNotFound(w, name)
+		return
+	}
+	if c.state != "RUNNING" {
+		h.WriteJSONError(w, "ConflictException",
+			fmt.Sprintf("Canary %s is in %s state and cannot be stopped.", name, s.status(c)), http.StatusConflict)
+		return
+	}
+	s.stop(c, s.now())
+
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{})
+}
+
+// stop moves c to STOPPED at t. The caller must hold s.mu.
+func (s *Service) stop(c *canary, t time.Time) {
+	c.state = "STOPPED"
+	c.lastStopped = &t
+	c.modified = t
+}
+
+func (s *Service) deleteCanary(w http.ResponseWriter, name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c, exists := s.canaries[name]
+	if !exists {
+		writeNotFound(w, name)
+		return
+	}
+	if c.state == "RUNNING" {
+		h.WriteJSONError(w, "ConflictException",
+			"Canary "+name+" is in RUNNING state. Stop the canary before deleting it.", http.StatusConflict)
+		return
+	}
+	delete(s.canaries, name)
+	s.tags.Delete(c.arn)
+
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{})
+}
+
+// canaryToMap renders c as a Canary. The caller must hold s.mu.
+func (s *Service) canaryToMap(c *canary) map[string]interface{} {
+	timeline := map[string]interface{}{
+		"Created":      float64(c.created.Unix()),
+		"LastModified": float64(c.modified.Unix()),
+	}
+	if c.lastStarted != nil {
+		timeline["LastStarted"] = float64(c.lastStarted.Unix())
+	}
+	if c.lastStopped != nil {
+		timeline["LastStopped"] = float64(c.lastStopped.Unix())
+	}
+	m := map[string]interface{}{
+		"Id":               c.id,
+		"Name":             c.name,
+		"Code":             c.code,
+		"ExecutionRoleArn": c.executionRoleArn,
+		"Schedule": map[string]interface{}{
+			"Expression":        c.expression,
+			"DurationInSeconds": c.duration,
+		},
+		"RunConfig":                    c.runConfig,
+		"SuccessRetentionPeriodInDays": c.successRetention,
+		"FailureRetentionPeriodInDays": c.failureRetention,
+		"Status":                       map[string]interface{}{"State": s.status(c)},
+		"Timeline":                     timeline,
+		"ArtifactS3Location":           c.artifactLocation,
+		"RuntimeVersion":               c.runtimeVersion,
+		"EngineArn":                    fmt.Sprintf("arn:aws:lambda:us-east-1:%s:function:cwsyn-%s-%s", h.DefaultAccountID, c.name, c.id),
+		"Tags":                         s.tags.Get(c.arn),
+	}
+	if c.vpcConfig != nil {
+		m["VpcConfig"] = c.vpcConfig
+	}
+	return m
+}
+
+func (s *Service) handleTags(w http.ResponseWriter, r *http.Request, arn string) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if !s.hasResource(arn) {
+		h.WriteJSONError(w, "ResourceNotFoundException", "Resource not found: "+arn, http.StatusNotFound)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPost:
+		s.tags.Tag(arn, tags.FromMap(readBody(r)["Tags"]))
+		h.WriteJSON(w, http.StatusOK, map[string]interface{}{})
+	case http.MethodDelete:
+		s.tags.Untag(arn, r.URL.Query()["tagKeys"])
+		h.WriteJSON(w, http.StatusOK, map[string]interface{}{})
+	default:
+		h.WriteJSON(w, http.StatusOK, map[string]interface{}{
+			"Tags": s.tags.Get(arn),
+		})
+	}
+}
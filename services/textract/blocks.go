@@ -0,0 +1,136 @@
+package textract
+
+import (
+	"sort"
+	"strings"
+
+	h "github.com/riyanimam/goto/internal/mockhelpers"
+)
+
+// confidence is reported for every block.
+const confidence = 99.5
+
+// Text is laid out from the top left of each page in lines of lineHeight,
+// with each character charWidth wide.
+const (
+	margin     = 0.05
+	lineHeight = 0.03
+	charWidth  = 0.01
+)
+
+// renderBlocks returns the blocks of doc: a PAGE block for each page with
+// its LINE blocks as children, each with its WORD blocks as children, and,
+// if forms is set, KEY_VALUE_SET blocks for doc's fields on the first page.
+func renderBlocks(doc Document, forms bool) []map[string]interface{} {
+	pages := doc.Pages
+	if len(pages) == 0 {
+		pages = [][]string{nil}
+	}
+	var blocks []map[string]interface{}
+	for p, lines := range pages {
+		page := newBlock("PAGE", p+1, "", box(0, 0, 1, 1))
+		blocks = append(blocks, page)
+		var lineIDs []string
+		for i, text := range lines {
+			top := margin + float64(i)*lineHeight
+			line := newBlock("LINE", p+1, text, box(margin, top, float64(len(text))*charWidth, lineHeight))
+			words := wordBlocks(text, p+1, margin, top)
+			addChildren(line, "CHILD", words)
+			lineIDs = append(lineIDs, line["Id"].(string))
+			blocks = append(blocks, line)
+			blocks = append(blocks, words...)
+		}
+		if len(lineIDs) > 0 {
+			page["Relationships"] = []map[string]interface{}{{"Type": "CHILD", "Ids": lineIDs}}
+		}
+	}
+	if !forms {
+		return blocks
+	}
+
+	keys := make([]string, 0, len(doc.Fields))
+	for k := range doc.Fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	// Form fields are laid out below the page's lines.
+	top := margin + float64(len(pages[0]))*lineHeight
+	for i, k := range keys {
+		y := top + float64(i)*lineHeight
+		valueLeft := margin + float64(len(k)+1)*charWidth
+		keyWords := wordBlocks(k, 1, margin, y)
+		valueWords := wordBlocks(doc.Fields[k], 1, valueLeft, y)
+
+		key := newBlock("KEY_VALUE_SET", 1, "", box(margin, y, float64(len(k))*charWidth, lineHeight))
+		key["EntityTypes"] = []string{"KEY"}
+		value := newBlock("KEY_VALUE_SET", 1, "", box(valueLeft, y, float64(len(doc.Fields[k]))*charWidth, lineHeight))
+		value["EntityTypes"] = []string{"VALUE"}
+		key["Relationships"] = []map[string]interface{}{{"Type": "VALUE", "Ids": []string{value["Id"].(string)}}}
+		addChildren(key, "CHILD", keyWords)
+		addChildren(value, "CHILD", valueWords)
+
+		blocks = append(blocks, key, value)
+		blocks = append(blocks, keyWords...)
+		blocks = append(blocks, valueWords...)
+	}
+	return blocks
+}
+
+// wordBlocks returns the WORD blocks of a line of text starting at left,
+// top.
+func wordBlocks(text string, page int, left, top float64) []map[string]interface{} {
+	var words []map[string]interface{}
+	offset := 0
+	for _, word := range strings.Split(text, " ") {
+		if word != "" {
+			x := left + float64(offset)*charWidth
+			b := newBlock("WORD", page, word, box(x, top, float64(len(word))*charWidth, lineHeight))
+			b["TextType"] = "PRINTED"
+			words = append(words, b)
+		}
+		offset += len(word) + 1
+	}
+	return words
+}
+
+func newBlock(blockType string, page int, text string, geometry map[string]interface{}) map[string]interface{} {
+	b := map[string]interface{}{
+		"BlockType":  blockType,
+		"Id":         h.NewRequestID(),
+		"Confidence": confidence,
+		"Geometry":   geometry,
+		"Page":       page,
+	}
+	if text != "" {
+		b["Text"] = text
+	}
+	return b
+}
+
+// addChildren appends a relationship from parent to children, if there are
+// any.
+func addChildren(parent map[string]interface{}, relType string, children []map[string]interface{}) {
+	if len(children) == 0 {
+		return
+	}
+	ids := make([]string, len(children))
+	for i, c := range children {
+		ids[i] = c["Id"].(string)
+	}
+	rels, _ := parent["Relationships"].([]map[string]interface{})
+	parent["Relationships"] = append(rels, map[string]interface{}{"Type": relType, "Ids": ids})
+}
+
+// box returns the geometry of a rectangle, as a bounding box and polygon in
+// page-relative coordinates.
+func box(left, top, width, height float64) map[string]interface{} {
+	return map[string]interface{}{
+		"BoundingBox": map[string]float64{"Left": left, "Top": top, "Width": width, "Height": height},
+		"Polygon": []map[string]float64{
+			{"X": left, "Y": top},
+			{"X": left + width, "Y": top},
+			{"X": left + width, "Y": top + height},
+			{"X": left, "Y": top + height},
+		},
+	}
+}
@@ -0,0 +1,361 @@
+// Package textract provides a mock implementation of Amazon Textract.
+//
+// Supported actions:
+//   - DetectDocumentText
+//   - AnalyzeDocument
+//   - StartDocumentTextDetection
+//   - GetDocumentTextDetection
+//
+// No OCR runs. The text of a document in S3 is the [Document] fixture set
+// for its bucket and key with [Service.SetDocument]; S3 objects without a
+// fixture, and documents passed as bytes, are a single blank page. Fixtures
+// are rendered as PAGE, LINE, and WORD blocks with evenly spaced geometry,
+// plus KEY_VALUE_SET blocks for AnalyzeDocument's FORMS feature.
+//
+// Text detection jobs are IN_PROGRESS until their lifecycle transition
+// completes and then SUCCEEDED. A job with a NotificationChannel publishes
+// its completion to the SNS topic when that is first seen: at once without
+// a transition delay, and otherwise when the mock clock advances past it or
+// the job is fetched.
+package textract
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/riyanimam/goto/internal/clock"
+	"github.com/riyanimam/goto/internal/lifecycle"
+	h "github.com/riyanimam/goto/internal/mockhelpers"
+	"github.com/riyanimam/goto/internal/paginate"
+)
+
+// Document is the content Textract reports for a document.
+type Document struct {
+	// Pages holds the lines of text on each page.
+	Pages [][]string
+	// Fields are form keys and their values, reported on the first page by
+	// AnalyzeDocument with the FORMS feature.
+	Fields map[string]string
+}
+
+// Service implements the Textract mock.
+type Service struct {
+	mu        sync.RWMutex
+	documents map[string]Document // by "bucket/key"
+	jobs      map[string]*job
+	tokens    map[string]string // client request token to job ID
+
+	store       h.ObjectStore
+	dispatch    h.Dispatcher
+	clock       *clock.Clock
+	transitions *lifecycle.Transitions
+}
+
+type job struct {
+	id       string
+	bucket   string
+	key      string
+	tag      string
+	topicArn string
+	pages    int
+	blocks   []map[string]interface{}
+	ready    lifecycle.Transition
+	notified bool
+}
+
+// notice is a job completion to publish once the service's lock is
+// released.
+type notice struct {
+	topicArn string
+	message  []byte
+}
+
+// New creates a new Textract mock service.
+func New() *Service {
+	return &Service{
+		documents: make(map[string]Document),
+		jobs:      make(map[string]*job),
+		tokens:    make(map[string]string),
+	}
+}
+
+// Name returns the service identifier.
+func (s *Service) Name() string { return "textract" }
+
+// Handler returns the HTTP handler for Textract requests.
+func (s *Service) Handler() http.Handler {
+	return h.JSONRouter{
+		"DetectDocumentText":         s.detectDocumentText,
+		"AnalyzeDocument":            s.analyzeDocument,
+		"StartDocumentTextDetection": s.startDocumentTextDetection,
+		"GetDocumentTextDetection":   s.getDocumentTextDetection,
+	}
+}
+
+// Reset clears all state, including document fixtures.
+func (s *Service) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.documents = make(map[string]Document)
+	s.jobs = make(map[string]*job)
+	s.tokens = make(map[string]string)
+}
+
+// SetObjectStore sets the store S3 documents are checked against.
+func (s *Service) SetObjectStore(store h.ObjectStore) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.store = store
+}
+
+// SetDispatcher sets the function used to publish job completions to SNS.
+func (s *Service) SetDispatcher(d h.Dispatcher) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.dispatch = d
+}
+
+// SetTransitions sets how long text detection jobs stay IN_PROGRESS.
+func (s *Service) SetTransitions(t *lifecycle.Transitions) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.transitions = t
+}
+
+// SetClock attaches the mock clock. Jobs that complete as it advances
+// publish their completion.
+func (s *Service) SetClock(c *clock.Clock) {
+	s.mu.Lock()
+	s.clock = c
+	s.mu.Unlock()
+	c.OnAdvance(func(_, _ time.Time) {
+		s.mu.Lock()
+		notices := s.completed()
+		s.mu.Unlock()
+		s.notify(notices)
+	})
+}
+
+func (s *Service) now() time.Time {
+	if s.clock != nil {
+		return s.clock.Now()
+	}
+	return time.Now().UTC()
+}
+
+// SetDocument sets the content reported for the S3 object at bucket/key.
+func (s *Service) SetDocument(bucket, key string, doc Document) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.documents[bucket+"/"+key] = doc
+}
+
+func writeInvalid(w http.ResponseWriter, message string) {
+	h.WriteJSONError(w, "InvalidParameterException", message, http.StatusBadRequest)
+}
+
+// document returns the fixture for a Document or DocumentLocation
+// parameter, or writes an error if it names an S3 object that does not
+// exist. The caller must hold s.mu.
+func (s *Service) document(w http.ResponseWriter, params map[string]interface{}, field string) (Document, string, string, bool) {
+	doc, _ := params[field].(map[string]interface{})
+	object, _ := doc["S3Object"].(map[string]interface{})
+	if object == nil {
+		if _, ok := doc["Bytes"].(string); ok && field == "Document" {
+			return Document{}, "", "", true
+		}
+		writeInvalid(w, "Request has invalid parameters: "+field+" must set S3Object or Bytes.")
+		return Document{}, "", "", false
+	}
+	bucket, key := h.GetString(object, "Bucket"), h.GetString(object, "Name")
+	if s.store != nil {
+		if _, err := s.store.GetObject(bucket, key); err != nil {
+			h.WriteJSONError(w, "InvalidS3ObjectException", "Unable to get object metadata from S3. Check object key, region and/or access permissions.", http.StatusBadRequest)
+			return Document{}, "", "", false
+		}
+	}
+	return s.documents[bucket+"/"+key], bucket, key, true
+}
+
+func (s *Service) detectDocumentText(w http.ResponseWriter, params map[string]interface{}) {
+	s.mu.RLock()
+	doc, _, _, ok := s.document(w, params, "Document")
+	s.mu.RUnlock()
+	if !ok {
+		return
+	}
+	if len(doc.Pages) > 1 {
+		writeUnsupported(w)
+		return
+	}
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"DocumentMetadata":               map[string]int{"Pages": 1},
+		"Blocks":                         renderBlocks(doc, false),
+		"DetectDocumentTextModelVersion": "1.0",
+	})
+}
+
+func (s *Service) analyzeDocument(w http.ResponseWriter, params map[string]interface{}) {
+	features, _ := params["FeatureTypes"].([]interface{})
+	if len(features) == 0 {
+		writeInvalid(w, "Request has invalid parameters: FeatureTypes must contain at least one feature.")
+		return
+	}
+	forms := false
+	for _, f := range features {
+		switch f {
+		case "FORMS":
+			forms = true
+		case "TABLES", "QUERIES", "SIGNATURES", "LAYOUT":
+		default:
+			writeInvalid(w, "Request has invalid parameters: unsupported feature type.")
+			return
+		}
+	}
+
+	s.mu.RLock()
+	doc, _, _, ok := s.document(w, params, "Document")
+	s.mu.RUnlock()
+	if !ok {
+		return
+	}
+	if len(doc.Pages) > 1 {
+		writeUnsupported(w)
+		return
+	}
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"DocumentMetadata":            map[string]int{"Pages": 1},
+		"Blocks":                      renderBlocks(doc, forms),
+		"AnalyzeDocumentModelVersion": "1.0",
+	})
+}
+
+// writeUnsupported rejects multi-page documents sent to the synchronous
+// operations, which only process single pages.
+func writeUnsupported(w http.ResponseWriter) {
+	h.WriteJSONError(w, "UnsupportedDocumentException", "Request has unsupported document format", http.StatusBadRequest)
+}
+
+func (s *Service) startDocumentTextDetection(w http.ResponseWriter, params map[string]interface{}) {
+	channel, _ := params["NotificationChannel"].(map[string]interface{})
+	if channel != nil && (h.GetString(channel, "SNSTopicArn") == "" || h.GetString(channel, "RoleArn") == "") {
+		writeInvalid(w, "Request has invalid parameters: NotificationChannel requires SNSTopicArn and RoleArn.")
+		return
+	}
+
+	s.mu.Lock()
+	token := h.GetString(params, "ClientRequestToken")
+	if id, seen := s.tokens[token]; seen && token != "" {
+		s.mu.Unlock()
+		h.WriteJSON(w, http.StatusOK, map[string]interface{}{"JobId": id})
+		return
+	}
+	doc, bucket, key, ok := s.document(w, params, "DocumentLocation")
+	if !ok {
+		s.mu.Unlock()
+		return
+	}
+	pages := len(doc.Pages)
+	if pages == 0 {
+		pages = 1
+	}
+	j := &job{
+		id:       h.RandomHex(64),
+		bucket:   bucket,
+		key:      key,
+		tag:      h.GetString(params, "JobTag"),
+		topicArn: h.GetString(channel, "SNSTopicArn"),
+		pages:    pages,
+		blocks:   renderBlocks(doc, false),
+		ready:    s.transitions.Begin(),
+	}
+	s.jobs[j.id] = j
+	if token != "" {
+		s.tokens[token] = j.id
+	}
+	notices := s.completed()
+	s.mu.Unlock()
+	s.notify(notices)
+
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{"JobId": j.id})
+}
+
+func (s *Service) getDocumentTextDetection(w http.ResponseWriter, params map[string]interface{}) {
+	id := h.GetString(params, "JobId")
+	s.mu.Lock()
+	j, exists := s.jobs[id]
+	if !exists {
+		s.mu.Unlock()
+		h.WriteJSONError(w, "InvalidJobIdException", "An invalid job identifier was passed.", http.StatusBadRequest)
+		return
+	}
+	notices := s.completed()
+	done := s.transitions.Done(j.ready)
+	s.mu.Unlock()
+	s.notify(notices)
+
+	out := map[string]interface{}{
+		"DocumentMetadata":               map[string]int{"Pages": j.pages},
+		"DetectDocumentTextModelVersion": "1.0",
+	}
+	if !done {
+		out["JobStatus"] = "IN_PROGRESS"
+		h.WriteJSON(w, http.StatusOK, out)
+		return
+	}
+	blocks, next, err := paginate.Page(j.blocks, paginate.Seq, h.GetString(params, "NextToken"), h.GetInt(params, "MaxResults", 1000), 1000)
+	if err != nil {
+		writeInvalid(w, "Request has invalid parameters: invalid NextToken.")
+		return
+	}
+	out["JobStatus"] = "SUCCEEDED"
+	out["Blocks"] = blocks
+	if next != "" {
+		out["NextToken"] = next
+	}
+	h.WriteJSON(w, http.StatusOK, out)
+}
+
+// completed marks jobs that have completed since last checked as notified
+// and returns the completions to publish. The caller must hold s.mu.
+func (s *Service) completed() []notice {
+	var notices []notice
+	for _, j := range s.jobs {
+		if j.notified || !s.transitions.Done(j.ready) {
+			continue
+		}
+		j.notified = true
+		if j.topicArn == "" {
+			continue
+		}
+		message, _ := json.Marshal(map[string]interface{}{
+			"JobId":     j.id,
+			"Status":    "SUCCEEDED",
+			"API":       "StartDocumentTextDetection",
+			"JobTag":    j.tag,
+			"Timestamp": s.now().UnixMilli(),
+			"DocumentLocation": map[string]string{
+				"S3ObjectName": j.key,
+				"S3Bucket":     j.bucket,
+			},
+		})
+		notices = append(notices, notice{topicArn: j.topicArn, message: message})
+	}
+	return notices
+}
+
+// notify publishes job completions. Failures are dropped, as Textract does
+// when it cannot publish to a topic.
+func (s *Service) notify(notices []notice) {
+	s.mu.RLock()
+	dispatch := s.dispatch
+	s.mu.RUnlock()
+	if dispatch == nil {
+		return
+	}
+	for _, n := range notices {
+		dispatch(n.topicArn, n.message)
+	}
+}
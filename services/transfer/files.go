@@ -0,0 +1,191 @@
+package transfer
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	h "github.com/riyanimam/goto/internal/mockhelpers"
+)
+
+// maxKeysPerUser is the Transfer Family limit on SSH public keys per user.
+const maxKeysPerUser = 50
+
+// keyTypes are the SSH public key algorithms Transfer Family accepts.
+var keyTypes = map[string]bool{
+	"ssh-rsa":             true,
+	"ssh-ed25519":         true,
+	"ecdsa-sha2-nistp256": true,
+	"ecdsa-sha2-nistp384": true,
+	"ecdsa-sha2-nistp521": true,
+}
+
+// validKey reports whether body looks like an OpenSSH authorized_keys entry:
+// a supported key type followed by a base64 blob and an optional comment.
+func validKey(body string) bool {
+	fields := strings.Fields(body)
+	if len(fields) < 2 || !keyTypes[fields[0]] {
+		return false
+	}
+	_, err := base64.StdEncoding.DecodeString(fields[1])
+	return err == nil
+}
+
+// sameKey compares two public keys by type and blob, ignoring comments.
+func sameKey(a, b string) bool {
+	fa, fb := strings.Fields(a), strings.Fields(b)
+	return len(fa) >= 2 && len(fb) >= 2 && fa[0] == fb[0] && fa[1] == fb[1]
+}
+
+// ErrPermissionDenied is returned by [Session] operations on paths outside the
+// user's logical directory mappings.
+var ErrPermissionDenied = errors.New("permission denied")
+
+// Session is an authenticated file session for a Transfer Family user,
+// standing in for an SFTP connection. Paths are resolved the way the server
+// would resolve them: relative paths start at the user's home directory, and
+// users with LOGICAL home directories only see their mapped entries. Files
+// are read and written as objects in the S3 mock.
+type Session struct {
+	svc      *Service
+	serverID string
+	userName string
+}
+
+// Login authenticates userName on serverID with an SSH public key that has
+// been imported for the user, as an SFTP client would.
+func (s *Service) Login(serverID, userName, publicKey string) (*Session, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	srv, exists := s.servers[serverID]
+	if !exists {
+		return nil, fmt.Errorf("server not found: %s", serverID)
+	}
+	if srv.state != "ONLINE" {
+		return nil, fmt.Errorf("server %s is %s", serverID, srv.state)
+	}
+	if srv.identityProviderType != "SERVICE_MANAGED" {
+		return nil, fmt.Errorf("server %s does not use service-managed users", serverID)
+	}
+	u, exists := srv.users[userName]
+	if !exists {
+		return nil, fmt.Errorf("authentication failed for user %s", userName)
+	}
+	for _, k := range u.sshKeys {
+		if sameKey(k.body, publicKey) {
+			return &Session{svc: s, serverID: serverID, userName: userName}, nil
+		}
+	}
+	return nil, fmt.Errorf("authentication failed for user %s", userName)
+}
+
+// Put writes data to the file at p.
+func (sess *Session) Put(p string, data []byte) error {
+	store, bucket, key, err := sess.resolve(p)
+	if err != nil {
+		return err
+	}
+	return store.PutObject(bucket, key, data)
+}
+
+// Get reads the file at p.
+func (sess *Session) Get(p string) ([]byte, error) {
+	store, bucket, key, err := sess.resolve(p)
+	if err != nil {
+		return nil, err
+	}
+	return store.GetObject(bucket, key)
+}
+
+// Remove deletes the file at p.
+func (sess *Session) Remove(p string) error {
+	store, bucket, key, err := sess.resolve(p)
+	if err != nil {
+		return err
+	}
+	return store.DeleteObject(bucket, key)
+}
+
+// List returns the sorted names of the entries in directory dir.
+// Subdirectories are returned with a trailing slash.
+func (sess *Session) List(dir string) ([]string, error) {
+	store, bucket, prefix, err := sess.resolve(dir)
+	if err != nil {
+		return nil, err
+	}
+	if prefix != "" {
+		prefix += "/"
+	}
+
+	keys, err := store.ListObjects(bucket, prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool)
+	var names []string
+	for _, k := range keys {
+		name := strings.TrimPrefix(k, prefix)
+		if i := strings.Index(name, "/"); i >= 0 {
+			name = name[:i+1]
+		}
+		if name != "" && !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// resolve maps a path in the user's view onto an S3 bucket and key.
+func (sess *Session) resolve(p string) (store h.ObjectStore, bucket, key string, err error) {
+	s := sess.svc
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.store == nil {
+		return nil, "", "", fmt.Errorf("no object store is configured")
+	}
+	u, _, msg := s.findUser(sess.serverID, sess.userName)
+	if u == nil {
+		return nil, "", "", errors.New(msg)
+	}
+
+	home := u.homeDirectory
+	if home == "" {
+		home = "/"
+	}
+	if !strings.HasPrefix(p, "/") {
+		p = path.Join(home, p)
+	}
+	p = path.Clean(p)
+
+	if u.homeDirectoryType == "LOGICAL" {
+		best := -1
+		for i, m := range u.mappings {
+			entry := path.Clean(m.entry)
+			if p != entry && !strings.HasPrefix(p, strings.TrimSuffix(entry, "/")+"/") {
+				continue
+			}
+			if best < 0 || len(entry) > len(path.Clean(u.mappings[best].entry)) {
+				best = i
+			}
+		}
+		if best < 0 {
+			return nil, "", "", fmt.Errorf("%s: %w", p, ErrPermissionDenied)
+		}
+		m := u.mappings[best]
+		p = path.Join(m.target, strings.TrimPrefix(p, path.Clean(m.entry)))
+	}
+
+	bucket, key, _ = strings.Cut(strings.TrimPrefix(p, "/"), "/")
+	if bucket == "" {
+		return nil, "", "", fmt.Errorf("%s: path does not name a bucket", p)
+	}
+	return s.store, bucket, key, nil
+}
@@ -8,24 +8,39 @@
 //   - CreateUser
 //   - DescribeUser
 //   - DeleteUser
+//   - UpdateUser
+//   - ListUsers
+//   - ImportSshPublicKey
+//   - DeleteSshPublicKey
+//   - TagResource
+//   - UntagResource
+//   - ListTagsForResource
+//
+// Users can also log in in-process with [Service.Login] to read and write
+// files, which are stored as objects in the S3 mock according to the user's
+// home directory or logical directory mappings. Servers do not listen for
+// SFTP connections; the listener is deferred until the module depends on an
+// SSH server library, and Login stands in for it.
 package transfer
 
 import (
-	"encoding/json"
 	"fmt"
-	"io"
 	"net/http"
 	"sort"
 	"strings"
 	"sync"
+	"time"
 
 	h "github.com/riyanimam/goto/internal/mockhelpers"
+	"github.com/riyanimam/goto/internal/tags"
 )
 
 // Service implements the Transfer Family mock.
 type Service struct {
 	mu      sync.RWMutex
 	servers map[string]*server
+	store   h.ObjectStore
+	tags    *tags.Store
 }
 
 type server struct {
@@ -39,17 +54,32 @@ type server struct {
 }
 
 type user struct {
-	userName      string
-	serverID      string
-	arn           string
-	role          string
-	homeDirectory string
+	userName          string
+	serverID          string
+	arn               string
+	role              string
+	homeDirectory     string
+	homeDirectoryType string
+	mappings          []mapping
+	sshKeys           []*sshKey
+}
+
+type mapping struct {
+	entry  string
+	target string
+}
+
+type sshKey struct {
+	id       string
+	body     string
+	imported time.Time
 }
 
 // New creates a new Transfer Family mock service.
 func New() *Service {
 	return &Service{
 		servers: make(map[string]*server),
+		tags:    tags.New(),
 	}
 }
 
@@ -58,7 +88,22 @@ func (s *Service) Name() string { return "transfer" }
 
 // Handler returns the HTTP handler for Transfer Family requests.
 func (s *Service) Handler() http.Handler {
-	return http.HandlerFunc(s.handle)
+	return h.JSONRouter{
+		"CreateServer":        s.createServer,
+		"DescribeServer":      s.describeServer,
+		"DeleteServer":        s.deleteServer,
+		"ListServers":         s.listServers,
+		"CreateUser":          s.createUser,
+		"DescribeUser":        s.describeUser,
+		"DeleteUser":          s.deleteUser,
+		"UpdateUser":          s.updateUser,
+		"ListUsers":           s.listUsers,
+		"ImportSshPublicKey":  s.importSshPublicKey,
+		"DeleteSshPublicKey":  s.deleteSshPublicKey,
+		"TagResource":         s.tagResource,
+		"UntagResource":       s.untagResource,
+		"ListTagsForResource": s.listTagsForResource,
+	}
 }
 
 // Reset clears all state.
@@ -66,51 +111,21 @@ func (s *Service) Reset() {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	s.servers = make(map[string]*server)
+	s.tags.DeleteService("transfer")
 }
 
-func (s *Service) handle(w http.ResponseWriter, r *http.Request) {
-	target := r.Header.Get("X-Amz-Target")
-
-	bodyBytes, err := io.ReadAll(r.Body)
-	if err != nil {
-		h.WriteJSONError(w, "InternalFailure", "could not read request body", http.StatusInternalServerError)
-		return
-	}
-
-	var params map[string]interface{}
-	if len(bodyBytes) > 0 {
-		json.Unmarshal(bodyBytes, &params)
-	}
-	if params == nil {
-		params = make(map[string]interface{})
-	}
-
-	action := ""
-	if target != "" {
-		parts := strings.SplitN(target, ".", 2)
-		if len(parts) == 2 {
-			action = parts[1]
-		}
-	}
+// SetTagStore sets the registry resource tags are recorded in.
+func (s *Service) SetTagStore(store *tags.Store) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tags = store
+}
 
-	switch action {
-	case "CreateServer":
-		s.createServer(w, params)
-	case "DescribeServer":
-		s.describeServer(w, params)
-	case "DeleteServer":
-		s.deleteServer(w, params)
-	case "ListServers":
-		s.listServers(w, params)
-	case "CreateUser":
-		s.createUser(w, params)
-	case "DescribeUser":
-		s.describeUser(w, params)
-	case "DeleteUser":
-		s.deleteUser(w, params)
-	default:
-		h.WriteJSONError(w, "UnknownOperationException", fmt.Sprintf("action %q is not supported", action), http.StatusBadRequest)
-	}
+// SetObjectStore sets the store that backs user home directories.
+func (s *Service) SetObjectStore(store h.ObjectStore) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.store = store
 }
 
 func (s *Service) createServer(w http.ResponseWriter, params map[string]interface{}) {
@@ -151,6 +166,7 @@ func (s *Service) createServer(w http.ResponseWriter, params map[string]interfac
 
 	s.mu.Lock()
 	s.servers[id] = srv
+	s.tags.Tag(arn, tags.FromList(params["Tags"], "Key", "Value"))
 	s.mu.Unlock()
 
 	h.WriteJSON(w, http.StatusOK, map[string]interface{}{
@@ -179,12 +195,17 @@ func (s *Service) deleteServer(w http.ResponseWriter, params map[string]interfac
 	serverID := h.GetString(params, "ServerId")
 
 	s.mu.Lock()
-	if _, exists := s.servers[serverID]; !exists {
+	srv, exists := s.servers[serverID]
+	if !exists {
 		s.mu.Unlock()
 		h.WriteJSONError(w, "ResourceNotFoundException", "Server not found: "+serverID, http.StatusBadRequest)
 		return
 	}
 	delete(s.servers, serverID)
+	s.tags.Delete(srv.arn)
+	for _, u := range srv.users {
+		s.tags.Delete(u.arn)
+	}
 	s.mu.Unlock()
 
 	h.WriteJSON(w, http.StatusOK, map[string]interface{}{})
@@ -219,6 +240,11 @@ func (s *Service) createUser(w http.ResponseWriter, params map[string]interface{
 	userName := h.GetString(params, "UserName")
 	role := h.GetString(params, "Role")
 	homeDirectory := h.GetString(params, "HomeDirectory")
+	homeDirectoryType := h.GetString(params, "HomeDirectoryType")
+	if homeDirectoryType == "" {
+		homeDirectoryType = "PATH"
+	}
+	keyBody := h.GetString(params, "SshPublicKeyBody")
 
 	if serverID == "" {
 		h.WriteJSONError(w, "InvalidParameterException", "ServerId is required", http.StatusBadRequest)
@@ -228,6 +254,14 @@ func (s *Service) createUser(w http.ResponseWriter, params map[string]interface{
 		h.WriteJSONError(w, "InvalidParameterException", "UserName is required", http.StatusBadRequest)
 		return
 	}
+	if homeDirectoryType != "PATH" && homeDirectoryType != "LOGICAL" {
+		h.WriteJSONError(w, "InvalidRequestException", "HomeDirectoryType must be PATH or LOGICAL", http.StatusBadRequest)
+		return
+	}
+	if keyBody != "" && !validKey(keyBody) {
+		h.WriteJSONError(w, "InvalidRequestException", "Unsupported or invalid SSH public key format", http.StatusBadRequest)
+		return
+	}
 
 	s.mu.Lock()
 	srv, exists := s.servers[serverID]
@@ -245,13 +279,19 @@ func (s *Service) createUser(w http.ResponseWriter, params map[string]interface{
 
 	arn := fmt.Sprintf("arn:aws:transfer:us-east-1:%s:user/%s/%s", h.DefaultAccountID, serverID, userName)
 	u := &user{
-		userName:      userName,
-		serverID:      serverID,
-		arn:           arn,
-		role:          role,
-		homeDirectory: homeDirectory,
+		userName:          userName,
+		serverID:          serverID,
+		arn:               arn,
+		role:              role,
+		homeDirectory:     homeDirectory,
+		homeDirectoryType: homeDirectoryType,
+		mappings:          parseMappings(params),
+	}
+	if keyBody != "" {
+		u.sshKeys = append(u.sshKeys, &sshKey{id: "key-" + h.RandomHex(17), body: keyBody, imported: time.Now().UTC()})
 	}
 	srv.users[userName] = u
+	s.tags.Tag(arn, tags.FromList(params["Tags"], "Key", "Value"))
 	s.mu.Unlock()
 
 	h.WriteJSON(w, http.StatusOK, map[string]interface{}{
@@ -298,17 +338,241 @@ func (s *Service) deleteUser(w http.ResponseWriter, params map[string]interface{
 		return
 	}
 
-	if _, exists := srv.users[userName]; !exists {
+	u, exists := srv.users[userName]
+	if !exists {
 		s.mu.Unlock()
 		h.WriteJSONError(w, "ResourceNotFoundException", "User not found: "+userName, http.StatusBadRequest)
 		return
 	}
 	delete(srv.users, userName)
+	s.tags.Delete(u.arn)
 	s.mu.Unlock()
 
 	h.WriteJSON(w, http.StatusOK, map[string]interface{}{})
 }
 
+func (s *Service) updateUser(w http.ResponseWriter, params map[string]interface{}) {
+	serverID := h.GetString(params, "ServerId")
+	userName := h.GetString(params, "UserName")
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u, errCode, errMsg := s.findUser(serverID, userName)
+	if u == nil {
+		h.WriteJSONError(w, errCode, errMsg, http.StatusBadRequest)
+		return
+	}
+
+	if t := h.GetString(params, "HomeDirectoryType"); t != "" {
+		if t != "PATH" && t != "LOGICAL" {
+			h.WriteJSONError(w, "InvalidRequestException", "HomeDirectoryType must be PATH or LOGICAL", http.StatusBadRequest)
+			return
+		}
+		u.homeDirectoryType = t
+	}
+	if _, ok := params["HomeDirectory"]; ok {
+		u.homeDirectory = h.GetString(params, "HomeDirectory")
+	}
+	if _, ok := params["HomeDirectoryMappings"]; ok {
+		u.mappings = parseMappings(params)
+	}
+	if role := h.GetString(params, "Role"); role != "" {
+		u.role = role
+	}
+
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"ServerId": serverID,
+		"UserName": userName,
+	})
+}
+
+// hasResource reports whether arn names a server or user. Caller must hold
+// s.mu.
+func (s *Service) hasResource(arn string) bool {
+	for _, srv := range s.servers {
+		if srv.arn == arn {
+			return true
+		}
+		for _, u := range srv.users {
+			if u.arn == arn {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (s *Service) tagResource(w http.ResponseWriter, params map[string]interface{}) {
+	arn := h.GetString(params, "Arn")
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if !s.hasResource(arn) {
+		h.WriteJSONError(w, "ResourceNotFoundException", "Resource not found: "+arn, http.StatusBadRequest)
+		return
+	}
+	s.tags.Tag(arn, tags.FromList(params["Tags"], "Key", "Value"))
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{})
+}
+
+func (s *Service) untagResource(w http.ResponseWriter, params map[string]interface{}) {
+	arn := h.GetString(params, "Arn")
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if !s.hasResource(arn) {
+		h.WriteJSONError(w, "ResourceNotFoundException", "Resource not found: "+arn, http.StatusBadRequest)
+		return
+	}
+	s.tags.Untag(arn, tags.Keys(params["TagKeys"]))
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{})
+}
+
+func (s *Service) listTagsForResource(w http.ResponseWriter, params map[string]interface{}) {
+	arn := h.GetString(params, "Arn")
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if !s.hasResource(arn) {
+		h.WriteJSONError(w, "ResourceNotFoundException", "Resource not found: "+arn, http.StatusBadRequest)
+		return
+	}
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"Tags": tags.ToList(s.tags.Get(arn), "Key", "Value"),
+	})
+}
+
+func (s *Service) listUsers(w http.ResponseWriter, params map[string]interface{}) {
+	serverID := h.GetString(params, "ServerId")
+
+	s.mu.RLock()
+	srv, exists := s.servers[serverID]
+	if !exists {
+		s.mu.RUnlock()
+		h.WriteJSONError(w, "ResourceNotFoundException", "Server not found: "+serverID, http.StatusBadRequest)
+		return
+	}
+
+	list := make([]map[string]interface{}, 0, len(srv.users))
+	for _, u := range srv.users {
+		list = append(list, map[string]interface{}{
+			"Arn":               u.arn,
+			"UserName":          u.userName,
+			"Role":              u.role,
+			"HomeDirectory":     u.homeDirectory,
+			"HomeDirectoryType": u.homeDirectoryType,
+			"SshPublicKeyCount": len(u.sshKeys),
+		})
+	}
+	s.mu.RUnlock()
+
+	sort.Slice(list, func(i, j int) bool {
+		return list[i]["UserName"].(string) < list[j]["UserName"].(string)
+	})
+
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"ServerId": serverID,
+		"Users":    list,
+	})
+}
+
+func (s *Service) importSshPublicKey(w http.ResponseWriter, params map[string]interface{}) {
+	serverID := h.GetString(params, "ServerId")
+	userName := h.GetString(params, "UserName")
+	body := strings.TrimSpace(h.GetString(params, "SshPublicKeyBody"))
+
+	if !validKey(body) {
+		h.WriteJSONError(w, "InvalidRequestException", "Unsupported or invalid SSH public key format", http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u, errCode, errMsg := s.findUser(serverID, userName)
+	if u == nil {
+		h.WriteJSONError(w, errCode, errMsg, http.StatusBadRequest)
+		return
+	}
+
+	for _, k := range u.sshKeys {
+		if sameKey(k.body, body) {
+			h.WriteJSONError(w, "ResourceExistsException", "Public key already exists for user "+userName, http.StatusBadRequest)
+			return
+		}
+	}
+	if len(u.sshKeys) >= maxKeysPerUser {
+		h.WriteJSONError(w, "ResourceExistsException", fmt.Sprintf("User %s already has %d public keys", userName, maxKeysPerUser), http.StatusBadRequest)
+		return
+	}
+
+	key := &sshKey{id: "key-" + h.RandomHex(17), body: body, imported: time.Now().UTC()}
+	u.sshKeys = append(u.sshKeys, key)
+
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"ServerId":       serverID,
+		"UserName":       userName,
+		"SshPublicKeyId": key.id,
+	})
+}
+
+func (s *Service) deleteSshPublicKey(w http.ResponseWriter, params map[string]interface{}) {
+	serverID := h.GetString(params, "ServerId")
+	userName := h.GetString(params, "UserName")
+	keyID := h.GetString(params, "SshPublicKeyId")
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u, errCode, errMsg := s.findUser(serverID, userName)
+	if u == nil {
+		h.WriteJSONError(w, errCode, errMsg, http.StatusBadRequest)
+		return
+	}
+
+	for i, k := range u.sshKeys {
+		if k.id == keyID {
+			u.sshKeys = append(u.sshKeys[:i], u.sshKeys[i+1:]...)
+			h.WriteJSON(w, http.StatusOK, map[string]interface{}{})
+			return
+		}
+	}
+	h.WriteJSONError(w, "ResourceNotFoundException", "Public key not found: "+keyID, http.StatusBadRequest)
+}
+
+// findUser looks up a user on a server. The caller must hold s.mu. When the
+// server or user does not exist it returns nil and the error to report.
+func (s *Service) findUser(serverID, userName string) (*user, string, string) {
+	srv, exists := s.servers[serverID]
+	if !exists {
+		return nil, "ResourceNotFoundException", "Server not found: " + serverID
+	}
+	u, exists := srv.users[userName]
+	if !exists {
+		return nil, "ResourceNotFoundException", "User not found: " + userName
+	}
+	return u, "", ""
+}
+
+func parseMappings(params map[string]interface{}) []mapping {
+	var mappings []mapping
+	if list, ok := params["HomeDirectoryMappings"].([]interface{}); ok {
+		for _, item := range list {
+			if m, ok := item.(map[string]interface{}); ok {
+				mappings = append(mappings, mapping{
+					entry:  h.GetString(m, "Entry"),
+					target: h.GetString(m, "Target"),
+				})
+			}
+		}
+	}
+	return mappings
+}
+
 func serverResp(srv *server) map[string]interface{} {
 	return map[string]interface{}{
 		"ServerId":             srv.id,
@@ -322,11 +586,30 @@ func serverResp(srv *server) map[string]interface{} {
 }
 
 func userResp(u *user) map[string]interface{} {
-	return map[string]interface{}{
-		"UserName":      u.userName,
-		"ServerId":      u.serverID,
-		"Arn":           u.arn,
-		"Role":          u.role,
-		"HomeDirectory": u.homeDirectory,
+	keys := make([]map[string]interface{}, 0, len(u.sshKeys))
+	for _, k := range u.sshKeys {
+		keys = append(keys, map[string]interface{}{
+			"SshPublicKeyId":   k.id,
+			"SshPublicKeyBody": k.body,
+			"DateImported":     float64(k.imported.Unix()),
+		})
+	}
+
+	resp := map[string]interface{}{
+		"UserName":          u.userName,
+		"ServerId":          u.serverID,
+		"Arn":               u.arn,
+		"Role":              u.role,
+		"HomeDirectory":     u.homeDirectory,
+		"HomeDirectoryType": u.homeDirectoryType,
+		"SshPublicKeys":     keys,
+	}
+	if len(u.mappings) > 0 {
+		var mappings []map[string]interface{}
+		for _, m := range u.mappings {
+			mappings = append(mappings, map[string]interface{}{"Entry": m.entry, "Target": m.target})
+		}
+		resp["HomeDirectoryMappings"] = mappings
 	}
+	return resp
 }
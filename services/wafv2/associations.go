@@ -0,0 +1,154 @@
+package wafv2
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/riyanimam/goto/internal/arn"
+	h "github.com/riyanimam/goto/internal/mockhelpers"
+)
+
+// SetResolver sets the function used to check that resources web ACLs are
+// associated with exist.
+func (s *Service) SetResolver(r h.Resolver) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.resolve = r
+}
+
+// associableType returns the ListResourcesForWebACL resource type of the
+// resource named by resourceArn, or "" if a regional web ACL cannot be
+// associated with it. Of the load balancers, only Application Load
+// Balancers can be protected.
+func associableType(resourceArn string) string {
+	a, err := arn.Parse(resourceArn)
+	if err != nil {
+		return ""
+	}
+	switch {
+	case a.Service == "elasticloadbalancing" && strings.HasPrefix(a.Resource, "loadbalancer/app/"):
+		return "APPLICATION_LOAD_BALANCER"
+	case a.Service == "apigateway" && strings.Contains(a.Resource, "/stages/"):
+		return "API_GATEWAY"
+	case a.Service == "appsync" && a.ResourceType() == "apis":
+		return "APPSYNC"
+	case a.Service == "cognito-idp" && a.ResourceType() == "userpool":
+		return "COGNITO_USER_POOL"
+	case a.Service == "apprunner" && a.ResourceType() == "service":
+		return "APP_RUNNER_SERVICE"
+	case a.Service == "ec2" && a.ResourceType() == "verified-access-instance":
+		return "VERIFIED_ACCESS_INSTANCE"
+	}
+	return ""
+}
+
+// findWebACLByARN returns the web ACL identified by aclArn, or nil. The
+// caller must hold s.mu.
+func (s *Service) findWebACLByARN(aclArn string) *webACL {
+	for _, acl := range s.webACLs {
+		if acl.arn == aclArn {
+			return acl
+		}
+	}
+	return nil
+}
+
+func (s *Service) associateWebACL(w http.ResponseWriter, params map[string]interface{}) {
+	aclArn := h.GetString(params, "WebACLArn")
+	resourceArn := h.GetString(params, "ResourceArn")
+	if associableType(resourceArn) == "" {
+		h.WriteJSONError(w, "WAFInvalidParameterException",
+			"Error reason: The ARN isn't valid for a web ACL association., field: RESOURCE_ARN, parameter: "+resourceArn,
+			http.StatusBadRequest)
+		return
+	}
+
+	s.mu.RLock()
+	resolve := s.resolve
+	s.mu.RUnlock()
+	if resolve != nil && resolve(resourceArn) != nil {
+		h.WriteJSONError(w, "WAFNonexistentItemException", "AWS WAF couldn't perform the operation because your resource doesn't exist.", http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	acl := s.findWebACLByARN(aclArn)
+	if acl == nil {
+		h.WriteJSONError(w, "WAFNonexistentItemException", "WebACL not found", http.StatusBadRequest)
+		return
+	}
+	if strings.EqualFold(acl.scope, "CLOUDFRONT") {
+		h.WriteJSONError(w, "WAFInvalidParameterException",
+			"Error reason: A web ACL with CLOUDFRONT scope is associated through the distribution., field: WEB_ACL, parameter: "+aclArn,
+			http.StatusBadRequest)
+		return
+	}
+	s.associations[resourceArn] = aclArn
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{})
+}
+
+func (s *Service) disassociateWebACL(w http.ResponseWriter, params map[string]interface{}) {
+	resourceArn := h.GetString(params, "ResourceArn")
+	if associableType(resourceArn) == "" {
+		h.WriteJSONError(w, "WAFInvalidParameterException",
+			"Error reason: The ARN isn't valid for a web ACL association., field: RESOURCE_ARN, parameter: "+resourceArn,
+			http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	delete(s.associations, resourceArn)
+	s.mu.Unlock()
+
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{})
+}
+
+func (s *Service) getWebACLForResource(w http.ResponseWriter, params map[string]interface{}) {
+	resourceArn := h.GetString(params, "ResourceArn")
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	aclArn, ok := s.associations[resourceArn]
+	if !ok {
+		// AWS omits the web ACL for resources without one.
+		h.WriteJSON(w, http.StatusOK, map[string]interface{}{})
+		return
+	}
+	acl := s.findWebACLByARN(aclArn)
+	if acl == nil {
+		h.WriteJSON(w, http.StatusOK, map[string]interface{}{})
+		return
+	}
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"WebACL": webACLResp(acl),
+	})
+}
+
+func (s *Service) listResourcesForWebACL(w http.ResponseWriter, params map[string]interface{}) {
+	aclArn := h.GetString(params, "WebACLArn")
+	resourceType := h.GetString(params, "ResourceType")
+	if resourceType == "" {
+		resourceType = "APPLICATION_LOAD_BALANCER"
+	}
+
+	s.mu.RLock()
+	if s.findWebACLByARN(aclArn) == nil {
+		s.mu.RUnlock()
+		h.WriteJSONError(w, "WAFNonexistentItemException", "WebACL not found", http.StatusBadRequest)
+		return
+	}
+	resources := []string{}
+	for resourceArn, associated := range s.associations {
+		if associated == aclArn && associableType(resourceArn) == resourceType {
+			resources = append(resources, resourceArn)
+		}
+	}
+	s.mu.RUnlock()
+
+	sort.Strings(resources)
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"ResourceArns": resources,
+	})
+}
@@ -0,0 +1,391 @@
+package wafv2
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"html"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	h "github.com/riyanimam/goto/internal/mockhelpers"
+)
+
+// countryHeader carries the request's country for geo match statements, as
+// CloudFront reports it. The mock has no geolocation database.
+const countryHeader = "CloudFront-Viewer-Country"
+
+// Verdict is the outcome of running a request through a web ACL.
+type Verdict struct {
+	// Action is ALLOW, BLOCK, CAPTCHA, or CHALLENGE.
+	Action string
+	// TerminatingRule is the name of the rule that decided the action, or
+	// Default_Action if no rule did.
+	TerminatingRule string
+	// MatchedRules lists every rule that matched in evaluation order,
+	// including Count rules and the terminating rule.
+	MatchedRules []string
+	// Labels are the fully qualified labels the matching rules added.
+	Labels []string
+}
+
+// evaluation holds the state of one request's run through a web ACL.
+type evaluation struct {
+	s      *Service
+	acl    *webACL
+	rule   string
+	req    *http.Request
+	body   []byte
+	labels []string
+}
+
+// Evaluate runs req through the rules of the web ACL identified by aclArn,
+// in priority order, as WAF would, and reports the resulting action. The
+// client IP is taken from req.RemoteAddr, or from a forwarded IP header if
+// a statement asks for one, and the country from the
+// CloudFront-Viewer-Country header. Rate-based rules count the requests
+// evaluated so far within their window on the mock clock. Managed rule
+// groups are placeholders that match nothing. Evaluate reports an error
+// for statements the mock cannot evaluate.
+func (s *Service) Evaluate(aclArn string, req *http.Request) (Verdict, error) {
+	var body []byte
+	if req.Body != nil {
+		body, _ = io.ReadAll(req.Body)
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	acl := s.findWebACLByARN(aclArn)
+	if acl == nil {
+		return Verdict{}, fmt.Errorf("web ACL %s does not exist", aclArn)
+	}
+
+	rules, _ := acl.rules.([]interface{})
+	sorted := make([]map[string]interface{}, 0, len(rules))
+	for _, r := range rules {
+		if m, ok := r.(map[string]interface{}); ok {
+			sorted = append(sorted, m)
+		}
+	}
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return h.GetInt(sorted[i], "Priority", 0) < h.GetInt(sorted[j], "Priority", 0)
+	})
+
+	ev := &evaluation{s: s, acl: acl, req: req, body: body}
+	var v Verdict
+	for _, rule := range sorted {
+		ev.rule = h.GetString(rule, "Name")
+		stmt, _ := rule["Statement"].(map[string]interface{})
+		matched, err := ev.match(stmt)
+		if err != nil {
+			return Verdict{}, fmt.Errorf("rule %s: %w", ev.rule, err)
+		}
+		if !matched {
+			continue
+		}
+		v.MatchedRules = append(v.MatchedRules, ev.rule)
+		if labels, ok := rule["RuleLabels"].([]interface{}); ok {
+			for _, l := range labels {
+				if m, ok := l.(map[string]interface{}); ok {
+					ev.labels = append(ev.labels, fmt.Sprintf("awswaf:%s:webacl:%s:%s", h.DefaultAccountID, acl.name, h.GetString(m, "Name")))
+				}
+			}
+		}
+		if action := actionOf(rule["Action"]); action != "" && action != "COUNT" {
+			v.Action, v.TerminatingRule = action, ev.rule
+			break
+		}
+	}
+	if v.Action == "" {
+		v.Action, v.TerminatingRule = actionOf(acl.defaultAction), "Default_Action"
+	}
+	v.Labels = ev.labels
+	return v, nil
+}
+
+// actionOf returns the action a rule or default action object names.
+func actionOf(action interface{}) string {
+	m, _ := action.(map[string]interface{})
+	for _, name := range []string{"Allow", "Block", "Count", "Captcha", "Challenge"} {
+		if _, ok := m[name]; ok {
+			return strings.ToUpper(name)
+		}
+	}
+	return ""
+}
+
+// match reports whether the request matches the statement.
+func (ev *evaluation) match(stmt map[string]interface{}) (bool, error) {
+	for kind, raw := range stmt {
+		body, _ := raw.(map[string]interface{})
+		switch kind {
+		case "AndStatement", "OrStatement":
+			statements, _ := body["Statements"].([]interface{})
+			for _, st := range statements {
+				m, _ := st.(map[string]interface{})
+				matched, err := ev.match(m)
+				if err != nil {
+					return false, err
+				}
+				if kind == "AndStatement" && !matched {
+					return false, nil
+				}
+				if kind == "OrStatement" && matched {
+					return true, nil
+				}
+			}
+			return kind == "AndStatement", nil
+		case "NotStatement":
+			inner, _ := body["Statement"].(map[string]interface{})
+			matched, err := ev.match(inner)
+			return !matched, err
+		case "IPSetReferenceStatement":
+			set := ev.s.findIPSetByARN(h.GetString(body, "ARN"))
+			if set == nil {
+				return false, fmt.Errorf("IP set %s does not exist", h.GetString(body, "ARN"))
+			}
+			ip, ok := ev.clientIP(body["IPSetForwardedIPConfig"])
+			if ip == nil {
+				return ok, nil
+			}
+			for _, cidr := range set.addresses {
+				if _, network, err := net.ParseCIDR(cidr); err == nil && network.Contains(ip) {
+					return true, nil
+				}
+			}
+			return false, nil
+		case "GeoMatchStatement":
+			country := ev.req.Header.Get(countryHeader)
+			for _, code := range stringList(body["CountryCodes"]) {
+				if strings.EqualFold(code, country) {
+					return true, nil
+				}
+			}
+			return false, nil
+		case "LabelMatchStatement":
+			key := h.GetString(body, "Key")
+			for _, label := range ev.labels {
+				if h.GetString(body, "Scope") == "NAMESPACE" {
+					if strings.Contains(label+":", ":"+strings.TrimSuffix(key, ":")+":") {
+						return true, nil
+					}
+				} else if label == key || strings.HasSuffix(label, ":"+key) {
+					return true, nil
+				}
+			}
+			return false, nil
+		case "ByteMatchStatement":
+			field, err := ev.field(body)
+			if err != nil {
+				return false, err
+			}
+			search := h.GetString(body, "SearchString")
+			if decoded, err := base64.StdEncoding.DecodeString(search); err == nil {
+				search = string(decoded)
+			}
+			switch h.GetString(body, "PositionalConstraint") {
+			case "EXACTLY":
+				return field == search, nil
+			case "STARTS_WITH":
+				return strings.HasPrefix(field, search), nil
+			case "ENDS_WITH":
+				return strings.HasSuffix(field, search), nil
+			case "CONTAINS_WORD":
+				re := regexp.MustCompile(`(^|[^A-Za-z0-9_])` + regexp.QuoteMeta(search) + `($|[^A-Za-z0-9_])`)
+				return re.MatchString(field), nil
+			default:
+				return strings.Contains(field, search), nil
+			}
+		case "RegexMatchStatement":
+			field, err := ev.field(body)
+			if err != nil {
+				return false, err
+			}
+			re, err := regexp.Compile(h.GetString(body, "RegexString"))
+			if err != nil {
+				return false, fmt.Errorf("invalid regex: %w", err)
+			}
+			return re.MatchString(field), nil
+		case "SizeConstraintStatement":
+			field, err := ev.field(body)
+			if err != nil {
+				return false, err
+			}
+			size, limit := len(field), h.GetInt(body, "Size", 0)
+			switch h.GetString(body, "ComparisonOperator") {
+			case "EQ":
+				return size == limit, nil
+			case "NE":
+				return size != limit, nil
+			case "LE":
+				return size <= limit, nil
+			case "LT":
+				return size < limit, nil
+			case "GE":
+				return size >= limit, nil
+			case "GT":
+				return size > limit, nil
+			}
+			return false, fmt.Errorf("unknown comparison operator %q", h.GetString(body, "ComparisonOperator"))
+		case "RateBasedStatement":
+			return ev.rateExceeded(body)
+		case "ManagedRuleGroupStatement":
+			return false, nil
+		default:
+			return false, fmt.Errorf("%s is not supported", kind)
+		}
+	}
+	return false, fmt.Errorf("empty statement")
+}
+
+// clientIP returns the IP the request came from, read from the forwarded
+// IP header if config names one. If the header is missing or invalid, ip
+// is nil and matched is the config's fallback behavior.
+func (ev *evaluation) clientIP(config interface{}) (ip net.IP, matched bool) {
+	cfg, _ := config.(map[string]interface{})
+	if header := h.GetString(cfg, "HeaderName"); header != "" {
+		values := strings.Split(ev.req.Header.Get(header), ",")
+		pick := strings.TrimSpace(values[0])
+		switch h.GetString(cfg, "Position") {
+		case "LAST":
+			pick = strings.TrimSpace(values[len(values)-1])
+		case "ANY":
+			for _, v := range values {
+				if net.ParseIP(strings.TrimSpace(v)) != nil {
+					pick = strings.TrimSpace(v)
+					break
+				}
+			}
+		}
+		if ip = net.ParseIP(pick); ip == nil {
+			return nil, h.GetString(cfg, "FallbackBehavior") == "MATCH"
+		}
+		return ip, false
+	}
+	host, _, err := net.SplitHostPort(ev.req.RemoteAddr)
+	if err != nil {
+		host = ev.req.RemoteAddr
+	}
+	return net.ParseIP(host), false
+}
+
+// rateExceeded records the request against its aggregation key, if it
+// passes the scope-down statement, and reports whether the key's requests
+// within the evaluation window exceed the limit. The caller must hold s.mu.
+func (ev *evaluation) rateExceeded(body map[string]interface{}) (bool, error) {
+	if scope, ok := body["ScopeDownStatement"].(map[string]interface{}); ok {
+		matched, err := ev.match(scope)
+		if err != nil || !matched {
+			return false, err
+		}
+	}
+	key := "*"
+	switch h.GetString(body, "AggregateKeyType") {
+	case "CONSTANT":
+	case "FORWARDED_IP":
+		ip, _ := ev.clientIP(body["ForwardedIPConfig"])
+		if ip == nil {
+			return false, nil
+		}
+		key = ip.String()
+	default:
+		ip, _ := ev.clientIP(nil)
+		key = ip.String()
+	}
+	window := time.Duration(h.GetInt(body, "EvaluationWindowSec", 300)) * time.Second
+	now := ev.s.now()
+	id := ev.acl.arn + "/" + ev.rule + "/" + key
+	kept := ev.s.rates[id][:0]
+	for _, t := range ev.s.rates[id] {
+		if now.Sub(t) < window {
+			kept = append(kept, t)
+		}
+	}
+	ev.s.rates[id] = append(kept, now)
+	return len(ev.s.rates[id]) > h.GetInt(body, "Limit", 0), nil
+}
+
+// field returns the part of the request a statement's FieldToMatch names,
+// after its text transformations.
+func (ev *evaluation) field(body map[string]interface{}) (string, error) {
+	ftm, _ := body["FieldToMatch"].(map[string]interface{})
+	var value string
+	switch {
+	case ftm["UriPath"] != nil:
+		value = ev.req.URL.Path
+	case ftm["QueryString"] != nil:
+		value = ev.req.URL.RawQuery
+	case ftm["Method"] != nil:
+		value = ev.req.Method
+	case ftm["Body"] != nil:
+		value = string(ev.body)
+	case ftm["SingleHeader"] != nil:
+		m, _ := ftm["SingleHeader"].(map[string]interface{})
+		value = ev.req.Header.Get(h.GetString(m, "Name"))
+	case ftm["SingleQueryArgument"] != nil:
+		m, _ := ftm["SingleQueryArgument"].(map[string]interface{})
+		value = ev.req.URL.Query().Get(h.GetString(m, "Name"))
+	default:
+		return "", fmt.Errorf("FieldToMatch is not supported")
+	}
+
+	transforms, _ := body["TextTransformations"].([]interface{})
+	sorted := make([]map[string]interface{}, 0, len(transforms))
+	for _, t := range transforms {
+		if m, ok := t.(map[string]interface{}); ok {
+			sorted = append(sorted, m)
+		}
+	}
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return h.GetInt(sorted[i], "Priority", 0) < h.GetInt(sorted[j], "Priority", 0)
+	})
+	for _, t := range sorted {
+		switch h.GetString(t, "Type") {
+		case "LOWERCASE":
+			value = strings.ToLower(value)
+		case "URL_DECODE":
+			if decoded, err := url.QueryUnescape(value); err == nil {
+				value = decoded
+			}
+		case "HTML_ENTITY_DECODE":
+			value = html.UnescapeString(value)
+		case "COMPRESS_WHITE_SPACE":
+			value = strings.Join(strings.Fields(value), " ")
+		case "BASE64_DECODE":
+			if decoded, err := base64.StdEncoding.DecodeString(value); err == nil {
+				value = string(decoded)
+			}
+		}
+	}
+	return value, nil
+}
+
+// findIPSetByARN returns the IP set identified by setArn, or nil. The
+// caller must hold s.mu.
+func (s *Service) findIPSetByARN(setArn string) *ipSet {
+	for _, set := range s.ipSets {
+		if set.arn == setArn {
+			return set
+		}
+	}
+	return nil
+}
+
+// stringList returns the strings in a JSON list.
+func stringList(v interface{}) []string {
+	list, _ := v.([]interface{})
+	out := make([]string, 0, len(list))
+	for _, item := range list {
+		if str, ok := item.(string); ok {
+			out = append(out, str)
+		}
+	}
+	return out
+}
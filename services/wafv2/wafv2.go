@@ -10,18 +10,29 @@
 //   - GetIPSet
 //   - DeleteIPSet
 //   - ListIPSets
+//   - TagResource
+//   - UntagResource
+//   - ListTagsForResource
+//   - AssociateWebACL
+//   - DisassociateWebACL
+//   - GetWebACLForResource
+//   - ListResourcesForWebACL
+//
+// Evaluate runs a synthetic request through a web ACL's rules without
+// deploying it.
 package wafv2
 
 import (
-	"encoding/json"
 	"fmt"
-	"io"
 	"net/http"
 	"sort"
 	"strings"
 	"sync"
+	"time"
 
+	"github.com/riyanimam/goto/internal/clock"
 	h "github.com/riyanimam/goto/internal/mockhelpers"
+	"github.com/riyanimam/goto/internal/tags"
 )
 
 type webACL struct {
@@ -49,16 +60,24 @@ type ipSet struct {
 
 // Service implements the WAFv2 mock.
 type Service struct {
-	mu      sync.RWMutex
-	webACLs map[string]*webACL
-	ipSets  map[string]*ipSet
+	mu           sync.RWMutex
+	webACLs      map[string]*webACL
+	ipSets       map[string]*ipSet
+	associations map[string]string // web ACL ARN by resource ARN
+	tags         *tags.Store
+	resolve      h.Resolver
+	clock        *clock.Clock
+	rates        map[string][]time.Time // evaluation times by rate-based rule and key
 }
 
 // New creates a new WAFv2 mock service.
 func New() *Service {
 	return &Service{
-		webACLs: make(map[string]*webACL),
-		ipSets:  make(map[string]*ipSet),
+		webACLs:      make(map[string]*webACL),
+		ipSets:       make(map[string]*ipSet),
+		associations: make(map[string]string),
+		tags:         tags.New(),
+		rates:        make(map[string][]time.Time),
 	}
 }
 
@@ -67,7 +86,24 @@ func (s *Service) Name() string { return "wafv2" }
 
 // Handler returns the HTTP handler for WAFv2 requests.
 func (s *Service) Handler() http.Handler {
-	return http.HandlerFunc(s.handle)
+	return h.JSONRouter{
+		"CreateWebACL":           s.createWebACL,
+		"GetWebACL":              s.getWebACL,
+		"DeleteWebACL":           s.deleteWebACL,
+		"ListWebACLs":            s.listWebACLs,
+		"UpdateWebACL":           s.updateWebACL,
+		"CreateIPSet":            s.createIPSet,
+		"GetIPSet":               s.getIPSet,
+		"DeleteIPSet":            s.deleteIPSet,
+		"ListIPSets":             s.listIPSets,
+		"TagResource":            s.tagResource,
+		"UntagResource":          s.untagResource,
+		"ListTagsForResource":    s.listTagsForResource,
+		"AssociateWebACL":        s.associateWebACL,
+		"DisassociateWebACL":     s.disassociateWebACL,
+		"GetWebACLForResource":   s.getWebACLForResource,
+		"ListResourcesForWebACL": s.listResourcesForWebACL,
+	}
 }
 
 // Reset clears all state.
@@ -76,55 +112,30 @@ func (s *Service) Reset() {
 	defer s.mu.Unlock()
 	s.webACLs = make(map[string]*webACL)
 	s.ipSets = make(map[string]*ipSet)
+	s.associations = make(map[string]string)
+	s.rates = make(map[string][]time.Time)
+	s.tags.DeleteService("wafv2")
 }
 
-func (s *Service) handle(w http.ResponseWriter, r *http.Request) {
-	target := r.Header.Get("X-Amz-Target")
-
-	bodyBytes, err := io.ReadAll(r.Body)
-	if err != nil {
-		h.WriteJSONError(w, "InternalFailure", "could not read request body", http.StatusInternalServerError)
-		return
-	}
-
-	var params map[string]interface{}
-	if len(bodyBytes) > 0 {
-		json.Unmarshal(bodyBytes, &params)
-	}
-	if params == nil {
-		params = make(map[string]interface{})
-	}
+// SetClock attaches the mock clock rate-based rules count requests against.
+func (s *Service) SetClock(c *clock.Clock) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.clock = c
+}
 
-	action := ""
-	if target != "" {
-		parts := strings.SplitN(target, ".", 2)
-		if len(parts) == 2 {
-			action = parts[1]
-		}
+func (s *Service) now() time.Time {
+	if s.clock != nil {
+		return s.clock.Now()
 	}
+	return time.Now().UTC()
+}
 
-	switch action {
-	case "CreateWebACL":
-		s.createWebACL(w, params)
-	case "GetWebACL":
-		s.getWebACL(w, params)
-	case "DeleteWebACL":
-		s.deleteWebACL(w, params)
-	case "ListWebACLs":
-		s.listWebACLs(w, params)
-	case "UpdateWebACL":
-		s.updateWebACL(w, params)
-	case "CreateIPSet":
-		s.createIPSet(w, params)
-	case "GetIPSet":
-		s.getIPSet(w, params)
-	case "DeleteIPSet":
-		s.deleteIPSet(w, params)
-	case "ListIPSets":
-		s.listIPSets(w, params)
-	default:
-		h.WriteJSONError(w, "UnknownOperationException", fmt.Sprintf("action %q is not supported", action), http.StatusBadRequest)
-	}
+// SetTagStore sets the registry resource tags are recorded in.
+func (s *Service) SetTagStore(store *tags.Store) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tags = store
 }
 
 func buildARN(resource, scope, name, id string) string {
@@ -169,6 +180,7 @@ func (s *Service) createWebACL(w http.ResponseWriter, params map[string]interfac
 		description:      h.GetString(params, "Description"),
 	}
 	s.webACLs[id] = acl
+	s.tags.Tag(arn, tags.FromList(params["Tags"], "Key", "Value"))
 
 	h.WriteJSON(w, http.StatusOK, map[string]interface{}{
 		"Summary": map[string]interface{}{
@@ -214,7 +226,15 @@ func (s *Service) deleteWebACL(w http.ResponseWriter, params map[string]interfac
 		h.WriteJSONError(w, "WAFOptimisticLockException", "LockToken mismatch", http.StatusBadRequest)
 		return
 	}
+	for _, associated := range s.associations {
+		if associated == acl.arn {
+			s.mu.Unlock()
+			h.WriteJSONError(w, "WAFAssociatedItemException", "AWS WAF couldn't perform the operation because your resource is being used by another resource or it's associated with another resource.", http.StatusBadRequest)
+			return
+		}
+	}
 	delete(s.webACLs, id)
+	s.tags.Delete(acl.arn)
 	s.mu.Unlock()
 
 	h.WriteJSON(w, http.StatusOK, map[string]interface{}{})
@@ -329,6 +349,7 @@ func (s *Service) createIPSet(w http.ResponseWriter, params map[string]interface
 		description:      h.GetString(params, "Description"),
 	}
 	s.ipSets[id] = set
+	s.tags.Tag(arn, tags.FromList(params["Tags"], "Key", "Value"))
 
 	h.WriteJSON(w, http.StatusOK, map[string]interface{}{
 		"Summary": map[string]interface{}{
@@ -375,6 +396,7 @@ func (s *Service) deleteIPSet(w http.ResponseWriter, params map[string]interface
 		return
 	}
 	delete(s.ipSets, id)
+	s.tags.Delete(set.arn)
 	s.mu.Unlock()
 
 	h.WriteJSON(w, http.StatusOK, map[string]interface{}{})
@@ -407,6 +429,68 @@ func (s *Service) listIPSets(w http.ResponseWriter, params map[string]interface{
 	})
 }
 
+// hasResource reports whether arn names an existing web ACL or IP set. The
+// caller must hold s.mu.
+func (s *Service) hasResource(arn string) bool {
+	for _, acl := range s.webACLs {
+		if acl.arn == arn {
+			return true
+		}
+	}
+	for _, set := range s.ipSets {
+		if set.arn == arn {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Service) tagResource(w http.ResponseWriter, params map[string]interface{}) {
+	arn := h.GetString(params, "ResourceARN")
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if !s.hasResource(arn) {
+		h.WriteJSONError(w, "WAFNonexistentItemException", "Resource not found: "+arn, http.StatusBadRequest)
+		return
+	}
+	s.tags.Tag(arn, tags.FromList(params["Tags"], "Key", "Value"))
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{})
+}
+
+func (s *Service) untagResource(w http.ResponseWriter, params map[string]interface{}) {
+	arn := h.GetString(params, "ResourceARN")
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if !s.hasResource(arn) {
+		h.WriteJSONError(w, "WAFNonexistentItemException", "Resource not found: "+arn, http.StatusBadRequest)
+		return
+	}
+	s.tags.Untag(arn, tags.Keys(params["TagKeys"]))
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{})
+}
+
+func (s *Service) listTagsForResource(w http.ResponseWriter, params map[string]interface{}) {
+	arn := h.GetString(params, "ResourceARN")
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if !s.hasResource(arn) {
+		h.WriteJSONError(w, "WAFNonexistentItemException", "Resource not found: "+arn, http.StatusBadRequest)
+		return
+	}
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"TagInfoForResource": map[string]interface{}{
+			"ResourceARN": arn,
+			"TagList":     tags.ToList(s.tags.Get(arn), "Key", "Value"),
+		},
+	})
+}
+
 func webACLResp(acl *webACL) map[string]interface{} {
 	resp := map[string]interface{}{
 		"Id":               acl.id,
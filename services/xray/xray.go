@@ -8,6 +8,9 @@
 //   - GetGroup
 //   - DeleteGroup
 //   - GetGroups
+//   - TagResource
+//   - UntagResource
+//   - ListTagsForResource
 package xray
 
 import (
@@ -20,6 +23,7 @@ import (
 	"time"
 
 	h "github.com/riyanimam/goto/internal/mockhelpers"
+	"github.com/riyanimam/goto/internal/tags"
 )
 
 // Service implements the X-Ray mock.
@@ -27,6 +31,7 @@ type Service struct {
 	mu       sync.RWMutex
 	segments map[string]*traceSegment // keyed by segmentId
 	groups   map[string]*group        // keyed by group name
+	tags     *tags.Store
 }
 
 type traceSegment struct {
@@ -47,6 +52,7 @@ func New() *Service {
 	return &Service{
 		segments: make(map[string]*traceSegment),
 		groups:   make(map[string]*group),
+		tags:     tags.New(),
 	}
 }
 
@@ -64,6 +70,14 @@ func (s *Service) Reset() {
 	defer s.mu.Unlock()
 	s.segments = make(map[string]*traceSegment)
 	s.groups = make(map[string]*group)
+	s.tags.DeleteService("xray")
+}
+
+// SetTagStore sets the registry group tags are recorded in.
+func (s *Service) SetTagStore(store *tags.Store) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tags = store
 }
 
 func (s *Service) handle(w http.ResponseWriter, r *http.Request) {
@@ -89,6 +103,12 @@ func (s *Service) handle(w http.ResponseWriter, r *http.Request) {
 		s.deleteGroup(w, r)
 	case "/Groups":
 		s.getGroups(w, r)
+	case "/TagResource":
+		s.tagResource(w, r)
+	case "/UntagResource":
+		s.untagResource(w, r)
+	case "/ListTagsForResource":
+		s.listTagsForResource(w, r)
 	default:
 		h.WriteJSONError(w, "InvalidAction", "unsupported operation", http.StatusBadRequest)
 	}
@@ -278,6 +298,7 @@ func (s *Service) createGroup(w http.ResponseWriter, r *http.Request) {
 		filterExpression: h.GetString(params, "FilterExpression"),
 	}
 	s.groups[name] = g
+	s.tags.Tag(g.arn, tags.FromList(params["Tags"], "Key", "Value"))
 	s.mu.Unlock()
 
 	h.WriteJSON(w, http.StatusOK, map[string]interface{}{
@@ -327,6 +348,7 @@ func (s *Service) deleteGroup(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	delete(s.groups, g.name)
+	s.tags.Delete(g.arn)
 	s.mu.Unlock()
 
 	h.WriteJSON(w, http.StatusOK, map[string]interface{}{})
@@ -349,6 +371,57 @@ func (s *Service) getGroups(w http.ResponseWriter, _ *http.Request) {
 	})
 }
 
+// Tags. Groups are the taggable resources.
+
+func (s *Service) tagResource(w http.ResponseWriter, r *http.Request) {
+	bodyBytes, _ := io.ReadAll(r.Body)
+	var params map[string]interface{}
+	json.Unmarshal(bodyBytes, &params)
+	arn := h.GetString(params, "ResourceARN")
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if arn == "" || s.findGroup("", arn) == nil {
+		h.WriteJSONError(w, "ResourceNotFoundException", "Resource not found: "+arn, http.StatusNotFound)
+		return
+	}
+	s.tags.Tag(arn, tags.FromList(params["Tags"], "Key", "Value"))
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{})
+}
+
+func (s *Service) untagResource(w http.ResponseWriter, r *http.Request) {
+	bodyBytes, _ := io.ReadAll(r.Body)
+	var params map[string]interface{}
+	json.Unmarshal(bodyBytes, &params)
+	arn := h.GetString(params, "ResourceARN")
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if arn == "" || s.findGroup("", arn) == nil {
+		h.WriteJSONError(w, "ResourceNotFoundException", "Resource not found: "+arn, http.StatusNotFound)
+		return
+	}
+	s.tags.Untag(arn, tags.Keys(params["TagKeys"]))
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{})
+}
+
+func (s *Service) listTagsForResource(w http.ResponseWriter, r *http.Request) {
+	bodyBytes, _ := io.ReadAll(r.Body)
+	var params map[string]interface{}
+	json.Unmarshal(bodyBytes, &params)
+	arn := h.GetString(params, "ResourceARN")
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if arn == "" || s.findGroup("", arn) == nil {
+		h.WriteJSONError(w, "ResourceNotFoundException", "Resource not found: "+arn, http.StatusNotFound)
+		return
+	}
+	h.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"Tags": tags.ToList(s.tags.Get(arn), "Key", "Value"),
+	})
+}
+
 // findGroup looks up a group by name or ARN. Must be called with s.mu held.
 func (s *Service) findGroup(name, arn string) *group {
 	if name != "" {
@@ -0,0 +1,112 @@
+package awsmock
+
+import (
+	"sort"
+
+	"github.com/riyanimam/goto/internal/mockhelpers"
+)
+
+// stateExporter is implemented by services that can list the resources they
+// hold.
+type stateExporter interface {
+	ExportState() []mockhelpers.Resource
+}
+
+// State is an inventory of the resources held by the mock, as returned by
+// [MockServer.ExportState]. It marshals to JSON in a stable order.
+type State struct {
+	Resources []Resource `json:"resources"`
+}
+
+// Resource is one resource in a [State].
+type Resource struct {
+	// Service is the mock service holding the resource (e.g. "sqs").
+	Service string `json:"service"`
+	// Type is the Terraform resource type (e.g. "aws_sqs_queue").
+	Type string `json:"type"`
+	// ID identifies the resource the way Terraform does: a name for most
+	// resources, an ARN for some.
+	ID  string `json:"id"`
+	ARN string `json:"arn,omitempty"`
+	// Attributes describe the resource's configuration, named as in
+	// Terraform where the two overlap.
+	Attributes map[string]interface{} `json:"attributes"`
+	// Tags are the resource's tags, if it has any.
+	Tags map[string]string `json:"tags,omitempty"`
+}
+
+// ExportState returns an inventory of every resource held by the mock, so
+// that tests can compare the infrastructure their code built with what they
+// expected, or keep it as a golden file:
+//
+//	got, _ := json.MarshalIndent(mock.ExportState(), "", "  ")
+//	want, _ := os.ReadFile("testdata/state.golden.json")
+//
+// Resources are sorted by type and ID. Attributes leave out creation times
+// and server URLs, so the export of a deterministic setup only changes where
+// the mock generates IDs.
+//
+// S3, SQS, SNS, DynamoDB, Lambda, IAM, KMS, Secrets Manager, SSM,
+// CloudWatch Logs, EC2, and ECR report their resources; other services are
+// left out.
+func (m *MockServer) ExportState() State {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	resources := []Resource{}
+	for name, svc := range m.services {
+		e, ok := svc.(stateExporter)
+		if !ok {
+			continue
+		}
+		for _, r := range e.ExportState() {
+			res := Resource{
+				Service:    name,
+				Type:       r.Type,
+				ID:         r.ID,
+				ARN:        r.ARN,
+				Attributes: r.Attributes,
+			}
+			if r.ARN != "" {
+				if t := m.tags.Get(r.ARN); len(t) > 0 {
+					res.Tags = t
+				}
+			}
+			resources = append(resources, res)
+		}
+	}
+	sort.Slice(resources, func(i, j int) bool {
+		a, b := resources[i], resources[j]
+		if a.Type != b.Type {
+			return a.Type < b.Type
+		}
+		if a.ID != b.ID {
+			return a.ID < b.ID
+		}
+		return a.ARN < b.ARN
+	})
+	return State{Resources: resources}
+}
+
+// listResources returns the resources held by the named service, as
+// ExportState reports them, for services that report on resources they do
+// not own.
+func (m *MockServer) listResources(service string) []mockhelpers.Resource {
+	m.mu.RLock()
+	svc := m.services[service]
+	m.mu.RUnlock()
+	if e, ok := svc.(stateExporter); ok {
+		return e.ExportState()
+	}
+	return nil
+}
+
+// Find returns the resource of the given type and ID.
+func (s State) Find(resourceType, id string) (Resource, bool) {
+	for _, r := range s.Resources {
+		if r.Type == resourceType && r.ID == id {
+			return r, true
+		}
+	}
+	return Resource{}, false
+}
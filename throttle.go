@@ -0,0 +1,123 @@
+package awsmock
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"math"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/riyanimam/goto/internal/clock"
+)
+
+// rateLimiter is a token bucket refilled as time passes in real time or on
+// the mock clock, whichever has moved further, so SDK retries backing off in
+// real time eventually get through, and tests can refill it at once by
+// advancing the clock.
+type rateLimiter struct {
+	mu     sync.Mutex
+	clock  *clock.Clock
+	rate   float64
+	burst  float64
+	tokens float64
+	last   clock.Mark
+}
+
+func newRateLimiter(c *clock.Clock, rate float64, burst int) *rateLimiter {
+	if burst < 1 {
+		burst = 1
+	}
+	return &rateLimiter{
+		clock:  c,
+		rate:   rate,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   c.Mark(),
+	}
+}
+
+// allow takes a token if one is available.
+func (l *rateLimiter) allow() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.tokens = math.Min(l.burst, l.tokens+l.rate*l.clock.Since(l.last).Seconds())
+	l.last = l.clock.Mark()
+	if l.tokens < 1 {
+		return false
+	}
+	l.tokens--
+	return true
+}
+
+// throttled reports whether a request to service exceeds its rate limit.
+func (m *MockServer) throttled(service string) bool {
+	l, ok := m.limiters[service]
+	return ok && !l.allow()
+}
+
+// writeThrottled rejects a request with the throttling error its protocol
+// uses, which the SDKs recognize as retryable.
+func writeThrottled(w http.ResponseWriter, r *http.Request, service string) {
+	if service == "s3" {
+		writeServiceError(w, r, service, http.StatusServiceUnavailable, "SlowDown", "Please reduce your request rate.")
+		return
+	}
+	code := "ThrottlingException"
+	if r.Header.Get("X-Amz-Target") == "" && isXMLRequest(r, service) {
+		code = "Throttling"
+	}
+	writeServiceError(w, r, service, http.StatusBadRequest, code, "Rate exceeded")
+}
+
+// writeServiceError writes an error response in the format of the request's
+// protocol: S3's XML, the query protocols' and REST XML services' XML, or
+// JSON for everything else, with the code also in the X-Amzn-ErrorType
+// header that REST JSON clients read.
+func writeServiceError(w http.ResponseWriter, r *http.Request, service string, status int, code, message string) {
+	switch {
+	case service == "s3":
+		w.Header().Set("Content-Type", "application/xml")
+		w.WriteHeader(status)
+		xml.NewEncoder(w).Encode(struct {
+			XMLName xml.Name `xml:"Error"`
+			Code    string   `xml:"Code"`
+			Message string   `xml:"Message"`
+		}{Code: code, Message: message})
+	case r.Header.Get("X-Amz-Target") == "" && isXMLRequest(r, service):
+		errType := "Sender"
+		if status >= http.StatusInternalServerError {
+			errType = "Receiver"
+		}
+		w.Header().Set("Content-Type", "text/xml")
+		w.WriteHeader(status)
+		xml.NewEncoder(w).Encode(struct {
+			XMLName xml.Name `xml:"ErrorResponse"`
+			Type    string   `xml:"Error>Type"`
+			Code    string   `xml:"Error>Code"`
+			Message string   `xml:"Error>Message"`
+		}{Type: errType, Code: code, Message: message})
+	default:
+		w.Header().Set("Content-Type", "application/x-amz-json-1.1")
+		w.Header().Set("X-Amzn-ErrorType", code)
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(map[string]string{
+			"__type":  code,
+			"message": message,
+		})
+	}
+}
+
+// isXMLRequest reports whether a request without an X-Amz-Target header
+// expects an XML error: query protocol requests, which are form-encoded, and
+// requests to the REST XML services.
+func isXMLRequest(r *http.Request, service string) bool {
+	switch {
+	case service == "route53" || service == "cloudfront":
+		return true
+	case strings.Contains(r.Header.Get("Content-Type"), "json"):
+		return false
+	}
+	return strings.HasPrefix(r.Header.Get("Content-Type"), "application/x-www-form-urlencoded") || r.URL.Query().Get("Action") != ""
+}
@@ -0,0 +1,402 @@
+package awsmock
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/riyanimam/goto/internal/arn"
+	"github.com/riyanimam/goto/internal/clock"
+	"github.com/riyanimam/goto/internal/lifecycle"
+	"github.com/riyanimam/goto/internal/mockhelpers"
+	"github.com/riyanimam/goto/internal/tags"
+)
+
+// clockUser is implemented by services whose behavior depends on mock time.
+type clockUser interface {
+	SetClock(c *clock.Clock)
+}
+
+// dispatchUser is implemented by services that deliver payloads to resources
+// owned by other services (e.g. schedule targets).
+type dispatchUser interface {
+	SetDispatcher(d mockhelpers.Dispatcher)
+}
+
+// resolverUser is implemented by services that accept ARNs of resources
+// owned by other services (e.g. rule targets) and reject dangling ones.
+type resolverUser interface {
+	SetResolver(r mockhelpers.Resolver)
+}
+
+// resourceListerUser is implemented by services that report on resources
+// owned by other services (e.g. vulnerability scan coverage).
+type resourceListerUser interface {
+	SetResourceLister(l mockhelpers.ResourceLister)
+}
+
+// baseURLUser is implemented by services that hand out endpoints served by
+// the mock server itself (e.g. OpenSearch domain endpoints).
+type baseURLUser interface {
+	SetBaseURL(url string)
+}
+
+// objectStoreUser is implemented by services that read or write objects in
+// the S3 mock's buckets.
+type objectStoreUser interface {
+	SetObjectStore(store mockhelpers.ObjectStore)
+}
+
+// fileStoreUser is implemented by services that read or write files in the
+// EFS mock's file systems.
+type fileStoreUser interface {
+	SetFileStore(store mockhelpers.FileStore)
+}
+
+// metricStoreUser is implemented by services that publish metrics to the
+// CloudWatch metrics mock.
+type metricStoreUser interface {
+	SetMetricStore(store mockhelpers.MetricStore)
+}
+
+// eventSourceUser is implemented by services that consume the records of
+// queues and streams held by other services.
+type eventSourceUser interface {
+	SetEventSource(src mockhelpers.EventSource)
+}
+
+// tokenVerifierUser is implemented by services that accept the bearer
+// tokens issued by the SSO OIDC mock.
+type tokenVerifierUser interface {
+	SetTokenVerifier(v mockhelpers.TokenVerifier)
+}
+
+// credentialIssuerUser is implemented by services that hand out role
+// credentials issued by the STS mock.
+type credentialIssuerUser interface {
+	SetCredentialIssuer(c mockhelpers.CredentialIssuer)
+}
+
+// tagStoreUser is implemented by services that record resource tags, so
+// that the Resource Groups Tagging API sees every service's tags.
+type tagStoreUser interface {
+	SetTagStore(store *tags.Store)
+}
+
+// transitionsUser is implemented by services whose resources start in a
+// transitional status (CREATING, PENDING) before they are ready.
+type transitionsUser interface {
+	SetTransitions(t *lifecycle.Transitions)
+}
+
+// resourceOwner is implemented by services that can report whether a
+// resource they own exists, so that references to it can be checked.
+type resourceOwner interface {
+	HasResource(arn string) bool
+}
+
+// deliveryTarget is implemented by services whose resources can receive
+// payloads dispatched by other services.
+type deliveryTarget interface {
+	Deliver(arn string, payload []byte) ([]byte, error)
+}
+
+// wire connects a service to the server's shared clock, dispatcher,
+// resolver, resource lister, URL, object, file, and metric stores, event
+// sources, token verifier, credential issuer, tag registry, and status
+// transitions.
+func (m *MockServer) wire(svc Service) {
+	if b, ok := svc.(baseURLUser); ok && m.URL() != "" {
+		b.SetBaseURL(m.URL())
+	}
+	if c, ok := svc.(clockUser); ok {
+		c.SetClock(m.clock)
+	}
+	if d, ok := svc.(dispatchUser); ok {
+		d.SetDispatcher(m.dispatch)
+	}
+	if r, ok := svc.(resolverUser); ok {
+		r.SetResolver(m.resolve)
+	}
+	if l, ok := svc.(resourceListerUser); ok {
+		l.SetResourceLister(m.listResources)
+	}
+	if o, ok := svc.(objectStoreUser); ok {
+		o.SetObjectStore(serverObjectStore{m})
+	}
+	if f, ok := svc.(fileStoreUser); ok {
+		f.SetFileStore(serverFileStore{m})
+	}
+	if s, ok := svc.(metricStoreUser); ok {
+		s.SetMetricStore(serverMetricStore{m})
+	}
+	if e, ok := svc.(eventSourceUser); ok {
+		e.SetEventSource(serverEventSource{m})
+	}
+	if v, ok := svc.(tokenVerifierUser); ok {
+		v.SetTokenVerifier(serverTokenVerifier{m})
+	}
+	if c, ok := svc.(credentialIssuerUser); ok {
+		c.SetCredentialIssuer(serverCredentialIssuer{m})
+	}
+	if t, ok := svc.(tagStoreUser); ok {
+		t.SetTagStore(m.tags)
+	}
+	if t, ok := svc.(transitionsUser); ok {
+		t.SetTransitions(m.transitions)
+	}
+}
+
+// dispatch delivers payload to the resource identified by arn by handing it
+// to the registered service owning it, as named in the ARN.
+func (m *MockServer) dispatch(resource string, payload []byte) ([]byte, error) {
+	a, err := arn.Parse(resource)
+	if err != nil {
+		return nil, err
+	}
+
+	m.mu.RLock()
+	svc, ok := m.services[ownerOf(a)]
+	m.mu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("no service registered for %s", a.Service)
+	}
+	target, ok := svc.(deliveryTarget)
+	if !ok {
+		return nil, fmt.Errorf("service %s cannot receive deliveries", a.Service)
+	}
+	return target.Deliver(resource, payload)
+}
+
+// ownerOf returns the name of the service owning the resource a names,
+// which is the ARN's service field except for Application Auto Scaling
+// policies: they share the "autoscaling" field with EC2 Auto Scaling, and
+// are told apart by naming their scalable resource.
+func ownerOf(a arn.ARN) string {
+	if a.Service == "autoscaling" && strings.Contains(a.Resource, ":resource/") {
+		return "application-autoscaling"
+	}
+	return a.Service
+}
+
+// resolve checks that resource is a well-formed ARN and, if the service
+// named in it is registered and can report on its resources, that the
+// resource exists.
+func (m *MockServer) resolve(resource string) error {
+	a, err := arn.Parse(resource)
+	if err != nil {
+		return err
+	}
+
+	m.mu.RLock()
+	svc := m.services[ownerOf(a)]
+	m.mu.RUnlock()
+
+	owner, ok := svc.(resourceOwner)
+	if !ok {
+		return nil
+	}
+	if !owner.HasResource(resource) {
+		return fmt.Errorf("%w: %s", arn.ErrNotFound, resource)
+	}
+	return nil
+}
+
+// serverObjectStore forwards to whichever "s3" service is registered at the
+// time of the call, so a replacement registered with [WithService] is seen by
+// services wired before it.
+type serverObjectStore struct {
+	m *MockServer
+}
+
+func (o serverObjectStore) store() (mockhelpers.ObjectStore, error) {
+	o.m.mu.RLock()
+	defer o.m.mu.RUnlock()
+	store, ok := o.m.services["s3"].(mockhelpers.ObjectStore)
+	if !ok {
+		return nil, fmt.Errorf("s3 service does not support direct object access")
+	}
+	return store, nil
+}
+
+func (o serverObjectStore) PutObject(bucket, key string, data []byte) error {
+	store, err := o.store()
+	if err != nil {
+		return err
+	}
+	return store.PutObject(bucket, key, data)
+}
+
+func (o serverObjectStore) GetObject(bucket, key string) ([]byte, error) {
+	store, err := o.store()
+	if err != nil {
+		return nil, err
+	}
+	return store.GetObject(bucket, key)
+}
+
+func (o serverObjectStore) DeleteObject(bucket, key string) error {
+	store, err := o.store()
+	if err != nil {
+		return err
+	}
+	return store.DeleteObject(bucket, key)
+}
+
+func (o serverObjectStore) ListObjects(bucket, prefix string) ([]string, error) {
+	store, err := o.store()
+	if err != nil {
+		return nil, err
+	}
+	return store.ListObjects(bucket, prefix)
+}
+
+// serverFileStore forwards to whichever "elasticfilesystem" service is
+// registered at the time of the call, as serverObjectStore does for S3.
+type serverFileStore struct {
+	m *MockServer
+}
+
+func (f serverFileStore) store() (mockhelpers.FileStore, error) {
+	f.m.mu.RLock()
+	defer f.m.mu.RUnlock()
+	store, ok := f.m.services["elasticfilesystem"].(mockhelpers.FileStore)
+	if !ok {
+		return nil, fmt.Errorf("efs service does not support direct file access")
+	}
+	return store, nil
+}
+
+func (f serverFileStore) WriteFile(fileSystemID, path string, data []byte) error {
+	store, err := f.store()
+	if err != nil {
+		return err
+	}
+	return store.WriteFile(fileSystemID, path, data)
+}
+
+func (f serverFileStore) ReadFile(fileSystemID, path string) ([]byte, error) {
+	store, err := f.store()
+	if err != nil {
+		return nil, err
+	}
+	return store.ReadFile(fileSystemID, path)
+}
+
+func (f serverFileStore) DeleteFile(fileSystemID, path string) error {
+	store, err := f.store()
+	if err != nil {
+		return err
+	}
+	return store.DeleteFile(fileSystemID, path)
+}
+
+func (f serverFileStore) ListFiles(fileSystemID, dir string) ([]string, error) {
+	store, err := f.store()
+	if err != nil {
+		return nil, err
+	}
+	return store.ListFiles(fileSystemID, dir)
+}
+
+// serverMetricStore forwards to whichever "monitoring" service is registered
+// at the time of the call, as serverObjectStore does for S3.
+type serverMetricStore struct {
+	m *MockServer
+}
+
+func (s serverMetricStore) PutMetrics(metrics []mockhelpers.Metric) error {
+	s.m.mu.RLock()
+	store, ok := s.m.services["monitoring"].(mockhelpers.MetricStore)
+	s.m.mu.RUnlock()
+	if !ok {
+		return fmt.Errorf("cloudwatch service does not support direct metric access")
+	}
+	return store.PutMetrics(metrics)
+}
+
+// serverEventSource forwards to the registered service named in the source
+// ARN's service field, as dispatch does for deliveries.
+type serverEventSource struct {
+	m *MockServer
+}
+
+func (e serverEventSource) source(resource string) (mockhelpers.EventSource, error) {
+	a, err := arn.Parse(resource)
+	if err != nil {
+		return nil, err
+	}
+
+	e.m.mu.RLock()
+	svc, ok := e.m.services[a.Service]
+	e.m.mu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("no service registered for %s", a.Service)
+	}
+	src, ok := svc.(mockhelpers.EventSource)
+	if !ok {
+		return nil, fmt.Errorf("service %s cannot be read as an event source", a.Service)
+	}
+	return src, nil
+}
+
+func (e serverEventSource) ReadEvents(resource, position string, max int) ([]map[string]interface{}, string, error) {
+	src, err := e.source(resource)
+	if err != nil {
+		return nil, "", err
+	}
+	return src.ReadEvents(resource, position, max)
+}
+
+func (e serverEventSource) AckEvents(resource string, events []map[string]interface{}, processed bool) error {
+	src, err := e.source(resource)
+	if err != nil {
+		return err
+	}
+	return src.AckEvents(resource, events, processed)
+}
+
+// serverTokenVerifier forwards to whichever "sso-oauth" service is
+// registered at the time of the call, as serverObjectStore does for S3.
+type serverTokenVerifier struct {
+	m *MockServer
+}
+
+func (v serverTokenVerifier) verifier() (mockhelpers.TokenVerifier, bool) {
+	v.m.mu.RLock()
+	defer v.m.mu.RUnlock()
+	tv, ok := v.m.services["sso-oauth"].(mockhelpers.TokenVerifier)
+	return tv, ok
+}
+
+func (v serverTokenVerifier) VerifyToken(accessToken string) error {
+	tv, ok := v.verifier()
+	if !ok {
+		return fmt.Errorf("sso-oauth service does not issue tokens")
+	}
+	return tv.VerifyToken(accessToken)
+}
+
+func (v serverTokenVerifier) RevokeToken(accessToken string) {
+	if tv, ok := v.verifier(); ok {
+		tv.RevokeToken(accessToken)
+	}
+}
+
+// serverCredentialIssuer forwards to whichever "sts" service is registered
+// at the time of the call.
+type serverCredentialIssuer struct {
+	m *MockServer
+}
+
+func (c serverCredentialIssuer) IssueCredentials(roleArn string, duration time.Duration) (mockhelpers.Credentials, error) {
+	c.m.mu.RLock()
+	issuer, ok := c.m.services["sts"].(mockhelpers.CredentialIssuer)
+	c.m.mu.RUnlock()
+	if !ok {
+		return mockhelpers.Credentials{}, fmt.Errorf("sts service does not issue credentials")
+	}
+	return issuer.IssueCredentials(roleArn, duration)
+}